@@ -0,0 +1,283 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package accounts models the accounts of hierarchical deterministic wallets
+// following BIP0044 and its BIP0049 and BIP0084 variants.
+//
+// Accounts are derived at m/purpose'/coin_type'/account' below a master key,
+// with the coin type registered for the network, and hold an external chain
+// of receive addresses and an internal chain of change addresses.  The
+// metadata of an account serializes to JSON along with its extended public
+// key, so wallets can store and restore their account structure without
+// handling raw derivation paths.
+package accounts
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil/hdkeychain"
+)
+
+// Purpose is the first level of the derivation path of an account, which
+// identifies the scheme its addresses follow.
+type Purpose uint32
+
+// These constants define the supported purposes.
+const (
+	// PurposeBIP0044 is for pay-to-pubkey-hash addresses.
+	PurposeBIP0044 Purpose = 44
+
+	// PurposeBIP0049 is for pay-to-witness-pubkey-hash addresses nested
+	// in pay-to-script-hash addresses.
+	PurposeBIP0049 Purpose = 49
+
+	// PurposeBIP0084 is for native pay-to-witness-pubkey-hash addresses.
+	PurposeBIP0084 Purpose = 84
+)
+
+// Map of purposes back to their constant names for pretty printing.
+var purposeStrings = map[Purpose]string{
+	PurposeBIP0044: "PurposeBIP0044",
+	PurposeBIP0049: "PurposeBIP0049",
+	PurposeBIP0084: "PurposeBIP0084",
+}
+
+// String returns the Purpose as a human-readable name.
+func (p Purpose) String() string {
+	if s := purposeStrings[p]; s != "" {
+		return s
+	}
+	return fmt.Sprintf("Unknown Purpose (%d)", uint32(p))
+}
+
+// KeyPurpose returns the purpose signalled by the version bytes of extended
+// keys of accounts of the purpose.
+func (p Purpose) KeyPurpose() hdkeychain.KeyPurpose {
+	switch p {
+	case PurposeBIP0049:
+		return hdkeychain.PurposeNestedWitness
+	case PurposeBIP0084:
+		return hdkeychain.PurposeWitness
+	}
+	return hdkeychain.PurposeLegacy
+}
+
+// These constants define the chains of an account.
+const (
+	// ExternalChain is the chain of receive addresses.
+	ExternalChain uint32 = 0
+
+	// InternalChain is the chain of change addresses.
+	InternalChain uint32 = 1
+)
+
+var (
+	// ErrUnknownPurpose describes an error where an account is requested
+	// for a purpose which is not supported.
+	ErrUnknownPurpose = errors.New("unknown account purpose")
+
+	// ErrInvalidIndex describes an error where an account or coin type
+	// index is not below hdkeychain.HardenedKeyStart.
+	ErrInvalidIndex = errors.New("invalid account index")
+
+	// ErrNotMasterKey describes an error where an account is derived from
+	// an extended key which is not a master key.
+	ErrNotMasterKey = errors.New("extended key is not a master key")
+
+	// ErrInvalidMetadata describes an error where serialized account
+	// metadata is malformed or does not match the account key.
+	ErrInvalidMetadata = errors.New("invalid account metadata")
+)
+
+// CoinType returns the coin type of accounts of net, as registered in
+// SLIP-0044 and held by its parameters.
+func CoinType(net *chaincfg.Params) uint32 {
+	return net.HDCoinType
+}
+
+// Account is a wallet account, the extended key at m/purpose'/coin_type'/
+// account' below a master key.
+type Account struct {
+	// Name is a label of the account chosen by the application.
+	Name string
+
+	// Purpose is the purpose of the account.
+	Purpose Purpose
+
+	// CoinType is the coin type of the account.
+	CoinType uint32
+
+	// Index is the index of the account, without the hardened offset.
+	Index uint32
+
+	// MasterFingerprint is the fingerprint of the master key the account
+	// was derived from.
+	MasterFingerprint uint32
+
+	// Key is the extended key of the account.  It is an extended public
+	// key for watch-only accounts.
+	Key *hdkeychain.ExtendedKey
+}
+
+// Derive returns account index of purpose on net, derived from the extended
+// private key master.
+func Derive(master *hdkeychain.ExtendedKey, net *chaincfg.Params,
+	purpose Purpose, index uint32) (*Account, error) {
+	if _, ok := purposeStrings[purpose]; !ok {
+		return nil, ErrUnknownPurpose
+	}
+	if master.Depth() != 0 {
+		return nil, ErrNotMasterKey
+	}
+	a := &Account{
+		Purpose:           purpose,
+		CoinType:          CoinType(net),
+		Index:             index,
+		MasterFingerprint: master.Fingerprint(),
+	}
+	if a.CoinType >= hdkeychain.HardenedKeyStart ||
+		index >= hdkeychain.HardenedKeyStart {
+		return nil, ErrInvalidIndex
+	}
+	key, err := master.DerivePath(a.Path())
+	if err != nil {
+		return nil, err
+	}
+	a.Key = key
+	return a, nil
+}
+
+// Path returns the derivation path of the account below the master key.
+func (a *Account) Path() hdkeychain.DerivationPath {
+	return hdkeychain.DerivationPath{
+		uint32(a.Purpose) + hdkeychain.HardenedKeyStart,
+		a.CoinType + hdkeychain.HardenedKeyStart,
+		a.Index + hdkeychain.HardenedKeyStart,
+	}
+}
+
+// Origin returns the key origin of the account key.
+func (a *Account) Origin() hdkeychain.KeyOrigin {
+	return hdkeychain.KeyOrigin{
+		Fingerprint: a.MasterFingerprint,
+		Path:        a.Path(),
+	}
+}
+
+// IsWatchOnly returns whether the account lacks the private keys to sign.
+func (a *Account) IsWatchOnly() bool {
+	return !a.Key.IsPrivate()
+}
+
+// Neuter returns a watch-only copy of the account.
+func (a *Account) Neuter() (*Account, error) {
+	key, err := a.Key.Neuter()
+	if err != nil {
+		return nil, err
+	}
+	neutered := *a
+	neutered.Key = key
+	return &neutered, nil
+}
+
+// Chain returns the extended key of chain, ExternalChain or InternalChain.
+func (a *Account) Chain(chain uint32) (*hdkeychain.ExtendedKey, error) {
+	return a.Key.Child(chain)
+}
+
+// External returns the extended key of the chain of receive addresses.
+func (a *Account) External() (*hdkeychain.ExtendedKey, error) {
+	return a.Chain(ExternalChain)
+}
+
+// Internal returns the extended key of the chain of change addresses.
+func (a *Account) Internal() (*hdkeychain.ExtendedKey, error) {
+	return a.Chain(InternalChain)
+}
+
+// AddressKey returns the extended key at index of chain, along with its key
+// origin.
+func (a *Account) AddressKey(chain, index uint32) (*hdkeychain.ExtendedKey,
+	hdkeychain.KeyOrigin, error) {
+	chainKey, err := a.Chain(chain)
+	if err != nil {
+		return nil, hdkeychain.KeyOrigin{}, err
+	}
+	key, err := chainKey.Child(index)
+	if err != nil {
+		return nil, hdkeychain.KeyOrigin{}, err
+	}
+	origin := a.Origin()
+	origin.Path = origin.Path.Child(chain).Child(index)
+	return key, origin, nil
+}
+
+// accountJSON is the serialized metadata of an account.
+type accountJSON struct {
+	Name        string `json:"name,omitempty"`
+	Purpose     uint32 `json:"purpose"`
+	CoinType    uint32 `json:"coin_type"`
+	Account     uint32 `json:"account"`
+	Fingerprint string `json:"master_fingerprint"`
+	Key         string `json:"xpub"`
+}
+
+// MarshalJSON implements the json.Marshaler interface.  Only the extended
+// public key of the account is serialized, so the metadata of an account
+// never holds secrets and restores a watch-only account.
+func (a *Account) MarshalJSON() ([]byte, error) {
+	pub, err := a.Key.Neuter()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(accountJSON{
+		Name:        a.Name,
+		Purpose:     uint32(a.Purpose),
+		CoinType:    a.CoinType,
+		Account:     a.Index,
+		Fingerprint: fmt.Sprintf("%08x", a.MasterFingerprint),
+		Key:         pub.String(),
+	})
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.  The account is
+// restored watch-only.  ErrInvalidMetadata is returned when the metadata is
+// malformed or the key is not at the depth of an account key.
+func (a *Account) UnmarshalJSON(b []byte) error {
+	var aj accountJSON
+	if err := json.Unmarshal(b, &aj); err != nil {
+		return err
+	}
+	if _, ok := purposeStrings[Purpose(aj.Purpose)]; !ok {
+		return ErrUnknownPurpose
+	}
+	if aj.CoinType >= hdkeychain.HardenedKeyStart ||
+		aj.Account >= hdkeychain.HardenedKeyStart {
+		return ErrInvalidIndex
+	}
+	fingerprint, err := strconv.ParseUint(aj.Fingerprint, 16, 32)
+	if err != nil || len(aj.Fingerprint) != 8 {
+		return ErrInvalidMetadata
+	}
+	key, err := hdkeychain.NewKeyFromString(aj.Key)
+	if err != nil {
+		return err
+	}
+	if key.IsPrivate() || key.Depth() != 3 {
+		return ErrInvalidMetadata
+	}
+	*a = Account{
+		Name:              aj.Name,
+		Purpose:           Purpose(aj.Purpose),
+		CoinType:          aj.CoinType,
+		Index:             aj.Account,
+		MasterFingerprint: uint32(fingerprint),
+		Key:               key,
+	}
+	return nil
+}
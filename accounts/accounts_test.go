@@ -0,0 +1,174 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package accounts_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil/accounts"
+	"github.com/zeusyf/btcutil/hdkeychain"
+)
+
+// testMaster returns a master key of the main network.
+func testMaster(t *testing.T) *hdkeychain.ExtendedKey {
+	t.Helper()
+	seed := bytes.Repeat([]byte{0x42}, hdkeychain.RecommendedSeedLen)
+	master, err := hdkeychain.NewMaster(seed, &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("NewMaster: unexpected error: %v", err)
+	}
+	return master
+}
+
+// TestDerive ensures accounts are derived at the path of their purpose, coin
+// type and index, and expose their chains.
+func TestDerive(t *testing.T) {
+	net := &chaincfg.MainNetParams
+	master := testMaster(t)
+	a, err := accounts.Derive(master, net, accounts.PurposeBIP0084, 2)
+	if err != nil {
+		t.Fatalf("Derive: unexpected error: %v", err)
+	}
+	wantPath := fmt.Sprintf("m/84'/%d'/2'", accounts.CoinType(net))
+	if a.Path().String() != wantPath {
+		t.Errorf("Path: got %v, want %s", a.Path(), wantPath)
+	}
+	if a.Origin().Fingerprint != master.Fingerprint() {
+		t.Errorf("Origin: got fingerprint %08x, want %08x",
+			a.Origin().Fingerprint, master.Fingerprint())
+	}
+	want, err := master.DerivePath(a.Path())
+	if err != nil {
+		t.Fatalf("DerivePath: unexpected error: %v", err)
+	}
+	if a.Key.String() != want.String() || a.IsWatchOnly() {
+		t.Errorf("Derive: got key %v, want %v", a.Key, want)
+	}
+
+	key, origin, err := a.AddressKey(accounts.InternalChain, 5)
+	if err != nil {
+		t.Fatalf("AddressKey: unexpected error: %v", err)
+	}
+	wantKey, err := master.DerivePath(origin.Path)
+	if err != nil {
+		t.Fatalf("DerivePath: unexpected error: %v", err)
+	}
+	if key.String() != wantKey.String() ||
+		origin.Path.String() != wantPath+"/1/5" {
+		t.Errorf("AddressKey: got key at %v", origin.Path)
+	}
+	internal, err := a.Internal()
+	if err != nil {
+		t.Fatalf("Internal: unexpected error: %v", err)
+	}
+	external, err := a.External()
+	if err != nil {
+		t.Fatalf("External: unexpected error: %v", err)
+	}
+	if internal.String() == external.String() {
+		t.Errorf("Internal: same key as the external chain")
+	}
+
+	watch, err := a.Neuter()
+	if err != nil {
+		t.Fatalf("Neuter: unexpected error: %v", err)
+	}
+	if !watch.IsWatchOnly() || a.IsWatchOnly() {
+		t.Errorf("Neuter: got watch-only %v and %v", watch.IsWatchOnly(),
+			a.IsWatchOnly())
+	}
+	watchExternal, err := watch.External()
+	if err != nil {
+		t.Fatalf("External: unexpected error: %v", err)
+	}
+	pub, _ := external.Neuter()
+	if watchExternal.String() != pub.String() {
+		t.Errorf("External: got %v, want %v", watchExternal, pub)
+	}
+}
+
+// TestDeriveErrors ensures accounts are only derived from master keys, for
+// known purposes and non-hardened indexes.
+func TestDeriveErrors(t *testing.T) {
+	net := &chaincfg.MainNetParams
+	master := testMaster(t)
+	child, err := master.Child(0)
+	if err != nil {
+		t.Fatalf("Child: unexpected error: %v", err)
+	}
+	pub, err := master.Neuter()
+	if err != nil {
+		t.Fatalf("Neuter: unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		key     *hdkeychain.ExtendedKey
+		purpose accounts.Purpose
+		index   uint32
+		want    error
+	}{
+		{master, 45, 0, accounts.ErrUnknownPurpose},
+		{master, accounts.PurposeBIP0044, hdkeychain.HardenedKeyStart,
+			accounts.ErrInvalidIndex},
+		{child, accounts.PurposeBIP0044, 0, accounts.ErrNotMasterKey},
+		{pub, accounts.PurposeBIP0044, 0, hdkeychain.ErrDeriveHardFromPublic},
+	}
+	for i, test := range tests {
+		_, err := accounts.Derive(test.key, net, test.purpose, test.index)
+		if err != test.want {
+			t.Errorf("Derive #%d: got error %v, want %v", i, err, test.want)
+		}
+	}
+}
+
+// TestAccountJSON ensures account metadata round-trips through JSON without
+// the private key.
+func TestAccountJSON(t *testing.T) {
+	a, err := accounts.Derive(testMaster(t), &chaincfg.MainNetParams,
+		accounts.PurposeBIP0049, 0)
+	if err != nil {
+		t.Fatalf("Derive: unexpected error: %v", err)
+	}
+	a.Name = "savings"
+	b, err := json.Marshal(a)
+	if err != nil {
+		t.Fatalf("Marshal: unexpected error: %v", err)
+	}
+	priv := a.Key.String()
+	if bytes.Contains(b, []byte(priv)) {
+		t.Fatalf("Marshal: private key serialized: %s", b)
+	}
+
+	var restored accounts.Account
+	if err := json.Unmarshal(b, &restored); err != nil {
+		t.Fatalf("Unmarshal: unexpected error: %v", err)
+	}
+	watch, err := a.Neuter()
+	if err != nil {
+		t.Fatalf("Neuter: unexpected error: %v", err)
+	}
+	if restored.Name != a.Name || restored.Purpose != a.Purpose ||
+		restored.CoinType != a.CoinType || restored.Index != a.Index ||
+		restored.MasterFingerprint != a.MasterFingerprint ||
+		restored.Key.String() != watch.Key.String() {
+		t.Errorf("Unmarshal: got %+v, want %+v", restored, *watch)
+	}
+
+	invalid := []string{
+		`{"purpose":45,"coin_type":0,"account":0,"master_fingerprint":"00000000","xpub":""}`,
+		`{"purpose":44,"coin_type":0,"account":2147483648,"master_fingerprint":"00000000","xpub":""}`,
+		`{"purpose":44,"coin_type":0,"account":0,"master_fingerprint":"0000","xpub":""}`,
+		`{"purpose":44,"coin_type":0,"account":0,"master_fingerprint":"00000000","xpub":"` + priv + `"}`,
+	}
+	for _, s := range invalid {
+		if err := json.Unmarshal([]byte(s), &restored); err == nil {
+			t.Errorf("Unmarshal(%s): unexpected success", s)
+		}
+	}
+}
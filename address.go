@@ -8,6 +8,7 @@ package btcutil
 import (
 	"encoding/hex"
 	"errors"
+	"fmt"
 
 	"github.com/zeusyf/btcd/btcec"
 	"github.com/zeusyf/btcd/chaincfg"
@@ -49,6 +50,10 @@ var (
 	// than assuming or defaulting to one or the other, this error is
 	// returned and the caller must decide how to decode the address.
 	ErrAddressCollision = errors.New("address collision")
+
+	// ErrInvalidHashLength describes an error where the hash an address
+	// is created from is not the 20 bytes of a RIPEMD160 hash.
+	ErrInvalidHashLength = errors.New("hash must be 20 bytes")
 )
 
 // encodeAddress returns a human-readable payment address given a ripemd160 hash
@@ -116,11 +121,15 @@ func DecodeAddress(addr string, defaultNet *chaincfg.Params) (Address, error) {
 	}
 
 	// Switch on decoded length to determine the type.
-	decoded, netID, err := base58.CheckDecode(addr)
-	if err != nil {
+	decoded, netID, checkErr := base58.CheckDecode(addr)
+	if checkErr != nil {
 		serialized, err := hex.DecodeString(addr)
 		if len(serialized) == 0 || err != nil {
-			return nil, errors.New("decoded address is of unknown format")
+			if checkErr == base58.ErrChecksum {
+				return nil, ErrChecksumMismatch
+			}
+			return nil, fmt.Errorf("%w: decoded address is of "+
+				"unknown format", ErrUnknownAddressType)
 		}
 		netID = serialized[0]
 		decoded = serialized[1:]
@@ -143,11 +152,25 @@ func DecodeAddress(addr string, defaultNet *chaincfg.Params) (Address, error) {
 		case ismultisig:
 			return newAddressMultiSig(hash160, netID)
 		default:
-			return nil, ErrUnknownAddressType
+			// Custom networks may be registered with
+			// RegisterNetParams rather than chaincfg.
+			net, ok := NetParamsForAddrID(netID)
+			if !ok {
+				return nil, ErrUnknownAddressType
+			}
+			switch netID {
+			case net.PubKeyHashAddrID():
+				return newAddressPubKeyHash(hash160, netID)
+			case net.ScriptHashAddrID():
+				return newAddressScriptHashFromHash(hash160, netID)
+			default:
+				return nil, ErrUnknownAddressType
+			}
 		}
 
 	default:
-		return nil, errors.New("decoded address is of unknown size")
+		return nil, fmt.Errorf("%w: decoded address is of unknown "+
+			"size", ErrUnknownAddressType)
 	}
 }
 
@@ -158,8 +181,10 @@ type AddressContract struct {
 	netID byte
 }
 
-// NewAddressContract returns a new AddressPubKeyHash.  pkHash mustbe 20
-// bytes.
+// NewAddressContract returns a new AddressContract.  pkHash must be 20
+// bytes.  The contract version byte of net is used, or 0x88 when net is nil.
+// Use ContractAddresses to derive the addresses of contracts created by a
+// transaction.
 func NewAddressContract(pkHash []byte, net *chaincfg.Params) (*AddressContract, error) {
 	if net == nil {
 		return newAddressContract(pkHash, 0x88)
@@ -175,7 +200,7 @@ func NewAddressContract(pkHash []byte, net *chaincfg.Params) (*AddressContract,
 func newAddressContract(pkHash []byte, netID byte) (*AddressContract, error) {
 	// Check for a valid pubkey hash length.
 	if len(pkHash) != ripemd160.Size {
-		return nil, errors.New("pkHash must be 20 bytes")
+		return nil, fmt.Errorf("pkHash: %w", ErrInvalidHashLength)
 	}
 
 	addr := &AddressContract{netID: netID}
@@ -183,42 +208,47 @@ func newAddressContract(pkHash []byte, netID byte) (*AddressContract, error) {
 	return addr, nil
 }
 
-// EncodeAddress returns the string encoding of a pay-to-pubkey-hash
-// address.  Part of the Address interface.
+// EncodeAddress returns the string encoding of a pay-to-contract address.
+// Part of the Address interface.
 func (a *AddressContract) EncodeAddress() string {
 	return encodeAddress(a.hash[:], a.netID)
 }
 
 // ScriptAddress returns the bytes to be included in a txout script to pay
-// to a pubkey hash.  Part of the Address interface.
+// to a contract.  Part of the Address interface.
 func (a *AddressContract) ScriptAddress() []byte {
 	return a.hash[:]
 }
 
+// ScriptNetAddress returns the contract version byte followed by the
+// contract hash, which is how contract addresses appear in public key
+// scripts.  Part of the Address interface.
 func (a *AddressContract) ScriptNetAddress() []byte {
 	return append([]byte{a.netID}, a.hash[:]...)
 }
 
+// Version returns the contract version byte of the address.  Part of the
+// Address interface.
 func (a *AddressContract) Version() byte {
 	return a.netID
 }
 
-// IsForNet returns whether or not the pay-to-pubkey-hash address is associated
+// IsForNet returns whether or not the pay-to-contract address is associated
 // with the passed bitcoin network.
 func (a *AddressContract) IsForNet(net *chaincfg.Params) bool {
 	return a.netID == net.ContractAddrID
 }
 
-// String returns a human-readable string for the pay-to-pubkey-hash address.
+// String returns a human-readable string for the pay-to-contract address.
 // This is equivalent to calling EncodeAddress, but is provided so the type can
 // be used as a fmt.Stringer.
 func (a *AddressContract) String() string {
 	return a.EncodeAddress()
 }
 
-// Hash160 returns the underlying array of the pubkey hash.  This can be useful
-// when an array is more appropiate than a slice (for example, when used as map
-// keys).
+// Hash160 returns the underlying array of the contract hash.  This can be
+// useful when an array is more appropiate than a slice (for example, when used
+// as map keys).
 func (a *AddressContract) Hash160() *[ripemd160.Size]byte {
 	return &a.hash
 }
@@ -234,7 +264,7 @@ type AddressPubKeyHash struct {
 // bytes.
 func NewAddressPubKeyHash(pkHash []byte, net *chaincfg.Params) (*AddressPubKeyHash, error) {
 	if net == nil {
-		return nil, errors.New("Missing chaincfg.Params")
+		return nil, ErrNoNet
 	}
 	return newAddressPubKeyHash(pkHash, net.PubKeyHashAddrID)
 }
@@ -247,7 +277,7 @@ func NewAddressPubKeyHash(pkHash []byte, net *chaincfg.Params) (*AddressPubKeyHa
 func newAddressPubKeyHash(pkHash []byte, netID byte) (*AddressPubKeyHash, error) {
 	// Check for a valid pubkey hash length.
 	if len(pkHash) != ripemd160.Size {
-		return nil, errors.New("pkHash must be 20 bytes")
+		return nil, fmt.Errorf("pkHash: %w", ErrInvalidHashLength)
 	}
 
 	addr := &AddressPubKeyHash{netID: netID}
@@ -322,7 +352,7 @@ func NewAddressScriptHashFromHash(scriptHash []byte, net *chaincfg.Params) (*Add
 func newAddressScriptHashFromHash(scriptHash []byte, netID byte) (*AddressScriptHash, error) {
 	// Check for a valid script hash length.
 	if len(scriptHash) != ripemd160.Size {
-		return nil, errors.New("scriptHash must be 20 bytes")
+		return nil, fmt.Errorf("scriptHash: %w", ErrInvalidHashLength)
 	}
 
 	addr := &AddressScriptHash{netID: netID}
@@ -422,6 +452,9 @@ func NewAddressPubKey(serializedPubKey []byte, net *chaincfg.Params) (*AddressPu
 	}, nil
 }
 
+// NewAddressPubKeyPubKey returns a new AddressPubKey for an already parsed
+// public key.  The address uses the uncompressed format; use SetFormat to
+// select another.
 func NewAddressPubKeyPubKey(pubKey btcec.PublicKey, net *chaincfg.Params) (*AddressPubKey, error) {
 	key := pubKey
 	pkFormat := PKFUncompressed
@@ -469,10 +502,14 @@ func (a *AddressPubKey) ScriptAddress() []byte {
 	return a.serialize()
 }
 
+// ScriptNetAddress returns the same bytes as ScriptAddress since a serialized
+// public key carries no network identifier.  Part of the Address interface.
 func (a *AddressPubKey) ScriptNetAddress() []byte {
 	return a.ScriptAddress()
 }
 
+// Version returns the netID of the pay-to-pubkey-hash address the public key
+// converts to.  Part of the Address interface.
 func (a *AddressPubKey) Version() byte {
 	return a.pubKeyHashID
 }
@@ -529,7 +566,7 @@ type AddressMultiSig struct {
 // bytes.
 func NewAddressMultiSig(pkHash []byte, net *chaincfg.Params) (*AddressMultiSig, error) {
 	if net == nil {
-		return nil, errors.New("Missing chaincfg.Params")
+		return nil, ErrNoNet
 	}
 	return newAddressMultiSig(pkHash, net.MultiSigAddrID)
 }
@@ -542,7 +579,7 @@ func NewAddressMultiSig(pkHash []byte, net *chaincfg.Params) (*AddressMultiSig,
 func newAddressMultiSig(pkHash []byte, netID byte) (*AddressMultiSig, error) {
 	// Check for a valid pubkey hash length.
 	if len(pkHash) != ripemd160.Size {
-		return nil, errors.New("pkHash must be 20 bytes")
+		return nil, fmt.Errorf("pkHash: %w", ErrInvalidHashLength)
 	}
 
 	addr := &AddressMultiSig{netID: netID}
@@ -7,6 +7,7 @@ package btcutil_test
 import (
 	"bytes"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"reflect"
 	"strings"
@@ -14,6 +15,7 @@ import (
 
 	"github.com/zeusyf/btcd/chaincfg"
 	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/base58"
 	"golang.org/x/crypto/ripemd160"
 )
 
@@ -803,3 +805,108 @@ func TestAddresses(t *testing.T) {
 		}
 	}
 }
+
+// TestAddressErrors ensures the errors of malformed addresses wrap the
+// exported errors describing them.
+func TestAddressErrors(t *testing.T) {
+	net := &chaincfg.MainNetParams
+	valid := "13CG6SJ3yHUXo4Cr2RY4THLLJrNFuG3gUg"
+	badChecksum := valid[:len(valid)-1] + "h"
+	tests := []struct {
+		addr string
+		err  error
+	}{
+		{badChecksum, btcutil.ErrChecksumMismatch},
+		{"not an address", btcutil.ErrUnknownAddressType},
+		{base58.CheckEncode(make([]byte, 10), net.PubKeyHashAddrID),
+			btcutil.ErrUnknownAddressType},
+	}
+	for _, test := range tests {
+		_, err := btcutil.DecodeAddress(test.addr, net)
+		if !errors.Is(err, test.err) {
+			t.Errorf("DecodeAddress(%q): got error %v, want %v",
+				test.addr, err, test.err)
+		}
+	}
+
+	_, err := btcutil.NewAddressPubKeyHash(make([]byte, 19), net)
+	if !errors.Is(err, btcutil.ErrInvalidHashLength) {
+		t.Errorf("NewAddressPubKeyHash: got error %v, want %v", err,
+			btcutil.ErrInvalidHashLength)
+	}
+	_, err = btcutil.NewAddressScriptHashFromHash(make([]byte, 21), net)
+	if !errors.Is(err, btcutil.ErrInvalidHashLength) {
+		t.Errorf("NewAddressScriptHashFromHash: got error %v, want %v",
+			err, btcutil.ErrInvalidHashLength)
+	}
+	_, err = btcutil.NewAddressPubKeyHash(make([]byte, 20), nil)
+	if err != btcutil.ErrNoNet {
+		t.Errorf("NewAddressPubKeyHash: got error %v, want %v", err,
+			btcutil.ErrNoNet)
+	}
+}
+
+// TestAddressPubKeyFormats ensures switching the format of a pay-to-pubkey
+// address changes its serialization and the pay-to-pubkey-hash address it
+// converts to.
+func TestAddressPubKeyFormats(t *testing.T) {
+	compressed, _ := hex.DecodeString("02192d74d0cb94344c9569c2e77901573d8d" +
+		"7903c3ebec3a957724895dca52c6b4")
+	addr, err := btcutil.NewAddressPubKey(compressed, &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("NewAddressPubKey: unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		format  btcutil.PubKeyFormat
+		str     string
+		encoded string
+	}{
+		{
+			format:  btcutil.PKFCompressed,
+			str:     "02192d74d0cb94344c9569c2e77901573d8d7903c3ebec3a957724895dca52c6b4",
+			encoded: "13CG6SJ3yHUXo4Cr2RY4THLLJrNFuG3gUg",
+		},
+		{
+			format: btcutil.PKFHybrid,
+			str: "06192d74d0cb94344c9569c2e77901573d8d7903c3ebec3a957724895dca52c6b4" +
+				"0d45264838c0bd96852662ce6a847b197376830160c6d2eb5e6a4c44d33f453e",
+			encoded: "1Ja5rs7XBZnK88EuLVcFqYGMEbBitzchmX",
+		},
+		{
+			format: btcutil.PKFUncompressed,
+			str: "04192d74d0cb94344c9569c2e77901573d8d7903c3ebec3a957724895dca52c6b4" +
+				"0d45264838c0bd96852662ce6a847b197376830160c6d2eb5e6a4c44d33f453e",
+		},
+	}
+
+	for _, test := range tests {
+		addr.SetFormat(test.format)
+		if got := addr.Format(); got != test.format {
+			t.Errorf("Format: got %v, want %v", got, test.format)
+		}
+		if got := addr.String(); got != test.str {
+			t.Errorf("String(%v): got %s, want %s", test.format, got,
+				test.str)
+		}
+		if got := hex.EncodeToString(addr.ScriptAddress()); got != test.str {
+			t.Errorf("ScriptAddress(%v): got %s, want %s", test.format,
+				got, test.str)
+		}
+
+		p2pkh := addr.AddressPubKeyHash()
+		if got := p2pkh.EncodeAddress(); got != addr.EncodeAddress() {
+			t.Errorf("AddressPubKeyHash(%v): got %s, want %s",
+				test.format, got, addr.EncodeAddress())
+		}
+		if test.encoded != "" && p2pkh.EncodeAddress() != test.encoded {
+			t.Errorf("AddressPubKeyHash(%v): got %s, want %s",
+				test.format, p2pkh.EncodeAddress(), test.encoded)
+		}
+		want := btcutil.Hash160(addr.ScriptAddress())
+		if !bytes.Equal(p2pkh.ScriptAddress(), want) {
+			t.Errorf("AddressPubKeyHash(%v): hash does not commit to "+
+				"serialized public key", test.format)
+		}
+	}
+}
@@ -0,0 +1,185 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package btcutil
+
+import "fmt"
+
+// ScriptType identifies the kind of public key script an address pays to.
+type ScriptType byte
+
+// These constants are the script types returned by AddressInfo.ScriptType.
+const (
+	// ScriptTypeUnknown is the script type of addresses which do not
+	// belong to any of the other types.
+	ScriptTypeUnknown ScriptType = iota
+
+	// ScriptTypePubKey is a pay-to-pubkey script.
+	ScriptTypePubKey
+
+	// ScriptTypePubKeyHash is a pay-to-pubkey-hash script.
+	ScriptTypePubKeyHash
+
+	// ScriptTypeScriptHash is a pay-to-script-hash script.
+	ScriptTypeScriptHash
+
+	// ScriptTypeMultiSig is a script paying to the hash of a set of public
+	// keys, of which a number must sign to spend it.
+	ScriptTypeMultiSig
+
+	// ScriptTypeContract is a script paying to a contract.
+	ScriptTypeContract
+)
+
+// Map of script types back to their constant names for pretty printing.
+var scriptTypeStrings = map[ScriptType]string{
+	ScriptTypeUnknown:    "ScriptTypeUnknown",
+	ScriptTypePubKey:     "ScriptTypePubKey",
+	ScriptTypePubKeyHash: "ScriptTypePubKeyHash",
+	ScriptTypeScriptHash: "ScriptTypeScriptHash",
+	ScriptTypeMultiSig:   "ScriptTypeMultiSig",
+	ScriptTypeContract:   "ScriptTypeContract",
+}
+
+// String returns the ScriptType as a human-readable name.
+func (t ScriptType) String() string {
+	if s := scriptTypeStrings[t]; s != "" {
+		return s
+	}
+	return fmt.Sprintf("Unknown ScriptType (%d)", byte(t))
+}
+
+// AddressInfo is implemented by addresses which describe the script they pay
+// to and the cost of spending it.  All the address types of this package
+// implement it, but it is not part of the Address interface, so callers
+// type-assert for it and handle addresses of other packages lacking it.
+type AddressInfo interface {
+	// ScriptType returns the kind of public key script the address pays
+	// to.
+	ScriptType() ScriptType
+
+	// WitnessVersion returns the witness version of the address and true
+	// when the address pays to a witness program, or false otherwise.
+	WitnessVersion() (byte, bool)
+
+	// EstimatedInputVsize returns the estimated virtual size in bytes of
+	// an input spending an output paying to the address, or zero when it
+	// can not be known from the address alone.
+	EstimatedInputVsize() int
+}
+
+// AddressScriptType returns the script type of addr, or ScriptTypeUnknown
+// when addr does not implement AddressInfo.
+func AddressScriptType(addr Address) ScriptType {
+	if info, ok := addr.(AddressInfo); ok {
+		return info.ScriptType()
+	}
+	return ScriptTypeUnknown
+}
+
+// Estimated virtual sizes of inputs spending to the address types.  They
+// assume signatures of signing.HighRSigLen bytes, the worst case, so fees
+// calculated from them are never too low.
+const (
+	// p2pkInputVSize is the size of an input spending a pay-to-pubkey
+	// script: the outpoint, a one byte script length, the signature
+	// push, and the sequence number.
+	p2pkInputVSize = 36 + 1 + (1 + 72) + 4
+
+	// p2pkhInputVSize is the size of an input spending a
+	// pay-to-pubkey-hash script with a compressed public key: the
+	// outpoint, a one byte script length, the signature and public key
+	// pushes, and the sequence number.
+	p2pkhInputVSize = 36 + 1 + (1 + 72) + (1 + 33) + 4
+)
+
+// ScriptType returns ScriptTypePubKeyHash.  Part of the AddressInfo interface.
+func (a *AddressPubKeyHash) ScriptType() ScriptType {
+	return ScriptTypePubKeyHash
+}
+
+// WitnessVersion returns false since pay-to-pubkey-hash addresses are not
+// witness programs.  Part of the AddressInfo interface.
+func (a *AddressPubKeyHash) WitnessVersion() (byte, bool) {
+	return 0, false
+}
+
+// EstimatedInputVsize returns the estimated virtual size of an input spending
+// to the address, assuming it is spent with a compressed public key.  Part of
+// the AddressInfo interface.
+func (a *AddressPubKeyHash) EstimatedInputVsize() int {
+	return p2pkhInputVSize
+}
+
+// ScriptType returns ScriptTypeScriptHash.  Part of the AddressInfo interface.
+func (a *AddressScriptHash) ScriptType() ScriptType {
+	return ScriptTypeScriptHash
+}
+
+// WitnessVersion returns false since pay-to-script-hash addresses are not
+// witness programs.  Part of the AddressInfo interface.
+func (a *AddressScriptHash) WitnessVersion() (byte, bool) {
+	return 0, false
+}
+
+// EstimatedInputVsize returns zero since the size of an input spending to
+// the address depends on the redeem script, which the address does not
+// reveal.  Part of the AddressInfo interface.
+func (a *AddressScriptHash) EstimatedInputVsize() int {
+	return 0
+}
+
+// ScriptType returns ScriptTypePubKey.  Part of the AddressInfo interface.
+func (a *AddressPubKey) ScriptType() ScriptType {
+	return ScriptTypePubKey
+}
+
+// WitnessVersion returns false since pay-to-pubkey addresses are not witness
+// programs.  Part of the AddressInfo interface.
+func (a *AddressPubKey) WitnessVersion() (byte, bool) {
+	return 0, false
+}
+
+// EstimatedInputVsize returns the estimated virtual size of an input spending
+// to the address.  The public key format does not matter since the public key
+// is not part of the input.  Part of the AddressInfo interface.
+func (a *AddressPubKey) EstimatedInputVsize() int {
+	return p2pkInputVSize
+}
+
+// ScriptType returns ScriptTypeMultiSig.  Part of the AddressInfo interface.
+func (a *AddressMultiSig) ScriptType() ScriptType {
+	return ScriptTypeMultiSig
+}
+
+// WitnessVersion returns false since multi-signature addresses are not
+// witness programs.  Part of the AddressInfo interface.
+func (a *AddressMultiSig) WitnessVersion() (byte, bool) {
+	return 0, false
+}
+
+// EstimatedInputVsize returns zero since the size of an input spending to
+// the address depends on the number of keys and signatures, which the
+// address does not reveal.  Part of the AddressInfo interface.
+func (a *AddressMultiSig) EstimatedInputVsize() int {
+	return 0
+}
+
+// ScriptType returns ScriptTypeContract.  Part of the AddressInfo interface.
+func (a *AddressContract) ScriptType() ScriptType {
+	return ScriptTypeContract
+}
+
+// WitnessVersion returns false since contract addresses are not witness
+// programs.  Part of the AddressInfo interface.
+func (a *AddressContract) WitnessVersion() (byte, bool) {
+	return 0, false
+}
+
+// EstimatedInputVsize returns zero since outputs paying to a contract are
+// spent by the contract itself rather than by a signed input.  Part of the
+// AddressInfo interface.
+func (a *AddressContract) EstimatedInputVsize() int {
+	return 0
+}
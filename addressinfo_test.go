@@ -0,0 +1,108 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package btcutil_test
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil"
+)
+
+// TestAddressInfo ensures every address type reports its script type,
+// witness version, and input size estimate through the AddressInfo interface.
+func TestAddressInfo(t *testing.T) {
+	net := &chaincfg.MainNetParams
+	hash := make([]byte, 20)
+	pubKey, _ := hex.DecodeString("0279be667ef9dcbbac55a06295ce870b07029b" +
+		"fcdb2dce28d959f2815b16f81798")
+
+	p2pkh, err := btcutil.NewAddressPubKeyHash(hash, net)
+	if err != nil {
+		t.Fatalf("NewAddressPubKeyHash: unexpected error: %v", err)
+	}
+	p2sh, err := btcutil.NewAddressScriptHashFromHash(hash, net)
+	if err != nil {
+		t.Fatalf("NewAddressScriptHashFromHash: unexpected error: %v", err)
+	}
+	p2pk, err := btcutil.NewAddressPubKey(pubKey, net)
+	if err != nil {
+		t.Fatalf("NewAddressPubKey: unexpected error: %v", err)
+	}
+	multiSig, err := btcutil.NewAddressMultiSig(hash, net)
+	if err != nil {
+		t.Fatalf("NewAddressMultiSig: unexpected error: %v", err)
+	}
+	contract, err := btcutil.NewAddressContract(hash, net)
+	if err != nil {
+		t.Fatalf("NewAddressContract: unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		addr       btcutil.Address
+		scriptType btcutil.ScriptType
+		inputVsize int
+	}{
+		{p2pkh, btcutil.ScriptTypePubKeyHash, 148},
+		{p2sh, btcutil.ScriptTypeScriptHash, 0},
+		{p2pk, btcutil.ScriptTypePubKey, 114},
+		{multiSig, btcutil.ScriptTypeMultiSig, 0},
+		{contract, btcutil.ScriptTypeContract, 0},
+	}
+	for _, test := range tests {
+		name := test.scriptType.String()
+		info, ok := test.addr.(btcutil.AddressInfo)
+		if !ok {
+			t.Errorf("%s: does not implement AddressInfo", name)
+			continue
+		}
+		if got := info.ScriptType(); got != test.scriptType {
+			t.Errorf("%s: ScriptType: got %v, want %v", name, got,
+				test.scriptType)
+		}
+		if _, ok := info.WitnessVersion(); ok {
+			t.Errorf("%s: WitnessVersion: unexpected witness program",
+				name)
+		}
+		if got := info.EstimatedInputVsize(); got != test.inputVsize {
+			t.Errorf("%s: EstimatedInputVsize: got %d, want %d", name,
+				got, test.inputVsize)
+		}
+		if !test.addr.IsForNet(net) {
+			t.Errorf("%s: IsForNet: address is not for %s", name,
+				net.Name)
+		}
+	}
+
+	if s := btcutil.ScriptType(200).String(); s != "Unknown ScriptType (200)" {
+		t.Errorf("String: got %q for unknown script type", s)
+	}
+}
+
+// foreignAddress is an Address of another package, which does not implement
+// AddressInfo.
+type foreignAddress struct {
+	btcutil.Address
+}
+
+// TestAddressScriptType ensures the script type of addresses not implementing
+// AddressInfo is unknown.
+func TestAddressScriptType(t *testing.T) {
+	p2pkh, err := btcutil.NewAddressPubKeyHash(make([]byte, 20),
+		&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("NewAddressPubKeyHash: unexpected error: %v", err)
+	}
+	if got := btcutil.AddressScriptType(p2pkh); got != btcutil.ScriptTypePubKeyHash {
+		t.Errorf("AddressScriptType: got %v, want %v", got,
+			btcutil.ScriptTypePubKeyHash)
+	}
+	foreign := foreignAddress{p2pkh}
+	if got := btcutil.AddressScriptType(foreign); got != btcutil.ScriptTypeUnknown {
+		t.Errorf("AddressScriptType: got %v, want %v", got,
+			btcutil.ScriptTypeUnknown)
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package btcutil
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"strings"
+)
+
+// AddressTokenLen is the number of symbols in an address token.  Each symbol
+// encodes 6 bits of the commitment, so a token commits to 24 bits.
+const AddressTokenLen = 4
+
+// AddressTokenStyle selects the symbols an address token is rendered with.
+type AddressTokenStyle uint8
+
+const (
+	// TokenEmoji renders tokens as emoji, which are quick to compare at a
+	// glance.
+	TokenEmoji AddressTokenStyle = iota
+
+	// TokenWords renders tokens as short English words, which can be read
+	// aloud and are displayed by every terminal.
+	TokenWords
+)
+
+// String returns the AddressTokenStyle in human-readable form.
+func (s AddressTokenStyle) String() string {
+	switch s {
+	case TokenEmoji:
+		return "TokenEmoji"
+	case TokenWords:
+		return "TokenWords"
+	}
+	return fmt.Sprintf("Unknown AddressTokenStyle (%d)", uint8(s))
+}
+
+// addressTokenDomain separates address token commitments from other uses of
+// the same key.
+var addressTokenDomain = []byte("btcutil address token")
+
+// tokenEmoji and tokenWords are the 64 symbols of each token style.  The
+// emoji are pictures of distinct, familiar things and the words are short and
+// dissimilar, so no two symbols are easily mistaken for each other.
+var (
+	tokenEmoji = [64]string{
+		"🐶", "🐱", "🐭", "🐰", "🦊", "🐻", "🐼", "🐨",
+		"🐯", "🦁", "🐮", "🐷", "🐸", "🐵", "🐔", "🐧",
+		"🐦", "🦆", "🦉", "🐴", "🦄", "🐝", "🐛", "🦋",
+		"🐌", "🐞", "🐢", "🐍", "🐙", "🦀", "🐠", "🐳",
+		"🌵", "🌲", "🌻", "🍄", "🌙", "⭐", "🔥", "🌈",
+		"🍎", "🍌", "🍇", "🍓", "🍒", "🍍", "🥕", "🌽",
+		"🍕", "🍩", "🎂", "🍪", "⚽", "🏀", "🎸", "🎈",
+		"🔑", "🔔", "💡", "📚", "✏️", "🚗", "🚀", "⚓",
+	}
+	tokenWords = [64]string{
+		"acorn", "anchor", "apple", "arrow", "badge", "banjo", "basket", "beach",
+		"bell", "bike", "bison", "boat", "bread", "brick", "cactus", "camel",
+		"candle", "castle", "cherry", "cloud", "comet", "crown", "daisy", "dragon",
+		"drum", "eagle", "falcon", "feather", "flute", "forest", "ghost", "glove",
+		"grape", "hammer", "harbor", "honey", "igloo", "island", "jacket", "jungle",
+		"kettle", "koala", "ladder", "lemon", "lizard", "magnet", "maple", "meadow",
+		"mitten", "moose", "nickel", "ocean", "orchid", "otter", "panda", "pepper",
+		"piano", "pirate", "quilt", "rabbit", "rocket", "saddle", "tiger", "violin",
+	}
+)
+
+// addressCommitment returns the 24 bit commitment to the encoded address,
+// keyed with key when it is not empty.
+func addressCommitment(addr Address, key []byte) uint32 {
+	var sum []byte
+	if len(key) > 0 {
+		mac := hmac.New(sha256.New, key)
+		mac.Write(addressTokenDomain)
+		mac.Write([]byte(addr.EncodeAddress()))
+		sum = mac.Sum(nil)
+	} else {
+		h := sha256.New()
+		h.Write(addressTokenDomain)
+		h.Write([]byte(addr.EncodeAddress()))
+		sum = h.Sum(nil)
+	}
+	return uint32(sum[0])<<16 | uint32(sum[1])<<8 | uint32(sum[2])
+}
+
+// AddressToken returns a short, human checkable commitment to the address,
+// rendered in the passed style.  Emoji tokens are the symbols concatenated and
+// word tokens the words separated by hyphens.
+//
+// User interfaces display the token when an address is copied and again when
+// one is pasted, so the user notices when malware swapped the clipboard
+// contents for a lookalike address in between.  An unkeyed token only costs
+// an attacker about 2^24 address generations to match, so applications should
+// pass a key which is random per installation or per session and kept out of
+// reach of other processes.  A nil key is only meant for tokens which must be
+// reproducible elsewhere, such as on a hardware wallet.
+func AddressToken(addr Address, key []byte, style AddressTokenStyle) string {
+	c := addressCommitment(addr, key)
+	symbols := make([]string, AddressTokenLen)
+	for i := range symbols {
+		index := c >> (6 * uint(AddressTokenLen-1-i)) & 0x3f
+		switch style {
+		case TokenWords:
+			symbols[i] = tokenWords[index]
+		default:
+			symbols[i] = tokenEmoji[index]
+		}
+	}
+	if style == TokenWords {
+		return strings.Join(symbols, "-")
+	}
+	return strings.Join(symbols, "")
+}
+
+// VerifyAddressToken returns whether the token is the token of the address
+// for the passed key, in either style.  Word tokens are compared ignoring
+// case and accept spaces as well as hyphens between words.
+func VerifyAddressToken(addr Address, key []byte, token string) bool {
+	token = strings.TrimSpace(token)
+	var want string
+	if strings.IndexFunc(token, isASCIILetter) == 0 {
+		token = strings.ToLower(strings.Join(strings.FieldsFunc(token,
+			isTokenSeparator), "-"))
+		want = AddressToken(addr, key, TokenWords)
+	} else {
+		want = AddressToken(addr, key, TokenEmoji)
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(want)) == 1
+}
+
+// isASCIILetter returns whether r is an ASCII letter.
+func isASCIILetter(r rune) bool {
+	return r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z'
+}
+
+// isTokenSeparator returns whether r separates the words of a word token.
+func isTokenSeparator(r rune) bool {
+	return r == '-' || r == ' ' || r == '\t'
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package btcutil_test
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil"
+)
+
+// TestAddressToken ensures address tokens are deterministic, depend on the
+// address and key, and verify in both styles.
+func TestAddressToken(t *testing.T) {
+	addr1, err := btcutil.NewAddressPubKeyHash(make([]byte, 20),
+		&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("NewAddressPubKeyHash: unexpected error: %v", err)
+	}
+	hash := make([]byte, 20)
+	hash[19] = 1
+	addr2, err := btcutil.NewAddressPubKeyHash(hash, &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("NewAddressPubKeyHash: unexpected error: %v", err)
+	}
+	key := []byte("per session secret")
+
+	words := btcutil.AddressToken(addr1, key, btcutil.TokenWords)
+	if n := len(strings.Split(words, "-")); n != btcutil.AddressTokenLen {
+		t.Errorf("word token %q has %d words, want %d", words, n,
+			btcutil.AddressTokenLen)
+	}
+	emoji := btcutil.AddressToken(addr1, key, btcutil.TokenEmoji)
+	if n := utf8.RuneCountInString(emoji); n < btcutil.AddressTokenLen {
+		t.Errorf("emoji token %q has %d runes, want at least %d", emoji,
+			n, btcutil.AddressTokenLen)
+	}
+	if again := btcutil.AddressToken(addr1, key, btcutil.TokenWords); again != words {
+		t.Errorf("token is not deterministic: %q != %q", again, words)
+	}
+
+	valid := []string{
+		words,
+		emoji,
+		" " + strings.ToUpper(words) + "\n",
+		strings.ReplaceAll(words, "-", " "),
+	}
+	for _, token := range valid {
+		if !btcutil.VerifyAddressToken(addr1, key, token) {
+			t.Errorf("VerifyAddressToken(%q): token rejected", token)
+		}
+	}
+
+	// A token must not verify for another address or another key.  Tokens
+	// of distinct inputs only collide with probability 2^-24.
+	if btcutil.VerifyAddressToken(addr2, key, words) {
+		t.Error("VerifyAddressToken: token verified for another address")
+	}
+	if btcutil.VerifyAddressToken(addr1, []byte("other secret"), words) {
+		t.Error("VerifyAddressToken: token verified for another key")
+	}
+	if btcutil.VerifyAddressToken(addr1, nil, words) {
+		t.Error("VerifyAddressToken: keyed token verified without key")
+	}
+	if btcutil.VerifyAddressToken(addr1, key, "") {
+		t.Error("VerifyAddressToken: empty token verified")
+	}
+}
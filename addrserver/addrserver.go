@@ -0,0 +1,169 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package addrserver hands out fresh receive addresses derived from an
+// account extended public key, as merchant backends do for every invoice.
+//
+// The next index of the address branch is kept in a pluggable Store, and is
+// advanced and persisted before an address is handed out, so addresses are
+// never reused, even by servers which crash or run concurrently against the
+// same store.  No private key is ever needed.
+package addrserver
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/hdkeychain"
+)
+
+// ExternalBranch is the branch of an account receive addresses are derived
+// from, as opposed to the change branch.
+const ExternalBranch = 0
+
+var (
+	// ErrPrivateKey describes an error where a server is created from an
+	// extended private key rather than the public key of the account.
+	ErrPrivateKey = errors.New("account key is private")
+
+	// ErrExhausted describes an error where a branch has no non-hardened
+	// index left to reserve.
+	ErrExhausted = errors.New("address indexes exhausted")
+)
+
+// Option is a functional option of New.
+type Option func(*Server)
+
+// WithBranch sets the branch of the account addresses are derived from.  It
+// defaults to ExternalBranch.
+func WithBranch(branch uint32) Option {
+	return func(s *Server) {
+		s.branchIndex = branch
+	}
+}
+
+// WithBatchSize sets the number of indexes reserved from the store at a time.
+// Larger batches write to the store less often, at the cost of skipping the
+// indexes of a batch not handed out when the server stops.  Non-positive
+// sizes reserve one index at a time, which is the default.
+func WithBatchSize(n int) Option {
+	return func(s *Server) {
+		if n > 0 {
+			s.batchSize = uint32(n)
+		}
+	}
+}
+
+// WithStoreKey sets the key the next index of the branch is stored under.  It
+// defaults to the serialized extended public key of the branch.
+func WithStoreKey(key string) Option {
+	return func(s *Server) {
+		s.key = key
+	}
+}
+
+// Address is an address handed out by a server along with the index of its
+// key in the branch.
+type Address struct {
+	Index   uint32
+	Address *btcutil.AddressPubKeyHash
+}
+
+// Server hands out receive addresses of an account.  It is safe for
+// concurrent use.
+type Server struct {
+	branchIndex uint32
+	batchSize   uint32
+	key         string
+	branch      *hdkeychain.ExtendedKey
+	store       Store
+	net         *chaincfg.Params
+
+	// mtx protects the range of indexes reserved but not handed out yet,
+	// from next up to but excluding end.
+	mtx  sync.Mutex
+	next uint32
+	end  uint32
+}
+
+// New returns a server handing out addresses of net derived from account, an
+// extended public key such as that of a BIP0044 account, keeping the next
+// index in store.  ErrPrivateKey is returned for an extended private key.
+func New(account *hdkeychain.ExtendedKey, store Store, net *chaincfg.Params,
+	opts ...Option) (*Server, error) {
+	if account.IsPrivate() {
+		return nil, ErrPrivateKey
+	}
+	s := &Server{
+		branchIndex: ExternalBranch,
+		batchSize:   1,
+		store:       store,
+		net:         net,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	branch, err := account.Child(s.branchIndex)
+	if err != nil {
+		return nil, err
+	}
+	s.branch = branch
+	if s.key == "" {
+		s.key = branch.String()
+	}
+	return s, nil
+}
+
+// reserve returns the next index reserved for the server, reserving a batch
+// from the store when none is left.
+func (s *Server) reserve() (uint32, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if s.next == s.end {
+		first, err := s.store.Reserve(s.key, s.batchSize)
+		if err != nil {
+			return 0, err
+		}
+		s.next, s.end = first, first+s.batchSize
+	}
+	index := s.next
+	s.next++
+	return index, nil
+}
+
+// NextAddress returns a fresh address, never handed out before by any server
+// of the branch sharing the store.  Indexes whose key is invalid are
+// skipped.
+func (s *Server) NextAddress() (*Address, error) {
+	for {
+		index, err := s.reserve()
+		if err != nil {
+			return nil, err
+		}
+		addr, err := s.AddressAt(index)
+		if err == hdkeychain.ErrInvalidChild {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		return addr, nil
+	}
+}
+
+// AddressAt returns the address at index of the branch, without reserving
+// it, such as to watch addresses already handed out.
+func (s *Server) AddressAt(index uint32) (*Address, error) {
+	child, err := s.branch.Child(index)
+	if err != nil {
+		return nil, err
+	}
+	addr, err := child.Address(s.net)
+	if err != nil {
+		return nil, err
+	}
+	return &Address{Index: index, Address: addr}, nil
+}
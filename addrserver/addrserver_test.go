@@ -0,0 +1,151 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package addrserver_test
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil/addrserver"
+	"github.com/zeusyf/btcutil/hdkeychain"
+)
+
+// testAccount returns the private and public extended keys of a test
+// account.
+func testAccount(t *testing.T) (*hdkeychain.ExtendedKey, *hdkeychain.ExtendedKey) {
+	t.Helper()
+	seed := bytes.Repeat([]byte{0x42}, hdkeychain.RecommendedSeedLen)
+	master, err := hdkeychain.NewMaster(seed, &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("NewMaster: unexpected error: %v", err)
+	}
+	priv, err := master.Child(hdkeychain.HardenedKeyStart)
+	if err != nil {
+		t.Fatalf("Child: unexpected error: %v", err)
+	}
+	pub, err := priv.Neuter()
+	if err != nil {
+		t.Fatalf("Neuter: unexpected error: %v", err)
+	}
+	return priv, pub
+}
+
+// TestNextAddress ensures concurrent callers never get the same address and
+// addresses are those of their index in the branch.
+func TestNextAddress(t *testing.T) {
+	priv, pub := testAccount(t)
+	net := &chaincfg.MainNetParams
+	store := addrserver.NewMemoryStore()
+	s, err := addrserver.New(pub, store, net, addrserver.WithBatchSize(5))
+	if err != nil {
+		t.Fatalf("New: unexpected error: %v", err)
+	}
+
+	const workers, perWorker = 8, 25
+	var (
+		mtx  sync.Mutex
+		seen = make(map[uint32]string)
+		wg   sync.WaitGroup
+	)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				addr, err := s.NextAddress()
+				if err != nil {
+					t.Errorf("NextAddress: unexpected error: %v", err)
+					return
+				}
+				mtx.Lock()
+				if _, ok := seen[addr.Index]; ok {
+					t.Errorf("NextAddress: index %d handed out twice",
+						addr.Index)
+				}
+				seen[addr.Index] = addr.Address.EncodeAddress()
+				mtx.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if len(seen) != workers*perWorker {
+		t.Fatalf("NextAddress: got %d addresses, want %d", len(seen),
+			workers*perWorker)
+	}
+
+	// The addresses are those of the private branch, and the indexes
+	// handed out are the first ones since batches are used up.
+	branch, err := priv.Child(addrserver.ExternalBranch)
+	if err != nil {
+		t.Fatalf("Child: unexpected error: %v", err)
+	}
+	for index, encoded := range seen {
+		if index >= workers*perWorker {
+			t.Errorf("NextAddress: unexpected index %d", index)
+		}
+		child, err := branch.Child(index)
+		if err != nil {
+			t.Fatalf("Child: unexpected error: %v", err)
+		}
+		want, err := child.Address(net)
+		if err != nil {
+			t.Fatalf("Address: unexpected error: %v", err)
+		}
+		if encoded != want.EncodeAddress() {
+			t.Errorf("NextAddress: index %d got %s, want %s", index,
+				encoded, want.EncodeAddress())
+		}
+	}
+
+	// A server restarted on the same store continues after the indexes
+	// already reserved.
+	s, err = addrserver.New(pub, store, net)
+	if err != nil {
+		t.Fatalf("New: unexpected error: %v", err)
+	}
+	addr, err := s.NextAddress()
+	if err != nil {
+		t.Fatalf("NextAddress: unexpected error: %v", err)
+	}
+	if addr.Index != workers*perWorker {
+		t.Errorf("NextAddress: got index %d, want %d", addr.Index,
+			workers*perWorker)
+	}
+	at, err := s.AddressAt(addr.Index)
+	if err != nil {
+		t.Fatalf("AddressAt: unexpected error: %v", err)
+	}
+	if at.Address.EncodeAddress() != addr.Address.EncodeAddress() {
+		t.Errorf("AddressAt: got %s, want %s", at.Address.EncodeAddress(),
+			addr.Address.EncodeAddress())
+	}
+
+	// Other branches are stored separately.
+	change, err := addrserver.New(pub, store, net, addrserver.WithBranch(1))
+	if err != nil {
+		t.Fatalf("New: unexpected error: %v", err)
+	}
+	if addr, err := change.NextAddress(); err != nil || addr.Index != 0 {
+		t.Errorf("NextAddress: got %v (%v), want index 0", addr, err)
+	}
+}
+
+// TestNewErrors ensures servers are only created from public keys.
+func TestNewErrors(t *testing.T) {
+	priv, pub := testAccount(t)
+	net := &chaincfg.MainNetParams
+	store := addrserver.NewMemoryStore()
+	if _, err := addrserver.New(priv, store, net); err != addrserver.ErrPrivateKey {
+		t.Errorf("New: got error %v, want %v", err, addrserver.ErrPrivateKey)
+	}
+	_, err := addrserver.New(pub, store, net,
+		addrserver.WithBranch(hdkeychain.HardenedKeyStart))
+	if err != hdkeychain.ErrDeriveHardFromPublic {
+		t.Errorf("New: got error %v, want %v", err,
+			hdkeychain.ErrDeriveHardFromPublic)
+	}
+}
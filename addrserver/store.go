@@ -0,0 +1,117 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package addrserver
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/zeusyf/btcutil/hdkeychain"
+)
+
+// Store persists the next unused index of address branches.  Implementations
+// must be safe for concurrent use.
+type Store interface {
+	// Reserve atomically advances the next index of the branch identified
+	// by key by n and returns the first of the n indexes reserved.  The
+	// new next index must be persisted before Reserve returns, so no index
+	// is ever reserved twice.  A branch never reserved from starts at zero,
+	// and ErrExhausted is returned when the reservation would go beyond
+	// the last non-hardened index.
+	Reserve(key string, n uint32) (uint32, error)
+}
+
+// reserve advances next[key] by n as stores do.
+func reserve(next map[string]uint32, key string, n uint32) (uint32, error) {
+	first := next[key]
+	if n > hdkeychain.HardenedKeyStart-first {
+		return 0, ErrExhausted
+	}
+	next[key] = first + n
+	return first, nil
+}
+
+// MemoryStore is a Store keeping indexes in memory, for tests and servers
+// whose addresses need not survive a restart.
+type MemoryStore struct {
+	mtx  sync.Mutex
+	next map[string]uint32
+}
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{next: make(map[string]uint32)}
+}
+
+// Reserve implements the Store interface.
+func (s *MemoryStore) Reserve(key string, n uint32) (uint32, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return reserve(s.next, key, n)
+}
+
+// FileStore is a Store keeping indexes in a JSON file, which is replaced
+// atomically on every reservation so a crash never loses one.  A file must
+// only be used by a single FileStore at a time.
+type FileStore struct {
+	mtx  sync.Mutex
+	path string
+}
+
+// NewFileStore returns a FileStore keeping indexes in the file at path,
+// which is created on the first reservation.
+func NewFileStore(path string) *FileStore {
+	return &FileStore{path: path}
+}
+
+// Reserve implements the Store interface.
+func (s *FileStore) Reserve(key string, n uint32) (uint32, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	next := make(map[string]uint32)
+	b, err := os.ReadFile(s.path)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(b, &next); err != nil {
+			return 0, err
+		}
+	case !os.IsNotExist(err):
+		return 0, err
+	}
+	first, err := reserve(next, key, n)
+	if err != nil {
+		return 0, err
+	}
+	b, err = json.Marshal(next)
+	if err != nil {
+		return 0, err
+	}
+	return first, s.writeFile(b)
+}
+
+// writeFile replaces the file of the store with b, by writing and syncing a
+// temporary file which is then renamed over it.
+func (s *FileStore) writeFile(b []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), s.path)
+}
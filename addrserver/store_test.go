@@ -0,0 +1,73 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package addrserver_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zeusyf/btcutil/addrserver"
+	"github.com/zeusyf/btcutil/hdkeychain"
+)
+
+// testStore ensures store reserves consecutive indexes per key and never
+// beyond the last non-hardened index.
+func testStore(t *testing.T, store addrserver.Store) {
+	t.Helper()
+	tests := []struct {
+		key  string
+		n    uint32
+		want uint32
+	}{
+		{"a", 1, 0},
+		{"a", 10, 1},
+		{"b", 1, 0},
+		{"a", 1, 11},
+	}
+	for _, test := range tests {
+		got, err := store.Reserve(test.key, test.n)
+		if err != nil || got != test.want {
+			t.Errorf("Reserve(%q, %d): got %d (%v), want %d", test.key,
+				test.n, got, err, test.want)
+		}
+	}
+
+	const last = hdkeychain.HardenedKeyStart - 1
+	if got, err := store.Reserve("c", last); err != nil || got != 0 {
+		t.Errorf("Reserve: got %d (%v), want 0", got, err)
+	}
+	if _, err := store.Reserve("c", 2); err != addrserver.ErrExhausted {
+		t.Errorf("Reserve: got error %v, want %v", err,
+			addrserver.ErrExhausted)
+	}
+	if got, err := store.Reserve("c", 1); err != nil || got != last {
+		t.Errorf("Reserve: got %d (%v), want %d", got, err, last)
+	}
+}
+
+// TestMemoryStore ensures memory stores reserve indexes as stores must.
+func TestMemoryStore(t *testing.T) {
+	testStore(t, addrserver.NewMemoryStore())
+}
+
+// TestFileStore ensures file stores reserve indexes as stores must, and keep
+// them across instances.
+func TestFileStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "indexes.json")
+	testStore(t, addrserver.NewFileStore(path))
+
+	got, err := addrserver.NewFileStore(path).Reserve("a", 1)
+	if err != nil || got != 12 {
+		t.Errorf("Reserve: got %d (%v), want 12", got, err)
+	}
+
+	if err := os.WriteFile(path, []byte("{"), 0600); err != nil {
+		t.Fatalf("WriteFile: unexpected error: %v", err)
+	}
+	if _, err := addrserver.NewFileStore(path).Reserve("a", 1); err == nil {
+		t.Errorf("Reserve: unexpected success reading a corrupt file")
+	}
+}
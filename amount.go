@@ -6,9 +6,33 @@
 package btcutil
 
 import (
+	"database/sql/driver"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"math"
+	"math/big"
 	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalidAmount describes an error where an amount is not a finite
+	// number, or is not written as a decimal number.  Its message is the
+	// one NewAmount has always returned.
+	ErrInvalidAmount = errors.New("invalid bitcoin amount")
+
+	// ErrAmountPrecision describes an error where a decimal amount has
+	// non-zero digits beyond the precision of one Hao.
+	ErrAmountPrecision = errors.New("amount is more precise than one Hao")
+
+	// ErrAmountOverflow describes an error where a parsed, converted or
+	// summed amount does not fit in an Amount.
+	ErrAmountOverflow = errors.New("amount out of range")
+
+	// ErrUnknownUnit describes an error where the unit label of an amount
+	// or fee rate is not recognized.
+	ErrUnknownUnit = errors.New("unknown unit")
 )
 
 // AmountUnit describes a method of converting an Amount to something
@@ -25,12 +49,13 @@ const (
 	AmountOMC      AmountUnit = 0
 	AmountMilliOMC AmountUnit = -3
 	AmountMicroOMC AmountUnit = -6
-	AmountHao  AmountUnit = -8
+	AmountHao      AmountUnit = -OMCDecimals
 )
 
 // String returns the unit as a string.  For recognized units, the SI
-// prefix is used, or "Hao" for the base unit.  For all unrecognized
-// units, "1eN OMC" is returned, where N is the AmountUnit.
+// prefix is used, or "Hao" for the base unit, and units registered with
+// RegisterAmountUnit return their symbol.  For all unrecognized units,
+// "1eN OMC" is returned, where N is the AmountUnit.
 func (u AmountUnit) String() string {
 	switch u {
 	case AmountMegaOMC:
@@ -46,6 +71,9 @@ func (u AmountUnit) String() string {
 	case AmountHao:
 		return "Hao"
 	default:
+		if info, ok := registeredUnit(u); ok {
+			return info.Symbol
+		}
 		return "1e" + strconv.FormatInt(int64(u), 10) + " OMC"
 	}
 }
@@ -83,11 +111,11 @@ func NewAmount(f float64, tokentype uint64) (Amount, error) {
 	case math.IsInf(f, 1):
 		fallthrough
 	case math.IsInf(f, -1):
-		return 0, errors.New("invalid bitcoin amount")
+		return 0, ErrInvalidAmount
 	}
 
 	if tokentype == 0 {
-		return round(f * HaoPerBitcoin), nil
+		return round(f * HaoPerOMC), nil
 	} else {
 		return round(f), nil
 	}
@@ -96,7 +124,7 @@ func NewAmount(f float64, tokentype uint64) (Amount, error) {
 // ToUnit converts a monetary amount counted in bitcoin base units to a
 // floating point value representing an amount of bitcoin.
 func (a Amount) ToUnit(u AmountUnit) float64 {
-	return float64(a) / math.Pow10(int(u+8))
+	return float64(a) / math.Pow10(int(u)+OMCDecimals)
 }
 
 // ToOMC is the equivalent of calling ToUnit with AmountOMC.
@@ -107,10 +135,15 @@ func (a Amount) ToOMC() float64 {
 // Format formats a monetary amount counted in bitcoin base units as a
 // string for a given unit.  The conversion will succeed for any unit,
 // however, known units will be formated with an appended label describing
-// the units with SI notation, or "Hao" for the base unit.
+// the units with SI notation, or "Hao" for the base unit.  Units registered
+// with RegisterAmountUnit are formatted with their symbol and precision.
 func (a Amount) Format(u AmountUnit) string {
 	units := " " + u.String()
-	return strconv.FormatFloat(a.ToUnit(u), 'f', -int(u+8), 64) + units
+	prec := -(int(u) + OMCDecimals)
+	if info, ok := registeredUnit(u); ok {
+		prec = info.Precision
+	}
+	return strconv.FormatFloat(a.ToUnit(u), 'f', prec, 64) + units
 }
 
 // String is the equivalent of calling Format with AmountOMC.
@@ -121,7 +154,523 @@ func (a Amount) String() string {
 // MulF64 multiplies an Amount by a floating point value.  While this is not
 // an operation that must typically be done by a full node or wallet, it is
 // useful for services that build on top of bitcoin (for example, calculating
-// a fee by multiplying by a percentage).
+// a fee by multiplying by a percentage).  The product is rounded to the
+// nearest Hao, and halves away from zero; use MulF64Mode to round otherwise.
 func (a Amount) MulF64(f float64) Amount {
 	return round(float64(a) * f)
 }
+
+// MulF64Mode multiplies an Amount by a floating point value like MulF64, but
+// rounds the product to a Hao in the passed mode.  Fee services which must
+// never collect less than a percentage of an amount should round with
+// RoundCeil, and those summing many rounded fees may prefer the unbiased
+// RoundHalfEven.
+func (a Amount) MulF64Mode(f float64, mode RoundingMode) Amount {
+	p := float64(a) * f
+	switch mode {
+	case RoundHalfEven:
+		return Amount(math.RoundToEven(p))
+	case RoundFloor:
+		return Amount(math.Floor(p))
+	case RoundCeil:
+		return Amount(math.Ceil(p))
+	}
+	return round(p)
+}
+
+// knownUnits lists the units with a dedicated label, in the order they are
+// matched when parsing.
+var knownUnits = []AmountUnit{
+	AmountMegaOMC,
+	AmountKiloOMC,
+	AmountOMC,
+	AmountMilliOMC,
+	AmountMicroOMC,
+	AmountHao,
+}
+
+// parseUnit returns the unit whose label, as returned by AmountUnit.String,
+// matches s.  Registered units are matched after those of knownUnits.
+func parseUnit(s string) (AmountUnit, bool) {
+	for _, u := range knownUnits {
+		if u.String() == s {
+			return u, true
+		}
+	}
+	units, _ := unitRegistry.Load().(map[AmountUnit]UnitInfo)
+	for u, info := range units {
+		if info.Symbol == s {
+			return u, true
+		}
+	}
+	return 0, false
+}
+
+// isDigits returns whether s consists solely of ASCII decimal digits.
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// parseDecimal parses the decimal string s, denominated in a unit worth
+// 10^exp Hao, into an exact Amount.  Digits beyond the precision of a Hao
+// are only accepted when they are zero.
+func parseDecimal(s string, exp int) (Amount, error) {
+	n, err := parseDecimalInt(s, exp)
+	if err != nil {
+		return 0, err
+	}
+	if !n.IsInt64() {
+		return 0, fmt.Errorf("%w: %q", ErrAmountOverflow, s)
+	}
+	return Amount(n.Int64()), nil
+}
+
+// parseDecimalInt parses the decimal string s like parseDecimal, into a
+// number of Hao of any size.
+func parseDecimalInt(s string, exp int) (*big.Int, error) {
+	orig := s
+	neg := false
+	switch {
+	case strings.HasPrefix(s, "-"):
+		neg = true
+		s = s[1:]
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+
+	intPart, fracPart := s, ""
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		intPart, fracPart = s[:i], s[i+1:]
+	}
+	if intPart == "" && fracPart == "" || !isDigits(intPart) ||
+		!isDigits(fracPart) {
+
+		return nil, fmt.Errorf("%w %q", ErrInvalidAmount, orig)
+	}
+
+	n, _ := new(big.Int).SetString(intPart+fracPart, 10)
+	shift := exp - len(fracPart)
+	if shift >= 0 {
+		n.Mul(n, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(shift)), nil))
+	} else {
+		var rem big.Int
+		div := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(-shift)), nil)
+		n.QuoRem(n, div, &rem)
+		if rem.Sign() != 0 {
+			return nil, fmt.Errorf("%w: %q", ErrAmountPrecision, orig)
+		}
+	}
+	if neg {
+		n.Neg(n)
+	}
+	return n, nil
+}
+
+// splitDecimal returns the digits of the magnitude of the amount expressed in
+// a unit worth 10^exp Hao, split into the whole and fractional parts.  The
+// fractional part always has exactly exp digits, or none when exp is not
+// positive.
+func splitDecimal(a Amount, exp int) (whole, frac string) {
+	// Work on the magnitude as an unsigned integer so the most negative
+	// amount can be represented.
+	mag := uint64(a)
+	if a < 0 {
+		mag = -mag
+	}
+	return splitDigits(strconv.FormatUint(mag, 10), exp)
+}
+
+// splitDigits splits the decimal digits of a number of Hao like splitDecimal.
+func splitDigits(digits string, exp int) (whole, frac string) {
+	if exp <= 0 {
+		if digits != "0" {
+			digits += strings.Repeat("0", -exp)
+		}
+		return digits, ""
+	}
+	if len(digits) <= exp {
+		digits = strings.Repeat("0", exp-len(digits)+1) + digits
+	}
+	return digits[:len(digits)-exp], digits[len(digits)-exp:]
+}
+
+// formatDecimal formats the amount exactly in a unit worth 10^exp Hao.
+// Trailing fractional zeros are not emitted.
+func formatDecimal(a Amount, exp int) string {
+	whole, frac := splitDecimal(a, exp)
+	frac = strings.TrimRight(frac, "0")
+
+	var sb strings.Builder
+	if a < 0 {
+		sb.WriteByte('-')
+	}
+	sb.WriteString(whole)
+	if frac != "" {
+		sb.WriteByte('.')
+		sb.WriteString(frac)
+	}
+	return sb.String()
+}
+
+// ParseAmount parses a decimal string denominated in OMC, optionally followed
+// by a single space and the label of a recognized unit as returned by
+// AmountUnit.String (for example "1.5", "1.5 kOMC" or "2300 Hao").  Unlike
+// NewAmount, no floating point math is involved, so every amount round-trips
+// exactly.
+func ParseAmount(s string) (Amount, error) {
+	num, unit, err := splitUnit(s)
+	if err != nil {
+		return 0, err
+	}
+	return parseDecimal(num, int(unit)+OMCDecimals)
+}
+
+// ParseFormatted parses a string produced by Format or String in any unit,
+// such as "1.5 kOMC", "2300 Hao" or "1200.00 1e-10 OMC", back into an Amount,
+// for tools echoing values edited by users.  Unlike ParseAmount, it accepts
+// the labels of unrecognized units, and rounds digits beyond the precision of
+// a Hao to the nearest Hao, since Format converts through floating point.
+// Every amount of less than 2^51 Hao (about 22 million OMC) in magnitude, for
+// which the floating point error of Format stays below half a Hao,
+// round-trips.  A missing label denotes OMC.
+func ParseFormatted(s string) (Amount, error) {
+	num, unit, err := splitUnit(s)
+	if err != nil {
+		var ok bool
+		num, unit, ok = splitExpUnit(s)
+		if !ok {
+			return 0, err
+		}
+	}
+
+	// Bound the exponent of the unit before computing powers of ten from
+	// it, so labels such as "1e999999999 OMC" can't exhaust memory.  Past
+	// these bounds every nonzero amount overflows an Amount or has digits
+	// far beyond the precision of a Hao.
+	switch {
+	case int(unit) > maxUnitExp:
+		return 0, fmt.Errorf("%w: %q", ErrAmountOverflow, s)
+	case int(unit) < -maxUnitExp:
+		return 0, fmt.Errorf("%w: %q", ErrAmountPrecision, s)
+	}
+	exp := int(unit) + OMCDecimals
+
+	// Parse the number with enough digits to be exact, then round it to
+	// the Hao.
+	extra := 0
+	if i := strings.IndexByte(num, '.'); i >= 0 {
+		extra = len(num) - i - 1 - exp
+	}
+	if extra < 0 {
+		extra = 0
+	}
+	n, err := parseDecimalInt(num, exp+extra)
+	if err != nil {
+		return 0, err
+	}
+	if extra > 0 {
+		// Halves are rounded away from zero, like round.
+		div := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(extra)), nil)
+		var rem big.Int
+		n.QuoRem(n, div, &rem)
+		switch half := rem.Lsh(&rem, 1); {
+		case half.Cmp(div) >= 0:
+			n.Add(n, big.NewInt(1))
+		case half.Neg(half).Cmp(div) >= 0:
+			n.Sub(n, big.NewInt(1))
+		}
+	}
+	if !n.IsInt64() {
+		return 0, fmt.Errorf("%w: %q", ErrAmountOverflow, s)
+	}
+	return Amount(n.Int64()), nil
+}
+
+// maxUnitExp is the largest magnitude of the exponent of a unit label
+// accepted by ParseFormatted.  An int64 has 19 digits, so a unit worth more
+// than 10^19 Hao can't express any nonzero Amount.
+const maxUnitExp = OMCDecimals + 19
+
+// splitExpUnit splits s into its number and the unit of its label when the
+// label is the "1eN OMC" form returned by AmountUnit.String for unrecognized
+// units.
+func splitExpUnit(s string) (string, AmountUnit, bool) {
+	i := strings.IndexByte(s, ' ')
+	if i < 0 {
+		return "", 0, false
+	}
+	label := s[i+1:]
+	if !strings.HasPrefix(label, "1e") || !strings.HasSuffix(label, " OMC") {
+		return "", 0, false
+	}
+	n, err := strconv.Atoi(label[2 : len(label)-len(" OMC")])
+	if err != nil {
+		return "", 0, false
+	}
+	return s[:i], AmountUnit(n), true
+}
+
+// splitUnit splits s into its number and the unit of its optional label,
+// which defaults to OMC.
+func splitUnit(s string) (string, AmountUnit, error) {
+	i := strings.IndexByte(s, ' ')
+	if i < 0 {
+		return s, AmountOMC, nil
+	}
+	unit, ok := parseUnit(s[i+1:])
+	if !ok {
+		return "", 0, fmt.Errorf("%w %q", ErrUnknownUnit, s[i+1:])
+	}
+	return s[:i], unit, nil
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.  The amount is
+// encoded as an exact decimal number of OMC without a unit label, such as
+// "1.5", so it can be used directly in configuration files and CSV exports.
+func (a Amount) MarshalText() ([]byte, error) {
+	return []byte(formatDecimal(a, int(AmountOMC)+OMCDecimals)), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.  It accepts
+// any string accepted by ParseAmount.
+func (a *Amount) UnmarshalText(text []byte) error {
+	amt, err := ParseAmount(string(text))
+	if err != nil {
+		return err
+	}
+	*a = amt
+	return nil
+}
+
+// MarshalJSON implements the json.Marshaler interface.  Amounts are encoded
+// as an integer number of Hao, as they were before Amount implemented
+// encoding.TextMarshaler, rather than as the decimal string of MarshalText.
+func (a Amount) MarshalJSON() ([]byte, error) {
+	return strconv.AppendInt(nil, int64(a), 10), nil
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.  It accepts an
+// integer number of Hao, as written by MarshalJSON, and leaves the amount
+// unchanged for null.
+func (a *Amount) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	var v int64
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	*a = Amount(v)
+	return nil
+}
+
+// Value implements the database/sql/driver.Valuer interface.  Amounts are
+// stored as an integer number of Hao so no precision is lost.
+func (a Amount) Value() (driver.Value, error) {
+	return int64(a), nil
+}
+
+// Scan implements the database/sql.Scanner interface.  It accepts integer
+// columns holding a number of Hao, as written by Value, as well as textual
+// and floating point columns as long as they hold an integral number of Hao.
+// Use a pointer to an Amount, or sql.NullInt64, to scan nullable columns.
+func (a *Amount) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case int64:
+		*a = Amount(v)
+	case []byte:
+		return a.scanString(string(v))
+	case string:
+		return a.scanString(v)
+	case float64:
+		if v != math.Trunc(v) || v < math.MinInt64 || v >= math.MaxInt64 {
+			return fmt.Errorf("cannot scan non-integral or out of "+
+				"range value %v into Amount", v)
+		}
+		*a = Amount(v)
+	case nil:
+		return errors.New("cannot scan NULL into Amount")
+	default:
+		return fmt.Errorf("cannot scan %T into Amount", src)
+	}
+	return nil
+}
+
+// scanString parses a textual column holding an integer number of Hao.
+func (a *Amount) scanString(s string) error {
+	v, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return fmt.Errorf("cannot scan %q into Amount: %v", s, err)
+	}
+	*a = Amount(v)
+	return nil
+}
+
+// UnitPlacement describes where the unit label is placed when formatting an
+// amount with FormatWithOptions.
+type UnitPlacement int
+
+const (
+	// UnitSuffix places the unit label after the number, separated by a
+	// space, as in "1.5 OMC".  This is the default.
+	UnitSuffix UnitPlacement = iota
+
+	// UnitPrefix places the unit label before the number, separated by a
+	// space, as in "OMC 1.5".
+	UnitPrefix
+
+	// UnitNone omits the unit label.
+	UnitNone
+)
+
+// FormatOptions controls how FormatWithOptions renders an amount.  The zero
+// value formats in OMC with a "." decimal separator, no digit grouping, the
+// full precision of the unit, and a trailing unit label, which matches the
+// output of Format for AmountOMC.
+type FormatOptions struct {
+	// Unit is the unit the amount is expressed in.
+	Unit AmountUnit
+
+	// GroupSeparator, when not empty, is inserted between every group of
+	// three digits of the whole part, as in "1,234,567".
+	GroupSeparator string
+
+	// DecimalSeparator separates the whole and fractional parts.  A "."
+	// is used when empty.
+	DecimalSeparator string
+
+	// TrimTrailingZeros drops trailing zeros of the fractional part, and
+	// the decimal separator when no fractional digits remain.
+	TrimTrailingZeros bool
+
+	// MinFractionDigits is the number of fractional digits kept when
+	// trimming trailing zeros.
+	MinFractionDigits int
+
+	// ExplicitSign prefixes positive amounts with "+".
+	ExplicitSign bool
+
+	// UnitPlacement controls where the unit label is placed.
+	UnitPlacement UnitPlacement
+}
+
+// groupDigits inserts sep between every group of three digits of s counting
+// from the right.
+func groupDigits(s, sep string) string {
+	if sep == "" || len(s) <= 3 {
+		return s
+	}
+	var sb strings.Builder
+	lead := len(s) % 3
+	if lead == 0 {
+		lead = 3
+	}
+	sb.WriteString(s[:lead])
+	for i := lead; i < len(s); i += 3 {
+		sb.WriteString(sep)
+		sb.WriteString(s[i : i+3])
+	}
+	return sb.String()
+}
+
+// FormatWithOptions formats the amount for display according to the passed
+// options.  Unlike Format, the conversion is exact for every amount since no
+// floating point math is involved.
+func (a Amount) FormatWithOptions(opts FormatOptions) string {
+	whole, frac := splitDecimal(a, int(opts.Unit)+OMCDecimals)
+	return formatWithOptions(a < 0, a > 0, whole, frac, opts)
+}
+
+// formatWithOptions formats the whole and fractional digits of the magnitude
+// of an amount according to opts.  Neg and pos report whether the amount is
+// negative or positive.
+func formatWithOptions(neg, pos bool, whole, frac string,
+	opts FormatOptions) string {
+	if opts.TrimTrailingZeros {
+		keep := len(strings.TrimRight(frac, "0"))
+		if keep < opts.MinFractionDigits {
+			keep = opts.MinFractionDigits
+		}
+		if keep < len(frac) {
+			frac = frac[:keep]
+		}
+	}
+
+	var sb strings.Builder
+	if opts.UnitPlacement == UnitPrefix {
+		sb.WriteString(opts.Unit.String())
+		sb.WriteByte(' ')
+	}
+	switch {
+	case neg:
+		sb.WriteByte('-')
+	case pos && opts.ExplicitSign:
+		sb.WriteByte('+')
+	}
+	sb.WriteString(groupDigits(whole, opts.GroupSeparator))
+	if frac != "" {
+		if opts.DecimalSeparator == "" {
+			sb.WriteByte('.')
+		} else {
+			sb.WriteString(opts.DecimalSeparator)
+		}
+		sb.WriteString(frac)
+	}
+	if opts.UnitPlacement == UnitSuffix {
+		sb.WriteByte(' ')
+		sb.WriteString(opts.Unit.String())
+	}
+	return sb.String()
+}
+
+// AmountFormatter pairs an Amount with FormatOptions.  It implements
+// fmt.Formatter, so it can be passed directly to the fmt functions as well as
+// to printers that build on them, such as those of golang.org/x/text/message,
+// with locale specific options supplied by the caller.
+type AmountFormatter struct {
+	Amount  Amount
+	Options FormatOptions
+}
+
+// Formatter returns an AmountFormatter which renders the amount using the
+// passed options.
+func (a Amount) Formatter(opts FormatOptions) AmountFormatter {
+	return AmountFormatter{Amount: a, Options: opts}
+}
+
+// String returns the amount formatted with the options of the formatter.
+func (f AmountFormatter) String() string {
+	return f.Amount.FormatWithOptions(f.Options)
+}
+
+// Format implements the fmt.Formatter interface.  The 'v' and 's' verbs
+// produce the output of FormatWithOptions and honor the width and '-' flag
+// for padding.  The 'd' verb produces the integer number of Hao.
+func (f AmountFormatter) Format(s fmt.State, verb rune) {
+	var str string
+	switch verb {
+	case 'v', 's':
+		str = f.String()
+	case 'd':
+		str = strconv.FormatInt(int64(f.Amount), 10)
+	default:
+		fmt.Fprintf(s, "%%!%c(btcutil.Amount=%d)", verb, int64(f.Amount))
+		return
+	}
+
+	if width, ok := s.Width(); ok {
+		if pad := width - len([]rune(str)); pad > 0 {
+			if s.Flag('-') {
+				str += strings.Repeat(" ", pad)
+			} else {
+				str = strings.Repeat(" ", pad) + str
+			}
+		}
+	}
+	fmt.Fprint(s, str)
+}
@@ -5,6 +5,9 @@
 package btcutil_test
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
 	"math"
 	"testing"
 
@@ -307,3 +310,369 @@ func TestAmountMulF64(t *testing.T) {
 		}
 	}
 }
+
+func TestAmountMulF64Mode(t *testing.T) {
+	tests := []struct {
+		name string
+		amt  Amount
+		mul  float64
+		mode RoundingMode
+		res  Amount
+	}{
+		{"Half away from zero", 1, 0.5, RoundHalfAwayFromZero, 1},
+		{"Negative half away from zero", -1, 0.5, RoundHalfAwayFromZero, -1},
+		{"Half to even down", 5, 0.5, RoundHalfEven, 2},
+		{"Half to even up", 7, 0.5, RoundHalfEven, 4},
+		{"Negative half to even", -5, 0.5, RoundHalfEven, -2},
+		{"Above half to even", 51, 0.01, RoundHalfEven, 1},
+		{"Floor", 199, 0.01, RoundFloor, 1},
+		{"Negative floor", -101, 0.01, RoundFloor, -2},
+		{"Ceil", 101, 0.01, RoundCeil, 2},
+		{"Negative ceil", -199, 0.01, RoundCeil, -1},
+		{"Ceil of exact product", 100, 0.66, RoundCeil, 66},
+		{"Floor of exact product", 100, 2, RoundFloor, 200},
+		{"Unknown mode", 50, 0.01, RoundingMode(9), 1},
+	}
+
+	for _, test := range tests {
+		a := test.amt.MulF64Mode(test.mul, test.mode)
+		if a != test.res {
+			t.Errorf("%v: expected %v got %v", test.name, test.res, a)
+		}
+	}
+}
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		s     string
+		valid bool
+		want  Amount
+		err   error
+	}{
+		{s: "0", valid: true, want: 0},
+		{s: "1.5", valid: true, want: 150000000},
+		{s: "-0.00000001", valid: true, want: -1},
+		{s: "+2.", valid: true, want: 2 * HaoPerBitcoin},
+		{s: ".25", valid: true, want: 25000000},
+		{s: "1.5 kOMC", valid: true, want: 1500 * HaoPerBitcoin},
+		{s: "2300 Hao", valid: true, want: 2300},
+		{s: "12.34 μOMC", valid: true, want: 1234},
+		{s: "1.000000010", valid: true, want: 100000001},
+		{s: "92233720368.54775807", valid: true, want: math.MaxInt64},
+		{s: "-92233720368.54775808", valid: true, want: math.MinInt64},
+		{s: "92233720368.54775808", err: ErrAmountOverflow},
+		{s: "0.000000001", err: ErrAmountPrecision},
+		{s: "1.5 Hao", err: ErrAmountPrecision},
+		{s: "1.5 BTC", err: ErrUnknownUnit},
+		{s: "", err: ErrInvalidAmount},
+		{s: ".", err: ErrInvalidAmount},
+		{s: "1e8", err: ErrInvalidAmount},
+		{s: "1,5", err: ErrInvalidAmount},
+		{s: "--1", err: ErrInvalidAmount},
+	}
+
+	for _, test := range tests {
+		a, err := ParseAmount(test.s)
+		switch {
+		case test.valid && err != nil:
+			t.Errorf("ParseAmount(%q): unexpected error: %v", test.s, err)
+			continue
+		case !test.valid && err == nil:
+			t.Errorf("ParseAmount(%q): succeeded (value %d) when "+
+				"should fail", test.s, a)
+			continue
+		case !test.valid && !errors.Is(err, test.err):
+			t.Errorf("ParseAmount(%q): got error %v, want %v", test.s,
+				err, test.err)
+		}
+		if test.valid && a != test.want {
+			t.Errorf("ParseAmount(%q): got %d, want %d", test.s, a,
+				test.want)
+		}
+	}
+}
+
+func TestParseFormatted(t *testing.T) {
+	units := []AmountUnit{AmountMegaOMC, AmountKiloOMC, AmountOMC,
+		AmountMilliOMC, AmountMicroOMC, AmountHao, AmountUnit(2),
+		AmountUnit(-1), AmountUnit(-10)}
+	amounts := []Amount{0, 1, -1, 2300, 150000000, -123456789,
+		1<<51 - 1, -(1<<51 - 1)}
+	for _, u := range units {
+		for _, a := range amounts {
+			s := a.Format(u)
+			got, err := ParseFormatted(s)
+			if err != nil {
+				t.Errorf("ParseFormatted(%q): unexpected error: %v", s,
+					err)
+				continue
+			}
+			if got != a {
+				t.Errorf("ParseFormatted(%q): got %d, want %d", s, got,
+					a)
+			}
+		}
+	}
+
+	tests := []struct {
+		s     string
+		valid bool
+		want  Amount
+		err   error
+	}{
+		{s: "1.5", valid: true, want: 150000000},
+		{s: "0.000000015 OMC", valid: true, want: 2},
+		{s: "-0.000000015 OMC", valid: true, want: -2},
+		{s: "0.0000000149 OMC", valid: true, want: 1},
+		{s: "2300.4 Hao", valid: true, want: 2300},
+		{s: "1.5 1e-10 OMC", valid: true, want: 0},
+		{s: "1250.00 1e-10 OMC", valid: true, want: 13},
+		{s: "9223372036854775808 Hao", err: ErrAmountOverflow},
+		{s: "0 1e27 OMC", valid: true, want: 0},
+		{s: "1 1e28 OMC", err: ErrAmountOverflow},
+		{s: "1 1e999999999 OMC", err: ErrAmountOverflow},
+		{s: "1 1e-28 OMC", err: ErrAmountPrecision},
+		{s: "1 1e-999999999 OMC", err: ErrAmountPrecision},
+		{s: "1.5 1eX OMC", err: ErrUnknownUnit},
+		{s: "1.5 BTC", err: ErrUnknownUnit},
+		{s: "1e8 Hao", err: ErrInvalidAmount},
+	}
+	for _, test := range tests {
+		a, err := ParseFormatted(test.s)
+		switch {
+		case test.valid && err != nil:
+			t.Errorf("ParseFormatted(%q): unexpected error: %v", test.s,
+				err)
+		case !test.valid && !errors.Is(err, test.err):
+			t.Errorf("ParseFormatted(%q): got error %v, want %v", test.s,
+				err, test.err)
+		case test.valid && a != test.want:
+			t.Errorf("ParseFormatted(%q): got %d, want %d", test.s, a,
+				test.want)
+		}
+	}
+}
+
+func TestAmountText(t *testing.T) {
+	tests := []struct {
+		amount Amount
+		text   string
+	}{
+		{0, "0"},
+		{1, "0.00000001"},
+		{-1, "-0.00000001"},
+		{150000000, "1.5"},
+		{MaxHao, "430000000"},
+		{math.MaxInt64, "92233720368.54775807"},
+		{math.MinInt64, "-92233720368.54775808"},
+	}
+
+	for _, test := range tests {
+		text, err := test.amount.MarshalText()
+		if err != nil {
+			t.Errorf("MarshalText(%d): unexpected error: %v",
+				test.amount, err)
+			continue
+		}
+		if string(text) != test.text {
+			t.Errorf("MarshalText(%d): got %q, want %q", test.amount,
+				text, test.text)
+			continue
+		}
+
+		var a Amount
+		if err := a.UnmarshalText(text); err != nil {
+			t.Errorf("UnmarshalText(%q): unexpected error: %v", text,
+				err)
+			continue
+		}
+		if a != test.amount {
+			t.Errorf("UnmarshalText(%q): got %d, want %d", text, a,
+				test.amount)
+		}
+	}
+}
+
+// TestAmountJSON ensures amounts encode to JSON as an integer number of Hao
+// rather than the decimal string of MarshalText, also within structs.
+func TestAmountJSON(t *testing.T) {
+	v := struct {
+		Fee   Amount  `json:"fee"`
+		Total *Amount `json:"total,omitempty"`
+	}{Fee: 150000000}
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("Marshal: unexpected error: %v", err)
+	}
+	if want := `{"fee":150000000}`; string(b) != want {
+		t.Errorf("Marshal: got %s, want %s", b, want)
+	}
+
+	tests := []struct {
+		in    string
+		valid bool
+		want  Amount
+	}{
+		{in: "0", valid: true, want: 0},
+		{in: "-1", valid: true, want: -1},
+		{in: "9223372036854775807", valid: true, want: math.MaxInt64},
+		{in: "null", valid: true, want: 7},
+		{in: `"1.5"`, valid: false},
+		{in: "1.5", valid: false},
+		{in: "9223372036854775808", valid: false},
+	}
+	for _, test := range tests {
+		a := Amount(7)
+		err := json.Unmarshal([]byte(test.in), &a)
+		switch {
+		case test.valid && err != nil:
+			t.Errorf("Unmarshal(%s): unexpected error: %v", test.in, err)
+		case !test.valid && err == nil:
+			t.Errorf("Unmarshal(%s): succeeded when should fail", test.in)
+		case test.valid && a != test.want:
+			t.Errorf("Unmarshal(%s): got %d, want %d", test.in, a,
+				test.want)
+		}
+	}
+}
+
+func TestAmountSQL(t *testing.T) {
+	v, err := Amount(12345).Value()
+	if err != nil || v != int64(12345) {
+		t.Fatalf("Value: got %v (%v), want int64 12345", v, err)
+	}
+
+	tests := []struct {
+		src   interface{}
+		valid bool
+		want  Amount
+	}{
+		{src: int64(-7), valid: true, want: -7},
+		{src: []byte("420"), valid: true, want: 420},
+		{src: "1000", valid: true, want: 1000},
+		{src: float64(25), valid: true, want: 25},
+		{src: float64(2.5), valid: false},
+		{src: "1.5", valid: false},
+		{src: nil, valid: false},
+		{src: true, valid: false},
+	}
+
+	for _, test := range tests {
+		var a Amount
+		err := a.Scan(test.src)
+		switch {
+		case test.valid && err != nil:
+			t.Errorf("Scan(%v): unexpected error: %v", test.src, err)
+		case !test.valid && err == nil:
+			t.Errorf("Scan(%v): succeeded when should fail", test.src)
+		case test.valid && a != test.want:
+			t.Errorf("Scan(%v): got %d, want %d", test.src, a, test.want)
+		}
+	}
+}
+
+func TestAmountFormatWithOptions(t *testing.T) {
+	tests := []struct {
+		name   string
+		amount Amount
+		opts   FormatOptions
+		want   string
+	}{
+		{
+			name:   "defaults",
+			amount: 150000000,
+			want:   "1.50000000 OMC",
+		},
+		{
+			name:   "grouped and trimmed",
+			amount: 123456789000000,
+			opts: FormatOptions{
+				GroupSeparator:    ",",
+				TrimTrailingZeros: true,
+			},
+			want: "1,234,567.89 OMC",
+		},
+		{
+			name:   "european locale",
+			amount: -123456789000000,
+			opts: FormatOptions{
+				GroupSeparator:    ".",
+				DecimalSeparator:  ",",
+				TrimTrailingZeros: true,
+				MinFractionDigits: 4,
+			},
+			want: "-1.234.567,8900 OMC",
+		},
+		{
+			name:   "whole amount trimmed",
+			amount: 100000000,
+			opts:   FormatOptions{TrimTrailingZeros: true},
+			want:   "1 OMC",
+		},
+		{
+			name:   "explicit sign prefix unit",
+			amount: 2500,
+			opts: FormatOptions{
+				Unit:          AmountHao,
+				ExplicitSign:  true,
+				UnitPlacement: UnitPrefix,
+			},
+			want: "Hao +2500",
+		},
+		{
+			name:   "zero has no sign",
+			amount: 0,
+			opts: FormatOptions{
+				Unit:          AmountMilliOMC,
+				ExplicitSign:  true,
+				UnitPlacement: UnitNone,
+			},
+			want: "0.00000",
+		},
+		{
+			name:   "smaller than base unit",
+			amount: 12,
+			opts:   FormatOptions{Unit: AmountUnit(-10)},
+			want:   "1200 1e-10 OMC",
+		},
+		{
+			name:   "max int64",
+			amount: math.MaxInt64,
+			opts: FormatOptions{
+				Unit:           AmountKiloOMC,
+				GroupSeparator: " ",
+			},
+			want: "92 233 720.36854775807 kOMC",
+		},
+	}
+
+	for _, test := range tests {
+		got := test.amount.FormatWithOptions(test.opts)
+		if got != test.want {
+			t.Errorf("%s: got %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+func TestAmountFormatter(t *testing.T) {
+	f := Amount(123456789).Formatter(FormatOptions{
+		TrimTrailingZeros: true,
+	})
+	tests := []struct {
+		format string
+		want   string
+	}{
+		{"%v", "1.23456789 OMC"},
+		{"%s", "1.23456789 OMC"},
+		{"%16s|", "  1.23456789 OMC|"},
+		{"%-16v|", "1.23456789 OMC  |"},
+		{"%d", "123456789"},
+		{"%x", "%!x(btcutil.Amount=123456789)"},
+	}
+	for _, test := range tests {
+		if got := fmt.Sprintf(test.format, f); got != test.want {
+			t.Errorf("Sprintf(%q): got %q, want %q", test.format, got,
+				test.want)
+		}
+	}
+}
@@ -0,0 +1,319 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package btcutil
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+var (
+	// ErrAmbiguousAmount describes an error where an entered amount has a
+	// single comma or period followed by exactly three digits, which may
+	// either be a decimal separator or a digit group separator.
+	ErrAmbiguousAmount = errors.New("ambiguous decimal separator in amount")
+
+	// ErrAmbiguousUnit describes an error where the unit word of an entered
+	// amount differs from a known unit only in case and matches more than
+	// one unit, such as "momc" for mOMC or MOMC.
+	ErrAmbiguousUnit = errors.New("ambiguous amount unit")
+)
+
+// AmountInputChanges is a set of flags describing the normalizations
+// SanitizeAmount applied to an entered amount.  User interfaces show them so
+// users can confirm the amount was understood as intended.
+type AmountInputChanges uint32
+
+const (
+	// AmountTrimmedSpace is set when leading or trailing white space was
+	// removed.
+	AmountTrimmedSpace AmountInputChanges = 1 << iota
+
+	// AmountConvertedDigits is set when digits or signs of other scripts,
+	// such as full-width or Arabic-Indic digits, were converted to ASCII.
+	AmountConvertedDigits
+
+	// AmountRemovedGrouping is set when digit group separators, such as
+	// the commas of "1,234.5" or the thin spaces of "1 234,5", were
+	// removed.
+	AmountRemovedGrouping
+
+	// AmountConvertedDecimal is set when a decimal comma was replaced by
+	// a period.
+	AmountConvertedDecimal
+
+	// AmountNormalizedUnit is set when a unit word was replaced by the
+	// label of its unit, such as "hao" by "Hao", or moved after the
+	// number.
+	AmountNormalizedUnit
+)
+
+// amountChangeStrings is a map of change flags back to their descriptions for
+// pretty printing, in the order they are listed.
+var amountChangeStrings = []struct {
+	change AmountInputChanges
+	desc   string
+}{
+	{AmountTrimmedSpace, "trimmed space"},
+	{AmountConvertedDigits, "converted digits"},
+	{AmountRemovedGrouping, "removed digit grouping"},
+	{AmountConvertedDecimal, "converted decimal comma"},
+	{AmountNormalizedUnit, "normalized unit"},
+}
+
+// String returns the changes as a comma separated list of descriptions, or
+// "none" when no changes were made.
+func (c AmountInputChanges) String() string {
+	if c == 0 {
+		return "none"
+	}
+	var descs []string
+	for _, s := range amountChangeStrings {
+		if c&s.change != 0 {
+			descs = append(descs, s.desc)
+			c &^= s.change
+		}
+	}
+	if c != 0 {
+		descs = append(descs, fmt.Sprintf("unknown (%#x)", uint32(c)))
+	}
+	return strings.Join(descs, ", ")
+}
+
+// digitZeros are the zero digits of the scripts whose digits SanitizeAmount
+// converts.  The digits of each script are consecutive code points.
+var digitZeros = []rune{
+	'٠', // Arabic-Indic
+	'۰', // Extended Arabic-Indic
+	'०', // Devanagari
+	'০', // Bengali
+	'０', // Full-width
+}
+
+// amountRuneMap maps punctuation of other scripts to its ASCII equivalent.
+var amountRuneMap = map[rune]rune{
+	'．': '.', // Full-width full stop
+	'，': ',', // Full-width comma
+	'٫': ',', // Arabic decimal separator
+	'٬': ' ', // Arabic thousands separator
+	'＋': '+', // Full-width plus
+	'－': '-', // Full-width hyphen-minus
+	'−': '-', // Minus sign
+}
+
+// isGroupSpace returns whether r is a space or quote used to group digits,
+// such as the thin space of "1 234" or the apostrophe of "1'234".
+func isGroupSpace(r rune) bool {
+	return unicode.IsSpace(r) || r == '\'' || r == '\u2019'
+}
+
+// unitAliases maps lower case unit words to their unit.  Words which only
+// match by case, such as "momc", are ambiguous and absent.
+var unitAliases = map[string]AmountUnit{
+	"omc":       AmountOMC,
+	"komc":      AmountKiloOMC,
+	"uomc":      AmountMicroOMC,
+	"\u00b5omc": AmountMicroOMC, // Micro sign
+	"\u03bcomc": AmountMicroOMC, // Greek small letter mu
+	"hao":       AmountHao,
+	"haos":      AmountHao,
+}
+
+// sanitizeUnit returns the unit a unit word denotes.
+func sanitizeUnit(word string) (AmountUnit, error) {
+	if u, ok := parseUnit(word); ok {
+		return u, nil
+	}
+	lower := strings.ToLower(word)
+	if u, ok := unitAliases[lower]; ok {
+		return u, nil
+	}
+	if lower == "momc" {
+		return 0, ErrAmbiguousUnit
+	}
+	return 0, fmt.Errorf("%w %q", ErrUnknownUnit, word)
+}
+
+// SanitizeAmount normalizes an amount entered by a user into the form
+// accepted by ParseAmount and reports the changes made.  It accepts:
+//
+//   - digits, signs and separators of other scripts, such as full-width
+//     digits
+//   - a decimal comma, as in "1,5"
+//   - digit grouping with commas, periods, spaces, thin spaces or
+//     apostrophes, as in "1,234.5", "1.234,5" or "1 234,5"
+//   - a unit word before or after the number in any case, as in "1.5 omc",
+//     "OMC 1.5" or "2300hao"
+//
+// A single comma or period followed by exactly three digits is rejected with
+// ErrAmbiguousAmount, since "1,234" means 1.234 in some locales and 1234 in
+// others.  The caller should ask the user to enter the amount without digit
+// grouping instead.
+func SanitizeAmount(s string) (string, AmountInputChanges, error) {
+	var changes AmountInputChanges
+
+	// Convert the digits and punctuation of other scripts to ASCII.
+	var sb strings.Builder
+	for _, r := range s {
+		switch mapped, ok := amountRuneMap[r]; {
+		case ok:
+			if mapped != ' ' {
+				changes |= AmountConvertedDigits
+			}
+			r = mapped
+		case unicode.IsDigit(r) && r > unicode.MaxASCII:
+			for _, zero := range digitZeros {
+				if r >= zero && r <= zero+9 {
+					r = '0' + r - zero
+					changes |= AmountConvertedDigits
+					break
+				}
+			}
+		}
+		sb.WriteRune(r)
+	}
+	num := sb.String()
+
+	trimmed := strings.TrimFunc(num, isGroupSpace)
+	if trimmed != num {
+		changes |= AmountTrimmedSpace
+	}
+	num = trimmed
+
+	// Split off a unit word at either end.
+	unit, hasUnit := AmountOMC, false
+	notLetter := func(r rune) bool { return !unicode.IsLetter(r) }
+	if i := strings.LastIndexFunc(num, notLetter); i >= 0 && i < len(num)-1 {
+		_, size := utf8.DecodeRuneInString(num[i:])
+		word, rest := num[i+size:], num[:i+size]
+		u, err := sanitizeUnit(word)
+		if err != nil {
+			return "", changes, err
+		}
+		unit, hasUnit = u, true
+		num = strings.TrimRightFunc(rest, isGroupSpace)
+		if word != u.String() || rest != num+" " {
+			changes |= AmountNormalizedUnit
+		}
+	} else if i := strings.IndexFunc(num, notLetter); i > 0 {
+		u, err := sanitizeUnit(num[:i])
+		if err != nil {
+			return "", changes, err
+		}
+		unit, hasUnit = u, true
+		num = strings.TrimLeftFunc(num[i:], isGroupSpace)
+		changes |= AmountNormalizedUnit
+	}
+
+	num, numChanges, err := sanitizeNumber(num)
+	if err != nil {
+		return "", changes, err
+	}
+	changes |= numChanges
+	if hasUnit {
+		num += " " + unit.String()
+	}
+	return num, changes, nil
+}
+
+// sanitizeNumber removes digit grouping from the number and converts a
+// decimal comma to a period.
+func sanitizeNumber(num string) (string, AmountInputChanges, error) {
+	var changes AmountInputChanges
+
+	sign := ""
+	if strings.HasPrefix(num, "-") || strings.HasPrefix(num, "+") {
+		sign, num = num[:1], num[1:]
+	}
+
+	// Remove grouping spaces and quotes, which are never decimal
+	// separators.
+	if strings.IndexFunc(num, isGroupSpace) >= 0 {
+		groups := strings.FieldsFunc(num, isGroupSpace)
+		if !validGroups(groups) {
+			return "", changes, fmt.Errorf("%w %q", ErrInvalidAmount,
+				num)
+		}
+		num = strings.Join(groups, "")
+		changes |= AmountRemovedGrouping
+	}
+
+	// The decimal separator is the last period or comma when both are
+	// used, and the other is the group separator.
+	lastComma := strings.LastIndexByte(num, ',')
+	lastPeriod := strings.LastIndexByte(num, '.')
+	var decimal, group byte
+	switch {
+	case lastComma >= 0 && lastPeriod >= 0:
+		decimal, group = '.', ','
+		if lastComma > lastPeriod {
+			decimal, group = ',', '.'
+		}
+
+	case lastComma >= 0 || lastPeriod >= 0:
+		sep := byte('.')
+		if lastComma >= 0 {
+			sep = ','
+		}
+		switch parts := strings.Split(num, string(sep)); {
+		case len(parts) > 2:
+			group = sep
+		case len(parts[1]) == 3 && parts[0] != "" && parts[0] != "0":
+			return "", changes, ErrAmbiguousAmount
+		default:
+			decimal = sep
+		}
+	}
+
+	if group != 0 {
+		whole, frac := num, ""
+		if i := strings.IndexByte(num, decimal); decimal != 0 && i >= 0 {
+			whole, frac = num[:i], num[i:]
+		}
+		groups := strings.Split(whole, string(group))
+		if !validGroups(groups) || strings.IndexByte(frac, group) >= 0 {
+			return "", changes, fmt.Errorf("%w %q", ErrInvalidAmount,
+				num)
+		}
+		num = strings.Join(groups, "") + frac
+		changes |= AmountRemovedGrouping
+	}
+	if decimal == ',' {
+		num = strings.Replace(num, ",", ".", 1)
+		changes |= AmountConvertedDecimal
+	}
+	return sign + num, changes, nil
+}
+
+// validGroups returns whether the digit groups of a number are valid: a first
+// group of one to three digits followed by groups of exactly three digits.
+// The last group may carry the fractional part.
+func validGroups(groups []string) bool {
+	for i, g := range groups {
+		if i == len(groups)-1 {
+			if j := strings.IndexAny(g, ".,"); j >= 0 {
+				g = g[:j]
+			}
+		}
+		if i == 0 && (len(g) < 1 || len(g) > 3) || i > 0 && len(g) != 3 {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseAmountInput parses an amount entered by a user after normalizing it
+// with SanitizeAmount, and returns the changes made alongside the amount.
+func ParseAmountInput(s string) (Amount, AmountInputChanges, error) {
+	sanitized, changes, err := SanitizeAmount(s)
+	if err != nil {
+		return 0, changes, err
+	}
+	amt, err := ParseAmount(sanitized)
+	return amt, changes, err
+}
@@ -0,0 +1,100 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package btcutil_test
+
+import (
+	"testing"
+
+	"github.com/zeusyf/btcutil"
+)
+
+// TestSanitizeAmount ensures entered amounts are normalized as expected and
+// the changes made are reported.
+func TestSanitizeAmount(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		changes btcutil.AmountInputChanges
+		err     error
+	}{
+		{in: "1.5", want: "1.5"},
+		{in: "1.5 kOMC", want: "1.5 kOMC"},
+		{in: "  1.5\t", want: "1.5", changes: btcutil.AmountTrimmedSpace},
+		{in: "1,5", want: "1.5", changes: btcutil.AmountConvertedDecimal},
+		{in: "0,123", want: "0.123", changes: btcutil.AmountConvertedDecimal},
+		{in: "1,234.5", want: "1234.5", changes: btcutil.AmountRemovedGrouping},
+		{
+			in:      "1.234.567,89",
+			want:    "1234567.89",
+			changes: btcutil.AmountRemovedGrouping | btcutil.AmountConvertedDecimal,
+		},
+		{
+			in:      "1 234,5",
+			want:    "1234.5",
+			changes: btcutil.AmountRemovedGrouping | btcutil.AmountConvertedDecimal,
+		},
+		{in: "1'234'567", want: "1234567", changes: btcutil.AmountRemovedGrouping},
+		{in: "1,234,567", want: "1234567", changes: btcutil.AmountRemovedGrouping},
+		{
+			in:      "１．５",
+			want:    "1.5",
+			changes: btcutil.AmountConvertedDigits,
+		},
+		{
+			in:      "١٫٥",
+			want:    "1.5",
+			changes: btcutil.AmountConvertedDigits | btcutil.AmountConvertedDecimal,
+		},
+		{in: "−1.5", want: "-1.5", changes: btcutil.AmountConvertedDigits},
+		{in: "2300 hao", want: "2300 Hao", changes: btcutil.AmountNormalizedUnit},
+		{in: "2300hao", want: "2300 Hao", changes: btcutil.AmountNormalizedUnit},
+		{in: "1.5 omc", want: "1.5 OMC", changes: btcutil.AmountNormalizedUnit},
+		{in: "OMC 1.5", want: "1.5 OMC", changes: btcutil.AmountNormalizedUnit},
+		{in: "5 µOMC", want: "5 μOMC", changes: btcutil.AmountNormalizedUnit},
+		{in: "1,234", err: btcutil.ErrAmbiguousAmount},
+		{in: "1.234", err: btcutil.ErrAmbiguousAmount},
+		{in: "5 momc", err: btcutil.ErrAmbiguousUnit},
+	}
+
+	for _, test := range tests {
+		got, changes, err := btcutil.SanitizeAmount(test.in)
+		if err != test.err {
+			t.Errorf("SanitizeAmount(%q): got error %v, want %v",
+				test.in, err, test.err)
+			continue
+		}
+		if got != test.want {
+			t.Errorf("SanitizeAmount(%q): got %q, want %q", test.in,
+				got, test.want)
+		}
+		if changes != test.changes {
+			t.Errorf("SanitizeAmount(%q): got changes %v, want %v",
+				test.in, changes, test.changes)
+		}
+	}
+
+	invalid := []string{"1,23,456", "12345,678.9", "1 23", "1.5 dollars", ""}
+	for _, in := range invalid {
+		if _, _, err := btcutil.ParseAmountInput(in); err == nil {
+			t.Errorf("ParseAmountInput(%q): unexpected success", in)
+		}
+	}
+
+	amt, changes, err := btcutil.ParseAmountInput("1.234,5 hao")
+	if err == nil {
+		t.Errorf("ParseAmountInput: fractional Hao accepted as %d", amt)
+	}
+	amt, changes, err = btcutil.ParseAmountInput("1.234,5 kOMC")
+	if err != nil {
+		t.Fatalf("ParseAmountInput: unexpected error: %v", err)
+	}
+	if want := btcutil.Amount(1234500 * btcutil.HaoPerBitcoin); amt != want {
+		t.Errorf("ParseAmountInput: got %d, want %d", amt, want)
+	}
+	want := "removed digit grouping, converted decimal comma"
+	if changes.String() != want {
+		t.Errorf("changes: got %q, want %q", changes, want)
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package btcutil
+
+import (
+	"errors"
+	"math"
+)
+
+var (
+	// ErrNegativeAmount describes an error where an amount which must not
+	// be negative is.
+	ErrNegativeAmount = errors.New("amount is negative")
+
+	// ErrAmountTooLarge describes an error where an amount exceeds the
+	// largest valid amount of its token type.
+	ErrAmountTooLarge = errors.New("amount exceeds maximum")
+)
+
+// IsValid returns whether the amount is a valid OMC amount, between zero and
+// MaxHao inclusive.
+func (a Amount) IsValid() bool {
+	return a.CheckRange(MaxHao) == nil
+}
+
+// CheckRange returns ErrNegativeAmount when the amount is negative and
+// ErrAmountTooLarge when it exceeds max, such as the total supply of a token.
+func (a Amount) CheckRange(max Amount) error {
+	switch {
+	case a < 0:
+		return ErrNegativeAmount
+	case a > max:
+		return ErrAmountTooLarge
+	}
+	return nil
+}
+
+// SumChecked returns the sum of amounts, or ErrAmountOverflow when a partial
+// sum does not fit in an Amount.  The amounts themselves are not checked, so
+// callers validating outputs should check each with CheckRange, and the sum
+// against the same bound.
+func SumChecked(amounts []Amount) (Amount, error) {
+	var sum Amount
+	for _, a := range amounts {
+		if (a > 0 && sum > math.MaxInt64-a) ||
+			(a < 0 && sum < math.MinInt64-a) {
+			return 0, ErrAmountOverflow
+		}
+		sum += a
+	}
+	return sum, nil
+}
+
+// AmountLimits holds the largest valid amount of token types, such as their
+// total supply.  OMC is bounded by MaxHao unless it has a limit, and other
+// token types without one only by the range of Amount.
+type AmountLimits map[uint64]Amount
+
+// Max returns the largest valid amount of tokenType.
+func (l AmountLimits) Max(tokenType uint64) Amount {
+	if max, ok := l[tokenType]; ok {
+		return max
+	}
+	if tokenType == 0 {
+		return MaxHao
+	}
+	return math.MaxInt64
+}
+
+// Check returns the error of CheckRange for a, an amount of tokenType.
+func (l AmountLimits) Check(tokenType uint64, a Amount) error {
+	return a.CheckRange(l.Max(tokenType))
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package btcutil_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/zeusyf/btcutil"
+)
+
+// TestAmountRange ensures amounts are checked against the bounds of their
+// token type.
+func TestAmountRange(t *testing.T) {
+	if !btcutil.Amount(0).IsValid() || !btcutil.Amount(btcutil.MaxHao).IsValid() {
+		t.Errorf("IsValid: rejected a bound")
+	}
+	if btcutil.Amount(-1).IsValid() || btcutil.Amount(btcutil.MaxHao+1).IsValid() {
+		t.Errorf("IsValid: accepted an amount out of range")
+	}
+
+	limits := btcutil.AmountLimits{4: 1000}
+	tests := []struct {
+		tokenType uint64
+		amount    btcutil.Amount
+		err       error
+	}{
+		{0, btcutil.MaxHao, nil},
+		{0, btcutil.MaxHao + 1, btcutil.ErrAmountTooLarge},
+		{0, -1, btcutil.ErrNegativeAmount},
+		{4, 1000, nil},
+		{4, 1001, btcutil.ErrAmountTooLarge},
+		{6, math.MaxInt64, nil},
+		{6, -5, btcutil.ErrNegativeAmount},
+	}
+	for _, test := range tests {
+		err := limits.Check(test.tokenType, test.amount)
+		if err != test.err {
+			t.Errorf("Check(%d, %d): got %v, want %v", test.tokenType,
+				int64(test.amount), err, test.err)
+		}
+	}
+
+	limits[0] = 5
+	if err := limits.Check(0, 6); err != btcutil.ErrAmountTooLarge {
+		t.Errorf("Check: OMC limit not applied, got %v", err)
+	}
+}
+
+// TestSumChecked ensures sums overflowing an Amount are rejected.
+func TestSumChecked(t *testing.T) {
+	tests := []struct {
+		amounts []btcutil.Amount
+		sum     btcutil.Amount
+		err     error
+	}{
+		{nil, 0, nil},
+		{[]btcutil.Amount{1, 2, -4}, -1, nil},
+		{[]btcutil.Amount{math.MaxInt64, -1, 1}, math.MaxInt64, nil},
+		{[]btcutil.Amount{math.MaxInt64, 1}, 0, btcutil.ErrAmountOverflow},
+		{[]btcutil.Amount{math.MinInt64, -1}, 0, btcutil.ErrAmountOverflow},
+		{[]btcutil.Amount{-1, math.MinInt64}, 0, btcutil.ErrAmountOverflow},
+	}
+	for i, test := range tests {
+		sum, err := btcutil.SumChecked(test.amounts)
+		if sum != test.sum || err != test.err {
+			t.Errorf("#%d SumChecked: got %d, %v, want %d, %v", i,
+				int64(sum), err, int64(test.sum), test.err)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package btcutil
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"sort"
+)
+
+var (
+	// ErrNoValues describes an error where a statistic is requested of an
+	// empty set of values, or of values whose weights are all zero.
+	ErrNoValues = errors.New("no values")
+
+	// ErrInvalidPercentile describes an error where a percentile is not
+	// between 0 and 100 inclusive.
+	ErrInvalidPercentile = errors.New("percentile out of range")
+
+	// ErrInvalidBuckets describes an error where histogram bucket bounds
+	// are not strictly increasing.
+	ErrInvalidBuckets = errors.New("bucket bounds are not increasing")
+
+	// ErrInvalidWeights describes an error where weights do not match the
+	// values they weigh, are negative, or sum beyond the range of an int64.
+	ErrInvalidWeights = errors.New("invalid weights")
+)
+
+// percentile returns the value of nearest rank percent of values, without
+// modifying values.
+func percentile(values []int64, percent int) (int64, error) {
+	if len(values) == 0 {
+		return 0, ErrNoValues
+	}
+	if percent < 0 || percent > 100 {
+		return 0, ErrInvalidPercentile
+	}
+	sorted := append([]int64(nil), values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	// The nearest rank is the ceiling of percent/100 of the number of
+	// values, and the smallest value for the zeroth percentile.
+	rank := (percent*len(sorted) + 99) / 100
+	if rank == 0 {
+		rank = 1
+	}
+	return sorted[rank-1], nil
+}
+
+// checkWeights returns the sum of weights, which must be nil or hold one
+// weight for each of n values.  Nil weights weigh each value once.
+func checkWeights(weights []int64, n int) (int64, error) {
+	if weights == nil {
+		return int64(n), nil
+	}
+	if len(weights) != n {
+		return 0, ErrInvalidWeights
+	}
+	var sum int64
+	for _, w := range weights {
+		if w < 0 || sum > math.MaxInt64-w {
+			return 0, ErrInvalidWeights
+		}
+		sum += w
+	}
+	return sum, nil
+}
+
+// histogram returns the weight of values in each of the buckets delimited by
+// bounds.
+func histogram(values, weights, bounds []int64) ([]int64, error) {
+	if _, err := checkWeights(weights, len(values)); err != nil {
+		return nil, err
+	}
+	for i := 1; i < len(bounds); i++ {
+		if bounds[i] <= bounds[i-1] {
+			return nil, ErrInvalidBuckets
+		}
+	}
+	counts := make([]int64, len(bounds)+1)
+	for i, v := range values {
+		// The bucket of a value is the number of bounds not above it.
+		b := sort.Search(len(bounds), func(j int) bool {
+			return bounds[j] > v
+		})
+		if weights == nil {
+			counts[b]++
+		} else {
+			counts[b] += weights[i]
+		}
+	}
+	return counts, nil
+}
+
+// weightedAverage returns the average of values weighed by weights, rounded
+// according to mode.
+func weightedAverage(values, weights []int64, mode RoundingMode) (int64, error) {
+	total, err := checkWeights(weights, len(values))
+	if err != nil {
+		return 0, err
+	}
+	if total == 0 {
+		return 0, ErrNoValues
+	}
+	var sum, term big.Int
+	for i, v := range values {
+		term.SetInt64(v)
+		if weights != nil {
+			term.Mul(&term, big.NewInt(weights[i]))
+		}
+		sum.Add(&sum, &term)
+	}
+	avg := roundRat(new(big.Rat).SetFrac(&sum, big.NewInt(total)), mode)
+	if !avg.IsInt64() {
+		return 0, ErrAmountOverflow
+	}
+	return avg.Int64(), nil
+}
+
+// amountsToInt64s returns amounts as int64 values.
+func amountsToInt64s(amounts []Amount) []int64 {
+	values := make([]int64, len(amounts))
+	for i, a := range amounts {
+		values[i] = int64(a)
+	}
+	return values
+}
+
+// feeRatesToInt64s returns rates as int64 values.
+func feeRatesToInt64s(rates []FeeRate) []int64 {
+	values := make([]int64, len(rates))
+	for i, r := range rates {
+		values[i] = int64(r)
+	}
+	return values
+}
+
+// AmountPercentile returns the nearest rank percentile of amounts: the
+// smallest amount such that at least percent of amounts are no larger.  The
+// zeroth percentile is the smallest amount, the 50th the lower median and the
+// 100th the largest.  The amounts are not modified.
+func AmountPercentile(amounts []Amount, percent int) (Amount, error) {
+	v, err := percentile(amountsToInt64s(amounts), percent)
+	return Amount(v), err
+}
+
+// FeeRatePercentile returns the nearest rank percentile of rates, as
+// AmountPercentile does of amounts.
+func FeeRatePercentile(rates []FeeRate, percent int) (FeeRate, error) {
+	v, err := percentile(feeRatesToInt64s(rates), percent)
+	return FeeRate(v), err
+}
+
+// AmountHistogram returns the total weight of amounts in each of the buckets
+// delimited by bounds, which must be strictly increasing.  Bucket 0 holds
+// amounts below bounds[0], bucket i amounts from bounds[i-1] up to but
+// excluding bounds[i], and the last bucket amounts of at least the last
+// bound, so there is one more bucket than bounds.  Nil weights count each
+// amount once.
+func AmountHistogram(amounts []Amount, weights []int64,
+	bounds []Amount) ([]int64, error) {
+	return histogram(amountsToInt64s(amounts), weights,
+		amountsToInt64s(bounds))
+}
+
+// FeeRateHistogram returns the total weight of rates in each of the buckets
+// delimited by bounds, as AmountHistogram does of amounts.  Weighing each
+// rate by the virtual size of its transaction gives the fee rate histogram
+// of a mempool.
+func FeeRateHistogram(rates []FeeRate, weights []int64,
+	bounds []FeeRate) ([]int64, error) {
+	return histogram(feeRatesToInt64s(rates), weights,
+		feeRatesToInt64s(bounds))
+}
+
+// WeightedAverageAmount returns the average of amounts weighed by weights,
+// computed exactly and rounded according to mode.  Nil weights weigh each
+// amount once.  ErrNoValues is returned when the weights sum to zero.
+func WeightedAverageAmount(amounts []Amount, weights []int64,
+	mode RoundingMode) (Amount, error) {
+	v, err := weightedAverage(amountsToInt64s(amounts), weights, mode)
+	return Amount(v), err
+}
+
+// WeightedAverageFeeRate returns the average of rates weighed by weights, as
+// WeightedAverageAmount does of amounts.  Weighing each rate by the virtual
+// size of its transaction gives the fee rate of the transactions together,
+// exactly rather than rounded down as NewFeeRateFromFee does.
+func WeightedAverageFeeRate(rates []FeeRate, weights []int64,
+	mode RoundingMode) (FeeRate, error) {
+	v, err := weightedAverage(feeRatesToInt64s(rates), weights, mode)
+	return FeeRate(v), err
+}
@@ -0,0 +1,150 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package btcutil_test
+
+import (
+	"math"
+	"reflect"
+	"testing"
+
+	"github.com/zeusyf/btcutil"
+)
+
+// TestPercentile ensures percentiles are the nearest rank values of their
+// inputs, which are left unmodified.
+func TestPercentile(t *testing.T) {
+	amounts := []btcutil.Amount{50, 10, 40, 20, 30}
+	tests := []struct {
+		percent int
+		want    btcutil.Amount
+	}{
+		{0, 10},
+		{1, 10},
+		{20, 10},
+		{21, 20},
+		{50, 30},
+		{90, 50},
+		{100, 50},
+	}
+	for _, test := range tests {
+		got, err := btcutil.AmountPercentile(amounts, test.percent)
+		if err != nil || got != test.want {
+			t.Errorf("AmountPercentile(%d): got %v (%v), want %v",
+				test.percent, got, err, test.want)
+		}
+	}
+	if amounts[0] != 50 || amounts[4] != 30 {
+		t.Errorf("AmountPercentile: input was modified: %v", amounts)
+	}
+
+	rates := []btcutil.FeeRate{3000, 1000, 2000, 4000}
+	if got, err := btcutil.FeeRatePercentile(rates, 50); err != nil || got != 2000 {
+		t.Errorf("FeeRatePercentile: got %v (%v), want 2000", got, err)
+	}
+
+	if _, err := btcutil.AmountPercentile(nil, 50); err != btcutil.ErrNoValues {
+		t.Errorf("AmountPercentile: got error %v, want %v", err,
+			btcutil.ErrNoValues)
+	}
+	for _, percent := range []int{-1, 101} {
+		_, err := btcutil.FeeRatePercentile(rates, percent)
+		if err != btcutil.ErrInvalidPercentile {
+			t.Errorf("FeeRatePercentile(%d): got error %v, want %v",
+				percent, err, btcutil.ErrInvalidPercentile)
+		}
+	}
+}
+
+// TestHistogram ensures values are counted in the buckets delimited by the
+// bounds, with their weights when given.
+func TestHistogram(t *testing.T) {
+	amounts := []btcutil.Amount{0, 5, 10, 15, 20, 100}
+	bounds := []btcutil.Amount{10, 20}
+	got, err := btcutil.AmountHistogram(amounts, nil, bounds)
+	if err != nil {
+		t.Fatalf("AmountHistogram: unexpected error: %v", err)
+	}
+	if want := []int64{2, 2, 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("AmountHistogram: got %v, want %v", got, want)
+	}
+
+	rates := []btcutil.FeeRate{1000, 1500, 5000}
+	vsizes := []int64{200, 150, 400}
+	got, err = btcutil.FeeRateHistogram(rates, vsizes,
+		[]btcutil.FeeRate{2000})
+	if err != nil {
+		t.Fatalf("FeeRateHistogram: unexpected error: %v", err)
+	}
+	if want := []int64{350, 400}; !reflect.DeepEqual(got, want) {
+		t.Errorf("FeeRateHistogram: got %v, want %v", got, want)
+	}
+
+	got, err = btcutil.AmountHistogram(nil, nil, nil)
+	if err != nil || !reflect.DeepEqual(got, []int64{0}) {
+		t.Errorf("AmountHistogram: got %v (%v), want [0]", got, err)
+	}
+
+	badBounds := [][]btcutil.Amount{{10, 10}, {20, 10}}
+	for _, b := range badBounds {
+		_, err := btcutil.AmountHistogram(amounts, nil, b)
+		if err != btcutil.ErrInvalidBuckets {
+			t.Errorf("AmountHistogram(%v): got error %v, want %v", b,
+				err, btcutil.ErrInvalidBuckets)
+		}
+	}
+	badWeights := [][]int64{{1, 2}, {1, -1, 1}, {math.MaxInt64, 1, 0}}
+	for _, w := range badWeights {
+		_, err := btcutil.FeeRateHistogram(rates, w, nil)
+		if err != btcutil.ErrInvalidWeights {
+			t.Errorf("FeeRateHistogram(%v): got error %v, want %v", w,
+				err, btcutil.ErrInvalidWeights)
+		}
+	}
+}
+
+// TestWeightedAverage ensures weighted averages are computed exactly and
+// rounded according to the requested mode.
+func TestWeightedAverage(t *testing.T) {
+	got, err := btcutil.WeightedAverageAmount([]btcutil.Amount{1, 2}, nil,
+		btcutil.RoundHalfEven)
+	if err != nil || got != 2 {
+		t.Errorf("WeightedAverageAmount: got %v (%v), want 2", got, err)
+	}
+	got, err = btcutil.WeightedAverageAmount([]btcutil.Amount{1, 2}, nil,
+		btcutil.RoundFloor)
+	if err != nil || got != 1 {
+		t.Errorf("WeightedAverageAmount: got %v (%v), want 1", got, err)
+	}
+
+	// Products overflowing an int64 are still computed exactly.
+	huge := []btcutil.Amount{math.MaxInt64, math.MaxInt64 - 2}
+	got, err = btcutil.WeightedAverageAmount(huge, []int64{1 << 40, 1 << 40},
+		btcutil.RoundFloor)
+	if err != nil || got != math.MaxInt64-1 {
+		t.Errorf("WeightedAverageAmount: got %v (%v), want %v", got, err,
+			btcutil.Amount(math.MaxInt64-1))
+	}
+
+	rates := []btcutil.FeeRate{1000, 4000}
+	vsizes := []int64{300, 100}
+	rate, err := btcutil.WeightedAverageFeeRate(rates, vsizes,
+		btcutil.RoundHalfAwayFromZero)
+	if err != nil || rate != 1750 {
+		t.Errorf("WeightedAverageFeeRate: got %v (%v), want 1750", rate, err)
+	}
+
+	_, err = btcutil.WeightedAverageFeeRate(rates, []int64{0, 0},
+		btcutil.RoundFloor)
+	if err != btcutil.ErrNoValues {
+		t.Errorf("WeightedAverageFeeRate: got error %v, want %v", err,
+			btcutil.ErrNoValues)
+	}
+	_, err = btcutil.WeightedAverageFeeRate(rates, []int64{1},
+		btcutil.RoundFloor)
+	if err != btcutil.ErrInvalidWeights {
+		t.Errorf("WeightedAverageFeeRate: got error %v, want %v", err,
+			btcutil.ErrInvalidWeights)
+	}
+}
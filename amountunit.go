@@ -0,0 +1,115 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package btcutil
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"unicode"
+)
+
+var (
+	// ErrDuplicateUnit describes an error where an amount unit being
+	// registered already has a label, or its symbol is the label of
+	// another unit.
+	ErrDuplicateUnit = errors.New("duplicate amount unit")
+
+	// ErrInvalidUnit describes an error where an amount unit being
+	// registered has no symbol, or a symbol holding white space.
+	ErrInvalidUnit = errors.New("amount unit symbol must be a single word")
+)
+
+// UnitInfo describes how amounts are displayed in a registered unit.
+type UnitInfo struct {
+	// Symbol is the label of the unit, returned by AmountUnit.String and
+	// accepted by ParseAmount, such as "bits" or the ticker of a token.
+	Symbol string
+
+	// Precision is the number of fractional digits written by Format, or
+	// -1 for the fewest digits representing the amount exactly.
+	Precision int
+}
+
+// unitRegistry holds the registered units.  Like the network registry it is
+// copy-on-write: the stored map is never modified, and writers serialize on
+// unitMtx and store a modified copy.
+var (
+	unitMtx      sync.Mutex
+	unitRegistry atomic.Value // map[AmountUnit]UnitInfo
+)
+
+// registeredUnit returns the information of the unit when it is registered.
+func registeredUnit(u AmountUnit) (UnitInfo, bool) {
+	units, _ := unitRegistry.Load().(map[AmountUnit]UnitInfo)
+	info, ok := units[u]
+	return info, ok
+}
+
+// AmountUnitEntry is a registered amount unit and its information.
+type AmountUnitEntry struct {
+	Unit AmountUnit
+	Info UnitInfo
+}
+
+// RegisteredAmountUnits returns every unit registered with
+// RegisterAmountUnit, sorted from the smallest unit to the largest.  The
+// result is a snapshot of the registry taken at the time of the call; units
+// registered concurrently or later are not included.
+func RegisteredAmountUnits() []AmountUnitEntry {
+	units, _ := unitRegistry.Load().(map[AmountUnit]UnitInfo)
+	entries := make([]AmountUnitEntry, 0, len(units))
+	for unit, info := range units {
+		entries = append(entries, AmountUnitEntry{unit, info})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Unit < entries[j].Unit
+	})
+	return entries
+}
+
+// RegisterAmountUnit gives the unit a label and display precision, so Format
+// and String use them rather than the "1eN OMC" form of unrecognized units,
+// and ParseAmount accepts amounts labeled with the symbol.  The units with a
+// dedicated label, such as AmountMilliOMC, can not be registered again.
+//
+// ErrInvalidUnit is returned when the symbol is empty or holds white space,
+// and ErrDuplicateUnit when the unit already has a label or the symbol is the
+// label, or a unit word accepted by SanitizeAmount, of another unit.
+func RegisterAmountUnit(u AmountUnit, info UnitInfo) error {
+	if info.Symbol == "" || strings.IndexFunc(info.Symbol, unicode.IsSpace) >= 0 {
+		return ErrInvalidUnit
+	}
+	if _, ok := unitAliases[strings.ToLower(info.Symbol)]; ok {
+		return ErrDuplicateUnit
+	}
+	for _, known := range knownUnits {
+		if u == known || info.Symbol == known.String() {
+			return ErrDuplicateUnit
+		}
+	}
+
+	unitMtx.Lock()
+	defer unitMtx.Unlock()
+
+	old, _ := unitRegistry.Load().(map[AmountUnit]UnitInfo)
+	if _, ok := old[u]; ok {
+		return ErrDuplicateUnit
+	}
+	for _, existing := range old {
+		if existing.Symbol == info.Symbol {
+			return ErrDuplicateUnit
+		}
+	}
+	units := make(map[AmountUnit]UnitInfo, len(old)+1)
+	for k, v := range old {
+		units[k] = v
+	}
+	units[u] = info
+	unitRegistry.Store(units)
+	return nil
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package btcutil_test
+
+import (
+	"testing"
+
+	. "github.com/zeusyf/btcutil"
+)
+
+// TestRegisterAmountUnit ensures registered units are formatted with their
+// symbol and precision and parsed back, and conflicting units are rejected.
+func TestRegisterAmountUnit(t *testing.T) {
+	centi, dots := AmountUnit(-2), AmountUnit(-5)
+	if err := RegisterAmountUnit(centi, UnitInfo{Symbol: "cOMC", Precision: 2}); err != nil {
+		t.Fatalf("RegisterAmountUnit: unexpected error: %v", err)
+	}
+	if err := RegisterAmountUnit(dots, UnitInfo{Symbol: "dots", Precision: -1}); err != nil {
+		t.Fatalf("RegisterAmountUnit: unexpected error: %v", err)
+	}
+
+	if got := centi.String(); got != "cOMC" {
+		t.Errorf("String: got %q, want %q", got, "cOMC")
+	}
+	formats := []struct {
+		amount Amount
+		unit   AmountUnit
+		want   string
+	}{
+		{123456789, centi, "123.46 cOMC"},
+		{-1000000, centi, "-1.00 cOMC"},
+		{123456789, dots, "123456.789 dots"},
+		{1000, dots, "1 dots"},
+	}
+	for _, test := range formats {
+		if got := test.amount.Format(test.unit); got != test.want {
+			t.Errorf("Format(%d, %v): got %q, want %q", test.amount,
+				test.unit, got, test.want)
+		}
+	}
+	opts := FormatOptions{Unit: centi}
+	if got := Amount(123456789).FormatWithOptions(opts); got != "123.456789 cOMC" {
+		t.Errorf("FormatWithOptions: got %q, want %q", got,
+			"123.456789 cOMC")
+	}
+
+	parses := []struct {
+		s    string
+		want Amount
+	}{
+		{"1.5 cOMC", 1500000},
+		{"123456.789 dots", 123456789},
+	}
+	for _, test := range parses {
+		a, err := ParseAmount(test.s)
+		if err != nil || a != test.want {
+			t.Errorf("ParseAmount(%q): got %d, %v, want %d", test.s, a,
+				err, test.want)
+		}
+	}
+
+	errs := []struct {
+		name string
+		unit AmountUnit
+		info UnitInfo
+		err  error
+	}{
+		{"registered unit", centi, UnitInfo{Symbol: "centi"}, ErrDuplicateUnit},
+		{"registered symbol", -9, UnitInfo{Symbol: "dots"}, ErrDuplicateUnit},
+		{"built-in unit", AmountKiloOMC, UnitInfo{Symbol: "grand"}, ErrDuplicateUnit},
+		{"built-in symbol", -9, UnitInfo{Symbol: "Hao"}, ErrDuplicateUnit},
+		{"alias symbol", -9, UnitInfo{Symbol: "HAOS"}, ErrDuplicateUnit},
+		{"empty symbol", -9, UnitInfo{}, ErrInvalidUnit},
+		{"spaced symbol", -9, UnitInfo{Symbol: "deci Hao"}, ErrInvalidUnit},
+	}
+	for _, test := range errs {
+		if err := RegisterAmountUnit(test.unit, test.info); err != test.err {
+			t.Errorf("RegisterAmountUnit(%s): got error %v, want %v",
+				test.name, err, test.err)
+		}
+	}
+	if got := AmountUnit(-9).String(); got != "1e-9 OMC" {
+		t.Errorf("String: got %q, want %q", got, "1e-9 OMC")
+	}
+
+	// Registered units are listed from the smallest one.
+	var listed []AmountUnit
+	for _, entry := range RegisteredAmountUnits() {
+		if entry.Unit == centi || entry.Unit == dots {
+			listed = append(listed, entry.Unit)
+		}
+	}
+	if len(listed) != 2 || listed[0] != dots || listed[1] != centi {
+		t.Errorf("RegisteredAmountUnits: got %v, want [%v %v]", listed,
+			dots, centi)
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package analysis
+
+import (
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/signing"
+)
+
+// Analyzer clusters the addresses involved in transactions by the heuristics
+// of the package.  The outputs spent by the transactions are looked up with a
+// PrevOutFetcher, such as one backed by the transaction index of a node.  An
+// Analyzer is not safe for concurrent use.
+type Analyzer struct {
+	net      *chaincfg.Params
+	fetcher  signing.PrevOutFetcher
+	cfg      config
+	clusters *Clusters
+}
+
+// NewAnalyzer returns an analyzer of transactions of the network net whose
+// spent outputs are looked up with fetcher.
+func NewAnalyzer(net *chaincfg.Params, fetcher signing.PrevOutFetcher,
+	opts ...Option) *Analyzer {
+	return &Analyzer{
+		net:      net,
+		fetcher:  fetcher,
+		cfg:      newConfig(opts),
+		clusters: NewClusters(),
+	}
+}
+
+// AddTx adds the addresses involved in tx to the clusters, linking the
+// addresses of its inputs by common input ownership and its change output to
+// them.  Addresses which are not linked to any other form clusters of their
+// own.  ErrMissingPrevOut is returned, and the clusters left unchanged, when
+// the fetcher does not know an output spent by tx.
+func (a *Analyzer) AddTx(tx *btcutil.Tx) error {
+	owned, err := commonInputOwnership(tx, a.fetcher, a.net, a.cfg)
+	if err != nil {
+		return err
+	}
+	var change *Change
+	if !a.cfg.noChange {
+		change, err = detectChange(tx, a.fetcher, a.net, a.cfg)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Spent outputs are known from here on, as the heuristics succeeded.
+	msgTx := tx.MsgTx()
+	if !tx.IsCoinBase() {
+		for _, in := range msgTx.TxIn {
+			prevOut := a.fetcher.FetchPrevOutput(in.PreviousOutPoint)
+			if addr := extract(prevOut, a.net).addr; addr != nil {
+				a.clusters.Add(addr)
+			}
+		}
+	}
+	for _, txOut := range msgTx.TxOut {
+		if addr := extract(txOut, a.net).addr; addr != nil {
+			a.clusters.Add(addr)
+		}
+	}
+
+	a.clusters.Link(owned...)
+	if change != nil && len(owned) != 0 {
+		a.clusters.Link(owned[0], change.Address)
+	}
+	return nil
+}
+
+// Cluster returns the ID of the cluster of addr and true, or false when addr
+// is not involved in any added transaction.
+func (a *Analyzer) Cluster(addr btcutil.Address) (ClusterID, bool) {
+	return a.clusters.Cluster(addr)
+}
+
+// Clusters returns the clusters of the analyzer, which change as
+// transactions are added.
+func (a *Analyzer) Clusters() *Clusters {
+	return a.clusters
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package analysis_test
+
+import (
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/analysis"
+	"github.com/zeusyf/btcutil/signing"
+)
+
+// TestAnalyzer ensures transactions link their inputs and change into
+// clusters, and leave payees in clusters of their own.
+func TestAnalyzer(t *testing.T) {
+	a, b, c, d, e := pkhAddr(1), pkhAddr(2), shAddr(3), pkhAddr(4), pkhAddr(5)
+
+	// A pays c from a and b, and receives its change at d, singled out by
+	// its script type and unround amount.
+	tx1, fetcher1 := testTx(t, []btcutil.Address{a, b},
+		[]payment{{c, 1e8}, {d, 123456}})
+	// The owner of d spends it along with e.
+	tx2, fetcher2 := testTx(t, []btcutil.Address{d, e},
+		[]payment{{pkhAddr(6), 3e8}})
+	fetcher := signing.NewMultiPrevOutFetcher(fetcher1, fetcher2)
+
+	for _, opts := range [][]analysis.Option{nil, {analysis.WithoutChangeDetection()}} {
+		an := analysis.NewAnalyzer(&chaincfg.MainNetParams, fetcher, opts...)
+		for _, tx := range []*btcutil.Tx{tx1, tx2} {
+			if err := an.AddTx(tx); err != nil {
+				t.Fatalf("AddTx: unexpected error: %v", err)
+			}
+		}
+
+		owner, _ := an.Cluster(a)
+		linked := map[btcutil.Address]bool{a: true, b: true, c: false,
+			d: opts == nil, e: opts == nil}
+		for addr, want := range linked {
+			id, ok := an.Cluster(addr)
+			if !ok {
+				t.Fatalf("address %v not found", addr)
+			}
+			if got := id == owner; got != want {
+				t.Errorf("%d options: %v linked to a %v, want %v",
+					len(opts), addr, got, want)
+			}
+		}
+		if mustCluster(t, an, d) != mustCluster(t, an, e) {
+			t.Errorf("%d options: d and e not linked", len(opts))
+		}
+		want := 3
+		if opts != nil {
+			want = 4
+		}
+		if n := an.Clusters().Len(); n != want {
+			t.Errorf("%d options: got %d clusters, want %d", len(opts), n,
+				want)
+		}
+	}
+
+	an := analysis.NewAnalyzer(&chaincfg.MainNetParams, fetcher1)
+	if err := an.AddTx(tx2); err != analysis.ErrMissingPrevOut {
+		t.Errorf("AddTx: got error %v, want %v", err,
+			analysis.ErrMissingPrevOut)
+	}
+	if n := an.Clusters().Len(); n != 0 {
+		t.Errorf("AddTx: failed transaction added %d clusters", n)
+	}
+}
+
+// mustCluster returns the ID of the cluster of addr.
+func mustCluster(t *testing.T, an *analysis.Analyzer,
+	addr btcutil.Address) analysis.ClusterID {
+	t.Helper()
+	id, ok := an.Cluster(addr)
+	if !ok {
+		t.Fatalf("address %v not found", addr)
+	}
+	return id
+}
@@ -0,0 +1,152 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package analysis groups the addresses of the chain into clusters of
+// addresses likely controlled by the same entity, for compliance and
+// analytics tools.
+//
+// Clusters are built by heuristics linking the addresses a transaction
+// involves:
+//
+//   - common input ownership: the addresses of all inputs of a transaction
+//     belong to the entity which signed them, with the exception of
+//     transactions looking like CoinJoins, which several entities fund
+//   - change detection: the output of a payment returning its change is paid
+//     to an address of the payer, which is recognized when it is the only
+//     output of the script type of the inputs, or the only output whose
+//     amount is not round
+//
+// Both heuristics are heuristics: a cluster is evidence, not proof, of common
+// ownership.
+//
+// Cluster IDs are deterministic: the ID of a cluster is the smallest encoded
+// address it holds, so the same transactions yield the same IDs whatever the
+// order they are added in.  As clusters merge when transactions link them,
+// the ID of an address may change as transactions are added.
+package analysis
+
+import (
+	"sort"
+
+	"github.com/zeusyf/btcutil"
+)
+
+// ClusterID identifies a cluster of addresses.  It is the smallest encoded
+// address of the cluster.
+type ClusterID string
+
+// Clusters is a partition of addresses into clusters, merged by Link.  The
+// zero value is not usable; Clusters must be created with NewClusters.  It is
+// not safe for concurrent use.
+type Clusters struct {
+	// parent maps each encoded address to its parent in a tree of the
+	// addresses of its cluster, whose root maps to itself.
+	parent map[string]string
+
+	// ids and members map the root of each cluster to its ID and its
+	// addresses.
+	ids     map[string]ClusterID
+	members map[string][]string
+}
+
+// NewClusters returns an empty partition.
+func NewClusters() *Clusters {
+	return &Clusters{
+		parent:  make(map[string]string),
+		ids:     make(map[string]ClusterID),
+		members: make(map[string][]string),
+	}
+}
+
+// root returns the root of the cluster of the encoded address addr, adding
+// addr as a cluster of its own when it is unknown.
+func (c *Clusters) root(addr string) string {
+	parent, ok := c.parent[addr]
+	if !ok {
+		c.parent[addr] = addr
+		c.ids[addr] = ClusterID(addr)
+		c.members[addr] = []string{addr}
+		return addr
+	}
+	if parent == addr {
+		return addr
+	}
+	root := c.root(parent)
+	c.parent[addr] = root
+	return root
+}
+
+// Add adds addr as a cluster of its own unless it is known, and returns the
+// ID of its cluster.
+func (c *Clusters) Add(addr btcutil.Address) ClusterID {
+	return c.ids[c.root(addr.EncodeAddress())]
+}
+
+// Link merges the clusters of the passed addresses, adding the unknown ones,
+// and returns the ID of the merged cluster.  Link returns the empty ID when
+// no address is passed.
+func (c *Clusters) Link(addrs ...btcutil.Address) ClusterID {
+	if len(addrs) == 0 {
+		return ""
+	}
+	root := c.root(addrs[0].EncodeAddress())
+	for _, addr := range addrs[1:] {
+		other := c.root(addr.EncodeAddress())
+		if other == root {
+			continue
+		}
+		// Hang the smaller cluster below the larger one.
+		if len(c.members[other]) > len(c.members[root]) {
+			root, other = other, root
+		}
+		c.parent[other] = root
+		c.members[root] = append(c.members[root], c.members[other]...)
+		if c.ids[other] < c.ids[root] {
+			c.ids[root] = c.ids[other]
+		}
+		delete(c.members, other)
+		delete(c.ids, other)
+	}
+	return c.ids[root]
+}
+
+// Cluster returns the ID of the cluster of addr and true, or false when addr
+// is unknown.
+func (c *Clusters) Cluster(addr btcutil.Address) (ClusterID, bool) {
+	encoded := addr.EncodeAddress()
+	if _, ok := c.parent[encoded]; !ok {
+		return "", false
+	}
+	return c.ids[c.root(encoded)], true
+}
+
+// Members returns the encoded addresses of the cluster id in increasing
+// order, or nil when there is no such cluster.
+func (c *Clusters) Members(id ClusterID) []string {
+	if _, ok := c.parent[string(id)]; !ok {
+		return nil
+	}
+	root := c.root(string(id))
+	if c.ids[root] != id {
+		return nil
+	}
+	members := append([]string(nil), c.members[root]...)
+	sort.Strings(members)
+	return members
+}
+
+// IDs returns the IDs of all clusters in increasing order.
+func (c *Clusters) IDs() []ClusterID {
+	ids := make([]ClusterID, 0, len(c.ids))
+	for _, id := range c.ids {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// Len returns the number of clusters.
+func (c *Clusters) Len() int {
+	return len(c.ids)
+}
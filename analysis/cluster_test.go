@@ -0,0 +1,82 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package analysis_test
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/analysis"
+)
+
+// TestClusters ensures linked addresses share the ID of their smallest
+// address, whatever the order they are linked in.
+func TestClusters(t *testing.T) {
+	addrs := make([]btcutil.Address, 6)
+	for i := range addrs {
+		addrs[i] = pkhAddr(byte(i + 1))
+	}
+	links := [][]btcutil.Address{
+		{addrs[0], addrs[1]},
+		{addrs[2], addrs[3]},
+		{addrs[3], addrs[1]},
+		{addrs[4]},
+	}
+
+	var ids [][]analysis.ClusterID
+	for _, order := range [][]int{{0, 1, 2, 3}, {3, 2, 1, 0}, {1, 3, 0, 2}} {
+		c := analysis.NewClusters()
+		for _, i := range order {
+			c.Link(links[i]...)
+		}
+		if c.Len() != 2 {
+			t.Errorf("order %v: got %d clusters, want 2", order, c.Len())
+		}
+		ids = append(ids, c.IDs())
+
+		id, ok := c.Cluster(addrs[2])
+		if !ok {
+			t.Fatalf("order %v: address not found", order)
+		}
+		want := make([]string, 4)
+		for i := range want {
+			want[i] = addrs[i].EncodeAddress()
+		}
+		sort.Strings(want)
+		if string(id) != want[0] {
+			t.Errorf("order %v: got ID %v, want %v", order, id, want[0])
+		}
+		if got := c.Members(id); !reflect.DeepEqual(got, want) {
+			t.Errorf("order %v: got members %v, want %v", order, got,
+				want)
+		}
+		if _, ok := c.Cluster(addrs[5]); ok {
+			t.Errorf("order %v: unknown address found", order)
+		}
+	}
+	for _, got := range ids[1:] {
+		if !reflect.DeepEqual(got, ids[0]) {
+			t.Errorf("IDs depend on the order of links: %v and %v",
+				got, ids[0])
+		}
+	}
+
+	c := analysis.NewClusters()
+	if id := c.Link(); id != "" {
+		t.Errorf("Link: got ID %v for no addresses", id)
+	}
+	id := c.Add(addrs[5])
+	if id != analysis.ClusterID(addrs[5].EncodeAddress()) || c.Len() != 1 {
+		t.Errorf("Add: got ID %v", id)
+	}
+	if c.Add(addrs[5]) != id || c.Len() != 1 {
+		t.Errorf("Add: adding again changed the clusters")
+	}
+	if got := c.Members("unknown"); got != nil {
+		t.Errorf("Members: got %v for an unknown cluster", got)
+	}
+}
@@ -0,0 +1,352 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package analysis
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/scriptclass"
+	"github.com/zeusyf/btcutil/signing"
+	"github.com/zeusyf/omega/token"
+)
+
+// DefaultRoundUnit is the amount which the amounts of payments are deemed
+// round multiples of when no round unit is configured: a thousandth of an
+// OMC.
+const DefaultRoundUnit btcutil.Amount = btcutil.HaoPerOMC / 1000
+
+// ErrMissingPrevOut describes an error where the output spent by an input of
+// an analyzed transaction is unknown to the fetcher of spent outputs.
+var ErrMissingPrevOut = errors.New("spent output unknown")
+
+// ChangeReason is a set of heuristics identifying the change output of a
+// transaction.
+type ChangeReason uint8
+
+// These constants are the heuristics identifying change outputs.
+const (
+	// ChangeAddressReuse is set when the output is the only one paying to
+	// the address of an input.
+	ChangeAddressReuse ChangeReason = 1 << iota
+
+	// ChangeScriptType is set when the inputs all spend outputs of one
+	// script type and the output is the only one of that type.
+	ChangeScriptType
+
+	// ChangeUnroundAmount is set when the output is the only one whose
+	// amount of OMC is not a multiple of the round unit.
+	ChangeUnroundAmount
+)
+
+// Map of change reasons back to their constant names for pretty printing.
+var changeReasonStrings = []struct {
+	reason ChangeReason
+	name   string
+}{
+	{ChangeAddressReuse, "ChangeAddressReuse"},
+	{ChangeScriptType, "ChangeScriptType"},
+	{ChangeUnroundAmount, "ChangeUnroundAmount"},
+}
+
+// String returns the heuristics of the set as a human-readable list.
+func (r ChangeReason) String() string {
+	var names []string
+	for _, s := range changeReasonStrings {
+		if r&s.reason != 0 {
+			names = append(names, s.name)
+			r &^= s.reason
+		}
+	}
+	if r != 0 {
+		names = append(names, fmt.Sprintf("0x%x", uint8(r)))
+	}
+	if len(names) == 0 {
+		return "0"
+	}
+	return strings.Join(names, "|")
+}
+
+// Change is the change output of a transaction.
+type Change struct {
+	// Index is the index of the output.
+	Index int
+
+	// Address is the address the output pays.
+	Address btcutil.Address
+
+	// Reasons holds the heuristics identifying the output.
+	Reasons ChangeReason
+}
+
+// Option configures the heuristics.
+type Option func(*config)
+
+// WithRoundUnit sets the amount which the amounts of payments are deemed
+// round multiples of.  DefaultRoundUnit is used by default.
+func WithRoundUnit(unit btcutil.Amount) Option {
+	return func(c *config) {
+		c.roundUnit = unit
+	}
+}
+
+// WithCoinJoins disables the exception of transactions looking like CoinJoins
+// from the heuristics, for chains or periods where none are expected.
+func WithCoinJoins() Option {
+	return func(c *config) {
+		c.coinJoins = true
+	}
+}
+
+// WithoutChangeDetection disables the linking of change outputs to the inputs
+// of their transaction by an Analyzer, which then only links addresses by
+// common input ownership.
+func WithoutChangeDetection() Option {
+	return func(c *config) {
+		c.noChange = true
+	}
+}
+
+// config holds the configuration of the heuristics.
+type config struct {
+	roundUnit btcutil.Amount
+	coinJoins bool
+	noChange  bool
+}
+
+// newConfig returns the configuration set by opts.
+func newConfig(opts []Option) config {
+	c := config{roundUnit: DefaultRoundUnit}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	if c.roundUnit < 1 {
+		c.roundUnit = DefaultRoundUnit
+	}
+	return c
+}
+
+// scriptInfo is the script type and the address of an output.  The address
+// is nil for outputs which do not pay to exactly one address.
+type scriptInfo struct {
+	class scriptclass.Class
+	addr  btcutil.Address
+}
+
+// extract returns the script type and address of txOut on the network net.
+// Outputs of multi-signature scripts listing keys, which several entities may
+// hold, have no address.
+func extract(txOut *wire.TxOut, net *chaincfg.Params) scriptInfo {
+	class, addrs, _, err := scriptclass.ExtractPkScriptAddrs(txOut.PkScript, net)
+	if err != nil || len(addrs) != 1 {
+		return scriptInfo{class: class}
+	}
+	return scriptInfo{class: class, addr: addrs[0]}
+}
+
+// omcAmount returns the amount of OMC of txOut, or false when it holds
+// another token.
+func omcAmount(txOut *wire.TxOut) (btcutil.Amount, bool) {
+	value, ok := txOut.Token.Value.(*token.NumeralVal)
+	if !ok || txOut.Token.TokenType != 0 {
+		return 0, false
+	}
+	return btcutil.Amount(value.Val), true
+}
+
+// spentScripts returns the script types and addresses of the outputs spent
+// by the inputs of tx, or nil for coinbase transactions.
+func spentScripts(tx *btcutil.Tx, fetcher signing.PrevOutFetcher,
+	net *chaincfg.Params) ([]scriptInfo, error) {
+	if tx.IsCoinBase() {
+		return nil, nil
+	}
+	msgTx := tx.MsgTx()
+	infos := make([]scriptInfo, len(msgTx.TxIn))
+	for i, in := range msgTx.TxIn {
+		prevOut := fetcher.FetchPrevOutput(in.PreviousOutPoint)
+		if prevOut == nil {
+			return nil, ErrMissingPrevOut
+		}
+		infos[i] = extract(prevOut, net)
+	}
+	return infos, nil
+}
+
+// IsCoinJoinLike returns whether tx looks like a CoinJoin, a transaction
+// funded by several entities each paying itself an output of the same amount
+// to hide which output is whose: it has several inputs, and at least half of
+// its outputs, and at least two, are of the same amount of OMC.
+func IsCoinJoinLike(tx *btcutil.Tx) bool {
+	msgTx := tx.MsgTx()
+	if len(msgTx.TxIn) < 2 {
+		return false
+	}
+	counts := make(map[btcutil.Amount]int)
+	for _, txOut := range msgTx.TxOut {
+		if amount, ok := omcAmount(txOut); ok && amount > 0 {
+			counts[amount]++
+		}
+	}
+	for _, n := range counts {
+		if n >= 2 && 2*n >= len(msgTx.TxOut) {
+			return true
+		}
+	}
+	return false
+}
+
+// CommonInputOwnership returns the distinct addresses paid by the outputs
+// spent by tx, in the order of its inputs, which the common input ownership
+// heuristic deems controlled by one entity.  Nil is returned for coinbase
+// transactions and, unless WithCoinJoins is passed, for transactions looking
+// like CoinJoins.  ErrMissingPrevOut is returned when fetcher does not know
+// an output spent by tx.
+func CommonInputOwnership(tx *btcutil.Tx, fetcher signing.PrevOutFetcher,
+	net *chaincfg.Params, opts ...Option) ([]btcutil.Address, error) {
+	return commonInputOwnership(tx, fetcher, net, newConfig(opts))
+}
+
+// commonInputOwnership implements CommonInputOwnership with the configuration
+// cfg.
+func commonInputOwnership(tx *btcutil.Tx, fetcher signing.PrevOutFetcher,
+	net *chaincfg.Params, cfg config) ([]btcutil.Address, error) {
+	inputs, err := spentScripts(tx, fetcher, net)
+	if err != nil || (!cfg.coinJoins && IsCoinJoinLike(tx)) {
+		return nil, err
+	}
+	return distinctAddrs(inputs), nil
+}
+
+// distinctAddrs returns the distinct addresses of infos in their order.
+func distinctAddrs(infos []scriptInfo) []btcutil.Address {
+	var addrs []btcutil.Address
+	seen := make(map[string]bool)
+	for _, info := range infos {
+		if info.addr == nil || seen[info.addr.EncodeAddress()] {
+			continue
+		}
+		seen[info.addr.EncodeAddress()] = true
+		addrs = append(addrs, info.addr)
+	}
+	return addrs
+}
+
+// DetectChange returns the change output of tx, or nil when no output is
+// identified as change.  An output is change when it is singled out by at
+// least one of the heuristics of ChangeReason and no heuristic singles out
+// another output.  Coinbase transactions, transactions of a single output,
+// and, unless WithCoinJoins is passed, transactions looking like CoinJoins
+// have no change.  ErrMissingPrevOut is returned when fetcher does not know an
+// output spent by tx.
+func DetectChange(tx *btcutil.Tx, fetcher signing.PrevOutFetcher,
+	net *chaincfg.Params, opts ...Option) (*Change, error) {
+	return detectChange(tx, fetcher, net, newConfig(opts))
+}
+
+// detectChange implements DetectChange with the configuration cfg.
+func detectChange(tx *btcutil.Tx, fetcher signing.PrevOutFetcher,
+	net *chaincfg.Params, cfg config) (*Change, error) {
+	inputs, err := spentScripts(tx, fetcher, net)
+	if err != nil {
+		return nil, err
+	}
+	msgTx := tx.MsgTx()
+	if len(inputs) == 0 || len(msgTx.TxOut) < 2 ||
+		(!cfg.coinJoins && IsCoinJoinLike(tx)) {
+		return nil, nil
+	}
+	outputs := make([]scriptInfo, len(msgTx.TxOut))
+	for i, txOut := range msgTx.TxOut {
+		outputs[i] = extract(txOut, net)
+	}
+
+	candidates := []struct {
+		reason ChangeReason
+		index  int
+	}{
+		{ChangeAddressReuse, reusedAddress(inputs, outputs)},
+		{ChangeScriptType, sameScriptType(inputs, outputs)},
+		{ChangeUnroundAmount, unroundAmount(msgTx.TxOut, cfg.roundUnit)},
+	}
+	change := Change{Index: -1}
+	for _, c := range candidates {
+		switch {
+		case c.index < 0:
+			continue
+		case change.Index >= 0 && c.index != change.Index:
+			// The heuristics disagree.
+			return nil, nil
+		}
+		change.Index = c.index
+		change.Reasons |= c.reason
+	}
+	if change.Index < 0 || outputs[change.Index].addr == nil {
+		return nil, nil
+	}
+	change.Address = outputs[change.Index].addr
+	return &change, nil
+}
+
+// single returns the only index for which match is true, or -1 when there is
+// none or more than one.
+func single(n int, match func(i int) bool) int {
+	index := -1
+	for i := 0; i < n; i++ {
+		if !match(i) {
+			continue
+		}
+		if index >= 0 {
+			return -1
+		}
+		index = i
+	}
+	return index
+}
+
+// reusedAddress returns the index of the only output paying to the address of
+// an input, or -1.
+func reusedAddress(inputs, outputs []scriptInfo) int {
+	spent := make(map[string]bool)
+	for _, addr := range distinctAddrs(inputs) {
+		spent[addr.EncodeAddress()] = true
+	}
+	return single(len(outputs), func(i int) bool {
+		addr := outputs[i].addr
+		return addr != nil && spent[addr.EncodeAddress()]
+	})
+}
+
+// sameScriptType returns the index of the only output of the script type of
+// all inputs, or -1 when the inputs are of several types.
+func sameScriptType(inputs, outputs []scriptInfo) int {
+	class := inputs[0].class
+	for _, in := range inputs[1:] {
+		if in.class != class {
+			return -1
+		}
+	}
+	return single(len(outputs), func(i int) bool {
+		return outputs[i].class == class
+	})
+}
+
+// unroundAmount returns the index of the only output whose amount of OMC is
+// not a multiple of unit, or -1.  All outputs must hold OMC.
+func unroundAmount(txOuts []*wire.TxOut, unit btcutil.Amount) int {
+	for _, txOut := range txOuts {
+		if _, ok := omcAmount(txOut); !ok {
+			return -1
+		}
+	}
+	return single(len(txOuts), func(i int) bool {
+		amount, _ := omcAmount(txOuts[i])
+		return amount%unit != 0
+	})
+}
@@ -0,0 +1,209 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package analysis_test
+
+import (
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/analysis"
+	"github.com/zeusyf/btcutil/scriptclass"
+	"github.com/zeusyf/btcutil/signing"
+	"github.com/zeusyf/omega/token"
+)
+
+// pkhAddr returns a pay-to-pubkey-hash address of a hash filled with b.
+func pkhAddr(b byte) btcutil.Address {
+	hash := make([]byte, 20)
+	for i := range hash {
+		hash[i] = b
+	}
+	addr, _ := btcutil.NewAddressPubKeyHash(hash, &chaincfg.MainNetParams)
+	return addr
+}
+
+// shAddr returns a pay-to-script-hash address of a hash filled with b.
+func shAddr(b byte) btcutil.Address {
+	hash := make([]byte, 20)
+	for i := range hash {
+		hash[i] = b
+	}
+	addr, _ := btcutil.NewAddressScriptHashFromHash(hash, &chaincfg.MainNetParams)
+	return addr
+}
+
+// payment is an output of a test transaction.
+type payment struct {
+	addr   btcutil.Address
+	amount int64
+}
+
+// testTx returns a transaction spending outputs paying the addresses of
+// inputs to the payments of outputs, and a fetcher of the spent outputs.
+// Each call spends distinct outpoints.
+func testTx(t *testing.T, inputs []btcutil.Address,
+	outputs []payment) (*btcutil.Tx, signing.MapPrevOutFetcher) {
+	t.Helper()
+	fetcher := make(signing.MapPrevOutFetcher)
+	tx := wire.NewMsgTx(wire.TxVersion)
+	for _, addr := range inputs {
+		op := wire.OutPoint{Hash: chainhash.Hash{byte(nextOutPoint)},
+			Index: nextOutPoint}
+		nextOutPoint++
+		tx.AddTxIn(&wire.TxIn{PreviousOutPoint: op, Sequence: wire.MaxTxInSequenceNum})
+		fetcher[op] = testOutput(t, payment{addr, 1e8})
+	}
+	for _, p := range outputs {
+		tx.AddTxOut(testOutput(t, p))
+	}
+	return btcutil.NewTx(tx), fetcher
+}
+
+// nextOutPoint is the index of the next outpoint spent by testTx.
+var nextOutPoint uint32 = 1
+
+// testOutput returns an output of the OMC amount of p paying its address.
+func testOutput(t *testing.T, p payment) *wire.TxOut {
+	pkScript, err := scriptclass.PayToAddrScript(p.addr)
+	if err != nil {
+		t.Fatalf("PayToAddrScript: unexpected error: %v", err)
+	}
+	return &wire.TxOut{
+		Token:    token.Token{Value: &token.NumeralVal{Val: p.amount}},
+		PkScript: pkScript,
+	}
+}
+
+// TestCommonInputOwnership ensures the distinct input addresses of ordinary
+// transactions are returned, and none for CoinJoins.
+func TestCommonInputOwnership(t *testing.T) {
+	a, b := pkhAddr(1), shAddr(2)
+	tx, fetcher := testTx(t, []btcutil.Address{a, b, a},
+		[]payment{{pkhAddr(3), 1e8}})
+	got, err := analysis.CommonInputOwnership(tx, fetcher,
+		&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("CommonInputOwnership: unexpected error: %v", err)
+	}
+	if len(got) != 2 || got[0].EncodeAddress() != a.EncodeAddress() ||
+		got[1].EncodeAddress() != b.EncodeAddress() {
+		t.Errorf("CommonInputOwnership: got %v, want %v", got,
+			[]btcutil.Address{a, b})
+	}
+
+	coinJoin, fetcher := testTx(t, []btcutil.Address{a, b},
+		[]payment{{pkhAddr(3), 5e7}, {pkhAddr(4), 5e7}, {pkhAddr(5), 1234}})
+	if !analysis.IsCoinJoinLike(coinJoin) {
+		t.Errorf("IsCoinJoinLike: got false for a CoinJoin")
+	}
+	got, err = analysis.CommonInputOwnership(coinJoin, fetcher,
+		&chaincfg.MainNetParams)
+	if err != nil || got != nil {
+		t.Errorf("CoinJoin: got %v, %v", got, err)
+	}
+	got, _ = analysis.CommonInputOwnership(coinJoin, fetcher,
+		&chaincfg.MainNetParams, analysis.WithCoinJoins())
+	if len(got) != 2 {
+		t.Errorf("WithCoinJoins: got %v", got)
+	}
+
+	delete(fetcher, coinJoin.MsgTx().TxIn[1].PreviousOutPoint)
+	_, err = analysis.CommonInputOwnership(coinJoin, fetcher,
+		&chaincfg.MainNetParams)
+	if err != analysis.ErrMissingPrevOut {
+		t.Errorf("missing output: got error %v, want %v", err,
+			analysis.ErrMissingPrevOut)
+	}
+}
+
+// TestDetectChange tests which outputs the change heuristics single out.
+func TestDetectChange(t *testing.T) {
+	a, b := pkhAddr(1), pkhAddr(2)
+	tests := []struct {
+		name    string
+		inputs  []btcutil.Address
+		outputs []payment
+		index   int
+		reasons analysis.ChangeReason
+	}{
+		{"unround amount", []btcutil.Address{a},
+			[]payment{{shAddr(3), 2e8}, {shAddr(4), 123456}},
+			1, analysis.ChangeUnroundAmount},
+		{"script type", []btcutil.Address{a, b},
+			[]payment{{pkhAddr(3), 1e8}, {shAddr(4), 5e7}},
+			0, analysis.ChangeScriptType},
+		{"agreeing heuristics", []btcutil.Address{a},
+			[]payment{{shAddr(3), 2e8}, {pkhAddr(4), 123456}},
+			1, analysis.ChangeScriptType | analysis.ChangeUnroundAmount},
+		{"address reuse", []btcutil.Address{a},
+			[]payment{{pkhAddr(3), 2e8}, {a, 3e8}},
+			1, analysis.ChangeAddressReuse},
+		{"disagreeing heuristics", []btcutil.Address{a},
+			[]payment{{pkhAddr(3), 2e8}, {shAddr(4), 123456}},
+			-1, 0},
+		{"no heuristic", []btcutil.Address{a},
+			[]payment{{pkhAddr(3), 2e8}, {pkhAddr(4), 1e8}},
+			-1, 0},
+		{"single output", []btcutil.Address{a},
+			[]payment{{pkhAddr(3), 123456}},
+			-1, 0},
+		{"coinjoin", []btcutil.Address{a, b},
+			[]payment{{shAddr(3), 1e8}, {shAddr(4), 1e8}, {pkhAddr(5), 1234}},
+			-1, 0},
+	}
+	for _, test := range tests {
+		tx, fetcher := testTx(t, test.inputs, test.outputs)
+		change, err := analysis.DetectChange(tx, fetcher,
+			&chaincfg.MainNetParams)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", test.name, err)
+		}
+		if test.index < 0 {
+			if change != nil {
+				t.Errorf("%s: got change %+v, want none", test.name,
+					change)
+			}
+			continue
+		}
+		if change == nil || change.Index != test.index ||
+			change.Reasons != test.reasons ||
+			change.Address.EncodeAddress() !=
+				test.outputs[test.index].addr.EncodeAddress() {
+			t.Errorf("%s: got change %+v, want output %d for %v",
+				test.name, change, test.index, test.reasons)
+		}
+	}
+
+	// A coarser round unit makes more amounts unround.
+	tx, fetcher := testTx(t, []btcutil.Address{a},
+		[]payment{{shAddr(3), 1e8}, {shAddr(4), 2e7}})
+	change, _ := analysis.DetectChange(tx, fetcher, &chaincfg.MainNetParams,
+		analysis.WithRoundUnit(1e8))
+	if change == nil || change.Index != 1 {
+		t.Errorf("WithRoundUnit: got change %+v, want output 1", change)
+	}
+}
+
+// TestChangeReasonString tests the stringized form of change reasons.
+func TestChangeReasonString(t *testing.T) {
+	tests := []struct {
+		in   analysis.ChangeReason
+		want string
+	}{
+		{0, "0"},
+		{analysis.ChangeScriptType, "ChangeScriptType"},
+		{analysis.ChangeAddressReuse | analysis.ChangeUnroundAmount,
+			"ChangeAddressReuse|ChangeUnroundAmount"},
+		{0x81, "ChangeAddressReuse|0x80"},
+	}
+	for _, test := range tests {
+		if got := test.in.String(); got != test.want {
+			t.Errorf("String(%d): got %q, want %q", test.in, got, test.want)
+		}
+	}
+}
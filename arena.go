@@ -0,0 +1,138 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package btcutil
+
+import (
+	"bytes"
+	"io"
+	"sync"
+
+	"github.com/zeusyf/btcd/wire"
+)
+
+// DefaultArenaSlabSize is the number of transactions a BlockArena allocates
+// room for at once when no slab size is given.
+const DefaultArenaSlabSize = 4096
+
+// BlockArena is a slab allocator for the objects created while parsing
+// blocks and wrapping their transactions.  Instead of one heap allocation
+// per block and per wrapped transaction, objects are carved out of a few
+// large slabs which become garbage together once every block allocated from
+// them is unreachable.  This reduces the number of objects the garbage
+// collector has to track during initial sync, when blocks are parsed,
+// processed, and dropped in quick succession.
+//
+// Blocks allocated from an arena behave exactly like other blocks, and may be
+// shared between goroutines like them, since the transactions they wrap
+// lazily are allocated under a lock.  Parsing blocks with an arena is however
+// not safe for concurrent use; each goroutine parsing blocks should have its
+// own.
+type BlockArena struct {
+	slabSize  int
+	blocks    []Block
+	msgBlocks []wire.MsgBlock
+	txMtx     sync.Mutex // Protects txs and txPtrs
+	txs       []Tx
+	txPtrs    []*Tx
+}
+
+// NewBlockArena returns an arena whose transaction slabs hold slabSize
+// transactions.  DefaultArenaSlabSize is used when slabSize is not positive.
+func NewBlockArena(slabSize int) *BlockArena {
+	if slabSize <= 0 {
+		slabSize = DefaultArenaSlabSize
+	}
+	return &BlockArena{slabSize: slabSize}
+}
+
+// blockSlabSize returns the number of blocks allocated at once, assuming
+// blocks of a few hundred transactions.
+func (a *BlockArena) blockSlabSize() int {
+	n := a.slabSize / 256
+	if n < 1 {
+		n = 1
+	}
+	return n
+}
+
+// newBlock returns a zeroed block and message block from the arena.
+func (a *BlockArena) newBlock() (*Block, *wire.MsgBlock) {
+	if len(a.blocks) == cap(a.blocks) {
+		a.blocks = make([]Block, 0, a.blockSlabSize())
+	}
+	if len(a.msgBlocks) == cap(a.msgBlocks) {
+		a.msgBlocks = make([]wire.MsgBlock, 0, a.blockSlabSize())
+	}
+	a.blocks = a.blocks[:len(a.blocks)+1]
+	a.msgBlocks = a.msgBlocks[:len(a.msgBlocks)+1]
+	return &a.blocks[len(a.blocks)-1], &a.msgBlocks[len(a.msgBlocks)-1]
+}
+
+// newTx returns a wrapped transaction from the arena.
+func (a *BlockArena) newTx(msgTx *wire.MsgTx) *Tx {
+	a.txMtx.Lock()
+	defer a.txMtx.Unlock()
+
+	if len(a.txs) == cap(a.txs) {
+		a.txs = make([]Tx, 0, a.slabSize)
+	}
+	a.txs = append(a.txs, Tx{msgTx: msgTx, txIndex: TxIndexUnknown})
+	return &a.txs[len(a.txs)-1]
+}
+
+// newTxSlice returns a slice of n nil transaction pointers from the arena.
+func (a *BlockArena) newTxSlice(n int) []*Tx {
+	a.txMtx.Lock()
+	defer a.txMtx.Unlock()
+
+	if cap(a.txPtrs)-len(a.txPtrs) < n {
+		size := a.slabSize
+		if n > size {
+			size = n
+		}
+		a.txPtrs = make([]*Tx, 0, size)
+	}
+	start := len(a.txPtrs)
+	a.txPtrs = a.txPtrs[:start+n]
+	return a.txPtrs[start : start+n : start+n]
+}
+
+// NewBlockFromReader is like the package level NewBlockFromReader, but the
+// block and its wrapped transactions are allocated from the arena.
+func (a *BlockArena) NewBlockFromReader(r io.Reader) (*Block, error) {
+	b, msgBlock := a.newBlock()
+	if err := msgBlock.Deserialize(r); err != nil {
+		*msgBlock = wire.MsgBlock{}
+		return nil, err
+	}
+
+	b.msgBlock = msgBlock
+	b.blockHeight = BlockHeightUnknown
+	b.arena = a
+	return b, nil
+}
+
+// NewBlockFromBytes is like the package level NewBlockFromBytes, but the
+// block and its wrapped transactions are allocated from the arena.
+func (a *BlockArena) NewBlockFromBytes(serializedBlock []byte) (*Block, error) {
+	b, err := a.NewBlockFromReader(bytes.NewReader(serializedBlock))
+	if err != nil {
+		return nil, err
+	}
+	b.serializedBlock.Store(&serializedBlock)
+	return b, nil
+}
+
+// Reset drops the arena's references to its current slabs so that further
+// allocations start new ones.  Blocks already allocated remain valid and
+// their memory is reclaimed once they are no longer referenced.
+func (a *BlockArena) Reset() {
+	a.blocks = nil
+	a.msgBlocks = nil
+	a.txMtx.Lock()
+	a.txs = nil
+	a.txPtrs = nil
+	a.txMtx.Unlock()
+}
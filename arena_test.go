@@ -0,0 +1,121 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package btcutil_test
+
+import (
+	"bytes"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/zeusyf/btcutil"
+)
+
+// TestBlockArena ensures blocks allocated from an arena behave like blocks
+// allocated from the heap, including across slab boundaries and resets.
+func TestBlockArena(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Block100000.Serialize(&buf); err != nil {
+		t.Fatalf("Serialize: %v", err)
+	}
+	serialized := buf.Bytes()
+
+	want, err := btcutil.NewBlockFromBytes(serialized)
+	if err != nil {
+		t.Fatalf("NewBlockFromBytes: %v", err)
+	}
+
+	// A tiny slab size forces new slabs to be started for every block.
+	arena := btcutil.NewBlockArena(1)
+	var blocks []*btcutil.Block
+	for i := 0; i < 5; i++ {
+		b, err := arena.NewBlockFromBytes(serialized)
+		if err != nil {
+			t.Fatalf("NewBlockFromBytes: %v", err)
+		}
+		blocks = append(blocks, b)
+		if i == 2 {
+			arena.Reset()
+		}
+	}
+
+	for i, b := range blocks {
+		if !reflect.DeepEqual(b.MsgBlock(), want.MsgBlock()) {
+			t.Fatalf("block %d: mismatched MsgBlock", i)
+		}
+		if *b.Hash() != *want.Hash() {
+			t.Fatalf("block %d: got hash %v, want %v", i, b.Hash(),
+				want.Hash())
+		}
+
+		tx, err := b.Tx(1)
+		if err != nil {
+			t.Fatalf("block %d: Tx: %v", i, err)
+		}
+		if tx.Index() != 1 || *tx.Hash() != *want.Transactions()[1].Hash() {
+			t.Fatalf("block %d: mismatched transaction 1", i)
+		}
+
+		txns := b.Transactions()
+		if len(txns) != len(want.Transactions()) {
+			t.Fatalf("block %d: got %d transactions, want %d", i,
+				len(txns), len(want.Transactions()))
+		}
+		for j, tx := range txns {
+			if tx.Index() != j || *tx.Hash() != *want.Transactions()[j].Hash() {
+				t.Fatalf("block %d: mismatched transaction %d", i, j)
+			}
+		}
+	}
+
+	if _, err := arena.NewBlockFromBytes(serialized[:80]); err == nil {
+		t.Fatalf("NewBlockFromBytes: truncated block did not fail")
+	}
+}
+
+// TestBlockArenaConcurrentReaders ensures blocks allocated from the same arena
+// can have their transactions wrapped concurrently.  It is meant to be run
+// with the race detector.
+func TestBlockArenaConcurrentReaders(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Block100000.Serialize(&buf); err != nil {
+		t.Fatalf("Serialize: %v", err)
+	}
+
+	arena := btcutil.NewBlockArena(1)
+	var blocks []*btcutil.Block
+	for i := 0; i < 4; i++ {
+		b, err := arena.NewBlockFromBytes(buf.Bytes())
+		if err != nil {
+			t.Fatalf("NewBlockFromBytes: %v", err)
+		}
+		blocks = append(blocks, b)
+	}
+
+	var wg sync.WaitGroup
+	for _, b := range blocks {
+		for i := 0; i < 2; i++ {
+			wg.Add(1)
+			go func(b *btcutil.Block) {
+				defer wg.Done()
+				for j, tx := range b.Transactions() {
+					if tx.Index() != j {
+						t.Errorf("transaction %d has index %d",
+							j, tx.Index())
+					}
+				}
+			}(b)
+		}
+	}
+	wg.Wait()
+
+	for i, b := range blocks {
+		for j, tx := range b.Transactions() {
+			if *tx.Hash() != Block100000.Transactions[j].TxHash() {
+				t.Errorf("block %d: mismatched transaction %d", i, j)
+			}
+		}
+	}
+}
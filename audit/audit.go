@@ -0,0 +1,168 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package audit defines the structured records of key usage which the key
+// derivation and signing adapters of this library emit to a caller-provided
+// sink, as required by the compliance teams of custodial services.
+//
+// Every record names the operation, the key involved by its origin, the
+// purpose the caller declared for the operation, and the digest that was
+// signed.  Auditing fails closed: when the sink returns an error, the
+// operation being audited fails too, so no key is used without a record of
+// it.
+package audit
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// hardenedKeyStart is the index of the first hardened child of an extended
+// key.  It matches hdkeychain.HardenedKeyStart, which can not be imported
+// since hdkeychain depends on this package.
+const hardenedKeyStart = 0x80000000
+
+// Operation identifies the kind of key usage recorded by an Event.
+type Operation uint8
+
+const (
+	// OpDerive is the derivation of a child key.
+	OpDerive Operation = iota + 1
+
+	// OpSign is the creation of a signature.
+	OpSign
+)
+
+// opStrings is a map of operations back to their constant names for pretty
+// printing.
+var opStrings = map[Operation]string{
+	OpDerive: "derive",
+	OpSign:   "sign",
+}
+
+// String returns the operation in human-readable form.
+func (op Operation) String() string {
+	if s, ok := opStrings[op]; ok {
+		return s
+	}
+	return fmt.Sprintf("Unknown Operation (%d)", uint8(op))
+}
+
+// KeyOrigin identifies a key by the fingerprint of the extended key it was
+// derived from and the derivation path below that key.
+type KeyOrigin struct {
+	// Fingerprint is the fingerprint of the extended key the path starts
+	// at, the first 4 bytes of the Hash160 of its public key, as a big
+	// endian integer.
+	Fingerprint uint32
+
+	// Path is the child indexes leading from that key to the key used.
+	Path []uint32
+}
+
+// String returns the origin in the usual notation, the hex fingerprint
+// followed by the path with hardened indexes marked by an apostrophe, such as
+// "3442193e/44'/0'/0'/0/1".
+func (o KeyOrigin) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%08x", o.Fingerprint)
+	for _, index := range o.Path {
+		if index >= hardenedKeyStart {
+			fmt.Fprintf(&b, "/%d'", index-hardenedKeyStart)
+		} else {
+			fmt.Fprintf(&b, "/%d", index)
+		}
+	}
+	return b.String()
+}
+
+// Event is a single audited use of a key.
+type Event struct {
+	// Time is when the key was used.
+	Time time.Time
+
+	// Operation is the kind of key usage.
+	Operation Operation
+
+	// Origin identifies the key used: the key signed with, or the key
+	// derived from along with the path derived.
+	Origin KeyOrigin
+
+	// Purpose is the reason for the operation as declared by the caller,
+	// such as "withdrawal" or "deposit address".
+	Purpose string
+
+	// Digest is the hash that was signed.  It is nil for derivations.
+	Digest []byte
+
+	// PubKey is the serialized compressed public key which signed, or
+	// which was derived.
+	PubKey []byte
+}
+
+// Sink receives audit events.  Implementations must be safe for concurrent
+// use.
+type Sink interface {
+	// Record stores the event.  A non-nil error makes the audited
+	// operation fail.
+	Record(e *Event) error
+}
+
+// SinkFunc is an adapter allowing ordinary functions to be used as a Sink.
+type SinkFunc func(e *Event) error
+
+// Record calls f(e).
+//
+// This is part of the Sink interface.
+func (f SinkFunc) Record(e *Event) error {
+	return f(e)
+}
+
+// jsonEvent is the JSON form of an Event written by JSONSink.
+type jsonEvent struct {
+	Time      string `json:"time"`
+	Operation string `json:"operation"`
+	Origin    string `json:"origin"`
+	Purpose   string `json:"purpose,omitempty"`
+	Digest    string `json:"digest,omitempty"`
+	PubKey    string `json:"pubkey,omitempty"`
+}
+
+// JSONSink is a Sink writing every event to an io.Writer as a single line of
+// JSON, a format log collectors ingest directly.
+type JSONSink struct {
+	mtx sync.Mutex
+	enc *json.Encoder
+}
+
+// Ensure JSONSink implements the Sink interface.
+var _ Sink = (*JSONSink)(nil)
+
+// NewJSONSink returns a sink writing events to w.
+func NewJSONSink(w io.Writer) *JSONSink {
+	return &JSONSink{enc: json.NewEncoder(w)}
+}
+
+// Record writes the event as a line of JSON.
+//
+// This is part of the Sink interface.
+func (s *JSONSink) Record(e *Event) error {
+	je := jsonEvent{
+		Time:      e.Time.UTC().Format(time.RFC3339Nano),
+		Operation: e.Operation.String(),
+		Origin:    e.Origin.String(),
+		Purpose:   e.Purpose,
+		Digest:    hex.EncodeToString(e.Digest),
+		PubKey:    hex.EncodeToString(e.PubKey),
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.enc.Encode(&je)
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package audit_test
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/zeusyf/btcutil/audit"
+)
+
+// TestKeyOriginString ensures origins are formatted with hardened indexes
+// marked.
+func TestKeyOriginString(t *testing.T) {
+	tests := []struct {
+		origin audit.KeyOrigin
+		want   string
+	}{
+		{audit.KeyOrigin{}, "00000000"},
+		{audit.KeyOrigin{Fingerprint: 0x3442193e, Path: []uint32{
+			0x80000000 + 44, 0x80000000, 0x80000000, 0, 1}},
+			"3442193e/44'/0'/0'/0/1"},
+	}
+	for i, test := range tests {
+		if got := test.origin.String(); got != test.want {
+			t.Errorf("#%d: got %s, want %s", i, got, test.want)
+		}
+	}
+
+	if got := audit.Operation(0).String(); got != "Unknown Operation (0)" {
+		t.Errorf("String: got %s for unknown operation", got)
+	}
+}
+
+// TestJSONSink ensures events are written as lines of JSON.
+func TestJSONSink(t *testing.T) {
+	var buf bytes.Buffer
+	sink := audit.NewJSONSink(&buf)
+	events := []*audit.Event{
+		{
+			Time:      time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC),
+			Operation: audit.OpSign,
+			Origin:    audit.KeyOrigin{Fingerprint: 1, Path: []uint32{2}},
+			Purpose:   "withdrawal",
+			Digest:    []byte{0xab, 0xcd},
+			PubKey:    []byte{0x02, 0x01},
+		},
+		{
+			Time:      time.Date(2021, 3, 4, 5, 6, 8, 0, time.UTC),
+			Operation: audit.OpDerive,
+			Origin:    audit.KeyOrigin{Fingerprint: 1},
+		},
+	}
+	for _, e := range events {
+		if err := sink.Record(e); err != nil {
+			t.Fatalf("Record: unexpected error: %v", err)
+		}
+	}
+
+	want := `{"time":"2021-03-04T05:06:07Z","operation":"sign",` +
+		`"origin":"00000001/2","purpose":"withdrawal","digest":"abcd",` +
+		`"pubkey":"0201"}` + "\n" +
+		`{"time":"2021-03-04T05:06:08Z","operation":"derive",` +
+		`"origin":"00000001"}` + "\n"
+	if got := buf.String(); got != want {
+		t.Fatalf("Record: got\n%s\nwant\n%s", got, want)
+	}
+}
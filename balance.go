@@ -0,0 +1,250 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package btcutil
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcd/wire/common"
+	"github.com/zeusyf/omega/token"
+)
+
+var (
+	// ErrHashToken describes an error where an output carrying a hash
+	// token, which has no amount, is added to a Balance.
+	ErrHashToken = errors.New("hash tokens have no amount")
+
+	// ErrInvalidBalance describes an error where a serialized balance is
+	// truncated, has trailing bytes, or lists token types out of order or
+	// with a zero amount.
+	ErrInvalidBalance = errors.New("malformed serialized balance")
+)
+
+// Balance holds amounts of several token types, such as the holdings of a
+// wallet or the value of the outputs of a transaction, keyed by token type.
+// Each amount is in the base unit of its token type, the Hao for OMC.
+//
+// The methods of Balance never keep a token type with a zero amount, so
+// balances holding the same amounts have the same entries.  A nil Balance is
+// an empty balance, but amounts can only be added to a non-nil one.
+type Balance map[uint64]Amount
+
+// Amount returns the amount of tokenType held by the balance.
+func (b Balance) Amount(tokenType uint64) Amount {
+	return b[tokenType]
+}
+
+// AddAmount adds a, which may be negative, to the amount of tokenType.
+func (b Balance) AddAmount(tokenType uint64, a Amount) {
+	sum := b[tokenType] + a
+	if sum == 0 {
+		delete(b, tokenType)
+		return
+	}
+	b[tokenType] = sum
+}
+
+// AddTxOut adds the amount of the token carried by out.  Outputs of hash
+// tokens are rejected with ErrHashToken.  The rights of the token are not
+// kept apart, so outputs of a token type with different rights add up.
+func (b Balance) AddTxOut(out *wire.TxOut) error {
+	value, ok := out.Token.Value.(*token.NumeralVal)
+	if out.Token.TokenType&1 != 0 || !ok {
+		return ErrHashToken
+	}
+	b.AddAmount(out.Token.TokenType, Amount(value.Val))
+	return nil
+}
+
+// Clone returns a copy of the balance.
+func (b Balance) Clone() Balance {
+	c := make(Balance, len(b))
+	for tokenType, a := range b {
+		if a != 0 {
+			c[tokenType] = a
+		}
+	}
+	return c
+}
+
+// Add returns the sum of the balance and other as a new balance.
+func (b Balance) Add(other Balance) Balance {
+	sum := b.Clone()
+	for tokenType, a := range other {
+		sum.AddAmount(tokenType, a)
+	}
+	return sum
+}
+
+// Sub returns the balance less other as a new balance.  Token types held in
+// other in excess of the balance have negative amounts in the result.
+func (b Balance) Sub(other Balance) Balance {
+	diff := b.Clone()
+	for tokenType, a := range other {
+		diff.AddAmount(tokenType, -a)
+	}
+	return diff
+}
+
+// TokenTypes returns the token types with a non-zero amount in ascending
+// order.
+func (b Balance) TokenTypes() []uint64 {
+	tokenTypes := make([]uint64, 0, len(b))
+	for tokenType, a := range b {
+		if a != 0 {
+			tokenTypes = append(tokenTypes, tokenType)
+		}
+	}
+	sort.Slice(tokenTypes, func(i, j int) bool {
+		return tokenTypes[i] < tokenTypes[j]
+	})
+	return tokenTypes
+}
+
+// IsZero returns whether the balance holds no amount of any token type.
+func (b Balance) IsZero() bool {
+	for _, a := range b {
+		if a != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// HasNegative returns whether the amount of some token type is negative, as
+// when a Sub leaves a shortfall.
+func (b Balance) HasNegative() bool {
+	for _, a := range b {
+		if a < 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// Equal returns whether the balance and other hold the same amounts.
+func (b Balance) Equal(other Balance) bool {
+	return b.Sub(other).IsZero()
+}
+
+// Covers returns whether the balance holds at least the amount of every token
+// type of other, that is whether it can pay for other.
+func (b Balance) Covers(other Balance) bool {
+	return !b.Sub(other).HasNegative()
+}
+
+// Compare compares the balance with other token type by token type.  It
+// returns -1, 0 or +1 when the amount of every token type of the balance is
+// respectively at most, equal to, or at least that of other, and ok is false
+// when neither balance covers the other, such as when each holds more of
+// some token type.
+func (b Balance) Compare(other Balance) (cmp int, ok bool) {
+	var less, greater bool
+	for _, a := range b.Sub(other) {
+		if a < 0 {
+			less = true
+		} else if a > 0 {
+			greater = true
+		}
+	}
+	switch {
+	case less && greater:
+		return 0, false
+	case less:
+		return -1, true
+	case greater:
+		return 1, true
+	}
+	return 0, true
+}
+
+// String returns the amounts of the balance in ascending order of token type,
+// separated by commas.  OMC is formatted as by Amount.String, and other token
+// types as an integer of their base unit followed by the token type, as in
+// "1.5 OMC, 100 token 4".  An empty balance is formatted as zero OMC.
+func (b Balance) String() string {
+	tokenTypes := b.TokenTypes()
+	if len(tokenTypes) == 0 {
+		return Amount(0).String()
+	}
+	parts := make([]string, len(tokenTypes))
+	for i, tokenType := range tokenTypes {
+		if tokenType == 0 {
+			parts[i] = b[tokenType].String()
+			continue
+		}
+		parts[i] = fmt.Sprintf("%d token %d", int64(b[tokenType]),
+			tokenType)
+	}
+	return strings.Join(parts, ", ")
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface.  The
+// balance is serialized as the number of its token types followed by each of
+// them in ascending order with its amount, so equal balances always serialize
+// to the same bytes and can be hashed or compared directly.  Token types and
+// the count are variable length integers, and amounts are 64-bit little
+// endian integers.
+func (b Balance) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	tokenTypes := b.TokenTypes()
+	if err := common.WriteVarInt(&buf, 0, uint64(len(tokenTypes))); err != nil {
+		return nil, err
+	}
+	for _, tokenType := range tokenTypes {
+		if err := common.WriteVarInt(&buf, 0, tokenType); err != nil {
+			return nil, err
+		}
+		var amount [8]byte
+		binary.LittleEndian.PutUint64(amount[:], uint64(b[tokenType]))
+		buf.Write(amount[:])
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface.  Only
+// the serialization written by MarshalBinary is accepted, so every balance
+// has exactly one serialization.
+func (b *Balance) UnmarshalBinary(data []byte) error {
+	r := bytes.NewReader(data)
+	n, err := common.ReadVarInt(r, 0)
+	if err != nil || n > uint64(len(data)) {
+		return ErrInvalidBalance
+	}
+
+	balance := make(Balance, n)
+	var prev uint64
+	for i := uint64(0); i < n; i++ {
+		tokenType, err := common.ReadVarInt(r, 0)
+		if err != nil {
+			return ErrInvalidBalance
+		}
+		var amount [8]byte
+		if _, err := io.ReadFull(r, amount[:]); err != nil {
+			return ErrInvalidBalance
+		}
+		a := Amount(binary.LittleEndian.Uint64(amount[:]))
+
+		// Token types must be in strictly ascending order and have a
+		// non-zero amount.
+		if (i > 0 && tokenType <= prev) || a == 0 {
+			return ErrInvalidBalance
+		}
+		balance[tokenType] = a
+		prev = tokenType
+	}
+	if r.Len() != 0 {
+		return ErrInvalidBalance
+	}
+	*b = balance
+	return nil
+}
@@ -0,0 +1,148 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package btcutil_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/omega/token"
+)
+
+// TestBalanceArithmetic ensures balances add up token type by token type and
+// never keep zero amounts.
+func TestBalanceArithmetic(t *testing.T) {
+	b := btcutil.Balance{}
+	b.AddAmount(0, 150000000)
+	b.AddAmount(4, 100)
+	b.AddAmount(4, -100)
+	if len(b) != 1 || b.Amount(0) != 150000000 || b.Amount(4) != 0 {
+		t.Fatalf("AddAmount: got %v", map[uint64]btcutil.Amount(b))
+	}
+
+	outs := []*wire.TxOut{
+		{Token: token.Token{TokenType: 0,
+			Value: &token.NumeralVal{Val: 50000000}}},
+		{Token: token.Token{TokenType: 4,
+			Value: &token.NumeralVal{Val: 7}}},
+	}
+	for _, out := range outs {
+		if err := b.AddTxOut(out); err != nil {
+			t.Fatalf("AddTxOut: %v", err)
+		}
+	}
+	hashOut := &wire.TxOut{Token: token.Token{TokenType: 5,
+		Value: &token.HashVal{Hash: chainhash.Hash{1}}}}
+	if err := b.AddTxOut(hashOut); err != btcutil.ErrHashToken {
+		t.Fatalf("AddTxOut: got error %v, want %v", err,
+			btcutil.ErrHashToken)
+	}
+	want := btcutil.Balance{0: 200000000, 4: 7}
+	if !b.Equal(want) {
+		t.Fatalf("AddTxOut: got %v, want %v", b, want)
+	}
+
+	other := btcutil.Balance{0: 200000000, 8: 3}
+	sum := b.Add(other)
+	if !sum.Equal(btcutil.Balance{0: 400000000, 4: 7, 8: 3}) {
+		t.Errorf("Add: got %v", sum)
+	}
+	diff := b.Sub(other)
+	if !diff.Equal(btcutil.Balance{4: 7, 8: -3}) || !diff.HasNegative() {
+		t.Errorf("Sub: got %v", diff)
+	}
+	if _, ok := diff[0]; ok {
+		t.Errorf("Sub: kept a zero amount")
+	}
+	if !b.Equal(want) {
+		t.Errorf("Add and Sub modified their receiver: %v", b)
+	}
+	if !b.Sub(b).IsZero() || b.IsZero() || !btcutil.Balance(nil).IsZero() {
+		t.Errorf("IsZero: unexpected result")
+	}
+	if got := sum.TokenTypes(); len(got) != 3 || got[0] != 0 ||
+		got[1] != 4 || got[2] != 8 {
+		t.Errorf("TokenTypes: got %v", got)
+	}
+}
+
+// TestBalanceCompare ensures balances are compared token type by token type.
+func TestBalanceCompare(t *testing.T) {
+	tests := []struct {
+		a, b   btcutil.Balance
+		cmp    int
+		ok     bool
+		covers bool
+	}{
+		{nil, nil, 0, true, true},
+		{btcutil.Balance{0: 5, 4: 1}, btcutil.Balance{4: 1, 0: 5}, 0, true, true},
+		{btcutil.Balance{0: 5, 4: 1}, btcutil.Balance{0: 5}, 1, true, true},
+		{btcutil.Balance{0: 5}, btcutil.Balance{0: 6}, -1, true, false},
+		{btcutil.Balance{0: 5}, btcutil.Balance{4: 1}, 0, false, false},
+		{btcutil.Balance{0: 5, 4: 0}, btcutil.Balance{0: 5}, 0, true, true},
+	}
+	for i, test := range tests {
+		cmp, ok := test.a.Compare(test.b)
+		if cmp != test.cmp || ok != test.ok {
+			t.Errorf("#%d Compare: got %d, %v, want %d, %v", i, cmp, ok,
+				test.cmp, test.ok)
+		}
+		if covers := test.a.Covers(test.b); covers != test.covers {
+			t.Errorf("#%d Covers: got %v, want %v", i, covers,
+				test.covers)
+		}
+	}
+}
+
+// TestBalanceSerialization ensures balances serialize deterministically and
+// only canonical serializations are parsed.
+func TestBalanceSerialization(t *testing.T) {
+	b := btcutil.Balance{300: 1, 0: 150000000, 4: -2, 9: 0}
+	want := []byte{
+		0x03,
+		0x00, 0x80, 0xd1, 0xf0, 0x08, 0x00, 0x00, 0x00, 0x00,
+		0x04, 0xfe, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+		0xfd, 0x2c, 0x01, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+	}
+	for i := 0; i < 5; i++ {
+		got, err := b.MarshalBinary()
+		if err != nil || !bytes.Equal(got, want) {
+			t.Fatalf("MarshalBinary: got %x, %v, want %x", got, err, want)
+		}
+	}
+
+	var parsed btcutil.Balance
+	if err := parsed.UnmarshalBinary(want); err != nil {
+		t.Fatalf("UnmarshalBinary: %v", err)
+	}
+	if !parsed.Equal(b) || len(parsed) != 3 {
+		t.Fatalf("UnmarshalBinary: got %v, want %v", parsed, b)
+	}
+
+	invalid := [][]byte{
+		nil,
+		want[:len(want)-1],
+		append(append([]byte{}, want...), 0x00),
+		{0x02, 0x04, 1, 0, 0, 0, 0, 0, 0, 0, 0x00, 1, 0, 0, 0, 0, 0, 0, 0},
+		{0x02, 0x04, 1, 0, 0, 0, 0, 0, 0, 0, 0x04, 1, 0, 0, 0, 0, 0, 0, 0},
+		{0x01, 0x04, 0, 0, 0, 0, 0, 0, 0, 0},
+	}
+	for i, data := range invalid {
+		if err := parsed.UnmarshalBinary(data); err != btcutil.ErrInvalidBalance {
+			t.Errorf("#%d UnmarshalBinary: got error %v, want %v", i,
+				err, btcutil.ErrInvalidBalance)
+		}
+	}
+
+	if s := b.String(); s != "1.5 OMC, -2 token 4, 1 token 300" {
+		t.Errorf("String: got %q", s)
+	}
+	if s := (btcutil.Balance{}).String(); s != "0 OMC" {
+		t.Errorf("String: got %q", s)
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package btcutil
+
+import (
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/omega/token"
+)
+
+// WalletUTXO is an unspent output of a wallet with its confirmations.
+type WalletUTXO struct {
+	// TxOut is the output.
+	TxOut *wire.TxOut
+
+	// Confirmations is the number of blocks including and built on the
+	// block of the output, or zero for an output not mined yet.
+	Confirmations int64
+
+	// Coinbase is whether the output is one of a coinbase transaction.
+	Coinbase bool
+}
+
+// Balances splits the holdings of a wallet by whether they can be spent.
+// The split is the one of the balances reported by the wallet RPC server:
+// every output counts toward Total, coinbase outputs without the coinbase
+// maturity toward Immature, and others toward Confirmed once they have the
+// minimum number of confirmations, or Unconfirmed until then.
+type Balances struct {
+	// Total is the value of all the outputs.
+	Total Balance
+
+	// Confirmed is the value of the outputs which can be spent.
+	Confirmed Balance
+
+	// Unconfirmed is the value of the outputs with fewer confirmations
+	// than the minimum, other than immature coinbase outputs.
+	Unconfirmed Balance
+
+	// Immature is the value of the coinbase outputs with fewer
+	// confirmations than the coinbase maturity.
+	Immature Balance
+}
+
+// CalcBalances returns the balances of the passed outputs.  minConf is the
+// number of confirmations making an output confirmed, where zero makes every
+// output confirmed but immature coinbase outputs, and coinbaseMaturity is the
+// CoinbaseMaturity of the network's parameters.  Outputs of hash tokens, which
+// have no amount, are skipped.
+func CalcBalances(utxos []WalletUTXO, minConf int64, coinbaseMaturity uint16) *Balances {
+	b := &Balances{
+		Total:       make(Balance),
+		Confirmed:   make(Balance),
+		Unconfirmed: make(Balance),
+		Immature:    make(Balance),
+	}
+	for _, utxo := range utxos {
+		value, ok := utxo.TxOut.Token.Value.(*token.NumeralVal)
+		if utxo.TxOut.Token.TokenType&1 != 0 || !ok {
+			continue
+		}
+		tokenType, amount := utxo.TxOut.Token.TokenType, Amount(value.Val)
+
+		b.Total.AddAmount(tokenType, amount)
+		switch {
+		case utxo.Coinbase && utxo.Confirmations < int64(coinbaseMaturity):
+			b.Immature.AddAmount(tokenType, amount)
+		case utxo.Confirmations >= minConf:
+			b.Confirmed.AddAmount(tokenType, amount)
+		default:
+			b.Unconfirmed.AddAmount(tokenType, amount)
+		}
+	}
+	return b
+}
+
+// Confirmations returns the number of confirmations of an output mined at
+// height when the best block is at tipHeight: one for an output of the best
+// block, and zero for an output not mined yet, passed with a negative height,
+// or mined above tipHeight.
+func Confirmations(height, tipHeight int32) int64 {
+	if height < 0 || height > tipHeight {
+		return 0
+	}
+	return int64(tipHeight) - int64(height) + 1
+}
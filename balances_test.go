@@ -0,0 +1,97 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package btcutil_test
+
+import (
+	"testing"
+
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/omega/token"
+)
+
+// walletUTXO returns an output paying value of tokenType with the passed
+// confirmations.
+func walletUTXO(tokenType uint64, value int64, confs int64, coinbase bool) btcutil.WalletUTXO {
+	return btcutil.WalletUTXO{
+		TxOut: &wire.TxOut{
+			Token: token.Token{
+				TokenType: tokenType,
+				Value:     &token.NumeralVal{Val: value},
+			},
+		},
+		Confirmations: confs,
+		Coinbase:      coinbase,
+	}
+}
+
+// TestCalcBalances ensures outputs are split by confirmations and coinbase
+// maturity, token type by token type.
+func TestCalcBalances(t *testing.T) {
+	utxos := []btcutil.WalletUTXO{
+		walletUTXO(0, 100, 0, false),
+		walletUTXO(0, 200, 1, false),
+		walletUTXO(0, 400, 6, false),
+		walletUTXO(0, 800, 99, true),
+		walletUTXO(0, 1600, 100, true),
+		walletUTXO(4, 5, 0, false),
+		walletUTXO(4, 7, 3, false),
+		{TxOut: &wire.TxOut{Token: token.Token{TokenType: 3,
+			Value: &token.HashVal{}}}, Confirmations: 10},
+	}
+
+	tests := []struct {
+		minConf     int64
+		confirmed   btcutil.Balance
+		unconfirmed btcutil.Balance
+	}{
+		{0, btcutil.Balance{0: 2300, 4: 12}, btcutil.Balance{}},
+		{1, btcutil.Balance{0: 2200, 4: 7}, btcutil.Balance{0: 100, 4: 5}},
+		{6, btcutil.Balance{0: 2000}, btcutil.Balance{0: 300, 4: 12}},
+	}
+	for _, test := range tests {
+		b := btcutil.CalcBalances(utxos, test.minConf, 100)
+		if !b.Total.Equal(btcutil.Balance{0: 3100, 4: 12}) {
+			t.Errorf("minConf %d: got total %v", test.minConf, b.Total)
+		}
+		if !b.Immature.Equal(btcutil.Balance{0: 800}) {
+			t.Errorf("minConf %d: got immature %v", test.minConf,
+				b.Immature)
+		}
+		if !b.Confirmed.Equal(test.confirmed) {
+			t.Errorf("minConf %d: got confirmed %v, want %v",
+				test.minConf, b.Confirmed, test.confirmed)
+		}
+		if !b.Unconfirmed.Equal(test.unconfirmed) {
+			t.Errorf("minConf %d: got unconfirmed %v, want %v",
+				test.minConf, b.Unconfirmed, test.unconfirmed)
+		}
+		sum := b.Confirmed.Add(b.Unconfirmed).Add(b.Immature)
+		if !sum.Equal(b.Total) {
+			t.Errorf("minConf %d: parts add up to %v, not the total %v",
+				test.minConf, sum, b.Total)
+		}
+	}
+}
+
+// TestConfirmations ensures confirmations count the block of the output.
+func TestConfirmations(t *testing.T) {
+	tests := []struct {
+		height, tip int32
+		want        int64
+	}{
+		{-1, 100, 0},
+		{101, 100, 0},
+		{100, 100, 1},
+		{1, 100, 100},
+		{0, 0, 1},
+	}
+	for _, test := range tests {
+		if got := btcutil.Confirmations(test.height, test.tip); got != test.want {
+			t.Errorf("Confirmations(%d, %d): got %d, want %d",
+				test.height, test.tip, got, test.want)
+		}
+	}
+}
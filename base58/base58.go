@@ -10,34 +10,26 @@ import (
 
 //go:generate go run genalphabet.go
 
-var bigRadix = big.NewInt(58)
-var bigZero = big.NewInt(0)
-
-// Decode decodes a modified base58 string to a byte slice.
+// Decode decodes a modified base58 string to a byte slice.  Long strings are
+// split in halves converted separately, so decoding multi-kilobyte payloads,
+// such as encrypted backups, is not quadratic in their length.
 func Decode(b string) []byte {
-	answer := big.NewInt(0)
-	j := big.NewInt(1)
-
-	scratch := new(big.Int)
-	for i := len(b) - 1; i >= 0; i-- {
-		tmp := b58[b[i]]
-		if tmp == 255 {
+	for i := 0; i < len(b); i++ {
+		if b58[b[i]] == 255 {
 			return []byte("")
 		}
-		scratch.SetInt64(int64(tmp))
-		scratch.Mul(j, scratch)
-		answer.Add(answer, scratch)
-		j.Mul(j, bigRadix)
 	}
 
-	tmpval := answer.Bytes()
-
 	var numZeros int
 	for numZeros = 0; numZeros < len(b); numZeros++ {
 		if b[numZeros] != alphabetIdx0 {
 			break
 		}
 	}
+
+	var c converter
+	tmpval := c.decode(b[numZeros:]).Bytes()
+
 	flen := numZeros + len(tmpval)
 	val := make([]byte, flen)
 	copy(val[numZeros:], tmpval)
@@ -45,31 +37,33 @@ func Decode(b string) []byte {
 	return val
 }
 
-// Encode encodes a byte slice to a modified base58 string.
+// Encode encodes a byte slice to a modified base58 string.  Like Decode, it
+// is not quadratic in the length of long payloads.
 func Encode(b []byte) string {
-	x := new(big.Int)
-	x.SetBytes(b)
-
-	answer := make([]byte, 0, len(b)*136/100)
-	for x.Cmp(bigZero) > 0 {
-		mod := new(big.Int)
-		x.DivMod(x, bigRadix, mod)
-		answer = append(answer, alphabet[mod.Int64()])
-	}
-
 	// leading zero bytes
-	for _, i := range b {
-		if i != 0 {
+	var numZeros int
+	for numZeros = 0; numZeros < len(b); numZeros++ {
+		if b[numZeros] != 0 {
 			break
 		}
-		answer = append(answer, alphabetIdx0)
 	}
 
-	// reverse
-	alen := len(answer)
-	for i := 0; i < alen/2; i++ {
-		answer[i], answer[alen-1-i] = answer[alen-1-i], answer[i]
+	// Each byte takes at most log(256)/log(58) ~= 1.366 digits, and the
+	// digits of the value are written after those of the leading zeros.
+	// The excess leading digits of the value are zero, like those of the
+	// leading zeros, so removing them from the front keeps the others.
+	ndigits := (len(b)-numZeros)*1366/1000 + 1
+	answer := make([]byte, numZeros+ndigits)
+	for i := 0; i < numZeros; i++ {
+		answer[i] = alphabetIdx0
+	}
+	var c converter
+	c.encode(answer[numZeros:], new(big.Int).SetBytes(b[numZeros:]))
+
+	excess := 0
+	for excess < ndigits && answer[numZeros+excess] == alphabetIdx0 {
+		excess++
 	}
 
-	return string(answer)
+	return string(answer[excess:])
 }
@@ -6,6 +6,7 @@ package base58_test
 
 import (
 	"bytes"
+	"fmt"
 	"testing"
 
 	"github.com/zeusyf/btcutil/base58"
@@ -33,3 +34,64 @@ func BenchmarkBase58Decode(b *testing.B) {
 		base58.Decode(encoded)
 	}
 }
+
+// payloadSizes are the sizes of the payloads of the scaling benchmarks, from
+// an address hash to a multi-kilobyte encrypted backup.
+var payloadSizes = []int{20, 256, 1024, 4096, 16384, 65536}
+
+func BenchmarkBase58EncodeSize(b *testing.B) {
+	for _, size := range payloadSizes {
+		data := bytes.Repeat([]byte{0xff}, size)
+		b.Run(fmt.Sprint(size), func(b *testing.B) {
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				base58.Encode(data)
+			}
+		})
+	}
+}
+
+func BenchmarkBase58DecodeSize(b *testing.B) {
+	for _, size := range payloadSizes {
+		encoded := base58.Encode(bytes.Repeat([]byte{0xff}, size))
+		b.Run(fmt.Sprint(size), func(b *testing.B) {
+			b.SetBytes(int64(len(encoded)))
+			for i := 0; i < b.N; i++ {
+				base58.Decode(encoded)
+			}
+		})
+	}
+}
+
+func BenchmarkCheckEncoder(b *testing.B) {
+	data := bytes.Repeat([]byte{0xff}, 16384)
+	b.SetBytes(int64(len(data)))
+	var buf bytes.Buffer
+	for i := 0; i < b.N; i++ {
+		buf.Reset()
+		e := base58.NewCheckEncoder(&buf, 0)
+		e.Write(data)
+		e.Close()
+	}
+}
+
+// benchHash is the 20 byte hash of the base58check benchmarks, the payload of
+// pay-to-pubkey-hash and pay-to-script-hash addresses.
+var benchHash = bytes.Repeat([]byte{0xa5}, 20)
+
+func BenchmarkCheckEncode(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		base58.CheckEncode(benchHash, 0)
+	}
+}
+
+func BenchmarkCheckDecode(b *testing.B) {
+	encoded := base58.CheckEncode(benchHash, 0)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		base58.CheckDecode(encoded)
+	}
+}
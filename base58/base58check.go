@@ -5,8 +5,10 @@
 package base58
 
 import (
-	"crypto/sha256"
+	"crypto/subtle"
 	"errors"
+
+	"github.com/zeusyf/btcutil/hashing"
 )
 
 // ErrChecksum indicates that the checksum of a check-encoded string does not verify against
@@ -18,9 +20,8 @@ var ErrInvalidFormat = errors.New("invalid format: version and/or checksum bytes
 
 // checksum: first four bytes of sha256^2
 func checksum(input []byte) (cksum [4]byte) {
-	h := sha256.Sum256(input)
-	h2 := sha256.Sum256(h[:])
-	copy(cksum[:], h2[:4])
+	h := hashing.DoubleSum256(input)
+	copy(cksum[:], h[:4])
 	return
 }
 
@@ -41,9 +42,11 @@ func CheckDecode(input string) (result []byte, version byte, err error) {
 		return nil, 0, ErrInvalidFormat
 	}
 	version = decoded[0]
-	var cksum [4]byte
-	copy(cksum[:], decoded[len(decoded)-4:])
-	if checksum(decoded[:len(decoded)-4]) != cksum {
+
+	// The checksum is compared in constant time, since check-encoded
+	// strings may hold secrets such as private keys.
+	cksum := checksum(decoded[:len(decoded)-4])
+	if subtle.ConstantTimeCompare(cksum[:], decoded[len(decoded)-4:]) != 1 {
 		return nil, 0, ErrChecksum
 	}
 	payload := decoded[1 : len(decoded)-4]
@@ -64,3 +64,28 @@ func TestBase58Check(t *testing.T) {
 	}
 
 }
+
+// TestCheckAllocs ensures encoding and decoding an address payload allocate
+// no more than their budgets, so an allocation added to code run for every
+// address fails the tests.  Budgets should be lowered when an optimization
+// makes them slack.
+func TestCheckAllocs(t *testing.T) {
+	hash := []byte("abcdefghijklmnopqrst")
+	encoded := base58.CheckEncode(hash, 0)
+
+	tests := []struct {
+		name   string
+		budget float64
+		f      func()
+	}{
+		{"CheckEncode", 4, func() { base58.CheckEncode(hash, 0) }},
+		{"CheckDecode", 7, func() { base58.CheckDecode(encoded) }},
+	}
+	for _, test := range tests {
+		allocs := testing.AllocsPerRun(100, test.f)
+		if allocs > test.budget {
+			t.Errorf("%s: got %v allocations, want at most %v",
+				test.name, allocs, test.budget)
+		}
+	}
+}
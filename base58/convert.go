@@ -0,0 +1,106 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package base58
+
+import (
+	"math/big"
+)
+
+const (
+	// chunkDigits is the number of base58 digits converted at once, the
+	// most whose value fits in a uint64.
+	chunkDigits = 10
+
+	// chunkRadix is 58^chunkDigits.
+	chunkRadix = 430804206899405824
+
+	// leafDigits is the length of the longest run of digits converted
+	// chunk by chunk.  Longer runs are split in two halves converted
+	// separately, so converting n digits takes the time of a few big
+	// multiplications or divisions of n digit numbers, rather than of n
+	// multiplications or divisions by a small number.
+	leafDigits = 32 * chunkDigits
+)
+
+var bigChunkRadix = new(big.Int).SetUint64(chunkRadix)
+
+// converter converts between numbers and base58 digits, keeping the powers
+// of 58 which split long runs of digits.
+type converter struct {
+	// powers holds 58^(chunkDigits*2^i) at index i.
+	powers []*big.Int
+}
+
+// split returns the number of low digits split from a run of n digits longer
+// than leafDigits, along with 58 raised to that number.
+func (c *converter) split(n int) (int, *big.Int) {
+	if c.powers == nil {
+		c.powers = []*big.Int{bigChunkRadix}
+	}
+	i := 0
+	for chunkDigits<<(i+1) < n {
+		i++
+		if i == len(c.powers) {
+			p := c.powers[i-1]
+			c.powers = append(c.powers, new(big.Int).Mul(p, p))
+		}
+	}
+	return chunkDigits << i, c.powers[i]
+}
+
+// encode writes x, which must be less than 58^len(dst), to dst as base58
+// digits, most significant first.  x is destroyed.
+func (c *converter) encode(dst []byte, x *big.Int) {
+	n := len(dst)
+	if n > leafDigits {
+		k, pow := c.split(n)
+		q, r := new(big.Int).QuoRem(x, pow, new(big.Int))
+		c.encode(dst[:n-k], q)
+		c.encode(dst[n-k:], r)
+		return
+	}
+
+	r := new(big.Int)
+	for n > 0 {
+		x.QuoRem(x, bigChunkRadix, r)
+		w := r.Uint64()
+		for i := 0; i < chunkDigits && n > 0; i++ {
+			n--
+			dst[n] = alphabet[w%58]
+			w /= 58
+		}
+	}
+}
+
+// decode returns the number whose base58 digits, most significant first, are
+// the values of the characters of s, which must all be valid.
+func (c *converter) decode(s string) *big.Int {
+	n := len(s)
+	if n > leafDigits {
+		k, pow := c.split(n)
+		x := c.decode(s[:n-k])
+		x.Mul(x, pow)
+		return x.Add(x, c.decode(s[n-k:]))
+	}
+
+	x := new(big.Int)
+	w := new(big.Int)
+	for len(s) > 0 {
+		// The first chunk takes the digits left over by the others.
+		m := len(s) % chunkDigits
+		if m == 0 {
+			m = chunkDigits
+		}
+		var v, radix uint64 = 0, 1
+		for i := 0; i < m; i++ {
+			v = v*58 + uint64(b58[s[i]])
+			radix *= 58
+		}
+		x.Mul(x, w.SetUint64(radix))
+		x.Add(x, w.SetUint64(v))
+		s = s[m:]
+	}
+	return x
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package base58_test
+
+import (
+	"bytes"
+	"math/big"
+	"math/rand"
+	"testing"
+
+	"github.com/zeusyf/btcutil/base58"
+)
+
+const testAlphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// naiveEncode encodes b one digit at a time, like the original
+// implementation.
+func naiveEncode(b []byte) string {
+	x := new(big.Int).SetBytes(b)
+	radix := big.NewInt(58)
+	mod := new(big.Int)
+	var answer []byte
+	for x.Sign() > 0 {
+		x.DivMod(x, radix, mod)
+		answer = append([]byte{testAlphabet[mod.Int64()]}, answer...)
+	}
+	for _, c := range b {
+		if c != 0 {
+			break
+		}
+		answer = append([]byte{'1'}, answer...)
+	}
+	return string(answer)
+}
+
+// TestLargePayloads ensures payloads long enough to be converted in halves
+// encode like they are digit by digit, and decode back.
+func TestLargePayloads(t *testing.T) {
+	r := rand.New(rand.NewSource(58))
+	sizes := []int{1, 7, 8, 9, 100, 233, 234, 235, 236, 468, 469, 470, 1000,
+		4096, 10000}
+	for _, size := range sizes {
+		for _, zeros := range []int{0, 1, size / 2} {
+			data := make([]byte, size)
+			r.Read(data[zeros:])
+			if zeros < size && data[zeros] == 0 {
+				data[zeros] = 1
+			}
+
+			got := base58.Encode(data)
+			if want := naiveEncode(data); got != want {
+				t.Errorf("Encode(%d bytes, %d zeros): got %s, want %s",
+					size, zeros, got, want)
+				continue
+			}
+			if decoded := base58.Decode(got); !bytes.Equal(decoded, data) {
+				t.Errorf("Decode(%d bytes, %d zeros): got %x, want %x",
+					size, zeros, decoded, data)
+			}
+		}
+	}
+
+	// Runs of the greatest digit carry through every chunk.
+	for _, n := range []int{10, 11, 320, 321, 640, 641, 5000} {
+		s := string(bytes.Repeat([]byte{'z'}, n))
+		if got := base58.Encode(base58.Decode(s)); got != s {
+			t.Errorf("Encode(Decode(%d digits)): got %s", n, got)
+		}
+	}
+}
@@ -25,5 +25,13 @@ byte arrays into human-readable strings along with a version byte that can be
 used to differentiate the same payload.  For Bitcoin addresses, the extra
 version is used to differentiate the network of otherwise identical public keys
 which helps prevent using an address intended for one network on another.
+
+Large Payloads
+
+Long strings are converted by splitting them in halves, so encoding and
+decoding multi-kilobyte payloads, such as encrypted backups, takes the time of
+a few big number multiplications rather than growing with the square of their
+length.  CheckEncoder encodes payloads written in pieces, and
+CheckDecodeReader decodes encodings read from an io.Reader.
 */
 package base58
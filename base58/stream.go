@@ -0,0 +1,64 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package base58
+
+import (
+	"bytes"
+	"errors"
+	"io"
+)
+
+// ErrEncoderClosed indicates a write to a CheckEncoder which was closed.
+var ErrEncoderClosed = errors.New("write to closed encoder")
+
+// CheckEncoder Base58Check encodes the payload written to it, such as an
+// encrypted backup produced by an io.Writer chain.  Since every base58 digit
+// depends on the whole payload, the encoding is only written to the
+// underlying writer when the encoder is closed.
+type CheckEncoder struct {
+	w      io.Writer
+	buf    bytes.Buffer
+	closed bool
+}
+
+// NewCheckEncoder returns a CheckEncoder writing the encoding of the payload
+// with the version byte to w.
+func NewCheckEncoder(w io.Writer, version byte) *CheckEncoder {
+	e := &CheckEncoder{w: w}
+	e.buf.WriteByte(version)
+	return e
+}
+
+// Write appends p to the payload.
+func (e *CheckEncoder) Write(p []byte) (int, error) {
+	if e.closed {
+		return 0, ErrEncoderClosed
+	}
+	return e.buf.Write(p)
+}
+
+// Close appends the checksum to the payload and writes its encoding to the
+// underlying writer.  Closing an encoder again does nothing.
+func (e *CheckEncoder) Close() error {
+	if e.closed {
+		return nil
+	}
+	e.closed = true
+	cksum := checksum(e.buf.Bytes())
+	e.buf.Write(cksum[:])
+	_, err := io.WriteString(e.w, Encode(e.buf.Bytes()))
+	e.buf = bytes.Buffer{}
+	return err
+}
+
+// CheckDecodeReader decodes the Base58Check encoding read from r to its end,
+// like CheckDecode.
+func CheckDecodeReader(r io.Reader) (result []byte, version byte, err error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, 0, err
+	}
+	return CheckDecode(string(b))
+}
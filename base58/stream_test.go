@@ -0,0 +1,59 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package base58_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/zeusyf/btcutil/base58"
+)
+
+// TestCheckEncoder ensures payloads written in pieces encode like
+// CheckEncode, and decode back from a reader.
+func TestCheckEncoder(t *testing.T) {
+	payload := bytes.Repeat([]byte("encrypted backup "), 200)
+
+	var buf bytes.Buffer
+	e := base58.NewCheckEncoder(&buf, 0x42)
+	for i := 0; i < len(payload); i += 100 {
+		end := i + 100
+		if end > len(payload) {
+			end = len(payload)
+		}
+		if _, err := e.Write(payload[i:end]); err != nil {
+			t.Fatalf("Write: unexpected error: %v", err)
+		}
+	}
+	if buf.Len() != 0 {
+		t.Errorf("Write: wrote %d bytes before Close", buf.Len())
+	}
+	if err := e.Close(); err != nil {
+		t.Fatalf("Close: unexpected error: %v", err)
+	}
+	if want := base58.CheckEncode(payload, 0x42); buf.String() != want {
+		t.Errorf("Close: got %s, want %s", buf.String(), want)
+	}
+	if err := e.Close(); err != nil || buf.Len() == 0 {
+		t.Errorf("Close: closing again got error %v", err)
+	}
+	if _, err := e.Write([]byte{0x01}); err != base58.ErrEncoderClosed {
+		t.Errorf("Write: got error %v, want %v", err,
+			base58.ErrEncoderClosed)
+	}
+
+	got, version, err := base58.CheckDecodeReader(&buf)
+	if err != nil {
+		t.Fatalf("CheckDecodeReader: unexpected error: %v", err)
+	}
+	if !bytes.Equal(got, payload) || version != 0x42 {
+		t.Errorf("CheckDecodeReader: got %q version %#x", got, version)
+	}
+	if _, _, err := base58.CheckDecodeReader(strings.NewReader("3MNQE1Y")); err != base58.ErrChecksum {
+		t.Errorf("CheckDecodeReader: got error %v, want %v", err,
+			base58.ErrChecksum)
+	}
+}
@@ -0,0 +1,235 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package batchverify verifies many ECDSA signatures at once, such as the
+// signatures of all the inputs of a block.
+//
+// Signatures are queued as public key, signature hash and signature triples
+// and verified in batches by a pool of workers.  ECDSA signatures can't be
+// aggregated, so each signature is still verified on its own, but batches
+// spread the work over all CPUs and verification stops as soon as a failing
+// signature is known, reporting the index of the first failing triple.
+package batchverify
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/zeusyf/btcd/btcec"
+)
+
+// DefaultBatchSize is the number of signatures a worker verifies at a time
+// when no batch size is set.
+const DefaultBatchSize = 64
+
+// ErrInvalidSignature describes an error where a signature does not match its
+// public key and signature hash.
+var ErrInvalidSignature = errors.New("invalid signature")
+
+// SignatureError describes an error where one of the queued signatures failed
+// to verify.
+type SignatureError struct {
+	// Index is the position of the signature in the queue.
+	Index int
+
+	// Err is ErrInvalidSignature, or the error parsing the public key or
+	// signature.
+	Err error
+}
+
+// Error returns the error along with the index of the offending signature.
+func (e *SignatureError) Error() string {
+	return fmt.Sprintf("signature %d: %v", e.Index, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *SignatureError) Unwrap() error {
+	return e.Err
+}
+
+// entry is a queued signature.  err is set when the signature could not be
+// parsed, in which case it fails without being verified.
+type entry struct {
+	pubKey  *btcec.PublicKey
+	sigHash []byte
+	sig     *btcec.Signature
+	err     error
+}
+
+// Option configures a Verifier.
+type Option func(*Verifier)
+
+// WithWorkers sets the number of goroutines verifying signatures.  The
+// number of CPUs usable by the process is used by default.
+func WithWorkers(n int) Option {
+	return func(v *Verifier) {
+		v.workers = n
+	}
+}
+
+// WithBatchSize sets the number of signatures a worker verifies at a time.
+// DefaultBatchSize is used by default.
+func WithBatchSize(n int) Option {
+	return func(v *Verifier) {
+		if n > 0 {
+			v.batchSize = n
+		}
+	}
+}
+
+// Verifier queues signatures and verifies them in batches.  It is not safe
+// for concurrent use while signatures are added.
+type Verifier struct {
+	entries   []entry
+	workers   int
+	batchSize int
+}
+
+// New returns an empty verifier.
+func New(opts ...Option) *Verifier {
+	v := &Verifier{
+		workers:   runtime.GOMAXPROCS(0),
+		batchSize: DefaultBatchSize,
+	}
+	for _, opt := range opts {
+		opt(v)
+	}
+	return v
+}
+
+// Add queues the signature sig of sigHash by pubKey and returns its index.
+func (v *Verifier) Add(pubKey *btcec.PublicKey, sigHash []byte,
+	sig *btcec.Signature) int {
+	v.entries = append(v.entries, entry{
+		pubKey:  pubKey,
+		sigHash: sigHash,
+		sig:     sig,
+	})
+	return len(v.entries) - 1
+}
+
+// AddRaw queues the serialized public key and DER encoded signature sig of
+// sigHash and returns its index.  A public key or signature which can't be
+// parsed is queued anyway, and fails verification with its parsing error, so
+// that failures are reported in queue order.
+func (v *Verifier) AddRaw(pubKey, sigHash, sig []byte) int {
+	e := entry{sigHash: sigHash}
+	e.pubKey, e.err = btcec.ParsePubKey(pubKey, btcec.S256())
+	if e.err == nil {
+		e.sig, e.err = btcec.ParseDERSignature(sig, btcec.S256())
+	}
+	v.entries = append(v.entries, e)
+	return len(v.entries) - 1
+}
+
+// Len returns the number of queued signatures.
+func (v *Verifier) Len() int {
+	return len(v.entries)
+}
+
+// Reset empties the queue, keeping its memory for reuse.
+func (v *Verifier) Reset() {
+	for i := range v.entries {
+		v.entries[i] = entry{}
+	}
+	v.entries = v.entries[:0]
+}
+
+// verify returns the error of the signature at index i, or nil when it is
+// valid.
+func (v *Verifier) verify(i int) error {
+	e := &v.entries[i]
+	switch {
+	case e.err != nil:
+		return e.err
+	case e.pubKey == nil || e.sig == nil || !e.sig.Verify(e.sigHash, e.pubKey):
+		return ErrInvalidSignature
+	}
+	return nil
+}
+
+// Verify verifies all the queued signatures.  It returns nil when they are
+// all valid, and otherwise a *SignatureError for the failing signature with
+// the lowest index.  Batches after a known failure are skipped, so an invalid
+// signature aborts verification early.
+func (v *Verifier) Verify() error {
+	n := len(v.entries)
+	batches := (n + v.batchSize - 1) / v.batchSize
+	workers := v.workers
+	if workers > batches {
+		workers = batches
+	}
+	if workers <= 1 {
+		for i := 0; i < n; i++ {
+			if err := v.verify(i); err != nil {
+				return &SignatureError{Index: i, Err: err}
+			}
+		}
+		return nil
+	}
+
+	var (
+		next   int64
+		failed int64 = math.MaxInt64
+		errs         = make([]error, workers)
+		wg     sync.WaitGroup
+	)
+	// fail records a failure at index i unless one was found before it.
+	fail := func(i int) bool {
+		for {
+			cur := atomic.LoadInt64(&failed)
+			if int64(i) >= cur {
+				return false
+			}
+			if atomic.CompareAndSwapInt64(&failed, cur, int64(i)) {
+				return true
+			}
+		}
+	}
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func(w int) {
+			defer wg.Done()
+			for {
+				batch := int(atomic.AddInt64(&next, 1) - 1)
+				if batch >= batches {
+					return
+				}
+				start := batch * v.batchSize
+				if int64(start) >= atomic.LoadInt64(&failed) {
+					return
+				}
+				end := start + v.batchSize
+				if end > n {
+					end = n
+				}
+				for i := start; i < end; i++ {
+					err := v.verify(i)
+					if err == nil {
+						continue
+					}
+					if fail(i) {
+						errs[w] = &SignatureError{Index: i, Err: err}
+					}
+					break
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+
+	if failed == math.MaxInt64 {
+		return nil
+	}
+	for _, err := range errs {
+		if err != nil && err.(*SignatureError).Index == int(failed) {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package batchverify_test
+
+import (
+	"crypto/sha256"
+	"errors"
+	"testing"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcutil/batchverify"
+)
+
+// signed returns the public key, signature hash and DER encoded signature of
+// n messages signed by the same key.
+func signed(t *testing.T, n int) (*btcec.PublicKey, [][]byte, [][]byte) {
+	key, pubKey := btcec.PrivKeyFromBytes(btcec.S256(), []byte{
+		0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08,
+		0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f, 0x10,
+		0x11, 0x12, 0x13, 0x14, 0x15, 0x16, 0x17, 0x18,
+		0x19, 0x1a, 0x1b, 0x1c, 0x1d, 0x1e, 0x1f, 0x20,
+	})
+	hashes := make([][]byte, n)
+	sigs := make([][]byte, n)
+	for i := range hashes {
+		h := sha256.Sum256([]byte{byte(i), byte(i >> 8)})
+		sig, err := key.Sign(h[:])
+		if err != nil {
+			t.Fatalf("Sign: unexpected error: %v", err)
+		}
+		hashes[i], sigs[i] = h[:], sig.Serialize()
+	}
+	return pubKey, hashes, sigs
+}
+
+// TestVerify ensures valid batches verify and the first failing signature is
+// reported whatever the number of workers.
+func TestVerify(t *testing.T) {
+	pubKey, hashes, sigs := signed(t, 200)
+	raw := pubKey.SerializeCompressed()
+
+	for _, workers := range []int{1, 4} {
+		v := batchverify.New(batchverify.WithWorkers(workers),
+			batchverify.WithBatchSize(8))
+		if err := v.Verify(); err != nil {
+			t.Errorf("Verify(%d workers): unexpected error for empty "+
+				"queue: %v", workers, err)
+		}
+		for i := range hashes {
+			sig, err := btcec.ParseDERSignature(sigs[i], btcec.S256())
+			if err != nil {
+				t.Fatalf("ParseDERSignature: unexpected error: %v", err)
+			}
+			if idx := v.Add(pubKey, hashes[i], sig); idx != i {
+				t.Fatalf("Add: got index %d, want %d", idx, i)
+			}
+		}
+		if err := v.Verify(); err != nil {
+			t.Errorf("Verify(%d workers): unexpected error: %v", workers,
+				err)
+		}
+
+		// Signatures of the wrong hash fail, and the lowest index is
+		// reported.
+		v.AddRaw(raw, hashes[0], sigs[1])
+		v.AddRaw(raw, hashes[0], sigs[0])
+		v.AddRaw(raw, hashes[2], sigs[3])
+		err := v.Verify()
+		var sigErr *batchverify.SignatureError
+		if !errors.As(err, &sigErr) || sigErr.Index != 200 ||
+			!errors.Is(err, batchverify.ErrInvalidSignature) {
+			t.Errorf("Verify(%d workers): got error %v, want failure "+
+				"at 200", workers, err)
+		}
+
+		// Signatures which can't be parsed fail at their index.
+		v.Reset()
+		if v.Len() != 0 {
+			t.Fatalf("Reset: got %d signatures, want 0", v.Len())
+		}
+		for i := 0; i < 50; i++ {
+			v.AddRaw(raw, hashes[i], sigs[i])
+		}
+		v.AddRaw(raw, hashes[50], []byte{0x30})
+		v.AddRaw([]byte{0x02}, hashes[51], sigs[51])
+		err = v.Verify()
+		if !errors.As(err, &sigErr) || sigErr.Index != 50 ||
+			errors.Is(err, batchverify.ErrInvalidSignature) {
+			t.Errorf("Verify(%d workers): got error %v, want parse "+
+				"failure at 50", workers, err)
+		}
+	}
+}
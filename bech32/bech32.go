@@ -16,10 +16,21 @@ var gen = []int{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
 // Decode decodes a bech32 encoded string, returning the human-readable
 // part and the data part excluding the checksum.
 func Decode(bech string) (string, []byte, error) {
-	// The maximum allowed length for a bech32 string is 90. It must also
-	// be at least 8 characters, since it needs a non-empty HRP, a
-	// separator, and a 6 character checksum.
-	if len(bech) < 8 || len(bech) > 90 {
+	// The maximum allowed length for a bech32 string is 90.
+	if len(bech) > 90 {
+		return "", nil, fmt.Errorf("invalid bech32 string length %d",
+			len(bech))
+	}
+	return DecodeNoLimit(bech)
+}
+
+// DecodeNoLimit decodes a bech32 encoded string like Decode, without the
+// limit of 90 characters BIP 173 sets for addresses.  It is meant for longer
+// strings using the bech32 encoding, such as payment invoices.
+func DecodeNoLimit(bech string) (string, []byte, error) {
+	// The string must be at least 8 characters, since it needs a
+	// non-empty HRP, a separator, and a 6 character checksum.
+	if len(bech) < 8 {
 		return "", nil, fmt.Errorf("invalid bech32 string length %d",
 			len(bech))
 	}
@@ -44,8 +55,7 @@ func Decode(bech string) (string, []byte, error) {
 
 	// The string is invalid if the last '1' is non-existent, it is the
 	// first character of the string (no human-readable part) or one of the
-	// last 6 characters of the string (since checksum cannot contain '1'),
-	// or if the string is more than 90 characters in total.
+	// last 6 characters of the string (since checksum cannot contain '1').
 	one := strings.LastIndexByte(bech, '1')
 	if one < 1 || one+7 > len(bech) {
 		return "", nil, fmt.Errorf("invalid index of 1")
@@ -67,3 +67,32 @@ func TestBech32(t *testing.T) {
 		}
 	}
 }
+
+// TestDecodeNoLimit ensures strings longer than 90 characters are only decoded
+// by DecodeNoLimit.
+func TestDecodeNoLimit(t *testing.T) {
+	data := make([]byte, 200)
+	for i := range data {
+		data[i] = byte(i % 32)
+	}
+	str, err := bech32.Encode("lnomc", data)
+	if err != nil {
+		t.Fatalf("Encode: unexpected error: %v", err)
+	}
+
+	if _, _, err := bech32.Decode(str); err == nil {
+		t.Errorf("Decode: expected error for a %d character string",
+			len(str))
+	}
+	hrp, decoded, err := bech32.DecodeNoLimit(str)
+	if err != nil {
+		t.Fatalf("DecodeNoLimit: unexpected error: %v", err)
+	}
+	if hrp != "lnomc" || string(decoded) != string(data) {
+		t.Errorf("DecodeNoLimit: got hrp %q and data %x", hrp, decoded)
+	}
+
+	if _, _, err := bech32.DecodeNoLimit("a1qqqqq"); err == nil {
+		t.Error("DecodeNoLimit: expected error for a short string")
+	}
+}
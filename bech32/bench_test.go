@@ -0,0 +1,104 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package bech32_test
+
+import (
+	"testing"
+
+	"github.com/zeusyf/btcutil/bech32"
+)
+
+// benchBech32 is the segregated witness address of the benchmarks, paying to
+// a 20 byte witness program.
+const benchBech32 = "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4"
+
+// Sinks keep the compiler from optimizing the benchmarked calls away.
+var (
+	stringSink string
+	bytesSink  []byte
+)
+
+// benchData returns the data part of benchBech32.
+func benchData(tb testing.TB) []byte {
+	_, data, err := bech32.Decode(benchBech32)
+	if err != nil {
+		tb.Fatalf("Decode: unexpected error: %v", err)
+	}
+	return data
+}
+
+// BenchmarkDecode benchmarks decoding a segregated witness address.
+func BenchmarkDecode(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		_, data, err := bech32.Decode(benchBech32)
+		if err != nil {
+			b.Fatalf("Decode: unexpected error: %v", err)
+		}
+		bytesSink = data
+	}
+}
+
+// BenchmarkEncode benchmarks encoding a segregated witness address.
+func BenchmarkEncode(b *testing.B) {
+	data := benchData(b)
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		s, err := bech32.Encode("bc", data)
+		if err != nil {
+			b.Fatalf("Encode: unexpected error: %v", err)
+		}
+		stringSink = s
+	}
+}
+
+// BenchmarkConvertBits benchmarks regrouping a witness program into the 5 bit
+// groups of the data part.
+func BenchmarkConvertBits(b *testing.B) {
+	program, err := bech32.ConvertBits(benchData(b)[1:], 5, 8, false)
+	if err != nil {
+		b.Fatalf("ConvertBits: unexpected error: %v", err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		bytesSink, _ = bech32.ConvertBits(program, 8, 5, true)
+	}
+}
+
+// TestAllocs ensures encoding and decoding allocate no more than their
+// budgets, so an allocation added to code run for every address fails the
+// tests.  Budgets should be lowered when an optimization makes them slack, but
+// hold for the race detector, under which ConvertBits can not keep its result
+// on the stack.
+func TestAllocs(t *testing.T) {
+	data := benchData(t)
+	program, err := bech32.ConvertBits(data[1:], 5, 8, false)
+	if err != nil {
+		t.Fatalf("ConvertBits: unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		budget float64
+		f      func()
+	}{
+		{"Decode", 5, func() { _, bytesSink, _ = bech32.Decode(benchBech32) }},
+		{"Encode", 8, func() { stringSink, _ = bech32.Encode("bc", data) }},
+		{"ConvertBits", 3, func() {
+			bytesSink, _ = bech32.ConvertBits(program, 8, 5, true)
+		}},
+	}
+	for _, test := range tests {
+		allocs := testing.AllocsPerRun(100, test.f)
+		if allocs > test.budget {
+			t.Errorf("%s: got %v allocations, want at most %v",
+				test.name, allocs, test.budget)
+		}
+	}
+}
@@ -0,0 +1,125 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package btcutil_test
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil"
+)
+
+// benchAddr is the pay-to-pubkey-hash address of the benchmarks.
+const benchAddr = "1MirQ9bwyQcGVJPwKUgapu5ouK2E2Ey4gX"
+
+// benchPubKey is the compressed public key hashed by the benchmarks.
+const benchPubKey = "02192d74d0cb94344c9569c2e77901573d8d7903c3ebec3a957724895dca52c6b4"
+
+// benchAmount is the amount formatted by the benchmarks, with digits both
+// before and after the decimal point.
+const benchAmount = btcutil.Amount(123456789012)
+
+// Sinks keep the compiler from optimizing the benchmarked calls away.
+var (
+	addrSink   btcutil.Address
+	stringSink string
+	bytesSink  []byte
+)
+
+// BenchmarkDecodeAddress benchmarks decoding a pay-to-pubkey-hash address.
+func BenchmarkDecodeAddress(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		addr, err := btcutil.DecodeAddress(benchAddr, &chaincfg.MainNetParams)
+		if err != nil {
+			b.Fatalf("DecodeAddress: unexpected error: %v", err)
+		}
+		addrSink = addr
+	}
+}
+
+// BenchmarkEncodeAddress benchmarks encoding a pay-to-pubkey-hash address.
+func BenchmarkEncodeAddress(b *testing.B) {
+	addr, err := btcutil.DecodeAddress(benchAddr, &chaincfg.MainNetParams)
+	if err != nil {
+		b.Fatalf("DecodeAddress: unexpected error: %v", err)
+	}
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		stringSink = addr.EncodeAddress()
+	}
+}
+
+// BenchmarkHash160 benchmarks hashing a compressed public key.
+func BenchmarkHash160(b *testing.B) {
+	pubKey, _ := hex.DecodeString(benchPubKey)
+	b.SetBytes(int64(len(pubKey)))
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		bytesSink = btcutil.Hash160(pubKey)
+	}
+}
+
+// BenchmarkAmountString benchmarks formatting an amount with String.
+func BenchmarkAmountString(b *testing.B) {
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		stringSink = benchAmount.String()
+	}
+}
+
+// BenchmarkAmountFormatWithOptions benchmarks formatting an amount for
+// display with grouped digits.
+func BenchmarkAmountFormatWithOptions(b *testing.B) {
+	opts := btcutil.FormatOptions{Unit: btcutil.AmountOMC, GroupSeparator: ","}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		stringSink = benchAmount.FormatWithOptions(opts)
+	}
+}
+
+// TestAllocBudgets ensures the hot paths benchmarked above allocate no more
+// than their budgets, so an allocation added to code run for every address,
+// hash or amount fails the tests instead of showing up in the profiles of
+// consumers.  Budgets should be lowered when an optimization makes them
+// slack.  They are upper bounds, since newer Go releases keep some small
+// buffers on the stack and allocate less.
+func TestAllocBudgets(t *testing.T) {
+	addr, err := btcutil.DecodeAddress(benchAddr, &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("DecodeAddress: unexpected error: %v", err)
+	}
+	pubKey, _ := hex.DecodeString(benchPubKey)
+	opts := btcutil.FormatOptions{Unit: btcutil.AmountOMC, GroupSeparator: ","}
+
+	tests := []struct {
+		name   string
+		budget float64
+		f      func()
+	}{
+		{"DecodeAddress", 8, func() {
+			addrSink, _ = btcutil.DecodeAddress(benchAddr,
+				&chaincfg.MainNetParams)
+		}},
+		{"EncodeAddress", 4, func() { stringSink = addr.EncodeAddress() }},
+		{"Hash160", 5, func() { bytesSink = btcutil.Hash160(pubKey) }},
+		{"Amount.String", 3, func() { stringSink = benchAmount.String() }},
+		{"Amount.FormatWithOptions", 6, func() {
+			stringSink = benchAmount.FormatWithOptions(opts)
+		}},
+	}
+	for _, test := range tests {
+		allocs := testing.AllocsPerRun(100, test.f)
+		if allocs > test.budget {
+			t.Errorf("%s: got %v allocations, want at most %v",
+				test.name, allocs, test.budget)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package btcutil
+
+import "math/big"
+
+// BigAmount is a number of Hao of any size.  It holds aggregates which may
+// not fit in an Amount, such as totals across many accounts or token types,
+// so reporting never silently truncates them.  BigAmounts are immutable and
+// their zero value is zero Hao.
+type BigAmount struct {
+	hao *big.Int
+}
+
+// NewBigAmount returns the BigAmount of a.
+func NewBigAmount(a Amount) BigAmount {
+	return BigAmount{hao: big.NewInt(int64(a))}
+}
+
+// NewBigAmountFromInt returns the BigAmount of hao Hao.  The integer is
+// copied, so the caller may modify it afterwards.
+func NewBigAmountFromInt(hao *big.Int) BigAmount {
+	return BigAmount{hao: new(big.Int).Set(hao)}
+}
+
+// SumBig returns the exact sum of amounts, unlike SumChecked which fails
+// when it does not fit in an Amount.
+func SumBig(amounts []Amount) BigAmount {
+	sum := new(big.Int)
+	var a big.Int
+	for _, amt := range amounts {
+		sum.Add(sum, a.SetInt64(int64(amt)))
+	}
+	return BigAmount{hao: sum}
+}
+
+// int returns the number of Hao of the amount, which must not be modified.
+func (b BigAmount) int() *big.Int {
+	if b.hao == nil {
+		return new(big.Int)
+	}
+	return b.hao
+}
+
+// Int returns a copy of the number of Hao of the amount.
+func (b BigAmount) Int() *big.Int {
+	return new(big.Int).Set(b.int())
+}
+
+// Amount returns the amount as an Amount, or ErrAmountOverflow when it does
+// not fit in one.
+func (b BigAmount) Amount() (Amount, error) {
+	if !b.int().IsInt64() {
+		return 0, ErrAmountOverflow
+	}
+	return Amount(b.int().Int64()), nil
+}
+
+// Sign returns -1, 0 or +1 depending on whether the amount is negative, zero
+// or positive.
+func (b BigAmount) Sign() int {
+	return b.int().Sign()
+}
+
+// Cmp compares the amount with o and returns -1, 0 or +1 depending on
+// whether it is less than, equal to or greater than o.
+func (b BigAmount) Cmp(o BigAmount) int {
+	return b.int().Cmp(o.int())
+}
+
+// Add returns the sum of the amount and o.
+func (b BigAmount) Add(o BigAmount) BigAmount {
+	return BigAmount{hao: new(big.Int).Add(b.int(), o.int())}
+}
+
+// AddAmount returns the sum of the amount and a.
+func (b BigAmount) AddAmount(a Amount) BigAmount {
+	return BigAmount{hao: new(big.Int).Add(b.int(), big.NewInt(int64(a)))}
+}
+
+// Sub returns the difference of the amount and o.
+func (b BigAmount) Sub(o BigAmount) BigAmount {
+	return BigAmount{hao: new(big.Int).Sub(b.int(), o.int())}
+}
+
+// Neg returns the negation of the amount.
+func (b BigAmount) Neg() BigAmount {
+	return BigAmount{hao: new(big.Int).Neg(b.int())}
+}
+
+// Format formats the amount in the unit u followed by the unit label, as
+// Amount.Format does.  The conversion is exact for every amount since no
+// floating point math is involved.
+func (b BigAmount) Format(u AmountUnit) string {
+	return b.FormatWithOptions(FormatOptions{
+		Unit:              u,
+		TrimTrailingZeros: true,
+	})
+}
+
+// String is the equivalent of calling Format with AmountOMC.
+func (b BigAmount) String() string {
+	return b.Format(AmountOMC)
+}
+
+// FormatWithOptions formats the amount for display according to the passed
+// options, as Amount.FormatWithOptions does.
+func (b BigAmount) FormatWithOptions(opts FormatOptions) string {
+	mag := new(big.Int).Abs(b.int())
+	whole, frac := splitDigits(mag.String(), int(opts.Unit)+OMCDecimals)
+	return formatWithOptions(b.Sign() < 0, b.Sign() > 0, whole, frac, opts)
+}
+
+// ParseBigAmount parses a string accepted by ParseAmount into a BigAmount,
+// without limiting its range.
+func ParseBigAmount(s string) (BigAmount, error) {
+	num, unit, err := splitUnit(s)
+	if err != nil {
+		return BigAmount{}, err
+	}
+	n, err := parseDecimalInt(num, int(unit)+OMCDecimals)
+	if err != nil {
+		return BigAmount{}, err
+	}
+	return BigAmount{hao: n}, nil
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.  The amount is
+// encoded as an exact decimal number of OMC without a unit label, as
+// Amount.MarshalText does.
+func (b BigAmount) MarshalText() ([]byte, error) {
+	return []byte(b.FormatWithOptions(FormatOptions{
+		TrimTrailingZeros: true,
+		UnitPlacement:     UnitNone,
+	})), nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface.  It accepts
+// any string accepted by ParseBigAmount.
+func (b *BigAmount) UnmarshalText(text []byte) error {
+	amt, err := ParseBigAmount(string(text))
+	if err != nil {
+		return err
+	}
+	*b = amt
+	return nil
+}
@@ -0,0 +1,125 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package btcutil_test
+
+import (
+	"encoding/json"
+	"math"
+	"math/big"
+	"testing"
+
+	"github.com/zeusyf/btcutil"
+)
+
+// TestBigAmount ensures big amounts hold sums beyond the range of an Amount
+// exactly and convert back to Amounts only when they fit.
+func TestBigAmount(t *testing.T) {
+	var zero btcutil.BigAmount
+	if zero.Sign() != 0 || zero.String() != "0 OMC" {
+		t.Errorf("BigAmount: zero value is %v", zero)
+	}
+
+	sum := btcutil.SumBig([]btcutil.Amount{math.MaxInt64, math.MaxInt64, 2})
+	want, _ := new(big.Int).SetString("18446744073709551616", 10)
+	if sum.Int().Cmp(want) != 0 {
+		t.Errorf("SumBig: got %v, want %v", sum.Int(), want)
+	}
+	if _, err := sum.Amount(); err != btcutil.ErrAmountOverflow {
+		t.Errorf("Amount: got error %v, want %v", err,
+			btcutil.ErrAmountOverflow)
+	}
+
+	max := btcutil.NewBigAmount(math.MaxInt64)
+	back := sum.Sub(max).Sub(max).AddAmount(-2)
+	if back.Sign() != 0 || back.Cmp(zero) != 0 {
+		t.Errorf("Sub: got %v, want zero", back)
+	}
+	if a, err := max.Neg().Add(btcutil.NewBigAmount(-1)).Amount(); err != nil ||
+		a != math.MinInt64 {
+		t.Errorf("Amount: got %v (%v), want %v", a, err,
+			btcutil.Amount(math.MinInt64))
+	}
+
+	n := big.NewInt(5)
+	b := btcutil.NewBigAmountFromInt(n)
+	n.SetInt64(6)
+	if a, _ := b.Amount(); a != 5 {
+		t.Errorf("NewBigAmountFromInt: amount changed to %v", a)
+	}
+}
+
+// TestBigAmountFormat ensures big amounts format and parse like Amounts, and
+// exactly beyond their range.
+func TestBigAmountFormat(t *testing.T) {
+	amounts := []btcutil.Amount{0, 1, -150000000, 123456789012, 1 << 40}
+	units := []btcutil.AmountUnit{btcutil.AmountOMC, btcutil.AmountMilliOMC,
+		btcutil.AmountHao}
+	opts := btcutil.FormatOptions{
+		Unit:              btcutil.AmountKiloOMC,
+		GroupSeparator:    ",",
+		TrimTrailingZeros: true,
+		ExplicitSign:      true,
+	}
+	for _, a := range amounts {
+		b := btcutil.NewBigAmount(a)
+		for _, u := range units {
+			if got, want := b.Format(u), a.Format(u); got != want {
+				t.Errorf("Format(%v): got %q, want %q", u, got, want)
+			}
+		}
+		if got, want := b.FormatWithOptions(opts),
+			a.FormatWithOptions(opts); got != want {
+			t.Errorf("FormatWithOptions: got %q, want %q", got, want)
+		}
+		gotText, _ := b.MarshalText()
+		wantText, _ := a.MarshalText()
+		if string(gotText) != string(wantText) {
+			t.Errorf("MarshalText: got %q, want %q", gotText, wantText)
+		}
+	}
+
+	sum := btcutil.SumBig([]btcutil.Amount{math.MaxInt64, math.MaxInt64, 2})
+	if got, want := sum.String(), "184467440737.09551616 OMC"; got != want {
+		t.Errorf("String: got %q, want %q", got, want)
+	}
+	if got, want := sum.FormatWithOptions(btcutil.FormatOptions{
+		GroupSeparator: ",",
+	}), "184,467,440,737.09551616 OMC"; got != want {
+		t.Errorf("FormatWithOptions: got %q, want %q", got, want)
+	}
+
+	var report struct {
+		Total btcutil.BigAmount `json:"total"`
+	}
+	report.Total = sum
+	encoded, err := json.Marshal(report)
+	if err != nil {
+		t.Fatalf("Marshal: unexpected error: %v", err)
+	}
+	if want := `{"total":"184467440737.09551616"}`; string(encoded) != want {
+		t.Errorf("Marshal: got %s, want %s", encoded, want)
+	}
+	report.Total = btcutil.BigAmount{}
+	if err := json.Unmarshal(encoded, &report); err != nil {
+		t.Fatalf("Unmarshal: unexpected error: %v", err)
+	}
+	if report.Total.Cmp(sum) != 0 {
+		t.Errorf("Unmarshal: got %v, want %v", report.Total, sum)
+	}
+
+	parsed, err := btcutil.ParseBigAmount("-184467440737.09551616 kOMC")
+	if err != nil {
+		t.Fatalf("ParseBigAmount: unexpected error: %v", err)
+	}
+	if got, want := parsed.Format(btcutil.AmountKiloOMC),
+		"-184467440737.09551616 kOMC"; got != want {
+		t.Errorf("ParseBigAmount: got %q, want %q", got, want)
+	}
+	for _, s := range []string{"", "1.5 XYZ", "0.000000001", "1e3"} {
+		if _, err := btcutil.ParseBigAmount(s); err == nil {
+			t.Errorf("ParseBigAmount(%q): unexpected success", s)
+		}
+	}
+}
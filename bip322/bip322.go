@@ -0,0 +1,280 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package bip322 implements generic signed messages following BIP0322, which
+// prove control of any public key script rather than only of the key behind a
+// pay-to-pubkey-hash address.
+//
+// A proof is the signature of a virtual transaction, to_sign, spending the
+// only output of another virtual transaction, to_spend, which pays to the
+// public key script being proven and commits to the message.  Since neither
+// is valid on chain, a proof can not be used to move funds.  Verifying a
+// proof means executing the script of its signature against the public key
+// script, so callers supply the script engine through a ScriptVerifier, and
+// likewise the wallet producing signatures through an InputSigner.
+//
+// Omega transactions keep signature scripts out of the transaction hash, so
+// to_spend commits to the message hash through the previous outpoint of its
+// input instead of through its signature script as in Bitcoin.
+package bip322
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcd/txscript"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcd/wire/common"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/omega/token"
+)
+
+const (
+	// messageTag is the tag of the tagged hash of a message.
+	messageTag = "BIP0322-signed-message"
+
+	// opReturn is the opcode of the only output of to_sign, which makes
+	// it unspendable.
+	opReturn = 0x6a
+
+	// varIntProtoVer is the protocol version to use for serializing
+	// variable length integers.
+	varIntProtoVer uint32 = 0
+
+	// maxSigScriptLen is the largest signature script accepted in a simple
+	// proof.  It guards against allocating huge buffers for corrupt or
+	// malicious lengths.
+	maxSigScriptLen = 100000
+)
+
+var (
+	// ErrInvalidProof describes an error where a proof can not be decoded,
+	// or a full proof is not a valid to_sign transaction for the message
+	// and public key script.
+	ErrInvalidProof = errors.New("malformed message proof")
+
+	// ErrUnknownFormat describes an error where a proof format other than
+	// FormatSimple or FormatFull is requested.
+	ErrUnknownFormat = errors.New("unknown proof format")
+)
+
+// Format selects the encoding of a proof.
+type Format uint8
+
+const (
+	// FormatSimple encodes only the signature script of to_sign, which
+	// suffices for scripts without time locks.
+	FormatSimple Format = iota
+
+	// FormatFull encodes the complete to_sign transaction, so scripts
+	// which check the version, lock time or sequence of the spending
+	// transaction can be proven.
+	FormatFull
+)
+
+// String returns the Format in human-readable form.
+func (f Format) String() string {
+	switch f {
+	case FormatSimple:
+		return "simple"
+	case FormatFull:
+		return "full"
+	}
+	return fmt.Sprintf("Unknown Format (%d)", uint8(f))
+}
+
+// InputSigner produces the signature script for an input of a transaction
+// spending an output with the passed public key script, such as a wallet
+// holding the keys of the script.  When producing a full proof for a script
+// with time locks, the signer may set the version, lock time and sequence of
+// tx before signing it; simple proofs must leave them unchanged.
+type InputSigner interface {
+	SignInput(tx *wire.MsgTx, index int, pkScript []byte) ([]byte, error)
+}
+
+// InputSignerFunc is an adapter allowing a function to be used as an
+// InputSigner.
+type InputSignerFunc func(tx *wire.MsgTx, index int, pkScript []byte) ([]byte, error)
+
+// SignInput calls f(tx, index, pkScript).
+func (f InputSignerFunc) SignInput(tx *wire.MsgTx, index int, pkScript []byte) ([]byte, error) {
+	return f(tx, index, pkScript)
+}
+
+// ScriptVerifier executes the signature script of an input of a transaction
+// against the public key script of the output it spends, such as the script
+// engine of a node, and returns an error unless the script succeeds.
+type ScriptVerifier interface {
+	VerifyInput(tx *wire.MsgTx, index int, pkScript []byte) error
+}
+
+// ScriptVerifierFunc is an adapter allowing a function to be used as a
+// ScriptVerifier.
+type ScriptVerifierFunc func(tx *wire.MsgTx, index int, pkScript []byte) error
+
+// VerifyInput calls f(tx, index, pkScript).
+func (f ScriptVerifierFunc) VerifyInput(tx *wire.MsgTx, index int, pkScript []byte) error {
+	return f(tx, index, pkScript)
+}
+
+// MessageHash returns the tagged hash of the message committed to by
+// to_spend: SHA256(SHA256(tag) || SHA256(tag) || message) with the tag
+// "BIP0322-signed-message".
+func MessageHash(message []byte) chainhash.Hash {
+	tag := sha256.Sum256([]byte(messageTag))
+	h := sha256.New()
+	h.Write(tag[:])
+	h.Write(tag[:])
+	h.Write(message)
+
+	var hash chainhash.Hash
+	copy(hash[:], h.Sum(nil))
+	return hash
+}
+
+// zeroOutput returns an output of no value paying to pkScript.
+func zeroOutput(pkScript []byte) *wire.TxOut {
+	return &wire.TxOut{
+		Token: token.Token{
+			TokenType: 0,
+			Value:     &token.NumeralVal{Val: 0},
+		},
+		PkScript: pkScript,
+	}
+}
+
+// ToSpend returns the virtual transaction whose only output pays no value to
+// pkScript and whose hash commits to the message.
+func ToSpend(pkScript, message []byte) *wire.MsgTx {
+	tx := wire.NewMsgTx(0)
+	tx.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: wire.OutPoint{
+			Hash:  MessageHash(message),
+			Index: math.MaxUint32,
+		},
+		Sequence:       0,
+		SignatureIndex: math.MaxUint32,
+	})
+	tx.AddTxOut(zeroOutput(pkScript))
+	return tx
+}
+
+// ToSign returns the unsigned virtual transaction spending the output of
+// toSpend, whose signature is the proof.  Its only output is unspendable.
+func ToSign(toSpend *wire.MsgTx) *wire.MsgTx {
+	tx := wire.NewMsgTx(0)
+	tx.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: wire.OutPoint{Hash: toSpend.TxHash(), Index: 0},
+		Sequence:         0,
+		SignatureIndex:   0,
+	})
+	tx.AddTxOut(zeroOutput([]byte{opReturn}))
+	return tx
+}
+
+// Sign returns a proof in the passed format that signer controls pkScript,
+// signing message.
+func Sign(signer InputSigner, pkScript, message []byte, format Format) ([]byte, error) {
+	if format != FormatSimple && format != FormatFull {
+		return nil, ErrUnknownFormat
+	}
+
+	toSign := ToSign(ToSpend(pkScript, message))
+	sigScript, err := signer.SignInput(toSign, 0, pkScript)
+	if err != nil {
+		return nil, err
+	}
+	toSign.SignatureScripts = [][]byte{sigScript}
+
+	var buf bytes.Buffer
+	if format == FormatFull {
+		if err := toSign.Serialize(&buf); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+	err = common.WriteVarInt(&buf, varIntProtoVer, uint64(len(sigScript)))
+	if err != nil {
+		return nil, err
+	}
+	buf.Write(sigScript)
+	return buf.Bytes(), nil
+}
+
+// decodeSimple returns the to_sign transaction of a simple proof.
+func decodeSimple(toSpend *wire.MsgTx, proof []byte) (*wire.MsgTx, error) {
+	r := bytes.NewReader(proof)
+	n, err := common.ReadVarInt(r, varIntProtoVer)
+	if err != nil || n > maxSigScriptLen || n != uint64(r.Len()) {
+		return nil, ErrInvalidProof
+	}
+	toSign := ToSign(toSpend)
+	toSign.SignatureScripts = [][]byte{proof[len(proof)-int(n):]}
+	return toSign, nil
+}
+
+// decodeFull returns the to_sign transaction of a full proof after checking
+// that it spends the output of toSpend to an unspendable output.  The
+// version, lock time and sequence are left to the signer.
+func decodeFull(toSpend *wire.MsgTx, proof []byte) (*wire.MsgTx, error) {
+	r := bytes.NewReader(proof)
+	toSign := new(wire.MsgTx)
+	if err := toSign.Deserialize(r); err != nil || r.Len() != 0 {
+		return nil, ErrInvalidProof
+	}
+
+	want := ToSign(toSpend)
+	if len(toSign.TxIn) != 1 || len(toSign.TxOut) != 1 {
+		return nil, ErrInvalidProof
+	}
+	in, out := toSign.TxIn[0], toSign.TxOut[0]
+	if in.PreviousOutPoint != want.TxIn[0].PreviousOutPoint ||
+		int(in.SignatureIndex) >= len(toSign.SignatureScripts) {
+		return nil, ErrInvalidProof
+	}
+	value, ok := out.Token.Value.(*token.NumeralVal)
+	if out.Token.TokenType != 0 || !ok || value.Val != 0 ||
+		!bytes.Equal(out.PkScript, []byte{opReturn}) {
+		return nil, ErrInvalidProof
+	}
+	return toSign, nil
+}
+
+// Verify verifies that proof, in the passed format, proves control of
+// pkScript by signing message.  ErrInvalidProof is returned for proofs which
+// can not be decoded or do not belong to the message and script, and the
+// error of verifier for proofs whose signature is invalid.
+func Verify(verifier ScriptVerifier, pkScript, message, proof []byte, format Format) error {
+	toSpend := ToSpend(pkScript, message)
+
+	var toSign *wire.MsgTx
+	var err error
+	switch format {
+	case FormatSimple:
+		toSign, err = decodeSimple(toSpend, proof)
+	case FormatFull:
+		toSign, err = decodeFull(toSpend, proof)
+	default:
+		err = ErrUnknownFormat
+	}
+	if err != nil {
+		return err
+	}
+	return verifier.VerifyInput(toSign, 0, pkScript)
+}
+
+// VerifyAddress verifies that proof, in the passed format, proves control of
+// addr by signing message.  It is Verify with the public key script paying to
+// addr.
+func VerifyAddress(verifier ScriptVerifier, addr btcutil.Address, message, proof []byte, format Format) error {
+	pkScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		return err
+	}
+	return Verify(verifier, pkScript, message, proof, format)
+}
@@ -0,0 +1,173 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package bip322_test
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcd/txscript"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/bip322"
+)
+
+// errBadSignature is returned by testVerifier for signatures which do not
+// verify.
+var errBadSignature = errors.New("bad signature")
+
+// testSig is a stand-in for a script signature: an HMAC of the hash of the
+// spending transaction keyed by the public key script spent.  It lets the
+// tests check what the proofs commit to without a script engine.
+func testSig(tx *wire.MsgTx, pkScript []byte) []byte {
+	txHash := tx.TxHash()
+	mac := hmac.New(sha256.New, pkScript)
+	mac.Write(txHash[:])
+	return mac.Sum(nil)
+}
+
+var testSigner = bip322.InputSignerFunc(func(tx *wire.MsgTx, index int, pkScript []byte) ([]byte, error) {
+	return testSig(tx, pkScript), nil
+})
+
+var testVerifier = bip322.ScriptVerifierFunc(func(tx *wire.MsgTx, index int, pkScript []byte) error {
+	sigScript := tx.SignatureScripts[tx.TxIn[index].SignatureIndex]
+	if !hmac.Equal(sigScript, testSig(tx, pkScript)) {
+		return errBadSignature
+	}
+	return nil
+})
+
+// TestMessageHash ensures messages are hashed with the BIP0322 tag, using the
+// test vectors of the BIP.
+func TestMessageHash(t *testing.T) {
+	tests := []struct {
+		message string
+		want    string
+	}{
+		{"", "c90c269c4f8fcbe6880f72a721ddfbf1914268a794cbb21cfafee13770ae19f1"},
+		{"Hello World", "f0eb03b1a75ac6d9847f55c624a99169b5dccba2a31f5b23bea77ba270de0a7a"},
+	}
+	for _, test := range tests {
+		hash := bip322.MessageHash([]byte(test.message))
+		if got := hex.EncodeToString(hash[:]); got != test.want {
+			t.Errorf("MessageHash(%q): got %v, want %v", test.message,
+				got, test.want)
+		}
+	}
+}
+
+// TestSignVerify ensures proofs in both formats verify for the message and
+// script they were made for, and for nothing else.
+func TestSignVerify(t *testing.T) {
+	pkScript := []byte{0xa9, 0x14}
+	pkScript = append(pkScript, bytes.Repeat([]byte{0x11}, 20)...)
+	pkScript = append(pkScript, 0x87)
+	otherScript := append([]byte(nil), pkScript...)
+	otherScript[2] ^= 1
+	message := []byte("Hello World")
+
+	for _, format := range []bip322.Format{bip322.FormatSimple, bip322.FormatFull} {
+		proof, err := bip322.Sign(testSigner, pkScript, message, format)
+		if err != nil {
+			t.Fatalf("%v: Sign: unexpected error: %v", format, err)
+		}
+
+		tests := []struct {
+			name     string
+			pkScript []byte
+			message  []byte
+			proof    []byte
+			err      error
+		}{
+			{"valid", pkScript, message, proof, nil},
+			{"other script", otherScript, message, proof, nil},
+			{"other message", pkScript, []byte("Hello World!"), proof, nil},
+			{"truncated", pkScript, message, proof[:len(proof)-1], bip322.ErrInvalidProof},
+			{"trailing data", pkScript, message, append(proof[:len(proof):len(proof)], 0), bip322.ErrInvalidProof},
+		}
+		for _, test := range tests {
+			err := bip322.Verify(testVerifier, test.pkScript, test.message,
+				test.proof, format)
+			want := test.err
+			if test.name == "other script" || test.name == "other message" {
+				// Full proofs spend a different to_spend, which
+				// makes them malformed, while simple proofs only
+				// fail their signature check.
+				want = errBadSignature
+				if format == bip322.FormatFull {
+					want = bip322.ErrInvalidProof
+				}
+			}
+			if err != want {
+				t.Errorf("%v, %s: got error %v, want %v", format,
+					test.name, err, want)
+			}
+		}
+	}
+
+	if _, err := bip322.Sign(testSigner, pkScript, message, 2); err != bip322.ErrUnknownFormat {
+		t.Errorf("Sign: got error %v, want %v", err, bip322.ErrUnknownFormat)
+	}
+}
+
+// TestFullProofTimeLock ensures full proofs carry the lock time chosen by the
+// signer, which simple proofs can not.
+func TestFullProofTimeLock(t *testing.T) {
+	pkScript := []byte{0x51}
+	message := []byte("locked")
+	signer := bip322.InputSignerFunc(func(tx *wire.MsgTx, index int, pkScript []byte) ([]byte, error) {
+		tx.LockTime = 100
+		tx.TxIn[index].Sequence = 0xfffffffe
+		return testSig(tx, pkScript), nil
+	})
+
+	proof, err := bip322.Sign(signer, pkScript, message, bip322.FormatFull)
+	if err != nil {
+		t.Fatalf("Sign: unexpected error: %v", err)
+	}
+	err = bip322.Verify(testVerifier, pkScript, message, proof, bip322.FormatFull)
+	if err != nil {
+		t.Errorf("Verify full: unexpected error: %v", err)
+	}
+
+	proof, err = bip322.Sign(signer, pkScript, message, bip322.FormatSimple)
+	if err != nil {
+		t.Fatalf("Sign: unexpected error: %v", err)
+	}
+	err = bip322.Verify(testVerifier, pkScript, message, proof, bip322.FormatSimple)
+	if err != errBadSignature {
+		t.Errorf("Verify simple: got error %v, want %v", err, errBadSignature)
+	}
+}
+
+// TestVerifyAddress ensures proofs verify against the script paying to an
+// address.
+func TestVerifyAddress(t *testing.T) {
+	addr, err := btcutil.NewAddressScriptHashFromHash(
+		bytes.Repeat([]byte{0x22}, 20), &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pkScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	message := []byte("address")
+	proof, err := bip322.Sign(testSigner, pkScript, message, bip322.FormatSimple)
+	if err != nil {
+		t.Fatalf("Sign: unexpected error: %v", err)
+	}
+	err = bip322.VerifyAddress(testVerifier, addr, message, proof,
+		bip322.FormatSimple)
+	if err != nil {
+		t.Errorf("VerifyAddress: unexpected error: %v", err)
+	}
+}
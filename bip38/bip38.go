@@ -0,0 +1,238 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package bip38 implements passphrase-protected private keys as specified by
+// BIP0038, so paper backups of keys can be exchanged with other wallets.
+//
+// Encrypt and Decrypt protect an existing private key with a passphrase.  The
+// EC-multiply mode lets a party holding only an intermediate code, derived
+// from the passphrase by its owner with NewIntermediateCode, generate new
+// encrypted keys whose private keys only the owner can recover, as paper
+// wallet printers do.  The confirmation code returned along with such a key
+// lets the owner check the address of the key before funding it.
+//
+// As in BIP0038, the address of a key is the pay-to-pubkey-hash address of
+// its public key, compressed or not, on the network passed to the functions
+// of this package.  Passphrases are used as the bytes of the passed string;
+// callers should normalize them to Unicode NFC as BIP0038 requires.
+package bip38
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"math/big"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/base58"
+	"github.com/zeusyf/btcutil/kdf"
+)
+
+const (
+	// EncryptedKeyLen is the length of an encrypted key before base58
+	// encoding.
+	EncryptedKeyLen = 39
+
+	// prefixNonEC and prefixEC are the second byte of encrypted keys
+	// without and with EC multiplication, following the common first
+	// byte 0x01.
+	prefixNonEC = 0x42
+	prefixEC    = 0x43
+
+	// flagNonEC is set in the flag byte of keys encrypted without EC
+	// multiplication, flagCompressed when the address of the key uses
+	// the compressed public key, and flagLotSequence when an EC-multiplied
+	// key carries a lot and sequence number.
+	flagNonEC       = 0xc0
+	flagCompressed  = 0x20
+	flagLotSequence = 0x04
+)
+
+var (
+	// scryptParams are the scrypt parameters deriving keys from the
+	// passphrase.
+	scryptParams = kdf.Params{Algorithm: kdf.Scrypt, N: 16384, R: 8, P: 8}
+
+	// ErrInvalidKey describes an error where an encrypted key is not a
+	// valid BIP0038 encoding.
+	ErrInvalidKey = errors.New("malformed BIP0038 encrypted key")
+
+	// ErrWrongPassphrase describes an error where the key decrypted with
+	// a passphrase does not match the address hash of the encrypted key,
+	// which happens when the passphrase is wrong.
+	ErrWrongPassphrase = errors.New("wrong BIP0038 passphrase")
+)
+
+// addressHash returns the hash committing to the address of the serialized
+// public key pubKey on the network net: the first four bytes of the double
+// SHA-256 of the encoded address.
+func addressHash(pubKey []byte, net *chaincfg.Params) ([]byte, error) {
+	addr, err := addressOf(pubKey, net)
+	if err != nil {
+		return nil, err
+	}
+	return chainhash.DoubleHashB([]byte(addr.EncodeAddress()))[:4], nil
+}
+
+// addressOf returns the pay-to-pubkey-hash address of the serialized public
+// key pubKey on the network net.
+func addressOf(pubKey []byte, net *chaincfg.Params) (*btcutil.AddressPubKeyHash, error) {
+	return btcutil.NewAddressPubKeyHash(btcutil.Hash160(pubKey), net)
+}
+
+// encode returns the base58 check encoding of payload, whose first byte is
+// used as the version.
+func encode(payload []byte) string {
+	return base58.CheckEncode(payload[1:], payload[0])
+}
+
+// decode returns the payload of the base58 check encoding s, version included,
+// or nil when s is not a valid base58 check encoding.
+func decode(s string) []byte {
+	b, version, err := base58.CheckDecode(s)
+	if err != nil {
+		return nil
+	}
+	return append([]byte{version}, b...)
+}
+
+// xor returns a xor b, both of the same length.
+func xor(a, b []byte) []byte {
+	c := make([]byte, len(a))
+	for i := range a {
+		c[i] = a[i] ^ b[i]
+	}
+	return c
+}
+
+// encryptHalves returns the encryption with block of the two 16-byte halves
+// of data, each first xored with the matching half of mask.
+func encryptHalves(block cipher.Block, data, mask []byte) []byte {
+	out := xor(data, mask)
+	block.Encrypt(out[:16], out[:16])
+	block.Encrypt(out[16:], out[16:])
+	return out
+}
+
+// decryptHalves reverts encryptHalves.
+func decryptHalves(block cipher.Block, data, mask []byte) []byte {
+	out := make([]byte, 32)
+	block.Decrypt(out[:16], data[:16])
+	block.Decrypt(out[16:], data[16:])
+	return xor(out, mask)
+}
+
+// validScalar returns whether b, as a big endian integer, is a valid private
+// key: non-zero and less than the order of the curve.
+func validScalar(b []byte) bool {
+	k := new(big.Int).SetBytes(b)
+	return k.Sign() != 0 && k.Cmp(btcec.S256().N) < 0
+}
+
+// scalarBytes returns k as a 32-byte big endian integer.
+func scalarBytes(k *big.Int) []byte {
+	b := make([]byte, 32)
+	kb := k.Bytes()
+	copy(b[32-len(kb):], kb)
+	return b
+}
+
+// serializePubKey serializes pubKey compressed or uncompressed.
+func serializePubKey(pubKey *btcec.PublicKey, compressed bool) []byte {
+	if compressed {
+		return pubKey.SerializeCompressed()
+	}
+	return pubKey.SerializeUncompressed()
+}
+
+// Encrypt encrypts the private key of wif with passphrase without EC
+// multiplication.  The address hash of the encrypted key commits to the
+// address of the key on the network net, which must be passed again to
+// Decrypt.
+func Encrypt(wif *btcutil.WIF, passphrase string, net *chaincfg.Params) (string, error) {
+	flag := byte(flagNonEC)
+	if wif.CompressPubKey {
+		flag |= flagCompressed
+	}
+	addrHash, err := addressHash(wif.SerializePubKey(), net)
+	if err != nil {
+		return "", err
+	}
+	derived, err := scryptParams.Key([]byte(passphrase), addrHash, 64)
+	if err != nil {
+		return "", err
+	}
+	block, err := aes.NewCipher(derived[32:])
+	if err != nil {
+		return "", err
+	}
+
+	payload := make([]byte, 0, EncryptedKeyLen)
+	payload = append(payload, 0x01, prefixNonEC, flag)
+	payload = append(payload, addrHash...)
+	payload = append(payload, encryptHalves(block,
+		scalarBytes(wif.PrivKey.D), derived[:32])...)
+	return encode(payload), nil
+}
+
+// Decrypt decrypts encrypted, a key encrypted with or without EC
+// multiplication, with passphrase and returns its private key.
+// ErrWrongPassphrase is returned when the decrypted key does not match the
+// address hash of encrypted, such as when the passphrase is wrong or the key
+// was encrypted for another network than net.
+func Decrypt(encrypted, passphrase string, net *chaincfg.Params) (*btcutil.WIF, error) {
+	payload := decode(encrypted)
+	if len(payload) != EncryptedKeyLen || payload[0] != 0x01 {
+		return nil, ErrInvalidKey
+	}
+	flag := payload[2]
+	var privKey []byte
+	var err error
+	switch {
+	case payload[1] == prefixNonEC && flag&^flagCompressed == flagNonEC:
+		privKey, err = decryptNonEC(payload, passphrase)
+	case payload[1] == prefixEC && flag&^(flagCompressed|flagLotSequence) == 0:
+		privKey, err = decryptEC(payload, passphrase)
+	default:
+		return nil, ErrInvalidKey
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !validScalar(privKey) {
+		return nil, ErrWrongPassphrase
+	}
+
+	key, _ := btcec.PrivKeyFromBytes(btcec.S256(), privKey)
+	wif, err := btcutil.NewWIF(key, net, flag&flagCompressed != 0)
+	if err != nil {
+		return nil, err
+	}
+	addrHash, err := addressHash(wif.SerializePubKey(), net)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(addrHash, payload[3:7]) {
+		return nil, ErrWrongPassphrase
+	}
+	return wif, nil
+}
+
+// decryptNonEC returns the private key of payload, a key encrypted without EC
+// multiplication, decrypted with passphrase.
+func decryptNonEC(payload []byte, passphrase string) ([]byte, error) {
+	derived, err := scryptParams.Key([]byte(passphrase), payload[3:7], 64)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(derived[32:])
+	if err != nil {
+		return nil, err
+	}
+	return decryptHalves(block, payload[7:], derived[:32]), nil
+}
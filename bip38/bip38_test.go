@@ -0,0 +1,78 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package bip38_test
+
+import (
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/bip38"
+)
+
+// TestEncryptDecrypt ensures keys encrypted without EC multiplication match
+// the test vectors of BIP0038 and decrypt back.
+func TestEncryptDecrypt(t *testing.T) {
+	tests := []struct {
+		passphrase string
+		encrypted  string
+		wif        string
+	}{
+		{
+			passphrase: "TestingOneTwoThree",
+			encrypted:  "6PRVWUbkzzsbcVac2qwfssoUJAN1Xhrg6bNk8J7Nzm5H7kxEbn2Nh2ZoGg",
+			wif:        "5KN7MzqK5wt2TP1fQCYyHBtDrXdJuXbUzm4A9rKAteGu3Qi5CVR",
+		},
+		{
+			passphrase: "Satoshi",
+			encrypted:  "6PRNFFkZc2NZ6dJqFfhRoFNMR9Lnyj7dYGrzdgXXVMXcxoKTePPX1dWByq",
+			wif:        "5HtasZ6ofTHP6HCwTqTkLDuLQisYPah7aUnSKfC7h4hMUVw2gi5",
+		},
+		{
+			passphrase: "TestingOneTwoThree",
+			encrypted:  "6PYNKZ1EAgYgmQfmNVamxyXVWHzK5s6DGhwP4J5o44cvXdoY7sRzhtpUeo",
+			wif:        "L44B5gGEpqEDRS9vVPz7QT35jcBG2r3CZwSwQ4fCewXAhAhqGVpP",
+		},
+		{
+			passphrase: "Satoshi",
+			encrypted:  "6PYLtMnXvfG3oJde97zRyLYFZCYizPU5T3LwgdYJz1fRhh16bU7u6PPmY7",
+			wif:        "KwYgW8gcxj1JWJXhPSu4Fqwzfhp5Yfi42mdYmMa4XqK7NJxXUSK7",
+		},
+	}
+
+	net := &chaincfg.MainNetParams
+	for i, test := range tests {
+		wif, err := btcutil.DecodeWIF(test.wif)
+		if err != nil {
+			t.Fatalf("#%d DecodeWIF: %v", i, err)
+		}
+		encrypted, err := bip38.Encrypt(wif, test.passphrase, net)
+		if err != nil || encrypted != test.encrypted {
+			t.Errorf("#%d Encrypt: got %s, %v, want %s", i, encrypted,
+				err, test.encrypted)
+		}
+		decrypted, err := bip38.Decrypt(test.encrypted, test.passphrase, net)
+		if err != nil || decrypted.String() != test.wif {
+			t.Errorf("#%d Decrypt: got %v, %v, want %s", i, decrypted,
+				err, test.wif)
+		}
+	}
+
+	if _, err := bip38.Decrypt(tests[0].encrypted, "wrong", net); err != bip38.ErrWrongPassphrase {
+		t.Errorf("Decrypt: got error %v, want %v", err,
+			bip38.ErrWrongPassphrase)
+	}
+	invalid := []string{
+		"",
+		tests[0].wif,
+		tests[0].encrypted[:len(tests[0].encrypted)-1] + "h",
+	}
+	for i, encrypted := range invalid {
+		if _, err := bip38.Decrypt(encrypted, "", net); err != bip38.ErrInvalidKey {
+			t.Errorf("#%d Decrypt: got error %v, want %v", i, err,
+				bip38.ErrInvalidKey)
+		}
+	}
+}
@@ -0,0 +1,349 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package bip38
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/big"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/entropy"
+	"github.com/zeusyf/btcutil/kdf"
+)
+
+const (
+	// MaxLot is the exclusive upper bound of lot numbers.
+	MaxLot = 1 << 20
+
+	// MaxSequence is the exclusive upper bound of sequence numbers.
+	MaxSequence = 1 << 12
+
+	// intermediateLen and confirmationLen are the lengths of intermediate
+	// and confirmation codes before base58 encoding.
+	intermediateLen = 49
+	confirmationLen = 51
+
+	// seedLen is the length of the random seed of generated keys.
+	seedLen = 24
+)
+
+var (
+	// ecScryptParams are the scrypt parameters deriving the encryption key
+	// of generated keys from the passpoint.
+	ecScryptParams = kdf.Params{Algorithm: kdf.Scrypt, N: 1024, R: 1, P: 1}
+
+	// intermediateMagic is the magic of intermediate codes, whose last
+	// byte is 0x51 without and 0x53 with a lot and sequence number.
+	intermediateMagic = []byte{0x2c, 0xe9, 0xb3, 0xe1, 0xff, 0x39, 0xe2}
+
+	// confirmationMagic is the magic of confirmation codes.
+	confirmationMagic = []byte{0x64, 0x3b, 0xf6, 0xa8, 0x9a}
+)
+
+var (
+	// ErrInvalidIntermediate describes an error where an intermediate code
+	// is not a valid BIP0038 encoding.
+	ErrInvalidIntermediate = errors.New("malformed BIP0038 intermediate code")
+
+	// ErrInvalidConfirmation describes an error where a confirmation code
+	// is not a valid BIP0038 encoding.
+	ErrInvalidConfirmation = errors.New("malformed BIP0038 confirmation code")
+
+	// ErrInvalidLotSequence describes an error where a lot number is not
+	// less than MaxLot or a sequence number not less than MaxSequence.
+	ErrInvalidLotSequence = errors.New("BIP0038 lot or sequence out of range")
+
+	// ErrInvalidFactor describes an error where a factor derived from a
+	// passphrase or a random seed is not a valid private key.  It happens
+	// with negligible probability, and trying again with another owner
+	// salt or seed succeeds.
+	ErrInvalidFactor = errors.New("BIP0038 factor is not a valid scalar")
+)
+
+// GeneratedKey is a key generated from an intermediate code by
+// GenerateEncryptedKey.
+type GeneratedKey struct {
+	// Encrypted is the encrypted key, which Decrypt decrypts with the
+	// passphrase of the intermediate code.
+	Encrypted string
+
+	// Confirmation is the confirmation code proving Address is the address
+	// of Encrypted to the owner of the passphrase, checked with
+	// VerifyConfirmation.
+	Confirmation string
+
+	// Address is the address of the key.
+	Address *btcutil.AddressPubKeyHash
+}
+
+// derivePassFactor returns the factor derived from passphrase and the owner
+// entropy of an intermediate code, which is also the owner salt when
+// lotSequence is false.
+func derivePassFactor(passphrase string, ownerEntropy []byte, lotSequence bool) ([]byte, error) {
+	ownerSalt := ownerEntropy
+	if lotSequence {
+		ownerSalt = ownerEntropy[:4]
+	}
+	factor, err := scryptParams.Key([]byte(passphrase), ownerSalt, 32)
+	if err != nil {
+		return nil, err
+	}
+	if lotSequence {
+		factor = chainhash.DoubleHashB(append(factor, ownerEntropy...))
+	}
+	if !validScalar(factor) {
+		return nil, ErrInvalidFactor
+	}
+	return factor, nil
+}
+
+// pointOf returns the compressed serialization of factor times the generator.
+func pointOf(factor []byte) []byte {
+	curve := btcec.S256()
+	x, y := curve.ScalarBaseMult(factor)
+	pubKey := btcec.PublicKey{Curve: curve, X: x, Y: y}
+	return pubKey.SerializeCompressed()
+}
+
+// multiply returns factor times pubKey.
+func multiply(pubKey *btcec.PublicKey, factor []byte) *btcec.PublicKey {
+	curve := btcec.S256()
+	x, y := curve.ScalarMult(pubKey.X, pubKey.Y, factor)
+	return &btcec.PublicKey{Curve: curve, X: x, Y: y}
+}
+
+// ecDerived returns the key material encrypting a generated key: the scrypt
+// derivation of its passpoint salted with its address hash and owner entropy.
+func ecDerived(passPoint, addrHash, ownerEntropy []byte) ([]byte, error) {
+	salt := append(append([]byte{}, addrHash...), ownerEntropy...)
+	return ecScryptParams.Key(passPoint, salt, 64)
+}
+
+// NewIntermediateCode returns an intermediate code of passphrase without a
+// lot and sequence number.  The random owner salt is read from src, or from
+// the default source of the entropy package when src is nil.
+func NewIntermediateCode(passphrase string, src io.Reader) (string, error) {
+	ownerEntropy := make([]byte, 8)
+	if err := entropy.Read(src, ownerEntropy); err != nil {
+		return "", err
+	}
+	return intermediateCode(passphrase, ownerEntropy, false)
+}
+
+// NewIntermediateCodeWithLot returns an intermediate code of passphrase
+// carrying a lot and sequence number, which are recovered from the keys
+// generated from it by LotSequence.  The random owner salt is read from src,
+// or from the default source of the entropy package when src is nil.
+func NewIntermediateCodeWithLot(passphrase string, lot, sequence uint32,
+	src io.Reader) (string, error) {
+	if lot >= MaxLot || sequence >= MaxSequence {
+		return "", ErrInvalidLotSequence
+	}
+	ownerEntropy := make([]byte, 8)
+	if err := entropy.Read(src, ownerEntropy[:4]); err != nil {
+		return "", err
+	}
+	binary.BigEndian.PutUint32(ownerEntropy[4:], lot*MaxSequence+sequence)
+	return intermediateCode(passphrase, ownerEntropy, true)
+}
+
+// intermediateCode returns the intermediate code of passphrase with the owner
+// entropy ownerEntropy.
+func intermediateCode(passphrase string, ownerEntropy []byte, lotSequence bool) (string, error) {
+	factor, err := derivePassFactor(passphrase, ownerEntropy, lotSequence)
+	if err != nil {
+		return "", err
+	}
+	payload := make([]byte, 0, intermediateLen)
+	payload = append(payload, intermediateMagic...)
+	if lotSequence {
+		payload = append(payload, 0x53)
+	} else {
+		payload = append(payload, 0x51)
+	}
+	payload = append(payload, ownerEntropy...)
+	payload = append(payload, pointOf(factor)...)
+	return encode(payload), nil
+}
+
+// GenerateEncryptedKey generates a new key from intermediate, an intermediate
+// code returned by NewIntermediateCode or NewIntermediateCodeWithLot, and
+// returns it encrypted with the passphrase of the intermediate code along
+// with its confirmation code and address on the network net.  The address
+// uses the compressed public key when compressed is true.  The random seed of
+// the key is read from src, or from the default source of the entropy package
+// when src is nil.
+func GenerateEncryptedKey(intermediate string, compressed bool,
+	net *chaincfg.Params, src io.Reader) (*GeneratedKey, error) {
+	payload := decode(intermediate)
+	if len(payload) != intermediateLen ||
+		!bytes.Equal(payload[:7], intermediateMagic) ||
+		(payload[7] != 0x51 && payload[7] != 0x53) {
+		return nil, ErrInvalidIntermediate
+	}
+	ownerEntropy := payload[8:16]
+	passPoint := payload[16:]
+	passPointKey, err := btcec.ParsePubKey(passPoint, btcec.S256())
+	if err != nil {
+		return nil, ErrInvalidIntermediate
+	}
+	var flag byte
+	if compressed {
+		flag |= flagCompressed
+	}
+	if payload[7] == 0x53 {
+		flag |= flagLotSequence
+	}
+
+	seed := make([]byte, seedLen)
+	if err := entropy.Read(src, seed); err != nil {
+		return nil, err
+	}
+	factor := chainhash.DoubleHashB(seed)
+	if !validScalar(factor) {
+		return nil, ErrInvalidFactor
+	}
+	pubKey := multiply(passPointKey, factor)
+	addr, err := addressOf(serializePubKey(pubKey, compressed), net)
+	if err != nil {
+		return nil, err
+	}
+	addrHash := chainhash.DoubleHashB([]byte(addr.EncodeAddress()))[:4]
+	derived, err := ecDerived(passPoint, addrHash, ownerEntropy)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(derived[32:])
+	if err != nil {
+		return nil, err
+	}
+
+	// The second half of the first encrypted block is chained into the
+	// second block, so only its first half is kept in the key.
+	part1 := xor(seed[:16], derived[:16])
+	block.Encrypt(part1, part1)
+	part2 := xor(append(append([]byte{}, part1[8:]...), seed[16:]...),
+		derived[16:32])
+	block.Encrypt(part2, part2)
+
+	key := make([]byte, 0, EncryptedKeyLen)
+	key = append(key, 0x01, prefixEC, flag)
+	key = append(key, addrHash...)
+	key = append(key, ownerEntropy...)
+	key = append(key, part1[:8]...)
+	key = append(key, part2...)
+
+	pointB := pointOf(factor)
+	confirmation := make([]byte, 0, confirmationLen)
+	confirmation = append(confirmation, confirmationMagic...)
+	confirmation = append(confirmation, flag)
+	confirmation = append(confirmation, addrHash...)
+	confirmation = append(confirmation, ownerEntropy...)
+	confirmation = append(confirmation, pointB[0]^(derived[63]&1))
+	confirmation = append(confirmation, encryptHalves(block, pointB[1:],
+		derived[:32])...)
+
+	return &GeneratedKey{
+		Encrypted:    encode(key),
+		Confirmation: encode(confirmation),
+		Address:      addr,
+	}, nil
+}
+
+// decryptEC returns the private key of payload, a key encrypted with EC
+// multiplication, decrypted with passphrase.
+func decryptEC(payload []byte, passphrase string) ([]byte, error) {
+	flag, addrHash, ownerEntropy := payload[2], payload[3:7], payload[7:15]
+	passFactor, err := derivePassFactor(passphrase, ownerEntropy,
+		flag&flagLotSequence != 0)
+	if err != nil {
+		return nil, err
+	}
+	derived, err := ecDerived(pointOf(passFactor), addrHash, ownerEntropy)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(derived[32:])
+	if err != nil {
+		return nil, err
+	}
+
+	part2 := make([]byte, 16)
+	block.Decrypt(part2, payload[23:39])
+	part2 = xor(part2, derived[16:32])
+	part1 := append(append([]byte{}, payload[15:23]...), part2[:8]...)
+	block.Decrypt(part1, part1)
+	seed := append(xor(part1, derived[:16]), part2[8:]...)
+
+	factor := new(big.Int).SetBytes(chainhash.DoubleHashB(seed))
+	privKey := new(big.Int).SetBytes(passFactor)
+	privKey.Mul(privKey, factor)
+	privKey.Mod(privKey, btcec.S256().N)
+	return scalarBytes(privKey), nil
+}
+
+// VerifyConfirmation checks confirmation, a confirmation code returned by
+// GenerateEncryptedKey, with passphrase and returns the address on the network
+// net of the key it confirms.  ErrWrongPassphrase is returned when the code
+// does not match passphrase.
+func VerifyConfirmation(confirmation, passphrase string,
+	net *chaincfg.Params) (*btcutil.AddressPubKeyHash, error) {
+	payload := decode(confirmation)
+	if len(payload) != confirmationLen ||
+		!bytes.Equal(payload[:5], confirmationMagic) ||
+		payload[5]&^(flagCompressed|flagLotSequence) != 0 {
+		return nil, ErrInvalidConfirmation
+	}
+	flag, addrHash, ownerEntropy := payload[5], payload[6:10], payload[10:18]
+	passFactor, err := derivePassFactor(passphrase, ownerEntropy,
+		flag&flagLotSequence != 0)
+	if err != nil {
+		return nil, err
+	}
+	derived, err := ecDerived(pointOf(passFactor), addrHash, ownerEntropy)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(derived[32:])
+	if err != nil {
+		return nil, err
+	}
+
+	pointB := append([]byte{payload[18] ^ (derived[63] & 1)},
+		decryptHalves(block, payload[19:], derived[:32])...)
+	pointBKey, err := btcec.ParsePubKey(pointB, btcec.S256())
+	if err != nil {
+		return nil, ErrWrongPassphrase
+	}
+	pubKey := multiply(pointBKey, passFactor)
+	addr, err := addressOf(serializePubKey(pubKey, flag&flagCompressed != 0), net)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(chainhash.DoubleHashB([]byte(addr.EncodeAddress()))[:4], addrHash) {
+		return nil, ErrWrongPassphrase
+	}
+	return addr, nil
+}
+
+// LotSequence returns the lot and sequence number of encrypted, a key
+// generated from an intermediate code with a lot and sequence number, and
+// false for any other key.
+func LotSequence(encrypted string) (lot, sequence uint32, ok bool) {
+	payload := decode(encrypted)
+	if len(payload) != EncryptedKeyLen || payload[0] != 0x01 ||
+		payload[1] != prefixEC || payload[2]&flagLotSequence == 0 {
+		return 0, 0, false
+	}
+	lotSequence := binary.BigEndian.Uint32(payload[11:15])
+	return lotSequence / MaxSequence, lotSequence % MaxSequence, true
+}
@@ -0,0 +1,143 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package bip38_test
+
+import (
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/bip38"
+)
+
+// TestDecryptEC ensures keys encrypted with EC multiplication in the test
+// vectors of BIP0038 decrypt to their private keys.
+func TestDecryptEC(t *testing.T) {
+	tests := []struct {
+		passphrase    string
+		encrypted     string
+		wif           string
+		lot, sequence uint32
+		hasLot        bool
+	}{
+		{
+			passphrase: "TestingOneTwoThree",
+			encrypted:  "6PfQu77ygVyJLZjfvMLyhLMQbYnu5uguoJJ4kMCLqWwPEdfpwANVS76gTX",
+			wif:        "5K4caxezwjGCGfnoPTZ8tMcJBLB7Jvyjv4xxeacadhq8nLisLR2",
+		},
+		{
+			passphrase: "Satoshi",
+			encrypted:  "6PfLGnQs6VZnrNpmVKfjotbnQuaJK4KZoPFrAjx1JMJUa1Ft8gnf5WxfKd",
+			wif:        "5KJ51SgxWaAYR13zd9ReMhJpwrcX47xTJh2D3fGPG9CM8vkv5sH",
+		},
+		{
+			passphrase: "MOLON LABE",
+			encrypted:  "6PgNBNNzDkKdhkT6uJntUXwwzQV8Rr2tZcbkDcuC9DZRsS6AtHts4Ypo1j",
+			wif:        "5JLdxTtcTHcfYcmJsNVy1v2PMDx432JPoYcBTVVRHpPaxUrdtf8",
+			lot:        263183,
+			sequence:   1,
+			hasLot:     true,
+		},
+		{
+			passphrase: "ΜΟΛΩΝ ΛΑΒΕ",
+			encrypted:  "6PgGWtx25kUg8QWvwuJAgorN6k9FbE25rv5dMRwu5SKMnfpfVe5mar2ngH",
+			wif:        "5KMKKuUmAkiNbA3DazMQiLfDq47qs8MAEThm4yL8R2PhV1ov33D",
+			lot:        806938,
+			sequence:   1,
+			hasLot:     true,
+		},
+	}
+
+	net := &chaincfg.MainNetParams
+	for i, test := range tests {
+		wif, err := bip38.Decrypt(test.encrypted, test.passphrase, net)
+		if err != nil || wif.String() != test.wif {
+			t.Errorf("#%d Decrypt: got %v, %v, want %s", i, wif, err,
+				test.wif)
+		}
+		lot, sequence, ok := bip38.LotSequence(test.encrypted)
+		if lot != test.lot || sequence != test.sequence || ok != test.hasLot {
+			t.Errorf("#%d LotSequence: got %d, %d, %v, want %d, %d, %v",
+				i, lot, sequence, ok, test.lot, test.sequence,
+				test.hasLot)
+		}
+	}
+}
+
+// TestGenerateEncryptedKey ensures keys generated from intermediate codes
+// decrypt with their passphrase to the key of their address, and their
+// confirmation codes prove that address.
+func TestGenerateEncryptedKey(t *testing.T) {
+	net := &chaincfg.MainNetParams
+	passphrase := "correct horse battery staple"
+	plain, err := bip38.NewIntermediateCode(passphrase, nil)
+	if err != nil {
+		t.Fatalf("NewIntermediateCode: %v", err)
+	}
+	withLot, err := bip38.NewIntermediateCodeWithLot(passphrase, 1000, 7, nil)
+	if err != nil {
+		t.Fatalf("NewIntermediateCodeWithLot: %v", err)
+	}
+	if _, err := bip38.NewIntermediateCodeWithLot(passphrase, bip38.MaxLot,
+		0, nil); err != bip38.ErrInvalidLotSequence {
+		t.Errorf("NewIntermediateCodeWithLot: got error %v, want %v", err,
+			bip38.ErrInvalidLotSequence)
+	}
+
+	for i, test := range []struct {
+		intermediate string
+		compressed   bool
+	}{
+		{plain, false},
+		{plain, true},
+		{withLot, true},
+	} {
+		key, err := bip38.GenerateEncryptedKey(test.intermediate,
+			test.compressed, net, nil)
+		if err != nil {
+			t.Fatalf("#%d GenerateEncryptedKey: %v", i, err)
+		}
+
+		wif, err := bip38.Decrypt(key.Encrypted, passphrase, net)
+		if err != nil {
+			t.Fatalf("#%d Decrypt: %v", i, err)
+		}
+		if wif.CompressPubKey != test.compressed {
+			t.Errorf("#%d Decrypt: got compressed %v, want %v", i,
+				wif.CompressPubKey, test.compressed)
+		}
+		addr, err := btcutil.NewAddressPubKeyHash(
+			btcutil.Hash160(wif.SerializePubKey()), net)
+		if err != nil {
+			t.Fatalf("#%d NewAddressPubKeyHash: %v", i, err)
+		}
+		if addr.EncodeAddress() != key.Address.EncodeAddress() {
+			t.Errorf("#%d Decrypt: key of %v, want %v", i, addr,
+				key.Address)
+		}
+
+		confirmed, err := bip38.VerifyConfirmation(key.Confirmation,
+			passphrase, net)
+		if err != nil || confirmed.EncodeAddress() != key.Address.EncodeAddress() {
+			t.Errorf("#%d VerifyConfirmation: got %v, %v, want %v", i,
+				confirmed, err, key.Address)
+		}
+		_, _, hasLot := bip38.LotSequence(key.Encrypted)
+		if hasLot != (test.intermediate == withLot) {
+			t.Errorf("#%d LotSequence: got %v", i, hasLot)
+		}
+		if _, err := bip38.VerifyConfirmation(key.Confirmation, "wrong",
+			net); err != bip38.ErrWrongPassphrase {
+			t.Errorf("#%d VerifyConfirmation: got error %v, want %v", i,
+				err, bip38.ErrWrongPassphrase)
+		}
+	}
+
+	if _, err := bip38.GenerateEncryptedKey(plain[:len(plain)-1], true, net,
+		nil); err != bip38.ErrInvalidIntermediate {
+		t.Errorf("GenerateEncryptedKey: got error %v, want %v", err,
+			bip38.ErrInvalidIntermediate)
+	}
+}
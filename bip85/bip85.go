@@ -0,0 +1,228 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package bip85 derives deterministic entropy for application secrets from a
+// master extended key as specified by BIP0085.
+//
+// Every secret is derived along a hardened path below 83696968', so a single
+// backed up seed can recreate any number of independent mnemonics, private
+// keys and passwords, and none of them reveals the master key or each other.
+package bip85
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"math/big"
+	"strings"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/hdkeychain"
+)
+
+// Purpose is the first, hardened index of every BIP0085 derivation path.
+const Purpose = 83696968
+
+// The application numbers, the second index of BIP0085 derivation paths.
+const (
+	// AppHDSeedWIF derives private keys encoded as WIF.
+	AppHDSeedWIF = 2
+
+	// AppXPRV derives master extended private keys.
+	AppXPRV = 32
+
+	// AppBIP39 derives BIP0039 mnemonics.
+	AppBIP39 = 39
+
+	// AppHex derives raw entropy.
+	AppHex = 128169
+)
+
+// Language identifies the BIP0039 word list of a mnemonic, which selects a
+// distinct derivation path per language.
+type Language uint32
+
+// The BIP0039 languages in the order assigned by BIP0085.
+const (
+	English Language = iota
+	Japanese
+	Korean
+	Spanish
+	ChineseSimplified
+	ChineseTraditional
+	French
+	Italian
+	Czech
+)
+
+// entropyKey is the HMAC-SHA512 key used to turn a derived private key into
+// entropy.
+var entropyKey = []byte("bip-entropy-from-k")
+
+var (
+	// ErrInvalidWordCount describes an error where a mnemonic length other
+	// than 12, 18 or 24 words is requested.
+	ErrInvalidWordCount = errors.New("mnemonic must have 12, 18 or 24 words")
+
+	// ErrInvalidByteCount describes an error where hex entropy of fewer
+	// than 16 or more than 64 bytes is requested.
+	ErrInvalidByteCount = errors.New("hex entropy must be 16 to 64 bytes")
+
+	// ErrInvalidWordList describes an error where a BIP0039 word list does
+	// not hold exactly 2048 words.
+	ErrInvalidWordList = errors.New("word list must have 2048 words")
+
+	// ErrInvalidEntropyLen describes an error where the entropy to encode
+	// as a mnemonic is not 16 to 32 bytes in multiples of 4.
+	ErrInvalidEntropyLen = errors.New("mnemonic entropy must be 16 to 32 " +
+		"bytes in multiples of 4")
+)
+
+// Entropy returns the 64 bytes of entropy derived from the master key along
+// the path m/83696968'/path..., where every index of path is hardened by this
+// function.  The master key must be a private extended key.
+func Entropy(master *hdkeychain.ExtendedKey, path ...uint32) ([]byte, error) {
+	if !master.IsPrivate() {
+		return nil, hdkeychain.ErrNotPrivExtKey
+	}
+
+	k := master
+	for _, index := range append([]uint32{Purpose}, path...) {
+		child, err := k.Child(hdkeychain.HardenedKeyStart + index)
+		if k != master {
+			k.Zero()
+		}
+		if err != nil {
+			return nil, err
+		}
+		k = child
+	}
+	defer k.Zero()
+
+	privKey, err := k.ECPrivKey()
+	if err != nil {
+		return nil, err
+	}
+	key := privKey.Serialize()
+	defer zero(key)
+
+	mac := hmac.New(sha512.New, entropyKey)
+	mac.Write(key)
+	return mac.Sum(nil), nil
+}
+
+// BIP39Entropy returns the entropy of the mnemonic with the passed number of
+// words at the passed index for the passed language.  Encode it with Mnemonic
+// and the word list of the language to obtain the mnemonic.
+func BIP39Entropy(master *hdkeychain.ExtendedKey, lang Language, words,
+	index uint32) ([]byte, error) {
+
+	if words != 12 && words != 18 && words != 24 {
+		return nil, ErrInvalidWordCount
+	}
+	entropy, err := Entropy(master, AppBIP39, uint32(lang), words, index)
+	if err != nil {
+		return nil, err
+	}
+	n := words * 4 / 3
+	zero(entropy[n:])
+	return entropy[:n:n], nil
+}
+
+// Mnemonic encodes entropy as a BIP0039 mnemonic using the passed list of
+// 2048 words, joining the words with single spaces.  This package does not
+// ship word lists; callers pass the list of the language the entropy was
+// derived for.  Japanese mnemonics are conventionally joined with ideographic
+// spaces instead, which callers may substitute.
+func Mnemonic(entropy []byte, wordList []string) (string, error) {
+	if len(wordList) != 2048 {
+		return "", ErrInvalidWordList
+	}
+	if len(entropy) < 16 || len(entropy) > 32 || len(entropy)%4 != 0 {
+		return "", ErrInvalidEntropyLen
+	}
+
+	// The mnemonic encodes the entropy followed by the first len/32 bits
+	// of its SHA256 hash, 11 bits per word.
+	checksumBits := uint(len(entropy) / 4)
+	sum := sha256.Sum256(entropy)
+	data := new(big.Int).SetBytes(entropy)
+	data.Lsh(data, checksumBits)
+	data.Or(data, big.NewInt(int64(sum[0]>>(8-checksumBits))))
+
+	numWords := (len(entropy)*8 + int(checksumBits)) / 11
+	words := make([]string, numWords)
+	mask := big.NewInt(2047)
+	index := new(big.Int)
+	for i := numWords - 1; i >= 0; i-- {
+		index.And(data, mask)
+		words[i] = wordList[index.Int64()]
+		data.Rsh(data, 11)
+	}
+	return strings.Join(words, " "), nil
+}
+
+// WIF returns the private key at the passed index, encoded for the passed
+// network with a compressed public key.
+func WIF(master *hdkeychain.ExtendedKey, index uint32,
+	net *chaincfg.Params) (*btcutil.WIF, error) {
+
+	entropy, err := Entropy(master, AppHDSeedWIF, index)
+	if err != nil {
+		return nil, err
+	}
+	defer zero(entropy)
+
+	privKey, _ := btcec.PrivKeyFromBytes(btcec.S256(), entropy[:32])
+	return btcutil.NewWIF(privKey, net, true)
+}
+
+// XPRV returns the master extended private key at the passed index for the
+// passed network.  The first 32 bytes of the entropy are its chain code and
+// the last 32 its private key.  hdkeychain.ErrUnusableSeed is returned in the
+// vanishingly unlikely case the private key is invalid.
+func XPRV(master *hdkeychain.ExtendedKey, index uint32,
+	net *chaincfg.Params) (*hdkeychain.ExtendedKey, error) {
+
+	entropy, err := Entropy(master, AppXPRV, index)
+	if err != nil {
+		return nil, err
+	}
+
+	keyNum := new(big.Int).SetBytes(entropy[32:])
+	if keyNum.Cmp(btcec.S256().N) >= 0 || keyNum.Sign() == 0 {
+		zero(entropy)
+		return nil, hdkeychain.ErrUnusableSeed
+	}
+
+	parentFP := []byte{0x00, 0x00, 0x00, 0x00}
+	return hdkeychain.NewExtendedKey(net.HDPrivateKeyID[:], entropy[32:],
+		entropy[:32], parentFP, 0, 0, true), nil
+}
+
+// Hex returns numBytes bytes of raw entropy at the passed index, for use as
+// passwords or other application secrets.  numBytes must be between 16 and
+// 64.
+func Hex(master *hdkeychain.ExtendedKey, numBytes, index uint32) ([]byte, error) {
+	if numBytes < 16 || numBytes > 64 {
+		return nil, ErrInvalidByteCount
+	}
+	entropy, err := Entropy(master, AppHex, numBytes, index)
+	if err != nil {
+		return nil, err
+	}
+	zero(entropy[numBytes:])
+	return entropy[:numBytes:numBytes], nil
+}
+
+// zero sets all bytes in the passed slice to zero.  This is used to
+// explicitly clear private key material from memory.
+func zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
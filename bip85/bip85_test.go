@@ -0,0 +1,148 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package bip85_test
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil/bip85"
+	"github.com/zeusyf/btcutil/hdkeychain"
+)
+
+// masterKey is the master key of the BIP0085 test vectors.
+const masterKey = "xprv9s21ZrQH143K2LBWUUQRFXhucrQqBpKdRRxNVq2zBqsx8HVqFk2uYo8kmbaLLHRdqtQpUm98uKfu3vca1LqdGhUtyoFnCNkfmXRyPXLjbKb"
+
+func master(t *testing.T) *hdkeychain.ExtendedKey {
+	t.Helper()
+	k, err := hdkeychain.NewKeyFromString(masterKey)
+	if err != nil {
+		t.Fatalf("NewKeyFromString: %v", err)
+	}
+	return k
+}
+
+// TestEntropy ensures the derived entropy matches the BIP0085 test vectors.
+func TestEntropy(t *testing.T) {
+	tests := []struct {
+		path []uint32
+		want string
+	}{{
+		path: []uint32{0, 0},
+		want: "efecfbccffea313214232d29e71563d941229afb4338c21f9517c41aaa0d16f00b83d2a09ef747e7a64e8e2bd5a14869e693da66ce94ac2da570ab7ee48618f7",
+	}, {
+		path: []uint32{0, 1},
+		want: "70c6e3e8ebee8dc4c0dbba66076819bb8c09672527c4277ca8729532ad711872218f826919f6b67218adde99018a6df9095ab2b58d803b5b93ec9802085a690e",
+	}}
+
+	k := master(t)
+	for _, test := range tests {
+		got, err := bip85.Entropy(k, test.path...)
+		if err != nil {
+			t.Fatalf("Entropy(%v): %v", test.path, err)
+		}
+		if hex.EncodeToString(got) != test.want {
+			t.Errorf("Entropy(%v): got %x, want %s", test.path, got,
+				test.want)
+		}
+	}
+
+	pub, err := k.Neuter()
+	if err != nil {
+		t.Fatalf("Neuter: %v", err)
+	}
+	if _, err := bip85.Entropy(pub, 0, 0); err != hdkeychain.ErrNotPrivExtKey {
+		t.Errorf("Entropy of public key: got error %v, want %v", err,
+			hdkeychain.ErrNotPrivExtKey)
+	}
+}
+
+// TestApplications ensures the application secrets match the BIP0085 test
+// vectors.
+func TestApplications(t *testing.T) {
+	k := master(t)
+
+	entropy, err := bip85.BIP39Entropy(k, bip85.English, 12, 0)
+	if err != nil {
+		t.Fatalf("BIP39Entropy: %v", err)
+	}
+	if got, want := hex.EncodeToString(entropy), "6250b68daf746d12a24d58b4787a714b"; got != want {
+		t.Errorf("BIP39Entropy: got %s, want %s", got, want)
+	}
+	if _, err := bip85.BIP39Entropy(k, bip85.English, 13, 0); err != bip85.ErrInvalidWordCount {
+		t.Errorf("BIP39Entropy: got error %v, want %v", err,
+			bip85.ErrInvalidWordCount)
+	}
+
+	wif, err := bip85.WIF(k, 0, &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("WIF: %v", err)
+	}
+	if got, want := wif.String(), "Kzyv4uF39d4Jrw2W7UryTHwZr1zQVNk4dAFyqE6BuMrMh1Za7uhp"; got != want {
+		t.Errorf("WIF: got %s, want %s", got, want)
+	}
+
+	xprv, err := bip85.XPRV(k, 0, &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("XPRV: %v", err)
+	}
+	if got, want := xprv.String(), "xprv9s21ZrQH143K2srSbCSg4m4kLvPMzcWydgmKEnMmoZUurYuBuYG46c6P71UGXMzmriLzCCBvKQWBUv3vPB3m1SATMhp3uEjXHJ42jFg7myX"; got != want {
+		t.Errorf("XPRV: got %s, want %s", got, want)
+	}
+
+	hexEntropy, err := bip85.Hex(k, 64, 0)
+	if err != nil {
+		t.Fatalf("Hex: %v", err)
+	}
+	if got, want := hex.EncodeToString(hexEntropy), "492db4698cf3b73a5a24998aa3e9d7fa96275d85724a91e71aa2d645442f878555d078fd1f1f67e368976f04137b1f7a0d19232136ca50c44614af72b5582a5c"; got != want {
+		t.Errorf("Hex: got %s, want %s", got, want)
+	}
+	if _, err := bip85.Hex(k, 15, 0); err != bip85.ErrInvalidByteCount {
+		t.Errorf("Hex: got error %v, want %v", err, bip85.ErrInvalidByteCount)
+	}
+}
+
+// TestMnemonic ensures entropy is encoded with the right words and checksum.
+// It uses a synthetic word list naming each word after its index, so the
+// expected words are the indexes of the BIP0085 mnemonic vector "girl mad pet
+// galaxy egg matter matrix prison refuse sense ordinary nose" in the English
+// word list.
+func TestMnemonic(t *testing.T) {
+	wordList := make([]string, 2048)
+	for i := range wordList {
+		wordList[i] = fmt.Sprint(i)
+	}
+
+	entropy, _ := hex.DecodeString("6250b68daf746d12a24d58b4787a714b")
+	got, err := bip85.Mnemonic(entropy, wordList)
+	if err != nil {
+		t.Fatalf("Mnemonic: %v", err)
+	}
+	want := "786 1069 1307 759 566 1098 1097 1368 1443 1566 1250 1203"
+	if got != want {
+		t.Errorf("Mnemonic: got %q, want %q", got, want)
+	}
+
+	// 32 bytes of zero entropy encode as 23 zero words followed by the
+	// word holding the 8 bit checksum.
+	got, err = bip85.Mnemonic(make([]byte, 32), wordList)
+	if err != nil {
+		t.Fatalf("Mnemonic: %v", err)
+	}
+	want = strings.Repeat("0 ", 23) + "102"
+	if got != want {
+		t.Errorf("Mnemonic: got %q, want %q", got, want)
+	}
+
+	if _, err := bip85.Mnemonic(entropy, wordList[:2047]); err != bip85.ErrInvalidWordList {
+		t.Errorf("Mnemonic: got error %v, want %v", err, bip85.ErrInvalidWordList)
+	}
+	if _, err := bip85.Mnemonic(entropy[:15], wordList); err != bip85.ErrInvalidEntropyLen {
+		t.Errorf("Mnemonic: got error %v, want %v", err, bip85.ErrInvalidEntropyLen)
+	}
+}
@@ -9,6 +9,9 @@ import (
 	"bytes"
 	"fmt"
 	"io"
+	"runtime"
+	"sync"
+	"sync/atomic"
 
 	"github.com/zeusyf/btcd/chaincfg/chainhash"
 	"github.com/zeusyf/btcd/wire"
@@ -32,22 +35,98 @@ func (e OutOfRangeError) Error() string {
 // manipulation of raw blocks.  It also memoizes hashes for the block and its
 // transactions on their first access so subsequent accesses don't have to
 // repeat the relatively expensive hashing operations.
+//
+// The memoized hashes, serialized bytes and wrapped transactions are safe for
+// concurrent readers: any number of goroutines may call the methods reading
+// a shared block, such as Hash, Bytes, Tx, Transactions and TxHashes, and
+// they all get the same wrapped transactions, so indexers can share blocks
+// between their workers.  The block must however not be modified, through
+// its MsgBlock or SetHeight, while it is shared, and ClearSize must not race
+// with the methods serializing it.
+//
+// A Block holds a mutex and atomic values, so it must not be copied after
+// first use; share a *Block instead, or use Copy.
 type Block struct {
-	msgBlock                 *wire.MsgBlock  // Underlying MsgBlock
-	serializedBlock          []byte          // Serialized bytes for the block
-	serializedBlockNoWitness []byte          // Serialized bytes for block w/o witness data
-	blockHash                *chainhash.Hash // Cached block hash
-	blockHeight              int32           // Height in the main block chain
-	transactions             []*Tx           // Height
-	txnsGenerated            bool            // ALL wrapped transactions generated
+	msgBlock                 *wire.MsgBlock                   // Underlying MsgBlock
+	serializedBlock          atomic.Pointer[[]byte]           // Serialized bytes for the block
+	serializedBlockNoWitness atomic.Pointer[[]byte]           // Serialized bytes for block w/o witness data
+	blockHash                atomic.Pointer[chainhash.Hash]   // Cached block hash
+	blockHeight              int32                            // Height in the main block chain
+	txMtx                    sync.Mutex                       // Protects transactions
+	transactions             []*Tx                            // Height
+	txnsGenerated            atomic.Bool                      // ALL wrapped transactions generated
+	arena                    *BlockArena                      // Allocator for wrapped transactions
+	txHashes                 atomic.Pointer[[]chainhash.Hash] // Cached hashes of all transactions
+	readOnly                 bool                             // MsgBlock returns copies and mutation panics
 }
 
-// MsgBlock returns the underlying wire.MsgBlock for the Block.
+// MsgBlock returns the underlying wire.MsgBlock for the Block.  A read-only
+// block returns a deep copy of it instead, which the caller is free to
+// modify.
 func (b *Block) MsgBlock() *wire.MsgBlock {
+	if b.readOnly {
+		return copyMsgBlock(b.msgBlock)
+	}
+
 	// Return the cached block.
 	return b.msgBlock
 }
 
+// copyMsgBlock returns a deep copy of msgBlock.
+func copyMsgBlock(msgBlock *wire.MsgBlock) *wire.MsgBlock {
+	c := *msgBlock
+	c.Transactions = make([]*wire.MsgTx, len(msgBlock.Transactions))
+	for i, msgTx := range msgBlock.Transactions {
+		c.Transactions[i] = msgTx.Copy()
+	}
+	return &c
+}
+
+// Copy returns a deep copy of the block, including its underlying
+// wire.MsgBlock, which can be modified without affecting the original.  The
+// copy is never read-only and keeps the height and cached hash of the
+// original, but not its other caches, since they would no longer hold once
+// the copy is modified.
+func (b *Block) Copy() *Block {
+	c := &Block{
+		msgBlock:    copyMsgBlock(b.msgBlock),
+		blockHeight: b.blockHeight,
+	}
+	if h := b.blockHash.Load(); h != nil {
+		hash := *h
+		c.blockHash.Store(&hash)
+	}
+	return c
+}
+
+// ReadOnly returns a read-only view of the block, sharing its underlying
+// wire.MsgBlock and caches.  MsgBlock returns a deep copy on every call, the
+// transactions returned by Tx and Transactions are read-only views, and
+// SetHeight panics, so callers of the view can't corrupt the shared block.
+// Caches handing out shared blocks should hand out views.  The slices
+// returned by Bytes, BytesNoWitness and TxHashes are still shared and must
+// not be modified.
+func (b *Block) ReadOnly() *Block {
+	if b.readOnly {
+		return b
+	}
+	c := &Block{
+		msgBlock:    b.msgBlock,
+		blockHeight: b.blockHeight,
+		readOnly:    true,
+	}
+	c.serializedBlock.Store(b.serializedBlock.Load())
+	c.serializedBlockNoWitness.Store(b.serializedBlockNoWitness.Load())
+	c.blockHash.Store(b.blockHash.Load())
+	c.txHashes.Store(b.txHashes.Load())
+	return c
+}
+
+// IsReadOnly returns whether the block is a read-only view.
+func (b *Block) IsReadOnly() bool {
+	return b.readOnly
+}
+
 var zerohash chainhash.Hash
 
 // countSpentOutputs returns the number of utxos the passed block spends.
@@ -55,8 +134,8 @@ func (block *Block)  CountSpentOutputs() int {
 	// Exclude the coinbase transaction since it can't spend anything.
 	var numSpent int
 	for _, tx := range block.Transactions()[1:] {
-		numSpent += len(tx.MsgTx().TxIn)
-		for _, ti := range tx.MsgTx().TxIn {
+		numSpent += len(tx.msgTx.TxIn)
+		for _, ti := range tx.msgTx.TxIn {
 			if ti.PreviousOutPoint.Hash.IsEqual(&zerohash) {
 				numSpent--
 			}
@@ -70,8 +149,8 @@ func (block *Block)  CountSpentOutputs() int {
 // result so subsequent calls are more efficient.
 func (b *Block) Bytes() ([]byte, error) {
 	// Return the cached serialized bytes if it has already been generated.
-	if len(b.serializedBlock) != 0 {
-		return b.serializedBlock, nil
+	if serializedBlock := b.cachedBytes(); len(serializedBlock) != 0 {
+		return serializedBlock, nil
 	}
 
 	// Serialize the MsgBlock.
@@ -83,27 +162,38 @@ func (b *Block) Bytes() ([]byte, error) {
 	serializedBlock := w.Bytes()
 
 	// Cache the serialized bytes and return them.
-	b.serializedBlock = serializedBlock
+	b.serializedBlock.Store(&serializedBlock)
 	return serializedBlock, nil
 }
 
+// cachedBytes returns the serialized bytes cached by Bytes, or nil when there
+// are none.
+func (b *Block) cachedBytes() []byte {
+	if serializedBlock := b.serializedBlock.Load(); serializedBlock != nil {
+		return *serializedBlock
+	}
+	return nil
+}
+
 func (b *Block) ClearSize() {
-	b.serializedBlock = nil
-	b.serializedBlockNoWitness = nil
+	b.serializedBlock.Store(nil)
+	b.serializedBlockNoWitness.Store(nil)
 }
 
 func (b *Block) Size() int {
 	// Return the cached serialized bytes if it has already been generated.
-	b.Bytes()
-	return len(b.serializedBlock)
+	serializedBlock, _ := b.Bytes()
+	return len(serializedBlock)
 }
 
 // BytesNoWitness returns the serialized bytes for the block with transactions
 // encoded without any witness data.
 func (b *Block) BytesNoWitness() ([]byte, error) {
 	// Return the cached serialized bytes if it has already been generated.
-	if len(b.serializedBlockNoWitness) != 0 {
-		return b.serializedBlockNoWitness, nil
+	if serializedBlock := b.serializedBlockNoWitness.Load(); serializedBlock != nil &&
+		len(*serializedBlock) != 0 {
+
+		return *serializedBlock, nil
 	}
 
 	// Serialize the MsgBlock.
@@ -115,23 +205,27 @@ func (b *Block) BytesNoWitness() ([]byte, error) {
 	serializedBlock := w.Bytes()
 
 	// Cache the serialized bytes and return them.
-	b.serializedBlockNoWitness = serializedBlock
+	b.serializedBlockNoWitness.Store(&serializedBlock)
 	return serializedBlock, nil
 }
 
 // Hash returns the block identifier hash for the Block.  This is equivalent to
 // calling BlockHash on the underlying wire.MsgBlock, however it caches the
-// result so subsequent calls are more efficient.
+// result so subsequent calls are more efficient.  It is safe for concurrent
+// use; goroutines racing to hash the block first all return the hash cached
+// by the winner.
 func (b *Block) Hash() *chainhash.Hash {
 	// Return the cached block hash if it has already been generated.
-	if b.blockHash != nil {
-		return b.blockHash
+	if hash := b.blockHash.Load(); hash != nil {
+		return hash
 	}
 
 	// Cache the block hash and return it.
 	hash := b.msgBlock.BlockHash()
-	b.blockHash = &hash
-	return &hash
+	if b.blockHash.CompareAndSwap(nil, &hash) {
+		return &hash
+	}
+	return b.blockHash.Load()
 }
 
 // Tx returns a wrapped transaction (btcutil.Tx) for the transaction at the
@@ -149,9 +243,12 @@ func (b *Block) Tx(txNum int) (*Tx, error) {
 		return nil, OutOfRangeError(str)
 	}
 
+	b.txMtx.Lock()
+	defer b.txMtx.Unlock()
+
 	// Generate slice to hold all of the wrapped transactions if needed.
 	if len(b.transactions) == 0 {
-		b.transactions = make([]*Tx, numTx)
+		b.transactions = b.newTxSlice(int(numTx))
 	}
 
 	// Return the wrapped transaction if it has already been generated.
@@ -160,8 +257,7 @@ func (b *Block) Tx(txNum int) (*Tx, error) {
 	}
 
 	// Generate and cache the wrapped transaction and return it.
-	newTx := NewTx(b.msgBlock.Transactions[txNum])
-	newTx.SetIndex(txNum)
+	newTx := b.newTx(b.msgBlock.Transactions[txNum], txNum)
 	b.transactions[txNum] = newTx
 	return newTx, nil
 }
@@ -173,30 +269,59 @@ func (b *Block) Tx(txNum int) (*Tx, error) {
 func (b *Block) Transactions() []*Tx {
 	// Return transactions if they have ALL already been generated.  This
 	// flag is necessary because the wrapped transactions are lazily
-	// generated in a sparse fashion.
-	if b.txnsGenerated {
+	// generated in a sparse fashion.  It is set once the slice is
+	// complete, so the slice is only read without the mutex afterwards.
+	if b.txnsGenerated.Load() {
+		return b.transactions
+	}
+
+	b.txMtx.Lock()
+	defer b.txMtx.Unlock()
+	if b.txnsGenerated.Load() {
 		return b.transactions
 	}
 
 	// Generate slice to hold all of the wrapped transactions if needed.
 	if len(b.transactions) == 0 {
-		b.transactions = make([]*Tx, len(b.msgBlock.Transactions))
+		b.transactions = b.newTxSlice(len(b.msgBlock.Transactions))
 	}
 
 	// Generate and cache the wrapped transactions for all that haven't
 	// already been done.
 	for i, tx := range b.transactions {
 		if tx == nil {
-			newTx := NewTx(b.msgBlock.Transactions[i])
-			newTx.SetIndex(i)
-			b.transactions[i] = newTx
+			b.transactions[i] = b.newTx(b.msgBlock.Transactions[i], i)
 		}
 	}
 
-	b.txnsGenerated = true
+	b.txnsGenerated.Store(true)
 	return b.transactions
 }
 
+// newTx wraps the passed transaction at index txNum, allocating it from the
+// arena of the block if it has one.  The transactions of read-only blocks are
+// read-only.
+func (b *Block) newTx(msgTx *wire.MsgTx, txNum int) *Tx {
+	var tx *Tx
+	if b.arena != nil {
+		tx = b.arena.newTx(msgTx)
+	} else {
+		tx = NewTx(msgTx)
+	}
+	tx.txIndex = txNum
+	tx.readOnly = b.readOnly
+	return tx
+}
+
+// newTxSlice returns a slice of n nil transactions, allocated from the arena
+// of the block if it has one.
+func (b *Block) newTxSlice(n int) []*Tx {
+	if b.arena != nil {
+		return b.arena.newTxSlice(n)
+	}
+	return make([]*Tx, n)
+}
+
 // TxHash returns the hash for the requested transaction number in the Block.
 // The supplied index is 0 based.  That is to say, the first transaction in the
 // block is txNum 0.  This is equivalent to calling TxHash on the underlying
@@ -216,6 +341,63 @@ func (b *Block) TxHash(txNum int) (*chainhash.Hash, error) {
 	return tx.Hash(), nil
 }
 
+// minParallelTxHashes is the number of transactions below which TxHashes
+// hashes them on the calling goroutine, since starting workers costs more
+// than it saves for small blocks.
+const minParallelTxHashes = 64
+
+// TxHashes returns the hashes of all transactions in the Block in order.  The
+// transactions are hashed concurrently by a pool of GOMAXPROCS workers, and
+// the hashes are cached both in the wrapped transactions and in the block,
+// so subsequent calls, as well as calls to TxHash, are free.  The returned
+// slice is shared by all callers and must not be modified.  Goroutines racing
+// to hash the transactions first all return the slice cached by the winner.
+func (b *Block) TxHashes() []chainhash.Hash {
+	if hashes := b.txHashes.Load(); hashes != nil {
+		return *hashes
+	}
+
+	txs := b.Transactions()
+	hashes := make([]chainhash.Hash, len(txs))
+	workers := runtime.GOMAXPROCS(0)
+	if len(txs) < minParallelTxHashes || workers == 1 {
+		for i, tx := range txs {
+			hashes[i] = *tx.Hash()
+		}
+		return b.cacheTxHashes(hashes)
+	}
+
+	// Each worker hashes a contiguous range of transactions, so no two
+	// workers touch the same wrapped transaction.
+	chunk := (len(txs) + workers - 1) / workers
+	var wg sync.WaitGroup
+	for start := 0; start < len(txs); start += chunk {
+		end := start + chunk
+		if end > len(txs) {
+			end = len(txs)
+		}
+		wg.Add(1)
+		go func(start, end int) {
+			defer wg.Done()
+			for i := start; i < end; i++ {
+				hashes[i] = *txs[i].Hash()
+			}
+		}(start, end)
+	}
+	wg.Wait()
+
+	return b.cacheTxHashes(hashes)
+}
+
+// cacheTxHashes caches the hashes of the transactions unless another
+// goroutine already did, and returns the cached hashes.
+func (b *Block) cacheTxHashes(hashes []chainhash.Hash) []chainhash.Hash {
+	if b.txHashes.CompareAndSwap(nil, &hashes) {
+		return hashes
+	}
+	return *b.txHashes.Load()
+}
+
 // TxLoc returns the offsets and lengths of each transaction in a raw block.
 // It is used to allow fast indexing into transactions within the raw byte
 // stream.
@@ -242,6 +424,9 @@ func (b *Block) Height() int32 {
 
 // SetHeight sets the height of the block in the block chain.
 func (b *Block) SetHeight(height int32) {
+	if b.readOnly {
+		panic("btcutil: modification of a read-only block")
+	}
 	b.blockHeight = height
 }
 
@@ -262,7 +447,7 @@ func NewBlockFromBytes(serializedBlock []byte) (*Block, error) {
 	if err != nil {
 		return nil, err
 	}
-	b.serializedBlock = serializedBlock
+	b.serializedBlock.Store(&serializedBlock)
 	return b, nil
 }
 
@@ -309,9 +494,10 @@ func NewMinerBlockFromReader(r io.Reader) (*wire.MinerBlock, error) {
 // NewBlockFromBlockAndBytes returns a new instance of a bitcoin block given
 // an underlying wire.MsgBlock and the serialized bytes for it.  See Block.
 func NewBlockFromBlockAndBytes(msgBlock *wire.MsgBlock, serializedBlock []byte) *Block {
-	return &Block{
-		msgBlock:        msgBlock,
-		serializedBlock: serializedBlock,
-		blockHeight:     BlockHeightUnknown,
+	b := &Block{
+		msgBlock:    msgBlock,
+		blockHeight: BlockHeightUnknown,
 	}
+	b.serializedBlock.Store(&serializedBlock)
+	return b
 }
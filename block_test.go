@@ -8,6 +8,7 @@ import (
 	"bytes"
 	"io"
 	"reflect"
+	"sync"
 	"testing"
 	"time"
 
@@ -231,6 +232,175 @@ func TestNewBlockFromBlockAndBytes(t *testing.T) {
 	}
 }
 
+// TestBlockTxHashes ensures the concurrently computed transaction hashes of
+// small and large blocks match the hashes of the transactions.
+func TestBlockTxHashes(t *testing.T) {
+	large := Block100000
+	large.Transactions = nil
+	for i := 0; i < 50; i++ {
+		for _, tx := range Block100000.Transactions {
+			tx := tx.Copy()
+			tx.LockTime = uint32(i)
+			large.Transactions = append(large.Transactions, tx)
+		}
+	}
+
+	for _, msgBlock := range []*wire.MsgBlock{&Block100000, &large} {
+		b := btcutil.NewBlock(msgBlock)
+		hashes := b.TxHashes()
+		if len(hashes) != len(msgBlock.Transactions) {
+			t.Fatalf("TxHashes: got %d hashes, want %d", len(hashes),
+				len(msgBlock.Transactions))
+		}
+		for i, tx := range msgBlock.Transactions {
+			if want := tx.TxHash(); hashes[i] != want {
+				t.Errorf("TxHashes: hash %d is %v, want %v", i,
+					hashes[i], want)
+			}
+			cached, err := b.TxHash(i)
+			if err != nil || *cached != hashes[i] {
+				t.Errorf("TxHash(%d): got %v, %v", i, cached, err)
+			}
+		}
+		if again := b.TxHashes(); &again[0] != &hashes[0] {
+			t.Errorf("TxHashes: hashes not cached")
+		}
+	}
+}
+
+// TestBlockConcurrentReaders ensures goroutines reading a shared block all
+// get the same cached hashes, bytes and wrapped transactions.  It is meant to
+// be run with the race detector.
+func TestBlockConcurrentReaders(t *testing.T) {
+	b := btcutil.NewBlock(&Block100000)
+	numTx := len(Block100000.Transactions)
+
+	type result struct {
+		hash     *chainhash.Hash
+		bytes    []byte
+		txns     []*btcutil.Tx
+		txHashes []chainhash.Hash
+	}
+	const goroutines = 8
+	results := make([]result, goroutines)
+	var wg sync.WaitGroup
+	for i := range results {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			r := &results[i]
+			r.hash = b.Hash()
+
+			// Half of the goroutines wrap the transactions one by
+			// one, racing the others wrapping them all at once.
+			if i%2 == 0 {
+				for j := numTx - 1; j >= 0; j-- {
+					if _, err := b.Tx(j); err != nil {
+						t.Errorf("Tx(%d): %v", j, err)
+					}
+				}
+			}
+			r.txns = b.Transactions()
+			r.txHashes = b.TxHashes()
+			r.bytes, _ = b.Bytes()
+		}(i)
+	}
+	wg.Wait()
+
+	want := results[0]
+	if *want.hash != Block100000.BlockHash() {
+		t.Fatalf("Hash: got %v, want %v", want.hash,
+			Block100000.BlockHash())
+	}
+	for i, r := range results {
+		if r.hash != want.hash {
+			t.Errorf("goroutine %d: Hash not shared", i)
+		}
+		if len(r.bytes) == 0 || &r.bytes[0] != &want.bytes[0] {
+			t.Errorf("goroutine %d: Bytes not shared", i)
+		}
+		if &r.txHashes[0] != &want.txHashes[0] {
+			t.Errorf("goroutine %d: TxHashes not shared", i)
+		}
+		for j, tx := range r.txns {
+			if tx != want.txns[j] {
+				t.Errorf("goroutine %d: transaction %d not shared",
+					i, j)
+			}
+			if r.txHashes[j] != Block100000.Transactions[j].TxHash() {
+				t.Errorf("goroutine %d: mismatched hash %d", i, j)
+			}
+		}
+	}
+}
+
+// TestBlockCopy ensures copies and read-only views of a block can't be used
+// to modify it or its transactions.
+func TestBlockCopy(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Block100000.Serialize(&buf); err != nil {
+		t.Fatalf("Serialize: %v", err)
+	}
+	b, err := btcutil.NewBlockFromBytes(buf.Bytes())
+	if err != nil {
+		t.Fatalf("NewBlockFromBytes: %v", err)
+	}
+	b.SetHeight(100000)
+	hash := *b.Hash()
+	wantBlock, err := btcutil.NewBlockFromBytes(buf.Bytes())
+	if err != nil {
+		t.Fatalf("NewBlockFromBytes: %v", err)
+	}
+	want := wantBlock.MsgBlock()
+
+	c := b.Copy()
+	if c.IsReadOnly() || c.Height() != 100000 || *c.Hash() != hash ||
+		!reflect.DeepEqual(c.MsgBlock(), want) {
+		t.Errorf("Copy: mismatched copy")
+	}
+	c.MsgBlock().Transactions[0].TxOut[0].PkScript[0] ^= 0xff
+	c.MsgBlock().Transactions = nil
+	c.SetHeight(1)
+	if !reflect.DeepEqual(b.MsgBlock(), want) || b.Height() != 100000 {
+		t.Errorf("Copy: block modified through its copy")
+	}
+
+	view := b.ReadOnly()
+	if !view.IsReadOnly() || view.ReadOnly() != view || b.IsReadOnly() ||
+		view.Height() != 100000 || *view.Hash() != hash {
+		t.Errorf("ReadOnly: mismatched view")
+	}
+	if got := view.MsgBlock(); got == b.MsgBlock() ||
+		!reflect.DeepEqual(got, want) {
+		t.Errorf("MsgBlock: got %v, want a copy of %v", spew.Sdump(got),
+			spew.Sdump(want))
+	}
+	view.MsgBlock().Transactions[1].TxOut[0].PkScript[0] ^= 0xff
+	tx, err := view.Tx(1)
+	if err != nil {
+		t.Fatalf("Tx: %v", err)
+	}
+	if !tx.IsReadOnly() || tx.Index() != 1 {
+		t.Errorf("Tx: got index %d and read-only %v", tx.Index(),
+			tx.IsReadOnly())
+	}
+	tx.MsgTx().LockTime++
+	for i, tx := range view.Transactions() {
+		if !tx.IsReadOnly() || tx.Index() != i {
+			t.Errorf("Transactions: mismatched transaction %d", i)
+		}
+	}
+	if b.Transactions()[1].IsReadOnly() {
+		t.Errorf("Transactions: transaction of the block is read-only")
+	}
+	if !reflect.DeepEqual(b.MsgBlock(), want) {
+		t.Errorf("ReadOnly: block modified through its view")
+	}
+
+	assertPanics(t, "SetHeight", func() { view.SetHeight(0) })
+	assertPanics(t, "SetIndex", func() { tx.SetIndex(0) })
+}
+
 // TestBlockErrors tests the error paths for the Block API.
 func TestBlockErrors(t *testing.T) {
 	// Ensure out of range errors are as expected.
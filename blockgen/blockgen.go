@@ -0,0 +1,491 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package blockgen generates deterministic chains of blocks for integration
+// testing indexers, SPV clients and other software consuming blocks of this
+// package, without running a node.
+//
+// A Generator extends the genesis block of the configured network with blocks
+// holding a configurable mix of transactions: pay-to-pubkey-hash payments,
+// payments carrying signature scripts, the witness data of Omega, and
+// payments with outputs of several token types.  Every transaction spends an
+// OMC output of an earlier one, so the chain forms a consistent graph of
+// spends.  Coinbase outputs are only spent from the next block on, so the
+// first block holds only its coinbase.  Blocks have valid merkle roots, a
+// witness commitment whenever they hold signature scripts, and hashes meeting
+// an adjustable target.  The same seed and options always produce the same
+// chain.
+//
+// The chains are structurally valid only: signature scripts are placeholders,
+// token outputs are not backed by inputs and fees are not claimed, so blocks
+// are not accepted by a node.  Blocks are solved and linked the way the
+// regtest package mines them.
+package blockgen
+
+import (
+	"fmt"
+	"math"
+	"math/big"
+	"math/rand"
+	"time"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/difficulty"
+	"github.com/zeusyf/btcutil/internal/chaingen"
+	"github.com/zeusyf/btcutil/merkle"
+	"github.com/zeusyf/btcutil/scriptclass"
+	"github.com/zeusyf/omega/token"
+)
+
+const (
+	// DefaultBlockInterval is the time between the timestamps of
+	// consecutive blocks.
+	DefaultBlockInterval = chaingen.DefaultBlockInterval
+
+	// Fee is the fee, in hao, every generated transaction leaves.
+	Fee = 1000
+
+	// numericTokenType and hashTokenType are the token types of the
+	// token outputs of multi-token transactions.
+	numericTokenType = 4
+	hashTokenType    = 1
+)
+
+// ErrNoSolution describes an error where no nonce satisfying the proof of
+// work target could be found.
+var ErrNoSolution = chaingen.ErrNoSolution
+
+// WitnessNonce is the witness nonce the witness commitments of generated
+// blocks are computed with, as passed to merkle.ValidateWitnessCommitment.
+var WitnessNonce chainhash.Hash
+
+// TxKind is a kind of generated transaction.
+type TxKind uint8
+
+const (
+	// TxP2PKH is a payment from and to pay-to-pubkey-hash outputs, without
+	// signature scripts.
+	TxP2PKH TxKind = iota
+
+	// TxWitness is a TxP2PKH payment carrying placeholder signature
+	// scripts, which only the witness merkle root commits to.
+	TxWitness
+
+	// TxMultiToken is a TxP2PKH payment with additional outputs of a
+	// numeric and a hash token.
+	TxMultiToken
+)
+
+// txKindStrings is a map of transaction kinds back to their constant names
+// for pretty printing.
+var txKindStrings = map[TxKind]string{
+	TxP2PKH:      "TxP2PKH",
+	TxWitness:    "TxWitness",
+	TxMultiToken: "TxMultiToken",
+}
+
+// String returns the TxKind as a human-readable name.
+func (k TxKind) String() string {
+	if s, ok := txKindStrings[k]; ok {
+		return s
+	}
+	return fmt.Sprintf("Unknown TxKind (%d)", uint8(k))
+}
+
+// Mix is the number of transactions of each kind in a block, besides the
+// coinbase.  They are shuffled in a block deterministically.
+type Mix struct {
+	P2PKH      int
+	Witness    int
+	MultiToken int
+}
+
+// total returns the number of transactions of the mix.
+func (m Mix) total() int {
+	return m.P2PKH + m.Witness + m.MultiToken
+}
+
+// utxo is an unspent OMC output of the chain.
+type utxo struct {
+	outPoint wire.OutPoint
+	value    int64
+}
+
+// Generator generates a chain of blocks.  It is not safe for concurrent
+// access.
+type Generator struct {
+	params   *chaincfg.Params
+	rng      *rand.Rand
+	mix      Mix
+	bits     uint32
+	interval time.Duration
+	subsidy  btcutil.Amount
+	chain    []*btcutil.Block
+	headers  []difficulty.Header
+	pool     []utxo
+	addrs    []btcutil.Address
+}
+
+// Option configures a Generator.
+type Option func(*Generator)
+
+// WithParams sets the network whose genesis block the chain extends.  The
+// regression test network is used by default.
+func WithParams(params *chaincfg.Params) Option {
+	return func(g *Generator) {
+		g.params = params
+	}
+}
+
+// WithSeed sets the seed the keys, amounts and order of transactions are
+// drawn from.  The seed is zero by default.
+func WithSeed(seed int64) Option {
+	return func(g *Generator) {
+		g.rng = rand.New(rand.NewSource(seed))
+	}
+}
+
+// WithMix sets the transactions of each block.  Blocks hold a single
+// transaction of each kind by default.
+func WithMix(mix Mix) Option {
+	return func(g *Generator) {
+		g.mix = mix
+	}
+}
+
+// WithBits sets the compact target blocks are mined at, which is the proof of
+// work limit of the network by default.  Invalid targets make Next fail with
+// btcutil.ErrTargetOutOfRange.
+func WithBits(bits uint32) Option {
+	return func(g *Generator) {
+		g.bits = bits
+	}
+}
+
+// WithBlockInterval sets the time between the timestamps of consecutive
+// blocks.
+func WithBlockInterval(d time.Duration) Option {
+	return func(g *Generator) {
+		g.interval = d
+	}
+}
+
+// WithSubsidy sets the amount the coinbase of each block pays, split among
+// as many outputs as the block has transactions.
+func WithSubsidy(amount btcutil.Amount) Option {
+	return func(g *Generator) {
+		g.subsidy = amount
+	}
+}
+
+// New returns a generator whose chain consists only of the genesis block of
+// its network.
+func New(opts ...Option) *Generator {
+	g := &Generator{
+		params:   &chaincfg.RegressionNetParams,
+		rng:      rand.New(rand.NewSource(0)),
+		mix:      Mix{P2PKH: 1, Witness: 1, MultiToken: 1},
+		interval: DefaultBlockInterval,
+		subsidy:  50 * btcutil.HaoPerBitcoin,
+	}
+	for _, opt := range opts {
+		opt(g)
+	}
+	if g.bits == 0 {
+		g.bits = btcutil.BigToCompact(g.params.PowLimit)
+	}
+
+	g.chain = []*btcutil.Block{chaingen.Genesis(g.params)}
+	g.headers = []difficulty.Header{{
+		Height:    0,
+		Timestamp: g.params.GenesisBlock.Header.Timestamp,
+		Bits:      btcutil.BigToCompact(g.params.PowLimit),
+	}}
+	return g
+}
+
+// SetBits sets the compact target of the blocks generated next, adjusting the
+// difficulty within a chain.
+func (g *Generator) SetBits(bits uint32) {
+	g.bits = bits
+}
+
+// Params returns the network parameters of the chain.
+func (g *Generator) Params() *chaincfg.Params {
+	return g.params
+}
+
+// Tip returns the last block of the chain.
+func (g *Generator) Tip() *btcutil.Block {
+	return g.chain[len(g.chain)-1]
+}
+
+// Chain returns the blocks of the chain from genesis to tip.
+func (g *Generator) Chain() []*btcutil.Block {
+	chain := make([]*btcutil.Block, len(g.chain))
+	copy(chain, g.chain)
+	return chain
+}
+
+// Headers returns the headers of the chain from genesis to tip along with the
+// targets they were mined at, for validation with package difficulty.  The
+// genesis block is recorded at the proof of work limit.
+func (g *Generator) Headers() []difficulty.Header {
+	headers := make([]difficulty.Header, len(g.headers))
+	copy(headers, g.headers)
+	return headers
+}
+
+// Addresses returns the addresses paid by the chain, in the order they were
+// first paid.
+func (g *Generator) Addresses() []btcutil.Address {
+	addrs := make([]btcutil.Address, len(g.addrs))
+	copy(addrs, g.addrs)
+	return addrs
+}
+
+// Generate generates n blocks on top of the tip and returns them.
+func (g *Generator) Generate(n int) ([]*btcutil.Block, error) {
+	blocks := make([]*btcutil.Block, 0, n)
+	for i := 0; i < n; i++ {
+		block, err := g.Next()
+		if err != nil {
+			return blocks, err
+		}
+		blocks = append(blocks, block)
+	}
+	return blocks, nil
+}
+
+// Next generates a block on top of the tip and returns it.  The chain is left
+// unchanged when an error is returned.
+func (g *Generator) Next() (*btcutil.Block, error) {
+	target := btcutil.CompactToBig(g.bits)
+	if target.Sign() <= 0 || target.Cmp(g.params.PowLimit) > 0 {
+		return nil, btcutil.ErrTargetOutOfRange
+	}
+
+	// The pool and addresses are restored if the block can't be solved,
+	// which leaves the generator as it was but for its random source.
+	pool, numAddrs := g.pool, len(g.addrs)
+	block, err := g.build(target)
+	if err != nil {
+		g.pool, g.addrs = pool, g.addrs[:numAddrs]
+		return nil, err
+	}
+
+	g.chain = append(g.chain, block)
+	g.headers = append(g.headers, difficulty.Header{
+		Height:    block.Height(),
+		Timestamp: block.MsgBlock().Header.Timestamp,
+		Bits:      g.bits,
+	})
+	return block, nil
+}
+
+// build builds and solves the block following the tip.
+func (g *Generator) build(target *big.Int) (*btcutil.Block, error) {
+	prev := g.Tip()
+	height := prev.Height() + 1
+
+	kinds := make([]TxKind, 0, g.mix.total())
+	for kind, n := range []int{g.mix.P2PKH, g.mix.Witness, g.mix.MultiToken} {
+		for i := 0; i < n; i++ {
+			kinds = append(kinds, TxKind(kind))
+		}
+	}
+	g.rng.Shuffle(len(kinds), func(i, j int) {
+		kinds[i], kinds[j] = kinds[j], kinds[i]
+	})
+
+	coinbase, err := g.coinbaseTx(height, len(kinds))
+	if err != nil {
+		return nil, err
+	}
+	msgBlock := &wire.MsgBlock{}
+	msgBlock.AddTransaction(coinbase)
+	witness := false
+	for _, kind := range kinds {
+		if len(g.pool) == 0 {
+			break
+		}
+		tx, err := g.tx(kind)
+		if err != nil {
+			return nil, err
+		}
+		msgBlock.AddTransaction(tx)
+		witness = witness || len(tx.SignatureScripts) > 0
+	}
+
+	// The coinbase is a zero leaf of the witness tree, so the commitment
+	// added to it does not change the root it commits to.  Its outputs are
+	// only spent from the next block on, as its hash changes with the
+	// commitment.
+	block := btcutil.NewBlock(msgBlock)
+	if witness {
+		root, err := merkle.CalcMerkleRoot(block.Transactions(), true)
+		if err != nil {
+			return nil, err
+		}
+		commitment := merkle.WitnessCommitment(&root, &WitnessNonce)
+		coinbase.AddTxOut(&wire.TxOut{
+			Token:    omc(0),
+			PkScript: merkle.WitnessCommitmentScript(&commitment),
+		})
+		block = btcutil.NewBlock(msgBlock)
+	}
+	g.addToPool(coinbase)
+
+	timestamp := prev.MsgBlock().Header.Timestamp.Add(g.interval)
+	return chaingen.Solve(prev, msgBlock, timestamp, target)
+}
+
+// coinbaseTx returns the coinbase of the block at height, splitting the
+// subsidy among n outputs, or a single one when n is zero.
+func (g *Generator) coinbaseTx(height int32, n int) (*wire.MsgTx, error) {
+	tx := chaingen.CoinbaseTx(height)
+	if n == 0 {
+		n = 1
+	}
+	values := make([]int64, n)
+	for i := range values {
+		values[i] = int64(g.subsidy) / int64(n)
+	}
+	values[0] += int64(g.subsidy) % int64(n)
+	if err := g.pay(tx, values); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// tx returns a transaction of the passed kind spending the oldest output of
+// the pool, which must not be empty.  Its OMC outputs are added to the pool.
+func (g *Generator) tx(kind TxKind) (*wire.MsgTx, error) {
+	in := g.pool[0]
+	g.pool = g.pool[1:]
+
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: in.outPoint,
+		Sequence:         wire.MaxTxInSequenceNum,
+		SignatureIndex:   math.MaxUint32,
+	})
+
+	// The input is split into a payment and change when it is large
+	// enough to leave two outputs above the fee.  Inputs below the fee
+	// are spent whole to a zero output.
+	value := in.value - Fee
+	values := []int64{value}
+	switch {
+	case value < 0:
+		values = []int64{0}
+	case value > 2*Fee:
+		payment := 1 + g.rng.Int63n(value-1)
+		values = []int64{payment, value - payment}
+	}
+	if err := g.pay(tx, values); err != nil {
+		return nil, err
+	}
+
+	switch kind {
+	case TxWitness:
+		for i, txIn := range tx.TxIn {
+			txIn.SignatureIndex = uint32(i)
+			tx.SignatureScripts = append(tx.SignatureScripts,
+				g.sigScript())
+		}
+
+	case TxMultiToken:
+		pkScript, err := g.pkScript()
+		if err != nil {
+			return nil, err
+		}
+		tx.AddTxOut(&wire.TxOut{
+			Token: token.Token{
+				TokenType: numericTokenType,
+				Value:     &token.NumeralVal{Val: 1 + g.rng.Int63n(1e6)},
+			},
+			PkScript: pkScript,
+		})
+
+		var hash chainhash.Hash
+		g.rng.Read(hash[:])
+		pkScript, err = g.pkScript()
+		if err != nil {
+			return nil, err
+		}
+		tx.AddTxOut(&wire.TxOut{
+			Token: token.Token{
+				TokenType: hashTokenType,
+				Value:     &token.HashVal{Hash: hash},
+			},
+			PkScript: pkScript,
+		})
+	}
+
+	g.addToPool(tx)
+	return tx, nil
+}
+
+// pay adds OMC outputs of the passed values to tx, each paying a new address.
+func (g *Generator) pay(tx *wire.MsgTx, values []int64) error {
+	for _, value := range values {
+		pkScript, err := g.pkScript()
+		if err != nil {
+			return err
+		}
+		tx.AddTxOut(&wire.TxOut{Token: omc(value), PkScript: pkScript})
+	}
+	return nil
+}
+
+// addToPool adds the OMC outputs of tx to the pool.  The outputs of the
+// transaction must all be added before it is.
+func (g *Generator) addToPool(tx *wire.MsgTx) {
+	hash := tx.TxHash()
+	for i, txOut := range tx.TxOut {
+		if txOut.Token.TokenType != 0 {
+			continue
+		}
+		g.pool = append(g.pool, utxo{
+			outPoint: wire.OutPoint{Hash: hash, Index: uint32(i)},
+			value:    txOut.Token.Value.(*token.NumeralVal).Val,
+		})
+	}
+}
+
+// pkScript returns the script paying to a new pay-to-pubkey-hash address.
+func (g *Generator) pkScript() ([]byte, error) {
+	hash := make([]byte, 20)
+	g.rng.Read(hash)
+	addr, err := btcutil.NewAddressPubKeyHash(hash, g.params)
+	if err != nil {
+		return nil, err
+	}
+	g.addrs = append(g.addrs, addr)
+	return scriptclass.PayToAddrScript(addr)
+}
+
+// sigScript returns a placeholder signature script, pushing a signature and
+// a compressed public key of random bytes.
+func (g *Generator) sigScript() []byte {
+	script := make([]byte, 1+72+1+33)
+	script[0] = 72
+	g.rng.Read(script[1:73])
+	script[73] = 33
+	g.rng.Read(script[74:])
+	script[74] = 0x02
+	return script
+}
+
+// omc returns an OMC token of value hao.
+func omc(value int64) token.Token {
+	return token.Token{
+		TokenType: 0,
+		Value:     &token.NumeralVal{Val: value},
+	}
+}
@@ -0,0 +1,182 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package blockgen_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/blockgen"
+	"github.com/zeusyf/btcutil/difficulty"
+	"github.com/zeusyf/btcutil/merkle"
+)
+
+// TestGenerate ensures generated blocks link up, hold the configured mix of
+// transactions, commit to them with valid merkle roots and witness
+// commitments, and meet their target.
+func TestGenerate(t *testing.T) {
+	mix := blockgen.Mix{P2PKH: 3, Witness: 2, MultiToken: 2}
+	g := blockgen.New(blockgen.WithSeed(7), blockgen.WithMix(mix))
+	blocks, err := g.Generate(5)
+	if err != nil {
+		t.Fatalf("Generate: unexpected error: %v", err)
+	}
+	if len(g.Chain()) != 6 || g.Tip() != blocks[4] {
+		t.Fatalf("Generate: got chain of %d blocks", len(g.Chain()))
+	}
+	if n := len(blocks[0].Transactions()); n != 1 {
+		t.Errorf("first block: got %d transactions, want 1", n)
+	}
+
+	params := g.Params()
+	headers := g.Headers()
+	spent := make(map[wire.OutPoint]bool)
+	for i, block := range blocks {
+		header := &block.MsgBlock().Header
+		prev := g.Chain()[i]
+		if header.PrevBlock != *prev.Hash() || block.Height() != int32(i+1) {
+			t.Errorf("block %d: does not extend block %d", i+1, i)
+		}
+
+		root, err := merkle.CalcMerkleRoot(block.Transactions(), false)
+		if err != nil || root != header.MerkleRoot {
+			t.Errorf("block %d: got merkle root %v, want %v", i+1,
+				header.MerkleRoot, root)
+		}
+		err = btcutil.NewBlockHeader(header).CheckProofOfWork(
+			headers[i+1].Bits, params.PowLimit)
+		if err != nil {
+			t.Errorf("block %d: unexpected error: %v", i+1, err)
+		}
+
+		if i == 0 {
+			continue
+		}
+		if n := len(block.Transactions()); n != 1+7 {
+			t.Errorf("block %d: got %d transactions, want 8", i+1, n)
+		}
+		witness, tokens := 0, 0
+		for _, tx := range block.Transactions()[1:] {
+			msgTx := tx.MsgTx()
+			if len(msgTx.SignatureScripts) > 0 {
+				witness++
+			}
+			for _, txOut := range msgTx.TxOut {
+				if txOut.Token.TokenType != 0 {
+					tokens++
+				}
+			}
+			for _, txIn := range msgTx.TxIn {
+				if spent[txIn.PreviousOutPoint] {
+					t.Errorf("block %d: %v spent twice", i+1,
+						txIn.PreviousOutPoint)
+				}
+				spent[txIn.PreviousOutPoint] = true
+			}
+		}
+		if witness != mix.Witness || tokens != 2*mix.MultiToken {
+			t.Errorf("block %d: got %d witness transactions and %d "+
+				"token outputs", i+1, witness, tokens)
+		}
+		err = merkle.ValidateWitnessCommitment(block, &blockgen.WitnessNonce)
+		if err != nil {
+			t.Errorf("block %d: unexpected error: %v", i+1, err)
+		}
+	}
+	if len(g.Addresses()) == 0 {
+		t.Errorf("Addresses: got no addresses")
+	}
+}
+
+// TestDeterministic ensures the same seed generates the same chain, and
+// another seed another one.
+func TestDeterministic(t *testing.T) {
+	hashes := func(seed int64) []chainhash.Hash {
+		blocks, err := blockgen.New(blockgen.WithSeed(seed)).Generate(4)
+		if err != nil {
+			t.Fatalf("Generate: unexpected error: %v", err)
+		}
+		var hashes []chainhash.Hash
+		for _, block := range blocks {
+			hashes = append(hashes, *block.Hash())
+		}
+		return hashes
+	}
+
+	a, b, c := hashes(1), hashes(1), hashes(2)
+	for i := range a {
+		if a[i] != b[i] {
+			t.Errorf("block %d: got hashes %v and %v for the same seed",
+				i+1, a[i], b[i])
+		}
+	}
+	if a[3] == c[3] {
+		t.Errorf("got the same chain for different seeds")
+	}
+}
+
+// TestDifficulty ensures blocks are mined at the target set, and invalid
+// targets are rejected.
+func TestDifficulty(t *testing.T) {
+	params := &chaincfg.RegressionNetParams
+	easy := btcutil.BigToCompact(params.PowLimit)
+	g := blockgen.New()
+	if _, err := g.Generate(2); err != nil {
+		t.Fatalf("Generate: unexpected error: %v", err)
+	}
+
+	// A target 16 times harder.
+	hard := btcutil.BigToCompact(new(big.Int).Rsh(params.PowLimit, 4))
+	g.SetBits(hard)
+	block, err := g.Next()
+	if err != nil {
+		t.Fatalf("Next: unexpected error: %v", err)
+	}
+	header := btcutil.NewBlockHeader(&block.MsgBlock().Header)
+	if err := header.CheckProofOfWork(hard, params.PowLimit); err != nil {
+		t.Errorf("Next: unexpected error: %v", err)
+	}
+
+	headers := g.Headers()
+	if headers[1].Bits != easy || headers[3].Bits != hard {
+		t.Errorf("Headers: got bits %08x and %08x, want %08x and %08x",
+			headers[1].Bits, headers[3].Bits, easy, hard)
+	}
+	if difficulty.Work(headers[3:]).Cmp(difficulty.Work(headers[1:2])) <= 0 {
+		t.Errorf("Work: harder block has no more work")
+	}
+
+	g.SetBits(0)
+	if _, err := g.Next(); err != btcutil.ErrTargetOutOfRange {
+		t.Errorf("Next: got error %v, want %v", err,
+			btcutil.ErrTargetOutOfRange)
+	}
+	if len(g.Chain()) != 4 {
+		t.Errorf("Next: got chain of %d blocks after failure, want 4",
+			len(g.Chain()))
+	}
+}
+
+// TestTxKindStringer tests the stringized output for transaction kinds.
+func TestTxKindStringer(t *testing.T) {
+	tests := []struct {
+		in   blockgen.TxKind
+		want string
+	}{
+		{blockgen.TxP2PKH, "TxP2PKH"},
+		{blockgen.TxWitness, "TxWitness"},
+		{blockgen.TxMultiToken, "TxMultiToken"},
+		{0xff, "Unknown TxKind (255)"},
+	}
+	for _, test := range tests {
+		if got := test.in.String(); got != test.want {
+			t.Errorf("String: got %q, want %q", got, test.want)
+		}
+	}
+}
@@ -5,8 +5,11 @@
 package bloom
 
 import (
+	"crypto/rand"
 	"encoding/binary"
+	"errors"
 	"math"
+	"math/bits"
 	"sync"
 
 	"github.com/zeusyf/btcd/blockchain/indexers"
@@ -21,6 +24,18 @@ import (
 // ln2Squared is simply the square of the natural log of 2.
 const ln2Squared = math.Ln2 * math.Ln2
 
+var (
+	// ErrNoElements describes an error where a filter is sized for no
+	// elements.
+	ErrNoElements = errors.New("filter must be sized for at least one " +
+		"element")
+
+	// ErrInvalidFPRate describes an error where a filter is sized for a
+	// false positive rate which is not strictly between 0 and 1.
+	ErrInvalidFPRate = errors.New("false positive rate must be between 0 " +
+		"and 1")
+)
+
 // minUint32 is a convenience function to return the minimum value of the two
 // passed uint32 values.
 func minUint32(a, b uint32) uint32 {
@@ -79,6 +94,46 @@ func NewFilter(elements, tweak uint32, fprate float64, flags common.BloomUpdateT
 	}
 }
 
+// NewFilterForElements creates a new bloom filter sized to hold n elements at
+// the passed false positive rate, with a random tweak and no automatic
+// updates.  Unlike NewFilter, the arguments are checked rather than adjusted,
+// and the sizes are rounded so the filter holds at least one byte and uses
+// at least one hash function.
+//
+// The size of the filter is capped at wire.MaxFilterLoadFilterSize, in which
+// case the filter holding n elements has a higher false positive rate than
+// requested.  EstimateFPRate reports the rate actually achieved.
+func NewFilterForElements(n uint32, fpRate float64) (*Filter, error) {
+	if n == 0 {
+		return nil, ErrNoElements
+	}
+	if !(fpRate > 0 && fpRate < 1) {
+		return nil, ErrInvalidFPRate
+	}
+
+	// m = -(n*ln(p) / ln(2)^2) bits, rounded up to whole bytes, and
+	// k = (m/n) * ln(2) hash functions, rounded to the nearest.
+	bitLen := math.Ceil(-float64(n) * math.Log(fpRate) / ln2Squared)
+	dataLen := uint32(math.Min(math.Ceil(bitLen/8),
+		wire.MaxFilterLoadFilterSize))
+	if dataLen == 0 {
+		dataLen = 1
+	}
+	hashFuncs := uint32(math.Round(float64(dataLen*8) / float64(n) * math.Ln2))
+	if hashFuncs == 0 {
+		hashFuncs = 1
+	}
+	hashFuncs = minUint32(hashFuncs, wire.MaxFilterLoadHashFuncs)
+
+	var tweak [4]byte
+	if _, err := rand.Read(tweak[:]); err != nil {
+		return nil, err
+	}
+	msg := wire.NewMsgFilterLoad(make([]byte, dataLen), hashFuncs,
+		binary.LittleEndian.Uint32(tweak[:]), wire.BloomUpdateNone)
+	return LoadFilter(msg), nil
+}
+
 // LoadFilter creates a new Filter instance with the given underlying
 // wire.MsgFilterLoad.
 func LoadFilter(filter *wire.MsgFilterLoad) *Filter {
@@ -115,6 +170,30 @@ func (bf *Filter) Unload() {
 	bf.mtx.Unlock()
 }
 
+// EstimateFPRate returns the estimated probability that data which was never
+// added matches the filter, given the elements added so far.  Data matches
+// when each hash function selects a set bit, so the estimate is the fraction
+// of set bits raised to the number of hash functions.  SPV clients use it to
+// weigh the privacy gained from false positives against the bandwidth they
+// cost, and to decide when a filter has filled up and should be resized.
+// Zero is returned when no filter is loaded.
+//
+// This function is safe for concurrent access.
+func (bf *Filter) EstimateFPRate() float64 {
+	bf.mtx.Lock()
+	defer bf.mtx.Unlock()
+
+	if bf.msgFilterLoad == nil || len(bf.msgFilterLoad.Filter) == 0 {
+		return 0
+	}
+	var set int
+	for _, b := range bf.msgFilterLoad.Filter {
+		set += bits.OnesCount8(b)
+	}
+	ratio := float64(set) / float64(len(bf.msgFilterLoad.Filter)*8)
+	return math.Pow(ratio, float64(bf.msgFilterLoad.HashFuncs))
+}
+
 // hash returns the bit offset in the bloom filter which corresponds to the
 // passed data for the given indepedent hash function number.
 func (bf *Filter) hash(hashNum uint32, data []byte) uint32 {
@@ -6,7 +6,9 @@ package bloom_test
 
 import (
 	"bytes"
+	"encoding/binary"
 	"encoding/hex"
+	"math"
 	"testing"
 
 	"github.com/zeusyf/btcd/chaincfg/chainhash"
@@ -658,3 +660,99 @@ func TestFilterReload(t *testing.T) {
 		t.Errorf("TestFilterReload Reload test failed")
 	}
 }
+
+// TestNewFilterForElements ensures filters are sized for the requested number
+// of elements and false positive rate, and invalid requests are rejected.
+func TestNewFilterForElements(t *testing.T) {
+	tests := []struct {
+		elements  uint32
+		fpRate    float64
+		dataLen   int
+		hashFuncs uint32
+	}{
+		{1, 0.5, 1, 6},
+		{1000, 0.01, 1199, 7},
+		{1000, 0.0001, 2397, 13},
+		{100000000, 0.01, wire.MaxFilterLoadFilterSize, 1},
+	}
+	for _, test := range tests {
+		f, err := bloom.NewFilterForElements(test.elements, test.fpRate)
+		if err != nil {
+			t.Errorf("NewFilterForElements(%d, %v): unexpected error: %v",
+				test.elements, test.fpRate, err)
+			continue
+		}
+		msg := f.MsgFilterLoad()
+		if len(msg.Filter) != test.dataLen || msg.HashFuncs != test.hashFuncs {
+			t.Errorf("NewFilterForElements(%d, %v): got %d bytes and %d "+
+				"hash funcs, want %d and %d", test.elements, test.fpRate,
+				len(msg.Filter), msg.HashFuncs, test.dataLen,
+				test.hashFuncs)
+		}
+		if msg.Flags != wire.BloomUpdateNone {
+			t.Errorf("NewFilterForElements: got flags %v", msg.Flags)
+		}
+	}
+
+	invalid := []struct {
+		elements uint32
+		fpRate   float64
+		err      error
+	}{
+		{0, 0.01, bloom.ErrNoElements},
+		{10, 0, bloom.ErrInvalidFPRate},
+		{10, 1, bloom.ErrInvalidFPRate},
+		{10, -0.5, bloom.ErrInvalidFPRate},
+		{10, math.NaN(), bloom.ErrInvalidFPRate},
+	}
+	for _, test := range invalid {
+		_, err := bloom.NewFilterForElements(test.elements, test.fpRate)
+		if err != test.err {
+			t.Errorf("NewFilterForElements(%d, %v): got error %v, want %v",
+				test.elements, test.fpRate, err, test.err)
+		}
+	}
+}
+
+// TestEstimateFPRate ensures the estimated false positive rate of a filter
+// filled to its capacity is close to the requested rate and to the observed
+// rate.
+func TestEstimateFPRate(t *testing.T) {
+	const elements, fpRate = 2000, 0.01
+	f, err := bloom.NewFilterForElements(elements, fpRate)
+	if err != nil {
+		t.Fatalf("NewFilterForElements: unexpected error: %v", err)
+	}
+	if rate := f.EstimateFPRate(); rate != 0 {
+		t.Errorf("EstimateFPRate: got %v for empty filter, want 0", rate)
+	}
+
+	var data [4]byte
+	for i := uint32(0); i < elements; i++ {
+		binary.BigEndian.PutUint32(data[:], i)
+		f.Add(data[:])
+	}
+	estimate := f.EstimateFPRate()
+	if estimate < fpRate/2 || estimate > fpRate*2 {
+		t.Errorf("EstimateFPRate: got %v, want about %v", estimate, fpRate)
+	}
+
+	const trials = 100000
+	var matches int
+	for i := uint32(elements); i < elements+trials; i++ {
+		binary.BigEndian.PutUint32(data[:], i)
+		if f.Matches(data[:]) {
+			matches++
+		}
+	}
+	observed := float64(matches) / trials
+	if observed < estimate/2 || observed > estimate*2 {
+		t.Errorf("EstimateFPRate: estimated %v, observed %v", estimate,
+			observed)
+	}
+
+	f.Unload()
+	if rate := f.EstimateFPRate(); rate != 0 {
+		t.Errorf("EstimateFPRate: got %v for unloaded filter, want 0", rate)
+	}
+}
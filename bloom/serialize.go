@@ -0,0 +1,197 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package bloom
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcd/wire/common"
+)
+
+var (
+	// ErrNotLoaded describes an error where a filter which is not loaded
+	// is serialized.
+	ErrNotLoaded = errors.New("bloom filter is not loaded")
+
+	// ErrInvalidFilter describes an error where a serialized filter is
+	// malformed, exceeds the size limits of filterload messages or is
+	// followed by extra bytes.
+	ErrInvalidFilter = errors.New("invalid serialized bloom filter")
+
+	// ErrUnknownUpdateType describes an error where a serialized filter
+	// has an update type other than none, all and p2pubkey-only.
+	ErrUnknownUpdateType = errors.New("unknown bloom filter update type")
+)
+
+// Map of bloom update types back to their names, as written in the JSON
+// encoding of filters.
+var updateTypeStrings = map[common.BloomUpdateType]string{
+	wire.BloomUpdateNone:         "none",
+	wire.BloomUpdateAll:          "all",
+	wire.BloomUpdateP2PubkeyOnly: "p2pubkeyonly",
+}
+
+// validateFilterLoad checks the filter respects the limits of filterload
+// messages and has a known update type.
+func validateFilterLoad(msg *wire.MsgFilterLoad) error {
+	if len(msg.Filter) > wire.MaxFilterLoadFilterSize ||
+		msg.HashFuncs > wire.MaxFilterLoadHashFuncs {
+		return ErrInvalidFilter
+	}
+	if _, ok := updateTypeStrings[msg.Flags]; !ok {
+		return ErrUnknownUpdateType
+	}
+	return nil
+}
+
+// Bytes returns the filter serialized as the payload of a filterload message:
+// the compact size length and the bytes of the filter, the number of hash
+// functions, the tweak and the update type.  The format is stable, so
+// serialized filters may be persisted across restarts.
+//
+// This function is safe for concurrent access.
+func (bf *Filter) Bytes() ([]byte, error) {
+	bf.mtx.Lock()
+	defer bf.mtx.Unlock()
+
+	if bf.msgFilterLoad == nil {
+		return nil, ErrNotLoaded
+	}
+	var buf bytes.Buffer
+	err := bf.msgFilterLoad.BtcEncode(&buf, wire.ProtocolVersion,
+		wire.LatestEncoding)
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeFilterLoad decodes a filter serialized by Bytes.
+func decodeFilterLoad(b []byte) (*wire.MsgFilterLoad, error) {
+	r := bytes.NewReader(b)
+	var msg wire.MsgFilterLoad
+	err := msg.BtcDecode(r, wire.ProtocolVersion, wire.LatestEncoding)
+	if err != nil || r.Len() != 0 {
+		return nil, ErrInvalidFilter
+	}
+	if err := validateFilterLoad(&msg); err != nil {
+		return nil, err
+	}
+	return &msg, nil
+}
+
+// NewFilterFromBytes returns the filter serialized by Bytes.
+func NewFilterFromBytes(b []byte) (*Filter, error) {
+	msg, err := decodeFilterLoad(b)
+	if err != nil {
+		return nil, err
+	}
+	return LoadFilter(msg), nil
+}
+
+// MarshalText implements the encoding.TextMarshaler interface.  The filter
+// is encoded as the hex of its serialization by Bytes.
+//
+// This function is safe for concurrent access.
+func (bf *Filter) MarshalText() ([]byte, error) {
+	b, err := bf.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	text := make([]byte, hex.EncodedLen(len(b)))
+	hex.Encode(text, b)
+	return text, nil
+}
+
+// UnmarshalText implements the encoding.TextUnmarshaler interface, loading
+// the filter encoded by MarshalText.
+//
+// This function is safe for concurrent access.
+func (bf *Filter) UnmarshalText(text []byte) error {
+	b := make([]byte, hex.DecodedLen(len(text)))
+	if _, err := hex.Decode(b, text); err != nil {
+		return ErrInvalidFilter
+	}
+	msg, err := decodeFilterLoad(b)
+	if err != nil {
+		return err
+	}
+	bf.Reload(msg)
+	return nil
+}
+
+// filterJSON is the JSON encoding of a filter.
+type filterJSON struct {
+	Filter    string `json:"filter"`
+	HashFuncs uint32 `json:"hashfuncs"`
+	Tweak     uint32 `json:"tweak"`
+	Flags     string `json:"flags"`
+}
+
+// MarshalJSON implements the json.Marshaler interface.  The filter is encoded
+// as an object with the hex of its bytes, its number of hash functions, its
+// tweak and the name of its update type: "none", "all" or "p2pubkeyonly".  A
+// filter which is not loaded is encoded as null.
+//
+// This function is safe for concurrent access.
+func (bf *Filter) MarshalJSON() ([]byte, error) {
+	bf.mtx.Lock()
+	msg := bf.msgFilterLoad
+	var v *filterJSON
+	if msg != nil {
+		v = &filterJSON{
+			Filter:    hex.EncodeToString(msg.Filter),
+			HashFuncs: msg.HashFuncs,
+			Tweak:     msg.Tweak,
+			Flags:     updateTypeStrings[msg.Flags],
+		}
+	}
+	bf.mtx.Unlock()
+	return json.Marshal(v)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface, loading the filter
+// encoded by MarshalJSON, or unloading it for null.
+//
+// This function is safe for concurrent access.
+func (bf *Filter) UnmarshalJSON(data []byte) error {
+	var v *filterJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if v == nil {
+		bf.Unload()
+		return nil
+	}
+
+	filter, err := hex.DecodeString(v.Filter)
+	if err != nil {
+		return ErrInvalidFilter
+	}
+	msg := &wire.MsgFilterLoad{
+		Filter:    filter,
+		HashFuncs: v.HashFuncs,
+		Tweak:     v.Tweak,
+	}
+	found := false
+	for flags, name := range updateTypeStrings {
+		if name == v.Flags {
+			msg.Flags, found = flags, true
+			break
+		}
+	}
+	if !found {
+		return ErrUnknownUpdateType
+	}
+	if err := validateFilterLoad(msg); err != nil {
+		return err
+	}
+	bf.Reload(msg)
+	return nil
+}
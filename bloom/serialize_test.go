@@ -0,0 +1,142 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package bloom_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil/bloom"
+)
+
+// testFilter returns a filter holding a few elements.
+func testFilter() *bloom.Filter {
+	f := bloom.NewFilter(3, 2147483649, 0.01, wire.BloomUpdateAll)
+	f.Add([]byte("one"))
+	f.Add([]byte("two"))
+	return f
+}
+
+// sameFilter reports whether a and b are loaded with the same filter.
+func sameFilter(a, b *bloom.Filter) bool {
+	ma, mb := a.MsgFilterLoad(), b.MsgFilterLoad()
+	return bytes.Equal(ma.Filter, mb.Filter) && ma.HashFuncs == mb.HashFuncs &&
+		ma.Tweak == mb.Tweak && ma.Flags == mb.Flags
+}
+
+// TestFilterBytes tests filters round trip through their serialization, which
+// is the payload of filterload messages.
+func TestFilterBytes(t *testing.T) {
+	f := testFilter()
+	b, err := f.Bytes()
+	if err != nil {
+		t.Fatalf("Bytes: unexpected error: %v", err)
+	}
+	var want bytes.Buffer
+	err = f.MsgFilterLoad().BtcEncode(&want, wire.ProtocolVersion,
+		wire.LatestEncoding)
+	if err != nil {
+		t.Fatalf("BtcEncode: unexpected error: %v", err)
+	}
+	if !bytes.Equal(b, want.Bytes()) {
+		t.Errorf("Bytes: got %x, want %x", b, want.Bytes())
+	}
+
+	g, err := bloom.NewFilterFromBytes(b)
+	if err != nil {
+		t.Fatalf("NewFilterFromBytes: unexpected error: %v", err)
+	}
+	if !sameFilter(f, g) || !g.Matches([]byte("one")) {
+		t.Errorf("NewFilterFromBytes: filter does not round trip")
+	}
+
+	if _, err := bloom.NewFilterFromBytes(append(b, 0)); err != bloom.ErrInvalidFilter {
+		t.Errorf("NewFilterFromBytes: got error %v, want %v", err,
+			bloom.ErrInvalidFilter)
+	}
+	if _, err := bloom.NewFilterFromBytes(b[:len(b)-1]); err != bloom.ErrInvalidFilter {
+		t.Errorf("NewFilterFromBytes: got error %v, want %v", err,
+			bloom.ErrInvalidFilter)
+	}
+	b[len(b)-1] = 7
+	if _, err := bloom.NewFilterFromBytes(b); err != bloom.ErrUnknownUpdateType {
+		t.Errorf("NewFilterFromBytes: got error %v, want %v", err,
+			bloom.ErrUnknownUpdateType)
+	}
+
+	f.Unload()
+	if _, err := f.Bytes(); err != bloom.ErrNotLoaded {
+		t.Errorf("Bytes: got error %v, want %v", err, bloom.ErrNotLoaded)
+	}
+	if _, err := f.MarshalText(); err != bloom.ErrNotLoaded {
+		t.Errorf("MarshalText: got error %v, want %v", err,
+			bloom.ErrNotLoaded)
+	}
+}
+
+// TestFilterText tests filters round trip through their hex encoding.
+func TestFilterText(t *testing.T) {
+	f := testFilter()
+	text, err := f.MarshalText()
+	if err != nil {
+		t.Fatalf("MarshalText: unexpected error: %v", err)
+	}
+	var g bloom.Filter
+	if err := g.UnmarshalText(text); err != nil {
+		t.Fatalf("UnmarshalText: unexpected error: %v", err)
+	}
+	if !sameFilter(f, &g) {
+		t.Errorf("UnmarshalText: filter does not round trip")
+	}
+	if err := g.UnmarshalText([]byte("zz")); err != bloom.ErrInvalidFilter {
+		t.Errorf("UnmarshalText: got error %v, want %v", err,
+			bloom.ErrInvalidFilter)
+	}
+}
+
+// TestFilterJSON tests the JSON encoding of filters.
+func TestFilterJSON(t *testing.T) {
+	f := bloom.LoadFilter(wire.NewMsgFilterLoad([]byte{0x01, 0xab}, 3, 42,
+		wire.BloomUpdateP2PubkeyOnly))
+	got, err := json.Marshal(f)
+	if err != nil {
+		t.Fatalf("Marshal: unexpected error: %v", err)
+	}
+	want := `{"filter":"01ab","hashfuncs":3,"tweak":42,"flags":"p2pubkeyonly"}`
+	if string(got) != want {
+		t.Errorf("Marshal: got %s, want %s", got, want)
+	}
+
+	var g bloom.Filter
+	if err := json.Unmarshal(got, &g); err != nil {
+		t.Fatalf("Unmarshal: unexpected error: %v", err)
+	}
+	if !sameFilter(f, &g) {
+		t.Errorf("Unmarshal: filter does not round trip")
+	}
+	if err := json.Unmarshal([]byte("null"), &g); err != nil || g.IsLoaded() {
+		t.Errorf("Unmarshal: null left the filter loaded: %v", err)
+	}
+	if got, _ := json.Marshal(&g); string(got) != "null" {
+		t.Errorf("Marshal: got %s for an unloaded filter", got)
+	}
+
+	tests := []struct {
+		in  string
+		err error
+	}{
+		{`{"filter":"0g","hashfuncs":1,"flags":"none"}`, bloom.ErrInvalidFilter},
+		{`{"filter":"00","hashfuncs":51,"flags":"none"}`, bloom.ErrInvalidFilter},
+		{`{"filter":"00","hashfuncs":1,"flags":"some"}`, bloom.ErrUnknownUpdateType},
+	}
+	for _, test := range tests {
+		if err := json.Unmarshal([]byte(test.in), &g); err != test.err {
+			t.Errorf("Unmarshal(%s): got error %v, want %v", test.in, err,
+				test.err)
+		}
+	}
+}
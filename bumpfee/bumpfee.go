@@ -0,0 +1,243 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package bumpfee builds replacements of unconfirmed transactions paying a
+// higher fee, following the replace-by-fee rules of BIP0125.
+//
+// The replacement spends the same inputs and pays the same outputs as the
+// original transaction, except for its change output, which is reduced by
+// the additional fee.  A replacement is relayed only when it pays more than
+// the original both in fee rate and in absolute fee, and when the additional
+// fee pays for its own relay at the incremental relay fee rate.  BumpFee
+// raises the fee to the least satisfying all of these and the requested fee
+// rate, and removes the change output when what remains of it would be dust.
+//
+// Every input of the replacement signals replaceability, so it can be bumped
+// again.  The replacement is returned unsigned, since changing its outputs
+// invalidates the signatures of the original.
+package bumpfee
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/signing"
+	"github.com/zeusyf/btcutil/txsizes"
+	"github.com/zeusyf/omega/token"
+)
+
+const (
+	// MaxRBFSequence is the largest sequence number of an input signaling
+	// that its transaction may be replaced.  Inputs of replacements with a
+	// larger sequence number are given this one.
+	MaxRBFSequence = wire.MaxTxInSequenceNum - 2
+
+	// DefaultIncrementalRelayFeeRate is the fee rate the additional fee of
+	// a replacement must at least pay for its size when no other is set.
+	DefaultIncrementalRelayFeeRate btcutil.FeeRate = 1000
+
+	// DefaultDustRelayFeeRate is the fee rate the reduced change output is
+	// checked against for dust when no other is set.
+	DefaultDustRelayFeeRate btcutil.FeeRate = 3000
+)
+
+var (
+	// ErrMissingPrevOut describes an error where the output spent by an
+	// input of the original transaction is unknown, so its fee can't be
+	// computed.
+	ErrMissingPrevOut = errors.New("output spent by input is unknown")
+
+	// ErrInvalidChange describes an error where the change output is out
+	// of range or does not carry the base token.
+	ErrInvalidChange = errors.New("invalid change output")
+
+	// ErrFeeRateTooLow describes an error where the requested fee rate is
+	// not above the fee rate of the original transaction.
+	ErrFeeRateTooLow = errors.New("fee rate not above the original")
+
+	// ErrInsufficientChange describes an error where the change output
+	// can't pay the additional fee.
+	ErrInsufficientChange = errors.New("change can't pay the additional fee")
+)
+
+// Option configures BumpFee.
+type Option func(*config)
+
+// config holds the configuration of BumpFee.
+type config struct {
+	incrementalFeeRate btcutil.FeeRate
+	dustFeeRate        btcutil.FeeRate
+}
+
+// WithIncrementalRelayFeeRate sets the fee rate the additional fee of the
+// replacement must at least pay for its size.
+// DefaultIncrementalRelayFeeRate is used by default.
+func WithIncrementalRelayFeeRate(feeRate btcutil.FeeRate) Option {
+	return func(c *config) {
+		c.incrementalFeeRate = feeRate
+	}
+}
+
+// WithDustRelayFeeRate sets the fee rate the reduced change output is checked
+// against for dust.  DefaultDustRelayFeeRate is used by default.
+func WithDustRelayFeeRate(feeRate btcutil.FeeRate) Option {
+	return func(c *config) {
+		c.dustFeeRate = feeRate
+	}
+}
+
+// Result is a replacement transaction.
+type Result struct {
+	// Tx is the unsigned replacement.
+	Tx *wire.MsgTx
+
+	// OriginalFee is the fee paid by the original transaction.
+	OriginalFee btcutil.Amount
+
+	// Fee is the fee paid by the replacement.
+	Fee btcutil.Amount
+
+	// Size is the size the fee of the replacement was computed for.
+	Size int
+
+	// ChangeRemoved is set when the change output was removed, its whole
+	// value going to the fee.
+	ChangeRemoved bool
+}
+
+// baseValue returns the amount of the base token carried by tok, and
+// whether it carries the base token.
+func baseValue(tok *token.Token) (btcutil.Amount, bool) {
+	value, ok := tok.Value.(*token.NumeralVal)
+	if tok.TokenType != 0 || !ok {
+		return 0, false
+	}
+	return btcutil.Amount(value.Val), true
+}
+
+// copyTx returns a deep copy of tx made through its serialization.
+func copyTx(tx *wire.MsgTx) (*wire.MsgTx, error) {
+	var buf bytes.Buffer
+	if err := tx.Serialize(&buf); err != nil {
+		return nil, err
+	}
+	c := new(wire.MsgTx)
+	if err := c.Deserialize(&buf); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// signedSize returns the size of tx once re-signed: its size with the
+// signature scripts of the original, allowing each to grow by a byte, as a
+// signature of the same key may be one byte longer.
+func signedSize(tx *wire.MsgTx) (int, error) {
+	var buf bytes.Buffer
+	if err := tx.Serialize(&buf); err != nil {
+		return 0, err
+	}
+	return buf.Len() + len(tx.TxIn), nil
+}
+
+// BumpFee returns the replacement of the signed transaction tx paying at
+// least feeRate, whose additional fee is taken from the output at
+// changeIndex.  prevOuts supplies the outputs spent by tx to compute its fee.
+//
+// ErrFeeRateTooLow is returned when feeRate is not above the fee rate of tx,
+// and ErrInsufficientChange when the change output can't pay the additional
+// fee, even by removing it.
+func BumpFee(tx *wire.MsgTx, prevOuts signing.PrevOutFetcher, changeIndex int,
+	feeRate btcutil.FeeRate, opts ...Option) (*Result, error) {
+	cfg := config{
+		incrementalFeeRate: DefaultIncrementalRelayFeeRate,
+		dustFeeRate:        DefaultDustRelayFeeRate,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if changeIndex < 0 || changeIndex >= len(tx.TxOut) {
+		return nil, ErrInvalidChange
+	}
+	change, ok := baseValue(&tx.TxOut[changeIndex].Token)
+	if !ok {
+		return nil, ErrInvalidChange
+	}
+
+	var originalFee btcutil.Amount
+	for _, txIn := range tx.TxIn {
+		prevOut := prevOuts.FetchPrevOutput(txIn.PreviousOutPoint)
+		if prevOut == nil {
+			return nil, ErrMissingPrevOut
+		}
+		value, _ := baseValue(&prevOut.Token)
+		originalFee += value
+	}
+	for _, txOut := range tx.TxOut {
+		value, _ := baseValue(&txOut.Token)
+		originalFee -= value
+	}
+	var buf bytes.Buffer
+	if err := tx.Serialize(&buf); err != nil {
+		return nil, err
+	}
+	originalRate := btcutil.NewFeeRateFromFee(originalFee, int64(buf.Len()))
+	if feeRate <= originalRate {
+		return nil, ErrFeeRateTooLow
+	}
+
+	replacement, err := copyTx(tx)
+	if err != nil {
+		return nil, err
+	}
+	for _, txIn := range replacement.TxIn {
+		if txIn.Sequence > MaxRBFSequence {
+			txIn.Sequence = MaxRBFSequence
+		}
+	}
+
+	// requiredFee returns the least fee of a replacement of the passed
+	// size: the requested fee rate, and the original fee plus the relay
+	// of the replacement at the incremental fee rate.
+	requiredFee := func(size int) btcutil.Amount {
+		fee := feeRate.FeeForVSize(int64(size))
+		relayFee := originalFee +
+			cfg.incrementalFeeRate.FeeForVSize(int64(size))
+		if fee < relayFee {
+			fee = relayFee
+		}
+		return fee
+	}
+
+	size, err := signedSize(replacement)
+	if err != nil {
+		return nil, err
+	}
+	fee := requiredFee(size)
+	newChange := change - (fee - originalFee)
+	changeOut := replacement.TxOut[changeIndex]
+	changeOut.Token.Value = &token.NumeralVal{Val: int64(newChange)}
+	res := &Result{OriginalFee: originalFee, Fee: fee, Size: size}
+	if newChange <= 0 || txsizes.IsDust(changeOut, cfg.dustFeeRate) {
+		// Give the whole change to the fee.
+		replacement.TxOut = append(replacement.TxOut[:changeIndex],
+			replacement.TxOut[changeIndex+1:]...)
+		if size, err = signedSize(replacement); err != nil {
+			return nil, err
+		}
+		fee = originalFee + change
+		if len(replacement.TxOut) == 0 || fee < requiredFee(size) {
+			return nil, ErrInsufficientChange
+		}
+		res.Fee, res.Size, res.ChangeRemoved = fee, size, true
+	}
+
+	for _, txIn := range replacement.TxIn {
+		txIn.SignatureIndex = 0
+	}
+	replacement.SignatureScripts = nil
+	res.Tx = replacement
+	return res, nil
+}
@@ -0,0 +1,136 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package bumpfee_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/bumpfee"
+	"github.com/zeusyf/btcutil/signing"
+	"github.com/zeusyf/omega/token"
+)
+
+// output returns an output paying amount of the base token to pkScript.
+func output(amount int64, pkScript []byte) *wire.TxOut {
+	return &wire.TxOut{
+		Token:    token.Token{Value: &token.NumeralVal{Val: amount}},
+		PkScript: pkScript,
+	}
+}
+
+// testTx returns a signed transaction spending two outputs worth 100000 in
+// total, paying change and the rest less a fee of 1000, along with the
+// outputs it spends.
+func testTx(change int64) (*wire.MsgTx, signing.MapPrevOutFetcher) {
+	tx := wire.NewMsgTx(wire.TxVersion)
+	prevOuts := make(signing.MapPrevOutFetcher)
+	for i, amount := range []int64{60000, 40000} {
+		op := wire.OutPoint{Hash: chainhash.Hash{byte(i + 1)}}
+		prevOuts.AddPrevOut(op, output(amount, []byte{0x51}))
+		tx.AddTxIn(&wire.TxIn{
+			PreviousOutPoint: op,
+			Sequence:         wire.MaxTxInSequenceNum,
+			SignatureIndex:   uint32(i),
+		})
+		tx.SignatureScripts = append(tx.SignatureScripts,
+			bytes.Repeat([]byte{0x01}, 107))
+	}
+	tx.AddTxOut(output(99000-change, []byte{0x52}))
+	tx.AddTxOut(output(change, []byte{0x53}))
+	return tx, prevOuts
+}
+
+// TestBumpFee ensures replacements take the additional fee from the change,
+// signal replaceability, and satisfy the fee rules of BIP0125.
+func TestBumpFee(t *testing.T) {
+	tx, prevOuts := testTx(45000)
+	pay := int64(99000 - 45000)
+	origHash := tx.TxHash()
+	feeRate := btcutil.NewFeeRateFromHaoPerVByte(50)
+	res, err := bumpfee.BumpFee(tx, prevOuts, 1, feeRate)
+	if err != nil {
+		t.Fatalf("BumpFee: unexpected error: %v", err)
+	}
+	if res.OriginalFee != 1000 || res.ChangeRemoved {
+		t.Errorf("BumpFee: got %+v", res)
+	}
+	if res.Fee < feeRate.FeeForVSize(int64(res.Size)) ||
+		res.Fee < res.OriginalFee+bumpfee.DefaultIncrementalRelayFeeRate.FeeForVSize(int64(res.Size)) {
+		t.Errorf("BumpFee: fee %v too low for size %d", res.Fee, res.Size)
+	}
+
+	r := res.Tx
+	if len(r.TxIn) != 2 || len(r.TxOut) != 2 || len(r.SignatureScripts) != 0 {
+		t.Fatalf("BumpFee: got %d inputs, %d outputs, %d signature "+
+			"scripts", len(r.TxIn), len(r.TxOut), len(r.SignatureScripts))
+	}
+	for i, txIn := range r.TxIn {
+		if txIn.PreviousOutPoint != tx.TxIn[i].PreviousOutPoint ||
+			txIn.Sequence != bumpfee.MaxRBFSequence {
+			t.Errorf("BumpFee: got input %d %+v", i, txIn)
+		}
+	}
+	paid := r.TxOut[0].Token.Value.(*token.NumeralVal).Val
+	change := r.TxOut[1].Token.Value.(*token.NumeralVal).Val
+	if paid != pay || btcutil.Amount(100000-paid-change) != res.Fee {
+		t.Errorf("BumpFee: got outputs %d and %d for fee %v", paid,
+			change, res.Fee)
+	}
+	if tx.TxHash() != origHash || len(tx.SignatureScripts) != 2 {
+		t.Errorf("BumpFee: original transaction modified")
+	}
+
+	// A small bump is raised to pay for the relay of the replacement.
+	small := btcutil.NewFeeRateFromHaoPerVByte(4)
+	res, err = bumpfee.BumpFee(tx, prevOuts, 1, small,
+		bumpfee.WithIncrementalRelayFeeRate(5000))
+	if err != nil {
+		t.Fatalf("BumpFee: unexpected error: %v", err)
+	}
+	if want := res.OriginalFee + btcutil.FeeRate(5000).FeeForVSize(int64(res.Size)); res.Fee != want {
+		t.Errorf("BumpFee: got fee %v, want %v", res.Fee, want)
+	}
+}
+
+// TestBumpFeeChange ensures dust change is removed, and replacements the
+// change can't pay for are rejected.
+func TestBumpFeeChange(t *testing.T) {
+	tx, prevOuts := testTx(1500)
+	feeRate := btcutil.NewFeeRateFromHaoPerVByte(4)
+	res, err := bumpfee.BumpFee(tx, prevOuts, 1, feeRate)
+	if err != nil {
+		t.Fatalf("BumpFee: unexpected error: %v", err)
+	}
+	if !res.ChangeRemoved || len(res.Tx.TxOut) != 1 || res.Fee != 2500 {
+		t.Errorf("BumpFee: got %+v", res)
+	}
+
+	tests := []struct {
+		name        string
+		changeIndex int
+		feeRate     btcutil.FeeRate
+		err         error
+	}{
+		{"no change", 2, feeRate, bumpfee.ErrInvalidChange},
+		{"lower rate", 1, btcutil.NewFeeRateFromHaoPerVByte(1), bumpfee.ErrFeeRateTooLow},
+		{"change too small", 1, btcutil.NewFeeRateFromHaoPerVByte(500), bumpfee.ErrInsufficientChange},
+	}
+	for _, test := range tests {
+		_, err := bumpfee.BumpFee(tx, prevOuts, test.changeIndex, test.feeRate)
+		if err != test.err {
+			t.Errorf("BumpFee(%s): got error %v, want %v", test.name, err,
+				test.err)
+		}
+	}
+	_, err = bumpfee.BumpFee(tx, signing.MapPrevOutFetcher{}, 1, feeRate)
+	if err != bumpfee.ErrMissingPrevOut {
+		t.Errorf("BumpFee: got error %v, want %v", err,
+			bumpfee.ErrMissingPrevOut)
+	}
+}
@@ -0,0 +1,164 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package btcutil
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+
+	"golang.org/x/crypto/ripemd160"
+)
+
+var (
+	// ErrDuplicateBurnAddress describes an error where a burn address
+	// being registered is already in the registry.
+	ErrDuplicateBurnAddress = errors.New("duplicate burn address")
+
+	// ErrInvalidBurnAddress describes an error where a burn address being
+	// registered does not commit to a 20 byte hash.
+	ErrInvalidBurnAddress = errors.New("burn address must commit to a " +
+		"20 byte hash")
+)
+
+// burnRegistry holds the hashes of addresses nobody can spend from, keyed by
+// the 20 byte hash the address commits to, mapped to a short description.
+// Keying on the hash rather than the encoded address makes an entry match
+// the same hash on every network and for every address type.
+//
+// The registry is copy-on-write: the map stored in burnRegistry is never
+// modified once stored, so readers load it without locking and always see a
+// consistent snapshot.  Writers serialize on burnMtx, copy the current map,
+// add their entry, and store the copy.
+var (
+	burnMtx      sync.Mutex
+	burnRegistry atomic.Value // map[[ripemd160.Size]byte]string
+)
+
+// burnSnapshot returns the current, immutable contents of the registry.
+func burnSnapshot() map[[ripemd160.Size]byte]string {
+	m, _ := burnRegistry.Load().(map[[ripemd160.Size]byte]string)
+	return m
+}
+
+func init() {
+	// The all zero hash is the conventional burn destination.  Finding a
+	// public key or script hashing to it is infeasible.
+	mustRegisterBurnHash("0000000000000000000000000000000000000000",
+		"all zero hash")
+
+	// The vanity address 1BitcoinEaterAddressDontSendf59kuE was made by
+	// choosing the encoded string and fixing up its checksum, so it has
+	// no known preimage.
+	mustRegisterBurnHash("759d6677091e973b9e9d99f19c68fbf43e3f05f9",
+		"bitcoin eater address")
+}
+
+// mustRegisterBurnHash adds a built-in entry to the registry and panics if it
+// is malformed.  It is only used for the hard-coded entries so errors in the
+// source code can be detected.
+func mustRegisterBurnHash(s, description string) {
+	hash, err := hex.DecodeString(s)
+	if err != nil {
+		panic("invalid hex in source file: " + s)
+	}
+	if err := RegisterBurnHash(hash, description); err != nil {
+		panic(err)
+	}
+}
+
+// RegisterBurnAddress adds the hash committed to by the passed address to
+// the registry of burn addresses along with a short description of why it is
+// unspendable.  Applications use it to tag destinations specific to their
+// network or their own provably unspendable scripts.
+//
+// ErrDuplicateBurnAddress is returned when the hash is already registered and
+// ErrInvalidBurnAddress when the address does not commit to a 20 byte hash,
+// such as a pay-to-pubkey address.
+func RegisterBurnAddress(addr Address, description string) error {
+	return RegisterBurnHash(addr.ScriptAddress(), description)
+}
+
+// RegisterBurnHash is like RegisterBurnAddress, but takes the 20 byte hash
+// directly.
+func RegisterBurnHash(hash []byte, description string) error {
+	if len(hash) != ripemd160.Size {
+		return ErrInvalidBurnAddress
+	}
+	var key [ripemd160.Size]byte
+	copy(key[:], hash)
+
+	burnMtx.Lock()
+	defer burnMtx.Unlock()
+
+	old := burnSnapshot()
+	if _, ok := old[key]; ok {
+		return ErrDuplicateBurnAddress
+	}
+	m := make(map[[ripemd160.Size]byte]string, len(old)+1)
+	for k, v := range old {
+		m[k] = v
+	}
+	m[key] = description
+	burnRegistry.Store(m)
+	return nil
+}
+
+// IsBurnAddress returns whether the passed address is a registered burn
+// address.  Funds sent to it can never be spent.
+func IsBurnAddress(addr Address) bool {
+	_, ok := BurnAddressDescription(addr)
+	return ok
+}
+
+// IsBurnHash returns whether the passed 20 byte hash is registered as the
+// hash of a burn address.
+func IsBurnHash(hash []byte) bool {
+	_, ok := burnHashDescription(hash)
+	return ok
+}
+
+// BurnAddressDescription returns the description the passed address was
+// registered with and whether it is a registered burn address.
+func BurnAddressDescription(addr Address) (string, bool) {
+	return burnHashDescription(addr.ScriptAddress())
+}
+
+// burnHashDescription returns the description the passed hash was registered
+// with and whether it is registered.
+func burnHashDescription(hash []byte) (string, bool) {
+	if len(hash) != ripemd160.Size {
+		return "", false
+	}
+	var key [ripemd160.Size]byte
+	copy(key[:], hash)
+
+	description, ok := burnSnapshot()[key]
+	return description, ok
+}
+
+// BurnHashEntry is a registered burn hash and its description.
+type BurnHashEntry struct {
+	Hash        [ripemd160.Size]byte
+	Description string
+}
+
+// BurnHashes returns every registered burn hash, sorted by hash.  The result
+// is a snapshot of the registry taken at the time of the call; entries
+// registered concurrently or later are not included.
+func BurnHashes() []BurnHashEntry {
+	m := burnSnapshot()
+	entries := make([]BurnHashEntry, 0, len(m))
+	for hash, description := range m {
+		entries = append(entries, BurnHashEntry{hash, description})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return bytes.Compare(entries[i].Hash[:], entries[j].Hash[:]) < 0
+	})
+	return entries
+}
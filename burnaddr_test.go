@@ -0,0 +1,136 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package btcutil_test
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil"
+)
+
+// TestBurnAddress ensures the built-in burn addresses are detected on every
+// network and custom entries can be registered.
+func TestBurnAddress(t *testing.T) {
+	zero := make([]byte, 20)
+	for _, net := range []*chaincfg.Params{&chaincfg.MainNetParams,
+		&chaincfg.TestNet3Params, &chaincfg.RegressionNetParams} {
+
+		addr, err := btcutil.NewAddressPubKeyHash(zero, net)
+		if err != nil {
+			t.Fatalf("NewAddressPubKeyHash: unexpected error: %v", err)
+		}
+		if !btcutil.IsBurnAddress(addr) {
+			t.Errorf("IsBurnAddress(%s): zero hash on %s not detected",
+				addr, net.Name)
+		}
+	}
+
+	custom := bytes.Repeat([]byte{0xbe}, 20)
+	addr, err := btcutil.NewAddressScriptHashFromHash(custom,
+		&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("NewAddressScriptHashFromHash: unexpected error: %v", err)
+	}
+	if btcutil.IsBurnAddress(addr) {
+		t.Fatalf("IsBurnAddress(%s): unregistered address detected", addr)
+	}
+	if err := btcutil.RegisterBurnAddress(addr, "test"); err != nil {
+		t.Fatalf("RegisterBurnAddress: unexpected error: %v", err)
+	}
+	desc, ok := btcutil.BurnAddressDescription(addr)
+	if !ok || desc != "test" {
+		t.Fatalf("BurnAddressDescription: got %q %v, want %q true",
+			desc, ok, "test")
+	}
+	if !btcutil.IsBurnHash(custom) {
+		t.Fatalf("IsBurnHash: registered hash not detected")
+	}
+
+	err = btcutil.RegisterBurnAddress(addr, "again")
+	if err != btcutil.ErrDuplicateBurnAddress {
+		t.Fatalf("RegisterBurnAddress: got %v, want %v", err,
+			btcutil.ErrDuplicateBurnAddress)
+	}
+	err = btcutil.RegisterBurnHash([]byte{1, 2, 3}, "short")
+	if err != btcutil.ErrInvalidBurnAddress {
+		t.Fatalf("RegisterBurnHash: got %v, want %v", err,
+			btcutil.ErrInvalidBurnAddress)
+	}
+}
+
+// concurrentRuns counts the runs of TestBurnRegistryConcurrent.
+var concurrentRuns byte
+
+// TestBurnRegistryConcurrent registers burn hashes while other goroutines
+// read and iterate the registry, to be run with the race detector, and
+// ensures snapshots are sorted and complete once registration finishes.
+func TestBurnRegistryConcurrent(t *testing.T) {
+	const writers, perWriter = 4, 50
+
+	// The registry is global, so make the hashes unique to this run in
+	// case the test is run repeatedly with -count.
+	concurrentRuns++
+	run := concurrentRuns
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				entries := btcutil.BurnHashes()
+				for j := 1; j < len(entries); j++ {
+					if bytes.Compare(entries[j-1].Hash[:],
+						entries[j].Hash[:]) >= 0 {
+						t.Errorf("BurnHashes: snapshot not sorted")
+						return
+					}
+				}
+				btcutil.IsBurnHash(make([]byte, 20))
+			}
+		}()
+	}
+
+	var writersWg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		writersWg.Add(1)
+		go func(w int) {
+			defer writersWg.Done()
+			for i := 0; i < perWriter; i++ {
+				hash := make([]byte, 20)
+				hash[0], hash[1], hash[2], hash[3] = 0xc0, run,
+					byte(w), byte(i)
+				err := btcutil.RegisterBurnHash(hash, "concurrent")
+				if err != nil {
+					t.Errorf("RegisterBurnHash: unexpected error: %v",
+						err)
+				}
+			}
+		}(w)
+	}
+	writersWg.Wait()
+	close(done)
+	wg.Wait()
+
+	var found int
+	for _, entry := range btcutil.BurnHashes() {
+		if entry.Hash[0] == 0xc0 && entry.Hash[1] == run {
+			found++
+		}
+	}
+	if found != writers*perWriter {
+		t.Fatalf("BurnHashes: got %d registered entries, want %d", found,
+			writers*perWriter)
+	}
+}
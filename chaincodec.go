@@ -0,0 +1,215 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package btcutil
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil/base58"
+	"golang.org/x/crypto/ripemd160"
+)
+
+// ErrWrongChain describes an error where an address or key is decoded for a
+// chain whose version bytes it does not carry.
+var ErrWrongChain = errors.New("encoding is not for the chain")
+
+// SigHashFlavor identifies the algorithm a chain uses to compute the hashes
+// signed by the ECDSA signatures of transaction inputs.
+type SigHashFlavor uint8
+
+const (
+	// SigHashLegacy is the original algorithm, which commits to the
+	// transaction with the script of the spent output, but not to its
+	// amount.  Omega uses it.
+	SigHashLegacy SigHashFlavor = iota
+
+	// SigHashWitnessV0 is the algorithm of BIP0143, which also commits to
+	// the amount of the spent output.
+	SigHashWitnessV0
+
+	// SigHashForkID is the algorithm of BIP0143 with a chain specific fork
+	// id mixed into the hash type, used by chains which split from
+	// Bitcoin to prevent replays.
+	SigHashForkID
+)
+
+// sigHashFlavorStrings is a map of signature hash flavors back to their
+// constant names for pretty printing.
+var sigHashFlavorStrings = map[SigHashFlavor]string{
+	SigHashLegacy:    "SigHashLegacy",
+	SigHashWitnessV0: "SigHashWitnessV0",
+	SigHashForkID:    "SigHashForkID",
+}
+
+// String returns the SigHashFlavor in human-readable form.
+func (f SigHashFlavor) String() string {
+	if s, ok := sigHashFlavorStrings[f]; ok {
+		return s
+	}
+	return fmt.Sprintf("Unknown SigHashFlavor (%d)", uint8(f))
+}
+
+// ChainCodec describes how a chain encodes addresses, keys, amounts and
+// transactions, so services handling OMC alongside Bitcoin-like chains can
+// decode and validate the data of each chain with one code path.
+type ChainCodec interface {
+	// Name returns the name of the chain.
+	Name() string
+
+	// Params returns the network parameters of the chain, which hold the
+	// version bytes of its addresses and keys.
+	Params() *chaincfg.Params
+
+	// AmountDecimals returns the number of decimals of the unit amounts
+	// of the chain are displayed in, which is the base unit when zero.
+	AmountDecimals() int
+
+	// SigHashFlavor returns the algorithm computing the hashes signed by
+	// the ECDSA signatures of the chain.
+	SigHashFlavor() SigHashFlavor
+
+	// HasTokens returns whether outputs of the chain carry tokens other
+	// than its coin, along with the pay-to-contract and multi-signature
+	// addresses of Omega.
+	HasTokens() bool
+
+	// SeparateSignatureScripts returns whether transactions of the chain
+	// carry the signature scripts of their inputs apart from the inputs,
+	// as Omega transactions do.
+	SeparateSignatureScripts() bool
+}
+
+// BasicChainCodec is a ChainCodec whose properties are set by its fields.
+type BasicChainCodec struct {
+	ChainName          string
+	Net                *chaincfg.Params
+	Decimals           int
+	SigHash            SigHashFlavor
+	Tokens             bool
+	SeparateSigScripts bool
+}
+
+// Ensure BasicChainCodec implements the ChainCodec interface.
+var _ ChainCodec = (*BasicChainCodec)(nil)
+
+// NewOmegaChainCodec returns the codec of Omega on the network of net.
+func NewOmegaChainCodec(net *chaincfg.Params) *BasicChainCodec {
+	return &BasicChainCodec{
+		ChainName:          "omega",
+		Net:                net,
+		Decimals:           OMCDecimals,
+		SigHash:            SigHashLegacy,
+		Tokens:             true,
+		SeparateSigScripts: true,
+	}
+}
+
+// Name returns the name of the chain.  Part of the ChainCodec interface.
+func (c *BasicChainCodec) Name() string {
+	return c.ChainName
+}
+
+// Params returns the network parameters of the chain.  Part of the
+// ChainCodec interface.
+func (c *BasicChainCodec) Params() *chaincfg.Params {
+	return c.Net
+}
+
+// AmountDecimals returns the number of decimals of the display unit of the
+// chain.  Part of the ChainCodec interface.
+func (c *BasicChainCodec) AmountDecimals() int {
+	return c.Decimals
+}
+
+// SigHashFlavor returns the signature hash algorithm of the chain.  Part of
+// the ChainCodec interface.
+func (c *BasicChainCodec) SigHashFlavor() SigHashFlavor {
+	return c.SigHash
+}
+
+// HasTokens returns whether outputs of the chain carry tokens.  Part of the
+// ChainCodec interface.
+func (c *BasicChainCodec) HasTokens() bool {
+	return c.Tokens
+}
+
+// SeparateSignatureScripts returns whether transactions of the chain carry
+// their signature scripts apart from their inputs.  Part of the ChainCodec
+// interface.
+func (c *BasicChainCodec) SeparateSignatureScripts() bool {
+	return c.SeparateSigScripts
+}
+
+// DecodeAddressForChain decodes the string encoding of an address of the
+// chain described by codec.  Unlike DecodeAddress, the version byte of the
+// address is matched against the parameters of the chain only, rather than
+// against every registered network, so chains whose version bytes collide
+// with those of other chains are decoded unambiguously.  Addresses of other
+// chains fail with ErrWrongChain.
+func DecodeAddressForChain(addr string, codec ChainCodec) (Address, error) {
+	net := codec.Params()
+
+	// Serialized public keys are either 65 bytes (130 hex chars) if
+	// uncompressed/hybrid or 33 bytes (66 hex chars) if compressed.
+	if len(addr) == 130 || len(addr) == 66 {
+		serializedPubKey, err := hex.DecodeString(addr)
+		if err != nil {
+			return nil, err
+		}
+		return NewAddressPubKey(serializedPubKey, net)
+	}
+
+	decoded, netID, err := base58.CheckDecode(addr)
+	if err == base58.ErrChecksum {
+		return nil, ErrChecksumMismatch
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(decoded) != ripemd160.Size {
+		return nil, fmt.Errorf("%w: decoded address is of unknown "+
+			"size", ErrUnknownAddressType)
+	}
+	switch {
+	case netID == net.PubKeyHashAddrID:
+		return newAddressPubKeyHash(decoded, netID)
+	case netID == net.ScriptHashAddrID:
+		return newAddressScriptHashFromHash(decoded, netID)
+	case codec.HasTokens() && netID == net.ContractAddrID:
+		return newAddressContract(decoded, netID)
+	case codec.HasTokens() && netID == net.MultiSigAddrID:
+		return newAddressMultiSig(decoded, netID)
+	}
+	return nil, ErrWrongChain
+}
+
+// DecodeWIFForChain decodes the string encoding of a private key of the chain
+// described by codec.  Keys of other chains fail with ErrWrongChain.
+func DecodeWIFForChain(wif string, codec ChainCodec) (*WIF, error) {
+	w, err := DecodeWIF(wif)
+	if err != nil {
+		return nil, err
+	}
+	if !w.IsForNet(codec.Params()) {
+		return nil, ErrWrongChain
+	}
+	return w, nil
+}
+
+// FormatAmountForChain formats the amount, in the base unit of the chain
+// described by codec, as an exact decimal number of its display unit without
+// a unit label.
+func FormatAmountForChain(a Amount, codec ChainCodec) string {
+	return formatDecimal(a, codec.AmountDecimals())
+}
+
+// ParseAmountForChain parses an amount formatted by FormatAmountForChain for
+// the chain described by codec, returning it in the base unit of the chain.
+func ParseAmountForChain(s string, codec ChainCodec) (Amount, error) {
+	return parseDecimal(s, codec.AmountDecimals())
+}
@@ -0,0 +1,131 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package btcutil_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil"
+)
+
+// TestDecodeAddressForChain ensures addresses decode only for the chain whose
+// version bytes they carry.
+func TestDecodeAddressForChain(t *testing.T) {
+	omega := btcutil.NewOmegaChainCodec(&chaincfg.MainNetParams)
+	other := btcutil.NewOmegaChainCodec(&chaincfg.TestNet3Params)
+	tokenless := &btcutil.BasicChainCodec{
+		ChainName: "tokenless",
+		Net:       &chaincfg.MainNetParams,
+		Decimals:  8,
+		SigHash:   btcutil.SigHashWitnessV0,
+	}
+
+	hash := bytes.Repeat([]byte{0x11}, 20)
+	pkh, err := btcutil.NewAddressPubKeyHash(hash, &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("NewAddressPubKeyHash: %v", err)
+	}
+	contract, err := btcutil.NewAddressContract(hash, &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("NewAddressContract: %v", err)
+	}
+
+	tests := []struct {
+		name  string
+		addr  btcutil.Address
+		codec btcutil.ChainCodec
+		err   error
+	}{
+		{"p2pkh", pkh, omega, nil},
+		{"p2pkh tokenless", pkh, tokenless, nil},
+		{"p2pkh other network", pkh, other, btcutil.ErrWrongChain},
+		{"contract", contract, omega, nil},
+		{"contract tokenless", contract, tokenless, btcutil.ErrWrongChain},
+	}
+	for _, test := range tests {
+		addr, err := btcutil.DecodeAddressForChain(test.addr.EncodeAddress(),
+			test.codec)
+		if err != test.err {
+			t.Errorf("%s: got error %v, want %v", test.name, err, test.err)
+			continue
+		}
+		if err == nil && addr.EncodeAddress() != test.addr.EncodeAddress() {
+			t.Errorf("%s: got %v, want %v", test.name, addr, test.addr)
+		}
+	}
+}
+
+// TestDecodeWIFForChain ensures private keys decode only for the chain whose
+// version byte they carry.
+func TestDecodeWIFForChain(t *testing.T) {
+	priv, _ := btcec.PrivKeyFromBytes(btcec.S256(), []byte{0x2a})
+	wif, err := btcutil.NewWIF(priv, &chaincfg.TestNet3Params, true)
+	if err != nil {
+		t.Fatalf("NewWIF: %v", err)
+	}
+
+	codec := btcutil.NewOmegaChainCodec(&chaincfg.TestNet3Params)
+	decoded, err := btcutil.DecodeWIFForChain(wif.String(), codec)
+	if err != nil {
+		t.Fatalf("DecodeWIFForChain: %v", err)
+	}
+	if decoded.String() != wif.String() {
+		t.Errorf("got %v, want %v", decoded, wif)
+	}
+
+	codec = btcutil.NewOmegaChainCodec(&chaincfg.MainNetParams)
+	if _, err := btcutil.DecodeWIFForChain(wif.String(), codec); err != btcutil.ErrWrongChain {
+		t.Errorf("other network: got error %v, want %v", err,
+			btcutil.ErrWrongChain)
+	}
+}
+
+// TestFormatAmountForChain ensures amounts are formatted and parsed in the
+// display unit of each chain.
+func TestFormatAmountForChain(t *testing.T) {
+	cents := &btcutil.BasicChainCodec{ChainName: "cents", Decimals: 2}
+	tests := []struct {
+		codec btcutil.ChainCodec
+		amt   btcutil.Amount
+		str   string
+	}{
+		{btcutil.NewOmegaChainCodec(&chaincfg.MainNetParams), 150500000, "1.505"},
+		{cents, 15050, "150.5"},
+		{cents, -1, "-0.01"},
+	}
+	for _, test := range tests {
+		if got := btcutil.FormatAmountForChain(test.amt, test.codec); got != test.str {
+			t.Errorf("%s: got %q, want %q", test.codec.Name(), got,
+				test.str)
+		}
+		got, err := btcutil.ParseAmountForChain(test.str, test.codec)
+		if err != nil || got != test.amt {
+			t.Errorf("%s: parsed %q as %v (%v), want %v",
+				test.codec.Name(), test.str, got, err, test.amt)
+		}
+	}
+}
+
+// TestSigHashFlavorStringer tests the stringized output for signature hash
+// flavors.
+func TestSigHashFlavorStringer(t *testing.T) {
+	tests := []struct {
+		in   btcutil.SigHashFlavor
+		want string
+	}{
+		{btcutil.SigHashLegacy, "SigHashLegacy"},
+		{btcutil.SigHashWitnessV0, "SigHashWitnessV0"},
+		{btcutil.SigHashForkID, "SigHashForkID"},
+		{0xff, "Unknown SigHashFlavor (255)"},
+	}
+	for _, test := range tests {
+		if got := test.in.String(); got != test.want {
+			t.Errorf("got %q, want %q", got, test.want)
+		}
+	}
+}
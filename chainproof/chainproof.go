@@ -0,0 +1,341 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package chainproof packages a contiguous range of block headers together
+// with selected transactions and the merkle branches proving their inclusion
+// into a single archive that can be verified offline.
+//
+// An auditor that trusts the hash of the block preceding the range (or any
+// header within it) can use a Bundle to check that specific payments were
+// included in the chain without access to a full node.
+package chainproof
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcd/wire/common"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/envelope"
+	"github.com/zeusyf/btcutil/merkle"
+)
+
+const (
+	// bundleVersion is the envelope version written by Serialize.  It
+	// only needs to be bumped for changes older readers can not skip.
+	bundleVersion = 1
+
+	// These are the types of the envelope fields of a serialized bundle.
+	fieldStartHeight = 1
+	fieldHeader      = 2
+	fieldProof       = 3
+
+	// varIntProtoVer is the protocol version to use for serializing
+	// variable length integers.
+	varIntProtoVer uint32 = 0
+
+	// maxBranchLen is the maximum number of hashes allowed in a single
+	// merkle branch.  It is far beyond the depth of any realistic block
+	// and only guards against malicious archives.
+	maxBranchLen = 32
+)
+
+// bundleMagic identifies serialized bundles.
+var bundleMagic = [envelope.MagicSize]byte{'o', 'c', 'p', 'b'}
+
+var (
+	// ErrEmptyRange describes an error where a bundle is requested for
+	// or contains no blocks.
+	ErrEmptyRange = errors.New("block range is empty")
+
+	// ErrBrokenChain describes an error where the headers in a bundle do
+	// not form a contiguous chain.
+	ErrBrokenChain = errors.New("headers do not form a contiguous chain")
+
+	// ErrInvalidProof describes an error where a transaction in a bundle
+	// does not hash to the merkle root of the header it references.
+	ErrInvalidProof = errors.New("merkle proof does not match header")
+
+	// ErrUnsupportedVersion describes an error where a serialized bundle
+	// uses a version this package does not understand.
+	ErrUnsupportedVersion = errors.New("unsupported bundle version")
+)
+
+// TxProof proves the inclusion of a single transaction in one of the blocks
+// of a Bundle.
+type TxProof struct {
+	// HeaderIndex is the index into Bundle.Headers of the block that
+	// includes the transaction.
+	HeaderIndex uint32
+
+	// TxIndex is the position of the transaction within its block.
+	TxIndex uint32
+
+	// Tx is the full transaction being proven.
+	Tx *wire.MsgTx
+
+	// Branch is the list of sibling hashes from the transaction up to,
+	// but not including, the merkle root.
+	Branch []chainhash.Hash
+}
+
+// Bundle is a verifiable archive of a contiguous range of blocks.  Only the
+// headers of the range are carried in full, along with the transactions that
+// were selected when the bundle was created.
+type Bundle struct {
+	// StartHeight is the height of the first header in the bundle.
+	StartHeight int32
+
+	// Headers is the contiguous list of block headers in the range.
+	Headers []wire.BlockHeader
+
+	// Proofs holds the selected transactions and their inclusion proofs.
+	Proofs []TxProof
+}
+
+// NewBundle creates a Bundle from a contiguous range of blocks.  Every
+// transaction for which match returns true is included along with its merkle
+// branch.  The height of the first block is taken from the block itself so
+// callers should set it with SetHeight when it is known.
+func NewBundle(blocks []*btcutil.Block, match func(*btcutil.Tx) bool) (*Bundle, error) {
+	if len(blocks) == 0 {
+		return nil, ErrEmptyRange
+	}
+
+	bundle := &Bundle{
+		StartHeight: blocks[0].Height(),
+		Headers:     make([]wire.BlockHeader, 0, len(blocks)),
+	}
+	for i, block := range blocks {
+		header := block.MsgBlock().Header
+		if i > 0 && header.PrevBlock != *blocks[i-1].Hash() {
+			return nil, ErrBrokenChain
+		}
+		bundle.Headers = append(bundle.Headers, header)
+
+		txns := block.Transactions()
+		leaves := make([]chainhash.Hash, len(txns))
+		for j, tx := range txns {
+			leaves[j] = *tx.Hash()
+		}
+		for j, tx := range txns {
+			if !match(tx) {
+				continue
+			}
+			branch, _, err := merkle.Branch(leaves, j)
+			if err != nil {
+				return nil, err
+			}
+			bundle.Proofs = append(bundle.Proofs, TxProof{
+				HeaderIndex: uint32(i),
+				TxIndex:     uint32(j),
+				Tx:          tx.MsgTx(),
+				Branch:      branch,
+			})
+		}
+	}
+
+	return bundle, nil
+}
+
+// Verify checks that the headers in the bundle link together and that every
+// included transaction is committed to by the merkle root of its block.
+//
+// Verify does not check proof of work nor does it establish that the range
+// belongs to the best chain.  Auditors must compare PrevBlock of the first
+// header, or the hash of any header, against a source they trust.
+func (b *Bundle) Verify() error {
+	if len(b.Headers) == 0 {
+		return ErrEmptyRange
+	}
+
+	for i := 1; i < len(b.Headers); i++ {
+		if b.Headers[i].PrevBlock != b.Headers[i-1].BlockHash() {
+			return ErrBrokenChain
+		}
+	}
+
+	for i := range b.Proofs {
+		proof := &b.Proofs[i]
+		if int(proof.HeaderIndex) >= len(b.Headers) {
+			return fmt.Errorf("proof %d: header index %d out of "+
+				"range: %w", i, proof.HeaderIndex, ErrInvalidProof)
+		}
+		root := merkle.BranchRoot(proof.Tx.TxHash(), proof.TxIndex, proof.Branch)
+		if root != b.Headers[proof.HeaderIndex].MerkleRoot {
+			return fmt.Errorf("proof %d: tx %v: %w", i,
+				proof.Tx.TxHash(), ErrInvalidProof)
+		}
+	}
+
+	return nil
+}
+
+// TipHash returns the hash of the last header in the bundle.
+func (b *Bundle) TipHash() chainhash.Hash {
+	if len(b.Headers) == 0 {
+		return chainhash.Hash{}
+	}
+	return b.Headers[len(b.Headers)-1].BlockHash()
+}
+
+// Serialize writes the bundle to w as an envelope with the following fields,
+// in order:
+//
+//	start height (uint32) || header || ... || proof || ...
+//
+// where each header is a serialized block header and each proof is encoded
+// as:
+//
+//	header index (varint) || tx index (varint) || tx ||
+//	branch length (varint) || branch hashes (32 each)
+func (b *Bundle) Serialize(w io.Writer) error {
+	ew, err := envelope.NewWriter(w, bundleMagic, bundleVersion)
+	if err != nil {
+		return err
+	}
+	err = ew.WriteUint32(fieldStartHeight, uint32(b.StartHeight))
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	for i := range b.Headers {
+		buf.Reset()
+		if err := b.Headers[i].Serialize(&buf); err != nil {
+			return err
+		}
+		if err := ew.WriteField(fieldHeader, buf.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	for i := range b.Proofs {
+		buf.Reset()
+		if err := b.Proofs[i].serialize(&buf); err != nil {
+			return err
+		}
+		if err := ew.WriteField(fieldProof, buf.Bytes()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// serialize writes the proof to w.
+func (proof *TxProof) serialize(w io.Writer) error {
+	err := common.WriteVarInt(w, varIntProtoVer, uint64(proof.HeaderIndex))
+	if err != nil {
+		return err
+	}
+	err = common.WriteVarInt(w, varIntProtoVer, uint64(proof.TxIndex))
+	if err != nil {
+		return err
+	}
+	if err := proof.Tx.Serialize(w); err != nil {
+		return err
+	}
+	err = common.WriteVarInt(w, varIntProtoVer, uint64(len(proof.Branch)))
+	if err != nil {
+		return err
+	}
+	for j := range proof.Branch {
+		if _, err := w.Write(proof.Branch[j][:]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Deserialize reads a bundle previously written by Serialize from r into b.
+// Fields of types added by newer versions of this package are skipped.
+func (b *Bundle) Deserialize(r io.Reader) error {
+	er, err := envelope.NewReader(r, bundleMagic, bundleVersion)
+	if err != nil {
+		if err == envelope.ErrUnsupportedVersion {
+			return ErrUnsupportedVersion
+		}
+		return err
+	}
+
+	*b = Bundle{}
+	for {
+		fieldType, value, err := er.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		switch fieldType {
+		case fieldStartHeight:
+			height, err := envelope.Uint32(value)
+			if err != nil {
+				return err
+			}
+			b.StartHeight = int32(height)
+
+		case fieldHeader:
+			var header wire.BlockHeader
+			err := header.Deserialize(bytes.NewReader(value))
+			if err != nil {
+				return err
+			}
+			b.Headers = append(b.Headers, header)
+
+		case fieldProof:
+			var proof TxProof
+			if err := proof.deserialize(bytes.NewReader(value)); err != nil {
+				return err
+			}
+			b.Proofs = append(b.Proofs, proof)
+		}
+	}
+
+	if len(b.Headers) == 0 {
+		return ErrEmptyRange
+	}
+	return nil
+}
+
+// deserialize reads a proof previously written by serialize from r.
+func (proof *TxProof) deserialize(r io.Reader) error {
+	headerIndex, err := common.ReadVarInt(r, varIntProtoVer)
+	if err != nil {
+		return err
+	}
+	txIndex, err := common.ReadVarInt(r, varIntProtoVer)
+	if err != nil {
+		return err
+	}
+	proof.HeaderIndex = uint32(headerIndex)
+	proof.TxIndex = uint32(txIndex)
+
+	proof.Tx = new(wire.MsgTx)
+	if err := proof.Tx.Deserialize(r); err != nil {
+		return err
+	}
+
+	branchLen, err := common.ReadVarInt(r, varIntProtoVer)
+	if err != nil {
+		return err
+	}
+	if branchLen > maxBranchLen {
+		return fmt.Errorf("merkle branch of %d hashes exceeds "+
+			"max of %d", branchLen, maxBranchLen)
+	}
+	proof.Branch = make([]chainhash.Hash, branchLen)
+	for j := range proof.Branch {
+		if _, err := io.ReadFull(r, proof.Branch[j][:]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package chainproof
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/merkle"
+)
+
+// testTx returns a distinct transaction identified by the passed index.
+func testTx(id uint32) *wire.MsgTx {
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: wire.OutPoint{Index: id},
+		Sequence:         wire.MaxTxInSequenceNum,
+	})
+	return tx
+}
+
+// testChain builds a chain of numBlocks blocks, each with txPerBlock
+// transactions and a correctly computed merkle root.
+func testChain(numBlocks, txPerBlock int) []*btcutil.Block {
+	var prev chainhash.Hash
+	blocks := make([]*btcutil.Block, 0, numBlocks)
+	for i := 0; i < numBlocks; i++ {
+		msgBlock := &wire.MsgBlock{}
+		leaves := make([]chainhash.Hash, 0, txPerBlock)
+		for j := 0; j < txPerBlock; j++ {
+			tx := testTx(uint32(i*1000 + j))
+			msgBlock.Transactions = append(msgBlock.Transactions, tx)
+			leaves = append(leaves, tx.TxHash())
+		}
+		msgBlock.Header = wire.BlockHeader{
+			PrevBlock:  prev,
+			MerkleRoot: merkle.Root(leaves),
+			Timestamp:  time.Unix(int64(1600000000+i*600), 0),
+		}
+		block := btcutil.NewBlock(msgBlock)
+		block.SetHeight(int32(100 + i))
+		blocks = append(blocks, block)
+		prev = *block.Hash()
+	}
+	return blocks
+}
+
+// TestBundleRoundTrip ensures bundles built from a block range verify, survive
+// serialization, and reject tampering.
+func TestBundleRoundTrip(t *testing.T) {
+	blocks := testChain(4, 7)
+
+	// Select every third transaction across the range.
+	var n int
+	bundle, err := NewBundle(blocks, func(*btcutil.Tx) bool {
+		n++
+		return n%3 == 0
+	})
+	if err != nil {
+		t.Fatalf("NewBundle: unexpected error: %v", err)
+	}
+	if len(bundle.Proofs) != 28/3 {
+		t.Fatalf("NewBundle: got %d proofs, want %d",
+			len(bundle.Proofs), 28/3)
+	}
+	if bundle.StartHeight != 100 {
+		t.Fatalf("NewBundle: got start height %d, want 100",
+			bundle.StartHeight)
+	}
+	if err := bundle.Verify(); err != nil {
+		t.Fatalf("Verify: unexpected error: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := bundle.Serialize(&buf); err != nil {
+		t.Fatalf("Serialize: unexpected error: %v", err)
+	}
+	var decoded Bundle
+	if err := decoded.Deserialize(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatalf("Deserialize: unexpected error: %v", err)
+	}
+	if err := decoded.Verify(); err != nil {
+		t.Fatalf("Verify decoded: unexpected error: %v", err)
+	}
+	if decoded.TipHash() != *blocks[len(blocks)-1].Hash() {
+		t.Fatalf("TipHash: got %v, want %v", decoded.TipHash(),
+			blocks[len(blocks)-1].Hash())
+	}
+
+	// Fields added by newer writers are skipped, while newer versions
+	// are rejected.
+	extended := append(buf.Bytes(), 0x63, 0x03, 'n', 'e', 'w')
+	var skipped Bundle
+	if err := skipped.Deserialize(bytes.NewReader(extended)); err != nil {
+		t.Fatalf("Deserialize extended: unexpected error: %v", err)
+	}
+	if len(skipped.Headers) != len(decoded.Headers) ||
+		len(skipped.Proofs) != len(decoded.Proofs) {
+		t.Fatalf("Deserialize extended: got %d headers and %d proofs, "+
+			"want %d and %d", len(skipped.Headers), len(skipped.Proofs),
+			len(decoded.Headers), len(decoded.Proofs))
+	}
+	newer := append([]byte(nil), buf.Bytes()...)
+	newer[4] = bundleVersion + 1
+	err = skipped.Deserialize(bytes.NewReader(newer))
+	if err != ErrUnsupportedVersion {
+		t.Fatalf("Deserialize newer: got %v, want %v", err,
+			ErrUnsupportedVersion)
+	}
+
+	// Swapping the proven transaction must invalidate the proof.
+	decoded.Proofs[0].Tx = testTx(999999)
+	if err := decoded.Verify(); !errors.Is(err, ErrInvalidProof) {
+		t.Fatalf("Verify tampered tx: got %v, want %v", err,
+			ErrInvalidProof)
+	}
+
+	// Breaking the header linkage must be detected.
+	bundle.Headers[2].PrevBlock = chainhash.Hash{}
+	if err := bundle.Verify(); err != ErrBrokenChain {
+		t.Fatalf("Verify broken chain: got %v, want %v", err,
+			ErrBrokenChain)
+	}
+}
+
+// TestNewBundleErrors ensures invalid block ranges are rejected.
+func TestNewBundleErrors(t *testing.T) {
+	matchAll := func(*btcutil.Tx) bool { return true }
+	if _, err := NewBundle(nil, matchAll); err != ErrEmptyRange {
+		t.Fatalf("NewBundle empty: got %v, want %v", err, ErrEmptyRange)
+	}
+
+	blocks := testChain(3, 2)
+	gapped := []*btcutil.Block{blocks[0], blocks[2]}
+	if _, err := NewBundle(gapped, matchAll); err != ErrBrokenChain {
+		t.Fatalf("NewBundle gapped: got %v, want %v", err,
+			ErrBrokenChain)
+	}
+}
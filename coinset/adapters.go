@@ -0,0 +1,203 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package coinset
+
+import (
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/omega/token"
+)
+
+var (
+	// ErrNotBaseToken describes an error where an output offered as a
+	// Coin does not carry a numeric amount of the base token, which is
+	// all coin selection handles.
+	ErrNotBaseToken = errors.New("output does not carry the base token")
+
+	// ErrNegativeValue describes an error where an output offered as a
+	// Coin has a negative value.
+	ErrNegativeValue = errors.New("output value is negative")
+)
+
+// UTXOCoin is a Coin holding the details of an unspent output directly, as
+// created by the adapters from the representations of outputs used by RPC
+// servers, databases and the wire protocol.
+type UTXOCoin struct {
+	OutPoint      wire.OutPoint
+	Amount        btcutil.Amount
+	Script        []byte
+	Confirmations int64
+}
+
+// Ensure that UTXOCoin is a Coin
+var _ Coin = &UTXOCoin{}
+
+// Hash returns the hash value of the transaction on which the Coin is an output
+func (c *UTXOCoin) Hash() *chainhash.Hash {
+	return &c.OutPoint.Hash
+}
+
+// Index returns the index of the output on the transaction which the Coin represents
+func (c *UTXOCoin) Index() uint32 {
+	return c.OutPoint.Index
+}
+
+// Value returns the value of the Coin
+func (c *UTXOCoin) Value() btcutil.Amount {
+	return c.Amount
+}
+
+// PkScript returns the outpoint script of the Coin.
+func (c *UTXOCoin) PkScript() []byte {
+	return c.Script
+}
+
+// NumConfs returns the number of confirmations that the transaction the Coin references
+// has had.
+func (c *UTXOCoin) NumConfs() int64 {
+	return c.Confirmations
+}
+
+// ValueAge returns the product of the value and the number of confirmations.  This is
+// used as an input to calculate the priority of the transaction.
+func (c *UTXOCoin) ValueAge() int64 {
+	return c.Confirmations * int64(c.Amount)
+}
+
+// NewTxOutCoin returns the Coin of txOut, the output at outPoint, which has the
+// passed number of confirmations.  ErrNotBaseToken is returned for outputs of
+// other tokens.
+func NewTxOutCoin(outPoint wire.OutPoint, txOut *wire.TxOut, numConfs int64) (*UTXOCoin, error) {
+	value, ok := txOut.Token.Value.(*token.NumeralVal)
+	if txOut.Token.TokenType != 0 || !ok {
+		return nil, ErrNotBaseToken
+	}
+	if value.Val < 0 {
+		return nil, ErrNegativeValue
+	}
+	return &UTXOCoin{
+		OutPoint:      outPoint,
+		Amount:        btcutil.Amount(value.Val),
+		Script:        txOut.PkScript,
+		Confirmations: numConfs,
+	}, nil
+}
+
+// RPCUnspent holds the fields of an entry of the result of the listunspent
+// RPC which coin selection needs.  Its JSON field names match those of the
+// RPC, so results can be decoded into a slice of RPCUnspent directly.
+type RPCUnspent struct {
+	TxID          string  `json:"txid"`
+	Vout          uint32  `json:"vout"`
+	ScriptPubKey  string  `json:"scriptPubKey"`
+	Amount        float64 `json:"amount"`
+	Confirmations int64   `json:"confirmations"`
+}
+
+// NewRPCCoin returns the Coin of an entry of the result of the listunspent
+// RPC, whose amount is in OMC.
+func NewRPCCoin(u *RPCUnspent) (*UTXOCoin, error) {
+	hash, err := chainhash.NewHashFromStr(u.TxID)
+	if err != nil {
+		return nil, err
+	}
+	script, err := hex.DecodeString(u.ScriptPubKey)
+	if err != nil {
+		return nil, err
+	}
+	amount, err := btcutil.NewAmount(u.Amount, 0)
+	if err != nil {
+		return nil, err
+	}
+	if amount < 0 {
+		return nil, ErrNegativeValue
+	}
+	return &UTXOCoin{
+		OutPoint:      wire.OutPoint{Hash: *hash, Index: u.Vout},
+		Amount:        amount,
+		Script:        script,
+		Confirmations: u.Confirmations,
+	}, nil
+}
+
+// NewRPCCoins returns the Coins of the entries of a result of the listunspent
+// RPC.
+func NewRPCCoins(unspent []RPCUnspent) ([]Coin, error) {
+	coins := make([]Coin, len(unspent))
+	for i := range unspent {
+		coin, err := NewRPCCoin(&unspent[i])
+		if err != nil {
+			return nil, fmt.Errorf("unspent output %d: %v", i, err)
+		}
+		coins[i] = coin
+	}
+	return coins, nil
+}
+
+// RowScanner is the subset of the methods of *sql.Rows used by ScanCoins, so
+// that other database drivers can be adapted as well.
+type RowScanner interface {
+	Next() bool
+	Scan(dest ...interface{}) error
+	Err() error
+}
+
+// Ensure that *sql.Rows is a RowScanner
+var _ RowScanner = (*sql.Rows)(nil)
+
+// ScanCoins returns the Coins of the unspent outputs held by rows, such as
+// the result of a query for the outputs of a wallet, with their
+// confirmations counted up to the block at tipHeight.  Each row must have the
+// columns, in order:
+//
+//   - the transaction hash, as 32 bytes in internal byte order
+//   - the output index
+//   - the value in Hao, scanned with btcutil.Amount.Scan
+//   - the public key script
+//   - the height of the block including the transaction, or NULL while
+//     unconfirmed
+//
+// ScanCoins does not close rows.
+func ScanCoins(rows RowScanner, tipHeight int32) ([]Coin, error) {
+	var coins []Coin
+	for rows.Next() {
+		var hash, script []byte
+		var index uint32
+		var amount btcutil.Amount
+		var height sql.NullInt64
+		err := rows.Scan(&hash, &index, &amount, &script, &height)
+		if err != nil {
+			return nil, err
+		}
+		if len(hash) != chainhash.HashSize {
+			return nil, fmt.Errorf("row %d: transaction hash of %d "+
+				"bytes", len(coins), len(hash))
+		}
+		if amount < 0 {
+			return nil, ErrNegativeValue
+		}
+
+		coin := &UTXOCoin{
+			OutPoint: wire.OutPoint{Index: index},
+			Amount:   amount,
+			Script:   script,
+		}
+		copy(coin.OutPoint.Hash[:], hash)
+		if height.Valid && height.Int64 <= int64(tipHeight) {
+			coin.Confirmations = int64(tipHeight) - height.Int64 + 1
+		}
+		coins = append(coins, coin)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return coins, nil
+}
@@ -0,0 +1,147 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package coinset_test
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/coinset"
+	"github.com/zeusyf/omega/token"
+)
+
+// testRows is a RowScanner over rows of driver values, which it assigns the
+// way database/sql does for the column types ScanCoins uses.
+type testRows struct {
+	rows [][]interface{}
+	cur  []interface{}
+}
+
+func (r *testRows) Next() bool {
+	if len(r.rows) == 0 {
+		return false
+	}
+	r.cur, r.rows = r.rows[0], r.rows[1:]
+	return true
+}
+
+func (r *testRows) Scan(dest ...interface{}) error {
+	for i, d := range dest {
+		switch d := d.(type) {
+		case *[]byte:
+			*d, _ = r.cur[i].([]byte)
+		case *uint32:
+			*d = uint32(r.cur[i].(int64))
+		case sql.Scanner:
+			if err := d.Scan(r.cur[i]); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *testRows) Err() error { return nil }
+
+// TestTxOutCoin ensures outputs of the base token are adapted and outputs of
+// other tokens rejected.
+func TestTxOutCoin(t *testing.T) {
+	outPoint := wire.OutPoint{Hash: chainhash.Hash{0x01}, Index: 3}
+	txOut := &wire.TxOut{
+		Token: token.Token{
+			TokenType: 0,
+			Value:     &token.NumeralVal{Val: 5000},
+		},
+		PkScript: []byte{0x51},
+	}
+	coin, err := coinset.NewTxOutCoin(outPoint, txOut, 4)
+	if err != nil {
+		t.Fatalf("NewTxOutCoin: unexpected error: %v", err)
+	}
+	if *coin.Hash() != outPoint.Hash || coin.Index() != 3 ||
+		coin.Value() != 5000 || !bytes.Equal(coin.PkScript(), []byte{0x51}) ||
+		coin.NumConfs() != 4 || coin.ValueAge() != 20000 {
+		t.Errorf("NewTxOutCoin: got %+v", coin)
+	}
+
+	txOut.Token.TokenType = 1
+	if _, err := coinset.NewTxOutCoin(outPoint, txOut, 4); err != coinset.ErrNotBaseToken {
+		t.Errorf("NewTxOutCoin other token: got error %v, want %v", err,
+			coinset.ErrNotBaseToken)
+	}
+}
+
+// TestRPCCoins ensures listunspent results decode into Coins.
+func TestRPCCoins(t *testing.T) {
+	result := `[{"txid":"0000000000000000000000000000000000000000000000000000000000000001",` +
+		`"vout":1,"address":"ignored","scriptPubKey":"51","amount":1.5,"confirmations":6}]`
+	var unspent []coinset.RPCUnspent
+	if err := json.Unmarshal([]byte(result), &unspent); err != nil {
+		t.Fatal(err)
+	}
+	coins, err := coinset.NewRPCCoins(unspent)
+	if err != nil {
+		t.Fatalf("NewRPCCoins: unexpected error: %v", err)
+	}
+	if len(coins) != 1 {
+		t.Fatalf("NewRPCCoins: got %d coins, want 1", len(coins))
+	}
+	coin := coins[0]
+	if coin.Hash().String() != unspent[0].TxID || coin.Index() != 1 ||
+		coin.Value() != 150000000 ||
+		!bytes.Equal(coin.PkScript(), []byte{0x51}) || coin.NumConfs() != 6 {
+		t.Errorf("NewRPCCoins: got %+v", coin)
+	}
+
+	unspent[0].ScriptPubKey = "zz"
+	if _, err := coinset.NewRPCCoins(unspent); err == nil {
+		t.Error("NewRPCCoins bad script: unexpected success")
+	}
+}
+
+// TestScanCoins ensures database rows are adapted with their confirmations
+// counted from the tip.
+func TestScanCoins(t *testing.T) {
+	hash := bytes.Repeat([]byte{0x02}, chainhash.HashSize)
+	rows := &testRows{rows: [][]interface{}{
+		{hash, int64(0), int64(1000), []byte{0x51}, int64(100)},
+		{hash, int64(1), "2000", []byte{0x52}, nil},
+	}}
+	coins, err := coinset.ScanCoins(rows, 109)
+	if err != nil {
+		t.Fatalf("ScanCoins: unexpected error: %v", err)
+	}
+	want := []struct {
+		index    uint32
+		value    btcutil.Amount
+		numConfs int64
+	}{
+		{0, 1000, 10},
+		{1, 2000, 0},
+	}
+	if len(coins) != len(want) {
+		t.Fatalf("ScanCoins: got %d coins, want %d", len(coins), len(want))
+	}
+	for i, coin := range coins {
+		if !bytes.Equal(coin.Hash()[:], hash) ||
+			coin.Index() != want[i].index ||
+			coin.Value() != want[i].value ||
+			coin.NumConfs() != want[i].numConfs {
+			t.Errorf("ScanCoins: coin %d: got %+v", i, coin)
+		}
+	}
+
+	rows = &testRows{rows: [][]interface{}{
+		{hash[1:], int64(0), int64(1000), []byte{0x51}, nil},
+	}}
+	if _, err := coinset.ScanCoins(rows, 109); err == nil {
+		t.Error("ScanCoins short hash: unexpected success")
+	}
+}
@@ -0,0 +1,254 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package coinset
+
+import (
+	"sort"
+
+	"github.com/zeusyf/btcutil"
+)
+
+const (
+	// DefaultLongTermFeeRate is the fee rate assumed for spending coins in
+	// the future when FeeParams leaves it unset, 10 Hao/vB.  Selecting
+	// more inputs than needed is only worthwhile while fee rates are
+	// below it.
+	DefaultLongTermFeeRate = btcutil.FeeRate(10000)
+
+	// DefaultInputVSize is the virtual size assumed for inputs when
+	// FeeParams has no InputVSize function: a pay-to-pubkey-hash input
+	// with a worst case signature.
+	DefaultInputVSize = 36 + 1 + 1 + 72 + 1 + 33 + 4
+
+	// DefaultChangeOutputVSize is the virtual size assumed for a change
+	// output when FeeParams leaves it unset: a pay-to-pubkey-hash output.
+	DefaultChangeOutputVSize = 8 + 1 + 25
+
+	// DefaultBnBMaxTries is the number of steps the branch and bound
+	// search takes before settling for the best selection found, as in
+	// Bitcoin Core.
+	DefaultBnBMaxTries = 100000
+)
+
+// FeeParams describes the fees of spending coins, which the fee aware
+// selectors use to pick the cheapest selection.
+type FeeParams struct {
+	// FeeRate is the fee rate of the transaction being funded.
+	FeeRate btcutil.FeeRate
+
+	// LongTermFeeRate is the fee rate expected for spending coins in the
+	// future.  DefaultLongTermFeeRate is used when it is zero.
+	LongTermFeeRate btcutil.FeeRate
+
+	// InputVSize returns the virtual size of the input spending a coin.
+	// DefaultInputVSize is used for all coins when it is nil.
+	InputVSize func(c Coin) int64
+
+	// ChangeOutputVSize is the virtual size of a change output.
+	// DefaultChangeOutputVSize is used when it is zero.
+	ChangeOutputVSize int64
+
+	// ChangeSpendVSize is the virtual size of the input later spending a
+	// change output.  DefaultInputVSize is used when it is zero.
+	ChangeSpendVSize int64
+}
+
+// inputVSize returns the virtual size of the input spending c.
+func (p *FeeParams) inputVSize(c Coin) int64 {
+	if p.InputVSize == nil {
+		return DefaultInputVSize
+	}
+	return p.InputVSize(c)
+}
+
+// longTermFeeRate returns the long term fee rate, applying its default.
+func (p *FeeParams) longTermFeeRate() btcutil.FeeRate {
+	if p.LongTermFeeRate == 0 {
+		return DefaultLongTermFeeRate
+	}
+	return p.LongTermFeeRate
+}
+
+// InputFee returns the fee for spending c at the fee rate.
+func (p *FeeParams) InputFee(c Coin) btcutil.Amount {
+	return p.FeeRate.FeeForVSize(p.inputVSize(c))
+}
+
+// EffectiveValue returns the value c contributes to a transaction after
+// paying for its own input at the fee rate.
+func (p *FeeParams) EffectiveValue(c Coin) btcutil.Amount {
+	return c.Value() - p.InputFee(c)
+}
+
+// ChangeFee returns the fee for adding a change output at the fee rate.
+func (p *FeeParams) ChangeFee() btcutil.Amount {
+	vsize := p.ChangeOutputVSize
+	if vsize == 0 {
+		vsize = DefaultChangeOutputVSize
+	}
+	return p.FeeRate.FeeForVSize(vsize)
+}
+
+// CostOfChange returns the cost of creating a change output now and
+// spending it later at the long term fee rate.  A selection without change
+// may exceed its target by up to this much and still be no worse than one
+// with change.
+func (p *FeeParams) CostOfChange() btcutil.Amount {
+	vsize := p.ChangeSpendVSize
+	if vsize == 0 {
+		vsize = DefaultInputVSize
+	}
+	return p.ChangeFee() + p.longTermFeeRate().FeeForVSize(vsize)
+}
+
+// Waste returns the waste metric of spending coins to fund a transaction
+// whose outputs and fixed parts need target of effective value: the fees paid
+// for the inputs beyond what spending them at the long term fee rate would
+// cost, plus the cost of change when hasChange is set, or otherwise the
+// excess given up to fees.  Lower is better, and the waste is negative when
+// fees are below the long term fee rate.
+func (p *FeeParams) Waste(coins []Coin, target btcutil.Amount, hasChange bool) btcutil.Amount {
+	longTerm := p.longTermFeeRate()
+	var waste, effective btcutil.Amount
+	for _, c := range coins {
+		vsize := p.inputVSize(c)
+		fee := p.FeeRate.FeeForVSize(vsize)
+		waste += fee - longTerm.FeeForVSize(vsize)
+		effective += c.Value() - fee
+	}
+	if hasChange {
+		return waste + p.CostOfChange()
+	}
+	return waste + effective - target
+}
+
+// bnbCoin is a coin with the amounts the branch and bound search needs.
+type bnbCoin struct {
+	coin      Coin
+	effective btcutil.Amount
+	fee       btcutil.Amount
+	waste     btcutil.Amount
+}
+
+// BnBCoinSelector is a CoinSelector searching for a selection of coins which
+// needs no change output, using the branch and bound algorithm of Bitcoin
+// Core.  Of the selections whose effective value lies between the target
+// and the target plus the cost of change, it picks the one of least waste.
+//
+// The target passed to CoinSelect is the effective value the selection
+// must provide, that is the value of the outputs plus the fee for the parts
+// of the transaction other than the inputs.
+type BnBCoinSelector struct {
+	FeeParams
+
+	// MaxTries bounds the steps of the search, after which the best
+	// selection found so far is returned.  DefaultBnBMaxTries is used
+	// when it is zero.
+	MaxTries int
+}
+
+// CoinSelect will attempt to select coins using the algorithm described
+// in the BnBCoinSelector struct.
+func (s BnBCoinSelector) CoinSelect(targetValue btcutil.Amount, coins []Coin) (Coins, error) {
+	longTerm := s.longTermFeeRate()
+	pool := make([]bnbCoin, 0, len(coins))
+	var available btcutil.Amount
+	for _, c := range coins {
+		vsize := s.inputVSize(c)
+		fee := s.FeeRate.FeeForVSize(vsize)
+		effective := c.Value() - fee
+		if effective <= 0 {
+			continue
+		}
+		pool = append(pool, bnbCoin{
+			coin:      c,
+			effective: effective,
+			fee:       fee,
+			waste:     fee - longTerm.FeeForVSize(vsize),
+		})
+		available += effective
+	}
+	if available < targetValue {
+		return nil, ErrCoinsNoSelectionAvailable
+	}
+
+	// Explore the largest coins first so the search reaches the target
+	// quickly.
+	sort.SliceStable(pool, func(i, j int) bool {
+		return pool[i].effective > pool[j].effective
+	})
+
+	maxTries := s.MaxTries
+	if maxTries == 0 {
+		maxTries = DefaultBnBMaxTries
+	}
+	costOfChange := s.CostOfChange()
+	feeRateHigh := s.FeeRate > longTerm
+
+	var value, waste btcutil.Amount
+	var selection, best []int
+	bestWaste := btcutil.Amount(btcutil.MaxHao)
+	index := 0
+	for try := 0; try < maxTries; try, index = try+1, index+1 {
+		backtrack := false
+		switch {
+		case value+available < targetValue ||
+			value > targetValue+costOfChange ||
+			(waste > bestWaste && feeRateHigh):
+			// The branch can not reach the target, overshoots
+			// it, or is already worse than the best selection.
+			backtrack = true
+
+		case value >= targetValue:
+			// The selection is a solution; the excess counts as
+			// waste since it goes to fees.
+			if waste+value-targetValue <= bestWaste {
+				best = append(best[:0], selection...)
+				bestWaste = waste + value - targetValue
+			}
+			backtrack = true
+		}
+
+		if backtrack {
+			if len(selection) == 0 {
+				break
+			}
+			last := selection[len(selection)-1]
+
+			// Return the coins skipped after the last selected
+			// one, then explore the branch omitting it.
+			for index--; index > last; index-- {
+				available += pool[index].effective
+			}
+			value -= pool[index].effective
+			waste -= pool[index].waste
+			selection = selection[:len(selection)-1]
+			continue
+		}
+
+		c := &pool[index]
+		available -= c.effective
+
+		// Skip including a coin equivalent to the previous one when
+		// that was omitted, since the branch was already explored.
+		if len(selection) == 0 || selection[len(selection)-1] == index-1 ||
+			c.effective != pool[index-1].effective ||
+			c.fee != pool[index-1].fee {
+
+			selection = append(selection, index)
+			value += c.effective
+			waste += c.waste
+		}
+	}
+
+	if best == nil {
+		return nil, ErrCoinsNoSelectionAvailable
+	}
+	cs := NewCoinSet(nil)
+	for _, i := range best {
+		cs.PushCoin(pool[i].coin)
+	}
+	return cs, nil
+}
@@ -0,0 +1,121 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package coinset_test
+
+import (
+	"testing"
+
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/coinset"
+)
+
+// feeCoins returns coins whose effective values are the passed multiples of
+// 100000 Hao after paying inputFee.
+func feeCoins(inputFee btcutil.Amount, values ...btcutil.Amount) []coinset.Coin {
+	coins := make([]coinset.Coin, len(values))
+	for i, v := range values {
+		coins[i] = NewCoin(int64(i), v*100000+inputFee, 1)
+	}
+	return coins
+}
+
+// With the default sizes, inputs are 148 vB and change outputs 34 vB.
+var (
+	// lowFees pays 74 Hao per input, 74 below the long term fee.
+	lowFees = coinset.FeeParams{
+		FeeRate:         btcutil.NewFeeRateFromHaoPerKVByte(500),
+		LongTermFeeRate: btcutil.NewFeeRateFromHaoPerVByte(1),
+	}
+
+	// highFees pays 296 Hao per input, 148 above the long term fee, and
+	// change costs 68 + 148 Hao.
+	highFees = coinset.FeeParams{
+		FeeRate:         btcutil.NewFeeRateFromHaoPerVByte(2),
+		LongTermFeeRate: btcutil.NewFeeRateFromHaoPerVByte(1),
+	}
+)
+
+// TestFeeParams ensures the fees and waste of selections are computed from
+// the fee rates and sizes.
+func TestFeeParams(t *testing.T) {
+	p := highFees
+	coins := feeCoins(296, 1, 2)
+	if fee := p.InputFee(coins[0]); fee != 296 {
+		t.Errorf("InputFee: got %d, want 296", fee)
+	}
+	if value := p.EffectiveValue(coins[1]); value != 200000 {
+		t.Errorf("EffectiveValue: got %d, want 200000", value)
+	}
+	if cost := p.CostOfChange(); cost != 216 {
+		t.Errorf("CostOfChange: got %d, want 216", cost)
+	}
+
+	tests := []struct {
+		target    btcutil.Amount
+		hasChange bool
+		want      btcutil.Amount
+	}{
+		{300000, false, 296},
+		{299900, false, 396},
+		{250000, true, 512},
+	}
+	for _, test := range tests {
+		waste := p.Waste(coins, test.target, test.hasChange)
+		if waste != test.want {
+			t.Errorf("Waste(%d, %v): got %d, want %d", test.target,
+				test.hasChange, waste, test.want)
+		}
+	}
+
+	// The long term fee rate defaults to 10 Hao/vB.
+	p.LongTermFeeRate = 0
+	if waste := p.Waste(coins[:1], 100000, false); waste != 296-1480 {
+		t.Errorf("Waste: got %d with the default long term fee rate, "+
+			"want %d", waste, 296-1480)
+	}
+
+	p.InputVSize = func(coinset.Coin) int64 { return 50 }
+	if fee := p.InputFee(coins[0]); fee != 100 {
+		t.Errorf("InputFee: got %d with custom input size, want 100", fee)
+	}
+}
+
+// TestBnBSelector ensures the branch and bound selector finds the changeless
+// selection of least waste.
+func TestBnBSelector(t *testing.T) {
+	low := feeCoins(74, 1, 2, 3, 4)
+	high := feeCoins(296, 1, 2, 3, 4)
+	dust := append(feeCoins(296, 1, 2), NewCoin(9, 200, 1))
+
+	lowSelector := coinset.BnBCoinSelector{FeeParams: lowFees}
+	highSelector := coinset.BnBCoinSelector{FeeParams: highFees}
+	tests := []coinSelectTest{
+		// Exact matches.
+		{highSelector, high, 100000, []coinset.Coin{high[0]}, nil},
+		{highSelector, high, 700000, []coinset.Coin{high[3], high[2]}, nil},
+		{highSelector, high, 1000000, []coinset.Coin{high[3], high[2], high[1], high[0]}, nil},
+
+		// The excess may not exceed the cost of change.
+		{highSelector, high, 100000 - 216, []coinset.Coin{high[0]}, nil},
+		{highSelector, high, 100000 - 217, nil, coinset.ErrCoinsNoSelectionAvailable},
+		{highSelector, high, 1000001, nil, coinset.ErrCoinsNoSelectionAvailable},
+
+		// Fewer inputs are cheaper while fees are high, more are while
+		// fees are low.
+		{highSelector, high, 300000, []coinset.Coin{high[2]}, nil},
+		{lowSelector, low, 300000, []coinset.Coin{low[1], low[0]}, nil},
+
+		// Coins worth less than their input fee are never selected.
+		{highSelector, dust, 300000, []coinset.Coin{dust[1], dust[0]}, nil},
+	}
+	testCoinSelector(tests, t)
+
+	// The search gives up after its maximum number of tries.
+	limited := coinset.BnBCoinSelector{FeeParams: highFees, MaxTries: 2}
+	if _, err := limited.CoinSelect(300000, high); err != coinset.ErrCoinsNoSelectionAvailable {
+		t.Errorf("CoinSelect: got error %v with 2 tries, want %v", err,
+			coinset.ErrCoinsNoSelectionAvailable)
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package coinset
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/zeusyf/btcd/wire/common"
+	"github.com/zeusyf/btcutil"
+)
+
+const (
+	// DefaultMaxConsolidationVSize is the virtual size consolidation
+	// transactions are limited to when Consolidator leaves it unset, the
+	// largest standard transaction size.
+	DefaultMaxConsolidationVSize = 100000
+
+	// txOverheadVSize is the size of the version and lock time fields of
+	// a transaction.
+	txOverheadVSize = 4 + 4
+)
+
+// ErrConsolidationNotWorthwhile describes an error where consolidation is
+// requested while the fee rate exceeds the long term fee rate, so spending
+// the coins later would be cheaper than consolidating them now.
+var ErrConsolidationNotWorthwhile = errors.New("fee rate exceeds the long " +
+	"term fee rate")
+
+// ConsolidationPlan is a transaction sweeping coins into fewer outputs.
+type ConsolidationPlan struct {
+	// Inputs are the coins spent by the transaction.
+	Inputs []Coin
+
+	// Outputs are the values of the outputs of the transaction, which
+	// add up to the value of the inputs less the fee.
+	Outputs []btcutil.Amount
+
+	// VSize is the estimated virtual size of the transaction.
+	VSize int64
+
+	// Fee is the fee paid by the transaction at the fee rate.
+	Fee btcutil.Amount
+}
+
+// Consolidator plans transactions sweeping many small coins into few
+// outputs, to be broadcast while fees are low so the coins need not be spent
+// individually when fees are high.
+//
+// The FeeRate of FeeParams is the current fee rate, paid by the planned
+// transactions, and LongTermFeeRate is the target fee rate at or below which
+// consolidating is worthwhile.  Consolidation outputs are assumed to be of
+// ChangeOutputVSize.
+type Consolidator struct {
+	FeeParams
+
+	// MaxTxVSize is the virtual size each transaction is limited to.
+	// DefaultMaxConsolidationVSize is used when it is zero.
+	MaxTxVSize int64
+
+	// OutputsPerTx is the number of outputs each transaction sweeps its
+	// inputs into, one when it is zero.  Several outputs keep funds
+	// available for parallel spending.
+	OutputsPerTx int
+
+	// MinOutputValue is the smallest value of an output, such as the dust
+	// limit.  Transactions whose outputs would be worth less are not
+	// planned.
+	MinOutputValue btcutil.Amount
+}
+
+// txVSize returns the virtual size of a transaction with numInputs inputs
+// of total inputsVSize, and numOutputs consolidation outputs.
+func (c *Consolidator) txVSize(numInputs int, inputsVSize int64, numOutputs int) int64 {
+	outputVSize := c.ChangeOutputVSize
+	if outputVSize == 0 {
+		outputVSize = DefaultChangeOutputVSize
+	}
+	return txOverheadVSize + int64(common.VarIntSerializeSize(uint64(numInputs))) +
+		int64(common.VarIntSerializeSize(uint64(numOutputs))) +
+		inputsVSize + int64(numOutputs)*outputVSize
+}
+
+// Plan returns the consolidation transactions for coins.  The smallest coins
+// are swept first, each transaction taking as many as fit in MaxTxVSize, and
+// at least one more input than it has outputs so it reduces the number of
+// coins.  Coins worth less than the fee of spending them are left alone.
+//
+// ErrConsolidationNotWorthwhile is returned when the fee rate exceeds the
+// long term fee rate, and ErrCoinsNoSelectionAvailable when no transaction
+// could be planned.
+func (c Consolidator) Plan(coins []Coin) ([]*ConsolidationPlan, error) {
+	if c.FeeRate > c.longTermFeeRate() {
+		return nil, ErrConsolidationNotWorthwhile
+	}
+	maxVSize := c.MaxTxVSize
+	if maxVSize == 0 {
+		maxVSize = DefaultMaxConsolidationVSize
+	}
+	numOutputs := c.OutputsPerTx
+	if numOutputs <= 0 {
+		numOutputs = 1
+	}
+
+	candidates := make([]Coin, 0, len(coins))
+	for _, coin := range coins {
+		if c.EffectiveValue(coin) > 0 {
+			candidates = append(candidates, coin)
+		}
+	}
+	sort.Stable(byAmount(candidates))
+
+	var plans []*ConsolidationPlan
+	for len(candidates) > numOutputs {
+		var inputsVSize int64
+		n := 0
+		for ; n < len(candidates); n++ {
+			vsize := c.inputVSize(candidates[n])
+			if c.txVSize(n+1, inputsVSize+vsize, numOutputs) > maxVSize {
+				break
+			}
+			inputsVSize += vsize
+		}
+		if n <= numOutputs {
+			break
+		}
+
+		if plan := c.plan(candidates[:n], inputsVSize, numOutputs); plan != nil {
+			plans = append(plans, plan)
+		}
+		candidates = candidates[n:]
+	}
+
+	if len(plans) == 0 {
+		return nil, ErrCoinsNoSelectionAvailable
+	}
+	return plans, nil
+}
+
+// plan returns the transaction sweeping inputs into numOutputs outputs of
+// equal value, or nil when the outputs would be worth less than
+// MinOutputValue.
+func (c *Consolidator) plan(inputs []Coin, inputsVSize int64, numOutputs int) *ConsolidationPlan {
+	vsize := c.txVSize(len(inputs), inputsVSize, numOutputs)
+	fee := c.FeeRate.FeeForVSize(vsize)
+	total := -fee
+	for _, coin := range inputs {
+		total += coin.Value()
+	}
+
+	share := total / btcutil.Amount(numOutputs)
+	if share <= 0 || share < c.MinOutputValue {
+		return nil
+	}
+	outputs := make([]btcutil.Amount, numOutputs)
+	for i := range outputs {
+		outputs[i] = share
+	}
+	outputs[0] += total - share*btcutil.Amount(numOutputs)
+
+	return &ConsolidationPlan{
+		Inputs:  inputs,
+		Outputs: outputs,
+		VSize:   vsize,
+		Fee:     fee,
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package coinset_test
+
+import (
+	"testing"
+
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/coinset"
+)
+
+// TestConsolidator ensures the smallest coins are swept into transactions
+// within the size limit, leaving uneconomical coins alone.
+func TestConsolidator(t *testing.T) {
+	// Listed largest first, with a coin worth less than its input fee.
+	var coins []coinset.Coin
+	for i := 10; i > 0; i-- {
+		coins = append(coins, NewCoin(int64(i), btcutil.Amount(i)*10000, 1))
+	}
+	coins = append(coins, NewCoin(0, 50, 1))
+
+	// Transactions of one output are 44 vB plus 148 vB per input, so four
+	// inputs fit in 700 vB and pay a fee of 318 Hao.
+	c := coinset.Consolidator{FeeParams: lowFees, MaxTxVSize: 700}
+	plans, err := c.Plan(coins)
+	if err != nil {
+		t.Fatalf("Plan: unexpected error: %v", err)
+	}
+	wantInputs := [][]btcutil.Amount{
+		{10000, 20000, 30000, 40000},
+		{50000, 60000, 70000, 80000},
+		{90000, 100000},
+	}
+	if len(plans) != len(wantInputs) {
+		t.Fatalf("Plan: got %d plans, want %d", len(plans), len(wantInputs))
+	}
+	for i, plan := range plans {
+		var total btcutil.Amount
+		for j, in := range plan.Inputs {
+			if j >= len(wantInputs[i]) || in.Value() != wantInputs[i][j] {
+				t.Errorf("Plan: plan %d input %d is worth %d", i, j,
+					in.Value())
+			}
+			total += in.Value()
+		}
+		vsize := int64(44 + 148*len(plan.Inputs))
+		if plan.VSize != vsize || plan.Fee != lowFees.FeeRate.FeeForVSize(vsize) {
+			t.Errorf("Plan: plan %d has size %d and fee %d", i, plan.VSize,
+				plan.Fee)
+		}
+		if len(plan.Outputs) != 1 || plan.Outputs[0] != total-plan.Fee {
+			t.Errorf("Plan: plan %d has outputs %v", i, plan.Outputs)
+		}
+	}
+
+	// With two outputs per transaction, the last two coins would not
+	// reduce the number of coins.
+	c.OutputsPerTx = 2
+	plans, err = c.Plan(coins)
+	if err != nil {
+		t.Fatalf("Plan: unexpected error: %v", err)
+	}
+	if len(plans) != 2 {
+		t.Fatalf("Plan: got %d plans with two outputs, want 2", len(plans))
+	}
+	out := plans[0].Outputs
+	if len(out) != 2 || out[0]+out[1] != 100000-plans[0].Fee || out[0]-out[1] > 1 {
+		t.Errorf("Plan: got outputs %v", out)
+	}
+
+	tests := []struct {
+		name string
+		c    coinset.Consolidator
+		err  error
+	}{
+		{"high fees", coinset.Consolidator{FeeParams: highFees},
+			coinset.ErrConsolidationNotWorthwhile},
+		{"small transactions", coinset.Consolidator{FeeParams: lowFees, MaxTxVSize: 300},
+			coinset.ErrCoinsNoSelectionAvailable},
+		{"large outputs", coinset.Consolidator{FeeParams: lowFees, MinOutputValue: btcutil.MaxHao},
+			coinset.ErrCoinsNoSelectionAvailable},
+	}
+	for _, test := range tests {
+		if _, err := test.c.Plan(coins); err != test.err {
+			t.Errorf("Plan(%s): got error %v, want %v", test.name, err,
+				test.err)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package coinset
+
+import (
+	"math/rand"
+	"sort"
+
+	"github.com/zeusyf/btcutil"
+)
+
+// knapsackIterations is the number of random subsets the knapsack selector
+// tries for each target, as in Bitcoin Core.
+const knapsackIterations = 1000
+
+// KnapsackCoinSelector is a CoinSelector that attempts to construct a
+// selection of coins whose effective value is exactly targetValue, or
+// exceeds it by at least MinChangeAmount, using the stochastic knapsack
+// solver of Bitcoin Core.  Should no subset of the smaller coins come close,
+// the smallest coin exceeding targetValue plus MinChangeAmount is selected.
+//
+// Rand is the source of randomness used to shuffle the coins and pick
+// subsets, and the global source of math/rand is used when it is nil.
+type KnapsackCoinSelector struct {
+	FeeParams
+	MinChangeAmount btcutil.Amount
+	Rand            *rand.Rand
+}
+
+// knapsackCoin is a coin with its effective value.
+type knapsackCoin struct {
+	coin      Coin
+	effective btcutil.Amount
+}
+
+// CoinSelect will attempt to select coins using the algorithm described
+// in the KnapsackCoinSelector struct.
+func (s KnapsackCoinSelector) CoinSelect(targetValue btcutil.Amount, coins []Coin) (Coins, error) {
+	shuffle, coinFlip := rand.Shuffle, func() bool { return rand.Intn(2) == 0 }
+	if s.Rand != nil {
+		shuffle, coinFlip = s.Rand.Shuffle, func() bool { return s.Rand.Intn(2) == 0 }
+	}
+
+	pool := make([]knapsackCoin, 0, len(coins))
+	for _, c := range coins {
+		if effective := s.EffectiveValue(c); effective > 0 {
+			pool = append(pool, knapsackCoin{c, effective})
+		}
+	}
+	shuffle(len(pool), func(i, j int) { pool[i], pool[j] = pool[j], pool[i] })
+
+	var applicable []knapsackCoin
+	var lowestLarger *knapsackCoin
+	var totalLower btcutil.Amount
+	for i := range pool {
+		c := &pool[i]
+		switch {
+		case c.effective == targetValue:
+			return NewCoinSet([]Coin{c.coin}), nil
+
+		case c.effective < targetValue+s.MinChangeAmount:
+			applicable = append(applicable, *c)
+			totalLower += c.effective
+
+		case lowestLarger == nil || c.effective < lowestLarger.effective:
+			lowestLarger = c
+		}
+	}
+
+	if totalLower == targetValue {
+		return knapsackSet(applicable, nil), nil
+	}
+	if totalLower < targetValue {
+		if lowestLarger == nil {
+			return nil, ErrCoinsNoSelectionAvailable
+		}
+		return NewCoinSet([]Coin{lowestLarger.coin}), nil
+	}
+
+	// Approximate the best subset of the smaller coins, and should it
+	// not be exact, one leaving enough change.
+	sort.SliceStable(applicable, func(i, j int) bool {
+		return applicable[i].effective > applicable[j].effective
+	})
+	included, best := approximateBestSubset(applicable, totalLower, targetValue, coinFlip)
+	if best != targetValue && totalLower >= targetValue+s.MinChangeAmount {
+		included, best = approximateBestSubset(applicable, totalLower,
+			targetValue+s.MinChangeAmount, coinFlip)
+	}
+
+	// Prefer the larger coin when the subsets leave too little change, or
+	// it is closer to the target.
+	if lowestLarger != nil && ((best != targetValue &&
+		best < targetValue+s.MinChangeAmount) ||
+		lowestLarger.effective <= best) {
+
+		return NewCoinSet([]Coin{lowestLarger.coin}), nil
+	}
+	return knapsackSet(applicable, included), nil
+}
+
+// approximateBestSubset returns the smallest subset of coins found whose
+// effective value reaches targetValue, along with that value.  Each round
+// includes random coins, and then further coins in order until the target is
+// reached, backing off each coin which reaches it to look for a closer
+// subset.
+func approximateBestSubset(coins []knapsackCoin, totalLower, targetValue btcutil.Amount,
+	coinFlip func() bool) ([]bool, btcutil.Amount) {
+
+	best := make([]bool, len(coins))
+	for i := range best {
+		best[i] = true
+	}
+	bestValue := totalLower
+
+	included := make([]bool, len(coins))
+	for rep := 0; rep < knapsackIterations && bestValue != targetValue; rep++ {
+		for i := range included {
+			included[i] = false
+		}
+		var total btcutil.Amount
+		reachedTarget := false
+		for pass := 0; pass < 2 && !reachedTarget; pass++ {
+			for i, c := range coins {
+				if pass == 0 && !coinFlip() || pass == 1 && included[i] {
+					continue
+				}
+				total += c.effective
+				included[i] = true
+				if total < targetValue {
+					continue
+				}
+				reachedTarget = true
+				if total < bestValue {
+					bestValue = total
+					copy(best, included)
+				}
+				total -= c.effective
+				included[i] = false
+			}
+		}
+	}
+	return best, bestValue
+}
+
+// knapsackSet returns the coins included in a subset, or all of them when
+// included is nil.
+func knapsackSet(coins []knapsackCoin, included []bool) *CoinSet {
+	cs := NewCoinSet(nil)
+	for i, c := range coins {
+		if included == nil || included[i] {
+			cs.PushCoin(c.coin)
+		}
+	}
+	return cs
+}
+
+// Selection is a selection of coins funding a transaction, along with its
+// waste metric.
+type Selection struct {
+	Coins *CoinSet
+
+	// Change is the value of the change output after paying its fee, or
+	// zero when the selection needs no change output.
+	Change btcutil.Amount
+
+	// Waste is the waste metric of the selection as returned by
+	// FeeParams.Waste.
+	Waste btcutil.Amount
+}
+
+// WasteCoinSelector is a CoinSelector that runs both the branch and bound
+// and the knapsack selectors and picks the selection of least waste,
+// preferring more inputs on a tie, as Bitcoin Core does.  The knapsack
+// selection pays for a change output, which is dropped to fees should it be
+// less than MinChangeAmount.
+//
+// As for BnBCoinSelector, the target is the effective value the selection
+// must provide.
+type WasteCoinSelector struct {
+	FeeParams
+	MinChangeAmount btcutil.Amount
+	Rand            *rand.Rand
+}
+
+// Select returns the selection of coins of least waste which funds
+// targetValue.
+func (s WasteCoinSelector) Select(targetValue btcutil.Amount, coins []Coin) (*Selection, error) {
+	var best *Selection
+	consider := func(sel *Selection) {
+		switch {
+		case best == nil, sel.Waste < best.Waste:
+			best = sel
+		case sel.Waste == best.Waste && sel.Coins.Num() > best.Coins.Num():
+			best = sel
+		}
+	}
+
+	bnb := BnBCoinSelector{FeeParams: s.FeeParams}
+	if cs, err := bnb.CoinSelect(targetValue, coins); err == nil {
+		consider(&Selection{
+			Coins: NewCoinSet(cs.Coins()),
+			Waste: s.Waste(cs.Coins(), targetValue, false),
+		})
+	}
+
+	knapsack := KnapsackCoinSelector{
+		FeeParams:       s.FeeParams,
+		MinChangeAmount: s.MinChangeAmount,
+		Rand:            s.Rand,
+	}
+	changeFee := s.ChangeFee()
+	if cs, err := knapsack.CoinSelect(targetValue+changeFee, coins); err == nil {
+		var effective btcutil.Amount
+		for _, c := range cs.Coins() {
+			effective += s.EffectiveValue(c)
+		}
+		sel := &Selection{Coins: NewCoinSet(cs.Coins())}
+		if change := effective - targetValue - changeFee; change >= s.MinChangeAmount {
+			sel.Change = change
+			sel.Waste = s.Waste(cs.Coins(), targetValue, true)
+		} else {
+			sel.Waste = s.Waste(cs.Coins(), targetValue, false)
+		}
+		consider(sel)
+	}
+
+	if best == nil {
+		return nil, ErrCoinsNoSelectionAvailable
+	}
+	return best, nil
+}
+
+// CoinSelect will attempt to select coins using the algorithm described
+// in the WasteCoinSelector struct.
+func (s WasteCoinSelector) CoinSelect(targetValue btcutil.Amount, coins []Coin) (Coins, error) {
+	sel, err := s.Select(targetValue, coins)
+	if err != nil {
+		return nil, err
+	}
+	return sel.Coins, nil
+}
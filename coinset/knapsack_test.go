@@ -0,0 +1,100 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package coinset_test
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/coinset"
+)
+
+// TestKnapsackSelector ensures the knapsack selector finds exact matches and
+// selections leaving enough change.
+func TestKnapsackSelector(t *testing.T) {
+	free := coinset.FeeParams{}
+	c := feeCoins(0, 1, 2, 3, 4)
+	large := feeCoins(0, 1, 2, 20)
+
+	tests := []struct {
+		coins     []coinset.Coin
+		target    btcutil.Amount
+		minChange btcutil.Amount
+		want      btcutil.Amount
+		err       error
+	}{
+		{c, 300000, 100000, 300000, nil},
+		{c, 500000, 100000, 500000, nil},
+		{c, 1000000, 100000, 1000000, nil},
+		{c, 1000001, 100000, 0, coinset.ErrCoinsNoSelectionAvailable},
+
+		// The change left must be at least the minimum, or none at all.
+		{c, 250000, 100000, 400000, nil},
+
+		// The smaller coins fall short, so the large one is used.
+		{large, 400000, 100000, 2000000, nil},
+		{large, 300000, 100000, 300000, nil},
+	}
+	for i, test := range tests {
+		selector := coinset.KnapsackCoinSelector{
+			FeeParams:       free,
+			MinChangeAmount: test.minChange,
+			Rand:            rand.New(rand.NewSource(int64(i))),
+		}
+		cs, err := selector.CoinSelect(test.target, test.coins)
+		if err != test.err {
+			t.Errorf("[%d] CoinSelect: got error %v, want %v", i, err, test.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		total := coinset.NewCoinSet(cs.Coins()).TotalValue()
+		if total != test.want {
+			t.Errorf("[%d] CoinSelect: got total %d, want %d", i, total,
+				test.want)
+		}
+	}
+}
+
+// TestWasteSelector ensures the selection of least waste is picked among the
+// branch and bound and knapsack selections.
+func TestWasteSelector(t *testing.T) {
+	coins := feeCoins(296, 1, 2, 3, 4)
+	selector := coinset.WasteCoinSelector{
+		FeeParams:       highFees,
+		MinChangeAmount: 10000,
+		Rand:            rand.New(rand.NewSource(1)),
+	}
+
+	// An exact match needs no change.
+	sel, err := selector.Select(300000, coins)
+	if err != nil {
+		t.Fatalf("Select: unexpected error: %v", err)
+	}
+	if sel.Change != 0 || sel.Coins.Num() != 1 || sel.Waste != 148 {
+		t.Errorf("Select: got %d coins, change %d and waste %d",
+			sel.Coins.Num(), sel.Change, sel.Waste)
+	}
+
+	// Without a changeless selection, change is paid for.
+	sel, err = selector.Select(250000, coins)
+	if err != nil {
+		t.Fatalf("Select: unexpected error: %v", err)
+	}
+	if sel.Change == 0 || sel.Waste != highFees.Waste(sel.Coins.Coins(), 250000, true) {
+		t.Errorf("Select: got change %d and waste %d", sel.Change, sel.Waste)
+	}
+	if value := sel.Coins.TotalValue() - btcutil.Amount(sel.Coins.Num())*296; value != 250000+68+sel.Change {
+		t.Errorf("Select: got effective value %d with change %d", value,
+			sel.Change)
+	}
+
+	if _, err := selector.CoinSelect(2000000, coins); err != coinset.ErrCoinsNoSelectionAvailable {
+		t.Errorf("CoinSelect: got error %v, want %v", err,
+			coinset.ErrCoinsNoSelectionAvailable)
+	}
+}
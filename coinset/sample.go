@@ -0,0 +1,162 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package coinset
+
+import (
+	"container/heap"
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/zeusyf/btcutil"
+)
+
+// Weight returns the weight of a coin for weighted sampling.  Coins with a
+// weight which is not positive are never sampled.
+type Weight func(c Coin) float64
+
+// ByValue weighs coins by their value.
+func ByValue(c Coin) float64 {
+	return float64(c.Value())
+}
+
+// ByValueAge weighs coins by their value-age, their value multiplied by their
+// number of confirmations.
+func ByValueAge(c Coin) float64 {
+	return float64(c.ValueAge())
+}
+
+// weightedCoin is a coin with its sampling key.
+type weightedCoin struct {
+	coin Coin
+	key  float64
+}
+
+// reservoirHeap is a min-heap of weighted coins by key.
+type reservoirHeap []weightedCoin
+
+func (h reservoirHeap) Len() int            { return len(h) }
+func (h reservoirHeap) Less(i, j int) bool  { return h[i].key < h[j].key }
+func (h reservoirHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *reservoirHeap) Push(x interface{}) { *h = append(*h, x.(weightedCoin)) }
+func (h *reservoirHeap) Pop() interface{} {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
+
+// sampleKey returns the key of a coin of the passed weight in the weighted
+// reservoir sampling of Efraimidis and Spirakis.  The key is u^(1/w) for a
+// uniform u in (0, 1), computed as its logarithm ln(u)/w so that small
+// weights do not underflow.
+func sampleKey(float func() float64, w float64) float64 {
+	u := float()
+	for u == 0 {
+		u = float()
+	}
+	return math.Log(u) / w
+}
+
+// Reservoir samples coins without replacement with probability proportional
+// to their weight, in a single pass over any number of coins while holding
+// only the sample.  The zero value is not usable; a Reservoir must be created
+// with NewReservoir.
+type Reservoir struct {
+	size   int
+	weight Weight
+	float  func() float64
+	sample reservoirHeap
+}
+
+// NewReservoir returns a reservoir sampling size coins weighted by weight.
+// r is the source of randomness, and the global source of math/rand is used
+// when it is nil.
+func NewReservoir(size int, weight Weight, r *rand.Rand) *Reservoir {
+	float := rand.Float64
+	if r != nil {
+		float = r.Float64
+	}
+	return &Reservoir{
+		size:   size,
+		weight: weight,
+		float:  float,
+	}
+}
+
+// Add offers a coin to the reservoir.
+func (r *Reservoir) Add(c Coin) {
+	w := r.weight(c)
+	if w <= 0 || r.size <= 0 {
+		return
+	}
+	key := sampleKey(r.float, w)
+	if len(r.sample) < r.size {
+		heap.Push(&r.sample, weightedCoin{c, key})
+		return
+	}
+	if key > r.sample[0].key {
+		r.sample[0] = weightedCoin{c, key}
+		heap.Fix(&r.sample, 0)
+	}
+}
+
+// Coins returns the sampled coins, in the order in which a weighted random
+// permutation of all the coins offered would list them.
+func (r *Reservoir) Coins() []Coin {
+	sorted := make([]weightedCoin, len(r.sample))
+	copy(sorted, r.sample)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].key > sorted[j].key
+	})
+	coins := make([]Coin, len(sorted))
+	for i, wc := range sorted {
+		coins[i] = wc.coin
+	}
+	return coins
+}
+
+// Sample returns size coins sampled from coins without replacement with
+// probability proportional to their weight.  Fewer coins are returned when
+// fewer have a positive weight.  r is the source of randomness, and the
+// global source of math/rand is used when it is nil.
+func Sample(coins []Coin, size int, weight Weight, r *rand.Rand) []Coin {
+	res := NewReservoir(size, weight, r)
+	for _, c := range coins {
+		res.Add(c)
+	}
+	return res.Coins()
+}
+
+// WeightedRandomCoinSelector is a CoinSelector that attempts to construct a
+// selection of coins whose total value is at least targetValue by drawing
+// coins at random with probability proportional to their weight, until the
+// selection is exactly targetValue or exceeds it by at least
+// MinChangeAmount.  Weighing coins by value favors selections with few
+// inputs, while leaving which coins are spent to chance, so the coins of a
+// wallet can not be linked by the selection policy alone.
+//
+// Weight defaults to ByValue, and Rand is the source of randomness, the
+// global source of math/rand being used when it is nil.
+type WeightedRandomCoinSelector struct {
+	Weight          Weight
+	MaxInputs       int
+	MinChangeAmount btcutil.Amount
+	Rand            *rand.Rand
+}
+
+// CoinSelect will attempt to select coins using the algorithm described
+// in the WeightedRandomCoinSelector struct.
+func (s WeightedRandomCoinSelector) CoinSelect(targetValue btcutil.Amount, coins []Coin) (Coins, error) {
+	weight := s.Weight
+	if weight == nil {
+		weight = ByValue
+	}
+	order := Sample(coins, len(coins), weight, s.Rand)
+	return MinIndexCoinSelector{
+		MaxInputs:       s.MaxInputs,
+		MinChangeAmount: s.MinChangeAmount,
+	}.CoinSelect(targetValue, order)
+}
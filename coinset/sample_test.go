@@ -0,0 +1,120 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package coinset_test
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/coinset"
+)
+
+// TestSample ensures samples hold distinct coins of positive weight.
+func TestSample(t *testing.T) {
+	coins := []coinset.Coin{
+		NewCoin(0, 100, 1), NewCoin(1, 0, 1), NewCoin(2, 300, 0),
+		NewCoin(3, 400, 2), NewCoin(4, 500, 3),
+	}
+	r := rand.New(rand.NewSource(1))
+
+	sample := coinset.Sample(coins, 3, coinset.ByValue, r)
+	if len(sample) != 3 {
+		t.Fatalf("Sample: got %d coins, want 3", len(sample))
+	}
+	seen := make(map[coinset.Coin]bool)
+	for _, c := range sample {
+		if seen[c] || c.Value() == 0 {
+			t.Errorf("Sample: got coin of value %v twice or with no "+
+				"weight", c.Value())
+		}
+		seen[c] = true
+	}
+
+	// Only four coins have value, and three value-age.
+	if n := len(coinset.Sample(coins, 10, coinset.ByValue, r)); n != 4 {
+		t.Errorf("Sample: got %d coins by value, want 4", n)
+	}
+	if n := len(coinset.Sample(coins, 10, coinset.ByValueAge, r)); n != 3 {
+		t.Errorf("Sample: got %d coins by value-age, want 3", n)
+	}
+	if n := len(coinset.Sample(coins, 0, coinset.ByValue, r)); n != 0 {
+		t.Errorf("Sample: got %d coins for an empty sample", n)
+	}
+}
+
+// TestSampleDistribution ensures coins are sampled with probability
+// proportional to their weight.
+func TestSampleDistribution(t *testing.T) {
+	coins := []coinset.Coin{NewCoin(0, 1, 1), NewCoin(1, 2, 1),
+		NewCoin(2, 3, 1), NewCoin(3, 4, 1)}
+	r := rand.New(rand.NewSource(2))
+
+	const rounds = 20000
+	counts := make(map[btcutil.Amount]int)
+	for i := 0; i < rounds; i++ {
+		counts[coinset.Sample(coins, 1, coinset.ByValue, r)[0].Value()]++
+	}
+	for _, c := range coins {
+		want := rounds * float64(c.Value()) / 10
+		if got := float64(counts[c.Value()]); math.Abs(got-want) > want*0.05 {
+			t.Errorf("coin of value %v sampled %v times, want about %v",
+				c.Value(), got, want)
+		}
+	}
+}
+
+// TestReservoir ensures a reservoir fed coins one at a time samples as Sample
+// does.
+func TestReservoir(t *testing.T) {
+	var coins []coinset.Coin
+	for i := int64(0); i < 1000; i++ {
+		coins = append(coins, NewCoin(i, btcutil.Amount(i+1), 1))
+	}
+
+	res := coinset.NewReservoir(10, coinset.ByValue,
+		rand.New(rand.NewSource(3)))
+	for _, c := range coins {
+		res.Add(c)
+	}
+	got := res.Coins()
+	want := coinset.Sample(coins, 10, coinset.ByValue,
+		rand.New(rand.NewSource(3)))
+	if len(got) != len(want) {
+		t.Fatalf("Coins: got %d coins, want %d", len(got), len(want))
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Errorf("Coins: coin %d differs from Sample", i)
+		}
+	}
+}
+
+// TestWeightedRandomCoinSelector ensures the weighted random selector reaches
+// the target within its input limit.
+func TestWeightedRandomCoinSelector(t *testing.T) {
+	coins := feeCoins(0, 1, 2, 3, 4, 5)
+	s := coinset.WeightedRandomCoinSelector{
+		MaxInputs: 5,
+		Rand:      rand.New(rand.NewSource(4)),
+	}
+	for i := 0; i < 100; i++ {
+		cs, err := s.CoinSelect(700000, coins)
+		if err != nil {
+			t.Fatalf("CoinSelect: unexpected error: %v", err)
+		}
+		total := coinset.NewCoinSet(cs.Coins()).TotalValue()
+		if total < 700000 {
+			t.Fatalf("CoinSelect: got %v, want at least 700000", total)
+		}
+	}
+
+	s.MaxInputs = 1
+	if _, err := s.CoinSelect(700000, coins); err != coinset.ErrCoinsNoSelectionAvailable {
+		t.Errorf("CoinSelect: got error %v, want %v", err,
+			coinset.ErrCoinsNoSelectionAvailable)
+	}
+}
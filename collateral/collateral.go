@@ -0,0 +1,83 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package collateral helps mining pools and monitoring services handle the
+// collateral Omega miners lock up to obtain mining rights, and the reports
+// proving that a miner violated the rules it committed to, which forfeit that
+// collateral.
+//
+// Collateral is an ordinary output of the base token paying to the miner,
+// which the miner references when it claims mining rights.  NewOutput creates
+// such an output and CheckOutput verifies that an existing output qualifies.
+//
+// A ViolationReport carries two signatures by the same miner over different
+// blocks at the same height.  Anybody can check a report offline with its
+// Validate method, and exchange it in the binary form written by Serialize.
+package collateral
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/zeusyf/btcd/txscript"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/omega/token"
+)
+
+var (
+	// ErrNotCollateral describes an error where an output offered as
+	// collateral does not pay the base token to the miner.
+	ErrNotCollateral = errors.New("output does not pay the base token " +
+		"to the miner")
+
+	// ErrInsufficientCollateral describes an error where an output
+	// offered as collateral pays less than required.
+	ErrInsufficientCollateral = errors.New("collateral amount below " +
+		"requirement")
+)
+
+// NewOutput returns an output locking amount of the base token as collateral
+// of the miner controlling the passed address.
+func NewOutput(miner btcutil.Address, amount btcutil.Amount) (*wire.TxOut, error) {
+	if amount <= 0 {
+		return nil, ErrInsufficientCollateral
+	}
+	pkScript, err := txscript.PayToAddrScript(miner)
+	if err != nil {
+		return nil, err
+	}
+	return &wire.TxOut{
+		Token: token.Token{
+			TokenType: 0,
+			Value:     &token.NumeralVal{Val: int64(amount)},
+		},
+		PkScript: pkScript,
+	}, nil
+}
+
+// CheckOutput verifies that txOut can serve as collateral of at least
+// minAmount for the miner controlling the passed address, and returns the
+// amount it locks.  ErrNotCollateral is returned for outputs of other tokens
+// or paying to other scripts, and ErrInsufficientCollateral for outputs of
+// too little value.
+func CheckOutput(txOut *wire.TxOut, miner btcutil.Address, minAmount btcutil.Amount) (btcutil.Amount, error) {
+	value, ok := txOut.Token.Value.(*token.NumeralVal)
+	if txOut.Token.TokenType != 0 || !ok {
+		return 0, ErrNotCollateral
+	}
+	pkScript, err := txscript.PayToAddrScript(miner)
+	if err != nil {
+		return 0, err
+	}
+	if !bytes.Equal(txOut.PkScript, pkScript) {
+		return 0, ErrNotCollateral
+	}
+
+	amount := btcutil.Amount(value.Val)
+	if amount <= 0 || amount < minAmount {
+		return amount, ErrInsufficientCollateral
+	}
+	return amount, nil
+}
@@ -0,0 +1,77 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package collateral_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/collateral"
+	"github.com/zeusyf/omega/token"
+)
+
+// testAddress returns a pay-to-pubkey-hash address whose hash repeats b.
+func testAddress(t *testing.T, b byte) btcutil.Address {
+	addr, err := btcutil.NewAddressPubKeyHash(bytes.Repeat([]byte{b}, 20),
+		&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return addr
+}
+
+// TestOutput ensures collateral outputs are created and checked.
+func TestOutput(t *testing.T) {
+	miner := testAddress(t, 0x01)
+	other := testAddress(t, 0x02)
+
+	txOut, err := collateral.NewOutput(miner, 1000)
+	if err != nil {
+		t.Fatalf("NewOutput: unexpected error: %v", err)
+	}
+	if _, err := collateral.NewOutput(miner, 0); err != collateral.ErrInsufficientCollateral {
+		t.Errorf("NewOutput zero: got error %v, want %v", err,
+			collateral.ErrInsufficientCollateral)
+	}
+
+	otherToken := &wire.TxOut{
+		Token: token.Token{
+			TokenType: 1,
+			Value:     &token.NumeralVal{Val: 1000},
+		},
+		PkScript: txOut.PkScript,
+	}
+
+	tests := []struct {
+		name      string
+		txOut     *wire.TxOut
+		miner     btcutil.Address
+		minAmount btcutil.Amount
+		amount    btcutil.Amount
+		err       error
+	}{
+		{"valid", txOut, miner, 1000, 1000, nil},
+		{"below minimum", txOut, miner, 1001, 1000,
+			collateral.ErrInsufficientCollateral},
+		{"other miner", txOut, other, 1000, 0, collateral.ErrNotCollateral},
+		{"other token", otherToken, miner, 1000, 0,
+			collateral.ErrNotCollateral},
+	}
+	for _, test := range tests {
+		amount, err := collateral.CheckOutput(test.txOut, test.miner,
+			test.minAmount)
+		if err != test.err {
+			t.Errorf("%s: got error %v, want %v", test.name, err,
+				test.err)
+		}
+		if amount != test.amount {
+			t.Errorf("%s: got amount %v, want %v", test.name, amount,
+				test.amount)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package collateral
+
+import (
+	"bytes"
+	"errors"
+	"io"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/envelope"
+)
+
+const (
+	// reportVersion is the envelope version written by Serialize.  It
+	// only needs to be bumped for changes older readers can not skip.
+	reportVersion = 1
+
+	// These are the types of the envelope fields of a serialized report.
+	fieldHeight   = 1
+	fieldEvidence = 2
+)
+
+// reportMagic identifies serialized violation reports.
+var reportMagic = [envelope.MagicSize]byte{'o', 'v', 'r', 'p'}
+
+var (
+	// ErrSameBlock describes an error where both pieces of evidence of a
+	// violation report sign the same block, which is no violation.
+	ErrSameBlock = errors.New("evidence signs the same block twice")
+
+	// ErrDifferentMiners describes an error where the pieces of evidence
+	// of a violation report were signed by different miners.
+	ErrDifferentMiners = errors.New("evidence signed by different miners")
+
+	// ErrMalformedReport describes an error where a serialized violation
+	// report does not hold a height and exactly two pieces of evidence.
+	ErrMalformedReport = errors.New("malformed violation report")
+
+	// ErrUnsupportedVersion describes an error where a serialized report
+	// uses a version this package does not understand.
+	ErrUnsupportedVersion = errors.New("unsupported violation report " +
+		"version")
+)
+
+// Evidence is a miner's signature of a block.
+type Evidence struct {
+	// Hash is the hash of the signed block.
+	Hash chainhash.Hash
+
+	// Signature is the signature of the miner: its serialized compressed
+	// public key followed by the DER encoded signature of Hash, as
+	// checked by btcutil.VerifySigScript.
+	Signature []byte
+}
+
+// ViolationReport proves that a miner signed two different blocks at the same
+// height, which forfeits its collateral.
+type ViolationReport struct {
+	// Height is the height both signed blocks claim.
+	Height uint32
+
+	// Evidence holds the signatures of the two blocks.
+	Evidence [2]Evidence
+}
+
+// Validate verifies that the evidence of the report holds valid signatures of
+// two different blocks by the same miner, and returns the address of that
+// miner on the passed network, which must not be nil.
+//
+// The signatures only cover the block hashes, so Validate can not tell which
+// heights the blocks are at.  Callers must check that both blocks are at the
+// height of the report, such as by looking them up in the chain, before
+// acting on it.
+func (r *ViolationReport) Validate(net *chaincfg.Params) (*btcutil.AddressPubKeyHash, error) {
+	first, second := &r.Evidence[0], &r.Evidence[1]
+	if first.Hash == second.Hash {
+		return nil, ErrSameBlock
+	}
+	miner, err := btcutil.VerifySigScript(first.Signature, first.Hash[:], net)
+	if err != nil {
+		return nil, err
+	}
+	other, err := btcutil.VerifySigScript(second.Signature, second.Hash[:], net)
+	if err != nil {
+		return nil, err
+	}
+	if !bytes.Equal(miner.ScriptAddress(), other.ScriptAddress()) {
+		return nil, ErrDifferentMiners
+	}
+	return miner, nil
+}
+
+// Serialize writes the report to w.
+func (r *ViolationReport) Serialize(w io.Writer) error {
+	ew, err := envelope.NewWriter(w, reportMagic, reportVersion)
+	if err != nil {
+		return err
+	}
+	if err := ew.WriteUint32(fieldHeight, r.Height); err != nil {
+		return err
+	}
+	for i := range r.Evidence {
+		e := &r.Evidence[i]
+		value := make([]byte, 0, chainhash.HashSize+len(e.Signature))
+		value = append(value, e.Hash[:]...)
+		value = append(value, e.Signature...)
+		if err := ew.WriteField(fieldEvidence, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Deserialize reads a report previously written by Serialize from rd into r.
+// Fields of types added by newer versions of this package are skipped.
+func (r *ViolationReport) Deserialize(rd io.Reader) error {
+	er, err := envelope.NewReader(rd, reportMagic, reportVersion)
+	if err != nil {
+		if err == envelope.ErrUnsupportedVersion {
+			return ErrUnsupportedVersion
+		}
+		return err
+	}
+
+	*r = ViolationReport{}
+	var haveHeight bool
+	var numEvidence int
+	for {
+		fieldType, value, err := er.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		switch fieldType {
+		case fieldHeight:
+			r.Height, err = envelope.Uint32(value)
+			if err != nil {
+				return err
+			}
+			haveHeight = true
+
+		case fieldEvidence:
+			if numEvidence == len(r.Evidence) ||
+				len(value) <= chainhash.HashSize {
+				return ErrMalformedReport
+			}
+			e := &r.Evidence[numEvidence]
+			copy(e.Hash[:], value)
+			e.Signature = value[chainhash.HashSize:]
+			numEvidence++
+		}
+	}
+
+	if !haveHeight || numEvidence != len(r.Evidence) {
+		return ErrMalformedReport
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package collateral_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/collateral"
+)
+
+// testEvidence returns the evidence of the miner with the passed key signing
+// the block with the passed hash.
+func testEvidence(t *testing.T, key *btcec.PrivateKey, hash chainhash.Hash) collateral.Evidence {
+	sig, err := key.Sign(hash[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	return collateral.Evidence{
+		Hash: hash,
+		Signature: append(key.PubKey().SerializeCompressed(),
+			sig.Serialize()...),
+	}
+}
+
+// TestViolationReport ensures reports are validated and survive
+// serialization.
+func TestViolationReport(t *testing.T) {
+	net := &chaincfg.MainNetParams
+	key, _ := btcec.PrivKeyFromBytes(btcec.S256(), bytes.Repeat([]byte{0x2a}, 32))
+	otherKey, _ := btcec.PrivKeyFromBytes(btcec.S256(), bytes.Repeat([]byte{0x2b}, 32))
+	block1 := chainhash.DoubleHashH([]byte("block 1"))
+	block2 := chainhash.DoubleHashH([]byte("block 2"))
+
+	report := &collateral.ViolationReport{
+		Height: 1234,
+		Evidence: [2]collateral.Evidence{
+			testEvidence(t, key, block1),
+			testEvidence(t, key, block2),
+		},
+	}
+	miner, err := report.Validate(net)
+	if err != nil {
+		t.Fatalf("Validate: unexpected error: %v", err)
+	}
+	want, _ := btcutil.NewAddressPubKeyHash(
+		btcutil.Hash160(key.PubKey().SerializeCompressed()), net)
+	if !bytes.Equal(miner.ScriptAddress(), want.ScriptAddress()) {
+		t.Errorf("Validate: got miner %v, want %v", miner, want)
+	}
+
+	var buf bytes.Buffer
+	if err := report.Serialize(&buf); err != nil {
+		t.Fatalf("Serialize: unexpected error: %v", err)
+	}
+	serialized := buf.Bytes()
+	var decoded collateral.ViolationReport
+	if err := decoded.Deserialize(bytes.NewReader(serialized)); err != nil {
+		t.Fatalf("Deserialize: unexpected error: %v", err)
+	}
+	if decoded.Height != report.Height {
+		t.Errorf("Deserialize: got height %d, want %d", decoded.Height,
+			report.Height)
+	}
+	for i := range report.Evidence {
+		got, want := decoded.Evidence[i], report.Evidence[i]
+		if got.Hash != want.Hash ||
+			!bytes.Equal(got.Signature, want.Signature) {
+			t.Errorf("Deserialize: evidence %d mismatch", i)
+		}
+	}
+
+	sameBlock := *report
+	sameBlock.Evidence[1] = testEvidence(t, key, block1)
+	if _, err := sameBlock.Validate(net); err != collateral.ErrSameBlock {
+		t.Errorf("Validate same block: got error %v, want %v", err,
+			collateral.ErrSameBlock)
+	}
+
+	otherMiner := *report
+	otherMiner.Evidence[1] = testEvidence(t, otherKey, block2)
+	if _, err := otherMiner.Validate(net); err != collateral.ErrDifferentMiners {
+		t.Errorf("Validate other miner: got error %v, want %v", err,
+			collateral.ErrDifferentMiners)
+	}
+
+	forged := *report
+	forged.Evidence[1].Hash = chainhash.DoubleHashH([]byte("block 3"))
+	if _, err := forged.Validate(net); err == nil {
+		t.Error("Validate forged: unexpected success")
+	}
+
+	// A report missing evidence is malformed.
+	var short collateral.ViolationReport
+	err = short.Deserialize(bytes.NewReader(serialized[:len(serialized)-
+		len(report.Evidence[1].Signature)-chainhash.HashSize-2]))
+	if err != collateral.ErrMalformedReport {
+		t.Errorf("Deserialize short: got error %v, want %v", err,
+			collateral.ErrMalformedReport)
+	}
+}
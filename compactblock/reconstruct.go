@@ -0,0 +1,177 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package compactblock
+
+import (
+	"errors"
+
+	"github.com/zeusyf/btcutil"
+)
+
+var (
+	// ErrShortIDCollision describes an error where two transactions of a
+	// compact block have the same short ID, so the block cannot be
+	// reconstructed and must be requested in full.
+	ErrShortIDCollision = errors.New("short ID collision in compact block")
+
+	// ErrTxCount describes an error where the number of transactions
+	// filling a reconstruction is not the number it misses.
+	ErrTxCount = errors.New("wrong number of missing transactions")
+
+	// ErrShortIDMismatch describes an error where a transaction filling a
+	// reconstruction does not have the short ID of its position.
+	ErrShortIDMismatch = errors.New("transaction does not match short ID")
+)
+
+// PrefilledTx is a transaction sent in full in a compact block.
+type PrefilledTx struct {
+	// Index is the position of the transaction in the block.
+	Index uint32
+
+	// Tx is the transaction.
+	Tx *btcutil.Tx
+}
+
+// ShortIDs splits the transactions of block into the prefilled transactions
+// at the strictly increasing indexes of prefill and the short IDs of the
+// others, in order.  Relaying nodes usually prefill the coinbase, which the
+// receiver cannot have.
+func ShortIDs(key *ShortIDKey, block *btcutil.Block,
+	prefill []uint32) ([]PrefilledTx, []uint64, error) {
+	txns := block.Transactions()
+	prefilled := make([]PrefilledTx, 0, len(prefill))
+	for i, index := range prefill {
+		if i > 0 && index <= prefill[i-1] {
+			return nil, nil, ErrIndexOrder
+		}
+		if int(index) >= len(txns) {
+			return nil, nil, ErrIndexOutOfRange
+		}
+		prefilled = append(prefilled, PrefilledTx{index, txns[index]})
+	}
+
+	shortIDs := make([]uint64, 0, len(txns)-len(prefilled))
+	next := 0
+	for i, tx := range txns {
+		if next < len(prefill) && int(prefill[next]) == i {
+			next++
+			continue
+		}
+		shortIDs = append(shortIDs, key.TxShortID(tx))
+	}
+	return prefilled, shortIDs, nil
+}
+
+// Reconstruction is a block being reconstructed from a compact block.
+type Reconstruction struct {
+	key      ShortIDKey
+	txns     []*btcutil.Tx
+	shortIDs map[int]uint64
+	missing  []uint32
+}
+
+// Reconstruct starts the reconstruction of the compact block with the short
+// ID key, short IDs and prefilled transactions, whose indexes must be
+// strictly increasing.  The transactions of the block are looked up among the
+// prefilled transactions and the pool of transactions of the node, such as
+// its mempool and recently rejected or replaced transactions.  A short ID
+// matched by several transactions of the pool is treated as missing, since
+// the transaction of the block cannot be told apart.
+//
+// ErrShortIDCollision is returned when the compact block has a short ID
+// twice.
+func Reconstruct(key *ShortIDKey, shortIDs []uint64, prefilled []PrefilledTx,
+	pool []*btcutil.Tx) (*Reconstruction, error) {
+	n := len(shortIDs) + len(prefilled)
+	r := &Reconstruction{
+		key:      *key,
+		txns:     make([]*btcutil.Tx, n),
+		shortIDs: make(map[int]uint64, len(shortIDs)),
+	}
+	for i, p := range prefilled {
+		if i > 0 && p.Index <= prefilled[i-1].Index {
+			return nil, ErrIndexOrder
+		}
+		if int(p.Index) >= n {
+			return nil, ErrIndexOutOfRange
+		}
+		r.txns[p.Index] = p.Tx
+	}
+
+	// Assign the short IDs to the positions left by the prefilled
+	// transactions, in order.
+	positions := make(map[uint64]int, len(shortIDs))
+	pos := 0
+	for _, id := range shortIDs {
+		for r.txns[pos] != nil {
+			pos++
+		}
+		if _, ok := positions[id]; ok {
+			return nil, ErrShortIDCollision
+		}
+		positions[id] = pos
+		r.shortIDs[pos] = id
+		pos++
+	}
+
+	collided := make(map[int]bool)
+	for _, tx := range pool {
+		pos, ok := positions[r.key.TxShortID(tx)]
+		if !ok || collided[pos] {
+			continue
+		}
+		switch have := r.txns[pos]; {
+		case have == nil:
+			r.txns[pos] = tx
+		case !have.Hash().IsEqual(tx.Hash()):
+			r.txns[pos] = nil
+			collided[pos] = true
+		}
+	}
+
+	for i, tx := range r.txns {
+		if tx == nil {
+			r.missing = append(r.missing, uint32(i))
+		}
+	}
+	return r, nil
+}
+
+// Missing returns the indexes of the transactions of the block which were
+// not found, which must be requested from the relaying peer.
+func (r *Reconstruction) Missing() []uint32 {
+	return r.missing
+}
+
+// Complete returns whether all the transactions of the block were found.
+func (r *Reconstruction) Complete() bool {
+	return len(r.missing) == 0
+}
+
+// Fill completes the reconstruction with the missing transactions, in the
+// order of Missing, as the relaying peer returns them.  Every transaction is
+// checked against the short ID of its position, and the reconstruction is
+// left unchanged on error.
+func (r *Reconstruction) Fill(txns []*btcutil.Tx) error {
+	if len(txns) != len(r.missing) {
+		return ErrTxCount
+	}
+	for i, tx := range txns {
+		if r.key.TxShortID(tx) != r.shortIDs[int(r.missing[i])] {
+			return ErrShortIDMismatch
+		}
+	}
+	for i, tx := range txns {
+		r.txns[r.missing[i]] = tx
+	}
+	r.missing = nil
+	return nil
+}
+
+// Transactions returns the transactions of the block, in order, with nil for
+// the missing ones.
+func (r *Reconstruction) Transactions() []*btcutil.Tx {
+	return r.txns
+}
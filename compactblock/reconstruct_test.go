@@ -0,0 +1,137 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package compactblock_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/compactblock"
+)
+
+// testTx returns a distinct transaction identified by the passed index.
+func testTx(id uint32) *btcutil.Tx {
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: wire.OutPoint{Index: id},
+		Sequence:         wire.MaxTxInSequenceNum,
+	})
+	return btcutil.NewTx(tx)
+}
+
+// testBlock returns a block of n distinct transactions.
+func testBlock(n int) *btcutil.Block {
+	msgBlock := &wire.MsgBlock{Header: testHeader}
+	for i := 0; i < n; i++ {
+		msgBlock.Transactions = append(msgBlock.Transactions,
+			testTx(uint32(i)).MsgTx())
+	}
+	return btcutil.NewBlock(msgBlock)
+}
+
+// hashes returns the hashes of txns, with empty strings for nil ones.
+func hashes(txns []*btcutil.Tx) []string {
+	s := make([]string, len(txns))
+	for i, tx := range txns {
+		if tx != nil {
+			s[i] = tx.Hash().String()
+		}
+	}
+	return s
+}
+
+// TestReconstruct tests blocks are reconstructed from their short IDs, a pool
+// and the missing transactions.
+func TestReconstruct(t *testing.T) {
+	block := testBlock(6)
+	txns := block.Transactions()
+	key, _ := compactblock.NewShortIDKey(&testHeader, 7)
+
+	prefilled, shortIDs, err := compactblock.ShortIDs(&key, block,
+		[]uint32{0, 3})
+	if err != nil {
+		t.Fatalf("ShortIDs: unexpected error: %v", err)
+	}
+	if len(prefilled) != 2 || prefilled[1].Index != 3 || len(shortIDs) != 4 {
+		t.Fatalf("ShortIDs: got %d prefilled and %d short IDs",
+			len(prefilled), len(shortIDs))
+	}
+	if shortIDs[2] != key.TxShortID(txns[4]) {
+		t.Errorf("ShortIDs: short ID 2 is not the one of transaction 4")
+	}
+
+	// The pool misses transactions 2 and 5, and has unrelated ones.
+	pool := []*btcutil.Tx{testTx(100), txns[4], txns[1], testTx(101), txns[1]}
+	r, err := compactblock.Reconstruct(&key, shortIDs, prefilled, pool)
+	if err != nil {
+		t.Fatalf("Reconstruct: unexpected error: %v", err)
+	}
+	if want := []uint32{2, 5}; !reflect.DeepEqual(r.Missing(), want) {
+		t.Fatalf("Missing: got %v, want %v", r.Missing(), want)
+	}
+	if r.Complete() {
+		t.Errorf("Complete: incomplete reconstruction is complete")
+	}
+
+	if err := r.Fill([]*btcutil.Tx{txns[2]}); err != compactblock.ErrTxCount {
+		t.Errorf("Fill: got error %v, want %v", err, compactblock.ErrTxCount)
+	}
+	err = r.Fill([]*btcutil.Tx{txns[5], txns[2]})
+	if err != compactblock.ErrShortIDMismatch {
+		t.Errorf("Fill: got error %v, want %v", err,
+			compactblock.ErrShortIDMismatch)
+	}
+	if err := r.Fill([]*btcutil.Tx{txns[2], txns[5]}); err != nil {
+		t.Fatalf("Fill: unexpected error: %v", err)
+	}
+	if !r.Complete() {
+		t.Errorf("Complete: filled reconstruction is incomplete")
+	}
+	if got, want := hashes(r.Transactions()), hashes(txns); !reflect.DeepEqual(got, want) {
+		t.Errorf("Transactions: got %v, want %v", got, want)
+	}
+}
+
+// TestReconstructErrors tests invalid compact blocks are rejected.
+func TestReconstructErrors(t *testing.T) {
+	block := testBlock(3)
+	txns := block.Transactions()
+	key, _ := compactblock.NewShortIDKey(&testHeader, 0)
+
+	if _, _, err := compactblock.ShortIDs(&key, block, []uint32{1, 0}); err != compactblock.ErrIndexOrder {
+		t.Errorf("ShortIDs: got error %v, want %v", err,
+			compactblock.ErrIndexOrder)
+	}
+	if _, _, err := compactblock.ShortIDs(&key, block, []uint32{3}); err != compactblock.ErrIndexOutOfRange {
+		t.Errorf("ShortIDs: got error %v, want %v", err,
+			compactblock.ErrIndexOutOfRange)
+	}
+
+	id := key.TxShortID(txns[1])
+	tests := []struct {
+		name      string
+		shortIDs  []uint64
+		prefilled []compactblock.PrefilledTx
+		err       error
+	}{
+		{"collision", []uint64{id, id}, nil,
+			compactblock.ErrShortIDCollision},
+		{"order", []uint64{id}, []compactblock.PrefilledTx{
+			{Index: 1, Tx: txns[1]}, {Index: 0, Tx: txns[0]},
+		}, compactblock.ErrIndexOrder},
+		{"range", []uint64{id}, []compactblock.PrefilledTx{
+			{Index: 2, Tx: txns[2]},
+		}, compactblock.ErrIndexOutOfRange},
+	}
+	for _, test := range tests {
+		_, err := compactblock.Reconstruct(&key, test.shortIDs,
+			test.prefilled, txns)
+		if err != test.err {
+			t.Errorf("%s: got error %v, want %v", test.name, err, test.err)
+		}
+	}
+}
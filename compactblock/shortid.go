@@ -0,0 +1,135 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package compactblock computes the short transaction IDs of compact block
+// relay, as specified by BIP 152, and reconstructs blocks from them.
+//
+// A node relaying a compact block sends its header, a random nonce, the
+// transactions the receiver is unlikely to have, and a six byte short ID for
+// every other transaction.  Short IDs are SipHash-2-4 digests of transaction
+// hashes keyed with the SHA256 of the header and nonce, so they differ for
+// every block and an attacker cannot precompute collisions.  The receiver
+// matches the short IDs against the transactions of its mempool, and
+// requests the few it lacks from the sender.
+//
+// Transaction hashes of Omega do not commit to signatures, so short IDs are
+// computed from the hash of transactions and there is no witness variant.
+package compactblock
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/hashing"
+)
+
+const (
+	// ShortIDLen is the length in bytes of short IDs.
+	ShortIDLen = 6
+
+	// shortIDMask selects the bits of SipHash digests kept in short IDs.
+	shortIDMask = 1<<(8*ShortIDLen) - 1
+)
+
+var (
+	// ErrIndexOrder describes an error where the indexes of prefilled or
+	// requested transactions are not strictly increasing.
+	ErrIndexOrder = errors.New("transaction indexes are not increasing")
+
+	// ErrIndexOutOfRange describes an error where the index of a
+	// prefilled or requested transaction is beyond the transactions of
+	// the block.
+	ErrIndexOutOfRange = errors.New("transaction index out of range")
+)
+
+// ShortIDKey is the SipHash key of the short IDs of a compact block.
+type ShortIDKey [16]byte
+
+// NewShortIDKey returns the key of the short IDs of the compact block with
+// header and nonce: the first 16 bytes of the SHA256 of the serialized header
+// followed by the little-endian nonce.
+func NewShortIDKey(header *wire.BlockHeader, nonce uint64) (ShortIDKey, error) {
+	var buf bytes.Buffer
+	if err := header.Serialize(&buf); err != nil {
+		return ShortIDKey{}, err
+	}
+	var n [8]byte
+	binary.LittleEndian.PutUint64(n[:], nonce)
+	buf.Write(n[:])
+
+	var key ShortIDKey
+	digest := hashing.Sum256(buf.Bytes())
+	copy(key[:], digest[:])
+	return key, nil
+}
+
+// ShortID returns the short ID of the transaction hash: the SipHash-2-4 of
+// the hash, truncated to its 48 least significant bits.
+func (k *ShortIDKey) ShortID(hash *chainhash.Hash) uint64 {
+	return hashing.SipHash24((*[16]byte)(k), hash[:]) & shortIDMask
+}
+
+// TxShortID returns the short ID of tx.
+func (k *ShortIDKey) TxShortID(tx *btcutil.Tx) uint64 {
+	return k.ShortID(tx.Hash())
+}
+
+// PutShortID writes the short ID to the first ShortIDLen bytes of b in
+// little-endian, as it is serialized in compact blocks.
+func PutShortID(b []byte, id uint64) {
+	_ = b[ShortIDLen-1]
+	for i := 0; i < ShortIDLen; i++ {
+		b[i] = byte(id >> (8 * i))
+	}
+}
+
+// ReadShortID returns the short ID serialized in the first ShortIDLen bytes
+// of b.
+func ReadShortID(b []byte) uint64 {
+	_ = b[ShortIDLen-1]
+	var id uint64
+	for i := 0; i < ShortIDLen; i++ {
+		id |= uint64(b[i]) << (8 * i)
+	}
+	return id
+}
+
+// EncodeIndexes returns the differential encoding of the strictly increasing
+// transaction indexes, as prefilled and requested transactions are
+// serialized: the first index, followed by the number of indexes skipped
+// between each index and the previous one.
+func EncodeIndexes(indexes []uint32) ([]uint32, error) {
+	diffs := make([]uint32, len(indexes))
+	for i, index := range indexes {
+		if i == 0 {
+			diffs[i] = index
+			continue
+		}
+		if index <= indexes[i-1] {
+			return nil, ErrIndexOrder
+		}
+		diffs[i] = index - indexes[i-1] - 1
+	}
+	return diffs, nil
+}
+
+// DecodeIndexes returns the transaction indexes of their differential
+// encoding.  ErrIndexOutOfRange is returned when an index overflows.
+func DecodeIndexes(diffs []uint32) ([]uint32, error) {
+	indexes := make([]uint32, len(diffs))
+	var next uint64
+	for i, diff := range diffs {
+		index := next + uint64(diff)
+		if index > 0xffffffff {
+			return nil, ErrIndexOutOfRange
+		}
+		indexes[i] = uint32(index)
+		next = index + 1
+	}
+	return indexes, nil
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package compactblock_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil/compactblock"
+)
+
+// testHeader is the header of the test blocks.
+var testHeader = wire.BlockHeader{
+	PrevBlock: chainhash.Hash{1},
+	Timestamp: time.Unix(1600000000, 0),
+}
+
+// TestShortID tests short IDs depend on the header and nonce of their key and
+// fit in ShortIDLen bytes.
+func TestShortID(t *testing.T) {
+	key1, err := compactblock.NewShortIDKey(&testHeader, 1)
+	if err != nil {
+		t.Fatalf("NewShortIDKey: unexpected error: %v", err)
+	}
+	key2, _ := compactblock.NewShortIDKey(&testHeader, 2)
+	header := testHeader
+	header.PrevBlock[0] = 2
+	key3, _ := compactblock.NewShortIDKey(&header, 1)
+	if key1 == key2 || key1 == key3 {
+		t.Fatalf("NewShortIDKey: keys do not depend on header and nonce")
+	}
+	again, _ := compactblock.NewShortIDKey(&testHeader, 1)
+	if again != key1 {
+		t.Fatalf("NewShortIDKey: keys are not deterministic")
+	}
+
+	hash := chainhash.Hash{0xaa}
+	seen := make(map[uint64]bool)
+	for _, key := range []compactblock.ShortIDKey{key1, key2, key3} {
+		id := key.ShortID(&hash)
+		if id >= 1<<48 {
+			t.Errorf("ShortID: %x is longer than 6 bytes", id)
+		}
+		seen[id] = true
+	}
+	if len(seen) != 3 {
+		t.Errorf("ShortID: keys give the same short IDs")
+	}
+
+	var b [compactblock.ShortIDLen]byte
+	compactblock.PutShortID(b[:], 0x0605040302010)
+	if want := [6]byte{0x10, 0x20, 0x30, 0x40, 0x50, 0x60}; b != want {
+		t.Errorf("PutShortID: got %x, want %x", b, want)
+	}
+	if id := compactblock.ReadShortID(b[:]); id != 0x0605040302010 {
+		t.Errorf("ReadShortID: got %x", id)
+	}
+}
+
+// TestIndexes tests the differential encoding of transaction indexes.
+func TestIndexes(t *testing.T) {
+	indexes := []uint32{0, 1, 5, 6, 100}
+	diffs, err := compactblock.EncodeIndexes(indexes)
+	if err != nil {
+		t.Fatalf("EncodeIndexes: unexpected error: %v", err)
+	}
+	if want := []uint32{0, 0, 3, 0, 93}; !reflect.DeepEqual(diffs, want) {
+		t.Errorf("EncodeIndexes: got %v, want %v", diffs, want)
+	}
+	decoded, err := compactblock.DecodeIndexes(diffs)
+	if err != nil || !reflect.DeepEqual(decoded, indexes) {
+		t.Errorf("DecodeIndexes: got %v, %v, want %v", decoded, err, indexes)
+	}
+
+	if _, err := compactblock.EncodeIndexes([]uint32{3, 3}); err != compactblock.ErrIndexOrder {
+		t.Errorf("EncodeIndexes: got error %v, want %v", err,
+			compactblock.ErrIndexOrder)
+	}
+	_, err = compactblock.DecodeIndexes([]uint32{0xfffffffe, 0, 0})
+	if err != compactblock.ErrIndexOutOfRange {
+		t.Errorf("DecodeIndexes: got error %v, want %v", err,
+			compactblock.ErrIndexOutOfRange)
+	}
+}
@@ -0,0 +1,241 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+//go:build compat
+
+package compat_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	upchaincfg "github.com/btcsuite/btcd/chaincfg"
+	upstream "github.com/btcsuite/btcutil"
+	upbase58 "github.com/btcsuite/btcutil/base58"
+	upbech32 "github.com/btcsuite/btcutil/bech32"
+	uphdkeychain "github.com/btcsuite/btcutil/hdkeychain"
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/base58"
+	"github.com/zeusyf/btcutil/bech32"
+	"github.com/zeusyf/btcutil/hdkeychain"
+)
+
+// upstreamParams returns upstream network parameters carrying the version
+// bytes of the passed parameters of this fork, so both implementations
+// encode keys for the same network.
+func upstreamParams(net *chaincfg.Params) *upchaincfg.Params {
+	return &upchaincfg.Params{
+		Name:             net.Name,
+		PubKeyHashAddrID: net.PubKeyHashAddrID,
+		ScriptHashAddrID: net.ScriptHashAddrID,
+		PrivateKeyID:     net.PrivateKeyID,
+		HDPrivateKeyID:   net.HDPrivateKeyID,
+		HDPublicKeyID:    net.HDPublicKeyID,
+	}
+}
+
+// diverged reports a divergence between this fork and upstream for the
+// passed input.
+func diverged(t *testing.T, what string, input interface{}, fork, up interface{}) {
+	t.Helper()
+	t.Errorf("%s diverges from upstream for input %q:\n  fork:     %v\n"+
+		"  upstream: %v", what, input, fork, up)
+}
+
+// FuzzBase58 compares base58 and base58check encoding and decoding.
+func FuzzBase58(f *testing.F) {
+	f.Add([]byte{}, "")
+	f.Add([]byte{0, 0, 1, 2, 3}, "1111")
+	f.Add([]byte("hello world"), "3mJr7AoUXx2Wqd")
+	f.Add([]byte{0xff}, "1BitcoinEaterAddressDontSendf59kuE")
+
+	f.Fuzz(func(t *testing.T, data []byte, s string) {
+		if got, want := base58.Encode(data), upbase58.Encode(data); got != want {
+			diverged(t, "base58.Encode", data, got, want)
+		}
+		if got, want := base58.Decode(s), upbase58.Decode(s); !bytes.Equal(got, want) {
+			diverged(t, "base58.Decode", s, got, want)
+		}
+
+		if len(data) > 0 {
+			version, payload := data[0], data[1:]
+			got := base58.CheckEncode(payload, version)
+			want := upbase58.CheckEncode(payload, version)
+			if got != want {
+				diverged(t, "base58.CheckEncode", data, got, want)
+			}
+		}
+
+		got, gotVer, gotErr := base58.CheckDecode(s)
+		want, wantVer, wantErr := upbase58.CheckDecode(s)
+		if (gotErr == nil) != (wantErr == nil) {
+			diverged(t, "base58.CheckDecode error", s, gotErr, wantErr)
+		} else if gotErr == nil && (gotVer != wantVer || !bytes.Equal(got, want)) {
+			diverged(t, "base58.CheckDecode", s,
+				hex.EncodeToString(append([]byte{gotVer}, got...)),
+				hex.EncodeToString(append([]byte{wantVer}, want...)))
+		}
+	})
+}
+
+// FuzzBech32 compares bech32 encoding and decoding.
+func FuzzBech32(f *testing.F) {
+	f.Add("a12uel5l", "bc", []byte{0, 14, 20, 15})
+	f.Add("abcdef1qpzry9x8gf2tvdw0s3jn54khce6mua7lmqqqxw", "tb", []byte{})
+	f.Add("split1checkupstagehandshakeupstreamerranterredcaperred2y9e3w",
+		"split", []byte{31, 31})
+
+	f.Fuzz(func(t *testing.T, s, hrp string, data []byte) {
+		gotHRP, got, gotErr := bech32.Decode(s)
+		wantHRP, want, wantErr := upbech32.Decode(s)
+		if (gotErr == nil) != (wantErr == nil) {
+			diverged(t, "bech32.Decode error", s, gotErr, wantErr)
+		} else if gotErr == nil && (gotHRP != wantHRP || !bytes.Equal(got, want)) {
+			diverged(t, "bech32.Decode", s, gotHRP+" "+hex.EncodeToString(got),
+				wantHRP+" "+hex.EncodeToString(want))
+		}
+
+		// Encoding takes 5 bit groups.
+		groups := make([]byte, len(data))
+		for i, b := range data {
+			groups[i] = b & 0x1f
+		}
+		gotStr, gotErr := bech32.Encode(hrp, groups)
+		wantStr, wantErr := upbech32.Encode(hrp, groups)
+		if (gotErr == nil) != (wantErr == nil) || gotStr != wantStr {
+			diverged(t, "bech32.Encode", hrp+" "+hex.EncodeToString(groups),
+				gotStr, wantStr)
+		}
+
+		gotBits, gotErr := bech32.ConvertBits(data, 8, 5, true)
+		wantBits, wantErr := upbech32.ConvertBits(data, 8, 5, true)
+		if (gotErr == nil) != (wantErr == nil) || !bytes.Equal(gotBits, wantBits) {
+			diverged(t, "bech32.ConvertBits", data, gotBits, wantBits)
+		}
+	})
+}
+
+// FuzzWIF compares decoding and re-encoding of wallet import format strings.
+func FuzzWIF(f *testing.F) {
+	f.Add("5HueCGU8rMjxEXxiPuD5BDku4MkFqeZyd4dZ1jvhTVqvbTLvyTJ")
+	f.Add("KwdMAjGmerYanjeui5SHS7JkmpZvVipYvB2LJGU1ZxJwYvP98617")
+	f.Add("cV1Y7ARUr9Yx7BR55nTdnR7ZXNJphZtCCMBTEZBJe1hXt2kB684q")
+
+	f.Fuzz(func(t *testing.T, s string) {
+		got, gotErr := btcutil.DecodeWIF(s)
+		want, wantErr := upstream.DecodeWIF(s)
+		if (gotErr == nil) != (wantErr == nil) {
+			diverged(t, "DecodeWIF error", s, gotErr, wantErr)
+			return
+		}
+		if gotErr != nil {
+			return
+		}
+
+		if !bytes.Equal(got.PrivKey.Serialize(), want.PrivKey.Serialize()) ||
+			got.CompressPubKey != want.CompressPubKey {
+			diverged(t, "DecodeWIF", s, got, want)
+		}
+		if got.String() != want.String() {
+			diverged(t, "WIF.String", s, got.String(), want.String())
+		}
+		if !bytes.Equal(got.SerializePubKey(), want.SerializePubKey()) {
+			diverged(t, "WIF.SerializePubKey", s, got.SerializePubKey(),
+				want.SerializePubKey())
+		}
+	})
+}
+
+// FuzzBIP32 compares master key generation, child derivation, and extended
+// key serialization.
+func FuzzBIP32(f *testing.F) {
+	f.Add([]byte{0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08,
+		0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f}, uint32(0), uint32(1))
+	f.Add(bytes.Repeat([]byte{0xff}, 64), uint32(0x80000000), uint32(2))
+
+	net := &chaincfg.MainNetParams
+	upNet := upstreamParams(net)
+
+	f.Fuzz(func(t *testing.T, seed []byte, i, j uint32) {
+		got, gotErr := hdkeychain.NewMaster(seed, net)
+		want, wantErr := uphdkeychain.NewMaster(seed, upNet)
+		if (gotErr == nil) != (wantErr == nil) {
+			diverged(t, "NewMaster error", seed, gotErr, wantErr)
+			return
+		}
+		if gotErr != nil {
+			return
+		}
+
+		for _, index := range []uint32{i, j} {
+			if got.String() != want.String() {
+				diverged(t, "ExtendedKey.String", seed, got.String(),
+					want.String())
+				return
+			}
+
+			gotPub, err1 := got.Neuter()
+			wantPub, err2 := want.Neuter()
+			if (err1 == nil) != (err2 == nil) ||
+				(err1 == nil && gotPub.String() != wantPub.String()) {
+				diverged(t, "ExtendedKey.Neuter", seed, gotPub, wantPub)
+			}
+
+			// Public derivation must match private derivation on
+			// both sides for non-hardened children.
+			if err1 == nil && err2 == nil && index < hdkeychain.HardenedKeyStart {
+				gotChild, err1 := gotPub.Child(index)
+				wantChild, err2 := wantPub.Child(index)
+				if (err1 == nil) != (err2 == nil) ||
+					(err1 == nil && gotChild.String() != wantChild.String()) {
+					diverged(t, "public ExtendedKey.Child", seed,
+						gotChild, wantChild)
+				}
+			}
+
+			got, gotErr = got.Child(index)
+			want, wantErr = want.Child(index)
+			if (gotErr == nil) != (wantErr == nil) {
+				diverged(t, "ExtendedKey.Child error", seed, gotErr,
+					wantErr)
+				return
+			}
+			if gotErr != nil {
+				return
+			}
+		}
+
+		// Decoding the serialized key must round trip on both sides.
+		s := got.String()
+		decoded, gotErr := hdkeychain.NewKeyFromString(s)
+		upDecoded, wantErr := uphdkeychain.NewKeyFromString(s)
+		if (gotErr == nil) != (wantErr == nil) ||
+			(gotErr == nil && decoded.String() != upDecoded.String()) {
+			diverged(t, "NewKeyFromString", s, gotErr, wantErr)
+		}
+	})
+}
+
+// FuzzExtendedKeyString compares decoding of arbitrary extended key strings.
+func FuzzExtendedKeyString(f *testing.F) {
+	f.Add("xprv9s21ZrQH143K3QTDL4LXw2F7HEK3wJUD2nW2nRk4stbPy6cq3jPPqjiCh" +
+		"kVvvNKmPGJxWUtg6LnF5kejMRNNU3TGtRBeJgk33yuGBxrMPHi")
+	f.Add("xpub661MyMwAqRbcFtXgS5sYJABqqG9YLmC4Q1Rdap9gSE8NqtwybGhePY2gZ" +
+		"29ESFjqJoCu1Rupje8YtGqsefD265TMg7usUDFdp6W1EGMcet8")
+
+	f.Fuzz(func(t *testing.T, s string) {
+		got, gotErr := hdkeychain.NewKeyFromString(s)
+		want, wantErr := uphdkeychain.NewKeyFromString(s)
+		if (gotErr == nil) != (wantErr == nil) {
+			diverged(t, "NewKeyFromString error", s, gotErr, wantErr)
+			return
+		}
+		if gotErr == nil && got.String() != want.String() {
+			diverged(t, "NewKeyFromString", s, got.String(),
+				want.String())
+		}
+	})
+}
@@ -0,0 +1,28 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+/*
+Package compat holds the differential tests which check that the encodings this
+fork shares with upstream btcutil stay byte for byte compatible with it.
+
+The fuzz targets feed the same inputs to this module's base58, bech32, WIF, and
+BIP0032 implementations and to those of github.com/btcsuite/btcutil, and fail
+on any divergence: one implementation accepting an input the other rejects, or
+both accepting it but producing different results.  Network specific version
+bytes are taken from this fork's parameters and handed to upstream through
+custom parameters, so only the encodings themselves are compared.
+
+The psbt packages are not compared, since the transactions of upstream can
+not carry the tokens of Omega.
+
+The tests import upstream btcutil, which the rest of the module does not
+depend on, so they are only built with the compat build tag:
+
+	go test -tags compat ./compat
+	go test -tags compat -fuzz FuzzBase58 ./compat
+
+Divergences are reported with the input that triggered them, and the fuzzer
+saves the input under testdata/fuzz so it is replayed by later runs.
+*/
+package compat
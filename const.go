@@ -6,12 +6,38 @@
 package btcutil
 
 const (
+	// OMCDecimals is the number of decimals of an amount in OMC, so that
+	// one Hao is 10^-OMCDecimals OMC.  HaoPerOMC must be 10^OMCDecimals.
+	OMCDecimals = 8
+
+	// HaoPerOMC is the number of hao in one OMC.
+	HaoPerOMC = 1e8
+
+	// HaoPerBitcoin is the number of hao in one bitcoin (1 OMC).  It is
+	// the same as HaoPerOMC.
+	HaoPerBitcoin = HaoPerOMC
+
 	// HaoPerBitcent is the number of hao in one bitcoin cent.
-	HaoPerBitcent = 1e6
+	HaoPerBitcent = HaoPerOMC / 100
 
-	// HaoPerBitcoin is the number of hao in one bitcoin (1 OMC).
-	HaoPerBitcoin = 1e8
+	// HaoPerBit is the number of hao in one bit, a millionth of an OMC.
+	HaoPerBit = HaoPerOMC / 1e6
+
+	// MaxOMC is the total supply of OMC.
+	MaxOMC = 430e6
 
 	// MaxHao is the maximum transaction amount allowed in hao.
-	MaxHao = 430e6 * HaoPerBitcoin
+	MaxHao = MaxOMC * HaoPerOMC
+)
+
+// Compile-time assertions that the constants above and the units of Amount
+// agree.  Each indexes a one element array with the difference of two values
+// which must be equal, so a fork changing one constant without the others
+// fails to build.
+var (
+	_ = [1]struct{}{}[-AmountHao-OMCDecimals]
+	_ = [1]struct{}{}[AmountMicroOMC-AmountOMC+6]
+	_ = [1]struct{}{}[HaoPerBit*1e6-HaoPerOMC]
+	_ = [1]struct{}{}[HaoPerBitcent*100-HaoPerOMC]
+	_ = [1]struct{}{}[MaxHao/HaoPerOMC-MaxOMC]
 )
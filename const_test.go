@@ -0,0 +1,32 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package btcutil_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/zeusyf/btcutil"
+)
+
+// TestConstants ensures the number of Hao per OMC matches the decimals of OMC,
+// which the compile-time assertions can't express, and amounts convert with
+// them.
+func TestConstants(t *testing.T) {
+	if math.Pow10(btcutil.OMCDecimals) != btcutil.HaoPerOMC {
+		t.Fatalf("HaoPerOMC %v is not 10^%d", float64(btcutil.HaoPerOMC),
+			btcutil.OMCDecimals)
+	}
+	if got := btcutil.Amount(btcutil.HaoPerOMC).ToOMC(); got != 1 {
+		t.Errorf("ToOMC: got %v, want 1", got)
+	}
+	if got := btcutil.Amount(btcutil.HaoPerBit).ToUnit(btcutil.AmountMicroOMC); got != 1 {
+		t.Errorf("ToUnit: got %v, want 1", got)
+	}
+	if got, err := btcutil.NewAmount(btcutil.MaxOMC, 0); err != nil || got != btcutil.MaxHao {
+		t.Errorf("NewAmount: got %v, %v, want %v", got, err,
+			btcutil.Amount(btcutil.MaxHao))
+	}
+}
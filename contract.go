@@ -0,0 +1,71 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package btcutil
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcd/wire"
+	"golang.org/x/crypto/ripemd160"
+)
+
+// ErrNoContractCreation describes an error where a contract address is
+// requested for a transaction which does not create a contract.
+var ErrNoContractCreation = errors.New("transaction does not create a contract")
+
+// IsContractCreation returns whether the passed public key script creates a
+// new contract.  A creation script pays to a contract address, as identified
+// by its leading network identifier byte, whose hash is all zero; the
+// remainder of the script carries the contract code.
+func IsContractCreation(pkScript []byte) bool {
+	if len(pkScript) < 1+ripemd160.Size ||
+		!chaincfg.IsContractAddrID(pkScript[0]) {
+		return false
+	}
+	for _, b := range pkScript[1 : 1+ripemd160.Size] {
+		if b != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// NewAddressContractFromOutPoint returns the address of the contract created
+// by the passed output.  The contract hash is the Hash160 of the transaction
+// hash followed by the little-endian output index, so every creation output
+// yields a distinct address which is known as soon as the creating
+// transaction is signed.
+func NewAddressContractFromOutPoint(op *wire.OutPoint, net *chaincfg.Params) (*AddressContract, error) {
+	var buf [chainhash.HashSize + 4]byte
+	copy(buf[:], op.Hash[:])
+	binary.LittleEndian.PutUint32(buf[chainhash.HashSize:], op.Index)
+	return NewAddressContract(Hash160(buf[:]), net)
+}
+
+// ContractAddresses returns the addresses of all contracts created by the
+// passed transaction, in output order.  ErrNoContractCreation is returned
+// when the transaction has no creation outputs.
+func ContractAddresses(tx *Tx, net *chaincfg.Params) ([]*AddressContract, error) {
+	var addrs []*AddressContract
+	for i, txOut := range tx.MsgTx().TxOut {
+		if txOut.IsSeparator() || !IsContractCreation(txOut.PkScript) {
+			continue
+		}
+
+		op := wire.OutPoint{Hash: *tx.Hash(), Index: uint32(i)}
+		addr, err := NewAddressContractFromOutPoint(&op, net)
+		if err != nil {
+			return nil, err
+		}
+		addrs = append(addrs, addr)
+	}
+	if len(addrs) == 0 {
+		return nil, ErrNoContractCreation
+	}
+	return addrs, nil
+}
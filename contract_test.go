@@ -0,0 +1,119 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package btcutil_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/omega/token"
+)
+
+// TestContractAddresses ensures contract addresses are derived from the
+// creation outputs of a transaction and round trip through DecodeAddress.
+func TestContractAddresses(t *testing.T) {
+	net := &chaincfg.MainNetParams
+	payScript := append([]byte{net.PubKeyHashAddrID},
+		bytes.Repeat([]byte{0x01}, 20)...)
+	createScript := append([]byte{net.ContractAddrID},
+		make([]byte, 20)...)
+	createScript = append(createScript, 0xde, 0xad, 0xbe, 0xef)
+
+	output := func(pkScript []byte) *wire.TxOut {
+		return &wire.TxOut{
+			Token: token.Token{
+				TokenType: 0,
+				Value:     &token.NumeralVal{Val: 0},
+			},
+			PkScript: pkScript,
+		}
+	}
+
+	msgTx := wire.NewMsgTx(wire.TxVersion)
+	msgTx.AddTxIn(&wire.TxIn{Sequence: wire.MaxTxInSequenceNum})
+	msgTx.AddTxOut(output(payScript))
+	msgTx.AddTxOut(output(createScript))
+	tx := btcutil.NewTx(msgTx)
+
+	addrs, err := btcutil.ContractAddresses(tx, net)
+	if err != nil {
+		t.Fatalf("ContractAddresses: unexpected error: %v", err)
+	}
+	if len(addrs) != 1 {
+		t.Fatalf("ContractAddresses: got %d addresses, want 1", len(addrs))
+	}
+
+	op := wire.OutPoint{Hash: *tx.Hash(), Index: 1}
+	want, err := btcutil.NewAddressContractFromOutPoint(&op, net)
+	if err != nil {
+		t.Fatalf("NewAddressContractFromOutPoint: unexpected error: %v", err)
+	}
+	if addrs[0].EncodeAddress() != want.EncodeAddress() {
+		t.Fatalf("ContractAddresses: got %s, want %s",
+			addrs[0].EncodeAddress(), want.EncodeAddress())
+	}
+	if !addrs[0].IsForNet(net) || addrs[0].Version() != net.ContractAddrID {
+		t.Fatalf("ContractAddresses: address is not for %s", net.Name)
+	}
+
+	// Another output of the same transaction yields another contract.
+	op.Index = 2
+	other, _ := btcutil.NewAddressContractFromOutPoint(&op, net)
+	if other.EncodeAddress() == want.EncodeAddress() {
+		t.Fatalf("NewAddressContractFromOutPoint: outputs 1 and 2 " +
+			"yield the same address")
+	}
+
+	decoded, err := btcutil.DecodeAddress(want.EncodeAddress(), net)
+	if err != nil {
+		t.Fatalf("DecodeAddress: unexpected error: %v", err)
+	}
+	contract, ok := decoded.(*btcutil.AddressContract)
+	if !ok {
+		t.Fatalf("DecodeAddress: got %T, want *btcutil.AddressContract",
+			decoded)
+	}
+	if *contract.Hash160() != *want.Hash160() {
+		t.Fatalf("DecodeAddress: got hash %x, want %x",
+			contract.Hash160(), want.Hash160())
+	}
+
+	noCreate := wire.NewMsgTx(wire.TxVersion)
+	noCreate.AddTxOut(output(payScript))
+	_, err = btcutil.ContractAddresses(btcutil.NewTx(noCreate), net)
+	if err != btcutil.ErrNoContractCreation {
+		t.Fatalf("ContractAddresses: got error %v, want %v", err,
+			btcutil.ErrNoContractCreation)
+	}
+}
+
+// TestIsContractCreation ensures only scripts paying to the all zero
+// contract hash are treated as contract creations.
+func TestIsContractCreation(t *testing.T) {
+	id := chaincfg.MainNetParams.ContractAddrID
+	call := append([]byte{id}, bytes.Repeat([]byte{0x01}, 20)...)
+
+	tests := []struct {
+		name     string
+		pkScript []byte
+		want     bool
+	}{
+		{"empty", nil, false},
+		{"short", []byte{id, 0x00}, false},
+		{"create", append([]byte{id}, make([]byte, 21)...), true},
+		{"create without code", append([]byte{id}, make([]byte, 20)...), true},
+		{"call", call, false},
+		{"wrong id", append([]byte{chaincfg.MainNetParams.PubKeyHashAddrID},
+			make([]byte, 20)...), false},
+	}
+	for _, test := range tests {
+		if got := btcutil.IsContractCreation(test.pkScript); got != test.want {
+			t.Errorf("%s: got %v, want %v", test.name, got, test.want)
+		}
+	}
+}
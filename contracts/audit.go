@@ -0,0 +1,217 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package contracts
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcd/txscript"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/locktime"
+	"github.com/zeusyf/btcutil/scriptclass"
+	"github.com/zeusyf/omega/token"
+)
+
+var (
+	// ErrNotHTLC describes an error where a redeem script is not the one of
+	// a hash time locked contract built by NewHTLC.
+	ErrNotHTLC = errors.New("script is not a hash time locked contract")
+
+	// ErrContractNotFunded describes an error where a transaction has no
+	// output paying the base token to the address of a contract.
+	ErrContractNotFunded = errors.New("transaction does not fund the " +
+		"contract")
+
+	// ErrWrongRecipient describes an error where an audited contract does
+	// not pay the expected recipient.
+	ErrWrongRecipient = errors.New("contract pays another recipient")
+
+	// ErrWrongPaymentHash describes an error where an audited contract is
+	// not claimed by the preimage of the expected payment hash.
+	ErrWrongPaymentHash = errors.New("contract has another payment hash")
+
+	// ErrInsufficientAmount describes an error where an audited contract
+	// holds less than the expected amount.
+	ErrInsufficientAmount = errors.New("contract holds less than expected")
+
+	// ErrLockTimeTooSoon describes an error where the refund branch of an
+	// audited contract unlocks before the expected lock time, or has a
+	// lock time of another kind.
+	ErrLockTimeTooSoon = errors.New("contract refund unlocks too soon")
+
+	// ErrPreimageNotFound describes an error where a transaction spending
+	// a hash time locked contract does not reveal its preimage.
+	ErrPreimageNotFound = errors.New("transaction does not reveal the " +
+		"preimage")
+)
+
+// HTLCTerms are the terms of a hash time locked contract, as parsed from its
+// redeem script.
+type HTLCTerms struct {
+	// Recipient is the key claiming the contract with the preimage.
+	Recipient *btcec.PublicKey
+
+	// Sender is the key refunded after the lock time.
+	Sender *btcec.PublicKey
+
+	// PaymentHash is the SHA256 of the preimage claiming the contract.
+	PaymentHash [sha256.Size]byte
+
+	// LockTime is the lock time of the refund branch.
+	LockTime locktime.LockTime
+}
+
+// ParseHTLC returns the terms of the hash time locked contract with the passed
+// redeem script.  ErrNotHTLC is returned when the script is not exactly the
+// one NewHTLC builds for these terms.
+func ParseHTLC(redeemScript []byte) (*HTLCTerms, error) {
+	ops, err := scriptclass.Parse(redeemScript)
+	if err != nil || len(ops) != 15 {
+		return nil, ErrNotHTLC
+	}
+	recipient, err := btcec.ParsePubKey(ops[7].Data, btcec.S256())
+	if err != nil {
+		return nil, ErrNotHTLC
+	}
+	sender, err := btcec.ParsePubKey(ops[12].Data, btcec.S256())
+	if err != nil {
+		return nil, ErrNotHTLC
+	}
+	terms := &HTLCTerms{Recipient: recipient, Sender: sender}
+	if len(ops[5].Data) != len(terms.PaymentHash) {
+		return nil, ErrNotHTLC
+	}
+	copy(terms.PaymentHash[:], ops[5].Data)
+	lockTime, ok := parseLockTime(ops[9])
+	if !ok || lockTime == 0 {
+		return nil, ErrNotHTLC
+	}
+	terms.LockTime = lockTime
+
+	// Rebuilding the script from the terms checks the opcodes around the
+	// pushes, and that every push is minimal.
+	htlc, err := NewHTLC(recipient, sender, terms.PaymentHash, lockTime,
+		&chaincfg.MainNetParams)
+	if err != nil || !bytes.Equal(htlc.RedeemScript, redeemScript) {
+		return nil, ErrNotHTLC
+	}
+	return terms, nil
+}
+
+// parseLockTime returns the lock time pushed by op, which is a small integer
+// opcode or a push of at most five bytes.
+func parseLockTime(op scriptclass.Opcode) (locktime.LockTime, bool) {
+	if op.Op >= scriptclass.OP_1 && op.Op <= scriptclass.OP_16 {
+		return locktime.LockTime(op.Op - (scriptclass.OP_1 - 1)), true
+	}
+	if len(op.Data) == 0 || len(op.Data) > 5 ||
+		op.Data[len(op.Data)-1]&0x80 != 0 {
+		return 0, false
+	}
+	var n uint64
+	for i := len(op.Data) - 1; i >= 0; i-- {
+		n = n<<8 | uint64(op.Data[i])
+	}
+	if n > uint64(^uint32(0)) {
+		return 0, false
+	}
+	return locktime.LockTime(n), true
+}
+
+// HTLCAudit is what a party to an atomic swap learns auditing the contract
+// funded by its counterparty.
+type HTLCAudit struct {
+	HTLCTerms
+
+	// Address is the pay-to-script-hash address of the contract.
+	Address *btcutil.AddressScriptHash
+
+	// OutPoint is the output funding the contract.
+	OutPoint wire.OutPoint
+
+	// Amount is the amount of OMC held by the contract.
+	Amount btcutil.Amount
+}
+
+// AuditHTLC returns the terms and funds of the hash time locked contract with
+// the passed redeem script which tx funds.  The first output of tx paying the
+// base token to the address of the contract holds the funds.  ErrNotHTLC is
+// returned when the redeem script is not one of a hash time locked contract,
+// and ErrContractNotFunded when tx does not pay it.
+//
+// The audit only tells what the contract is.  Whether it is the one agreed on
+// is checked by Verify.
+func AuditHTLC(tx *wire.MsgTx, redeemScript []byte, net *chaincfg.Params) (*HTLCAudit, error) {
+	terms, err := ParseHTLC(redeemScript)
+	if err != nil {
+		return nil, err
+	}
+	addr, err := btcutil.NewAddressScriptHash(redeemScript, net)
+	if err != nil {
+		return nil, err
+	}
+	pkScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		return nil, err
+	}
+	for i, txOut := range tx.TxOut {
+		value, ok := txOut.Token.Value.(*token.NumeralVal)
+		if txOut.Token.TokenType != 0 || !ok || value.Val <= 0 ||
+			!bytes.Equal(txOut.PkScript, pkScript) {
+			continue
+		}
+		return &HTLCAudit{
+			HTLCTerms: *terms,
+			Address:   addr,
+			OutPoint:  wire.OutPoint{Hash: tx.TxHash(), Index: uint32(i)},
+			Amount:    btcutil.Amount(value.Val),
+		}, nil
+	}
+	return nil, ErrContractNotFunded
+}
+
+// Verify checks the audited contract pays at least amount to recipient
+// against the preimage of paymentHash, and can't be refunded before
+// minLockTime.  The lock time of the contract must be of the same kind as
+// minLockTime, a height or a time.  It returns ErrWrongRecipient,
+// ErrInsufficientAmount, ErrWrongPaymentHash or ErrLockTimeTooSoon for the
+// first check failing.
+func (a *HTLCAudit) Verify(recipient *btcec.PublicKey, amount btcutil.Amount,
+	paymentHash [sha256.Size]byte, minLockTime locktime.LockTime) error {
+	switch {
+	case !a.Recipient.IsEqual(recipient):
+		return ErrWrongRecipient
+	case a.Amount < amount:
+		return ErrInsufficientAmount
+	case a.PaymentHash != paymentHash:
+		return ErrWrongPaymentHash
+	case a.LockTime.IsHeight() != minLockTime.IsHeight() ||
+		a.LockTime < minLockTime:
+		return ErrLockTimeTooSoon
+	}
+	return nil
+}
+
+// ExtractTxPreimage returns the preimage of paymentHash revealed by an input
+// of tx, the transaction claiming a hash time locked contract.
+// ErrPreimageNotFound is returned when no signature script of tx pushes the
+// preimage.
+func ExtractTxPreimage(tx *wire.MsgTx, paymentHash [sha256.Size]byte) ([]byte, error) {
+	for _, txIn := range tx.TxIn {
+		if int(txIn.SignatureIndex) >= len(tx.SignatureScripts) {
+			continue
+		}
+		sigScript := tx.SignatureScripts[txIn.SignatureIndex]
+		if preimage, ok := ExtractPreimage(sigScript, paymentHash); ok {
+			return preimage, nil
+		}
+	}
+	return nil, ErrPreimageNotFound
+}
@@ -0,0 +1,147 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package contracts_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcd/txscript"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/contracts"
+	"github.com/zeusyf/btcutil/locktime"
+	"github.com/zeusyf/omega/token"
+)
+
+// TestAuditHTLC ensures the contract of a counterparty is parsed back from
+// its redeem script and funding transaction, and checked against the agreed
+// terms.
+func TestAuditHTLC(t *testing.T) {
+	keys := testPubKeys(3)
+	preimage := bytes.Repeat([]byte{0x42}, contracts.PreimageSize)
+	paymentHash := sha256.Sum256(preimage)
+	net := &chaincfg.MainNetParams
+
+	htlc, err := contracts.NewHTLC(keys[0], keys[1], paymentHash, 500000, net)
+	if err != nil {
+		t.Fatalf("NewHTLC: unexpected error: %v", err)
+	}
+	terms, err := contracts.ParseHTLC(htlc.RedeemScript)
+	if err != nil || !terms.Recipient.IsEqual(keys[0]) ||
+		!terms.Sender.IsEqual(keys[1]) || terms.PaymentHash != paymentHash ||
+		terms.LockTime != 500000 {
+		t.Fatalf("ParseHTLC: got %+v, %v", terms, err)
+	}
+
+	pkScript, err := txscript.PayToAddrScript(htlc.Address)
+	if err != nil {
+		t.Fatalf("PayToAddrScript: unexpected error: %v", err)
+	}
+	output := func(tokenType uint64, value int64, pkScript []byte) *wire.TxOut {
+		return &wire.TxOut{
+			Token: token.Token{
+				TokenType: tokenType,
+				Value:     &token.NumeralVal{Val: value},
+			},
+			PkScript: pkScript,
+		}
+	}
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(&wire.TxIn{SignatureIndex: 0xffffffff})
+	tx.AddTxOut(output(0, 5000, []byte{0x00}))
+	tx.AddTxOut(output(4, 7000, pkScript))
+	tx.AddTxOut(output(0, 10000, pkScript))
+
+	audit, err := contracts.AuditHTLC(tx, htlc.RedeemScript, net)
+	if err != nil {
+		t.Fatalf("AuditHTLC: unexpected error: %v", err)
+	}
+	if audit.OutPoint.Index != 2 || audit.OutPoint.Hash != tx.TxHash() ||
+		audit.Amount != 10000 || audit.LockTime != 500000 ||
+		audit.Address.EncodeAddress() != htlc.Address.EncodeAddress() {
+		t.Errorf("AuditHTLC: got %+v", audit)
+	}
+
+	verify := []struct {
+		amount      btcutil.Amount
+		recipient   int
+		paymentHash [sha256.Size]byte
+		minLockTime locktime.LockTime
+		err         error
+	}{
+		{10000, 0, paymentHash, 500000, nil},
+		{9000, 0, paymentHash, 400000, nil},
+		{10000, 2, paymentHash, 500000, contracts.ErrWrongRecipient},
+		{10001, 0, paymentHash, 500000, contracts.ErrInsufficientAmount},
+		{10000, 0, [sha256.Size]byte{}, 500000, contracts.ErrWrongPaymentHash},
+		{10000, 0, paymentHash, 500001, contracts.ErrLockTimeTooSoon},
+		{10000, 0, paymentHash, 1600000000, contracts.ErrLockTimeTooSoon},
+	}
+	for i, test := range verify {
+		err := audit.Verify(keys[test.recipient], test.amount,
+			test.paymentHash, test.minLockTime)
+		if err != test.err {
+			t.Errorf("%d: Verify: got error %v, want %v", i, err, test.err)
+		}
+	}
+
+	other := wire.NewMsgTx(wire.TxVersion)
+	other.AddTxOut(output(0, 10000, []byte{0x00}))
+	if _, err := contracts.AuditHTLC(other, htlc.RedeemScript, net); err != contracts.ErrContractNotFunded {
+		t.Errorf("AuditHTLC: got error %v, want %v", err,
+			contracts.ErrContractNotFunded)
+	}
+
+	// Scripts differing from the template, even by a non-minimal push,
+	// are rejected.
+	escrow, _ := contracts.NewEscrow(keys[0], keys[1], keys[2], 500000, net)
+	nonMinimal := append([]byte(nil), htlc.RedeemScript[:2]...)
+	nonMinimal = append(nonMinimal, 0x4c, 0x01, 0x20)
+	nonMinimal = append(nonMinimal, htlc.RedeemScript[4:]...)
+	trailing := append(append([]byte(nil), htlc.RedeemScript...), 0x75)
+	for i, script := range [][]byte{nil, escrow.RedeemScript, nonMinimal,
+		trailing, htlc.RedeemScript[:len(htlc.RedeemScript)-1]} {
+		if _, err := contracts.ParseHTLC(script); err != contracts.ErrNotHTLC {
+			t.Errorf("%d: ParseHTLC: got error %v, want %v", i, err,
+				contracts.ErrNotHTLC)
+		}
+	}
+}
+
+// TestExtractTxPreimage ensures the preimage is found in the signature
+// scripts of the transaction claiming a contract.
+func TestExtractTxPreimage(t *testing.T) {
+	keys := testPubKeys(2)
+	preimage := bytes.Repeat([]byte{0x42}, contracts.PreimageSize)
+	paymentHash := sha256.Sum256(preimage)
+	htlc, err := contracts.NewHTLC(keys[0], keys[1], paymentHash, 500000,
+		&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("NewHTLC: unexpected error: %v", err)
+	}
+	sigScript, err := htlc.Claim.SignatureScript(htlc.RedeemScript,
+		[][]byte{{0x30, 0x01}}, preimage)
+	if err != nil {
+		t.Fatalf("SignatureScript: unexpected error: %v", err)
+	}
+
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(&wire.TxIn{SignatureIndex: 0xffffffff})
+	tx.AddTxIn(&wire.TxIn{SignatureIndex: 1})
+	tx.SignatureScripts = [][]byte{{0x00}, sigScript}
+	got, err := contracts.ExtractTxPreimage(tx, paymentHash)
+	if err != nil || !bytes.Equal(got, preimage) {
+		t.Errorf("ExtractTxPreimage: got %x, %v", got, err)
+	}
+
+	tx.SignatureScripts = tx.SignatureScripts[:1]
+	if _, err := contracts.ExtractTxPreimage(tx, paymentHash); err != contracts.ErrPreimageNotFound {
+		t.Errorf("ExtractTxPreimage: got error %v, want %v", err,
+			contracts.ErrPreimageNotFound)
+	}
+}
@@ -0,0 +1,188 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package contracts builds the redeem scripts of contracts whose outputs are
+// spent along one of several branches:
+//
+//   - hash time locked contracts, claimed by a recipient revealing the
+//     preimage of a hash, or refunded to the sender after a lock time.  They
+//     are the building block of atomic swaps.
+//   - 2-of-3 escrows between a buyer, a seller and an arbiter, refunded to the
+//     buyer should no two of them settle it before a lock time.
+//   - vaults, spent by a hot key some time after they are funded, or at once
+//     by a recovery key, which lets the owner of the recovery key take back
+//     funds unvaulted by someone who stole the hot key.
+//
+// Contracts are funded by paying their pay-to-script-hash address.  Each
+// branch of a contract is described by a Branch, which tells the spender the
+// keys which must sign, the lock time and sequence number the spending
+// transaction needs, and builds the signature script from the signatures.
+//
+// In an atomic swap, each party audits the contract funded by the other with
+// AuditHTLC before funding or claiming its own, and the party which did not
+// choose the preimage learns it from the transaction claiming its contract
+// with ExtractTxPreimage.
+package contracts
+
+import (
+	"errors"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/locktime"
+	"github.com/zeusyf/btcutil/scriptclass"
+)
+
+var (
+	// ErrInvalidLockTime describes an error where a contract is built with
+	// a zero lock time, which would make its refund branch spendable at
+	// once.
+	ErrInvalidLockTime = errors.New("invalid contract lock time")
+
+	// ErrInvalidPreimage describes an error where a branch is spent with a
+	// preimage of the wrong size, or with a preimage it does not require.
+	ErrInvalidPreimage = errors.New("invalid preimage")
+
+	// ErrSignatureCount describes an error where a branch is spent with
+	// another number of signatures than it requires.
+	ErrSignatureCount = errors.New("wrong number of signatures")
+)
+
+// Contract is the redeem script of a contract and the address paying to it.
+type Contract struct {
+	// RedeemScript is the redeem script of the contract, which is pushed
+	// last by the signature script of any branch.
+	RedeemScript []byte
+
+	// Address is the pay-to-script-hash address funding the contract.
+	Address *btcutil.AddressScriptHash
+}
+
+// newContract returns the contract with the passed redeem script.
+func newContract(redeemScript []byte, net *chaincfg.Params) (Contract, error) {
+	addr, err := btcutil.NewAddressScriptHash(redeemScript, net)
+	if err != nil {
+		return Contract{}, err
+	}
+	return Contract{RedeemScript: redeemScript, Address: addr}, nil
+}
+
+// Branch describes how to spend one branch of a contract.
+type Branch struct {
+	// PubKeys are the keys whose signatures the branch requires, in the
+	// order their signatures are passed to SignatureScript.
+	PubKeys []*btcec.PublicKey
+
+	// RequiredSigs is the number of signatures of PubKeys the branch
+	// requires.
+	RequiredSigs int
+
+	// LockTime is the smallest lock time of a transaction spending the
+	// branch, or zero when the branch is not timelocked.
+	LockTime locktime.LockTime
+
+	// Sequence is the sequence number of the input spending the branch.
+	// It is not final for timelocked branches, since the lock time of a
+	// transaction whose inputs are all final is not enforced, and encodes
+	// the relative lock time of branches with one.
+	Sequence uint32
+
+	// NeedsPreimage is whether the branch requires the preimage of the
+	// payment hash of the contract.
+	NeedsPreimage bool
+
+	selector byte
+	multiSig bool
+}
+
+// newBranch returns a branch selected by selector, requiring m signatures of
+// pubKeys and lockTime.
+func newBranch(selector byte, m int, pubKeys []*btcec.PublicKey,
+	lockTime locktime.LockTime) Branch {
+	sequence := uint32(locktime.SequenceFinal)
+	if lockTime != 0 {
+		sequence--
+	}
+	return Branch{
+		PubKeys:      pubKeys,
+		RequiredSigs: m,
+		LockTime:     lockTime,
+		Sequence:     sequence,
+		selector:     selector,
+		multiSig:     len(pubKeys) > 1,
+	}
+}
+
+// SignatureScript returns the signature script spending the branch of the
+// contract with the passed redeem script.  sigs are the signatures of the
+// spending input, with their hash type, by RequiredSigs of PubKeys in their
+// order.  preimage is the preimage of the payment hash for branches which
+// need it, and nil otherwise.
+func (b *Branch) SignatureScript(redeemScript []byte, sigs [][]byte,
+	preimage []byte) ([]byte, error) {
+	if len(sigs) != b.RequiredSigs {
+		return nil, ErrSignatureCount
+	}
+	if b.NeedsPreimage != (preimage != nil) ||
+		(b.NeedsPreimage && len(preimage) != PreimageSize) {
+		return nil, ErrInvalidPreimage
+	}
+
+	var script []byte
+	// OP_CHECKMULTISIG pops one element more than it uses.
+	if b.multiSig {
+		script = append(script, scriptclass.OP_0)
+	}
+	for _, sig := range sigs {
+		script = appendPush(script, sig)
+	}
+	if preimage != nil {
+		script = appendPush(script, preimage)
+	}
+	script = append(script, b.selector)
+	return appendPush(script, redeemScript), nil
+}
+
+// appendPush appends the minimal push of data to script.
+func appendPush(script, data []byte) []byte {
+	switch n := len(data); {
+	case n == 0:
+		return append(script, scriptclass.OP_0)
+	case n == 1 && data[0] >= 1 && data[0] <= 16:
+		return append(script, scriptclass.OP_1-1+data[0])
+	case n == 1 && data[0] == 0x81:
+		return append(script, scriptclass.OP_1NEGATE)
+	case n <= scriptclass.OP_DATA_75:
+		script = append(script, byte(n))
+	case n <= 0xff:
+		script = append(script, scriptclass.OP_PUSHDATA1, byte(n))
+	default:
+		script = append(script, scriptclass.OP_PUSHDATA2, byte(n), byte(n>>8))
+	}
+	return append(script, data...)
+}
+
+// appendLockTime appends the push of lockTime, which must not be zero, as a
+// minimally encoded script number, as read by OP_CHECKLOCKTIMEVERIFY.
+func appendLockTime(script []byte, lockTime locktime.LockTime) []byte {
+	return appendNum(script, uint32(lockTime))
+}
+
+// appendNum appends the push of n, which must not be zero, as a minimally
+// encoded script number.
+func appendNum(script []byte, n uint32) []byte {
+	if n <= 16 {
+		return append(script, scriptclass.OP_1-1+byte(n))
+	}
+	var num []byte
+	for ; n > 0; n >>= 8 {
+		num = append(num, byte(n))
+	}
+	// A set high bit would make the number negative.
+	if num[len(num)-1]&0x80 != 0 {
+		num = append(num, 0x00)
+	}
+	return appendPush(script, num)
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package contracts
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil/locktime"
+	"github.com/zeusyf/btcutil/multisig"
+	"github.com/zeusyf/btcutil/scriptclass"
+)
+
+// EscrowRequiredSigs is the number of the keys of the buyer, the seller and
+// the arbiter whose signatures settle an escrow.
+const EscrowRequiredSigs = 2
+
+// Escrow is a 2-of-3 escrow between a buyer, a seller and an arbiter, which
+// the buyer alone can spend back after its lock time.  Its redeem script is:
+//
+//	OP_IF
+//	    <2-of-3 multisig script>
+//	OP_ELSE
+//	    <lock time> OP_CHECKLOCKTIMEVERIFY OP_DROP <buyer key> OP_CHECKSIG
+//	OP_ENDIF
+//
+// The multisig script is the one of package multisig, whose keys are sorted.
+type Escrow struct {
+	Contract
+
+	// Settle is the branch spent by two of the buyer, the seller and the
+	// arbiter.  Its keys are sorted as in the multisig script.
+	Settle Branch
+
+	// Refund is the branch paying the buyer after the lock time.
+	Refund Branch
+}
+
+// NewEscrow returns the 2-of-3 escrow between buyer, seller and arbiter,
+// refunded to buyer from lockTime on.  ErrInvalidLockTime is returned when
+// lockTime is zero, and multisig.ErrDuplicatePubKey when a key is passed
+// twice.
+func NewEscrow(buyer, seller, arbiter *btcec.PublicKey,
+	lockTime locktime.LockTime, net *chaincfg.Params) (*Escrow, error) {
+	if lockTime == 0 {
+		return nil, ErrInvalidLockTime
+	}
+	pubKeys := []*btcec.PublicKey{buyer, seller, arbiter}
+	settle, err := multisig.RedeemScript(EscrowRequiredSigs, pubKeys)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(pubKeys, func(i, j int) bool {
+		return bytes.Compare(pubKeys[i].SerializeCompressed(),
+			pubKeys[j].SerializeCompressed()) < 0
+	})
+
+	script := make([]byte, 0, len(settle)+btcec.PubKeyBytesLenCompressed+12)
+	script = append(script, scriptclass.OP_IF)
+	script = append(script, settle...)
+	script = append(script, scriptclass.OP_ELSE)
+	script = appendLockTime(script, lockTime)
+	script = append(script, scriptclass.OP_CHECKLOCKTIMEVERIFY,
+		scriptclass.OP_DROP)
+	script = appendPush(script, buyer.SerializeCompressed())
+	script = append(script, scriptclass.OP_CHECKSIG, scriptclass.OP_ENDIF)
+
+	contract, err := newContract(script, net)
+	if err != nil {
+		return nil, err
+	}
+	return &Escrow{
+		Contract: contract,
+		Settle: newBranch(scriptclass.OP_1, EscrowRequiredSigs, pubKeys,
+			0),
+		Refund: newBranch(scriptclass.OP_0, 1, []*btcec.PublicKey{buyer},
+			lockTime),
+	}, nil
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package contracts_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil/contracts"
+	"github.com/zeusyf/btcutil/locktime"
+	"github.com/zeusyf/btcutil/multisig"
+	"github.com/zeusyf/btcutil/scriptclass"
+)
+
+// TestEscrow ensures escrows wrap the 2-of-3 multisig script of their keys
+// with a timelocked refund to the buyer.
+func TestEscrow(t *testing.T) {
+	keys := testPubKeys(3)
+	buyer, seller, arbiter := keys[2], keys[0], keys[1]
+	net := &chaincfg.MainNetParams
+
+	escrow, err := contracts.NewEscrow(buyer, seller, arbiter, 1000, net)
+	if err != nil {
+		t.Fatalf("NewEscrow: unexpected error: %v", err)
+	}
+
+	settle, _ := multisig.RedeemScript(2, keys)
+	var want []byte
+	want = append(want, 0x63)
+	want = append(want, settle...)
+	want = append(want, 0x67, 0x02, 0xe8, 0x03, 0xb1, 0x75, 0x21)
+	want = append(want, buyer.SerializeCompressed()...)
+	want = append(want, 0xac, 0x68)
+	if !bytes.Equal(escrow.RedeemScript, want) {
+		t.Errorf("NewEscrow: got script %x, want %x", escrow.RedeemScript, want)
+	}
+
+	// The settle keys are in the order of the multisig script.
+	ops, _ := scriptclass.Parse(settle)
+	if len(escrow.Settle.PubKeys) != 3 || escrow.Settle.RequiredSigs != 2 {
+		t.Fatalf("NewEscrow: got settle branch %+v", escrow.Settle)
+	}
+	for i, key := range escrow.Settle.PubKeys {
+		if !bytes.Equal(key.SerializeCompressed(), ops[i+1].Data) {
+			t.Errorf("NewEscrow: settle key %d out of script order", i)
+		}
+	}
+	if escrow.Settle.Sequence != locktime.SequenceFinal ||
+		escrow.Refund.LockTime != 1000 ||
+		escrow.Refund.Sequence == locktime.SequenceFinal {
+		t.Errorf("NewEscrow: got branches %+v, %+v", escrow.Settle,
+			escrow.Refund)
+	}
+
+	// Settle: OP_0 <sig> <sig> OP_1 <redeem script>
+	sigs := [][]byte{{0x30, 0x01}, {0x30, 0x02}}
+	sigScript, err := escrow.Settle.SignatureScript(escrow.RedeemScript,
+		sigs, nil)
+	if err != nil {
+		t.Fatalf("SignatureScript: unexpected error: %v", err)
+	}
+	ops, _ = scriptclass.Parse(sigScript)
+	if len(ops) != 5 || ops[0].Op != scriptclass.OP_0 ||
+		!bytes.Equal(ops[2].Data, sigs[1]) || ops[3].Op != scriptclass.OP_1 ||
+		!bytes.Equal(ops[4].Data, escrow.RedeemScript) {
+		t.Errorf("SignatureScript: got settle script %x", sigScript)
+	}
+	if _, err := escrow.Settle.SignatureScript(escrow.RedeemScript,
+		sigs[:1], nil); err != contracts.ErrSignatureCount {
+		t.Errorf("SignatureScript: got error %v, want %v", err,
+			contracts.ErrSignatureCount)
+	}
+
+	// Refund: <sig> OP_0 <redeem script>
+	sigScript, err = escrow.Refund.SignatureScript(escrow.RedeemScript,
+		sigs[:1], nil)
+	ops, _ = scriptclass.Parse(sigScript)
+	if err != nil || len(ops) != 3 || ops[1].Op != scriptclass.OP_0 {
+		t.Errorf("SignatureScript: got refund script %x, %v", sigScript, err)
+	}
+
+	_, err = contracts.NewEscrow(buyer, seller, buyer, 1000, net)
+	if err != multisig.ErrDuplicatePubKey {
+		t.Errorf("NewEscrow: got error %v, want %v", err,
+			multisig.ErrDuplicatePubKey)
+	}
+	_, err = contracts.NewEscrow(buyer, seller, arbiter, 0, net)
+	if err != contracts.ErrInvalidLockTime {
+		t.Errorf("NewEscrow: got error %v, want %v", err,
+			contracts.ErrInvalidLockTime)
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package contracts
+
+import (
+	"crypto/sha256"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil/locktime"
+	"github.com/zeusyf/btcutil/scriptclass"
+)
+
+// PreimageSize is the size of the preimage claiming a hash time locked
+// contract.  The redeem script enforces it, so a swap with a chain whose
+// contracts accept no other size can't be claimed on one side only.
+const PreimageSize = 32
+
+// HTLC is a hash time locked contract, paying a recipient who reveals the
+// preimage of its payment hash, or paying the sender back after its lock
+// time.  Its redeem script is:
+//
+//	OP_IF
+//	    OP_SIZE 32 OP_EQUALVERIFY OP_SHA256 <payment hash> OP_EQUALVERIFY
+//	    <recipient key>
+//	OP_ELSE
+//	    <lock time> OP_CHECKLOCKTIMEVERIFY OP_DROP <sender key>
+//	OP_ENDIF
+//	OP_CHECKSIG
+type HTLC struct {
+	Contract
+
+	// PaymentHash is the SHA256 of the preimage claiming the contract.
+	PaymentHash [sha256.Size]byte
+
+	// Claim is the branch paying the recipient against the preimage.
+	Claim Branch
+
+	// Refund is the branch paying the sender after the lock time.
+	Refund Branch
+}
+
+// NewHTLC returns the hash time locked contract paying recipient against the
+// preimage of paymentHash, or sender from lockTime on.  ErrInvalidLockTime is
+// returned when lockTime is zero.
+func NewHTLC(recipient, sender *btcec.PublicKey, paymentHash [sha256.Size]byte,
+	lockTime locktime.LockTime, net *chaincfg.Params) (*HTLC, error) {
+	if lockTime == 0 {
+		return nil, ErrInvalidLockTime
+	}
+
+	script := make([]byte, 0, 2*btcec.PubKeyBytesLenCompressed+
+		len(paymentHash)+20)
+	script = append(script, scriptclass.OP_IF, scriptclass.OP_SIZE,
+		scriptclass.OP_DATA_1, PreimageSize, scriptclass.OP_EQUALVERIFY,
+		scriptclass.OP_SHA256)
+	script = appendPush(script, paymentHash[:])
+	script = append(script, scriptclass.OP_EQUALVERIFY)
+	script = appendPush(script, recipient.SerializeCompressed())
+	script = append(script, scriptclass.OP_ELSE)
+	script = appendLockTime(script, lockTime)
+	script = append(script, scriptclass.OP_CHECKLOCKTIMEVERIFY,
+		scriptclass.OP_DROP)
+	script = appendPush(script, sender.SerializeCompressed())
+	script = append(script, scriptclass.OP_ENDIF, scriptclass.OP_CHECKSIG)
+
+	contract, err := newContract(script, net)
+	if err != nil {
+		return nil, err
+	}
+	claim := newBranch(scriptclass.OP_1, 1, []*btcec.PublicKey{recipient}, 0)
+	claim.NeedsPreimage = true
+	return &HTLC{
+		Contract:    contract,
+		PaymentHash: paymentHash,
+		Claim:       claim,
+		Refund: newBranch(scriptclass.OP_0, 1,
+			[]*btcec.PublicKey{sender}, lockTime),
+	}, nil
+}
+
+// ExtractPreimage returns the preimage of paymentHash pushed by a signature
+// script, such as the one of the input claiming a hash time locked contract.
+// It is how the sender of an atomic swap learns the preimage claiming the
+// contract of the other side.  False is returned when no push of the script is
+// the preimage.
+func ExtractPreimage(sigScript []byte, paymentHash [sha256.Size]byte) ([]byte, bool) {
+	ops, _ := scriptclass.Parse(sigScript)
+	for _, op := range ops {
+		if len(op.Data) == PreimageSize && sha256.Sum256(op.Data) == paymentHash {
+			return op.Data, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,155 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package contracts_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/contracts"
+	"github.com/zeusyf/btcutil/locktime"
+	"github.com/zeusyf/btcutil/scriptclass"
+)
+
+// testPubKeys returns n distinct public keys derived from the private keys
+// 1 through n.
+func testPubKeys(n int) []*btcec.PublicKey {
+	keys := make([]*btcec.PublicKey, n)
+	for i := range keys {
+		_, pub := btcec.PrivKeyFromBytes(btcec.S256(), []byte{byte(i + 1)})
+		keys[i] = pub
+	}
+	return keys
+}
+
+// TestHTLC ensures hash time locked contracts commit to their keys, payment
+// hash and lock time, and describe how to spend both of their branches.
+func TestHTLC(t *testing.T) {
+	keys := testPubKeys(2)
+	preimage := bytes.Repeat([]byte{0x42}, contracts.PreimageSize)
+	paymentHash := sha256.Sum256(preimage)
+	net := &chaincfg.MainNetParams
+
+	htlc, err := contracts.NewHTLC(keys[0], keys[1], paymentHash, 500000, net)
+	if err != nil {
+		t.Fatalf("NewHTLC: unexpected error: %v", err)
+	}
+
+	var want []byte
+	want = append(want, 0x63, 0x82, 0x01, 0x20, 0x88, 0xa8, 0x20)
+	want = append(want, paymentHash[:]...)
+	want = append(want, 0x88, 0x21)
+	want = append(want, keys[0].SerializeCompressed()...)
+	want = append(want, 0x67, 0x03, 0x20, 0xa1, 0x07, 0xb1, 0x75, 0x21)
+	want = append(want, keys[1].SerializeCompressed()...)
+	want = append(want, 0x68, 0xac)
+	if !bytes.Equal(htlc.RedeemScript, want) {
+		t.Errorf("NewHTLC: got script %x, want %x", htlc.RedeemScript, want)
+	}
+	addr, _ := btcutil.NewAddressScriptHash(want, net)
+	if htlc.Address.EncodeAddress() != addr.EncodeAddress() {
+		t.Errorf("NewHTLC: got address %v, want %v", htlc.Address, addr)
+	}
+
+	if htlc.Claim.LockTime != 0 || htlc.Claim.Sequence != locktime.SequenceFinal ||
+		!htlc.Claim.NeedsPreimage {
+		t.Errorf("NewHTLC: got claim branch %+v", htlc.Claim)
+	}
+	if htlc.Refund.LockTime != 500000 ||
+		htlc.Refund.Sequence == locktime.SequenceFinal ||
+		htlc.Refund.NeedsPreimage || htlc.Refund.PubKeys[0] != keys[1] {
+		t.Errorf("NewHTLC: got refund branch %+v", htlc.Refund)
+	}
+
+	// Claim: <sig> <preimage> OP_1 <redeem script>
+	sig := []byte{0x30, 0x01}
+	sigScript, err := htlc.Claim.SignatureScript(htlc.RedeemScript,
+		[][]byte{sig}, preimage)
+	if err != nil {
+		t.Fatalf("SignatureScript: unexpected error: %v", err)
+	}
+	ops, err := scriptclass.Parse(sigScript)
+	if err != nil || len(ops) != 4 || !bytes.Equal(ops[0].Data, sig) ||
+		!bytes.Equal(ops[1].Data, preimage) || ops[2].Op != scriptclass.OP_1 ||
+		!bytes.Equal(ops[3].Data, htlc.RedeemScript) {
+		t.Errorf("SignatureScript: got claim script %x", sigScript)
+	}
+	got, ok := contracts.ExtractPreimage(sigScript, paymentHash)
+	if !ok || !bytes.Equal(got, preimage) {
+		t.Errorf("ExtractPreimage: got %x, %v", got, ok)
+	}
+
+	// Refund: <sig> OP_0 <redeem script>
+	sigScript, err = htlc.Refund.SignatureScript(htlc.RedeemScript,
+		[][]byte{sig}, nil)
+	if err != nil {
+		t.Fatalf("SignatureScript: unexpected error: %v", err)
+	}
+	ops, _ = scriptclass.Parse(sigScript)
+	if len(ops) != 3 || ops[1].Op != scriptclass.OP_0 {
+		t.Errorf("SignatureScript: got refund script %x", sigScript)
+	}
+	if _, ok := contracts.ExtractPreimage(sigScript, paymentHash); ok {
+		t.Errorf("ExtractPreimage: found a preimage in a refund")
+	}
+
+	errs := []struct {
+		branch   *contracts.Branch
+		sigs     [][]byte
+		preimage []byte
+		err      error
+	}{
+		{&htlc.Claim, nil, preimage, contracts.ErrSignatureCount},
+		{&htlc.Claim, [][]byte{sig}, nil, contracts.ErrInvalidPreimage},
+		{&htlc.Claim, [][]byte{sig}, preimage[1:], contracts.ErrInvalidPreimage},
+		{&htlc.Refund, [][]byte{sig}, preimage, contracts.ErrInvalidPreimage},
+	}
+	for i, test := range errs {
+		_, err := test.branch.SignatureScript(htlc.RedeemScript, test.sigs,
+			test.preimage)
+		if err != test.err {
+			t.Errorf("%d: got error %v, want %v", i, err, test.err)
+		}
+	}
+
+	if _, err := contracts.NewHTLC(keys[0], keys[1], paymentHash, 0, net); err != contracts.ErrInvalidLockTime {
+		t.Errorf("NewHTLC: got error %v, want %v", err,
+			contracts.ErrInvalidLockTime)
+	}
+}
+
+// TestHTLCLockTime ensures lock times are pushed as minimal script numbers.
+func TestHTLCLockTime(t *testing.T) {
+	keys := testPubKeys(2)
+	tests := []struct {
+		lockTime locktime.LockTime
+		push     string
+	}{
+		{1, "51"},
+		{16, "60"},
+		{17, "0111"},
+		{128, "028000"},
+		{65535, "03ffff00"},
+		{1600000000, "0400105e5f"},
+		{0xffffffff, "05ffffffff00"},
+	}
+	for _, test := range tests {
+		htlc, err := contracts.NewHTLC(keys[0], keys[1], [32]byte{},
+			test.lockTime, &chaincfg.MainNetParams)
+		if err != nil {
+			t.Fatalf("NewHTLC(%d): unexpected error: %v", test.lockTime, err)
+		}
+		push, _ := hex.DecodeString(test.push + "b175")
+		if !bytes.Contains(htlc.RedeemScript, append([]byte{0x67}, push...)) {
+			t.Errorf("NewHTLC(%d): got script %x, want push %s",
+				test.lockTime, htlc.RedeemScript, test.push)
+		}
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package contracts
+
+import (
+	"errors"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil/locktime"
+	"github.com/zeusyf/btcutil/scriptclass"
+)
+
+// ErrInvalidDelay describes an error where a vault is built with a zero
+// delay, which would let the hot key spend it at once.
+var ErrInvalidDelay = errors.New("invalid vault delay")
+
+// Vault is the unvaulting stage of a vault, paying a hot key some time after
+// it is funded, or a recovery key at any time.  Its redeem script is:
+//
+//	OP_IF
+//	    <recovery key>
+//	OP_ELSE
+//	    <delay> OP_CHECKSEQUENCEVERIFY OP_DROP <hot key>
+//	OP_ENDIF
+//	OP_CHECKSIG
+//
+// The delay is the relative lock time of the input spending the hot branch,
+// during which a watcher seeing funds unvaulted against the wishes of the
+// owner sweeps them away with the recovery key.
+type Vault struct {
+	Contract
+
+	// Delay is the relative lock time of the hot branch.
+	Delay locktime.RelativeLock
+
+	// Recover is the branch paying the recovery key at once.
+	Recover Branch
+
+	// Spend is the branch paying the hot key after the delay.  Its
+	// sequence number encodes the delay.
+	Spend Branch
+}
+
+// NewVault returns the vault paying hot after delay, or recovery at once.
+// ErrInvalidDelay is returned when delay is zero.
+func NewVault(hot, recovery *btcec.PublicKey, delay locktime.RelativeLock,
+	net *chaincfg.Params) (*Vault, error) {
+	if delay.Value == 0 {
+		return nil, ErrInvalidDelay
+	}
+
+	script := make([]byte, 0, 2*btcec.PubKeyBytesLenCompressed+12)
+	script = append(script, scriptclass.OP_IF)
+	script = appendPush(script, recovery.SerializeCompressed())
+	script = append(script, scriptclass.OP_ELSE)
+	script = appendNum(script, delay.Sequence())
+	script = append(script, scriptclass.OP_CHECKSEQUENCEVERIFY,
+		scriptclass.OP_DROP)
+	script = appendPush(script, hot.SerializeCompressed())
+	script = append(script, scriptclass.OP_ENDIF, scriptclass.OP_CHECKSIG)
+
+	contract, err := newContract(script, net)
+	if err != nil {
+		return nil, err
+	}
+	spend := newBranch(scriptclass.OP_0, 1, []*btcec.PublicKey{hot}, 0)
+	spend.Sequence = delay.Sequence()
+	return &Vault{
+		Contract: contract,
+		Delay:    delay,
+		Recover: newBranch(scriptclass.OP_1, 1,
+			[]*btcec.PublicKey{recovery}, 0),
+		Spend: spend,
+	}, nil
+}
@@ -0,0 +1,83 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package contracts_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil/contracts"
+	"github.com/zeusyf/btcutil/locktime"
+)
+
+// TestVault ensures vaults pay the recovery key at once and the hot key
+// after their delay, encoded in the script and the spend branch sequence.
+func TestVault(t *testing.T) {
+	keys := testPubKeys(2)
+	hot, recovery := keys[0], keys[1]
+	net := &chaincfg.MainNetParams
+
+	tests := []struct {
+		name  string
+		delay locktime.RelativeLock
+		push  []byte
+	}{
+		{"small", locktime.RelativeBlocks(6), []byte{0x56}},
+		{"sign byte", locktime.RelativeBlocks(144), []byte{0x02, 0x90, 0x00}},
+		{"seconds", locktime.RelativeLock{IsSeconds: true, Value: 2},
+			[]byte{0x03, 0x02, 0x00, 0x40}},
+	}
+	for _, test := range tests {
+		vault, err := contracts.NewVault(hot, recovery, test.delay, net)
+		if err != nil {
+			t.Fatalf("%s: NewVault: unexpected error: %v", test.name, err)
+		}
+
+		var want []byte
+		want = append(want, 0x63, 0x21)
+		want = append(want, recovery.SerializeCompressed()...)
+		want = append(want, 0x67)
+		want = append(want, test.push...)
+		want = append(want, 0xb2, 0x75, 0x21)
+		want = append(want, hot.SerializeCompressed()...)
+		want = append(want, 0x68, 0xac)
+		if !bytes.Equal(vault.RedeemScript, want) {
+			t.Errorf("%s: got script %x, want %x", test.name,
+				vault.RedeemScript, want)
+		}
+		if vault.Spend.Sequence != test.delay.Sequence() ||
+			vault.Spend.LockTime != 0 ||
+			vault.Recover.Sequence != locktime.SequenceFinal {
+			t.Errorf("%s: got branches %+v, %+v", test.name,
+				vault.Recover, vault.Spend)
+		}
+		if !vault.Spend.PubKeys[0].IsEqual(hot) ||
+			!vault.Recover.PubKeys[0].IsEqual(recovery) {
+			t.Errorf("%s: branches have the wrong keys", test.name)
+		}
+	}
+
+	// Recover: <sig> OP_1 <redeem script>, whose 75 bytes are pushed with
+	// a single opcode.
+	vault, _ := contracts.NewVault(hot, recovery, locktime.RelativeBlocks(6),
+		net)
+	sigScript, err := vault.Recover.SignatureScript(vault.RedeemScript,
+		[][]byte{{0x30, 0x01}}, nil)
+	if err != nil {
+		t.Fatalf("SignatureScript: unexpected error: %v", err)
+	}
+	want := append([]byte{0x02, 0x30, 0x01, 0x51, 0x4b},
+		vault.RedeemScript...)
+	if !bytes.Equal(sigScript, want) {
+		t.Errorf("SignatureScript: got %x, want %x", sigScript, want)
+	}
+
+	_, err = contracts.NewVault(hot, recovery, locktime.RelativeBlocks(0), net)
+	if err != contracts.ErrInvalidDelay {
+		t.Errorf("NewVault: got error %v, want %v", err,
+			contracts.ErrInvalidDelay)
+	}
+}
@@ -0,0 +1,113 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package cpfp computes the fee a child transaction must pay to get its
+// unconfirmed ancestors mined, known as child-pays-for-parent.
+//
+// Miners select a transaction along with all its unconfirmed ancestors, at
+// the fee rate of the whole package: the total fee of the transactions over
+// their total size.  A deposit stuck in a transaction paying too low a fee
+// can therefore be unstuck by its recipient spending it in a child paying
+// enough to bring the package to the target fee rate.
+package cpfp
+
+import (
+	"errors"
+
+	"github.com/zeusyf/btcutil"
+)
+
+var (
+	// ErrNoAncestors describes an error where a package is computed
+	// without any unconfirmed ancestor.
+	ErrNoAncestors = errors.New("no unconfirmed ancestors")
+
+	// ErrInvalidTx describes an error where a transaction of a package has
+	// a non-positive size or a negative fee.
+	ErrInvalidTx = errors.New("invalid package transaction")
+)
+
+// Tx is the size and fee of an unconfirmed transaction of a package.
+type Tx struct {
+	// VSize is the virtual size of the transaction.
+	VSize int64
+
+	// Fee is the fee paid by the transaction.
+	Fee btcutil.Amount
+}
+
+// Package is the result of a child-pays-for-parent computation.
+type Package struct {
+	// ChildFee is the fee the child must pay.
+	ChildFee btcutil.Amount
+
+	// VSize and Fee are the total size and fee of the ancestors and the
+	// child.
+	VSize int64
+	Fee   btcutil.Amount
+
+	// AncestorFeeRate is the fee rate of the ancestors alone, and
+	// FeeRate the effective fee rate of the package with the child.
+	AncestorFeeRate btcutil.FeeRate
+	FeeRate         btcutil.FeeRate
+}
+
+// FeeRate returns the fee rate of the package of the passed transactions,
+// their total fee over their total size.  ErrInvalidTx is returned for a
+// transaction with a non-positive size or a negative fee.
+func FeeRate(txs []Tx) (btcutil.FeeRate, error) {
+	vsize, fee, err := totals(txs)
+	if err != nil {
+		return 0, err
+	}
+	return btcutil.NewFeeRateFromFee(fee, vsize), nil
+}
+
+// totals returns the total size and fee of txs.
+func totals(txs []Tx) (int64, btcutil.Amount, error) {
+	var vsize int64
+	var fee btcutil.Amount
+	for _, tx := range txs {
+		if tx.VSize <= 0 || tx.Fee < 0 {
+			return 0, 0, ErrInvalidTx
+		}
+		vsize += tx.VSize
+		fee += tx.Fee
+	}
+	return vsize, fee, nil
+}
+
+// ChildFee returns the package of the unconfirmed ancestors and a child of
+// childVSize virtual bytes paying the least fee bringing the package to the
+// target fee rate.  The child pays at least the target fee rate for its own
+// size, even when the ancestors already pay more, so it is not itself left
+// behind.  ErrNoAncestors is returned when ancestors is empty, and
+// ErrInvalidTx when any of them or the child has a non-positive size or a
+// negative fee.
+func ChildFee(ancestors []Tx, childVSize int64,
+	target btcutil.FeeRate) (*Package, error) {
+	if len(ancestors) == 0 {
+		return nil, ErrNoAncestors
+	}
+	if childVSize <= 0 {
+		return nil, ErrInvalidTx
+	}
+	vsize, fee, err := totals(ancestors)
+	if err != nil {
+		return nil, err
+	}
+
+	childFee := target.FeeForVSize(vsize+childVSize) - fee
+	if own := target.FeeForVSize(childVSize); childFee < own {
+		childFee = own
+	}
+	return &Package{
+		ChildFee:        childFee,
+		VSize:           vsize + childVSize,
+		Fee:             fee + childFee,
+		AncestorFeeRate: btcutil.NewFeeRateFromFee(fee, vsize),
+		FeeRate: btcutil.NewFeeRateFromFee(fee+childFee,
+			vsize+childVSize),
+	}, nil
+}
@@ -0,0 +1,99 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package cpfp_test
+
+import (
+	"testing"
+
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/cpfp"
+)
+
+// TestChildFee ensures the child fee brings the package to the target fee
+// rate and pays at least the target for the child itself.
+func TestChildFee(t *testing.T) {
+	tests := []struct {
+		name      string
+		ancestors []cpfp.Tx
+		childSize int64
+		target    btcutil.FeeRate
+		childFee  btcutil.Amount
+		rate      btcutil.FeeRate
+	}{{
+		name:      "stuck parent",
+		ancestors: []cpfp.Tx{{VSize: 200, Fee: 200}},
+		childSize: 100,
+		target:    btcutil.NewFeeRateFromHaoPerVByte(10),
+		childFee:  2800,
+		rate:      10000,
+	}, {
+		name:      "two ancestors",
+		ancestors: []cpfp.Tx{{VSize: 150, Fee: 150}, {VSize: 250, Fee: 1000}},
+		childSize: 110,
+		target:    btcutil.NewFeeRateFromHaoPerVByte(5),
+		childFee:  1400,
+		rate:      5000,
+	}, {
+		name:      "rounded up",
+		ancestors: []cpfp.Tx{{VSize: 141, Fee: 0}},
+		childSize: 110,
+		target:    1500,
+		childFee:  377,
+		rate:      1501,
+	}, {
+		name:      "parent already paying",
+		ancestors: []cpfp.Tx{{VSize: 200, Fee: 10000}},
+		childSize: 100,
+		target:    btcutil.NewFeeRateFromHaoPerVByte(10),
+		childFee:  1000,
+		rate:      36666,
+	}}
+	for _, test := range tests {
+		p, err := cpfp.ChildFee(test.ancestors, test.childSize, test.target)
+		if err != nil {
+			t.Fatalf("ChildFee(%s): unexpected error: %v", test.name, err)
+		}
+		if p.ChildFee != test.childFee || p.FeeRate != test.rate {
+			t.Errorf("ChildFee(%s): got fee %d at %d, want %d at %d",
+				test.name, p.ChildFee, p.FeeRate, test.childFee,
+				test.rate)
+		}
+		if p.FeeRate < test.target {
+			t.Errorf("ChildFee(%s): package rate %v below target %v",
+				test.name, p.FeeRate, test.target)
+		}
+		rate, err := cpfp.FeeRate(test.ancestors)
+		if err != nil || rate != p.AncestorFeeRate {
+			t.Errorf("FeeRate(%s): got %v, %v, want %v", test.name, rate,
+				err, p.AncestorFeeRate)
+		}
+	}
+}
+
+// TestChildFeeErrors ensures invalid packages are rejected.
+func TestChildFeeErrors(t *testing.T) {
+	target := btcutil.NewFeeRateFromHaoPerVByte(10)
+	if _, err := cpfp.ChildFee(nil, 100, target); err != cpfp.ErrNoAncestors {
+		t.Errorf("ChildFee: got error %v, want %v", err, cpfp.ErrNoAncestors)
+	}
+	invalid := [][]cpfp.Tx{
+		{{VSize: 0, Fee: 100}},
+		{{VSize: 100, Fee: -1}},
+	}
+	for _, ancestors := range invalid {
+		if _, err := cpfp.ChildFee(ancestors, 100, target); err != cpfp.ErrInvalidTx {
+			t.Errorf("ChildFee(%+v): got error %v, want %v", ancestors,
+				err, cpfp.ErrInvalidTx)
+		}
+		if _, err := cpfp.FeeRate(ancestors); err != cpfp.ErrInvalidTx {
+			t.Errorf("FeeRate(%+v): got error %v, want %v", ancestors,
+				err, cpfp.ErrInvalidTx)
+		}
+	}
+	valid := []cpfp.Tx{{VSize: 100, Fee: 100}}
+	if _, err := cpfp.ChildFee(valid, 0, target); err != cpfp.ErrInvalidTx {
+		t.Errorf("ChildFee: got error %v, want %v", err, cpfp.ErrInvalidTx)
+	}
+}
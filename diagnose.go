@@ -0,0 +1,222 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package btcutil
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil/base58"
+	"github.com/zeusyf/btcutil/bech32"
+)
+
+// AddressEncoding identifies the encoding an address string appears to use.
+type AddressEncoding uint8
+
+const (
+	// EncodingUnknown is the encoding of strings which look like neither
+	// base58 nor bech32.
+	EncodingUnknown AddressEncoding = iota
+
+	// EncodingBase58 is the base58check encoding of pay-to-pubkey-hash,
+	// pay-to-script-hash, multi-signature and contract addresses.
+	EncodingBase58
+
+	// EncodingBech32 is the bech32 encoding of BIP 173.
+	EncodingBech32
+)
+
+// addressEncodingStrings is a map of address encodings back to their constant
+// names for pretty printing.
+var addressEncodingStrings = map[AddressEncoding]string{
+	EncodingUnknown: "EncodingUnknown",
+	EncodingBase58:  "EncodingBase58",
+	EncodingBech32:  "EncodingBech32",
+}
+
+// String returns the AddressEncoding as a human-readable name.
+func (e AddressEncoding) String() string {
+	if s, ok := addressEncodingStrings[e]; ok {
+		return s
+	}
+	return fmt.Sprintf("Unknown AddressEncoding (%d)", uint8(e))
+}
+
+// AddressDiagnostics describes why an address string failed to decode, in
+// enough detail for a wallet to show an actionable error rather than a bare
+// checksum mismatch.  It is the error returned by
+// DecodeAddressWithDiagnostics.
+type AddressDiagnostics struct {
+	// Err is the error DecodeAddress returned for the string.
+	Err error
+
+	// Encoding is the encoding the string appears to use.
+	Encoding AddressEncoding
+
+	// Net is the registered network the string likely belongs to, as
+	// told by its version byte or human-readable part, or nil when it is
+	// unknown.
+	Net NetParams
+
+	// ErrorPositions are the indexes, in increasing order, of the
+	// characters whose substitution yields a valid encoding, which are
+	// probably mistyped.  The bech32 checksum locates any single
+	// substitution, so bech32 strings have at most one.
+	ErrorPositions []int
+
+	// Suggestions are the valid encodings, in sorted order, within one
+	// substituted, missing or extra character of the string.  Users must
+	// confirm a suggestion against another record, since it may be valid
+	// but unintended.
+	Suggestions []string
+}
+
+// Error returns the error of DecodeAddress, suggesting the only valid
+// encoding within one character when there is one.
+func (d *AddressDiagnostics) Error() string {
+	if len(d.Suggestions) == 1 {
+		return fmt.Sprintf("%v; did you mean %s?", d.Err, d.Suggestions[0])
+	}
+	return d.Err.Error()
+}
+
+// Unwrap returns the error of DecodeAddress.
+func (d *AddressDiagnostics) Unwrap() error {
+	return d.Err
+}
+
+// DecodeAddressWithDiagnostics decodes an address like DecodeAddress, but
+// returns an *AddressDiagnostics wrapping the error when decoding fails.
+// Diagnosing enumerates every string within one character of addr, so it is
+// opt-in for user interfaces rather than done by DecodeAddress.  Suggestions
+// are never decoded in place of addr.
+func DecodeAddressWithDiagnostics(addr string, defaultNet *chaincfg.Params) (Address, error) {
+	a, err := DecodeAddress(addr, defaultNet)
+	if err == nil {
+		return a, nil
+	}
+
+	d := &AddressDiagnostics{Err: err, Encoding: addressEncoding(addr)}
+	switch d.Encoding {
+	case EncodingBase58:
+		d.diagnoseBase58(addr, defaultNet)
+	case EncodingBech32:
+		d.diagnoseBech32(addr)
+	}
+	return nil, d
+}
+
+// diagnoseBase58 fills in the network and the repairs of a base58 address.
+func (d *AddressDiagnostics) diagnoseBase58(addr string, defaultNet *chaincfg.Params) {
+	d.Suggestions = repairCandidates(addr, base58Alphabet,
+		func(candidate string) bool {
+			_, _, err := base58.CheckDecode(candidate)
+			if err != nil {
+				return false
+			}
+			_, err = DecodeAddress(candidate, defaultNet)
+			return err == nil
+		})
+	d.ErrorPositions = substitutions(addr, d.Suggestions)
+
+	// An invalid character leaves the version byte unknown, which is then
+	// taken from a repair.
+	decoded := base58.Decode(addr)
+	if len(decoded) == 0 && len(d.Suggestions) > 0 {
+		decoded = base58.Decode(d.Suggestions[0])
+	}
+	if len(decoded) > 0 {
+		d.Net, _ = NetParamsForAddrID(decoded[0])
+	}
+}
+
+// diagnoseBech32 fills in the network and the repairs of a bech32 string.
+// Only the data part is repaired, since the human-readable part holds
+// characters outside of the alphabet.
+func (d *AddressDiagnostics) diagnoseBech32(addr string) {
+	lower := strings.ToLower(addr)
+	one := strings.LastIndexByte(lower, '1')
+	d.Net = netForHRP(lower[:one])
+
+	// Strings with a valid checksum, but not decoding as an address,
+	// have no repairs.
+	if _, _, err := bech32.Decode(lower); err == nil {
+		return
+	}
+	repairs := repairCandidates(lower[one+1:], bech32Alphabet,
+		func(candidate string) bool {
+			_, _, err := bech32.Decode(lower[:one+1] + candidate)
+			return err == nil
+		})
+	for _, repair := range repairs {
+		d.Suggestions = append(d.Suggestions, lower[:one+1]+repair)
+	}
+	d.ErrorPositions = substitutions(lower, d.Suggestions)
+}
+
+// substitutions returns the indexes, in increasing order, of the characters
+// of s which differ in the repairs of the same length.
+func substitutions(s string, repairs []string) []int {
+	var positions []int
+	for _, repair := range repairs {
+		if len(repair) != len(s) {
+			continue
+		}
+		for i := 0; i < len(s); i++ {
+			if s[i] != repair[i] {
+				positions = append(positions, i)
+				break
+			}
+		}
+	}
+	sort.Ints(positions)
+	return positions
+}
+
+// netForHRP returns the first registered network whose bech32 addresses use
+// the human-readable part hrp, or nil when there is none.
+func netForHRP(hrp string) NetParams {
+	for _, net := range netSnapshot() {
+		if net.Bech32HRP() != "" && net.Bech32HRP() == hrp {
+			return net
+		}
+	}
+	return nil
+}
+
+// addressEncoding returns the encoding addr appears to use.  Strings of a
+// single case are bech32 when their human-readable part is that of a
+// registered network, or when it is made of letters and their data part of
+// characters of the bech32 alphabet.  Other strings are base58 when at most
+// one of their characters is not in the base58 alphabet.
+func addressEncoding(addr string) AddressEncoding {
+	lower := strings.ToLower(addr)
+	one := strings.LastIndexByte(lower, '1')
+	singleCase := addr == lower || addr == strings.ToUpper(addr)
+	if singleCase && one > 0 && one+7 <= len(lower) {
+		hrp, data := lower[:one], lower[one+1:]
+		if netForHRP(hrp) != nil {
+			return EncodingBech32
+		}
+		letters := strings.Trim(hrp, "abcdefghijklmnopqrstuvwxyz") == ""
+		alphabet := strings.Trim(data, bech32Alphabet) == ""
+		if letters && alphabet {
+			return EncodingBech32
+		}
+	}
+
+	invalid := 0
+	for i := 0; i < len(addr); i++ {
+		if strings.IndexByte(base58Alphabet, addr[i]) < 0 {
+			invalid++
+		}
+	}
+	if len(addr) == 0 || invalid > 1 {
+		return EncodingUnknown
+	}
+	return EncodingBase58
+}
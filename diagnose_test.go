@@ -0,0 +1,164 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package btcutil_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/bech32"
+)
+
+// diagnose returns the diagnostics of decoding addr, failing the test when it
+// decodes.
+func diagnose(t *testing.T, addr string) *btcutil.AddressDiagnostics {
+	t.Helper()
+	_, err := btcutil.DecodeAddressWithDiagnostics(addr, &chaincfg.MainNetParams)
+	var d *btcutil.AddressDiagnostics
+	if !errors.As(err, &d) {
+		t.Fatalf("DecodeAddressWithDiagnostics(%q): got error %v, want "+
+			"diagnostics", addr, err)
+	}
+	return d
+}
+
+// TestDecodeAddressWithDiagnostics ensures base58 addresses failing to decode
+// are diagnosed with their likely network and the valid addresses within one
+// character.
+func TestDecodeAddressWithDiagnostics(t *testing.T) {
+	const addr = "1MirQ9bwyQcGVJPwKUgapu5ouK2E2Ey4gX"
+
+	a, err := btcutil.DecodeAddressWithDiagnostics(addr, &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("DecodeAddressWithDiagnostics: unexpected error: %v", err)
+	}
+	if a.EncodeAddress() != addr {
+		t.Errorf("DecodeAddressWithDiagnostics: got %v, want %v",
+			a.EncodeAddress(), addr)
+	}
+
+	tests := []struct {
+		name      string
+		in        string
+		positions []int
+	}{
+		{"substituted", addr[:10] + "x" + addr[11:], []int{10}},
+		{"invalid character", addr[:10] + "0" + addr[11:], []int{10}},
+		{"deleted", addr[:20] + addr[21:], nil},
+		{"inserted", addr[:5] + "z" + addr[5:], nil},
+	}
+	for _, test := range tests {
+		d := diagnose(t, test.in)
+		if d.Encoding != btcutil.EncodingBase58 {
+			t.Errorf("%s: got encoding %v, want %v", test.name, d.Encoding,
+				btcutil.EncodingBase58)
+		}
+		if d.Net != btcutil.MainNet {
+			t.Errorf("%s: got network %v, want %v", test.name, d.Net,
+				btcutil.MainNet.Name())
+		}
+		if !reflect.DeepEqual(d.Suggestions, []string{addr}) {
+			t.Errorf("%s: got suggestions %v, want [%s]", test.name,
+				d.Suggestions, addr)
+		}
+		if !reflect.DeepEqual(d.ErrorPositions, test.positions) {
+			t.Errorf("%s: got error positions %v, want %v", test.name,
+				d.ErrorPositions, test.positions)
+		}
+	}
+
+	d := diagnose(t, addr[:10]+"x"+addr[11:])
+	if !errors.Is(d, btcutil.ErrChecksumMismatch) {
+		t.Errorf("Unwrap: got error %v, want %v", d.Err,
+			btcutil.ErrChecksumMismatch)
+	}
+	want := btcutil.ErrChecksumMismatch.Error() + "; did you mean " + addr + "?"
+	if d.Error() != want {
+		t.Errorf("Error: got %q, want %q", d.Error(), want)
+	}
+
+	d = diagnose(t, "not an address")
+	if d.Encoding != btcutil.EncodingUnknown || d.Net != nil ||
+		d.Suggestions != nil || d.ErrorPositions != nil {
+
+		t.Errorf("garbage: got diagnostics %+v", d)
+	}
+}
+
+// TestDiagnoseBech32 ensures bech32 strings failing their checksum are
+// diagnosed with the network of their human-readable part and the position
+// of a substituted character.
+func TestDiagnoseBech32(t *testing.T) {
+	net := &btcutil.BasicNetParams{
+		Net:        "diagnosenet",
+		PubKeyHash: 0xf4,
+		ScriptHash: 0xf5,
+		HRP:        "dg",
+	}
+	err := btcutil.RegisterNetParams(net)
+	if err != nil && err != btcutil.ErrDuplicateNet {
+		t.Fatalf("RegisterNetParams: unexpected error: %v", err)
+	}
+	if registered, _ := btcutil.LookupNetParams(net.Net); registered != nil {
+		net = registered.(*btcutil.BasicNetParams)
+	}
+
+	data := make([]byte, 32)
+	for i := range data {
+		data[i] = byte(i)
+	}
+	valid, err := bech32.Encode(net.HRP, data)
+	if err != nil {
+		t.Fatalf("Encode: unexpected error: %v", err)
+	}
+
+	const pos = 12
+	corrupt := func(s string) string {
+		c := "q"
+		if s[pos] == c[0] {
+			c = "p"
+		}
+		return s[:pos] + c + s[pos+1:]
+	}
+	d := diagnose(t, corrupt(valid))
+	if d.Encoding != btcutil.EncodingBech32 {
+		t.Errorf("got encoding %v, want %v", d.Encoding,
+			btcutil.EncodingBech32)
+	}
+	if d.Net != btcutil.NetParams(net) {
+		t.Errorf("got network %v, want %v", d.Net, net.Net)
+	}
+	if !reflect.DeepEqual(d.ErrorPositions, []int{pos}) {
+		t.Errorf("got error positions %v, want [%d]", d.ErrorPositions, pos)
+	}
+	found := false
+	for _, s := range d.Suggestions {
+		found = found || s == valid
+	}
+	if !found {
+		t.Errorf("got suggestions %v, want %v among them", d.Suggestions,
+			valid)
+	}
+
+	// Upper case strings are diagnosed in lower case.
+	d = diagnose(t, strings.ToUpper(corrupt(valid)))
+	if d.Encoding != btcutil.EncodingBech32 || d.Net != btcutil.NetParams(net) {
+		t.Errorf("upper case: got diagnostics %+v", d)
+	}
+
+	// Strings of unknown networks are still diagnosed.
+	other, err := bech32.Encode("zz", data)
+	if err != nil {
+		t.Fatalf("Encode: unexpected error: %v", err)
+	}
+	d = diagnose(t, corrupt(other))
+	if d.Encoding != btcutil.EncodingBech32 || d.Net != nil {
+		t.Errorf("unknown network: got diagnostics %+v", d)
+	}
+}
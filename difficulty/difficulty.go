@@ -0,0 +1,184 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package difficulty provides the proof of work arithmetic indexers and light
+// clients need to validate a chain of headers without importing a full node:
+// the work of headers and of chains of them, difficulties, and the target
+// each block must meet.
+//
+// Targets are handled in their compact form, and converted with
+// btcutil.CompactToBig and btcutil.BigToCompact.  Rather than fixing the
+// retarget rules, the target of each block is computed by a Retargeter, so
+// the same validation serves every chain.  IntervalRetarget implements the
+// rule of adjusting the target every fixed number of blocks by the time
+// those blocks took, as btcd does.
+package difficulty
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/zeusyf/btcutil"
+)
+
+var (
+	// ErrUnexpectedBits describes an error where a header was mined at a
+	// target other than the one its retarget rules require.
+	ErrUnexpectedBits = errors.New("header target does not match " +
+		"retarget rules")
+
+	// ErrBrokenChain describes an error where the heights of headers are
+	// not consecutive.
+	ErrBrokenChain = errors.New("header heights are not consecutive")
+
+	// ErrInsufficientHistory describes an error where the target of a
+	// block is requested without the earlier headers it depends on.
+	ErrInsufficientHistory = errors.New("not enough headers to compute " +
+		"the target")
+
+	// ErrInvalidRetarget describes an error where the parameters of an
+	// IntervalRetarget can not yield a target, such as a zero interval or
+	// adjustment factor.
+	ErrInvalidRetarget = errors.New("invalid retarget parameters")
+)
+
+// Header holds the parts of a block header the retarget rules depend on.
+type Header struct {
+	Height    int32
+	Timestamp time.Time
+
+	// Bits is the compact form of the target the block was mined at.
+	Bits uint32
+}
+
+// Work returns the total work of the passed headers, which is the expected
+// number of hashes needed to mine them all.  Of two chains from a common
+// ancestor, the one whose headers since the ancestor have the most work is
+// the best chain.
+func Work(headers []Header) *big.Int {
+	work := new(big.Int)
+	for i := range headers {
+		work.Add(work, btcutil.CalcWork(headers[i].Bits))
+	}
+	return work
+}
+
+// Difficulty returns the difficulty of the target whose compact form is bits,
+// the multiple of the easiest target, powLimit, that it is.  Zero is returned
+// for a target which is not positive.
+func Difficulty(bits uint32, powLimit *big.Int) float64 {
+	target := btcutil.CompactToBig(bits)
+	if target.Sign() <= 0 {
+		return 0
+	}
+	d, _ := new(big.Rat).SetFrac(powLimit, target).Float64()
+	return d
+}
+
+// Retargeter computes the target of blocks under the retarget rules of a
+// chain.
+type Retargeter interface {
+	// NextBits returns the compact target of the block following the
+	// last of the passed headers, which are consecutive and end with
+	// the parent of the block.  Rules only needing the recent headers
+	// look at the end of the slice.
+	NextBits(prev []Header) (uint32, error)
+}
+
+// RetargetFunc is an adapter allowing the use of ordinary functions as a
+// Retargeter.
+type RetargetFunc func(prev []Header) (uint32, error)
+
+// NextBits calls f(prev).
+func (f RetargetFunc) NextBits(prev []Header) (uint32, error) {
+	return f(prev)
+}
+
+// Verify checks that each of headers, which follow prev, is mined at the
+// target the retarget rules require.  The heights of all headers must be
+// consecutive.  Verify does not check the proof of work of the headers, which
+// BlockHeader.CheckProofOfWork of package btcutil does against the targets.
+func Verify(prev, headers []Header, r Retargeter) error {
+	chain := make([]Header, 0, len(prev)+len(headers))
+	chain = append(chain, prev...)
+	for i := range headers {
+		h := &headers[i]
+		if len(chain) != 0 && h.Height != chain[len(chain)-1].Height+1 {
+			return ErrBrokenChain
+		}
+		bits, err := r.NextBits(chain)
+		if err != nil {
+			return err
+		}
+		if h.Bits != bits {
+			return fmt.Errorf("header at height %d has bits %08x, "+
+				"want %08x: %w", h.Height, h.Bits, bits,
+				ErrUnexpectedBits)
+		}
+		chain = append(chain, *h)
+	}
+	return nil
+}
+
+// IntervalRetarget is a Retargeter adjusting the target every Interval
+// blocks, by the ratio of the time the last Interval blocks took to
+// TargetTimespan.  The adjustment is limited to a factor of AdjustmentFactor
+// either way, and the target never exceeds PowLimit, which is also the
+// target of the genesis block.
+type IntervalRetarget struct {
+	PowLimit         *big.Int
+	Interval         int32
+	TargetTimespan   time.Duration
+	AdjustmentFactor int64
+}
+
+// validate returns ErrInvalidRetarget unless the proof of work limit is
+// positive, the interval and adjustment factor are at least one, and the
+// target timespan is at least a second.
+func (r *IntervalRetarget) validate() error {
+	if r.PowLimit == nil || r.PowLimit.Sign() <= 0 || r.Interval <= 0 ||
+		r.TargetTimespan < time.Second || r.AdjustmentFactor <= 0 {
+		return ErrInvalidRetarget
+	}
+	return nil
+}
+
+// NextBits returns the compact target of the block following prev.
+// ErrInvalidRetarget is returned when the parameters of r are invalid.
+func (r *IntervalRetarget) NextBits(prev []Header) (uint32, error) {
+	if err := r.validate(); err != nil {
+		return 0, err
+	}
+	if len(prev) == 0 {
+		return btcutil.BigToCompact(r.PowLimit), nil
+	}
+	last := &prev[len(prev)-1]
+	if (last.Height+1)%r.Interval != 0 {
+		return last.Bits, nil
+	}
+	if len(prev) < int(r.Interval) {
+		return 0, ErrInsufficientHistory
+	}
+	first := &prev[len(prev)-int(r.Interval)]
+
+	// Limit the adjustment by clamping the time the blocks took.
+	timespan := int64(last.Timestamp.Sub(first.Timestamp) / time.Second)
+	target := int64(r.TargetTimespan / time.Second)
+	if minTimespan := target / r.AdjustmentFactor; timespan < minTimespan {
+		timespan = minTimespan
+	} else if maxTimespan := target * r.AdjustmentFactor; timespan > maxTimespan {
+		timespan = maxTimespan
+	}
+
+	// newTarget = oldTarget * timespan / targetTimespan
+	newTarget := btcutil.CompactToBig(last.Bits)
+	newTarget.Mul(newTarget, big.NewInt(timespan))
+	newTarget.Div(newTarget, big.NewInt(target))
+	if newTarget.Cmp(r.PowLimit) > 0 {
+		newTarget.Set(r.PowLimit)
+	}
+	return btcutil.BigToCompact(newTarget), nil
+}
@@ -0,0 +1,176 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package difficulty_test
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/difficulty"
+)
+
+// limitBits is the compact form of the proof of work limit of the tests.
+const limitBits = 0x1d00ffff
+
+// testRetarget retargets every 4 blocks aiming for 10 minutes per block.
+var testRetarget = &difficulty.IntervalRetarget{
+	PowLimit:         btcutil.CompactToBig(limitBits),
+	Interval:         4,
+	TargetTimespan:   40 * time.Minute,
+	AdjustmentFactor: 4,
+}
+
+// testChain returns n headers from genesis, spaced by the passed durations in
+// turn and mined at the targets testRetarget requires.
+func testChain(t *testing.T, n int, spacing ...time.Duration) []difficulty.Header {
+	var headers []difficulty.Header
+	timestamp := time.Unix(1600000000, 0)
+	for i := 0; i < n; i++ {
+		bits, err := testRetarget.NextBits(headers)
+		if err != nil {
+			t.Fatalf("NextBits: unexpected error: %v", err)
+		}
+		headers = append(headers, difficulty.Header{
+			Height:    int32(i),
+			Timestamp: timestamp,
+			Bits:      bits,
+		})
+		timestamp = timestamp.Add(spacing[i%len(spacing)])
+	}
+	return headers
+}
+
+// TestWork ensures the work of headers adds up and difficulties are relative
+// to the proof of work limit.
+func TestWork(t *testing.T) {
+	headers := []difficulty.Header{{Bits: limitBits}, {Bits: 0x1c7fff80}, {Bits: 0}}
+	want := new(big.Int).Add(btcutil.CalcWork(limitBits), btcutil.CalcWork(0x1c7fff80))
+	if work := difficulty.Work(headers); work.Cmp(want) != 0 {
+		t.Errorf("Work: got %v, want %v", work, want)
+	}
+	if work := difficulty.Work(nil); work.Sign() != 0 {
+		t.Errorf("Work: got %v without headers", work)
+	}
+
+	powLimit := testRetarget.PowLimit
+	tests := []struct {
+		bits uint32
+		want float64
+	}{
+		{limitBits, 1},
+		{0x1c7fff80, 2},
+		{0x1c3fffc0, 4},
+		{0, 0},
+	}
+	for _, test := range tests {
+		if d := difficulty.Difficulty(test.bits, powLimit); d != test.want {
+			t.Errorf("Difficulty(%08x): got %v, want %v", test.bits, d,
+				test.want)
+		}
+	}
+}
+
+// TestIntervalRetarget ensures targets adjust every interval by the time the
+// interval took, within the adjustment factor and the proof of work limit.
+func TestIntervalRetarget(t *testing.T) {
+	tests := []struct {
+		name    string
+		spacing time.Duration
+		want    uint32
+	}{
+		// The three spacings between the first and last header of an
+		// interval take 20 minutes instead of 40, so the target halves.
+		{"fast", 20 * time.Minute / 3, 0x1c7fff80},
+		{"on time", 40 * time.Minute / 3, limitBits},
+		{"too slow", time.Hour, limitBits},
+		{"too fast", time.Second, 0x1c3fffc0},
+	}
+	for _, test := range tests {
+		headers := testChain(t, 5, test.spacing)
+		for _, h := range headers[:4] {
+			if h.Bits != limitBits {
+				t.Fatalf("%s: height %d has bits %08x before the first "+
+					"retarget", test.name, h.Height, h.Bits)
+			}
+		}
+		if got := headers[4].Bits; got != test.want {
+			t.Errorf("%s: got bits %08x, want %08x", test.name, got,
+				test.want)
+		}
+	}
+}
+
+// TestIntervalRetargetInvalid ensures parameters which can not yield a target
+// are rejected rather than dividing by zero.
+func TestIntervalRetargetInvalid(t *testing.T) {
+	headers := testChain(t, 4, 10*time.Minute)
+	tests := []struct {
+		name   string
+		modify func(r *difficulty.IntervalRetarget)
+	}{
+		{"no pow limit", func(r *difficulty.IntervalRetarget) { r.PowLimit = nil }},
+		{"zero pow limit", func(r *difficulty.IntervalRetarget) { r.PowLimit = new(big.Int) }},
+		{"zero interval", func(r *difficulty.IntervalRetarget) { r.Interval = 0 }},
+		{"negative interval", func(r *difficulty.IntervalRetarget) { r.Interval = -4 }},
+		{"zero timespan", func(r *difficulty.IntervalRetarget) { r.TargetTimespan = 0 }},
+		{"zero factor", func(r *difficulty.IntervalRetarget) { r.AdjustmentFactor = 0 }},
+	}
+	for _, test := range tests {
+		r := *testRetarget
+		test.modify(&r)
+		for _, prev := range [][]difficulty.Header{nil, headers} {
+			if _, err := r.NextBits(prev); err != difficulty.ErrInvalidRetarget {
+				t.Errorf("%s: NextBits: got error %v, want %v", test.name,
+					err, difficulty.ErrInvalidRetarget)
+			}
+		}
+	}
+}
+
+// TestVerify ensures chains mined at the required targets verify, and others
+// are rejected.
+func TestVerify(t *testing.T) {
+	headers := testChain(t, 12, 5*time.Minute, 10*time.Minute)
+	if err := difficulty.Verify(nil, headers, testRetarget); err != nil {
+		t.Fatalf("Verify: unexpected error: %v", err)
+	}
+	if err := difficulty.Verify(headers[:4], headers[4:], testRetarget); err != nil {
+		t.Errorf("Verify: unexpected error from height 4: %v", err)
+	}
+
+	tampered := append([]difficulty.Header(nil), headers...)
+	tampered[9].Bits = limitBits
+	if err := difficulty.Verify(nil, tampered, testRetarget); !errors.Is(err, difficulty.ErrUnexpectedBits) {
+		t.Errorf("Verify: got error %v, want %v", err,
+			difficulty.ErrUnexpectedBits)
+	}
+
+	gapped := append(append([]difficulty.Header(nil), headers[:5]...), headers[6:]...)
+	if err := difficulty.Verify(nil, gapped, testRetarget); err != difficulty.ErrBrokenChain {
+		t.Errorf("Verify: got error %v, want %v", err,
+			difficulty.ErrBrokenChain)
+	}
+
+	// The retarget at height 8 needs the headers from height 4.
+	if err := difficulty.Verify(headers[5:7], headers[7:], testRetarget); err != difficulty.ErrInsufficientHistory {
+		t.Errorf("Verify: got error %v, want %v", err,
+			difficulty.ErrInsufficientHistory)
+	}
+
+	// Other retarget rules plug in as functions.
+	fixed := difficulty.RetargetFunc(func([]difficulty.Header) (uint32, error) {
+		return limitBits, nil
+	})
+	if err := difficulty.Verify(nil, headers[:4], fixed); err != nil {
+		t.Errorf("Verify: unexpected error with fixed target: %v", err)
+	}
+	if err := difficulty.Verify(nil, headers, fixed); !errors.Is(err, difficulty.ErrUnexpectedBits) {
+		t.Errorf("Verify: got error %v with fixed target, want %v", err,
+			difficulty.ErrUnexpectedBits)
+	}
+}
@@ -0,0 +1,293 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package discovery finds the used addresses of a BIP0044 account, as a
+// wallet restored from its seed does before it knows which of its keys the
+// chain pays.
+//
+// An account derives the keys of receiving addresses from its external branch
+// and the keys of change addresses from its internal branch.  Wallets hand out
+// the addresses of each branch in order of index, and may hand out a number of
+// addresses, the gap limit, beyond the last one the chain pays.  A scan thus
+// checks the addresses of each branch in order until it finds as many
+// consecutive unused addresses as the gap limit.
+//
+// Whether an address was used is reported by a callback, such as a lookup in
+// an address index or a query to a block explorer.  As such lookups are
+// usually slow, a scan runs its callbacks concurrently: both branches are
+// scanned at once, and the addresses of a branch which are checked for sure
+// are checked in parallel.  The result of a scan does not depend on the
+// number of concurrent callbacks or on scheduling.
+package discovery
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/hdkeychain"
+)
+
+const (
+	// ExternalBranch is the branch of an account deriving the keys of
+	// receiving addresses.
+	ExternalBranch = 0
+
+	// InternalBranch is the branch of an account deriving the keys of
+	// change addresses.
+	InternalBranch = 1
+
+	// DefaultGapLimit is the number of consecutive unused addresses ending
+	// the scan of a branch when no gap limit is configured, as recommended
+	// by BIP0044.
+	DefaultGapLimit = 20
+
+	// DefaultConcurrency is the number of callbacks run concurrently when
+	// no concurrency is configured.
+	DefaultConcurrency = 8
+)
+
+// HistoryFunc returns whether addr appears in any transaction of the chain.
+// It is called concurrently from several goroutines.
+type HistoryFunc func(ctx context.Context, addr btcutil.Address) (bool, error)
+
+// AddressFunc returns the address of the serialized compressed public key
+// pubKey on the network net.
+type AddressFunc func(pubKey []byte, net *chaincfg.Params) (btcutil.Address, error)
+
+// PubKeyHashAddress is the AddressFunc of pay-to-pubkey-hash addresses, which
+// scans use by default.
+func PubKeyHashAddress(pubKey []byte, net *chaincfg.Params) (btcutil.Address, error) {
+	return btcutil.NewAddressPubKeyHash(btcutil.Hash160(pubKey), net)
+}
+
+// Result holds the used addresses found by a scan.
+type Result struct {
+	// External holds the indices of the used addresses of the external
+	// branch, in increasing order.
+	External []uint32
+
+	// Internal holds the indices of the used addresses of the internal
+	// branch, in increasing order.
+	Internal []uint32
+}
+
+// NextIndex returns the index following the last used address of branch,
+// which is the index of the next address a restored wallet hands out.
+func (r *Result) NextIndex(branch uint32) uint32 {
+	used := r.External
+	if branch == InternalBranch {
+		used = r.Internal
+	}
+	if len(used) == 0 {
+		return 0
+	}
+	return used[len(used)-1] + 1
+}
+
+// Option configures a scan.
+type Option func(*scanner)
+
+// WithGapLimit sets the number of consecutive unused addresses ending the
+// scan of a branch.  DefaultGapLimit is used by default, and when n is zero.
+func WithGapLimit(n uint32) Option {
+	return func(s *scanner) {
+		s.gapLimit = n
+	}
+}
+
+// WithConcurrency sets the number of callbacks run concurrently, shared by
+// both branches.  Values less than one select DefaultConcurrency, which is
+// the default.
+func WithConcurrency(n int) Option {
+	return func(s *scanner) {
+		s.concurrency = n
+	}
+}
+
+// WithAddressFunc sets the function returning the addresses checked for the
+// keys of the account, such as for accounts of witness or taproot outputs.
+// PubKeyHashAddress is used by default.
+func WithAddressFunc(f AddressFunc) Option {
+	return func(s *scanner) {
+		s.address = f
+	}
+}
+
+// scanner holds the configuration and state of a scan.
+type scanner struct {
+	net         *chaincfg.Params
+	hasHistory  HistoryFunc
+	gapLimit    uint32
+	concurrency int
+	address     AddressFunc
+	sem         chan struct{}
+	cancel      context.CancelFunc
+}
+
+// Scan returns the used addresses of both branches of the account key
+// account, which may be public, on the network net, as reported by
+// hasHistory.  The scan of each branch ends after as many consecutive unused
+// addresses as the gap limit.
+//
+// The scan stops at the first error returned by hasHistory or an AddressFunc,
+// which is returned, and the error of ctx is returned when it is done before
+// the scan ends.  The context passed to hasHistory is canceled when the scan
+// stops.
+func Scan(ctx context.Context, account *hdkeychain.ExtendedKey, net *chaincfg.Params,
+	hasHistory HistoryFunc, opts ...Option) (*Result, error) {
+	s := scanner{
+		net:         net,
+		hasHistory:  hasHistory,
+		gapLimit:    DefaultGapLimit,
+		concurrency: DefaultConcurrency,
+		address:     PubKeyHashAddress,
+	}
+	for _, opt := range opts {
+		opt(&s)
+	}
+	if s.gapLimit == 0 {
+		s.gapLimit = DefaultGapLimit
+	}
+	if s.concurrency < 1 {
+		s.concurrency = DefaultConcurrency
+	}
+	s.sem = make(chan struct{}, s.concurrency)
+
+	ctx, s.cancel = context.WithCancel(ctx)
+	defer s.cancel()
+
+	var (
+		wg     sync.WaitGroup
+		used   [2][]uint32
+		errs   [2]error
+		branch = [2]uint32{ExternalBranch, InternalBranch}
+	)
+	for i := range branch {
+		key, err := account.Child(branch[i])
+		if err != nil {
+			return nil, err
+		}
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			used[i], errs[i] = s.scanBranch(ctx, key)
+		}(i)
+	}
+	wg.Wait()
+
+	for _, err := range errs {
+		if err != nil && err != context.Canceled {
+			return nil, err
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return &Result{External: used[0], Internal: used[1]}, nil
+}
+
+// scanBranch returns the indices of the used addresses of the children of
+// key.  The addresses which must be checked, those within the gap limit of
+// the last used address found so far, are checked in parallel.
+func (s *scanner) scanBranch(ctx context.Context, key *hdkeychain.ExtendedKey) ([]uint32, error) {
+	d, err := hdkeychain.NewBulkDeriver(key, hdkeychain.WithWorkers(1))
+	if err != nil {
+		return nil, err
+	}
+
+	var used []uint32
+	next, end := uint64(0), uint64(s.gapLimit)
+	for {
+		if end > hdkeychain.HardenedKeyStart {
+			end = hdkeychain.HardenedKeyStart
+		}
+		if next >= end {
+			return used, nil
+		}
+		found, err := s.checkRange(ctx, d, uint32(next), int(end-next))
+		if err != nil {
+			return nil, err
+		}
+		used = append(used, found...)
+		next = end
+		if len(found) != 0 {
+			end = uint64(found[len(found)-1]) + 1 + uint64(s.gapLimit)
+		}
+	}
+}
+
+// checkRange returns the indices of the used addresses among the count
+// children of d starting at index start, in increasing order.  Invalid
+// children are skipped like callers of Child skip ErrInvalidChild.
+func (s *scanner) checkRange(ctx context.Context, d *hdkeychain.BulkDeriver,
+	start uint32, count int) ([]uint32, error) {
+	keys, err := d.PubKeysContext(ctx, start, count)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mtx      sync.Mutex
+		found    []uint32
+		firstErr error
+	)
+	fail := func(err error) {
+		mtx.Lock()
+		if firstErr == nil {
+			firstErr = err
+		}
+		mtx.Unlock()
+		s.cancel()
+	}
+	for i, key := range keys {
+		if key == nil {
+			continue
+		}
+		select {
+		case s.sem <- struct{}{}:
+		case <-ctx.Done():
+			fail(ctx.Err())
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(index uint32, key []byte) {
+			defer func() {
+				<-s.sem
+				wg.Done()
+			}()
+			addr, err := s.address(key, s.net)
+			if err != nil {
+				fail(err)
+				return
+			}
+			used, err := s.hasHistory(ctx, addr)
+			if err != nil {
+				fail(err)
+				return
+			}
+			if used {
+				mtx.Lock()
+				found = append(found, index)
+				mtx.Unlock()
+			}
+		}(start+uint32(i), key)
+	}
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	sort.Slice(found, func(i, j int) bool { return found[i] < found[j] })
+	return found, nil
+}
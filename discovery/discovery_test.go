@@ -0,0 +1,162 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package discovery_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/discovery"
+	"github.com/zeusyf/btcutil/hdkeychain"
+)
+
+// testAccount returns a public account key and the encoded addresses of the
+// passed indices of its branches.
+func testAccount(t *testing.T, used map[uint32][]uint32) (*hdkeychain.ExtendedKey, map[string]bool) {
+	master, err := hdkeychain.NewMaster(bytes.Repeat([]byte{7}, 32),
+		&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("NewMaster: unexpected error: %v", err)
+	}
+	account, err := master.Neuter()
+	if err != nil {
+		t.Fatalf("Neuter: unexpected error: %v", err)
+	}
+
+	addrs := make(map[string]bool)
+	for branch, indices := range used {
+		branchKey, err := account.Child(branch)
+		if err != nil {
+			t.Fatalf("Child: unexpected error: %v", err)
+		}
+		for _, index := range indices {
+			child, err := branchKey.Child(index)
+			if err != nil {
+				t.Fatalf("Child: unexpected error: %v", err)
+			}
+			addr, err := child.Address(&chaincfg.MainNetParams)
+			if err != nil {
+				t.Fatalf("Address: unexpected error: %v", err)
+			}
+			addrs[addr.EncodeAddress()] = true
+		}
+	}
+	return account, addrs
+}
+
+// TestScan ensures the used addresses of both branches within the gap limit
+// of each other are found, and the addresses beyond it are not.
+func TestScan(t *testing.T) {
+	account, addrs := testAccount(t, map[uint32][]uint32{
+		discovery.ExternalBranch: {0, 3, 8, 13, 30},
+		discovery.InternalBranch: {4},
+	})
+
+	var mtx sync.Mutex
+	checked := make(map[string]bool)
+	hasHistory := func(_ context.Context, addr btcutil.Address) (bool, error) {
+		mtx.Lock()
+		defer mtx.Unlock()
+		if checked[addr.EncodeAddress()] {
+			t.Errorf("address %v checked twice", addr)
+		}
+		checked[addr.EncodeAddress()] = true
+		return addrs[addr.EncodeAddress()], nil
+	}
+
+	for _, concurrency := range []int{1, 3, 16} {
+		checked = make(map[string]bool)
+		result, err := discovery.Scan(context.Background(), account,
+			&chaincfg.MainNetParams, hasHistory, discovery.WithGapLimit(5),
+			discovery.WithConcurrency(concurrency))
+		if err != nil {
+			t.Fatalf("Scan: unexpected error: %v", err)
+		}
+		want := &discovery.Result{
+			External: []uint32{0, 3, 8, 13},
+			Internal: []uint32{4},
+		}
+		if !reflect.DeepEqual(result, want) {
+			t.Errorf("concurrency %d: got %+v, want %+v", concurrency,
+				result, want)
+		}
+		// Both branches are checked up to the gap limit beyond their
+		// last used address.
+		if len(checked) != 19+10 {
+			t.Errorf("concurrency %d: checked %d addresses, want %d",
+				concurrency, len(checked), 19+10)
+		}
+		if n := result.NextIndex(discovery.ExternalBranch); n != 14 {
+			t.Errorf("external NextIndex: got %d, want 14", n)
+		}
+		if n := result.NextIndex(discovery.InternalBranch); n != 5 {
+			t.Errorf("internal NextIndex: got %d, want 5", n)
+		}
+	}
+
+	// A larger gap limit reaches the last address.
+	result, err := discovery.Scan(context.Background(), account,
+		&chaincfg.MainNetParams, func(_ context.Context,
+			addr btcutil.Address) (bool, error) {
+			return addrs[addr.EncodeAddress()], nil
+		})
+	if err != nil {
+		t.Fatalf("Scan: unexpected error: %v", err)
+	}
+	if n := result.NextIndex(discovery.ExternalBranch); n != 31 {
+		t.Errorf("default gap limit: got next index %d, want 31", n)
+	}
+}
+
+// TestScanErrors ensures scans stop at the first callback error and when
+// their context is canceled.
+func TestScanErrors(t *testing.T) {
+	account, _ := testAccount(t, nil)
+
+	errLookup := errors.New("lookup failed")
+	var calls int32
+	_, err := discovery.Scan(context.Background(), account,
+		&chaincfg.MainNetParams, func(ctx context.Context,
+			_ btcutil.Address) (bool, error) {
+			if atomic.AddInt32(&calls, 1) == 3 {
+				return false, errLookup
+			}
+			return false, ctx.Err()
+		}, discovery.WithConcurrency(1))
+	if err != errLookup {
+		t.Errorf("callback error: got %v, want %v", err, errLookup)
+	}
+	if n := atomic.LoadInt32(&calls); n >= 2*discovery.DefaultGapLimit {
+		t.Errorf("callback error: scan went on for %d calls", n)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = discovery.Scan(ctx, account, &chaincfg.MainNetParams,
+		func(context.Context, btcutil.Address) (bool, error) {
+			return true, nil
+		})
+	if err != context.Canceled {
+		t.Errorf("canceled context: got %v, want %v", err, context.Canceled)
+	}
+
+	errAddress := errors.New("no address")
+	_, err = discovery.Scan(context.Background(), account,
+		&chaincfg.MainNetParams, func(context.Context, btcutil.Address) (bool, error) {
+			return false, nil
+		}, discovery.WithAddressFunc(func([]byte, *chaincfg.Params) (btcutil.Address, error) {
+			return nil, errAddress
+		}))
+	if err != errAddress {
+		t.Errorf("address error: got %v, want %v", err, errAddress)
+	}
+}
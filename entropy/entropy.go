@@ -0,0 +1,89 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package entropy provides the source of randomness used by this library for
+// seed generation, signing nonce extra entropy, and randomized wallet
+// policies.
+//
+// By default randomness is read from crypto/rand.  Tests inject a
+// deterministic source with SetDefault, or pass one to the components which
+// accept a source of their own.  Deployments which must be certified against
+// NIST SP 800-90B wrap their source in a HealthCheckedSource, which
+// continuously tests its output and fails permanently once the source appears
+// to be broken.
+package entropy
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"io"
+	"sync/atomic"
+)
+
+// ErrInvalidRange describes an error where a random integer is requested
+// from an empty range.
+var ErrInvalidRange = errors.New("random integer range must be positive")
+
+// source wraps the default source so sources of different concrete types
+// can be stored in an atomic.Value.
+type source struct {
+	io.Reader
+}
+
+// defaultSource holds the source used when none is passed explicitly.
+var defaultSource atomic.Value // source
+
+func init() {
+	defaultSource.Store(source{rand.Reader})
+}
+
+// Default returns the source used by this library when none is configured.
+func Default() io.Reader {
+	return defaultSource.Load().(source).Reader
+}
+
+// SetDefault replaces the source used by this library when none is
+// configured.  Passing nil restores crypto/rand.  It is safe to call
+// concurrently with readers, which see either the old or the new source.
+func SetDefault(src io.Reader) {
+	if src == nil {
+		src = rand.Reader
+	}
+	defaultSource.Store(source{src})
+}
+
+// Read fills b with random bytes from src, or from the default source when
+// src is nil.  Unlike src.Read, it only returns a nil error when b was filled
+// completely.
+func Read(src io.Reader, b []byte) error {
+	if src == nil {
+		src = Default()
+	}
+	_, err := io.ReadFull(src, b)
+	return err
+}
+
+// Intn returns a uniformly distributed random integer in [0, n) read from
+// src, or from the default source when src is nil.
+func Intn(src io.Reader, n int) (int, error) {
+	if n <= 0 {
+		return 0, ErrInvalidRange
+	}
+
+	// Reject values from the incomplete range at the top of the uint64
+	// space so every result is equally likely.
+	bound := uint64(n)
+	limit := ^uint64(0) - (^uint64(0)%bound+1)%bound
+	var buf [8]byte
+	for {
+		if err := Read(src, buf[:]); err != nil {
+			return 0, err
+		}
+		v := binary.LittleEndian.Uint64(buf[:])
+		if v <= limit {
+			return int(v % bound), nil
+		}
+	}
+}
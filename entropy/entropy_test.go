@@ -0,0 +1,66 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package entropy_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"testing"
+
+	"github.com/zeusyf/btcutil/entropy"
+)
+
+// TestDefault ensures the default source can be replaced and restored.
+func TestDefault(t *testing.T) {
+	if entropy.Default() != rand.Reader {
+		t.Fatal("Default: crypto/rand is not the initial source")
+	}
+
+	entropy.SetDefault(bytes.NewReader([]byte{1, 2, 3}))
+	defer entropy.SetDefault(nil)
+
+	b := make([]byte, 2)
+	if err := entropy.Read(nil, b); err != nil || !bytes.Equal(b, []byte{1, 2}) {
+		t.Fatalf("Read: got %x, %v, want 0102", b, err)
+	}
+	if err := entropy.Read(nil, b); err != io.ErrUnexpectedEOF {
+		t.Fatalf("Read: got error %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+
+	entropy.SetDefault(nil)
+	if entropy.Default() != rand.Reader {
+		t.Fatal("SetDefault: nil did not restore crypto/rand")
+	}
+}
+
+// TestIntn ensures random integers are in range and values from the biased
+// top of the uint64 range are rejected.
+func TestIntn(t *testing.T) {
+	if _, err := entropy.Intn(nil, 0); err != entropy.ErrInvalidRange {
+		t.Fatalf("Intn: got error %v, want %v", err,
+			entropy.ErrInvalidRange)
+	}
+
+	// 2^64 - 1 lies in the incomplete top range for n = 10 and must be
+	// rejected in favor of the next value, 25.
+	src := bytes.NewReader([]byte{
+		0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff,
+		25, 0, 0, 0, 0, 0, 0, 0,
+	})
+	if v, err := entropy.Intn(src, 10); v != 5 || err != nil {
+		t.Fatalf("Intn: got %d, %v, want 5", v, err)
+	}
+	if _, err := entropy.Intn(src, 10); err != io.EOF {
+		t.Fatalf("Intn: got error %v, want %v", err, io.EOF)
+	}
+
+	for i := 0; i < 1000; i++ {
+		v, err := entropy.Intn(nil, 7)
+		if err != nil || v < 0 || v >= 7 {
+			t.Fatalf("Intn: got %d, %v, want value in [0, 7)", v, err)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package entropy
+
+import (
+	"errors"
+	"io"
+	"math"
+	"sync"
+)
+
+const (
+	// FalsePositiveExp is the negated base 2 logarithm of the probability
+	// that a health test fails for a given sample of a source working as
+	// assessed.  At 2^-40, a healthy source producing a gigabyte a day
+	// fails spuriously about once in three thousand years.
+	FalsePositiveExp = 40
+
+	// AdaptiveProportionWindow is the number of samples over which the
+	// adaptive proportion test counts repetitions of a value, as
+	// recommended by SP 800-90B for non-binary sources.
+	AdaptiveProportionWindow = 512
+)
+
+var (
+	// ErrInvalidMinEntropy describes an error where the assessed min-entropy
+	// of a source is not in the range (0, 8] bits per byte.
+	ErrInvalidMinEntropy = errors.New("min-entropy per byte must be " +
+		"greater than 0 and at most 8 bits")
+
+	// ErrRepetitionCount describes an error where a source produced the
+	// same byte more times in a row than its assessed min-entropy makes
+	// plausible.
+	ErrRepetitionCount = errors.New("entropy source failed the " +
+		"repetition count test")
+
+	// ErrAdaptiveProportion describes an error where a byte occurred
+	// within a window of a source's output more often than its assessed
+	// min-entropy makes plausible.
+	ErrAdaptiveProportion = errors.New("entropy source failed the " +
+		"adaptive proportion test")
+)
+
+// HealthCheckedSource wraps a source of random bytes with the continuous
+// health tests of NIST SP 800-90B section 4.4, treating each byte as a
+// sample: the repetition count test, which detects a source stuck on a
+// value, and the adaptive proportion test, which detects a source whose
+// output became heavily biased.
+//
+// The cutoffs of both tests follow from the min-entropy per byte the source
+// was assessed to have.  Once a test fails, the source is considered broken
+// and every further Read returns the error of the failed test, so a failure
+// can not go unnoticed.  Bytes of the read which triggered the failure are
+// zeroed rather than returned.
+//
+// A HealthCheckedSource is safe for concurrent use.
+type HealthCheckedSource struct {
+	mtx sync.Mutex
+	src io.Reader
+	err error
+
+	// Repetition count test state.
+	rctCutoff int
+	last      byte
+	run       int
+
+	// Adaptive proportion test state.
+	aptCutoff int
+	reference byte
+	count     int
+	index     int
+}
+
+// NewHealthCheckedSource returns a source which reads from src and tests its
+// output, assuming src provides minEntropy bits of min-entropy per byte.
+// Sources which have not been assessed should use a conservative estimate;
+// crypto/rand may be assumed to provide the full 8 bits.
+func NewHealthCheckedSource(src io.Reader, minEntropy float64) (*HealthCheckedSource, error) {
+	if !(minEntropy > 0 && minEntropy <= 8) {
+		return nil, ErrInvalidMinEntropy
+	}
+	return &HealthCheckedSource{
+		src:       src,
+		rctCutoff: repetitionCountCutoff(minEntropy),
+		aptCutoff: adaptiveProportionCutoff(minEntropy),
+		index:     AdaptiveProportionWindow,
+	}, nil
+}
+
+// repetitionCountCutoff returns the number of identical samples in a row at
+// which the repetition count test fails: C = 1 + ceil(FalsePositiveExp / H).
+func repetitionCountCutoff(minEntropy float64) int {
+	return 1 + int(math.Ceil(FalsePositiveExp/minEntropy))
+}
+
+// adaptiveProportionCutoff returns the number of occurrences of the first
+// sample of a window within the window at which the adaptive proportion test
+// fails.  It is the smallest count c for which a binomially distributed count
+// over the window, with success probability 2^-H, reaches c with probability
+// at most 2^-FalsePositiveExp, the equivalent of SP 800-90B's
+// 1 + CRITBINOM(W, 2^-H, 1 - alpha).
+func adaptiveProportionCutoff(minEntropy float64) int {
+	const w = AdaptiveProportionWindow
+	p := math.Exp2(-minEntropy)
+	alpha := math.Exp2(-FalsePositiveExp)
+
+	// Sum the probability mass from the top of the distribution down so
+	// the tiny tail probabilities don't lose precision.
+	lgW, _ := math.Lgamma(w + 1)
+	var tail float64
+	for c := w; c > 0; c-- {
+		lgC, _ := math.Lgamma(float64(c) + 1)
+		lgRest, _ := math.Lgamma(float64(w-c) + 1)
+		logPMF := lgW - lgC - lgRest + float64(c)*math.Log(p) +
+			float64(w-c)*math.Log1p(-p)
+		tail += math.Exp(logPMF)
+		if tail > alpha {
+			return c + 1
+		}
+	}
+	return 1
+}
+
+// Read fills p from the underlying source and tests every byte read.  Once a
+// health test fails, Read zeroes p and returns the test's error on this and
+// every further call.
+func (s *HealthCheckedSource) Read(p []byte) (int, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.err != nil {
+		zero(p)
+		return 0, s.err
+	}
+	n, err := s.src.Read(p)
+	for _, b := range p[:n] {
+		if s.err = s.test(b); s.err != nil {
+			zero(p)
+			return 0, s.err
+		}
+	}
+	return n, err
+}
+
+// Err returns the error of the failed health test, or nil when the source is
+// healthy.
+func (s *HealthCheckedSource) Err() error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.err
+}
+
+// test feeds a sample to both health tests.  It must be called with the lock
+// held.
+func (s *HealthCheckedSource) test(b byte) error {
+	// Repetition count test.
+	if s.run > 0 && b == s.last {
+		s.run++
+		if s.run >= s.rctCutoff {
+			return ErrRepetitionCount
+		}
+	} else {
+		s.last, s.run = b, 1
+	}
+
+	// Adaptive proportion test.  The first sample of every window is the
+	// reference whose occurrences are counted.
+	if s.index == AdaptiveProportionWindow {
+		s.reference, s.count, s.index = b, 1, 1
+		return nil
+	}
+	s.index++
+	if b == s.reference {
+		s.count++
+		if s.count >= s.aptCutoff {
+			return ErrAdaptiveProportion
+		}
+	}
+	return nil
+}
+
+// zero sets all bytes in the passed slice to zero.
+func zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package entropy_test
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"testing"
+
+	"github.com/zeusyf/btcutil/entropy"
+)
+
+// TestHealthCheckedSourceHealthy ensures a healthy source passes the health
+// tests and its output is returned unchanged.
+func TestHealthCheckedSourceHealthy(t *testing.T) {
+	data := make([]byte, 1<<20)
+	if _, err := rand.Read(data); err != nil {
+		t.Fatalf("rand.Read: unexpected error: %v", err)
+	}
+	s, err := entropy.NewHealthCheckedSource(bytes.NewReader(data), 8)
+	if err != nil {
+		t.Fatalf("NewHealthCheckedSource: unexpected error: %v", err)
+	}
+	got, err := io.ReadAll(s)
+	if err != nil || !bytes.Equal(got, data) {
+		t.Fatalf("ReadAll: got %d bytes, %v, want %d bytes", len(got), err,
+			len(data))
+	}
+	if s.Err() != nil {
+		t.Fatalf("Err: got %v, want nil", s.Err())
+	}
+}
+
+// TestHealthCheckedSourceFailures ensures stuck and biased sources fail the
+// health tests permanently.
+func TestHealthCheckedSourceFailures(t *testing.T) {
+	// With 8 bits of min-entropy per byte, the repetition count test
+	// allows 5 identical bytes in a row but not 6.
+	five := []byte{7, 7, 7, 7, 7, 8}
+	tests := []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{"five repetitions", five, nil},
+		{"stuck", bytes.Repeat([]byte{7}, 6), entropy.ErrRepetitionCount},
+		{"biased", bytes.Repeat([]byte{1, 2, 3, 4, 5}, 100),
+			entropy.ErrAdaptiveProportion},
+	}
+	for _, test := range tests {
+		s, err := entropy.NewHealthCheckedSource(
+			bytes.NewReader(test.data), 8)
+		if err != nil {
+			t.Fatalf("NewHealthCheckedSource: unexpected error: %v", err)
+		}
+
+		b := make([]byte, len(test.data))
+		n, err := s.Read(b)
+		if err != test.err {
+			t.Fatalf("%s: Read: got error %v, want %v", test.name, err,
+				test.err)
+		}
+		if err == nil {
+			if n != len(test.data) || !bytes.Equal(b, test.data) {
+				t.Fatalf("%s: Read: got %x, want %x", test.name,
+					b[:n], test.data)
+			}
+			continue
+		}
+		if n != 0 || !bytes.Equal(b, make([]byte, len(b))) {
+			t.Fatalf("%s: Read: got %d bytes %x after failure, want "+
+				"zeroed output", test.name, n, b)
+		}
+
+		// The failure is permanent.
+		if _, err := s.Read(b); err != test.err {
+			t.Fatalf("%s: Read: got error %v after failure, want %v",
+				test.name, err, test.err)
+		}
+		if s.Err() != test.err {
+			t.Fatalf("%s: Err: got %v, want %v", test.name, s.Err(),
+				test.err)
+		}
+	}
+
+	// Sources assessed to provide less entropy tolerate more repetition.
+	s, _ := entropy.NewHealthCheckedSource(
+		bytes.NewReader(bytes.Repeat([]byte{7}, 6)), 4)
+	if _, err := io.ReadAll(s); err != nil {
+		t.Fatalf("ReadAll: unexpected error: %v", err)
+	}
+
+	for _, h := range []float64{0, -1, 8.5} {
+		_, err := entropy.NewHealthCheckedSource(rand.Reader, h)
+		if err != entropy.ErrInvalidMinEntropy {
+			t.Fatalf("NewHealthCheckedSource(%v): got error %v, want %v",
+				h, err, entropy.ErrInvalidMinEntropy)
+		}
+	}
+}
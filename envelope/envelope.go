@@ -0,0 +1,177 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package envelope implements the versioned container used by the binary
+// formats of this module, such as backups, snapshots, proofs and filters.
+//
+// An envelope is a fixed header followed by a sequence of typed fields:
+//
+//	magic (4) || version (2, little endian) ||
+//	field type (varint) || field length (varint) || field value || ...
+//
+// The magic identifies the format, so a reader handed the wrong kind of file
+// fails right away instead of misinterpreting it.  The version is bumped when
+// a format changes in a way older readers can not handle, which makes them
+// fail with ErrUnsupportedVersion rather than produce garbage.  Changes that
+// older readers can safely ignore are made by adding new field types instead:
+// since every field carries its length, readers skip the types they do not
+// know, so newer writers stay compatible with older readers.
+package envelope
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/zeusyf/btcd/wire/common"
+)
+
+const (
+	// MagicSize is the size of the magic identifying a format.
+	MagicSize = 4
+
+	// DefaultMaxFieldSize is the largest field a Reader accepts unless
+	// configured otherwise.  It guards against allocating huge buffers
+	// for corrupt or malicious lengths.
+	DefaultMaxFieldSize = 32 * 1024 * 1024
+
+	// varIntProtoVer is the protocol version to use for serializing
+	// variable length integers.
+	varIntProtoVer uint32 = 0
+)
+
+var (
+	// ErrBadMagic describes an error where the data being read does not
+	// start with the magic of the expected format.
+	ErrBadMagic = errors.New("envelope magic mismatch")
+
+	// ErrUnsupportedVersion describes an error where the data being read
+	// was written with a newer version of the format than the reader
+	// understands.
+	ErrUnsupportedVersion = errors.New("unsupported envelope version")
+
+	// ErrFieldTooLarge describes an error where a field is larger than
+	// the reader's maximum field size.
+	ErrFieldTooLarge = errors.New("envelope field too large")
+
+	// ErrMalformedField describes an error where the value of a field
+	// does not have the size its type requires.
+	ErrMalformedField = errors.New("malformed envelope field")
+)
+
+// Writer writes an envelope field by field.
+type Writer struct {
+	w io.Writer
+}
+
+// NewWriter writes the envelope header for the format identified by magic at
+// the passed version to w and returns a Writer for its fields.
+func NewWriter(w io.Writer, magic [MagicSize]byte, version uint16) (*Writer, error) {
+	var hdr [MagicSize + 2]byte
+	copy(hdr[:], magic[:])
+	binary.LittleEndian.PutUint16(hdr[MagicSize:], version)
+	if _, err := w.Write(hdr[:]); err != nil {
+		return nil, err
+	}
+	return &Writer{w: w}, nil
+}
+
+// WriteField writes a field of the passed type.
+func (w *Writer) WriteField(fieldType uint64, value []byte) error {
+	err := common.WriteVarInt(w.w, varIntProtoVer, fieldType)
+	if err != nil {
+		return err
+	}
+	err = common.WriteVarInt(w.w, varIntProtoVer, uint64(len(value)))
+	if err != nil {
+		return err
+	}
+	_, err = w.w.Write(value)
+	return err
+}
+
+// WriteUint32 writes a field holding a little endian uint32.
+func (w *Writer) WriteUint32(fieldType uint64, v uint32) error {
+	var buf [4]byte
+	binary.LittleEndian.PutUint32(buf[:], v)
+	return w.WriteField(fieldType, buf[:])
+}
+
+// Reader reads an envelope field by field.
+type Reader struct {
+	r       io.Reader
+	version uint16
+
+	// MaxFieldSize is the largest field Next accepts.  It is set to
+	// DefaultMaxFieldSize by NewReader.
+	MaxFieldSize uint64
+}
+
+// NewReader reads the envelope header from r and returns a Reader for its
+// fields.  ErrBadMagic is returned when the envelope is not of the format
+// identified by magic and ErrUnsupportedVersion when its version is newer
+// than maxVersion.
+func NewReader(r io.Reader, magic [MagicSize]byte, maxVersion uint16) (*Reader, error) {
+	var hdr [MagicSize + 2]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, err
+	}
+	if [MagicSize]byte{hdr[0], hdr[1], hdr[2], hdr[3]} != magic {
+		return nil, ErrBadMagic
+	}
+	version := binary.LittleEndian.Uint16(hdr[MagicSize:])
+	if version > maxVersion {
+		return nil, ErrUnsupportedVersion
+	}
+	return &Reader{
+		r:            r,
+		version:      version,
+		MaxFieldSize: DefaultMaxFieldSize,
+	}, nil
+}
+
+// Version returns the version the envelope was written with.
+func (r *Reader) Version() uint16 {
+	return r.version
+}
+
+// Next returns the type and value of the next field.  io.EOF is returned when
+// there are no more fields, and io.ErrUnexpectedEOF when the envelope ends in
+// the middle of a field.  Callers should ignore fields of types they do not
+// know.
+func (r *Reader) Next() (uint64, []byte, error) {
+	fieldType, err := common.ReadVarInt(r.r, varIntProtoVer)
+	if err != nil {
+		return 0, nil, err
+	}
+	length, err := common.ReadVarInt(r.r, varIntProtoVer)
+	if err != nil {
+		return 0, nil, unexpectedEOF(err)
+	}
+	if length > r.MaxFieldSize {
+		return 0, nil, ErrFieldTooLarge
+	}
+	value := make([]byte, length)
+	if _, err := io.ReadFull(r.r, value); err != nil {
+		return 0, nil, unexpectedEOF(err)
+	}
+	return fieldType, value, nil
+}
+
+// unexpectedEOF converts io.EOF to io.ErrUnexpectedEOF since the envelope
+// ending within a field is an error.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// Uint32 decodes the value of a field written by WriteUint32.
+func Uint32(value []byte) (uint32, error) {
+	if len(value) != 4 {
+		return 0, ErrMalformedField
+	}
+	return binary.LittleEndian.Uint32(value), nil
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package envelope_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/zeusyf/btcutil/envelope"
+)
+
+var testMagic = [envelope.MagicSize]byte{'t', 'e', 's', 't'}
+
+// TestEnvelopeRoundTrip ensures fields are read back as written, including
+// fields a reader does not know, which it can skip.
+func TestEnvelopeRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	w, err := envelope.NewWriter(&buf, testMagic, 3)
+	if err != nil {
+		t.Fatalf("NewWriter: unexpected error: %v", err)
+	}
+	large := bytes.Repeat([]byte{0xaa}, 300)
+	fields := []struct {
+		fieldType uint64
+		value     []byte
+	}{
+		{1, []byte("known")},
+		{1000, large},
+		{2, nil},
+	}
+	for _, f := range fields {
+		if err := w.WriteField(f.fieldType, f.value); err != nil {
+			t.Fatalf("WriteField: unexpected error: %v", err)
+		}
+	}
+	if err := w.WriteUint32(3, 0xdeadbeef); err != nil {
+		t.Fatalf("WriteUint32: unexpected error: %v", err)
+	}
+
+	r, err := envelope.NewReader(bytes.NewReader(buf.Bytes()), testMagic, 3)
+	if err != nil {
+		t.Fatalf("NewReader: unexpected error: %v", err)
+	}
+	if r.Version() != 3 {
+		t.Fatalf("Version: got %d, want 3", r.Version())
+	}
+	for _, f := range fields {
+		fieldType, value, err := r.Next()
+		if err != nil {
+			t.Fatalf("Next: unexpected error: %v", err)
+		}
+		if fieldType != f.fieldType || !bytes.Equal(value, f.value) {
+			t.Fatalf("Next: got field %d %x, want %d %x", fieldType,
+				value, f.fieldType, f.value)
+		}
+	}
+	_, value, err := r.Next()
+	if err != nil {
+		t.Fatalf("Next: unexpected error: %v", err)
+	}
+	if v, err := envelope.Uint32(value); err != nil || v != 0xdeadbeef {
+		t.Fatalf("Uint32: got %x, %v, want deadbeef", v, err)
+	}
+	if _, _, err := r.Next(); err != io.EOF {
+		t.Fatalf("Next: got error %v at end, want %v", err, io.EOF)
+	}
+	if _, err := envelope.Uint32([]byte{1, 2}); err != envelope.ErrMalformedField {
+		t.Fatalf("Uint32: got error %v, want %v", err,
+			envelope.ErrMalformedField)
+	}
+}
+
+// TestEnvelopeErrors ensures readers reject other formats, newer versions,
+// oversized fields, and truncated data.
+func TestEnvelopeErrors(t *testing.T) {
+	var buf bytes.Buffer
+	w, _ := envelope.NewWriter(&buf, testMagic, 2)
+	w.WriteField(1, bytes.Repeat([]byte{1}, 100))
+	data := buf.Bytes()
+
+	other := [envelope.MagicSize]byte{'o', 't', 'h', 'r'}
+	_, err := envelope.NewReader(bytes.NewReader(data), other, 2)
+	if err != envelope.ErrBadMagic {
+		t.Errorf("NewReader: got error %v, want %v", err,
+			envelope.ErrBadMagic)
+	}
+	_, err = envelope.NewReader(bytes.NewReader(data), testMagic, 1)
+	if err != envelope.ErrUnsupportedVersion {
+		t.Errorf("NewReader: got error %v, want %v", err,
+			envelope.ErrUnsupportedVersion)
+	}
+
+	r, _ := envelope.NewReader(bytes.NewReader(data), testMagic, 2)
+	r.MaxFieldSize = 99
+	if _, _, err := r.Next(); err != envelope.ErrFieldTooLarge {
+		t.Errorf("Next: got error %v, want %v", err,
+			envelope.ErrFieldTooLarge)
+	}
+
+	r, _ = envelope.NewReader(bytes.NewReader(data[:len(data)-1]),
+		testMagic, 2)
+	if _, _, err := r.Next(); err != io.ErrUnexpectedEOF {
+		t.Errorf("Next: got error %v, want %v", err, io.ErrUnexpectedEOF)
+	}
+}
@@ -0,0 +1,246 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package escrow implements 2-of-3 escrow between a buyer, a seller, and an
+// arbiter settling disputes between them.
+//
+// The buyer funds a pay-to-script-hash address of a 2-of-3 multi-signature
+// script over the keys of all three.  Normally the buyer and seller agree and
+// sign a settlement releasing the funds to the seller, or refunding them to
+// the buyer.  Should they disagree, either of them raises a dispute, and the
+// arbiter signs the settlement along with the party it sides with.
+//
+// An Escrow tracks the state of one such arrangement from the transactions
+// observed funding and settling it, and builds the settlement transactions as
+// partially signed transactions for the signers to pass between them.
+package escrow
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcd/txscript"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/multisig"
+	"github.com/zeusyf/omega/token"
+)
+
+// RequiredSigs is the number of the three keys whose signatures settle an
+// escrow.
+const RequiredSigs = 2
+
+var (
+	// ErrInvalidTransition describes an error where an escrow is moved to
+	// a state which can not follow its current one, such as releasing
+	// funds which were already refunded.
+	ErrInvalidTransition = errors.New("invalid escrow state transition")
+
+	// ErrNotFunding describes an error where a transaction observed as
+	// funding an escrow has no output paying the base token to its
+	// address.
+	ErrNotFunding = errors.New("transaction does not fund the escrow")
+
+	// ErrNotSettlement describes an error where a transaction observed as
+	// settling an escrow does not spend its funds to the buyer or the
+	// seller.
+	ErrNotSettlement = errors.New("transaction does not settle the escrow")
+)
+
+// State is the state of an escrow.
+type State uint8
+
+const (
+	// StateCreated is the state of an escrow whose funds have not been
+	// observed yet.
+	StateCreated State = iota
+
+	// StateFunded is the state of an escrow holding the funds of the
+	// buyer.
+	StateFunded
+
+	// StateDisputed is the state of a funded escrow whose settlement is
+	// left to the arbiter.
+	StateDisputed
+
+	// StateReleased is the state of an escrow whose funds were paid to
+	// the seller.
+	StateReleased
+
+	// StateRefunded is the state of an escrow whose funds were returned
+	// to the buyer.
+	StateRefunded
+)
+
+// stateStrings is a map of escrow states back to their constant names for
+// pretty printing.
+var stateStrings = map[State]string{
+	StateCreated:  "StateCreated",
+	StateFunded:   "StateFunded",
+	StateDisputed: "StateDisputed",
+	StateReleased: "StateReleased",
+	StateRefunded: "StateRefunded",
+}
+
+// String returns the State as a human-readable name.
+func (s State) String() string {
+	if str, ok := stateStrings[s]; ok {
+		return str
+	}
+	return "Unknown State"
+}
+
+// IsSettled returns whether the escrow funds were paid out.
+func (s State) IsSettled() bool {
+	return s == StateReleased || s == StateRefunded
+}
+
+// Template holds the keys of the parties to an escrow.
+type Template struct {
+	Buyer   *btcec.PublicKey
+	Seller  *btcec.PublicKey
+	Arbiter *btcec.PublicKey
+}
+
+// RedeemScript returns the 2-of-3 multi-signature redeem script of the
+// escrow.  The keys are sorted, so the script does not depend on which
+// party holds which key.
+func (t *Template) RedeemScript() ([]byte, error) {
+	return multisig.RedeemScript(RequiredSigs,
+		[]*btcec.PublicKey{t.Buyer, t.Seller, t.Arbiter})
+}
+
+// Address returns the pay-to-script-hash address the buyer funds, along with
+// the redeem script needed to spend from it.
+func (t *Template) Address(net *chaincfg.Params) (*btcutil.AddressScriptHash, []byte, error) {
+	return multisig.AddressScriptHash(RequiredSigs,
+		[]*btcec.PublicKey{t.Buyer, t.Seller, t.Arbiter}, net)
+}
+
+// Escrow tracks one escrow from its funding to its settlement.  The zero
+// value is not usable; an Escrow must be created with New.
+type Escrow struct {
+	template     Template
+	redeemScript []byte
+	pkScript     []byte
+	buyerScript  []byte
+	sellerScript []byte
+
+	state    State
+	fundTx   *wire.MsgTx
+	outPoint wire.OutPoint
+	amount   btcutil.Amount
+}
+
+// New returns an escrow between the parties of the template, which pays
+// buyerAddr on a refund and sellerAddr on a release.
+func New(t *Template, buyerAddr, sellerAddr btcutil.Address, net *chaincfg.Params) (*Escrow, error) {
+	addr, redeemScript, err := t.Address(net)
+	if err != nil {
+		return nil, err
+	}
+	pkScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		return nil, err
+	}
+	buyerScript, err := txscript.PayToAddrScript(buyerAddr)
+	if err != nil {
+		return nil, err
+	}
+	sellerScript, err := txscript.PayToAddrScript(sellerAddr)
+	if err != nil {
+		return nil, err
+	}
+	return &Escrow{
+		template:     *t,
+		redeemScript: redeemScript,
+		pkScript:     pkScript,
+		buyerScript:  buyerScript,
+		sellerScript: sellerScript,
+	}, nil
+}
+
+// State returns the current state of the escrow.
+func (e *Escrow) State() State {
+	return e.state
+}
+
+// PkScript returns the public key script paying to the escrow address.
+func (e *Escrow) PkScript() []byte {
+	return e.pkScript
+}
+
+// RedeemScript returns the redeem script spending the escrow funds.
+func (e *Escrow) RedeemScript() []byte {
+	return e.redeemScript
+}
+
+// Funds returns the outpoint and amount held by a funded escrow.
+func (e *Escrow) Funds() (wire.OutPoint, btcutil.Amount) {
+	return e.outPoint, e.amount
+}
+
+// Fund records tx as funding the escrow, which moves it to StateFunded.  The
+// first output of tx paying the base token to the escrow address holds the
+// funds.
+func (e *Escrow) Fund(tx *wire.MsgTx) error {
+	if e.state != StateCreated {
+		return ErrInvalidTransition
+	}
+	for i, txOut := range tx.TxOut {
+		value, ok := txOut.Token.Value.(*token.NumeralVal)
+		if txOut.Token.TokenType != 0 || !ok || value.Val <= 0 ||
+			!bytes.Equal(txOut.PkScript, e.pkScript) {
+			continue
+		}
+		e.state = StateFunded
+		e.fundTx = tx
+		e.outPoint = wire.OutPoint{Hash: tx.TxHash(), Index: uint32(i)}
+		e.amount = btcutil.Amount(value.Val)
+		return nil
+	}
+	return ErrNotFunding
+}
+
+// Dispute moves a funded escrow to StateDisputed, after which the arbiter
+// is expected to sign its settlement.
+func (e *Escrow) Dispute() error {
+	if e.state != StateFunded {
+		return ErrInvalidTransition
+	}
+	e.state = StateDisputed
+	return nil
+}
+
+// Settle records tx as spending the escrow funds, which moves the escrow to
+// StateReleased when tx pays the seller, or StateRefunded when it pays the
+// buyer.
+func (e *Escrow) Settle(tx *wire.MsgTx) error {
+	if e.state != StateFunded && e.state != StateDisputed {
+		return ErrInvalidTransition
+	}
+	spends := false
+	for _, txIn := range tx.TxIn {
+		if txIn.PreviousOutPoint == e.outPoint {
+			spends = true
+			break
+		}
+	}
+	if !spends {
+		return ErrNotSettlement
+	}
+	for _, txOut := range tx.TxOut {
+		switch {
+		case bytes.Equal(txOut.PkScript, e.sellerScript):
+			e.state = StateReleased
+			return nil
+		case bytes.Equal(txOut.PkScript, e.buyerScript):
+			e.state = StateRefunded
+			return nil
+		}
+	}
+	return ErrNotSettlement
+}
@@ -0,0 +1,180 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package escrow_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/escrow"
+	"github.com/zeusyf/btcutil/multisig"
+	"github.com/zeusyf/omega/token"
+)
+
+// pubKey returns the public key of the private key b.
+func pubKey(b byte) *btcec.PublicKey {
+	_, pub := btcec.PrivKeyFromBytes(btcec.S256(), []byte{b})
+	return pub
+}
+
+// payoutAddress returns the pay-to-pubkey-hash address of pub.
+func payoutAddress(t *testing.T, pub *btcec.PublicKey) btcutil.Address {
+	addr, err := btcutil.NewAddressPubKeyHash(
+		btcutil.Hash160(pub.SerializeCompressed()), &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("NewAddressPubKeyHash: unexpected error: %v", err)
+	}
+	return addr
+}
+
+// testEscrow returns an escrow between the keys 1, 2 and 3 paying their
+// pay-to-pubkey-hash addresses.
+func testEscrow(t *testing.T) *escrow.Escrow {
+	tmpl := &escrow.Template{Buyer: pubKey(1), Seller: pubKey(2), Arbiter: pubKey(3)}
+	e, err := escrow.New(tmpl, payoutAddress(t, tmpl.Buyer),
+		payoutAddress(t, tmpl.Seller), &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("New: unexpected error: %v", err)
+	}
+	return e
+}
+
+// fundingTx returns a transaction paying value of the passed token type to
+// pkScript as its second output.
+func fundingTx(tokenType uint64, value int64, pkScript []byte) *wire.MsgTx {
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(&wire.TxIn{SignatureIndex: 0xffffffff})
+	tx.AddTxOut(&wire.TxOut{
+		Token:    token.Token{Value: &token.NumeralVal{Val: 1000}},
+		PkScript: []byte{0x51},
+	})
+	tx.AddTxOut(&wire.TxOut{
+		Token:    token.Token{TokenType: tokenType, Value: &token.NumeralVal{Val: value}},
+		PkScript: pkScript,
+	})
+	return tx
+}
+
+// TestTemplate ensures the escrow address commits to a 2-of-3 script of the
+// three keys.
+func TestTemplate(t *testing.T) {
+	tmpl := &escrow.Template{Buyer: pubKey(1), Seller: pubKey(2), Arbiter: pubKey(3)}
+	redeemScript, err := tmpl.RedeemScript()
+	if err != nil {
+		t.Fatalf("RedeemScript: unexpected error: %v", err)
+	}
+	want, err := multisig.RedeemScript(2, []*btcec.PublicKey{pubKey(3), pubKey(1), pubKey(2)})
+	if err != nil {
+		t.Fatalf("multisig.RedeemScript: unexpected error: %v", err)
+	}
+	if !bytes.Equal(redeemScript, want) {
+		t.Errorf("RedeemScript: got %x, want %x", redeemScript, want)
+	}
+
+	addr, script, err := tmpl.Address(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("Address: unexpected error: %v", err)
+	}
+	if !bytes.Equal(script, redeemScript) ||
+		!bytes.Equal(addr.ScriptAddress(), btcutil.Hash160(redeemScript)) {
+		t.Errorf("Address: got %x for script %x", addr.ScriptAddress(), script)
+	}
+
+	tmpl.Arbiter = tmpl.Buyer
+	if _, err := tmpl.RedeemScript(); err != multisig.ErrDuplicatePubKey {
+		t.Errorf("RedeemScript: got error %v, want %v", err,
+			multisig.ErrDuplicatePubKey)
+	}
+}
+
+// TestStateTransitions ensures escrows move through their states as their
+// funding and settlement are observed, and reject transitions out of order.
+func TestStateTransitions(t *testing.T) {
+	e := testEscrow(t)
+	if e.State() != escrow.StateCreated {
+		t.Fatalf("New: got state %v", e.State())
+	}
+	if err := e.Dispute(); err != escrow.ErrInvalidTransition {
+		t.Errorf("Dispute: got error %v, want %v", err,
+			escrow.ErrInvalidTransition)
+	}
+
+	// Only the base token paid to the escrow address funds it.
+	for _, tx := range []*wire.MsgTx{
+		fundingTx(0, 50000, []byte{0x52}),
+		fundingTx(4, 50000, e.PkScript()),
+		fundingTx(0, 0, e.PkScript()),
+	} {
+		if err := e.Fund(tx); err != escrow.ErrNotFunding {
+			t.Errorf("Fund: got error %v, want %v", err,
+				escrow.ErrNotFunding)
+		}
+	}
+	fundTx := fundingTx(0, 50000, e.PkScript())
+	if err := e.Fund(fundTx); err != nil {
+		t.Fatalf("Fund: unexpected error: %v", err)
+	}
+	op, amount := e.Funds()
+	if e.State() != escrow.StateFunded || op.Hash != fundTx.TxHash() ||
+		op.Index != 1 || amount != 50000 {
+		t.Errorf("Fund: got state %v, outpoint %v and amount %d",
+			e.State(), op, amount)
+	}
+	if err := e.Fund(fundTx); err != escrow.ErrInvalidTransition {
+		t.Errorf("Fund: got error %v, want %v", err,
+			escrow.ErrInvalidTransition)
+	}
+
+	if err := e.Dispute(); err != nil || e.State() != escrow.StateDisputed {
+		t.Fatalf("Dispute: got state %v and error %v", e.State(), err)
+	}
+
+	// A settlement must spend the funds to the buyer or the seller.
+	packet, err := e.Release(1000)
+	if err != nil {
+		t.Fatalf("Release: unexpected error: %v", err)
+	}
+	unrelated := wire.NewMsgTx(wire.TxVersion)
+	unrelated.AddTxIn(&wire.TxIn{})
+	unrelated.TxOut = packet.UnsignedTx.TxOut
+	if err := e.Settle(unrelated); err != escrow.ErrNotSettlement {
+		t.Errorf("Settle: got error %v, want %v", err,
+			escrow.ErrNotSettlement)
+	}
+	if err := e.Settle(packet.UnsignedTx); err != nil {
+		t.Fatalf("Settle: unexpected error: %v", err)
+	}
+	if e.State() != escrow.StateReleased || !e.State().IsSettled() {
+		t.Errorf("Settle: got state %v", e.State())
+	}
+	if err := e.Settle(packet.UnsignedTx); err != escrow.ErrInvalidTransition {
+		t.Errorf("Settle: got error %v, want %v", err,
+			escrow.ErrInvalidTransition)
+	}
+}
+
+// TestStateStringer tests the stringized output for the State type.
+func TestStateStringer(t *testing.T) {
+	tests := []struct {
+		in   escrow.State
+		want string
+	}{
+		{escrow.StateCreated, "StateCreated"},
+		{escrow.StateFunded, "StateFunded"},
+		{escrow.StateDisputed, "StateDisputed"},
+		{escrow.StateReleased, "StateReleased"},
+		{escrow.StateRefunded, "StateRefunded"},
+		{0xff, "Unknown State"},
+	}
+	for i, test := range tests {
+		if got := test.in.String(); got != test.want {
+			t.Errorf("String #%d: got %s, want %s", i, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package escrow
+
+import (
+	"errors"
+
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/psbt"
+	"github.com/zeusyf/btcutil/txbuilder"
+)
+
+// ErrInvalidFee describes an error where the fee of a settlement is negative
+// or leaves nothing of the escrow funds.
+var ErrInvalidFee = errors.New("fee must be less than the escrow funds")
+
+// Release returns the settlement paying the escrow funds less fee to the
+// seller, for the buyer and seller, or the arbiter and either of them, to
+// sign.  The options configure the transaction as for txbuilder.New.
+func (e *Escrow) Release(fee btcutil.Amount, opts ...txbuilder.Option) (*psbt.Packet, error) {
+	return e.settlement(e.sellerScript, fee, opts)
+}
+
+// Refund returns the settlement paying the escrow funds less fee back to the
+// buyer, for the buyer and seller, or the arbiter and either of them, to
+// sign.  The options configure the transaction as for txbuilder.New.
+func (e *Escrow) Refund(fee btcutil.Amount, opts ...txbuilder.Option) (*psbt.Packet, error) {
+	return e.settlement(e.buyerScript, fee, opts)
+}
+
+// settlement returns the packet of a transaction paying the escrow funds less
+// fee to pkScript.  The input carries the funding transaction and the redeem
+// script, which signers need to sign it.
+func (e *Escrow) settlement(pkScript []byte, fee btcutil.Amount, opts []txbuilder.Option) (*psbt.Packet, error) {
+	if e.state != StateFunded && e.state != StateDisputed {
+		return nil, ErrInvalidTransition
+	}
+	if fee < 0 || fee >= e.amount {
+		return nil, ErrInvalidFee
+	}
+
+	tx, err := txbuilder.New(opts...).
+		AddInput(e.outPoint).
+		AddOutput(pkScript, e.amount-fee).
+		Build()
+	if err != nil {
+		return nil, err
+	}
+	packet, err := psbt.New(tx)
+	if err != nil {
+		return nil, err
+	}
+	packet.Inputs[0].NonWitnessUtxo = e.fundTx
+	packet.Inputs[0].RedeemScript = e.redeemScript
+	return packet, nil
+}
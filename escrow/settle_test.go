@@ -0,0 +1,90 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package escrow_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcd/txscript"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/escrow"
+	"github.com/zeusyf/btcutil/psbt"
+	"github.com/zeusyf/btcutil/txbuilder"
+	"github.com/zeusyf/omega/token"
+)
+
+// payoutScript returns the public key script paying the pay-to-pubkey-hash
+// address of pub.
+func payoutScript(t *testing.T, pub *btcec.PublicKey) []byte {
+	pkScript, err := txscript.PayToAddrScript(payoutAddress(t, pub))
+	if err != nil {
+		t.Fatalf("PayToAddrScript: unexpected error: %v", err)
+	}
+	return pkScript
+}
+
+// TestSettlement ensures settlements spend the escrow funds to the seller or
+// the buyer, and carry what signers need.
+func TestSettlement(t *testing.T) {
+	e := testEscrow(t)
+	if _, err := e.Refund(1000); err != escrow.ErrInvalidTransition {
+		t.Errorf("Refund: got error %v, want %v", err,
+			escrow.ErrInvalidTransition)
+	}
+	fundTx := fundingTx(0, 50000, e.PkScript())
+	if err := e.Fund(fundTx); err != nil {
+		t.Fatalf("Fund: unexpected error: %v", err)
+	}
+	op, _ := e.Funds()
+
+	tests := []struct {
+		name     string
+		build    func(btcutil.Amount, ...txbuilder.Option) (*psbt.Packet, error)
+		pkScript []byte
+	}{
+		{"Release", e.Release, payoutScript(t, pubKey(2))},
+		{"Refund", e.Refund, payoutScript(t, pubKey(1))},
+	}
+	for _, test := range tests {
+		packet, err := test.build(1000, txbuilder.WithLockTime(7))
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", test.name, err)
+		}
+		tx := packet.UnsignedTx
+		if len(tx.TxIn) != 1 || tx.TxIn[0].PreviousOutPoint != op ||
+			len(tx.TxOut) != 1 || tx.LockTime != 7 {
+			t.Fatalf("%s: got transaction %+v", test.name, tx)
+		}
+		out := tx.TxOut[0]
+		if value := out.Token.Value.(*token.NumeralVal).Val; value != 49000 ||
+			!bytes.Equal(out.PkScript, test.pkScript) {
+			t.Errorf("%s: got output of %d to %x", test.name, value,
+				out.PkScript)
+		}
+		in := packet.Inputs[0]
+		if in.NonWitnessUtxo != fundTx ||
+			!bytes.Equal(in.RedeemScript, e.RedeemScript()) {
+			t.Errorf("%s: input lacks the funding transaction or "+
+				"redeem script", test.name)
+		}
+	}
+
+	for _, fee := range []btcutil.Amount{-1, 50000} {
+		if _, err := e.Release(btcutil.Amount(fee)); err != escrow.ErrInvalidFee {
+			t.Errorf("Release(%d): got error %v, want %v", fee, err,
+				escrow.ErrInvalidFee)
+		}
+	}
+
+	packet, err := e.Refund(1000)
+	if err != nil {
+		t.Fatalf("Refund: unexpected error: %v", err)
+	}
+	if err := e.Settle(packet.UnsignedTx); err != nil || e.State() != escrow.StateRefunded {
+		t.Errorf("Settle: got state %v and error %v", e.State(), err)
+	}
+}
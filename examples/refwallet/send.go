@@ -0,0 +1,204 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package refwallet
+
+import (
+	"bytes"
+
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/coinset"
+	"github.com/zeusyf/btcutil/psbt"
+	"github.com/zeusyf/btcutil/signing"
+	"github.com/zeusyf/btcutil/taproot"
+	"github.com/zeusyf/btcutil/txbuilder"
+)
+
+const (
+	// txOverheadVSize is the size of the parts of a transaction other
+	// than its inputs and outputs: the version, the input and output
+	// counts, the lock time and the signature script count.
+	txOverheadVSize = 4 + 1 + 1 + 4 + 1
+
+	// minChange is the smallest change output created.  Less is left to
+	// the fee, since it would cost more to spend than it is worth.
+	minChange = 1000
+)
+
+// CreateTx returns a packet of an unsigned transaction paying amount to addr
+// at feeRate, funded by coins of the wallet and paying any change to a new
+// change address.  The inputs of the packet carry the transactions creating
+// the coins they spend, and the inputs and change output the key origins of
+// their keys.
+//
+// Watch-only wallets create packets too, which can then be signed by the
+// wallet holding the private keys of the account.
+func (w *Wallet) CreateTx(addr btcutil.Address, amount btcutil.Amount,
+	feeRate btcutil.FeeRate) (*psbt.Packet, error) {
+	selector := coinset.WasteCoinSelector{
+		FeeParams:       coinset.FeeParams{FeeRate: feeRate},
+		MinChangeAmount: minChange,
+	}
+	target := amount + feeRate.FeeForVSize(txOverheadVSize+
+		coinset.DefaultChangeOutputVSize)
+	sel, err := selector.Select(target, w.spendable())
+	if err != nil {
+		return nil, err
+	}
+
+	b := txbuilder.New(
+		txbuilder.WithChainCodec(btcutil.NewOmegaChainCodec(w.net)),
+		txbuilder.WithAntiFeeSniping(w),
+	)
+	for _, coin := range sel.Coins.Coins() {
+		b.AddInput(wire.OutPoint{Hash: *coin.Hash(), Index: coin.Index()})
+	}
+	b.AddAddressOutput(addr, amount)
+	changeIndex := -1
+	if sel.Change > 0 {
+		changeAddr, err := w.nextAddress(InternalBranch)
+		if err != nil {
+			return nil, err
+		}
+		changeIndex = 1
+		b.AddAddressOutput(changeAddr, sel.Change)
+	}
+	tx, err := b.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	packet, err := psbt.New(tx)
+	if err != nil {
+		return nil, err
+	}
+	for i, in := range tx.TxIn {
+		c := w.credits[in.PreviousOutPoint]
+		d, err := w.derivation(c.path)
+		if err != nil {
+			return nil, err
+		}
+		packet.Inputs[i].NonWitnessUtxo = c.prevTx
+		packet.Inputs[i].Bip32Derivations = []*psbt.Bip32Derivation{d}
+	}
+	if changeIndex >= 0 {
+		d, err := w.derivation(keyPath{InternalBranch,
+			w.issued[InternalBranch] - 1})
+		if err != nil {
+			return nil, err
+		}
+		packet.Outputs[changeIndex].Bip32Derivations =
+			[]*psbt.Bip32Derivation{d}
+	}
+	return packet, nil
+}
+
+// derivation returns the BIP0032 derivation of the compressed public key at
+// path, which lets a hardware wallet find the key of an input and recognize
+// its change.
+func (w *Wallet) derivation(path keyPath) (*psbt.Bip32Derivation, error) {
+	key, err := w.key(path)
+	if err != nil {
+		return nil, err
+	}
+	pubKey, err := key.ECPubKey()
+	if err != nil {
+		return nil, err
+	}
+	return &psbt.Bip32Derivation{
+		PubKey: pubKey.SerializeCompressed(),
+		Origin: w.origin(path),
+	}, nil
+}
+
+// Sign signs the inputs of the packet spending coins of the wallet and sets
+// their final signature scripts, which push the signature followed by the
+// compressed public key.  Inputs of other wallets are left alone, so a packet
+// funded by several wallets is signed by passing it to each in turn, or by
+// combining the packets each of them signed.
+func (w *Wallet) Sign(p *psbt.Packet) error {
+	if w.IsWatchOnly() {
+		return ErrWatchOnly
+	}
+	for i := range p.Inputs {
+		prevOut := p.PrevOutput(i)
+		if prevOut == nil {
+			continue
+		}
+		path, ok := w.lookup(prevOut.PkScript)
+		if !ok {
+			continue
+		}
+		key, err := w.key(path)
+		if err != nil {
+			return err
+		}
+		privKey, err := key.ECPrivKey()
+		if err != nil {
+			return err
+		}
+		signer := signing.NewKeySigner(privKey)
+		err = p.SignSchnorr(i, signer, signing.SigHashDefault)
+		if err != nil {
+			return err
+		}
+
+		pubKey := signer.PubKey()
+		xOnly := taproot.SerializeXOnly(pubKey)
+		for _, sig := range p.Inputs[i].PartialSigs {
+			if bytes.Equal(sig.PubKey, xOnly) {
+				p.Inputs[i].FinalScriptSig = sigScript(sig.Signature,
+					pubKey.SerializeCompressed())
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// sigScript returns a signature script pushing sig and pubKey, which must both
+// be shorter than 76 bytes so they are pushed by their length alone.
+func sigScript(sig, pubKey []byte) []byte {
+	script := make([]byte, 0, 2+len(sig)+len(pubKey))
+	script = append(script, byte(len(sig)))
+	script = append(script, sig...)
+	script = append(script, byte(len(pubKey)))
+	return append(script, pubKey...)
+}
+
+// Send creates a transaction paying amount to addr at feeRate, signs it, and
+// relays it through the broadcaster of the wallet.  The coins it spends are
+// not spent again by the wallet, and no longer counted in its balance, unless
+// relaying fails.  The sent transaction is returned.
+func (w *Wallet) Send(addr btcutil.Address, amount btcutil.Amount,
+	feeRate btcutil.FeeRate) (*wire.MsgTx, error) {
+	if w.broadcaster == nil {
+		return nil, ErrNoBroadcaster
+	}
+	p, err := w.CreateTx(addr, amount, feeRate)
+	if err != nil {
+		return nil, err
+	}
+	if err := w.Sign(p); err != nil {
+		return nil, err
+	}
+	tx, err := p.Extract()
+	if err != nil {
+		return nil, err
+	}
+
+	// Lock the coins before relaying the transaction, since a broadcaster
+	// may deliver the block mining it before returning.
+	for _, in := range tx.TxIn {
+		w.locked[in.PreviousOutPoint] = struct{}{}
+	}
+	if err := w.broadcaster.Broadcast(tx); err != nil {
+		for _, in := range tx.TxIn {
+			delete(w.locked, in.PreviousOutPoint)
+		}
+		return nil, err
+	}
+	return tx, nil
+}
@@ -0,0 +1,370 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package refwallet is a small reference wallet showing how the packages of
+// btcutil fit together.  It is meant to be read as executable documentation,
+// and its tests, run against chains built by the regtest package, double as a
+// canary for changes to the APIs it uses.
+//
+// A Wallet derives the keys of a BIP0032 account with hdkeychain, following
+// the usual layout of an external branch for receiving and an internal branch
+// for change.  The addresses of the keys it watches are kept in a
+// keystore.Store along with their key origins, and the wallet watches the
+// chain by receiving connected and disconnected blocks, as a
+// regtest.Listener, keeping the unspent outputs paying the addresses of the
+// store.  Payments are funded by coin selection with coinset, assembled with
+// txbuilder into a psbt.Packet listing the key origins of its inputs and
+// change, signed with the amount-committing Schnorr signature hashes of the
+// signing package, and handed to a Broadcaster.
+//
+// btcutil has no watch-only tracker or chain view package, so the wallet
+// matches the blocks it receives against its store itself.
+//
+// A Wallet created from an extended public key is watch-only: it tracks its
+// coins and creates packets for another wallet holding the private key to
+// sign.
+//
+// The wallet keeps its state in memory only, and is not safe for concurrent
+// use.
+package refwallet
+
+import (
+	"errors"
+	"time"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/coinset"
+	"github.com/zeusyf/btcutil/hdkeychain"
+	"github.com/zeusyf/btcutil/keystore"
+)
+
+const (
+	// ExternalBranch is the branch of the account deriving the keys of
+	// receiving addresses.
+	ExternalBranch = 0
+
+	// InternalBranch is the branch of the account deriving the keys of
+	// change addresses.
+	InternalBranch = 1
+
+	// DefaultGapLimit is the number of keys of each branch watched beyond
+	// the last one handed out, so coins paid to addresses of a restored
+	// account are found.
+	DefaultGapLimit = 20
+
+	// DefaultMinConf is the number of confirmations an output needs before
+	// it is spent or counted in the balance.
+	DefaultMinConf = 1
+)
+
+var (
+	// ErrWatchOnly describes an error where a wallet created from an
+	// extended public key is asked to sign.
+	ErrWatchOnly = errors.New("wallet is watch-only")
+
+	// ErrNoBroadcaster describes an error where a wallet created without
+	// a Broadcaster is asked to send a transaction.
+	ErrNoBroadcaster = errors.New("wallet has no broadcaster")
+)
+
+// Broadcaster relays signed transactions to the network, such as through the
+// sendrawtransaction RPC of a node.
+type Broadcaster interface {
+	// Broadcast relays tx to the network.
+	Broadcast(tx *wire.MsgTx) error
+}
+
+// keyPath identifies a key of the account by its branch and index.
+type keyPath struct {
+	branch uint32
+	index  uint32
+}
+
+// credit is an output paying a key of the wallet.
+type credit struct {
+	outPoint wire.OutPoint
+	prevTx   *wire.MsgTx
+	height   int32
+	path     keyPath
+}
+
+// txOut returns the output of the credit.
+func (c *credit) txOut() *wire.TxOut {
+	return c.prevTx.TxOut[c.outPoint.Index]
+}
+
+// Option configures a Wallet.
+type Option func(*Wallet)
+
+// WithBroadcaster sets the broadcaster Send relays transactions through.
+func WithBroadcaster(b Broadcaster) Option {
+	return func(w *Wallet) {
+		w.broadcaster = b
+	}
+}
+
+// WithGapLimit sets the number of keys of each branch watched beyond the last
+// one handed out.  DefaultGapLimit is used by default.
+func WithGapLimit(n uint32) Option {
+	return func(w *Wallet) {
+		w.gapLimit = n
+	}
+}
+
+// WithMinConf sets the number of confirmations an output needs before it is
+// spent or counted in the balance.  DefaultMinConf is used by default.
+func WithMinConf(n int32) Option {
+	return func(w *Wallet) {
+		w.minConf = n
+	}
+}
+
+// Wallet is a reference wallet of a single BIP0032 account.  The zero value
+// is not usable; a Wallet must be created with New.
+type Wallet struct {
+	net         *chaincfg.Params
+	fingerprint uint32
+	branches    [2]*hdkeychain.ExtendedKey
+	broadcaster Broadcaster
+	gapLimit    uint32
+	minConf     int32
+
+	// issued is the number of addresses handed out on each branch, and
+	// watched the number of keys whose addresses are in book.
+	issued  [2]uint32
+	watched [2]uint32
+	book    *keystore.Store
+
+	height    int32
+	timestamp time.Time
+	credits   map[wire.OutPoint]*credit
+
+	// spent holds the credits spent by mined transactions so they can be
+	// restored when the block spending them is disconnected, and locked
+	// the outpoints spent by transactions sent but not yet mined.
+	spent  map[wire.OutPoint]*credit
+	locked map[wire.OutPoint]struct{}
+}
+
+// New returns a wallet of account, the extended key of a BIP0032 account such
+// as m/44'/0'/0', for the network net.  The wallet is watch-only when account
+// is an extended public key.
+func New(account *hdkeychain.ExtendedKey, net *chaincfg.Params,
+	opts ...Option) (*Wallet, error) {
+	w := &Wallet{
+		net:         net,
+		fingerprint: account.Fingerprint(),
+		gapLimit:    DefaultGapLimit,
+		minConf:     DefaultMinConf,
+		book:        keystore.NewStore(net),
+		credits:     make(map[wire.OutPoint]*credit),
+		spent:       make(map[wire.OutPoint]*credit),
+		locked:      make(map[wire.OutPoint]struct{}),
+	}
+	for _, opt := range opts {
+		opt(w)
+	}
+	for _, branch := range []uint32{ExternalBranch, InternalBranch} {
+		key, err := account.Child(branch)
+		if err != nil {
+			return nil, err
+		}
+		w.branches[branch] = key
+		if err := w.watch(branch); err != nil {
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+// IsWatchOnly returns whether the wallet lacks the private keys to sign.
+func (w *Wallet) IsWatchOnly() bool {
+	return !w.branches[ExternalBranch].IsPrivate()
+}
+
+// key returns the extended key at path.
+func (w *Wallet) key(path keyPath) (*hdkeychain.ExtendedKey, error) {
+	return w.branches[path.branch].Child(path.index)
+}
+
+// origin returns the origin of the key at path.  The wallet does not know the
+// master key of its account, so origins are relative to the account key.
+func (w *Wallet) origin(path keyPath) hdkeychain.KeyOrigin {
+	return hdkeychain.KeyOrigin{
+		Fingerprint: w.fingerprint,
+		Path:        hdkeychain.DerivationPath{path.branch, path.index},
+	}
+}
+
+// lookup returns the path of the key of the address pkScript pays to, and
+// false when the wallet does not watch it.
+func (w *Wallet) lookup(pkScript []byte) (keyPath, bool) {
+	e, ok := w.book.LookupScript(pkScript)
+	if !ok || e.Origin == nil || len(e.Origin.Path) != 2 {
+		return keyPath{}, false
+	}
+	return keyPath{e.Origin.Path[0], e.Origin.Path[1]}, true
+}
+
+// address returns the pay-to-pubkey-hash address of the key at path.
+func (w *Wallet) address(path keyPath) (*btcutil.AddressPubKeyHash, error) {
+	key, err := w.key(path)
+	if err != nil {
+		return nil, err
+	}
+	return key.Address(w.net)
+}
+
+// watch adds the addresses of branch up to the gap limit beyond its last
+// issued address to the store, whose addresses are recognized in blocks.
+func (w *Wallet) watch(branch uint32) error {
+	for w.watched[branch] < w.issued[branch]+w.gapLimit {
+		path := keyPath{branch, w.watched[branch]}
+		addr, err := w.address(path)
+		if err != nil {
+			return err
+		}
+		origin := w.origin(path)
+		err = w.book.Add(keystore.Entry{Address: addr, Origin: &origin})
+		if err != nil {
+			return err
+		}
+		w.watched[branch]++
+	}
+	return nil
+}
+
+// nextAddress hands out the next address of branch.
+func (w *Wallet) nextAddress(branch uint32) (*btcutil.AddressPubKeyHash, error) {
+	addr, err := w.address(keyPath{branch, w.issued[branch]})
+	if err != nil {
+		return nil, err
+	}
+	w.issued[branch]++
+	if err := w.watch(branch); err != nil {
+		return nil, err
+	}
+	return addr, nil
+}
+
+// NewAddress returns the next unused receiving address of the wallet.
+func (w *Wallet) NewAddress() (*btcutil.AddressPubKeyHash, error) {
+	return w.nextAddress(ExternalBranch)
+}
+
+// Addresses returns the addresses watched by the wallet, with their labels
+// and key origins, in ascending order of encoded address.
+func (w *Wallet) Addresses() []keystore.Entry {
+	return w.book.Entries()
+}
+
+// SetLabel sets the label of addr, an address watched by the wallet.
+// keystore.ErrAddressNotFound is returned when the wallet does not watch it.
+func (w *Wallet) SetLabel(addr btcutil.Address, label string) error {
+	return w.book.SetLabel(addr, label)
+}
+
+// BlockConnected adds the outputs of block paying the wallet to its coins and
+// removes those it spends.  The blocks of the best chain must be passed in
+// order.  Part of the regtest.Listener interface.
+func (w *Wallet) BlockConnected(block *btcutil.Block) {
+	height := block.Height()
+	for _, tx := range block.Transactions() {
+		msgTx := tx.MsgTx()
+		for _, in := range msgTx.TxIn {
+			op := in.PreviousOutPoint
+			if c, ok := w.credits[op]; ok {
+				delete(w.credits, op)
+				w.spent[op] = c
+			}
+			delete(w.locked, op)
+		}
+		for i, out := range msgTx.TxOut {
+			path, ok := w.lookup(out.PkScript)
+			if !ok {
+				continue
+			}
+			op := wire.OutPoint{Hash: *tx.Hash(), Index: uint32(i)}
+			w.credits[op] = &credit{
+				outPoint: op,
+				prevTx:   msgTx,
+				height:   height,
+				path:     path,
+			}
+
+			// Keep the gap limit ahead of the addresses the chain
+			// shows to be in use.  Deriving a key fails with
+			// negligible probability, and the keys following one
+			// which can't be derived are then left unwatched.
+			if path.index >= w.issued[path.branch] {
+				w.issued[path.branch] = path.index + 1
+				_ = w.watch(path.branch)
+			}
+		}
+	}
+	w.height = height
+	w.timestamp = block.MsgBlock().Header.Timestamp
+}
+
+// BlockDisconnected reverts BlockConnected for block, the tip being removed
+// by a reorg.  Transactions of the block are not resent.  Part of the
+// regtest.Listener interface.
+func (w *Wallet) BlockDisconnected(block *btcutil.Block) {
+	txns := block.Transactions()
+	for i := len(txns) - 1; i >= 0; i-- {
+		msgTx := txns[i].MsgTx()
+		for j := range msgTx.TxOut {
+			op := wire.OutPoint{Hash: *txns[i].Hash(), Index: uint32(j)}
+			delete(w.credits, op)
+		}
+		for _, in := range msgTx.TxIn {
+			op := in.PreviousOutPoint
+			if c, ok := w.spent[op]; ok {
+				delete(w.spent, op)
+				w.credits[op] = c
+			}
+		}
+	}
+	w.height = block.Height() - 1
+}
+
+// BestBlock returns the height of the tip of the wallet along with the
+// timestamp of the last block connected to it, which during a reorg is that of
+// a disconnected block.  It makes the wallet a txbuilder.TipSource.
+func (w *Wallet) BestBlock() (int32, time.Time, error) {
+	return w.height, w.timestamp, nil
+}
+
+// numConfs returns the number of confirmations of c.
+func (w *Wallet) numConfs(c *credit) int32 {
+	return w.height - c.height + 1
+}
+
+// spendable returns the coins of the wallet with at least the minimum number
+// of confirmations which are not spent by a sent transaction.  Outputs of
+// tokens other than OMC are left alone.
+func (w *Wallet) spendable() []coinset.Coin {
+	var coins []coinset.Coin
+	for op, c := range w.credits {
+		if _, ok := w.locked[op]; ok || w.numConfs(c) < w.minConf {
+			continue
+		}
+		coin, err := coinset.NewTxOutCoin(op, c.txOut(),
+			int64(w.numConfs(c)))
+		if err != nil {
+			continue
+		}
+		coins = append(coins, coin)
+	}
+	return coins
+}
+
+// Balance returns the total value of the OMC coins of the wallet with at
+// least the minimum number of confirmations.  Coins spent by transactions
+// sent but not yet mined are not counted.
+func (w *Wallet) Balance() btcutil.Amount {
+	return coinset.NewCoinSet(w.spendable()).TotalValue()
+}
@@ -0,0 +1,261 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package refwallet_test
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/examples/refwallet"
+	"github.com/zeusyf/btcutil/hdkeychain"
+	"github.com/zeusyf/btcutil/regtest"
+	"github.com/zeusyf/btcutil/signing"
+	"github.com/zeusyf/omega/token"
+)
+
+// miner is a refwallet.Broadcaster mining each transaction into a block of
+// its scenario, as a node would once the transaction is relayed.
+type miner struct {
+	s *regtest.Scenario
+}
+
+func (m miner) Broadcast(tx *wire.MsgTx) error {
+	_, err := m.s.MineWithTxs(tx)
+	return err
+}
+
+// account returns the account m/44'/1'/0' of the master key of a seed of
+// repeated seedByte.
+func account(t *testing.T, seedByte byte) *hdkeychain.ExtendedKey {
+	t.Helper()
+	master, err := hdkeychain.NewMaster(bytes.Repeat([]byte{seedByte}, 32),
+		&chaincfg.RegressionNetParams)
+	if err != nil {
+		t.Fatalf("NewMaster: %v", err)
+	}
+	path, err := hdkeychain.ParseDerivationPath("m/44'/1'/0'")
+	if err != nil {
+		t.Fatalf("ParseDerivationPath: %v", err)
+	}
+	key, err := master.DerivePath(path)
+	if err != nil {
+		t.Fatalf("DerivePath: %v", err)
+	}
+	return key
+}
+
+// receiveAddress returns the receiving address at index of account.
+func receiveAddress(t *testing.T, account *hdkeychain.ExtendedKey,
+	index uint32) *btcutil.AddressPubKeyHash {
+	t.Helper()
+	branch, err := account.Child(refwallet.ExternalBranch)
+	if err != nil {
+		t.Fatalf("Child: %v", err)
+	}
+	key, err := branch.Child(index)
+	if err != nil {
+		t.Fatalf("Child: %v", err)
+	}
+	addr, err := key.Address(&chaincfg.RegressionNetParams)
+	if err != nil {
+		t.Fatalf("Address: %v", err)
+	}
+	return addr
+}
+
+// value returns the OMC value of out.
+func value(out *wire.TxOut) btcutil.Amount {
+	return btcutil.Amount(out.Token.Value.(*token.NumeralVal).Val)
+}
+
+// verifyTx ensures every input of tx, as finalized by Wallet.Sign, carries a
+// valid signature over the outputs it spends, found in the chain of s, and
+// returns the fee of tx.
+func verifyTx(t *testing.T, s *regtest.Scenario, tx *wire.MsgTx) btcutil.Amount {
+	t.Helper()
+	txns := make(map[chainhash.Hash]*wire.MsgTx)
+	for _, block := range s.Chain() {
+		for _, tx := range block.Transactions() {
+			txns[*tx.Hash()] = tx.MsgTx()
+		}
+	}
+	var fee btcutil.Amount
+	prevOuts := make([]*wire.TxOut, len(tx.TxIn))
+	for i, in := range tx.TxIn {
+		op := in.PreviousOutPoint
+		prevOuts[i] = txns[op.Hash].TxOut[op.Index]
+		fee += value(prevOuts[i])
+	}
+	for _, out := range tx.TxOut {
+		fee -= value(out)
+	}
+
+	for i, in := range tx.TxIn {
+		script := tx.SignatureScripts[in.SignatureIndex]
+		sig := script[1 : 1+script[0]]
+		pubKey, err := btcec.ParsePubKey(script[2+len(sig):], btcec.S256())
+		if err != nil {
+			t.Fatalf("input %d: ParsePubKey: %v", i, err)
+		}
+		if !signing.VerifySchnorrInput(pubKey, sig, tx, i, prevOuts) {
+			t.Fatalf("input %d: invalid signature", i)
+		}
+	}
+	return fee
+}
+
+// TestWallet runs a wallet, a watch-only wallet and an offline signer against
+// a regtest scenario: funding, paying between them, signing a packet created
+// by the watch-only wallet, and undoing a payment with a reorg.
+func TestWallet(t *testing.T) {
+	params := &chaincfg.RegressionNetParams
+	s := regtest.New(params)
+	feeRate := btcutil.NewFeeRateFromHaoPerVByte(10)
+
+	aliceAccount := account(t, 0x01)
+	alice, err := refwallet.New(aliceAccount, params,
+		refwallet.WithBroadcaster(miner{s}))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	bobAccount := account(t, 0x02)
+	bobPub, err := bobAccount.Neuter()
+	if err != nil {
+		t.Fatalf("Neuter: %v", err)
+	}
+	bobWatch, err := refwallet.New(bobPub, params)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if alice.IsWatchOnly() || !bobWatch.IsWatchOnly() {
+		t.Fatalf("IsWatchOnly: got %v and %v", alice.IsWatchOnly(),
+			bobWatch.IsWatchOnly())
+	}
+	s.AddListener(alice)
+	s.AddListener(bobWatch)
+
+	// Fund the first address of Alice and one within the gap limit she
+	// has not handed out yet.
+	addr, err := alice.NewAddress()
+	if err != nil {
+		t.Fatalf("NewAddress: %v", err)
+	}
+	if err := alice.SetLabel(addr, "savings"); err != nil {
+		t.Fatalf("SetLabel: %v", err)
+	}
+	labeled := 0
+	for _, e := range alice.Addresses() {
+		if e.Label == "savings" {
+			labeled++
+			if e.Origin == nil || e.Origin.String() !=
+				fmt.Sprintf("%08x/0/0", aliceAccount.Fingerprint()) {
+				t.Fatalf("Addresses: got origin %v", e.Origin)
+			}
+		}
+	}
+	if labeled != 1 {
+		t.Fatalf("Addresses: got %d labeled addresses", labeled)
+	}
+	if err := s.Fund(addr, 10*btcutil.HaoPerBitcoin); err != nil {
+		t.Fatalf("Fund: %v", err)
+	}
+	unissued := receiveAddress(t, aliceAccount, 5)
+	if err := s.Fund(unissued, btcutil.HaoPerBitcoin); err != nil {
+		t.Fatalf("Fund: %v", err)
+	}
+	if _, err := s.Mine(1); err != nil {
+		t.Fatalf("Mine: %v", err)
+	}
+	funded := btcutil.Amount(11 * btcutil.HaoPerBitcoin)
+	if got := alice.Balance(); got != funded {
+		t.Fatalf("Balance: got %v, want %v", got, funded)
+	}
+
+	// The next address follows the last one used on chain.
+	next, err := alice.NewAddress()
+	if err != nil {
+		t.Fatalf("NewAddress: %v", err)
+	}
+	want := receiveAddress(t, aliceAccount, 6)
+	if !bytes.Equal(next.ScriptAddress(), want.ScriptAddress()) {
+		t.Fatalf("NewAddress: got %v, want %v", next, want)
+	}
+
+	// Alice pays Bob, whose watch-only wallet sees the payment.
+	bobAddr, err := bobWatch.NewAddress()
+	if err != nil {
+		t.Fatalf("NewAddress: %v", err)
+	}
+	paid := btcutil.Amount(3 * btcutil.HaoPerBitcoin)
+	tx, err := alice.Send(bobAddr, paid, feeRate)
+	if err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	fee := verifyTx(t, s, tx)
+	if got := bobWatch.Balance(); got != paid {
+		t.Fatalf("Balance: got %v, want %v", got, paid)
+	}
+	if fee <= 0 || fee > feeRate.FeeForVSize(1000) {
+		t.Fatalf("Send: paid a fee of %v", fee)
+	}
+	if got := alice.Balance(); got != funded-paid-fee {
+		t.Fatalf("Balance: got %v, want %v", got, funded-paid-fee)
+	}
+
+	// The watch-only wallet of Bob can't sign or send, but creates a
+	// packet for his offline signer.
+	if _, err := bobWatch.Send(addr, btcutil.HaoPerBitcoin, feeRate); err != refwallet.ErrNoBroadcaster {
+		t.Fatalf("Send: got error %v, want %v", err,
+			refwallet.ErrNoBroadcaster)
+	}
+	packet, err := bobWatch.CreateTx(addr, btcutil.HaoPerBitcoin, feeRate)
+	if err != nil {
+		t.Fatalf("CreateTx: %v", err)
+	}
+	for i, in := range packet.Inputs {
+		if len(in.Bip32Derivations) != 1 ||
+			in.Bip32Derivations[0].Origin.Fingerprint != bobAccount.Fingerprint() {
+			t.Fatalf("CreateTx: input %d has derivations %v", i,
+				in.Bip32Derivations)
+		}
+	}
+	change := packet.Outputs[len(packet.Outputs)-1].Bip32Derivations
+	if len(packet.Outputs) != 2 || len(change) != 1 ||
+		change[0].Origin.Path.String() != "m/1/0" {
+		t.Fatalf("CreateTx: got change derivations %v", change)
+	}
+	if err := bobWatch.Sign(packet); err != refwallet.ErrWatchOnly {
+		t.Fatalf("Sign: got error %v, want %v", err, refwallet.ErrWatchOnly)
+	}
+	bobSigner, err := refwallet.New(bobAccount, params)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if err := bobSigner.Sign(packet); err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	signed, err := packet.Extract()
+	if err != nil {
+		t.Fatalf("Extract: %v", err)
+	}
+	verifyTx(t, s, signed)
+
+	// Reorging out the payment restores the coins of Alice.
+	if _, err := s.Reorg(1, 2); err != nil {
+		t.Fatalf("Reorg: %v", err)
+	}
+	if got := alice.Balance(); got != funded {
+		t.Fatalf("Balance after reorg: got %v, want %v", got, funded)
+	}
+	if got := bobWatch.Balance(); got != 0 {
+		t.Fatalf("Balance after reorg: got %v, want 0", got)
+	}
+}
@@ -0,0 +1,298 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package export renders the transactions of a wallet for accounting
+// software, as CSV or as the journal of the ledger-cli plain text accounting
+// tool.
+//
+// Each transaction is described by a Record: its hash, time and direction,
+// the amount of each token it moves, and the fee the wallet paid.  A
+// Formatter renders records with a configurable unit for amounts of OMC and a
+// Locale selecting the decimal and digit group separators, so integrations
+// don't reimplement the formatting rules of Amount.  Amounts are converted
+// exactly, without floating point math.
+//
+// Unlike the entries of the ledger package, which record every movement of
+// funds of an account, a record summarizes a whole transaction on one line of
+// a statement.
+package export
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcutil"
+)
+
+var (
+	// ErrUnknownDirection describes an error where a record has a
+	// direction which is not one of the defined directions.
+	ErrUnknownDirection = errors.New("unknown transaction direction")
+
+	// ErrNegativeAmount describes an error where a record has a negative
+	// amount or fee.  The direction of a record is given by its
+	// direction, so amounts are always magnitudes.
+	ErrNegativeAmount = errors.New("record amount is negative")
+)
+
+// Direction is the direction in which a transaction moves funds relative to
+// the wallet.
+type Direction uint8
+
+const (
+	// DirectionIncoming is a transaction paying the wallet.
+	DirectionIncoming Direction = iota
+
+	// DirectionOutgoing is a transaction paying outside the wallet.
+	DirectionOutgoing
+
+	// DirectionSelf is a transaction between addresses of the wallet,
+	// which only costs its fee.
+	DirectionSelf
+
+	// numDirections is the number of directions.
+	numDirections
+)
+
+// directionStrings is a map of directions back to their names, as written in
+// exports.
+var directionStrings = map[Direction]string{
+	DirectionIncoming: "incoming",
+	DirectionOutgoing: "outgoing",
+	DirectionSelf:     "self",
+}
+
+// String returns the Direction as a human-readable name.
+func (d Direction) String() string {
+	if str, ok := directionStrings[d]; ok {
+		return str
+	}
+	return "unknown"
+}
+
+// TokenAmount is an amount of a token type.
+type TokenAmount struct {
+	TokenType uint64
+	Amount    btcutil.Amount
+}
+
+// Record describes a transaction of a wallet.
+type Record struct {
+	// TxHash is the hash of the transaction.
+	TxHash chainhash.Hash
+
+	// Time is when the transaction was confirmed or observed.
+	Time time.Time
+
+	// Direction is the direction of the transaction.
+	Direction Direction
+
+	// Account is the account of the wallet the transaction belongs to.
+	Account string
+
+	// Amounts holds the magnitude of the amount of each token type the
+	// transaction moves, excluding the fee.
+	Amounts []TokenAmount
+
+	// Fee is the fee in OMC paid by the wallet, zero for transactions
+	// paid for by others.
+	Fee btcutil.Amount
+
+	// Memo is a free form description of the transaction.
+	Memo string
+}
+
+// Validate checks the record has a known direction and no negative amount.
+func (r *Record) Validate() error {
+	if r.Direction >= numDirections {
+		return ErrUnknownDirection
+	}
+	if r.Fee < 0 {
+		return ErrNegativeAmount
+	}
+	for _, a := range r.Amounts {
+		if a.Amount < 0 {
+			return ErrNegativeAmount
+		}
+	}
+	return nil
+}
+
+// sortedAmounts returns the amounts of the record in order of token type.
+func (r *Record) sortedAmounts() []TokenAmount {
+	amounts := append([]TokenAmount(nil), r.Amounts...)
+	sort.SliceStable(amounts, func(i, j int) bool {
+		return amounts[i].TokenType < amounts[j].TokenType
+	})
+	return amounts
+}
+
+// Locale selects the separators of exported numbers and fields.
+type Locale struct {
+	// DecimalSeparator separates the whole and fractional parts of
+	// amounts.  A "." is used when empty.
+	DecimalSeparator string
+
+	// GroupSeparator, when not empty, is inserted between every group of
+	// three digits of the whole part of amounts.
+	GroupSeparator string
+
+	// FieldSeparator separates the fields of CSV rows.  A ',' is used when
+	// zero.
+	FieldSeparator rune
+}
+
+// Locales of common conventions.  Locales with a decimal comma separate CSV
+// fields with semicolons, as spreadsheets of those locales expect.
+var (
+	// LocaleC is the default locale: a "." decimal separator, no digit
+	// grouping and comma separated fields, which any software parses.
+	LocaleC = Locale{DecimalSeparator: ".", FieldSeparator: ','}
+
+	// LocaleEnUS groups digits with commas, as in "1,234.5".
+	LocaleEnUS = Locale{DecimalSeparator: ".", GroupSeparator: ",",
+		FieldSeparator: ','}
+
+	// LocaleDeDE uses a decimal comma and groups digits with periods, as
+	// in "1.234,5".
+	LocaleDeDE = Locale{DecimalSeparator: ",", GroupSeparator: ".",
+		FieldSeparator: ';'}
+
+	// LocaleFrFR uses a decimal comma and groups digits with no-break
+	// spaces, as in "1 234,5".
+	LocaleFrFR = Locale{DecimalSeparator: ",", GroupSeparator: "\u00a0",
+		FieldSeparator: ';'}
+)
+
+// LedgerAccounts names the accounts of ledger-cli postings.
+type LedgerAccounts struct {
+	// Assets is the account holding the funds of the wallet.  The account
+	// of a record, when set, is appended as a subaccount.
+	Assets string
+
+	// Income is the account incoming payments are credited from.
+	Income string
+
+	// Expenses is the account outgoing payments are debited to.
+	Expenses string
+
+	// Fees is the account fees are debited to.
+	Fees string
+}
+
+// DefaultLedgerAccounts are the ledger-cli accounts used when none are
+// configured.
+var DefaultLedgerAccounts = LedgerAccounts{
+	Assets:   "Assets:Omega",
+	Income:   "Income:Omega",
+	Expenses: "Expenses:Omega",
+	Fees:     "Expenses:Omega:Fees",
+}
+
+// Option configures a Formatter.
+type Option func(*Formatter)
+
+// WithUnit sets the unit amounts of OMC are expressed in.  AmountOMC is used
+// by default.
+func WithUnit(u btcutil.AmountUnit) Option {
+	return func(f *Formatter) {
+		f.unit = u
+	}
+}
+
+// WithLocale sets the separators of numbers and fields.  LocaleC is used by
+// default.
+func WithLocale(l Locale) Option {
+	return func(f *Formatter) {
+		f.locale = l
+	}
+}
+
+// WithLocation sets the time zone times are written in.  UTC is used by
+// default.
+func WithLocation(loc *time.Location) Option {
+	return func(f *Formatter) {
+		f.location = loc
+	}
+}
+
+// WithTokenNames sets the names of token types, as written in the token
+// column of CSV exports and as commodities of ledger-cli journals.  Token
+// types without a name are written as "OMC", or the unit of amounts of OMC,
+// for token type zero and as "T" followed by the token type for others.
+func WithTokenNames(names map[uint64]string) Option {
+	return func(f *Formatter) {
+		f.tokenNames = names
+	}
+}
+
+// WithLedgerAccounts sets the accounts of ledger-cli postings.
+// DefaultLedgerAccounts are used by default.
+func WithLedgerAccounts(accounts LedgerAccounts) Option {
+	return func(f *Formatter) {
+		f.accounts = accounts
+	}
+}
+
+// Formatter renders records.  The zero value is not usable; a Formatter must
+// be created with NewFormatter.
+type Formatter struct {
+	unit       btcutil.AmountUnit
+	locale     Locale
+	location   *time.Location
+	tokenNames map[uint64]string
+	accounts   LedgerAccounts
+}
+
+// NewFormatter returns a formatter configured by the passed options.
+func NewFormatter(opts ...Option) *Formatter {
+	f := &Formatter{
+		unit:     btcutil.AmountOMC,
+		locale:   LocaleC,
+		location: time.UTC,
+		accounts: DefaultLedgerAccounts,
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+	if f.locale.FieldSeparator == 0 {
+		f.locale.FieldSeparator = ','
+	}
+	if f.location == nil {
+		f.location = time.UTC
+	}
+	return f
+}
+
+// FormatAmount formats amount of tokenType as a number without unit, with the
+// separators of the locale.  Amounts of OMC are expressed in the unit of the
+// formatter with trailing fractional zeros trimmed, and amounts of other
+// tokens, whose decimals are unknown, as integers.
+func (f *Formatter) FormatAmount(tokenType uint64, amount btcutil.Amount) string {
+	unit := f.unit
+	if tokenType != 0 {
+		unit = btcutil.AmountHao
+	}
+	return amount.FormatWithOptions(btcutil.FormatOptions{
+		Unit:              unit,
+		GroupSeparator:    f.locale.GroupSeparator,
+		DecimalSeparator:  f.locale.DecimalSeparator,
+		TrimTrailingZeros: true,
+		UnitPlacement:     btcutil.UnitNone,
+	})
+}
+
+// TokenName returns the name tokenType is written as.
+func (f *Formatter) TokenName(tokenType uint64) string {
+	if name, ok := f.tokenNames[tokenType]; ok {
+		return name
+	}
+	if tokenType == 0 {
+		return f.unit.String()
+	}
+	return "T" + strconv.FormatUint(tokenType, 10)
+}
@@ -0,0 +1,90 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package export_test
+
+import (
+	"testing"
+
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/export"
+)
+
+// TestFormatAmount tests amounts are formatted in the unit and with the
+// separators of the formatter.
+func TestFormatAmount(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      []export.Option
+		tokenType uint64
+		amount    btcutil.Amount
+		want      string
+	}{
+		{"default", nil, 0, 123456789000, "1234.56789"},
+		{"whole", nil, 0, 5e8, "5"},
+		{"negative", nil, 0, -1, "-0.00000001"},
+		{"en-US", []export.Option{export.WithLocale(export.LocaleEnUS)},
+			0, 123456789000, "1,234.56789"},
+		{"de-DE", []export.Option{export.WithLocale(export.LocaleDeDE)},
+			0, 123456789000, "1.234,56789"},
+		{"fr-FR", []export.Option{export.WithLocale(export.LocaleFrFR)},
+			0, 123456789000, "1\u00a0234,56789"},
+		{"milli", []export.Option{export.WithUnit(btcutil.AmountMilliOMC)},
+			0, 123456789, "1234.56789"},
+		{"hao", []export.Option{export.WithUnit(btcutil.AmountHao),
+			export.WithLocale(export.LocaleEnUS)}, 0, 123456789,
+			"123,456,789"},
+		{"token", []export.Option{export.WithLocale(export.LocaleDeDE)},
+			4, 1234567, "1.234.567"},
+	}
+	for _, test := range tests {
+		f := export.NewFormatter(test.opts...)
+		if got := f.FormatAmount(test.tokenType, test.amount); got != test.want {
+			t.Errorf("%s: got %q, want %q", test.name, got, test.want)
+		}
+	}
+}
+
+// TestTokenName tests the default and configured names of token types.
+func TestTokenName(t *testing.T) {
+	f := export.NewFormatter(export.WithUnit(btcutil.AmountMilliOMC))
+	if got := f.TokenName(0); got != "mOMC" {
+		t.Errorf("TokenName(0): got %q, want %q", got, "mOMC")
+	}
+	if got := f.TokenName(6); got != "T6" {
+		t.Errorf("TokenName(6): got %q, want %q", got, "T6")
+	}
+	f = export.NewFormatter(export.WithTokenNames(map[uint64]string{
+		6: "GOLD",
+	}))
+	if got := f.TokenName(6); got != "GOLD" {
+		t.Errorf("TokenName(6): got %q, want %q", got, "GOLD")
+	}
+}
+
+// TestRecordValidate tests records with unknown directions or negative
+// amounts are rejected.
+func TestRecordValidate(t *testing.T) {
+	tests := []struct {
+		name   string
+		record export.Record
+		err    error
+	}{
+		{"valid", export.Record{Direction: export.DirectionSelf, Fee: 1}, nil},
+		{"direction", export.Record{Direction: 7},
+			export.ErrUnknownDirection},
+		{"fee", export.Record{Fee: -1}, export.ErrNegativeAmount},
+		{"amount", export.Record{Amounts: []export.TokenAmount{
+			{TokenType: 0, Amount: -5},
+		}}, export.ErrNegativeAmount},
+	}
+	for _, test := range tests {
+		if err := test.record.Validate(); err != test.err {
+			t.Errorf("%s: got %v, want %v", test.name, err, test.err)
+		}
+	}
+	if s := export.Direction(7).String(); s != "unknown" {
+		t.Errorf("String: got %q for an unknown direction", s)
+	}
+}
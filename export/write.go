@@ -0,0 +1,166 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package export
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/zeusyf/btcutil"
+)
+
+// CSVHeader is the header row written by WriteCSV.
+var CSVHeader = []string{
+	"time", "txid", "direction", "account", "token", "amount", "fee", "memo",
+}
+
+// WriteCSV writes the records to w as CSV with a header row.  A record is
+// written as one row per token it moves, in order of token type, or as a
+// single row with empty token and amount when it moves none.  The fee is
+// written on the first row of its record and left empty on the others, so
+// summing the fee column counts each fee once.  Times are written in RFC 3339.
+func (f *Formatter) WriteCSV(w io.Writer, records []Record) error {
+	cw := csv.NewWriter(w)
+	cw.Comma = f.locale.FieldSeparator
+	if err := cw.Write(CSVHeader); err != nil {
+		return err
+	}
+	for i := range records {
+		r := &records[i]
+		if err := r.Validate(); err != nil {
+			return err
+		}
+		amounts := r.sortedAmounts()
+		row := []string{
+			r.Time.In(f.location).Format(time.RFC3339),
+			r.TxHash.String(),
+			r.Direction.String(),
+			r.Account,
+			"",
+			"",
+			f.FormatAmount(0, r.Fee),
+			r.Memo,
+		}
+		if len(amounts) == 0 {
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+			continue
+		}
+		for j, a := range amounts {
+			row[4] = f.TokenName(a.TokenType)
+			row[5] = f.FormatAmount(a.TokenType, a.Amount)
+			if j > 0 {
+				row[6] = ""
+			}
+			if err := cw.Write(row); err != nil {
+				return err
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// commodity returns the ledger-cli commodity of tokenType, quoted unless it
+// is made of letters only.
+func (f *Formatter) commodity(tokenType uint64) string {
+	name := f.TokenName(tokenType)
+	for _, r := range name {
+		if !unicode.IsLetter(r) {
+			return `"` + strings.ReplaceAll(name, `"`, "") + `"`
+		}
+	}
+	return name
+}
+
+// posting is a posting of a ledger-cli transaction.
+type posting struct {
+	account   string
+	tokenType uint64
+	amount    btcutil.Amount
+}
+
+// postings returns the postings of the record, which balance for every
+// token.  Incoming amounts move from the income account to the assets of the
+// wallet and outgoing amounts from the assets to the expenses account, and
+// the fee from the assets to the fees account.  Amounts of transactions
+// within the wallet leave its assets unchanged and are not posted.
+func (f *Formatter) postings(r *Record) []posting {
+	assets := f.accounts.Assets
+	if r.Account != "" {
+		assets += ":" + r.Account
+	}
+
+	var ps []posting
+	for _, a := range r.sortedAmounts() {
+		switch r.Direction {
+		case DirectionIncoming:
+			ps = append(ps,
+				posting{assets, a.TokenType, a.Amount},
+				posting{f.accounts.Income, a.TokenType, -a.Amount})
+		case DirectionOutgoing:
+			ps = append(ps,
+				posting{f.accounts.Expenses, a.TokenType, a.Amount},
+				posting{assets, a.TokenType, -a.Amount})
+		}
+	}
+	if r.Fee != 0 {
+		ps = append(ps,
+			posting{f.accounts.Fees, 0, r.Fee},
+			posting{assets, 0, -r.Fee})
+	}
+	return ps
+}
+
+// WriteLedger writes the records to w as a ledger-cli journal.  Each record
+// is a cleared transaction dated in the time zone of the formatter, whose
+// payee is the memo of the record, or its hash when it has no memo, and
+// which carries the hash as TxID metadata.  Every posting has an explicit
+// amount.  Records moving nothing, such as transactions within the wallet
+// whose fee was paid by others, are skipped.
+//
+// Journals written with a decimal comma must be read with the --decimal-comma
+// option of ledger-cli.
+func (f *Formatter) WriteLedger(w io.Writer, records []Record) error {
+	bw := bufio.NewWriter(w)
+	written := false
+	for i := range records {
+		r := &records[i]
+		if err := r.Validate(); err != nil {
+			return err
+		}
+		ps := f.postings(r)
+		if len(ps) == 0 {
+			continue
+		}
+
+		if written {
+			bw.WriteByte('\n')
+		}
+		written = true
+
+		payee := r.Memo
+		if payee == "" {
+			payee = r.TxHash.String()
+		}
+		fmt.Fprintf(bw, "%s * %s\n    ; TxID: %s\n",
+			r.Time.In(f.location).Format("2006/01/02"),
+			strings.ReplaceAll(payee, "\n", " "), r.TxHash)
+		for _, p := range ps {
+			// Two spaces at least separate the account from the
+			// amount.
+			fmt.Fprintf(bw, "    %-40s  %s %s\n", p.account,
+				f.FormatAmount(p.tokenType, p.amount),
+				f.commodity(p.tokenType))
+		}
+	}
+	return bw.Flush()
+}
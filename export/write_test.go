@@ -0,0 +1,123 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package export_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcutil/export"
+)
+
+// testRecords returns an incoming payment of two tokens, an outgoing payment
+// and a transaction within the wallet.
+func testRecords() []export.Record {
+	at := time.Date(2021, 3, 1, 23, 30, 0, 0, time.UTC)
+	return []export.Record{{
+		TxHash:    chainhash.Hash{1},
+		Time:      at,
+		Direction: export.DirectionIncoming,
+		Account:   "savings",
+		Amounts: []export.TokenAmount{
+			{TokenType: 4, Amount: 1500},
+			{TokenType: 0, Amount: 250000000},
+		},
+		Memo: "salary",
+	}, {
+		TxHash:    chainhash.Hash{2},
+		Time:      at.Add(time.Hour),
+		Direction: export.DirectionOutgoing,
+		Amounts:   []export.TokenAmount{{TokenType: 0, Amount: 1e8}},
+		Fee:       2000,
+	}, {
+		TxHash:    chainhash.Hash{3},
+		Time:      at.Add(2 * time.Hour),
+		Direction: export.DirectionSelf,
+		Amounts:   []export.TokenAmount{{TokenType: 0, Amount: 3e8}},
+	}}
+}
+
+// TestWriteCSV tests records are written as a row per token, with the fee on
+// the first row and the separators of the locale.
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	f := export.NewFormatter(export.WithLocale(export.LocaleDeDE),
+		export.WithLocation(time.FixedZone("CET", 3600)))
+	if err := f.WriteCSV(&buf, testRecords()); err != nil {
+		t.Fatalf("WriteCSV: unexpected error: %v", err)
+	}
+	r := csv.NewReader(&buf)
+	r.Comma = ';'
+	rows, err := r.ReadAll()
+	if err != nil {
+		t.Fatalf("reading CSV: %v", err)
+	}
+	if len(rows) != 5 {
+		t.Fatalf("got %d rows, want 5", len(rows))
+	}
+	if strings.Join(rows[0], ",") != strings.Join(export.CSVHeader, ",") {
+		t.Errorf("header: got %v", rows[0])
+	}
+
+	tests := []struct {
+		time, direction, token, amount, fee string
+	}{
+		{"2021-03-02T00:30:00+01:00", "incoming", "OMC", "2,5", "0"},
+		{"2021-03-02T00:30:00+01:00", "incoming", "T4", "1.500", ""},
+		{"2021-03-02T01:30:00+01:00", "outgoing", "OMC", "1", "0,00002"},
+		{"2021-03-02T02:30:00+01:00", "self", "OMC", "3", "0"},
+	}
+	for i, test := range tests {
+		row := rows[i+1]
+		if row[0] != test.time || row[2] != test.direction ||
+			row[4] != test.token || row[5] != test.amount ||
+			row[6] != test.fee {
+			t.Errorf("row %d: got %v, want %v", i+1, row, test)
+		}
+	}
+	if rows[1][1] != (chainhash.Hash{1}).String() || rows[1][3] != "savings" ||
+		rows[1][7] != "salary" {
+		t.Errorf("row 1: got %v", rows[1])
+	}
+
+	records := testRecords()
+	records[1].Fee = -1
+	if err := f.WriteCSV(&buf, records); err != export.ErrNegativeAmount {
+		t.Errorf("WriteCSV: got error %v, want %v", err,
+			export.ErrNegativeAmount)
+	}
+}
+
+// TestWriteLedger tests the journal written for the test records.
+func TestWriteLedger(t *testing.T) {
+	var buf bytes.Buffer
+	f := export.NewFormatter(export.WithTokenNames(map[uint64]string{
+		4: "GOLD BAR",
+	}))
+	if err := f.WriteLedger(&buf, testRecords()); err != nil {
+		t.Fatalf("WriteLedger: unexpected error: %v", err)
+	}
+
+	want := "2021/03/01 * salary\n" +
+		"    ; TxID: " + (chainhash.Hash{1}).String() + "\n" +
+		"    Assets:Omega:savings                      2.5 OMC\n" +
+		"    Income:Omega                              -2.5 OMC\n" +
+		"    Assets:Omega:savings                      1500 \"GOLD BAR\"\n" +
+		"    Income:Omega                              -1500 \"GOLD BAR\"\n" +
+		"\n" +
+		"2021/03/02 * " + (chainhash.Hash{2}).String() + "\n" +
+		"    ; TxID: " + (chainhash.Hash{2}).String() + "\n" +
+		"    Expenses:Omega                            1 OMC\n" +
+		"    Assets:Omega                              -1 OMC\n" +
+		"    Expenses:Omega:Fees                       0.00002 OMC\n" +
+		"    Assets:Omega                              -0.00002 OMC\n"
+	if got := buf.String(); got != want {
+		t.Errorf("WriteLedger: got\n%s\nwant\n%s", got, want)
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package btcutil
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"strings"
+)
+
+// FeeRate is a transaction fee rate expressed in Hao per 1000 virtual bytes
+// (Hao/kvB).  It is the unit fee estimators and relay policy work with, and
+// since it is an integer type, fee rates compare with the ordinary comparison
+// operators.
+type FeeRate int64
+
+// Fee rate units accepted by ParseFeeRate and produced by String.
+const (
+	feeRateUnitHaoPerVByte  = "Hao/vB"
+	feeRateUnitHaoPerKVByte = "Hao/kvB"
+	feeRateUnitOMCPerKVByte = "OMC/kvB"
+)
+
+// NewFeeRateFromHaoPerVByte returns the fee rate for the passed number of Hao
+// per virtual byte.  Like NewFeeRateFromFee, the result saturates at the
+// range of FeeRate rather than overflowing.
+func NewFeeRateFromHaoPerVByte(hao Amount) FeeRate {
+	switch {
+	case hao > math.MaxInt64/1000:
+		return math.MaxInt64
+	case hao < math.MinInt64/1000:
+		return math.MinInt64
+	}
+	return FeeRate(hao * 1000)
+}
+
+// NewFeeRateFromHaoPerKVByte returns the fee rate for the passed number of
+// Hao per 1000 virtual bytes.
+func NewFeeRateFromHaoPerKVByte(hao Amount) FeeRate {
+	return FeeRate(hao)
+}
+
+// NewFeeRateFromFee returns the fee rate paid by a transaction of vsize
+// virtual bytes paying fee.  The result is rounded down so the fee rate of a
+// transaction is never overstated.  A zero fee rate is returned when vsize is
+// not positive.
+func NewFeeRateFromFee(fee Amount, vsize int64) FeeRate {
+	if vsize <= 0 {
+		return 0
+	}
+	hi, lo := bits.Mul64(uint64(absAmount(fee)), 1000)
+	if hi >= uint64(vsize) {
+		if fee < 0 {
+			return math.MinInt64
+		}
+		return math.MaxInt64
+	}
+	quo, _ := bits.Div64(hi, lo, uint64(vsize))
+	if quo > math.MaxInt64 {
+		quo = math.MaxInt64
+	}
+	if fee < 0 {
+		return -FeeRate(quo)
+	}
+	return FeeRate(quo)
+}
+
+// absAmount returns the magnitude of a as an unsigned integer.
+func absAmount(a Amount) uint64 {
+	if a < 0 {
+		return -uint64(a)
+	}
+	return uint64(a)
+}
+
+// HaoPerVByte returns the fee rate in Hao per virtual byte.
+func (r FeeRate) HaoPerVByte() float64 {
+	return float64(r) / 1000
+}
+
+// HaoPerKVByte returns the fee rate in Hao per 1000 virtual bytes.
+func (r FeeRate) HaoPerKVByte() Amount {
+	return Amount(r)
+}
+
+// OMCPerKVByte returns the fee rate in OMC per 1000 virtual bytes, the unit
+// used by the fee related RPCs of the node.
+func (r FeeRate) OMCPerKVByte() float64 {
+	return Amount(r).ToOMC()
+}
+
+// FeeForVSize returns the fee for a transaction of vsize virtual bytes at the
+// fee rate.  The result is rounded up to the next whole Hao so that paying it
+// always achieves at least the fee rate.  Zero is returned for negative fee
+// rates or sizes, and the result saturates at the maximum Amount.
+func (r FeeRate) FeeForVSize(vsize int64) Amount {
+	if r <= 0 || vsize <= 0 {
+		return 0
+	}
+	hi, lo := bits.Mul64(uint64(r), uint64(vsize))
+	lo, carry := bits.Add64(lo, 999, 0)
+	hi += carry
+	if hi >= 1000 {
+		return math.MaxInt64
+	}
+	quo, _ := bits.Div64(hi, lo, 1000)
+	if quo > math.MaxInt64 {
+		return math.MaxInt64
+	}
+	return Amount(quo)
+}
+
+// String returns the fee rate in Hao per virtual byte, such as "12.5 Hao/vB".
+func (r FeeRate) String() string {
+	return formatDecimal(Amount(r), 3) + " " + feeRateUnitHaoPerVByte
+}
+
+// ParseFeeRate parses a fee rate expressed as an exact decimal number
+// followed by a single space and one of the units "Hao/vB", "Hao/kvB", or
+// "OMC/kvB".  Units are matched case-insensitively and a missing unit is
+// interpreted as Hao/vB.  Precision beyond one Hao per 1000 virtual bytes is
+// rejected rather than rounded.
+func ParseFeeRate(s string) (FeeRate, error) {
+	num, unit := s, feeRateUnitHaoPerVByte
+	if i := strings.IndexByte(s, ' '); i >= 0 {
+		num, unit = s[:i], s[i+1:]
+	}
+
+	var exp int
+	switch {
+	case strings.EqualFold(unit, feeRateUnitHaoPerVByte):
+		exp = 3
+	case strings.EqualFold(unit, feeRateUnitHaoPerKVByte):
+		exp = 0
+	case strings.EqualFold(unit, feeRateUnitOMCPerKVByte):
+		exp = int(AmountOMC) + OMCDecimals
+	default:
+		return 0, fmt.Errorf("%w %q", ErrUnknownUnit, unit)
+	}
+
+	rate, err := parseDecimal(num, exp)
+	if err != nil {
+		return 0, err
+	}
+	return FeeRate(rate), nil
+}
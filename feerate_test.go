@@ -0,0 +1,142 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package btcutil_test
+
+import (
+	"errors"
+	"math"
+	"testing"
+
+	. "github.com/zeusyf/btcutil"
+)
+
+func TestFeeRateConversions(t *testing.T) {
+	r := NewFeeRateFromHaoPerVByte(12)
+	if r != 12000 || r.HaoPerKVByte() != 12000 || r.HaoPerVByte() != 12 {
+		t.Fatalf("NewFeeRateFromHaoPerVByte: got %d", r)
+	}
+	if r.OMCPerKVByte() != 0.00012 {
+		t.Fatalf("OMCPerKVByte: got %v, want 0.00012", r.OMCPerKVByte())
+	}
+	if NewFeeRateFromHaoPerKVByte(1500).String() != "1.5 Hao/vB" {
+		t.Fatalf("String: got %q", NewFeeRateFromHaoPerKVByte(1500))
+	}
+	if NewFeeRateFromHaoPerKVByte(1500) <= NewFeeRateFromHaoPerVByte(1) {
+		t.Fatalf("comparison: 1.5 Hao/vB is not above 1 Hao/vB")
+	}
+
+	// Rates beyond the range of FeeRate saturate.
+	tests := []struct {
+		hao  Amount
+		want FeeRate
+	}{
+		{math.MaxInt64 / 1000, math.MaxInt64 / 1000 * 1000},
+		{math.MaxInt64/1000 + 1, math.MaxInt64},
+		{math.MaxInt64, math.MaxInt64},
+		{math.MinInt64 / 1000, math.MinInt64 / 1000 * 1000},
+		{math.MinInt64/1000 - 1, math.MinInt64},
+		{math.MinInt64, math.MinInt64},
+	}
+	for _, test := range tests {
+		if r := NewFeeRateFromHaoPerVByte(test.hao); r != test.want {
+			t.Errorf("NewFeeRateFromHaoPerVByte(%d): got %d, want %d",
+				int64(test.hao), r, test.want)
+		}
+	}
+}
+
+func TestFeeRateFeeForVSize(t *testing.T) {
+	tests := []struct {
+		rate  FeeRate
+		vsize int64
+		fee   Amount
+	}{
+		{rate: 1000, vsize: 141, fee: 141},
+		{rate: 1001, vsize: 141, fee: 142},
+		{rate: 1500, vsize: 1, fee: 2},
+		{rate: 1, vsize: 1, fee: 1},
+		{rate: 0, vsize: 1000, fee: 0},
+		{rate: -5, vsize: 1000, fee: 0},
+		{rate: 1000, vsize: -1, fee: 0},
+		{rate: math.MaxInt64, vsize: math.MaxInt64, fee: math.MaxInt64},
+	}
+
+	for _, test := range tests {
+		fee := test.rate.FeeForVSize(test.vsize)
+		if fee != test.fee {
+			t.Errorf("FeeForVSize(%d @ %d): got %d, want %d",
+				test.vsize, test.rate, fee, test.fee)
+			continue
+		}
+
+		// The fee rate paid must never fall short of the requested
+		// rate.
+		if test.rate > 0 && test.vsize > 0 && test.fee < math.MaxInt64 {
+			paid := NewFeeRateFromFee(fee, test.vsize)
+			if paid < test.rate {
+				t.Errorf("FeeForVSize(%d @ %d): pays %d", test.vsize,
+					test.rate, paid)
+			}
+		}
+	}
+}
+
+func TestNewFeeRateFromFee(t *testing.T) {
+	if r := NewFeeRateFromFee(142, 141); r != 1007 {
+		t.Fatalf("NewFeeRateFromFee: got %d, want 1007", r)
+	}
+	if r := NewFeeRateFromFee(-142, 141); r != -1007 {
+		t.Fatalf("NewFeeRateFromFee: got %d, want -1007", r)
+	}
+	if r := NewFeeRateFromFee(100, 0); r != 0 {
+		t.Fatalf("NewFeeRateFromFee: got %d, want 0", r)
+	}
+	if r := NewFeeRateFromFee(math.MaxInt64, 1); r != math.MaxInt64 {
+		t.Fatalf("NewFeeRateFromFee: got %d, want max", r)
+	}
+}
+
+func TestParseFeeRate(t *testing.T) {
+	tests := []struct {
+		s     string
+		valid bool
+		rate  FeeRate
+		err   error
+	}{
+		{s: "12.5", valid: true, rate: 12500},
+		{s: "12.5 Hao/vB", valid: true, rate: 12500},
+		{s: "12.5 hao/vb", valid: true, rate: 12500},
+		{s: "12500 Hao/kvB", valid: true, rate: 12500},
+		{s: "0.000125 OMC/kvB", valid: true, rate: 12500},
+		{s: "0.0001 Hao/vB", err: ErrAmountPrecision},
+		{s: "12.5 sat/vB", err: ErrUnknownUnit},
+		{s: "abc", err: ErrInvalidAmount},
+	}
+
+	for _, test := range tests {
+		rate, err := ParseFeeRate(test.s)
+		switch {
+		case test.valid && err != nil:
+			t.Errorf("ParseFeeRate(%q): unexpected error: %v", test.s, err)
+		case !test.valid && err == nil:
+			t.Errorf("ParseFeeRate(%q): succeeded when should fail", test.s)
+		case !test.valid && !errors.Is(err, test.err):
+			t.Errorf("ParseFeeRate(%q): got error %v, want %v", test.s,
+				err, test.err)
+		case test.valid && rate != test.rate:
+			t.Errorf("ParseFeeRate(%q): got %d, want %d", test.s, rate,
+				test.rate)
+		}
+	}
+
+	// String output must parse back to the same fee rate.
+	for _, r := range []FeeRate{0, 1, 999, 1000, 123456789} {
+		got, err := ParseFeeRate(r.String())
+		if err != nil || got != r {
+			t.Errorf("ParseFeeRate(%q): got %d (%v), want %d", r, got,
+				err, r)
+		}
+	}
+}
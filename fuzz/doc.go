@@ -0,0 +1,23 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+/*
+Package fuzz provides the entry points for fuzzing the decoders of addresses,
+WIF strings, base58check and bech32 strings, and packets of the psbt package.
+
+Each entry point takes the raw input in the form expected by go-fuzz and
+OSS-Fuzz, decodes it at every strictness of btcutil.DecodeOptions, and panics
+when the decoders disagree with each other: input accepted by strict decoding
+must be accepted by lenient decoding with the same result, and its encoding
+must decode back to the same result.  Entry points return 1 when the input decoded, so the
+fuzzer gives priority to it, and 0 otherwise.
+
+Recovering decoding tries every single character repair of its input, so it
+is only run on input no longer than MaxRecoveryLen.
+
+The entry points are also run by the native fuzz tests of the package:
+
+	go test -fuzz FuzzAddress ./fuzz
+*/
+package fuzz
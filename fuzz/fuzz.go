@@ -0,0 +1,159 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package fuzz
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/base58"
+	"github.com/zeusyf/btcutil/bech32"
+	"github.com/zeusyf/btcutil/psbt"
+)
+
+// MaxRecoveryLen is the length of the longest input decoded with recovering
+// decoding.
+const MaxRecoveryLen = 128
+
+var (
+	strict   = btcutil.DecodeOptions{Strictness: btcutil.Strict}
+	lenient  = btcutil.DecodeOptions{Strictness: btcutil.Lenient}
+	recovery = btcutil.DecodeOptions{Strictness: btcutil.Recovery}
+)
+
+// check panics with the message built from format and args unless ok.
+func check(ok bool, format string, args ...interface{}) {
+	if !ok {
+		panic(fmt.Sprintf(format, args...))
+	}
+}
+
+// Address is the entry point for fuzzing DecodeAddressWithOptions.
+func Address(data []byte) int {
+	s := string(data)
+	a, err := btcutil.DecodeAddressWithOptions(s, &chaincfg.MainNetParams,
+		strict)
+	if len(data) <= MaxRecoveryLen {
+		btcutil.DecodeAddressWithOptions(s, &chaincfg.MainNetParams,
+			recovery)
+	}
+	if err != nil {
+		btcutil.DecodeAddressWithOptions(s, &chaincfg.MainNetParams,
+			lenient)
+		return 0
+	}
+
+	// Public keys and hex encoded hashes encode to base58 addresses, so
+	// the encoding is only checked to decode to itself.
+	encoded := a.EncodeAddress()
+	again, err := btcutil.DecodeAddressWithOptions(encoded,
+		&chaincfg.MainNetParams, strict)
+	check(err == nil && again.EncodeAddress() == encoded,
+		"address %q encodes to %q, which does not decode to itself", s,
+		encoded)
+	l, err := btcutil.DecodeAddressWithOptions(s, &chaincfg.MainNetParams,
+		lenient)
+	check(err == nil, "address %q rejected by lenient decoding: %v", s, err)
+	check(l.EncodeAddress() == encoded, "address %q decodes leniently to %q",
+		s, l.EncodeAddress())
+	return 1
+}
+
+// WIF is the entry point for fuzzing DecodeWIFWithOptions.
+func WIF(data []byte) int {
+	s := string(data)
+	w, err := btcutil.DecodeWIFWithOptions(s, strict)
+	if len(data) <= MaxRecoveryLen {
+		btcutil.DecodeWIFWithOptions(s, recovery)
+	}
+	if err != nil {
+		btcutil.DecodeWIFWithOptions(s, lenient)
+		return 0
+	}
+
+	encoded := w.String()
+	again, err := btcutil.DecodeWIFWithOptions(encoded, strict)
+	check(err == nil && again.String() == encoded,
+		"WIF %q encodes to %q, which does not decode to itself", s, encoded)
+	l, err := btcutil.DecodeWIFWithOptions(s, lenient)
+	check(err == nil, "WIF %q rejected by lenient decoding: %v", s, err)
+	check(l.String() == encoded, "WIF %q decodes leniently to %q", s,
+		l.String())
+	return 1
+}
+
+// Base58Check is the entry point for fuzzing DecodeBase58CheckWithOptions.
+func Base58Check(data []byte) int {
+	s := string(data)
+	payload, version, err := btcutil.DecodeBase58CheckWithOptions(s, strict)
+	if len(data) <= MaxRecoveryLen {
+		btcutil.DecodeBase58CheckWithOptions(s, recovery)
+	}
+	if err != nil {
+		btcutil.DecodeBase58CheckWithOptions(s, lenient)
+		return 0
+	}
+
+	encoded := base58.CheckEncode(payload, version)
+	check(encoded == s, "base58check %q encodes to %q", s, encoded)
+	l, lVersion, err := btcutil.DecodeBase58CheckWithOptions(s, lenient)
+	check(err == nil, "base58check %q rejected by lenient decoding: %v", s,
+		err)
+	check(bytes.Equal(l, payload) && lVersion == version,
+		"base58check %q decodes leniently to %x version %d", s, l, lVersion)
+	return 1
+}
+
+// Bech32 is the entry point for fuzzing DecodeBech32WithOptions.
+func Bech32(data []byte) int {
+	s := string(data)
+	hrp, values, err := btcutil.DecodeBech32WithOptions(s, strict)
+	if len(data) <= MaxRecoveryLen {
+		btcutil.DecodeBech32WithOptions(s, recovery)
+	}
+	if err != nil {
+		btcutil.DecodeBech32WithOptions(s, lenient)
+		return 0
+	}
+
+	encoded, err := bech32.Encode(hrp, values)
+	check(err == nil, "bech32 %q does not encode: %v", s, err)
+	check(encoded == strings.ToLower(s), "bech32 %q encodes to %q", s,
+		encoded)
+	lHRP, l, err := btcutil.DecodeBech32WithOptions(s, lenient)
+	check(err == nil, "bech32 %q rejected by lenient decoding: %v", s, err)
+	check(lHRP == hrp && bytes.Equal(l, values),
+		"bech32 %q decodes leniently to %q %x", s, lHRP, l)
+	return 1
+}
+
+// Packet is the entry point for fuzzing psbt.ParseWithOptions.  Since the
+// serialization of a packet orders its fields, the serialization of a parsed
+// packet is only checked to parse back to the same serialization.
+func Packet(data []byte) int {
+	p, err := psbt.ParseWithOptions(data, strict)
+	psbt.ParseWithOptions(data, recovery)
+	if err != nil {
+		psbt.ParseWithOptions(data, lenient)
+		return 0
+	}
+
+	var buf bytes.Buffer
+	check(p.Serialize(&buf) == nil, "packet %x does not serialize", data)
+	again, err := psbt.ParseWithOptions(buf.Bytes(), strict)
+	check(err == nil, "packet %x serializes to unparsable %x: %v", data,
+		buf.Bytes(), err)
+	var againBuf bytes.Buffer
+	check(again.Serialize(&againBuf) == nil &&
+		bytes.Equal(againBuf.Bytes(), buf.Bytes()),
+		"packet %x serializes to %x, then to %x", data, buf.Bytes(),
+		againBuf.Bytes())
+	_, err = psbt.ParseWithOptions(data, lenient)
+	check(err == nil, "packet %x rejected by lenient parsing: %v", data, err)
+	return 1
+}
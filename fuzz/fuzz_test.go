@@ -0,0 +1,87 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package fuzz_test
+
+import (
+	"testing"
+
+	"github.com/zeusyf/btcutil/fuzz"
+)
+
+// FuzzAddress runs the address entry point.
+func FuzzAddress(f *testing.F) {
+	f.Add([]byte("1MirQ9bwyQcGVJPwKUgapu5ouK2E2Ey4gX"))
+	f.Add([]byte(" 1MirQ9bwyQcGVJPwKUgapu5ouK2E2Ey4gX\n"))
+	f.Add([]byte("1MirQ9bwyQxGVJPwKUgapu5ouK2E2Ey4gX"))
+	f.Add([]byte("02192d74d0cb94344c9569c2e77901573d8d7903c3ebec3a957724895dca52c6b4"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fuzz.Address(data)
+	})
+}
+
+// FuzzWIF runs the WIF entry point.
+func FuzzWIF(f *testing.F) {
+	f.Add([]byte("5HueCGU8rMjxEXxiPuD5BDku4MkFqeZyd4dZ1jvhTVqvbTLvyTJ"))
+	f.Add([]byte("\t5HueCGU8rMjxEXxiPuD5BDku4MkFqeZyd4dZ1jvhTVqvbTLvyTJ "))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fuzz.WIF(data)
+	})
+}
+
+// FuzzBase58Check runs the base58check entry point.
+func FuzzBase58Check(f *testing.F) {
+	f.Add([]byte("1J8ypdfLDyaj"))
+	f.Add([]byte("1111111111"))
+	f.Add([]byte("\"1J8ypdfLDyaj\""))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fuzz.Base58Check(data)
+	})
+}
+
+// FuzzBech32 runs the bech32 entry point.
+func FuzzBech32(f *testing.F) {
+	f.Add([]byte("a12uel5l"))
+	f.Add([]byte("A12UEL5L"))
+	f.Add([]byte("A12uel5l"))
+	f.Add([]byte("abcdef1qpzry9x8gf2tvdw0s3jn54khce6mua7lmqqqxw"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fuzz.Bech32(data)
+	})
+}
+
+// FuzzPacket runs the packet entry point.
+func FuzzPacket(f *testing.F) {
+	f.Add([]byte("psbt\xff\x00"))
+	f.Add([]byte("psbt\xff\x01\x00\x00\x00\x00"))
+	f.Fuzz(func(t *testing.T, data []byte) {
+		fuzz.Packet(data)
+	})
+}
+
+// TestEntryPoints ensures the entry points report which inputs decoded.
+func TestEntryPoints(t *testing.T) {
+	tests := []struct {
+		name string
+		fn   func([]byte) int
+		data string
+		want int
+	}{
+		{"Address", fuzz.Address, "1MirQ9bwyQcGVJPwKUgapu5ouK2E2Ey4gX", 1},
+		{"Address", fuzz.Address, "not an address", 0},
+		{"WIF", fuzz.WIF, "5HueCGU8rMjxEXxiPuD5BDku4MkFqeZyd4dZ1jvhTVqvbTLvyTJ", 1},
+		{"WIF", fuzz.WIF, " 5HueCGU8rMjxEXxiPuD5BDku4MkFqeZyd4dZ1jvhTVqvbTLvyTJ", 0},
+		{"Base58Check", fuzz.Base58Check, "1J8ypdfLDyaj", 1},
+		{"Base58Check", fuzz.Base58Check, "1J8ypdfLDyak", 0},
+		{"Bech32", fuzz.Bech32, "A12UEL5L", 1},
+		{"Bech32", fuzz.Bech32, "A12uel5l", 0},
+		{"Packet", fuzz.Packet, "psbu\xff\x00", 0},
+	}
+	for _, test := range tests {
+		if got := test.fn([]byte(test.data)); got != test.want {
+			t.Errorf("%s(%q): got %d, want %d", test.name, test.data,
+				got, test.want)
+		}
+	}
+}
@@ -187,6 +187,27 @@ func (b *GCSBuilder) AddHash(hash *chainhash.Hash) *GCSBuilder {
 	return b.AddEntry(hash.CloneBytes())
 }
 
+// FilterWriter returns a gcs.FilterWriter with the builder's key and
+// parameters which already holds the entries added to the builder.  Further
+// entries written to it are not added to the builder.  It is meant for
+// filters with many or large entries, which the writer does not keep in
+// memory.
+func (b *GCSBuilder) FilterWriter() (*gcs.FilterWriter, error) {
+	// Do nothing if the builder's already errored out.
+	if b.err != nil {
+		return nil, b.err
+	}
+
+	w, err := gcs.NewFilterWriter(b.p, b.m, b.key)
+	if err != nil {
+		return nil, err
+	}
+	for item := range b.data {
+		w.Write([]byte(item))
+	}
+	return w, nil
+}
+
 // Build returns a function which builds a GCS filter with the given parameters
 // and data.
 func (b *GCSBuilder) Build() (*gcs.Filter, error) {
@@ -291,11 +312,10 @@ func isContract(netid byte) bool {
 // as well as the data pushes within all the outputs created within a block.
 func BuildBasicFilter(block *wire.MsgBlock, prevOutScripts [][]byte) (*gcs.Filter, error) {
 	blockHash := block.BlockHash()
-	b := WithKeyHash(&blockHash)
 
-	// If the filter had an issue with the specified key, then we force it
-	// to bubble up here by calling the Key() function.
-	_, err := b.Key()
+	// The scripts are streamed into a filter writer, which only keeps
+	// their hashes, so large blocks don't need a copy of every script.
+	w, err := gcs.NewFilterWriter(DefaultP, DefaultM, DeriveKey(&blockHash))
 	if err != nil {
 		return nil, err
 	}
@@ -317,7 +337,7 @@ func BuildBasicFilter(block *wire.MsgBlock, prevOutScripts [][]byte) (*gcs.Filte
 				continue
 			}
 
-			b.AddEntry(txOut.PkScript)
+			w.Write(txOut.PkScript)
 		}
 	}
 
@@ -328,10 +348,10 @@ func BuildBasicFilter(block *wire.MsgBlock, prevOutScripts [][]byte) (*gcs.Filte
 			continue
 		}
 
-		b.AddEntry(prevScript)
+		w.Write(prevScript)
 	}
 
-	return b.Build()
+	return w.Filter()
 }
 
 // GetFilterHash returns the double-SHA256 of the filter.
@@ -347,18 +367,10 @@ func GetFilterHash(filter *gcs.Filter) (chainhash.Hash, error) {
 // MakeHeaderForFilter makes a filter chain header for a filter, given the
 // filter and the previous filter chain header.
 func MakeHeaderForFilter(filter *gcs.Filter, prevHeader chainhash.Hash) (chainhash.Hash, error) {
-	filterTip := make([]byte, 2*chainhash.HashSize)
 	filterHash, err := GetFilterHash(filter)
 	if err != nil {
 		return chainhash.Hash{}, err
 	}
 
-	// In the buffer we created above we'll compute hash || prevHash as an
-	// intermediate value.
-	copy(filterTip, filterHash[:])
-	copy(filterTip[chainhash.HashSize:], prevHeader[:])
-
-	// The final filter hash is the double-sha256 of the hash computed
-	// above.
-	return chainhash.DoubleHashH(filterTip), nil
+	return MakeHeaderForFilterHash(filterHash, prevHeader), nil
 }
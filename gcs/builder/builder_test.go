@@ -6,6 +6,7 @@
 package builder_test
 
 import (
+	"bytes"
 	"encoding/hex"
 	"testing"
 
@@ -280,3 +281,43 @@ func BuilderTest(b *builder.GCSBuilder, hash *chainhash.Hash, p uint8,
 		t.Fatal("Filter size increased with duplicate items")
 	}
 }
+
+// TestBuilderFilterWriter ensures a filter writer obtained from a builder
+// starts out with the builder's entries and parameters.
+func TestBuilderFilterWriter(t *testing.T) {
+	b := builder.WithKeyPM(testKey, builder.DefaultP, builder.DefaultM)
+	b.AddEntries(contents[:10])
+	want, err := b.Build()
+	if err != nil {
+		t.Fatalf("Filter build failed: %s", err.Error())
+	}
+
+	w, err := b.FilterWriter()
+	if err != nil {
+		t.Fatalf("FilterWriter failed: %s", err.Error())
+	}
+	got, err := w.Filter()
+	if err != nil {
+		t.Fatalf("Filter build failed: %s", err.Error())
+	}
+	gotBytes, _ := got.NBytes()
+	wantBytes, _ := want.NBytes()
+	if !bytes.Equal(gotBytes, wantBytes) {
+		t.Fatal("Filter from writer differs from built filter")
+	}
+
+	// Entries written to the writer are not added to the builder.
+	for _, entry := range contents[10:] {
+		w.Write(entry)
+	}
+	got, err = w.Filter()
+	if err != nil {
+		t.Fatalf("Filter build failed: %s", err.Error())
+	}
+	if got.N() != uint32(len(contents)) {
+		t.Fatalf("Filter has %d entries, want %d", got.N(), len(contents))
+	}
+	if f, _ := b.Build(); f.N() != want.N() {
+		t.Fatal("Writing to the filter writer changed the builder")
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package builder
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcutil/gcs"
+)
+
+var (
+	// ErrHeaderMismatch describes an error where a filter, or a filter
+	// header received from a peer, does not match the header computed
+	// from the filter hashes and the previous header.
+	ErrHeaderMismatch = errors.New("filter header does not match chain")
+
+	// ErrHeaderCount describes an error where a header chain segment does
+	// not have one header for each filter hash.
+	ErrHeaderCount = errors.New("filter hash and header counts differ")
+)
+
+// MakeHeaderForFilterHash makes a filter chain header from the hash of a
+// filter, as returned by GetFilterHash, and the previous filter chain header:
+// the double-SHA256 of the filter hash followed by the previous header.  The
+// previous header of the genesis block is the zero hash.
+func MakeHeaderForFilterHash(filterHash, prevHeader chainhash.Hash) chainhash.Hash {
+	var filterTip [2 * chainhash.HashSize]byte
+	copy(filterTip[:], filterHash[:])
+	copy(filterTip[chainhash.HashSize:], prevHeader[:])
+	return chainhash.DoubleHashH(filterTip[:])
+}
+
+// MakeHeaderChain returns the filter chain headers of consecutive blocks
+// whose filters have the passed hashes, following prevHeader, the header of
+// the block before the first.
+func MakeHeaderChain(filterHashes []chainhash.Hash, prevHeader chainhash.Hash) []chainhash.Hash {
+	headers := make([]chainhash.Hash, len(filterHashes))
+	for i := range filterHashes {
+		prevHeader = MakeHeaderForFilterHash(filterHashes[i], prevHeader)
+		headers[i] = prevHeader
+	}
+	return headers
+}
+
+// VerifyHeaderChain checks that headers is the segment of the filter header
+// chain following prevHeader for filters with the passed hashes, as received
+// from a peer in a cfheaders exchange.  The error of a mismatch names the
+// first header of the segment which does not link.
+//
+// Only the consistency of the segment is checked.  Light clients must compare
+// prevHeader, or any of the headers, against a checkpoint or the headers of
+// other peers before trusting it.
+func VerifyHeaderChain(prevHeader chainhash.Hash, filterHashes, headers []chainhash.Hash) error {
+	if len(filterHashes) != len(headers) {
+		return ErrHeaderCount
+	}
+	for i := range filterHashes {
+		prevHeader = MakeHeaderForFilterHash(filterHashes[i], prevHeader)
+		if headers[i] != prevHeader {
+			return fmt.Errorf("header %d: %w", i, ErrHeaderMismatch)
+		}
+	}
+	return nil
+}
+
+// VerifyFilter checks that filter is the one committed to by header, the
+// filter chain header of its block, given the header of the block before.
+func VerifyFilter(filter *gcs.Filter, prevHeader, header chainhash.Hash) error {
+	filterHash, err := GetFilterHash(filter)
+	if err != nil {
+		return err
+	}
+	if MakeHeaderForFilterHash(filterHash, prevHeader) != header {
+		return ErrHeaderMismatch
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package builder_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcutil/gcs"
+	"github.com/zeusyf/btcutil/gcs/builder"
+)
+
+// TestFilterHeaderChain ensures header chains made from filters verify, and
+// altered filters and headers are detected.
+func TestFilterHeaderChain(t *testing.T) {
+	var filters []*gcs.Filter
+	var filterHashes []chainhash.Hash
+	for i := 0; i < 3; i++ {
+		filter, err := gcs.BuildGCSFilter(builder.DefaultP, builder.DefaultM,
+			testKey, contents[i*4:i*4+4])
+		if err != nil {
+			t.Fatalf("BuildGCSFilter: unexpected error: %v", err)
+		}
+		filterHash, err := builder.GetFilterHash(filter)
+		if err != nil {
+			t.Fatalf("GetFilterHash: unexpected error: %v", err)
+		}
+		filters = append(filters, filter)
+		filterHashes = append(filterHashes, filterHash)
+	}
+
+	// The chain starts from the zero hash at genesis, and agrees with the
+	// header made from each filter.
+	var genesis chainhash.Hash
+	headers := builder.MakeHeaderChain(filterHashes, genesis)
+	prev := genesis
+	for i, filter := range filters {
+		header, err := builder.MakeHeaderForFilter(filter, prev)
+		if err != nil {
+			t.Fatalf("MakeHeaderForFilter: unexpected error: %v", err)
+		}
+		if header != headers[i] {
+			t.Errorf("MakeHeaderChain: header %d is %v, want %v", i,
+				headers[i], header)
+		}
+		if err := builder.VerifyFilter(filter, prev, header); err != nil {
+			t.Errorf("VerifyFilter: unexpected error: %v", err)
+		}
+		prev = header
+	}
+	if err := builder.VerifyHeaderChain(genesis, filterHashes, headers); err != nil {
+		t.Errorf("VerifyHeaderChain: unexpected error: %v", err)
+	}
+
+	// A segment from the middle of the chain verifies from the header
+	// before it.
+	if err := builder.VerifyHeaderChain(headers[0], filterHashes[1:], headers[1:]); err != nil {
+		t.Errorf("VerifyHeaderChain: unexpected error for segment: %v", err)
+	}
+
+	if err := builder.VerifyFilter(filters[1], genesis, headers[1]); err != builder.ErrHeaderMismatch {
+		t.Errorf("VerifyFilter: got error %v, want %v", err,
+			builder.ErrHeaderMismatch)
+	}
+
+	tampered := append([]chainhash.Hash(nil), headers...)
+	tampered[1][0] ^= 1
+	tests := []struct {
+		name         string
+		prev         chainhash.Hash
+		filterHashes []chainhash.Hash
+		headers      []chainhash.Hash
+		err          error
+	}{
+		{"tampered header", genesis, filterHashes, tampered, builder.ErrHeaderMismatch},
+		{"wrong previous header", headers[0], filterHashes, headers, builder.ErrHeaderMismatch},
+		{"swapped filters", genesis, []chainhash.Hash{filterHashes[1],
+			filterHashes[0], filterHashes[2]}, headers, builder.ErrHeaderMismatch},
+		{"missing header", genesis, filterHashes, headers[:2], builder.ErrHeaderCount},
+	}
+	for _, test := range tests {
+		err := builder.VerifyHeaderChain(test.prev, test.filterHashes, test.headers)
+		if !errors.Is(err, test.err) {
+			t.Errorf("VerifyHeaderChain(%s): got error %v, want %v",
+				test.name, err, test.err)
+		}
+	}
+}
@@ -11,10 +11,10 @@ import (
 	"io"
 	"sort"
 
-	"github.com/aead/siphash"
 //	"github.com/zeusyf/btcd/wire"
 	"github.com/kkdai/bstream"
 	"github.com/zeusyf/btcd/wire/common"
+	"github.com/zeusyf/btcutil/hashing"
 )
 
 // Inspired by https://github.com/rasky/gcs
@@ -121,7 +121,6 @@ func BuildGCSFilter(P uint8, M uint64, key [KeySize]byte, data [][]byte) (*Filte
 
 	// Build the filter.
 	values := make(uint64Slice, 0, len(data))
-	b := bstream.NewBStreamWriter(0)
 
 	// Insert the hash (fast-ranged over a space of N*P) of each data
 	// element into a slice and sort the slice. This can be greatly
@@ -132,19 +131,37 @@ func BuildGCSFilter(P uint8, M uint64, key [KeySize]byte, data [][]byte) (*Filte
 	// multiplication of 2 64-bit integers into a 128-bit integer.
 	nphi := f.modulusNP >> 32
 	nplo := uint64(uint32(f.modulusNP))
+	h := hashing.Current()
 	for _, d := range data {
 		// For each datum, we assign the initial hash to a uint64.
-		v := siphash.Sum64(d, &key)
+		v := h.SipHash24(&key, d)
 
 		v = fastReduction(v, nphi, nplo)
 		values = append(values, v)
 	}
 	sort.Sort(values)
 
-	// Write the sorted list of values into the filter bitstream,
-	// compressing it using Golomb coding.
+	f.encode(values, true)
+
+	return &f, nil
+}
+
+// encode writes the sorted list of values into the filter bitstream,
+// compressing it using Golomb coding.  When reduced is false, the values are
+// raw hashes which are reduced to the range of the filter's modulus as they
+// are written.  Reduction is monotonic, so sorted hashes remain sorted.
+func (f *Filter) encode(values []uint64, reduced bool) {
+	b := bstream.NewBStreamWriter(0)
+
+	nphi := f.modulusNP >> 32
+	nplo := uint64(uint32(f.modulusNP))
+
 	var value, lastValue, remainder uint64
 	for _, v := range values {
+		if !reduced {
+			v = fastReduction(v, nphi, nplo)
+		}
+
 		// Calculate the difference between this value and the last,
 		// modulo P.
 		remainder = (v - lastValue) & ((uint64(1) << f.p) - 1)
@@ -167,10 +184,8 @@ func BuildGCSFilter(P uint8, M uint64, key [KeySize]byte, data [][]byte) (*Filte
 		b.WriteBits(remainder, int(f.p))
 	}
 
-	// Copy the bitstream into the filter object and return the object.
+	// Copy the bitstream into the filter object.
 	f.filterData = b.Bytes()
-
-	return &f, nil
 }
 
 // FromBytes deserializes a GCS filter from a known N, P, and serialized filter
@@ -287,13 +302,8 @@ func (f *Filter) N() uint32 {
 // Match checks whether a []byte value is likely (within collision probability)
 // to be a member of the set represented by the filter.
 func (f *Filter) Match(key [KeySize]byte, data []byte) (bool, error) {
-	// Create a filter bitstream.
-	filterData, err := f.Bytes()
-	if err != nil {
-		return false, err
-	}
-
-	b := bstream.NewBStreamReader(filterData)
+	// Read the filter bitstream in place.
+	r := bitReader{data: f.filterData}
 
 	// We take the high and low bits of modulusNP for the multiplication
 	// of 2 64-bit integers into a 128-bit integer.
@@ -301,7 +311,7 @@ func (f *Filter) Match(key [KeySize]byte, data []byte) (bool, error) {
 	nplo := uint64(uint32(f.modulusNP))
 
 	// Then we hash our search term with the same parameters as the filter.
-	term := siphash.Sum64(data, &key)
+	term := hashing.SipHash24(&key, data)
 	term = fastReduction(term, nphi, nplo)
 
 	// Go through the search filter and look for the desired value.
@@ -310,7 +320,7 @@ func (f *Filter) Match(key [KeySize]byte, data []byte) (bool, error) {
 
 		// Read the difference between previous and new value from
 		// bitstream.
-		value, err := f.readFullUint64(b)
+		value, err := r.readFullUint64(f.p)
 		if err != nil {
 			if err == io.EOF {
 				return false, nil
@@ -332,102 +342,10 @@ func (f *Filter) Match(key [KeySize]byte, data []byte) (bool, error) {
 
 // MatchAny returns checks whether any []byte value is likely (within collision
 // probability) to be a member of the set represented by the filter faster than
-// calling Match() for each value individually.
+// calling Match() for each value individually.  Queries of up to 32 values do
+// not allocate; use a Matcher to match larger queries against many filters.
 func (f *Filter) MatchAny(key [KeySize]byte, data [][]byte) (bool, error) {
-	// Basic sanity check.
-	if len(data) == 0 {
-		return false, nil
-	}
-
-	// Create a filter bitstream.
-	filterData, err := f.Bytes()
-	if err != nil {
-		return false, err
-	}
-
-	b := bstream.NewBStreamReader(filterData)
-
-	// Create an uncompressed filter of the search values.
-	values := make(uint64Slice, 0, len(data))
-
-	// First, we cache the high and low bits of modulusNP for the
-	// multiplication of 2 64-bit integers into a 128-bit integer.
-	nphi := f.modulusNP >> 32
-	nplo := uint64(uint32(f.modulusNP))
-	for _, d := range data {
-		// For each datum, we assign the initial hash to a uint64.
-		v := siphash.Sum64(d, &key)
-
-		// We'll then reduce the value down to the range of our
-		// modulus.
-		v = fastReduction(v, nphi, nplo)
-		values = append(values, v)
-	}
-	sort.Sort(values)
-
-	// Zip down the filters, comparing values until we either run out of
-	// values to compare in one of the filters or we reach a matching
-	// value.
-	var lastValue1, lastValue2 uint64
-	lastValue2 = values[0]
-	i := 1
-	for lastValue1 != lastValue2 {
-		// Check which filter to advance to make sure we're comparing
-		// the right values.
-		switch {
-		case lastValue1 > lastValue2:
-			// Advance filter created from search terms or return
-			// false if we're at the end because nothing matched.
-			if i < len(values) {
-				lastValue2 = values[i]
-				i++
-			} else {
-				return false, nil
-			}
-		case lastValue2 > lastValue1:
-			// Advance filter we're searching or return false if
-			// we're at the end because nothing matched.
-			value, err := f.readFullUint64(b)
-			if err != nil {
-				if err == io.EOF {
-					return false, nil
-				}
-				return false, err
-			}
-			lastValue1 += value
-		}
-	}
-
-	// If we've made it this far, an element matched between filters so we
-	// return true.
-	return true, nil
-}
-
-// readFullUint64 reads a value represented by the sum of a unary multiple of
-// the filter's P modulus (`2**P`) and a big-endian P-bit remainder.
-func (f *Filter) readFullUint64(b *bstream.BStream) (uint64, error) {
-	var quotient uint64
-
-	// Count the 1s until we reach a 0.
-	c, err := b.ReadBit()
-	if err != nil {
-		return 0, err
-	}
-	for c {
-		quotient++
-		c, err = b.ReadBit()
-		if err != nil {
-			return 0, err
-		}
-	}
-
-	// Read P bits.
-	remainder, err := b.ReadBits(int(f.p))
-	if err != nil {
-		return 0, err
-	}
-
-	// Add the multiple and the remainder.
-	v := (quotient << f.p) + remainder
-	return v, nil
+	var buf [matchAnyStackSize]uint64
+	match, _, err := f.matchAny(key, data, buf[:0])
+	return match, err
 }
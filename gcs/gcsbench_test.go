@@ -85,6 +85,34 @@ func BenchmarkGCSFilterBuild100000(b *testing.B) {
 	generatedFilter = localFilter
 }
 
+// BenchmarkFilterWriter50000 benchmarks building a filter by streaming its
+// entries through a FilterWriter.
+func BenchmarkFilterWriter50000(b *testing.B) {
+	b.StopTimer()
+	randFilterElems, genErr := genRandFilterElements(50000)
+	if genErr != nil {
+		b.Fatalf("unable to generate random item: %v", genErr)
+	}
+	b.ReportAllocs()
+	b.StartTimer()
+
+	var localFilter *gcs.Filter
+	for i := 0; i < b.N; i++ {
+		w, err := gcs.NewFilterWriter(P, M, key)
+		if err != nil {
+			b.Fatalf("unable to create filter writer: %v", err)
+		}
+		for _, elem := range randFilterElems {
+			w.Write(elem)
+		}
+		localFilter, err = w.Filter()
+		if err != nil {
+			b.Fatalf("unable to generate filter: %v", err)
+		}
+	}
+	generatedFilter = localFilter
+}
+
 var (
 	match bool
 )
@@ -96,6 +124,7 @@ func BenchmarkGCSFilterMatch(b *testing.B) {
 	if err != nil {
 		b.Fatalf("Failed to build filter")
 	}
+	b.ReportAllocs()
 	b.StartTimer()
 
 	var (
@@ -123,6 +152,7 @@ func BenchmarkGCSFilterMatchAny(b *testing.B) {
 	if err != nil {
 		b.Fatalf("Failed to build filter")
 	}
+	b.ReportAllocs()
 	b.StartTimer()
 
 	var (
@@ -136,3 +166,43 @@ func BenchmarkGCSFilterMatchAny(b *testing.B) {
 	}
 	match = localMatch
 }
+
+// BenchmarkMatcherRescan benchmarks matching a wallet's scripts against many
+// block filters, as done when rescanning the chain.
+func BenchmarkMatcherRescan(b *testing.B) {
+	b.StopTimer()
+	const numFilters = 100
+	filters := make([]*gcs.Filter, numFilters)
+	keys := make([][gcs.KeySize]byte, numFilters)
+	for i := range filters {
+		elems, err := genRandFilterElements(2000)
+		if err != nil {
+			b.Fatalf("unable to generate random item: %v", err)
+		}
+		binary.LittleEndian.PutUint32(keys[i][:], uint32(i))
+		filters[i], err = gcs.BuildGCSFilter(P, M, keys[i], elems)
+		if err != nil {
+			b.Fatalf("unable to generate filter: %v", err)
+		}
+	}
+	query, err := genRandFilterElements(100)
+	if err != nil {
+		b.Fatalf("unable to generate random item: %v", err)
+	}
+	b.ReportAllocs()
+	b.StartTimer()
+
+	var (
+		m          gcs.Matcher
+		localMatch bool
+	)
+	for i := 0; i < b.N; i++ {
+		for j, filter := range filters {
+			localMatch, err = m.MatchAny(filter, keys[j], query)
+			if err != nil {
+				b.Fatalf("unable to match filter: %v", err)
+			}
+		}
+	}
+	match = localMatch
+}
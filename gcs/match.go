@@ -0,0 +1,196 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package gcs
+
+import (
+	"context"
+	"io"
+	"math/bits"
+	"slices"
+
+	"github.com/zeusyf/btcutil/hashing"
+)
+
+// matchAnyStackSize is the number of query values MatchAny hashes into a
+// buffer on the stack.  Larger queries allocate, so callers matching many
+// values against many filters should use a Matcher.
+const matchAnyStackSize = 32
+
+// bitReader reads the Golomb coded values of a filter.  Unlike a
+// bstream.BStream it is used by value and reads the filter data in place, so
+// matching neither copies the filter nor allocates.  It buffers up to 64 bits
+// of the filter at a time, so the unary part of a value is decoded by
+// counting leading ones instead of bit by bit.
+type bitReader struct {
+	data []byte
+
+	// cache holds the next n bits of the filter, aligned to the most
+	// significant bit.
+	cache uint64
+	n     uint
+}
+
+// refill moves as many whole bytes of the filter data into the cache as fit.
+func (r *bitReader) refill() {
+	for r.n <= 56 && len(r.data) > 0 {
+		r.cache |= uint64(r.data[0]) << (56 - r.n)
+		r.data = r.data[1:]
+		r.n += 8
+	}
+}
+
+// readFullUint64 reads a value represented by the sum of a unary multiple of
+// the filter's P modulus (`2**P`) and a big-endian P-bit remainder.  io.EOF
+// is returned once the filter holds no further complete value.
+func (r *bitReader) readFullUint64(p uint8) (uint64, error) {
+	// Count the 1s until we reach a 0.
+	var quotient uint64
+	for {
+		if r.n == 0 {
+			r.refill()
+			if r.n == 0 {
+				return 0, io.EOF
+			}
+		}
+		ones := uint(bits.LeadingZeros64(^r.cache))
+		if ones < r.n {
+			quotient += uint64(ones)
+			r.cache <<= ones + 1
+			r.n -= ones + 1
+			break
+		}
+		quotient += uint64(r.n)
+		r.cache, r.n = 0, 0
+	}
+
+	// Read P bits.
+	if r.n < uint(p) {
+		r.refill()
+		if r.n < uint(p) {
+			return 0, io.EOF
+		}
+	}
+	var remainder uint64
+	if p > 0 {
+		remainder = r.cache >> (64 - p)
+		r.cache <<= p
+		r.n -= uint(p)
+	}
+
+	// Add the multiple and the remainder.
+	return (quotient << p) + remainder, nil
+}
+
+// Matcher matches sets of values against filters, reusing its buffers from
+// one filter to the next.  Rescanning the chain for a wallet's scripts means
+// matching the same values against every block filter, and since the key of
+// each filter differs the values must be hashed anew for each of them; a
+// Matcher does so without allocating once its buffer has grown to the size of
+// the query.
+//
+// The zero value is ready for use.  A Matcher is not safe for concurrent use,
+// but each goroutine of a parallel rescan can use its own.
+type Matcher struct {
+	values []uint64
+}
+
+// MatchAny returns whether any []byte value is likely (within collision
+// probability) to be a member of the set represented by the filter.  It gives
+// the same result as Filter.MatchAny.
+func (m *Matcher) MatchAny(f *Filter, key [KeySize]byte, data [][]byte) (bool, error) {
+	match, values, err := f.matchAny(key, data, m.values[:0])
+	m.values = values
+	return match, err
+}
+
+// KeyedFilter is a filter along with the key it was built with, such as the
+// filter of a block and the key derived from the block hash.
+type KeyedFilter struct {
+	Filter *Filter
+	Key    [KeySize]byte
+}
+
+// MatchFilters returns the indices, in increasing order, of the filters
+// likely (within collision probability) to hold any of the []byte values, as
+// a rescan matching a wallet's scripts against a range of block filters
+// does.  The error of ctx is returned once it is done, so servers can stop
+// the scan of a client which went away.
+func (m *Matcher) MatchFilters(ctx context.Context, filters []KeyedFilter,
+	data [][]byte) ([]int, error) {
+	done := ctx.Done()
+	var matched []int
+	for i, f := range filters {
+		select {
+		case <-done:
+			return nil, ctx.Err()
+		default:
+		}
+		match, err := m.MatchAny(f.Filter, f.Key, data)
+		if err != nil {
+			return nil, err
+		}
+		if match {
+			matched = append(matched, i)
+		}
+	}
+	return matched, nil
+}
+
+// matchAny hashes the search values into the passed buffer, growing it as
+// needed, and returns whether any of them is a member of the filter along
+// with the buffer for reuse.
+func (f *Filter) matchAny(key [KeySize]byte, data [][]byte,
+	values []uint64) (bool, []uint64, error) {
+
+	// Basic sanity check.
+	if len(data) == 0 || f.n == 0 {
+		return false, values, nil
+	}
+
+	// Hash the search values over the filter's range and sort them, so
+	// they can be probed in a single pass over the filter.
+	nphi := f.modulusNP >> 32
+	nplo := uint64(uint32(f.modulusNP))
+	h := hashing.Current()
+	for _, d := range data {
+		v := h.SipHash24(&key, d)
+		values = append(values, fastReduction(v, nphi, nplo))
+	}
+	slices.Sort(values)
+
+	match, err := f.matchSorted(values)
+	return match, values, err
+}
+
+// matchSorted returns whether any of the passed sorted values is a member of
+// the filter.  Both the filter and the values are sorted, so they are zipped
+// down together, decoding each filter value once and advancing past all
+// search values below it in a batch.
+func (f *Filter) matchSorted(values []uint64) (bool, error) {
+	r := bitReader{data: f.filterData}
+
+	var lastValue uint64
+	i := 0
+	for {
+		delta, err := r.readFullUint64(f.p)
+		if err != nil {
+			if err == io.EOF {
+				return false, nil
+			}
+			return false, err
+		}
+		lastValue += delta
+
+		for values[i] < lastValue {
+			i++
+			if i == len(values) {
+				return false, nil
+			}
+		}
+		if values[i] == lastValue {
+			return true, nil
+		}
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package gcs_test
+
+import (
+	"context"
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"github.com/zeusyf/btcutil/gcs"
+)
+
+// TestMatcher ensures a Matcher reused across filters agrees with matching
+// each value individually, and that every member of a filter is matched.
+func TestMatcher(t *testing.T) {
+	var m gcs.Matcher
+	for _, p := range []uint8{10, 19, 32} {
+		for numEntries := 0; numEntries < 300; numEntries += 37 {
+			var filterKey [gcs.KeySize]byte
+			binary.LittleEndian.PutUint32(filterKey[:], uint32(numEntries))
+			filterKey[4] = p
+
+			entries := make([][]byte, numEntries)
+			for i := range entries {
+				entries[i] = []byte{byte(i), byte(i >> 8), p, 1}
+			}
+			f, err := gcs.BuildGCSFilter(p, M, filterKey, entries)
+			if err != nil {
+				t.Fatalf("BuildGCSFilter: unexpected error: %v", err)
+			}
+
+			for _, entry := range entries {
+				match, err := f.Match(filterKey, entry)
+				if err != nil || !match {
+					t.Fatalf("P=%d N=%d: member %x not matched: %v",
+						p, numEntries, entry, err)
+				}
+			}
+
+			// Query in batches that are mostly non-members and
+			// contain a member every so often.
+			for start := 0; start < 200; start += 40 {
+				query := make([][]byte, 40)
+				want := false
+				for i := range query {
+					query[i] = []byte{byte(start + i), 0, p, 2}
+					match, _ := f.Match(filterKey, query[i])
+					want = want || match
+				}
+				if start < numEntries && start%80 == 0 {
+					query[len(query)-1] = entries[start]
+					want = true
+				}
+
+				got, err := m.MatchAny(f, filterKey, query)
+				if err != nil || got != want {
+					t.Fatalf("P=%d N=%d: Matcher.MatchAny got %v, %v, "+
+						"want %v", p, numEntries, got, err, want)
+				}
+				got, err = f.MatchAny(filterKey, query)
+				if err != nil || got != want {
+					t.Fatalf("P=%d N=%d: MatchAny got %v, %v, want %v",
+						p, numEntries, got, err, want)
+				}
+			}
+
+			if match, _ := m.MatchAny(f, filterKey, nil); match {
+				t.Fatalf("P=%d N=%d: empty query matched", p,
+					numEntries)
+			}
+		}
+	}
+}
+
+// TestMatchFilters ensures the filters holding any of the values are found,
+// and matching stops once its context is done.
+func TestMatchFilters(t *testing.T) {
+	filters := make([]gcs.KeyedFilter, 10)
+	for i := range filters {
+		filters[i].Key[0] = byte(i)
+		f, err := gcs.BuildGCSFilter(P, M, filters[i].Key, [][]byte{
+			{byte(i), 0xff}, {byte(i), 0xfe},
+		})
+		if err != nil {
+			t.Fatalf("BuildGCSFilter: unexpected error: %v", err)
+		}
+		filters[i].Filter = f
+	}
+
+	var m gcs.Matcher
+	query := [][]byte{{3, 0xfe}, {7, 0xff}, {42, 0xff}}
+	got, err := m.MatchFilters(context.Background(), filters, query)
+	if err != nil || !reflect.DeepEqual(got, []int{3, 7}) {
+		t.Errorf("MatchFilters: got %v, %v, want [3 7]", got, err)
+	}
+	got, err = m.MatchFilters(context.Background(), filters, nil)
+	if err != nil || got != nil {
+		t.Errorf("MatchFilters: got %v, %v for empty query", got, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := m.MatchFilters(ctx, filters, query); err != context.Canceled {
+		t.Errorf("MatchFilters: got error %v, want %v", err,
+			context.Canceled)
+	}
+}
+
+// TestMatchAllocs ensures matching does not allocate.
+func TestMatchAllocs(t *testing.T) {
+	f, err := gcs.BuildGCSFilter(P, M, key, contents)
+	if err != nil {
+		t.Fatalf("BuildGCSFilter: unexpected error: %v", err)
+	}
+
+	var m gcs.Matcher
+	large := append(append([][]byte{}, contents2...), contents2...)
+	m.MatchAny(f, key, large)
+
+	tests := []struct {
+		name  string
+		match func()
+	}{
+		{"Match", func() { f.Match(key, contents[5]) }},
+		{"MatchAny", func() { f.MatchAny(key, contents2) }},
+		{"Matcher.MatchAny", func() { m.MatchAny(f, key, large) }},
+	}
+	for _, test := range tests {
+		if allocs := testing.AllocsPerRun(100, test.match); allocs != 0 {
+			t.Errorf("%s: got %v allocations, want 0", test.name, allocs)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package gcs
+
+import (
+	"bytes"
+	"errors"
+	"io"
+
+	"github.com/zeusyf/btcd/wire/common"
+)
+
+const (
+	// FilterVersion1 is the first version of the persistence format of
+	// filters, holding P, M, N and the compressed filter data.
+	FilterVersion1 uint8 = 1
+
+	// FilterVersion is the version Serialize writes filters in.
+	FilterVersion = FilterVersion1
+)
+
+var (
+	// ErrUnknownVersion describes an error where a persisted filter has a
+	// version this library does not know, such as one written by a newer
+	// release.
+	ErrUnknownVersion = errors.New("unknown filter persistence version")
+
+	// ErrTrailingBytes describes an error where a persisted filter is
+	// followed by extra bytes.
+	ErrTrailingBytes = errors.New("trailing bytes after persisted filter")
+)
+
+// M returns the modulus scalar the filter was built with.  It is zero for
+// empty filters, whose modulus is not recorded.
+func (f *Filter) M() uint64 {
+	if f.n == 0 {
+		return 0
+	}
+	return f.modulusNP / uint64(f.n)
+}
+
+// Serialize writes the filter to w in the current version of the persistence
+// format.  Unlike NPBytes, the format is self-describing: it starts with a
+// version byte, followed for version 1 by P, then M, N and the length of the
+// filter data as variable length integers, and the filter data.  Filters
+// cached on disk in a version remain readable by Deserialize in later
+// releases.  The key used by SipHash is not included.
+func (f *Filter) Serialize(w io.Writer) error {
+	if _, err := w.Write([]byte{FilterVersion, f.p}); err != nil {
+		return err
+	}
+	for _, v := range []uint64{f.M(), uint64(f.n), uint64(len(f.filterData))} {
+		if err := common.WriteVarInt(w, varIntProtoVer, v); err != nil {
+			return err
+		}
+	}
+	_, err := w.Write(f.filterData)
+	return err
+}
+
+// Deserialize reads a filter written by Serialize in any known version of the
+// persistence format.
+func Deserialize(r io.Reader) (*Filter, error) {
+	var version [1]byte
+	if _, err := io.ReadFull(r, version[:]); err != nil {
+		return nil, err
+	}
+	switch version[0] {
+	case FilterVersion1:
+		return deserializeV1(r)
+	}
+	return nil, ErrUnknownVersion
+}
+
+// deserializeV1 reads a filter in version 1 of the persistence format, after
+// its version byte.
+func deserializeV1(r io.Reader) (*Filter, error) {
+	var p [1]byte
+	if _, err := io.ReadFull(r, p[:]); err != nil {
+		return nil, err
+	}
+	var fields [3]uint64
+	for i := range fields {
+		v, err := common.ReadVarInt(r, varIntProtoVer)
+		if err != nil {
+			return nil, err
+		}
+		fields[i] = v
+	}
+	m, n, dataLen := fields[0], fields[1], fields[2]
+	if n >= (1 << 32) {
+		return nil, ErrNTooBig
+	}
+
+	// The length is not trusted to allocate the data up front, so a
+	// corrupted length fails on the end of the input instead.  No input
+	// holds more than 1<<63 bytes.
+	if int64(dataLen) < 0 {
+		return nil, io.ErrUnexpectedEOF
+	}
+	var data bytes.Buffer
+	if _, err := io.CopyN(&data, r, int64(dataLen)); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return FromBytes(uint32(n), p[0], m, data.Bytes())
+}
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface, returning
+// the filter as written by Serialize.
+func (f *Filter) MarshalBinary() ([]byte, error) {
+	var buf bytes.Buffer
+	if err := f.Serialize(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface,
+// replacing the filter with the one persisted in data.
+func (f *Filter) UnmarshalBinary(data []byte) error {
+	r := bytes.NewReader(data)
+	filter, err := Deserialize(r)
+	if err != nil {
+		return err
+	}
+	if r.Len() != 0 {
+		return ErrTrailingBytes
+	}
+	*f = *filter
+	return nil
+}
@@ -0,0 +1,110 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package gcs_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"testing"
+
+	"github.com/zeusyf/btcutil/gcs"
+)
+
+// TestFilterPersistence ensures filters round trip through their persistence
+// format and still match their contents.
+func TestFilterPersistence(t *testing.T) {
+	var k [gcs.KeySize]byte
+	copy(k[:], "persistence key!")
+	built, err := gcs.BuildGCSFilter(P, M, k, contents)
+	if err != nil {
+		t.Fatalf("BuildGCSFilter: unexpected error: %v", err)
+	}
+	empty, err := gcs.BuildGCSFilter(P, M, k, nil)
+	if err != nil {
+		t.Fatalf("BuildGCSFilter: unexpected error: %v", err)
+	}
+
+	for _, f := range []*gcs.Filter{built, empty} {
+		if f.N() != 0 && f.M() != M {
+			t.Errorf("M: got %d, want %d", f.M(), M)
+		}
+		b, err := f.MarshalBinary()
+		if err != nil {
+			t.Fatalf("MarshalBinary: unexpected error: %v", err)
+		}
+		if b[0] != gcs.FilterVersion {
+			t.Errorf("MarshalBinary: got version %d, want %d", b[0],
+				gcs.FilterVersion)
+		}
+
+		var got gcs.Filter
+		if err := got.UnmarshalBinary(b); err != nil {
+			t.Fatalf("UnmarshalBinary: unexpected error: %v", err)
+		}
+		want, _ := f.NPBytes()
+		have, _ := got.NPBytes()
+		if !bytes.Equal(have, want) || got.M() != f.M() {
+			t.Errorf("UnmarshalBinary: filter does not round trip")
+		}
+		if f.N() == 0 {
+			continue
+		}
+		match, err := got.MatchAny(k, contents)
+		if err != nil || !match {
+			t.Errorf("MatchAny: restored filter does not match: %v", err)
+		}
+	}
+}
+
+// TestFilterVersion1 ensures filters persisted in version 1 of the format
+// remain readable.
+func TestFilterVersion1(t *testing.T) {
+	// Version 1, P of 19, M of 784931, N of 2 and 3 bytes of data.
+	persisted, _ := hex.DecodeString("0113fe23fa0b000203abcdef")
+	f, err := gcs.Deserialize(bytes.NewReader(persisted))
+	if err != nil {
+		t.Fatalf("Deserialize: unexpected error: %v", err)
+	}
+	data, _ := f.Bytes()
+	if f.P() != 19 || f.M() != 784931 || f.N() != 2 ||
+		!bytes.Equal(data, []byte{0xab, 0xcd, 0xef}) {
+		t.Errorf("Deserialize: got P %d, M %d, N %d and data %x", f.P(),
+			f.M(), f.N(), data)
+	}
+	var buf bytes.Buffer
+	if err := f.Serialize(&buf); err != nil {
+		t.Fatalf("Serialize: unexpected error: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), persisted) {
+		t.Errorf("Serialize: got %x, want %x", buf.Bytes(), persisted)
+	}
+
+	tests := []struct {
+		name string
+		in   string
+		err  error
+	}{
+		{"version", "0213fe23fa0b000203abcdef", gcs.ErrUnknownVersion},
+		{"P", "0121fe23fa0b000203abcdef", gcs.ErrPTooBig},
+		{"N", "0113fe23fa0b00ff000000000100000003abcdef", gcs.ErrNTooBig},
+		{"data", "0113fe23fa0b000204abcdef", io.ErrUnexpectedEOF},
+		{"length", "0113fe23fa0b0002ffffffffffffffffffabcdef",
+			io.ErrUnexpectedEOF},
+		{"empty", "", io.EOF},
+	}
+	for _, test := range tests {
+		b, _ := hex.DecodeString(test.in)
+		if _, err := gcs.Deserialize(bytes.NewReader(b)); err != test.err {
+			t.Errorf("%s: got error %v, want %v", test.name, err, test.err)
+		}
+	}
+
+	var g gcs.Filter
+	if err := g.UnmarshalBinary(append(persisted, 0)); err != gcs.ErrTrailingBytes {
+		t.Errorf("UnmarshalBinary: got error %v, want %v", err,
+			gcs.ErrTrailingBytes)
+	}
+}
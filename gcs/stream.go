@@ -0,0 +1,113 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package gcs
+
+import (
+	"sort"
+
+	"github.com/zeusyf/btcutil/hashing"
+)
+
+// minCompaction is the number of hashes a FilterWriter buffers before it
+// first sorts and deduplicates them.
+const minCompaction = 1024
+
+// FilterWriter builds a filter from entries written to it one at a time, such
+// as the scripts of a block as they are parsed.  Rather than keeping every
+// entry until the filter is built, it keeps only the 8 byte SipHash of each
+// distinct entry, so memory use does not depend on the size of the entries
+// and large blocks need not be held in memory twice.
+//
+// Entries written more than once are only included once, as with the
+// deduplicating GCSBuilder.  Since entries are deduplicated by hash, two
+// distinct entries whose 64-bit hashes collide are also included once; the
+// chance of that happening is negligible for any realistic filter size.
+//
+// The resulting filter is identical to one built by BuildGCSFilter from the
+// distinct entries.  A FilterWriter is not safe for concurrent use.
+type FilterWriter struct {
+	p   uint8
+	m   uint64
+	key [KeySize]byte
+
+	// hashes holds the SipHash of the entries written so far.  The first
+	// compacted hashes are sorted and distinct.
+	hashes    uint64Slice
+	compacted int
+}
+
+// NewFilterWriter returns a FilterWriter for a filter with the collision
+// probability of `1/(2**P)`, modulus M and key `key`.
+func NewFilterWriter(P uint8, M uint64, key [KeySize]byte) (*FilterWriter, error) {
+	if P > 32 {
+		return nil, ErrPTooBig
+	}
+	return &FilterWriter{p: P, m: M, key: key}, nil
+}
+
+// Write adds data as a single entry of the filter.  It always returns the
+// length of data and a nil error, and allows a FilterWriter to be used where
+// an io.Writer is expected as long as every call to Write passes one whole
+// entry.
+func (w *FilterWriter) Write(data []byte) (int, error) {
+	w.hashes = append(w.hashes, hashing.SipHash24(&w.key, data))
+
+	// Sort and deduplicate whenever the number of hashes doubles, so
+	// repeated entries never grow the buffer beyond twice the number of
+	// distinct entries while keeping the amortized cost low.
+	if len(w.hashes) >= minCompaction && len(w.hashes) >= 2*w.compacted {
+		w.compact()
+	}
+	return len(data), nil
+}
+
+// compact sorts the hashes and removes duplicates.
+func (w *FilterWriter) compact() {
+	sort.Sort(w.hashes)
+
+	n := 0
+	for i, h := range w.hashes {
+		if i > 0 && h == w.hashes[n-1] {
+			continue
+		}
+		w.hashes[n] = h
+		n++
+	}
+	w.hashes = w.hashes[:n]
+	w.compacted = n
+}
+
+// N returns the number of distinct entries written so far.
+func (w *FilterWriter) N() uint32 {
+	if w.compacted != len(w.hashes) {
+		w.compact()
+	}
+	return uint32(len(w.hashes))
+}
+
+// Filter builds the filter from the entries written so far.  More entries
+// may be written afterwards to build a larger filter.
+func (w *FilterWriter) Filter() (*Filter, error) {
+	if w.compacted != len(w.hashes) {
+		w.compact()
+	}
+	if uint64(len(w.hashes)) >= (1 << 32) {
+		return nil, ErrNTooBig
+	}
+
+	f := Filter{
+		n: uint32(len(w.hashes)),
+		p: w.p,
+	}
+	f.modulusNP = uint64(f.n) * w.m
+
+	// Shortcut if the filter is empty.
+	if f.n == 0 {
+		return &f, nil
+	}
+
+	f.encode(w.hashes, false)
+	return &f, nil
+}
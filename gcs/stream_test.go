@@ -0,0 +1,79 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package gcs_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/zeusyf/btcutil/gcs"
+)
+
+// TestFilterWriter ensures filters built by writing entries one at a time,
+// including repeated entries, are identical to filters built from the
+// distinct entries at once.
+func TestFilterWriter(t *testing.T) {
+	var streamKey [gcs.KeySize]byte
+	copy(streamKey[:], "filter writer ke")
+
+	for _, numEntries := range []int{0, 1, 17, 5000} {
+		entries := make([][]byte, numEntries)
+		for i := range entries {
+			entries[i] = make([]byte, 36)
+			binary.LittleEndian.PutUint32(entries[i], uint32(i))
+		}
+
+		want, err := gcs.BuildGCSFilter(P, M, streamKey, entries)
+		if err != nil {
+			t.Fatalf("BuildGCSFilter: unexpected error: %v", err)
+		}
+
+		w, err := gcs.NewFilterWriter(P, M, streamKey)
+		if err != nil {
+			t.Fatalf("NewFilterWriter: unexpected error: %v", err)
+		}
+
+		// Write every entry, and every third entry a second time after
+		// all others, so duplicates span compactions.
+		for _, entry := range entries {
+			n, err := w.Write(entry)
+			if n != len(entry) || err != nil {
+				t.Fatalf("Write: got %d, %v, want %d, nil", n, err,
+					len(entry))
+			}
+		}
+		for i := 0; i < len(entries); i += 3 {
+			w.Write(entries[i])
+		}
+		if w.N() != uint32(numEntries) {
+			t.Fatalf("N: got %d, want %d", w.N(), numEntries)
+		}
+
+		got, err := w.Filter()
+		if err != nil {
+			t.Fatalf("Filter: unexpected error: %v", err)
+		}
+		gotBytes, _ := got.NPBytes()
+		wantBytes, _ := want.NPBytes()
+		if !bytes.Equal(gotBytes, wantBytes) {
+			t.Fatalf("%d entries: streamed filter differs from built "+
+				"filter", numEntries)
+		}
+
+		if numEntries == 0 {
+			continue
+		}
+		match, err := got.Match(streamKey, entries[numEntries-1])
+		if err != nil || !match {
+			t.Fatalf("Match: entry not matched: %v", err)
+		}
+	}
+
+	if _, err := gcs.NewFilterWriter(33, M, streamKey); err != gcs.ErrPTooBig {
+		t.Fatalf("NewFilterWriter: got error %v, want %v", err,
+			gcs.ErrPTooBig)
+	}
+}
@@ -1,3 +1,8 @@
 module github.com/zeusyf/btcutil
 
 go 1.22.1
+
+require (
+	github.com/aead/siphash v1.0.1
+	github.com/kkdai/bstream v1.0.0
+)
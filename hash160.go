@@ -5,19 +5,11 @@
 package btcutil
 
 import (
-	"crypto/sha256"
-	"hash"
-
-	"golang.org/x/crypto/ripemd160"
+	"github.com/zeusyf/btcutil/hashing"
 )
 
-// Calculate the hash of hasher over buf.
-func calcHash(buf []byte, hasher hash.Hash) []byte {
-	hasher.Write(buf)
-	return hasher.Sum(nil)
-}
-
 // Hash160 calculates the hash ripemd160(sha256(b)).
 func Hash160(buf []byte) []byte {
-	return calcHash(calcHash(buf, sha256.New()), ripemd160.New())
+	h := hashing.Hash160(buf)
+	return h[:]
 }
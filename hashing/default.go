@@ -0,0 +1,10 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+//go:build !purego
+
+package hashing
+
+// defaultBackend is the backend in use unless another is selected.
+const defaultBackend = Std
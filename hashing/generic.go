@@ -0,0 +1,143 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package hashing
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// sha256K are the round constants of SHA256, the first 32 bits of the
+// fractional parts of the cube roots of the first 64 primes.
+var sha256K = [64]uint32{
+	0x428a2f98, 0x71374491, 0xb5c0fbcf, 0xe9b5dba5, 0x3956c25b, 0x59f111f1,
+	0x923f82a4, 0xab1c5ed5, 0xd807aa98, 0x12835b01, 0x243185be, 0x550c7dc3,
+	0x72be5d74, 0x80deb1fe, 0x9bdc06a7, 0xc19bf174, 0xe49b69c1, 0xefbe4786,
+	0x0fc19dc6, 0x240ca1cc, 0x2de92c6f, 0x4a7484aa, 0x5cb0a9dc, 0x76f988da,
+	0x983e5152, 0xa831c66d, 0xb00327c8, 0xbf597fc7, 0xc6e00bf3, 0xd5a79147,
+	0x06ca6351, 0x14292967, 0x27b70a85, 0x2e1b2138, 0x4d2c6dfc, 0x53380d13,
+	0x650a7354, 0x766a0abb, 0x81c2c92e, 0x92722c85, 0xa2bfe8a1, 0xa81a664b,
+	0xc24b8b70, 0xc76c51a3, 0xd192e819, 0xd6990624, 0xf40e3585, 0x106aa070,
+	0x19a4c116, 0x1e376c08, 0x2748774c, 0x34b0bcb5, 0x391c0cb3, 0x4ed8aa4a,
+	0x5b9cca4f, 0x682e6ff3, 0x748f82ee, 0x78a5636f, 0x84c87814, 0x8cc70208,
+	0x90befffa, 0xa4506ceb, 0xbef9a3f7, 0xc67178f2,
+}
+
+// sha256Init is the initial state of SHA256, the first 32 bits of the
+// fractional parts of the square roots of the first 8 primes.
+var sha256Init = [8]uint32{
+	0x6a09e667, 0xbb67ae85, 0x3c6ef372, 0xa54ff53a,
+	0x510e527f, 0x9b05688c, 0x1f83d9ab, 0x5be0cd19,
+}
+
+// sum256 returns the SHA256 hash of data, as specified by FIPS 180-4.
+func sum256(data []byte) [32]byte {
+	h := sha256Init
+	n := len(data)
+	for ; len(data) >= 64; data = data[64:] {
+		sha256Block(&h, data[:64])
+	}
+
+	// The final one or two blocks hold the remaining data, a single set
+	// bit, and the bit length of the data.
+	var tail [128]byte
+	rem := copy(tail[:], data)
+	tail[rem] = 0x80
+	size := 64
+	if rem >= 56 {
+		size = 128
+	}
+	binary.BigEndian.PutUint64(tail[size-8:size], uint64(n)<<3)
+	for i := 0; i < size; i += 64 {
+		sha256Block(&h, tail[i:i+64])
+	}
+
+	var sum [32]byte
+	for i, v := range h {
+		binary.BigEndian.PutUint32(sum[4*i:], v)
+	}
+	return sum
+}
+
+// sha256Block updates the state h with the 64 byte block p.
+func sha256Block(h *[8]uint32, p []byte) {
+	var w [64]uint32
+	for i := 0; i < 16; i++ {
+		w[i] = binary.BigEndian.Uint32(p[4*i:])
+	}
+	for i := 16; i < 64; i++ {
+		v1, v2 := w[i-2], w[i-15]
+		s1 := bits.RotateLeft32(v1, -17) ^ bits.RotateLeft32(v1, -19) ^ v1>>10
+		s0 := bits.RotateLeft32(v2, -7) ^ bits.RotateLeft32(v2, -18) ^ v2>>3
+		w[i] = s1 + w[i-7] + s0 + w[i-16]
+	}
+
+	a, b, c, d, e, f, g, hh := h[0], h[1], h[2], h[3], h[4], h[5], h[6], h[7]
+	for i := 0; i < 64; i++ {
+		s1 := bits.RotateLeft32(e, -6) ^ bits.RotateLeft32(e, -11) ^
+			bits.RotateLeft32(e, -25)
+		t1 := hh + s1 + (e&f ^ ^e&g) + sha256K[i] + w[i]
+		s0 := bits.RotateLeft32(a, -2) ^ bits.RotateLeft32(a, -13) ^
+			bits.RotateLeft32(a, -22)
+		t2 := s0 + (a&b ^ a&c ^ b&c)
+		hh, g, f, e, d, c, b, a = g, f, e, d+t1, c, b, a, t1+t2
+	}
+	h[0] += a
+	h[1] += b
+	h[2] += c
+	h[3] += d
+	h[4] += e
+	h[5] += f
+	h[6] += g
+	h[7] += hh
+}
+
+// sipHash24 returns the SipHash-2-4 hash of data with key, as specified by
+// Aumasson and Bernstein.
+func sipHash24(key *[16]byte, data []byte) uint64 {
+	k0 := binary.LittleEndian.Uint64(key[:8])
+	k1 := binary.LittleEndian.Uint64(key[8:])
+	v0 := k0 ^ 0x736f6d6570736575
+	v1 := k1 ^ 0x646f72616e646f6d
+	v2 := k0 ^ 0x6c7967656e657261
+	v3 := k1 ^ 0x7465646279746573
+
+	round := func() {
+		v0 += v1
+		v1 = bits.RotateLeft64(v1, 13) ^ v0
+		v0 = bits.RotateLeft64(v0, 32)
+		v2 += v3
+		v3 = bits.RotateLeft64(v3, 16) ^ v2
+		v0 += v3
+		v3 = bits.RotateLeft64(v3, 21) ^ v0
+		v2 += v1
+		v1 = bits.RotateLeft64(v1, 17) ^ v2
+		v2 = bits.RotateLeft64(v2, 32)
+	}
+	compress := func(m uint64) {
+		v3 ^= m
+		round()
+		round()
+		v0 ^= m
+	}
+
+	n := len(data)
+	for ; len(data) >= 8; data = data[8:] {
+		compress(binary.LittleEndian.Uint64(data))
+	}
+
+	// The last word holds the remaining bytes and the length of the data
+	// in its most significant byte.
+	var last [8]byte
+	copy(last[:], data)
+	last[7] = byte(n)
+	compress(binary.LittleEndian.Uint64(last[:]))
+
+	v2 ^= 0xff
+	for i := 0; i < 4; i++ {
+		round()
+	}
+	return v0 ^ v1 ^ v2 ^ v3
+}
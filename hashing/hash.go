@@ -0,0 +1,180 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package hashing
+
+import "errors"
+
+const (
+	// HashSize is the size of a Hash in bytes.
+	HashSize = 32
+
+	// MaxHashStringSize is the length of the string of a Hash.
+	MaxHashStringSize = HashSize * 2
+)
+
+var (
+	// ErrHashSize describes an error where a hash is set from a byte
+	// slice of another length than HashSize.
+	ErrHashSize = errors.New("invalid hash length")
+
+	// ErrHashStrSize describes an error where a hash string is not
+	// MaxHashStringSize characters long.
+	ErrHashStrSize = errors.New("invalid hash string length")
+
+	// ErrInvalidHex describes an error where a hex string holds a
+	// character which is not a hexadecimal digit.
+	ErrInvalidHex = errors.New("invalid hex character")
+)
+
+// hexDigits are the lower case hexadecimal digits.
+const hexDigits = "0123456789abcdef"
+
+// Hash is a double SHA256 hash, such as the hash of a transaction or block.
+// It has the layout of chainhash.Hash, so the two convert to each other with
+// a type conversion.  Its bytes are in the order they are hashed and
+// serialized in, the reverse of the order its string is written in.
+type Hash [HashSize]byte
+
+// NewHash returns a Hash holding the bytes of b, which must be HashSize bytes
+// long.
+func NewHash(b []byte) (*Hash, error) {
+	var h Hash
+	if err := h.SetBytes(b); err != nil {
+		return nil, err
+	}
+	return &h, nil
+}
+
+// NewHashFromStr parses a hash from its string, the byte-reversed hex of
+// block explorers and RPC.  Unlike chainhash.NewHashFromStr, the string must
+// be exactly MaxHashStringSize characters long, so truncated txids are
+// rejected rather than padded with zeros.  Upper case digits are accepted.
+func NewHashFromStr(s string) (Hash, error) {
+	var h Hash
+	if len(s) != MaxHashStringSize {
+		return h, ErrHashStrSize
+	}
+	for i := 0; i < HashSize; i++ {
+		hi, ok1 := fromHexChar(s[2*i])
+		lo, ok2 := fromHexChar(s[2*i+1])
+		if !ok1 || !ok2 {
+			return Hash{}, ErrInvalidHex
+		}
+		h[HashSize-1-i] = hi<<4 | lo
+	}
+	return h, nil
+}
+
+// SetBytes sets the bytes of the hash to those of b, which must be HashSize
+// bytes long.
+func (h *Hash) SetBytes(b []byte) error {
+	if len(b) != HashSize {
+		return ErrHashSize
+	}
+	copy(h[:], b)
+	return nil
+}
+
+// Reversed returns the hash with its bytes in reverse order, the big endian
+// number block explorers display.
+func (h Hash) Reversed() Hash {
+	Reverse(h[:])
+	return h
+}
+
+// String returns the hash as the hex of its reversed bytes.
+func (h Hash) String() string {
+	var buf [MaxHashStringSize]byte
+	EncodeReversedHex(buf[:], h[:])
+	return string(buf[:])
+}
+
+// AppendString appends the string of the hash to dst and returns the extended
+// slice.  Nothing is allocated when dst has room for MaxHashStringSize more
+// bytes.
+func (h *Hash) AppendString(dst []byte) []byte {
+	n := len(dst)
+	if cap(dst)-n < MaxHashStringSize {
+		grown := make([]byte, n, n+MaxHashStringSize)
+		copy(grown, dst)
+		dst = grown
+	}
+	dst = dst[:n+MaxHashStringSize]
+	EncodeReversedHex(dst[n:], h[:])
+	return dst
+}
+
+// MarshalText returns the string of the hash, so hashes are written as
+// strings by packages such as encoding/json.
+func (h Hash) MarshalText() ([]byte, error) {
+	return h.AppendString(make([]byte, 0, MaxHashStringSize)), nil
+}
+
+// UnmarshalText parses a hash from its string, as NewHashFromStr does.
+func (h *Hash) UnmarshalText(text []byte) error {
+	parsed, err := NewHashFromStr(string(text))
+	if err != nil {
+		return err
+	}
+	*h = parsed
+	return nil
+}
+
+// Reverse reverses the order of the bytes of b in place.
+func Reverse(b []byte) {
+	for i, j := 0, len(b)-1; i < j; i, j = i+1, j-1 {
+		b[i], b[j] = b[j], b[i]
+	}
+}
+
+// EncodeReversedHex writes the hex of the bytes of src, in reverse order, to
+// dst and returns the number of bytes written, 2*len(src).  dst must hold at
+// least that many bytes.  It is the encoding of the strings of hashes.
+func EncodeReversedHex(dst, src []byte) int {
+	n := len(src)
+	for i, j := 0, n-1; j >= 0; i, j = i+2, j-1 {
+		dst[i] = hexDigits[src[j]>>4]
+		dst[i+1] = hexDigits[src[j]&0x0f]
+	}
+	return 2 * n
+}
+
+// DecodeReversedHex decodes the hex string src to dst with its bytes in
+// reverse order, undoing EncodeReversedHex, and returns the number of bytes
+// written, len(src)/2.  dst must hold at least that many bytes.
+// ErrHashStrSize is returned for strings of an odd length, and
+// ErrInvalidHex for strings holding other characters than hexadecimal
+// digits, in which case dst is left unchanged.
+func DecodeReversedHex(dst, src []byte) (int, error) {
+	if len(src)%2 != 0 {
+		return 0, ErrHashStrSize
+	}
+	for _, c := range src {
+		if _, ok := fromHexChar(c); !ok {
+			return 0, ErrInvalidHex
+		}
+	}
+	n := len(src) / 2
+	for i := 0; i < n; i++ {
+		hi, _ := fromHexChar(src[2*i])
+		lo, _ := fromHexChar(src[2*i+1])
+		dst[n-1-i] = hi<<4 | lo
+	}
+	return n, nil
+}
+
+// fromHexChar returns the value of the hexadecimal digit c, and whether c is
+// one.
+func fromHexChar(c byte) (byte, bool) {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0', true
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10, true
+	case 'A' <= c && c <= 'F':
+		return c - 'A' + 10, true
+	}
+	return 0, false
+}
@@ -0,0 +1,155 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package hashing_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/zeusyf/btcutil/hashing"
+)
+
+// genesisHash is the string of the hash of the genesis block of bitcoin,
+// whose bytes are reversed.
+const genesisHash = "000000000019d6689c085ae165831e934ff763ae46a2a6c172b3f1b60a8ce26f"
+
+// TestNewHashFromStr ensures hash strings parse into their reversed bytes and
+// strings of other lengths or with other characters are rejected.
+func TestNewHashFromStr(t *testing.T) {
+	h, err := hashing.NewHashFromStr(genesisHash)
+	if err != nil {
+		t.Fatalf("NewHashFromStr: unexpected error: %v", err)
+	}
+	if h[0] != 0x6f || h[31] != 0x00 || h[26] != 0x19 {
+		t.Errorf("NewHashFromStr: got bytes %x", h[:])
+	}
+	if h.String() != genesisHash {
+		t.Errorf("String: got %s, want %s", h, genesisHash)
+	}
+	upper, err := hashing.NewHashFromStr(strings.ToUpper(genesisHash))
+	if err != nil || upper != h {
+		t.Errorf("NewHashFromStr: got %v, %v for upper case", upper, err)
+	}
+
+	tests := []struct {
+		name string
+		in   string
+		err  error
+	}{
+		{"empty", "", hashing.ErrHashStrSize},
+		{"short", genesisHash[2:], hashing.ErrHashStrSize},
+		{"long", genesisHash + "00", hashing.ErrHashStrSize},
+		{"invalid", "g" + genesisHash[1:], hashing.ErrInvalidHex},
+	}
+	for _, test := range tests {
+		_, err := hashing.NewHashFromStr(test.in)
+		if err != test.err {
+			t.Errorf("%s: got error %v, want %v", test.name, err, test.err)
+		}
+	}
+}
+
+// TestHashBytes ensures hashes are set from slices of their size only, and
+// reversing twice restores a hash.
+func TestHashBytes(t *testing.T) {
+	b := make([]byte, hashing.HashSize)
+	for i := range b {
+		b[i] = byte(i)
+	}
+	h, err := hashing.NewHash(b)
+	if err != nil {
+		t.Fatalf("NewHash: unexpected error: %v", err)
+	}
+	if !bytes.Equal(h[:], b) {
+		t.Errorf("NewHash: got %x, want %x", h[:], b)
+	}
+	if _, err := hashing.NewHash(b[1:]); err != hashing.ErrHashSize {
+		t.Errorf("NewHash: got error %v, want %v", err, hashing.ErrHashSize)
+	}
+
+	r := h.Reversed()
+	if r[0] != 31 || r[31] != 0 || r.Reversed() != *h {
+		t.Errorf("Reversed: got %x", r[:])
+	}
+	if r.String() != reverseHex(h.String()) {
+		t.Errorf("Reversed: got string %s", r)
+	}
+}
+
+// reverseHex returns the hex string s with the order of its bytes reversed.
+func reverseHex(s string) string {
+	var b strings.Builder
+	for i := len(s) - 2; i >= 0; i -= 2 {
+		b.WriteString(s[i : i+2])
+	}
+	return b.String()
+}
+
+// TestReversedHex ensures byte strings of any length round trip through their
+// reversed hex.
+func TestReversedHex(t *testing.T) {
+	src := []byte{0x01, 0xab, 0xff}
+	dst := make([]byte, 6)
+	if n := hashing.EncodeReversedHex(dst, src); n != 6 || string(dst) != "ffab01" {
+		t.Errorf("EncodeReversedHex: got %q (%d)", dst, n)
+	}
+
+	decoded := make([]byte, 3)
+	n, err := hashing.DecodeReversedHex(decoded, []byte("FFab01"))
+	if err != nil || n != 3 || !bytes.Equal(decoded, src) {
+		t.Errorf("DecodeReversedHex: got %x (%d), %v", decoded, n, err)
+	}
+	if _, err := hashing.DecodeReversedHex(decoded, []byte("abc")); err != hashing.ErrHashStrSize {
+		t.Errorf("DecodeReversedHex: got error %v, want %v", err,
+			hashing.ErrHashStrSize)
+	}
+	if _, err := hashing.DecodeReversedHex(decoded, []byte("zz")); err != hashing.ErrInvalidHex {
+		t.Errorf("DecodeReversedHex: got error %v, want %v", err,
+			hashing.ErrInvalidHex)
+	}
+}
+
+// TestHashJSON ensures hashes are written to and read from JSON as strings.
+func TestHashJSON(t *testing.T) {
+	h, _ := hashing.NewHashFromStr(genesisHash)
+	b, err := json.Marshal(struct{ TxID hashing.Hash }{h})
+	if err != nil {
+		t.Fatalf("Marshal: unexpected error: %v", err)
+	}
+	want := `{"TxID":"` + genesisHash + `"}`
+	if string(b) != want {
+		t.Errorf("Marshal: got %s, want %s", b, want)
+	}
+
+	var v struct{ TxID hashing.Hash }
+	if err := json.Unmarshal(b, &v); err != nil || v.TxID != h {
+		t.Errorf("Unmarshal: got %v, %v", v.TxID, err)
+	}
+	err = json.Unmarshal([]byte(`{"TxID":"00"}`), &v)
+	if err == nil {
+		t.Errorf("Unmarshal: got no error for a short hash")
+	}
+}
+
+// TestHashAllocs ensures hashes are parsed and formatted without allocating.
+func TestHashAllocs(t *testing.T) {
+	h, _ := hashing.NewHashFromStr(genesisHash)
+	buf := make([]byte, 0, hashing.MaxHashStringSize)
+	var decoded [hashing.HashSize]byte
+	s := []byte(genesisHash)
+	allocs := testing.AllocsPerRun(100, func() {
+		buf = h.AppendString(buf[:0])
+		hashing.NewHashFromStr(genesisHash)
+		hashing.DecodeReversedHex(decoded[:], s)
+	})
+	if allocs != 0 {
+		t.Errorf("got %v allocations, want 0", allocs)
+	}
+	if string(buf) != genesisHash {
+		t.Errorf("AppendString: got %s, want %s", buf, genesisHash)
+	}
+}
@@ -0,0 +1,148 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package hashing provides the hash functions dominating block filter, merkle
+// and address computations behind a stable API, with selectable backends.
+//
+// The functions of the package hash with the backend in use, which is Std
+// unless the package is built with the purego tag, in which case it is
+// Generic.  Std hashes with crypto/sha256, which selects the SHA-NI, AVX2 or
+// ARMv8 SHA2 instructions at run time when the CPU has them, and with the
+// SipHash implementation of github.com/aead/siphash, which uses assembly on
+// amd64 and arm.  Generic is written in portable Go only, for platforms whose
+// assembly is suspect and as the reference the other backends are checked
+// against.
+//
+// Backends are a closed set dispatched without interfaces, so the data
+// hashed does not escape to the heap and hot paths such as merkle tree and
+// filter construction stay free of allocations.
+//
+// The Hash type holds the double SHA256 hashes identifying transactions and
+// blocks, and parses and formats them in the byte-reversed hex of block
+// explorers and RPC, which EncodeReversedHex and DecodeReversedHex write and
+// read for byte strings of any length.
+package hashing
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/aead/siphash"
+	"golang.org/x/crypto/ripemd160"
+)
+
+// ErrUnknownBackend describes an error where a backend other than those of
+// the package is selected.
+var ErrUnknownBackend = errors.New("unknown hash backend")
+
+// Backend identifies an implementation of the hash functions of the package.
+type Backend uint32
+
+const (
+	// Std is the backend of the standard library, using CPU specific
+	// instructions when available.
+	Std Backend = iota
+
+	// Generic is the portable Go backend.
+	Generic
+
+	// numBackends is the number of backends.  It must be the last
+	// constant.
+	numBackends
+)
+
+// backendStrings is a map of backends back to their names for pretty
+// printing.
+var backendStrings = map[Backend]string{
+	Std:     "std",
+	Generic: "generic",
+}
+
+// String returns the name of the backend.
+func (b Backend) String() string {
+	if s, ok := backendStrings[b]; ok {
+		return s
+	}
+	return fmt.Sprintf("Unknown Backend (%d)", uint32(b))
+}
+
+// Sum256 returns the SHA256 hash of data.
+func (b Backend) Sum256(data []byte) [32]byte {
+	if b == Generic {
+		return sum256(data)
+	}
+	return sha256.Sum256(data)
+}
+
+// Hash160 returns the RIPEMD160 hash of the SHA256 hash of data.  The
+// RIPEMD160 implementation is portable Go, so all backends share it.
+func (b Backend) Hash160(data []byte) [20]byte {
+	sum := b.Sum256(data)
+	var h [20]byte
+	hasher := ripemd160.New()
+	hasher.Write(sum[:])
+	copy(h[:], hasher.Sum(nil))
+	return h
+}
+
+// SipHash24 returns the SipHash-2-4 hash of data with key.
+func (b Backend) SipHash24(key *[16]byte, data []byte) uint64 {
+	if b == Generic {
+		return sipHash24(key, data)
+	}
+	return siphash.Sum64(data, key)
+}
+
+// current is the backend in use.
+var current atomic.Uint32
+
+func init() {
+	current.Store(uint32(defaultBackend))
+}
+
+// SetBackend selects the backend the functions of the package hash with.  It
+// is meant to be called at start up, since hashes computed while switching
+// may come from either backend.  ErrUnknownBackend is returned for backends
+// other than those of the package.
+func SetBackend(b Backend) error {
+	if b >= numBackends {
+		return ErrUnknownBackend
+	}
+	current.Store(uint32(b))
+	return nil
+}
+
+// Current returns the backend in use.  Loops hashing many values may call
+// its methods directly rather than the functions of the package, which look
+// up the backend on each call.
+func Current() Backend {
+	return Backend(current.Load())
+}
+
+// Sum256 returns the SHA256 hash of data.
+func Sum256(data []byte) [32]byte {
+	return Current().Sum256(data)
+}
+
+// DoubleSum256 returns the SHA256 hash of the SHA256 hash of data, the hash of
+// transactions, blocks and merkle tree nodes.
+func DoubleSum256(data []byte) [32]byte {
+	b := Current()
+	h := b.Sum256(data)
+	return b.Sum256(h[:])
+}
+
+// Hash160 returns the RIPEMD160 hash of the SHA256 hash of data, the hash of
+// public keys and scripts in addresses.
+func Hash160(data []byte) [20]byte {
+	return Current().Hash160(data)
+}
+
+// SipHash24 returns the SipHash-2-4 hash of data with key, the hash of block
+// filter entries and compact block short IDs.
+func SipHash24(key *[16]byte, data []byte) uint64 {
+	return Current().SipHash24(key, data)
+}
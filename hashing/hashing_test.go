@@ -0,0 +1,141 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package hashing_test
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/zeusyf/btcutil/hashing"
+)
+
+// backends are the backends of the package.
+var backends = []hashing.Backend{hashing.Std, hashing.Generic}
+
+// TestVectors ensures the backends return the published hashes of SHA256,
+// Hash160 and SipHash-2-4.
+func TestVectors(t *testing.T) {
+	var key [16]byte
+	msg := make([]byte, 15)
+	for i := range key {
+		key[i] = byte(i)
+		if i < len(msg) {
+			msg[i] = byte(i)
+		}
+	}
+
+	for _, b := range backends {
+		sum := b.Sum256([]byte("abc"))
+		want := "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"
+		if got := hex.EncodeToString(sum[:]); got != want {
+			t.Errorf("%v: got Sum256 %s, want %s", b, got, want)
+		}
+
+		h := b.Hash160(nil)
+		want = "b472a266d0bd89c13706a4132ccfb16f7c3b9fcb"
+		if got := hex.EncodeToString(h[:]); got != want {
+			t.Errorf("%v: got Hash160 %s, want %s", b, got, want)
+		}
+
+		// The test vector of the SipHash paper.
+		if got := b.SipHash24(&key, msg); got != 0xa129ca6149be45e5 {
+			t.Errorf("%v: got SipHash24 %x, want a129ca6149be45e5", b, got)
+		}
+	}
+}
+
+// TestBackendsAgree ensures the backends return the same hashes for inputs of
+// every length up to a few blocks, covering all padding cases.
+func TestBackendsAgree(t *testing.T) {
+	var key [16]byte
+	for i := range key {
+		key[i] = byte(i * 3)
+	}
+	data := make([]byte, 300)
+	for i := range data {
+		data[i] = byte(i*7 + 3)
+	}
+	for n := 0; n <= len(data); n++ {
+		d := data[:n]
+		if hashing.Std.Sum256(d) != hashing.Generic.Sum256(d) {
+			t.Errorf("Sum256 of %d bytes differs", n)
+		}
+		if hashing.Std.Hash160(d) != hashing.Generic.Hash160(d) {
+			t.Errorf("Hash160 of %d bytes differs", n)
+		}
+		if hashing.Std.SipHash24(&key, d) != hashing.Generic.SipHash24(&key, d) {
+			t.Errorf("SipHash24 of %d bytes differs", n)
+		}
+	}
+}
+
+// TestSetBackend ensures the functions of the package hash with the selected
+// backend, and unknown backends are rejected.
+func TestSetBackend(t *testing.T) {
+	def := hashing.Current()
+	defer hashing.SetBackend(def)
+
+	for _, b := range backends {
+		if err := hashing.SetBackend(b); err != nil {
+			t.Fatalf("SetBackend(%v): unexpected error: %v", b, err)
+		}
+		if hashing.Current() != b {
+			t.Errorf("SetBackend(%v): got backend %v", b, hashing.Current())
+		}
+		first := b.Sum256([]byte("abc"))
+		if got, want := hashing.DoubleSum256([]byte("abc")),
+			b.Sum256(first[:]); got != want {
+
+			t.Errorf("%v: got DoubleSum256 %x, want %x", b, got, want)
+		}
+	}
+
+	b := hashing.Backend(100)
+	if err := hashing.SetBackend(b); err != hashing.ErrUnknownBackend {
+		t.Errorf("SetBackend(%v): got error %v, want %v", b, err,
+			hashing.ErrUnknownBackend)
+	}
+}
+
+// TestAllocs ensures hashing does not move the data hashed to the heap.
+func TestAllocs(t *testing.T) {
+	var key [16]byte
+	for _, b := range backends {
+		hashing.SetBackend(b)
+		allocs := testing.AllocsPerRun(100, func() {
+			var buf [64]byte
+			hashing.DoubleSum256(buf[:])
+			hashing.SipHash24(&key, buf[:])
+		})
+		if allocs != 0 {
+			t.Errorf("%v: got %v allocations, want 0", b, allocs)
+		}
+	}
+	hashing.SetBackend(hashing.Std)
+}
+
+func benchmarkSum256(b *testing.B, backend hashing.Backend) {
+	data := make([]byte, 1024)
+	b.SetBytes(int64(len(data)))
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		backend.Sum256(data)
+	}
+}
+
+func BenchmarkSum256Std(b *testing.B)     { benchmarkSum256(b, hashing.Std) }
+func BenchmarkSum256Generic(b *testing.B) { benchmarkSum256(b, hashing.Generic) }
+
+func benchmarkSipHash24(b *testing.B, backend hashing.Backend) {
+	var key [16]byte
+	data := make([]byte, 25)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		backend.SipHash24(&key, data)
+	}
+}
+
+func BenchmarkSipHash24Std(b *testing.B)     { benchmarkSipHash24(b, hashing.Std) }
+func BenchmarkSipHash24Generic(b *testing.B) { benchmarkSipHash24(b, hashing.Generic) }
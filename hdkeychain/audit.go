@@ -0,0 +1,52 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package hdkeychain
+
+import (
+	"time"
+
+	"github.com/zeusyf/btcutil/audit"
+)
+
+// WithAudit makes the cache record every derivation, whether answered from the
+// cache or not, to sink with purpose declared as its reason.  Derive fails
+// with the error of the sink if recording fails.
+func WithAudit(sink audit.Sink, purpose string) CacheOption {
+	return func(c *DerivationCache) {
+		c.auditSink = sink
+		c.auditPurpose = purpose
+	}
+}
+
+// DeriveAudited derives the key at path below k, as repeated calls to Child
+// would, and records the derivation to sink with purpose declared as its
+// reason.  The derived key is only returned if it was recorded.
+func DeriveAudited(sink audit.Sink, purpose string, k *ExtendedKey, path ...uint32) (*ExtendedKey, error) {
+	child, err := k.DerivePath(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := recordDerivation(sink, purpose, k, path, child); err != nil {
+		if child != k {
+			child.Zero()
+		}
+		return nil, err
+	}
+	return child, nil
+}
+
+// recordDerivation records the derivation of child from parent along path.
+func recordDerivation(sink audit.Sink, purpose string, parent *ExtendedKey,
+	path []uint32, child *ExtendedKey) error {
+
+	origin := KeyOrigin{Fingerprint: parent.Fingerprint(), Path: path}
+	return sink.Record(&audit.Event{
+		Time:      time.Now(),
+		Operation: audit.OpDerive,
+		Origin:    origin.AuditOrigin(),
+		Purpose:   purpose,
+		PubKey:    append([]byte(nil), child.pubKeyBytes()...),
+	})
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package hdkeychain
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil/audit"
+)
+
+// TestAuditedDerivation ensures derivations are recorded with their origin
+// and derived key, and that a failing sink prevents derivation.
+func TestAuditedDerivation(t *testing.T) {
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	master, err := NewMaster(seed, &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("NewMaster: unexpected error: %v", err)
+	}
+	path := []uint32{HardenedKeyStart + 44, 0, 3}
+	want := deriveUncached(t, master, path...)
+
+	// The fingerprint of the master key is the parent fingerprint of its
+	// children.
+	fp := deriveUncached(t, master, 0).ParentFingerprint()
+	wantOrigin := fmt.Sprintf("%08x/44'/0/3", fp)
+
+	var events []*audit.Event
+	sink := audit.SinkFunc(func(e *audit.Event) error {
+		events = append(events, e)
+		return nil
+	})
+	c := NewDerivationCache(10, WithAudit(sink, "deposit"))
+	derive := []func() (*ExtendedKey, error){
+		func() (*ExtendedKey, error) {
+			return DeriveAudited(sink, "deposit", master, path...)
+		},
+		func() (*ExtendedKey, error) { return c.Derive(master, path...) },
+		func() (*ExtendedKey, error) { return c.Derive(master, path...) },
+	}
+	for i, f := range derive {
+		got, err := f()
+		if err != nil {
+			t.Fatalf("#%d: unexpected error: %v", i, err)
+		}
+		if got.String() != want.String() {
+			t.Fatalf("#%d: got %s, want %s", i, got, want)
+		}
+		if len(events) != i+1 {
+			t.Fatalf("#%d: got %d events, want %d", i, len(events), i+1)
+		}
+		e := events[i]
+		if e.Operation != audit.OpDerive || e.Purpose != "deposit" ||
+			e.Digest != nil || !bytes.Equal(e.PubKey, want.pubKeyBytes()) {
+			t.Fatalf("#%d: unexpected event %+v", i, e)
+		}
+		if e.Origin.String() != wantOrigin {
+			t.Fatalf("#%d: got origin %s, want %s", i, e.Origin,
+				wantOrigin)
+		}
+	}
+
+	sinkErr := errors.New("audit log unavailable")
+	failing := audit.SinkFunc(func(*audit.Event) error { return sinkErr })
+	if k, err := DeriveAudited(failing, "", master, 1); k != nil || err != sinkErr {
+		t.Fatalf("DeriveAudited: got %v, %v, want %v", k, err, sinkErr)
+	}
+	c = NewDerivationCache(10, WithAudit(failing, ""))
+	if k, err := c.Derive(master, 1); k != nil || err != sinkErr {
+		t.Fatalf("Derive: got %v, %v, want %v", k, err, sinkErr)
+	}
+}
@@ -7,6 +7,7 @@ package hdkeychain_test
 import (
 	"testing"
 
+	"github.com/zeusyf/btcd/chaincfg"
 	"github.com/zeusyf/btcutil/hdkeychain"
 )
 
@@ -82,3 +83,79 @@ func BenchmarkSerialize(b *testing.B) {
 		_ = masterKey.String()
 	}
 }
+
+// benchAddressCount is the number of addresses derived per iteration of the
+// address derivation benchmarks.
+const benchAddressCount = 1000
+
+// benchMasterPub returns the public extended key of the first BIP0032 test
+// vector.
+func benchMasterPub(b *testing.B) *hdkeychain.ExtendedKey {
+	masterKey, err := hdkeychain.NewKeyFromString(bip0032MasterPriv1)
+	if err != nil {
+		b.Fatalf("Failed to decode master seed: %v", err)
+	}
+	pub, err := masterKey.Neuter()
+	if err != nil {
+		b.Fatalf("Failed to neuter master key: %v", err)
+	}
+	return pub
+}
+
+// BenchmarkDeriveAddresses benchmarks deriving consecutive child addresses of
+// an extended public key one at a time with Child.  It is the baseline for
+// BenchmarkBulkDeriveAddresses.
+func BenchmarkDeriveAddresses(b *testing.B) {
+	pub := benchMasterPub(b)
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		for j := uint32(0); j < benchAddressCount; j++ {
+			child, err := pub.Child(j)
+			if err != nil {
+				continue
+			}
+			child.Address(&chaincfg.MainNetParams)
+		}
+	}
+	b.ReportMetric(float64(b.N*benchAddressCount)/b.Elapsed().Seconds(),
+		"addrs/s")
+}
+
+// BenchmarkBulkDeriveAddresses benchmarks deriving consecutive child addresses
+// of an extended public key with a BulkDeriver for various tuning options.
+func BenchmarkBulkDeriveAddresses(b *testing.B) {
+	pub := benchMasterPub(b)
+
+	tests := []struct {
+		name    string
+		workers int
+		batch   int
+	}{
+		{"workers=1/batch=1", 1, 1},
+		{"workers=1/batch=128", 1, 128},
+		{"workers=max/batch=1", 0, 1},
+		{"workers=max/batch=128", 0, 128},
+	}
+	for _, test := range tests {
+		b.Run(test.name, func(b *testing.B) {
+			d, err := hdkeychain.NewBulkDeriver(pub,
+				hdkeychain.WithWorkers(test.workers),
+				hdkeychain.WithBatchSize(test.batch))
+			if err != nil {
+				b.Fatalf("NewBulkDeriver: %v", err)
+			}
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				_, err := d.Addresses(&chaincfg.MainNetParams, 0,
+					benchAddressCount)
+				if err != nil {
+					b.Fatalf("Addresses: %v", err)
+				}
+			}
+			b.ReportMetric(float64(b.N*benchAddressCount)/
+				b.Elapsed().Seconds(), "addrs/s")
+		})
+	}
+}
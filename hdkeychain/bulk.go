@@ -0,0 +1,392 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package hdkeychain
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"math/big"
+	"runtime"
+	"sync"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil"
+)
+
+// DefaultBulkBatchSize is the number of children whose point additions share
+// a single modular inversion when no batch size is configured.
+const DefaultBulkBatchSize = 128
+
+// ErrDeriveRangeHardened describes an error in which a bulk derivation range
+// extends into the hardened child indices.
+var ErrDeriveRangeHardened = errors.New("bulk derivation range includes " +
+	"hardened children")
+
+// BulkOption configures a BulkDeriver.
+type BulkOption func(*BulkDeriver)
+
+// WithWorkers sets the number of goroutines deriving children concurrently.
+// Values less than one select runtime.NumCPU, which is the default.
+func WithWorkers(n int) BulkOption {
+	return func(d *BulkDeriver) {
+		d.workers = n
+	}
+}
+
+// WithBatchSize sets how many children have their public key points added to
+// the parent point with a shared modular inversion, using Montgomery's
+// trick.  Larger batches amortize the inversion better at the cost of memory
+// per worker.  A batch size of one disables batching.
+func WithBatchSize(n int) BulkOption {
+	return func(d *BulkDeriver) {
+		d.batchSize = n
+	}
+}
+
+// BulkDeriver derives large numbers of consecutive non-hardened child public
+// keys and addresses of a single extended key, such as the deposit addresses
+// of an account.  It is considerably faster than repeated calls to Child:
+//
+//   - the parent public key is decompressed once rather than per child
+//   - each worker keeps a keyed HMAC-SHA512 state which is reset rather than
+//     rekeyed for every child
+//   - the affine point additions of a batch of children share one modular
+//     inversion
+//   - children are derived by multiple goroutines
+//
+// A BulkDeriver is safe for concurrent use.
+type BulkDeriver struct {
+	chainCode []byte
+	pubKey    []byte
+	px, py    *big.Int
+	workers   int
+	batchSize int
+}
+
+// NewBulkDeriver returns a deriver for the children of the passed extended
+// key, which may be public or private.  Only public keys are derived.
+func NewBulkDeriver(k *ExtendedKey, opts ...BulkOption) (*BulkDeriver, error) {
+	if k.depth == maxUint8 {
+		return nil, ErrDeriveBeyondMaxDepth
+	}
+	pubKey := k.pubKeyBytes()
+	parsed, err := btcec.ParsePubKey(pubKey, btcec.S256())
+	if err != nil {
+		return nil, err
+	}
+
+	d := &BulkDeriver{
+		chainCode: k.chainCode,
+		pubKey:    pubKey,
+		px:        parsed.X,
+		py:        parsed.Y,
+		batchSize: DefaultBulkBatchSize,
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	if d.workers < 1 {
+		d.workers = runtime.NumCPU()
+	}
+	if d.batchSize < 1 {
+		d.batchSize = 1
+	}
+	return d, nil
+}
+
+// PubKeys returns the serialized compressed public keys of the count children
+// starting at index start.  The entry of a child which is invalid, which
+// happens with a probability below 1 in 2^127, is nil and callers are
+// expected to skip it just like they skip ErrInvalidChild from Child.
+//
+// The keys share a single backing array, so the whole range is allocated at
+// once rather than key by key.
+func (d *BulkDeriver) PubKeys(start uint32, count int) ([][]byte, error) {
+	return d.PubKeysContext(context.Background(), start, count)
+}
+
+// PubKeysContext is like PubKeys, but stops deriving once ctx is done, in
+// which case the error of ctx is returned.  The workers check ctx between
+// batches, and have all returned by the time PubKeysContext does.
+func (d *BulkDeriver) PubKeysContext(ctx context.Context, start uint32,
+	count int) ([][]byte, error) {
+	if count <= 0 {
+		return nil, nil
+	}
+	if uint64(start)+uint64(count) > HardenedKeyStart {
+		return nil, ErrDeriveRangeHardened
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	keys := make([][]byte, count)
+	buf := make([]byte, count*btcec.PubKeyBytesLenCompressed)
+	chunk := (count + d.workers - 1) / d.workers
+	if chunk < d.batchSize {
+		chunk = d.batchSize
+	}
+
+	done := ctx.Done()
+	var wg sync.WaitGroup
+	for lo := 0; lo < count; lo += chunk {
+		hi := lo + chunk
+		if hi > count {
+			hi = count
+		}
+		wg.Add(1)
+		go func(lo, hi int) {
+			defer wg.Done()
+			w := d.newWorker()
+			for i := lo; i < hi; i += d.batchSize {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				end := i + d.batchSize
+				if end > hi {
+					end = hi
+				}
+				w.deriveBatch(start+uint32(i), keys[i:end],
+					buf[i*btcec.PubKeyBytesLenCompressed:])
+			}
+		}(lo, hi)
+	}
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return keys, nil
+}
+
+// Addresses returns the pay-to-pubkey-hash addresses for the passed network
+// of the count children starting at index start.  The entries of invalid
+// children are nil.
+func (d *BulkDeriver) Addresses(net *chaincfg.Params, start uint32, count int) ([]*btcutil.AddressPubKeyHash, error) {
+	return d.AddressesContext(context.Background(), net, start, count)
+}
+
+// AddressesContext is like Addresses, but stops deriving once ctx is done, in
+// which case the error of ctx is returned.
+func (d *BulkDeriver) AddressesContext(ctx context.Context, net *chaincfg.Params,
+	start uint32, count int) ([]*btcutil.AddressPubKeyHash, error) {
+	keys, err := d.PubKeysContext(ctx, start, count)
+	if err != nil {
+		return nil, err
+	}
+	addrs := make([]*btcutil.AddressPubKeyHash, len(keys))
+	for i, key := range keys {
+		if key == nil {
+			continue
+		}
+		addr, err := btcutil.NewAddressPubKeyHash(btcutil.Hash160(key), net)
+		if err != nil {
+			return nil, err
+		}
+		addrs[i] = addr
+	}
+	return addrs, nil
+}
+
+// bulkWorker holds the per goroutine state of a BulkDeriver.
+type bulkWorker struct {
+	d    *BulkDeriver
+	mac  hash.Hash
+	data [33 + 4]byte
+	sum  []byte
+
+	// Scratch space for a batch.
+	idx        []int
+	qx, qy, dx []*big.Int
+}
+
+// newWorker returns a worker with an HMAC keyed with the chain code.
+func (d *BulkDeriver) newWorker() *bulkWorker {
+	w := &bulkWorker{
+		d:   d,
+		mac: hmac.New(sha512.New, d.chainCode),
+		sum: make([]byte, 0, sha512.Size),
+		idx: make([]int, d.batchSize),
+		qx:  make([]*big.Int, d.batchSize),
+		qy:  make([]*big.Int, d.batchSize),
+		dx:  make([]*big.Int, d.batchSize),
+	}
+	copy(w.data[:], d.pubKey)
+	return w
+}
+
+// deriveBatch derives the public keys of len(keys) consecutive children
+// starting at index first into keys.  The keys are serialized into buf, which
+// must have room for len(keys) compressed public keys.
+func (w *bulkWorker) deriveBatch(first uint32, keys [][]byte, buf []byte) {
+	const keyLen = btcec.PubKeyBytesLenCompressed
+
+	curve := btcec.S256()
+	p := curve.Params().P
+	px, py := w.d.px, w.d.py
+
+	// Compute the intermediate public key point(parse256(Il)) of every
+	// child along with the x distance to the parent point.
+	n := 0
+	for i := range keys {
+		binary.BigEndian.PutUint32(w.data[33:], first+uint32(i))
+		w.mac.Reset()
+		w.mac.Write(w.data[:])
+		w.sum = w.mac.Sum(w.sum[:0])
+		il := w.sum[:32]
+
+		keys[i] = nil
+		ilNum := new(big.Int).SetBytes(il)
+		if ilNum.Cmp(curve.N) >= 0 || ilNum.Sign() == 0 {
+			continue
+		}
+		qx, qy := curve.ScalarBaseMult(il)
+		if qx.Sign() == 0 || qy.Sign() == 0 {
+			continue
+		}
+
+		dx := new(big.Int).Sub(qx, px)
+		dx.Mod(dx, p)
+		if dx.Sign() == 0 {
+			// The points are equal or inverses of each other, which
+			// the affine addition below can't handle.
+			x, y := curve.Add(qx, qy, px, py)
+			if x.Sign() != 0 || y.Sign() != 0 {
+				keys[i] = putCompressedPoint(
+					buf[i*keyLen:(i+1)*keyLen:(i+1)*keyLen], x, y)
+			}
+			continue
+		}
+		w.idx[n], w.qx[n], w.qy[n], w.dx[n] = i, qx, qy, dx
+		n++
+	}
+	batchInvert(w.dx[:n], p)
+
+	// childKey = serP(point(parse256(Il)) + parentKey)
+	lambda, x3, y3 := new(big.Int), new(big.Int), new(big.Int)
+	for j := 0; j < n; j++ {
+		qx, qy, inv := w.qx[j], w.qy[j], w.dx[j]
+
+		// lambda = (qy - py) / (qx - px)
+		lambda.Sub(qy, py)
+		lambda.Mul(lambda, inv)
+		lambda.Mod(lambda, p)
+
+		// x3 = lambda^2 - px - qx
+		x3.Mul(lambda, lambda)
+		x3.Sub(x3, px)
+		x3.Sub(x3, qx)
+		x3.Mod(x3, p)
+
+		// y3 = lambda * (px - x3) - py
+		y3.Sub(px, x3)
+		y3.Mul(y3, lambda)
+		y3.Sub(y3, py)
+		y3.Mod(y3, p)
+
+		i := w.idx[j]
+		keys[i] = putCompressedPoint(
+			buf[i*keyLen:(i+1)*keyLen:(i+1)*keyLen], x3, y3)
+	}
+}
+
+// batchInvert replaces every element of xs with its inverse modulo p using a
+// single modular inversion.  All elements must be non-zero modulo p.
+func batchInvert(xs []*big.Int, p *big.Int) {
+	if len(xs) == 0 {
+		return
+	}
+
+	// prefix[i] is the product of xs[0] through xs[i].
+	prefix := make([]*big.Int, len(xs))
+	acc := new(big.Int).Set(xs[0])
+	prefix[0] = new(big.Int).Set(acc)
+	for i := 1; i < len(xs); i++ {
+		acc.Mul(acc, xs[i])
+		acc.Mod(acc, p)
+		prefix[i] = new(big.Int).Set(acc)
+	}
+
+	inv := new(big.Int).ModInverse(acc, p)
+	tmp := new(big.Int)
+	for i := len(xs) - 1; i > 0; i-- {
+		// xs[i]^-1 = (xs[0]...xs[i])^-1 * (xs[0]...xs[i-1])
+		tmp.Mul(inv, prefix[i-1])
+		tmp.Mod(tmp, p)
+		inv.Mul(inv, xs[i])
+		inv.Mod(inv, p)
+		xs[i].Set(tmp)
+	}
+	xs[0].Set(inv)
+}
+
+// putCompressedPoint writes the compressed serialization of a public key
+// point to b, which must be 33 bytes long, and returns b.
+func putCompressedPoint(b []byte, x, y *big.Int) []byte {
+	b[0] = 0x02 | byte(y.Bit(0))
+	x.FillBytes(b[1:])
+	return b
+}
+
+// DerivedKey is a child public key derived by DeriveRange.
+type DerivedKey struct {
+	// Index is the index of the child.
+	Index uint32
+
+	// PubKey is the serialized compressed public key of the child.
+	PubKey []byte
+}
+
+// Address returns the pay-to-pubkey-hash address of the key for the passed
+// network.
+func (d DerivedKey) Address(net *chaincfg.Params) (*btcutil.AddressPubKeyHash, error) {
+	return btcutil.NewAddressPubKeyHash(btcutil.Hash160(d.PubKey), net)
+}
+
+// DeriveRange derives the public keys of the count non-hardened children
+// starting at index start, as exchanges do when pre-generating deposit
+// addresses.  It is a shorthand for deriving the range with a BulkDeriver
+// configured with the passed options, so children are derived in parallel
+// and with amortized allocations.
+//
+// Invalid children, which occur with a probability below 1 in 2^127, are
+// skipped like callers of Child skip ErrInvalidChild, so the returned slice
+// may hold fewer than count keys; the Index of each key identifies the child.
+func (k *ExtendedKey) DeriveRange(start uint32, count int, opts ...BulkOption) ([]DerivedKey, error) {
+	return k.DeriveRangeContext(context.Background(), start, count, opts...)
+}
+
+// DeriveRangeContext is like DeriveRange, but stops deriving once ctx is done,
+// in which case the error of ctx is returned.
+func (k *ExtendedKey) DeriveRangeContext(ctx context.Context, start uint32,
+	count int, opts ...BulkOption) ([]DerivedKey, error) {
+	d, err := NewBulkDeriver(k, opts...)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := d.PubKeysContext(ctx, start, count)
+	if err != nil {
+		return nil, err
+	}
+
+	derived := make([]DerivedKey, 0, len(keys))
+	for i, key := range keys {
+		if key == nil {
+			continue
+		}
+		derived = append(derived, DerivedKey{
+			Index:  start + uint32(i),
+			PubKey: key,
+		})
+	}
+	return derived, nil
+}
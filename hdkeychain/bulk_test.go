@@ -0,0 +1,193 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package hdkeychain
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg"
+)
+
+// TestBulkDeriver ensures bulk derivation produces the same children as Child
+// for every combination of workers and batch sizes.
+func TestBulkDeriver(t *testing.T) {
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	master, err := NewMaster(seed, &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("NewMaster: unexpected error: %v", err)
+	}
+	pub, err := master.Neuter()
+	if err != nil {
+		t.Fatalf("Neuter: unexpected error: %v", err)
+	}
+
+	const start, count = 1000, 37
+	want := make([][]byte, count)
+	for i := range want {
+		child, err := pub.Child(start + uint32(i))
+		if err != nil {
+			t.Fatalf("Child: unexpected error: %v", err)
+		}
+		want[i] = child.pubKeyBytes()
+	}
+
+	for _, workers := range []int{1, 3} {
+		for _, batch := range []int{1, 8, 64} {
+			// Deriving from the private key must give the same
+			// public keys.
+			for _, key := range []*ExtendedKey{pub, master} {
+				d, err := NewBulkDeriver(key, WithWorkers(workers),
+					WithBatchSize(batch))
+				if err != nil {
+					t.Fatalf("NewBulkDeriver: unexpected error: %v", err)
+				}
+				keys, err := d.PubKeys(start, count)
+				if err != nil {
+					t.Fatalf("PubKeys: unexpected error: %v", err)
+				}
+				for i := range want {
+					if !bytes.Equal(keys[i], want[i]) {
+						t.Fatalf("PubKeys(workers %d, batch %d): "+
+							"child %d mismatch", workers, batch,
+							start+i)
+					}
+				}
+			}
+		}
+	}
+
+	d, _ := NewBulkDeriver(pub)
+	addrs, err := d.Addresses(&chaincfg.MainNetParams, start, count)
+	if err != nil {
+		t.Fatalf("Addresses: unexpected error: %v", err)
+	}
+	for i, addr := range addrs {
+		child, _ := pub.Child(start + uint32(i))
+		wantAddr, _ := child.Address(&chaincfg.MainNetParams)
+		if addr.EncodeAddress() != wantAddr.EncodeAddress() {
+			t.Fatalf("Addresses: child %d: got %s, want %s", start+i,
+				addr.EncodeAddress(), wantAddr.EncodeAddress())
+		}
+	}
+
+	_, err = d.PubKeys(HardenedKeyStart-1, 2)
+	if err != ErrDeriveRangeHardened {
+		t.Fatalf("PubKeys: got %v, want %v", err, ErrDeriveRangeHardened)
+	}
+}
+
+// TestBatchInvert ensures batched inversion matches individual inversion.
+func TestBatchInvert(t *testing.T) {
+	p := big.NewInt(1000003)
+	xs := make([]*big.Int, 50)
+	for i := range xs {
+		xs[i] = big.NewInt(int64(i*7919 + 1))
+	}
+
+	inverted := make([]*big.Int, len(xs))
+	for i := range xs {
+		inverted[i] = new(big.Int).Set(xs[i])
+	}
+	batchInvert(inverted, p)
+
+	for i := range xs {
+		want := new(big.Int).ModInverse(xs[i], p)
+		if inverted[i].Cmp(want) != 0 {
+			t.Fatalf("batchInvert: element %d: got %v, want %v", i,
+				inverted[i], want)
+		}
+	}
+}
+
+// TestDeriveRange ensures DeriveRange returns the same keys and addresses as
+// Child, indexed by child number.
+func TestDeriveRange(t *testing.T) {
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	master, err := NewMaster(seed, &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("NewMaster: unexpected error: %v", err)
+	}
+
+	const start, count = 20, 45
+	keys, err := master.DeriveRange(start, count, WithWorkers(2),
+		WithBatchSize(16))
+	if err != nil {
+		t.Fatalf("DeriveRange: unexpected error: %v", err)
+	}
+	if len(keys) != count {
+		t.Fatalf("DeriveRange: got %d keys, want %d", len(keys), count)
+	}
+	for i, key := range keys {
+		child, err := master.Child(start + uint32(i))
+		if err != nil {
+			t.Fatalf("Child: unexpected error: %v", err)
+		}
+		if key.Index != start+uint32(i) {
+			t.Fatalf("DeriveRange: key %d has index %d", i, key.Index)
+		}
+		if !bytes.Equal(key.PubKey, child.pubKeyBytes()) {
+			t.Fatalf("DeriveRange: child %d mismatch", key.Index)
+		}
+
+		addr, err := key.Address(&chaincfg.MainNetParams)
+		if err != nil {
+			t.Fatalf("Address: unexpected error: %v", err)
+		}
+		wantAddr, _ := child.Address(&chaincfg.MainNetParams)
+		if addr.EncodeAddress() != wantAddr.EncodeAddress() {
+			t.Fatalf("Address: child %d: got %s, want %s", key.Index,
+				addr.EncodeAddress(), wantAddr.EncodeAddress())
+		}
+	}
+
+	if keys, err := master.DeriveRange(0, 0); len(keys) != 0 || err != nil {
+		t.Fatalf("DeriveRange: got %d keys, %v for empty range", len(keys),
+			err)
+	}
+	_, err = master.DeriveRange(HardenedKeyStart-10, count)
+	if err != ErrDeriveRangeHardened {
+		t.Fatalf("DeriveRange: got error %v, want %v", err,
+			ErrDeriveRangeHardened)
+	}
+}
+
+// TestBulkDeriverContext ensures bulk derivation stops with the error of its
+// context once the context is done.
+func TestBulkDeriverContext(t *testing.T) {
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	master, err := NewMaster(seed, &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("NewMaster: unexpected error: %v", err)
+	}
+	d, err := NewBulkDeriver(master, WithWorkers(2), WithBatchSize(4))
+	if err != nil {
+		t.Fatalf("NewBulkDeriver: unexpected error: %v", err)
+	}
+
+	keys, err := d.PubKeysContext(context.Background(), 0, 10)
+	if err != nil || len(keys) != 10 {
+		t.Fatalf("PubKeysContext: got %d keys, %v", len(keys), err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := d.PubKeysContext(ctx, 0, 10); err != context.Canceled {
+		t.Errorf("PubKeysContext: got error %v, want %v", err,
+			context.Canceled)
+	}
+	_, err = d.AddressesContext(ctx, &chaincfg.MainNetParams, 0, 10)
+	if err != context.Canceled {
+		t.Errorf("AddressesContext: got error %v, want %v", err,
+			context.Canceled)
+	}
+	if _, err := master.DeriveRangeContext(ctx, 0, 10); err != context.Canceled {
+		t.Errorf("DeriveRangeContext: got error %v, want %v", err,
+			context.Canceled)
+	}
+}
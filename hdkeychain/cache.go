@@ -0,0 +1,294 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package hdkeychain
+
+import (
+	"container/list"
+	"encoding/binary"
+	"sync"
+
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/audit"
+	"github.com/zeusyf/btcutil/membudget"
+)
+
+// cacheEntryOverhead approximates the memory used by a cache entry besides
+// its key material and path: the extended key struct, the list element and
+// the map entry.
+const cacheEntryOverhead = 256
+
+// CacheOption configures a DerivationCache.
+type CacheOption func(*DerivationCache)
+
+// WithMemoryBudget registers the cache with the passed memory budget manager
+// under the passed name, so its entries count against the manager's limit
+// and are evicted, least recently used first, when the limit is exceeded.
+func WithMemoryBudget(m *membudget.Manager, name string) CacheOption {
+	return func(c *DerivationCache) {
+		c.consumer = m.Register(name, cacheEvictor{c})
+	}
+}
+
+// CacheStats describes the effectiveness of a DerivationCache.
+type CacheStats struct {
+	// Hits is the number of derivations answered from the cache.
+	Hits uint64
+
+	// Misses is the number of derivations which required deriving at
+	// least the last step of the path.
+	Misses uint64
+
+	// Evictions is the number of entries dropped to stay within the
+	// maximum number of entries or the memory budget.
+	Evictions uint64
+
+	// Entries is the number of extended keys currently cached.
+	Entries int
+}
+
+// cacheKey identifies a cached extended key by the key it was derived from
+// and the path of the derivation.  The parent is identified by the full
+// Hash160 of its public key, of which the fingerprint is the first 4 bytes,
+// so fingerprint collisions between unrelated parents can not return another
+// parent's children.  The chain code, version and privacy of the parent are
+// included too since they all affect the derived key.
+type cacheKey struct {
+	parentID  [20]byte
+	chainCode [32]byte
+	version   [4]byte
+	private   bool
+	path      string
+}
+
+// cacheEntry is the value of an element of the cache's LRU list.
+type cacheEntry struct {
+	key  cacheKey
+	ext  *ExtendedKey
+	size int64
+}
+
+// DerivationCache is a least recently used cache of derived extended keys.
+// Services which derive the same branches over and over, such as the receive
+// addresses of many accounts, use it to skip the elliptic curve operations of
+// every derivation step it has seen before.  Deriving a path also caches each
+// of its prefixes, so siblings share the derivation of their parent.
+//
+// Cached private keys stay in memory until they are evicted or the cache is
+// purged; evicted and purged keys are zeroed.  The keys returned by Derive
+// are copies, so callers may zero them without affecting the cache.
+//
+// A DerivationCache is safe for concurrent use.
+type DerivationCache struct {
+	mtx        sync.Mutex
+	maxEntries int
+	entries    map[cacheKey]*list.Element
+	lru        *list.List
+	hits       uint64
+	misses     uint64
+	evictions  uint64
+	consumer   *membudget.Consumer
+
+	auditSink    audit.Sink
+	auditPurpose string
+}
+
+// NewDerivationCache returns a cache holding at most maxEntries extended
+// keys.  A maxEntries of zero or less leaves the number of entries unbounded,
+// which is only sensible together with a memory budget.
+func NewDerivationCache(maxEntries int, opts ...CacheOption) *DerivationCache {
+	c := &DerivationCache{
+		maxEntries: maxEntries,
+		entries:    make(map[cacheKey]*list.Element),
+		lru:        list.New(),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Derive returns the extended key derived from k by successively deriving
+// the children at each index of path, as repeated calls to Child would.  The
+// longest prefix of path found in the cache is used as the starting point,
+// and every key derived along the way is added to the cache.
+func (c *DerivationCache) Derive(k *ExtendedKey, path ...uint32) (*ExtendedKey, error) {
+	if len(path) == 0 {
+		return k, nil
+	}
+
+	key := cacheKey{private: k.isPrivate}
+	copy(key.parentID[:], btcutil.Hash160(k.pubKeyBytes()))
+	copy(key.chainCode[:], k.chainCode)
+	copy(key.version[:], k.version)
+	pathBytes := make([]byte, 4*len(path))
+	for i, index := range path {
+		binary.BigEndian.PutUint32(pathBytes[4*i:], index)
+	}
+	fullPath := string(pathBytes)
+
+	// Find the longest cached prefix of the path.  The cached key is
+	// copied while the lock is held since it is zeroed when evicted.
+	cur := k
+	n := len(path)
+	c.mtx.Lock()
+	for ; n > 0; n-- {
+		key.path = fullPath[:4*n]
+		if elem, ok := c.entries[key]; ok {
+			c.lru.MoveToFront(elem)
+			cur = elem.Value.(*cacheEntry).ext.clone()
+			break
+		}
+	}
+	if n == len(path) {
+		c.hits++
+		c.mtx.Unlock()
+		return c.audit(k, path, cur)
+	}
+	c.misses++
+	c.mtx.Unlock()
+
+	for ; n < len(path); n++ {
+		child, err := cur.Child(path[n])
+		if err != nil {
+			return nil, err
+		}
+		key.path = fullPath[:4*(n+1)]
+		c.add(key, child)
+		cur = child
+	}
+	return c.audit(k, path, cur)
+}
+
+// audit records the derivation of child from parent along path when the
+// cache is audited, and returns child if that succeeded.
+func (c *DerivationCache) audit(parent *ExtendedKey, path []uint32,
+	child *ExtendedKey) (*ExtendedKey, error) {
+
+	if c.auditSink == nil {
+		return child, nil
+	}
+	err := recordDerivation(c.auditSink, c.auditPurpose, parent, path, child)
+	if err != nil {
+		child.Zero()
+		return nil, err
+	}
+	return child, nil
+}
+
+// add caches a copy of the passed extended key, evicting the least recently
+// used entries if the cache is full.
+func (c *DerivationCache) add(key cacheKey, k *ExtendedKey) {
+	// Memoize the public key before caching so the cached key is never
+	// written to again.
+	k.pubKeyBytes()
+	ext := k.clone()
+	size := int64(cacheEntryOverhead + len(key.path) + len(ext.key) +
+		len(ext.pubKey) + len(ext.chainCode) + len(ext.parentFP) +
+		len(ext.version))
+
+	c.mtx.Lock()
+	if _, ok := c.entries[key]; ok {
+		c.mtx.Unlock()
+		ext.Zero()
+		return
+	}
+	entry := &cacheEntry{key: key, ext: ext, size: size}
+	c.entries[key] = c.lru.PushFront(entry)
+	var freed int64
+	for c.maxEntries > 0 && c.lru.Len() > c.maxEntries {
+		freed += c.evictOldest()
+	}
+	c.mtx.Unlock()
+
+	// The consumer may call back into Evict, so it must be updated without
+	// holding the lock.
+	if c.consumer != nil {
+		c.consumer.Shrink(freed)
+		c.consumer.Grow(size)
+	}
+}
+
+// evictOldest removes and zeroes the least recently used entry and returns
+// its size.  It must be called with the lock held.
+func (c *DerivationCache) evictOldest() int64 {
+	entry := c.lru.Remove(c.lru.Back()).(*cacheEntry)
+	delete(c.entries, entry.key)
+	entry.ext.Zero()
+	c.evictions++
+	return entry.size
+}
+
+// Stats returns the hit, miss and eviction counts of the cache along with its
+// current number of entries.
+func (c *DerivationCache) Stats() CacheStats {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return CacheStats{
+		Hits:      c.hits,
+		Misses:    c.misses,
+		Evictions: c.evictions,
+		Entries:   c.lru.Len(),
+	}
+}
+
+// Purge removes and zeroes all cached keys.  Purged keys are not counted as
+// evictions.
+func (c *DerivationCache) Purge() {
+	c.mtx.Lock()
+	var freed int64
+	for c.lru.Len() > 0 {
+		entry := c.lru.Remove(c.lru.Back()).(*cacheEntry)
+		entry.ext.Zero()
+		freed += entry.size
+	}
+	c.entries = make(map[cacheKey]*list.Element)
+	c.mtx.Unlock()
+
+	if c.consumer != nil {
+		c.consumer.Shrink(freed)
+	}
+}
+
+// cacheEvictor evicts entries of a DerivationCache on behalf of its memory
+// budget manager.  It is a separate type so Evict is not part of the API of
+// DerivationCache.
+type cacheEvictor struct {
+	c *DerivationCache
+}
+
+// Evict drops least recently used entries until at least n bytes are freed
+// or the cache is empty.
+//
+// This is part of the membudget.Evictor interface.
+func (e cacheEvictor) Evict(n int64) int64 {
+	c := e.c
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	var freed int64
+	for freed < n && c.lru.Len() > 0 {
+		freed += c.evictOldest()
+	}
+	return freed
+}
+
+// clone returns a deep copy of the extended key, which can be zeroed
+// independently of the original.
+func (k *ExtendedKey) clone() *ExtendedKey {
+	c := &ExtendedKey{
+		key:       append([]byte(nil), k.key...),
+		pubKey:    append([]byte(nil), k.pubKey...),
+		isPrivate: k.isPrivate,
+		chainCode: append([]byte(nil), k.chainCode...),
+		depth:     k.depth,
+		parentFP:  append([]byte(nil), k.parentFP...),
+		childNum:  k.childNum,
+		version:   append([]byte(nil), k.version...),
+	}
+	if k.secret != nil {
+		c.Lock()
+	}
+	return c
+}
@@ -0,0 +1,151 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package hdkeychain
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil/membudget"
+)
+
+// deriveUncached derives the passed path with repeated calls to Child.
+func deriveUncached(t *testing.T, k *ExtendedKey, path ...uint32) *ExtendedKey {
+	t.Helper()
+	for _, index := range path {
+		var err error
+		k, err = k.Child(index)
+		if err != nil {
+			t.Fatalf("Child: unexpected error: %v", err)
+		}
+	}
+	return k
+}
+
+// TestDerivationCache ensures cached derivations match uncached ones, that
+// prefixes are reused, and that hits and misses are counted.
+func TestDerivationCache(t *testing.T) {
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	master, err := NewMaster(seed, &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("NewMaster: unexpected error: %v", err)
+	}
+	pub, err := master.Neuter()
+	if err != nil {
+		t.Fatalf("Neuter: unexpected error: %v", err)
+	}
+
+	c := NewDerivationCache(100)
+	tests := []struct {
+		parent  *ExtendedKey
+		path    []uint32
+		hit     bool
+		entries int
+	}{
+		{master, []uint32{HardenedKeyStart + 44, 0, 5}, false, 3},
+		{master, []uint32{HardenedKeyStart + 44, 0, 5}, true, 3},
+		{master, []uint32{HardenedKeyStart + 44, 0, 6}, false, 4},
+		{master, []uint32{HardenedKeyStart + 44, 0}, true, 4},
+		// The public parent has the same fingerprint as the private
+		// one, but its children must not be shared.
+		{pub, []uint32{1, 5}, false, 6},
+		{master, []uint32{1, 5}, false, 8},
+		{pub, []uint32{1, 5}, true, 8},
+	}
+	var hits, misses uint64
+	for i, test := range tests {
+		got, err := c.Derive(test.parent, test.path...)
+		if err != nil {
+			t.Fatalf("#%d: Derive: unexpected error: %v", i, err)
+		}
+		want := deriveUncached(t, test.parent, test.path...)
+		if got.String() != want.String() {
+			t.Fatalf("#%d: Derive: got %s, want %s", i, got, want)
+		}
+
+		if test.hit {
+			hits++
+		} else {
+			misses++
+		}
+		stats := c.Stats()
+		if stats.Hits != hits || stats.Misses != misses ||
+			stats.Entries != test.entries {
+			t.Fatalf("#%d: Stats: got %+v, want %d hits, %d misses, "+
+				"%d entries", i, stats, hits, misses, test.entries)
+		}
+
+		// Zeroing a returned key must not affect the cache.
+		got.Zero()
+	}
+
+	if _, err := c.Derive(pub, HardenedKeyStart); err != ErrDeriveHardFromPublic {
+		t.Fatalf("Derive: got error %v, want %v", err,
+			ErrDeriveHardFromPublic)
+	}
+	if got, _ := c.Derive(master); got != master {
+		t.Fatal("Derive: empty path did not return the parent")
+	}
+
+	c.Purge()
+	if stats := c.Stats(); stats.Entries != 0 || stats.Evictions != 0 {
+		t.Fatalf("Purge: got %+v, want no entries or evictions", stats)
+	}
+}
+
+// TestDerivationCacheEviction ensures the least recently used entries are
+// evicted to stay within the maximum number of entries and the memory budget.
+func TestDerivationCacheEviction(t *testing.T) {
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	master, err := NewMaster(seed, &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("NewMaster: unexpected error: %v", err)
+	}
+
+	c := NewDerivationCache(3)
+	for i := uint32(0); i < 5; i++ {
+		c.Derive(master, i)
+	}
+	if stats := c.Stats(); stats.Entries != 3 || stats.Evictions != 2 {
+		t.Fatalf("Stats: got %+v, want 3 entries and 2 evictions", stats)
+	}
+	c.Derive(master, 4)
+	c.Derive(master, 0)
+	if stats := c.Stats(); stats.Hits != 1 || stats.Misses != 6 {
+		t.Fatalf("Stats: got %+v, want 1 hit and 6 misses", stats)
+	}
+
+	m := membudget.NewManager(0)
+	c = NewDerivationCache(0, WithMemoryBudget(m, "hdkeychain"))
+	for i := uint32(0); i < 10; i++ {
+		c.Derive(master, i)
+	}
+	perEntry := m.Used() / 10
+	if perEntry <= 0 {
+		t.Fatalf("Used: got %d after adding entries, want more than 0",
+			m.Used())
+	}
+
+	m.SetLimit(4 * perEntry)
+	stats := c.Stats()
+	if stats.Entries != 4 || stats.Evictions != 6 {
+		t.Fatalf("Stats: got %+v, want 4 entries and 6 evictions", stats)
+	}
+	if m.Used() != 4*perEntry {
+		t.Fatalf("Used: got %d, want %d", m.Used(), 4*perEntry)
+	}
+
+	// The most recently used entries must have been kept.
+	c.Derive(master, 9)
+	if got := c.Stats().Hits; got != 1 {
+		t.Fatalf("Stats: got %d hits, want 1", got)
+	}
+
+	c.Purge()
+	if m.Used() != 0 {
+		t.Fatalf("Used: got %d after Purge, want 0", m.Used())
+	}
+}
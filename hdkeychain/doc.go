@@ -46,6 +46,24 @@ the HardenedKeyStart constant + the hardened key number as the index to the
 Child function.  This provides the ability to cascade the keys into a tree and
 hence generate the hierarchical deterministic key chains.
 
+Bulk Derivation
+
+Services which provision large numbers of addresses, such as the deposit
+addresses of an exchange, can use a BulkDeriver to derive consecutive
+non-hardened children of a single extended key.  It avoids the per child
+overhead of Child and spreads the work over multiple goroutines.  The
+WithWorkers and WithBatchSize options tune it for the host.  The DeriveRange
+method of an extended key is a shorthand for deriving a single range this way.
+
+Derivation Cache
+
+Services which derive the same paths repeatedly can use a DerivationCache.
+It keeps the most recently derived keys along with every prefix of their
+paths, so repeated derivations and derivations of siblings skip the elliptic
+curve operations already done.  Its Stats method reports the hit, miss, and
+eviction counts, and the WithMemoryBudget option makes its entries count
+against a shared membudget.Manager.
+
 Normal vs Hardened Child Extended Keys
 
 A private extended key can be used to derive both hardened and non-hardened
@@ -80,5 +98,24 @@ Extended keys are much like normal Bitcoin addresses in that they have version
 bytes which tie them to a specific network.  The SetNet and IsForNet functions
 are provided to set and determinine which network an extended key is associated
 with.
+
+Key Purposes
+
+Per SLIP-0132, some wallets use alternate version bytes to signal the kind of
+addresses to derive from an extended key, such as zpub for native
+pay-to-witness-pubkey-hash addresses.  Keys with these versions are parsed and
+serialized like any other, and the Purpose and WithPurpose methods report and
+change the purpose of a key.  The versions of the main and test networks are
+built in, and RegisterKeyVersions adds those of other networks.
+
+Key Origins
+
+Hardware wallets identify the keys they are asked to sign with by their key
+origin: the fingerprint of the master key and the derivation path below it.
+The DerivationPath and KeyOrigin types hold them, parse and print them in the
+usual "m/44'/0'/0'/0/1" and "d34db33f/44'/0'/0'" notations, and serialize key
+origins in their binary PSBT form, so paths need not be passed around as
+strings.  The Fingerprint and DerivePath methods of an extended key connect
+the two.
 */
 package hdkeychain
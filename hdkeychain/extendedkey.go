@@ -11,8 +11,8 @@ package hdkeychain
 import (
 	"bytes"
 	"crypto/hmac"
-	"crypto/rand"
 	"crypto/sha512"
+	"crypto/subtle"
 	"encoding/binary"
 	"errors"
 	"fmt"
@@ -23,6 +23,8 @@ import (
 	"github.com/zeusyf/btcd/chaincfg/chainhash"
 	"github.com/zeusyf/btcutil"
 	"github.com/zeusyf/btcutil/base58"
+	"github.com/zeusyf/btcutil/entropy"
+	"github.com/zeusyf/btcutil/securemem"
 )
 
 const (
@@ -116,6 +118,10 @@ type ExtendedKey struct {
 	parentFP  []byte
 	childNum  uint32
 	version   []byte
+
+	// secret is the buffer holding the private key once Lock moved it
+	// there, or nil.
+	secret *securemem.Buffer
 }
 
 // NewExtendedKey returns a new instance of an extended key with the given
@@ -187,6 +193,18 @@ func (k *ExtendedKey) ParentFingerprint() uint32 {
 	return binary.BigEndian.Uint32(k.parentFP)
 }
 
+// ChainCode returns the chain code of the extended key, which together with
+// the key derives its children.
+func (k *ExtendedKey) ChainCode() []byte {
+	return append([]byte(nil), k.chainCode...)
+}
+
+// ChildIndex returns the index at which the extended key was derived from its
+// parent.  Hardened keys have indexes of at least HardenedKeyStart.
+func (k *ExtendedKey) ChildIndex() uint32 {
+	return k.childNum
+}
+
 // Child returns a derived child extended key at the given index.  When this
 // extended key is a private extended key (as determined by the IsPrivate
 // function), a private extended key will be derived.  Otherwise, the derived
@@ -298,6 +316,8 @@ func (k *ExtendedKey) Child(i uint32) (*ExtendedKey, error) {
 		ilNum.Mod(ilNum, btcec.S256().N)
 		childKey = ilNum.Bytes()
 		isPrivate = true
+		zeroBigInt(keyNum)
+		zeroBigInt(ilNum)
 	} else {
 		// Case #3.
 		// Calculate the corresponding intermediate public key for
@@ -327,8 +347,12 @@ func (k *ExtendedKey) Child(i uint32) (*ExtendedKey, error) {
 	// The fingerprint of the parent for the derived child is the first 4
 	// bytes of the RIPEMD160(SHA256(parentPubKey)).
 	parentFP := btcutil.Hash160(k.pubKeyBytes())[:4]
-	return NewExtendedKey(k.version, childKey, childChainCode, parentFP,
-		k.depth+1, i, isPrivate), nil
+	child := NewExtendedKey(k.version, childKey, childChainCode, parentFP,
+		k.depth+1, i, isPrivate)
+	if k.secret != nil {
+		child.Lock()
+	}
+	return child, nil
 }
 
 // Neuter returns a new extended public key from this extended private key.  The
@@ -345,10 +369,18 @@ func (k *ExtendedKey) Neuter() (*ExtendedKey, error) {
 		return k, nil
 	}
 
-	// Get the associated public extended key version bytes.
-	version, err := chaincfg.HDPrivateKeyToPublicKeyID(k.version)
-	if err != nil {
-		return nil, err
+	// Get the associated public extended key version bytes.  Versions
+	// registered for other purposes, such as SLIP-0132 zprv, map to the
+	// public version registered with them.
+	var version []byte
+	if v, ok := LookupKeyVersions(k.version); ok {
+		version = v.Public[:]
+	} else {
+		var err error
+		version, err = chaincfg.HDPrivateKeyToPublicKeyID(k.version)
+		if err != nil {
+			return nil, err
+		}
 	}
 //	version := []byte{}
 
@@ -395,12 +427,10 @@ func paddedAppend(size uint, dst, src []byte) []byte {
 	return append(dst, src...)
 }
 
-// String returns the extended key as a human-readable base58-encoded string.
-func (k *ExtendedKey) String() string {
-	if len(k.key) == 0 {
-		return "zeroed extended key"
-	}
-
+// serialize returns the serialized extended key without its checksum, with
+// room for the checksum to be appended.  The bytes of private keys must be
+// zeroed once used.
+func (k *ExtendedKey) serialize() []byte {
 	var childNumBytes [4]byte
 	binary.BigEndian.PutUint32(childNumBytes[:], k.childNum)
 
@@ -419,15 +449,48 @@ func (k *ExtendedKey) String() string {
 	} else {
 		serializedBytes = append(serializedBytes, k.pubKeyBytes()...)
 	}
+	return serializedBytes
+}
+
+// String returns the extended key as a human-readable base58-encoded string.
+func (k *ExtendedKey) String() string {
+	if len(k.key) == 0 {
+		return "zeroed extended key"
+	}
 
+	serializedBytes := k.serialize()
 	checkSum := chainhash.DoubleHashB(serializedBytes)[:4]
 	serializedBytes = append(serializedBytes, checkSum...)
-	return base58.Encode(serializedBytes)
+	str := base58.Encode(serializedBytes)
+
+	// Clear the copy of the private key in the serialized bytes.
+	zero(serializedBytes)
+	return str
+}
+
+// Equal returns whether the extended keys are the same key, with the same
+// version, position in the hierarchy and chain code.  The keys are compared
+// in constant time, so the comparison does not leak how much of private keys
+// and chain codes match.  Zeroed keys are only equal to zeroed keys.
+func (k *ExtendedKey) Equal(other *ExtendedKey) bool {
+	if len(k.key) == 0 || len(other.key) == 0 {
+		return len(k.key) == len(other.key)
+	}
+	a, b := k.serialize(), other.serialize()
+	equal := subtle.ConstantTimeCompare(a, b) == 1
+	zero(a)
+	zero(b)
+	return equal
 }
 
 // IsForNet returns whether or not the extended key is associated with the
-// passed bitcoin network.
+// passed bitcoin network, either by the version bytes of the network
+// parameters or by versions registered for the network with
+// RegisterKeyVersions.
 func (k *ExtendedKey) IsForNet(net *chaincfg.Params) bool {
+	if v, ok := LookupKeyVersions(k.version); ok {
+		return v.Net == net
+	}
 	return bytes.Equal(k.version, net.HDPrivateKeyID[:]) ||
 		bytes.Equal(k.version, net.HDPublicKeyID[:])
 }
@@ -451,6 +514,33 @@ func zero(b []byte) {
 	}
 }
 
+// zeroBigInt clears the words of the passed big integer, which holds a copy of
+// private key material, and sets it to zero.
+func zeroBigInt(n *big.Int) {
+	words := n.Bits()
+	for i := range words {
+		words[i] = 0
+	}
+	n.SetInt64(0)
+}
+
+// Lock moves the private key of an extended private key into a
+// securemem.Buffer, locked into physical memory where the platform supports
+// it, so it is never written to swap, and returns whether it is locked.  The
+// copy of the key on the heap is zeroed.  Children derived from a locked key
+// are locked too, and Zero destroys the buffer.  Extended public keys hold no
+// secret and are left as they are.
+func (k *ExtendedKey) Lock() bool {
+	if !k.isPrivate {
+		return false
+	}
+	if k.secret == nil {
+		k.secret = securemem.NewFromBytes(k.key)
+		k.key = k.secret.Bytes()
+	}
+	return k.secret.Locked()
+}
+
 // Zero manually clears all fields and bytes in the extended key.  This can be
 // used to explicitly clear key material from memory for enhanced security
 // against memory scraping.  This function only clears this particular key and
@@ -460,6 +550,10 @@ func (k *ExtendedKey) Zero() {
 	zero(k.pubKey)
 	zero(k.chainCode)
 	zero(k.parentFP)
+	if k.secret != nil {
+		k.secret.Destroy()
+		k.secret = nil
+	}
 	k.version = nil
 	k.key = nil
 	k.depth = 0
@@ -495,7 +589,11 @@ func NewMaster(seed []byte, net *chaincfg.Params) (*ExtendedKey, error) {
 
 	// Ensure the key in usable.
 	secretKeyNum := new(big.Int).SetBytes(secretKey)
-	if secretKeyNum.Cmp(btcec.S256().N) >= 0 || secretKeyNum.Sign() == 0 {
+	unusable := secretKeyNum.Cmp(btcec.S256().N) >= 0 ||
+		secretKeyNum.Sign() == 0
+	zeroBigInt(secretKeyNum)
+	if unusable {
+		zero(lr)
 		return nil, ErrUnusableSeed
 	}
 
@@ -522,7 +620,8 @@ func NewKeyFromString(key string) (*ExtendedKey, error) {
 	payload := decoded[:len(decoded)-4]
 	checkSum := decoded[len(decoded)-4:]
 	expectedCheckSum := chainhash.DoubleHashB(payload)[:4]
-	if !bytes.Equal(checkSum, expectedCheckSum) {
+	if subtle.ConstantTimeCompare(checkSum, expectedCheckSum) != 1 {
+		zero(decoded)
 		return nil, ErrBadChecksum
 	}
 
@@ -542,7 +641,10 @@ func NewKeyFromString(key string) (*ExtendedKey, error) {
 		// of the order of the secp256k1 curve and not be 0.
 		keyData = keyData[1:]
 		keyNum := new(big.Int).SetBytes(keyData)
-		if keyNum.Cmp(btcec.S256().N) >= 0 || keyNum.Sign() == 0 {
+		unusable := keyNum.Cmp(btcec.S256().N) >= 0 || keyNum.Sign() == 0
+		zeroBigInt(keyNum)
+		if unusable {
+			zero(decoded)
 			return nil, ErrUnusableSeed
 		}
 	} else {
@@ -564,6 +666,8 @@ func NewKeyFromString(key string) (*ExtendedKey, error) {
 // The length is in bytes and it must be between 16 and 64 (128 to 512 bits).
 // The recommended length is 32 (256 bits) as defined by the RecommendedSeedLen
 // constant.
+//
+// The seed is read from the default entropy source, see the entropy package.
 func GenerateSeed(length uint8) ([]byte, error) {
 	// Per [BIP32], the seed must be in range [MinSeedBytes, MaxSeedBytes].
 	if length < MinSeedBytes || length > MaxSeedBytes {
@@ -571,7 +675,7 @@ func GenerateSeed(length uint8) ([]byte, error) {
 	}
 
 	buf := make([]byte, length)
-	_, err := rand.Read(buf)
+	err := entropy.Read(nil, buf)
 	if err != nil {
 		return nil, err
 	}
@@ -562,6 +562,8 @@ func TestExtendedKeyAPI(t *testing.T) {
 		extKey     string
 		isPrivate  bool
 		parentFP   uint32
+		childIndex uint32
+		chainCode  string
 		privKey    string
 		privKeyErr error
 		pubKey     string
@@ -572,6 +574,7 @@ func TestExtendedKeyAPI(t *testing.T) {
 			extKey:    "xprv9s21ZrQH143K3QTDL4LXw2F7HEK3wJUD2nW2nRk4stbPy6cq3jPPqjiChkVvvNKmPGJxWUtg6LnF5kejMRNNU3TGtRBeJgk33yuGBxrMPHi",
 			isPrivate: true,
 			parentFP:  0,
+			chainCode: "873dff81c02f525623fd1fe5167eac3a55a049de3d314bb42ee227ffed37d508",
 			privKey:   "e8f32e723decf4051aefac8e2c93c9c5b214313817cdb01a1494b917c8436b35",
 			pubKey:    "0339a36013301597daef41fbe593a02cc513d0b55527ec2df1050e2e8ff49c85c2",
 			address:   "15mKKb2eos1hWa6tisdPwwDC1a5J1y9nma",
@@ -581,6 +584,8 @@ func TestExtendedKeyAPI(t *testing.T) {
 			extKey:     "xpub6D4BDPcP2GT577Vvch3R8wDkScZWzQzMMUm3PWbmWvVJrZwQY4VUNgqFJPMM3No2dFDFGTsxxpG5uJh7n7epu4trkrX7x7DogT5Uv6fcLW5",
 			isPrivate:  false,
 			parentFP:   3203769081,
+			childIndex: HardenedKeyStart + 2,
+			chainCode:  "04466b9cc8e161e966409ca52986c584f07e9dc81f735db683c3ff6ec7b1503f",
 			privKeyErr: ErrNotPrivExtKey,
 			pubKey:     "0357bfe1e341d01c69fe5654309956cbea516822fba8a601743a012a7896ee8dc2",
 			address:    "1NjxqbA9aZWnh17q1UW3rB4EPu79wDXj7x",
@@ -610,6 +615,21 @@ func TestExtendedKeyAPI(t *testing.T) {
 			continue
 		}
 
+		if key.ChildIndex() != test.childIndex {
+			t.Errorf("ChildIndex #%d (%s): mismatched child index "+
+				"-- want %d, got %d", i, test.name,
+				test.childIndex, key.ChildIndex())
+			continue
+		}
+
+		chainCode := hex.EncodeToString(key.ChainCode())
+		if chainCode != test.chainCode {
+			t.Errorf("ChainCode #%d (%s): mismatched chain code "+
+				"-- want %s, got %s", i, test.name,
+				test.chainCode, chainCode)
+			continue
+		}
+
 		serializedKey := key.String()
 		if serializedKey != test.extKey {
 			t.Errorf("String #%d (%s): mismatched serialized key "+
@@ -1068,3 +1088,91 @@ func TestMaximumDepth(t *testing.T) {
 		t.Fatal("Child: deriving 256th key should not succeed")
 	}
 }
+
+// TestEqual ensures extended keys are equal only to the same key at the same
+// position of the hierarchy.
+func TestEqual(t *testing.T) {
+	net := &chaincfg.MainNetParams
+	master, err := NewMaster([]byte(`abcd1234abcd1234abcd1234abcd1234`), net)
+	if err != nil {
+		t.Fatalf("NewMaster: unexpected error: %v", err)
+	}
+	same, err := NewKeyFromString(master.String())
+	if err != nil {
+		t.Fatalf("NewKeyFromString: unexpected error: %v", err)
+	}
+	child, err := master.Child(0)
+	if err != nil {
+		t.Fatalf("Child: unexpected error: %v", err)
+	}
+	pub, err := master.Neuter()
+	if err != nil {
+		t.Fatalf("Neuter: unexpected error: %v", err)
+	}
+	other, err := NewMaster([]byte(`abcd1234abcd1234abcd1234abcd1235`), net)
+	if err != nil {
+		t.Fatalf("NewMaster: unexpected error: %v", err)
+	}
+
+	if !master.Equal(same) || !pub.Equal(pub) {
+		t.Errorf("Equal: identical keys are not equal")
+	}
+	for _, k := range []*ExtendedKey{child, pub, other} {
+		if master.Equal(k) || k.Equal(master) {
+			t.Errorf("Equal: %v is equal to the master key", k)
+		}
+	}
+
+	same.Zero()
+	if master.Equal(same) || same.Equal(master) {
+		t.Errorf("Equal: zeroed key is equal to the master key")
+	}
+	child.Zero()
+	if !same.Equal(child) {
+		t.Errorf("Equal: zeroed keys are not equal")
+	}
+}
+
+// TestLock ensures locking an extended private key leaves it and the keys
+// derived from it unchanged, locks its children, and Zero clears it.
+func TestLock(t *testing.T) {
+	net := &chaincfg.MainNetParams
+	seed := []byte(`abcd1234abcd1234abcd1234abcd1234`)
+	master, err := NewMaster(seed, net)
+	if err != nil {
+		t.Fatalf("NewMaster: unexpected error: %v", err)
+	}
+	want := master.String()
+	wantChild, _ := master.Child(HardenedKeyStart)
+
+	heapKey := master.key
+	locked := master.Lock()
+	t.Logf("key locked: %v", locked)
+	if !bytes.Equal(heapKey, make([]byte, len(heapKey))) {
+		t.Errorf("Lock: private key of the heap not cleared")
+	}
+	if master.String() != want {
+		t.Errorf("Lock: got %s, want %s", master, want)
+	}
+	child, err := master.Child(HardenedKeyStart)
+	if err != nil {
+		t.Fatalf("Child: unexpected error: %v", err)
+	}
+	if child.secret == nil || !child.Equal(wantChild) {
+		t.Errorf("Child: got unlocked or other child %v", child)
+	}
+
+	pub, _ := master.Neuter()
+	if pub.Lock() || pub.secret != nil {
+		t.Errorf("Lock: locked an extended public key")
+	}
+
+	master.Zero()
+	if master.secret != nil || master.key != nil {
+		t.Errorf("Zero: locked key not cleared")
+	}
+	if child.String() != wantChild.String() {
+		t.Errorf("Zero: cleared child of zeroed key")
+	}
+	child.Zero()
+}
@@ -0,0 +1,206 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package hdkeychain
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/audit"
+)
+
+var (
+	// ErrInvalidPath describes an error where a derivation path is not of
+	// the form "m/44'/0'/0'/0/1" or contains an index out of range.
+	ErrInvalidPath = errors.New("invalid derivation path")
+
+	// ErrInvalidKeyOrigin describes an error where a key origin is not a
+	// fingerprint of 8 hex digits optionally followed by a derivation
+	// path, or a serialized key origin is not a whole number of indexes.
+	ErrInvalidKeyOrigin = errors.New("invalid key origin")
+)
+
+// DerivationPath is the sequence of child indexes leading from an extended key
+// to one of its descendants.  Hardened indexes are HardenedKeyStart plus the
+// hardened key number.
+type DerivationPath []uint32
+
+// ParseDerivationPath parses a derivation path in the usual notation, such as
+// "m/44'/0'/0'/0/1".  The leading "m" is optional, and hardened indexes may be
+// marked by an apostrophe, "h" or "H".  An empty string or "m" alone is the
+// empty path.
+func ParseDerivationPath(s string) (DerivationPath, error) {
+	if s == "" || s == "m" {
+		return DerivationPath{}, nil
+	}
+	if strings.HasPrefix(s, "m/") {
+		s = s[2:]
+	} else {
+		s = strings.TrimPrefix(s, "/")
+	}
+
+	elems := strings.Split(s, "/")
+	path := make(DerivationPath, len(elems))
+	for i, elem := range elems {
+		var offset uint32
+		if n := len(elem); n > 0 && strings.IndexByte("'hH", elem[n-1]) >= 0 {
+			elem, offset = elem[:n-1], HardenedKeyStart
+		}
+
+		// Reject signs and empty elements, which ParseUint would
+		// otherwise accept or report less clearly.
+		if elem == "" || elem[0] < '0' || elem[0] > '9' {
+			return nil, ErrInvalidPath
+		}
+		index, err := strconv.ParseUint(elem, 10, 32)
+		if err != nil || index >= HardenedKeyStart {
+			return nil, ErrInvalidPath
+		}
+		path[i] = uint32(index) + offset
+	}
+	return path, nil
+}
+
+// writeIndexes appends the indexes of the path to b, each preceded by a
+// slash, with hardened indexes marked by an apostrophe.
+func (p DerivationPath) writeIndexes(b *strings.Builder) {
+	for _, index := range p {
+		if index >= HardenedKeyStart {
+			fmt.Fprintf(b, "/%d'", index-HardenedKeyStart)
+		} else {
+			fmt.Fprintf(b, "/%d", index)
+		}
+	}
+}
+
+// String returns the path in the usual notation with hardened indexes marked
+// by an apostrophe, such as "m/44'/0'/0'/0/1".
+func (p DerivationPath) String() string {
+	var b strings.Builder
+	b.WriteByte('m')
+	p.writeIndexes(&b)
+	return b.String()
+}
+
+// Child returns a new path extending p by index.  p itself is not modified,
+// so paths sharing a prefix can be built from the same parent path.
+func (p DerivationPath) Child(index uint32) DerivationPath {
+	child := make(DerivationPath, len(p), len(p)+1)
+	copy(child, p)
+	return append(child, index)
+}
+
+// KeyOrigin identifies a key by the fingerprint of the extended key it was
+// derived from, usually the master key, and the derivation path below that
+// key.  It is the key origin information hardware wallets expect alongside
+// the public keys of PSBT inputs and outputs and output descriptors.
+type KeyOrigin struct {
+	// Fingerprint is the fingerprint of the extended key the path starts
+	// at, as returned by its Fingerprint method.
+	Fingerprint uint32
+
+	// Path is the derivation path from that key to the key identified.
+	Path DerivationPath
+}
+
+// ParseKeyOrigin parses a key origin in the notation of output descriptors,
+// the fingerprint as 8 hex digits followed by the derivation path without the
+// leading "m", such as "d34db33f/44'/0'/0'".  The origin may be enclosed in
+// square brackets, as it is within a descriptor.
+func ParseKeyOrigin(s string) (KeyOrigin, error) {
+	if strings.HasPrefix(s, "[") && strings.HasSuffix(s, "]") {
+		s = s[1 : len(s)-1]
+	}
+	fp, rest := s, ""
+	if i := strings.IndexByte(s, '/'); i >= 0 {
+		fp, rest = s[:i], s[i:]
+	}
+
+	if len(fp) != 8 {
+		return KeyOrigin{}, ErrInvalidKeyOrigin
+	}
+	fingerprint, err := strconv.ParseUint(fp, 16, 32)
+	if err != nil {
+		return KeyOrigin{}, ErrInvalidKeyOrigin
+	}
+	path, err := ParseDerivationPath(rest)
+	if err != nil {
+		return KeyOrigin{}, err
+	}
+	return KeyOrigin{Fingerprint: uint32(fingerprint), Path: path}, nil
+}
+
+// String returns the origin in the notation of output descriptors without the
+// enclosing brackets, such as "d34db33f/44'/0'/0'".  It matches the notation
+// of audit records.
+func (o KeyOrigin) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%08x", o.Fingerprint)
+	o.Path.writeIndexes(&b)
+	return b.String()
+}
+
+// Serialize returns the origin in the binary form of PSBT key origin fields:
+// the 4 fingerprint bytes followed by each index as a 32-bit little endian
+// integer.
+func (o KeyOrigin) Serialize() []byte {
+	b := make([]byte, 4+4*len(o.Path))
+	binary.BigEndian.PutUint32(b, o.Fingerprint)
+	for i, index := range o.Path {
+		binary.LittleEndian.PutUint32(b[4+4*i:], index)
+	}
+	return b
+}
+
+// DeserializeKeyOrigin parses a key origin in the binary form returned by
+// Serialize.
+func DeserializeKeyOrigin(b []byte) (KeyOrigin, error) {
+	if len(b) < 4 || len(b)%4 != 0 {
+		return KeyOrigin{}, ErrInvalidKeyOrigin
+	}
+	o := KeyOrigin{
+		Fingerprint: binary.BigEndian.Uint32(b),
+		Path:        make(DerivationPath, len(b)/4-1),
+	}
+	for i := range o.Path {
+		o.Path[i] = binary.LittleEndian.Uint32(b[4+4*i:])
+	}
+	return o, nil
+}
+
+// AuditOrigin returns the origin as recorded in audit events.
+func (o KeyOrigin) AuditOrigin() audit.KeyOrigin {
+	return audit.KeyOrigin{
+		Fingerprint: o.Fingerprint,
+		Path:        append([]uint32(nil), o.Path...),
+	}
+}
+
+// Fingerprint returns the fingerprint of the extended key, the first 4 bytes
+// of the Hash160 of its public key as a big endian integer.  It is the parent
+// fingerprint of the children of the key, and identifies master keys in key
+// origins.
+func (k *ExtendedKey) Fingerprint() uint32 {
+	id := btcutil.Hash160(k.pubKeyBytes())
+	return binary.BigEndian.Uint32(id[:4])
+}
+
+// DerivePath derives the key at path below k, as repeated calls to Child
+// would.  The empty path returns k itself.
+func (k *ExtendedKey) DerivePath(path DerivationPath) (*ExtendedKey, error) {
+	child := k
+	for _, index := range path {
+		var err error
+		child, err = child.Child(index)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return child, nil
+}
@@ -0,0 +1,139 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package hdkeychain
+
+import (
+	"bytes"
+	"encoding/hex"
+	"reflect"
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg"
+)
+
+// TestParseDerivationPath ensures derivation paths are parsed in all accepted
+// notations and serialized in the canonical one.
+func TestParseDerivationPath(t *testing.T) {
+	const h = HardenedKeyStart
+
+	tests := []struct {
+		in   string
+		want DerivationPath
+		str  string
+		err  error
+	}{
+		{"m", DerivationPath{}, "m", nil},
+		{"", DerivationPath{}, "m", nil},
+		{"m/44'/0'/0'/0/1", DerivationPath{h + 44, h, h, 0, 1}, "m/44'/0'/0'/0/1", nil},
+		{"m/84h/1H/2147483647", DerivationPath{h + 84, h + 1, h - 1}, "m/84'/1'/2147483647", nil},
+		{"44'/0", DerivationPath{h + 44, 0}, "m/44'/0", nil},
+		{"m/2147483648", nil, "", ErrInvalidPath},
+		{"m/2147483648'", nil, "", ErrInvalidPath},
+		{"m/-1", nil, "", ErrInvalidPath},
+		{"m/+1", nil, "", ErrInvalidPath},
+		{"m//1", nil, "", ErrInvalidPath},
+		{"m/1/", nil, "", ErrInvalidPath},
+		{"m/'", nil, "", ErrInvalidPath},
+		{"m44", nil, "", ErrInvalidPath},
+		{"m/x", nil, "", ErrInvalidPath},
+	}
+
+	for _, test := range tests {
+		path, err := ParseDerivationPath(test.in)
+		if err != test.err {
+			t.Errorf("ParseDerivationPath(%q): got error %v, want %v",
+				test.in, err, test.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if !reflect.DeepEqual(path, test.want) {
+			t.Errorf("ParseDerivationPath(%q): got %v, want %v", test.in,
+				[]uint32(path), []uint32(test.want))
+		}
+		if got := path.String(); got != test.str {
+			t.Errorf("String(%q): got %q, want %q", test.in, got, test.str)
+		}
+	}
+
+	// Child must not share the backing array of its parent.
+	parent := make(DerivationPath, 1, 4)
+	a, b := parent.Child(1), parent.Child(2)
+	if a[1] != 1 || b[1] != 2 || len(parent) != 1 {
+		t.Errorf("Child: paths share storage: %v %v", a, b)
+	}
+}
+
+// TestKeyOrigin ensures key origins round trip through their text and binary
+// forms and match the fingerprint and derivation of the key they identify,
+// using the first BIP0032 test vector.
+func TestKeyOrigin(t *testing.T) {
+	origin, err := ParseKeyOrigin("[3442193e/0'/1]")
+	if err != nil {
+		t.Fatalf("ParseKeyOrigin: unexpected error: %v", err)
+	}
+	want := KeyOrigin{
+		Fingerprint: 0x3442193e,
+		Path:        DerivationPath{HardenedKeyStart, 1},
+	}
+	if !reflect.DeepEqual(origin, want) {
+		t.Fatalf("ParseKeyOrigin: got %+v, want %+v", origin, want)
+	}
+	if got := origin.String(); got != "3442193e/0'/1" {
+		t.Errorf("String: got %q", got)
+	}
+	if got := origin.AuditOrigin().String(); got != origin.String() {
+		t.Errorf("AuditOrigin: got %q, want %q", got, origin.String())
+	}
+
+	serialized := origin.Serialize()
+	wantSerialized, _ := hex.DecodeString("3442193e0000008001000000")
+	if !bytes.Equal(serialized, wantSerialized) {
+		t.Errorf("Serialize: got %x, want %x", serialized, wantSerialized)
+	}
+	deserialized, err := DeserializeKeyOrigin(serialized)
+	if err != nil {
+		t.Fatalf("DeserializeKeyOrigin: unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(deserialized, origin) {
+		t.Errorf("DeserializeKeyOrigin: got %+v, want %+v", deserialized,
+			origin)
+	}
+
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	master, err := NewMaster(seed, &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("NewMaster: unexpected error: %v", err)
+	}
+	if fp := master.Fingerprint(); fp != origin.Fingerprint {
+		t.Errorf("Fingerprint: got %08x, want %08x", fp, origin.Fingerprint)
+	}
+	child, err := master.DerivePath(origin.Path)
+	if err != nil {
+		t.Fatalf("DerivePath: unexpected error: %v", err)
+	}
+	const wantChild = "xpub6ASuArnXKPbfEwhqN6e3mwBcDTgzisQN1wXN9BJcM47sSikHjJf3UFHKkNAWbWMiGj7Wf5uMash7SyYq527Hqck2AxYysAA7xmALppuCkwQ"
+	pub, err := child.Neuter()
+	if err != nil {
+		t.Fatalf("Neuter: unexpected error: %v", err)
+	}
+	if got := pub.String(); got != wantChild {
+		t.Errorf("DerivePath: got %v, want %v", got, wantChild)
+	}
+
+	invalid := []string{"", "3442193", "3442193e0", "3442193g/1", "[3442193e", "3442193e/x"}
+	for _, s := range invalid {
+		if _, err := ParseKeyOrigin(s); err == nil {
+			t.Errorf("ParseKeyOrigin(%q): unexpected success", s)
+		}
+	}
+	for _, n := range []int{0, 3, 6} {
+		if _, err := DeserializeKeyOrigin(make([]byte, n)); err != ErrInvalidKeyOrigin {
+			t.Errorf("DeserializeKeyOrigin(%d bytes): got %v, want %v", n,
+				err, ErrInvalidKeyOrigin)
+		}
+	}
+}
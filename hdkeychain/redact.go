@@ -0,0 +1,27 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package hdkeychain
+
+import (
+	"log/slog"
+
+	"github.com/zeusyf/btcutil"
+)
+
+// Redacted returns the base58 string of the extended key redacted as by
+// btcutil.Redact.  Private keys must not be logged, and public ones link
+// every address of their account, so logs should only ever hold this form.
+func (k *ExtendedKey) Redacted() string {
+	if len(k.key) == 0 {
+		return k.String()
+	}
+	return btcutil.Redact(k.String())
+}
+
+// LogValue implements the slog.LogValuer interface, logging the key redacted
+// as by Redacted.
+func (k *ExtendedKey) LogValue() slog.Value {
+	return slog.StringValue(k.Redacted())
+}
@@ -0,0 +1,41 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package hdkeychain_test
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/hdkeychain"
+)
+
+// TestRedacted ensures extended keys are logged redacted.
+func TestRedacted(t *testing.T) {
+	seed := bytes.Repeat([]byte{0x42}, hdkeychain.RecommendedSeedLen)
+	master, err := hdkeychain.NewMaster(seed, &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("NewMaster: unexpected error: %v", err)
+	}
+	full := master.String()
+	if got, want := master.Redacted(), btcutil.Redact(full); got != want {
+		t.Errorf("Redacted: got %q, want %q", got, want)
+	}
+
+	var buf bytes.Buffer
+	slog.New(slog.NewTextHandler(&buf, nil)).Info("account", "key", master)
+	if strings.Contains(buf.String(), full) ||
+		!strings.Contains(buf.String(), master.Redacted()) {
+		t.Errorf("log %q does not hold the key redacted", buf.String())
+	}
+
+	master.Zero()
+	if got := master.Redacted(); got != "zeroed extended key" {
+		t.Errorf("Redacted: got %q for a zeroed key", got)
+	}
+}
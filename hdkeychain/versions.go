@@ -0,0 +1,232 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package hdkeychain
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/zeusyf/btcd/chaincfg"
+)
+
+// KeyPurpose identifies the kind of scripts the addresses derived from an
+// extended key are meant for, as signalled by its version bytes per
+// SLIP-0132.
+type KeyPurpose uint8
+
+const (
+	// PurposeLegacy is for pay-to-pubkey-hash and pay-to-script-hash
+	// addresses.  Its keys use the version bytes of the network
+	// parameters, such as xpub and xprv on the main network.
+	PurposeLegacy KeyPurpose = iota
+
+	// PurposeNestedWitness is for pay-to-witness-pubkey-hash nested in
+	// pay-to-script-hash addresses, ypub and yprv on the main network.
+	PurposeNestedWitness
+
+	// PurposeWitness is for native pay-to-witness-pubkey-hash addresses,
+	// zpub and zprv on the main network.
+	PurposeWitness
+
+	// PurposeNestedWitnessMultiSig is for multisig pay-to-witness-script-
+	// hash nested in pay-to-script-hash addresses, Ypub and Yprv on the
+	// main network.
+	PurposeNestedWitnessMultiSig
+
+	// PurposeWitnessMultiSig is for native multisig pay-to-witness-
+	// script-hash addresses, Zpub and Zprv on the main network.
+	PurposeWitnessMultiSig
+)
+
+// purposeStrings is a map of key purposes back to their constant names for
+// pretty printing.
+var purposeStrings = map[KeyPurpose]string{
+	PurposeLegacy:                "PurposeLegacy",
+	PurposeNestedWitness:         "PurposeNestedWitness",
+	PurposeWitness:               "PurposeWitness",
+	PurposeNestedWitnessMultiSig: "PurposeNestedWitnessMultiSig",
+	PurposeWitnessMultiSig:       "PurposeWitnessMultiSig",
+}
+
+// String returns the KeyPurpose in human-readable form.
+func (p KeyPurpose) String() string {
+	if s, ok := purposeStrings[p]; ok {
+		return s
+	}
+	return fmt.Sprintf("Unknown KeyPurpose (%d)", uint8(p))
+}
+
+var (
+	// ErrDuplicateKeyVersion describes an error where version bytes being
+	// registered are already registered or used by network parameters.
+	ErrDuplicateKeyVersion = errors.New("duplicate extended key version")
+
+	// ErrUnknownKeyVersion describes an error where no version bytes are
+	// known for the requested network and purpose.
+	ErrUnknownKeyVersion = errors.New("unknown extended key version")
+)
+
+// KeyVersions are the version bytes of the private and public extended keys
+// for one purpose on one network.
+type KeyVersions struct {
+	Net     *chaincfg.Params
+	Purpose KeyPurpose
+	Private [4]byte
+	Public  [4]byte
+}
+
+// versionRegistry holds the registered key versions, keyed by both their
+// private and public version bytes.  Like the burn address registry it is
+// copy-on-write: the stored map is never modified, and writers serialize on
+// versionMtx and store a modified copy.
+var (
+	versionMtx      sync.Mutex
+	versionRegistry atomic.Value // map[[4]byte]KeyVersions
+)
+
+// versionSnapshot returns the current, immutable contents of the registry.
+func versionSnapshot() map[[4]byte]KeyVersions {
+	m, _ := versionRegistry.Load().(map[[4]byte]KeyVersions)
+	return m
+}
+
+func init() {
+	// The SLIP-0132 version bytes of the main and test networks.  Legacy
+	// keys use the version bytes of the network parameters and are not
+	// registered.
+	builtin := []struct {
+		net     *chaincfg.Params
+		purpose KeyPurpose
+		private uint32
+		public  uint32
+	}{
+		{&chaincfg.MainNetParams, PurposeNestedWitness, 0x049d7878, 0x049d7cb2},
+		{&chaincfg.MainNetParams, PurposeWitness, 0x04b2430c, 0x04b24746},
+		{&chaincfg.MainNetParams, PurposeNestedWitnessMultiSig, 0x0295b005, 0x0295b43f},
+		{&chaincfg.MainNetParams, PurposeWitnessMultiSig, 0x02aa7a99, 0x02aa7ed3},
+		{&chaincfg.TestNet3Params, PurposeNestedWitness, 0x044a4e28, 0x044a5262},
+		{&chaincfg.TestNet3Params, PurposeWitness, 0x045f18bc, 0x045f1cf6},
+		{&chaincfg.TestNet3Params, PurposeNestedWitnessMultiSig, 0x024285b5, 0x024289ef},
+		{&chaincfg.TestNet3Params, PurposeWitnessMultiSig, 0x02575048, 0x02575483},
+	}
+	for _, b := range builtin {
+		v := KeyVersions{Net: b.net, Purpose: b.purpose}
+		putVersion(v.Private[:], b.private)
+		putVersion(v.Public[:], b.public)
+		if err := RegisterKeyVersions(v); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// putVersion writes a version as big endian bytes.
+func putVersion(b []byte, v uint32) {
+	b[0], b[1], b[2], b[3] = byte(v>>24), byte(v>>16), byte(v>>8), byte(v)
+}
+
+// RegisterKeyVersions registers the version bytes of extended keys for a
+// network and purpose, so keys with those versions can be neutered, matched
+// to their network, and converted to other purposes.  Applications call it to
+// add the versions of networks or purposes which are not built in.
+//
+// ErrDuplicateKeyVersion is returned when either version is already
+// registered or is a version of the network parameters, and
+// ErrUnknownKeyVersion when registering PurposeLegacy, whose versions are
+// those of the network parameters.
+func RegisterKeyVersions(v KeyVersions) error {
+	if v.Purpose == PurposeLegacy {
+		return ErrUnknownKeyVersion
+	}
+	if v.Private == v.Public || v.Private == v.Net.HDPrivateKeyID ||
+		v.Private == v.Net.HDPublicKeyID || v.Public == v.Net.HDPrivateKeyID ||
+		v.Public == v.Net.HDPublicKeyID {
+		return ErrDuplicateKeyVersion
+	}
+
+	versionMtx.Lock()
+	defer versionMtx.Unlock()
+
+	old := versionSnapshot()
+	if _, ok := old[v.Private]; ok {
+		return ErrDuplicateKeyVersion
+	}
+	if _, ok := old[v.Public]; ok {
+		return ErrDuplicateKeyVersion
+	}
+	for _, existing := range old {
+		if existing.Net == v.Net && existing.Purpose == v.Purpose {
+			return ErrDuplicateKeyVersion
+		}
+	}
+
+	m := make(map[[4]byte]KeyVersions, len(old)+2)
+	for version, versions := range old {
+		m[version] = versions
+	}
+	m[v.Private] = v
+	m[v.Public] = v
+	versionRegistry.Store(m)
+	return nil
+}
+
+// LookupKeyVersions returns the registered key versions which include the
+// passed private or public version bytes.
+func LookupKeyVersions(version []byte) (KeyVersions, bool) {
+	if len(version) != 4 {
+		return KeyVersions{}, false
+	}
+	v, ok := versionSnapshot()[[4]byte{version[0], version[1], version[2],
+		version[3]}]
+	return v, ok
+}
+
+// keyVersionsFor returns the key versions for the passed network and purpose.
+func keyVersionsFor(net *chaincfg.Params, purpose KeyPurpose) (KeyVersions, bool) {
+	if purpose == PurposeLegacy {
+		return KeyVersions{
+			Net:     net,
+			Purpose: PurposeLegacy,
+			Private: net.HDPrivateKeyID,
+			Public:  net.HDPublicKeyID,
+		}, true
+	}
+	for _, v := range versionSnapshot() {
+		if v.Net == net && v.Purpose == purpose {
+			return v, true
+		}
+	}
+	return KeyVersions{}, false
+}
+
+// Purpose returns the purpose signalled by the version bytes of the extended
+// key.  Keys with unregistered versions, including those of the network
+// parameters, are legacy keys.
+func (k *ExtendedKey) Purpose() KeyPurpose {
+	if v, ok := LookupKeyVersions(k.version); ok {
+		return v.Purpose
+	}
+	return PurposeLegacy
+}
+
+// WithPurpose returns a copy of the extended key with the version bytes for
+// the passed purpose on the passed network.  ErrUnknownKeyVersion is returned
+// when no versions are registered for them.  Converting a key does not change
+// the keys derived from it, only how it is serialized and which addresses
+// wallets derive with it.
+func (k *ExtendedKey) WithPurpose(net *chaincfg.Params, purpose KeyPurpose) (*ExtendedKey, error) {
+	v, ok := keyVersionsFor(net, purpose)
+	if !ok {
+		return nil, ErrUnknownKeyVersion
+	}
+	version := v.Public
+	if k.isPrivate {
+		version = v.Private
+	}
+	converted := k.clone()
+	converted.version = version[:]
+	return converted, nil
+}
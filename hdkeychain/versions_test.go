@@ -0,0 +1,136 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package hdkeychain
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg"
+)
+
+// TestKeyVersions ensures extended keys with SLIP-0132 versions are parsed,
+// derived, neutered and serialized with their own versions, using the BIP0084
+// test vector.
+func TestKeyVersions(t *testing.T) {
+	const (
+		rootZprv = "zprvAWgYBBk7JR8Gjrh4UJQ2uJdG1r3WNRRfURiABBE3RvMXYSrRJL62XuezvGdPvG6GFBZduosCc1YP5wixPox7zhZLfiUm8aunE96BBa4Kei5"
+		acctZpub = "zpub6rFR7y4Q2AijBEqTUquhVz398htDFrtymD9xYYfG1m4wAcvPhXNfE3EfH1r1ADqtfSdVCToUG868RvUUkgDKf31mGDtKsAYz2oz2AGutZYs"
+	)
+
+	root, err := NewKeyFromString(rootZprv)
+	if err != nil {
+		t.Fatalf("NewKeyFromString: %v", err)
+	}
+	if got := root.Purpose(); got != PurposeWitness {
+		t.Errorf("Purpose: got %v, want %v", got, PurposeWitness)
+	}
+	if !root.IsForNet(&chaincfg.MainNetParams) {
+		t.Error("IsForNet: zprv is not for the main network")
+	}
+	if root.IsForNet(&chaincfg.TestNet3Params) {
+		t.Error("IsForNet: zprv is for the test network")
+	}
+
+	acct := root
+	for _, index := range []uint32{84, 0, 0} {
+		acct, err = acct.Child(HardenedKeyStart + index)
+		if err != nil {
+			t.Fatalf("Child: %v", err)
+		}
+	}
+	pub, err := acct.Neuter()
+	if err != nil {
+		t.Fatalf("Neuter: %v", err)
+	}
+	if got := pub.String(); got != acctZpub {
+		t.Errorf("Neuter: got %s, want %s", got, acctZpub)
+	}
+	if got := pub.Purpose(); got != PurposeWitness {
+		t.Errorf("Purpose of neutered key: got %v, want %v", got, PurposeWitness)
+	}
+
+	// Converting to a legacy key and back must only change the version.
+	xpub, err := pub.WithPurpose(&chaincfg.MainNetParams, PurposeLegacy)
+	if err != nil {
+		t.Fatalf("WithPurpose: %v", err)
+	}
+	if got := xpub.String(); !strings.HasPrefix(got, "xpub") {
+		t.Errorf("WithPurpose: got %s, want an xpub", got)
+	}
+	if xpub.Purpose() != PurposeLegacy {
+		t.Errorf("Purpose: got %v, want %v", xpub.Purpose(), PurposeLegacy)
+	}
+	zpub, err := xpub.WithPurpose(&chaincfg.MainNetParams, PurposeWitness)
+	if err != nil {
+		t.Fatalf("WithPurpose: %v", err)
+	}
+	if got := zpub.String(); got != acctZpub {
+		t.Errorf("WithPurpose: got %s, want %s", got, acctZpub)
+	}
+	if pub.String() != acctZpub {
+		t.Error("WithPurpose modified the original key")
+	}
+
+	yprv, err := acct.WithPurpose(&chaincfg.MainNetParams, PurposeNestedWitness)
+	if err != nil {
+		t.Fatalf("WithPurpose: %v", err)
+	}
+	if got := yprv.String(); !strings.HasPrefix(got, "yprv") {
+		t.Errorf("WithPurpose: got %s, want a yprv", got)
+	}
+}
+
+// TestRegisterKeyVersions ensures invalid and duplicate registrations are
+// rejected.
+func TestRegisterKeyVersions(t *testing.T) {
+	net := &chaincfg.MainNetParams
+	tests := []struct {
+		name string
+		v    KeyVersions
+		err  error
+	}{{
+		name: "legacy",
+		v: KeyVersions{Net: net, Purpose: PurposeLegacy,
+			Private: [4]byte{1, 2, 3, 4}, Public: [4]byte{1, 2, 3, 5}},
+		err: ErrUnknownKeyVersion,
+	}, {
+		name: "network version",
+		v: KeyVersions{Net: net, Purpose: PurposeWitness + 100,
+			Private: net.HDPrivateKeyID, Public: [4]byte{1, 2, 3, 5}},
+		err: ErrDuplicateKeyVersion,
+	}, {
+		name: "registered version",
+		v: KeyVersions{Net: net, Purpose: PurposeWitness + 100,
+			Private: [4]byte{1, 2, 3, 4}, Public: [4]byte{0x04, 0xb2, 0x47, 0x46}},
+		err: ErrDuplicateKeyVersion,
+	}, {
+		name: "registered purpose",
+		v: KeyVersions{Net: net, Purpose: PurposeWitness,
+			Private: [4]byte{1, 2, 3, 4}, Public: [4]byte{1, 2, 3, 5}},
+		err: ErrDuplicateKeyVersion,
+	}, {
+		name: "same versions",
+		v: KeyVersions{Net: net, Purpose: PurposeWitness + 100,
+			Private: [4]byte{1, 2, 3, 4}, Public: [4]byte{1, 2, 3, 4}},
+		err: ErrDuplicateKeyVersion,
+	}}
+	for _, test := range tests {
+		if err := RegisterKeyVersions(test.v); err != test.err {
+			t.Errorf("%s: got error %v, want %v", test.name, err, test.err)
+		}
+	}
+
+	if _, ok := LookupKeyVersions([]byte{1, 2, 3, 4}); ok {
+		t.Error("LookupKeyVersions: rejected versions were registered")
+	}
+	key, err := NewKeyFromString("xpub661MyMwAqRbcFtXgS5sYJABqqG9YLmC4Q1Rdap9gSE8NqtwybGhePY2gZ29ESFjqJoCu1Rupje8YtGqsefD265TMg7usUDFdp6W1EGMcet8")
+	if err != nil {
+		t.Fatalf("NewKeyFromString: %v", err)
+	}
+	if _, err := key.WithPurpose(&chaincfg.RegressionNetParams, PurposeWitness); err != ErrUnknownKeyVersion {
+		t.Errorf("WithPurpose: got error %v, want %v", err, ErrUnknownKeyVersion)
+	}
+}
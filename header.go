@@ -0,0 +1,241 @@
+// Copyright (c) 2013-2016 The btcsuite developers
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package btcutil
+
+import (
+	"errors"
+	"math/big"
+	"sync/atomic"
+	"time"
+
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil/locktime"
+)
+
+var (
+	// ErrTargetOutOfRange describes an error where a proof of work target
+	// is not positive or exceeds the proof of work limit.
+	ErrTargetOutOfRange = errors.New("proof of work target out of range")
+
+	// ErrHighHash describes an error where a block hash is above the
+	// target it must meet.
+	ErrHighHash = errors.New("block hash is higher than the target")
+
+	// bigOne is 1 represented as a big.Int.  It is defined here to avoid
+	// the overhead of creating it multiple times.
+	bigOne = big.NewInt(1)
+
+	// oneLsh256 is 1 shifted left 256 bits.  It is defined here to avoid
+	// the overhead of creating it multiple times.
+	oneLsh256 = new(big.Int).Lsh(bigOne, 256)
+)
+
+// HashToBig converts a chainhash.Hash into a big.Int that can be used to
+// perform math comparisons.
+func HashToBig(hash *chainhash.Hash) *big.Int {
+	// A Hash is in little-endian, but the big package wants the bytes in
+	// big-endian, so reverse them.
+	buf := *hash
+	blen := len(buf)
+	for i := 0; i < blen/2; i++ {
+		buf[i], buf[blen-1-i] = buf[blen-1-i], buf[i]
+	}
+
+	return new(big.Int).SetBytes(buf[:])
+}
+
+// CompactToBig converts a compact representation of a whole number N to an
+// unsigned 32-bit number.  The representation is similar to IEEE754 floating
+// point numbers.
+//
+// Like IEEE754 floating point, there are three basic components: the sign,
+// the exponent, and the mantissa.  The most significant 8 bits hold the
+// unsigned base 256 exponent, bit 23 the sign, and the least significant 23
+// bits the mantissa:
+//
+//	-------------------------------------------------
+//	|   Exponent     |    Sign    |    Mantissa     |
+//	-------------------------------------------------
+//	| 8 bits [31-24] | 1 bit [23] | 23 bits [22-00] |
+//	-------------------------------------------------
+//
+// The formula to calculate N is:
+//
+//	N = (-1^sign) * mantissa * 256^(exponent-3)
+//
+// This compact form is only used to encode unsigned 256-bit numbers which
+// represent difficulty targets, thus there really is not a need for a sign
+// bit, but it is implemented here to stay consistent with bitcoind.
+func CompactToBig(compact uint32) *big.Int {
+	// Extract the mantissa, sign bit, and exponent.
+	mantissa := compact & 0x007fffff
+	isNegative := compact&0x00800000 != 0
+	exponent := uint(compact >> 24)
+
+	// Since the base for the exponent is 256, the exponent can be treated
+	// as the number of bytes to represent the full 256-bit number.  So,
+	// treat the exponent as the number of bytes and shift the mantissa
+	// right or left accordingly.  This is equivalent to:
+	// N = mantissa * 256^(exponent-3)
+	var bn *big.Int
+	if exponent <= 3 {
+		mantissa >>= 8 * (3 - exponent)
+		bn = big.NewInt(int64(mantissa))
+	} else {
+		bn = big.NewInt(int64(mantissa))
+		bn.Lsh(bn, 8*(exponent-3))
+	}
+
+	// Make it negative if the sign bit is set.
+	if isNegative {
+		bn = bn.Neg(bn)
+	}
+
+	return bn
+}
+
+// BigToCompact converts a whole number N to a compact representation using
+// an unsigned 32-bit number.  The compact representation only provides 23
+// bits of precision, so values larger than (2^23 - 1) only encode the most
+// significant digits of the number.  See CompactToBig for details.
+func BigToCompact(n *big.Int) uint32 {
+	// No need to do any work if it's zero.
+	if n.Sign() == 0 {
+		return 0
+	}
+
+	// Since the base for the exponent is 256, the exponent can be treated
+	// as the number of bytes.  So, shift the number right or left
+	// accordingly.  This is equivalent to:
+	// mantissa = mantissa / 256^(exponent-3)
+	var mantissa uint32
+	exponent := uint(len(n.Bytes()))
+	if exponent <= 3 {
+		mantissa = uint32(n.Bits()[0])
+		mantissa <<= 8 * (3 - exponent)
+	} else {
+		// Use a copy to avoid modifying the caller's original number.
+		tn := new(big.Int).Set(n)
+		mantissa = uint32(tn.Rsh(tn, 8*(exponent-3)).Bits()[0])
+	}
+
+	// When the mantissa already has the sign bit set, the number is too
+	// large to fit into the available 23-bits, so divide the number by 256
+	// and increment the exponent accordingly.
+	if mantissa&0x00800000 != 0 {
+		mantissa >>= 8
+		exponent++
+	}
+
+	// Pack the exponent, sign bit, and mantissa into an unsigned 32-bit
+	// int and return it.
+	compact := uint32(exponent<<24) | mantissa
+	if n.Sign() < 0 {
+		compact |= 0x00800000
+	}
+	return compact
+}
+
+// CalcWork calculates a work value from difficulty bits.  The work is the
+// expected number of hashes needed to find a block meeting the target, so
+// the chain with the most accumulated work is the best chain:
+//
+//	work = 2^256 / (target+1)
+//
+// Zero is returned for a target which is not positive.
+func CalcWork(bits uint32) *big.Int {
+	// Return a work value of zero if the passed difficulty bits represent
+	// a negative number.  Note this should not happen in practice with
+	// valid blocks, but an invalid block could trigger it.
+	difficultyNum := CompactToBig(bits)
+	if difficultyNum.Sign() <= 0 {
+		return big.NewInt(0)
+	}
+
+	// (1 << 256) / (difficultyNum + 1)
+	denominator := new(big.Int).Add(difficultyNum, bigOne)
+	return new(big.Int).Div(oneLsh256, denominator)
+}
+
+// BlockHeader defines a block header that provides easier and more efficient
+// manipulation of raw block headers.  It memoizes the hash of the header on
+// its first access so subsequent accesses don't repeat the hashing.
+//
+// The memoized hash is safe for concurrent readers, like the one of Block.  A
+// BlockHeader must not be copied after first use; share a *BlockHeader
+// instead.
+type BlockHeader struct {
+	msgHeader *wire.BlockHeader              // Underlying BlockHeader
+	hash      atomic.Pointer[chainhash.Hash] // Cached block hash
+}
+
+// NewBlockHeader returns a new instance of a block header given an underlying
+// wire.BlockHeader.  The header must not be modified afterwards, since its
+// hash is cached.
+func NewBlockHeader(header *wire.BlockHeader) *BlockHeader {
+	return &BlockHeader{msgHeader: header}
+}
+
+// MsgBlockHeader returns the underlying wire.BlockHeader.
+func (h *BlockHeader) MsgBlockHeader() *wire.BlockHeader {
+	return h.msgHeader
+}
+
+// Hash returns the block identifier hash for the header.  This is equivalent
+// to calling BlockHash on the underlying wire.BlockHeader, however it caches
+// the result so subsequent calls are more efficient.  It is safe for
+// concurrent use; goroutines racing to hash the header first all return the
+// hash cached by the winner.
+func (h *BlockHeader) Hash() *chainhash.Hash {
+	// Return the cached block hash if it has already been generated.
+	if hash := h.hash.Load(); hash != nil {
+		return hash
+	}
+
+	// Cache the block hash and return it.
+	hash := h.msgHeader.BlockHash()
+	if h.hash.CompareAndSwap(nil, &hash) {
+		return &hash
+	}
+	return h.hash.Load()
+}
+
+// CheckProofOfWork ensures the hash of the header meets the target whose
+// compact form is bits, and that the target is positive and no larger than
+// powLimit.  The target is passed in, rather than read from the header,
+// since the headers of Omega transaction blocks do not carry their target.
+func (h *BlockHeader) CheckProofOfWork(bits uint32, powLimit *big.Int) error {
+	target := CompactToBig(bits)
+	if target.Sign() <= 0 || target.Cmp(powLimit) > 0 {
+		return ErrTargetOutOfRange
+	}
+	if HashToBig(h.Hash()).Cmp(target) > 0 {
+		return ErrHighHash
+	}
+	return nil
+}
+
+// CheckTimestamp ensures the timestamp of the header is after medianTime, the
+// median time of the past blocks, and at most locktime.MaxTimeOffset past
+// now, the network adjusted time, as by locktime.CheckTimestamp.
+func (h *BlockHeader) CheckTimestamp(medianTime, now time.Time) error {
+	return locktime.CheckTimestamp(h.msgHeader.Timestamp, medianTime, now)
+}
+
+// MedianTimePast returns the median time of the past blocks with the passed
+// headers, ordered by height, as by locktime.MedianTimePast.  Only the
+// last locktime.MedianTimeBlocks headers are used.
+func MedianTimePast(headers []*BlockHeader) (time.Time, error) {
+	if len(headers) > locktime.MedianTimeBlocks {
+		headers = headers[len(headers)-locktime.MedianTimeBlocks:]
+	}
+	timestamps := make([]time.Time, len(headers))
+	for i, h := range headers {
+		timestamps[i] = h.msgHeader.Timestamp
+	}
+	return locktime.MedianTimePast(timestamps)
+}
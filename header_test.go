@@ -0,0 +1,189 @@
+// Copyright (c) 2013-2016 The btcsuite developers
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package btcutil_test
+
+import (
+	"math/big"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/locktime"
+)
+
+// hexToBig returns the big.Int of a hexadecimal string.
+func hexToBig(s string) *big.Int {
+	n, ok := new(big.Int).SetString(s, 16)
+	if !ok {
+		panic("invalid hex in source file: " + s)
+	}
+	return n
+}
+
+// TestCompact ensures compact targets convert to and from big integers.
+func TestCompact(t *testing.T) {
+	tests := []struct {
+		compact uint32
+		n       *big.Int
+	}{
+		{0x00000000, big.NewInt(0)},
+		{0x01003456, big.NewInt(0)},
+		{0x01123456, big.NewInt(0x12)},
+		{0x02123456, big.NewInt(0x1234)},
+		{0x03123456, big.NewInt(0x123456)},
+		{0x04123456, big.NewInt(0x12345600)},
+		{0x04923456, big.NewInt(-0x12345600)},
+		{0x05009234, big.NewInt(0x92340000)},
+		{0x1d00ffff, hexToBig("ffff0000000000000000000000000000000000000000000000000000")},
+		{0x207fffff, hexToBig("7fffff0000000000000000000000000000000000000000000000000000000000")},
+	}
+	for _, test := range tests {
+		if n := btcutil.CompactToBig(test.compact); n.Cmp(test.n) != 0 {
+			t.Errorf("CompactToBig(%08x): got %x, want %x", test.compact,
+				n, test.n)
+		}
+	}
+
+	// Only canonical compact forms survive a round trip.
+	for _, compact := range []uint32{0, 0x03123456, 0x04923456, 0x05009234,
+		0x1d00ffff, 0x207fffff} {
+
+		got := btcutil.BigToCompact(btcutil.CompactToBig(compact))
+		if got != compact {
+			t.Errorf("BigToCompact: got %08x, want %08x", got, compact)
+		}
+	}
+	if got := btcutil.BigToCompact(big.NewInt(-1)); got != 0x01810000 {
+		t.Errorf("BigToCompact(-1): got %08x, want 01810000", got)
+	}
+}
+
+// TestCalcWork ensures work is the expected number of hashes to meet a
+// target.
+func TestCalcWork(t *testing.T) {
+	tests := []struct {
+		bits uint32
+		work *big.Int
+	}{
+		{0x1d00ffff, big.NewInt(0x100010001)},
+		{0x207fffff, big.NewInt(2)},
+		{0x04923456, big.NewInt(0)},
+		{0, big.NewInt(0)},
+	}
+	for _, test := range tests {
+		if work := btcutil.CalcWork(test.bits); work.Cmp(test.work) != 0 {
+			t.Errorf("CalcWork(%08x): got %v, want %v", test.bits, work,
+				test.work)
+		}
+	}
+}
+
+// TestBlockHeader ensures headers cache their hash and check their proof of
+// work against a target.
+func TestBlockHeader(t *testing.T) {
+	const easyBits = 0x207fffff
+	powLimit := btcutil.CompactToBig(easyBits)
+
+	// Find a header meeting the easy target and one missing it.
+	var solved, unsolved *btcutil.BlockHeader
+	for nonce := int32(0); solved == nil || unsolved == nil; nonce++ {
+		h := btcutil.NewBlockHeader(&wire.BlockHeader{
+			Timestamp: time.Unix(1600000000, 0),
+			Nonce:     nonce,
+		})
+		if btcutil.HashToBig(h.Hash()).Cmp(powLimit) <= 0 {
+			solved = h
+		} else {
+			unsolved = h
+		}
+	}
+
+	if hash := solved.MsgBlockHeader().BlockHash(); *solved.Hash() != hash {
+		t.Errorf("Hash: got %v, want %v", solved.Hash(), hash)
+	}
+	if solved.Hash() != solved.Hash() {
+		t.Errorf("Hash: hash is not cached")
+	}
+
+	tests := []struct {
+		name   string
+		header *btcutil.BlockHeader
+		bits   uint32
+		err    error
+	}{
+		{"solved", solved, easyBits, nil},
+		{"unsolved", unsolved, easyBits, btcutil.ErrHighHash},
+		{"above limit", solved, 0x21008000, btcutil.ErrTargetOutOfRange},
+		{"zero target", solved, 0, btcutil.ErrTargetOutOfRange},
+		{"negative target", solved, 0x20ffffff, btcutil.ErrTargetOutOfRange},
+	}
+	for _, test := range tests {
+		err := test.header.CheckProofOfWork(test.bits, powLimit)
+		if err != test.err {
+			t.Errorf("CheckProofOfWork(%s): got error %v, want %v",
+				test.name, err, test.err)
+		}
+	}
+}
+
+// TestHeaderTimestamps ensures the median time of headers is the one of their
+// timestamps, and that header timestamps are checked against it.
+func TestHeaderTimestamps(t *testing.T) {
+	var headers []*btcutil.BlockHeader
+	for i := int64(0); i < 13; i++ {
+		headers = append(headers, btcutil.NewBlockHeader(&wire.BlockHeader{
+			Timestamp: time.Unix(1600000000+i*600, 0),
+		}))
+	}
+	median, err := btcutil.MedianTimePast(headers)
+	if err != nil {
+		t.Fatalf("MedianTimePast: unexpected error: %v", err)
+	}
+	if want := time.Unix(1600000000+7*600, 0); !median.Equal(want) {
+		t.Errorf("MedianTimePast: got %v, want %v", median, want)
+	}
+	if _, err := btcutil.MedianTimePast(nil); err != locktime.ErrNoTimestamps {
+		t.Errorf("MedianTimePast: got error %v, want %v", err,
+			locktime.ErrNoTimestamps)
+	}
+
+	now := time.Unix(1600000000+13*600, 0)
+	if err := headers[12].CheckTimestamp(median, now); err != nil {
+		t.Errorf("CheckTimestamp: unexpected error: %v", err)
+	}
+	if err := headers[7].CheckTimestamp(median, now); err != locktime.ErrTimeTooOld {
+		t.Errorf("CheckTimestamp: got error %v, want %v", err,
+			locktime.ErrTimeTooOld)
+	}
+}
+
+// TestBlockHeaderConcurrentHash ensures goroutines hashing a shared header all
+// get the same cached hash.  It is meant to be run with the race detector.
+func TestBlockHeaderConcurrentHash(t *testing.T) {
+	h := btcutil.NewBlockHeader(&Block100000.Header)
+
+	const goroutines = 8
+	hashes := make([]*chainhash.Hash, goroutines)
+	var wg sync.WaitGroup
+	for i := range hashes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			hashes[i] = h.Hash()
+		}(i)
+	}
+	wg.Wait()
+
+	want := Block100000.Header.BlockHash()
+	for i, hash := range hashes {
+		if hash != hashes[0] || *hash != want {
+			t.Errorf("goroutine %d: got hash %v, want %v", i, hash, want)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package chaingen builds the blocks of the chains generated by the regtest
+// and blockgen packages: it starts chains at the genesis block of a network,
+// creates coinbase transactions, and solves blocks extending a chain with a
+// valid merkle root and proof of work.
+package chaingen
+
+import (
+	"errors"
+	"math"
+	"math/big"
+	"time"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/merkle"
+)
+
+const (
+	// DefaultBlockInterval is the default time between the timestamps of
+	// consecutive blocks.
+	DefaultBlockInterval = 10 * time.Minute
+
+	// maxNonceTries bounds the number of nonces tried when solving a
+	// block so a misconfigured target can't hang a test forever.
+	maxNonceTries = 1 << 24
+)
+
+// ErrNoSolution describes an error where no nonce satisfying the proof of
+// work target could be found.
+var ErrNoSolution = errors.New("unable to solve block")
+
+// Genesis returns the genesis block of the passed network at height zero.
+func Genesis(params *chaincfg.Params) *btcutil.Block {
+	genesis := btcutil.NewBlock(params.GenesisBlock)
+	genesis.SetHeight(0)
+	return genesis
+}
+
+// CoinbaseTx returns a coinbase transaction without outputs for the block at
+// height.
+func CoinbaseTx(height int32) *wire.MsgTx {
+	tx := wire.NewMsgTx(wire.TxVersion)
+
+	// The sequence commits to the height so that coinbases paying the
+	// same outputs at different heights have distinct hashes.
+	tx.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: wire.OutPoint{Index: math.MaxUint32},
+		Sequence:         uint32(height),
+		SignatureIndex:   math.MaxUint32,
+	})
+	return tx
+}
+
+// Solve sets the header of msgBlock so the block extends prev with the
+// passed timestamp and commits to its transactions, and finds a nonce which
+// satisfies the proof of work target.  The solved block is returned at the
+// height following prev.
+func Solve(prev *btcutil.Block, msgBlock *wire.MsgBlock, timestamp time.Time,
+	target *big.Int) (*btcutil.Block, error) {
+
+	leaves := make([]chainhash.Hash, len(msgBlock.Transactions))
+	for i, tx := range msgBlock.Transactions {
+		leaves[i] = tx.TxHash()
+	}
+	msgBlock.Header = wire.BlockHeader{
+		Version:    prev.MsgBlock().Header.Version,
+		PrevBlock:  *prev.Hash(),
+		MerkleRoot: merkle.Root(leaves),
+		Timestamp:  timestamp,
+	}
+
+	for i := 0; i < maxNonceTries; i++ {
+		hash := msgBlock.Header.BlockHash()
+		if btcutil.HashToBig(&hash).Cmp(target) <= 0 {
+			block := btcutil.NewBlock(msgBlock)
+			block.SetHeight(prev.Height() + 1)
+			return block, nil
+		}
+		msgBlock.Header.Nonce++
+	}
+	return nil, ErrNoSolution
+}
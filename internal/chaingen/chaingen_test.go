@@ -0,0 +1,58 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package chaingen_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/internal/chaingen"
+	"github.com/zeusyf/btcutil/merkle"
+)
+
+// TestSolve ensures solved blocks extend their parent, commit to their
+// transactions and meet their target.
+func TestSolve(t *testing.T) {
+	params := &chaincfg.RegressionNetParams
+	genesis := chaingen.Genesis(params)
+	if genesis.Height() != 0 || *genesis.Hash() != params.GenesisBlock.BlockHash() {
+		t.Fatalf("Genesis: got block %v at height %d", genesis.Hash(),
+			genesis.Height())
+	}
+
+	prev := genesis
+	for height := int32(1); height <= 3; height++ {
+		coinbase := chaingen.CoinbaseTx(height)
+		if !coinbase.IsCoinBase() || coinbase.TxIn[0].Sequence != uint32(height) {
+			t.Errorf("CoinbaseTx(%d): got %+v", height, coinbase.TxIn[0])
+		}
+		msgBlock := &wire.MsgBlock{}
+		msgBlock.AddTransaction(coinbase)
+		timestamp := prev.MsgBlock().Header.Timestamp.Add(time.Minute)
+
+		block, err := chaingen.Solve(prev, msgBlock, timestamp,
+			params.PowLimit)
+		if err != nil {
+			t.Fatalf("Solve: unexpected error: %v", err)
+		}
+		header := &block.MsgBlock().Header
+		if header.PrevBlock != *prev.Hash() || block.Height() != height ||
+			!header.Timestamp.Equal(timestamp) {
+			t.Errorf("Solve: block %d does not extend its parent", height)
+		}
+		root, err := merkle.CalcMerkleRoot(block.Transactions(), false)
+		if err != nil || root != header.MerkleRoot {
+			t.Errorf("Solve: got merkle root %v, want %v",
+				header.MerkleRoot, root)
+		}
+		if btcutil.HashToBig(block.Hash()).Cmp(params.PowLimit) > 0 {
+			t.Errorf("Solve: block %d does not meet its target", height)
+		}
+		prev = block
+	}
+}
@@ -22,7 +22,7 @@ import (
 // buffer.  It is used to inject errors and is only available to the test
 // package.
 func (b *Block) SetBlockBytes(buf []byte) {
-	b.serializedBlock = buf
+	b.serializedBlock.Store(&buf)
 }
 
 // TstAppDataDir makes the internal appDataDir function available to the test
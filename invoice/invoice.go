@@ -0,0 +1,502 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package invoice matches incoming payments to open invoices.
+//
+// An Invoice requests an amount of OMC or any other numeric token to a
+// dedicated address by an expiry time.  A Matcher credits each output paying
+// the address of an invoice to it, so an invoice may be paid by several
+// partial payments, and settles the invoice once the payments reach its
+// amount less an underpayment tolerance.  Payments exceeding the amount by
+// more than the overpayment tolerance are reported so the excess can be
+// refunded, as are payments arriving after the invoice expired.
+//
+// Every change to an invoice is reported as an Event to the handler of the
+// Matcher.  The Matcher implements the BlockConnected and BlockDisconnected
+// methods of regtest.Listener, so it can follow the blocks of a chain
+// directly, using the block timestamps as the times payments were made and
+// expiring invoices as blocks pass their expiry.
+package invoice
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcd/txscript"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/omega/token"
+)
+
+var (
+	// ErrInvalidInvoice describes an error where an invoice has no ID or
+	// address, a non-positive amount, a hash token type, negative
+	// tolerances, or an underpayment tolerance not less than its amount.
+	ErrInvalidInvoice = errors.New("invalid invoice")
+
+	// ErrDuplicateInvoice describes an error where an invoice is added
+	// with the ID of one the matcher already has.
+	ErrDuplicateInvoice = errors.New("duplicate invoice ID")
+
+	// ErrDuplicateAddress describes an error where an invoice is added
+	// with the address and token type of one the matcher already has.
+	// Each invoice must be paid to its own address for payments to be
+	// matched to it.
+	ErrDuplicateAddress = errors.New("invoice address already in use")
+
+	// ErrUnknownInvoice describes an error where an invoice the matcher
+	// does not have is requested.
+	ErrUnknownInvoice = errors.New("unknown invoice")
+)
+
+// Invoice requests Amount of a numeric token to Address by Expiry.
+type Invoice struct {
+	ID        string
+	Address   btcutil.Address
+	TokenType uint64
+	Amount    btcutil.Amount
+	Expiry    time.Time
+
+	// Underpayment is the shortfall accepted in settlement, such as to
+	// absorb rounding by exchange rate conversions.
+	Underpayment btcutil.Amount
+
+	// Overpayment is the excess accepted without reporting the invoice
+	// as overpaid.
+	Overpayment btcutil.Amount
+}
+
+// State is the state of an invoice.
+type State uint8
+
+const (
+	// StateOpen is the state of an invoice which has not been paid.
+	StateOpen State = iota
+
+	// StatePartial is the state of an invoice whose payments do not yet
+	// reach its amount less the underpayment tolerance.
+	StatePartial
+
+	// StateSettled is the state of an invoice paid within its
+	// tolerances.
+	StateSettled
+
+	// StateOverpaid is the state of a settled invoice whose payments
+	// exceed its amount by more than the overpayment tolerance.
+	StateOverpaid
+
+	// StateExpired is the state of an invoice which was not settled by
+	// its expiry.  Payments made before the expiry remain credited to it.
+	StateExpired
+)
+
+// stateStrings is a map of invoice states back to their constant names for
+// pretty printing.
+var stateStrings = map[State]string{
+	StateOpen:     "StateOpen",
+	StatePartial:  "StatePartial",
+	StateSettled:  "StateSettled",
+	StateOverpaid: "StateOverpaid",
+	StateExpired:  "StateExpired",
+}
+
+// String returns the State as a human-readable name.
+func (s State) String() string {
+	if str, ok := stateStrings[s]; ok {
+		return str
+	}
+	return "Unknown State"
+}
+
+// IsSettled returns whether the state is that of an invoice paid in full.
+func (s State) IsSettled() bool {
+	return s == StateSettled || s == StateOverpaid
+}
+
+// EventType identifies the change to an invoice reported by an event.
+type EventType uint8
+
+const (
+	// EventPayment is the event of a payment credited to an invoice.
+	EventPayment EventType = iota
+
+	// EventLatePayment is the event of a payment made to an invoice
+	// after it expired.  Late payments are not credited, and are
+	// normally refunded.
+	EventLatePayment
+
+	// EventExpired is the event of an invoice expiring unsettled.
+	EventExpired
+
+	// EventReverted is the event of a payment to an invoice being
+	// removed, such as by a reorg.
+	EventReverted
+)
+
+// eventTypeStrings is a map of event types back to their constant names for
+// pretty printing.
+var eventTypeStrings = map[EventType]string{
+	EventPayment:     "EventPayment",
+	EventLatePayment: "EventLatePayment",
+	EventExpired:     "EventExpired",
+	EventReverted:    "EventReverted",
+}
+
+// String returns the EventType as a human-readable name.
+func (t EventType) String() string {
+	if str, ok := eventTypeStrings[t]; ok {
+		return str
+	}
+	return "Unknown EventType"
+}
+
+// Payment is an output paying an invoice.
+type Payment struct {
+	OutPoint wire.OutPoint
+	Amount   btcutil.Amount
+	At       time.Time
+}
+
+// Event reports a change to an invoice.  State and Paid are those of the
+// invoice after the change, and Payment is the payment the event is about,
+// if any.
+type Event struct {
+	Type    EventType
+	Invoice string
+	State   State
+	Paid    btcutil.Amount
+	Payment *Payment
+}
+
+// Settlement is the state of an invoice along with its payments.
+type Settlement struct {
+	Invoice  Invoice
+	State    State
+	Paid     btcutil.Amount
+	Payments []Payment
+
+	// Late are the payments made after the invoice expired.
+	Late []Payment
+}
+
+// Due returns the amount left to pay for the invoice to be settled, counting
+// the underpayment tolerance, or zero when it is settled.
+func (s *Settlement) Due() btcutil.Amount {
+	if due := s.Invoice.Amount - s.Invoice.Underpayment - s.Paid; due > 0 {
+		return due
+	}
+	return 0
+}
+
+// Excess returns the amount paid beyond the invoice amount.
+func (s *Settlement) Excess() btcutil.Amount {
+	if excess := s.Paid - s.Invoice.Amount; excess > 0 {
+		return excess
+	}
+	return 0
+}
+
+// entry is an invoice along with the payments made to it.
+type entry struct {
+	inv      Invoice
+	pkScript []byte
+	expired  bool
+	paid     btcutil.Amount
+	payments []Payment
+	late     []Payment
+}
+
+// state returns the state of the invoice.
+func (e *entry) state() State {
+	switch {
+	case e.paid > e.inv.Amount+e.inv.Overpayment:
+		return StateOverpaid
+	case e.paid >= e.inv.Amount-e.inv.Underpayment:
+		return StateSettled
+	case e.expired:
+		return StateExpired
+	case e.paid > 0:
+		return StatePartial
+	default:
+		return StateOpen
+	}
+}
+
+// event returns an event of the passed type about the invoice.
+func (e *entry) event(typ EventType, p *Payment) Event {
+	return Event{
+		Type:    typ,
+		Invoice: e.inv.ID,
+		State:   e.state(),
+		Paid:    e.paid,
+		Payment: p,
+	}
+}
+
+// matchKey identifies the outputs paying an invoice.
+type matchKey struct {
+	tokenType uint64
+	pkScript  string
+}
+
+// Matcher matches the outputs of the transactions it observes to its
+// invoices.  It is safe for concurrent use.
+type Matcher struct {
+	mtx      sync.Mutex
+	handler  func(Event)
+	invoices map[string]*entry
+	scripts  map[matchKey]*entry
+	txs      map[chainhash.Hash][]string
+}
+
+// NewMatcher returns a matcher without invoices which reports events to
+// handler.  The handler is called without the matcher locked, in the order
+// the events occurred, and may be nil.
+func NewMatcher(handler func(Event)) *Matcher {
+	return &Matcher{
+		handler:  handler,
+		invoices: make(map[string]*entry),
+		scripts:  make(map[matchKey]*entry),
+		txs:      make(map[chainhash.Hash][]string),
+	}
+}
+
+// notify reports events to the handler.
+func (m *Matcher) notify(events []Event) {
+	if m.handler == nil {
+		return
+	}
+	for _, ev := range events {
+		m.handler(ev)
+	}
+}
+
+// Add adds an open invoice to the matcher.
+func (m *Matcher) Add(inv *Invoice) error {
+	if inv.ID == "" || inv.Address == nil || inv.Amount <= 0 ||
+		inv.TokenType&1 != 0 || inv.Underpayment < 0 ||
+		inv.Overpayment < 0 || inv.Underpayment >= inv.Amount {
+		return ErrInvalidInvoice
+	}
+	pkScript, err := txscript.PayToAddrScript(inv.Address)
+	if err != nil {
+		return err
+	}
+	key := matchKey{inv.TokenType, string(pkScript)}
+
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	if _, ok := m.invoices[inv.ID]; ok {
+		return ErrDuplicateInvoice
+	}
+	if _, ok := m.scripts[key]; ok {
+		return ErrDuplicateAddress
+	}
+	e := &entry{inv: *inv, pkScript: pkScript}
+	m.invoices[inv.ID] = e
+	m.scripts[key] = e
+	return nil
+}
+
+// Remove removes the invoice with the passed ID, along with the record of its
+// payments.
+func (m *Matcher) Remove(id string) error {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	e, ok := m.invoices[id]
+	if !ok {
+		return ErrUnknownInvoice
+	}
+	delete(m.invoices, id)
+	delete(m.scripts, matchKey{e.inv.TokenType, string(e.pkScript)})
+	for txHash, ids := range m.txs {
+		kept := ids[:0]
+		for _, other := range ids {
+			if other != id {
+				kept = append(kept, other)
+			}
+		}
+		if len(kept) == 0 {
+			delete(m.txs, txHash)
+		} else {
+			m.txs[txHash] = kept
+		}
+	}
+	return nil
+}
+
+// Settlement returns the state and payments of the invoice with the passed
+// ID.
+func (m *Matcher) Settlement(id string) (*Settlement, error) {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	e, ok := m.invoices[id]
+	if !ok {
+		return nil, ErrUnknownInvoice
+	}
+	return &Settlement{
+		Invoice:  e.inv,
+		State:    e.state(),
+		Paid:     e.paid,
+		Payments: append([]Payment(nil), e.payments...),
+		Late:     append([]Payment(nil), e.late...),
+	}, nil
+}
+
+// expire marks the unsettled invoices expiring by now as expired, returning
+// the events of the invoices expired in order of their expiry.
+func (m *Matcher) expire(now time.Time) []Event {
+	var expired []*entry
+	for _, e := range m.invoices {
+		if e.expired || e.state().IsSettled() || now.Before(e.inv.Expiry) {
+			continue
+		}
+		e.expired = true
+		expired = append(expired, e)
+	}
+	sort.Slice(expired, func(i, j int) bool {
+		if !expired[i].inv.Expiry.Equal(expired[j].inv.Expiry) {
+			return expired[i].inv.Expiry.Before(expired[j].inv.Expiry)
+		}
+		return expired[i].inv.ID < expired[j].inv.ID
+	})
+	events := make([]Event, 0, len(expired))
+	for _, e := range expired {
+		events = append(events, e.event(EventExpired, nil))
+	}
+	return events
+}
+
+// Expire expires the unsettled invoices whose expiry is not after now.
+func (m *Matcher) Expire(now time.Time) {
+	m.mtx.Lock()
+	events := m.expire(now)
+	m.mtx.Unlock()
+	m.notify(events)
+}
+
+// ObserveTx matches the outputs of tx, observed at the passed time, to the
+// invoices paid to their scripts.  Invoices expiring by the passed time are
+// expired first, so that payments are only credited when made before the
+// expiry.  Transactions already observed are ignored.
+func (m *Matcher) ObserveTx(tx *wire.MsgTx, at time.Time) {
+	m.mtx.Lock()
+	events := m.observeTx(tx, at)
+	m.mtx.Unlock()
+	m.notify(events)
+}
+
+// observeTx matches the outputs of tx to the invoices, returning the events
+// of the changes made.
+func (m *Matcher) observeTx(tx *wire.MsgTx, at time.Time) []Event {
+	txHash := tx.TxHash()
+	if _, ok := m.txs[txHash]; ok {
+		return nil
+	}
+
+	events := m.expire(at)
+	var ids []string
+	for i, txOut := range tx.TxOut {
+		value, ok := txOut.Token.Value.(*token.NumeralVal)
+		if !ok || value.Val <= 0 {
+			continue
+		}
+		e, ok := m.scripts[matchKey{txOut.Token.TokenType,
+			string(txOut.PkScript)}]
+		if !ok {
+			continue
+		}
+
+		p := Payment{
+			OutPoint: wire.OutPoint{Hash: txHash, Index: uint32(i)},
+			Amount:   btcutil.Amount(value.Val),
+			At:       at,
+		}
+		if e.expired {
+			e.late = append(e.late, p)
+			events = append(events, e.event(EventLatePayment, &p))
+		} else {
+			e.payments = append(e.payments, p)
+			e.paid += p.Amount
+			events = append(events, e.event(EventPayment, &p))
+		}
+		if len(ids) == 0 || ids[len(ids)-1] != e.inv.ID {
+			ids = append(ids, e.inv.ID)
+		}
+	}
+	if len(ids) != 0 {
+		m.txs[txHash] = ids
+	}
+	return events
+}
+
+// RemoveTx forgets the payments made by the transaction with the passed hash,
+// such as when it is removed from the chain by a reorg.  Invoices which were
+// settled by the transaction are no longer settled, and expire as usual if
+// they are past their expiry.
+func (m *Matcher) RemoveTx(txHash *chainhash.Hash) {
+	m.mtx.Lock()
+	events := m.removeTx(txHash)
+	m.mtx.Unlock()
+	m.notify(events)
+}
+
+// removeTx forgets the payments made by the transaction with the passed hash,
+// returning the events of the changes made.
+func (m *Matcher) removeTx(txHash *chainhash.Hash) []Event {
+	var events []Event
+	for _, id := range m.txs[*txHash] {
+		e, ok := m.invoices[id]
+		if !ok {
+			continue
+		}
+		var removed []Payment
+		e.payments, removed = splitPayments(e.payments, txHash)
+		for i := range removed {
+			e.paid -= removed[i].Amount
+			events = append(events, e.event(EventReverted, &removed[i]))
+		}
+		e.late, removed = splitPayments(e.late, txHash)
+		for i := range removed {
+			events = append(events, e.event(EventReverted, &removed[i]))
+		}
+	}
+	delete(m.txs, *txHash)
+	return events
+}
+
+// splitPayments returns the payments not made by the transaction with the
+// passed hash, followed by those which were.
+func splitPayments(payments []Payment, txHash *chainhash.Hash) ([]Payment, []Payment) {
+	var kept, removed []Payment
+	for _, p := range payments {
+		if p.OutPoint.Hash == *txHash {
+			removed = append(removed, p)
+		} else {
+			kept = append(kept, p)
+		}
+	}
+	return kept, removed
+}
+
+// BlockConnected observes the transactions of block at its timestamp, then
+// expires the invoices whose expiry the block reached.
+func (m *Matcher) BlockConnected(block *btcutil.Block) {
+	at := block.MsgBlock().Header.Timestamp
+	for _, tx := range block.Transactions() {
+		m.ObserveTx(tx.MsgTx(), at)
+	}
+	m.Expire(at)
+}
+
+// BlockDisconnected forgets the payments made by the transactions of block.
+func (m *Matcher) BlockDisconnected(block *btcutil.Block) {
+	txs := block.Transactions()
+	for i := len(txs) - 1; i >= 0; i-- {
+		m.RemoveTx(txs[i].Hash())
+	}
+}
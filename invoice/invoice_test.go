@@ -0,0 +1,308 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package invoice_test
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcd/txscript"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/invoice"
+	"github.com/zeusyf/omega/token"
+)
+
+// testAddress returns a pay-to-pubkey-hash address of a hash filled with b.
+func testAddress(t *testing.T, b byte) btcutil.Address {
+	hash := make([]byte, 20)
+	for i := range hash {
+		hash[i] = b
+	}
+	addr, err := btcutil.NewAddressPubKeyHash(hash, &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("NewAddressPubKeyHash: unexpected error: %v", err)
+	}
+	return addr
+}
+
+// payTx returns a transaction paying each of the passed values of the passed
+// token type to addr.
+func payTx(t *testing.T, addr btcutil.Address, tokenType uint64, values ...int64) *wire.MsgTx {
+	pkScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		t.Fatalf("PayToAddrScript: unexpected error: %v", err)
+	}
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: wire.OutPoint{Index: uint32(values[0])},
+		SignatureIndex:   0xffffffff,
+	})
+	for _, value := range values {
+		tx.AddTxOut(&wire.TxOut{
+			Token: token.Token{
+				TokenType: tokenType,
+				Value:     &token.NumeralVal{Val: value},
+			},
+			PkScript: pkScript,
+		})
+	}
+	return tx
+}
+
+// hour returns the passed hour of June 1st 2021 UTC.
+func hour(h int) time.Time {
+	return time.Date(2021, time.June, 1, h, 0, 0, 0, time.UTC)
+}
+
+// recorder records the events reported by a matcher.
+type recorder struct {
+	events []invoice.Event
+}
+
+func (r *recorder) handle(ev invoice.Event) {
+	r.events = append(r.events, ev)
+}
+
+// take returns the types and states of the events recorded since the last
+// call.
+func (r *recorder) take() []string {
+	var got []string
+	for _, ev := range r.events {
+		got = append(got, ev.Invoice+" "+ev.Type.String()+" "+
+			ev.State.String())
+	}
+	r.events = nil
+	return got
+}
+
+// testInvoice returns an invoice of 100 units of token type 4 to addr,
+// accepting a shortfall of 2 and an excess of 5, expiring at noon.
+func testInvoice(id string, addr btcutil.Address) *invoice.Invoice {
+	return &invoice.Invoice{
+		ID:           id,
+		Address:      addr,
+		TokenType:    4,
+		Amount:       100,
+		Expiry:       hour(12),
+		Underpayment: 2,
+		Overpayment:  5,
+	}
+}
+
+// TestAdd ensures invalid and conflicting invoices are rejected.
+func TestAdd(t *testing.T) {
+	m := invoice.NewMatcher(nil)
+	if err := m.Add(testInvoice("a", testAddress(t, 1))); err != nil {
+		t.Fatalf("Add: unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		modify func(inv *invoice.Invoice)
+		err    error
+	}{
+		{"no ID", func(inv *invoice.Invoice) { inv.ID = "" }, invoice.ErrInvalidInvoice},
+		{"no address", func(inv *invoice.Invoice) { inv.Address = nil }, invoice.ErrInvalidInvoice},
+		{"zero amount", func(inv *invoice.Invoice) { inv.Amount = 0 }, invoice.ErrInvalidInvoice},
+		{"hash token", func(inv *invoice.Invoice) { inv.TokenType = 1 }, invoice.ErrInvalidInvoice},
+		{"negative overpayment", func(inv *invoice.Invoice) { inv.Overpayment = -1 }, invoice.ErrInvalidInvoice},
+		{"underpayment of amount", func(inv *invoice.Invoice) { inv.Underpayment = 100 }, invoice.ErrInvalidInvoice},
+		{"duplicate ID", func(inv *invoice.Invoice) { inv.ID = "a" }, invoice.ErrDuplicateInvoice},
+		{"duplicate address", func(inv *invoice.Invoice) { inv.Address = testAddress(t, 1) }, invoice.ErrDuplicateAddress},
+		{"other token", func(inv *invoice.Invoice) {
+			inv.Address = testAddress(t, 1)
+			inv.TokenType = 8
+		}, nil},
+	}
+	for _, test := range tests {
+		inv := testInvoice("b", testAddress(t, 2))
+		test.modify(inv)
+		if err := m.Add(inv); err != test.err {
+			t.Errorf("%s: got error %v, want %v", test.name, err, test.err)
+		}
+	}
+
+	if err := m.Remove("a"); err != nil {
+		t.Fatalf("Remove: unexpected error: %v", err)
+	}
+	if err := m.Remove("a"); err != invoice.ErrUnknownInvoice {
+		t.Errorf("Remove: got error %v, want %v", err,
+			invoice.ErrUnknownInvoice)
+	}
+	if err := m.Add(testInvoice("c", testAddress(t, 1))); err != nil {
+		t.Errorf("Add: unexpected error after removal: %v", err)
+	}
+}
+
+// TestSettlement ensures payments are credited to invoices within their
+// tolerances until they expire.
+func TestSettlement(t *testing.T) {
+	var r recorder
+	m := invoice.NewMatcher(r.handle)
+	addrs := []btcutil.Address{testAddress(t, 1), testAddress(t, 2),
+		testAddress(t, 3), testAddress(t, 4)}
+	for i, id := range []string{"exact", "partial", "over", "unpaid"} {
+		if err := m.Add(testInvoice(id, addrs[i])); err != nil {
+			t.Fatalf("Add: unexpected error: %v", err)
+		}
+	}
+
+	// Two outputs of one transaction and a later one settle within the
+	// underpayment tolerance.  Payments of other tokens are ignored.
+	m.ObserveTx(payTx(t, addrs[0], 4, 40, 30), hour(9))
+	m.ObserveTx(payTx(t, addrs[0], 0, 50), hour(9))
+	m.ObserveTx(payTx(t, addrs[0], 4, 28), hour(10))
+	m.ObserveTx(payTx(t, addrs[1], 4, 50), hour(10))
+	m.ObserveTx(payTx(t, addrs[2], 4, 106), hour(11))
+	want := []string{
+		"exact EventPayment StatePartial",
+		"exact EventPayment StatePartial",
+		"exact EventPayment StateSettled",
+		"partial EventPayment StatePartial",
+		"over EventPayment StateOverpaid",
+	}
+	if got := r.take(); !reflect.DeepEqual(got, want) {
+		t.Errorf("events: got %v, want %v", got, want)
+	}
+
+	// Observing a transaction again changes nothing.
+	m.ObserveTx(payTx(t, addrs[1], 4, 50), hour(11))
+	if got := r.take(); got != nil {
+		t.Errorf("events: got %v for a known transaction", got)
+	}
+
+	// Unsettled invoices expire, and later payments are not credited.
+	late := payTx(t, addrs[1], 4, 60)
+	m.ObserveTx(late, hour(13))
+	m.ObserveTx(payTx(t, addrs[0], 4, 1), hour(13))
+	want = []string{
+		"partial EventExpired StateExpired",
+		"unpaid EventExpired StateExpired",
+		"partial EventLatePayment StateExpired",
+		"exact EventPayment StateSettled",
+	}
+	if got := r.take(); !reflect.DeepEqual(got, want) {
+		t.Errorf("events: got %v, want %v", got, want)
+	}
+
+	tests := []struct {
+		id     string
+		state  invoice.State
+		paid   btcutil.Amount
+		due    btcutil.Amount
+		excess btcutil.Amount
+		late   int
+	}{
+		{"exact", invoice.StateSettled, 99, 0, 0, 0},
+		{"partial", invoice.StateExpired, 50, 48, 0, 1},
+		{"over", invoice.StateOverpaid, 106, 0, 6, 0},
+		{"unpaid", invoice.StateExpired, 0, 98, 0, 0},
+	}
+	for _, test := range tests {
+		s, err := m.Settlement(test.id)
+		if err != nil {
+			t.Fatalf("Settlement: unexpected error: %v", err)
+		}
+		if s.State != test.state || s.Paid != test.paid ||
+			s.Due() != test.due || s.Excess() != test.excess ||
+			len(s.Late) != test.late {
+			t.Errorf("%s: got state %v, paid %v, due %v, excess %v and "+
+				"%d late payments", test.id, s.State, s.Paid, s.Due(),
+				s.Excess(), len(s.Late))
+		}
+	}
+
+	s, _ := m.Settlement("partial")
+	wantLate := invoice.Payment{
+		OutPoint: wire.OutPoint{Hash: late.TxHash(), Index: 0},
+		Amount:   60,
+		At:       hour(13),
+	}
+	if !reflect.DeepEqual(s.Late, []invoice.Payment{wantLate}) {
+		t.Errorf("Late: got %v, want %v", s.Late, wantLate)
+	}
+	if _, err := m.Settlement("missing"); err != invoice.ErrUnknownInvoice {
+		t.Errorf("Settlement: got error %v, want %v", err,
+			invoice.ErrUnknownInvoice)
+	}
+}
+
+// TestBlocks ensures the matcher follows connected and disconnected blocks.
+func TestBlocks(t *testing.T) {
+	var r recorder
+	m := invoice.NewMatcher(r.handle)
+	addr := testAddress(t, 1)
+	if err := m.Add(testInvoice("a", addr)); err != nil {
+		t.Fatalf("Add: unexpected error: %v", err)
+	}
+
+	block := btcutil.NewBlock(&wire.MsgBlock{
+		Header:       wire.BlockHeader{Timestamp: hour(11)},
+		Transactions: []*wire.MsgTx{payTx(t, addr, 4, 100)},
+	})
+	m.BlockConnected(block)
+	m.Expire(hour(13))
+	m.BlockDisconnected(block)
+	want := []string{
+		"a EventPayment StateSettled",
+		"a EventReverted StateOpen",
+	}
+	if got := r.take(); !reflect.DeepEqual(got, want) {
+		t.Errorf("events: got %v, want %v", got, want)
+	}
+
+	// The invoice is past its expiry once it is no longer settled.
+	m.BlockConnected(btcutil.NewBlock(&wire.MsgBlock{
+		Header:       wire.BlockHeader{Timestamp: hour(14)},
+		Transactions: []*wire.MsgTx{payTx(t, addr, 4, 100)},
+	}))
+	want = []string{
+		"a EventExpired StateExpired",
+		"a EventLatePayment StateExpired",
+	}
+	if got := r.take(); !reflect.DeepEqual(got, want) {
+		t.Errorf("events: got %v, want %v", got, want)
+	}
+}
+
+// TestStateStringer tests the stringized output for the State and EventType
+// types.
+func TestStateStringer(t *testing.T) {
+	states := []struct {
+		in   invoice.State
+		want string
+	}{
+		{invoice.StateOpen, "StateOpen"},
+		{invoice.StatePartial, "StatePartial"},
+		{invoice.StateSettled, "StateSettled"},
+		{invoice.StateOverpaid, "StateOverpaid"},
+		{invoice.StateExpired, "StateExpired"},
+		{0xff, "Unknown State"},
+	}
+	for i, test := range states {
+		if got := test.in.String(); got != test.want {
+			t.Errorf("String #%d: got %s, want %s", i, got, test.want)
+		}
+	}
+
+	types := []struct {
+		in   invoice.EventType
+		want string
+	}{
+		{invoice.EventPayment, "EventPayment"},
+		{invoice.EventLatePayment, "EventLatePayment"},
+		{invoice.EventExpired, "EventExpired"},
+		{invoice.EventReverted, "EventReverted"},
+		{0xff, "Unknown EventType"},
+	}
+	for i, test := range types {
+		if got := test.in.String(); got != test.want {
+			t.Errorf("String #%d: got %s, want %s", i, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,363 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package webhook delivers the events of an invoice matcher over HTTP as
+// authenticated notifications.
+//
+// Each event is serialized canonically as JSON and sent as a Message carrying
+// a unique ID, the time it was sent, and signatures of the three, in the
+// headers of the Standard Webhooks specification.  Messages are signed with
+// a secret shared with the receiver using HMAC-SHA256, or with an Ed25519
+// private key whose public key the receiver holds.
+//
+// A Receiver verifies the signatures of the messages it receives, rejects
+// those sent too long ago, and remembers the IDs of those it accepted so a
+// captured message can not be replayed.
+package webhook
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zeusyf/btcutil/entropy"
+	"github.com/zeusyf/btcutil/invoice"
+)
+
+// Headers of a message.
+const (
+	HeaderID        = "Webhook-Id"
+	HeaderTimestamp = "Webhook-Timestamp"
+	HeaderSignature = "Webhook-Signature"
+)
+
+const (
+	// DefaultTolerance is the default largest difference between the time
+	// a message was sent and the time it is received.
+	DefaultTolerance = 5 * time.Minute
+
+	// MaxBodySize is the largest body of a request read by VerifyRequest.
+	MaxBodySize = 1 << 20
+
+	// idLen is the number of random bytes of a message ID.
+	idLen = 16
+
+	// Versions prefixing the signatures of each scheme.
+	versionHMAC    = "v1"
+	versionEd25519 = "v1a"
+)
+
+var (
+	// ErrMissingHeader describes an error where a message lacks one of
+	// its headers, or has a malformed timestamp.
+	ErrMissingHeader = errors.New("missing or malformed webhook header")
+
+	// ErrInvalidSignature describes an error where no signature of a
+	// message was made by the key of the receiver.
+	ErrInvalidSignature = errors.New("invalid webhook signature")
+
+	// ErrStaleMessage describes an error where a message was sent longer
+	// ago, or further in the future, than the receiver tolerates.
+	ErrStaleMessage = errors.New("webhook timestamp out of tolerance")
+
+	// ErrReplayedMessage describes an error where a message with the ID
+	// of one already accepted is received.
+	ErrReplayedMessage = errors.New("webhook message replayed")
+)
+
+// Payment is the JSON form of an invoice.Payment.
+type Payment struct {
+	TxHash string `json:"txid"`
+	Index  uint32 `json:"vout"`
+	Amount int64  `json:"amount"`
+	Time   int64  `json:"time"`
+}
+
+// Payload is the JSON form of an invoice.Event.  Amounts are in hao and times
+// are in seconds since the Unix epoch.
+type Payload struct {
+	Type    string   `json:"type"`
+	Invoice string   `json:"invoice"`
+	State   string   `json:"state"`
+	Paid    int64    `json:"paid"`
+	Payment *Payment `json:"payment,omitempty"`
+}
+
+// Marshal returns the canonical serialization of the event: the JSON of its
+// Payload, with the fields in a fixed order and no insignificant whitespace.
+func Marshal(ev *invoice.Event) ([]byte, error) {
+	p := Payload{
+		Type:    ev.Type.String(),
+		Invoice: ev.Invoice,
+		State:   ev.State.String(),
+		Paid:    int64(ev.Paid),
+	}
+	if ev.Payment != nil {
+		p.Payment = &Payment{
+			TxHash: ev.Payment.OutPoint.Hash.String(),
+			Index:  ev.Payment.OutPoint.Index,
+			Amount: int64(ev.Payment.Amount),
+			Time:   ev.Payment.At.Unix(),
+		}
+	}
+	return json.Marshal(&p)
+}
+
+// Signer signs the content of messages.
+type Signer interface {
+	// Sign returns the signature of content, prefixed with the version
+	// of its scheme and a comma.
+	Sign(content []byte) string
+}
+
+// Verifier verifies the signatures of messages.
+type Verifier interface {
+	// Verify returns whether signature, as returned by Sign, is a valid
+	// signature of content.
+	Verify(content []byte, signature string) bool
+}
+
+// HMACKey is a secret shared between the sender and the receiver of messages,
+// which it signs and verifies with HMAC-SHA256.
+type HMACKey []byte
+
+// Ensure HMACKey implements the Signer and Verifier interfaces.
+var (
+	_ Signer   = HMACKey(nil)
+	_ Verifier = HMACKey(nil)
+)
+
+// mac returns the HMAC-SHA256 of content.
+func (k HMACKey) mac(content []byte) []byte {
+	mac := hmac.New(sha256.New, k)
+	mac.Write(content)
+	return mac.Sum(nil)
+}
+
+// Sign returns the HMAC of content.
+//
+// This is part of the Signer interface.
+func (k HMACKey) Sign(content []byte) string {
+	return versionHMAC + "," + base64.StdEncoding.EncodeToString(k.mac(content))
+}
+
+// Verify returns whether signature is the HMAC of content.
+//
+// This is part of the Verifier interface.
+func (k HMACKey) Verify(content []byte, signature string) bool {
+	return subtle.ConstantTimeCompare([]byte(signature),
+		[]byte(k.Sign(content))) == 1
+}
+
+// Ed25519Signer signs messages with an Ed25519 private key.
+type Ed25519Signer ed25519.PrivateKey
+
+// Sign returns the Ed25519 signature of content.
+//
+// This is part of the Signer interface.
+func (k Ed25519Signer) Sign(content []byte) string {
+	sig := ed25519.Sign(ed25519.PrivateKey(k), content)
+	return versionEd25519 + "," + base64.StdEncoding.EncodeToString(sig)
+}
+
+// Ed25519Verifier verifies the signatures of messages with an Ed25519 public
+// key.
+type Ed25519Verifier ed25519.PublicKey
+
+// Verify returns whether signature is an Ed25519 signature of content.
+//
+// This is part of the Verifier interface.
+func (k Ed25519Verifier) Verify(content []byte, signature string) bool {
+	if !strings.HasPrefix(signature, versionEd25519+",") {
+		return false
+	}
+	sig, err := base64.StdEncoding.DecodeString(
+		signature[len(versionEd25519)+1:])
+	if err != nil || len(k) != ed25519.PublicKeySize {
+		return false
+	}
+	return ed25519.Verify(ed25519.PublicKey(k), content, sig)
+}
+
+// signedContent returns the content signed for a message: its ID, timestamp
+// and body separated by periods.
+func signedContent(id string, timestamp int64, body []byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(id)
+	buf.WriteByte('.')
+	buf.WriteString(strconv.FormatInt(timestamp, 10))
+	buf.WriteByte('.')
+	buf.Write(body)
+	return buf.Bytes()
+}
+
+// Message is a signed notification of an event.
+type Message struct {
+	ID        string
+	Timestamp time.Time
+	Body      []byte
+
+	// Signatures are the signatures of the message, one per signer, so
+	// receivers can move between keys.
+	Signatures []string
+}
+
+// NewMessage returns a message with a random ID notifying ev, sent at the
+// passed time and signed by each of the signers.  The ID is read from src, or
+// from the default entropy source when src is nil.
+func NewMessage(ev *invoice.Event, at time.Time, src io.Reader, signers ...Signer) (*Message, error) {
+	body, err := Marshal(ev)
+	if err != nil {
+		return nil, err
+	}
+	var id [idLen]byte
+	if err := entropy.Read(src, id[:]); err != nil {
+		return nil, err
+	}
+
+	m := &Message{
+		ID:        "msg_" + hex.EncodeToString(id[:]),
+		Timestamp: time.Unix(at.Unix(), 0),
+		Body:      body,
+	}
+	content := signedContent(m.ID, m.Timestamp.Unix(), body)
+	for _, signer := range signers {
+		m.Signatures = append(m.Signatures, signer.Sign(content))
+	}
+	return m, nil
+}
+
+// SetHeaders sets the headers of the message in h.
+func (m *Message) SetHeaders(h http.Header) {
+	h.Set(HeaderID, m.ID)
+	h.Set(HeaderTimestamp, strconv.FormatInt(m.Timestamp.Unix(), 10))
+	h.Set(HeaderSignature, strings.Join(m.Signatures, " "))
+}
+
+// NewRequest returns a POST request delivering the message to url.
+func (m *Message) NewRequest(url string) (*http.Request, error) {
+	req, err := http.NewRequest(http.MethodPost, url,
+		bytes.NewReader(m.Body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	m.SetHeaders(req.Header)
+	return req, nil
+}
+
+// ReceiverOption configures a Receiver.
+type ReceiverOption func(*Receiver)
+
+// WithTolerance sets the largest difference between the time a message was
+// sent and the time it is received.  It defaults to DefaultTolerance.
+func WithTolerance(tolerance time.Duration) ReceiverOption {
+	return func(r *Receiver) {
+		r.tolerance = tolerance
+	}
+}
+
+// WithClock sets the function returning the current time.  It is primarily
+// useful for deterministic tests.
+func WithClock(now func() time.Time) ReceiverOption {
+	return func(r *Receiver) {
+		r.now = now
+	}
+}
+
+// Receiver verifies the messages it receives.  It is safe for concurrent use.
+type Receiver struct {
+	verifier  Verifier
+	tolerance time.Duration
+	now       func() time.Time
+
+	mtx  sync.Mutex
+	seen map[string]time.Time
+}
+
+// NewReceiver returns a receiver accepting messages signed for verifier.
+func NewReceiver(verifier Verifier, opts ...ReceiverOption) *Receiver {
+	r := &Receiver{
+		verifier:  verifier,
+		tolerance: DefaultTolerance,
+		now:       time.Now,
+		seen:      make(map[string]time.Time),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// Verify verifies the message with the passed headers and body, and returns
+// its payload.  A message is accepted once: the IDs of accepted messages are
+// remembered until their timestamps fall out of tolerance.
+func (r *Receiver) Verify(h http.Header, body []byte) (*Payload, error) {
+	id, ts, sigs := h.Get(HeaderID), h.Get(HeaderTimestamp),
+		h.Get(HeaderSignature)
+	if id == "" || ts == "" || sigs == "" {
+		return nil, ErrMissingHeader
+	}
+	timestamp, err := strconv.ParseInt(ts, 10, 64)
+	if err != nil {
+		return nil, ErrMissingHeader
+	}
+	sent := time.Unix(timestamp, 0)
+	now := r.now()
+	if sent.Before(now.Add(-r.tolerance)) || sent.After(now.Add(r.tolerance)) {
+		return nil, ErrStaleMessage
+	}
+
+	content := signedContent(id, timestamp, body)
+	verified := false
+	for _, sig := range strings.Fields(sigs) {
+		if r.verifier.Verify(content, sig) {
+			verified = true
+			break
+		}
+	}
+	if !verified {
+		return nil, ErrInvalidSignature
+	}
+
+	var p Payload
+	if err := json.Unmarshal(body, &p); err != nil {
+		return nil, err
+	}
+
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	for seenID, seenAt := range r.seen {
+		if seenAt.Before(now.Add(-r.tolerance)) {
+			delete(r.seen, seenID)
+		}
+	}
+	if _, ok := r.seen[id]; ok {
+		return nil, ErrReplayedMessage
+	}
+	r.seen[id] = sent
+	return &p, nil
+}
+
+// VerifyRequest verifies the message delivered by req, reading a body of at
+// most MaxBodySize bytes, and returns its payload.
+func (r *Receiver) VerifyRequest(req *http.Request) (*Payload, error) {
+	body, err := io.ReadAll(io.LimitReader(req.Body, MaxBodySize))
+	if err != nil {
+		return nil, err
+	}
+	return r.Verify(req.Header, body)
+}
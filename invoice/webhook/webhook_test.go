@@ -0,0 +1,219 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package webhook_test
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil/invoice"
+	"github.com/zeusyf/btcutil/invoice/webhook"
+)
+
+// testTime is the time test messages are sent.
+var testTime = time.Unix(1600000000, 0)
+
+// testEvent returns an event of a payment settling an invoice.
+func testEvent() *invoice.Event {
+	return &invoice.Event{
+		Type:    invoice.EventPayment,
+		Invoice: "order-42",
+		State:   invoice.StateSettled,
+		Paid:    1500,
+		Payment: &invoice.Payment{
+			OutPoint: wire.OutPoint{Hash: chainhash.Hash{1}, Index: 2},
+			Amount:   1000,
+			At:       testTime,
+		},
+	}
+}
+
+// testMessage returns a message notifying testEvent signed by signers.
+func testMessage(t *testing.T, signers ...webhook.Signer) *webhook.Message {
+	src := bytes.NewReader(bytes.Repeat([]byte{7}, 64))
+	m, err := webhook.NewMessage(testEvent(), testTime, src, signers...)
+	if err != nil {
+		t.Fatalf("NewMessage: unexpected error: %v", err)
+	}
+	return m
+}
+
+// headers returns the headers of m.
+func headers(m *webhook.Message) http.Header {
+	h := make(http.Header)
+	m.SetHeaders(h)
+	return h
+}
+
+// clock returns a function returning the passed time.
+func clock(now time.Time) func() time.Time {
+	return func() time.Time { return now }
+}
+
+// TestMarshal ensures events are serialized canonically.
+func TestMarshal(t *testing.T) {
+	ev := testEvent()
+	body, err := webhook.Marshal(ev)
+	if err != nil {
+		t.Fatalf("Marshal: unexpected error: %v", err)
+	}
+	want := `{"type":"EventPayment","invoice":"order-42",` +
+		`"state":"StateSettled","paid":1500,"payment":{"txid":"` +
+		ev.Payment.OutPoint.Hash.String() + `","vout":2,"amount":1000,` +
+		`"time":1600000000}}`
+	if string(body) != want {
+		t.Errorf("Marshal: got %s, want %s", body, want)
+	}
+
+	ev.Type, ev.Payment = invoice.EventExpired, nil
+	body, _ = webhook.Marshal(ev)
+	want = `{"type":"EventExpired","invoice":"order-42",` +
+		`"state":"StateSettled","paid":1500}`
+	if string(body) != want {
+		t.Errorf("Marshal: got %s, want %s", body, want)
+	}
+}
+
+// TestVerify ensures messages are accepted once when signed by the key of the
+// receiver and sent within its tolerance.
+func TestVerify(t *testing.T) {
+	key := webhook.HMACKey("secret")
+	m := testMessage(t, key)
+	if m.ID != "msg_07070707070707070707070707070707" {
+		t.Errorf("NewMessage: got ID %s", m.ID)
+	}
+
+	r := webhook.NewReceiver(key, webhook.WithClock(clock(testTime.Add(time.Minute))))
+	p, err := r.Verify(headers(m), m.Body)
+	if err != nil {
+		t.Fatalf("Verify: unexpected error: %v", err)
+	}
+	want := &webhook.Payload{
+		Type:    "EventPayment",
+		Invoice: "order-42",
+		State:   "StateSettled",
+		Paid:    1500,
+		Payment: &webhook.Payment{
+			TxHash: chainhash.Hash{1}.String(),
+			Index:  2,
+			Amount: 1000,
+			Time:   testTime.Unix(),
+		},
+	}
+	if !reflect.DeepEqual(p, want) {
+		t.Errorf("Verify: got %+v, want %+v", p, want)
+	}
+	if _, err := r.Verify(headers(m), m.Body); err != webhook.ErrReplayedMessage {
+		t.Errorf("Verify: got error %v, want %v", err,
+			webhook.ErrReplayedMessage)
+	}
+
+	tests := []struct {
+		name   string
+		r      *webhook.Receiver
+		modify func(h http.Header, body []byte) []byte
+		err    error
+	}{
+		{"other key", webhook.NewReceiver(webhook.HMACKey("other"),
+			webhook.WithClock(clock(testTime))), nil,
+			webhook.ErrInvalidSignature},
+		{"too old", webhook.NewReceiver(key,
+			webhook.WithClock(clock(testTime.Add(10*time.Minute)))), nil,
+			webhook.ErrStaleMessage},
+		{"within tolerance", webhook.NewReceiver(key,
+			webhook.WithClock(clock(testTime.Add(10*time.Minute))),
+			webhook.WithTolerance(time.Hour)), nil, nil},
+		{"too new", webhook.NewReceiver(key,
+			webhook.WithClock(clock(testTime.Add(-10*time.Minute)))), nil,
+			webhook.ErrStaleMessage},
+		{"changed body", webhook.NewReceiver(key,
+			webhook.WithClock(clock(testTime))),
+			func(h http.Header, body []byte) []byte {
+				return bytes.Replace(body, []byte("1500"),
+					[]byte("9500"), 1)
+			}, webhook.ErrInvalidSignature},
+		{"changed timestamp", webhook.NewReceiver(key,
+			webhook.WithClock(clock(testTime))),
+			func(h http.Header, body []byte) []byte {
+				h.Set(webhook.HeaderTimestamp, "1600000001")
+				return body
+			}, webhook.ErrInvalidSignature},
+		{"no ID", webhook.NewReceiver(key,
+			webhook.WithClock(clock(testTime))),
+			func(h http.Header, body []byte) []byte {
+				h.Del(webhook.HeaderID)
+				return body
+			}, webhook.ErrMissingHeader},
+		{"malformed timestamp", webhook.NewReceiver(key,
+			webhook.WithClock(clock(testTime))),
+			func(h http.Header, body []byte) []byte {
+				h.Set(webhook.HeaderTimestamp, "soon")
+				return body
+			}, webhook.ErrMissingHeader},
+	}
+	for _, test := range tests {
+		h, body := headers(m), m.Body
+		if test.modify != nil {
+			body = test.modify(h, append([]byte(nil), body...))
+		}
+		if _, err := test.r.Verify(h, body); err != test.err {
+			t.Errorf("%s: got error %v, want %v", test.name, err, test.err)
+		}
+	}
+}
+
+// TestEd25519 ensures messages signed with Ed25519 keys are verified, and that
+// messages signed by several keys are accepted by receivers of any of them.
+func TestEd25519(t *testing.T) {
+	priv := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{1}, ed25519.SeedSize))
+	pub := priv.Public().(ed25519.PublicKey)
+	oldKey := webhook.HMACKey("old secret")
+	m := testMessage(t, oldKey, webhook.Ed25519Signer(priv))
+
+	for _, verifier := range []webhook.Verifier{oldKey, webhook.Ed25519Verifier(pub)} {
+		r := webhook.NewReceiver(verifier, webhook.WithClock(clock(testTime)))
+		if _, err := r.Verify(headers(m), m.Body); err != nil {
+			t.Errorf("Verify: unexpected error: %v", err)
+		}
+	}
+
+	other := ed25519.NewKeyFromSeed(bytes.Repeat([]byte{2}, ed25519.SeedSize))
+	r := webhook.NewReceiver(webhook.Ed25519Verifier(other.Public().(ed25519.PublicKey)),
+		webhook.WithClock(clock(testTime)))
+	if _, err := r.Verify(headers(m), m.Body); err != webhook.ErrInvalidSignature {
+		t.Errorf("Verify: got error %v, want %v", err,
+			webhook.ErrInvalidSignature)
+	}
+}
+
+// TestRequest ensures messages delivered as requests are verified.
+func TestRequest(t *testing.T) {
+	key := webhook.HMACKey("secret")
+	m := testMessage(t, key)
+	req, err := m.NewRequest("https://merchant.example/hooks/omc")
+	if err != nil {
+		t.Fatalf("NewRequest: unexpected error: %v", err)
+	}
+	if req.Method != http.MethodPost ||
+		req.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("NewRequest: got method %s and content type %s",
+			req.Method, req.Header.Get("Content-Type"))
+	}
+
+	r := webhook.NewReceiver(key, webhook.WithClock(clock(testTime)))
+	p, err := r.VerifyRequest(req)
+	if err != nil {
+		t.Fatalf("VerifyRequest: unexpected error: %v", err)
+	}
+	if p.Invoice != "order-42" {
+		t.Errorf("VerifyRequest: got invoice %s", p.Invoice)
+	}
+}
@@ -0,0 +1,263 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package zpay
+
+import (
+	"encoding/binary"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/bech32"
+)
+
+// maxUint64Groups is the largest number of 5 bit groups of an integer field.
+const maxUint64Groups = 12
+
+// parseUint64 returns the integer held by big endian 5 bit groups.
+func parseUint64(groups []byte) (uint64, error) {
+	if len(groups) > maxUint64Groups {
+		return 0, ErrInvalidFormat
+	}
+	var v uint64
+	for _, g := range groups {
+		v = v<<5 | uint64(g)
+	}
+	return v, nil
+}
+
+// fromGroups returns the bytes held by 5 bit groups, which must be padded with
+// zeros.
+func fromGroups(groups []byte) ([]byte, error) {
+	b, err := bech32.ConvertBits(groups, 5, 8, false)
+	if err != nil {
+		return nil, ErrInvalidFormat
+	}
+	return b, nil
+}
+
+// parseHash returns the hash held by a field, or nil when the field does not
+// have the length of a hash and must be skipped.
+func parseHash(groups []byte) (*[32]byte, error) {
+	if len(groups) != hashGroups {
+		return nil, nil
+	}
+	b, err := fromGroups(groups)
+	if err != nil {
+		return nil, err
+	}
+	var hash [32]byte
+	copy(hash[:], b)
+	return &hash, nil
+}
+
+// parseRoute returns the hops of a route hint field.
+func parseRoute(groups []byte) ([]HopHint, error) {
+	b, err := fromGroups(groups)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 || len(b)%hopHintLen != 0 {
+		return nil, ErrInvalidFormat
+	}
+	route := make([]HopHint, 0, len(b)/hopHintLen)
+	for ; len(b) != 0; b = b[hopHintLen:] {
+		nodeID, err := btcec.ParsePubKey(b[:33], btcec.S256())
+		if err != nil {
+			return nil, ErrInvalidFormat
+		}
+		route = append(route, HopHint{
+			NodeID:                    nodeID,
+			ChannelID:                 binary.BigEndian.Uint64(b[33:]),
+			FeeBaseMilliHao:           binary.BigEndian.Uint32(b[41:]),
+			FeeProportionalMillionths: binary.BigEndian.Uint32(b[45:]),
+			CLTVExpiryDelta:           binary.BigEndian.Uint16(b[49:]),
+		})
+	}
+	return route, nil
+}
+
+// parseFallbackAddr returns the address of a fallback address field, or nil
+// when its version is not that of an address type of the network.
+func parseFallbackAddr(groups []byte, net *chaincfg.Params) (btcutil.Address, error) {
+	if len(groups) == 0 {
+		return nil, ErrInvalidFormat
+	}
+	hash, err := fromGroups(groups[1:])
+	if err != nil {
+		return nil, err
+	}
+	var addr btcutil.Address
+	switch groups[0] {
+	case fallbackPubKeyHash:
+		addr, err = btcutil.NewAddressPubKeyHash(hash, net)
+	case fallbackScriptHash:
+		addr, err = btcutil.NewAddressScriptHashFromHash(hash, net)
+	default:
+		return nil, nil
+	}
+	if err != nil {
+		return nil, ErrInvalidFormat
+	}
+	return addr, nil
+}
+
+// parseFields sets the fields of the invoice from its tagged fields.  Fields
+// of unknown types, and payment hash, payment address, description hash and
+// destination fields of the wrong length, are skipped as BOLT #11 requires.
+// Fields other than route hints are set by the first field of their type.
+func (inv *Invoice) parseFields(data []byte) error {
+	for len(data) != 0 {
+		if len(data) < 3 {
+			return ErrInvalidFormat
+		}
+		fieldType := data[0]
+		n := int(data[1])<<5 | int(data[2])
+		if len(data) < 3+n {
+			return ErrInvalidFormat
+		}
+		groups := data[3 : 3+n]
+		data = data[3+n:]
+
+		var err error
+		switch fieldType {
+		case fieldPaymentHash:
+			if inv.PaymentHash == nil {
+				inv.PaymentHash, err = parseHash(groups)
+			}
+
+		case fieldPaymentAddr:
+			if inv.PaymentAddr == nil {
+				inv.PaymentAddr, err = parseHash(groups)
+			}
+
+		case fieldDescriptionHash:
+			if inv.DescriptionHash == nil {
+				inv.DescriptionHash, err = parseHash(groups)
+			}
+
+		case fieldDescription:
+			if inv.Description != nil {
+				continue
+			}
+			var b []byte
+			b, err = fromGroups(groups)
+			if err == nil && !utf8.Valid(b) {
+				err = ErrInvalidFormat
+			}
+			description := string(b)
+			inv.Description = &description
+
+		case fieldDestination:
+			if inv.Destination != nil || len(groups) != pubKeyGroups {
+				continue
+			}
+			var b []byte
+			if b, err = fromGroups(groups); err != nil {
+				break
+			}
+			inv.Destination, err = btcec.ParsePubKey(b, btcec.S256())
+			if err != nil {
+				err = ErrInvalidFormat
+			}
+
+		case fieldExpiry:
+			if inv.Expiry != 0 {
+				continue
+			}
+			var seconds uint64
+			seconds, err = parseUint64(groups)
+			inv.Expiry = time.Duration(seconds) * time.Second
+
+		case fieldMinFinalCLTVExpiry:
+			if inv.MinFinalCLTVExpiry == 0 {
+				inv.MinFinalCLTVExpiry, err = parseUint64(groups)
+			}
+
+		case fieldFallbackAddr:
+			if inv.FallbackAddr == nil {
+				inv.FallbackAddr, err = parseFallbackAddr(groups,
+					inv.Net)
+			}
+
+		case fieldRouteHint:
+			var route []HopHint
+			route, err = parseRoute(groups)
+			inv.RouteHints = append(inv.RouteHints, route)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Decode decodes an invoice for the passed network and verifies its
+// signature.  When the invoice does not name its destination, Destination is
+// set to the public key recovered from the signature.
+func Decode(invoice string, net *chaincfg.Params) (*Invoice, error) {
+	hrp, data, err := bech32.DecodeNoLimit(invoice)
+	if err != nil {
+		return nil, err
+	}
+	prefix, err := Prefix(net)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(hrp, prefix) {
+		return nil, ErrInvalidPrefix
+	}
+	amount, err := decodeAmount(hrp[len(prefix):])
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < timestampGroups+signatureGroups {
+		return nil, ErrInvalidFormat
+	}
+
+	// Recover the key of the signature, converting it back to the
+	// compact format whose header byte carries the recovery code.
+	sigGroups := data[len(data)-signatureGroups:]
+	data = data[:len(data)-signatureGroups]
+	sig, err := fromGroups(sigGroups)
+	if err != nil {
+		return nil, err
+	}
+	if sig[64] > 3 {
+		return nil, ErrInvalidSignature
+	}
+	sig = append([]byte{compactSigHeader + sig[64]}, sig[:64]...)
+	signer, _, err := btcec.RecoverCompact(btcec.S256(), sig,
+		signingHash(hrp, data))
+	if err != nil {
+		return nil, ErrInvalidSignature
+	}
+
+	var timestamp int64
+	for _, g := range data[:timestampGroups] {
+		timestamp = timestamp<<5 | int64(g)
+	}
+	inv := &Invoice{
+		Net:       net,
+		Amount:    amount,
+		Timestamp: time.Unix(timestamp, 0),
+	}
+	if err := inv.parseFields(data[timestampGroups:]); err != nil {
+		return nil, err
+	}
+	if err := inv.validate(); err != nil {
+		return nil, err
+	}
+
+	if inv.Destination == nil {
+		inv.Destination = signer
+	} else if !inv.Destination.IsEqual(signer) {
+		return nil, ErrInvalidSignature
+	}
+	return inv, nil
+}
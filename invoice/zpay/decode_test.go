@@ -0,0 +1,226 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package zpay_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/bech32"
+	"github.com/zeusyf/btcutil/invoice/zpay"
+)
+
+// TestRoundTrip ensures every field of an invoice survives encoding.
+func TestRoundTrip(t *testing.T) {
+	key, hopKey := testKey(1), testKey(2)
+	fallback, err := btcutil.NewAddressPubKeyHash(make([]byte, 20),
+		&chaincfg.TestNet3Params)
+	if err != nil {
+		t.Fatalf("NewAddressPubKeyHash: unexpected error: %v", err)
+	}
+	paymentAddr := [32]byte{9}
+	descriptionHash := [32]byte{7}
+	hop := zpay.HopHint{
+		NodeID:                    hopKey.PubKey(),
+		ChannelID:                 0x0102030405060708,
+		FeeBaseMilliHao:           1000,
+		FeeProportionalMillionths: 20,
+		CLTVExpiryDelta:           144,
+	}
+	want := testInvoice(2500000)
+	want.Net = &chaincfg.TestNet3Params
+	want.PaymentAddr = &paymentAddr
+	want.Description = nil
+	want.DescriptionHash = &descriptionHash
+	want.Destination = key.PubKey()
+	want.Expiry = 10 * time.Minute
+	want.MinFinalCLTVExpiry = 40
+	want.FallbackAddr = fallback
+	want.RouteHints = [][]zpay.HopHint{{hop}, {hop, hop}}
+
+	s, err := want.Encode(key)
+	if err != nil {
+		t.Fatalf("Encode: unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(s, "lntomc25m1") {
+		t.Errorf("Encode: got %s, want prefix lntomc25m1", s)
+	}
+	inv, err := zpay.Decode(s, &chaincfg.TestNet3Params)
+	if err != nil {
+		t.Fatalf("Decode: unexpected error: %v", err)
+	}
+
+	if inv.Amount != want.Amount || !inv.Timestamp.Equal(want.Timestamp) ||
+		*inv.PaymentHash != *want.PaymentHash ||
+		*inv.PaymentAddr != paymentAddr || inv.Description != nil ||
+		*inv.DescriptionHash != descriptionHash ||
+		!inv.Destination.IsEqual(key.PubKey()) ||
+		inv.Expiry != want.Expiry ||
+		inv.MinFinalCLTVExpiry != want.MinFinalCLTVExpiry {
+		t.Errorf("Decode: got %+v, want %+v", inv, want)
+	}
+	if !inv.ExpiresAt().Equal(want.Timestamp.Add(10 * time.Minute)) {
+		t.Errorf("ExpiresAt: got %v", inv.ExpiresAt())
+	}
+	if inv.FallbackAddr == nil || !bytes.Equal(inv.FallbackAddr.ScriptAddress(),
+		fallback.ScriptAddress()) {
+		t.Errorf("Decode: got fallback address %v, want %v",
+			inv.FallbackAddr, fallback)
+	}
+	if len(inv.RouteHints) != 2 || len(inv.RouteHints[0]) != 1 ||
+		len(inv.RouteHints[1]) != 2 {
+		t.Fatalf("Decode: got route hints %v", inv.RouteHints)
+	}
+	for _, route := range inv.RouteHints {
+		for _, got := range route {
+			if !got.NodeID.IsEqual(hop.NodeID) ||
+				got.ChannelID != hop.ChannelID ||
+				got.FeeBaseMilliHao != hop.FeeBaseMilliHao ||
+				got.FeeProportionalMillionths != hop.FeeProportionalMillionths ||
+				got.CLTVExpiryDelta != hop.CLTVExpiryDelta {
+				t.Errorf("Decode: got hop %+v, want %+v", got, hop)
+			}
+		}
+	}
+}
+
+// TestRecoverDestination ensures the destination of invoices which do not
+// name it is recovered from their signature.
+func TestRecoverDestination(t *testing.T) {
+	key := testKey(3)
+	s, err := testInvoice(0).Encode(key)
+	if err != nil {
+		t.Fatalf("Encode: unexpected error: %v", err)
+	}
+	inv, err := zpay.Decode(s, &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("Decode: unexpected error: %v", err)
+	}
+	if !inv.Destination.IsEqual(key.PubKey()) {
+		t.Error("Decode: recovered the wrong destination")
+	}
+	if *inv.Description != "coffee" || inv.Amount != 0 {
+		t.Errorf("Decode: got description %q and amount %v",
+			*inv.Description, inv.Amount)
+	}
+	if !inv.ExpiresAt().Equal(inv.Timestamp.Add(zpay.DefaultExpiry)) {
+		t.Errorf("ExpiresAt: got %v", inv.ExpiresAt())
+	}
+}
+
+// TestSkippedFields ensures fields of unknown types or the wrong length are
+// skipped, and only the first of a repeated field is used.
+func TestSkippedFields(t *testing.T) {
+	key := testKey(1)
+	s, err := testInvoice(1000).Encode(key)
+	if err != nil {
+		t.Fatalf("Encode: unexpected error: %v", err)
+	}
+	hrp, data, err := bech32.DecodeNoLimit(s)
+	if err != nil {
+		t.Fatalf("DecodeNoLimit: unexpected error: %v", err)
+	}
+
+	// A features field, a payment hash field one group short, then the
+	// fields of the invoice followed by a second description.
+	fields := []byte{5, 0, 2, 1, 1, 1, 1, 19}
+	fields = append(fields, make([]byte, 51)...)
+	fields = append(fields, data[7:len(data)-104]...)
+	fields = append(fields, 13, 0, 2, 3, 4)
+	s = sign(t, hrp, append(append([]byte(nil), data[:7]...), fields...), key)
+
+	inv, err := zpay.Decode(s, &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("Decode: unexpected error: %v", err)
+	}
+	if *inv.PaymentHash != *testInvoice(0).PaymentHash ||
+		*inv.Description != "coffee" {
+		t.Errorf("Decode: got payment hash %x and description %q",
+			*inv.PaymentHash, *inv.Description)
+	}
+}
+
+// TestErrors ensures invalid invoices are rejected.
+func TestErrors(t *testing.T) {
+	key, other := testKey(1), testKey(2)
+	valid, err := testInvoice(1000).Encode(key)
+	if err != nil {
+		t.Fatalf("Encode: unexpected error: %v", err)
+	}
+
+	encodeTests := []struct {
+		name   string
+		modify func(inv *zpay.Invoice)
+		err    error
+	}{
+		{"no payment hash", func(inv *zpay.Invoice) { inv.PaymentHash = nil }, zpay.ErrMissingPaymentHash},
+		{"no description", func(inv *zpay.Invoice) { inv.Description = nil }, zpay.ErrMissingDescription},
+		{"two descriptions", func(inv *zpay.Invoice) { inv.DescriptionHash = new([32]byte) }, zpay.ErrMissingDescription},
+		{"unknown net", func(inv *zpay.Invoice) { inv.Net = &chaincfg.Params{} }, zpay.ErrUnknownNet},
+		{"other destination", func(inv *zpay.Invoice) { inv.Destination = other.PubKey() }, zpay.ErrInvalidSignature},
+		{"timestamp", func(inv *zpay.Invoice) { inv.Timestamp = time.Unix(-1, 0) }, zpay.ErrInvalidFormat},
+		{"long description", func(inv *zpay.Invoice) {
+			description := strings.Repeat("x", 640)
+			inv.Description = &description
+		}, zpay.ErrFieldTooLong},
+	}
+	for _, test := range encodeTests {
+		inv := testInvoice(1000)
+		test.modify(inv)
+		if _, err := inv.Encode(key); err != test.err {
+			t.Errorf("%s: got error %v, want %v", test.name, err, test.err)
+		}
+	}
+
+	named := testInvoice(1000)
+	named.Destination = key.PubKey()
+	namedInvoice, err := named.Encode(key)
+	if err != nil {
+		t.Fatalf("Encode: unexpected error: %v", err)
+	}
+	namedHRP, namedData, _ := bech32.DecodeNoLimit(namedInvoice)
+	_, data, _ := bech32.DecodeNoLimit(valid)
+	unsigned, err := bech32.Encode("lnomc", data[:len(data)-104])
+	if err != nil {
+		t.Fatalf("Encode: unexpected error: %v", err)
+	}
+	decodeTests := []struct {
+		name    string
+		invoice string
+		net     *chaincfg.Params
+		err     error
+	}{
+		{"other net", valid, &chaincfg.TestNet3Params, zpay.ErrInvalidPrefix},
+		{"unknown net", valid, &chaincfg.Params{}, zpay.ErrUnknownNet},
+		{"signed by other key", sign(t, namedHRP, namedData[:len(namedData)-104],
+			other),
+			&chaincfg.MainNetParams, zpay.ErrInvalidSignature},
+		{"no signature", unsigned, &chaincfg.MainNetParams,
+			zpay.ErrInvalidFormat},
+		{"no fields", sign(t, "lnomc", data[:7], key),
+			&chaincfg.MainNetParams, zpay.ErrMissingPaymentHash},
+		{"truncated field", sign(t, "lnomc", append(data[:7:7], 1, 1),
+			key), &chaincfg.MainNetParams, zpay.ErrInvalidFormat},
+	}
+	for _, test := range decodeTests {
+		_, err := zpay.Decode(test.invoice, test.net)
+		if err != test.err {
+			t.Errorf("%s: got error %v, want %v", test.name, err, test.err)
+		}
+	}
+
+	// Any changed character fails the checksum.
+	tampered := valid[:len(valid)-10] + "q" + valid[len(valid)-9:]
+	if tampered == valid {
+		tampered = valid[:len(valid)-10] + "p" + valid[len(valid)-9:]
+	}
+	if _, err := zpay.Decode(tampered, &chaincfg.MainNetParams); err == nil {
+		t.Error("Decode: expected error for a tampered invoice")
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package zpay
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/bech32"
+)
+
+// compactSigHeader is the header byte of a compact signature by a compressed
+// public key with recovery code zero.
+const compactSigHeader = 27 + 4
+
+// uint64Groups returns v as big endian 5 bit groups, without leading zero
+// groups.
+func uint64Groups(v uint64) []byte {
+	var groups []byte
+	for ; v != 0; v >>= 5 {
+		groups = append([]byte{byte(v & 31)}, groups...)
+	}
+	return groups
+}
+
+// toGroups returns b as 5 bit groups, padding the last group with zeros.
+func toGroups(b []byte) []byte {
+	groups, _ := bech32.ConvertBits(b, 8, 5, true)
+	return groups
+}
+
+// appendField appends a tagged field of the passed type holding groups.
+func appendField(data []byte, fieldType byte, groups []byte) ([]byte, error) {
+	if len(groups) > maxFieldLen {
+		return nil, ErrFieldTooLong
+	}
+	data = append(data, fieldType, byte(len(groups)>>5), byte(len(groups)&31))
+	return append(data, groups...), nil
+}
+
+// signingHash returns the hash signed by the payee: the SHA256 of the
+// human-readable part followed by the data part as bytes, without the
+// signature.
+func signingHash(hrp string, data []byte) []byte {
+	b, _ := bech32.ConvertBits(data, 5, 8, true)
+	h := sha256.Sum256(append([]byte(hrp), b...))
+	return h[:]
+}
+
+// fields returns the tagged fields of the invoice as 5 bit groups.
+func (inv *Invoice) fields() ([]byte, error) {
+	type field struct {
+		fieldType byte
+		groups    []byte
+	}
+	fields := []field{{fieldPaymentHash, toGroups(inv.PaymentHash[:])}}
+	if inv.PaymentAddr != nil {
+		fields = append(fields, field{fieldPaymentAddr,
+			toGroups(inv.PaymentAddr[:])})
+	}
+	if inv.Description != nil {
+		fields = append(fields, field{fieldDescription,
+			toGroups([]byte(*inv.Description))})
+	}
+	if inv.DescriptionHash != nil {
+		fields = append(fields, field{fieldDescriptionHash,
+			toGroups(inv.DescriptionHash[:])})
+	}
+	if inv.Destination != nil {
+		fields = append(fields, field{fieldDestination,
+			toGroups(inv.Destination.SerializeCompressed())})
+	}
+	if inv.Expiry > 0 {
+		fields = append(fields, field{fieldExpiry,
+			uint64Groups(uint64(inv.Expiry.Seconds()))})
+	}
+	if inv.MinFinalCLTVExpiry > 0 {
+		fields = append(fields, field{fieldMinFinalCLTVExpiry,
+			uint64Groups(inv.MinFinalCLTVExpiry)})
+	}
+	switch addr := inv.FallbackAddr.(type) {
+	case nil:
+	case *btcutil.AddressPubKeyHash:
+		fields = append(fields, field{fieldFallbackAddr, append(
+			[]byte{fallbackPubKeyHash}, toGroups(addr.Hash160()[:])...)})
+	case *btcutil.AddressScriptHash:
+		fields = append(fields, field{fieldFallbackAddr, append(
+			[]byte{fallbackScriptHash}, toGroups(addr.Hash160()[:])...)})
+	default:
+		return nil, ErrInvalidFormat
+	}
+	for _, route := range inv.RouteHints {
+		b := make([]byte, 0, len(route)*hopHintLen)
+		for _, hop := range route {
+			if hop.NodeID == nil {
+				return nil, ErrInvalidFormat
+			}
+			b = append(b, hop.NodeID.SerializeCompressed()...)
+			b = binary.BigEndian.AppendUint64(b, hop.ChannelID)
+			b = binary.BigEndian.AppendUint32(b, hop.FeeBaseMilliHao)
+			b = binary.BigEndian.AppendUint32(b,
+				hop.FeeProportionalMillionths)
+			b = binary.BigEndian.AppendUint16(b, hop.CLTVExpiryDelta)
+		}
+		fields = append(fields, field{fieldRouteHint, toGroups(b)})
+	}
+
+	var data []byte
+	for _, f := range fields {
+		var err error
+		data, err = appendField(data, f.fieldType, f.groups)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
+
+// Encode returns the invoice signed by key.  The destination of the invoice,
+// when set, must be the public key of key.
+func (inv *Invoice) Encode(key *btcec.PrivateKey) (string, error) {
+	if err := inv.validate(); err != nil {
+		return "", err
+	}
+	if inv.Destination != nil && !inv.Destination.IsEqual(key.PubKey()) {
+		return "", ErrInvalidSignature
+	}
+	hrp, err := Prefix(inv.Net)
+	if err != nil {
+		return "", err
+	}
+	if inv.Amount != 0 {
+		hrp += encodeAmount(inv.Amount)
+	}
+
+	timestamp := inv.Timestamp.Unix()
+	if timestamp < 0 || timestamp >= 1<<(5*timestampGroups) {
+		return "", ErrInvalidFormat
+	}
+	data := make([]byte, timestampGroups)
+	for i := timestampGroups - 1; i >= 0; i-- {
+		data[i] = byte(timestamp & 31)
+		timestamp >>= 5
+	}
+	fields, err := inv.fields()
+	if err != nil {
+		return "", err
+	}
+	data = append(data, fields...)
+
+	// The invoice carries the R and S of the compact signature followed
+	// by the recovery code, rather than the header byte which leads the
+	// compact signature.
+	sig, err := btcec.SignCompact(btcec.S256(), key, signingHash(hrp, data),
+		true)
+	if err != nil {
+		return "", err
+	}
+	sig = append(sig[1:], sig[0]-compactSigHeader)
+	data = append(data, toGroups(sig)...)
+
+	return bech32.Encode(hrp, data)
+}
@@ -0,0 +1,310 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package zpay encodes and decodes payment channel invoices for OMC in the
+// format of BOLT #11.
+//
+// An invoice is a bech32 string whose human-readable part is the prefix of
+// its network followed by its amount, and whose data part is the time it was
+// created, a series of tagged fields, and the signature of its payee.  The
+// prefixes of the Omega networks are:
+//
+//	lnomc   main network
+//	lntomc  test network
+//	lnromc  regression test network
+//	lnsomc  simulation test network
+//
+// Amounts are written in OMC with an optional multiplier, m for milli, u for
+// micro, n for nano or p for pico, and are mapped onto Amount, so invoices
+// for fractions of a hao are rejected.
+//
+// Decode verifies the signature of an invoice, and when the invoice does not
+// name its payee, recovers the payee from the signature.
+package zpay
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil"
+)
+
+const (
+	// DefaultExpiry is the time after its creation an invoice without an
+	// expiry field expires.
+	DefaultExpiry = time.Hour
+
+	// DefaultMinFinalCLTVExpiry is the minimum CLTV expiry delta of the
+	// last hop of payments for invoices without the field.
+	DefaultMinFinalCLTVExpiry = 18
+)
+
+// Types of the tagged fields, each written as a single 5 bit group.
+const (
+	fieldPaymentHash        = 1
+	fieldRouteHint          = 3
+	fieldExpiry             = 6
+	fieldFallbackAddr       = 9
+	fieldDescription        = 13
+	fieldPaymentAddr        = 16
+	fieldDestination        = 19
+	fieldDescriptionHash    = 23
+	fieldMinFinalCLTVExpiry = 24
+)
+
+const (
+	// timestampGroups is the number of 5 bit groups of the timestamp.
+	timestampGroups = 7
+
+	// signatureGroups is the number of 5 bit groups of the signature.
+	signatureGroups = 104
+
+	// maxFieldLen is the largest number of 5 bit groups of a tagged
+	// field, whose length is written in two groups.
+	maxFieldLen = 1<<10 - 1
+
+	// hashGroups is the number of 5 bit groups of a 32 byte hash.
+	hashGroups = 52
+
+	// pubKeyGroups is the number of 5 bit groups of a compressed public
+	// key.
+	pubKeyGroups = 53
+
+	// hopHintLen is the serialized size of a hop of a route hint.
+	hopHintLen = 51
+
+	// Fallback address versions of pay-to-pubkey-hash and
+	// pay-to-script-hash addresses.
+	fallbackPubKeyHash = 17
+	fallbackScriptHash = 18
+)
+
+var (
+	// ErrUnknownNet describes an error where an invoice is encoded or
+	// decoded for a network without an invoice prefix.
+	ErrUnknownNet = errors.New("unknown network for invoices")
+
+	// ErrInvalidPrefix describes an error where the human-readable part
+	// of an invoice does not start with the prefix of the network.
+	ErrInvalidPrefix = errors.New("invoice prefix does not match network")
+
+	// ErrInvalidAmount describes an error where the amount of an invoice
+	// is malformed, not a whole number of hao, or out of range.
+	ErrInvalidAmount = errors.New("invalid invoice amount")
+
+	// ErrInvalidFormat describes an error where the data of an invoice is
+	// truncated, has a malformed field, or has a timestamp out of range.
+	ErrInvalidFormat = errors.New("invalid invoice format")
+
+	// ErrFieldTooLong describes an error where a field of an invoice, such
+	// as its description, is longer than a tagged field can be.
+	ErrFieldTooLong = errors.New("invoice field too long")
+
+	// ErrMissingPaymentHash describes an error where an invoice has no
+	// payment hash.
+	ErrMissingPaymentHash = errors.New("invoice has no payment hash")
+
+	// ErrMissingDescription describes an error where an invoice does not
+	// have exactly one of a description and a description hash.
+	ErrMissingDescription = errors.New("invoice must have either a " +
+		"description or a description hash")
+
+	// ErrInvalidSignature describes an error where the signature of an
+	// invoice is malformed or was not made by its destination, or an
+	// invoice is signed by a key other than its destination.
+	ErrInvalidSignature = errors.New("invalid invoice signature")
+)
+
+// prefixes are the invoice prefixes of the networks.
+var prefixes = map[*chaincfg.Params]string{
+	&chaincfg.MainNetParams:       "lnomc",
+	&chaincfg.TestNet3Params:      "lntomc",
+	&chaincfg.RegressionNetParams: "lnromc",
+	&chaincfg.SimNetParams:        "lnsomc",
+}
+
+// Prefix returns the invoice prefix of the passed network.
+func Prefix(net *chaincfg.Params) (string, error) {
+	prefix, ok := prefixes[net]
+	if !ok {
+		return "", ErrUnknownNet
+	}
+	return prefix, nil
+}
+
+// HopHint is a hop of a private route to the payee.
+type HopHint struct {
+	// NodeID is the public key of the node at the start of the channel.
+	NodeID *btcec.PublicKey
+
+	// ChannelID is the short channel ID of the channel.
+	ChannelID uint64
+
+	// FeeBaseMilliHao is the base fee of the channel in thousandths of a
+	// hao.
+	FeeBaseMilliHao uint32
+
+	// FeeProportionalMillionths is the fee of the channel proportional to
+	// the amount forwarded, in millionths.
+	FeeProportionalMillionths uint32
+
+	// CLTVExpiryDelta is the CLTV expiry delta of the channel.
+	CLTVExpiryDelta uint16
+}
+
+// Invoice is a payment channel invoice.
+type Invoice struct {
+	// Net is the network of the invoice.
+	Net *chaincfg.Params
+
+	// Amount is the amount requested, or zero when the payer chooses the
+	// amount.
+	Amount btcutil.Amount
+
+	// Timestamp is the time the invoice was created, with a precision of
+	// a second.
+	Timestamp time.Time
+
+	// PaymentHash is the hash whose preimage is released by the payment.
+	PaymentHash *[32]byte
+
+	// PaymentAddr is the secret the payer includes in the payment to
+	// prevent probing by intermediate nodes, if any.
+	PaymentAddr *[32]byte
+
+	// Description and DescriptionHash describe the purpose of the
+	// payment.  An invoice has exactly one of them, the hash being used
+	// for descriptions too long to be included.
+	Description     *string
+	DescriptionHash *[32]byte
+
+	// Destination is the public key of the payee.  When encoding, an
+	// invoice only names its destination when it is set, and when
+	// decoding, it is recovered from the signature when not named.
+	Destination *btcec.PublicKey
+
+	// Expiry is the time after the timestamp the invoice expires, or zero
+	// for DefaultExpiry.
+	Expiry time.Duration
+
+	// MinFinalCLTVExpiry is the minimum CLTV expiry delta of the last hop
+	// of the payment, or zero for DefaultMinFinalCLTVExpiry.
+	MinFinalCLTVExpiry uint64
+
+	// FallbackAddr is the on-chain address to pay when the payment can't
+	// be made off-chain, if any.
+	FallbackAddr btcutil.Address
+
+	// RouteHints are private routes to the payee.
+	RouteHints [][]HopHint
+}
+
+// ExpiresAt returns the time the invoice expires.
+func (inv *Invoice) ExpiresAt() time.Time {
+	expiry := inv.Expiry
+	if expiry == 0 {
+		expiry = DefaultExpiry
+	}
+	return inv.Timestamp.Add(expiry)
+}
+
+// validate checks the invoice has the required fields.
+func (inv *Invoice) validate() error {
+	if inv.PaymentHash == nil {
+		return ErrMissingPaymentHash
+	}
+	if (inv.Description == nil) == (inv.DescriptionHash == nil) {
+		return ErrMissingDescription
+	}
+	if inv.Amount < 0 || inv.Amount > btcutil.MaxHao {
+		return ErrInvalidAmount
+	}
+	return nil
+}
+
+// Amount multipliers and the number of hao per unit of each.  Nano and pico
+// units are fractions of a hao, so they are written as divisors.
+const (
+	haoPerMilli = 1e5
+	haoPerMicro = 1e2
+	nanoPerHao  = 10
+	picoPerHao  = 1e4
+)
+
+// encodeAmount returns the amount as written in the human-readable part, using
+// the largest multiplier expressing it as an integer.
+func encodeAmount(amount btcutil.Amount) string {
+	switch {
+	case amount%btcutil.HaoPerBitcoin == 0:
+		return strconv.FormatInt(int64(amount/btcutil.HaoPerBitcoin), 10)
+	case amount%haoPerMilli == 0:
+		return strconv.FormatInt(int64(amount/haoPerMilli), 10) + "m"
+	case amount%haoPerMicro == 0:
+		return strconv.FormatInt(int64(amount/haoPerMicro), 10) + "u"
+	default:
+		return strconv.FormatInt(int64(amount)*nanoPerHao, 10) + "n"
+	}
+}
+
+// decodeAmount returns the amount written in the human-readable part after the
+// prefix, which is zero when the invoice has no amount.
+func decodeAmount(s string) (btcutil.Amount, error) {
+	if s == "" {
+		return 0, nil
+	}
+	digits, multiplier := s, byte(0)
+	if last := s[len(s)-1]; last < '0' || last > '9' {
+		digits, multiplier = s[:len(s)-1], last
+	}
+	if digits == "" || digits[0] == '0' {
+		return 0, ErrInvalidAmount
+	}
+	for i := 0; i < len(digits); i++ {
+		if digits[i] < '0' || digits[i] > '9' {
+			return 0, ErrInvalidAmount
+		}
+	}
+	v, err := strconv.ParseInt(digits, 10, 64)
+	if err != nil {
+		return 0, ErrInvalidAmount
+	}
+
+	var amount int64
+	switch multiplier {
+	case 0:
+		if v > btcutil.MaxHao/btcutil.HaoPerBitcoin {
+			return 0, ErrInvalidAmount
+		}
+		amount = v * btcutil.HaoPerBitcoin
+	case 'm':
+		if v > btcutil.MaxHao/haoPerMilli {
+			return 0, ErrInvalidAmount
+		}
+		amount = v * haoPerMilli
+	case 'u':
+		if v > btcutil.MaxHao/haoPerMicro {
+			return 0, ErrInvalidAmount
+		}
+		amount = v * haoPerMicro
+	case 'n':
+		if v%nanoPerHao != 0 {
+			return 0, ErrInvalidAmount
+		}
+		amount = v / nanoPerHao
+	case 'p':
+		if v%picoPerHao != 0 {
+			return 0, ErrInvalidAmount
+		}
+		amount = v / picoPerHao
+	default:
+		return 0, ErrInvalidAmount
+	}
+	if amount > btcutil.MaxHao {
+		return 0, ErrInvalidAmount
+	}
+	return btcutil.Amount(amount), nil
+}
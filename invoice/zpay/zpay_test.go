@@ -0,0 +1,198 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package zpay_test
+
+import (
+	"crypto/sha256"
+	"testing"
+	"time"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/bech32"
+	"github.com/zeusyf/btcutil/invoice/zpay"
+)
+
+// testKey returns a private key whose scalar is filled with b.
+func testKey(b byte) *btcec.PrivateKey {
+	k := make([]byte, 32)
+	for i := range k {
+		k[i] = b
+	}
+	key, _ := btcec.PrivKeyFromBytes(btcec.S256(), k)
+	return key
+}
+
+// testInvoice returns a minimal invoice of the passed amount.
+func testInvoice(amount btcutil.Amount) *zpay.Invoice {
+	hash := [32]byte{1, 2, 3}
+	description := "coffee"
+	return &zpay.Invoice{
+		Net:         &chaincfg.MainNetParams,
+		Amount:      amount,
+		Timestamp:   time.Unix(1600000000, 0),
+		PaymentHash: &hash,
+		Description: &description,
+	}
+}
+
+// hrp returns the human-readable part of an encoded invoice.
+func hrp(t *testing.T, s string) string {
+	hrp, _, err := bech32.DecodeNoLimit(s)
+	if err != nil {
+		t.Fatalf("DecodeNoLimit: unexpected error: %v", err)
+	}
+	return hrp
+}
+
+// TestPrefix ensures each network has its own invoice prefix.
+func TestPrefix(t *testing.T) {
+	tests := []struct {
+		net  *chaincfg.Params
+		want string
+	}{
+		{&chaincfg.MainNetParams, "lnomc"},
+		{&chaincfg.TestNet3Params, "lntomc"},
+		{&chaincfg.RegressionNetParams, "lnromc"},
+		{&chaincfg.SimNetParams, "lnsomc"},
+	}
+	for _, test := range tests {
+		prefix, err := zpay.Prefix(test.net)
+		if err != nil || prefix != test.want {
+			t.Errorf("Prefix: got %q (error %v), want %q", prefix, err,
+				test.want)
+		}
+	}
+	if _, err := zpay.Prefix(&chaincfg.Params{}); err != zpay.ErrUnknownNet {
+		t.Errorf("Prefix: got error %v, want %v", err, zpay.ErrUnknownNet)
+	}
+}
+
+// TestAmount ensures amounts are written with the largest multiplier keeping
+// them whole, and read back to the hao.
+func TestAmount(t *testing.T) {
+	tests := []struct {
+		amount btcutil.Amount
+		hrp    string
+	}{
+		{0, "lnomc"},
+		{btcutil.HaoPerBitcoin, "lnomc1"},
+		{25 * btcutil.HaoPerBitcoin, "lnomc25"},
+		{250000, "lnomc2500u"},
+		{2500000, "lnomc25m"},
+		{123, "lnomc1230n"},
+		{1, "lnomc10n"},
+		{btcutil.MaxHao, "lnomc430000000"},
+	}
+	key := testKey(1)
+	for _, test := range tests {
+		s, err := testInvoice(test.amount).Encode(key)
+		if err != nil {
+			t.Fatalf("Encode: unexpected error: %v", err)
+		}
+		if got := hrp(t, s); got != test.hrp {
+			t.Errorf("Encode(%v): got prefix %q, want %q", test.amount,
+				got, test.hrp)
+		}
+		inv, err := zpay.Decode(s, &chaincfg.MainNetParams)
+		if err != nil {
+			t.Fatalf("Decode: unexpected error: %v", err)
+		}
+		if inv.Amount != test.amount {
+			t.Errorf("Decode(%s): got amount %v, want %v", test.hrp,
+				inv.Amount, test.amount)
+		}
+	}
+
+	for _, amount := range []btcutil.Amount{-1, btcutil.MaxHao + 1} {
+		_, err := testInvoice(amount).Encode(key)
+		if err != zpay.ErrInvalidAmount {
+			t.Errorf("Encode(%v): got error %v, want %v", amount, err,
+				zpay.ErrInvalidAmount)
+		}
+	}
+}
+
+// TestDecodeAmount ensures amounts are decoded from every multiplier, and
+// amounts which are malformed or not whole numbers of hao are rejected.
+func TestDecodeAmount(t *testing.T) {
+	valid := testInvoice(0)
+	tests := []struct {
+		amount string
+		want   btcutil.Amount
+		err    error
+	}{
+		{"2m", 200000, nil},
+		{"3u", 300, nil},
+		{"20n", 2, nil},
+		{"50000p", 5, nil},
+		{"1p", 0, zpay.ErrInvalidAmount},
+		{"15n", 0, zpay.ErrInvalidAmount},
+		{"01", 0, zpay.ErrInvalidAmount},
+		{"m", 0, zpay.ErrInvalidAmount},
+		{"1x", 0, zpay.ErrInvalidAmount},
+		{"430000001", 0, zpay.ErrInvalidAmount},
+		{"99999999999999999999", 0, zpay.ErrInvalidAmount},
+	}
+	for _, test := range tests {
+		s := resign(t, valid, "lnomc"+test.amount, testKey(1))
+		inv, err := zpay.Decode(s, &chaincfg.MainNetParams)
+		if err != test.err {
+			t.Errorf("Decode(%s): got error %v, want %v", test.amount,
+				err, test.err)
+			continue
+		}
+		if err == nil && inv.Amount != test.want {
+			t.Errorf("Decode(%s): got amount %v, want %v", test.amount,
+				inv.Amount, test.want)
+		}
+	}
+}
+
+// resign returns the invoice encoded with the passed human-readable part and
+// signed by key, so invoices the encoder would not produce can be tested.
+func resign(t *testing.T, inv *zpay.Invoice, hrp string, key *btcec.PrivateKey) string {
+	s, err := inv.Encode(key)
+	if err != nil {
+		t.Fatalf("Encode: unexpected error: %v", err)
+	}
+	_, data, err := bech32.DecodeNoLimit(s)
+	if err != nil {
+		t.Fatalf("DecodeNoLimit: unexpected error: %v", err)
+	}
+	return sign(t, hrp, data[:len(data)-104], key)
+}
+
+// sign returns the invoice with the passed human-readable part and data,
+// without its signature, signed by key as the encoder signs.
+func sign(t *testing.T, hrp string, data []byte, key *btcec.PrivateKey) string {
+	b, err := bech32.ConvertBits(data, 5, 8, true)
+	if err != nil {
+		t.Fatalf("ConvertBits: unexpected error: %v", err)
+	}
+	hash := sha256Sum(append([]byte(hrp), b...))
+	sig, err := btcec.SignCompact(btcec.S256(), key, hash, true)
+	if err != nil {
+		t.Fatalf("SignCompact: unexpected error: %v", err)
+	}
+	sig = append(sig[1:], sig[0]-31)
+	groups, err := bech32.ConvertBits(sig, 8, 5, true)
+	if err != nil {
+		t.Fatalf("ConvertBits: unexpected error: %v", err)
+	}
+	s, err := bech32.Encode(hrp, append(append([]byte(nil), data...),
+		groups...))
+	if err != nil {
+		t.Fatalf("Encode: unexpected error: %v", err)
+	}
+	return s
+}
+
+// sha256Sum returns the SHA256 of b.
+func sha256Sum(b []byte) []byte {
+	h := sha256.Sum256(b)
+	return h[:]
+}
@@ -0,0 +1,69 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package kdf
+
+import (
+	"time"
+)
+
+var (
+	// minScrypt and minArgon2id are the cheapest parameters returned by
+	// Calibrate, and the ones it benchmarks.
+	minScrypt   = Params{Algorithm: Scrypt, N: 1 << 14, R: 8, P: 1}
+	minArgon2id = Params{
+		Algorithm: Argon2id,
+		Time:      1,
+		Memory:    64 * 1024,
+		Threads:   4,
+	}
+
+	// calibrationSalt is the salt of the derivation benchmarked by
+	// Calibrate.
+	calibrationSalt = []byte("kdf calibration salt")
+)
+
+// Calibrate returns parameters of alg whose derivation takes about target on
+// the running machine, and no more unless the cheapest parameters do.
+//
+// A single derivation with the cheapest parameters is timed, and the cost
+// is scaled from it: N is doubled for scrypt, keeping R and P, and the number
+// of passes is raised for argon2id, keeping the memory and threads, up to the
+// bounds accepted by Validate.  Since the time is measured, the result varies
+// between runs and machines, and must be stored with the data it protects.
+func Calibrate(alg Algorithm, target time.Duration) (Params, error) {
+	var p Params
+	switch alg {
+	case Scrypt:
+		p = minScrypt
+	case Argon2id:
+		p = minArgon2id
+	default:
+		return Params{}, ErrUnknownAlgorithm
+	}
+
+	start := time.Now()
+	if _, err := p.Key([]byte("passphrase"), calibrationSalt, 32); err != nil {
+		return Params{}, err
+	}
+	elapsed := time.Since(start)
+	if elapsed <= 0 {
+		elapsed = 1
+	}
+
+	if alg == Argon2id {
+		for p.Time < MaxArgon2Time &&
+			elapsed*time.Duration(p.Time+1) <= target {
+			p.Time++
+		}
+		return p, nil
+	}
+	scale := 1
+	for p.N*scale*2 <= MaxScryptN &&
+		elapsed*time.Duration(scale*2) <= target {
+		scale *= 2
+	}
+	p.N *= scale
+	return p, nil
+}
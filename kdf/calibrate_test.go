@@ -0,0 +1,50 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package kdf_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zeusyf/btcutil/kdf"
+)
+
+// TestCalibrate ensures calibrated parameters stay within the cheapest
+// parameters and the bounds accepted by Validate.
+func TestCalibrate(t *testing.T) {
+	for _, alg := range []kdf.Algorithm{kdf.Scrypt, kdf.Argon2id} {
+		cheap, err := kdf.Calibrate(alg, 0)
+		if err != nil {
+			t.Fatalf("Calibrate(%v): unexpected error: %v", alg, err)
+		}
+		costly, err := kdf.Calibrate(alg, time.Hour)
+		if err != nil {
+			t.Fatalf("Calibrate(%v): unexpected error: %v", alg, err)
+		}
+		if cheap.Algorithm != alg || costly.Algorithm != alg {
+			t.Errorf("Calibrate(%v): got %v and %v", alg, cheap, costly)
+		}
+		if err := cheap.Validate(); err != nil {
+			t.Errorf("Calibrate(%v): got invalid %v", alg, cheap)
+		}
+		if err := costly.Validate(); err != nil {
+			t.Errorf("Calibrate(%v): got invalid %v", alg, costly)
+		}
+		switch alg {
+		case kdf.Scrypt:
+			if cheap.N != 1<<14 || costly.N != kdf.MaxScryptN {
+				t.Errorf("Calibrate: got %v and %v", cheap, costly)
+			}
+		case kdf.Argon2id:
+			if cheap.Time != 1 || costly.Time != kdf.MaxArgon2Time {
+				t.Errorf("Calibrate: got %v and %v", cheap, costly)
+			}
+		}
+	}
+	if _, err := kdf.Calibrate(0, time.Second); err != kdf.ErrUnknownAlgorithm {
+		t.Errorf("Calibrate: got error %v, want %v", err,
+			kdf.ErrUnknownAlgorithm)
+	}
+}
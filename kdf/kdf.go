@@ -0,0 +1,229 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package kdf derives encryption keys from passphrases with scrypt or
+// argon2id, for the encrypted exports of the bip38 and keystore packages.
+//
+// The parameters of a derivation are kept along with the data they protect,
+// so data encrypted with one set of parameters stays decryptable after the
+// defaults change.  Params serialize to a versioned binary form, and are
+// bounded when read back so crafted parameters can't make a decryption
+// allocate gigabytes or run for hours.  Calibrate picks parameters costing a
+// target time on the running machine.
+package kdf
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/scrypt"
+)
+
+// Algorithm identifies a key derivation function.
+type Algorithm uint8
+
+const (
+	// Scrypt is the scrypt key derivation function.
+	Scrypt Algorithm = 1
+
+	// Argon2id is the argon2id key derivation function.
+	Argon2id Algorithm = 2
+)
+
+// Map of algorithms back to their constant names for pretty printing.
+var algorithmStrings = map[Algorithm]string{
+	Scrypt:   "scrypt",
+	Argon2id: "argon2id",
+}
+
+// String returns the name of the algorithm, as used in serialized formats.
+func (a Algorithm) String() string {
+	if s, ok := algorithmStrings[a]; ok {
+		return s
+	}
+	return fmt.Sprintf("Unknown Algorithm (%d)", uint8(a))
+}
+
+// ParseAlgorithm returns the algorithm named s, as returned by String.
+func ParseAlgorithm(s string) (Algorithm, error) {
+	for a, name := range algorithmStrings {
+		if name == s {
+			return a, nil
+		}
+	}
+	return 0, ErrUnknownAlgorithm
+}
+
+const (
+	// ParamsVersion1 is the first version of the binary serialization of
+	// parameters.
+	ParamsVersion1 = 1
+
+	// ParamsVersion is the version of the binary serialization of
+	// parameters written by MarshalBinary.
+	ParamsVersion = ParamsVersion1
+
+	// MaxScryptN and MaxScryptRP bound the scrypt cost parameters N and
+	// the product of R and P.
+	MaxScryptN  = 1 << 20
+	MaxScryptRP = 64
+
+	// MaxArgon2Time, MaxArgon2Memory and MaxArgon2Threads bound the
+	// argon2id parameters.  Memory is in KiB.
+	MaxArgon2Time    = 64
+	MaxArgon2Memory  = 1 << 20
+	MaxArgon2Threads = 64
+)
+
+var (
+	// ErrUnknownAlgorithm describes an error where parameters name an
+	// unknown key derivation function.
+	ErrUnknownAlgorithm = errors.New("unknown key derivation function")
+
+	// ErrInvalidParams describes an error where the parameters of a key
+	// derivation function are out of range.
+	ErrInvalidParams = errors.New("invalid key derivation parameters")
+
+	// ErrUnknownVersion describes an error where serialized parameters are
+	// of a version this package does not know.
+	ErrUnknownVersion = errors.New("unknown key derivation parameters version")
+
+	// ErrInvalidEncoding describes an error where serialized parameters
+	// are truncated or have trailing bytes.
+	ErrInvalidEncoding = errors.New("malformed key derivation parameters")
+)
+
+// Params are the parameters of a key derivation.
+type Params struct {
+	Algorithm Algorithm
+
+	// N, R and P are the cost parameters of scrypt.  N must be a power
+	// of two.
+	N, R, P int
+
+	// Time is the number of passes, Memory the memory in KiB and Threads
+	// the parallelism of argon2id.
+	Time    uint32
+	Memory  uint32
+	Threads uint8
+}
+
+var (
+	// DefaultScrypt are the scrypt parameters used when no others are
+	// chosen.
+	DefaultScrypt = Params{Algorithm: Scrypt, N: 1 << 15, R: 8, P: 1}
+
+	// DefaultArgon2id are the argon2id parameters recommended by RFC 9106
+	// for memory constrained environments.
+	DefaultArgon2id = Params{
+		Algorithm: Argon2id,
+		Time:      3,
+		Memory:    64 * 1024,
+		Threads:   4,
+	}
+)
+
+// Validate returns ErrUnknownAlgorithm when the algorithm of the parameters
+// is unknown, and ErrInvalidParams when they are out of range.
+func (p *Params) Validate() error {
+	switch p.Algorithm {
+	case Scrypt:
+		if p.N <= 1 || p.N > MaxScryptN || p.N&(p.N-1) != 0 ||
+			p.R <= 0 || p.P <= 0 || p.R*p.P > MaxScryptRP {
+			return ErrInvalidParams
+		}
+	case Argon2id:
+		if p.Time == 0 || p.Time > MaxArgon2Time ||
+			p.Threads == 0 || p.Threads > MaxArgon2Threads ||
+			p.Memory < 8*uint32(p.Threads) || p.Memory > MaxArgon2Memory {
+			return ErrInvalidParams
+		}
+	default:
+		return ErrUnknownAlgorithm
+	}
+	return nil
+}
+
+// Key derives a key of keyLen bytes from passphrase and salt.  The
+// parameters are validated first.
+func (p *Params) Key(passphrase, salt []byte, keyLen int) ([]byte, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	if p.Algorithm == Argon2id {
+		return argon2.IDKey(passphrase, salt, p.Time, p.Memory, p.Threads,
+			uint32(keyLen)), nil
+	}
+	return scrypt.Key(passphrase, salt, p.N, p.R, p.P, keyLen)
+}
+
+// String returns the algorithm and parameters in a human readable form.
+func (p Params) String() string {
+	if p.Algorithm == Argon2id {
+		return fmt.Sprintf("%v(t=%d, m=%d, p=%d)", p.Algorithm, p.Time,
+			p.Memory, p.Threads)
+	}
+	return fmt.Sprintf("%v(N=%d, r=%d, p=%d)", p.Algorithm, p.N, p.R, p.P)
+}
+
+// MarshalBinary returns the serialization of the parameters: the version
+// byte, the algorithm byte, and for scrypt N, R and P as 32-bit little endian
+// integers, or for argon2id the time and memory as 32-bit little endian
+// integers followed by the threads byte.
+func (p *Params) MarshalBinary() ([]byte, error) {
+	if err := p.Validate(); err != nil {
+		return nil, err
+	}
+	b := []byte{ParamsVersion, byte(p.Algorithm)}
+	if p.Algorithm == Argon2id {
+		b = binary.LittleEndian.AppendUint32(b, p.Time)
+		b = binary.LittleEndian.AppendUint32(b, p.Memory)
+		return append(b, p.Threads), nil
+	}
+	b = binary.LittleEndian.AppendUint32(b, uint32(p.N))
+	b = binary.LittleEndian.AppendUint32(b, uint32(p.R))
+	return binary.LittleEndian.AppendUint32(b, uint32(p.P)), nil
+}
+
+// UnmarshalBinary replaces the parameters with the ones serialized in data,
+// as returned by MarshalBinary, and validates them.
+func (p *Params) UnmarshalBinary(data []byte) error {
+	if len(data) < 2 {
+		return ErrInvalidEncoding
+	}
+	if data[0] != ParamsVersion1 {
+		return ErrUnknownVersion
+	}
+	parsed := Params{Algorithm: Algorithm(data[1])}
+	body := data[2:]
+	switch parsed.Algorithm {
+	case Scrypt:
+		if len(body) != 12 {
+			return ErrInvalidEncoding
+		}
+		n := binary.LittleEndian.Uint32(body[0:4])
+		r := binary.LittleEndian.Uint32(body[4:8])
+		pp := binary.LittleEndian.Uint32(body[8:12])
+		if n > MaxScryptN || r > MaxScryptRP || pp > MaxScryptRP {
+			return ErrInvalidParams
+		}
+		parsed.N, parsed.R, parsed.P = int(n), int(r), int(pp)
+	case Argon2id:
+		if len(body) != 9 {
+			return ErrInvalidEncoding
+		}
+		parsed.Time = binary.LittleEndian.Uint32(body[0:4])
+		parsed.Memory = binary.LittleEndian.Uint32(body[4:8])
+		parsed.Threads = body[8]
+	default:
+		return ErrUnknownAlgorithm
+	}
+	if err := parsed.Validate(); err != nil {
+		return err
+	}
+	*p = parsed
+	return nil
+}
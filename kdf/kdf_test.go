@@ -0,0 +1,121 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package kdf_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/zeusyf/btcutil/kdf"
+)
+
+// TestKey ensures keys are derived with the algorithm and parameters of the
+// passed parameters.
+func TestKey(t *testing.T) {
+	// Test vector of RFC 7914.
+	p := kdf.Params{Algorithm: kdf.Scrypt, N: 16, R: 1, P: 1}
+	key, err := p.Key(nil, nil, 64)
+	if err != nil {
+		t.Fatalf("Key: unexpected error: %v", err)
+	}
+	want := "77d6576238657b203b19ca42c18a0497f16b4844e3074ae8dfdffa3fede21442" +
+		"fcd0069ded0948f8326a753a0fc81f17e8d3e0fb2e0d3628cf35e20c38d18906"
+	if hex.EncodeToString(key) != want {
+		t.Errorf("Key: got %x, want %s", key, want)
+	}
+
+	a := kdf.Params{Algorithm: kdf.Argon2id, Time: 1, Memory: 64, Threads: 1}
+	k1, err := a.Key([]byte("pass"), []byte("saltsalt"), 32)
+	if err != nil {
+		t.Fatalf("Key: unexpected error: %v", err)
+	}
+	a.Time = 2
+	k2, err := a.Key([]byte("pass"), []byte("saltsalt"), 32)
+	if err != nil {
+		t.Fatalf("Key: unexpected error: %v", err)
+	}
+	if len(k1) != 32 || bytes.Equal(k1, k2) {
+		t.Errorf("Key: got %x and %x", k1, k2)
+	}
+
+	bad := []kdf.Params{
+		{Algorithm: kdf.Scrypt, N: 1000, R: 8, P: 1},
+		{Algorithm: kdf.Scrypt, N: 1 << 21, R: 8, P: 1},
+		{Algorithm: kdf.Scrypt, N: 1 << 10, R: 64, P: 2},
+		{Algorithm: kdf.Argon2id, Time: 0, Memory: 64, Threads: 1},
+		{Algorithm: kdf.Argon2id, Time: 1, Memory: 1 << 21, Threads: 1},
+		{Algorithm: kdf.Argon2id, Time: 1, Memory: 8, Threads: 4},
+	}
+	for _, p := range bad {
+		if _, err := p.Key(nil, nil, 32); err != kdf.ErrInvalidParams {
+			t.Errorf("Key(%v): got error %v, want %v", p, err,
+				kdf.ErrInvalidParams)
+		}
+	}
+	unknown := kdf.Params{Algorithm: 3}
+	if _, err := unknown.Key(nil, nil, 32); err != kdf.ErrUnknownAlgorithm {
+		t.Errorf("Key: got error %v, want %v", err, kdf.ErrUnknownAlgorithm)
+	}
+}
+
+// TestParamsSerialize ensures parameters serialize and parse back, and
+// malformed serializations are rejected.
+func TestParamsSerialize(t *testing.T) {
+	for _, p := range []kdf.Params{kdf.DefaultScrypt, kdf.DefaultArgon2id} {
+		b, err := p.MarshalBinary()
+		if err != nil {
+			t.Fatalf("MarshalBinary(%v): unexpected error: %v", p, err)
+		}
+		if b[0] != kdf.ParamsVersion || b[1] != byte(p.Algorithm) {
+			t.Errorf("MarshalBinary(%v): got %x", p, b)
+		}
+		var parsed kdf.Params
+		if err := parsed.UnmarshalBinary(b); err != nil {
+			t.Fatalf("UnmarshalBinary(%v): unexpected error: %v", p, err)
+		}
+		if parsed != p {
+			t.Errorf("UnmarshalBinary: got %v, want %v", parsed, p)
+		}
+	}
+
+	scrypt, _ := kdf.DefaultScrypt.MarshalBinary()
+	tests := []struct {
+		name string
+		b    []byte
+		err  error
+	}{
+		{"empty", nil, kdf.ErrInvalidEncoding},
+		{"version", append([]byte{2}, scrypt[1:]...), kdf.ErrUnknownVersion},
+		{"algorithm", append([]byte{1, 3}, scrypt[2:]...), kdf.ErrUnknownAlgorithm},
+		{"truncated", scrypt[:len(scrypt)-1], kdf.ErrInvalidEncoding},
+		{"trailing", append(scrypt, 0x00), kdf.ErrInvalidEncoding},
+		{"huge n", []byte{1, 1, 0, 0, 0, 0x80, 8, 0, 0, 0, 1, 0, 0, 0}, kdf.ErrInvalidParams},
+	}
+	for _, test := range tests {
+		var p kdf.Params
+		if err := p.UnmarshalBinary(test.b); err != test.err {
+			t.Errorf("UnmarshalBinary(%s): got error %v, want %v",
+				test.name, err, test.err)
+		}
+	}
+}
+
+// TestParseAlgorithm ensures algorithm names parse back to their algorithm.
+func TestParseAlgorithm(t *testing.T) {
+	for _, a := range []kdf.Algorithm{kdf.Scrypt, kdf.Argon2id} {
+		parsed, err := kdf.ParseAlgorithm(a.String())
+		if err != nil || parsed != a {
+			t.Errorf("ParseAlgorithm(%v): got %v, %v", a, parsed, err)
+		}
+	}
+	if _, err := kdf.ParseAlgorithm("pbkdf2"); err != kdf.ErrUnknownAlgorithm {
+		t.Errorf("ParseAlgorithm: got error %v, want %v", err,
+			kdf.ErrUnknownAlgorithm)
+	}
+	if s := kdf.Algorithm(9).String(); s != "Unknown Algorithm (9)" {
+		t.Errorf("String: got %s", s)
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package keystore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/json"
+	"errors"
+	"io"
+
+	"github.com/zeusyf/btcutil/entropy"
+	"github.com/zeusyf/btcutil/kdf"
+)
+
+const (
+	// saltSize is the size of the random salt of encrypted stores.
+	saltSize = 16
+
+	// keySize is the size of the AES-256 key derived from the passphrase.
+	keySize = 32
+)
+
+var (
+	// ErrWrongPassphrase describes an error where an encrypted store can
+	// not be decrypted with the passphrase, or its ciphertext has been
+	// tampered with.
+	ErrWrongPassphrase = errors.New("wrong passphrase or corrupt keystore")
+
+	// ErrInvalidKDF describes an error where an encrypted store names an
+	// unknown key derivation function or has out of range parameters.
+	ErrInvalidKDF = errors.New("invalid keystore key derivation parameters")
+)
+
+// jsonEncrypted is the JSON form of an encrypted store.  Byte strings are
+// encoded in base64.  N, R and P are the parameters of scrypt, and Time,
+// Memory and Threads the ones of argon2id.
+type jsonEncrypted struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	N          int    `json:"n"`
+	R          int    `json:"r"`
+	P          int    `json:"p"`
+	Time       uint32 `json:"time,omitempty"`
+	Memory     uint32 `json:"memory,omitempty"`
+	Threads    uint8  `json:"threads,omitempty"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// kdfParams returns the key derivation parameters of je.
+func (je *jsonEncrypted) kdfParams() (*kdf.Params, error) {
+	alg, err := kdf.ParseAlgorithm(je.KDF)
+	if err != nil {
+		return nil, ErrInvalidKDF
+	}
+	params := &kdf.Params{
+		Algorithm: alg,
+		N:         je.N,
+		R:         je.R,
+		P:         je.P,
+		Time:      je.Time,
+		Memory:    je.Memory,
+		Threads:   je.Threads,
+	}
+	if params.Validate() != nil {
+		return nil, ErrInvalidKDF
+	}
+	return params, nil
+}
+
+// newAEAD returns the AES-256-GCM cipher keyed by the derivation of
+// passphrase with params and the salt of je.
+func newAEAD(passphrase []byte, params *kdf.Params, je *jsonEncrypted) (cipher.AEAD, error) {
+	key, err := params.Key(passphrase, je.Salt, keySize)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// MarshalEncrypted returns the JSON serialization of the store, as returned
+// by MarshalJSON, encrypted with AES-256-GCM under a key derived from
+// passphrase with the default scrypt parameters of the kdf package.  The salt
+// and nonce are read from src, or from the default source of the entropy
+// package when src is nil.  The result is itself a JSON document holding the
+// key derivation parameters along with the ciphertext.
+func (s *Store) MarshalEncrypted(passphrase []byte, src io.Reader) ([]byte, error) {
+	return s.MarshalEncryptedKDF(passphrase, &kdf.DefaultScrypt, src)
+}
+
+// MarshalEncryptedKDF is like MarshalEncrypted, but derives the key with the
+// passed parameters, such as ones returned by kdf.Calibrate.  ErrInvalidKDF
+// is returned when they are out of the range accepted by UnmarshalEncrypted.
+func (s *Store) MarshalEncryptedKDF(passphrase []byte, params *kdf.Params,
+	src io.Reader) ([]byte, error) {
+	if params.Validate() != nil {
+		return nil, ErrInvalidKDF
+	}
+	plaintext, err := s.MarshalJSON()
+	if err != nil {
+		return nil, err
+	}
+	je := jsonEncrypted{
+		Version: storeVersion,
+		KDF:     params.Algorithm.String(),
+		N:       params.N,
+		R:       params.R,
+		P:       params.P,
+		Time:    params.Time,
+		Memory:  params.Memory,
+		Threads: params.Threads,
+		Salt:    make([]byte, saltSize),
+	}
+	if err := entropy.Read(src, je.Salt); err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(passphrase, params, &je)
+	if err != nil {
+		return nil, err
+	}
+	je.Nonce = make([]byte, aead.NonceSize())
+	if err := entropy.Read(src, je.Nonce); err != nil {
+		return nil, err
+	}
+	je.Ciphertext = aead.Seal(nil, je.Nonce, plaintext, nil)
+	return json.Marshal(&je)
+}
+
+// UnmarshalEncrypted decrypts data, as returned by MarshalEncrypted, with
+// passphrase and replaces the entries of the store with the decrypted ones as
+// UnmarshalJSON does.  ErrWrongPassphrase is returned when data can not be
+// decrypted with passphrase.
+func (s *Store) UnmarshalEncrypted(data, passphrase []byte) error {
+	var je jsonEncrypted
+	if err := json.Unmarshal(data, &je); err != nil {
+		return err
+	}
+	if je.Version > storeVersion {
+		return ErrUnsupportedVersion
+	}
+	params, err := je.kdfParams()
+	if err != nil {
+		return err
+	}
+	if len(je.Salt) == 0 {
+		return ErrInvalidKDF
+	}
+	aead, err := newAEAD(passphrase, params, &je)
+	if err != nil {
+		return err
+	}
+	if len(je.Nonce) != aead.NonceSize() {
+		return ErrWrongPassphrase
+	}
+	plaintext, err := aead.Open(nil, je.Nonce, je.Ciphertext, nil)
+	if err != nil {
+		return ErrWrongPassphrase
+	}
+	return s.UnmarshalJSON(plaintext)
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package keystore
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/hdkeychain"
+)
+
+// storeVersion is the version of the JSON serialization of a store.
+const storeVersion = 1
+
+var (
+	// ErrUnsupportedVersion describes an error where a serialized store
+	// was written with a newer version of the format than this package
+	// understands.
+	ErrUnsupportedVersion = errors.New("unsupported keystore version")
+)
+
+// jsonEntry is the JSON form of an Entry.
+type jsonEntry struct {
+	Address string `json:"address"`
+	Label   string `json:"label,omitempty"`
+	Origin  string `json:"origin,omitempty"`
+}
+
+// jsonStore is the JSON form of a Store.
+type jsonStore struct {
+	Version int         `json:"version"`
+	Network string      `json:"network"`
+	Entries []jsonEntry `json:"entries"`
+}
+
+// MarshalJSON implements the json.Marshaler interface.  The store is
+// serialized as its network name and its entries in the order of Entries,
+// each with its encoded address, label and key origin, as in
+// "d34db33f/44'/0'/0'/0/1".
+func (s *Store) MarshalJSON() ([]byte, error) {
+	js := jsonStore{
+		Version: storeVersion,
+		Network: s.net.Name,
+		Entries: []jsonEntry{},
+	}
+	for _, e := range s.Entries() {
+		je := jsonEntry{
+			Address: e.Address.EncodeAddress(),
+			Label:   e.Label,
+		}
+		if e.Origin != nil {
+			je.Origin = e.Origin.String()
+		}
+		js.Entries = append(js.Entries, je)
+	}
+	return json.Marshal(&js)
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface.  The store must
+// have been created with NewStore, and its entries are replaced by the
+// serialized ones.  ErrWrongNetwork is returned when the serialized store is
+// for another network than that of the store.
+func (s *Store) UnmarshalJSON(data []byte) error {
+	var js jsonStore
+	if err := json.Unmarshal(data, &js); err != nil {
+		return err
+	}
+	if js.Version > storeVersion {
+		return ErrUnsupportedVersion
+	}
+	if js.Network != s.net.Name {
+		return ErrWrongNetwork
+	}
+
+	store := NewStore(s.net)
+	for _, je := range js.Entries {
+		addr, err := btcutil.DecodeAddress(je.Address, s.net)
+		if err != nil {
+			return err
+		}
+		e := Entry{Address: addr, Label: je.Label}
+		if je.Origin != "" {
+			origin, err := hdkeychain.ParseKeyOrigin(je.Origin)
+			if err != nil {
+				return err
+			}
+			e.Origin = &origin
+		}
+		if err := store.Add(e); err != nil {
+			return err
+		}
+	}
+	s.entries = store.entries
+	return nil
+}
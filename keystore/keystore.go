@@ -0,0 +1,183 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package keystore implements a lightweight address book for watch-only
+// wallets.
+//
+// A Store maps the addresses a tool watches to a label and, for addresses of
+// keys derived from an HD wallet, the origin of their key.  Entries are found
+// by address or by the public key script paying to it, so the outputs of a
+// transaction or a block can be matched against the store directly.
+//
+// A Store serializes to JSON, in the clear with MarshalJSON or protected by a
+// passphrase with MarshalEncrypted, so command line tools can keep their
+// watch-only wallets in a file without pulling in a full wallet.
+//
+// A Store is not safe for concurrent use.
+package keystore
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcd/txscript"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/hdkeychain"
+)
+
+var (
+	// ErrWrongNetwork describes an error where an address, or a serialized
+	// store, is for another network than that of the store.
+	ErrWrongNetwork = errors.New("address is for the wrong network")
+
+	// ErrDuplicateAddress describes an error where an address is added to
+	// a store which already holds it.
+	ErrDuplicateAddress = errors.New("address already in store")
+
+	// ErrAddressNotFound describes an error where an address is not held
+	// by the store.
+	ErrAddressNotFound = errors.New("address not in store")
+)
+
+// Entry is an address held by a Store.
+type Entry struct {
+	// Address is the watched address.
+	Address btcutil.Address
+
+	// Label is a free form description of the address, such as the name
+	// of the payer it was handed to.
+	Label string
+
+	// Origin is the origin of the key of the address when it was derived
+	// from an HD wallet, or nil otherwise.
+	Origin *hdkeychain.KeyOrigin
+}
+
+// clone returns a copy of the entry which does not share its origin.
+func (e *Entry) clone() *Entry {
+	c := *e
+	if e.Origin != nil {
+		origin := hdkeychain.KeyOrigin{
+			Fingerprint: e.Origin.Fingerprint,
+			Path:        append(hdkeychain.DerivationPath(nil), e.Origin.Path...),
+		}
+		c.Origin = &origin
+	}
+	return &c
+}
+
+// Store is an address book of watched addresses.  The zero value is not
+// usable; a Store must be created with NewStore.
+type Store struct {
+	net *chaincfg.Params
+
+	// entries holds the entries of the store keyed by the public key
+	// script paying to their address.
+	entries map[string]*Entry
+}
+
+// NewStore returns an empty store for addresses of the network net.
+func NewStore(net *chaincfg.Params) *Store {
+	return &Store{
+		net:     net,
+		entries: make(map[string]*Entry),
+	}
+}
+
+// Net returns the network of the addresses of the store.
+func (s *Store) Net() *chaincfg.Params {
+	return s.net
+}
+
+// Len returns the number of entries of the store.
+func (s *Store) Len() int {
+	return len(s.entries)
+}
+
+// Add adds a copy of e to the store.  ErrWrongNetwork is returned when its
+// address is for another network than that of the store, and
+// ErrDuplicateAddress when the store already holds it.
+func (s *Store) Add(e Entry) error {
+	if !e.Address.IsForNet(s.net) {
+		return ErrWrongNetwork
+	}
+	pkScript, err := txscript.PayToAddrScript(e.Address)
+	if err != nil {
+		return err
+	}
+	if _, ok := s.entries[string(pkScript)]; ok {
+		return ErrDuplicateAddress
+	}
+	s.entries[string(pkScript)] = e.clone()
+	return nil
+}
+
+// entry returns the entry of addr held by the store, or nil.
+func (s *Store) entry(addr btcutil.Address) *Entry {
+	pkScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		return nil
+	}
+	return s.entries[string(pkScript)]
+}
+
+// Remove removes addr from the store and returns whether it held it.
+func (s *Store) Remove(addr btcutil.Address) bool {
+	pkScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		return false
+	}
+	if _, ok := s.entries[string(pkScript)]; !ok {
+		return false
+	}
+	delete(s.entries, string(pkScript))
+	return true
+}
+
+// SetLabel sets the label of addr.  ErrAddressNotFound is returned when the
+// store does not hold addr.
+func (s *Store) SetLabel(addr btcutil.Address, label string) error {
+	e := s.entry(addr)
+	if e == nil {
+		return ErrAddressNotFound
+	}
+	e.Label = label
+	return nil
+}
+
+// Lookup returns a copy of the entry of addr, and false when the store does
+// not hold addr.
+func (s *Store) Lookup(addr btcutil.Address) (*Entry, bool) {
+	e := s.entry(addr)
+	if e == nil {
+		return nil, false
+	}
+	return e.clone(), true
+}
+
+// LookupScript returns a copy of the entry of the address pkScript pays to,
+// and false when the store does not hold such an address.  It is the lookup
+// used to find the outputs of a transaction paying the store.
+func (s *Store) LookupScript(pkScript []byte) (*Entry, bool) {
+	e, ok := s.entries[string(pkScript)]
+	if !ok {
+		return nil, false
+	}
+	return e.clone(), true
+}
+
+// Entries returns copies of the entries of the store in ascending order of
+// encoded address.
+func (s *Store) Entries() []Entry {
+	entries := make([]Entry, 0, len(s.entries))
+	for _, e := range s.entries {
+		entries = append(entries, *e.clone())
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Address.EncodeAddress() <
+			entries[j].Address.EncodeAddress()
+	})
+	return entries
+}
@@ -0,0 +1,241 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package keystore_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcd/txscript"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/hdkeychain"
+	"github.com/zeusyf/btcutil/kdf"
+	"github.com/zeusyf/btcutil/keystore"
+)
+
+// testStore returns a store of the main network holding a pay-to-pubkey-hash
+// address with a key origin and a pay-to-script-hash address without one.
+func testStore(t *testing.T) (*keystore.Store, btcutil.Address, btcutil.Address) {
+	t.Helper()
+	net := &chaincfg.MainNetParams
+	pkh, err := btcutil.NewAddressPubKeyHash(bytes.Repeat([]byte{1}, 20), net)
+	if err != nil {
+		t.Fatalf("NewAddressPubKeyHash: %v", err)
+	}
+	sh, err := btcutil.NewAddressScriptHashFromHash(bytes.Repeat([]byte{2}, 20), net)
+	if err != nil {
+		t.Fatalf("NewAddressScriptHashFromHash: %v", err)
+	}
+	origin, err := hdkeychain.ParseKeyOrigin("d34db33f/44'/0'/0'/0/1")
+	if err != nil {
+		t.Fatalf("ParseKeyOrigin: %v", err)
+	}
+
+	s := keystore.NewStore(net)
+	if err := s.Add(keystore.Entry{Address: pkh, Label: "alice",
+		Origin: &origin}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if err := s.Add(keystore.Entry{Address: sh, Label: "vault"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	return s, pkh, sh
+}
+
+// TestStore ensures entries are added, found by address and script, relabeled
+// and removed.
+func TestStore(t *testing.T) {
+	s, pkh, sh := testStore(t)
+	if s.Len() != 2 {
+		t.Fatalf("Len: got %d, want 2", s.Len())
+	}
+	if err := s.Add(keystore.Entry{Address: pkh}); err != keystore.ErrDuplicateAddress {
+		t.Errorf("Add: got error %v, want %v", err,
+			keystore.ErrDuplicateAddress)
+	}
+	testnet, err := btcutil.NewAddressPubKeyHash(bytes.Repeat([]byte{3}, 20),
+		&chaincfg.TestNet3Params)
+	if err != nil {
+		t.Fatalf("NewAddressPubKeyHash: %v", err)
+	}
+	if err := s.Add(keystore.Entry{Address: testnet}); err != keystore.ErrWrongNetwork {
+		t.Errorf("Add: got error %v, want %v", err, keystore.ErrWrongNetwork)
+	}
+
+	e, ok := s.Lookup(pkh)
+	if !ok || e.Label != "alice" || e.Origin == nil ||
+		e.Origin.String() != "d34db33f/44'/0'/0'/0/1" {
+		t.Fatalf("Lookup: got %+v, %v", e, ok)
+	}
+	e.Label = "changed"
+	e.Origin.Path[0] = 0
+	if e, _ := s.Lookup(pkh); e.Label != "alice" ||
+		e.Origin.String() != "d34db33f/44'/0'/0'/0/1" {
+		t.Errorf("Lookup: returned entry shares state with the store")
+	}
+
+	pkScript, err := txscript.PayToAddrScript(sh)
+	if err != nil {
+		t.Fatalf("PayToAddrScript: %v", err)
+	}
+	if e, ok := s.LookupScript(pkScript); !ok || e.Label != "vault" ||
+		e.Origin != nil {
+		t.Errorf("LookupScript: got %+v, %v", e, ok)
+	}
+	if _, ok := s.LookupScript([]byte{txscript.OP_TRUE}); ok {
+		t.Errorf("LookupScript: found an unknown script")
+	}
+
+	if err := s.SetLabel(sh, "cold"); err != nil {
+		t.Fatalf("SetLabel: %v", err)
+	}
+	if e, _ := s.Lookup(sh); e.Label != "cold" {
+		t.Errorf("SetLabel: got label %q, want %q", e.Label, "cold")
+	}
+	if err := s.SetLabel(testnet, "x"); err != keystore.ErrAddressNotFound {
+		t.Errorf("SetLabel: got error %v, want %v", err,
+			keystore.ErrAddressNotFound)
+	}
+
+	entries := s.Entries()
+	if len(entries) != 2 || entries[0].Address.EncodeAddress() >
+		entries[1].Address.EncodeAddress() {
+		t.Errorf("Entries: got %v", entries)
+	}
+
+	if !s.Remove(pkh) || s.Remove(pkh) || s.Len() != 1 {
+		t.Errorf("Remove: unexpected result")
+	}
+	if _, ok := s.Lookup(pkh); ok {
+		t.Errorf("Lookup: found a removed address")
+	}
+}
+
+// TestStoreJSON ensures stores round trip through their JSON serialization
+// and stores of another network are rejected.
+func TestStoreJSON(t *testing.T) {
+	s, pkh, _ := testStore(t)
+	data, err := json.Marshal(s)
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	parsed := keystore.NewStore(&chaincfg.MainNetParams)
+	if err := json.Unmarshal(data, parsed); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	again, err := json.Marshal(parsed)
+	if err != nil || !bytes.Equal(again, data) {
+		t.Fatalf("Marshal: got %s, %v, want %s", again, err, data)
+	}
+	if e, ok := parsed.Lookup(pkh); !ok || e.Label != "alice" ||
+		e.Origin.String() != "d34db33f/44'/0'/0'/0/1" {
+		t.Errorf("Lookup: got %+v, %v", e, ok)
+	}
+
+	other := keystore.NewStore(&chaincfg.TestNet3Params)
+	if err := json.Unmarshal(data, other); err != keystore.ErrWrongNetwork {
+		t.Errorf("Unmarshal: got error %v, want %v", err,
+			keystore.ErrWrongNetwork)
+	}
+	future := []byte(`{"version":2,"network":"mainnet","entries":[]}`)
+	if err := json.Unmarshal(future, parsed); err != keystore.ErrUnsupportedVersion {
+		t.Errorf("Unmarshal: got error %v, want %v", err,
+			keystore.ErrUnsupportedVersion)
+	}
+	if parsed.Len() != 2 {
+		t.Errorf("Unmarshal: a failed unmarshal changed the store")
+	}
+}
+
+// TestStoreEncrypted ensures encrypted stores decrypt with their passphrase
+// only and crafted key derivation parameters are rejected.
+func TestStoreEncrypted(t *testing.T) {
+	s, _, sh := testStore(t)
+	passphrase := []byte("correct horse battery staple")
+	data, err := s.MarshalEncrypted(passphrase, nil)
+	if err != nil {
+		t.Fatalf("MarshalEncrypted: %v", err)
+	}
+	if bytes.Contains(data, []byte("vault")) {
+		t.Fatalf("MarshalEncrypted: label in the clear")
+	}
+
+	parsed := keystore.NewStore(&chaincfg.MainNetParams)
+	if err := parsed.UnmarshalEncrypted(data, []byte("wrong")); err != keystore.ErrWrongPassphrase {
+		t.Fatalf("UnmarshalEncrypted: got error %v, want %v", err,
+			keystore.ErrWrongPassphrase)
+	}
+	if err := parsed.UnmarshalEncrypted(data, passphrase); err != nil {
+		t.Fatalf("UnmarshalEncrypted: %v", err)
+	}
+	if e, ok := parsed.Lookup(sh); !ok || e.Label != "vault" {
+		t.Errorf("Lookup: got %+v, %v", e, ok)
+	}
+
+	var fields map[string]interface{}
+	if err := json.Unmarshal(data, &fields); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	for _, param := range []struct {
+		name  string
+		value interface{}
+	}{
+		{"kdf", "pbkdf2"},
+		{"n", 1 << 30},
+		{"n", 1000},
+		{"r", 1 << 10},
+	} {
+		crafted := make(map[string]interface{})
+		for k, v := range fields {
+			crafted[k] = v
+		}
+		crafted[param.name] = param.value
+		craftedData, err := json.Marshal(crafted)
+		if err != nil {
+			t.Fatalf("Marshal: %v", err)
+		}
+		err = parsed.UnmarshalEncrypted(craftedData, passphrase)
+		if err != keystore.ErrInvalidKDF {
+			t.Errorf("UnmarshalEncrypted %s=%v: got error %v, want %v",
+				param.name, param.value, err, keystore.ErrInvalidKDF)
+		}
+	}
+}
+
+// TestStoreEncryptedKDF ensures stores encrypted with chosen key derivation
+// parameters decrypt with the parameters stored along with them.
+func TestStoreEncryptedKDF(t *testing.T) {
+	s, _, sh := testStore(t)
+	passphrase := []byte("correct horse battery staple")
+	params := kdf.Params{Algorithm: kdf.Argon2id, Time: 1, Memory: 1024, Threads: 1}
+	data, err := s.MarshalEncryptedKDF(passphrase, &params, nil)
+	if err != nil {
+		t.Fatalf("MarshalEncryptedKDF: %v", err)
+	}
+	if !bytes.Contains(data, []byte(`"kdf":"argon2id"`)) {
+		t.Errorf("MarshalEncryptedKDF: got %s", data)
+	}
+
+	parsed := keystore.NewStore(&chaincfg.MainNetParams)
+	if err := parsed.UnmarshalEncrypted(data, []byte("wrong")); err != keystore.ErrWrongPassphrase {
+		t.Errorf("UnmarshalEncrypted: got error %v, want %v", err,
+			keystore.ErrWrongPassphrase)
+	}
+	if err := parsed.UnmarshalEncrypted(data, passphrase); err != nil {
+		t.Fatalf("UnmarshalEncrypted: %v", err)
+	}
+	if e, ok := parsed.Lookup(sh); !ok || e.Label != "vault" {
+		t.Errorf("Lookup: got %+v, %v", e, ok)
+	}
+
+	params.Memory = 1 << 30
+	if _, err := s.MarshalEncryptedKDF(passphrase, &params, nil); err != keystore.ErrInvalidKDF {
+		t.Errorf("MarshalEncryptedKDF: got error %v, want %v", err,
+			keystore.ErrInvalidKDF)
+	}
+}
@@ -0,0 +1,168 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package kvcodec gives amounts, fee rates and outpoints fixed-width binary
+// encodings, for services keeping them in key-value stores.
+//
+// The encodings never change and compare, byte by byte, in the order of the
+// values they encode, so they serve as keys of ordered stores:
+//
+//   - Amount and FeeRate are 8 bytes, the big endian two's complement of the
+//     value with its sign bit inverted, so negative values sort first.
+//   - OutPoint is 36 bytes, the 32 bytes of the transaction hash in their
+//     wire order, which is the reverse of the order the hash is displayed
+//     in, followed by the big endian output index.
+//
+// The types of this package wrap those of btcutil and wire with these
+// encodings as their MarshalBinary and GobEncode methods, and are registered
+// with package gob under names which do not depend on the import path, so
+// values stored in interfaces decode after the package moves.  Composite keys
+// are built with the Append functions.
+package kvcodec
+
+import (
+	"encoding/binary"
+	"encoding/gob"
+	"errors"
+
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+)
+
+const (
+	// AmountSize is the size of an encoded amount.
+	AmountSize = 8
+
+	// FeeRateSize is the size of an encoded fee rate.
+	FeeRateSize = 8
+
+	// OutPointSize is the size of an encoded outpoint.
+	OutPointSize = chainhash.HashSize + 4
+)
+
+// signBit is the bit inverted in encoded signed integers.
+const signBit = 1 << 63
+
+// ErrInvalidLength describes an error where an encoding is decoded from data
+// of another size than its fixed one.
+var ErrInvalidLength = errors.New("encoding has the wrong length")
+
+func init() {
+	gob.RegisterName("btcutil/kvcodec.Amount", Amount(0))
+	gob.RegisterName("btcutil/kvcodec.FeeRate", FeeRate(0))
+	gob.RegisterName("btcutil/kvcodec.OutPoint", OutPoint{})
+}
+
+// appendInt64 appends the encoding of the signed integer n to b.
+func appendInt64(b []byte, n int64) []byte {
+	return binary.BigEndian.AppendUint64(b, uint64(n)^signBit)
+}
+
+// readInt64 returns the signed integer encoded by data.
+func readInt64(data []byte) int64 {
+	return int64(binary.BigEndian.Uint64(data) ^ signBit)
+}
+
+// AppendAmount appends the encoding of a to b and returns the extended
+// slice.
+func AppendAmount(b []byte, a btcutil.Amount) []byte {
+	return appendInt64(b, int64(a))
+}
+
+// AppendFeeRate appends the encoding of r to b and returns the extended
+// slice.
+func AppendFeeRate(b []byte, r btcutil.FeeRate) []byte {
+	return appendInt64(b, int64(r))
+}
+
+// AppendOutPoint appends the encoding of op to b and returns the extended
+// slice.
+func AppendOutPoint(b []byte, op wire.OutPoint) []byte {
+	b = append(b, op.Hash[:]...)
+	return binary.BigEndian.AppendUint32(b, op.Index)
+}
+
+// Amount is a btcutil.Amount with a fixed-width binary encoding.
+type Amount btcutil.Amount
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface.
+func (a Amount) MarshalBinary() ([]byte, error) {
+	return AppendAmount(make([]byte, 0, AmountSize), btcutil.Amount(a)), nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface.
+func (a *Amount) UnmarshalBinary(data []byte) error {
+	if len(data) != AmountSize {
+		return ErrInvalidLength
+	}
+	*a = Amount(readInt64(data))
+	return nil
+}
+
+// GobEncode implements the gob.GobEncoder interface with the binary
+// encoding, rather than the text one of btcutil.Amount.
+func (a Amount) GobEncode() ([]byte, error) {
+	return a.MarshalBinary()
+}
+
+// GobDecode implements the gob.GobDecoder interface.
+func (a *Amount) GobDecode(data []byte) error {
+	return a.UnmarshalBinary(data)
+}
+
+// FeeRate is a btcutil.FeeRate with a fixed-width binary encoding.
+type FeeRate btcutil.FeeRate
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface.
+func (r FeeRate) MarshalBinary() ([]byte, error) {
+	return AppendFeeRate(make([]byte, 0, FeeRateSize), btcutil.FeeRate(r)), nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface.
+func (r *FeeRate) UnmarshalBinary(data []byte) error {
+	if len(data) != FeeRateSize {
+		return ErrInvalidLength
+	}
+	*r = FeeRate(readInt64(data))
+	return nil
+}
+
+// GobEncode implements the gob.GobEncoder interface.
+func (r FeeRate) GobEncode() ([]byte, error) {
+	return r.MarshalBinary()
+}
+
+// GobDecode implements the gob.GobDecoder interface.
+func (r *FeeRate) GobDecode(data []byte) error {
+	return r.UnmarshalBinary(data)
+}
+
+// OutPoint is a wire.OutPoint with a fixed-width binary encoding.
+type OutPoint wire.OutPoint
+
+// MarshalBinary implements the encoding.BinaryMarshaler interface.
+func (op OutPoint) MarshalBinary() ([]byte, error) {
+	return AppendOutPoint(make([]byte, 0, OutPointSize), wire.OutPoint(op)), nil
+}
+
+// UnmarshalBinary implements the encoding.BinaryUnmarshaler interface.
+func (op *OutPoint) UnmarshalBinary(data []byte) error {
+	if len(data) != OutPointSize {
+		return ErrInvalidLength
+	}
+	copy(op.Hash[:], data[:chainhash.HashSize])
+	op.Index = binary.BigEndian.Uint32(data[chainhash.HashSize:])
+	return nil
+}
+
+// GobEncode implements the gob.GobEncoder interface.
+func (op OutPoint) GobEncode() ([]byte, error) {
+	return op.MarshalBinary()
+}
+
+// GobDecode implements the gob.GobDecoder interface.
+func (op *OutPoint) GobDecode(data []byte) error {
+	return op.UnmarshalBinary(data)
+}
@@ -0,0 +1,143 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package kvcodec_test
+
+import (
+	"bytes"
+	"encoding/gob"
+	"encoding/hex"
+	"math"
+	"testing"
+
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/kvcodec"
+)
+
+// TestAmount ensures amounts encode to their fixed bytes, decode back, and
+// compare in numeric order.
+func TestAmount(t *testing.T) {
+	tests := []struct {
+		amount btcutil.Amount
+		enc    string
+	}{
+		{math.MinInt64, "0000000000000000"},
+		{-1, "7fffffffffffffff"},
+		{0, "8000000000000000"},
+		{1, "8000000000000001"},
+		{btcutil.MaxHao, "8098c445ad578000"},
+		{math.MaxInt64, "ffffffffffffffff"},
+	}
+	var prev []byte
+	for _, test := range tests {
+		enc, err := kvcodec.Amount(test.amount).MarshalBinary()
+		if err != nil {
+			t.Fatalf("MarshalBinary: unexpected error: %v", err)
+		}
+		if hex.EncodeToString(enc) != test.enc {
+			t.Errorf("MarshalBinary(%d): got %x, want %s", test.amount,
+				enc, test.enc)
+		}
+		if prev != nil && bytes.Compare(prev, enc) >= 0 {
+			t.Errorf("MarshalBinary(%d): encoding out of order",
+				test.amount)
+		}
+		prev = enc
+
+		var a kvcodec.Amount
+		if err := a.UnmarshalBinary(enc); err != nil ||
+			btcutil.Amount(a) != test.amount {
+			t.Errorf("UnmarshalBinary(%x): got %d, %v", enc, a, err)
+		}
+	}
+
+	var a kvcodec.Amount
+	if err := a.UnmarshalBinary(make([]byte, 7)); err != kvcodec.ErrInvalidLength {
+		t.Errorf("UnmarshalBinary: got error %v, want %v", err,
+			kvcodec.ErrInvalidLength)
+	}
+}
+
+// TestFeeRate ensures fee rates encode as amounts do.
+func TestFeeRate(t *testing.T) {
+	r := btcutil.NewFeeRateFromHaoPerKVByte(1000)
+	enc, _ := kvcodec.FeeRate(r).MarshalBinary()
+	if want := kvcodec.AppendAmount(nil, btcutil.Amount(r)); !bytes.Equal(enc, want) {
+		t.Errorf("MarshalBinary: got %x, want %x", enc, want)
+	}
+	var got kvcodec.FeeRate
+	if err := got.UnmarshalBinary(enc); err != nil ||
+		btcutil.FeeRate(got) != r {
+		t.Errorf("UnmarshalBinary: got %d, %v", got, err)
+	}
+	if err := got.UnmarshalBinary(append(enc, 0)); err != kvcodec.ErrInvalidLength {
+		t.Errorf("UnmarshalBinary: got error %v, want %v", err,
+			kvcodec.ErrInvalidLength)
+	}
+}
+
+// TestOutPoint ensures outpoints encode as their hash followed by their big
+// endian index, and order by hash and then index.
+func TestOutPoint(t *testing.T) {
+	op := wire.OutPoint{Hash: [32]byte{0x01, 0x02}, Index: 0x0a0b0c0d}
+	enc, _ := kvcodec.OutPoint(op).MarshalBinary()
+	want := "0102" + hex.EncodeToString(make([]byte, 30)) + "0a0b0c0d"
+	if hex.EncodeToString(enc) != want {
+		t.Errorf("MarshalBinary: got %x, want %s", enc, want)
+	}
+	var got kvcodec.OutPoint
+	if err := got.UnmarshalBinary(enc); err != nil || wire.OutPoint(got) != op {
+		t.Errorf("UnmarshalBinary: got %v, %v", got, err)
+	}
+	if err := got.UnmarshalBinary(enc[1:]); err != kvcodec.ErrInvalidLength {
+		t.Errorf("UnmarshalBinary: got error %v, want %v", err,
+			kvcodec.ErrInvalidLength)
+	}
+
+	next := op
+	next.Index++
+	if bytes.Compare(enc, kvcodec.AppendOutPoint(nil, next)) >= 0 {
+		t.Errorf("AppendOutPoint: index out of order")
+	}
+	other := wire.OutPoint{Hash: [32]byte{0x01, 0x03}}
+	if bytes.Compare(enc, kvcodec.AppendOutPoint(nil, other)) >= 0 {
+		t.Errorf("AppendOutPoint: hash out of order")
+	}
+
+	// A composite key of a fee rate and an outpoint.
+	key := kvcodec.AppendOutPoint(kvcodec.AppendFeeRate(nil, 5), op)
+	if len(key) != kvcodec.FeeRateSize+kvcodec.OutPointSize ||
+		!bytes.Equal(key[kvcodec.FeeRateSize:], enc) {
+		t.Errorf("composite key: got %x", key)
+	}
+}
+
+// record is a struct persisted with gob.
+type record struct {
+	Amount   kvcodec.Amount
+	OutPoint kvcodec.OutPoint
+	Value    interface{}
+}
+
+// TestGob ensures the types round trip through gob, also when held by an
+// interface.
+func TestGob(t *testing.T) {
+	in := record{
+		Amount:   -5,
+		OutPoint: kvcodec.OutPoint{Hash: [32]byte{0xff}, Index: 3},
+		Value:    kvcodec.FeeRate(2500),
+	}
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(&in); err != nil {
+		t.Fatalf("Encode: unexpected error: %v", err)
+	}
+	var out record
+	if err := gob.NewDecoder(&buf).Decode(&out); err != nil {
+		t.Fatalf("Decode: unexpected error: %v", err)
+	}
+	if out != in {
+		t.Errorf("Decode: got %+v, want %+v", out, in)
+	}
+}
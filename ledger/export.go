@@ -0,0 +1,167 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ledger
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/zeusyf/btcutil"
+)
+
+// CSVHeader is the header row written by WriteCSV.
+var CSVHeader = []string{
+	"time", "type", "direction", "account", "token_type", "amount",
+	"signed_amount", "txid", "index", "memo",
+}
+
+// direction returns "credit" or "debit" for the entry type.
+func direction(t EntryType) string {
+	if t.IsCredit() {
+		return "credit"
+	}
+	return "debit"
+}
+
+// formatAmount formats an amount as an exact decimal number of OMC.
+func formatAmount(a btcutil.Amount) string {
+	text, _ := a.MarshalText()
+	return string(text)
+}
+
+// WriteCSV writes the entries to w as CSV with a header row.  Each row has
+// both the unsigned amount with the type and direction of the entry, and the
+// signed amount, so imports neither need to infer the type of an entry from
+// its sign nor the sign from its type.  Times are written in RFC 3339 in UTC.
+func WriteCSV(w io.Writer, entries []Entry) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(CSVHeader); err != nil {
+		return err
+	}
+	for i := range entries {
+		e := &entries[i]
+		if err := e.Validate(); err != nil {
+			return err
+		}
+		err := cw.Write([]string{
+			e.Time.UTC().Format(time.RFC3339),
+			e.Type.String(),
+			direction(e.Type),
+			e.Account,
+			strconv.FormatUint(e.TokenType, 10),
+			formatAmount(e.Amount),
+			formatAmount(e.SignedAmount()),
+			e.TxHash.String(),
+			strconv.FormatUint(uint64(e.Index), 10),
+			e.Memo,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ofxTypes maps entry types to the OFX transaction types they are exported
+// as.
+var ofxTypes = map[EntryType]string{
+	EntryReceive:     "CREDIT",
+	EntrySend:        "DEBIT",
+	EntryFee:         "FEE",
+	EntryRebate:      "CREDIT",
+	EntryTransferIn:  "XFER",
+	EntryTransferOut: "XFER",
+}
+
+// ofxTime formats t as an OFX date and time.
+func ofxTime(t time.Time) string {
+	return t.UTC().Format("20060102150405")
+}
+
+// ofxEscape escapes the characters OFX reserves in element content.
+func ofxEscape(s string) string {
+	var buf []byte
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '&':
+			buf = append(buf, "&amp;"...)
+		case '<':
+			buf = append(buf, "&lt;"...)
+		case '>':
+			buf = append(buf, "&gt;"...)
+		default:
+			buf = append(buf, s[i])
+		}
+	}
+	return string(buf)
+}
+
+// WriteOFX writes the entries of account in tokenType to w as an OFX 2 bank
+// statement.  Entries of other accounts or token types are skipped, since a
+// statement has a single currency.  Fees are exported with the FEE
+// transaction type and internal transfers with XFER, amounts are signed as
+// OFX requires, and the FITID of each transaction is made of the transaction
+// hash, entry type and index of its entry so it is stable across exports.
+func WriteOFX(w io.Writer, account string, tokenType uint64,
+	entries []Entry) error {
+	var start, end time.Time
+	var selected []*Entry
+	for i := range entries {
+		e := &entries[i]
+		if err := e.Validate(); err != nil {
+			return err
+		}
+		if e.Account != account || e.TokenType != tokenType {
+			continue
+		}
+		if start.IsZero() || e.Time.Before(start) {
+			start = e.Time
+		}
+		if e.Time.After(end) {
+			end = e.Time
+		}
+		selected = append(selected, e)
+	}
+
+	_, err := fmt.Fprintf(w, "<?xml version=\"1.0\" encoding=\"UTF-8\"?>\n"+
+		"<?OFX OFXHEADER=\"200\" VERSION=\"220\" SECURITY=\"NONE\" "+
+		"OLDFILEUID=\"NONE\" NEWFILEUID=\"NONE\"?>\n"+
+		"<OFX><BANKMSGSRSV1><STMTTRNRS><TRNUID>0</TRNUID>"+
+		"<STATUS><CODE>0</CODE><SEVERITY>INFO</SEVERITY></STATUS>"+
+		"<STMTRS><CURDEF>OMC</CURDEF><BANKACCTFROM><BANKID>OMC</BANKID>"+
+		"<ACCTID>%s</ACCTID><ACCTTYPE>CHECKING</ACCTTYPE></BANKACCTFROM>\n"+
+		"<BANKTRANLIST><DTSTART>%s</DTSTART><DTEND>%s</DTEND>\n",
+		ofxEscape(account), ofxTime(start), ofxTime(end))
+	if err != nil {
+		return err
+	}
+	for _, e := range selected {
+		_, err := fmt.Fprintf(w, "<STMTTRN><TRNTYPE>%s</TRNTYPE>"+
+			"<DTPOSTED>%s</DTPOSTED><TRNAMT>%s</TRNAMT>"+
+			"<FITID>%s:%s:%d</FITID><NAME>%s</NAME>",
+			ofxTypes[e.Type], ofxTime(e.Time),
+			formatAmount(e.SignedAmount()), e.TxHash, e.Type, e.Index,
+			e.Type)
+		if err != nil {
+			return err
+		}
+		if e.Memo != "" {
+			_, err = fmt.Fprintf(w, "<MEMO>%s</MEMO>", ofxEscape(e.Memo))
+			if err != nil {
+				return err
+			}
+		}
+		if _, err := io.WriteString(w, "</STMTTRN>\n"); err != nil {
+			return err
+		}
+	}
+	_, err = io.WriteString(w, "</BANKTRANLIST></STMTRS></STMTTRNRS>"+
+		"</BANKMSGSRSV1></OFX>\n")
+	return err
+}
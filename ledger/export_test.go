@@ -0,0 +1,86 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ledger_test
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+
+	"github.com/zeusyf/btcutil/ledger"
+)
+
+// TestWriteCSV tests the type, direction and sign of each entry are exported.
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := ledger.WriteCSV(&buf, testEntries()); err != nil {
+		t.Fatalf("WriteCSV: %v", err)
+	}
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("reading CSV: %v", err)
+	}
+	if len(rows) != 6 {
+		t.Fatalf("got %d rows, want 6", len(rows))
+	}
+	if strings.Join(rows[0], ",") != strings.Join(ledger.CSVHeader, ",") {
+		t.Errorf("header: got %v", rows[0])
+	}
+
+	tests := []struct {
+		typ, direction, amount, signed string
+	}{
+		{"receive", "credit", "5", "5"},
+		{"transfer_out", "debit", "2", "-2"},
+		{"fee", "debit", "0.00001", "-0.00001"},
+		{"transfer_in", "credit", "2", "2"},
+		{"rebate", "credit", "0.0000025", "0.0000025"},
+	}
+	for i, test := range tests {
+		row := rows[i+1]
+		if row[1] != test.typ || row[2] != test.direction ||
+			row[5] != test.amount || row[6] != test.signed {
+			t.Errorf("row %d: got %v, want %v", i+1, row, test)
+		}
+	}
+	if rows[1][0] != "2021-03-01T12:00:00Z" || rows[1][9] != "salary" {
+		t.Errorf("row 1: got %v", rows[1])
+	}
+}
+
+// TestWriteOFX tests the statement of an account maps entry types to OFX
+// transaction types with signed amounts.
+func TestWriteOFX(t *testing.T) {
+	var buf bytes.Buffer
+	if err := ledger.WriteOFX(&buf, "savings", 0, testEntries()); err != nil {
+		t.Fatalf("WriteOFX: %v", err)
+	}
+	ofx := buf.String()
+
+	for _, want := range []string{
+		"<ACCTID>savings</ACCTID>",
+		"<DTSTART>20210301120000</DTSTART><DTEND>20210301140000</DTEND>",
+		"<TRNTYPE>CREDIT</TRNTYPE><DTPOSTED>20210301120000</DTPOSTED>" +
+			"<TRNAMT>5</TRNAMT>",
+		"<TRNTYPE>XFER</TRNTYPE><DTPOSTED>20210301130000</DTPOSTED>" +
+			"<TRNAMT>-2</TRNAMT>",
+		"<TRNTYPE>FEE</TRNTYPE><DTPOSTED>20210301130000</DTPOSTED>" +
+			"<TRNAMT>-0.00001</TRNAMT>",
+		"<TRNTYPE>CREDIT</TRNTYPE><DTPOSTED>20210301140000</DTPOSTED>" +
+			"<TRNAMT>0.0000025</TRNAMT>",
+		"<MEMO>salary</MEMO>",
+	} {
+		if !strings.Contains(ofx, want) {
+			t.Errorf("statement lacks %q:\n%s", want, ofx)
+		}
+	}
+	if n := strings.Count(ofx, "<STMTTRN>"); n != 4 {
+		t.Errorf("got %d transactions, want 4", n)
+	}
+	if strings.Contains(ofx, "spending") {
+		t.Errorf("statement has entries of another account:\n%s", ofx)
+	}
+}
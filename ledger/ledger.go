@@ -0,0 +1,217 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package ledger records the movements of funds of a wallet as typed ledger
+// entries and exports them for accounting.
+//
+// Every Entry has an explicit EntryType which determines whether it credits
+// or debits its account, so fees, rebates and transfers between the accounts
+// of a wallet are told apart from payments without relying on the sign of an
+// amount or on conventions of the importing software.  Amounts of entries are
+// magnitudes, and SignedAmount applies the sign of the type.
+//
+// WriteCSV and WriteOFX export entries as CSV or as an OFX statement.
+package ledger
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcutil"
+)
+
+var (
+	// ErrUnknownEntryType describes an error where an entry has a type
+	// which is not one of the defined entry types.
+	ErrUnknownEntryType = errors.New("unknown ledger entry type")
+
+	// ErrNegativeAmount describes an error where an entry has a negative
+	// amount.  The direction of an entry is given by its type, so amounts
+	// are always magnitudes.
+	ErrNegativeAmount = errors.New("ledger entry amount is negative")
+
+	// ErrUnbalancedTransfer describes an error where the incoming and
+	// outgoing transfer entries of a transaction do not match, so a
+	// transfer between accounts would change the total of the wallet.
+	ErrUnbalancedTransfer = errors.New("internal transfer entries do not " +
+		"balance")
+)
+
+// EntryType is the type of a ledger entry.
+type EntryType uint8
+
+const (
+	// EntryReceive is a payment received from outside the wallet.
+	EntryReceive EntryType = iota
+
+	// EntrySend is a payment sent outside the wallet.
+	EntrySend
+
+	// EntryFee is a transaction fee paid by the account.
+	EntryFee
+
+	// EntryRebate is a refund of fees or a discount credited to the
+	// account.
+	EntryRebate
+
+	// EntryTransferIn is a transfer into the account from another account
+	// of the wallet.
+	EntryTransferIn
+
+	// EntryTransferOut is a transfer from the account to another account
+	// of the wallet.
+	EntryTransferOut
+
+	// numEntryTypes is the number of entry types.
+	numEntryTypes
+)
+
+// entryTypeStrings is a map of entry types back to their names, as written in
+// exports.
+var entryTypeStrings = map[EntryType]string{
+	EntryReceive:     "receive",
+	EntrySend:        "send",
+	EntryFee:         "fee",
+	EntryRebate:      "rebate",
+	EntryTransferIn:  "transfer_in",
+	EntryTransferOut: "transfer_out",
+}
+
+// String returns the EntryType as a human-readable name.
+func (t EntryType) String() string {
+	if str, ok := entryTypeStrings[t]; ok {
+		return str
+	}
+	return "unknown"
+}
+
+// IsCredit returns whether entries of the type increase the balance of their
+// account.
+func (t EntryType) IsCredit() bool {
+	return t == EntryReceive || t == EntryRebate || t == EntryTransferIn
+}
+
+// IsTransfer returns whether entries of the type move funds between accounts
+// of the wallet.
+func (t EntryType) IsTransfer() bool {
+	return t == EntryTransferIn || t == EntryTransferOut
+}
+
+// Entry is a movement of funds of an account.
+type Entry struct {
+	// Time is when the transaction of the entry was confirmed or
+	// observed.
+	Time time.Time
+
+	// Type determines the direction of the entry.
+	Type EntryType
+
+	// TxHash and Index identify the transaction of the entry and the
+	// output or input it concerns, which make the entry unique.
+	TxHash chainhash.Hash
+	Index  uint32
+
+	// Account is the account of the wallet the entry belongs to.
+	Account string
+
+	// TokenType is the token type of the amount.
+	TokenType uint64
+
+	// Amount is the magnitude of the movement.
+	Amount btcutil.Amount
+
+	// Memo is a free form description of the entry.
+	Memo string
+}
+
+// SignedAmount returns the amount of the entry, negative for debits.
+func (e *Entry) SignedAmount() btcutil.Amount {
+	if e.Type.IsCredit() {
+		return e.Amount
+	}
+	return -e.Amount
+}
+
+// Validate checks the entry has a known type and a non-negative amount.
+func (e *Entry) Validate() error {
+	if e.Type >= numEntryTypes {
+		return ErrUnknownEntryType
+	}
+	if e.Amount < 0 {
+		return ErrNegativeAmount
+	}
+	return nil
+}
+
+// balanceKey identifies the balance of a token type of an account.
+type balanceKey struct {
+	account   string
+	tokenType uint64
+}
+
+// Balances returns the balance of each token type of each account after the
+// entries, keyed by account and then token type.
+func Balances(entries []Entry) (map[string]map[uint64]btcutil.Amount, error) {
+	balances := make(map[string]map[uint64]btcutil.Amount)
+	for i := range entries {
+		e := &entries[i]
+		if err := e.Validate(); err != nil {
+			return nil, err
+		}
+		account, ok := balances[e.Account]
+		if !ok {
+			account = make(map[uint64]btcutil.Amount)
+			balances[e.Account] = account
+		}
+		account[e.TokenType] += e.SignedAmount()
+	}
+	return balances, nil
+}
+
+// CheckTransfers verifies that the transfer entries of each transaction
+// balance for each token type, so internal transfers leave the total of the
+// wallet unchanged.  Fees paid by a transfer are recorded as fee entries and
+// are not part of the check.
+func CheckTransfers(entries []Entry) error {
+	type transferKey struct {
+		txHash    chainhash.Hash
+		tokenType uint64
+	}
+	net := make(map[transferKey]btcutil.Amount)
+	for i := range entries {
+		e := &entries[i]
+		if err := e.Validate(); err != nil {
+			return err
+		}
+		if e.Type.IsTransfer() {
+			net[transferKey{e.TxHash, e.TokenType}] += e.SignedAmount()
+		}
+	}
+	for _, amount := range net {
+		if amount != 0 {
+			return ErrUnbalancedTransfer
+		}
+	}
+	return nil
+}
+
+// Sort sorts entries by time, and entries of the same time by transaction,
+// type and index, the order in which they are exported.
+func Sort(entries []Entry) {
+	sort.SliceStable(entries, func(i, j int) bool {
+		a, b := &entries[i], &entries[j]
+		if !a.Time.Equal(b.Time) {
+			return a.Time.Before(b.Time)
+		}
+		if a.TxHash != b.TxHash {
+			return string(a.TxHash[:]) < string(b.TxHash[:])
+		}
+		if a.Type != b.Type {
+			return a.Type < b.Type
+		}
+		return a.Index < b.Index
+	})
+}
@@ -0,0 +1,138 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ledger_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/ledger"
+)
+
+// testEntries returns a payment received into a savings account, moved to a
+// spending account paying a fee, part of which is rebated.
+func testEntries() []ledger.Entry {
+	t0 := time.Date(2021, 3, 1, 12, 0, 0, 0, time.UTC)
+	tx1 := chainhash.Hash{1}
+	tx2 := chainhash.Hash{2}
+	return []ledger.Entry{
+		{Time: t0, Type: ledger.EntryReceive, TxHash: tx1,
+			Account: "savings", Amount: 5e8, Memo: "salary"},
+		{Time: t0.Add(time.Hour), Type: ledger.EntryTransferOut,
+			TxHash: tx2, Account: "savings", Amount: 2e8},
+		{Time: t0.Add(time.Hour), Type: ledger.EntryFee, TxHash: tx2,
+			Account: "savings", Amount: 1000},
+		{Time: t0.Add(time.Hour), Type: ledger.EntryTransferIn,
+			TxHash: tx2, Index: 1, Account: "spending", Amount: 2e8},
+		{Time: t0.Add(2 * time.Hour), Type: ledger.EntryRebate,
+			TxHash: tx2, Account: "savings", Amount: 250},
+	}
+}
+
+// TestEntryType tests the names and directions of the entry types.
+func TestEntryType(t *testing.T) {
+	tests := []struct {
+		typ      ledger.EntryType
+		name     string
+		credit   bool
+		transfer bool
+	}{
+		{ledger.EntryReceive, "receive", true, false},
+		{ledger.EntrySend, "send", false, false},
+		{ledger.EntryFee, "fee", false, false},
+		{ledger.EntryRebate, "rebate", true, false},
+		{ledger.EntryTransferIn, "transfer_in", true, true},
+		{ledger.EntryTransferOut, "transfer_out", false, true},
+		{ledger.EntryType(200), "unknown", false, false},
+	}
+	for _, test := range tests {
+		if got := test.typ.String(); got != test.name {
+			t.Errorf("String: got %q, want %q", got, test.name)
+		}
+		if got := test.typ.IsCredit(); got != test.credit {
+			t.Errorf("%v: IsCredit got %v, want %v", test.typ, got,
+				test.credit)
+		}
+		if got := test.typ.IsTransfer(); got != test.transfer {
+			t.Errorf("%v: IsTransfer got %v, want %v", test.typ, got,
+				test.transfer)
+		}
+	}
+}
+
+// TestEntryValidate tests entries with invalid types and amounts are
+// rejected.
+func TestEntryValidate(t *testing.T) {
+	e := ledger.Entry{Type: ledger.EntryType(200), Amount: 1}
+	if err := e.Validate(); err != ledger.ErrUnknownEntryType {
+		t.Errorf("unknown type: got %v, want %v", err,
+			ledger.ErrUnknownEntryType)
+	}
+	e = ledger.Entry{Type: ledger.EntryFee, Amount: -1}
+	if err := e.Validate(); err != ledger.ErrNegativeAmount {
+		t.Errorf("negative amount: got %v, want %v", err,
+			ledger.ErrNegativeAmount)
+	}
+	if _, err := ledger.Balances([]ledger.Entry{e}); err != ledger.ErrNegativeAmount {
+		t.Errorf("Balances: got %v, want %v", err,
+			ledger.ErrNegativeAmount)
+	}
+}
+
+// TestBalances tests the balances of accounts account for the sign of each
+// entry type.
+func TestBalances(t *testing.T) {
+	balances, err := ledger.Balances(testEntries())
+	if err != nil {
+		t.Fatalf("Balances: %v", err)
+	}
+	if got, want := balances["savings"][0], btcutil.Amount(3e8-750); got != want {
+		t.Errorf("savings: got %v, want %v", got, want)
+	}
+	if got, want := balances["spending"][0], btcutil.Amount(2e8); got != want {
+		t.Errorf("spending: got %v, want %v", got, want)
+	}
+}
+
+// TestCheckTransfers tests transfers must balance within their transaction.
+func TestCheckTransfers(t *testing.T) {
+	entries := testEntries()
+	if err := ledger.CheckTransfers(entries); err != nil {
+		t.Fatalf("CheckTransfers: %v", err)
+	}
+
+	entries[3].Amount--
+	if err := ledger.CheckTransfers(entries); err != ledger.ErrUnbalancedTransfer {
+		t.Errorf("got %v, want %v", err, ledger.ErrUnbalancedTransfer)
+	}
+
+	// A transfer in another token type does not balance one in OMC.
+	entries[3].Amount++
+	entries[3].TokenType = 4
+	if err := ledger.CheckTransfers(entries); err != ledger.ErrUnbalancedTransfer {
+		t.Errorf("token type: got %v, want %v", err,
+			ledger.ErrUnbalancedTransfer)
+	}
+}
+
+// TestSort tests entries are ordered by time, then type.
+func TestSort(t *testing.T) {
+	entries := testEntries()
+	entries[0], entries[4] = entries[4], entries[0]
+	entries[1], entries[3] = entries[3], entries[1]
+	ledger.Sort(entries)
+
+	want := []ledger.EntryType{
+		ledger.EntryReceive, ledger.EntryFee, ledger.EntryTransferIn,
+		ledger.EntryTransferOut, ledger.EntryRebate,
+	}
+	for i, e := range entries {
+		if e.Type != want[i] {
+			t.Errorf("entry %d: got %v, want %v", i, e.Type, want[i])
+		}
+	}
+}
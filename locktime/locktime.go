@@ -0,0 +1,236 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package locktime converts between the absolute lock times of transactions,
+// the relative lock times of the sequence numbers of their inputs, and the
+// block heights, timestamps and durations they stand for.
+//
+// An absolute lock time is either a block height or, from LockTimeThreshold
+// on, a unix timestamp compared against the median time of the past blocks.
+// A relative lock time, as defined by BIP0068, is encoded in the sequence
+// number of an input as a number of blocks or of 512 second units which must
+// elapse after the output spent was confirmed.  The helpers here spare
+// timelocked contracts from hardcoding the thresholds and bit masks of both
+// encodings.
+//
+// The median time of the past blocks, over which time lock times and the
+// timestamps of blocks are checked, is computed by MedianTimePast, which the
+// header helpers of package btcutil share.
+package locktime
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	// LockTimeThreshold is the lock time from which lock times are unix
+	// timestamps rather than block heights.
+	LockTimeThreshold = 500000000
+
+	// SequenceFinal is the sequence number of inputs which are final and
+	// have no relative lock time.
+	SequenceFinal = math.MaxUint32
+
+	// SequenceLockTimeDisabled is the flag of sequence numbers without a
+	// relative lock time.
+	SequenceLockTimeDisabled = 1 << 31
+
+	// SequenceLockTimeIsSeconds is the flag of sequence numbers whose
+	// relative lock time is in units of SequenceLockTimeUnit rather than
+	// in blocks.
+	SequenceLockTimeIsSeconds = 1 << 22
+
+	// SequenceLockTimeMask is the mask of the relative lock time value of
+	// a sequence number.
+	SequenceLockTimeMask = 0x0000ffff
+
+	// SequenceLockTimeGranularity is the base two logarithm of the number
+	// of seconds of a unit of a relative lock time in seconds.
+	SequenceLockTimeGranularity = 9
+
+	// SequenceLockTimeUnit is the duration of a unit of a relative lock
+	// time in seconds.
+	SequenceLockTimeUnit = (1 << SequenceLockTimeGranularity) * time.Second
+
+	// MaxRelativeDuration is the longest duration a relative lock time can
+	// express.
+	MaxRelativeDuration = SequenceLockTimeMask * SequenceLockTimeUnit
+)
+
+var (
+	// ErrInvalidHeight describes an error where a block height is negative
+	// or too large to be expressed as a lock time.
+	ErrInvalidHeight = errors.New("height can't be expressed as a lock time")
+
+	// ErrInvalidTime describes an error where a timestamp is before
+	// LockTimeThreshold or after the largest lock time.
+	ErrInvalidTime = errors.New("time can't be expressed as a lock time")
+
+	// ErrInvalidDuration describes an error where a duration is negative
+	// or longer than a lock time can express.
+	ErrInvalidDuration = errors.New("duration can't be expressed as a " +
+		"lock time")
+)
+
+// LockTime is the absolute lock time of a transaction.
+type LockTime uint32
+
+// FromHeight returns the lock time of a transaction which can't be included
+// in a block before height + 1.  ErrInvalidHeight is returned when height is
+// negative or not below LockTimeThreshold.
+func FromHeight(height int32) (LockTime, error) {
+	if height < 0 || height >= LockTimeThreshold {
+		return 0, ErrInvalidHeight
+	}
+	return LockTime(height), nil
+}
+
+// FromTime returns the lock time of a transaction which can't be included in
+// a block before the median time of the past blocks exceeds t.
+// ErrInvalidTime is returned when t is before LockTimeThreshold or can't be
+// expressed as a 32 bit timestamp.
+func FromTime(t time.Time) (LockTime, error) {
+	unix := t.Unix()
+	if unix < LockTimeThreshold || unix > math.MaxUint32 {
+		return 0, ErrInvalidTime
+	}
+	return LockTime(unix), nil
+}
+
+// HeightAfter returns the lock time of the height expected to be reached d
+// after the block at height current, at one block every interval.  The
+// number of blocks is rounded up, so the lock time never expires early on
+// schedule.
+func HeightAfter(current int32, d, interval time.Duration) (LockTime, error) {
+	if d < 0 || interval <= 0 {
+		return 0, ErrInvalidDuration
+	}
+	blocks := d / interval
+	if d%interval != 0 {
+		blocks++
+	}
+	if current < 0 || int64(blocks) >= LockTimeThreshold-int64(current) {
+		return 0, ErrInvalidHeight
+	}
+	return FromHeight(current + int32(blocks))
+}
+
+// IsHeight returns whether the lock time is a block height.
+func (l LockTime) IsHeight() bool {
+	return l < LockTimeThreshold
+}
+
+// Height returns the block height of the lock time and whether it is one.
+func (l LockTime) Height() (int32, bool) {
+	return int32(l), l.IsHeight()
+}
+
+// Time returns the timestamp of the lock time and whether it is one.
+func (l LockTime) Time() (time.Time, bool) {
+	return time.Unix(int64(l), 0), !l.IsHeight()
+}
+
+// Satisfied returns whether a transaction with the lock time may be included
+// in a block at height whose past blocks have the passed median time.  As
+// for the lock time of a transaction with only final inputs, the zero lock
+// time is always satisfied.
+func (l LockTime) Satisfied(height int32, medianTime time.Time) bool {
+	if l == 0 {
+		return true
+	}
+	if l.IsHeight() {
+		return int64(l) < int64(height)
+	}
+	return int64(l) < medianTime.Unix()
+}
+
+// String returns the lock time in human-readable form.
+func (l LockTime) String() string {
+	if l.IsHeight() {
+		return fmt.Sprintf("height %d", uint32(l))
+	}
+	t, _ := l.Time()
+	return t.UTC().Format(time.RFC3339)
+}
+
+// RelativeLock is the relative lock time of an input.
+type RelativeLock struct {
+	// IsSeconds is whether Value counts units of SequenceLockTimeUnit
+	// rather than blocks.
+	IsSeconds bool
+
+	// Value is the number of blocks or time units.
+	Value uint16
+}
+
+// RelativeBlocks returns the relative lock time of n blocks.
+func RelativeBlocks(n uint16) RelativeLock {
+	return RelativeLock{Value: n}
+}
+
+// RelativeDuration returns the relative lock time of d, rounded up to a
+// whole number of SequenceLockTimeUnit so the lock time never expires early.
+// ErrInvalidDuration is returned when d is negative or longer than
+// MaxRelativeDuration.
+func RelativeDuration(d time.Duration) (RelativeLock, error) {
+	if d < 0 || d > MaxRelativeDuration {
+		return RelativeLock{}, ErrInvalidDuration
+	}
+	units := (d + SequenceLockTimeUnit - 1) / SequenceLockTimeUnit
+	return RelativeLock{IsSeconds: true, Value: uint16(units)}, nil
+}
+
+// DecodeSequence returns the relative lock time encoded by the sequence
+// number of an input, and false when the sequence number has relative lock
+// times disabled.  Bits of the sequence number without a meaning are
+// ignored.
+func DecodeSequence(sequence uint32) (RelativeLock, bool) {
+	if sequence&SequenceLockTimeDisabled != 0 {
+		return RelativeLock{}, false
+	}
+	return RelativeLock{
+		IsSeconds: sequence&SequenceLockTimeIsSeconds != 0,
+		Value:     uint16(sequence & SequenceLockTimeMask),
+	}, true
+}
+
+// Sequence returns the sequence number encoding the relative lock time.
+func (r RelativeLock) Sequence() uint32 {
+	sequence := uint32(r.Value)
+	if r.IsSeconds {
+		sequence |= SequenceLockTimeIsSeconds
+	}
+	return sequence
+}
+
+// Duration returns the duration of a relative lock time in seconds, or zero
+// for a lock time in blocks.
+func (r RelativeLock) Duration() time.Duration {
+	if !r.IsSeconds {
+		return 0
+	}
+	return time.Duration(r.Value) * SequenceLockTimeUnit
+}
+
+// Satisfied returns whether an input with the relative lock time may spend an
+// output confirmed confirmations blocks ago, counting the block including the
+// output, with elapsed the difference of the median times of the past blocks
+// of the spending block and of the block including the output.
+func (r RelativeLock) Satisfied(confirmations int32, elapsed time.Duration) bool {
+	if r.IsSeconds {
+		return elapsed >= r.Duration()
+	}
+	return int64(confirmations) >= int64(r.Value)
+}
+
+// String returns the relative lock time in human-readable form.
+func (r RelativeLock) String() string {
+	if r.IsSeconds {
+		return r.Duration().String()
+	}
+	return fmt.Sprintf("%d blocks", r.Value)
+}
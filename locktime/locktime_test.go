@@ -0,0 +1,144 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package locktime_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zeusyf/btcutil/locktime"
+)
+
+// TestLockTime ensures absolute lock times are built, validated and compared
+// against heights and median times as consensus does.
+func TestLockTime(t *testing.T) {
+	l, err := locktime.FromHeight(100)
+	if err != nil || !l.IsHeight() {
+		t.Fatalf("FromHeight: got %v, %v", l, err)
+	}
+	if h, ok := l.Height(); !ok || h != 100 {
+		t.Errorf("Height: got %d, %v", h, ok)
+	}
+	if l.Satisfied(100, time.Time{}) || !l.Satisfied(101, time.Time{}) {
+		t.Errorf("Satisfied: height lock time compared inclusively")
+	}
+	for _, h := range []int32{-1, locktime.LockTimeThreshold} {
+		if _, err := locktime.FromHeight(h); err != locktime.ErrInvalidHeight {
+			t.Errorf("FromHeight(%d): got error %v, want %v", h, err,
+				locktime.ErrInvalidHeight)
+		}
+	}
+
+	when := time.Unix(1600000000, 0)
+	l, err = locktime.FromTime(when)
+	if err != nil || l.IsHeight() {
+		t.Fatalf("FromTime: got %v, %v", l, err)
+	}
+	if got, ok := l.Time(); !ok || !got.Equal(when) {
+		t.Errorf("Time: got %v, %v", got, ok)
+	}
+	if l.Satisfied(1<<30, when) || !l.Satisfied(0, when.Add(time.Second)) {
+		t.Errorf("Satisfied: time lock time compared inclusively")
+	}
+	if l.String() != "2020-09-13T12:26:40Z" {
+		t.Errorf("String: got %s", l)
+	}
+	for _, bad := range []time.Time{time.Unix(locktime.LockTimeThreshold-1, 0),
+		time.Unix(1<<32, 0)} {
+		if _, err := locktime.FromTime(bad); err != locktime.ErrInvalidTime {
+			t.Errorf("FromTime(%v): got error %v, want %v", bad, err,
+				locktime.ErrInvalidTime)
+		}
+	}
+
+	if !locktime.LockTime(0).Satisfied(0, time.Time{}) {
+		t.Errorf("Satisfied: zero lock time not satisfied")
+	}
+
+	// A day at 10 minute blocks is 144 blocks, rounded up.
+	l, err = locktime.HeightAfter(1000, 24*time.Hour+time.Second, 10*time.Minute)
+	if h, _ := l.Height(); err != nil || h != 1145 {
+		t.Errorf("HeightAfter: got %v, %v", l, err)
+	}
+	if _, err := locktime.HeightAfter(0, -time.Second, time.Minute); err != locktime.ErrInvalidDuration {
+		t.Errorf("HeightAfter: got error %v, want %v", err,
+			locktime.ErrInvalidDuration)
+	}
+	if _, err := locktime.HeightAfter(1000, 1<<62, time.Second); err != locktime.ErrInvalidHeight {
+		t.Errorf("HeightAfter: got error %v, want %v", err,
+			locktime.ErrInvalidHeight)
+	}
+}
+
+// TestRelativeLock ensures relative lock times round trip through sequence
+// numbers with the BIP0068 encoding.
+func TestRelativeLock(t *testing.T) {
+	tests := []struct {
+		lock     locktime.RelativeLock
+		sequence uint32
+	}{
+		{locktime.RelativeBlocks(0), 0},
+		{locktime.RelativeBlocks(144), 144},
+		{locktime.RelativeBlocks(0xffff), 0xffff},
+		{locktime.RelativeLock{IsSeconds: true, Value: 1}, 0x00400001},
+		{locktime.RelativeLock{IsSeconds: true, Value: 0xffff}, 0x0040ffff},
+	}
+	for _, test := range tests {
+		if seq := test.lock.Sequence(); seq != test.sequence {
+			t.Errorf("Sequence(%v): got %08x, want %08x", test.lock, seq,
+				test.sequence)
+		}
+		lock, ok := locktime.DecodeSequence(test.sequence)
+		if !ok || lock != test.lock {
+			t.Errorf("DecodeSequence(%08x): got %v, %v", test.sequence, lock, ok)
+		}
+	}
+
+	if _, ok := locktime.DecodeSequence(locktime.SequenceFinal); ok {
+		t.Errorf("DecodeSequence: final sequence has a relative lock time")
+	}
+	lock, ok := locktime.DecodeSequence(0x00ff0010)
+	if !ok || lock != (locktime.RelativeLock{IsSeconds: true, Value: 16}) {
+		t.Errorf("DecodeSequence: got %v, %v with unused bits set", lock, ok)
+	}
+
+	// Durations are rounded up to 512 second units.
+	durations := []struct {
+		d     time.Duration
+		value uint16
+	}{
+		{0, 0},
+		{time.Second, 1},
+		{512 * time.Second, 1},
+		{513 * time.Second, 2},
+		{24 * time.Hour, 169},
+		{locktime.MaxRelativeDuration, 0xffff},
+	}
+	for _, test := range durations {
+		lock, err := locktime.RelativeDuration(test.d)
+		if err != nil || !lock.IsSeconds || lock.Value != test.value {
+			t.Errorf("RelativeDuration(%v): got %v, %v", test.d, lock, err)
+		}
+		if lock.Duration() < test.d {
+			t.Errorf("RelativeDuration(%v): expires early after %v", test.d,
+				lock.Duration())
+		}
+	}
+	for _, d := range []time.Duration{-1, locktime.MaxRelativeDuration + 1} {
+		if _, err := locktime.RelativeDuration(d); err != locktime.ErrInvalidDuration {
+			t.Errorf("RelativeDuration(%v): got error %v, want %v", d, err,
+				locktime.ErrInvalidDuration)
+		}
+	}
+
+	blocks := locktime.RelativeBlocks(10)
+	if blocks.Satisfied(9, time.Hour) || !blocks.Satisfied(10, 0) {
+		t.Errorf("Satisfied: wrong block relative lock time comparison")
+	}
+	secs, _ := locktime.RelativeDuration(time.Hour)
+	if secs.Satisfied(1000, time.Hour) || !secs.Satisfied(0, secs.Duration()) {
+		t.Errorf("Satisfied: wrong time relative lock time comparison")
+	}
+}
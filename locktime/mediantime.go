@@ -0,0 +1,78 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package locktime
+
+import (
+	"errors"
+	"sort"
+	"time"
+)
+
+const (
+	// MedianTimeBlocks is the number of past blocks whose timestamps the
+	// median time of the past blocks is taken over.
+	MedianTimeBlocks = 11
+
+	// MaxTimeOffset is how far past the network adjusted time the
+	// timestamp of a block may be.
+	MaxTimeOffset = 2 * time.Hour
+)
+
+var (
+	// ErrNoTimestamps describes an error where the median time of the past
+	// blocks is requested without any past block.
+	ErrNoTimestamps = errors.New("no timestamps to take the median of")
+
+	// ErrTimeTooOld describes an error where the timestamp of a block is
+	// not after the median time of its past blocks.
+	ErrTimeTooOld = errors.New("block timestamp is not after the median " +
+		"time of the past blocks")
+
+	// ErrTimeTooNew describes an error where the timestamp of a block is
+	// more than MaxTimeOffset past the network adjusted time.
+	ErrTimeTooNew = errors.New("block timestamp is too far in the future")
+)
+
+// MedianTimePast returns the median time of the past blocks with the passed
+// timestamps, ordered by height.  Only the last MedianTimeBlocks timestamps
+// are used, and fewer are accepted near the genesis block, in which case the
+// later of the two middle timestamps of an even number is the median, as
+// consensus takes it.  The timestamps are not modified.
+func MedianTimePast(timestamps []time.Time) (time.Time, error) {
+	if len(timestamps) == 0 {
+		return time.Time{}, ErrNoTimestamps
+	}
+	if len(timestamps) > MedianTimeBlocks {
+		timestamps = timestamps[len(timestamps)-MedianTimeBlocks:]
+	}
+	sorted := make([]time.Time, len(timestamps))
+	copy(sorted, timestamps)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Before(sorted[j])
+	})
+	return sorted[len(sorted)/2], nil
+}
+
+// CheckTimestamp returns whether timestamp is a valid timestamp of a block
+// whose past blocks have the passed median time, when the network adjusted
+// time is now.  It must be after the median time, and at most MaxTimeOffset
+// past now.  Timestamps are compared in whole seconds, as blocks carry them.
+func CheckTimestamp(timestamp, medianTime, now time.Time) error {
+	if timestamp.Unix() <= medianTime.Unix() {
+		return ErrTimeTooOld
+	}
+	if timestamp.Unix() > now.Add(MaxTimeOffset).Unix() {
+		return ErrTimeTooNew
+	}
+	return nil
+}
+
+// MaxTimeLock returns the largest lock time in time satisfied by a block
+// whose past blocks have the passed median time, that is the one of the
+// second before it.  ErrInvalidTime is returned when no lock time in time is
+// satisfied yet, or the median time is past the largest lock time.
+func MaxTimeLock(medianTime time.Time) (LockTime, error) {
+	return FromTime(medianTime.Add(-time.Second))
+}
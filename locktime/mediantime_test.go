@@ -0,0 +1,98 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package locktime_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zeusyf/btcutil/locktime"
+)
+
+// unix returns the time of the unix timestamp sec.
+func unix(sec int64) time.Time {
+	return time.Unix(sec, 0)
+}
+
+// TestMedianTimePast ensures the median is taken over the last eleven
+// timestamps, in any order, and is the later middle one of an even number.
+func TestMedianTimePast(t *testing.T) {
+	tests := []struct {
+		name       string
+		timestamps []int64
+		want       int64
+	}{
+		{"single", []int64{5}, 5},
+		{"even", []int64{10, 30, 20, 40}, 30},
+		{"unordered", []int64{9, 1, 8, 2, 7, 3, 6, 4, 5, 11, 10}, 6},
+		{"last eleven", []int64{100, 100, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10,
+			11}, 6},
+	}
+	for _, test := range tests {
+		timestamps := make([]time.Time, len(test.timestamps))
+		for i, ts := range test.timestamps {
+			timestamps[i] = unix(ts)
+		}
+		got, err := locktime.MedianTimePast(timestamps)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", test.name, err)
+		}
+		if got.Unix() != test.want {
+			t.Errorf("%s: got %d, want %d", test.name, got.Unix(),
+				test.want)
+		}
+		if timestamps[0].Unix() != test.timestamps[0] {
+			t.Errorf("%s: timestamps were modified", test.name)
+		}
+	}
+
+	if _, err := locktime.MedianTimePast(nil); err != locktime.ErrNoTimestamps {
+		t.Errorf("no timestamps: got %v, want %v", err,
+			locktime.ErrNoTimestamps)
+	}
+}
+
+// TestCheckTimestamp ensures block timestamps must follow the median time and
+// may only run two hours ahead of the adjusted time.
+func TestCheckTimestamp(t *testing.T) {
+	median, now := unix(1000), unix(5000)
+	tests := []struct {
+		timestamp time.Time
+		want      error
+	}{
+		{unix(1000), locktime.ErrTimeTooOld},
+		{unix(1000).Add(time.Second / 2), locktime.ErrTimeTooOld},
+		{unix(1001), nil},
+		{unix(5000 + 7200), nil},
+		{unix(5000 + 7201), locktime.ErrTimeTooNew},
+	}
+	for _, test := range tests {
+		err := locktime.CheckTimestamp(test.timestamp, median, now)
+		if err != test.want {
+			t.Errorf("CheckTimestamp(%v): got %v, want %v",
+				test.timestamp, err, test.want)
+		}
+	}
+}
+
+// TestMaxTimeLock ensures the largest satisfied lock time is the second
+// before the median time.
+func TestMaxTimeLock(t *testing.T) {
+	median := unix(1600000000)
+	l, err := locktime.MaxTimeLock(median)
+	if err != nil {
+		t.Fatalf("MaxTimeLock: unexpected error: %v", err)
+	}
+	if l != 1599999999 || !l.Satisfied(0, median) ||
+		(l+1).Satisfied(0, median) {
+		t.Errorf("MaxTimeLock: got %v", l)
+	}
+
+	_, err = locktime.MaxTimeLock(unix(locktime.LockTimeThreshold))
+	if err != locktime.ErrInvalidTime {
+		t.Errorf("MaxTimeLock: got error %v, want %v", err,
+			locktime.ErrInvalidTime)
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package btcutil
+
+import (
+	"sync"
+)
+
+// DefaultLookalikeAffixLen is the number of leading and trailing characters
+// two addresses must share to be reported as lookalikes by default.  Wallets
+// commonly abbreviate addresses to their first and last few characters, and
+// each additional matching character multiplies the work of generating a
+// lookalike by 58, so address poisoning attacks typically match three to
+// five characters at each end.
+const DefaultLookalikeAffixLen = 3
+
+// LookalikeMatch describes a recently used address which a destination
+// resembles.
+type LookalikeMatch struct {
+	// Recent is the encoded recently used address.
+	Recent string
+
+	// PrefixLen is the number of leading characters the destination
+	// shares with the recent address, not counting the leading character
+	// implied by the address type.
+	PrefixLen int
+
+	// SuffixLen is the number of trailing characters the destination
+	// shares with the recent address.
+	SuffixLen int
+}
+
+// LookalikeDetector flags destinations which look like, but are not,
+// addresses the user recently transacted with.
+//
+// In an address poisoning attack, the attacker sends a worthless transaction
+// from an address generated to share the leading and trailing characters of
+// an address the victim uses, hoping the victim later copies the attacker's
+// address from their transaction history.  Checking destinations before
+// building a transaction catches the swap while the user can still notice.
+//
+// A LookalikeDetector is safe for concurrent use.
+type LookalikeDetector struct {
+	mtx       sync.Mutex
+	affixLen  int
+	maxRecent int
+	recent    []string
+	next      int
+}
+
+// NewLookalikeDetector returns a detector which remembers the maxRecent most
+// recently added addresses and reports destinations sharing at least affixLen
+// leading and trailing characters with one of them.  An affixLen of zero or
+// less selects DefaultLookalikeAffixLen.
+func NewLookalikeDetector(maxRecent, affixLen int) *LookalikeDetector {
+	if affixLen <= 0 {
+		affixLen = DefaultLookalikeAffixLen
+	}
+	return &LookalikeDetector{
+		affixLen:  affixLen,
+		maxRecent: maxRecent,
+	}
+}
+
+// AddRecent remembers an address the user transacted with, replacing the
+// oldest remembered address when the detector is full.  Addresses already
+// remembered are not added again.
+func (d *LookalikeDetector) AddRecent(addr Address) {
+	encoded := addr.EncodeAddress()
+
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	if d.maxRecent <= 0 {
+		return
+	}
+	for _, recent := range d.recent {
+		if recent == encoded {
+			return
+		}
+	}
+	if len(d.recent) < d.maxRecent {
+		d.recent = append(d.recent, encoded)
+		return
+	}
+	d.recent[d.next] = encoded
+	d.next = (d.next + 1) % d.maxRecent
+}
+
+// Check returns the remembered addresses which the passed destination
+// resembles without being equal to.  A nil result means no lookalike was
+// found.  A destination which is itself a remembered address is never
+// reported, even if it also resembles another one.
+func (d *LookalikeDetector) Check(addr Address) []LookalikeMatch {
+	encoded := addr.EncodeAddress()
+
+	d.mtx.Lock()
+	defer d.mtx.Unlock()
+
+	var matches []LookalikeMatch
+	for _, recent := range d.recent {
+		if recent == encoded {
+			return nil
+		}
+		prefix, suffix := sharedAffixes(encoded, recent)
+		if prefix >= d.affixLen && suffix >= d.affixLen {
+			matches = append(matches, LookalikeMatch{
+				Recent:    recent,
+				PrefixLen: prefix,
+				SuffixLen: suffix,
+			})
+		}
+	}
+	return matches
+}
+
+// sharedAffixes returns the number of leading and trailing characters shared
+// by two distinct encoded addresses.  The first character is determined by
+// the address type rather than the key or script, so it is not counted, and
+// addresses of different types share no prefix.  The prefix and suffix never
+// overlap.
+func sharedAffixes(a, b string) (int, int) {
+	if len(a) == 0 || len(b) == 0 || a[0] != b[0] {
+		return 0, 0
+	}
+	a, b = a[1:], b[1:]
+
+	n := len(a)
+	if len(b) < n {
+		n = len(b)
+	}
+	prefix := 0
+	for prefix < n && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < n-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	return prefix, suffix
+}
@@ -0,0 +1,73 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package btcutil_test
+
+import (
+	"encoding/binary"
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil"
+)
+
+// TestLookalikeDetector ensures destinations sharing the leading and
+// trailing characters of a recent address are flagged, while the recent
+// address itself and unrelated addresses are not.
+func TestLookalikeDetector(t *testing.T) {
+	addrFromIndex := func(i uint32) btcutil.Address {
+		hash := make([]byte, 20)
+		binary.LittleEndian.PutUint32(hash, i)
+		addr, err := btcutil.NewAddressPubKeyHash(hash, &chaincfg.MainNetParams)
+		if err != nil {
+			t.Fatalf("NewAddressPubKeyHash: unexpected error: %v", err)
+		}
+		return addr
+	}
+
+	// Find a lookalike of the recent address which matches a single
+	// character at each end, which takes a few thousand attempts.
+	recent := addrFromIndex(0)
+	r := recent.EncodeAddress()
+	var lookalike, unrelated btcutil.Address
+	for i := uint32(1); lookalike == nil || unrelated == nil; i++ {
+		addr := addrFromIndex(i)
+		a := addr.EncodeAddress()
+		similar := a[1] == r[1] && a[len(a)-1] == r[len(r)-1]
+		if similar && lookalike == nil {
+			lookalike = addr
+		}
+		if a[1] != r[1] && a[len(a)-1] != r[len(r)-1] && unrelated == nil {
+			unrelated = addr
+		}
+	}
+
+	d := btcutil.NewLookalikeDetector(2, 1)
+	d.AddRecent(recent)
+	if matches := d.Check(recent); matches != nil {
+		t.Fatalf("Check: recent address flagged: %+v", matches)
+	}
+	if matches := d.Check(unrelated); matches != nil {
+		t.Fatalf("Check: unrelated address flagged: %+v", matches)
+	}
+	matches := d.Check(lookalike)
+	if len(matches) != 1 || matches[0].Recent != r ||
+		matches[0].PrefixLen < 1 || matches[0].SuffixLen < 1 {
+		t.Fatalf("Check: got %+v for lookalike of %s", matches, r)
+	}
+
+	// The default affix length does not flag the single character match.
+	strict := btcutil.NewLookalikeDetector(2, 0)
+	strict.AddRecent(recent)
+	if matches := strict.Check(lookalike); matches != nil {
+		t.Fatalf("Check: got %+v with default affix length", matches)
+	}
+
+	// Adding two more addresses evicts the first one.
+	d.AddRecent(addrFromIndex(1000000))
+	d.AddRecent(addrFromIndex(1000001))
+	if matches := d.Check(lookalike); matches != nil {
+		t.Fatalf("Check: evicted address matched: %+v", matches)
+	}
+}
@@ -0,0 +1,217 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package membudget provides a shared memory budget for the caches kept by
+// this library.
+//
+// Each cache registers with a Manager and reports the approximate number of
+// bytes it holds as entries are added and removed.  When the total across all
+// registered caches exceeds the manager's limit, every cache is asked to
+// evict a share of the excess proportional to its own usage, so one busy
+// cache can not starve the others and embedders can bound the memory used by
+// the library as a whole with a single setting.
+package membudget
+
+import (
+	"sort"
+	"sync"
+)
+
+// Evictor is implemented by caches which register with a Manager.
+type Evictor interface {
+	// Evict frees approximately n bytes, for example by dropping least
+	// recently used entries, and returns the number of bytes actually
+	// freed.  Evicted bytes must not also be reported with Shrink.
+	Evict(n int64) int64
+}
+
+// Manager tracks the memory used by its registered consumers and enforces a
+// global limit by asking them to evict entries.  It is safe for concurrent
+// use.
+type Manager struct {
+	mtx       sync.Mutex
+	limit     int64
+	used      int64
+	consumers map[*Consumer]struct{}
+}
+
+// NewManager returns a manager which keeps the total usage of its consumers
+// at or below limit bytes.  A limit of zero or less disables eviction.
+func NewManager(limit int64) *Manager {
+	return &Manager{
+		limit:     limit,
+		consumers: make(map[*Consumer]struct{}),
+	}
+}
+
+// Limit returns the manager's limit in bytes.
+func (m *Manager) Limit() int64 {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	return m.limit
+}
+
+// SetLimit changes the manager's limit, evicting from the consumers right
+// away if they use more than the new limit.
+func (m *Manager) SetLimit(limit int64) {
+	m.mtx.Lock()
+	m.limit = limit
+	m.mtx.Unlock()
+	m.enforce()
+}
+
+// Used returns the total number of bytes used by all consumers.
+func (m *Manager) Used() int64 {
+	m.mtx.Lock()
+	defer m.mtx.Unlock()
+	return m.used
+}
+
+// Register adds a consumer with the given name to the manager.  The name is
+// only used to identify the consumer in Stats.
+func (m *Manager) Register(name string, evictor Evictor) *Consumer {
+	c := &Consumer{m: m, name: name, evictor: evictor}
+	m.mtx.Lock()
+	m.consumers[c] = struct{}{}
+	m.mtx.Unlock()
+	return c
+}
+
+// ConsumerStats describes the memory used by a single consumer.
+type ConsumerStats struct {
+	Name    string
+	Used    int64
+	Evicted int64
+}
+
+// Stats returns the usage of every registered consumer, sorted by name.
+func (m *Manager) Stats() []ConsumerStats {
+	m.mtx.Lock()
+	stats := make([]ConsumerStats, 0, len(m.consumers))
+	for c := range m.consumers {
+		stats = append(stats, ConsumerStats{
+			Name:    c.name,
+			Used:    c.used,
+			Evicted: c.evicted,
+		})
+	}
+	m.mtx.Unlock()
+
+	sort.Slice(stats, func(i, j int) bool {
+		return stats[i].Name < stats[j].Name
+	})
+	return stats
+}
+
+// enforce asks every consumer to evict its share of the usage above the
+// limit.  The evictors are called without the manager lock held so they may
+// report other changes to their usage while evicting.
+func (m *Manager) enforce() {
+	type request struct {
+		c *Consumer
+		n int64
+	}
+
+	m.mtx.Lock()
+	excess := m.used - m.limit
+	if m.limit <= 0 || excess <= 0 || m.used == 0 {
+		m.mtx.Unlock()
+		return
+	}
+	requests := make([]request, 0, len(m.consumers))
+	for c := range m.consumers {
+		if c.used == 0 {
+			continue
+		}
+
+		// Round up so the shares always cover the excess.
+		n := (excess*c.used + m.used - 1) / m.used
+		requests = append(requests, request{c, n})
+	}
+	m.mtx.Unlock()
+
+	for _, r := range requests {
+		freed := r.c.evictor.Evict(r.n)
+		if freed <= 0 {
+			continue
+		}
+
+		m.mtx.Lock()
+		if _, ok := m.consumers[r.c]; ok {
+			if freed > r.c.used {
+				freed = r.c.used
+			}
+			r.c.used -= freed
+			r.c.evicted += freed
+			m.used -= freed
+		}
+		m.mtx.Unlock()
+	}
+}
+
+// Consumer is a cache's handle to the manager it registered with.
+//
+// Grow may call the Evict methods of every consumer, including the one it is
+// called on, so it must not be called while holding a lock that an Evict
+// method acquires.
+type Consumer struct {
+	m       *Manager
+	name    string
+	evictor Evictor
+	used    int64
+	evicted int64
+}
+
+// Grow reports that the consumer allocated n more bytes and evicts from the
+// manager's consumers if the limit is now exceeded.
+func (c *Consumer) Grow(n int64) {
+	c.m.mtx.Lock()
+	_, ok := c.m.consumers[c]
+	if ok {
+		c.used += n
+		c.m.used += n
+	}
+	c.m.mtx.Unlock()
+
+	if ok {
+		c.m.enforce()
+	}
+}
+
+// Shrink reports that the consumer freed n bytes other than those freed by
+// its Evict method, for example because entries were removed explicitly.
+func (c *Consumer) Shrink(n int64) {
+	c.m.mtx.Lock()
+	defer c.m.mtx.Unlock()
+
+	if _, ok := c.m.consumers[c]; !ok {
+		return
+	}
+	if n > c.used {
+		n = c.used
+	}
+	c.used -= n
+	c.m.used -= n
+}
+
+// Used returns the number of bytes the consumer currently uses.
+func (c *Consumer) Used() int64 {
+	c.m.mtx.Lock()
+	defer c.m.mtx.Unlock()
+	return c.used
+}
+
+// Unregister removes the consumer from its manager, releasing its usage.
+// Further calls to Grow and Shrink have no effect.
+func (c *Consumer) Unregister() {
+	c.m.mtx.Lock()
+	defer c.m.mtx.Unlock()
+
+	if _, ok := c.m.consumers[c]; !ok {
+		return
+	}
+	delete(c.m.consumers, c)
+	c.m.used -= c.used
+	c.used = 0
+}
@@ -0,0 +1,160 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package membudget_test
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/zeusyf/btcutil/membudget"
+)
+
+// testCache is a cache of fixed size entries which frees whole entries when
+// asked to evict.
+type testCache struct {
+	mtx       sync.Mutex
+	entries   int
+	entrySize int64
+	consumer  *membudget.Consumer
+}
+
+func (c *testCache) add(n int) {
+	c.mtx.Lock()
+	c.entries += n
+	c.mtx.Unlock()
+	c.consumer.Grow(int64(n) * c.entrySize)
+}
+
+func (c *testCache) Evict(n int64) int64 {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	var freed int64
+	for freed < n && c.entries > 0 {
+		c.entries--
+		freed += c.entrySize
+	}
+	return freed
+}
+
+func newTestCache(m *membudget.Manager, name string, entrySize int64) *testCache {
+	c := &testCache{entrySize: entrySize}
+	c.consumer = m.Register(name, c)
+	return c
+}
+
+// TestManagerProportionalEviction ensures usage above the limit is evicted
+// from every consumer in proportion to its usage.
+func TestManagerProportionalEviction(t *testing.T) {
+	m := membudget.NewManager(1000)
+	a := newTestCache(m, "a", 10)
+	b := newTestCache(m, "b", 10)
+
+	a.add(60)
+	b.add(30)
+	if m.Used() != 900 {
+		t.Fatalf("Used: got %d, want 900", m.Used())
+	}
+
+	// 1200 bytes used with 600 in each cache, so 100 must be evicted
+	// from each.
+	b.add(30)
+	if m.Used() > m.Limit() {
+		t.Fatalf("Used: got %d, want at most %d", m.Used(), m.Limit())
+	}
+	if a.consumer.Used() != 500 || b.consumer.Used() != 500 {
+		t.Fatalf("Used: got a=%d b=%d, want 500 each",
+			a.consumer.Used(), b.consumer.Used())
+	}
+	if a.entries != 50 || b.entries != 50 {
+		t.Fatalf("entries: got a=%d b=%d, want 50 each", a.entries,
+			b.entries)
+	}
+
+	stats := m.Stats()
+	if len(stats) != 2 || stats[0].Name != "a" || stats[0].Evicted != 100 ||
+		stats[1].Name != "b" || stats[1].Evicted != 100 {
+		t.Fatalf("Stats: unexpected %+v", stats)
+	}
+
+	// Lowering the limit evicts right away.
+	m.SetLimit(500)
+	if m.Used() > 500 {
+		t.Fatalf("Used after SetLimit: got %d, want at most 500", m.Used())
+	}
+}
+
+// TestManagerUnlimited ensures a manager without a limit never evicts.
+func TestManagerUnlimited(t *testing.T) {
+	m := membudget.NewManager(0)
+	c := newTestCache(m, "c", 1<<20)
+	c.add(1000)
+	if c.entries != 1000 || m.Used() != 1000<<20 {
+		t.Fatalf("unexpected eviction: %d entries, %d bytes used",
+			c.entries, m.Used())
+	}
+}
+
+// TestConsumerShrinkUnregister ensures explicit frees and unregistering
+// release usage.
+func TestConsumerShrinkUnregister(t *testing.T) {
+	m := membudget.NewManager(1000)
+	a := newTestCache(m, "a", 10)
+	b := newTestCache(m, "b", 10)
+	a.add(40)
+	b.add(40)
+
+	a.consumer.Shrink(100)
+	if a.consumer.Used() != 300 || m.Used() != 700 {
+		t.Fatalf("Shrink: got %d/%d used, want 300/700",
+			a.consumer.Used(), m.Used())
+	}
+	a.consumer.Shrink(1000)
+	if a.consumer.Used() != 0 {
+		t.Fatalf("Shrink: usage went negative: %d", a.consumer.Used())
+	}
+
+	b.consumer.Unregister()
+	if m.Used() != 0 || len(m.Stats()) != 1 {
+		t.Fatalf("Unregister: got %d used, %d consumers", m.Used(),
+			len(m.Stats()))
+	}
+	b.add(200)
+	if m.Used() != 0 || b.entries != 240 {
+		t.Fatalf("Grow after Unregister: got %d used, %d entries",
+			m.Used(), b.entries)
+	}
+}
+
+// TestManagerConcurrent exercises the manager from several goroutines to
+// catch races and lock ordering problems and ensures the totals stay
+// consistent.
+func TestManagerConcurrent(t *testing.T) {
+	m := membudget.NewManager(10000)
+	caches := make([]*testCache, 4)
+	for i := range caches {
+		caches[i] = newTestCache(m, string(rune('a'+i)), 8)
+	}
+
+	var wg sync.WaitGroup
+	for _, c := range caches {
+		wg.Add(1)
+		go func(c *testCache) {
+			defer wg.Done()
+			for i := 0; i < 1000; i++ {
+				c.add(3)
+			}
+		}(c)
+	}
+	wg.Wait()
+
+	var sum int64
+	for _, c := range caches {
+		sum += c.consumer.Used()
+	}
+	if m.Used() != sum {
+		t.Fatalf("Used: got %d, want sum of consumers %d", m.Used(), sum)
+	}
+}
@@ -0,0 +1,188 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package merkle builds and verifies merkle branches proving that a
+// transaction is included in a block, independently of bloom filters.
+//
+// A branch holds the sibling hashes on the path from a transaction hash to
+// the merkle root of its block, so anybody holding the header of the block
+// can check the inclusion of the transaction from the transaction hash, its
+// position in the block, and the branch alone.  Exchanges use such proofs to
+// show that a deposit or withdrawal was mined, and a MultiProof shows that of
+// many transactions of a block at once, sharing the hashes their branches
+// have in common.
+//
+// The package also computes the merkle roots of blocks, both over the
+// transaction hashes and over the witness hashes committing to signatures,
+// and the coinbase witness commitment, so SPV and mining code share one
+// implementation.
+package merkle
+
+import (
+	"errors"
+
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/hashing"
+)
+
+var (
+	// ErrTxNotFound describes an error where a proof is requested for a
+	// transaction which is not in the block.
+	ErrTxNotFound = errors.New("transaction not found in block")
+
+	// ErrRootMismatch describes an error where a proof does not fold into
+	// the merkle root it is verified against.
+	ErrRootMismatch = errors.New("merkle branch does not match root")
+
+	// ErrIndexOutOfRange describes an error where a branch is requested
+	// for a leaf past the last one, or a proof claims such a leaf, such as
+	// the copy of the last leaf of an odd level.
+	ErrIndexOutOfRange = errors.New("merkle leaf index out of range")
+)
+
+// HashBranches returns the double sha256 hash of the concatenation of the two
+// passed hashes, the parent of two nodes of a merkle tree.
+func HashBranches(left, right *chainhash.Hash) chainhash.Hash {
+	var buf [chainhash.HashSize * 2]byte
+	copy(buf[:chainhash.HashSize], left[:])
+	copy(buf[chainhash.HashSize:], right[:])
+	return hashing.DoubleSum256(buf[:])
+}
+
+// Branch returns the merkle branch for the leaf at index together with the
+// merkle root of the passed leaves.  The last node of a level with an odd
+// number of nodes is paired with itself, as the merkle tree construction
+// rules mandate.  ErrIndexOutOfRange is returned when there is no leaf at
+// index.
+func Branch(leaves []chainhash.Hash, index int) ([]chainhash.Hash, chainhash.Hash, error) {
+	if index < 0 || index >= len(leaves) {
+		return nil, chainhash.Hash{}, ErrIndexOutOfRange
+	}
+	level := make([]chainhash.Hash, len(leaves))
+	copy(level, leaves)
+
+	var branch []chainhash.Hash
+	for len(level) > 1 {
+		if len(level)%2 != 0 {
+			level = append(level, level[len(level)-1])
+		}
+		branch = append(branch, level[index^1])
+
+		next := make([]chainhash.Hash, len(level)/2)
+		for i := range next {
+			next[i] = HashBranches(&level[i*2], &level[i*2+1])
+		}
+		level = next
+		index >>= 1
+	}
+	return branch, level[0], nil
+}
+
+// Root returns the merkle root of the passed leaves, or the zero hash when
+// there are none.
+func Root(leaves []chainhash.Hash) chainhash.Hash {
+	if len(leaves) == 0 {
+		return chainhash.Hash{}
+	}
+	_, root, _ := Branch(leaves, 0)
+	return root
+}
+
+// BranchRoot folds the passed branch into the hash of the leaf at index to
+// produce the merkle root it commits to.
+func BranchRoot(leaf chainhash.Hash, index uint32, branch []chainhash.Hash) chainhash.Hash {
+	root := leaf
+	for i := range branch {
+		if index&1 == 1 {
+			root = HashBranches(&branch[i], &root)
+		} else {
+			root = HashBranches(&root, &branch[i])
+		}
+		index >>= 1
+	}
+	return root
+}
+
+// Proof proves the inclusion of a transaction in a block.
+type Proof struct {
+	// TxHash is the hash of the transaction.
+	TxHash chainhash.Hash
+
+	// Index is the position of the transaction within its block.
+	Index uint32
+
+	// Branch is the list of sibling hashes from the transaction up to,
+	// but not including, the merkle root.
+	Branch []chainhash.Hash
+}
+
+// NewProof returns the proof of inclusion of the transaction with the passed
+// hash in block.
+func NewProof(block *btcutil.Block, txHash *chainhash.Hash) (*Proof, error) {
+	txns := block.Transactions()
+	leaves := make([]chainhash.Hash, len(txns))
+	index := -1
+	for i, tx := range txns {
+		leaves[i] = *tx.Hash()
+		if index < 0 && leaves[i] == *txHash {
+			index = i
+		}
+	}
+	if index < 0 {
+		return nil, ErrTxNotFound
+	}
+
+	branch, _, err := Branch(leaves, index)
+	if err != nil {
+		return nil, err
+	}
+	return &Proof{
+		TxHash: *txHash,
+		Index:  uint32(index),
+		Branch: branch,
+	}, nil
+}
+
+// Root returns the merkle root the proof commits to.
+func (p *Proof) Root() chainhash.Hash {
+	return BranchRoot(p.TxHash, p.Index, p.Branch)
+}
+
+// Verify checks that the proof commits to the passed merkle root, which is
+// taken from the header of a block the verifier trusts.
+//
+// Since the last node of an odd level is paired with itself, a branch for
+// the last leaf of such a level also folds into the root from the index
+// following it, where there is no leaf.  ErrIndexOutOfRange is returned for
+// such indexes, recognized by a right node equal to its sibling, and for
+// indexes with bits above the depth of the branch.
+func (p *Proof) Verify(root *chainhash.Hash) error {
+	if len(p.Branch) < 32 && p.Index>>uint(len(p.Branch)) != 0 {
+		return ErrIndexOutOfRange
+	}
+	node, index := p.TxHash, p.Index
+	for i := range p.Branch {
+		if index&1 == 1 {
+			if p.Branch[i] == node {
+				return ErrIndexOutOfRange
+			}
+			node = HashBranches(&p.Branch[i], &node)
+		} else {
+			node = HashBranches(&node, &p.Branch[i])
+		}
+		index >>= 1
+	}
+	if node != *root {
+		return ErrRootMismatch
+	}
+	return nil
+}
+
+// Verify checks that branch proves the inclusion of the transaction with the
+// passed hash at index in the block with the passed merkle root.
+func Verify(txHash *chainhash.Hash, index uint32, branch []chainhash.Hash, root *chainhash.Hash) error {
+	p := Proof{TxHash: *txHash, Index: index, Branch: branch}
+	return p.Verify(root)
+}
@@ -0,0 +1,149 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package merkle_test
+
+import (
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/merkle"
+)
+
+// testTx returns a distinct transaction identified by the passed index.
+func testTx(id uint32) *wire.MsgTx {
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: wire.OutPoint{Index: id},
+		Sequence:         wire.MaxTxInSequenceNum,
+	})
+	return tx
+}
+
+// TestBranch ensures branches for every leaf of various tree sizes fold back
+// into the tree root.
+func TestBranch(t *testing.T) {
+	a, b, c := testTx(0).TxHash(), testTx(1).TxHash(), testTx(2).TxHash()
+	ab := merkle.HashBranches(&a, &b)
+	cc := merkle.HashBranches(&c, &c)
+	if root := merkle.Root([]chainhash.Hash{a, b, c}); root != merkle.HashBranches(&ab, &cc) {
+		t.Errorf("Root: got %v for three leaves", root)
+	}
+	if root := merkle.Root([]chainhash.Hash{a}); root != a {
+		t.Errorf("Root: got %v for a single leaf", root)
+	}
+	if root := merkle.Root(nil); root != (chainhash.Hash{}) {
+		t.Errorf("Root: got %v without leaves", root)
+	}
+
+	for size := 1; size <= 9; size++ {
+		leaves := make([]chainhash.Hash, size)
+		for i := range leaves {
+			leaves[i] = testTx(uint32(i)).TxHash()
+		}
+		want := merkle.Root(leaves)
+		for i := range leaves {
+			branch, root, err := merkle.Branch(leaves, i)
+			if err != nil || root != want {
+				t.Fatalf("size %d leaf %d: root mismatch", size, i)
+			}
+			got := merkle.BranchRoot(leaves[i], uint32(i), branch)
+			if got != want {
+				t.Fatalf("size %d leaf %d: got root %v, want %v",
+					size, i, got, want)
+			}
+		}
+	}
+}
+
+// TestProof ensures proofs for the transactions of a block verify against
+// its merkle root, and altered proofs do not.
+func TestProof(t *testing.T) {
+	msgBlock := &wire.MsgBlock{}
+	var leaves []chainhash.Hash
+	for i := uint32(0); i < 5; i++ {
+		tx := testTx(i)
+		msgBlock.Transactions = append(msgBlock.Transactions, tx)
+		leaves = append(leaves, tx.TxHash())
+	}
+	root := merkle.Root(leaves)
+	msgBlock.Header.MerkleRoot = root
+	block := btcutil.NewBlock(msgBlock)
+
+	for i := range leaves {
+		proof, err := merkle.NewProof(block, &leaves[i])
+		if err != nil {
+			t.Fatalf("NewProof: unexpected error: %v", err)
+		}
+		if proof.Index != uint32(i) || proof.TxHash != leaves[i] {
+			t.Errorf("NewProof: got index %d and hash %v for tx %d",
+				proof.Index, proof.TxHash, i)
+		}
+		if err := proof.Verify(&msgBlock.Header.MerkleRoot); err != nil {
+			t.Errorf("Verify: unexpected error for tx %d: %v", i, err)
+		}
+		err = merkle.Verify(&proof.TxHash, proof.Index, proof.Branch, &root)
+		if err != nil {
+			t.Errorf("Verify: unexpected error for tx %d: %v", i, err)
+		}
+	}
+
+	proof, err := merkle.NewProof(block, &leaves[3])
+	if err != nil {
+		t.Fatalf("NewProof: unexpected error: %v", err)
+	}
+	other := testTx(9).TxHash()
+	tampered := append([]chainhash.Hash(nil), proof.Branch...)
+	tampered[1][0] ^= 1
+	tests := []struct {
+		name   string
+		txHash chainhash.Hash
+		index  uint32
+		branch []chainhash.Hash
+	}{
+		{"other tx", other, 3, proof.Branch},
+		{"wrong index", proof.TxHash, 2, proof.Branch},
+		{"tampered branch", proof.TxHash, 3, tampered},
+		{"short branch", proof.TxHash, 3, proof.Branch[:2]},
+	}
+	for _, test := range tests {
+		err := merkle.Verify(&test.txHash, test.index, test.branch, &root)
+		if err != merkle.ErrRootMismatch {
+			t.Errorf("Verify(%s): got error %v, want %v", test.name, err,
+				merkle.ErrRootMismatch)
+		}
+	}
+
+	if _, err := merkle.NewProof(block, &other); err != merkle.ErrTxNotFound {
+		t.Errorf("NewProof: got error %v, want %v", err,
+			merkle.ErrTxNotFound)
+	}
+
+	// The branch of the last of the five leaves also folds into the root
+	// from the index of its padding copy, or with bits set above the depth
+	// of the branch, neither of which is a leaf.
+	last, err := merkle.NewProof(block, &leaves[4])
+	if err != nil {
+		t.Fatalf("NewProof: unexpected error: %v", err)
+	}
+	for _, index := range []uint32{5, 4 | 1<<uint(len(last.Branch))} {
+		if merkle.BranchRoot(last.TxHash, index, last.Branch) != root {
+			t.Fatalf("BranchRoot(%d): padding does not fold into root",
+				index)
+		}
+		err := merkle.Verify(&last.TxHash, index, last.Branch, &root)
+		if err != merkle.ErrIndexOutOfRange {
+			t.Errorf("Verify(%d): got error %v, want %v", index, err,
+				merkle.ErrIndexOutOfRange)
+		}
+	}
+	for _, index := range []int{-1, len(leaves)} {
+		if _, _, err := merkle.Branch(leaves, index); err != merkle.ErrIndexOutOfRange {
+			t.Errorf("Branch(%d): got error %v, want %v", index, err,
+				merkle.ErrIndexOutOfRange)
+		}
+	}
+}
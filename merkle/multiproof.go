@@ -0,0 +1,202 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package merkle
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcutil"
+)
+
+// ErrInvalidMultiProof describes an error where a multi-proof is malformed:
+// its positions are not sorted and distinct, lie outside the block, or it
+// does not have exactly the hashes needed to compute its root.
+var ErrInvalidMultiProof = errors.New("invalid merkle multi-proof")
+
+// MultiProof proves the inclusion of several transactions in a block.  The
+// branches of the transactions share all hashes above the point where their
+// paths meet, and the hashes of the proven transactions are not repeated as
+// siblings of one another, so a multi-proof is much smaller than separate
+// proofs of the same transactions.
+type MultiProof struct {
+	// NumLeaves is the number of transactions in the block, which
+	// determines the shape of its merkle tree.
+	NumLeaves uint32
+
+	// Indices are the positions of the proven transactions within the
+	// block, in increasing order.
+	Indices []uint32
+
+	// TxHashes are the hashes of the proven transactions, in the order of
+	// Indices.
+	TxHashes []chainhash.Hash
+
+	// Hashes are the sibling hashes not computed from the proven
+	// transactions, ordered level by level from the leaves up and left to
+	// right within a level.
+	Hashes []chainhash.Hash
+}
+
+// node is a known node of a level of the merkle tree.
+type node struct {
+	pos  uint32
+	hash chainhash.Hash
+}
+
+// foldLevel computes the known nodes of the next level from the known nodes of
+// a level of width nodes, calling sibling for each sibling hash that is not
+// known.  The last node of a level with an odd number of nodes is paired with
+// itself.
+func foldLevel(known []node, width uint32, sibling func(pos uint32) (chainhash.Hash, bool)) ([]node, bool) {
+	var next []node
+	for i := 0; i < len(known); i++ {
+		n := known[i]
+		var left, right chainhash.Hash
+		switch {
+		case n.pos^1 >= width:
+			left, right = n.hash, n.hash
+
+		case n.pos&1 == 0 && i+1 < len(known) && known[i+1].pos == n.pos+1:
+			left, right = n.hash, known[i+1].hash
+			i++
+
+		default:
+			h, ok := sibling(n.pos ^ 1)
+			if !ok {
+				return nil, false
+			}
+			left, right = n.hash, h
+			if n.pos&1 == 1 {
+				left, right = h, n.hash
+			}
+		}
+		next = append(next, node{n.pos >> 1, HashBranches(&left, &right)})
+	}
+	return next, true
+}
+
+// MultiBranch returns the hashes of the multi-proof for the leaves at the
+// passed indices, which must be sorted and distinct, together with the merkle
+// root of the leaves.
+func MultiBranch(leaves []chainhash.Hash, indices []uint32) ([]chainhash.Hash, chainhash.Hash) {
+	if len(leaves) == 0 {
+		return nil, chainhash.Hash{}
+	}
+	level := make([]chainhash.Hash, len(leaves))
+	copy(level, leaves)
+	known := make([]node, len(indices))
+	for i, index := range indices {
+		known[i] = node{index, leaves[index]}
+	}
+
+	var hashes []chainhash.Hash
+	for len(level) > 1 {
+		known, _ = foldLevel(known, uint32(len(level)), func(pos uint32) (chainhash.Hash, bool) {
+			hashes = append(hashes, level[pos])
+			return level[pos], true
+		})
+
+		if len(level)%2 != 0 {
+			level = append(level, level[len(level)-1])
+		}
+		next := make([]chainhash.Hash, len(level)/2)
+		for i := range next {
+			next[i] = HashBranches(&level[i*2], &level[i*2+1])
+		}
+		level = next
+	}
+	return hashes, level[0]
+}
+
+// NewMultiProof returns the proof of inclusion of the transactions with the
+// passed hashes in block.  The transactions may be passed in any order.
+func NewMultiProof(block *btcutil.Block, txHashes []*chainhash.Hash) (*MultiProof, error) {
+	txns := block.Transactions()
+	leaves := make([]chainhash.Hash, len(txns))
+	positions := make(map[chainhash.Hash]uint32, len(txns))
+	for i, tx := range txns {
+		leaves[i] = *tx.Hash()
+		if _, ok := positions[leaves[i]]; !ok {
+			positions[leaves[i]] = uint32(i)
+		}
+	}
+
+	seen := make(map[uint32]struct{}, len(txHashes))
+	indices := make([]uint32, 0, len(txHashes))
+	for _, txHash := range txHashes {
+		index, ok := positions[*txHash]
+		if !ok {
+			return nil, ErrTxNotFound
+		}
+		if _, ok := seen[index]; ok {
+			continue
+		}
+		seen[index] = struct{}{}
+		indices = append(indices, index)
+	}
+	sort.Slice(indices, func(i, j int) bool {
+		return indices[i] < indices[j]
+	})
+
+	p := &MultiProof{
+		NumLeaves: uint32(len(leaves)),
+		Indices:   indices,
+		TxHashes:  make([]chainhash.Hash, len(indices)),
+	}
+	for i, index := range indices {
+		p.TxHashes[i] = leaves[index]
+	}
+	p.Hashes, _ = MultiBranch(leaves, indices)
+	return p, nil
+}
+
+// Root returns the merkle root the multi-proof commits to.
+func (p *MultiProof) Root() (chainhash.Hash, error) {
+	if len(p.Indices) == 0 || len(p.Indices) != len(p.TxHashes) {
+		return chainhash.Hash{}, ErrInvalidMultiProof
+	}
+	known := make([]node, len(p.Indices))
+	for i, index := range p.Indices {
+		if index >= p.NumLeaves || (i > 0 && index <= p.Indices[i-1]) {
+			return chainhash.Hash{}, ErrInvalidMultiProof
+		}
+		known[i] = node{index, p.TxHashes[i]}
+	}
+
+	hashes := p.Hashes
+	for width := p.NumLeaves; width > 1; width = (width + 1) / 2 {
+		var ok bool
+		known, ok = foldLevel(known, width, func(uint32) (chainhash.Hash, bool) {
+			if len(hashes) == 0 {
+				return chainhash.Hash{}, false
+			}
+			h := hashes[0]
+			hashes = hashes[1:]
+			return h, true
+		})
+		if !ok {
+			return chainhash.Hash{}, ErrInvalidMultiProof
+		}
+	}
+	if len(hashes) != 0 {
+		return chainhash.Hash{}, ErrInvalidMultiProof
+	}
+	return known[0].hash, nil
+}
+
+// Verify checks that the multi-proof commits to the passed merkle root, which
+// is taken from the header of a block the verifier trusts.
+func (p *MultiProof) Verify(root *chainhash.Hash) error {
+	got, err := p.Root()
+	if err != nil {
+		return err
+	}
+	if got != *root {
+		return ErrRootMismatch
+	}
+	return nil
+}
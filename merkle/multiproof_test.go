@@ -0,0 +1,146 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package merkle_test
+
+import (
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/merkle"
+)
+
+// TestMultiBranch ensures multi-proofs of every subset of the leaves of
+// various tree sizes fold back into the tree root, and are no larger than
+// separate branches.
+func TestMultiBranch(t *testing.T) {
+	for size := 1; size <= 9; size++ {
+		leaves := make([]chainhash.Hash, size)
+		for i := range leaves {
+			leaves[i] = testTx(uint32(i)).TxHash()
+		}
+		want := merkle.Root(leaves)
+
+		for set := 1; set < 1<<uint(size); set++ {
+			p := merkle.MultiProof{NumLeaves: uint32(size)}
+			separate := 0
+			for i := 0; i < size; i++ {
+				if set&(1<<uint(i)) != 0 {
+					p.Indices = append(p.Indices, uint32(i))
+					p.TxHashes = append(p.TxHashes, leaves[i])
+					branch, _, _ := merkle.Branch(leaves, i)
+					separate += len(branch)
+				}
+			}
+			var root chainhash.Hash
+			p.Hashes, root = merkle.MultiBranch(leaves, p.Indices)
+			if root != want {
+				t.Fatalf("size %d set %b: root mismatch", size, set)
+			}
+			if len(p.Hashes) > separate {
+				t.Errorf("size %d set %b: %d hashes, more than %d "+
+					"of separate branches", size, set,
+					len(p.Hashes), separate)
+			}
+			if err := p.Verify(&want); err != nil {
+				t.Fatalf("size %d set %b: Verify: %v", size, set,
+					err)
+			}
+		}
+	}
+}
+
+// TestMultiProof ensures multi-proofs of the transactions of a block verify
+// against its merkle root, and malformed or altered proofs do not.
+func TestMultiProof(t *testing.T) {
+	msgBlock := &wire.MsgBlock{}
+	var leaves []chainhash.Hash
+	for i := uint32(0); i < 7; i++ {
+		tx := testTx(i)
+		msgBlock.Transactions = append(msgBlock.Transactions, tx)
+		leaves = append(leaves, tx.TxHash())
+	}
+	root := merkle.Root(leaves)
+	block := btcutil.NewBlock(msgBlock)
+
+	// Transactions may be passed in any order and more than once.
+	proof, err := merkle.NewMultiProof(block, []*chainhash.Hash{
+		&leaves[5], &leaves[1], &leaves[2], &leaves[5],
+	})
+	if err != nil {
+		t.Fatalf("NewMultiProof: unexpected error: %v", err)
+	}
+	if len(proof.Indices) != 3 || proof.Indices[0] != 1 ||
+		proof.Indices[1] != 2 || proof.Indices[2] != 5 {
+		t.Fatalf("NewMultiProof: got indices %v", proof.Indices)
+	}
+	if proof.TxHashes[2] != leaves[5] {
+		t.Errorf("NewMultiProof: got hash %v for index 5",
+			proof.TxHashes[2])
+	}
+	if err := proof.Verify(&root); err != nil {
+		t.Fatalf("Verify: unexpected error: %v", err)
+	}
+
+	// Leaves 1 and 2 need 0, 3 and the right half below the root; leaf 5
+	// needs 4 and the pair of 6 with itself.
+	if len(proof.Hashes) != 4 {
+		t.Errorf("got %d hashes, want 4", len(proof.Hashes))
+	}
+
+	clone := func() merkle.MultiProof {
+		p := *proof
+		p.Indices = append([]uint32(nil), proof.Indices...)
+		p.TxHashes = append([]chainhash.Hash(nil), proof.TxHashes...)
+		p.Hashes = append([]chainhash.Hash(nil), proof.Hashes...)
+		return p
+	}
+	tests := []struct {
+		name   string
+		modify func(*merkle.MultiProof)
+		err    error
+	}{
+		{"other tx", func(p *merkle.MultiProof) {
+			p.TxHashes[1] = testTx(9).TxHash()
+		}, merkle.ErrRootMismatch},
+		{"tampered hash", func(p *merkle.MultiProof) {
+			p.Hashes[2][0] ^= 1
+		}, merkle.ErrRootMismatch},
+		{"wrong size", func(p *merkle.MultiProof) {
+			p.NumLeaves = 6
+		}, merkle.ErrInvalidMultiProof},
+		{"missing hash", func(p *merkle.MultiProof) {
+			p.Hashes = p.Hashes[:3]
+		}, merkle.ErrInvalidMultiProof},
+		{"extra hash", func(p *merkle.MultiProof) {
+			p.Hashes = append(p.Hashes, root)
+		}, merkle.ErrInvalidMultiProof},
+		{"unsorted", func(p *merkle.MultiProof) {
+			p.Indices[0], p.Indices[1] = p.Indices[1], p.Indices[0]
+		}, merkle.ErrInvalidMultiProof},
+		{"out of range", func(p *merkle.MultiProof) {
+			p.Indices[2] = 7
+		}, merkle.ErrInvalidMultiProof},
+		{"no transactions", func(p *merkle.MultiProof) {
+			p.Indices, p.TxHashes = nil, nil
+		}, merkle.ErrInvalidMultiProof},
+	}
+	for _, test := range tests {
+		p := clone()
+		test.modify(&p)
+		if err := p.Verify(&root); err != test.err {
+			t.Errorf("Verify(%s): got error %v, want %v", test.name,
+				err, test.err)
+		}
+	}
+
+	other := testTx(9).TxHash()
+	_, err = merkle.NewMultiProof(block, []*chainhash.Hash{&leaves[0], &other})
+	if err != merkle.ErrTxNotFound {
+		t.Errorf("NewMultiProof: got error %v, want %v", err,
+			merkle.ErrTxNotFound)
+	}
+}
@@ -0,0 +1,186 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package merkle
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/hashing"
+)
+
+// CoinbaseWitnessDataLen is the length of the witness nonce mixed into the
+// witness commitment.
+const CoinbaseWitnessDataLen = chainhash.HashSize
+
+// WitnessCommitmentHeader is the prefix of the public key script of the
+// coinbase output carrying the witness commitment: an OP_RETURN pushing 36
+// bytes, the first four of which are the magic 0xaa21a9ed of BIP0141.
+var WitnessCommitmentHeader = []byte{0x6a, 0x24, 0xaa, 0x21, 0xa9, 0xed}
+
+// witnessCommitmentScriptLen is the length of the commitment script.
+const witnessCommitmentScriptLen = 6 + chainhash.HashSize
+
+var (
+	// ErrNoWitnessCommitment describes an error where the coinbase of a
+	// block has no output carrying a witness commitment.
+	ErrNoWitnessCommitment = errors.New("block has no witness commitment")
+
+	// ErrWitnessCommitmentMismatch describes an error where the witness
+	// commitment of a block does not match its transactions.
+	ErrWitnessCommitmentMismatch = errors.New("witness commitment does " +
+		"not match block")
+)
+
+// WitnessHash returns the hash of tx including its signature scripts, the
+// leaf of tx in the witness merkle tree.  Unlike the transaction hash, it
+// changes whenever a signature of the transaction does.
+func WitnessHash(tx *btcutil.Tx) (chainhash.Hash, error) {
+	var buf bytes.Buffer
+	buf.Grow(tx.MsgTx().SerializeSize())
+	if err := tx.MsgTx().Serialize(&buf); err != nil {
+		return chainhash.Hash{}, err
+	}
+	return hashing.DoubleSum256(buf.Bytes()), nil
+}
+
+// leaves returns the leaves of the merkle tree of transactions: their hashes,
+// or when witness is set, their witness hashes, with the zero hash for the
+// coinbase as the coinbase commits to the witness tree itself.
+func leaves(transactions []*btcutil.Tx, witness bool) ([]chainhash.Hash, error) {
+	hashes := make([]chainhash.Hash, len(transactions))
+	for i, tx := range transactions {
+		switch {
+		case !witness:
+			hashes[i] = *tx.Hash()
+		case i == 0:
+			// The coinbase is left as the zero hash.
+		default:
+			hash, err := WitnessHash(tx)
+			if err != nil {
+				return nil, err
+			}
+			hashes[i] = hash
+		}
+	}
+	return hashes, nil
+}
+
+// BuildMerkleTreeStore returns the merkle tree of transactions as a linear
+// array, in the layout of the tree store of btcd: the leaves padded to the
+// next power of two, followed by each level of the tree, ending with the
+// root.  Padding nodes are nil.  When witness is set, the tree is built over
+// the witness hashes, with the zero hash for the coinbase.
+//
+// For example, the store of three transactions is:
+//
+//	[h1 h2 h3 nil h12 h33 h1233]
+//
+// The tree store is how mining code reads the branch of the coinbase.  Code
+// only needing the root should use CalcMerkleRoot.
+func BuildMerkleTreeStore(transactions []*btcutil.Tx,
+	witness bool) ([]*chainhash.Hash, error) {
+	hashes, err := leaves(transactions, witness)
+	if err != nil {
+		return nil, err
+	}
+
+	width := 1
+	for width < len(hashes) {
+		width <<= 1
+	}
+	store := make([]*chainhash.Hash, width*2-1)
+	for i := range hashes {
+		store[i] = &hashes[i]
+	}
+
+	// Each level is hashed from the level below.  A missing right node
+	// pairs the left node with itself, and a missing left node leaves its
+	// parent missing.
+	offset := width
+	for i := 0; i < len(store)-1; i += 2 {
+		var parent *chainhash.Hash
+		switch {
+		case store[i] == nil:
+		case store[i+1] == nil:
+			hash := HashBranches(store[i], store[i])
+			parent = &hash
+		default:
+			hash := HashBranches(store[i], store[i+1])
+			parent = &hash
+		}
+		store[offset] = parent
+		offset++
+	}
+	return store, nil
+}
+
+// CalcMerkleRoot returns the merkle root of transactions, or of their witness
+// hashes when witness is set.  The zero hash is returned for no transactions.
+func CalcMerkleRoot(transactions []*btcutil.Tx, witness bool) (chainhash.Hash, error) {
+	hashes, err := leaves(transactions, witness)
+	if err != nil {
+		return chainhash.Hash{}, err
+	}
+	return Root(hashes), nil
+}
+
+// WitnessCommitment returns the commitment to the witness merkle root of a
+// block carried by its coinbase: the double sha256 hash of the root followed
+// by the witness nonce.
+func WitnessCommitment(witnessRoot, nonce *chainhash.Hash) chainhash.Hash {
+	return HashBranches(witnessRoot, nonce)
+}
+
+// WitnessCommitmentScript returns the public key script of the coinbase
+// output carrying commitment.
+func WitnessCommitmentScript(commitment *chainhash.Hash) []byte {
+	script := make([]byte, 0, witnessCommitmentScriptLen)
+	script = append(script, WitnessCommitmentHeader...)
+	return append(script, commitment[:]...)
+}
+
+// ExtractWitnessCommitment returns the witness commitment carried by the
+// passed coinbase and whether one was found.  As BIP0141 mandates, the last
+// output carrying a commitment wins when there are several.
+func ExtractWitnessCommitment(coinbase *btcutil.Tx) (chainhash.Hash, bool) {
+	txOuts := coinbase.MsgTx().TxOut
+	for i := len(txOuts) - 1; i >= 0; i-- {
+		script := txOuts[i].PkScript
+		if len(script) >= witnessCommitmentScriptLen &&
+			bytes.HasPrefix(script, WitnessCommitmentHeader) {
+			var commitment chainhash.Hash
+			copy(commitment[:], script[len(WitnessCommitmentHeader):])
+			return commitment, true
+		}
+	}
+	return chainhash.Hash{}, false
+}
+
+// ValidateWitnessCommitment checks that the coinbase of block commits to the
+// witness merkle root of its transactions with the passed witness nonce.
+// ErrNoWitnessCommitment is returned when the block has no transactions or
+// its coinbase carries no commitment, and ErrWitnessCommitmentMismatch when
+// the commitment does not match.
+func ValidateWitnessCommitment(block *btcutil.Block, nonce *chainhash.Hash) error {
+	txns := block.Transactions()
+	if len(txns) == 0 {
+		return ErrNoWitnessCommitment
+	}
+	commitment, ok := ExtractWitnessCommitment(txns[0])
+	if !ok {
+		return ErrNoWitnessCommitment
+	}
+	root, err := CalcMerkleRoot(txns, true)
+	if err != nil {
+		return err
+	}
+	if WitnessCommitment(&root, nonce) != commitment {
+		return ErrWitnessCommitmentMismatch
+	}
+	return nil
+}
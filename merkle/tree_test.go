@@ -0,0 +1,119 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package merkle_test
+
+import (
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/merkle"
+)
+
+// testTxs returns n distinct wrapped transactions, each with a signature.
+func testTxs(n int) []*btcutil.Tx {
+	txs := make([]*btcutil.Tx, n)
+	for i := range txs {
+		msgTx := testTx(uint32(i))
+		msgTx.SignatureScripts = [][]byte{{byte(i), 0x01}}
+		txs[i] = btcutil.NewTx(msgTx)
+	}
+	return txs
+}
+
+// TestBuildMerkleTreeStore ensures the root of the tree store matches the
+// merkle root and padding nodes are nil.
+func TestBuildMerkleTreeStore(t *testing.T) {
+	for size := 1; size <= 9; size++ {
+		txs := testTxs(size)
+		store, err := merkle.BuildMerkleTreeStore(txs, false)
+		if err != nil {
+			t.Fatalf("size %d: BuildMerkleTreeStore: %v", size, err)
+		}
+		root, err := merkle.CalcMerkleRoot(txs, false)
+		if err != nil {
+			t.Fatalf("size %d: CalcMerkleRoot: %v", size, err)
+		}
+		if *store[len(store)-1] != root {
+			t.Errorf("size %d: store root %v, want %v", size,
+				store[len(store)-1], root)
+		}
+		for i, tx := range txs {
+			if *store[i] != *tx.Hash() {
+				t.Errorf("size %d: leaf %d mismatch", size, i)
+			}
+		}
+		for i := size; i < (len(store)+1)/2; i++ {
+			if store[i] != nil {
+				t.Errorf("size %d: padding leaf %d not nil", size, i)
+			}
+		}
+	}
+
+	txs := testTxs(3)
+	store, _ := merkle.BuildMerkleTreeStore(txs, false)
+	h12 := merkle.HashBranches(store[0], store[1])
+	h33 := merkle.HashBranches(store[2], store[2])
+	if len(store) != 7 || *store[4] != h12 || *store[5] != h33 {
+		t.Errorf("BuildMerkleTreeStore: unexpected store %v", store)
+	}
+}
+
+// TestWitnessCommitment ensures witness roots commit to signatures and
+// coinbase commitments are extracted and validated.
+func TestWitnessCommitment(t *testing.T) {
+	txs := testTxs(4)
+	root, _ := merkle.CalcMerkleRoot(txs, false)
+	witnessRoot, err := merkle.CalcMerkleRoot(txs, true)
+	if err != nil {
+		t.Fatalf("CalcMerkleRoot: %v", err)
+	}
+	if witnessRoot == root {
+		t.Fatalf("CalcMerkleRoot: witness root equals merkle root")
+	}
+
+	// Changing a signature changes the witness root but not the root.
+	txs[2].MsgTx().SignatureScripts[0] = []byte{0xff}
+	if r, _ := merkle.CalcMerkleRoot(txs, false); r != root {
+		t.Errorf("CalcMerkleRoot: root changed with a signature")
+	}
+	if r, _ := merkle.CalcMerkleRoot(txs, true); r == witnessRoot {
+		t.Errorf("CalcMerkleRoot: witness root unchanged with a signature")
+	}
+	witnessRoot, _ = merkle.CalcMerkleRoot(txs, true)
+
+	nonce := chainhash.Hash{0x01}
+	commitment := merkle.WitnessCommitment(&witnessRoot, &nonce)
+	coinbase := txs[0].MsgTx()
+	coinbase.AddTxOut(&wire.TxOut{PkScript: []byte{0x51}})
+
+	msgBlock := &wire.MsgBlock{}
+	for _, tx := range txs {
+		msgBlock.Transactions = append(msgBlock.Transactions, tx.MsgTx())
+	}
+	err = merkle.ValidateWitnessCommitment(btcutil.NewBlock(msgBlock), &nonce)
+	if err != merkle.ErrNoWitnessCommitment {
+		t.Errorf("ValidateWitnessCommitment: got error %v, want %v", err,
+			merkle.ErrNoWitnessCommitment)
+	}
+
+	// A stale commitment followed by the right one validates.
+	stale := chainhash.Hash{0x02}
+	coinbase.AddTxOut(&wire.TxOut{PkScript: merkle.WitnessCommitmentScript(&stale)})
+	coinbase.AddTxOut(&wire.TxOut{PkScript: merkle.WitnessCommitmentScript(&commitment)})
+	got, ok := merkle.ExtractWitnessCommitment(btcutil.NewTx(coinbase))
+	if !ok || got != commitment {
+		t.Errorf("ExtractWitnessCommitment: got %v, %v", got, ok)
+	}
+	if err := merkle.ValidateWitnessCommitment(btcutil.NewBlock(msgBlock), &nonce); err != nil {
+		t.Errorf("ValidateWitnessCommitment: %v", err)
+	}
+	err = merkle.ValidateWitnessCommitment(btcutil.NewBlock(msgBlock), &stale)
+	if err != merkle.ErrWitnessCommitmentMismatch {
+		t.Errorf("ValidateWitnessCommitment: got error %v, want %v", err,
+			merkle.ErrWitnessCommitmentMismatch)
+	}
+}
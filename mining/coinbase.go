@@ -0,0 +1,332 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package mining provides helpers for building block templates, such as
+// coinbase transactions, for pool software and other miners built on OMC.
+//
+// The coinbase script is the data miners put in a coinbase.  It starts with the
+// height of the block as BIP0034 mandates, followed by room for an extra nonce
+// which miners vary to get a new merkle root once the nonce space of the
+// header is exhausted, and any data identifying the pool.  Since the hashes
+// of Omega transactions do not commit to their signature scripts, a coinbase
+// built by NewCoinbase carries its coinbase script in its first output
+// instead: a zero value null data output whose public key script is
+// OP_RETURN followed by the coinbase script.
+package mining
+
+import (
+	"bytes"
+	"errors"
+	"math"
+	"math/bits"
+
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/merkle"
+	"github.com/zeusyf/btcutil/scriptclass"
+	"github.com/zeusyf/omega/token"
+)
+
+const (
+	// MinCoinbaseScriptLen is the minimum length of a coinbase script.
+	MinCoinbaseScriptLen = 2
+
+	// MaxCoinbaseScriptLen is the maximum length of a coinbase script.
+	MaxCoinbaseScriptLen = 100
+
+	// MaxExtraNonceSize is the largest extra nonce a coinbase has room
+	// for, pushed with a single byte opcode.
+	MaxExtraNonceSize = 75
+)
+
+var (
+	// ErrNoPayouts describes an error where a coinbase is built without
+	// any payout or with payouts whose weights sum to zero.
+	ErrNoPayouts = errors.New("coinbase has no payouts")
+
+	// ErrInvalidHeight describes an error where a coinbase is built for a
+	// negative height.
+	ErrInvalidHeight = errors.New("invalid coinbase height")
+
+	// ErrExtraNonceSize describes an error where the extra nonce of a
+	// coinbase is larger than MaxExtraNonceSize, or an extra nonce being
+	// set is not of the size the coinbase has room for.
+	ErrExtraNonceSize = errors.New("invalid extra nonce size")
+
+	// ErrInvalidValue describes an error where a coinbase is built paying
+	// a negative value.
+	ErrInvalidValue = errors.New("invalid coinbase value")
+
+	// ErrNoHeight describes an error where a coinbase script does not
+	// start with a push of the block height.
+	ErrNoHeight = errors.New("coinbase script does not commit to a height")
+)
+
+// Payout is a share of the value of a coinbase paid to an address.
+type Payout struct {
+	// Address is the address paid.
+	Address btcutil.Address
+
+	// Weight is the share of the address relative to the weights of the
+	// other payouts.
+	Weight uint64
+}
+
+// CoinbaseConfig describes a coinbase transaction to build.
+type CoinbaseConfig struct {
+	// Height is the height of the block the coinbase is for.
+	Height int32
+
+	// Value is the value paid by the coinbase, the block subsidy plus the
+	// fees of the transactions of the block.
+	Value btcutil.Amount
+
+	// Payouts are the addresses the value is split between, in proportion
+	// to their weights.  The remainder of the division goes to the first
+	// payout.
+	Payouts []Payout
+
+	// ExtraNonceSize is the number of bytes reserved for the extra nonce.
+	ExtraNonceSize int
+
+	// Tag is data appended to the coinbase script, such as the name of
+	// the pool.  It is truncated so the script fits MaxCoinbaseScriptLen.
+	Tag []byte
+
+	// WitnessCommitment, when set, is added as the last output of the
+	// coinbase.  See merkle.WitnessCommitment.
+	WitnessCommitment *chainhash.Hash
+}
+
+// Coinbase is a coinbase transaction along with the position of its extra
+// nonce.
+type Coinbase struct {
+	// Tx is the coinbase transaction.
+	Tx *wire.MsgTx
+
+	extraNonceOffset int
+	extraNonceSize   int
+}
+
+// CoinbaseScript returns the coinbase script carried by the first output of a
+// coinbase built by NewCoinbase.  ErrNoHeight is returned when the output is
+// not a null data output starting with a height.
+func CoinbaseScript(tx *wire.MsgTx) ([]byte, error) {
+	if len(tx.TxOut) == 0 {
+		return nil, ErrNoHeight
+	}
+	pkScript := tx.TxOut[0].PkScript
+	if len(pkScript) < 1+MinCoinbaseScriptLen ||
+		pkScript[0] != scriptclass.OP_RETURN {
+		return nil, ErrNoHeight
+	}
+	script := pkScript[1:]
+	if _, err := ExtractHeight(script); err != nil {
+		return nil, err
+	}
+	return script, nil
+}
+
+// Script returns the coinbase script of the coinbase.
+func (c *Coinbase) Script() []byte {
+	return c.Tx.TxOut[0].PkScript[1:]
+}
+
+// NewCoinbase returns the coinbase described by cfg, with an extra nonce of
+// zeros.
+func NewCoinbase(cfg *CoinbaseConfig) (*Coinbase, error) {
+	if cfg.Height < 0 {
+		return nil, ErrInvalidHeight
+	}
+	if cfg.ExtraNonceSize < 0 || cfg.ExtraNonceSize > MaxExtraNonceSize {
+		return nil, ErrExtraNonceSize
+	}
+	values, err := splitValue(cfg.Value, cfg.Payouts)
+	if err != nil {
+		return nil, err
+	}
+
+	script := HeightScript(cfg.Height)
+	var extraNonceOffset int
+	if cfg.ExtraNonceSize > 0 {
+		script = append(script, byte(cfg.ExtraNonceSize))
+		extraNonceOffset = len(script)
+		script = append(script, make([]byte, cfg.ExtraNonceSize)...)
+	}
+	if tag := cfg.Tag; len(tag) > 0 {
+		if room := MaxCoinbaseScriptLen - len(script) - 1; len(tag) > room {
+			tag = tag[:room]
+		}
+		if len(tag) > 0 {
+			script = append(script, byte(len(tag)))
+			script = append(script, tag...)
+		}
+	}
+	// The script of the genesis height is a single OP_0, too short to
+	// be valid on its own.
+	for len(script) < MinCoinbaseScriptLen {
+		script = append(script, scriptclass.OP_0)
+	}
+
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: wire.OutPoint{Index: math.MaxUint32},
+		Sequence:         wire.MaxTxInSequenceNum,
+		SignatureIndex:   math.MaxUint32,
+	})
+	tx.AddTxOut(output(0, append([]byte{scriptclass.OP_RETURN}, script...)))
+	for i, p := range cfg.Payouts {
+		if p.Weight == 0 {
+			continue
+		}
+		pkScript, err := scriptclass.PayToAddrScript(p.Address)
+		if err != nil {
+			return nil, err
+		}
+		tx.AddTxOut(output(values[i], pkScript))
+	}
+	if cfg.WitnessCommitment != nil {
+		tx.AddTxOut(output(0,
+			merkle.WitnessCommitmentScript(cfg.WitnessCommitment)))
+	}
+
+	return &Coinbase{
+		Tx:               tx,
+		extraNonceOffset: extraNonceOffset,
+		extraNonceSize:   cfg.ExtraNonceSize,
+	}, nil
+}
+
+// splitValue returns the value paid to each payout.
+func splitValue(value btcutil.Amount, payouts []Payout) ([]btcutil.Amount, error) {
+	if value < 0 {
+		return nil, ErrInvalidValue
+	}
+	var total uint64
+	for _, p := range payouts {
+		if total+p.Weight < total {
+			return nil, ErrNoPayouts
+		}
+		total += p.Weight
+	}
+	if total == 0 {
+		return nil, ErrNoPayouts
+	}
+
+	values := make([]btcutil.Amount, len(payouts))
+	var paid btcutil.Amount
+	first := -1
+	for i, p := range payouts {
+		if p.Weight == 0 {
+			continue
+		}
+		if first < 0 {
+			first = i
+		}
+		values[i] = btcutil.Amount(mulDiv(uint64(value), p.Weight, total))
+		paid += values[i]
+	}
+	values[first] += value - paid
+	return values, nil
+}
+
+// mulDiv returns a*b/c rounded down, computing the product on 128 bits so
+// it can't overflow.  b must not be larger than c.
+func mulDiv(a, b, c uint64) uint64 {
+	hi, lo := bits.Mul64(a, b)
+	q, _ := bits.Div64(hi, lo, c)
+	return q
+}
+
+// output returns an output paying value of OMC to pkScript.
+func output(value btcutil.Amount, pkScript []byte) *wire.TxOut {
+	return &wire.TxOut{
+		Token: token.Token{
+			TokenType: 0,
+			Value:     &token.NumeralVal{Val: int64(value)},
+		},
+		PkScript: pkScript,
+	}
+}
+
+// ExtraNonceSize returns the size of the extra nonce of the coinbase.
+func (c *Coinbase) ExtraNonceSize() int {
+	return c.extraNonceSize
+}
+
+// SetExtraNonce overwrites the extra nonce of the coinbase, which changes the
+// hash of the transaction.  ErrExtraNonceSize is returned when nonce is not of
+// the size reserved by the coinbase.
+func (c *Coinbase) SetExtraNonce(nonce []byte) error {
+	if len(nonce) != c.extraNonceSize {
+		return ErrExtraNonceSize
+	}
+	copy(c.Script()[c.extraNonceOffset:], nonce)
+	return nil
+}
+
+// SetExtraNonceUint64 sets the extra nonce to the little endian encoding of
+// n, truncated or zero padded to the size of the extra nonce.
+func (c *Coinbase) SetExtraNonceUint64(n uint64) {
+	nonce := c.Script()[c.extraNonceOffset:][:c.extraNonceSize]
+	for i := range nonce {
+		nonce[i] = byte(n)
+		n >>= 8
+	}
+}
+
+// HeightScript returns the minimal push of height which starts the coinbase
+// script of a block at that height, as BIP0034 mandates: small heights are
+// pushed with their opcode, and others as little endian numbers with a sign
+// bit.
+func HeightScript(height int32) []byte {
+	switch {
+	case height == 0:
+		return []byte{scriptclass.OP_0}
+	case height <= 16:
+		return []byte{scriptclass.OP_1 - 1 + byte(height)}
+	}
+
+	var num []byte
+	for h := uint32(height); h > 0; h >>= 8 {
+		num = append(num, byte(h))
+	}
+	// A set high bit would make the number negative.
+	if num[len(num)-1]&0x80 != 0 {
+		num = append(num, 0x00)
+	}
+	return append([]byte{byte(len(num))}, num...)
+}
+
+// ExtractHeight returns the height committed to by a coinbase script.
+// ErrNoHeight is returned when the script does not start with a minimal push
+// of a non-negative height.
+func ExtractHeight(script []byte) (int32, error) {
+	if len(script) == 0 {
+		return 0, ErrNoHeight
+	}
+	switch op := script[0]; {
+	case op == scriptclass.OP_0:
+		return 0, nil
+	case op >= scriptclass.OP_1 && op <= scriptclass.OP_16:
+		return int32(op - (scriptclass.OP_1 - 1)), nil
+	case op < 1 || op > 4 || len(script) < 1+int(op):
+		return 0, ErrNoHeight
+	}
+
+	num := script[1 : 1+script[0]]
+	if num[len(num)-1]&0x80 != 0 {
+		return 0, ErrNoHeight
+	}
+	var height uint32
+	for i := len(num) - 1; i >= 0; i-- {
+		height = height<<8 | uint32(num[i])
+	}
+	if height > math.MaxInt32 || !bytes.Equal(HeightScript(int32(height)),
+		script[:1+len(num)]) {
+		return 0, ErrNoHeight
+	}
+	return int32(height), nil
+}
@@ -0,0 +1,162 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package mining_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/merkle"
+	"github.com/zeusyf/btcutil/mining"
+	"github.com/zeusyf/omega/token"
+)
+
+// TestHeightScript ensures heights are pushed minimally and extracted back.
+func TestHeightScript(t *testing.T) {
+	tests := []struct {
+		height int32
+		script string
+	}{
+		{0, "00"},
+		{1, "51"},
+		{16, "60"},
+		{17, "0111"},
+		{127, "017f"},
+		{128, "028000"},
+		{255, "02ff00"},
+		{256, "020001"},
+		{227836, "03fc7903"},
+		{8388608, "0400008000"},
+		{2147483647, "04ffffff7f"},
+	}
+	for _, test := range tests {
+		script := mining.HeightScript(test.height)
+		if hex.EncodeToString(script) != test.script {
+			t.Errorf("HeightScript(%d): got %x, want %s", test.height,
+				script, test.script)
+		}
+		height, err := mining.ExtractHeight(append(script, 0xab))
+		if err != nil || height != test.height {
+			t.Errorf("ExtractHeight(%x): got %d, %v", script, height, err)
+		}
+	}
+
+	// Non-minimal and negative pushes are rejected.
+	for _, s := range []string{"", "0101", "021100", "0180", "05ffffffff00", "02ff"} {
+		script, _ := hex.DecodeString(s)
+		if _, err := mining.ExtractHeight(script); err != mining.ErrNoHeight {
+			t.Errorf("ExtractHeight(%s): got error %v, want %v", s, err,
+				mining.ErrNoHeight)
+		}
+	}
+}
+
+// TestNewCoinbase ensures coinbases split their value between payouts and
+// carry their height, extra nonce and witness commitment.
+func TestNewCoinbase(t *testing.T) {
+	net := &chaincfg.MainNetParams
+	addr := func(b byte) btcutil.Address {
+		a, err := btcutil.NewAddressPubKeyHash(bytes.Repeat([]byte{b}, 20), net)
+		if err != nil {
+			t.Fatalf("NewAddressPubKeyHash: %v", err)
+		}
+		return a
+	}
+	commitment := chainhash.Hash{0x01}
+	cfg := &mining.CoinbaseConfig{
+		Height: 500000,
+		Value:  1000000001,
+		Payouts: []mining.Payout{
+			{Address: addr(1), Weight: 1},
+			{Address: addr(2), Weight: 0},
+			{Address: addr(3), Weight: 2},
+		},
+		ExtraNonceSize:    8,
+		Tag:               bytes.Repeat([]byte{'p'}, 200),
+		WitnessCommitment: &commitment,
+	}
+	cb, err := mining.NewCoinbase(cfg)
+	if err != nil {
+		t.Fatalf("NewCoinbase: %v", err)
+	}
+
+	tx := cb.Tx
+	if len(tx.TxIn) != 1 || len(tx.TxOut) != 4 {
+		t.Fatalf("NewCoinbase: got %d inputs and %d outputs", len(tx.TxIn),
+			len(tx.TxOut))
+	}
+	script, err := mining.CoinbaseScript(tx)
+	if err != nil || len(script) != mining.MaxCoinbaseScriptLen {
+		t.Fatalf("CoinbaseScript: got %x, %v", script, err)
+	}
+	if height, _ := mining.ExtractHeight(script); height != cfg.Height {
+		t.Errorf("ExtractHeight: got %d, want %d", height, cfg.Height)
+	}
+
+	var total int64
+	want := []int64{0, 333333334, 666666667, 0}
+	for i, out := range tx.TxOut {
+		value := out.Token.Value.(*token.NumeralVal).Val
+		if value != want[i] {
+			t.Errorf("output %d: got value %d, want %d", i, value, want[i])
+		}
+		total += value
+	}
+	if total != int64(cfg.Value) {
+		t.Errorf("NewCoinbase: paid %d, want %d", total, cfg.Value)
+	}
+	got, ok := merkle.ExtractWitnessCommitment(btcutil.NewTx(tx))
+	if !ok || got != commitment {
+		t.Errorf("ExtractWitnessCommitment: got %v, %v", got, ok)
+	}
+
+	// The extra nonce changes the hash of the coinbase.
+	hash := tx.TxHash()
+	if err := cb.SetExtraNonce(make([]byte, 4)); err != mining.ErrExtraNonceSize {
+		t.Errorf("SetExtraNonce: got error %v, want %v", err,
+			mining.ErrExtraNonceSize)
+	}
+	cb.SetExtraNonceUint64(0x0102)
+	if !bytes.Contains(cb.Script(), []byte{0x08, 0x02, 0x01, 0, 0, 0, 0, 0, 0}) {
+		t.Errorf("SetExtraNonceUint64: got script %x", cb.Script())
+	}
+	if tx.TxHash() == hash {
+		t.Errorf("SetExtraNonceUint64: hash unchanged")
+	}
+	if height, _ := mining.ExtractHeight(cb.Script()); height != cfg.Height {
+		t.Errorf("ExtractHeight: got %d after extra nonce", height)
+	}
+
+	// The genesis height is padded to the minimum script length.
+	cb, err = mining.NewCoinbase(&mining.CoinbaseConfig{
+		Value:   50,
+		Payouts: []mining.Payout{{Address: addr(1), Weight: 1}},
+	})
+	if err != nil || !bytes.Equal(cb.Script(), []byte{0x00, 0x00}) {
+		t.Errorf("NewCoinbase: got script %x, %v", cb.Script(), err)
+	}
+
+	bad := []struct {
+		cfg mining.CoinbaseConfig
+		err error
+	}{
+		{mining.CoinbaseConfig{Value: 50}, mining.ErrNoPayouts},
+		{mining.CoinbaseConfig{Value: -1, Payouts: cfg.Payouts},
+			mining.ErrInvalidValue},
+		{mining.CoinbaseConfig{Height: -1, Payouts: cfg.Payouts},
+			mining.ErrInvalidHeight},
+		{mining.CoinbaseConfig{ExtraNonceSize: 76, Payouts: cfg.Payouts},
+			mining.ErrExtraNonceSize},
+	}
+	for i, test := range bad {
+		if _, err := mining.NewCoinbase(&test.cfg); err != test.err {
+			t.Errorf("%d: got error %v, want %v", i, err, test.err)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package mining
+
+import (
+	"encoding/hex"
+	"errors"
+
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/merkle"
+)
+
+// ErrInvalidBranch describes an error where a merkle branch received from a
+// pool is not a list of hex encoded hashes.
+var ErrInvalidBranch = errors.New("invalid merkle branch")
+
+// CoinbaseBranch returns the merkle branch of the coinbase of a block whose
+// other transactions have the passed hashes, in block order.  It is what a
+// pool sends to its miners along with the coinbase: since the branch of the
+// first leaf never depends on the leaf itself, miners compute the merkle root
+// for any extra nonce with CoinbaseMerkleRoot, without knowing the
+// transactions of the block.
+func CoinbaseBranch(txHashes []chainhash.Hash) []chainhash.Hash {
+	leaves := make([]chainhash.Hash, len(txHashes)+1)
+	copy(leaves[1:], txHashes)
+	branch, _, _ := merkle.Branch(leaves, 0)
+	return branch
+}
+
+// BlockCoinbaseBranch returns the merkle branch of the coinbase of block.  See
+// CoinbaseBranch.
+func BlockCoinbaseBranch(block *btcutil.Block) []chainhash.Hash {
+	hashes := block.TxHashes()
+	if len(hashes) == 0 {
+		return nil
+	}
+	return CoinbaseBranch(hashes[1:])
+}
+
+// CoinbaseMerkleRoot returns the merkle root of a block from the hash of its
+// coinbase and the branch returned by CoinbaseBranch.
+func CoinbaseMerkleRoot(coinbaseHash *chainhash.Hash, branch []chainhash.Hash) chainhash.Hash {
+	return merkle.BranchRoot(*coinbaseHash, 0, branch)
+}
+
+// FormatStratumBranch returns the hashes of branch as the hex strings of the
+// merkle_branch of a stratum mining.notify message.  Unlike the String method
+// of chainhash.Hash, the bytes of each hash are encoded in their internal
+// order, as stratum mandates.
+func FormatStratumBranch(branch []chainhash.Hash) []string {
+	s := make([]string, len(branch))
+	for i := range branch {
+		s[i] = hex.EncodeToString(branch[i][:])
+	}
+	return s
+}
+
+// ParseStratumBranch parses the hex strings of the merkle_branch of a stratum
+// mining.notify message.  ErrInvalidBranch is returned when a string is not a
+// hex encoded hash.
+func ParseStratumBranch(s []string) ([]chainhash.Hash, error) {
+	branch := make([]chainhash.Hash, len(s))
+	for i, h := range s {
+		if hex.DecodedLen(len(h)) != chainhash.HashSize {
+			return nil, ErrInvalidBranch
+		}
+		if _, err := hex.Decode(branch[i][:], []byte(h)); err != nil {
+			return nil, ErrInvalidBranch
+		}
+	}
+	return branch, nil
+}
@@ -0,0 +1,56 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package mining_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcutil/merkle"
+	"github.com/zeusyf/btcutil/mining"
+)
+
+// TestCoinbaseBranch ensures the merkle root reconstructed from a coinbase
+// hash and its branch is the merkle root of the block for any number of
+// transactions.
+func TestCoinbaseBranch(t *testing.T) {
+	for n := 0; n <= 9; n++ {
+		leaves := make([]chainhash.Hash, n+1)
+		for i := range leaves {
+			leaves[i] = chainhash.DoubleHashH([]byte{byte(n), byte(i)})
+		}
+		branch := mining.CoinbaseBranch(leaves[1:])
+		root := mining.CoinbaseMerkleRoot(&leaves[0], branch)
+		if want := merkle.Root(leaves); root != want {
+			t.Errorf("%d transactions: got root %v, want %v", n, root, want)
+		}
+
+		// The branch does not depend on the coinbase.
+		leaves[0] = chainhash.Hash{0xff}
+		root = mining.CoinbaseMerkleRoot(&leaves[0], branch)
+		if want := merkle.Root(leaves); root != want {
+			t.Errorf("%d transactions: root mismatch after coinbase change", n)
+		}
+
+		parsed, err := mining.ParseStratumBranch(mining.FormatStratumBranch(branch))
+		if err != nil || len(parsed) != len(branch) ||
+			(len(branch) > 0 && !reflect.DeepEqual(parsed, branch)) {
+			t.Errorf("%d transactions: ParseStratumBranch: got %v, %v", n,
+				parsed, err)
+		}
+	}
+
+	s := mining.FormatStratumBranch([]chainhash.Hash{{0x01}})
+	if s[0][:4] != "0100" {
+		t.Errorf("FormatStratumBranch: got %s, want internal byte order", s[0])
+	}
+	for _, bad := range [][]string{{"00"}, {s[0][:62] + "zz"}} {
+		if _, err := mining.ParseStratumBranch(bad); err != mining.ErrInvalidBranch {
+			t.Errorf("ParseStratumBranch(%v): got error %v, want %v", bad,
+				err, mining.ErrInvalidBranch)
+		}
+	}
+}
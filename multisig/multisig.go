@@ -0,0 +1,162 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package multisig builds m-of-n multi-signature redeem scripts and the
+// pay-to-script-hash outputs which commit to them.
+//
+// Public keys are always serialized compressed and sorted as described by
+// BIP0067, so every cosigner arrives at the same script, and therefore the
+// same address, regardless of the order the keys were exchanged in.
+//
+// A Session coordinates the cosigners spending from such a script: it merges
+// the partially signed transactions each of them returns, rejecting
+// signatures by strangers or of conflicting hash types, and reports who has
+// signed and whether enough of them have.
+package multisig
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"sort"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/scriptclass"
+)
+
+const (
+	// MaxP2SHPubKeys is the maximum number of compressed public keys in a
+	// redeem script paid to by a pay-to-script-hash output.  Larger
+	// scripts exceed scriptclass.MaxScriptElementSize and can not be
+	// pushed by the signature script.
+	MaxP2SHPubKeys = 15
+
+	// MaxP2WSHPubKeys is the maximum number of public keys in a witness
+	// script paid to by a pay-to-witness-script-hash output.
+	MaxP2WSHPubKeys = scriptclass.MaxPubKeysPerMultiSig
+)
+
+var (
+	// ErrNoPubKeys describes an error where a multi-signature script is
+	// requested without any public keys.
+	ErrNoPubKeys = errors.New("no public keys")
+
+	// ErrTooManyPubKeys describes an error where a multi-signature script
+	// has more public keys than the output type allows.
+	ErrTooManyPubKeys = errors.New("too many public keys")
+
+	// ErrInvalidRequiredSigs describes an error where the number of
+	// required signatures is not between one and the number of keys.
+	ErrInvalidRequiredSigs = errors.New("required signatures must be " +
+		"between 1 and the number of public keys")
+
+	// ErrDuplicatePubKey describes an error where the same public key is
+	// passed more than once.  A duplicate key lets a single signer
+	// provide several of the required signatures.
+	ErrDuplicatePubKey = errors.New("duplicate public key")
+)
+
+// SortPubKeys sorts serialized public keys lexicographically in place, as
+// described by BIP0067.
+func SortPubKeys(pubKeys [][]byte) {
+	sort.Slice(pubKeys, func(i, j int) bool {
+		return bytes.Compare(pubKeys[i], pubKeys[j]) < 0
+	})
+}
+
+// pushSmallInt appends the shortest push of n, which must be between 0 and
+// 75, to script.
+func pushSmallInt(script []byte, n int) []byte {
+	switch {
+	case n == 0:
+		return append(script, scriptclass.OP_0)
+	case n <= 16:
+		return append(script, byte(scriptclass.OP_1-1+n))
+	}
+	return append(script, scriptclass.OP_DATA_1, byte(n))
+}
+
+// script returns the sorted m-of-n multi-signature script for the passed
+// keys after checking them against maxKeys.
+func script(m int, pubKeys []*btcec.PublicKey, maxKeys int) ([]byte, error) {
+	n := len(pubKeys)
+	switch {
+	case n == 0:
+		return nil, ErrNoPubKeys
+	case n > maxKeys:
+		return nil, ErrTooManyPubKeys
+	case m < 1 || m > n:
+		return nil, ErrInvalidRequiredSigs
+	}
+
+	keys := make([][]byte, n)
+	for i, key := range pubKeys {
+		keys[i] = key.SerializeCompressed()
+	}
+	SortPubKeys(keys)
+
+	// OP_M <OP_DATA_33 <pubkey>>... OP_N OP_CHECKMULTISIG
+	script := make([]byte, 0, 3+n*(1+btcec.PubKeyBytesLenCompressed)+2)
+	script = pushSmallInt(script, m)
+	for i, key := range keys {
+		if i > 0 && bytes.Equal(key, keys[i-1]) {
+			return nil, ErrDuplicatePubKey
+		}
+		script = append(script, byte(len(key)))
+		script = append(script, key...)
+	}
+	script = pushSmallInt(script, n)
+	return append(script, scriptclass.OP_CHECKMULTISIG), nil
+}
+
+// RedeemScript returns the m-of-n multi-signature redeem script for the
+// passed public keys, which may be given in any order.  At most
+// MaxP2SHPubKeys keys are allowed so the script can be paid to with a
+// pay-to-script-hash output.
+func RedeemScript(m int, pubKeys []*btcec.PublicKey) ([]byte, error) {
+	return script(m, pubKeys, MaxP2SHPubKeys)
+}
+
+// AddressScriptHash returns the pay-to-script-hash address of the m-of-n
+// multi-signature redeem script for the passed public keys along with the
+// redeem script, which is needed to spend from the address.
+func AddressScriptHash(m int, pubKeys []*btcec.PublicKey, net *chaincfg.Params) (*btcutil.AddressScriptHash, []byte, error) {
+	redeemScript, err := RedeemScript(m, pubKeys)
+	if err != nil {
+		return nil, nil, err
+	}
+	addr, err := btcutil.NewAddressScriptHash(redeemScript, net)
+	if err != nil {
+		return nil, nil, err
+	}
+	return addr, redeemScript, nil
+}
+
+// WitnessScript returns the m-of-n multi-signature witness script for the
+// passed public keys, which may be given in any order.  At most
+// MaxP2WSHPubKeys keys are allowed.
+func WitnessScript(m int, pubKeys []*btcec.PublicKey) ([]byte, error) {
+	return script(m, pubKeys, MaxP2WSHPubKeys)
+}
+
+// WitnessScriptHash returns the pay-to-witness-script-hash public key script
+// of the m-of-n multi-signature witness script for the passed public keys
+// along with the witness script, which is needed to spend the output.  The
+// witness program, the SHA256 of the witness script, is the last 32 bytes of
+// the public key script.
+func WitnessScriptHash(m int, pubKeys []*btcec.PublicKey) ([]byte, []byte, error) {
+	witnessScript, err := WitnessScript(m, pubKeys)
+	if err != nil {
+		return nil, nil, err
+	}
+	program := sha256.Sum256(witnessScript)
+
+	// OP_0 OP_DATA_32 <32 byte program>
+	pkScript := make([]byte, 0, 2+len(program))
+	pkScript = append(pkScript, scriptclass.OP_0, scriptclass.OP_DATA_32)
+	pkScript = append(pkScript, program[:]...)
+	return pkScript, witnessScript, nil
+}
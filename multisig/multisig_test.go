@@ -0,0 +1,143 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package multisig_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/multisig"
+)
+
+// testPubKeys returns n distinct public keys derived from the private keys
+// 1 through n.
+func testPubKeys(n int) []*btcec.PublicKey {
+	keys := make([]*btcec.PublicKey, n)
+	for i := range keys {
+		_, pub := btcec.PrivKeyFromBytes(btcec.S256(), []byte{byte(i + 1)})
+		keys[i] = pub
+	}
+	return keys
+}
+
+// TestRedeemScript ensures redeem scripts are built from sorted keys and
+// independent of the order the keys are passed in.
+func TestRedeemScript(t *testing.T) {
+	keys := testPubKeys(3)
+	want, err := multisig.RedeemScript(2, keys)
+	if err != nil {
+		t.Fatalf("RedeemScript: unexpected error: %v", err)
+	}
+
+	reversed := []*btcec.PublicKey{keys[2], keys[1], keys[0]}
+	got, err := multisig.RedeemScript(2, reversed)
+	if err != nil {
+		t.Fatalf("RedeemScript: unexpected error: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("RedeemScript: key order changed the script")
+	}
+
+	if len(want) != 3+3*34 || want[0] != 0x52 || want[len(want)-2] != 0x53 ||
+		want[len(want)-1] != 0xae {
+		t.Fatalf("RedeemScript: unexpected script %x", want)
+	}
+	for i := 1; i < 3; i++ {
+		prev := want[2+(i-1)*34 : 2+i*34-1]
+		cur := want[2+i*34 : 2+(i+1)*34-1]
+		if bytes.Compare(prev, cur) >= 0 {
+			t.Fatalf("RedeemScript: keys %d and %d are not sorted",
+				i-1, i)
+		}
+	}
+
+	// The well known 1-of-1 script for the generator point.
+	script, err := multisig.RedeemScript(1, keys[:1])
+	if err != nil {
+		t.Fatalf("RedeemScript: unexpected error: %v", err)
+	}
+	wantHex := "51210279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f28" +
+		"15b16f8179851ae"
+	if hex.EncodeToString(script) != wantHex {
+		t.Fatalf("RedeemScript: got %x, want %s", script, wantHex)
+	}
+}
+
+// TestRedeemScriptErrors ensures invalid key counts, signature counts and
+// duplicate keys are rejected.
+func TestRedeemScriptErrors(t *testing.T) {
+	keys := testPubKeys(16)
+
+	tests := []struct {
+		name string
+		m    int
+		keys []*btcec.PublicKey
+		err  error
+	}{
+		{"no keys", 1, nil, multisig.ErrNoPubKeys},
+		{"too many keys", 1, keys, multisig.ErrTooManyPubKeys},
+		{"zero required", 0, keys[:2], multisig.ErrInvalidRequiredSigs},
+		{"more required than keys", 3, keys[:2], multisig.ErrInvalidRequiredSigs},
+		{"duplicate key", 1, []*btcec.PublicKey{keys[0], keys[1], keys[0]},
+			multisig.ErrDuplicatePubKey},
+	}
+	for _, test := range tests {
+		_, err := multisig.RedeemScript(test.m, test.keys)
+		if err != test.err {
+			t.Errorf("%s: got error %v, want %v", test.name, err, test.err)
+		}
+	}
+
+	// Witness scripts allow more keys, with counts above 16 pushed as
+	// data.
+	script, err := multisig.WitnessScript(1, append(keys, testPubKeys(20)[16:]...))
+	if err != nil {
+		t.Fatalf("WitnessScript: unexpected error: %v", err)
+	}
+	if n := script[len(script)-3 : len(script)-1]; !bytes.Equal(n, []byte{0x01, 20}) {
+		t.Fatalf("WitnessScript: got key count push %x, want 0114", n)
+	}
+}
+
+// TestAddresses ensures the derived addresses and public key scripts commit
+// to the returned scripts.
+func TestAddresses(t *testing.T) {
+	keys := testPubKeys(3)
+
+	addr, redeemScript, err := multisig.AddressScriptHash(2, keys,
+		&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("AddressScriptHash: unexpected error: %v", err)
+	}
+	want, err := btcutil.NewAddressScriptHash(redeemScript,
+		&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("NewAddressScriptHash: unexpected error: %v", err)
+	}
+	if addr.EncodeAddress() != want.EncodeAddress() {
+		t.Fatalf("AddressScriptHash: got %s, want %s",
+			addr.EncodeAddress(), want.EncodeAddress())
+	}
+
+	pkScript, witnessScript, err := multisig.WitnessScriptHash(2, keys)
+	if err != nil {
+		t.Fatalf("WitnessScriptHash: unexpected error: %v", err)
+	}
+	if !bytes.Equal(witnessScript, redeemScript) {
+		t.Fatalf("WitnessScriptHash: witness script differs from " +
+			"redeem script")
+	}
+	program := sha256.Sum256(witnessScript)
+	wantScript := append([]byte{0x00, 0x20}, program[:]...)
+	if !bytes.Equal(pkScript, wantScript) {
+		t.Fatalf("WitnessScriptHash: got %x, want %x", pkScript,
+			wantScript)
+	}
+}
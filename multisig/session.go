@@ -0,0 +1,342 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package multisig
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/psbt"
+	"github.com/zeusyf/btcutil/scriptclass"
+	"github.com/zeusyf/btcutil/signing"
+)
+
+var (
+	// ErrNoSessionInputs describes an error where a session is started
+	// for a packet without any input spending its multi-signature script.
+	ErrNoSessionInputs = errors.New("packet spends no output of the " +
+		"multi-signature script")
+
+	// ErrUnknownCosigner describes an error where a packet holds a
+	// signature of a session input by a key which is not one of the
+	// cosigners.
+	ErrUnknownCosigner = errors.New("signature by unknown cosigner")
+
+	// ErrSigHashConflict describes an error where the signatures or the
+	// signature hash types of a session input do not agree on the hash
+	// type the input is signed with.
+	ErrSigHashConflict = errors.New("conflicting signature hash types")
+
+	// ErrInvalidSignature describes an error where a signature is too
+	// short to hold its hash type.
+	ErrInvalidSignature = errors.New("invalid partial signature")
+)
+
+// InputError describes an error found in an input of a packet merged into a
+// session.
+type InputError struct {
+	// Index is the index of the input.
+	Index int
+
+	// Err is the reason the input was rejected.
+	Err error
+}
+
+// Error returns the error along with the index of the input.
+func (e *InputError) Error() string {
+	return fmt.Sprintf("input %d: %v", e.Index, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *InputError) Unwrap() error {
+	return e.Err
+}
+
+// SessionState is the state of a signing session.
+type SessionState uint8
+
+const (
+	// SessionCollecting is the state of a session with inputs lacking
+	// signatures.
+	SessionCollecting SessionState = iota
+
+	// SessionReady is the state of a session whose inputs all hold the
+	// required number of signatures, or are finalized.
+	SessionReady
+
+	// SessionFinalized is the state of a session whose inputs are all
+	// finalized.
+	SessionFinalized
+)
+
+// sessionStateStrings is a map of session states back to their constant
+// names for pretty printing.
+var sessionStateStrings = map[SessionState]string{
+	SessionCollecting: "SessionCollecting",
+	SessionReady:      "SessionReady",
+	SessionFinalized:  "SessionFinalized",
+}
+
+// String returns the SessionState as a human-readable name.
+func (s SessionState) String() string {
+	if str, ok := sessionStateStrings[s]; ok {
+		return str
+	}
+	return "Unknown SessionState"
+}
+
+// Session coordinates the cosigners of an m-of-n multi-signature script
+// signing a packet which spends outputs paying to the script.  The copies of
+// the packet signed by each cosigner are merged into the session, which
+// tracks who has signed and when enough of them have.  Inputs spending other
+// outputs are merged along, but not tracked.  The zero value is not usable;
+// a Session must be created with NewSession.
+type Session struct {
+	packet       *psbt.Packet
+	redeemScript []byte
+	required     int
+	cosigners    [][]byte
+	inputs       []int
+}
+
+// NewSession returns a session of the m-of-n multi-signature script for the
+// passed public keys, which may be given in any order, signing p.  The
+// session takes ownership of p: inputs with the redeem script, or spending a
+// known output paying to its script hash, are tracked, and the latter are
+// given the redeem script.  The signatures p already holds are checked as
+// those of merged packets are.
+func NewSession(p *psbt.Packet, m int, pubKeys []*btcec.PublicKey) (*Session, error) {
+	redeemScript, err := RedeemScript(m, pubKeys)
+	if err != nil {
+		return nil, err
+	}
+	if err := p.SanityCheck(); err != nil {
+		return nil, err
+	}
+
+	// OP_HASH160 OP_DATA_20 <script hash> OP_EQUAL
+	pkScript := []byte{scriptclass.OP_HASH160, scriptclass.OP_DATA_20}
+	pkScript = append(pkScript, btcutil.Hash160(redeemScript)...)
+	pkScript = append(pkScript, scriptclass.OP_EQUAL)
+
+	s := &Session{
+		packet:       p,
+		redeemScript: redeemScript,
+		required:     m,
+	}
+	for _, key := range pubKeys {
+		s.cosigners = append(s.cosigners, key.SerializeCompressed())
+	}
+	SortPubKeys(s.cosigners)
+
+	for i := range p.Inputs {
+		in := &p.Inputs[i]
+		prevOut := p.PrevOutput(i)
+		switch {
+		case bytes.Equal(in.RedeemScript, redeemScript):
+		case in.RedeemScript == nil && prevOut != nil &&
+			bytes.Equal(prevOut.PkScript, pkScript):
+			in.RedeemScript = redeemScript
+		default:
+			continue
+		}
+		s.inputs = append(s.inputs, i)
+	}
+	if len(s.inputs) == 0 {
+		return nil, ErrNoSessionInputs
+	}
+	if err := s.check(p); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Packet returns the packet of the session, holding the signatures merged so
+// far.
+func (s *Session) Packet() *psbt.Packet {
+	return s.packet
+}
+
+// RedeemScript returns the multi-signature redeem script of the session.
+func (s *Session) RedeemScript() []byte {
+	return s.redeemScript
+}
+
+// Inputs returns the indexes of the inputs tracked by the session.
+func (s *Session) Inputs() []int {
+	return s.inputs
+}
+
+// check verifies the session inputs of other, a packet of the transaction of
+// the session, hold signatures by cosigners only, whose hash types agree with
+// the session and with each other.
+func (s *Session) check(other *psbt.Packet) error {
+	for _, i := range s.inputs {
+		in, o := &s.packet.Inputs[i], &other.Inputs[i]
+
+		// The hash type of unsigned inputs left to the signer is
+		// SigHashAll, as the signers of the psbt package choose.
+		want := in.SighashType
+		if want == 0 {
+			want = o.SighashType
+		} else if o.SighashType != 0 && o.SighashType != want {
+			return &InputError{Index: i, Err: ErrSigHashConflict}
+		}
+		if want == 0 {
+			want = uint32(signing.SigHashAll)
+		}
+
+		for _, sigs := range [][]*psbt.PartialSig{in.PartialSigs, o.PartialSigs} {
+			for _, sig := range sigs {
+				if !containsKey(s.cosigners, sig.PubKey) {
+					return &InputError{Index: i, Err: ErrUnknownCosigner}
+				}
+				n := len(sig.Signature)
+				if n == 0 {
+					return &InputError{Index: i, Err: ErrInvalidSignature}
+				}
+				if uint32(sig.Signature[n-1]) != want {
+					return &InputError{Index: i, Err: ErrSigHashConflict}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Merge merges the signatures and data of other, a copy of the packet of the
+// session, into the session, and returns the cosigners it added signatures
+// of, sorted.  Merging is idempotent: merging a packet again, or one whose
+// signatures the session already holds, adds nothing.  Signatures by keys
+// which already signed an input are not replaced.  The session is left
+// unchanged when other is a packet of another transaction, or holds
+// signatures by keys which are not cosigners, or whose hash types conflict
+// with those of the session; the latter are returned as *InputError.
+func (s *Session) Merge(other *psbt.Packet) ([][]byte, error) {
+	if err := other.SanityCheck(); err != nil {
+		return nil, err
+	}
+	if other.UnsignedTx.TxHash() != s.packet.UnsignedTx.TxHash() ||
+		len(other.Inputs) != len(s.packet.Inputs) {
+		return nil, psbt.ErrInputMismatch
+	}
+	if err := s.check(other); err != nil {
+		return nil, err
+	}
+
+	var added [][]byte
+	for _, i := range s.inputs {
+		in := &s.packet.Inputs[i]
+		for _, sig := range other.Inputs[i].PartialSigs {
+			if !hasPartialSig(in.PartialSigs, sig.PubKey) &&
+				!containsKey(added, sig.PubKey) {
+				added = append(added, sig.PubKey)
+			}
+		}
+	}
+	if err := s.packet.Combine(other); err != nil {
+		return nil, err
+	}
+	SortPubKeys(added)
+	return added, nil
+}
+
+// State returns the current state of the session.
+func (s *Session) State() SessionState {
+	state := SessionFinalized
+	for _, i := range s.inputs {
+		in := &s.packet.Inputs[i]
+		switch {
+		case in.FinalScriptSig != nil:
+		case s.signatures(in) >= s.required:
+			state = SessionReady
+		default:
+			return SessionCollecting
+		}
+	}
+	return state
+}
+
+// IsReady returns whether every input of the session holds the required
+// number of signatures, or is finalized, so the packet can be finalized.
+func (s *Session) IsReady() bool {
+	return s.State() != SessionCollecting
+}
+
+// Signatures returns the number of signatures by cosigners of the i-th input
+// of the packet.
+func (s *Session) Signatures(i int) int {
+	return s.signatures(&s.packet.Inputs[i])
+}
+
+// signatures returns the number of signatures of in by cosigners.
+func (s *Session) signatures(in *psbt.Input) int {
+	n := 0
+	for _, key := range s.cosigners {
+		if hasPartialSig(in.PartialSigs, key) {
+			n++
+		}
+	}
+	return n
+}
+
+// Signed returns the cosigners which signed every input of the session which
+// is not finalized, sorted.
+func (s *Session) Signed() [][]byte {
+	signed, _ := s.contributions()
+	return signed
+}
+
+// Pending returns the cosigners which have yet to sign an input of the
+// session which is not finalized, sorted.  Only as many of them as are
+// missing from the required number need to sign.
+func (s *Session) Pending() [][]byte {
+	_, pending := s.contributions()
+	return pending
+}
+
+// contributions splits the cosigners into those which signed every input of
+// the session which is not finalized, and the others.
+func (s *Session) contributions() (signed, pending [][]byte) {
+	for _, key := range s.cosigners {
+		done := true
+		for _, i := range s.inputs {
+			in := &s.packet.Inputs[i]
+			if in.FinalScriptSig == nil && !hasPartialSig(in.PartialSigs, key) {
+				done = false
+				break
+			}
+		}
+		if done {
+			signed = append(signed, key)
+		} else {
+			pending = append(pending, key)
+		}
+	}
+	return signed, pending
+}
+
+// containsKey returns whether key is one of keys.
+func containsKey(keys [][]byte, key []byte) bool {
+	for _, k := range keys {
+		if bytes.Equal(k, key) {
+			return true
+		}
+	}
+	return false
+}
+
+// hasPartialSig returns whether sigs has a signature by pubKey.
+func hasPartialSig(sigs []*psbt.PartialSig, pubKey []byte) bool {
+	for _, sig := range sigs {
+		if bytes.Equal(sig.PubKey, pubKey) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,206 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package multisig_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/multisig"
+	"github.com/zeusyf/btcutil/psbt"
+	"github.com/zeusyf/btcutil/signing"
+	"github.com/zeusyf/omega/token"
+)
+
+// sessionPacket returns a packet spending two outputs paying to the 2-of-3
+// script of the keys 1 through 3, and an output paying to another script.
+func sessionPacket(t *testing.T) *psbt.Packet {
+	redeemScript, err := multisig.RedeemScript(2, testPubKeys(3))
+	if err != nil {
+		t.Fatalf("RedeemScript: unexpected error: %v", err)
+	}
+	pkScript := append(append([]byte{0xa9, 0x14},
+		btcutil.Hash160(redeemScript)...), 0x87)
+
+	prev := wire.NewMsgTx(wire.TxVersion)
+	prev.AddTxIn(&wire.TxIn{SignatureIndex: 0xffffffff})
+	for _, script := range [][]byte{pkScript, {0x51}, pkScript} {
+		prev.AddTxOut(&wire.TxOut{
+			Token:    token.Token{Value: &token.NumeralVal{Val: 1000}},
+			PkScript: script,
+		})
+	}
+
+	tx := wire.NewMsgTx(wire.TxVersion)
+	for i := range prev.TxOut {
+		tx.AddTxIn(&wire.TxIn{
+			PreviousOutPoint: wire.OutPoint{Hash: prev.TxHash(), Index: uint32(i)},
+			Sequence:         wire.MaxTxInSequenceNum,
+		})
+	}
+	tx.AddTxOut(&wire.TxOut{
+		Token:    token.Token{Value: &token.NumeralVal{Val: 2900}},
+		PkScript: []byte{0x53},
+	})
+	p, err := psbt.New(tx)
+	if err != nil {
+		t.Fatalf("New: unexpected error: %v", err)
+	}
+	for i := range p.Inputs {
+		p.Inputs[i].NonWitnessUtxo = prev
+	}
+	return p
+}
+
+// signedCopy returns a copy of the packet of s signed by the private key n.
+func signedCopy(t *testing.T, s *multisig.Session, n byte) *psbt.Packet {
+	var buf bytes.Buffer
+	if err := s.Packet().Serialize(&buf); err != nil {
+		t.Fatalf("Serialize: unexpected error: %v", err)
+	}
+	p, err := psbt.Parse(&buf)
+	if err != nil {
+		t.Fatalf("Parse: unexpected error: %v", err)
+	}
+	key, _ := btcec.PrivKeyFromBytes(btcec.S256(), []byte{n})
+	signer := psbt.NewSoftwareSigner()
+	signer.AddKey(key)
+	for _, i := range s.Inputs() {
+		if err := signer.SignInput(p, i); err != nil {
+			t.Fatalf("SignInput: unexpected error: %v", err)
+		}
+	}
+	return p
+}
+
+// TestSession ensures sessions track the signatures merged from each
+// cosigner, idempotently, until enough of them have signed.
+func TestSession(t *testing.T) {
+	keys := testPubKeys(3)
+	s, err := multisig.NewSession(sessionPacket(t), 2, keys)
+	if err != nil {
+		t.Fatalf("NewSession: unexpected error: %v", err)
+	}
+	if inputs := s.Inputs(); len(inputs) != 2 || inputs[0] != 0 ||
+		inputs[1] != 2 {
+
+		t.Fatalf("Inputs: got %v, want [0 2]", inputs)
+	}
+	if !bytes.Equal(s.Packet().Inputs[2].RedeemScript, s.RedeemScript()) {
+		t.Errorf("NewSession: redeem script of input 2 not set")
+	}
+	if s.State() != multisig.SessionCollecting || len(s.Pending()) != 3 {
+		t.Fatalf("got state %v with %d pending", s.State(), len(s.Pending()))
+	}
+
+	first := signedCopy(t, s, 1)
+	for n := 0; n < 2; n++ {
+		added, err := s.Merge(first)
+		if err != nil {
+			t.Fatalf("Merge: unexpected error: %v", err)
+		}
+		want := 1
+		if n > 0 {
+			want = 0
+		}
+		if len(added) != want {
+			t.Fatalf("Merge %d: got %d added cosigners, want %d", n,
+				len(added), want)
+		}
+	}
+	pubKey := keys[0].SerializeCompressed()
+	if signed := s.Signed(); len(signed) != 1 ||
+		!bytes.Equal(signed[0], pubKey) {
+
+		t.Errorf("Signed: got %x, want [%x]", signed, pubKey)
+	}
+	if n := s.Signatures(0); n != 1 || s.IsReady() {
+		t.Errorf("got %d signatures, ready %v", n, s.IsReady())
+	}
+
+	if _, err := s.Merge(signedCopy(t, s, 3)); err != nil {
+		t.Fatalf("Merge: unexpected error: %v", err)
+	}
+	if s.State() != multisig.SessionReady || len(s.Pending()) != 1 ||
+		len(s.Signed()) != 2 {
+
+		t.Errorf("got state %v with %d pending and %d signed", s.State(),
+			len(s.Pending()), len(s.Signed()))
+	}
+
+	for _, i := range s.Inputs() {
+		s.Packet().Inputs[i].FinalScriptSig = []byte{}
+	}
+	if s.State() != multisig.SessionFinalized {
+		t.Errorf("got state %v, want %v", s.State(),
+			multisig.SessionFinalized)
+	}
+}
+
+// TestSessionConflicts ensures packets with conflicting hash types, or
+// signatures by other keys, are rejected without changing the session.
+func TestSessionConflicts(t *testing.T) {
+	keys := testPubKeys(4)
+	if _, err := multisig.NewSession(sessionPacket(t), 2, keys[1:]); err != multisig.ErrNoSessionInputs {
+		t.Errorf("NewSession: got error %v, want %v", err,
+			multisig.ErrNoSessionInputs)
+	}
+	s, err := multisig.NewSession(sessionPacket(t), 2, keys[:3])
+	if err != nil {
+		t.Fatalf("NewSession: unexpected error: %v", err)
+	}
+
+	single := signedCopy(t, s, 1)
+	single.Inputs[2].SighashType = uint32(signing.SigHashSingle)
+	other := signedCopy(t, s, 1)
+	other.Inputs[0].PartialSigs = nil
+	other.Inputs[2].PartialSigs[0].PubKey = keys[3].SerializeCompressed()
+	tests := []struct {
+		name string
+		p    *psbt.Packet
+		err  error
+	}{
+		{"sighash type", single, multisig.ErrSigHashConflict},
+		{"unknown cosigner", other, multisig.ErrUnknownCosigner},
+	}
+	for _, test := range tests {
+		_, err := s.Merge(test.p)
+		var inputErr *multisig.InputError
+		if !errors.Is(err, test.err) || !errors.As(err, &inputErr) ||
+			inputErr.Index != 2 {
+
+			t.Errorf("%s: got error %v, want %v for input 2", test.name,
+				err, test.err)
+		}
+		if s.Signatures(0) != 0 {
+			t.Fatalf("%s: session changed", test.name)
+		}
+	}
+
+	// A signature of another hash type conflicts with those merged.
+	if _, err := s.Merge(signedCopy(t, s, 2)); err != nil {
+		t.Fatalf("Merge: unexpected error: %v", err)
+	}
+	p := signedCopy(t, s, 3)
+	for _, sig := range p.Inputs[0].PartialSigs {
+		if bytes.Equal(sig.PubKey, keys[2].SerializeCompressed()) {
+			sig.Signature[len(sig.Signature)-1] = byte(signing.SigHashNone)
+		}
+	}
+	if _, err := s.Merge(p); !errors.Is(err, multisig.ErrSigHashConflict) {
+		t.Errorf("Merge: got error %v, want %v", err,
+			multisig.ErrSigHashConflict)
+	}
+
+	p = sessionPacket(t)
+	p.UnsignedTx.LockTime = 1
+	if _, err := s.Merge(p); err != psbt.ErrInputMismatch {
+		t.Errorf("Merge: got error %v, want %v", err, psbt.ErrInputMismatch)
+	}
+}
@@ -0,0 +1,265 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package musig2
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/zeusyf/btcd/btcec"
+)
+
+var (
+	// ErrUnknownSigner describes an error where a nonce or partial
+	// signature is registered for a key which is not one of the signers
+	// of the context.
+	ErrUnknownSigner = errors.New("unknown musig2 signer")
+
+	// ErrDuplicateNonce describes an error where the public nonce of a
+	// signer is registered twice.
+	ErrDuplicateNonce = errors.New("musig2 nonce already registered")
+
+	// ErrMissingNonces describes an error where a session signs before the
+	// public nonces of all signers are registered.
+	ErrMissingNonces = errors.New("musig2 session lacks public nonces")
+
+	// ErrDuplicatePartialSig describes an error where the partial signature
+	// of a signer is combined twice.
+	ErrDuplicatePartialSig = errors.New("musig2 partial signature already " +
+		"combined")
+
+	// ErrDuplicateSigner describes an error where a key is listed more
+	// than once in the signers of a context, whose sessions identify
+	// signers by their keys.
+	ErrDuplicateSigner = errors.New("duplicate musig2 signer")
+
+	// ErrNotSigned describes an error where partial signatures are combined
+	// before the session signed a message.
+	ErrNotSigned = errors.New("musig2 session has not signed")
+)
+
+// Context holds the private key of a signer and the public keys of the whole
+// group, which stay the same across the signatures they produce together.
+type Context struct {
+	privKey *btcec.PrivateKey
+	pubKey  *btcec.PublicKey
+	signers []*btcec.PublicKey
+	aggKey  *AggregateKey
+}
+
+// NewContext returns the context of the signer with privKey in the group of
+// signers, whose distinct keys include its own.  The keys are sorted, so all
+// signers derive the same aggregate key regardless of the order they list
+// them in.
+func NewContext(privKey *btcec.PrivateKey, signers []*btcec.PublicKey,
+	opts ...KeyAggOption) (*Context, error) {
+	pubKey := privKey.PubKey()
+	sorted := SortKeys(signers)
+	for i := 1; i < len(sorted); i++ {
+		if sorted[i].IsEqual(sorted[i-1]) {
+			return nil, ErrDuplicateSigner
+		}
+	}
+	aggKey, err := AggregateKeys(sorted, opts...)
+	if err != nil {
+		return nil, err
+	}
+	if !aggKey.hasKey(pubKey.SerializeCompressed()) {
+		return nil, ErrInvalidPubKey
+	}
+	return &Context{
+		privKey: privKey,
+		pubKey:  pubKey,
+		signers: sorted,
+		aggKey:  aggKey,
+	}, nil
+}
+
+// PubKey returns the public key of the signer.
+func (c *Context) PubKey() *btcec.PublicKey {
+	return c.pubKey
+}
+
+// AggregateKey returns the aggregate key of the group.
+func (c *Context) AggregateKey() *AggregateKey {
+	return c.aggKey
+}
+
+// NewSession returns a session producing one signature, with fresh nonces.
+// The private key and the aggregate key are always mixed into the nonces, and
+// opts may add further inputs.
+func (c *Context) NewSession(opts ...NonceGenOption) (*Session, error) {
+	opts = append([]NonceGenOption{
+		WithNoncePrivKey(c.privKey),
+		WithNonceAggKey(c.aggKey.FinalKey),
+	}, opts...)
+	nonces, err := GenNonces(c.pubKey, opts...)
+	if err != nil {
+		return nil, err
+	}
+	s := &Session{
+		ctx:         c,
+		nonces:      nonces,
+		pubNonces:   make(map[string][PubNonceSize]byte),
+		partialSigs: make(map[string]PartialSignature),
+	}
+	s.pubNonces[string(c.pubKey.SerializeCompressed())] = nonces.PubNonce
+	return s, nil
+}
+
+// Session runs the two rounds of one signature of the group: it collects the
+// public nonces of the signers, signs once they are all known, and then
+// collects and verifies the partial signatures of the other signers until the
+// final signature can be assembled.  A session signs at most one message.
+type Session struct {
+	ctx    *Context
+	nonces *Nonces
+
+	mtx         sync.Mutex
+	pubNonces   map[string][PubNonceSize]byte
+	aggNonce    [PubNonceSize]byte
+	msg         [32]byte
+	signed      bool
+	partialSigs map[string]PartialSignature
+	finalSig    *[SignatureSize]byte
+}
+
+// PublicNonce returns the public nonce of the signer, to be sent to the other
+// signers.
+func (s *Session) PublicNonce() [PubNonceSize]byte {
+	return s.nonces.PubNonce
+}
+
+// signerKey returns the compressed key of signer, which must be one of the
+// signers of the context.
+func (s *Session) signerKey(signer *btcec.PublicKey) (string, error) {
+	pk := signer.SerializeCompressed()
+	if !s.ctx.aggKey.hasKey(pk) {
+		return "", ErrUnknownSigner
+	}
+	return string(pk), nil
+}
+
+// RegisterPubNonce registers the public nonce of another signer and returns
+// whether the nonces of all signers are now known.
+func (s *Session) RegisterPubNonce(signer *btcec.PublicKey, nonce [PubNonceSize]byte) (bool, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	key, err := s.signerKey(signer)
+	if err != nil {
+		return false, err
+	}
+	if _, ok := s.pubNonces[key]; ok {
+		return false, ErrDuplicateNonce
+	}
+	if _, _, err := parsePoint(nonce[:33], false); err != nil {
+		return false, err
+	}
+	if _, _, err := parsePoint(nonce[33:], false); err != nil {
+		return false, err
+	}
+	s.pubNonces[key] = nonce
+	return s.haveAllNonces(), nil
+}
+
+// haveAllNonces returns whether the nonces of all signers are known.
+func (s *Session) haveAllNonces() bool {
+	return len(s.pubNonces) == len(s.ctx.signers)
+}
+
+// Sign returns the partial signature of msg by the signer, to be sent to the
+// other signers.  It fails with ErrMissingNonces until the nonces of all
+// signers are registered, and with ErrNonceReused when called again, since
+// the secret nonce is erased once used.
+func (s *Session) Sign(msg [32]byte) (PartialSignature, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.signed {
+		return PartialSignature{}, ErrNonceReused
+	}
+	if !s.haveAllNonces() {
+		return PartialSignature{}, ErrMissingNonces
+	}
+	nonces := make([][PubNonceSize]byte, 0, len(s.pubNonces))
+	for _, nonce := range s.pubNonces {
+		nonces = append(nonces, nonce)
+	}
+	aggNonce, err := AggregateNonces(nonces)
+	if err != nil {
+		return PartialSignature{}, err
+	}
+
+	sig, err := Sign(&s.nonces.SecNonce, s.ctx.privKey, aggNonce,
+		s.ctx.aggKey, msg)
+	s.signed = true
+	if err != nil {
+		return PartialSignature{}, err
+	}
+	s.aggNonce = aggNonce
+	s.msg = msg
+	s.partialSigs[string(s.ctx.pubKey.SerializeCompressed())] = sig
+	if _, err := s.combine(); err != nil {
+		return PartialSignature{}, err
+	}
+	return sig, nil
+}
+
+// CombineSig verifies the partial signature of another signer and adds it to
+// the signatures of the session.  It returns whether the final signature is
+// complete, which is then returned by FinalSig.  An invalid partial signature
+// fails with ErrInvalidPartialSig, identifying the signer who sent it.
+func (s *Session) CombineSig(signer *btcec.PublicKey, sig PartialSignature) (bool, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if !s.signed {
+		return false, ErrNotSigned
+	}
+	key, err := s.signerKey(signer)
+	if err != nil {
+		return false, err
+	}
+	if _, ok := s.partialSigs[key]; ok {
+		return false, ErrDuplicatePartialSig
+	}
+	if !VerifyPartialSignature(sig, s.pubNonces[key], signer, s.aggNonce,
+		s.ctx.aggKey, s.msg) {
+		return false, ErrInvalidPartialSig
+	}
+	s.partialSigs[key] = sig
+	return s.combine()
+}
+
+// combine assembles the final signature once the partial signatures of all
+// signers are known.
+func (s *Session) combine() (bool, error) {
+	if len(s.partialSigs) != len(s.ctx.signers) {
+		return false, nil
+	}
+	sigs := make([]PartialSignature, 0, len(s.partialSigs))
+	for _, sig := range s.partialSigs {
+		sigs = append(sigs, sig)
+	}
+	final, err := AggregateSignatures(sigs, s.aggNonce, s.ctx.aggKey, s.msg)
+	if err != nil {
+		return false, err
+	}
+	s.finalSig = &final
+	return true, nil
+}
+
+// FinalSig returns the final signature of the session, and whether it is
+// complete.
+func (s *Session) FinalSig() ([SignatureSize]byte, bool) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+
+	if s.finalSig == nil {
+		return [SignatureSize]byte{}, false
+	}
+	return *s.finalSig, true
+}
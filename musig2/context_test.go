@@ -0,0 +1,132 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package musig2_test
+
+import (
+	"testing"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcutil/musig2"
+)
+
+// TestSession tests a group of signers produce a signature through their
+// sessions.
+func TestSession(t *testing.T) {
+	privKeys := []*btcec.PrivateKey{privKey(3), privKey(1), privKey(2)}
+	signers := make([]*btcec.PublicKey, len(privKeys))
+	for i, priv := range privKeys {
+		signers[i] = priv.PubKey()
+	}
+
+	sessions := make([]*musig2.Session, len(privKeys))
+	var aggKey *musig2.AggregateKey
+	for i, priv := range privKeys {
+		// Each signer lists the keys in its own order.
+		order := append(signers[i:len(signers):len(signers)],
+			signers[:i]...)
+		ctx, err := musig2.NewContext(priv, order,
+			musig2.WithTaprootTweak(nil))
+		if err != nil {
+			t.Fatalf("NewContext: %v", err)
+		}
+		if aggKey != nil && !ctx.AggregateKey().FinalKey.IsEqual(aggKey.FinalKey) {
+			t.Fatalf("signers derive different aggregate keys")
+		}
+		aggKey = ctx.AggregateKey()
+		if sessions[i], err = ctx.NewSession(); err != nil {
+			t.Fatalf("NewSession: %v", err)
+		}
+	}
+
+	msg := [32]byte{0xaa}
+	if _, err := sessions[0].Sign(msg); err != musig2.ErrMissingNonces {
+		t.Errorf("early Sign: got %v, want %v", err,
+			musig2.ErrMissingNonces)
+	}
+
+	// Round one: exchange the public nonces.
+	for i, s := range sessions {
+		var done bool
+		for j, other := range sessions {
+			if i == j {
+				continue
+			}
+			var err error
+			done, err = s.RegisterPubNonce(signers[j], other.PublicNonce())
+			if err != nil {
+				t.Fatalf("RegisterPubNonce: %v", err)
+			}
+		}
+		if !done {
+			t.Errorf("session %d lacks nonces after the exchange", i)
+		}
+	}
+	_, err := sessions[0].RegisterPubNonce(signers[1],
+		sessions[1].PublicNonce())
+	if err != musig2.ErrDuplicateNonce {
+		t.Errorf("duplicate nonce: got %v, want %v", err,
+			musig2.ErrDuplicateNonce)
+	}
+	_, err = sessions[0].RegisterPubNonce(privKey(9).PubKey(),
+		sessions[1].PublicNonce())
+	if err != musig2.ErrUnknownSigner {
+		t.Errorf("unknown signer: got %v, want %v", err,
+			musig2.ErrUnknownSigner)
+	}
+
+	// Round two: exchange the partial signatures.
+	sigs := make([]musig2.PartialSignature, len(sessions))
+	for i, s := range sessions {
+		if sigs[i], err = s.Sign(msg); err != nil {
+			t.Fatalf("Sign: %v", err)
+		}
+	}
+	if _, err := sessions[0].Sign(msg); err != musig2.ErrNonceReused {
+		t.Errorf("second Sign: got %v, want %v", err,
+			musig2.ErrNonceReused)
+	}
+
+	bad := sigs[2]
+	bad[31] ^= 1
+	if _, err := sessions[0].CombineSig(signers[2], bad); err != musig2.ErrInvalidPartialSig {
+		t.Errorf("invalid partial signature: got %v, want %v", err,
+			musig2.ErrInvalidPartialSig)
+	}
+
+	for i, s := range sessions {
+		for j := range sessions {
+			if i == j {
+				continue
+			}
+			if _, err := s.CombineSig(signers[j], sigs[j]); err != nil {
+				t.Fatalf("CombineSig: %v", err)
+			}
+		}
+		sig, ok := s.FinalSig()
+		if !ok {
+			t.Fatalf("session %d has no final signature", i)
+		}
+		if !musig2.VerifySignature(aggKey.FinalKey, msg, sig) {
+			t.Errorf("session %d: final signature does not verify", i)
+		}
+	}
+}
+
+// TestNewContext tests invalid groups of signers are rejected.
+func TestNewContext(t *testing.T) {
+	priv := privKey(1)
+	_, err := musig2.NewContext(priv, []*btcec.PublicKey{privKey(2).PubKey()})
+	if err != musig2.ErrInvalidPubKey {
+		t.Errorf("signer not in group: got %v, want %v", err,
+			musig2.ErrInvalidPubKey)
+	}
+	_, err = musig2.NewContext(priv, []*btcec.PublicKey{
+		priv.PubKey(), priv.PubKey(),
+	})
+	if err != musig2.ErrDuplicateSigner {
+		t.Errorf("duplicate signer: got %v, want %v", err,
+			musig2.ErrDuplicateSigner)
+	}
+}
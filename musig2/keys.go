@@ -0,0 +1,163 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package musig2
+
+import (
+	"bytes"
+	"math/big"
+	"sort"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcutil/taproot"
+)
+
+// SortKeys returns a copy of keys sorted by their compressed serialization,
+// so that signers listing their keys in different orders agree on the
+// aggregate key.
+func SortKeys(keys []*btcec.PublicKey) []*btcec.PublicKey {
+	sorted := make([]*btcec.PublicKey, len(keys))
+	copy(sorted, keys)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i].SerializeCompressed(),
+			sorted[j].SerializeCompressed()) < 0
+	})
+	return sorted
+}
+
+// KeyAggOption configures the aggregation of keys.
+type KeyAggOption func(*keyAggOptions)
+
+// keyAggOptions holds the options of AggregateKeys.
+type keyAggOptions struct {
+	taprootTweak bool
+	scriptRoot   []byte
+}
+
+// WithTaprootTweak tweaks the aggregate key as the internal key of a taproot
+// output committing to the root of a script tree, so the final key is the
+// output key and final signatures spend the key path of the output.  A nil
+// scriptRoot commits to no scripts, as BIP0086 outputs do.
+func WithTaprootTweak(scriptRoot []byte) KeyAggOption {
+	return func(o *keyAggOptions) {
+		o.taprootTweak = true
+		o.scriptRoot = scriptRoot
+	}
+}
+
+// AggregateKey is the aggregate of the public keys of a group of signers.
+type AggregateKey struct {
+	// FinalKey is the key final signatures verify with.  It is the
+	// tweaked key when a tweak is applied.
+	FinalKey *btcec.PublicKey
+
+	// PreTweakedKey is the aggregate key before any tweak, the internal
+	// key of a taproot output.
+	PreTweakedKey *btcec.PublicKey
+
+	keys   [][]byte
+	second []byte
+	list   []byte
+
+	// gacc and tacc accumulate the sign and the value of the tweaks as
+	// in BIP0327.
+	gacc *big.Int
+	tacc *big.Int
+}
+
+// coefficient returns the coefficient of the compressed key pk in the
+// aggregate key.  The second distinct key of the list has coefficient one,
+// which saves a multiplication when aggregating.
+func (k *AggregateKey) coefficient(pk []byte) *big.Int {
+	if k.second != nil && bytes.Equal(pk, k.second) {
+		return big.NewInt(1)
+	}
+	return hashToScalar(tagKeyAggCoeff, k.list, pk)
+}
+
+// hasKey returns whether the compressed key pk is one of the aggregated keys.
+func (k *AggregateKey) hasKey(pk []byte) bool {
+	for _, key := range k.keys {
+		if bytes.Equal(key, pk) {
+			return true
+		}
+	}
+	return false
+}
+
+// AggregateKeys returns the aggregate of keys in the passed order, which must
+// be the same for all signers.  Keys are usually sorted with SortKeys first.
+func AggregateKeys(keys []*btcec.PublicKey, opts ...KeyAggOption) (*AggregateKey, error) {
+	var o keyAggOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	if len(keys) == 0 {
+		return nil, ErrNoKeys
+	}
+
+	k := &AggregateKey{
+		keys: make([][]byte, len(keys)),
+		gacc: big.NewInt(1),
+		tacc: new(big.Int),
+	}
+	for i, key := range keys {
+		k.keys[i] = key.SerializeCompressed()
+		if k.second == nil && !bytes.Equal(k.keys[i], k.keys[0]) {
+			k.second = k.keys[i]
+		}
+	}
+	list := taproot.TaggedHash(tagKeyAggList, k.keys...)
+	k.list = list[:]
+
+	// Q = sum(a_i * P_i)
+	qx, qy := new(big.Int), new(big.Int)
+	for i, key := range keys {
+		x, y := mul(key.X, key.Y, k.coefficient(k.keys[i]))
+		qx, qy = add(qx, qy, x, y)
+	}
+	if isInfinity(qx, qy) {
+		return nil, ErrInvalidPubKey
+	}
+	k.PreTweakedKey = &btcec.PublicKey{Curve: btcec.S256(), X: qx, Y: qy}
+	k.FinalKey = k.PreTweakedKey
+
+	if o.taprootTweak {
+		t := taproot.TaggedHash(taproot.TagTapTweak,
+			taproot.SerializeXOnly(k.FinalKey), o.scriptRoot)
+		if err := k.applyTweak(t[:], true); err != nil {
+			return nil, err
+		}
+	}
+	return k, nil
+}
+
+// applyTweak adds tweak*G to the final key.  An x-only tweak applies to the
+// final key with an even y coordinate, as taproot tweaks do.
+func (k *AggregateKey) applyTweak(tweak []byte, xOnly bool) error {
+	n := order()
+	qx, qy := k.FinalKey.X, k.FinalKey.Y
+	g := big.NewInt(1)
+	if xOnly && !hasEvenY(qy) {
+		g.Sub(n, g)
+		qx, qy = negate(qx, qy)
+	}
+	t := new(big.Int).SetBytes(tweak)
+	if t.Cmp(n) >= 0 {
+		return ErrInvalidTweak
+	}
+
+	// Q' = g*Q + t*G
+	tx, ty := baseMul(t)
+	qx, qy = add(qx, qy, tx, ty)
+	if isInfinity(qx, qy) {
+		return ErrInvalidTweak
+	}
+	k.FinalKey = &btcec.PublicKey{Curve: btcec.S256(), X: qx, Y: qy}
+	k.gacc = new(big.Int).Mul(g, k.gacc)
+	k.gacc.Mod(k.gacc, n)
+	k.tacc = new(big.Int).Mul(g, k.tacc)
+	k.tacc.Add(k.tacc, t).Mod(k.tacc, n)
+	return nil
+}
@@ -0,0 +1,105 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package musig2_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcutil/musig2"
+	"github.com/zeusyf/btcutil/taproot"
+)
+
+// keyAggPubKeys are the public keys of the key aggregation vectors of
+// BIP0327.
+var keyAggPubKeys = []string{
+	"02F9308A019258C31049344F85F89D5229B531C845836F99B08601F113BCE036F9",
+	"03DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659",
+	"023590A94E768F8E1815C2F24B4D80A8E3149316C3518CE7B7AD338368D038CA66",
+}
+
+// parseKeys returns the keys of keyAggPubKeys at the passed indices.
+func parseKeys(t *testing.T, indices ...int) []*btcec.PublicKey {
+	keys := make([]*btcec.PublicKey, len(indices))
+	for i, idx := range indices {
+		pub, err := btcec.ParsePubKey(hexToBytes(keyAggPubKeys[idx]),
+			btcec.S256())
+		if err != nil {
+			t.Fatalf("ParsePubKey: %v", err)
+		}
+		keys[i] = pub
+	}
+	return keys
+}
+
+// TestAggregateKeys tests the key aggregation vectors of BIP0327.
+func TestAggregateKeys(t *testing.T) {
+	tests := []struct {
+		indices []int
+		want    string
+	}{
+		{[]int{0, 1, 2}, "90539EEDE565F5D054F32CC0C220126889ED1E5D193BAF15AEF344FE59D4610C"},
+		{[]int{2, 1, 0}, "6204DE8B083426DC6EAF9502D27024D53FC826BF7D2012148A0575435DF54B2B"},
+		{[]int{0, 0, 0}, "B436E3BAD62B8CD409969A224731C193D051162D8C5AE8B109306127DA3AA935"},
+		{[]int{0, 0, 1, 1}, "69BC22BFA5D106306E48A20679DE1D7389386124D07571D0D872686028C26A3E"},
+	}
+	for _, test := range tests {
+		key, err := musig2.AggregateKeys(parseKeys(t, test.indices...))
+		if err != nil {
+			t.Fatalf("%v: AggregateKeys: %v", test.indices, err)
+		}
+		got := strings.ToUpper(hex.EncodeToString(
+			taproot.SerializeXOnly(key.FinalKey)))
+		if got != test.want {
+			t.Errorf("%v: got %s, want %s", test.indices, got, test.want)
+		}
+	}
+
+	if _, err := musig2.AggregateKeys(nil); err != musig2.ErrNoKeys {
+		t.Errorf("no keys: got %v, want %v", err, musig2.ErrNoKeys)
+	}
+}
+
+// TestSortKeys tests sorting makes the aggregate key independent of the order
+// of the keys.
+func TestSortKeys(t *testing.T) {
+	a, err := musig2.AggregateKeys(musig2.SortKeys(parseKeys(t, 0, 1, 2)))
+	if err != nil {
+		t.Fatalf("AggregateKeys: %v", err)
+	}
+	b, err := musig2.AggregateKeys(musig2.SortKeys(parseKeys(t, 2, 0, 1)))
+	if err != nil {
+		t.Fatalf("AggregateKeys: %v", err)
+	}
+	if !a.FinalKey.IsEqual(b.FinalKey) {
+		t.Errorf("sorted keys aggregate to different keys")
+	}
+}
+
+// TestTaprootTweak tests the tweaked aggregate key is the taproot output key
+// of the untweaked one.
+func TestTaprootTweak(t *testing.T) {
+	root := bytes.Repeat([]byte{0x11}, 32)
+	for _, scriptRoot := range [][]byte{nil, root} {
+		key, err := musig2.AggregateKeys(parseKeys(t, 0, 1, 2),
+			musig2.WithTaprootTweak(scriptRoot))
+		if err != nil {
+			t.Fatalf("AggregateKeys: %v", err)
+		}
+		want, err := taproot.ComputeTaprootOutputKey(key.PreTweakedKey,
+			scriptRoot)
+		if err != nil {
+			t.Fatalf("ComputeTaprootOutputKey: %v", err)
+		}
+		if !bytes.Equal(taproot.SerializeXOnly(key.FinalKey),
+			taproot.SerializeXOnly(want)) {
+			t.Errorf("root %x: final key is not the output key",
+				scriptRoot)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package musig2 implements the MuSig2 multi-signature scheme of BIP0327, with
+// which a group of signers produce a single BIP0340 signature for their
+// aggregate key, so an output paid to the key looks like any other
+// single-signer taproot output.
+//
+// Signing takes two rounds.  Each signer generates a nonce and shares its
+// public part; once all public nonces are known, each signer creates a
+// partial signature, and the partial signatures are aggregated into the
+// final signature.  A Context holds the keys of a signer and the group, and a
+// Session runs the rounds of one signature, making sure a secret nonce is
+// never used twice.
+//
+// The aggregate key may be tweaked with WithTaprootTweak, so the group can
+// sign for the key path of a taproot output committing to a script tree.
+package musig2
+
+import (
+	"bytes"
+	"errors"
+	"math/big"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcutil/signing"
+	"github.com/zeusyf/btcutil/taproot"
+)
+
+const (
+	// PubNonceSize is the size of a public nonce: two compressed points.
+	PubNonceSize = 66
+
+	// SecNonceSize is the size of a secret nonce: two scalars followed by
+	// the compressed public key of the signer.
+	SecNonceSize = 97
+
+	// PartialSigSize is the size of a partial signature.
+	PartialSigSize = 32
+
+	// SignatureSize is the size of a BIP0340 signature.
+	SignatureSize = 64
+)
+
+// Tags of the tagged hashes of BIP0327 and BIP0340.
+const (
+	tagKeyAggList  = "KeyAgg list"
+	tagKeyAggCoeff = "KeyAgg coefficient"
+	tagAux         = "MuSig/aux"
+	tagNonce       = "MuSig/nonce"
+	tagNonceCoeff  = "MuSig/noncecoef"
+	tagChallenge   = signing.TagBIP340Challenge
+)
+
+var (
+	// ErrNoKeys describes an error where keys are aggregated from an empty
+	// list.
+	ErrNoKeys = errors.New("no keys to aggregate")
+
+	// ErrInvalidPubKey describes an error where a public key is not a
+	// valid point or is not one of the keys of the group.
+	ErrInvalidPubKey = errors.New("invalid musig2 public key")
+
+	// ErrInvalidPrivKey describes an error where a private key is zero or
+	// not less than the order of the curve.
+	ErrInvalidPrivKey = errors.New("invalid musig2 private key")
+
+	// ErrInvalidNonce describes an error where a public or secret nonce is
+	// malformed, or a secret nonce does not belong to the signing key.
+	ErrInvalidNonce = errors.New("invalid musig2 nonce")
+
+	// ErrNonceReused describes an error where a secret nonce is used a
+	// second time.  Signing two messages with the same nonce reveals the
+	// private key, so secret nonces are erased when used.
+	ErrNonceReused = errors.New("musig2 secret nonce already used")
+
+	// ErrInvalidPartialSig describes an error where a partial signature is
+	// not less than the order of the curve or does not verify.
+	ErrInvalidPartialSig = errors.New("invalid musig2 partial signature")
+
+	// ErrInvalidTweak describes an error where tweaking the aggregate key
+	// yields the point at infinity or the tweak is out of range.  Either
+	// happens with negligible probability.
+	ErrInvalidTweak = errors.New("invalid musig2 tweak")
+)
+
+// order returns the order of the curve.
+func order() *big.Int {
+	return btcec.S256().N
+}
+
+// isInfinity returns whether the coordinates are those of the point at
+// infinity.
+func isInfinity(x, y *big.Int) bool {
+	return x.Sign() == 0 && y.Sign() == 0
+}
+
+// hasEvenY returns whether the point has an even y coordinate.
+func hasEvenY(y *big.Int) bool {
+	return y.Bit(0) == 0
+}
+
+// scalarBytes returns the 32 byte big-endian serialization of k.
+func scalarBytes(k *big.Int) []byte {
+	var b [32]byte
+	k.FillBytes(b[:])
+	return b[:]
+}
+
+// hashToScalar returns the tagged hash of msgs as a scalar modulo the order of
+// the curve.
+func hashToScalar(tag string, msgs ...[]byte) *big.Int {
+	h := taproot.TaggedHash(tag, msgs...)
+	k := new(big.Int).SetBytes(h[:])
+	return k.Mod(k, order())
+}
+
+// mul returns k*P.
+func mul(x, y, k *big.Int) (*big.Int, *big.Int) {
+	return btcec.S256().ScalarMult(x, y, scalarBytes(k))
+}
+
+// baseMul returns k*G.
+func baseMul(k *big.Int) (*big.Int, *big.Int) {
+	return btcec.S256().ScalarBaseMult(scalarBytes(k))
+}
+
+// add returns P1+P2.
+func add(x1, y1, x2, y2 *big.Int) (*big.Int, *big.Int) {
+	return btcec.S256().Add(x1, y1, x2, y2)
+}
+
+// negate returns -P.
+func negate(x, y *big.Int) (*big.Int, *big.Int) {
+	if isInfinity(x, y) {
+		return x, y
+	}
+	return x, new(big.Int).Sub(btcec.S256().P, y)
+}
+
+// serializePoint returns the compressed serialization of a point, or 33 zero
+// bytes for the point at infinity.
+func serializePoint(x, y *big.Int) []byte {
+	if isInfinity(x, y) {
+		return make([]byte, 33)
+	}
+	pub := btcec.PublicKey{Curve: btcec.S256(), X: x, Y: y}
+	return pub.SerializeCompressed()
+}
+
+// parsePoint parses a compressed point.  When allowInfinity is set, 33 zero
+// bytes are parsed as the point at infinity.
+func parsePoint(b []byte, allowInfinity bool) (*big.Int, *big.Int, error) {
+	if allowInfinity && bytes.Equal(b, make([]byte, 33)) {
+		return new(big.Int), new(big.Int), nil
+	}
+	if len(b) != 33 || (b[0] != 0x02 && b[0] != 0x03) {
+		return nil, nil, ErrInvalidNonce
+	}
+	pub, err := btcec.ParsePubKey(b, btcec.S256())
+	if err != nil {
+		return nil, nil, ErrInvalidNonce
+	}
+	return pub.X, pub.Y, nil
+}
+
+// VerifySignature returns whether sig is a valid BIP0340 signature of msg by
+// the public key, used with an even y coordinate as implied by its x-only
+// serialization.  Final signatures of a session verify with the final
+// aggregate key.
+func VerifySignature(pubKey *btcec.PublicKey, msg [32]byte, sig [SignatureSize]byte) bool {
+	return signing.VerifySchnorr(pubKey, msg[:], sig[:])
+}
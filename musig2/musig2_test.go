@@ -0,0 +1,89 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package musig2_test
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcutil/musig2"
+	"github.com/zeusyf/btcutil/taproot"
+)
+
+// hexToBytes converts the passed hex string into bytes and will panic if there
+// is an error.  This is only provided for the hard-coded constants so errors in
+// the source code can be detected.  It will only (and must only) be called with
+// hard-coded values.
+func hexToBytes(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic("invalid hex in source file: " + s)
+	}
+	return b
+}
+
+// privKey returns the private key with the passed scalar.
+func privKey(k byte) *btcec.PrivateKey {
+	var b [32]byte
+	b[31] = k
+	priv, _ := btcec.PrivKeyFromBytes(btcec.S256(), b[:])
+	return priv
+}
+
+// TestVerifySignature tests signatures are verified as specified by BIP0340.
+func TestVerifySignature(t *testing.T) {
+	tests := []struct {
+		pubKey string
+		msg    string
+		sig    string
+		valid  bool
+	}{
+		{
+			pubKey: "F9308A019258C31049344F85F89D5229B531C845836F99B08601F113BCE036F9",
+			msg:    "0000000000000000000000000000000000000000000000000000000000000000",
+			sig: "E907831F80848D1069A5371B402410364BDF1C5F8307B0084C55F1CE2DCA8215" +
+				"25F66A4A85EA8B71E482A74F382D2CE5EBEEE8FDB2172F477DF4900D310536C0",
+			valid: true,
+		},
+		{
+			pubKey: "DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659",
+			msg:    "243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89",
+			sig: "6896BD60EEAE296DB48A229FF71DFE071BDE413E6D43F917DC8DCF8C78DE3341" +
+				"8906D11AC976ABCCB20B091292BFF4EA897EFCB639EA871CFA95F6DE339E4B0A",
+			valid: true,
+		},
+		{
+			// The signature of the previous test for another message.
+			pubKey: "DFF1D77F2A671C5F36183726DB2341BE58FEAE1DA2DECED843240F7B502BA659",
+			msg:    "243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C8A",
+			sig: "6896BD60EEAE296DB48A229FF71DFE071BDE413E6D43F917DC8DCF8C78DE3341" +
+				"8906D11AC976ABCCB20B091292BFF4EA897EFCB639EA871CFA95F6DE339E4B0A",
+			valid: false,
+		},
+		{
+			// s is not less than the order of the curve.
+			pubKey: "F9308A019258C31049344F85F89D5229B531C845836F99B08601F113BCE036F9",
+			msg:    "0000000000000000000000000000000000000000000000000000000000000000",
+			sig: "E907831F80848D1069A5371B402410364BDF1C5F8307B0084C55F1CE2DCA8215" +
+				"FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEBAAEDCE6AF48A03BBFD25E8CD0364141",
+			valid: false,
+		},
+	}
+
+	for i, test := range tests {
+		pub, err := taproot.ParseXOnlyPubKey(hexToBytes(test.pubKey))
+		if err != nil {
+			t.Fatalf("test %d: ParseXOnlyPubKey: %v", i, err)
+		}
+		var msg [32]byte
+		var sig [musig2.SignatureSize]byte
+		copy(msg[:], hexToBytes(test.msg))
+		copy(sig[:], hexToBytes(test.sig))
+		if got := musig2.VerifySignature(pub, msg, sig); got != test.valid {
+			t.Errorf("test %d: got %v, want %v", i, got, test.valid)
+		}
+	}
+}
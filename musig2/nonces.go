@@ -0,0 +1,148 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package musig2
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+	"math/big"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcutil/taproot"
+)
+
+// Nonces are the nonces of a signer for one signature.  The public nonce is
+// shared with the other signers, and the secret nonce must be kept private
+// and used to sign only once.
+type Nonces struct {
+	PubNonce [PubNonceSize]byte
+	SecNonce [SecNonceSize]byte
+}
+
+// NonceGenOption configures the generation of nonces.
+type NonceGenOption func(*nonceGenOptions)
+
+// nonceGenOptions holds the options of GenNonces.
+type nonceGenOptions struct {
+	rand    io.Reader
+	privKey *btcec.PrivateKey
+	aggKey  *btcec.PublicKey
+	msg     []byte
+	hasMsg  bool
+	extraIn []byte
+}
+
+// WithNonceRand sets the source of the random bytes of the nonces.  By
+// default crypto/rand is used.
+func WithNonceRand(r io.Reader) NonceGenOption {
+	return func(o *nonceGenOptions) {
+		o.rand = r
+	}
+}
+
+// WithNoncePrivKey mixes the private key of the signer into the nonces, which
+// keeps them secret even if the random source is weak.
+func WithNoncePrivKey(privKey *btcec.PrivateKey) NonceGenOption {
+	return func(o *nonceGenOptions) {
+		o.privKey = privKey
+	}
+}
+
+// WithNonceAggKey mixes the aggregate key the nonces sign for into them.
+func WithNonceAggKey(aggKey *btcec.PublicKey) NonceGenOption {
+	return func(o *nonceGenOptions) {
+		o.aggKey = aggKey
+	}
+}
+
+// WithNonceMessage mixes the message the nonces sign into them, when it is
+// already known.
+func WithNonceMessage(msg []byte) NonceGenOption {
+	return func(o *nonceGenOptions) {
+		o.msg = msg
+		o.hasMsg = true
+	}
+}
+
+// WithNonceExtraInput mixes arbitrary data, such as a session identifier or
+// a counter, into the nonces.
+func WithNonceExtraInput(extra []byte) NonceGenOption {
+	return func(o *nonceGenOptions) {
+		o.extraIn = extra
+	}
+}
+
+// GenNonces generates the nonces of the signer with the passed public key as
+// specified by BIP0327.  The options are optional inputs which make the
+// nonces unique even when the random source fails.
+func GenNonces(pubKey *btcec.PublicKey, opts ...NonceGenOption) (*Nonces, error) {
+	o := nonceGenOptions{rand: rand.Reader}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	var randBytes [32]byte
+	if _, err := io.ReadFull(o.rand, randBytes[:]); err != nil {
+		return nil, err
+	}
+	if o.privKey != nil {
+		aux := taproot.TaggedHash(tagAux, randBytes[:])
+		sk := scalarBytes(o.privKey.D)
+		for i := range randBytes {
+			randBytes[i] = sk[i] ^ aux[i]
+		}
+	}
+
+	pk := pubKey.SerializeCompressed()
+	var aggPk []byte
+	if o.aggKey != nil {
+		aggPk = taproot.SerializeXOnly(o.aggKey)
+	}
+	var msgPrefixed []byte
+	if o.hasMsg {
+		msgPrefixed = make([]byte, 9, 9+len(o.msg))
+		msgPrefixed[0] = 1
+		binary.BigEndian.PutUint64(msgPrefixed[1:], uint64(len(o.msg)))
+		msgPrefixed = append(msgPrefixed, o.msg...)
+	} else {
+		msgPrefixed = []byte{0}
+	}
+	var extraLen [4]byte
+	binary.BigEndian.PutUint32(extraLen[:], uint32(len(o.extraIn)))
+
+	nonces := new(Nonces)
+	for i := 0; i < 2; i++ {
+		k := hashToScalar(tagNonce, randBytes[:],
+			[]byte{byte(len(pk))}, pk,
+			[]byte{byte(len(aggPk))}, aggPk,
+			msgPrefixed, extraLen[:], o.extraIn, []byte{byte(i)})
+		if k.Sign() == 0 {
+			return nil, ErrInvalidNonce
+		}
+		copy(nonces.SecNonce[32*i:], scalarBytes(k))
+		copy(nonces.PubNonce[33*i:], serializePoint(baseMul(k)))
+	}
+	copy(nonces.SecNonce[64:], pk)
+	return nonces, nil
+}
+
+// AggregateNonces returns the aggregate of the public nonces of all signers,
+// which every signer needs to create its partial signature.
+func AggregateNonces(pubNonces [][PubNonceSize]byte) ([PubNonceSize]byte, error) {
+	var agg [PubNonceSize]byte
+	for j := 0; j < 2; j++ {
+		rx, ry := new(big.Int), new(big.Int)
+		for _, nonce := range pubNonces {
+			x, y, err := parsePoint(nonce[33*j:33*(j+1)], false)
+			if err != nil {
+				return agg, err
+			}
+			rx, ry = add(rx, ry, x, y)
+		}
+		copy(agg[33*j:], serializePoint(rx, ry))
+	}
+	return agg, nil
+}
@@ -0,0 +1,71 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package musig2_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/zeusyf/btcutil/musig2"
+)
+
+// TestGenNonces tests nonces are derived deterministically from their inputs
+// and belong to the signer.
+func TestGenNonces(t *testing.T) {
+	priv := privKey(1)
+	rand := bytes.Repeat([]byte{0x42}, 32)
+	gen := func(opts ...musig2.NonceGenOption) *musig2.Nonces {
+		opts = append(opts, musig2.WithNonceRand(bytes.NewReader(rand)))
+		nonces, err := musig2.GenNonces(priv.PubKey(), opts...)
+		if err != nil {
+			t.Fatalf("GenNonces: %v", err)
+		}
+		return nonces
+	}
+
+	a, b := gen(), gen()
+	if a.PubNonce != b.PubNonce || a.SecNonce != b.SecNonce {
+		t.Errorf("nonces of the same inputs differ")
+	}
+	if !bytes.Equal(a.SecNonce[64:], priv.PubKey().SerializeCompressed()) {
+		t.Errorf("secret nonce does not end with the public key")
+	}
+	for _, nonces := range []*musig2.Nonces{
+		gen(musig2.WithNoncePrivKey(priv)),
+		gen(musig2.WithNonceAggKey(privKey(2).PubKey())),
+		gen(musig2.WithNonceMessage(nil)),
+		gen(musig2.WithNonceMessage([]byte{1})),
+		gen(musig2.WithNonceExtraInput([]byte{1})),
+	} {
+		if nonces.PubNonce == a.PubNonce {
+			t.Errorf("nonce input is not mixed into the nonces")
+		}
+	}
+}
+
+// TestAggregateNonces tests invalid public nonces are rejected.
+func TestAggregateNonces(t *testing.T) {
+	nonces, err := musig2.GenNonces(privKey(1).PubKey())
+	if err != nil {
+		t.Fatalf("GenNonces: %v", err)
+	}
+	agg, err := musig2.AggregateNonces(
+		[][musig2.PubNonceSize]byte{nonces.PubNonce})
+	if err != nil {
+		t.Fatalf("AggregateNonces: %v", err)
+	}
+	if agg != nonces.PubNonce {
+		t.Errorf("aggregate of one nonce is not the nonce")
+	}
+
+	bad := nonces.PubNonce
+	bad[33] = 0x04
+	_, err = musig2.AggregateNonces([][musig2.PubNonceSize]byte{
+		nonces.PubNonce, bad,
+	})
+	if err != musig2.ErrInvalidNonce {
+		t.Errorf("got %v, want %v", err, musig2.ErrInvalidNonce)
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package musig2
+
+import (
+	"bytes"
+	"math/big"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcutil/taproot"
+)
+
+// PartialSignature is the signature of one signer, which is aggregated with
+// those of the other signers by AggregateSignatures.
+type PartialSignature [PartialSigSize]byte
+
+// sessionValues are the values derived from the aggregate key, aggregate
+// nonce and message which all signers share.
+type sessionValues struct {
+	key    *AggregateKey
+	b      *big.Int
+	rx, ry *big.Int
+	e      *big.Int
+}
+
+// newSessionValues derives the values of the session signing msg for the
+// aggregate key with the aggregate nonce.
+func newSessionValues(key *AggregateKey, aggNonce [PubNonceSize]byte, msg [32]byte) (*sessionValues, error) {
+	r1x, r1y, err := parsePoint(aggNonce[:33], true)
+	if err != nil {
+		return nil, err
+	}
+	r2x, r2y, err := parsePoint(aggNonce[33:], true)
+	if err != nil {
+		return nil, err
+	}
+	qBytes := taproot.SerializeXOnly(key.FinalKey)
+	b := hashToScalar(tagNonceCoeff, aggNonce[:], qBytes, msg[:])
+
+	// R = R1 + b*R2, or G should that be the point at infinity.
+	bx, by := mul(r2x, r2y, b)
+	rx, ry := add(r1x, r1y, bx, by)
+	if isInfinity(rx, ry) {
+		rx, ry = btcec.S256().Gx, btcec.S256().Gy
+	}
+	rBytes := serializePoint(rx, ry)[1:]
+	e := hashToScalar(tagChallenge, rBytes, qBytes, msg[:])
+	return &sessionValues{key: key, b: b, rx: rx, ry: ry, e: e}, nil
+}
+
+// keyFactor returns the factor of the secret or public key of a signer: its
+// coefficient, and the signs of the final key and of the tweaks.
+func (v *sessionValues) keyFactor(pk []byte) *big.Int {
+	n := order()
+	f := new(big.Int).Mul(v.key.coefficient(pk), v.key.gacc)
+	if !hasEvenY(v.key.FinalKey.Y) {
+		f.Neg(f)
+	}
+	return f.Mod(f, n)
+}
+
+// Sign returns the partial signature of msg by privKey with its secret nonce,
+// for the aggregate key and the aggregate of the public nonces of all
+// signers.  The secret nonce is erased, so it can't be used again; signing
+// twice with a nonce reveals the private key.
+func Sign(secNonce *[SecNonceSize]byte, privKey *btcec.PrivateKey,
+	aggNonce [PubNonceSize]byte, key *AggregateKey, msg [32]byte) (PartialSignature, error) {
+	var sig PartialSignature
+	n := order()
+	k1 := new(big.Int).SetBytes(secNonce[:32])
+	k2 := new(big.Int).SetBytes(secNonce[32:64])
+	if k1.Sign() == 0 && k2.Sign() == 0 {
+		return sig, ErrNonceReused
+	}
+	if k1.Sign() == 0 || k1.Cmp(n) >= 0 || k2.Sign() == 0 || k2.Cmp(n) >= 0 {
+		return sig, ErrInvalidNonce
+	}
+	pk := append([]byte(nil), secNonce[64:]...)
+	for i := range secNonce {
+		secNonce[i] = 0
+	}
+
+	d := privKey.D
+	if d.Sign() == 0 || d.Cmp(n) >= 0 {
+		return sig, ErrInvalidPrivKey
+	}
+	pub := privKey.PubKey()
+	if !bytes.Equal(pub.SerializeCompressed(), pk) {
+		return sig, ErrInvalidNonce
+	}
+	if !key.hasKey(pk) {
+		return sig, ErrInvalidPubKey
+	}
+
+	v, err := newSessionValues(key, aggNonce, msg)
+	if err != nil {
+		return sig, err
+	}
+	if !hasEvenY(v.ry) {
+		k1.Sub(n, k1)
+		k2.Sub(n, k2)
+	}
+
+	// s = k1 + b*k2 + e*a*g*gacc*d
+	s := new(big.Int).Mul(v.e, v.keyFactor(pk))
+	s.Mul(s, d)
+	s.Add(s, k1)
+	s.Add(s, new(big.Int).Mul(v.b, k2))
+	s.Mod(s, n)
+	copy(sig[:], scalarBytes(s))
+	return sig, nil
+}
+
+// VerifyPartialSignature returns whether sig is a valid partial signature of
+// msg by the signer with the passed public key and public nonce, so that a
+// signer who sent an invalid signature can be identified before the final
+// signature fails to verify.
+func VerifyPartialSignature(sig PartialSignature, pubNonce [PubNonceSize]byte,
+	pubKey *btcec.PublicKey, aggNonce [PubNonceSize]byte, key *AggregateKey,
+	msg [32]byte) bool {
+	s := new(big.Int).SetBytes(sig[:])
+	if s.Cmp(order()) >= 0 {
+		return false
+	}
+	pk := pubKey.SerializeCompressed()
+	if !key.hasKey(pk) {
+		return false
+	}
+	v, err := newSessionValues(key, aggNonce, msg)
+	if err != nil {
+		return false
+	}
+	r1x, r1y, err := parsePoint(pubNonce[:33], false)
+	if err != nil {
+		return false
+	}
+	r2x, r2y, err := parsePoint(pubNonce[33:], false)
+	if err != nil {
+		return false
+	}
+
+	// s*G == Re + e*a*g*gacc*P, where Re = R1 + b*R2 negated along with
+	// the aggregate nonce.
+	bx, by := mul(r2x, r2y, v.b)
+	rex, rey := add(r1x, r1y, bx, by)
+	if !hasEvenY(v.ry) {
+		rex, rey = negate(rex, rey)
+	}
+	f := new(big.Int).Mul(v.e, v.keyFactor(pk))
+	px, py := mul(pubKey.X, pubKey.Y, f.Mod(f, order()))
+	wantX, wantY := add(rex, rey, px, py)
+	sx, sy := baseMul(s)
+	return sx.Cmp(wantX) == 0 && sy.Cmp(wantY) == 0
+}
+
+// AggregateSignatures returns the final BIP0340 signature of msg from the
+// partial signatures of all signers, which verifies with the final aggregate
+// key.
+func AggregateSignatures(sigs []PartialSignature, aggNonce [PubNonceSize]byte,
+	key *AggregateKey, msg [32]byte) ([SignatureSize]byte, error) {
+	var final [SignatureSize]byte
+	v, err := newSessionValues(key, aggNonce, msg)
+	if err != nil {
+		return final, err
+	}
+	n := order()
+	s := new(big.Int)
+	for i := range sigs {
+		si := new(big.Int).SetBytes(sigs[i][:])
+		if si.Cmp(n) >= 0 {
+			return final, ErrInvalidPartialSig
+		}
+		s.Add(s, si)
+	}
+
+	// s = sum(s_i) + e*g*tacc
+	t := new(big.Int).Mul(v.e, key.tacc)
+	if !hasEvenY(key.FinalKey.Y) {
+		t.Neg(t)
+	}
+	s.Add(s, t).Mod(s, n)
+	copy(final[:32], serializePoint(v.rx, v.ry)[1:])
+	copy(final[32:], scalarBytes(s))
+	return final, nil
+}
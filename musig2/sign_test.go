@@ -0,0 +1,152 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package musig2_test
+
+import (
+	"testing"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcutil/musig2"
+)
+
+// signAll signs msg with each of privKeys for the aggregate of their keys and
+// returns the final signature and key.
+func signAll(t *testing.T, privKeys []*btcec.PrivateKey, msg [32]byte,
+	opts ...musig2.KeyAggOption) ([musig2.SignatureSize]byte, *musig2.AggregateKey) {
+	pubKeys := make([]*btcec.PublicKey, len(privKeys))
+	for i, priv := range privKeys {
+		pubKeys[i] = priv.PubKey()
+	}
+	key, err := musig2.AggregateKeys(pubKeys, opts...)
+	if err != nil {
+		t.Fatalf("AggregateKeys: %v", err)
+	}
+
+	nonces := make([]*musig2.Nonces, len(privKeys))
+	pubNonces := make([][musig2.PubNonceSize]byte, len(privKeys))
+	for i, priv := range privKeys {
+		nonces[i], err = musig2.GenNonces(pubKeys[i],
+			musig2.WithNoncePrivKey(priv))
+		if err != nil {
+			t.Fatalf("GenNonces: %v", err)
+		}
+		pubNonces[i] = nonces[i].PubNonce
+	}
+	aggNonce, err := musig2.AggregateNonces(pubNonces)
+	if err != nil {
+		t.Fatalf("AggregateNonces: %v", err)
+	}
+
+	sigs := make([]musig2.PartialSignature, len(privKeys))
+	for i, priv := range privKeys {
+		sigs[i], err = musig2.Sign(&nonces[i].SecNonce, priv, aggNonce,
+			key, msg)
+		if err != nil {
+			t.Fatalf("Sign: %v", err)
+		}
+		if !musig2.VerifyPartialSignature(sigs[i], pubNonces[i],
+			pubKeys[i], aggNonce, key, msg) {
+			t.Errorf("partial signature %d does not verify", i)
+		}
+	}
+
+	// A partial signature does not verify for another signer.
+	if musig2.VerifyPartialSignature(sigs[0], pubNonces[1], pubKeys[1],
+		aggNonce, key, msg) {
+		t.Errorf("partial signature verifies for another signer")
+	}
+
+	final, err := musig2.AggregateSignatures(sigs, aggNonce, key, msg)
+	if err != nil {
+		t.Fatalf("AggregateSignatures: %v", err)
+	}
+	return final, key
+}
+
+// TestSign tests the partial signatures of a group aggregate into a valid
+// signature for the aggregate key, tweaked or not.
+func TestSign(t *testing.T) {
+	privKeys := []*btcec.PrivateKey{privKey(1), privKey(2), privKey(3)}
+	tests := []struct {
+		name string
+		opts []musig2.KeyAggOption
+	}{
+		{"untweaked", nil},
+		{"bip86", []musig2.KeyAggOption{musig2.WithTaprootTweak(nil)}},
+		{"script root", []musig2.KeyAggOption{
+			musig2.WithTaprootTweak(make([]byte, 32)),
+		}},
+	}
+	for _, test := range tests {
+		for i := byte(0); i < 4; i++ {
+			msg := [32]byte{i}
+			sig, key := signAll(t, privKeys, msg, test.opts...)
+			if !musig2.VerifySignature(key.FinalKey, msg, sig) {
+				t.Errorf("%s: signature %d does not verify",
+					test.name, i)
+			}
+			msg[31] = 1
+			if musig2.VerifySignature(key.FinalKey, msg, sig) {
+				t.Errorf("%s: signature %d verifies another "+
+					"message", test.name, i)
+			}
+		}
+	}
+}
+
+// TestSignNonceReuse tests a secret nonce can't be used twice.
+func TestSignNonceReuse(t *testing.T) {
+	priv := privKey(1)
+	key, err := musig2.AggregateKeys([]*btcec.PublicKey{priv.PubKey()})
+	if err != nil {
+		t.Fatalf("AggregateKeys: %v", err)
+	}
+	nonces, err := musig2.GenNonces(priv.PubKey())
+	if err != nil {
+		t.Fatalf("GenNonces: %v", err)
+	}
+
+	var msg [32]byte
+	_, err = musig2.Sign(&nonces.SecNonce, priv, nonces.PubNonce, key, msg)
+	if err != nil {
+		t.Fatalf("Sign: %v", err)
+	}
+	_, err = musig2.Sign(&nonces.SecNonce, priv, nonces.PubNonce, key, msg)
+	if err != musig2.ErrNonceReused {
+		t.Errorf("got %v, want %v", err, musig2.ErrNonceReused)
+	}
+}
+
+// TestSignWrongKey tests signing fails with a key outside the group or a
+// nonce of another key.
+func TestSignWrongKey(t *testing.T) {
+	priv, other := privKey(1), privKey(2)
+	key, err := musig2.AggregateKeys([]*btcec.PublicKey{priv.PubKey()})
+	if err != nil {
+		t.Fatalf("AggregateKeys: %v", err)
+	}
+
+	nonces, err := musig2.GenNonces(other.PubKey())
+	if err != nil {
+		t.Fatalf("GenNonces: %v", err)
+	}
+	_, err = musig2.Sign(&nonces.SecNonce, priv, nonces.PubNonce, key,
+		[32]byte{})
+	if err != musig2.ErrInvalidNonce {
+		t.Errorf("nonce of another key: got %v, want %v", err,
+			musig2.ErrInvalidNonce)
+	}
+
+	nonces, err = musig2.GenNonces(other.PubKey())
+	if err != nil {
+		t.Fatalf("GenNonces: %v", err)
+	}
+	_, err = musig2.Sign(&nonces.SecNonce, other, nonces.PubNonce, key,
+		[32]byte{})
+	if err != musig2.ErrInvalidPubKey {
+		t.Errorf("key outside the group: got %v, want %v", err,
+			musig2.ErrInvalidPubKey)
+	}
+}
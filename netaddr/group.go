@@ -0,0 +1,128 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package netaddr
+
+import (
+	"net"
+	"strconv"
+)
+
+// ipNet returns the network of the CIDR notation s.  It panics on an invalid
+// notation and is only used to initialize the ranges below.
+func ipNet(s string) *net.IPNet {
+	_, n, err := net.ParseCIDR(s)
+	if err != nil {
+		panic(err)
+	}
+	return n
+}
+
+var (
+	// unroutableNets are the ranges of IP addresses which are not
+	// reachable over the public internet: private, shared, link-local,
+	// documentation, benchmarking and ORCHID addresses.
+	unroutableNets = []*net.IPNet{
+		ipNet("10.0.0.0/8"),      // RFC 1918
+		ipNet("172.16.0.0/12"),   // RFC 1918
+		ipNet("192.168.0.0/16"),  // RFC 1918
+		ipNet("198.18.0.0/15"),   // RFC 2544
+		ipNet("169.254.0.0/16"),  // RFC 3927
+		ipNet("100.64.0.0/10"),   // RFC 6598
+		ipNet("192.0.2.0/24"),    // RFC 5737
+		ipNet("198.51.100.0/24"), // RFC 5737
+		ipNet("203.0.113.0/24"),  // RFC 5737
+		ipNet("0.0.0.0/8"),       // RFC 1122
+		ipNet("240.0.0.0/4"),     // RFC 1112
+		ipNet("2001:db8::/32"),   // RFC 3849
+		ipNet("fe80::/64"),       // RFC 4862
+		ipNet("fc00::/7"),        // RFC 4193
+		ipNet("2001:10::/28"),    // RFC 4843
+	}
+
+	// rfc6052Net and rfc6145Net are the ranges of IPv6 addresses
+	// translating IPv4 addresses, which are in their last four bytes.
+	rfc6052Net = ipNet("64:ff9b::/96")
+	rfc6145Net = ipNet("::ffff:0:0:0/96")
+
+	// rfc3964Net is the range of 6to4 addresses, which embed an IPv4
+	// address in their bytes 2 to 6.
+	rfc3964Net = ipNet("2002::/16")
+
+	// rfc4380Net is the range of Teredo addresses, which embed an
+	// inverted IPv4 address in their last four bytes.
+	rfc4380Net = ipNet("2001::/32")
+
+	// heNet is the range of the Hurricane Electric tunnel broker, which
+	// hands out addresses from a single /32 to many unrelated users.
+	heNet = ipNet("2001:470::/32")
+)
+
+// IsRoutable returns whether the address is reachable over the public
+// internet or the Tor network.  Loopback, unspecified, multicast, private and
+// reserved addresses are not routable.
+func (a *Addr) IsRoutable() bool {
+	if a.net == NetTorV3 {
+		return true
+	}
+	ip := net.IP(a.addr)
+	if ip.IsLoopback() || ip.IsUnspecified() || ip.IsMulticast() {
+		return false
+	}
+	for _, n := range unroutableNets {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
+
+// embeddedIPv4 returns the IPv4 address embedded in the IPv6 address ip by a
+// translation or tunneling mechanism, or nil when it embeds none.
+func embeddedIPv4(ip net.IP) net.IP {
+	switch {
+	case rfc6052Net.Contains(ip), rfc6145Net.Contains(ip):
+		return net.IPv4(ip[12], ip[13], ip[14], ip[15]).To4()
+	case rfc3964Net.Contains(ip):
+		return net.IPv4(ip[2], ip[3], ip[4], ip[5]).To4()
+	case rfc4380Net.Contains(ip):
+		return net.IPv4(ip[12]^0xff, ip[13]^0xff, ip[14]^0xff,
+			ip[15]^0xff).To4()
+	}
+	return nil
+}
+
+// GroupKey returns the group of the address, so that address managers put
+// peers likely to be run by the same operator in the same bucket:
+//
+//   - IPv4 addresses, including those embedded in IPv6 addresses by 6to4,
+//     Teredo and NAT64, are grouped by /16
+//   - IPv6 addresses are grouped by /32, or /36 for the Hurricane Electric
+//     tunnel broker
+//   - onion services are grouped by the first four bits of their key, since
+//     their addresses reveal nothing about their operators
+//   - unroutable addresses are all in the group "unroutable"
+func (a *Addr) GroupKey() string {
+	if !a.IsRoutable() {
+		return "unroutable"
+	}
+	if a.net == NetTorV3 {
+		return "tor:" + strconv.FormatUint(uint64(a.addr[0]>>4), 16)
+	}
+
+	ip := net.IP(a.addr)
+	if a.net == NetIPv6 {
+		if ip4 := embeddedIPv4(ip); ip4 != nil {
+			ip = ip4
+		}
+	}
+	if len(ip) == net.IPv4len {
+		return ip.Mask(net.CIDRMask(16, 32)).String()
+	}
+	bits := 32
+	if heNet.Contains(ip) {
+		bits = 36
+	}
+	return ip.Mask(net.CIDRMask(bits, 128)).String()
+}
@@ -0,0 +1,54 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package netaddr_test
+
+import (
+	"testing"
+
+	"github.com/zeusyf/btcutil/netaddr"
+)
+
+// TestGroupKey tests the routability and group of addresses.
+func TestGroupKey(t *testing.T) {
+	tests := []struct {
+		addr     string
+		routable bool
+		group    string
+	}{
+		{"127.0.0.1", false, "unroutable"},
+		{"0.0.0.0", false, "unroutable"},
+		{"10.1.2.3", false, "unroutable"},
+		{"192.168.0.1", false, "unroutable"},
+		{"100.64.0.1", false, "unroutable"},
+		{"169.254.1.1", false, "unroutable"},
+		{"224.0.0.1", false, "unroutable"},
+		{"::1", false, "unroutable"},
+		{"fe80::1", false, "unroutable"},
+		{"fd00::1", false, "unroutable"},
+		{"2001:db8::1", false, "unroutable"},
+		{"12.1.2.3", true, "12.1.0.0"},
+		{"::ffff:12.1.2.3", true, "12.1.0.0"},
+		{"64:ff9b::c01:203", true, "12.1.0.0"},
+		{"2002:c01:203::1", true, "12.1.0.0"},
+		{"2001:0:1234::f3fe:fdfc", true, "12.1.0.0"},
+		{"2602:100:4321::1", true, "2602:100::"},
+		{"2001:470:1f12:123::1", true, "2001:470:1000::"},
+		{testOnion, true, "tor:7"},
+	}
+	for _, test := range tests {
+		a, err := netaddr.ParseHost(test.addr, 8333)
+		if err != nil {
+			t.Fatalf("ParseHost(%q): unexpected error: %v", test.addr, err)
+		}
+		if got := a.IsRoutable(); got != test.routable {
+			t.Errorf("%s: got routable %v, want %v", test.addr, got,
+				test.routable)
+		}
+		if got := a.GroupKey(); got != test.group {
+			t.Errorf("%s: got group %q, want %q", test.addr, got,
+				test.group)
+		}
+	}
+}
@@ -0,0 +1,320 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package netaddr parses, normalizes and serializes the addresses of peers of
+// the Omega peer-to-peer network.
+//
+// An Addr is an IPv4, IPv6 or Tor v3 onion service address with a port.
+// Addresses are normalized when created, so IPv4-mapped IPv6 addresses are
+// IPv4 addresses and onion hosts are checked and lowercased, and two
+// addresses of the same peer compare equal.  Addresses are serialized in the
+// network-tagged format of BIP 155 addrv2 messages.
+//
+// GroupKey assigns addresses to the groups address managers bucket peers by,
+// so that a node does not connect to many peers an attacker controls from a
+// single network range.
+package netaddr
+
+import (
+	"bytes"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/sha3"
+)
+
+var (
+	// ErrInvalidAddress describes an error where a host is neither an IP
+	// address nor an onion service address.
+	ErrInvalidAddress = errors.New("invalid peer address")
+
+	// ErrInvalidPort describes an error where the port of an address is
+	// not a number between 1 and 65535.
+	ErrInvalidPort = errors.New("invalid peer port")
+
+	// ErrInvalidOnion describes an error where an onion service address
+	// has a bad length, checksum or version.  Tor v2 addresses are no
+	// longer supported by the Tor network and are rejected with this
+	// error.
+	ErrInvalidOnion = errors.New("invalid Tor v3 onion address")
+
+	// ErrUnsupportedNetwork describes an error where a serialized address
+	// belongs to a network other than IPv4, IPv6 and Tor v3.  The address
+	// is consumed from the reader, so callers may skip it and carry on as
+	// BIP 155 requires.
+	ErrUnsupportedNetwork = errors.New("unsupported address network")
+
+	// ErrInvalidLength describes an error where the length of a serialized
+	// address does not match its network.
+	ErrInvalidLength = errors.New("invalid address length")
+)
+
+// Network identifies the network of an address by its BIP 155 network ID.
+type Network uint8
+
+const (
+	// NetIPv4 is the network of IPv4 addresses.
+	NetIPv4 Network = 1
+
+	// NetIPv6 is the network of IPv6 addresses.
+	NetIPv6 Network = 2
+
+	// NetTorV3 is the network of Tor v3 onion services.
+	NetTorV3 Network = 4
+)
+
+// Map of networks back to their constant names for pretty printing.
+var networkStrings = map[Network]string{
+	NetIPv4:  "NetIPv4",
+	NetIPv6:  "NetIPv6",
+	NetTorV3: "NetTorV3",
+}
+
+// String returns the Network in human-readable form.
+func (n Network) String() string {
+	if s, ok := networkStrings[n]; ok {
+		return s
+	}
+	return "Unknown Network (" + strconv.Itoa(int(n)) + ")"
+}
+
+// addrLen returns the length of the addresses of the network, or zero for an
+// unsupported network.
+func (n Network) addrLen() int {
+	switch n {
+	case NetIPv4:
+		return net.IPv4len
+	case NetIPv6:
+		return net.IPv6len
+	case NetTorV3:
+		return torV3PubKeyLen
+	}
+	return 0
+}
+
+const (
+	// torV3PubKeyLen is the length of the ed25519 public key of a Tor v3
+	// onion service, which is its address.
+	torV3PubKeyLen = 32
+
+	// torV3Version is the version byte of Tor v3 onion addresses.
+	torV3Version = 3
+
+	// onionSuffix is the domain of onion service hosts.
+	onionSuffix = ".onion"
+
+	// maxSerializedAddrLen is the largest address length accepted in the
+	// addrv2 format, as set by BIP 155.
+	maxSerializedAddrLen = 512
+)
+
+// onionEncoding is the encoding of onion service hosts.
+var onionEncoding = base32.NewEncoding("abcdefghijklmnopqrstuvwxyz234567").
+	WithPadding(base32.NoPadding)
+
+// Addr is the normalized address of a peer.  Addresses must be created by one
+// of the functions of this package, and are not modified after.
+type Addr struct {
+	net  Network
+	addr []byte
+	port uint16
+}
+
+// FromIP returns the address of ip and port.  IPv4 and IPv4-mapped IPv6
+// addresses are both IPv4 addresses.
+func FromIP(ip net.IP, port uint16) (*Addr, error) {
+	if ip4 := ip.To4(); ip4 != nil {
+		return &Addr{NetIPv4, append([]byte(nil), ip4...), port}, nil
+	}
+	if len(ip) != net.IPv6len {
+		return nil, ErrInvalidAddress
+	}
+	return &Addr{NetIPv6, append([]byte(nil), ip...), port}, nil
+}
+
+// FromOnion returns the address of the Tor v3 onion service with the ed25519
+// public key pubKey and port.
+func FromOnion(pubKey []byte, port uint16) (*Addr, error) {
+	if len(pubKey) != torV3PubKeyLen {
+		return nil, ErrInvalidOnion
+	}
+	return &Addr{NetTorV3, append([]byte(nil), pubKey...), port}, nil
+}
+
+// onionChecksum returns the checksum of the onion address of pubKey.
+func onionChecksum(pubKey []byte) []byte {
+	h := sha3.New256()
+	h.Write([]byte(".onion checksum"))
+	h.Write(pubKey)
+	h.Write([]byte{torV3Version})
+	return h.Sum(nil)[:2]
+}
+
+// parseOnion returns the public key of the onion service host, which must
+// include the .onion suffix.
+func parseOnion(host string) ([]byte, error) {
+	name := strings.TrimSuffix(strings.ToLower(host), onionSuffix)
+	decoded, err := onionEncoding.DecodeString(name)
+	if err != nil || len(decoded) != torV3PubKeyLen+3 {
+		return nil, ErrInvalidOnion
+	}
+	pubKey := decoded[:torV3PubKeyLen]
+	if decoded[torV3PubKeyLen+2] != torV3Version ||
+		!bytes.Equal(decoded[torV3PubKeyLen:torV3PubKeyLen+2],
+			onionChecksum(pubKey)) {
+		return nil, ErrInvalidOnion
+	}
+	return pubKey, nil
+}
+
+// ParseHost returns the address of host and port.  The host is an IPv4
+// address, an IPv6 address, with or without brackets, or a Tor v3 onion
+// host.  Host names are not resolved.
+func ParseHost(host string, port uint16) (*Addr, error) {
+	if strings.HasSuffix(strings.ToLower(host), onionSuffix) {
+		pubKey, err := parseOnion(host)
+		if err != nil {
+			return nil, err
+		}
+		return FromOnion(pubKey, port)
+	}
+	if strings.HasPrefix(host, "[") && strings.HasSuffix(host, "]") {
+		host = host[1 : len(host)-1]
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return nil, ErrInvalidAddress
+	}
+	return FromIP(ip, port)
+}
+
+// Parse returns the address of s, a host as accepted by ParseHost optionally
+// followed by a colon and a port.  IPv6 addresses with a port must be
+// enclosed in brackets, as in "[::1]:8333".  The default port is used when s
+// has none.
+func Parse(s string, defaultPort uint16) (*Addr, error) {
+	host, portStr, err := net.SplitHostPort(s)
+	if err != nil {
+		// Without a port, s is a host, including unbracketed IPv6
+		// addresses whose colons confuse SplitHostPort.
+		return ParseHost(s, defaultPort)
+	}
+	port, err := strconv.ParseUint(portStr, 10, 16)
+	if err != nil || port == 0 {
+		return nil, ErrInvalidPort
+	}
+	return ParseHost(host, uint16(port))
+}
+
+// Network returns the network of the address.
+func (a *Addr) Network() Network {
+	return a.net
+}
+
+// Port returns the port of the address.
+func (a *Addr) Port() uint16 {
+	return a.port
+}
+
+// IP returns the IP address of IPv4 and IPv6 addresses, or nil for onion
+// services.
+func (a *Addr) IP() net.IP {
+	if a.net == NetTorV3 {
+		return nil
+	}
+	return append(net.IP(nil), a.addr...)
+}
+
+// Host returns the host of the address: the IP address or the lowercase
+// onion host.
+func (a *Addr) Host() string {
+	if a.net == NetTorV3 {
+		b := make([]byte, 0, torV3PubKeyLen+3)
+		b = append(b, a.addr...)
+		b = append(b, onionChecksum(a.addr)...)
+		b = append(b, torV3Version)
+		return onionEncoding.EncodeToString(b) + onionSuffix
+	}
+	return net.IP(a.addr).String()
+}
+
+// String returns the address as a host and port, which Parse accepts.
+func (a *Addr) String() string {
+	return net.JoinHostPort(a.Host(), strconv.Itoa(int(a.port)))
+}
+
+// Equal returns whether a and b are the same address.
+func (a *Addr) Equal(b *Addr) bool {
+	return a.net == b.net && a.port == b.port && bytes.Equal(a.addr, b.addr)
+}
+
+// SerializeSize returns the number of bytes it would take to serialize the
+// address.
+func (a *Addr) SerializeSize() int {
+	return 1 + 1 + len(a.addr) + 2
+}
+
+// AppendBytes appends the serialized address to dst and returns the extended
+// slice.  The address is serialized as in BIP 155 addrv2 messages: its
+// network ID, the compact size length and the bytes of the address, and the
+// big-endian port.
+func (a *Addr) AppendBytes(dst []byte) []byte {
+	// Supported addresses are all shorter than 0xfd bytes, so their
+	// compact size length is a single byte.
+	dst = append(dst, byte(a.net), byte(len(a.addr)))
+	dst = append(dst, a.addr...)
+	return binary.BigEndian.AppendUint16(dst, a.port)
+}
+
+// Serialize writes the serialized address to w.
+func (a *Addr) Serialize(w io.Writer) error {
+	_, err := w.Write(a.AppendBytes(make([]byte, 0, a.SerializeSize())))
+	return err
+}
+
+// Deserialize reads a serialized address from r.  Addresses of unsupported
+// networks are read in full before ErrUnsupportedNetwork is returned.
+func Deserialize(r io.Reader) (*Addr, error) {
+	var hdr [2]byte
+	if _, err := io.ReadFull(r, hdr[:]); err != nil {
+		return nil, err
+	}
+	n := Network(hdr[0])
+	length := int(hdr[1])
+	switch {
+	case length == 0xfd:
+		var b [2]byte
+		if _, err := io.ReadFull(r, b[:]); err != nil {
+			return nil, err
+		}
+		length = int(binary.LittleEndian.Uint16(b[:]))
+		if length < 0xfd || length > maxSerializedAddrLen {
+			return nil, ErrInvalidLength
+		}
+	case length > 0xfd:
+		return nil, ErrInvalidLength
+	}
+
+	buf := make([]byte, length+2)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	if n.addrLen() == 0 {
+		return nil, ErrUnsupportedNetwork
+	}
+	if length != n.addrLen() {
+		return nil, ErrInvalidLength
+	}
+	a := &Addr{n, buf[:length], binary.BigEndian.Uint16(buf[length:])}
+	if n == NetIPv6 && net.IP(a.addr).To4() != nil {
+		// BIP 155 forbids IPv4 addresses embedded in IPv6 ones.
+		return nil, ErrInvalidAddress
+	}
+	return a, nil
+}
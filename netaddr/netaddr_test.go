@@ -0,0 +1,165 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package netaddr_test
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/zeusyf/btcutil/netaddr"
+)
+
+// testOnion is a valid Tor v3 onion host.
+const testOnion = "pg6mmjiyjmcrsslvykfwnntlaru7p5svn6y2ymmju6nubxndf4pscryd.onion"
+
+// TestParse tests addresses are parsed and normalized.
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in      string
+		network netaddr.Network
+		want    string
+		err     error
+	}{
+		{"1.2.3.4", netaddr.NetIPv4, "1.2.3.4:8333", nil},
+		{"1.2.3.4:18333", netaddr.NetIPv4, "1.2.3.4:18333", nil},
+		{"[::ffff:1.2.3.4]:1", netaddr.NetIPv4, "1.2.3.4:1", nil},
+		{"2001:DB8::1", netaddr.NetIPv6, "[2001:db8::1]:8333", nil},
+		{"[2001:db8::1]", netaddr.NetIPv6, "[2001:db8::1]:8333", nil},
+		{"[2001:db8::1]:9", netaddr.NetIPv6, "[2001:db8::1]:9", nil},
+		{strings.ToUpper(testOnion) + ":7", netaddr.NetTorV3,
+			testOnion + ":7", nil},
+		{"example.com:8333", 0, "", netaddr.ErrInvalidAddress},
+		{"1.2.3.4:0", 0, "", netaddr.ErrInvalidPort},
+		{"1.2.3.4:65536", 0, "", netaddr.ErrInvalidPort},
+		{"expyuzz4wqqyqhjn.onion", 0, "", netaddr.ErrInvalidOnion},
+		{"a" + testOnion[1:], 0, "", netaddr.ErrInvalidOnion},
+	}
+	for _, test := range tests {
+		a, err := netaddr.Parse(test.in, 8333)
+		if err != test.err {
+			t.Errorf("Parse(%q): got error %v, want %v", test.in, err,
+				test.err)
+			continue
+		}
+		if err != nil {
+			continue
+		}
+		if a.Network() != test.network || a.String() != test.want {
+			t.Errorf("Parse(%q): got %v %v, want %v %v", test.in,
+				a.Network(), a, test.network, test.want)
+		}
+		b, err := netaddr.Parse(a.String(), 1)
+		if err != nil || !b.Equal(a) {
+			t.Errorf("Parse(%q): %v does not round trip", test.in, a)
+		}
+	}
+}
+
+// TestFromIP tests addresses created from IP addresses and onion keys.
+func TestFromIP(t *testing.T) {
+	a, err := netaddr.FromIP(net.ParseIP("::ffff:10.0.0.1"), 80)
+	if err != nil {
+		t.Fatalf("FromIP: unexpected error: %v", err)
+	}
+	if a.Network() != netaddr.NetIPv4 || !a.IP().Equal(net.IPv4(10, 0, 0, 1)) ||
+		a.Port() != 80 {
+		t.Errorf("FromIP: got %v", a)
+	}
+	if _, err := netaddr.FromIP(net.IP{1, 2, 3}, 80); err != netaddr.ErrInvalidAddress {
+		t.Errorf("FromIP: got error %v, want %v", err,
+			netaddr.ErrInvalidAddress)
+	}
+
+	pubKey := bytes.Repeat([]byte{0xab}, 32)
+	a, err = netaddr.FromOnion(pubKey, 9050)
+	if err != nil {
+		t.Fatalf("FromOnion: unexpected error: %v", err)
+	}
+	if a.IP() != nil {
+		t.Errorf("FromOnion: got IP %v", a.IP())
+	}
+	b, err := netaddr.ParseHost(a.Host(), 9050)
+	if err != nil || !b.Equal(a) {
+		t.Errorf("FromOnion: host %s does not round trip: %v", a.Host(),
+			err)
+	}
+	if _, err := netaddr.FromOnion(pubKey[1:], 1); err != netaddr.ErrInvalidOnion {
+		t.Errorf("FromOnion: got error %v, want %v", err,
+			netaddr.ErrInvalidOnion)
+	}
+}
+
+// TestSerialize tests addresses round trip through their addrv2 serialization
+// and invalid serializations are rejected.
+func TestSerialize(t *testing.T) {
+	for _, s := range []string{"1.2.3.4:8333", "[2001:db8::1]:1", testOnion + ":2"} {
+		a, err := netaddr.Parse(s, 0)
+		if err != nil {
+			t.Fatalf("Parse(%q): unexpected error: %v", s, err)
+		}
+		var buf bytes.Buffer
+		if err := a.Serialize(&buf); err != nil {
+			t.Fatalf("Serialize: unexpected error: %v", err)
+		}
+		if buf.Len() != a.SerializeSize() {
+			t.Errorf("%s: serialized %d bytes, want %d", s, buf.Len(),
+				a.SerializeSize())
+		}
+		b, err := netaddr.Deserialize(&buf)
+		if err != nil || !b.Equal(a) {
+			t.Errorf("%s: got %v, %v after round trip", s, b, err)
+		}
+	}
+
+	want := []byte{1, 4, 1, 2, 3, 4, 0x20, 0x8d}
+	a, _ := netaddr.Parse("1.2.3.4:8333", 0)
+	if got := a.AppendBytes(nil); !bytes.Equal(got, want) {
+		t.Errorf("AppendBytes: got %x, want %x", got, want)
+	}
+
+	tests := []struct {
+		name string
+		in   []byte
+		err  error
+	}{
+		{"length", []byte{1, 5, 1, 2, 3, 4, 5, 0, 1}, netaddr.ErrInvalidLength},
+		{"mapped", append([]byte{2, 16}, append(net.ParseIP("::ffff:1.2.3.4"),
+			0, 1)...), netaddr.ErrInvalidAddress},
+		{"i2p", append(append([]byte{5, 32}, make([]byte, 32)...), 0, 1),
+			netaddr.ErrUnsupportedNetwork},
+		{"huge", []byte{5, 0xfd, 0x01, 0x02}, netaddr.ErrInvalidLength},
+		{"short", []byte{1, 4, 1, 2}, io.ErrUnexpectedEOF},
+	}
+	for _, test := range tests {
+		r := bytes.NewReader(test.in)
+		if _, err := netaddr.Deserialize(r); err != test.err {
+			t.Errorf("%s: got error %v, want %v", test.name, err, test.err)
+		}
+		if test.err == netaddr.ErrUnsupportedNetwork && r.Len() != 0 {
+			t.Errorf("%s: %d bytes left unread", test.name, r.Len())
+		}
+	}
+}
+
+// TestNetworkStringer tests the stringized output for networks.
+func TestNetworkStringer(t *testing.T) {
+	tests := []struct {
+		in   netaddr.Network
+		want string
+	}{
+		{netaddr.NetIPv4, "NetIPv4"},
+		{netaddr.NetIPv6, "NetIPv6"},
+		{netaddr.NetTorV3, "NetTorV3"},
+		{0xff, "Unknown Network (255)"},
+	}
+	for _, test := range tests {
+		if got := test.in.String(); got != test.want {
+			t.Errorf("String: got %q, want %q", got, test.want)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package netaddr
+
+import (
+	"context"
+	"net"
+	"time"
+)
+
+// DefaultSeedInterval is the time waited between the lookups of two DNS seeds
+// unless another interval is configured.
+const DefaultSeedInterval = 500 * time.Millisecond
+
+// LookupFunc resolves the IP addresses of a host name.
+type LookupFunc func(ctx context.Context, host string) ([]net.IP, error)
+
+// lookupIP resolves host with the default resolver.
+func lookupIP(ctx context.Context, host string) ([]net.IP, error) {
+	return net.DefaultResolver.LookupIP(ctx, "ip", host)
+}
+
+// SeedOption configures ResolveSeeds.
+type SeedOption func(*seedConfig)
+
+// seedConfig holds the configuration of ResolveSeeds.
+type seedConfig struct {
+	interval time.Duration
+	lookup   LookupFunc
+}
+
+// WithSeedInterval sets the time waited between the start of two lookups, so
+// a batch of seeds does not flood the resolver.  DefaultSeedInterval is used
+// by default, and lookups are not rate limited when interval is zero.
+func WithSeedInterval(interval time.Duration) SeedOption {
+	return func(c *seedConfig) {
+		c.interval = interval
+	}
+}
+
+// WithLookupFunc sets the function resolving seeds.  The default resolver of
+// the net package is used by default.
+func WithLookupFunc(lookup LookupFunc) SeedOption {
+	return func(c *seedConfig) {
+		c.lookup = lookup
+	}
+}
+
+// ResolveSeeds looks up the DNS seeds one after the other and returns the
+// routable addresses they resolve to, with port, without duplicates and in
+// the order they were first resolved.
+//
+// A seed failing to resolve does not stop the lookup of the others; the
+// error of the first failing seed is only returned when no seed resolved to
+// an address.  The lookups stop when ctx is done, and the addresses resolved
+// until then are returned with the error of the context.
+func ResolveSeeds(ctx context.Context, seeds []string, port uint16,
+	opts ...SeedOption) ([]*Addr, error) {
+	cfg := seedConfig{
+		interval: DefaultSeedInterval,
+		lookup:   lookupIP,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	var (
+		addrs    []*Addr
+		seen     = make(map[string]struct{})
+		firstErr error
+	)
+	for i, seed := range seeds {
+		if i > 0 && cfg.interval > 0 {
+			t := time.NewTimer(cfg.interval)
+			select {
+			case <-ctx.Done():
+				t.Stop()
+				return addrs, ctx.Err()
+			case <-t.C:
+			}
+		}
+		if err := ctx.Err(); err != nil {
+			return addrs, err
+		}
+
+		ips, err := cfg.lookup(ctx, seed)
+		if err != nil {
+			if firstErr == nil {
+				firstErr = err
+			}
+			continue
+		}
+		for _, ip := range ips {
+			a, err := FromIP(ip, port)
+			if err != nil || !a.IsRoutable() {
+				continue
+			}
+			key := a.String()
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			addrs = append(addrs, a)
+		}
+	}
+	if len(addrs) == 0 && firstErr != nil {
+		return nil, firstErr
+	}
+	return addrs, nil
+}
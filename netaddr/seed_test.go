@@ -0,0 +1,74 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package netaddr_test
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/zeusyf/btcutil/netaddr"
+)
+
+// TestResolveSeeds tests the addresses of seeds are normalized, filtered and
+// deduplicated, and that lookups are spaced by the interval.
+func TestResolveSeeds(t *testing.T) {
+	errLookup := errors.New("lookup failed")
+	results := map[string][]net.IP{
+		"a.seed": {net.ParseIP("12.1.2.3"), net.ParseIP("10.0.0.1"),
+			net.ParseIP("2602:100::1")},
+		"b.seed": {net.ParseIP("::ffff:12.1.2.3"), net.ParseIP("13.1.2.3")},
+	}
+	var times []time.Time
+	lookup := func(ctx context.Context, host string) ([]net.IP, error) {
+		times = append(times, time.Now())
+		if ips, ok := results[host]; ok {
+			return ips, nil
+		}
+		return nil, errLookup
+	}
+
+	interval := 20 * time.Millisecond
+	addrs, err := netaddr.ResolveSeeds(context.Background(),
+		[]string{"a.seed", "bad.seed", "b.seed"}, 8333,
+		netaddr.WithLookupFunc(lookup), netaddr.WithSeedInterval(interval))
+	if err != nil {
+		t.Fatalf("ResolveSeeds: unexpected error: %v", err)
+	}
+	want := []string{"12.1.2.3:8333", "[2602:100::1]:8333", "13.1.2.3:8333"}
+	if len(addrs) != len(want) {
+		t.Fatalf("ResolveSeeds: got %v, want %v", addrs, want)
+	}
+	for i, a := range addrs {
+		if a.String() != want[i] {
+			t.Errorf("ResolveSeeds: address %d is %v, want %v", i, a,
+				want[i])
+		}
+	}
+	for i := 1; i < len(times); i++ {
+		if d := times[i].Sub(times[i-1]); d < interval {
+			t.Errorf("lookup %d started %v after the previous one", i, d)
+		}
+	}
+
+	_, err = netaddr.ResolveSeeds(context.Background(),
+		[]string{"bad.seed", "other.seed"}, 8333,
+		netaddr.WithLookupFunc(lookup), netaddr.WithSeedInterval(0))
+	if err != errLookup {
+		t.Errorf("ResolveSeeds: got error %v, want %v", err, errLookup)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	times = nil
+	cancel()
+	addrs, err = netaddr.ResolveSeeds(ctx, []string{"a.seed"}, 8333,
+		netaddr.WithLookupFunc(lookup))
+	if err != context.Canceled || len(addrs) != 0 || len(times) != 0 {
+		t.Errorf("ResolveSeeds: got %v, %v after %d lookups with a "+
+			"canceled context", addrs, err, len(times))
+	}
+}
@@ -0,0 +1,381 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package btcutil
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcd/chaincfg"
+)
+
+var (
+	// ErrDuplicateNet describes an error where network parameters being
+	// registered have the name of parameters already in the registry.
+	ErrDuplicateNet = errors.New("duplicate network parameters")
+
+	// ErrInvalidNet describes an error where network parameters being
+	// registered have no name.
+	ErrInvalidNet = errors.New("network parameters must have a name")
+
+	// ErrNoNet describes an error where nil network parameters are passed
+	// to a function encoding for a network.
+	ErrNoNet = errors.New("no network")
+)
+
+// NetCollision identifies the version bytes of network parameters which
+// collide with those of another network.
+type NetCollision uint8
+
+const (
+	// CollisionAddrID is a collision of the version byte of
+	// pay-to-pubkey-hash or pay-to-script-hash addresses with the version
+	// byte of any address type of another network.
+	CollisionAddrID NetCollision = iota
+
+	// CollisionHDKeyID is a collision of the version bytes of extended
+	// private or public keys with those of another network.
+	CollisionHDKeyID
+)
+
+// netCollisionStrings is a map of network collisions back to a description
+// for pretty printing.
+var netCollisionStrings = map[NetCollision]string{
+	CollisionAddrID:  "address version byte",
+	CollisionHDKeyID: "extended key version",
+}
+
+// String returns the NetCollision in human-readable form.
+func (c NetCollision) String() string {
+	if s, ok := netCollisionStrings[c]; ok {
+		return s
+	}
+	return fmt.Sprintf("Unknown NetCollision (%d)", uint8(c))
+}
+
+// NetCollisionError describes an error where network parameters being
+// registered with RegisterNetwork use version bytes already used by another
+// network, so their addresses or keys could be mistaken for those of the
+// other network.
+type NetCollisionError struct {
+	// Net is the name of the network being registered.
+	Net string
+
+	// Existing is the name of the network already using the version
+	// bytes, or "chaincfg" for networks only registered with chaincfg.
+	// It is Net when the network collides with itself, such as by using
+	// the same version byte for two address types.
+	Existing string
+
+	// Collision is the kind of version bytes colliding.
+	Collision NetCollision
+
+	// Version is the colliding version bytes.
+	Version []byte
+}
+
+// Error returns the error as a human-readable string.
+func (e *NetCollisionError) Error() string {
+	return fmt.Sprintf("network %s: %v %x collides with network %s", e.Net,
+		e.Collision, e.Version, e.Existing)
+}
+
+// NetParams describes the version bytes a network uses when encoding
+// addresses and keys.  It is all the address, WIF and extended key code needs
+// to know about a network, so code handling them need not depend on the full
+// chain parameters.
+type NetParams interface {
+	// Name returns the name of the network.
+	Name() string
+
+	// PubKeyHashAddrID returns the version byte of pay-to-pubkey-hash
+	// addresses.
+	PubKeyHashAddrID() byte
+
+	// ScriptHashAddrID returns the version byte of pay-to-script-hash
+	// addresses.
+	ScriptHashAddrID() byte
+
+	// PrivateKeyID returns the version byte of WIF encoded private keys.
+	PrivateKeyID() byte
+
+	// Bech32HRP returns the human-readable part of bech32 addresses.
+	Bech32HRP() string
+
+	// HDPrivateKeyID returns the version bytes of extended private keys.
+	HDPrivateKeyID() [4]byte
+
+	// HDPublicKeyID returns the version bytes of extended public keys.
+	HDPublicKeyID() [4]byte
+}
+
+// BasicNetParams is a NetParams holding its values in fields, for defining
+// the parameters of custom networks.
+type BasicNetParams struct {
+	Net        string
+	PubKeyHash byte
+	ScriptHash byte
+	PrivateKey byte
+	HRP        string
+	HDPrivate  [4]byte
+	HDPublic   [4]byte
+}
+
+// Ensure BasicNetParams implements the NetParams interface.
+var _ NetParams = (*BasicNetParams)(nil)
+
+// Name returns the name of the network.  Part of the NetParams interface.
+func (p *BasicNetParams) Name() string { return p.Net }
+
+// PubKeyHashAddrID returns the version byte of pay-to-pubkey-hash addresses.
+// Part of the NetParams interface.
+func (p *BasicNetParams) PubKeyHashAddrID() byte { return p.PubKeyHash }
+
+// ScriptHashAddrID returns the version byte of pay-to-script-hash addresses.
+// Part of the NetParams interface.
+func (p *BasicNetParams) ScriptHashAddrID() byte { return p.ScriptHash }
+
+// PrivateKeyID returns the version byte of WIF encoded private keys.  Part of
+// the NetParams interface.
+func (p *BasicNetParams) PrivateKeyID() byte { return p.PrivateKey }
+
+// Bech32HRP returns the human-readable part of bech32 addresses.  Part of the
+// NetParams interface.
+func (p *BasicNetParams) Bech32HRP() string { return p.HRP }
+
+// HDPrivateKeyID returns the version bytes of extended private keys.  Part of
+// the NetParams interface.
+func (p *BasicNetParams) HDPrivateKeyID() [4]byte { return p.HDPrivate }
+
+// HDPublicKeyID returns the version bytes of extended public keys.  Part of
+// the NetParams interface.
+func (p *BasicNetParams) HDPublicKeyID() [4]byte { return p.HDPublic }
+
+// ChainNetParams returns the NetParams of the passed chain parameters.
+func ChainNetParams(net *chaincfg.Params) NetParams {
+	return &BasicNetParams{
+		Net:        net.Name,
+		PubKeyHash: net.PubKeyHashAddrID,
+		ScriptHash: net.ScriptHashAddrID,
+		PrivateKey: net.PrivateKeyID,
+		HRP:        net.Bech32HRPSegwit,
+		HDPrivate:  net.HDPrivateKeyID,
+		HDPublic:   net.HDPublicKeyID,
+	}
+}
+
+// The parameters of the built-in networks, which are always registered.
+var (
+	// MainNet is the NetParams of the main network.
+	MainNet = ChainNetParams(&chaincfg.MainNetParams)
+
+	// TestNet is the NetParams of the test network.
+	TestNet = ChainNetParams(&chaincfg.TestNet3Params)
+
+	// RegTest is the NetParams of the regression test network.
+	RegTest = ChainNetParams(&chaincfg.RegressionNetParams)
+)
+
+// netRegistry holds the registered network parameters in registration order.
+// Like the burn address registry it is copy-on-write: the stored slice is
+// never modified, and writers serialize on netMtx and store a modified copy.
+var (
+	netMtx      sync.Mutex
+	netRegistry atomic.Value // []NetParams
+)
+
+// netSnapshot returns the current, immutable contents of the registry.
+func netSnapshot() []NetParams {
+	nets, _ := netRegistry.Load().([]NetParams)
+	return nets
+}
+
+func init() {
+	for _, net := range []NetParams{MainNet, TestNet, RegTest} {
+		if err := RegisterNetParams(net); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// RegisterNetParams adds the parameters of a network to the registry, so
+// DecodeAddress recognizes the addresses of the network even when they are
+// not known to chaincfg.  Networks may share version bytes, as test networks
+// commonly do, in which case lookups by version byte return the network
+// registered first.  Use RegisterNetwork to reject such networks.
+//
+// ErrInvalidNet is returned when the parameters have no name and
+// ErrDuplicateNet when parameters with the same name are already registered.
+func RegisterNetParams(net NetParams) error {
+	if net.Name() == "" {
+		return ErrInvalidNet
+	}
+
+	netMtx.Lock()
+	defer netMtx.Unlock()
+
+	return registerNetParams(net)
+}
+
+// registerNetParams adds net to the registry unless a network of the same
+// name is registered.  It must be called with netMtx held.
+func registerNetParams(net NetParams) error {
+	old := netSnapshot()
+	for _, existing := range old {
+		if existing.Name() == net.Name() {
+			return ErrDuplicateNet
+		}
+	}
+	nets := make([]NetParams, len(old), len(old)+1)
+	copy(nets, old)
+	netRegistry.Store(append(nets, net))
+	return nil
+}
+
+// RegisterNetwork is like RegisterNetParams, but additionally rejects
+// networks whose version bytes collide with those of a registered network or
+// of any network known to chaincfg, so their addresses and keys can never be
+// attributed to another network.  Sidechains and private networks should be
+// registered with it.
+//
+// A *NetCollisionError describing the first collision found is returned when
+// the pay-to-pubkey-hash and pay-to-script-hash version bytes are equal to
+// each other or to a version byte of any address type of another network, or
+// the extended key versions are equal to each other or to those of another
+// network.
+func RegisterNetwork(net NetParams) error {
+	if net.Name() == "" {
+		return ErrInvalidNet
+	}
+
+	netMtx.Lock()
+	defer netMtx.Unlock()
+
+	if err := checkCollisions(net, netSnapshot()); err != nil {
+		return err
+	}
+	return registerNetParams(net)
+}
+
+// checkCollisions returns a *NetCollisionError when the version bytes of net
+// collide with each other, with those of nets, or with the address version
+// bytes known to chaincfg.
+func checkCollisions(net NetParams, nets []NetParams) error {
+	collision := func(existing string, c NetCollision, version []byte) error {
+		return &NetCollisionError{
+			Net:       net.Name(),
+			Existing:  existing,
+			Collision: c,
+			Version:   version,
+		}
+	}
+
+	pkh, sh := net.PubKeyHashAddrID(), net.ScriptHashAddrID()
+	hdPriv, hdPub := net.HDPrivateKeyID(), net.HDPublicKeyID()
+	if pkh == sh {
+		return collision(net.Name(), CollisionAddrID, []byte{pkh})
+	}
+	if hdPriv == hdPub {
+		return collision(net.Name(), CollisionHDKeyID, hdPriv[:])
+	}
+
+	for _, existing := range nets {
+		if existing.Name() == net.Name() {
+			return ErrDuplicateNet
+		}
+	}
+	for _, existing := range nets {
+		for _, id := range []byte{pkh, sh} {
+			if id == existing.PubKeyHashAddrID() ||
+				id == existing.ScriptHashAddrID() {
+				return collision(existing.Name(), CollisionAddrID,
+					[]byte{id})
+			}
+		}
+		for _, id := range [][4]byte{hdPriv, hdPub} {
+			if id == existing.HDPrivateKeyID() ||
+				id == existing.HDPublicKeyID() {
+				return collision(existing.Name(), CollisionHDKeyID,
+					id[:])
+			}
+		}
+	}
+
+	for _, id := range []byte{pkh, sh} {
+		if chaincfg.IsPubKeyHashAddrID(id) || chaincfg.IsScriptHashAddrID(id) ||
+			chaincfg.IsContractAddrID(id) || chaincfg.IsMultiSigAddrID(id) {
+			return collision("chaincfg", CollisionAddrID, []byte{id})
+		}
+	}
+	return nil
+}
+
+// RegisteredNetParams returns the parameters of every registered network in
+// registration order, the built-in networks first.  The result is a snapshot
+// of the registry taken at the time of the call; networks registered
+// concurrently or later are not included.
+func RegisteredNetParams() []NetParams {
+	nets := netSnapshot()
+	return append(make([]NetParams, 0, len(nets)), nets...)
+}
+
+// LookupNetParams returns the registered parameters of the network with the
+// passed name.
+func LookupNetParams(name string) (NetParams, bool) {
+	for _, net := range netSnapshot() {
+		if net.Name() == name {
+			return net, true
+		}
+	}
+	return nil, false
+}
+
+// NetParamsForAddrID returns the first registered network whose
+// pay-to-pubkey-hash or pay-to-script-hash addresses use the passed version
+// byte.
+func NetParamsForAddrID(id byte) (NetParams, bool) {
+	for _, net := range netSnapshot() {
+		if net.PubKeyHashAddrID() == id || net.ScriptHashAddrID() == id {
+			return net, true
+		}
+	}
+	return nil, false
+}
+
+// NewAddressPubKeyHashForNet is like NewAddressPubKeyHash, but takes the
+// NetParams of the network.
+func NewAddressPubKeyHashForNet(pkHash []byte, net NetParams) (*AddressPubKeyHash, error) {
+	if net == nil {
+		return nil, ErrNoNet
+	}
+	return newAddressPubKeyHash(pkHash, net.PubKeyHashAddrID())
+}
+
+// NewAddressScriptHashForNet is like NewAddressScriptHashFromHash, but takes
+// the NetParams of the network.
+func NewAddressScriptHashForNet(scriptHash []byte, net NetParams) (*AddressScriptHash, error) {
+	if net == nil {
+		return nil, ErrNoNet
+	}
+	return newAddressScriptHashFromHash(scriptHash, net.ScriptHashAddrID())
+}
+
+// NewWIFForNet is like NewWIF, but takes the NetParams of the network.
+func NewWIFForNet(privKey *btcec.PrivateKey, net NetParams, compress bool) (*WIF, error) {
+	if net == nil {
+		return nil, ErrNoNet
+	}
+	return &WIF{PrivKey: privKey, CompressPubKey: compress,
+		netID: net.PrivateKeyID()}, nil
+}
+
+// IsForNetParams returns whether or not the decoded WIF structure is
+// associated with the passed network.
+func (w *WIF) IsForNetParams(net NetParams) bool {
+	return w.netID == net.PrivateKeyID()
+}
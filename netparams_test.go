@@ -0,0 +1,216 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package btcutil_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil"
+)
+
+// TestNetParams ensures the built-in networks match their chain parameters
+// and custom networks can be registered and decoded.
+func TestNetParams(t *testing.T) {
+	builtin := []struct {
+		net    btcutil.NetParams
+		params *chaincfg.Params
+	}{
+		{btcutil.MainNet, &chaincfg.MainNetParams},
+		{btcutil.TestNet, &chaincfg.TestNet3Params},
+		{btcutil.RegTest, &chaincfg.RegressionNetParams},
+	}
+	for _, b := range builtin {
+		net, p := b.net, b.params
+		if net.Name() != p.Name || net.PubKeyHashAddrID() != p.PubKeyHashAddrID ||
+			net.ScriptHashAddrID() != p.ScriptHashAddrID ||
+			net.PrivateKeyID() != p.PrivateKeyID ||
+			net.Bech32HRP() != p.Bech32HRPSegwit ||
+			net.HDPrivateKeyID() != p.HDPrivateKeyID ||
+			net.HDPublicKeyID() != p.HDPublicKeyID {
+			t.Errorf("%s: parameters do not match chaincfg", p.Name)
+		}
+		if got, ok := btcutil.LookupNetParams(p.Name); !ok || got != net {
+			t.Errorf("LookupNetParams(%s): not registered", p.Name)
+		}
+		if err := btcutil.RegisterNetParams(net); err != btcutil.ErrDuplicateNet {
+			t.Errorf("RegisterNetParams(%s): got error %v, want %v", p.Name,
+				err, btcutil.ErrDuplicateNet)
+		}
+	}
+	if err := btcutil.RegisterNetParams(&btcutil.BasicNetParams{}); err != btcutil.ErrInvalidNet {
+		t.Errorf("RegisterNetParams: got error %v, want %v", err,
+			btcutil.ErrInvalidNet)
+	}
+
+	custom := &btcutil.BasicNetParams{
+		Net:        "customnet",
+		PubKeyHash: 0xe4,
+		ScriptHash: 0xe5,
+		PrivateKey: 0xe6,
+		HRP:        "cust",
+		HDPrivate:  [4]byte{0x01, 0x02, 0x03, 0x04},
+		HDPublic:   [4]byte{0x05, 0x06, 0x07, 0x08},
+	}
+	hash := bytes.Repeat([]byte{0x42}, 20)
+	pkHash, err := btcutil.NewAddressPubKeyHashForNet(hash, custom)
+	if err != nil {
+		t.Fatalf("NewAddressPubKeyHashForNet: %v", err)
+	}
+	scriptHash, err := btcutil.NewAddressScriptHashForNet(hash, custom)
+	if err != nil {
+		t.Fatalf("NewAddressScriptHashForNet: %v", err)
+	}
+	if pkHash.Version() != custom.PubKeyHash ||
+		scriptHash.Version() != custom.ScriptHash {
+		t.Fatalf("got versions %x and %x", pkHash.Version(),
+			scriptHash.Version())
+	}
+	if chaincfg.IsPubKeyHashAddrID(custom.PubKeyHash) ||
+		chaincfg.IsScriptHashAddrID(custom.ScriptHash) {
+		t.Skip("custom version bytes are known to chaincfg")
+	}
+
+	if _, err := btcutil.DecodeAddress(pkHash.EncodeAddress(), nil); err == nil {
+		t.Fatalf("DecodeAddress: decoded address of unregistered network")
+	}
+	if err := btcutil.RegisterNetParams(custom); err != nil {
+		t.Fatalf("RegisterNetParams: %v", err)
+	}
+	nets := btcutil.RegisteredNetParams()
+	if len(nets) < 4 || nets[0] != btcutil.MainNet ||
+		nets[len(nets)-1] != btcutil.NetParams(custom) {
+		t.Errorf("RegisteredNetParams: got %v", nets)
+	}
+	if net, ok := btcutil.NetParamsForAddrID(custom.ScriptHash); !ok || net != custom {
+		t.Errorf("NetParamsForAddrID: got %v, %v", net, ok)
+	}
+	for _, addr := range []btcutil.Address{pkHash, scriptHash} {
+		decoded, err := btcutil.DecodeAddress(addr.EncodeAddress(), nil)
+		if err != nil || btcutil.AddressScriptType(decoded) != btcutil.AddressScriptType(addr) ||
+			decoded.Version() != addr.Version() ||
+			!bytes.Equal(decoded.ScriptAddress(), hash) {
+			t.Errorf("DecodeAddress(%s): got %v, %v", addr, decoded, err)
+		}
+	}
+
+	priv, _ := btcec.PrivKeyFromBytes(btcec.S256(), hash)
+	wif, err := btcutil.NewWIFForNet(priv, custom, true)
+	if err != nil {
+		t.Fatalf("NewWIFForNet: %v", err)
+	}
+	decoded, err := btcutil.DecodeWIF(wif.String())
+	if err != nil || !decoded.IsForNetParams(custom) ||
+		decoded.IsForNetParams(btcutil.MainNet) {
+		t.Errorf("DecodeWIF(%s): got %v, %v", wif, decoded, err)
+	}
+}
+
+// TestForNetNoNet ensures the constructors taking NetParams reject a nil
+// network rather than panicking.
+func TestForNetNoNet(t *testing.T) {
+	hash := bytes.Repeat([]byte{0x01}, 20)
+	if _, err := btcutil.NewAddressPubKeyHashForNet(hash, nil); err != btcutil.ErrNoNet {
+		t.Errorf("NewAddressPubKeyHashForNet: got error %v, want %v", err,
+			btcutil.ErrNoNet)
+	}
+	if _, err := btcutil.NewAddressScriptHashForNet(hash, nil); err != btcutil.ErrNoNet {
+		t.Errorf("NewAddressScriptHashForNet: got error %v, want %v", err,
+			btcutil.ErrNoNet)
+	}
+	priv, _ := btcec.PrivKeyFromBytes(btcec.S256(), hash)
+	if _, err := btcutil.NewWIFForNet(priv, nil, true); err != btcutil.ErrNoNet {
+		t.Errorf("NewWIFForNet: got error %v, want %v", err, btcutil.ErrNoNet)
+	}
+}
+
+// TestRegisterNetwork ensures networks whose version bytes collide with
+// those of another network, or with each other, are rejected.
+func TestRegisterNetwork(t *testing.T) {
+	sidechain := &btcutil.BasicNetParams{
+		Net:        "sidechain",
+		PubKeyHash: 0xd0,
+		ScriptHash: 0xd1,
+		PrivateKey: 0xd2,
+		HDPrivate:  [4]byte{0x0d, 0x00, 0x00, 0x01},
+		HDPublic:   [4]byte{0x0d, 0x00, 0x00, 0x02},
+	}
+	if chaincfg.IsPubKeyHashAddrID(sidechain.PubKeyHash) ||
+		chaincfg.IsScriptHashAddrID(sidechain.ScriptHash) {
+		t.Skip("sidechain version bytes are known to chaincfg")
+	}
+
+	colliding := func(f func(p *btcutil.BasicNetParams)) *btcutil.BasicNetParams {
+		p := *sidechain
+		f(&p)
+		return &p
+	}
+	mainNet := btcutil.MainNet
+	tests := []struct {
+		name      string
+		net       *btcutil.BasicNetParams
+		existing  string
+		collision btcutil.NetCollision
+	}{{
+		name: "same address versions",
+		net: colliding(func(p *btcutil.BasicNetParams) {
+			p.ScriptHash = p.PubKeyHash
+		}),
+		existing:  "sidechain",
+		collision: btcutil.CollisionAddrID,
+	}, {
+		name: "same extended key versions",
+		net: colliding(func(p *btcutil.BasicNetParams) {
+			p.HDPublic = p.HDPrivate
+		}),
+		existing:  "sidechain",
+		collision: btcutil.CollisionHDKeyID,
+	}, {
+		name: "main network pubkey hash",
+		net: colliding(func(p *btcutil.BasicNetParams) {
+			p.ScriptHash = mainNet.PubKeyHashAddrID()
+		}),
+		existing:  mainNet.Name(),
+		collision: btcutil.CollisionAddrID,
+	}, {
+		name: "main network extended public key",
+		net: colliding(func(p *btcutil.BasicNetParams) {
+			p.HDPrivate = mainNet.HDPublicKeyID()
+		}),
+		existing:  mainNet.Name(),
+		collision: btcutil.CollisionHDKeyID,
+	}}
+	for _, test := range tests {
+		err := btcutil.RegisterNetwork(test.net)
+		cerr, ok := err.(*btcutil.NetCollisionError)
+		if !ok {
+			t.Errorf("%s: got error %v, want collision", test.name, err)
+			continue
+		}
+		if cerr.Net != "sidechain" || cerr.Existing != test.existing ||
+			cerr.Collision != test.collision {
+			t.Errorf("%s: got error %v", test.name, err)
+		}
+	}
+
+	if err := btcutil.RegisterNetwork(sidechain); err != nil {
+		t.Fatalf("RegisterNetwork: %v", err)
+	}
+	if err := btcutil.RegisterNetwork(sidechain); err != btcutil.ErrDuplicateNet {
+		t.Errorf("RegisterNetwork: got error %v, want %v", err,
+			btcutil.ErrDuplicateNet)
+	}
+	other := colliding(func(p *btcutil.BasicNetParams) {
+		p.Net = "othersidechain"
+		p.PubKeyHash, p.ScriptHash = 0xd8, 0xd9
+	})
+	err := btcutil.RegisterNetwork(other)
+	if cerr, ok := err.(*btcutil.NetCollisionError); !ok ||
+		cerr.Existing != "sidechain" || cerr.Collision != btcutil.CollisionHDKeyID {
+		t.Errorf("RegisterNetwork: got error %v", err)
+	}
+}
@@ -0,0 +1,245 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package nulldata anchors arbitrary payloads on chain in provably unspendable
+// OP_RETURN outputs.
+//
+// A payload larger than a single null data script may carry under the relay
+// policy is split into chunks, each carried by its own output behind a small
+// header:
+//
+//	magic "od" (2) || payload id (4) || chunk index (1) || chunk count (1)
+//
+// The payload id is the start of the SHA-256 hash of the whole payload, so the
+// chunks of different payloads can be told apart and a reassembled payload is
+// checked against it.  Since nodes may relay a single null data output per
+// transaction, the chunks of a payload can be spread over several
+// transactions, and an Assembler collects them in any order.
+package nulldata
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil/scriptclass"
+	"github.com/zeusyf/omega/token"
+)
+
+const (
+	// HeaderSize is the size of the header of a chunk.
+	HeaderSize = 8
+
+	// MaxChunkData is the largest part of a payload carried by a chunk.
+	MaxChunkData = scriptclass.MaxDataCarrierSize - HeaderSize
+
+	// MaxChunks is the largest number of chunks of a payload.
+	MaxChunks = 255
+
+	// MaxPayloadSize is the largest payload which can be anchored.
+	MaxPayloadSize = MaxChunks * MaxChunkData
+)
+
+// magic marks the chunks of this package among other null data outputs.
+var magic = [2]byte{'o', 'd'}
+
+var (
+	// ErrPayloadTooLarge describes an error where a payload is larger than
+	// MaxPayloadSize.
+	ErrPayloadTooLarge = errors.New("null data payload too large")
+
+	// ErrNotChunk describes an error where a script is not a null data
+	// script carrying a chunk.
+	ErrNotChunk = errors.New("script is not a null data chunk")
+
+	// ErrInconsistentChunk describes an error where a chunk disagrees with
+	// the chunks of the same payload already collected, such as by its
+	// chunk count or by different data at the same index.
+	ErrInconsistentChunk = errors.New("inconsistent null data chunk")
+
+	// ErrCorruptPayload describes an error where a reassembled payload
+	// does not match its payload id.
+	ErrCorruptPayload = errors.New("reassembled payload does not match its id")
+
+	// ErrIncomplete describes an error where the chunks of no payload are
+	// all present.
+	ErrIncomplete = errors.New("null data payload incomplete")
+)
+
+// ID identifies a payload by the first bytes of its SHA-256 hash.
+type ID [4]byte
+
+// PayloadID returns the id of payload.
+func PayloadID(payload []byte) ID {
+	var id ID
+	sum := sha256.Sum256(payload)
+	copy(id[:], sum[:])
+	return id
+}
+
+// Chunk is a part of a payload carried by a null data script.
+type Chunk struct {
+	// ID is the id of the payload.
+	ID ID
+
+	// Index is the position of the chunk in the payload.
+	Index uint8
+
+	// Count is the number of chunks of the payload.
+	Count uint8
+
+	// Data is the part of the payload carried by the chunk.
+	Data []byte
+}
+
+// Encode splits payload into chunks and returns the null data scripts
+// carrying them in order.  ErrPayloadTooLarge is returned when the payload is
+// larger than MaxPayloadSize.
+func Encode(payload []byte) ([][]byte, error) {
+	if len(payload) > MaxPayloadSize {
+		return nil, ErrPayloadTooLarge
+	}
+	count := (len(payload) + MaxChunkData - 1) / MaxChunkData
+	if count == 0 {
+		count = 1
+	}
+
+	id := PayloadID(payload)
+	scripts := make([][]byte, count)
+	for i := range scripts {
+		end := (i + 1) * MaxChunkData
+		if end > len(payload) {
+			end = len(payload)
+		}
+		data := make([]byte, 0, HeaderSize+end-i*MaxChunkData)
+		data = append(data, magic[:]...)
+		data = append(data, id[:]...)
+		data = append(data, byte(i), byte(count))
+		data = append(data, payload[i*MaxChunkData:end]...)
+
+		script, err := scriptclass.NullDataScript(data)
+		if err != nil {
+			return nil, err
+		}
+		scripts[i] = script
+	}
+	return scripts, nil
+}
+
+// Outputs returns the outputs carrying the chunks of payload, as returned by
+// Encode, with no value.
+func Outputs(payload []byte) ([]*wire.TxOut, error) {
+	scripts, err := Encode(payload)
+	if err != nil {
+		return nil, err
+	}
+	outs := make([]*wire.TxOut, len(scripts))
+	for i, script := range scripts {
+		outs[i] = &wire.TxOut{
+			Token: token.Token{
+				TokenType: 0,
+				Value:     &token.NumeralVal{Val: 0},
+			},
+			PkScript: script,
+		}
+	}
+	return outs, nil
+}
+
+// ParseChunk returns the chunk carried by script.  ErrNotChunk is returned
+// when script is not a null data script carrying a chunk.
+func ParseChunk(script []byte) (*Chunk, error) {
+	ops, err := scriptclass.Parse(script)
+	if err != nil || len(ops) != 2 || ops[0].Op != scriptclass.OP_RETURN {
+		return nil, ErrNotChunk
+	}
+	data := ops[1].Data
+	if len(data) < HeaderSize || !bytes.Equal(data[:2], magic[:]) {
+		return nil, ErrNotChunk
+	}
+	c := &Chunk{
+		Index: data[6],
+		Count: data[7],
+		Data:  data[HeaderSize:],
+	}
+	copy(c.ID[:], data[2:6])
+	if c.Index >= c.Count {
+		return nil, ErrNotChunk
+	}
+	return c, nil
+}
+
+// Assembler reassembles payloads from their chunks, which may be added in
+// any order and from any number of transactions.  The zero value is not
+// usable; an Assembler must be created with NewAssembler.  An Assembler is
+// not safe for concurrent use.
+type Assembler struct {
+	partial map[ID][][]byte
+}
+
+// NewAssembler returns an assembler without any chunks.
+func NewAssembler() *Assembler {
+	return &Assembler{partial: make(map[ID][][]byte)}
+}
+
+// Add adds the chunk carried by script and returns its payload when the chunk
+// completes it, or nil otherwise.  A completed payload is forgotten, so its
+// chunks can be added again.  ErrNotChunk is returned for a script which does
+// not carry a chunk, ErrInconsistentChunk for a chunk disagreeing with those
+// already added, and ErrCorruptPayload when the completed payload does not
+// match its id, in which case its chunks are discarded.
+func (a *Assembler) Add(script []byte) ([]byte, error) {
+	c, err := ParseChunk(script)
+	if err != nil {
+		return nil, err
+	}
+	chunks, ok := a.partial[c.ID]
+	if !ok {
+		chunks = make([][]byte, c.Count)
+		a.partial[c.ID] = chunks
+	}
+	if len(chunks) != int(c.Count) {
+		return nil, ErrInconsistentChunk
+	}
+	if prev := chunks[c.Index]; prev != nil {
+		if !bytes.Equal(prev, c.Data) {
+			return nil, ErrInconsistentChunk
+		}
+		return nil, nil
+	}
+	chunks[c.Index] = append([]byte{}, c.Data...)
+
+	payload := make([]byte, 0, len(chunks)*MaxChunkData)
+	for _, data := range chunks {
+		if data == nil {
+			return nil, nil
+		}
+		payload = append(payload, data...)
+	}
+	delete(a.partial, c.ID)
+	if PayloadID(payload) != c.ID {
+		return nil, ErrCorruptPayload
+	}
+	return payload, nil
+}
+
+// DecodeTx returns the first payload whose chunks are all carried by the
+// outputs of tx.  Outputs not carrying chunks are ignored.  ErrIncomplete is
+// returned when tx completes no payload.
+func DecodeTx(tx *wire.MsgTx) ([]byte, error) {
+	a := NewAssembler()
+	for _, out := range tx.TxOut {
+		payload, err := a.Add(out.PkScript)
+		switch {
+		case err == ErrNotChunk:
+			continue
+		case err != nil:
+			return nil, err
+		case payload != nil:
+			return payload, nil
+		}
+	}
+	return nil, ErrIncomplete
+}
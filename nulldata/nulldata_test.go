@@ -0,0 +1,153 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package nulldata_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil/nulldata"
+	"github.com/zeusyf/btcutil/scriptclass"
+	"github.com/zeusyf/omega/token"
+)
+
+// testPayload returns a payload of n bytes.
+func testPayload(n int) []byte {
+	payload := make([]byte, n)
+	for i := range payload {
+		payload[i] = byte(i * 7)
+	}
+	return payload
+}
+
+// TestEncodeDecode ensures payloads round trip through their chunks whatever
+// their size and the order of the chunks.
+func TestEncodeDecode(t *testing.T) {
+	sizes := []int{0, 1, nulldata.MaxChunkData, nulldata.MaxChunkData + 1,
+		1000, nulldata.MaxPayloadSize}
+	for _, size := range sizes {
+		payload := testPayload(size)
+		scripts, err := nulldata.Encode(payload)
+		if err != nil {
+			t.Fatalf("%d bytes: Encode: %v", size, err)
+		}
+		wantChunks := (size + nulldata.MaxChunkData - 1) / nulldata.MaxChunkData
+		if wantChunks == 0 {
+			wantChunks = 1
+		}
+		if len(scripts) != wantChunks {
+			t.Fatalf("%d bytes: got %d chunks, want %d", size,
+				len(scripts), wantChunks)
+		}
+		for i, script := range scripts {
+			if class := scriptclass.Classify(script); class != scriptclass.NullDataTy {
+				t.Fatalf("%d bytes: chunk %d classified as %v", size,
+					i, class)
+			}
+		}
+
+		// Chunks are added in reverse order, with a duplicate and a
+		// foreign null data script.
+		a := nulldata.NewAssembler()
+		other, _ := scriptclass.NullDataScript([]byte("hello"))
+		if _, err := a.Add(other); err != nulldata.ErrNotChunk {
+			t.Fatalf("%d bytes: Add: got error %v, want %v", size, err,
+				nulldata.ErrNotChunk)
+		}
+		var got []byte
+		for i := len(scripts) - 1; i >= 0; i-- {
+			if i == len(scripts)-2 {
+				if p, err := a.Add(scripts[len(scripts)-1]); p != nil || err != nil {
+					t.Fatalf("%d bytes: Add duplicate: got %x, %v",
+						size, p, err)
+				}
+			}
+			p, err := a.Add(scripts[i])
+			if err != nil {
+				t.Fatalf("%d bytes: Add: %v", size, err)
+			}
+			if (p != nil) != (i == 0) {
+				t.Fatalf("%d bytes: Add chunk %d: completed %v", size,
+					i, p != nil)
+			}
+			got = p
+		}
+		if !bytes.Equal(got, payload) {
+			t.Fatalf("%d bytes: reassembled %x", size, got)
+		}
+	}
+
+	if _, err := nulldata.Encode(testPayload(nulldata.MaxPayloadSize + 1)); err != nulldata.ErrPayloadTooLarge {
+		t.Errorf("Encode: got error %v, want %v", err,
+			nulldata.ErrPayloadTooLarge)
+	}
+}
+
+// TestDecodeTx ensures payloads are decoded from the outputs of a transaction
+// among other outputs, and corrupt chunks are detected.
+func TestDecodeTx(t *testing.T) {
+	payload := testPayload(200)
+	outs, err := nulldata.Outputs(payload)
+	if err != nil {
+		t.Fatalf("Outputs: %v", err)
+	}
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxOut(&wire.TxOut{Token: outs[0].Token, PkScript: []byte{0x51}})
+	for _, out := range outs {
+		value, ok := out.Token.Value.(*token.NumeralVal)
+		if out.Token.TokenType != 0 || !ok || value.Val != 0 {
+			t.Fatalf("Outputs: got token %v", out.Token)
+		}
+		tx.AddTxOut(out)
+	}
+	got, err := nulldata.DecodeTx(tx)
+	if err != nil || !bytes.Equal(got, payload) {
+		t.Fatalf("DecodeTx: got %x, %v", got, err)
+	}
+
+	tx.TxOut = tx.TxOut[:len(tx.TxOut)-1]
+	if _, err := nulldata.DecodeTx(tx); err != nulldata.ErrIncomplete {
+		t.Fatalf("DecodeTx: got error %v, want %v", err,
+			nulldata.ErrIncomplete)
+	}
+
+	// Flipping a byte of the data of a chunk is caught by the payload
+	// id.
+	scripts, err := nulldata.Encode(payload)
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	last := len(scripts[0]) - 1
+	scripts[0][last] ^= 1
+	a := nulldata.NewAssembler()
+	for i, script := range scripts {
+		p, err := a.Add(script)
+		if i < len(scripts)-1 && (p != nil || err != nil) {
+			t.Fatalf("Add: got %x, %v", p, err)
+		}
+		if i == len(scripts)-1 && err != nulldata.ErrCorruptPayload {
+			t.Fatalf("Add: got error %v, want %v", err,
+				nulldata.ErrCorruptPayload)
+		}
+	}
+
+	// A chunk claiming another chunk count than its siblings is rejected.
+	c, err := nulldata.ParseChunk(scripts[1])
+	if err != nil || c.Index != 1 || int(c.Count) != len(scripts) ||
+		c.ID != nulldata.PayloadID(payload) {
+		t.Fatalf("ParseChunk: got %+v, %v", c, err)
+	}
+	a = nulldata.NewAssembler()
+	if _, err := a.Add(scripts[1]); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	forged := append([]byte{}, scripts[2]...)
+	forged[2+7] = byte(len(scripts) + 1)
+	if _, err := a.Add(forged); err != nulldata.ErrInconsistentChunk {
+		t.Fatalf("Add: got error %v, want %v", err,
+			nulldata.ErrInconsistentChunk)
+	}
+}
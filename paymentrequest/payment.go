@@ -0,0 +1,201 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package paymentrequest
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/omega/token"
+)
+
+// ErrOutputNotPaid describes an error where the transactions of a payment do
+// not include an output requested by the merchant.
+var ErrOutputNotPaid = errors.New("requested output not paid")
+
+// CheckPayment verifies that txs pay every output of the details.  Each
+// requested output must be matched by a distinct output of the base token
+// paying at least the requested amount to the requested script.
+func (d *PaymentDetails) CheckPayment(txs []*wire.MsgTx) error {
+	type outputRef struct{ tx, index int }
+	used := make(map[outputRef]struct{})
+
+next:
+	for i := range d.Outputs {
+		want := &d.Outputs[i]
+		for txIdx, tx := range txs {
+			for outIdx, txOut := range tx.TxOut {
+				ref := outputRef{txIdx, outIdx}
+				if _, ok := used[ref]; ok {
+					continue
+				}
+				value, ok := txOut.Token.Value.(*token.NumeralVal)
+				if txOut.Token.TokenType != 0 || !ok ||
+					value.Val < int64(want.Amount) ||
+					!bytes.Equal(txOut.PkScript, want.Script) {
+					continue
+				}
+				used[ref] = struct{}{}
+				continue next
+			}
+		}
+		return ErrOutputNotPaid
+	}
+	return nil
+}
+
+// Payment is the payer's answer to a request, carrying the transactions
+// paying it.
+type Payment struct {
+	// MerchantData is the merchant data of the request.
+	MerchantData []byte
+
+	// Transactions are the signed transactions paying the request.
+	Transactions []*wire.MsgTx
+
+	// RefundTo are the outputs the merchant pays refunds to.
+	RefundTo []Output
+
+	// Memo is a note to show to the merchant.
+	Memo string
+}
+
+// NewPayment returns the payment of the request with the passed details by
+// txs, after checking that they pay the request with CheckPayment.
+func NewPayment(details *PaymentDetails, txs []*wire.MsgTx, refundTo []Output, memo string) (*Payment, error) {
+	if err := details.CheckPayment(txs); err != nil {
+		return nil, err
+	}
+	return &Payment{
+		MerchantData: details.MerchantData,
+		Transactions: txs,
+		RefundTo:     refundTo,
+		Memo:         memo,
+	}, nil
+}
+
+// Marshal returns the encoding of the payment.
+func (p *Payment) Marshal() ([]byte, error) {
+	var b []byte
+	if p.MerchantData != nil {
+		b = appendBytes(b, paymentMerchantData, p.MerchantData)
+	}
+	for _, tx := range p.Transactions {
+		var buf bytes.Buffer
+		if err := tx.Serialize(&buf); err != nil {
+			return nil, err
+		}
+		b = appendBytes(b, paymentTransactions, buf.Bytes())
+	}
+	for i := range p.RefundTo {
+		out, err := appendOutput(nil, &p.RefundTo[i])
+		if err != nil {
+			return nil, err
+		}
+		b = appendBytes(b, paymentRefundTo, out)
+	}
+	if p.Memo != "" {
+		b = appendBytes(b, paymentMemo, []byte(p.Memo))
+	}
+	return b, nil
+}
+
+// Unmarshal decodes an encoded payment into p.
+func (p *Payment) Unmarshal(b []byte) error {
+	if err := checkSize(b); err != nil {
+		return err
+	}
+	*p = Payment{}
+	return readFields(b, func(f *protoField) error {
+		var data []byte
+		var err error
+		switch f.Num {
+		case paymentMerchantData:
+			p.MerchantData, err = bytesField(f)
+		case paymentTransactions:
+			data, err = bytesField(f)
+			if err != nil {
+				return err
+			}
+			tx := new(wire.MsgTx)
+			r := bytes.NewReader(data)
+			if err := tx.Deserialize(r); err != nil || r.Len() != 0 {
+				return ErrMalformedMessage
+			}
+			p.Transactions = append(p.Transactions, tx)
+		case paymentRefundTo:
+			data, err = bytesField(f)
+			if err != nil {
+				return err
+			}
+			var out Output
+			out, err = parseOutput(data)
+			p.RefundTo = append(p.RefundTo, out)
+		case paymentMemo:
+			data, err = bytesField(f)
+			p.Memo = string(data)
+		}
+		return err
+	})
+}
+
+// PaymentACK is the merchant's acknowledgment of a payment.
+type PaymentACK struct {
+	// Payment is the payment acknowledged.
+	Payment Payment
+
+	// Memo is a note to show to the payer, such as a receipt.
+	Memo string
+}
+
+// NewPaymentACK returns the acknowledgment of payment with the passed memo.
+func NewPaymentACK(payment *Payment, memo string) *PaymentACK {
+	return &PaymentACK{Payment: *payment, Memo: memo}
+}
+
+// Marshal returns the encoding of the acknowledgment.
+func (a *PaymentACK) Marshal() ([]byte, error) {
+	payment, err := a.Payment.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	b := appendBytes(nil, ackPayment, payment)
+	if a.Memo != "" {
+		b = appendBytes(b, ackMemo, []byte(a.Memo))
+	}
+	return b, nil
+}
+
+// Unmarshal decodes an encoded acknowledgment into a.
+func (a *PaymentACK) Unmarshal(b []byte) error {
+	if err := checkSize(b); err != nil {
+		return err
+	}
+	*a = PaymentACK{}
+	var havePayment bool
+	err := readFields(b, func(f *protoField) error {
+		data, err := bytesField(f)
+		switch f.Num {
+		case ackPayment:
+			if err != nil {
+				return err
+			}
+			havePayment = true
+			return a.Payment.Unmarshal(data)
+		case ackMemo:
+			a.Memo = string(data)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	if !havePayment {
+		return ErrMalformedMessage
+	}
+	return nil
+}
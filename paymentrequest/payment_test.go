@@ -0,0 +1,116 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package paymentrequest_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil/paymentrequest"
+	"github.com/zeusyf/omega/token"
+)
+
+// testTx returns a transaction paying the passed base token values to the
+// passed scripts.
+func testTx(values []int64, scripts [][]byte) *wire.MsgTx {
+	tx := wire.NewMsgTx(1)
+	tx.AddTxIn(&wire.TxIn{})
+	for i, value := range values {
+		tx.AddTxOut(&wire.TxOut{
+			Token: token.Token{
+				TokenType: 0,
+				Value:     &token.NumeralVal{Val: value},
+			},
+			PkScript: scripts[i],
+		})
+	}
+	return tx
+}
+
+// TestCheckPayment ensures payments must pay every requested output.
+func TestCheckPayment(t *testing.T) {
+	details := testDetails()
+	script0, script1 := details.Outputs[0].Script, details.Outputs[1].Script
+	amount0 := int64(details.Outputs[0].Amount)
+
+	tests := []struct {
+		name string
+		txs  []*wire.MsgTx
+		err  error
+	}{
+		{"exact", []*wire.MsgTx{testTx([]int64{amount0, 1},
+			[][]byte{script0, script1})}, nil},
+		{"separate transactions", []*wire.MsgTx{
+			testTx([]int64{1}, [][]byte{script1}),
+			testTx([]int64{amount0 + 1}, [][]byte{script0}),
+		}, nil},
+		{"underpaid", []*wire.MsgTx{testTx([]int64{amount0 - 1, 1},
+			[][]byte{script0, script1})}, paymentrequest.ErrOutputNotPaid},
+		{"missing output", []*wire.MsgTx{testTx([]int64{amount0},
+			[][]byte{script0})}, paymentrequest.ErrOutputNotPaid},
+	}
+	for _, test := range tests {
+		if err := details.CheckPayment(test.txs); err != test.err {
+			t.Errorf("%s: got error %v, want %v", test.name, err,
+				test.err)
+		}
+	}
+
+	// An output may not satisfy two requested outputs.
+	details.Outputs[1].Script = script0
+	txs := []*wire.MsgTx{testTx([]int64{amount0}, [][]byte{script0})}
+	if err := details.CheckPayment(txs); err != paymentrequest.ErrOutputNotPaid {
+		t.Errorf("output reused: got error %v, want %v", err,
+			paymentrequest.ErrOutputNotPaid)
+	}
+}
+
+// TestPaymentACK ensures payments and their acknowledgments survive
+// serialization.
+func TestPaymentACK(t *testing.T) {
+	details := testDetails()
+	txs := []*wire.MsgTx{testTx([]int64{int64(details.Outputs[0].Amount), 1},
+		[][]byte{details.Outputs[0].Script, details.Outputs[1].Script})}
+	refundTo := []paymentrequest.Output{{Amount: 0, Script: []byte{0x54}}}
+
+	payment, err := paymentrequest.NewPayment(details, txs, refundTo, "thanks")
+	if err != nil {
+		t.Fatalf("NewPayment: unexpected error: %v", err)
+	}
+	if !bytes.Equal(payment.MerchantData, details.MerchantData) {
+		t.Errorf("NewPayment: got merchant data %x, want %x",
+			payment.MerchantData, details.MerchantData)
+	}
+	if _, err := paymentrequest.NewPayment(details, nil, nil, ""); err != paymentrequest.ErrOutputNotPaid {
+		t.Errorf("NewPayment unpaid: got error %v, want %v", err,
+			paymentrequest.ErrOutputNotPaid)
+	}
+
+	ack := paymentrequest.NewPaymentACK(payment, "receipt 42")
+	b, err := ack.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal: unexpected error: %v", err)
+	}
+	var decoded paymentrequest.PaymentACK
+	if err := decoded.Unmarshal(b); err != nil {
+		t.Fatalf("Unmarshal: unexpected error: %v", err)
+	}
+	if decoded.Memo != ack.Memo || decoded.Payment.Memo != payment.Memo ||
+		!bytes.Equal(decoded.Payment.MerchantData, payment.MerchantData) ||
+		!reflect.DeepEqual(decoded.Payment.RefundTo, payment.RefundTo) {
+		t.Errorf("Unmarshal: got %+v, want %+v", decoded, ack)
+	}
+	if len(decoded.Payment.Transactions) != 1 ||
+		decoded.Payment.Transactions[0].TxHash() != txs[0].TxHash() {
+		t.Errorf("Unmarshal: transactions mismatch")
+	}
+
+	if err := decoded.Unmarshal([]byte{0x12, 0x00}); err != paymentrequest.ErrMalformedMessage {
+		t.Errorf("Unmarshal without payment: got error %v, want %v", err,
+			paymentrequest.ErrMalformedMessage)
+	}
+}
@@ -0,0 +1,398 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package paymentrequest implements the payment protocol of BIP0070 for OMC,
+// letting point-of-sale systems request payments from wallets.
+//
+// A merchant describes the outputs it wants paid in PaymentDetails, wraps
+// them in a PaymentRequest and optionally signs the request with the key of
+// an X.509 certificate naming the merchant.  The wallet verifies the request
+// against its trusted roots with Verify, checks that it has not expired, and
+// answers with a Payment holding the paying transactions, which the merchant
+// acknowledges with a PaymentACK.
+//
+// The messages are encoded as the protocol buffers of BIP0070, so they are
+// interchangeable with other implementations of the protocol.  Amounts are in
+// the base token.
+package paymentrequest
+
+import (
+	"errors"
+	"time"
+
+	"github.com/zeusyf/btcutil"
+)
+
+const (
+	// RequestMIMEType is the MIME type of a serialized PaymentRequest.
+	RequestMIMEType = "application/omc-paymentrequest"
+
+	// PaymentMIMEType is the MIME type of a serialized Payment.
+	PaymentMIMEType = "application/omc-payment"
+
+	// PaymentACKMIMEType is the MIME type of a serialized PaymentACK.
+	PaymentACKMIMEType = "application/omc-paymentack"
+
+	// MaxMessageSize is the largest serialized message accepted when
+	// parsing, as recommended by BIP0070.
+	MaxMessageSize = 50000
+
+	// DetailsVersion is the version of PaymentDetails implemented by this
+	// package.
+	DetailsVersion = 1
+
+	// NetworkMain and NetworkTest are the networks named in
+	// PaymentDetails.
+	NetworkMain = "main"
+	NetworkTest = "test"
+)
+
+var (
+	// ErrMessageTooLarge describes an error where a serialized message is
+	// larger than MaxMessageSize.
+	ErrMessageTooLarge = errors.New("payment protocol message too large")
+
+	// ErrInvalidAmount describes an error where an output requests a
+	// negative amount or more than btcutil.MaxHao.
+	ErrInvalidAmount = errors.New("invalid output amount")
+
+	// ErrUnsupportedVersion describes an error where a request uses a
+	// version of PaymentDetails this package does not understand.
+	ErrUnsupportedVersion = errors.New("unsupported payment details " +
+		"version")
+
+	// ErrExpired describes an error where a payment request is past its
+	// expiration time.
+	ErrExpired = errors.New("payment request expired")
+)
+
+// These are the field numbers of the messages.
+const (
+	outputAmount = 1
+	outputScript = 2
+
+	detailsNetwork      = 1
+	detailsOutputs      = 2
+	detailsTime         = 3
+	detailsExpires      = 4
+	detailsMemo         = 5
+	detailsPaymentURL   = 6
+	detailsMerchantData = 7
+
+	requestDetailsVersion    = 1
+	requestPKIType           = 2
+	requestPKIData           = 3
+	requestSerializedDetails = 4
+	requestSignature         = 5
+
+	certificatesCertificate = 1
+
+	paymentMerchantData = 1
+	paymentTransactions = 2
+	paymentRefundTo     = 3
+	paymentMemo         = 4
+
+	ackPayment = 1
+	ackMemo    = 2
+)
+
+// checkSize returns ErrMessageTooLarge for messages larger than
+// MaxMessageSize.
+func checkSize(b []byte) error {
+	if len(b) > MaxMessageSize {
+		return ErrMessageTooLarge
+	}
+	return nil
+}
+
+// unixTime returns t as seconds since the Unix epoch, or zero for the zero
+// time.
+func unixTime(t time.Time) uint64 {
+	if t.IsZero() || t.Unix() < 0 {
+		return 0
+	}
+	return uint64(t.Unix())
+}
+
+// fromUnix returns the time of the passed seconds since the Unix epoch, or
+// the zero time for zero.
+func fromUnix(secs uint64) time.Time {
+	if secs == 0 || secs > 1<<62 {
+		return time.Time{}
+	}
+	return time.Unix(int64(secs), 0)
+}
+
+// Output is an output a payment must include, or an output a refund may be
+// paid to.
+type Output struct {
+	// Amount is the amount of the base token to pay.  Zero lets the payer
+	// choose the amount.
+	Amount btcutil.Amount
+
+	// Script is the public key script to pay to.
+	Script []byte
+}
+
+// appendOutput appends the encoding of o.
+func appendOutput(b []byte, o *Output) ([]byte, error) {
+	if o.Amount < 0 {
+		return nil, ErrInvalidAmount
+	}
+	var m []byte
+	m = appendUint(m, outputAmount, uint64(o.Amount))
+	m = appendBytes(m, outputScript, o.Script)
+	return append(b, m...), nil
+}
+
+// parseOutput decodes an encoded Output.
+func parseOutput(b []byte) (Output, error) {
+	var o Output
+	var haveScript bool
+	err := readFields(b, func(f *protoField) error {
+		var err error
+		switch f.Num {
+		case outputAmount:
+			var amount uint64
+			amount, err = uintField(f)
+			if amount > uint64(btcutil.MaxHao) {
+				return ErrInvalidAmount
+			}
+			o.Amount = btcutil.Amount(amount)
+		case outputScript:
+			o.Script, err = bytesField(f)
+			haveScript = true
+		}
+		return err
+	})
+	if err != nil {
+		return Output{}, err
+	}
+	if !haveScript {
+		return Output{}, ErrMalformedMessage
+	}
+	return o, nil
+}
+
+// PaymentDetails describes the payment a merchant requests.
+type PaymentDetails struct {
+	// Network is the network the payment is made on, NetworkMain or
+	// NetworkTest.  Empty means NetworkMain.
+	Network string
+
+	// Outputs are the outputs the payment must include.
+	Outputs []Output
+
+	// Time is when the request was created.
+	Time time.Time
+
+	// Expires is when the request expires, or the zero time if it does
+	// not.
+	Expires time.Time
+
+	// Memo is a note to show to the payer.
+	Memo string
+
+	// PaymentURL is where the payer sends the Payment, if anywhere.
+	PaymentURL string
+
+	// MerchantData is opaque data the payer returns in its Payment.
+	MerchantData []byte
+}
+
+// Marshal returns the encoding of the details.
+func (d *PaymentDetails) Marshal() ([]byte, error) {
+	var b []byte
+	if d.Network != "" {
+		b = appendBytes(b, detailsNetwork, []byte(d.Network))
+	}
+	for i := range d.Outputs {
+		out, err := appendOutput(nil, &d.Outputs[i])
+		if err != nil {
+			return nil, err
+		}
+		b = appendBytes(b, detailsOutputs, out)
+	}
+	b = appendUint(b, detailsTime, unixTime(d.Time))
+	if !d.Expires.IsZero() {
+		b = appendUint(b, detailsExpires, unixTime(d.Expires))
+	}
+	if d.Memo != "" {
+		b = appendBytes(b, detailsMemo, []byte(d.Memo))
+	}
+	if d.PaymentURL != "" {
+		b = appendBytes(b, detailsPaymentURL, []byte(d.PaymentURL))
+	}
+	if d.MerchantData != nil {
+		b = appendBytes(b, detailsMerchantData, d.MerchantData)
+	}
+	return b, nil
+}
+
+// Unmarshal decodes encoded details into d.
+func (d *PaymentDetails) Unmarshal(b []byte) error {
+	if err := checkSize(b); err != nil {
+		return err
+	}
+	*d = PaymentDetails{}
+	var haveTime bool
+	err := readFields(b, func(f *protoField) error {
+		var data []byte
+		var val uint64
+		var err error
+		switch f.Num {
+		case detailsNetwork:
+			data, err = bytesField(f)
+			d.Network = string(data)
+		case detailsOutputs:
+			data, err = bytesField(f)
+			if err != nil {
+				return err
+			}
+			var out Output
+			out, err = parseOutput(data)
+			d.Outputs = append(d.Outputs, out)
+		case detailsTime:
+			val, err = uintField(f)
+			d.Time = fromUnix(val)
+			haveTime = true
+		case detailsExpires:
+			val, err = uintField(f)
+			d.Expires = fromUnix(val)
+		case detailsMemo:
+			data, err = bytesField(f)
+			d.Memo = string(data)
+		case detailsPaymentURL:
+			data, err = bytesField(f)
+			d.PaymentURL = string(data)
+		case detailsMerchantData:
+			d.MerchantData, err = bytesField(f)
+		}
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if !haveTime {
+		return ErrMalformedMessage
+	}
+	return nil
+}
+
+// CheckExpiry returns ErrExpired if the details have expired at the passed
+// time.
+func (d *PaymentDetails) CheckExpiry(now time.Time) error {
+	if !d.Expires.IsZero() && !now.Before(d.Expires) {
+		return ErrExpired
+	}
+	return nil
+}
+
+// PaymentRequest is a request for payment, optionally signed by the merchant.
+// Requests are created with NewPaymentRequest and decoded with
+// ParsePaymentRequest.
+type PaymentRequest struct {
+	// DetailsVersion is the version of the serialized details.
+	DetailsVersion uint32
+
+	// PKIType names how the request is signed: PKITypeNone,
+	// PKITypeX509SHA256 or PKITypeX509SHA1.
+	PKIType string
+
+	// PKIData holds the certificate chain of the signer.
+	PKIData []byte
+
+	// SerializedDetails is the encoding of the PaymentDetails, which the
+	// signature covers.
+	SerializedDetails []byte
+
+	// Signature is the signature of the request by the certificate key.
+	Signature []byte
+
+	// raw is the encoding of the request as parsed or signed, which
+	// the signature is checked against.
+	raw []byte
+}
+
+// NewPaymentRequest returns an unsigned request for the passed details.
+func NewPaymentRequest(details *PaymentDetails) (*PaymentRequest, error) {
+	serialized, err := details.Marshal()
+	if err != nil {
+		return nil, err
+	}
+	return &PaymentRequest{
+		DetailsVersion:    DetailsVersion,
+		PKIType:           PKITypeNone,
+		SerializedDetails: serialized,
+	}, nil
+}
+
+// ParsePaymentRequest decodes a serialized request.
+func ParsePaymentRequest(b []byte) (*PaymentRequest, error) {
+	if err := checkSize(b); err != nil {
+		return nil, err
+	}
+	r := &PaymentRequest{
+		DetailsVersion: DetailsVersion,
+		PKIType:        PKITypeNone,
+	}
+	var haveDetails bool
+	err := readFields(b, func(f *protoField) error {
+		var data []byte
+		var val uint64
+		var err error
+		switch f.Num {
+		case requestDetailsVersion:
+			val, err = uintField(f)
+			r.DetailsVersion = uint32(val)
+		case requestPKIType:
+			data, err = bytesField(f)
+			r.PKIType = string(data)
+		case requestPKIData:
+			r.PKIData, err = bytesField(f)
+		case requestSerializedDetails:
+			r.SerializedDetails, err = bytesField(f)
+			haveDetails = true
+		case requestSignature:
+			r.Signature, err = bytesField(f)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !haveDetails {
+		return nil, ErrMalformedMessage
+	}
+	r.raw = append([]byte{}, b...)
+	return r, nil
+}
+
+// Marshal returns the encoding of the request.
+func (r *PaymentRequest) Marshal() ([]byte, error) {
+	var b []byte
+	b = appendUint(b, requestDetailsVersion, uint64(r.DetailsVersion))
+	b = appendBytes(b, requestPKIType, []byte(r.PKIType))
+	if r.PKIData != nil {
+		b = appendBytes(b, requestPKIData, r.PKIData)
+	}
+	b = appendBytes(b, requestSerializedDetails, r.SerializedDetails)
+	if r.Signature != nil {
+		b = appendBytes(b, requestSignature, r.Signature)
+	}
+	return b, nil
+}
+
+// Details decodes the details of the request.  It does not verify the
+// signature of the request; see Verify.
+func (r *PaymentRequest) Details() (*PaymentDetails, error) {
+	if r.DetailsVersion != DetailsVersion {
+		return nil, ErrUnsupportedVersion
+	}
+	details := new(PaymentDetails)
+	if err := details.Unmarshal(r.SerializedDetails); err != nil {
+		return nil, err
+	}
+	return details, nil
+}
@@ -0,0 +1,155 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package paymentrequest_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/zeusyf/btcutil/paymentrequest"
+)
+
+// testDetails returns details requesting two outputs.
+func testDetails() *paymentrequest.PaymentDetails {
+	return &paymentrequest.PaymentDetails{
+		Network: paymentrequest.NetworkTest,
+		Outputs: []paymentrequest.Output{
+			{Amount: 100000, Script: []byte{0x51}},
+			{Amount: 0, Script: []byte{0x52, 0x53}},
+		},
+		Time:         time.Unix(1600000000, 0),
+		Expires:      time.Unix(1600000600, 0),
+		Memo:         "order 42",
+		PaymentURL:   "https://example.com/pay",
+		MerchantData: []byte{0xde, 0xad},
+	}
+}
+
+// TestDetailsEncoding ensures details encode as the protocol buffer of
+// BIP0070 and decode back.
+func TestDetailsEncoding(t *testing.T) {
+	details := &paymentrequest.PaymentDetails{
+		Outputs: []paymentrequest.Output{{Amount: 1, Script: []byte{0x51}}},
+		Time:    time.Unix(1, 0),
+	}
+	b, err := details.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal: unexpected error: %v", err)
+	}
+	// outputs { amount: 1 script: "\x51" } time: 1
+	want := "1205080112015118" + "01"
+	if got := hex.EncodeToString(b); got != want {
+		t.Errorf("Marshal: got %s, want %s", got, want)
+	}
+
+	details = testDetails()
+	b, err = details.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal: unexpected error: %v", err)
+	}
+	var decoded paymentrequest.PaymentDetails
+	if err := decoded.Unmarshal(b); err != nil {
+		t.Fatalf("Unmarshal: unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(&decoded, details) {
+		t.Errorf("Unmarshal: got %+v, want %+v", decoded, details)
+	}
+
+	// Unknown fields are skipped, while missing required fields and
+	// truncated messages are rejected.
+	tests := []struct {
+		name string
+		b    []byte
+		err  error
+	}{
+		{"unknown field", append(b[:len(b):len(b)], 0x4d, 1, 2, 3, 4), nil},
+		{"missing time", []byte{0x0a, 0x01, 'x'}, paymentrequest.ErrMalformedMessage},
+		{"truncated", b[:len(b)-1], paymentrequest.ErrMalformedMessage},
+		{"wrong wire type", []byte{0x18, 0x01, 0x28, 0x01}, paymentrequest.ErrMalformedMessage},
+		{"too large", make([]byte, paymentrequest.MaxMessageSize+1), paymentrequest.ErrMessageTooLarge},
+	}
+	for _, test := range tests {
+		if err := decoded.Unmarshal(test.b); err != test.err {
+			t.Errorf("Unmarshal(%s): got error %v, want %v", test.name,
+				err, test.err)
+		}
+	}
+
+	details.Outputs[0].Amount = -1
+	if _, err := details.Marshal(); err != paymentrequest.ErrInvalidAmount {
+		t.Errorf("Marshal negative amount: got error %v, want %v", err,
+			paymentrequest.ErrInvalidAmount)
+	}
+}
+
+// TestPaymentRequest ensures unsigned requests survive serialization and
+// carry their details.
+func TestPaymentRequest(t *testing.T) {
+	details := testDetails()
+	req, err := paymentrequest.NewPaymentRequest(details)
+	if err != nil {
+		t.Fatalf("NewPaymentRequest: unexpected error: %v", err)
+	}
+	b, err := req.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal: unexpected error: %v", err)
+	}
+	parsed, err := paymentrequest.ParsePaymentRequest(b)
+	if err != nil {
+		t.Fatalf("ParsePaymentRequest: unexpected error: %v", err)
+	}
+	if parsed.PKIType != paymentrequest.PKITypeNone ||
+		!bytes.Equal(parsed.SerializedDetails, req.SerializedDetails) {
+		t.Errorf("ParsePaymentRequest: got %+v", parsed)
+	}
+	got, err := parsed.Details()
+	if err != nil {
+		t.Fatalf("Details: unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, details) {
+		t.Errorf("Details: got %+v, want %+v", got, details)
+	}
+
+	parsed.DetailsVersion = 2
+	if _, err := parsed.Details(); err != paymentrequest.ErrUnsupportedVersion {
+		t.Errorf("Details: got error %v, want %v", err,
+			paymentrequest.ErrUnsupportedVersion)
+	}
+
+	_, err = paymentrequest.ParsePaymentRequest([]byte{0x08, 0x01})
+	if err != paymentrequest.ErrMalformedMessage {
+		t.Errorf("ParsePaymentRequest without details: got error %v, "+
+			"want %v", err, paymentrequest.ErrMalformedMessage)
+	}
+}
+
+// TestCheckExpiry ensures requests expire at their expiration time, and
+// requests without one never do.
+func TestCheckExpiry(t *testing.T) {
+	details := testDetails()
+	tests := []struct {
+		now time.Time
+		err error
+	}{
+		{details.Expires.Add(-time.Second), nil},
+		{details.Expires, paymentrequest.ErrExpired},
+		{details.Expires.Add(time.Hour), paymentrequest.ErrExpired},
+	}
+	for _, test := range tests {
+		if err := details.CheckExpiry(test.now); err != test.err {
+			t.Errorf("CheckExpiry(%v): got error %v, want %v", test.now,
+				err, test.err)
+		}
+	}
+
+	details.Expires = time.Time{}
+	if err := details.CheckExpiry(time.Now()); err != nil {
+		t.Errorf("CheckExpiry without expiration: unexpected error: %v",
+			err)
+	}
+}
@@ -0,0 +1,209 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package paymentrequest
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/rsa"
+	_ "crypto/sha1" // Register the hash functions of the PKI types.
+	_ "crypto/sha256"
+	"crypto/x509"
+	"errors"
+)
+
+const (
+	// PKITypeNone marks an unsigned request.
+	PKITypeNone = "none"
+
+	// PKITypeX509SHA256 marks a request signed with the key of an X.509
+	// certificate over its SHA-256 hash.
+	PKITypeX509SHA256 = "x509+sha256"
+
+	// PKITypeX509SHA1 marks a request signed with the key of an X.509
+	// certificate over its SHA-1 hash.  It is only accepted for
+	// verification.
+	PKITypeX509SHA1 = "x509+sha1"
+)
+
+var (
+	// ErrUnknownPKIType describes an error where a request is signed with
+	// a PKI type other than those this package supports.
+	ErrUnknownPKIType = errors.New("unknown PKI type")
+
+	// ErrNoCertificates describes an error where a signed request carries
+	// no certificates, or signing is requested without any.
+	ErrNoCertificates = errors.New("no merchant certificates")
+
+	// ErrUnsupportedKey describes an error where the certificate key is
+	// neither an RSA nor an ECDSA key.
+	ErrUnsupportedKey = errors.New("unsupported certificate key type")
+
+	// ErrInvalidSignature describes an error where the signature of a
+	// request does not match the certificate key.
+	ErrInvalidSignature = errors.New("invalid payment request signature")
+)
+
+// marshalCertificates returns the encoded X509Certificates message holding
+// the passed DER encoded certificates, leaf first.
+func marshalCertificates(certs []*x509.Certificate) []byte {
+	var b []byte
+	for _, cert := range certs {
+		b = appendBytes(b, certificatesCertificate, cert.Raw)
+	}
+	return b
+}
+
+// parseCertificates decodes the certificates of an X509Certificates message.
+func parseCertificates(b []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	err := readFields(b, func(f *protoField) error {
+		if f.Num != certificatesCertificate {
+			return nil
+		}
+		der, err := bytesField(f)
+		if err != nil {
+			return err
+		}
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			return err
+		}
+		certs = append(certs, cert)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(certs) == 0 {
+		return nil, ErrNoCertificates
+	}
+	return certs, nil
+}
+
+// signedBytes returns the bytes the signature of the request covers: its
+// encoding with an empty signature.  Requests are encoded as they were parsed
+// or signed, so fields unknown to this package and fields encoded differently
+// by other implementations are covered as they were signed.  Requests whose
+// fields were changed since are encoded anew.
+func (r *PaymentRequest) signedBytes() []byte {
+	if r.raw != nil {
+		parsed, err := ParsePaymentRequest(r.raw)
+		if err == nil && parsed.DetailsVersion == r.DetailsVersion &&
+			parsed.PKIType == r.PKIType &&
+			bytes.Equal(parsed.PKIData, r.PKIData) &&
+			bytes.Equal(parsed.SerializedDetails, r.SerializedDetails) {
+
+			var b []byte
+			readFields(r.raw, func(f *protoField) error {
+				if f.Num == requestSignature {
+					b = appendBytes(b, requestSignature, nil)
+				} else {
+					b = append(b, f.Raw...)
+				}
+				return nil
+			})
+			return b
+		}
+	}
+
+	unsigned := *r
+	unsigned.Signature = []byte{}
+	b, _ := unsigned.Marshal()
+	return b
+}
+
+// hashFor returns the hash function of a PKI type.
+func hashFor(pkiType string) (crypto.Hash, error) {
+	switch pkiType {
+	case PKITypeX509SHA256:
+		return crypto.SHA256, nil
+	case PKITypeX509SHA1:
+		return crypto.SHA1, nil
+	}
+	return 0, ErrUnknownPKIType
+}
+
+// Sign signs the request as PKITypeX509SHA256 with key, the private key of
+// the first certificate of chain.  The remaining certificates of chain are
+// the intermediates leading to a root trusted by the payer, and should not
+// include the root itself.
+func (r *PaymentRequest) Sign(key crypto.Signer, chain []*x509.Certificate) error {
+	if len(chain) == 0 {
+		return ErrNoCertificates
+	}
+	switch key.Public().(type) {
+	case *rsa.PublicKey, *ecdsa.PublicKey:
+	default:
+		return ErrUnsupportedKey
+	}
+
+	r.PKIType = PKITypeX509SHA256
+	r.PKIData = marshalCertificates(chain)
+	r.Signature = []byte{}
+	r.raw = nil
+	raw, _ := r.Marshal()
+
+	h := crypto.SHA256.New()
+	h.Write(raw)
+	sig, err := key.Sign(rand.Reader, h.Sum(nil), crypto.SHA256)
+	if err != nil {
+		return err
+	}
+	r.Signature = sig
+	r.raw, _ = r.Marshal()
+	return nil
+}
+
+// Verify verifies the certificate chain of a signed request with opts and the
+// signature of the request with the key of its leaf certificate, which is
+// returned.  The intermediates of opts are taken from the request, and its
+// roots should hold the certificate authorities trusted by the payer.
+// Callers display the subject of the leaf certificate as the merchant.
+//
+// Unsigned requests return a nil certificate without error, so callers must
+// decide whether to accept them.
+func (r *PaymentRequest) Verify(opts x509.VerifyOptions) (*x509.Certificate, error) {
+	if r.PKIType == PKITypeNone {
+		return nil, nil
+	}
+	hash, err := hashFor(r.PKIType)
+	if err != nil {
+		return nil, err
+	}
+	certs, err := parseCertificates(r.PKIData)
+	if err != nil {
+		return nil, err
+	}
+
+	leaf := certs[0]
+	opts.Intermediates = x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		opts.Intermediates.AddCert(cert)
+	}
+	if _, err := leaf.Verify(opts); err != nil {
+		return nil, err
+	}
+
+	h := hash.New()
+	h.Write(r.signedBytes())
+	digest := h.Sum(nil)
+	switch pub := leaf.PublicKey.(type) {
+	case *rsa.PublicKey:
+		err = rsa.VerifyPKCS1v15(pub, hash, digest, r.Signature)
+	case *ecdsa.PublicKey:
+		if !ecdsa.VerifyASN1(pub, digest, r.Signature) {
+			err = ErrInvalidSignature
+		}
+	default:
+		return nil, ErrUnsupportedKey
+	}
+	if err != nil {
+		return nil, ErrInvalidSignature
+	}
+	return leaf, nil
+}
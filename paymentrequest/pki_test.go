@@ -0,0 +1,144 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package paymentrequest_test
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/zeusyf/btcutil/paymentrequest"
+)
+
+// testCertificate returns a certificate for the public key of key issued by
+// parent with parentKey, or a self-signed root if parent is nil.
+func testCertificate(t *testing.T, name string, key crypto.Signer, parent *x509.Certificate, parentKey crypto.Signer) *x509.Certificate {
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: name},
+		NotBefore:    time.Unix(1500000000, 0),
+		NotAfter:     time.Unix(1700000000, 0),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:     []string{name},
+	}
+	if parent == nil {
+		template.IsCA = true
+		template.BasicConstraintsValid = true
+		template.KeyUsage |= x509.KeyUsageCertSign
+		parent, parentKey = template, key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parent,
+		key.Public(), parentKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cert
+}
+
+// TestSignVerify ensures requests signed by a merchant verify against the
+// roots trusted by the payer, and do not once tampered with.
+func TestSignVerify(t *testing.T) {
+	rootKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root := testCertificate(t, "root", rootKey, nil, nil)
+	roots := x509.NewCertPool()
+	roots.AddCert(root)
+	opts := x509.VerifyOptions{
+		Roots:       roots,
+		CurrentTime: time.Unix(1600000000, 0),
+	}
+
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, key := range []crypto.Signer{ecKey, rsaKey} {
+		leaf := testCertificate(t, "merchant.example.com", key, root,
+			rootKey)
+		req, err := paymentrequest.NewPaymentRequest(testDetails())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := req.Sign(key, []*x509.Certificate{leaf}); err != nil {
+			t.Fatalf("Sign: unexpected error: %v", err)
+		}
+		b, err := req.Marshal()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		parsed, err := paymentrequest.ParsePaymentRequest(b)
+		if err != nil {
+			t.Fatalf("ParsePaymentRequest: unexpected error: %v", err)
+		}
+		cert, err := parsed.Verify(opts)
+		if err != nil {
+			t.Fatalf("%T: Verify: unexpected error: %v", key, err)
+		}
+		if cert.Subject.CommonName != "merchant.example.com" {
+			t.Errorf("Verify: got merchant %v", cert.Subject)
+		}
+
+		// A request whose details are changed no longer verifies.
+		tampered := append([]byte{}, b...)
+		tampered[bytes.Index(b, req.SerializedDetails)] ^= 1
+		parsed, err = paymentrequest.ParsePaymentRequest(tampered)
+		if err != nil {
+			t.Fatalf("ParsePaymentRequest: unexpected error: %v", err)
+		}
+		if _, err := parsed.Verify(opts); err != paymentrequest.ErrInvalidSignature {
+			t.Errorf("%T: Verify tampered: got error %v, want %v", key,
+				err, paymentrequest.ErrInvalidSignature)
+		}
+
+		// Nor does it verify after the certificate expired or
+		// without trusting its root.
+		expired := opts
+		expired.CurrentTime = time.Unix(1800000000, 0)
+		if _, err := req.Verify(expired); err == nil {
+			t.Errorf("%T: Verify expired: unexpected success", key)
+		}
+		if _, err := req.Verify(x509.VerifyOptions{Roots: x509.NewCertPool()}); err == nil {
+			t.Errorf("%T: Verify untrusted: unexpected success", key)
+		}
+	}
+
+	unsigned, err := paymentrequest.NewPaymentRequest(testDetails())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cert, err := unsigned.Verify(opts); cert != nil || err != nil {
+		t.Errorf("Verify unsigned: got %v, %v", cert, err)
+	}
+	unsigned.PKIType = "pgp"
+	if _, err := unsigned.Verify(opts); err != paymentrequest.ErrUnknownPKIType {
+		t.Errorf("Verify unknown PKI type: got error %v, want %v", err,
+			paymentrequest.ErrUnknownPKIType)
+	}
+	if err := unsigned.Sign(ecKey, nil); err != paymentrequest.ErrNoCertificates {
+		t.Errorf("Sign without certificates: got error %v, want %v", err,
+			paymentrequest.ErrNoCertificates)
+	}
+}
@@ -0,0 +1,157 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package paymentrequest
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// The payment protocol messages are protocol buffers.  Since they only use a
+// handful of field types, they are encoded and decoded here directly rather
+// than through generated code.
+
+// These are the protocol buffer wire types used by the messages.
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// ErrMalformedMessage describes an error where a message is not a valid
+// protocol buffer, or lacks a required field.
+var ErrMalformedMessage = errors.New("malformed payment protocol message")
+
+// appendTag appends the key of a field with the passed number and wire type.
+func appendTag(b []byte, field int, wireType int) []byte {
+	return binary.AppendUvarint(b, uint64(field)<<3|uint64(wireType))
+}
+
+// appendUint appends a varint field.
+func appendUint(b []byte, field int, v uint64) []byte {
+	b = appendTag(b, field, wireVarint)
+	return binary.AppendUvarint(b, v)
+}
+
+// appendBytes appends a length delimited field.
+func appendBytes(b []byte, field int, v []byte) []byte {
+	b = appendTag(b, field, wireBytes)
+	b = binary.AppendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
+
+// protoField is a field read by protoReader.  Val holds the value of varint
+// fields and Data the value of length delimited fields.
+type protoField struct {
+	Num      int
+	WireType int
+	Val      uint64
+	Data     []byte
+
+	// Raw is the complete encoding of the field, including its key.
+	Raw []byte
+}
+
+// protoReader reads the fields of an encoded message in order.  The values of
+// the fields it returns refer to the encoded message.
+type protoReader struct {
+	b []byte
+}
+
+// readUvarint reads a varint from the front of the remaining message.
+func (r *protoReader) readUvarint() (uint64, error) {
+	v, n := binary.Uvarint(r.b)
+	if n <= 0 {
+		return 0, ErrMalformedMessage
+	}
+	r.b = r.b[n:]
+	return v, nil
+}
+
+// next reads the next field.  It returns false once the message is consumed.
+func (r *protoReader) next() (*protoField, bool, error) {
+	if len(r.b) == 0 {
+		return nil, false, nil
+	}
+	start := r.b
+	key, err := r.readUvarint()
+	if err != nil {
+		return nil, false, err
+	}
+	f := &protoField{Num: int(key >> 3), WireType: int(key & 7)}
+	if f.Num == 0 {
+		return nil, false, ErrMalformedMessage
+	}
+
+	switch f.WireType {
+	case wireVarint:
+		f.Val, err = r.readUvarint()
+		if err != nil {
+			return nil, false, err
+		}
+	case wireFixed64, wireFixed32:
+		size := 8
+		if f.WireType == wireFixed32 {
+			size = 4
+		}
+		if len(r.b) < size {
+			return nil, false, ErrMalformedMessage
+		}
+		r.b = r.b[size:]
+	case wireBytes:
+		length, err := r.readUvarint()
+		if err != nil {
+			return nil, false, err
+		}
+		if length > uint64(len(r.b)) {
+			return nil, false, ErrMalformedMessage
+		}
+		f.Data = r.b[:length:length]
+		r.b = r.b[length:]
+	default:
+		return nil, false, ErrMalformedMessage
+	}
+	f.Raw = start[:len(start)-len(r.b)]
+	return f, true, nil
+}
+
+// readFields calls fn for each field of the encoded message b.  fn should
+// ignore fields of unknown numbers, and read known fields with bytesField and
+// uintField, which reject values of the wrong wire type.
+func readFields(b []byte, fn func(f *protoField) error) error {
+	r := protoReader{b: b}
+	for {
+		f, ok, err := r.next()
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return nil
+		}
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+}
+
+// bytesField returns the value of a length delimited field, or
+// ErrMalformedMessage when f has another wire type.  The value is copied so
+// it does not refer to the encoded message.
+func bytesField(f *protoField) ([]byte, error) {
+	if f.WireType != wireBytes {
+		return nil, ErrMalformedMessage
+	}
+	return append([]byte{}, f.Data...), nil
+}
+
+// uintField returns the value of a varint field, or ErrMalformedMessage when
+// f has another wire type.
+func uintField(f *protoField) (uint64, error) {
+	if f.WireType != wireVarint {
+		return 0, ErrMalformedMessage
+	}
+	return f.Val, nil
+}
@@ -0,0 +1,124 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package polygon
+
+import (
+	"math/big"
+
+	"github.com/zeusyf/omega/token"
+)
+
+// The planners treat longitude as the x and latitude as the y axis of a
+// plane.  All arithmetic is exact: coordinates fit int64, and products of
+// them are computed with math/big.
+
+// point is a vertex in the plane.  Coordinates are doubled so that the
+// midpoints of segments between vertices are points as well.
+type point struct {
+	x, y int64
+}
+
+// pt returns the point of a vertex.
+func pt(v token.VertexDef) point {
+	return point{2 * int64(v.Lng), 2 * int64(v.Lat)}
+}
+
+// mid returns the midpoint of the segment from a to b.
+func mid(a, b point) point {
+	return point{(a.x + b.x) / 2, (a.y + b.y) / 2}
+}
+
+// cross returns the sign of the cross product of b-a and c-a, which is
+// positive when c is left of the line from a to b, negative when right and
+// zero when the points are collinear.
+func cross(a, b, c point) int {
+	l := new(big.Int).Mul(big.NewInt(b.x-a.x), big.NewInt(c.y-a.y))
+	r := new(big.Int).Mul(big.NewInt(b.y-a.y), big.NewInt(c.x-a.x))
+	return l.Cmp(r)
+}
+
+// onSegment returns whether p lies on the closed segment from a to b.
+func onSegment(p, a, b point) bool {
+	return cross(a, b, p) == 0 &&
+		min(a.x, b.x) <= p.x && p.x <= max(a.x, b.x) &&
+		min(a.y, b.y) <= p.y && p.y <= max(a.y, b.y)
+}
+
+// intersects returns whether the closed segments from a to b and from c to d
+// share a point.
+func intersects(a, b, c, d point) bool {
+	d1, d2 := cross(c, d, a), cross(c, d, b)
+	d3, d4 := cross(a, b, c), cross(a, b, d)
+	if d1*d2 < 0 && d3*d4 < 0 {
+		return true
+	}
+	return onSegment(a, c, d) || onSegment(b, c, d) ||
+		onSegment(c, a, b) || onSegment(d, a, b)
+}
+
+// overlaps returns whether the segments from a to b and from c to d are
+// collinear and share more than a single point.
+func overlaps(a, b, c, d point) bool {
+	if cross(a, b, c) != 0 || cross(a, b, d) != 0 {
+		return false
+	}
+	// Project onto the axis along which the segments extend.
+	lo1, hi1, lo2, hi2 := a.x, b.x, c.x, d.x
+	if a.x == b.x {
+		lo1, hi1, lo2, hi2 = a.y, b.y, c.y, d.y
+	}
+	if lo1 > hi1 {
+		lo1, hi1 = hi1, lo1
+	}
+	if lo2 > hi2 {
+		lo2, hi2 = hi2, lo2
+	}
+	return max(lo1, lo2) < min(hi1, hi2)
+}
+
+// location is where a point lies relative to a loop.
+type location int
+
+const (
+	outside location = iota
+	onBoundary
+	inside
+)
+
+// locate returns where p lies relative to the closed loop through vertices.
+func locate(p point, vertices []point) location {
+	in := false
+	for i, a := range vertices {
+		b := vertices[(i+1)%len(vertices)]
+		if onSegment(p, a, b) {
+			return onBoundary
+		}
+		// Count crossings of the ray from p towards positive x, using
+		// half-open edges so vertices are counted once.
+		if (a.y > p.y) != (b.y > p.y) {
+			c := cross(a, b, p)
+			if (c > 0) == (b.y > a.y) {
+				in = !in
+			}
+		}
+	}
+	if in {
+		return inside
+	}
+	return outside
+}
+
+// hasArea returns whether the loop through vertices encloses a nonzero
+// signed area.
+func hasArea(vertices []point) bool {
+	sum := new(big.Int)
+	var t big.Int
+	for i, a := range vertices {
+		b := vertices[(i+1)%len(vertices)]
+		sum.Add(sum, t.Mul(big.NewInt(a.x), big.NewInt(b.y)))
+		sum.Sub(sum, t.Mul(big.NewInt(b.x), big.NewInt(a.y)))
+	}
+	return sum.Sign() != 0
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package polygon
+
+import (
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+)
+
+// sharedRun returns the index of the first edge and the number of edges of
+// the single run of edges of loop for which shared is true.  It returns false
+// unless there is exactly one run, which does not cover the whole loop.
+func sharedRun(loop []Edge, shared func(e Edge) bool) (int, int, bool) {
+	n := len(loop)
+	start, count, runs := 0, 0, 0
+	for i, e := range loop {
+		if !shared(e) {
+			continue
+		}
+		count++
+		if !shared(loop[(i+n-1)%n]) {
+			start = i
+			runs++
+		}
+	}
+	return start, count, runs == 1 && count < n
+}
+
+// Merge plans joining lands a and b into one.  The lands must share a single
+// contiguous run of borders, traversed in opposite directions, and touch
+// nowhere else.  The shared borders are dropped from the resulting land, and
+// no new borders are defined.
+func Merge(a, b *Land) (*Plan, error) {
+	for _, land := range []*Land{a, b} {
+		if err := land.check(); err != nil {
+			return nil, err
+		}
+	}
+
+	inB := make(map[chainhash.Hash]bool, len(b.Loop))
+	for _, e := range b.Loop {
+		inB[e.Border.Hash()] = e.Reversed
+	}
+	inA := make(map[chainhash.Hash]bool, len(a.Loop))
+	for _, e := range a.Loop {
+		hash := e.Border.Hash()
+		inA[hash] = e.Reversed
+		if reversed, ok := inB[hash]; ok && reversed == e.Reversed {
+			// Both lands claim the same side of the border.
+			return nil, ErrNotAdjacent
+		}
+	}
+
+	startA, countA, okA := sharedRun(a.Loop, func(e Edge) bool {
+		_, ok := inB[e.Border.Hash()]
+		return ok
+	})
+	startB, countB, okB := sharedRun(b.Loop, func(e Edge) bool {
+		_, ok := inA[e.Border.Hash()]
+		return ok
+	})
+	if !okA || !okB || countA != countB {
+		return nil, ErrNotAdjacent
+	}
+
+	// Follow a from the end of the shared run back to its start, then b
+	// likewise, which closes the loop around both lands.
+	merged := new(Land)
+	for i := countA; i < len(a.Loop); i++ {
+		merged.Loop = append(merged.Loop, a.Loop[(startA+i)%len(a.Loop)])
+	}
+	pathA := len(merged.Loop)
+	for i := countB; i < len(b.Loop); i++ {
+		merged.Loop = append(merged.Loop, b.Loop[(startB+i)%len(b.Loop)])
+	}
+	if err := merged.check(); err != nil {
+		return nil, ErrNotAdjacent
+	}
+
+	// The rest of the lands may not cross each other.
+	for _, e := range merged.Loop[:pathA] {
+		c, d := pt(e.From()), pt(e.To())
+		for _, f := range merged.Loop[pathA:] {
+			g, h := pt(f.From()), pt(f.To())
+			if overlaps(c, d, g, h) {
+				return nil, ErrNotAdjacent
+			}
+			joined := c == g || c == h || d == g || d == h
+			if !joined && intersects(c, d, g, h) {
+				return nil, ErrNotAdjacent
+			}
+		}
+	}
+	return newPlan(nil, nil, merged), nil
+}
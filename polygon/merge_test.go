@@ -0,0 +1,60 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package polygon_test
+
+import (
+	"testing"
+
+	"github.com/zeusyf/btcutil/polygon"
+	"github.com/zeusyf/omega/token"
+)
+
+// TestMerge ensures adjacent lands merge into their union, and other lands
+// are rejected.
+func TestMerge(t *testing.T) {
+	square := testLand(v(0, 0), v(10, 0), v(10, 10), v(0, 10))
+	plan, err := polygon.Subdivide(square, []token.VertexDef{v(5, 0), v(5, 5), v(5, 10)})
+	if err != nil {
+		t.Fatalf("Subdivide: unexpected error: %v", err)
+	}
+
+	merged, err := polygon.Merge(plan.Lands[0], plan.Lands[1])
+	if err != nil {
+		t.Fatalf("Merge: unexpected error: %v", err)
+	}
+	if len(merged.Borders) != 0 || len(merged.Polygons) != 1 {
+		t.Errorf("Merge: got %d borders and %d polygons",
+			len(merged.Borders), len(merged.Polygons))
+	}
+	if !sameLoop(merged.Lands[0], v(0, 0), v(5, 0), v(10, 0), v(10, 10),
+		v(5, 10), v(0, 10)) {
+		t.Errorf("Merge: got %v", loopVertices(merged.Lands[0]))
+	}
+
+	// Of the halves of a diagonal cut, one shares no border with the right
+	// half of the vertical cut, and the other shares its right border on
+	// the same side.
+	diagonal, err := polygon.Subdivide(square, []token.VertexDef{v(0, 0), v(10, 10)})
+	if err != nil {
+		t.Fatalf("Subdivide: unexpected error: %v", err)
+	}
+	other := testLand(v(20, 0), v(30, 0), v(30, 10))
+
+	invalid := []struct {
+		name string
+		a, b *polygon.Land
+	}{
+		{"same land", plan.Lands[0], plan.Lands[0]},
+		{"disjoint", square, other},
+		{"no shared border", plan.Lands[0], diagonal.Lands[1]},
+		{"same side of border", plan.Lands[0], diagonal.Lands[0]},
+	}
+	for _, test := range invalid {
+		if _, err := polygon.Merge(test.a, test.b); err != polygon.ErrNotAdjacent {
+			t.Errorf("Merge(%s): got error %v, want %v", test.name, err,
+				polygon.ErrNotAdjacent)
+		}
+	}
+}
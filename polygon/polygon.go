@@ -0,0 +1,231 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package polygon plans subdivisions and merges of Omega land, the polygon
+// tokens whose value is the hash of a polygon definition.
+//
+// A polygon is defined by loops of borders, and each border by its two end
+// vertices and, for borders created by splitting another one, the border it
+// was split from.  Subdividing or merging land means spending the polygon
+// tokens, defining the new borders and polygons, and creating outputs of the
+// new polygons, all of which the consensus rules check against each other.
+// The planners compute these from the geometry: Subdivide cuts a land along a
+// path of vertices into two, and Merge joins two adjacent lands into one.
+// The resulting Plan holds the definitions to add to the transaction and
+// creates its outputs.
+//
+// Only lands of a single loop are handled, and coordinates are compared in
+// the plane of longitude and latitude.
+package polygon
+
+import (
+	"errors"
+
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/omega/token"
+)
+
+// TokenType is the token type of land: a hash valued token with rights.
+const TokenType = 3
+
+var (
+	// ErrUnknownBorder describes an error where a polygon refers to a
+	// border which is not among the known definitions.
+	ErrUnknownBorder = errors.New("polygon refers to unknown border")
+
+	// ErrUnsupportedLand describes an error where a land has holes, which
+	// the planners do not handle.
+	ErrUnsupportedLand = errors.New("land with holes not supported")
+
+	// ErrBrokenLoop describes an error where the borders of a loop do not
+	// join up into a closed loop enclosing an area.
+	ErrBrokenLoop = errors.New("borders do not form a closed loop")
+
+	// ErrInvalidCut describes an error where a subdivision path does not
+	// run through the interior of the land from one point of its boundary
+	// to another.
+	ErrInvalidCut = errors.New("invalid subdivision path")
+
+	// ErrNotAdjacent describes an error where lands to merge do not share
+	// a single contiguous run of borders, so their union is not a single
+	// loop.
+	ErrNotAdjacent = errors.New("lands are not adjacent")
+
+	// ErrOutputCount describes an error where the number of scripts passed
+	// to Plan.Outputs differs from the number of polygons planned.
+	ErrOutputCount = errors.New("one script required per polygon")
+)
+
+// Edge is a border of a loop, traversed either along or against its
+// direction.
+type Edge struct {
+	Border   *token.BorderDef
+	Reversed bool
+}
+
+// From returns the vertex the edge starts at.
+func (e Edge) From() token.VertexDef {
+	if e.Reversed {
+		return e.Border.End
+	}
+	return e.Border.Begin
+}
+
+// To returns the vertex the edge ends at.
+func (e Edge) To() token.VertexDef {
+	if e.Reversed {
+		return e.Border.Begin
+	}
+	return e.Border.End
+}
+
+// ref returns the reference to the edge in a loop definition.  Borders used
+// against their direction are referred to by their hash with the lowest bit
+// flipped.
+func (e Edge) ref() chainhash.Hash {
+	hash := e.Border.Hash()
+	if e.Reversed {
+		hash[0] ^= 1
+	}
+	return hash
+}
+
+// Land is the geometry of a polygon of a single loop.
+type Land struct {
+	// Loop holds the edges of the boundary in order.  Each edge ends
+	// where the next one starts.
+	Loop []Edge
+}
+
+// NewLand returns the land of a polygon definition, resolving the borders of
+// its loop from borders, which maps the hashes of border definitions to the
+// definitions.
+func NewLand(polygon *token.PolygonDef, borders map[chainhash.Hash]*token.BorderDef) (*Land, error) {
+	if len(polygon.Loops) != 1 {
+		return nil, ErrUnsupportedLand
+	}
+
+	loop := polygon.Loops[0]
+	land := &Land{Loop: make([]Edge, len(loop))}
+	for i, ref := range loop {
+		border, ok := borders[ref]
+		if ok {
+			land.Loop[i] = Edge{Border: border}
+			continue
+		}
+		ref[0] ^= 1
+		border, ok = borders[ref]
+		if !ok {
+			return nil, ErrUnknownBorder
+		}
+		land.Loop[i] = Edge{Border: border, Reversed: true}
+	}
+	if err := land.check(); err != nil {
+		return nil, err
+	}
+	return land, nil
+}
+
+// points returns the vertices of the loop of the land in order.
+func (l *Land) points() []point {
+	points := make([]point, len(l.Loop))
+	for i, e := range l.Loop {
+		points[i] = pt(e.From())
+	}
+	return points
+}
+
+// check verifies that the edges of the land form a closed loop enclosing an
+// area, without visiting a vertex twice.
+func (l *Land) check() error {
+	if len(l.Loop) < 3 {
+		return ErrBrokenLoop
+	}
+	seen := make(map[token.VertexDef]struct{}, len(l.Loop))
+	for i, e := range l.Loop {
+		next := l.Loop[(i+1)%len(l.Loop)]
+		if e.To() != next.From() {
+			return ErrBrokenLoop
+		}
+		if _, ok := seen[e.From()]; ok {
+			return ErrBrokenLoop
+		}
+		seen[e.From()] = struct{}{}
+	}
+	if !hasArea(l.points()) {
+		return ErrBrokenLoop
+	}
+	return nil
+}
+
+// definition returns the polygon definition of the land.
+func (l *Land) definition() *token.PolygonDef {
+	loop := make(token.LoopDef, len(l.Loop))
+	for i, e := range l.Loop {
+		loop[i] = e.ref()
+	}
+	return &token.PolygonDef{Loops: []token.LoopDef{loop}}
+}
+
+// Plan is the result of a subdivision or merge: the definitions of new
+// borders and polygons, and the lands of the polygons.
+type Plan struct {
+	// Vertices are the vertices introduced by the plan.
+	Vertices []token.VertexDef
+
+	// Borders are the border definitions introduced by the plan.
+	Borders []*token.BorderDef
+
+	// Polygons are the definitions of the polygons resulting from the
+	// plan, and Lands their geometry.
+	Polygons []*token.PolygonDef
+	Lands    []*Land
+}
+
+// newPlan returns the plan resulting in lands.
+func newPlan(vertices []token.VertexDef, borders []*token.BorderDef, lands ...*Land) *Plan {
+	plan := &Plan{
+		Vertices: vertices,
+		Borders:  borders,
+		Lands:    lands,
+	}
+	for _, land := range lands {
+		plan.Polygons = append(plan.Polygons, land.definition())
+	}
+	return plan
+}
+
+// Definitions returns the definitions to add to the transaction carrying out
+// the plan: the new borders, followed by the polygons.
+func (p *Plan) Definitions() []token.Definition {
+	defs := make([]token.Definition, 0, len(p.Borders)+len(p.Polygons))
+	for _, border := range p.Borders {
+		defs = append(defs, border)
+	}
+	for _, polygon := range p.Polygons {
+		defs = append(defs, polygon)
+	}
+	return defs
+}
+
+// Outputs returns the outputs creating the polygons of the plan, the i-th
+// paying to pkScripts[i] and carrying rights.
+func (p *Plan) Outputs(pkScripts [][]byte, rights *chainhash.Hash) ([]*wire.TxOut, error) {
+	if len(pkScripts) != len(p.Polygons) {
+		return nil, ErrOutputCount
+	}
+	outputs := make([]*wire.TxOut, len(p.Polygons))
+	for i, polygon := range p.Polygons {
+		outputs[i] = &wire.TxOut{
+			Token: token.Token{
+				TokenType: TokenType,
+				Value:     &token.HashVal{Hash: polygon.Hash()},
+				Rights:    rights,
+			},
+			PkScript: pkScripts[i],
+		}
+	}
+	return outputs, nil
+}
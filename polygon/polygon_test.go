@@ -0,0 +1,142 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package polygon_test
+
+import (
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcutil/polygon"
+	"github.com/zeusyf/omega/token"
+)
+
+// v returns the vertex at the passed longitude and latitude.
+func v(lng, lat int32) token.VertexDef {
+	return token.VertexDef{Lat: lat, Lng: lng}
+}
+
+// testLand returns the land enclosed by the loop through vertices, whose
+// borders are defined along the loop.
+func testLand(vertices ...token.VertexDef) *polygon.Land {
+	land := new(polygon.Land)
+	for i, from := range vertices {
+		to := vertices[(i+1)%len(vertices)]
+		border := &token.BorderDef{Begin: from, End: to}
+		land.Loop = append(land.Loop, polygon.Edge{Border: border})
+	}
+	return land
+}
+
+// loopVertices returns the vertices of the loop of land in order.
+func loopVertices(land *polygon.Land) []token.VertexDef {
+	vertices := make([]token.VertexDef, len(land.Loop))
+	for i, e := range land.Loop {
+		vertices[i] = e.From()
+	}
+	return vertices
+}
+
+// sameLoop returns whether the loop of land runs through want, starting at
+// any of them.
+func sameLoop(land *polygon.Land, want ...token.VertexDef) bool {
+	got := loopVertices(land)
+	if len(got) != len(want) {
+		return false
+	}
+	for offset := range got {
+		match := true
+		for i := range got {
+			if got[(offset+i)%len(got)] != want[i] {
+				match = false
+				break
+			}
+		}
+		if match {
+			return true
+		}
+	}
+	return false
+}
+
+// TestNewLand ensures lands are resolved from polygon definitions, including
+// borders used against their direction.
+func TestNewLand(t *testing.T) {
+	a, b, c := v(0, 0), v(10, 0), v(0, 10)
+	ab := &token.BorderDef{Begin: a, End: b}
+	cb := &token.BorderDef{Begin: c, End: b}
+	ca := &token.BorderDef{Begin: c, End: a}
+	borders := map[chainhash.Hash]*token.BorderDef{
+		ab.Hash(): ab,
+		cb.Hash(): cb,
+		ca.Hash(): ca,
+	}
+
+	reversed := cb.Hash()
+	reversed[0] ^= 1
+	def := &token.PolygonDef{Loops: []token.LoopDef{{ab.Hash(), reversed, ca.Hash()}}}
+	land, err := polygon.NewLand(def, borders)
+	if err != nil {
+		t.Fatalf("NewLand: unexpected error: %v", err)
+	}
+	if !sameLoop(land, a, b, c) || !land.Loop[1].Reversed {
+		t.Errorf("NewLand: got loop %v", loopVertices(land))
+	}
+
+	tests := []struct {
+		name string
+		def  *token.PolygonDef
+		err  error
+	}{
+		{"holes", &token.PolygonDef{Loops: []token.LoopDef{
+			{ab.Hash(), reversed, ca.Hash()},
+			{ab.Hash(), reversed, ca.Hash()},
+		}}, polygon.ErrUnsupportedLand},
+		{"broken", &token.PolygonDef{Loops: []token.LoopDef{
+			{ab.Hash(), cb.Hash(), ca.Hash()},
+		}}, polygon.ErrBrokenLoop},
+		{"unknown border", &token.PolygonDef{Loops: []token.LoopDef{
+			{ab.Hash(), {0x42}, ca.Hash()},
+		}}, polygon.ErrUnknownBorder},
+	}
+	for _, test := range tests {
+		if _, err := polygon.NewLand(test.def, borders); err != test.err {
+			t.Errorf("NewLand(%s): got error %v, want %v", test.name,
+				err, test.err)
+		}
+	}
+}
+
+// TestOutputs ensures plans create one land token per polygon.
+func TestOutputs(t *testing.T) {
+	land := testLand(v(0, 0), v(10, 0), v(10, 10), v(0, 10))
+	plan, err := polygon.Subdivide(land, []token.VertexDef{v(0, 0), v(10, 10)})
+	if err != nil {
+		t.Fatalf("Subdivide: unexpected error: %v", err)
+	}
+
+	rights := &chainhash.Hash{0x01}
+	scripts := [][]byte{{0x51}, {0x52}}
+	outputs, err := plan.Outputs(scripts, rights)
+	if err != nil {
+		t.Fatalf("Outputs: unexpected error: %v", err)
+	}
+	for i, out := range outputs {
+		value, ok := out.Token.Value.(*token.HashVal)
+		if out.Token.TokenType != polygon.TokenType || !ok ||
+			value.Hash != plan.Polygons[i].Hash() ||
+			out.Token.Rights != rights || out.PkScript[0] != scripts[i][0] {
+			t.Errorf("Outputs: output %d: got %+v", i, out)
+		}
+	}
+	if _, err := plan.Outputs(scripts[:1], rights); err != polygon.ErrOutputCount {
+		t.Errorf("Outputs: got error %v, want %v", err, polygon.ErrOutputCount)
+	}
+
+	defs := plan.Definitions()
+	if len(defs) != len(plan.Borders)+len(plan.Polygons) {
+		t.Errorf("Definitions: got %d, want %d", len(defs),
+			len(plan.Borders)+len(plan.Polygons))
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package polygon
+
+import (
+	"github.com/zeusyf/omega/token"
+)
+
+// splitAt returns the loop with v as one of its vertices.  When v lies within
+// an edge, that edge is replaced by the two halves of its border, whose
+// definitions are returned.
+func splitAt(loop []Edge, v token.VertexDef) ([]Edge, []*token.BorderDef, error) {
+	for _, e := range loop {
+		if e.From() == v {
+			return loop, nil, nil
+		}
+	}
+
+	p := pt(v)
+	for i, e := range loop {
+		if !onSegment(p, pt(e.From()), pt(e.To())) {
+			continue
+		}
+		father := e.Border.Hash()
+		first := &token.BorderDef{
+			Father: father,
+			Begin:  e.Border.Begin,
+			End:    v,
+		}
+		second := &token.BorderDef{
+			Father: father,
+			Begin:  v,
+			End:    e.Border.End,
+		}
+		halves := []Edge{{Border: first}, {Border: second}}
+		if e.Reversed {
+			halves = []Edge{
+				{Border: second, Reversed: true},
+				{Border: first, Reversed: true},
+			}
+		}
+
+		split := make([]Edge, 0, len(loop)+1)
+		split = append(split, loop[:i]...)
+		split = append(split, halves...)
+		split = append(split, loop[i+1:]...)
+		return split, []*token.BorderDef{first, second}, nil
+	}
+	return nil, nil, ErrInvalidCut
+}
+
+// checkCut verifies that cut runs through the interior of the land bounded
+// by loop, which has the end points of cut among its vertices.
+func checkCut(loop []Edge, boundary []point, cut []token.VertexDef) error {
+	points := make([]point, len(cut))
+	for i, v := range cut {
+		points[i] = pt(v)
+	}
+	last := len(points) - 1
+
+	for i, p := range points[1:last] {
+		if locate(p, boundary) != inside {
+			return ErrInvalidCut
+		}
+		for _, q := range points[:i+1] {
+			if p == q {
+				return ErrInvalidCut
+			}
+		}
+	}
+
+	for i := 0; i < last; i++ {
+		a, b := points[i], points[i+1]
+		if locate(mid(a, b), boundary) != inside {
+			return ErrInvalidCut
+		}
+
+		// The cut may only touch the boundary at its end points.
+		for _, e := range loop {
+			c, d := pt(e.From()), pt(e.To())
+			if !intersects(a, b, c, d) {
+				continue
+			}
+			touchesStart := i == 0 && (a == c || a == d)
+			touchesEnd := i == last-1 && (b == c || b == d)
+			if !(touchesStart || touchesEnd) || overlaps(a, b, c, d) {
+				return ErrInvalidCut
+			}
+		}
+
+		// Nor may it cross itself.
+		for j := 0; j < i; j++ {
+			c, d := points[j], points[j+1]
+			if j == i-1 {
+				if overlaps(a, b, c, d) {
+					return ErrInvalidCut
+				}
+				continue
+			}
+			if intersects(a, b, c, d) {
+				return ErrInvalidCut
+			}
+		}
+	}
+	return nil
+}
+
+// Subdivide plans cutting land into two along cut, a path of vertices
+// starting and ending on the boundary of land and running through its
+// interior.  The end points of cut may be vertices of land, or lie within
+// its borders, which are then split.  The plan defines the borders along cut
+// and any borders split, and results in the two parts of land.
+func Subdivide(land *Land, cut []token.VertexDef) (*Plan, error) {
+	if len(cut) < 2 || cut[0] == cut[len(cut)-1] {
+		return nil, ErrInvalidCut
+	}
+	if err := land.check(); err != nil {
+		return nil, err
+	}
+	boundary := land.points()
+
+	var vertices []token.VertexDef
+	var borders []*token.BorderDef
+	loop := land.Loop
+	for _, v := range []token.VertexDef{cut[0], cut[len(cut)-1]} {
+		var split []*token.BorderDef
+		var err error
+		loop, split, err = splitAt(loop, v)
+		if err != nil {
+			return nil, err
+		}
+		if split != nil {
+			vertices = append(vertices, v)
+			borders = append(borders, split...)
+		}
+	}
+	vertices = append(vertices, cut[1:len(cut)-1]...)
+
+	if err := checkCut(loop, boundary, cut); err != nil {
+		return nil, err
+	}
+
+	// Find the end points only now that both are part of the loop, since
+	// splitting a border for the second one shifts the first.
+	var start, end int
+	for i, e := range loop {
+		switch e.From() {
+		case cut[0]:
+			start = i
+		case cut[len(cut)-1]:
+			end = i
+		}
+	}
+
+	path := make([]*token.BorderDef, len(cut)-1)
+	for i := range path {
+		path[i] = &token.BorderDef{Begin: cut[i], End: cut[i+1]}
+	}
+	borders = append(borders, path...)
+
+	// The first part follows the loop from the start of the cut to its
+	// end and returns along the cut, the second follows the loop on from
+	// the end of the cut and back along the cut.
+	n := len(loop)
+	first, second := new(Land), new(Land)
+	for i := start; i != end; i = (i + 1) % n {
+		first.Loop = append(first.Loop, loop[i])
+	}
+	for i := len(path) - 1; i >= 0; i-- {
+		first.Loop = append(first.Loop, Edge{Border: path[i], Reversed: true})
+	}
+	for i := end; i != start; i = (i + 1) % n {
+		second.Loop = append(second.Loop, loop[i])
+	}
+	for _, border := range path {
+		second.Loop = append(second.Loop, Edge{Border: border})
+	}
+
+	for _, part := range []*Land{first, second} {
+		if err := part.check(); err != nil {
+			return nil, ErrInvalidCut
+		}
+	}
+	return newPlan(vertices, borders, first, second), nil
+}
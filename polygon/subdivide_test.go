@@ -0,0 +1,90 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package polygon_test
+
+import (
+	"testing"
+
+	"github.com/zeusyf/btcutil/polygon"
+	"github.com/zeusyf/omega/token"
+)
+
+// TestSubdivide ensures lands are cut into the expected parts, splitting
+// borders the cut ends within.
+func TestSubdivide(t *testing.T) {
+	square := testLand(v(0, 0), v(10, 0), v(10, 10), v(0, 10))
+
+	plan, err := polygon.Subdivide(square, []token.VertexDef{v(5, 0), v(5, 5), v(5, 10)})
+	if err != nil {
+		t.Fatalf("Subdivide: unexpected error: %v", err)
+	}
+	if len(plan.Lands) != 2 || len(plan.Polygons) != 2 {
+		t.Fatalf("Subdivide: got %d lands", len(plan.Lands))
+	}
+	if !sameLoop(plan.Lands[0], v(5, 0), v(10, 0), v(10, 10), v(5, 10), v(5, 5)) {
+		t.Errorf("Subdivide: got first part %v", loopVertices(plan.Lands[0]))
+	}
+	if !sameLoop(plan.Lands[1], v(5, 10), v(0, 10), v(0, 0), v(5, 0), v(5, 5)) {
+		t.Errorf("Subdivide: got second part %v", loopVertices(plan.Lands[1]))
+	}
+	if len(plan.Vertices) != 3 {
+		t.Errorf("Subdivide: got %d new vertices, want 3", len(plan.Vertices))
+	}
+
+	// Two borders are split in halves, and two are defined along the
+	// cut.
+	if len(plan.Borders) != 6 {
+		t.Fatalf("Subdivide: got %d borders, want 6", len(plan.Borders))
+	}
+	bottom, top := square.Loop[0].Border.Hash(), square.Loop[2].Border.Hash()
+	for i, border := range plan.Borders[:4] {
+		want := bottom
+		if i >= 2 {
+			want = top
+		}
+		if border.Father != want {
+			t.Errorf("Subdivide: border %d has father %v, want %v", i,
+				border.Father, want)
+		}
+	}
+
+	// A diagonal between vertices splits no border.
+	plan, err = polygon.Subdivide(square, []token.VertexDef{v(0, 0), v(10, 10)})
+	if err != nil {
+		t.Fatalf("Subdivide diagonal: unexpected error: %v", err)
+	}
+	if len(plan.Borders) != 1 || len(plan.Vertices) != 0 {
+		t.Errorf("Subdivide diagonal: got %d borders and %d vertices",
+			len(plan.Borders), len(plan.Vertices))
+	}
+
+	// The land is shaped like a U, so cuts across its opening leave it.
+	u := testLand(v(0, 0), v(30, 0), v(30, 30), v(20, 30), v(20, 10),
+		v(10, 10), v(10, 30), v(0, 30))
+	invalid := []struct {
+		name string
+		land *polygon.Land
+		cut  []token.VertexDef
+	}{
+		{"single vertex", square, []token.VertexDef{v(0, 0)}},
+		{"closed", square, []token.VertexDef{v(0, 0), v(5, 5), v(0, 0)}},
+		{"start inside", square, []token.VertexDef{v(5, 5), v(10, 10)}},
+		{"along border", square, []token.VertexDef{v(0, 0), v(10, 0)}},
+		{"outside", square, []token.VertexDef{v(0, 0), v(-5, 5), v(0, 10)}},
+		{"leaves land", u, []token.VertexDef{v(10, 30), v(20, 30)}},
+		{"crosses boundary", u, []token.VertexDef{v(5, 0), v(5, 20),
+			v(25, 20), v(25, 0)}},
+		{"through vertex", u, []token.VertexDef{v(0, 10), v(30, 10)}},
+		{"crosses itself", square, []token.VertexDef{v(0, 2), v(8, 8),
+			v(8, 2), v(2, 8), v(2, 10)}},
+	}
+	for _, test := range invalid {
+		_, err := polygon.Subdivide(test.land, test.cut)
+		if err != polygon.ErrInvalidCut {
+			t.Errorf("Subdivide(%s): got error %v, want %v", test.name,
+				err, polygon.ErrInvalidCut)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package btcutil
+
+import (
+	"errors"
+	"math/big"
+	"strings"
+)
+
+// ErrInvalidPrice describes an error where a price is not a positive rational
+// number.
+var ErrInvalidPrice = errors.New("price must be a positive number")
+
+// Price is the exchange rate of OMC in a fiat currency.  Conversions are done
+// with arbitrary precision rationals and rounded once, in an explicit mode, to
+// the minor unit of the currency or to the Hao, so unlike converting with
+// MulF64 no precision is lost to floating point math.
+type Price struct {
+	// Currency is the code of the fiat currency, such as "USD".
+	Currency string
+
+	// Decimals is the number of decimals of the minor unit of the
+	// currency, such as 2 for cents.
+	Decimals uint8
+
+	// rate is the price of one OMC in the currency.
+	rate *big.Rat
+}
+
+// NewPrice returns the price of one OMC in currency, whose minor unit has the
+// passed number of decimals.  The rate is an exact decimal, such as
+// "43250.17", or a fraction, such as "1/3".
+func NewPrice(currency string, rate string, decimals uint8) (*Price, error) {
+	r, ok := new(big.Rat).SetString(rate)
+	if !ok || r.Sign() <= 0 {
+		return nil, ErrInvalidPrice
+	}
+	return &Price{Currency: currency, Decimals: decimals, rate: r}, nil
+}
+
+// Rate returns the price of one OMC in the currency.
+func (p *Price) Rate() *big.Rat {
+	return new(big.Rat).Set(p.rate)
+}
+
+// minorPerHao returns the value of one Hao in minor units of the currency.
+func (p *Price) minorPerHao() *big.Rat {
+	scale := new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(p.Decimals)), nil)
+	r := new(big.Rat).Mul(p.rate, new(big.Rat).SetInt(scale))
+	return r.Quo(r, new(big.Rat).SetInt64(HaoPerBitcoin))
+}
+
+// ToFiat returns the value of a in minor units of the currency, rounded in
+// the passed mode.  Services collecting a fiat amount should round up with
+// RoundCeil so they never collect less than the value of a.
+func (p *Price) ToFiat(a Amount, mode RoundingMode) *big.Int {
+	r := new(big.Rat).SetInt64(int64(a))
+	return roundRat(r.Mul(r, p.minorPerHao()), mode)
+}
+
+// FromFiat returns the amount worth minor units of the currency, rounded to
+// the Hao in the passed mode.  ErrAmountOverflow is returned when the amount
+// does not fit in an Amount.
+func (p *Price) FromFiat(minor *big.Int, mode RoundingMode) (Amount, error) {
+	r := new(big.Rat).SetInt(minor)
+	hao := roundRat(r.Quo(r, p.minorPerHao()), mode)
+	if !hao.IsInt64() {
+		return 0, ErrAmountOverflow
+	}
+	return Amount(hao.Int64()), nil
+}
+
+// FormatFiat formats minor units of the currency as an exact decimal followed
+// by the currency code, such as "12.30 USD".  All decimals of the minor unit
+// are shown.
+func (p *Price) FormatFiat(minor *big.Int) string {
+	digits := new(big.Int).Abs(minor).String()
+	decimals := int(p.Decimals)
+	if len(digits) <= decimals {
+		digits = strings.Repeat("0", decimals-len(digits)+1) + digits
+	}
+
+	var sb strings.Builder
+	if minor.Sign() < 0 {
+		sb.WriteByte('-')
+	}
+	sb.WriteString(digits[:len(digits)-decimals])
+	if decimals > 0 {
+		sb.WriteByte('.')
+		sb.WriteString(digits[len(digits)-decimals:])
+	}
+	if p.Currency != "" {
+		sb.WriteByte(' ')
+		sb.WriteString(p.Currency)
+	}
+	return sb.String()
+}
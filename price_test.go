@@ -0,0 +1,137 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package btcutil_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/zeusyf/btcutil"
+)
+
+// TestPriceToFiat ensures amounts are converted exactly and rounded once in
+// the requested mode.
+func TestPriceToFiat(t *testing.T) {
+	// At 0.25 USD per OMC, one cent is worth 4000000 Hao, so multiples of
+	// 2000000 Hao fall on half cents.
+	price, err := btcutil.NewPrice("USD", "0.25", 2)
+	if err != nil {
+		t.Fatalf("NewPrice: %v", err)
+	}
+	tests := []struct {
+		amount btcutil.Amount
+		mode   btcutil.RoundingMode
+		want   int64
+	}{
+		{400000000, btcutil.RoundHalfEven, 100},
+		{2000000, btcutil.RoundHalfAwayFromZero, 1},
+		{2000000, btcutil.RoundHalfEven, 0},
+		{6000000, btcutil.RoundHalfEven, 2},
+		{-2000000, btcutil.RoundHalfAwayFromZero, -1},
+		{-6000000, btcutil.RoundHalfEven, -2},
+		{2000001, btcutil.RoundHalfEven, 1},
+		{1999999, btcutil.RoundHalfAwayFromZero, 0},
+		{1, btcutil.RoundFloor, 0},
+		{1, btcutil.RoundCeil, 1},
+		{-1, btcutil.RoundFloor, -1},
+		{-1, btcutil.RoundCeil, 0},
+	}
+	for _, test := range tests {
+		got := price.ToFiat(test.amount, test.mode)
+		if got.Cmp(big.NewInt(test.want)) != 0 {
+			t.Errorf("ToFiat(%d, %v): got %v, want %d", int64(test.amount),
+				test.mode, got, test.want)
+		}
+	}
+
+	// Rates with more decimals than a float64 holds convert exactly.
+	price, err = btcutil.NewPrice("USD", "43250.123456789012345", 2)
+	if err != nil {
+		t.Fatalf("NewPrice: %v", err)
+	}
+	got := price.ToFiat(btcutil.MaxHao, btcutil.RoundFloor)
+	want, _ := new(big.Int).SetString("1859755308641927", 10)
+	if got.Cmp(want) != 0 {
+		t.Errorf("ToFiat(MaxHao): got %v, want %v", got, want)
+	}
+}
+
+// TestPriceFromFiat ensures fiat amounts are converted to Hao in the
+// requested mode.
+func TestPriceFromFiat(t *testing.T) {
+	price, err := btcutil.NewPrice("EUR", "3", 2)
+	if err != nil {
+		t.Fatalf("NewPrice: %v", err)
+	}
+	tests := []struct {
+		minor int64
+		mode  btcutil.RoundingMode
+		want  btcutil.Amount
+	}{
+		{300, btcutil.RoundFloor, 100000000},
+		{1, btcutil.RoundFloor, 333333},
+		{1, btcutil.RoundCeil, 333334},
+		{2, btcutil.RoundHalfEven, 666667},
+		{-1, btcutil.RoundFloor, -333334},
+	}
+	for _, test := range tests {
+		got, err := price.FromFiat(big.NewInt(test.minor), test.mode)
+		if err != nil || got != test.want {
+			t.Errorf("FromFiat(%d, %v): got %d, %v, want %d", test.minor,
+				test.mode, int64(got), err, int64(test.want))
+		}
+	}
+
+	huge := new(big.Int).Lsh(big.NewInt(1), 80)
+	if _, err := price.FromFiat(huge, btcutil.RoundFloor); err != btcutil.ErrAmountOverflow {
+		t.Errorf("FromFiat: got error %v, want %v", err,
+			btcutil.ErrAmountOverflow)
+	}
+}
+
+// TestPriceFormat ensures prices are validated and fiat amounts formatted
+// with all decimals of the minor unit.
+func TestPriceFormat(t *testing.T) {
+	for _, rate := range []string{"0", "-1", "abc", ""} {
+		if _, err := btcutil.NewPrice("USD", rate, 2); err != btcutil.ErrInvalidPrice {
+			t.Errorf("NewPrice(%q): got error %v, want %v", rate, err,
+				btcutil.ErrInvalidPrice)
+		}
+	}
+
+	price, err := btcutil.NewPrice("USD", "1/3", 2)
+	if err != nil {
+		t.Fatalf("NewPrice: %v", err)
+	}
+	if got := price.Rate(); got.Cmp(big.NewRat(1, 3)) != 0 {
+		t.Errorf("Rate: got %v", got)
+	}
+	tests := []struct {
+		minor int64
+		want  string
+	}{
+		{1230, "12.30 USD"},
+		{5, "0.05 USD"},
+		{-5, "-0.05 USD"},
+		{0, "0.00 USD"},
+	}
+	for _, test := range tests {
+		if got := price.FormatFiat(big.NewInt(test.minor)); got != test.want {
+			t.Errorf("FormatFiat(%d): got %q, want %q", test.minor, got,
+				test.want)
+		}
+	}
+
+	yen, err := btcutil.NewPrice("JPY", "5000000", 0)
+	if err != nil {
+		t.Fatalf("NewPrice: %v", err)
+	}
+	if got := yen.FormatFiat(big.NewInt(42)); got != "42 JPY" {
+		t.Errorf("FormatFiat: got %q", got)
+	}
+	if got := btcutil.RoundingMode(9).String(); got != "Unknown RoundingMode (9)" {
+		t.Errorf("String: got %q", got)
+	}
+}
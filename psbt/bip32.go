@@ -0,0 +1,85 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package psbt
+
+import (
+	"bytes"
+	"io"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcutil/hdkeychain"
+)
+
+// Bip32Derivation is the key origin of a public key of an input or output,
+// which hardware wallets use to find the key they are asked to sign with, or
+// to recognize their own change outputs.
+type Bip32Derivation struct {
+	// PubKey is the serialized public key, compressed or uncompressed.
+	PubKey []byte
+
+	// Origin is the fingerprint of the master key and the derivation path
+	// of the key below it.
+	Origin hdkeychain.KeyOrigin
+}
+
+// parseBip32Derivation parses the key data and value of a BIP0032 derivation
+// field.
+func parseBip32Derivation(keyData, value []byte) (*Bip32Derivation, error) {
+	if len(keyData) != btcec.PubKeyBytesLenCompressed &&
+		len(keyData) != btcec.PubKeyBytesLenUncompressed {
+		return nil, ErrInvalidFormat
+	}
+	origin, err := hdkeychain.DeserializeKeyOrigin(value)
+	if err != nil {
+		return nil, ErrInvalidFormat
+	}
+	return &Bip32Derivation{PubKey: keyData, Origin: origin}, nil
+}
+
+// writeBip32Derivations writes the BIP0032 derivation fields of keyType.
+func writeBip32Derivations(w io.Writer, keyType byte,
+	derivations []*Bip32Derivation) error {
+	for _, d := range derivations {
+		err := writePair(w, keyType, d.PubKey, d.Origin.Serialize())
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// combineBip32Derivations returns a with the derivations of b whose public
+// keys are not in a.
+func combineBip32Derivations(a, b []*Bip32Derivation) []*Bip32Derivation {
+	for _, d := range b {
+		if bip32Derivation(a, d.PubKey) == nil {
+			a = append(a, d)
+		}
+	}
+	return a
+}
+
+// bip32Derivation returns the derivation of pubKey in derivations, or nil
+// when there is none.
+func bip32Derivation(derivations []*Bip32Derivation, pubKey []byte) *Bip32Derivation {
+	for _, d := range derivations {
+		if bytes.Equal(d.PubKey, pubKey) {
+			return d
+		}
+	}
+	return nil
+}
+
+// Bip32Derivation returns the derivation of pubKey listed by the input, or
+// nil when there is none.
+func (in *Input) Bip32Derivation(pubKey []byte) *Bip32Derivation {
+	return bip32Derivation(in.Bip32Derivations, pubKey)
+}
+
+// Bip32Derivation returns the derivation of pubKey listed by the output, or
+// nil when there is none.
+func (out *Output) Bip32Derivation(pubKey []byte) *Bip32Derivation {
+	return bip32Derivation(out.Bip32Derivations, pubKey)
+}
@@ -0,0 +1,106 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package psbt_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/zeusyf/btcutil/hdkeychain"
+	"github.com/zeusyf/btcutil/psbt"
+)
+
+// derivation returns a derivation of a compressed public key starting with
+// prefix from the key origin s.
+func derivation(t *testing.T, prefix byte, s string) *psbt.Bip32Derivation {
+	t.Helper()
+	origin, err := hdkeychain.ParseKeyOrigin(s)
+	if err != nil {
+		t.Fatalf("ParseKeyOrigin: unexpected error: %v", err)
+	}
+	pubKey := append([]byte{0x02, prefix}, bytes.Repeat([]byte{0x01}, 31)...)
+	return &psbt.Bip32Derivation{PubKey: pubKey, Origin: origin}
+}
+
+// TestBip32Derivations ensures the key origins of inputs and outputs survive
+// serializing, combining and canonical encoding.
+func TestBip32Derivations(t *testing.T) {
+	dA := derivation(t, 0x0a, "d34db33f/44'/0'/0'/0/1")
+	dB := derivation(t, 0x0b, "d34db33f/44'/0'/0'/1/0")
+
+	p := testPacket(t)
+	p.Inputs[0].Bip32Derivations = []*psbt.Bip32Derivation{dB, dA}
+	p.Outputs[0].Bip32Derivations = []*psbt.Bip32Derivation{dB}
+
+	var buf bytes.Buffer
+	if err := p.Serialize(&buf); err != nil {
+		t.Fatalf("Serialize: unexpected error: %v", err)
+	}
+	parsed, err := psbt.Parse(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("Parse: unexpected error: %v", err)
+	}
+	got := parsed.Inputs[0].Bip32Derivation(dA.PubKey)
+	if len(parsed.Inputs[0].Bip32Derivations) != 2 || got == nil ||
+		got.Origin.String() != dA.Origin.String() ||
+		parsed.Outputs[0].Bip32Derivation(dB.PubKey) == nil ||
+		parsed.Outputs[0].Bip32Derivation(dA.PubKey) != nil {
+		t.Errorf("Parse: got %+v", parsed)
+	}
+
+	// Combining adds the derivations of keys the packet lacks.
+	other := testPacket(t)
+	other.Inputs[0].Bip32Derivations = []*psbt.Bip32Derivation{dA}
+	if err := other.Combine(p); err != nil {
+		t.Fatalf("Combine: unexpected error: %v", err)
+	}
+	if len(other.Inputs[0].Bip32Derivations) != 2 ||
+		other.Inputs[0].Bip32Derivations[0] != dA ||
+		len(other.Outputs[0].Bip32Derivations) != 1 {
+		t.Errorf("Combine: got %+v", other)
+	}
+
+	jsonP, err := p.CanonicalJSON()
+	if err != nil {
+		t.Fatalf("CanonicalJSON: unexpected error: %v", err)
+	}
+	jsonOther, err := other.CanonicalJSON()
+	if err != nil {
+		t.Fatalf("CanonicalJSON: unexpected error: %v", err)
+	}
+	if !bytes.Equal(jsonP, jsonOther) ||
+		!strings.Contains(string(jsonP), `"master_fingerprint":"d34db33f"`) ||
+		!strings.Contains(string(jsonP), `"path":"m/44'/0'/0'/0/1"`) {
+		t.Errorf("CanonicalJSON: got %s and %s", jsonP, jsonOther)
+	}
+}
+
+// TestParseBip32DerivationErrors ensures derivation fields with a malformed
+// public key or key origin are rejected.
+func TestParseBip32DerivationErrors(t *testing.T) {
+	pubKey := derivation(t, 0x0a, "d34db33f").PubKey
+	tests := []struct {
+		name  string
+		key   []byte
+		value []byte
+	}{
+		{"short key", append([]byte{0x06}, pubKey[:32]...), []byte{1, 2, 3, 4}},
+		{"no fingerprint", append([]byte{0x06}, pubKey...), []byte{1, 2}},
+		{"partial index", append([]byte{0x06}, pubKey...), []byte{1, 2, 3, 4, 5}},
+	}
+	for _, test := range tests {
+		p := testPacket(t)
+		p.Inputs[0].Unknowns = []*psbt.Unknown{{Key: test.key, Value: test.value}}
+		var buf bytes.Buffer
+		if err := p.Serialize(&buf); err != nil {
+			t.Fatalf("Serialize: unexpected error: %v", err)
+		}
+		if _, err := psbt.Parse(bytes.NewReader(buf.Bytes())); err != psbt.ErrInvalidFormat {
+			t.Errorf("Parse(%s): got error %v, want %v", test.name, err,
+				psbt.ErrInvalidFormat)
+		}
+	}
+}
@@ -0,0 +1,412 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package psbt
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/omega/token"
+)
+
+// ErrUnknownTokenValue describes an error where a transaction output holds a
+// token value which has no canonical representation.
+var ErrUnknownTokenValue = errors.New("unknown token value")
+
+// canonicalToken is the canonical representation of a token.  Numeric tokens
+// have a value and non-numeric tokens a hash.
+type canonicalToken struct {
+	Type   uint64  `json:"type"`
+	Value  *int64  `json:"value,omitempty"`
+	Hash   *string `json:"hash,omitempty"`
+	Rights *string `json:"rights,omitempty"`
+}
+
+type canonicalTxIn struct {
+	TxID     string `json:"txid"`
+	Vout     uint32 `json:"vout"`
+	Sequence uint32 `json:"sequence"`
+}
+
+type canonicalTxOut struct {
+	Token    canonicalToken `json:"token"`
+	PkScript string         `json:"pkscript"`
+}
+
+// canonicalTx is the canonical representation of a transaction.  The hex
+// serialization is included so the representation covers every field of the
+// transaction, including those not decoded.
+type canonicalTx struct {
+	TxID     string           `json:"txid"`
+	Version  int32            `json:"version"`
+	LockTime uint32           `json:"locktime"`
+	Inputs   []canonicalTxIn  `json:"vin"`
+	Outputs  []canonicalTxOut `json:"vout"`
+	Hex      string           `json:"hex"`
+}
+
+type canonicalUnknown struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+type canonicalPartialSig struct {
+	PubKey    string `json:"pubkey"`
+	Signature string `json:"signature"`
+}
+
+type canonicalBip32Derivation struct {
+	PubKey      string `json:"pubkey"`
+	Fingerprint string `json:"master_fingerprint"`
+	Path        string `json:"path"`
+}
+
+type canonicalTaprootScriptSig struct {
+	XOnlyPubKey string `json:"xonly_pubkey"`
+	LeafHash    string `json:"leaf_hash"`
+	Signature   string `json:"signature"`
+}
+
+type canonicalTaprootLeafScript struct {
+	ControlBlock string `json:"control_block"`
+	Script       string `json:"script"`
+	LeafVersion  byte   `json:"leaf_version"`
+}
+
+type canonicalTaprootTapLeaf struct {
+	Depth       uint8  `json:"depth"`
+	LeafVersion byte   `json:"leaf_version"`
+	Script      string `json:"script"`
+}
+
+// canonicalInput is the canonical representation of an input.  Taproot
+// fields are omitted when empty, so inputs without them encode as they did
+// before the fields existed.
+type canonicalInput struct {
+	NonWitnessUtxo     *canonicalTx                 `json:"non_witness_utxo,omitempty"`
+	PartialSigs        []canonicalPartialSig        `json:"partial_sigs"`
+	SighashType        uint32                       `json:"sighash_type"`
+	RedeemScript       *string                      `json:"redeem_script,omitempty"`
+	Bip32Derivations   []canonicalBip32Derivation   `json:"bip32_derivs,omitempty"`
+	FinalScriptSig     *string                      `json:"final_scriptsig,omitempty"`
+	TaprootKeySig      *string                      `json:"taproot_key_sig,omitempty"`
+	TaprootScriptSigs  []canonicalTaprootScriptSig  `json:"taproot_script_sigs,omitempty"`
+	TaprootLeafScripts []canonicalTaprootLeafScript `json:"taproot_leaf_scripts,omitempty"`
+	TaprootInternalKey *string                      `json:"taproot_internal_key,omitempty"`
+	TaprootMerkleRoot  *string                      `json:"taproot_merkle_root,omitempty"`
+	Unknowns           []canonicalUnknown           `json:"unknown"`
+}
+
+type canonicalOutput struct {
+	RedeemScript       *string                    `json:"redeem_script,omitempty"`
+	Bip32Derivations   []canonicalBip32Derivation `json:"bip32_derivs,omitempty"`
+	TaprootInternalKey *string                    `json:"taproot_internal_key,omitempty"`
+	TaprootTapTree     []canonicalTaprootTapLeaf  `json:"taproot_tap_tree,omitempty"`
+	Unknowns           []canonicalUnknown         `json:"unknown"`
+}
+
+type canonicalPacket struct {
+	Tx       *canonicalTx       `json:"tx"`
+	Inputs   []canonicalInput   `json:"inputs"`
+	Outputs  []canonicalOutput  `json:"outputs"`
+	Unknowns []canonicalUnknown `json:"unknown"`
+}
+
+// optionalHex returns the hex encoding of b, or nil when b is nil, so that
+// absent fields are told apart from empty ones.
+func optionalHex(b []byte) *string {
+	if b == nil {
+		return nil
+	}
+	s := hex.EncodeToString(b)
+	return &s
+}
+
+// sortedBip32Derivations returns a copy of derivations sorted by public key,
+// or nil when derivations is nil.
+func sortedBip32Derivations(derivations []*Bip32Derivation) []*Bip32Derivation {
+	if derivations == nil {
+		return nil
+	}
+	sorted := append([]*Bip32Derivation(nil), derivations...)
+	sort.SliceStable(sorted, func(a, b int) bool {
+		return bytes.Compare(sorted[a].PubKey, sorted[b].PubKey) < 0
+	})
+	return sorted
+}
+
+// canonicalBip32Derivations returns the canonical representation of
+// derivations, whose master fingerprints and paths are written as in key
+// origins.
+func canonicalBip32Derivations(derivations []*Bip32Derivation) []canonicalBip32Derivation {
+	var c []canonicalBip32Derivation
+	for _, d := range derivations {
+		c = append(c, canonicalBip32Derivation{
+			PubKey:      hex.EncodeToString(d.PubKey),
+			Fingerprint: fmt.Sprintf("%08x", d.Origin.Fingerprint),
+			Path:        d.Origin.Path.String(),
+		})
+	}
+	return c
+}
+
+// newCanonicalTx returns the canonical representation of tx.
+func newCanonicalTx(tx *wire.MsgTx) (*canonicalTx, error) {
+	raw, err := serializeTx(tx)
+	if err != nil {
+		return nil, err
+	}
+	c := &canonicalTx{
+		TxID:     tx.TxHash().String(),
+		Version:  tx.Version,
+		LockTime: tx.LockTime,
+		Inputs:   make([]canonicalTxIn, 0, len(tx.TxIn)),
+		Outputs:  make([]canonicalTxOut, 0, len(tx.TxOut)),
+		Hex:      hex.EncodeToString(raw),
+	}
+	for _, txIn := range tx.TxIn {
+		c.Inputs = append(c.Inputs, canonicalTxIn{
+			TxID:     txIn.PreviousOutPoint.Hash.String(),
+			Vout:     txIn.PreviousOutPoint.Index,
+			Sequence: txIn.Sequence,
+		})
+	}
+	for _, txOut := range tx.TxOut {
+		tok := canonicalToken{Type: txOut.Token.TokenType}
+		switch v := txOut.Token.Value.(type) {
+		case *token.NumeralVal:
+			val := v.Val
+			tok.Value = &val
+		case *token.HashVal:
+			h := v.Hash.String()
+			tok.Hash = &h
+		default:
+			return nil, ErrUnknownTokenValue
+		}
+		if txOut.Token.Rights != nil {
+			r := txOut.Token.Rights.String()
+			tok.Rights = &r
+		}
+		c.Outputs = append(c.Outputs, canonicalTxOut{
+			Token:    tok,
+			PkScript: hex.EncodeToString(txOut.PkScript),
+		})
+	}
+	return c, nil
+}
+
+// sortedUnknowns returns a copy of unknowns sorted by key.
+func sortedUnknowns(unknowns []*Unknown) []*Unknown {
+	if unknowns == nil {
+		return nil
+	}
+	sorted := append([]*Unknown(nil), unknowns...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i].Key, sorted[j].Key) < 0
+	})
+	return sorted
+}
+
+// canonicalUnknowns returns the canonical representation of unknowns, which
+// must be sorted.
+func canonicalUnknowns(unknowns []*Unknown) []canonicalUnknown {
+	c := make([]canonicalUnknown, 0, len(unknowns))
+	for _, u := range unknowns {
+		c = append(c, canonicalUnknown{
+			Key:   hex.EncodeToString(u.Key),
+			Value: hex.EncodeToString(u.Value),
+		})
+	}
+	return c
+}
+
+// Canonical returns a copy of the packet whose partial signatures and BIP0032
+// derivations are sorted by public key, whose taproot script signatures are
+// sorted by key and leaf hash, whose taproot leaf scripts are sorted by
+// control block and whose unknowns are sorted by key.  Packets holding the
+// same data have the same canonical serialization whichever order their
+// signatures were added in.  The transactions and scripts of the copy are
+// shared with the packet.
+func (p *Packet) Canonical() *Packet {
+	c := &Packet{
+		UnsignedTx: p.UnsignedTx,
+		Inputs:     make([]Input, len(p.Inputs)),
+		Outputs:    make([]Output, len(p.Outputs)),
+		Unknowns:   sortedUnknowns(p.Unknowns),
+	}
+	for i, in := range p.Inputs {
+		if in.PartialSigs != nil {
+			in.PartialSigs = append([]*PartialSig(nil), in.PartialSigs...)
+			sigs := in.PartialSigs
+			sort.SliceStable(sigs, func(a, b int) bool {
+				return bytes.Compare(sigs[a].PubKey, sigs[b].PubKey) < 0
+			})
+		}
+		in.Bip32Derivations = sortedBip32Derivations(in.Bip32Derivations)
+		if in.TaprootScriptSpendSigs != nil {
+			in.TaprootScriptSpendSigs = append([]*TaprootScriptSpendSig(nil),
+				in.TaprootScriptSpendSigs...)
+			sigs := in.TaprootScriptSpendSigs
+			sort.SliceStable(sigs, func(a, b int) bool {
+				if c := bytes.Compare(sigs[a].XOnlyPubKey, sigs[b].XOnlyPubKey); c != 0 {
+					return c < 0
+				}
+				return bytes.Compare(sigs[a].LeafHash, sigs[b].LeafHash) < 0
+			})
+		}
+		if in.TaprootLeafScripts != nil {
+			in.TaprootLeafScripts = append([]*TaprootTapLeafScript(nil),
+				in.TaprootLeafScripts...)
+			leaves := in.TaprootLeafScripts
+			sort.SliceStable(leaves, func(a, b int) bool {
+				return bytes.Compare(leaves[a].ControlBlock,
+					leaves[b].ControlBlock) < 0
+			})
+		}
+		in.Unknowns = sortedUnknowns(in.Unknowns)
+		c.Inputs[i] = in
+	}
+	for i, out := range p.Outputs {
+		out.Bip32Derivations = sortedBip32Derivations(out.Bip32Derivations)
+		out.Unknowns = sortedUnknowns(out.Unknowns)
+		c.Outputs[i] = out
+	}
+	return c
+}
+
+// CanonicalJSON returns the canonical JSON encoding of the packet.  Fields
+// are always written in the same order, without whitespace, with byte
+// strings hex encoded and partial signatures and unknowns sorted, so
+// participants can compare proposals byte for byte.
+func (p *Packet) CanonicalJSON() ([]byte, error) {
+	if err := p.SanityCheck(); err != nil {
+		return nil, err
+	}
+	p = p.Canonical()
+	tx, err := newCanonicalTx(p.UnsignedTx)
+	if err != nil {
+		return nil, err
+	}
+	c := canonicalPacket{
+		Tx:       tx,
+		Inputs:   make([]canonicalInput, 0, len(p.Inputs)),
+		Outputs:  make([]canonicalOutput, 0, len(p.Outputs)),
+		Unknowns: canonicalUnknowns(p.Unknowns),
+	}
+	for _, in := range p.Inputs {
+		ci := canonicalInput{
+			PartialSigs:        make([]canonicalPartialSig, 0, len(in.PartialSigs)),
+			SighashType:        in.SighashType,
+			RedeemScript:       optionalHex(in.RedeemScript),
+			Bip32Derivations:   canonicalBip32Derivations(in.Bip32Derivations),
+			FinalScriptSig:     optionalHex(in.FinalScriptSig),
+			TaprootKeySig:      optionalHex(in.TaprootKeySpendSig),
+			TaprootInternalKey: optionalHex(in.TaprootInternalKey),
+			TaprootMerkleRoot:  optionalHex(in.TaprootMerkleRoot),
+			Unknowns:           canonicalUnknowns(in.Unknowns),
+		}
+		if in.NonWitnessUtxo != nil {
+			ci.NonWitnessUtxo, err = newCanonicalTx(in.NonWitnessUtxo)
+			if err != nil {
+				return nil, err
+			}
+		}
+		for _, sig := range in.PartialSigs {
+			ci.PartialSigs = append(ci.PartialSigs, canonicalPartialSig{
+				PubKey:    hex.EncodeToString(sig.PubKey),
+				Signature: hex.EncodeToString(sig.Signature),
+			})
+		}
+		for _, sig := range in.TaprootScriptSpendSigs {
+			ci.TaprootScriptSigs = append(ci.TaprootScriptSigs,
+				canonicalTaprootScriptSig{
+					XOnlyPubKey: hex.EncodeToString(sig.XOnlyPubKey),
+					LeafHash:    hex.EncodeToString(sig.LeafHash),
+					Signature:   hex.EncodeToString(sig.Signature),
+				})
+		}
+		for _, leaf := range in.TaprootLeafScripts {
+			ci.TaprootLeafScripts = append(ci.TaprootLeafScripts,
+				canonicalTaprootLeafScript{
+					ControlBlock: hex.EncodeToString(leaf.ControlBlock),
+					Script:       hex.EncodeToString(leaf.Script),
+					LeafVersion:  leaf.LeafVersion,
+				})
+		}
+		c.Inputs = append(c.Inputs, ci)
+	}
+	for _, out := range p.Outputs {
+		co := canonicalOutput{
+			RedeemScript:       optionalHex(out.RedeemScript),
+			Bip32Derivations:   canonicalBip32Derivations(out.Bip32Derivations),
+			TaprootInternalKey: optionalHex(out.TaprootInternalKey),
+			Unknowns:           canonicalUnknowns(out.Unknowns),
+		}
+		for _, leaf := range out.TaprootTapTree {
+			co.TaprootTapTree = append(co.TaprootTapTree,
+				canonicalTaprootTapLeaf{
+					Depth:       leaf.Depth,
+					LeafVersion: leaf.LeafVersion,
+					Script:      hex.EncodeToString(leaf.Script),
+				})
+		}
+		c.Outputs = append(c.Outputs, co)
+	}
+	return json.Marshal(c)
+}
+
+// CanonicalHex returns the hex encoding of the canonical serialization of the
+// packet.
+func (p *Packet) CanonicalHex() (string, error) {
+	var buf bytes.Buffer
+	if err := p.Canonical().Serialize(&buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf.Bytes()), nil
+}
+
+// ProposalHash returns the SHA256 hash of the canonical serialization of the
+// packet, which participants can exchange to check they hold the same
+// proposal.
+func (p *Packet) ProposalHash() ([sha256.Size]byte, error) {
+	var buf bytes.Buffer
+	if err := p.Canonical().Serialize(&buf); err != nil {
+		return [sha256.Size]byte{}, err
+	}
+	return sha256.Sum256(buf.Bytes()), nil
+}
+
+// CanonicalTxJSON returns the canonical JSON encoding of the unsigned
+// transaction tx, in the same form as the transaction of a packet encoded by
+// CanonicalJSON.
+func CanonicalTxJSON(tx *wire.MsgTx) ([]byte, error) {
+	if len(tx.SignatureScripts) != 0 {
+		return nil, ErrTxHasSignatures
+	}
+	c, err := newCanonicalTx(tx)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(c)
+}
+
+// CanonicalTxHex returns the hex encoding of the serialization of the
+// unsigned transaction tx.
+func CanonicalTxHex(tx *wire.MsgTx) (string, error) {
+	if len(tx.SignatureScripts) != 0 {
+		return "", ErrTxHasSignatures
+	}
+	raw, err := serializeTx(tx)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(raw), nil
+}
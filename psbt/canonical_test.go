@@ -0,0 +1,131 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package psbt_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil/psbt"
+)
+
+// TestCanonical ensures packets holding the same data encode to the same
+// bytes whatever the order their signatures and unknowns were added in.
+func TestCanonical(t *testing.T) {
+	sigA := &psbt.PartialSig{PubKey: []byte{0x02, 0x01}, Signature: []byte{0x30, 0x01}}
+	sigB := &psbt.PartialSig{PubKey: []byte{0x03, 0x01}, Signature: []byte{0x30, 0x02}}
+	unkA := &psbt.Unknown{Key: []byte{0xfc, 0x01}, Value: []byte{0x01}}
+	unkB := &psbt.Unknown{Key: []byte{0xfc, 0x02}, Value: []byte{0x02}}
+
+	a := testPacket(t)
+	a.Inputs[0].PartialSigs = []*psbt.PartialSig{sigA, sigB}
+	a.Inputs[0].RedeemScript = []byte{0x51}
+	a.Unknowns = []*psbt.Unknown{unkA, unkB}
+
+	b := testPacket(t)
+	b.Inputs[0].PartialSigs = []*psbt.PartialSig{sigB, sigA}
+	b.Inputs[0].RedeemScript = []byte{0x51}
+	b.Unknowns = []*psbt.Unknown{unkB, unkA}
+
+	jsonA, err := a.CanonicalJSON()
+	if err != nil {
+		t.Fatalf("CanonicalJSON: unexpected error: %v", err)
+	}
+	jsonB, err := b.CanonicalJSON()
+	if err != nil {
+		t.Fatalf("CanonicalJSON: unexpected error: %v", err)
+	}
+	if !bytes.Equal(jsonA, jsonB) {
+		t.Errorf("CanonicalJSON: got %s and %s", jsonA, jsonB)
+	}
+	if !json.Valid(jsonA) || !strings.HasPrefix(string(jsonA), `{"tx":{"txid":"`) {
+		t.Errorf("CanonicalJSON: got %s", jsonA)
+	}
+	if b.Inputs[0].PartialSigs[0] != sigB || b.Unknowns[0] != unkB {
+		t.Errorf("CanonicalJSON: packet was modified")
+	}
+
+	hexA, err := a.CanonicalHex()
+	if err != nil {
+		t.Fatalf("CanonicalHex: unexpected error: %v", err)
+	}
+	hexB, err := b.CanonicalHex()
+	if err != nil {
+		t.Fatalf("CanonicalHex: unexpected error: %v", err)
+	}
+	if hexA != hexB || !strings.HasPrefix(hexA, "70736274ff") {
+		t.Errorf("CanonicalHex: got %s and %s", hexA, hexB)
+	}
+
+	hashA, err := a.ProposalHash()
+	if err != nil {
+		t.Fatalf("ProposalHash: unexpected error: %v", err)
+	}
+	hashB, err := b.ProposalHash()
+	if err != nil {
+		t.Fatalf("ProposalHash: unexpected error: %v", err)
+	}
+	if hashA != hashB {
+		t.Errorf("ProposalHash: got %x and %x", hashA, hashB)
+	}
+
+	// Any change of the proposal changes its encodings.
+	b.Inputs[0].SighashType = 1
+	if jsonB, _ = b.CanonicalJSON(); bytes.Equal(jsonA, jsonB) {
+		t.Errorf("CanonicalJSON: same encoding for different packets")
+	}
+	if hashB, _ = b.ProposalHash(); hashA == hashB {
+		t.Errorf("ProposalHash: same hash for different packets")
+	}
+}
+
+// TestCanonicalTx ensures unsigned transactions encode in the same form as
+// the transaction of a packet, and signed ones are rejected.
+func TestCanonicalTx(t *testing.T) {
+	p := testPacket(t)
+	txJSON, err := psbt.CanonicalTxJSON(p.UnsignedTx)
+	if err != nil {
+		t.Fatalf("CanonicalTxJSON: unexpected error: %v", err)
+	}
+	packetJSON, err := p.CanonicalJSON()
+	if err != nil {
+		t.Fatalf("CanonicalJSON: unexpected error: %v", err)
+	}
+	if !bytes.Contains(packetJSON, txJSON) {
+		t.Errorf("CanonicalTxJSON: got %s, not in %s", txJSON, packetJSON)
+	}
+	want := `"vout":[{"token":{"type":0,"value":2900},"pkscript":"53"}]`
+	if !bytes.Contains(txJSON, []byte(want)) {
+		t.Errorf("CanonicalTxJSON: got %s, want outputs %s", txJSON, want)
+	}
+
+	var buf bytes.Buffer
+	if err := p.UnsignedTx.Serialize(&buf); err != nil {
+		t.Fatalf("Serialize: unexpected error: %v", err)
+	}
+	txHex, err := psbt.CanonicalTxHex(p.UnsignedTx)
+	if err != nil {
+		t.Fatalf("CanonicalTxHex: unexpected error: %v", err)
+	}
+	if txHex != hex.EncodeToString(buf.Bytes()) ||
+		!bytes.Contains(txJSON, []byte(`"hex":"`+txHex+`"`)) {
+		t.Errorf("CanonicalTxHex: got %s, not in %s", txHex, txJSON)
+	}
+
+	signed := wire.NewMsgTx(wire.TxVersion)
+	signed.SignatureScripts = [][]byte{{0x00}}
+	if _, err := psbt.CanonicalTxJSON(signed); err != psbt.ErrTxHasSignatures {
+		t.Errorf("CanonicalTxJSON: got error %v, want %v", err,
+			psbt.ErrTxHasSignatures)
+	}
+	if _, err := psbt.CanonicalTxHex(signed); err != psbt.ErrTxHasSignatures {
+		t.Errorf("CanonicalTxHex: got error %v, want %v", err,
+			psbt.ErrTxHasSignatures)
+	}
+}
@@ -0,0 +1,264 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package psbt
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/zeusyf/btcd/wire/common"
+)
+
+// proprietaryType is the key type of proprietary fields, in every map.
+const proprietaryType = 0xfc
+
+// OMCIdentifier is the identifier of the proprietary fields of OMC.
+var OMCIdentifier = []byte("omc")
+
+// Subtypes of the proprietary fields of OMC.
+const (
+	// OMCSubtypeTokenType is the subtype of the field of an input or
+	// output holding the token type of the output spent or paid, as a
+	// compact size integer.  Its key has no key data.
+	OMCSubtypeTokenType = 0x00
+)
+
+// ErrNotProprietary describes an error where a key parsed as the key of a
+// proprietary field is of another key type.
+var ErrNotProprietary = errors.New("key is not proprietary")
+
+// ProprietaryKey is the key of a proprietary field of BIP0174: the key type
+// 0xfc followed by the length prefixed identifier of the vendor, a compact
+// size subtype and key data, all defined by the vendor.  Fields of other
+// vendors are kept as Unknowns and written back unchanged, so vendors extend
+// packets without parsers knowing their fields.
+type ProprietaryKey struct {
+	Identifier []byte
+	Subtype    uint64
+	KeyData    []byte
+}
+
+// Bytes returns the serialized key.
+func (k *ProprietaryKey) Bytes() []byte {
+	var buf bytes.Buffer
+	buf.WriteByte(proprietaryType)
+	writeBytes(&buf, k.Identifier)
+	common.WriteVarInt(&buf, varIntProtoVer, k.Subtype)
+	buf.Write(k.KeyData)
+	return buf.Bytes()
+}
+
+// ParseProprietaryKey parses the serialized key of a proprietary field.
+// ErrNotProprietary is returned for keys of other types, and
+// ErrInvalidFormat for proprietary keys which are truncated.
+func ParseProprietaryKey(key []byte) (*ProprietaryKey, error) {
+	if len(key) == 0 || key[0] != proprietaryType {
+		return nil, ErrNotProprietary
+	}
+	r := bytes.NewReader(key[1:])
+	identifier, err := readBytes(r)
+	if err != nil {
+		return nil, ErrInvalidFormat
+	}
+	subtype, err := common.ReadVarInt(r, varIntProtoVer)
+	if err != nil {
+		return nil, ErrInvalidFormat
+	}
+	keyData := key[len(key)-r.Len():]
+	if len(keyData) == 0 {
+		keyData = nil
+	}
+	return &ProprietaryKey{identifier, subtype, keyData}, nil
+}
+
+// ProprietaryField is a proprietary field of a map.
+type ProprietaryField struct {
+	Key   ProprietaryKey
+	Value []byte
+}
+
+// proprietary returns the value of the field of unknowns with key, and
+// whether there is one.
+func proprietary(unknowns []*Unknown, key *ProprietaryKey) ([]byte, bool) {
+	k := key.Bytes()
+	for _, u := range unknowns {
+		if bytes.Equal(u.Key, k) {
+			return u.Value, true
+		}
+	}
+	return nil, false
+}
+
+// setProprietary returns unknowns with the field of key set to value.  The
+// pair is replaced rather than changed in place, as pairs may be shared with
+// the packets they were combined from.
+func setProprietary(unknowns []*Unknown, key *ProprietaryKey, value []byte) []*Unknown {
+	k := key.Bytes()
+	for i, u := range unknowns {
+		if bytes.Equal(u.Key, k) {
+			unknowns[i] = &Unknown{k, value}
+			return unknowns
+		}
+	}
+	return append(unknowns, &Unknown{k, value})
+}
+
+// deleteProprietary returns unknowns without the field of key.
+func deleteProprietary(unknowns []*Unknown, key *ProprietaryKey) []*Unknown {
+	k := key.Bytes()
+	var kept []*Unknown
+	for _, u := range unknowns {
+		if !bytes.Equal(u.Key, k) {
+			kept = append(kept, u)
+		}
+	}
+	return kept
+}
+
+// proprietaryFields returns the proprietary fields of unknowns with the
+// passed identifier, or of any identifier when it is nil, in the order of
+// unknowns.
+func proprietaryFields(unknowns []*Unknown, identifier []byte) []ProprietaryField {
+	var fields []ProprietaryField
+	for _, u := range unknowns {
+		key, err := ParseProprietaryKey(u.Key)
+		if err != nil {
+			continue
+		}
+		if identifier != nil && !bytes.Equal(key.Identifier, identifier) {
+			continue
+		}
+		fields = append(fields, ProprietaryField{*key, u.Value})
+	}
+	return fields
+}
+
+// onlyProprietary returns whether every field of unknowns is proprietary.
+func onlyProprietary(unknowns []*Unknown) bool {
+	for _, u := range unknowns {
+		if len(u.Key) == 0 || u.Key[0] != proprietaryType {
+			return false
+		}
+	}
+	return true
+}
+
+// Proprietary returns the value of the global proprietary field with key, and
+// whether there is one.
+func (p *Packet) Proprietary(key *ProprietaryKey) ([]byte, bool) {
+	return proprietary(p.Unknowns, key)
+}
+
+// SetProprietary sets the global proprietary field with key to value.
+func (p *Packet) SetProprietary(key *ProprietaryKey, value []byte) {
+	p.Unknowns = setProprietary(p.Unknowns, key, value)
+}
+
+// DeleteProprietary removes the global proprietary field with key.
+func (p *Packet) DeleteProprietary(key *ProprietaryKey) {
+	p.Unknowns = deleteProprietary(p.Unknowns, key)
+}
+
+// ProprietaryFields returns the global proprietary fields with the passed
+// identifier, or all of them when it is nil.
+func (p *Packet) ProprietaryFields(identifier []byte) []ProprietaryField {
+	return proprietaryFields(p.Unknowns, identifier)
+}
+
+// Proprietary returns the value of the proprietary field of the input with
+// key, and whether there is one.
+func (in *Input) Proprietary(key *ProprietaryKey) ([]byte, bool) {
+	return proprietary(in.Unknowns, key)
+}
+
+// SetProprietary sets the proprietary field of the input with key to value.
+func (in *Input) SetProprietary(key *ProprietaryKey, value []byte) {
+	in.Unknowns = setProprietary(in.Unknowns, key, value)
+}
+
+// DeleteProprietary removes the proprietary field of the input with key.
+func (in *Input) DeleteProprietary(key *ProprietaryKey) {
+	in.Unknowns = deleteProprietary(in.Unknowns, key)
+}
+
+// ProprietaryFields returns the proprietary fields of the input with the
+// passed identifier, or all of them when it is nil.
+func (in *Input) ProprietaryFields(identifier []byte) []ProprietaryField {
+	return proprietaryFields(in.Unknowns, identifier)
+}
+
+// TokenType returns the token type of the output spent by the input, as
+// annotated by SetTokenType, and whether it is annotated.
+func (in *Input) TokenType() (uint64, bool) {
+	return tokenType(in.Unknowns)
+}
+
+// SetTokenType annotates the input with the token type of the output it
+// spends, for signers which do not have the previous transaction.
+func (in *Input) SetTokenType(tokenType uint64) {
+	in.Unknowns = setTokenType(in.Unknowns, tokenType)
+}
+
+// Proprietary returns the value of the proprietary field of the output with
+// key, and whether there is one.
+func (out *Output) Proprietary(key *ProprietaryKey) ([]byte, bool) {
+	return proprietary(out.Unknowns, key)
+}
+
+// SetProprietary sets the proprietary field of the output with key to value.
+func (out *Output) SetProprietary(key *ProprietaryKey, value []byte) {
+	out.Unknowns = setProprietary(out.Unknowns, key, value)
+}
+
+// DeleteProprietary removes the proprietary field of the output with key.
+func (out *Output) DeleteProprietary(key *ProprietaryKey) {
+	out.Unknowns = deleteProprietary(out.Unknowns, key)
+}
+
+// ProprietaryFields returns the proprietary fields of the output with the
+// passed identifier, or all of them when it is nil.
+func (out *Output) ProprietaryFields(identifier []byte) []ProprietaryField {
+	return proprietaryFields(out.Unknowns, identifier)
+}
+
+// TokenType returns the token type of the output, as annotated by
+// SetTokenType, and whether it is annotated.
+func (out *Output) TokenType() (uint64, bool) {
+	return tokenType(out.Unknowns)
+}
+
+// SetTokenType annotates the output with its token type, for displays
+// telling the tokens paid apart without decoding the transaction.
+func (out *Output) SetTokenType(tokenType uint64) {
+	out.Unknowns = setTokenType(out.Unknowns, tokenType)
+}
+
+// tokenTypeKey is the key of the OMC token type field.
+var tokenTypeKey = ProprietaryKey{
+	Identifier: OMCIdentifier,
+	Subtype:    OMCSubtypeTokenType,
+}
+
+// tokenType returns the token type annotated in unknowns, and whether there
+// is a well formed one.
+func tokenType(unknowns []*Unknown) (uint64, bool) {
+	value, ok := proprietary(unknowns, &tokenTypeKey)
+	if !ok {
+		return 0, false
+	}
+	r := bytes.NewReader(value)
+	t, err := common.ReadVarInt(r, varIntProtoVer)
+	if err != nil || r.Len() != 0 {
+		return 0, false
+	}
+	return t, true
+}
+
+// setTokenType returns unknowns annotated with tokenType.
+func setTokenType(unknowns []*Unknown, tokenType uint64) []*Unknown {
+	var buf bytes.Buffer
+	common.WriteVarInt(&buf, varIntProtoVer, tokenType)
+	return setProprietary(unknowns, &tokenTypeKey, buf.Bytes())
+}
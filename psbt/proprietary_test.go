@@ -0,0 +1,132 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package psbt_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/zeusyf/btcutil/psbt"
+)
+
+// TestProprietaryKey ensures proprietary keys serialize in the layout of
+// BIP0174 and parse back, and keys of other types or truncated are rejected.
+func TestProprietaryKey(t *testing.T) {
+	key := psbt.ProprietaryKey{
+		Identifier: []byte("acme"),
+		Subtype:    0xfd,
+		KeyData:    []byte{0x01, 0x02},
+	}
+	want := []byte{0xfc, 0x04, 'a', 'c', 'm', 'e', 0xfd, 0xfd, 0x00, 0x01, 0x02}
+	if got := key.Bytes(); !bytes.Equal(got, want) {
+		t.Errorf("Bytes: got %x, want %x", got, want)
+	}
+	parsed, err := psbt.ParseProprietaryKey(want)
+	if err != nil {
+		t.Fatalf("ParseProprietaryKey: unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(*parsed, key) {
+		t.Errorf("ParseProprietaryKey: got %+v, want %+v", parsed, key)
+	}
+
+	tests := []struct {
+		name string
+		in   []byte
+		err  error
+	}{
+		{"empty", nil, psbt.ErrNotProprietary},
+		{"other type", []byte{0x70, 0x00}, psbt.ErrNotProprietary},
+		{"truncated identifier", []byte{0xfc, 0x04, 'a'}, psbt.ErrInvalidFormat},
+		{"no subtype", []byte{0xfc, 0x01, 'a'}, psbt.ErrInvalidFormat},
+	}
+	for _, test := range tests {
+		_, err := psbt.ParseProprietaryKey(test.in)
+		if err != test.err {
+			t.Errorf("%s: got error %v, want %v", test.name, err, test.err)
+		}
+	}
+}
+
+// TestProprietaryFields ensures proprietary fields are set, replaced, listed
+// and removed, and survive serialization and combining alongside the fields
+// of other vendors.
+func TestProprietaryFields(t *testing.T) {
+	acme := &psbt.ProprietaryKey{Identifier: []byte("acme"), Subtype: 1}
+	p := testPacket(t)
+	p.SetProprietary(acme, []byte{0x01})
+	p.SetProprietary(acme, []byte{0x02})
+	p.Inputs[1].SetTokenType(3)
+	p.Outputs[0].SetTokenType(0x1234)
+	p.Outputs[0].SetProprietary(acme, []byte("note"))
+	p.Outputs[0].Unknowns = append(p.Outputs[0].Unknowns,
+		&psbt.Unknown{Key: []byte{0x70}, Value: []byte{0x01}})
+
+	if v, ok := p.Proprietary(acme); !ok || !bytes.Equal(v, []byte{0x02}) {
+		t.Errorf("Proprietary: got %x, %v, want 02", v, ok)
+	}
+	if n := len(p.Unknowns); n != 1 {
+		t.Errorf("SetProprietary: got %d global fields, want 1", n)
+	}
+
+	var buf bytes.Buffer
+	if err := p.Serialize(&buf); err != nil {
+		t.Fatalf("Serialize: unexpected error: %v", err)
+	}
+	parsed, err := psbt.Parse(&buf)
+	if err != nil {
+		t.Fatalf("Parse: unexpected error: %v", err)
+	}
+
+	// The fields of the parsed packet are combined into an empty one.
+	combined := testPacket(t)
+	if err := combined.Combine(parsed); err != nil {
+		t.Fatalf("Combine: unexpected error: %v", err)
+	}
+	if v, ok := combined.Proprietary(acme); !ok || !bytes.Equal(v, []byte{0x02}) {
+		t.Errorf("Proprietary: got %x, %v after combining", v, ok)
+	}
+	if tt, ok := combined.Inputs[1].TokenType(); !ok || tt != 3 {
+		t.Errorf("Input TokenType: got %d, %v, want 3", tt, ok)
+	}
+	if _, ok := combined.Inputs[0].TokenType(); ok {
+		t.Errorf("Input TokenType: got annotation of unannotated input")
+	}
+	out := &combined.Outputs[0]
+	if tt, ok := out.TokenType(); !ok || tt != 0x1234 {
+		t.Errorf("Output TokenType: got %d, %v, want 4660", tt, ok)
+	}
+	if len(out.Unknowns) != 3 {
+		t.Errorf("Combine: got %d output fields, want 3", len(out.Unknowns))
+	}
+
+	fields := out.ProprietaryFields(psbt.OMCIdentifier)
+	if len(fields) != 1 || fields[0].Key.Subtype != psbt.OMCSubtypeTokenType {
+		t.Errorf("ProprietaryFields: got %+v", fields)
+	}
+	if fields := out.ProprietaryFields(nil); len(fields) != 2 {
+		t.Errorf("ProprietaryFields: got %d fields, want 2", len(fields))
+	}
+
+	// Setting a field of the combined packet leaves the packet it was
+	// combined from unchanged.
+	out.SetProprietary(acme, []byte("changed"))
+	if v, _ := parsed.Outputs[0].Proprietary(acme); string(v) != "note" {
+		t.Errorf("SetProprietary: changed combined packet to %q", v)
+	}
+	out.DeleteProprietary(acme)
+	if _, ok := out.Proprietary(acme); ok || len(out.Unknowns) != 2 {
+		t.Errorf("DeleteProprietary: got fields %+v", out.Unknowns)
+	}
+
+	// Malformed token types are not reported.
+	out.SetProprietary(&psbt.ProprietaryKey{
+		Identifier: psbt.OMCIdentifier,
+		Subtype:    psbt.OMCSubtypeTokenType,
+	}, []byte{0x01, 0x02})
+	if _, ok := out.TokenType(); ok {
+		t.Errorf("TokenType: got annotation from malformed value")
+	}
+}
@@ -0,0 +1,432 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package psbt implements partially signed Omega transactions in the format
+// of BIP0174, so that transactions spending the coins of several parties can
+// be passed between them to be signed.
+//
+// A packet holds the unsigned transaction, and for each of its inputs, the
+// previous transaction whose output it spends along with any signatures, and
+// for each of its outputs, any data the signers need.  Omega transactions
+// carry their signature scripts apart from their inputs and have no witness,
+// so inputs always refer to the full previous transaction, and Extract moves
+// the final signature scripts into the signature scripts of the transaction.
+//
+// Inputs spending taproot outputs carry the fields of BIP0371: the internal
+// key, merkle root and leaf scripts added by UpdateTaprootInput, and the key
+// path and script path signatures, which FinalizeTaprootInput pushes in the
+// final signature script in the order of the witness of BIP0341.
+//
+// Inputs and outputs list the key origins of their public keys as
+// Bip32Derivations, holding the hdkeychain.KeyOrigin of each key rather than
+// its path as a string, so hardware wallets can find the keys they are asked
+// to sign with and recognize their own change outputs.
+//
+// Fields this package does not interpret are kept as Unknowns and written
+// back unchanged, through Combine and finalizing too.  Proprietary fields,
+// which vendors define under their own identifier, are read and written with
+// the Proprietary methods of packets, inputs and outputs, and the token
+// types of inputs and outputs are annotated under the OMC identifier.
+package psbt
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+)
+
+// MaxValueSize is the largest key or value read from a serialized packet.
+const MaxValueSize = 4000000
+
+// magic is the prefix of serialized packets.
+var magic = []byte{'p', 's', 'b', 't', 0xff}
+
+// Key types of the global, input and output maps.
+const (
+	globalUnsignedTx = 0x00
+
+	inputNonWitnessUtxo  = 0x00
+	inputPartialSig      = 0x02
+	inputSighashType     = 0x03
+	inputRedeemScript    = 0x04
+	inputBip32Derivation = 0x06
+	inputFinalScriptSig  = 0x07
+
+	inputTaprootKeySig      = 0x13
+	inputTaprootScriptSig   = 0x14
+	inputTaprootLeafScript  = 0x15
+	inputTaprootInternalKey = 0x17
+	inputTaprootMerkleRoot  = 0x18
+
+	outputRedeemScript       = 0x00
+	outputBip32Derivation    = 0x02
+	outputTaprootInternalKey = 0x05
+	outputTaprootTapTree     = 0x06
+)
+
+var (
+	// ErrInvalidMagic describes an error where serialized data does not
+	// start with the packet magic.
+	ErrInvalidMagic = errors.New("invalid packet magic")
+
+	// ErrInvalidFormat describes an error where a serialized packet is
+	// malformed, such as a truncated map, a duplicate key, or a field of
+	// the wrong size.
+	ErrInvalidFormat = errors.New("invalid packet format")
+
+	// ErrTrailingData describes an error where strictly parsed data holds
+	// more than a packet.
+	ErrTrailingData = errors.New("trailing data after packet")
+
+	// ErrTxHasSignatures describes an error where the transaction of a
+	// packet already carries signature scripts.
+	ErrTxHasSignatures = errors.New("packet transaction must be unsigned")
+
+	// ErrInputMismatch describes an error where the maps of a packet do not
+	// match the inputs and outputs of its transaction, or the previous
+	// transaction of an input does not have the output it spends.
+	ErrInputMismatch = errors.New("packet does not match its transaction")
+
+	// ErrIncomplete describes an error where a transaction is extracted
+	// from a packet with inputs lacking a final signature script.
+	ErrIncomplete = errors.New("packet has unsigned inputs")
+
+	// ErrNotFinalizable describes an error where an input can not be
+	// finalized, or a transaction extracted, since the signatures of the
+	// packet are missing or do not satisfy the outputs spent.  It wraps
+	// the more specific error, such as ErrIncomplete or ErrUnsatisfied.
+	ErrNotFinalizable = errors.New("packet is not finalizable")
+)
+
+// Unknown is a key and value pair of a map which the package does not
+// interpret.
+type Unknown struct {
+	Key   []byte
+	Value []byte
+}
+
+// PartialSig is a signature of an input by one of its keys.
+type PartialSig struct {
+	PubKey    []byte
+	Signature []byte
+}
+
+// TaprootScriptSpendSig is a signature of the input spending a script of the
+// tree of a taproot output, by one of the keys of the script.
+type TaprootScriptSpendSig struct {
+	// XOnlyPubKey is the x-only serialization of the key.
+	XOnlyPubKey []byte
+
+	// LeafHash is the leaf hash of the script.
+	LeafHash []byte
+
+	// Signature is the Schnorr signature, followed by its signature hash
+	// type unless it is SigHashDefault.
+	Signature []byte
+}
+
+// TaprootTapLeafScript is a script of the tree of a taproot output along with
+// the control block proving the output commits to it.
+type TaprootTapLeafScript struct {
+	ControlBlock []byte
+	Script       []byte
+	LeafVersion  byte
+}
+
+// TaprootTapLeaf is a leaf of the script tree of a taproot output along with
+// its depth in the tree.  The leaves of a tree are listed in depth-first
+// order, which is enough to rebuild it.
+type TaprootTapLeaf struct {
+	Depth       uint8
+	LeafVersion byte
+	Script      []byte
+}
+
+// Input holds the data for signing an input of the transaction.
+type Input struct {
+	// NonWitnessUtxo is the previous transaction whose output the input
+	// spends.
+	NonWitnessUtxo *wire.MsgTx
+
+	// PartialSigs are the signatures made so far.
+	PartialSigs []*PartialSig
+
+	// SighashType is the signature hash type signers must use, or zero
+	// when any may be used.
+	SighashType uint32
+
+	// RedeemScript is the redeem script of a pay-to-script-hash output.
+	RedeemScript []byte
+
+	// Bip32Derivations are the key origins of the public keys of the
+	// spent output.
+	Bip32Derivations []*Bip32Derivation
+
+	// FinalScriptSig is the complete signature script of the input.
+	FinalScriptSig []byte
+
+	// TaprootKeySpendSig is the signature of the key path of a taproot
+	// output.
+	TaprootKeySpendSig []byte
+
+	// TaprootScriptSpendSigs are the signatures of the scripts of a
+	// taproot output made so far.
+	TaprootScriptSpendSigs []*TaprootScriptSpendSig
+
+	// TaprootLeafScripts are the scripts of a taproot output the input
+	// may spend.
+	TaprootLeafScripts []*TaprootTapLeafScript
+
+	// TaprootInternalKey is the x-only serialization of the internal key
+	// of a taproot output.
+	TaprootInternalKey []byte
+
+	// TaprootMerkleRoot is the root hash of the script tree of a taproot
+	// output.
+	TaprootMerkleRoot []byte
+
+	Unknowns []*Unknown
+}
+
+// Output holds the data signers need about an output of the transaction.
+type Output struct {
+	RedeemScript []byte
+
+	// Bip32Derivations are the key origins of the public keys of the
+	// output.
+	Bip32Derivations []*Bip32Derivation
+
+	// TaprootInternalKey is the x-only serialization of the internal key
+	// of a taproot output.
+	TaprootInternalKey []byte
+
+	// TaprootTapTree is the script tree of a taproot output.
+	TaprootTapTree []TaprootTapLeaf
+
+	Unknowns []*Unknown
+}
+
+// Packet is a partially signed transaction.
+type Packet struct {
+	UnsignedTx *wire.MsgTx
+	Inputs     []Input
+	Outputs    []Output
+	Unknowns   []*Unknown
+}
+
+// New returns a packet for the passed unsigned transaction, with empty maps
+// for its inputs and outputs.
+func New(tx *wire.MsgTx) (*Packet, error) {
+	if len(tx.SignatureScripts) != 0 {
+		return nil, ErrTxHasSignatures
+	}
+	return &Packet{
+		UnsignedTx: tx,
+		Inputs:     make([]Input, len(tx.TxIn)),
+		Outputs:    make([]Output, len(tx.TxOut)),
+	}, nil
+}
+
+// SanityCheck verifies the packet has a map for each input and output of its
+// unsigned transaction, and the previous transaction of each input, when
+// known, has the output spent.
+func (p *Packet) SanityCheck() error {
+	if len(p.UnsignedTx.SignatureScripts) != 0 {
+		return ErrTxHasSignatures
+	}
+	if len(p.Inputs) != len(p.UnsignedTx.TxIn) ||
+		len(p.Outputs) != len(p.UnsignedTx.TxOut) {
+		return ErrInputMismatch
+	}
+	for i := range p.Inputs {
+		prev := p.Inputs[i].NonWitnessUtxo
+		if prev == nil {
+			continue
+		}
+		op := p.UnsignedTx.TxIn[i].PreviousOutPoint
+		if prev.TxHash() != op.Hash || op.Index >= uint32(len(prev.TxOut)) {
+			return ErrInputMismatch
+		}
+	}
+	return nil
+}
+
+// PrevOutput returns the output spent by the i-th input, or nil when its
+// previous transaction is not known.
+func (p *Packet) PrevOutput(i int) *wire.TxOut {
+	prev := p.Inputs[i].NonWitnessUtxo
+	if prev == nil {
+		return nil
+	}
+	return prev.TxOut[p.UnsignedTx.TxIn[i].PreviousOutPoint.Index]
+}
+
+// IsComplete returns whether every input has a final signature script.
+func (p *Packet) IsComplete() bool {
+	for i := range p.Inputs {
+		if p.Inputs[i].FinalScriptSig == nil {
+			return false
+		}
+	}
+	return true
+}
+
+// Combine adds the signatures and data of other, a packet of the same
+// transaction, to p.  Each party to a transaction signs its own copy of the
+// packet, and the copies are then combined into one.  Fields set in both
+// packets keep the value of p.
+func (p *Packet) Combine(other *Packet) error {
+	if other.UnsignedTx.TxHash() != p.UnsignedTx.TxHash() ||
+		len(other.Inputs) != len(p.Inputs) ||
+		len(other.Outputs) != len(p.Outputs) {
+		return ErrInputMismatch
+	}
+
+	for i := range p.Inputs {
+		in, o := &p.Inputs[i], &other.Inputs[i]
+		if in.NonWitnessUtxo == nil {
+			in.NonWitnessUtxo = o.NonWitnessUtxo
+		}
+		for _, sig := range o.PartialSigs {
+			if !hasPartialSig(in.PartialSigs, sig.PubKey) {
+				in.PartialSigs = append(in.PartialSigs, sig)
+			}
+		}
+		if in.SighashType == 0 {
+			in.SighashType = o.SighashType
+		}
+		if in.RedeemScript == nil {
+			in.RedeemScript = o.RedeemScript
+		}
+		in.Bip32Derivations = combineBip32Derivations(
+			in.Bip32Derivations, o.Bip32Derivations)
+		if in.FinalScriptSig == nil {
+			in.FinalScriptSig = o.FinalScriptSig
+		}
+		in.combineTaproot(o)
+		in.Unknowns = combineUnknowns(in.Unknowns, o.Unknowns)
+	}
+	for i := range p.Outputs {
+		out, o := &p.Outputs[i], &other.Outputs[i]
+		if out.RedeemScript == nil {
+			out.RedeemScript = o.RedeemScript
+		}
+		out.Bip32Derivations = combineBip32Derivations(
+			out.Bip32Derivations, o.Bip32Derivations)
+		if out.TaprootInternalKey == nil {
+			out.TaprootInternalKey = o.TaprootInternalKey
+		}
+		if out.TaprootTapTree == nil {
+			out.TaprootTapTree = o.TaprootTapTree
+		}
+		out.Unknowns = combineUnknowns(out.Unknowns, o.Unknowns)
+	}
+	p.Unknowns = combineUnknowns(p.Unknowns, other.Unknowns)
+	return nil
+}
+
+// hasPartialSig returns whether sigs has a signature by pubKey.
+func hasPartialSig(sigs []*PartialSig, pubKey []byte) bool {
+	for _, sig := range sigs {
+		if bytes.Equal(sig.PubKey, pubKey) {
+			return true
+		}
+	}
+	return false
+}
+
+// combineUnknowns returns a with the pairs of b whose keys are not in a.
+func combineUnknowns(a, b []*Unknown) []*Unknown {
+	for _, u := range b {
+		found := false
+		for _, v := range a {
+			if bytes.Equal(u.Key, v.Key) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			a = append(a, u)
+		}
+	}
+	return a
+}
+
+// Extract returns the signed transaction of a complete packet, with the
+// final signature script of each input added to its signature scripts.  The
+// error of incomplete packets wraps both ErrNotFinalizable and ErrIncomplete.
+func (p *Packet) Extract() (*wire.MsgTx, error) {
+	if !p.IsComplete() {
+		return nil, fmt.Errorf("%w: %w", ErrNotFinalizable, ErrIncomplete)
+	}
+
+	// Copy the transaction through its serialization so the packet is
+	// left unchanged.
+	var buf bytes.Buffer
+	if err := p.UnsignedTx.Serialize(&buf); err != nil {
+		return nil, err
+	}
+	tx := new(wire.MsgTx)
+	if err := tx.Deserialize(&buf); err != nil {
+		return nil, err
+	}
+	for i, in := range tx.TxIn {
+		in.SignatureIndex = uint32(len(tx.SignatureScripts))
+		tx.SignatureScripts = append(tx.SignatureScripts,
+			p.Inputs[i].FinalScriptSig)
+	}
+	return tx, nil
+}
+
+// B64Encode returns the serialized packet encoded in base64, the usual form
+// for passing packets between wallets.
+func (p *Packet) B64Encode() (string, error) {
+	var buf bytes.Buffer
+	if err := p.Serialize(&buf); err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// ParseBase64 parses a packet encoded by B64Encode.
+func ParseBase64(s string) (*Packet, error) {
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(bytes.NewReader(b))
+}
+
+// ParseBase64WithOptions parses a packet encoded by B64Encode, tolerating the
+// deviations from the canonical encoding allowed by the strictness of opts.
+// Strict parsing rejects line breaks, padding bits which are not zero and
+// trailing data, all of which ParseBase64 accepts.  Lenient parsing also
+// ignores white space anywhere and missing padding.  See ParseWithOptions
+// for the deviations of the packet itself.
+func ParseBase64WithOptions(s string, opts btcutil.DecodeOptions) (*Packet, error) {
+	enc := base64.StdEncoding.Strict()
+	if opts.Strictness >= btcutil.Lenient {
+		s = strings.Map(func(r rune) rune {
+			if unicode.IsSpace(r) {
+				return -1
+			}
+			return r
+		}, s)
+		s = strings.TrimRight(s, "=")
+		enc = base64.RawStdEncoding
+	} else if strings.ContainsAny(s, "\r\n") {
+		return nil, base64.CorruptInputError(strings.IndexAny(s, "\r\n"))
+	}
+	b, err := enc.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return ParseWithOptions(b, opts)
+}
@@ -0,0 +1,139 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package psbt_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil/psbt"
+	"github.com/zeusyf/omega/token"
+)
+
+// output returns an output paying value of OMC to pkScript.
+func output(value int64, pkScript []byte) *wire.TxOut {
+	return &wire.TxOut{
+		Token:    token.Token{Value: &token.NumeralVal{Val: value}},
+		PkScript: pkScript,
+	}
+}
+
+// testPacket returns a packet spending both outputs of a previous
+// transaction, which is known for each input.
+func testPacket(t *testing.T) *psbt.Packet {
+	prev := wire.NewMsgTx(wire.TxVersion)
+	prev.AddTxIn(&wire.TxIn{SignatureIndex: 0xffffffff})
+	prev.AddTxOut(output(1000, []byte{0x51}))
+	prev.AddTxOut(output(2000, []byte{0x52}))
+
+	tx := wire.NewMsgTx(wire.TxVersion)
+	for i := uint32(0); i < 2; i++ {
+		tx.AddTxIn(&wire.TxIn{
+			PreviousOutPoint: wire.OutPoint{Hash: prev.TxHash(), Index: i},
+			Sequence:         wire.MaxTxInSequenceNum,
+		})
+	}
+	tx.AddTxOut(output(2900, []byte{0x53}))
+
+	p, err := psbt.New(tx)
+	if err != nil {
+		t.Fatalf("New: unexpected error: %v", err)
+	}
+	p.Inputs[0].NonWitnessUtxo = prev
+	p.Inputs[1].NonWitnessUtxo = prev
+	return p
+}
+
+// TestNew ensures packets get a map per input and output, and only unsigned
+// transactions are accepted.
+func TestNew(t *testing.T) {
+	p := testPacket(t)
+	if len(p.Inputs) != 2 || len(p.Outputs) != 1 {
+		t.Fatalf("New: got %d inputs and %d outputs", len(p.Inputs),
+			len(p.Outputs))
+	}
+	if err := p.SanityCheck(); err != nil {
+		t.Errorf("SanityCheck: unexpected error: %v", err)
+	}
+	if out := p.PrevOutput(1); out == nil || out.PkScript[0] != 0x52 {
+		t.Errorf("PrevOutput: got %+v", out)
+	}
+
+	signed := wire.NewMsgTx(wire.TxVersion)
+	signed.SignatureScripts = [][]byte{{0x00}}
+	if _, err := psbt.New(signed); err != psbt.ErrTxHasSignatures {
+		t.Errorf("New: got error %v, want %v", err, psbt.ErrTxHasSignatures)
+	}
+
+	p.Inputs[1].NonWitnessUtxo = wire.NewMsgTx(wire.TxVersion)
+	if err := p.SanityCheck(); err != psbt.ErrInputMismatch {
+		t.Errorf("SanityCheck: got error %v, want %v", err,
+			psbt.ErrInputMismatch)
+	}
+	p.Inputs[1].NonWitnessUtxo = nil
+	if out := p.PrevOutput(1); out != nil {
+		t.Errorf("PrevOutput: got %+v without previous transaction", out)
+	}
+}
+
+// TestCombineExtract ensures packets signed by different parties combine
+// into a complete packet, from which the signed transaction is extracted.
+func TestCombineExtract(t *testing.T) {
+	alice, bob := testPacket(t), testPacket(t)
+	alice.Inputs[0].FinalScriptSig = []byte{0x01, 0xaa}
+	alice.Inputs[0].PartialSigs = []*psbt.PartialSig{{PubKey: []byte{0x02}, Signature: []byte{0xaa}}}
+	bob.Inputs[0].PartialSigs = []*psbt.PartialSig{
+		{PubKey: []byte{0x02}, Signature: []byte{0xff}},
+		{PubKey: []byte{0x03}, Signature: []byte{0xbb}},
+	}
+	bob.Inputs[1].FinalScriptSig = []byte{0x01, 0xbb}
+	bob.Inputs[1].SighashType = 1
+	bob.Outputs[0].RedeemScript = []byte{0x51}
+	bob.Unknowns = []*psbt.Unknown{{Key: []byte{0x70}, Value: []byte{0x01}}}
+
+	if alice.IsComplete() {
+		t.Errorf("IsComplete: got true for half signed packet")
+	}
+	_, err := alice.Extract()
+	if !errors.Is(err, psbt.ErrNotFinalizable) ||
+		!errors.Is(err, psbt.ErrIncomplete) {
+
+		t.Errorf("Extract: got error %v, want %v", err,
+			psbt.ErrNotFinalizable)
+	}
+
+	if err := alice.Combine(bob); err != nil {
+		t.Fatalf("Combine: unexpected error: %v", err)
+	}
+	in := alice.Inputs
+	if !alice.IsComplete() || len(in[0].PartialSigs) != 2 ||
+		in[0].PartialSigs[0].Signature[0] != 0xaa || in[1].SighashType != 1 ||
+		alice.Outputs[0].RedeemScript == nil || len(alice.Unknowns) != 1 {
+		t.Errorf("Combine: got %+v", alice)
+	}
+
+	tx, err := alice.Extract()
+	if err != nil {
+		t.Fatalf("Extract: unexpected error: %v", err)
+	}
+	for i, txIn := range tx.TxIn {
+		sigScript := tx.SignatureScripts[txIn.SignatureIndex]
+		if !bytes.Equal(sigScript, in[i].FinalScriptSig) {
+			t.Errorf("Extract: input %d has signature script %x", i,
+				sigScript)
+		}
+	}
+	if len(alice.UnsignedTx.SignatureScripts) != 0 {
+		t.Errorf("Extract: modified the unsigned transaction")
+	}
+
+	other := testPacket(t)
+	other.UnsignedTx.LockTime = 1
+	if err := alice.Combine(other); err != psbt.ErrInputMismatch {
+		t.Errorf("Combine: got error %v, want %v", err, psbt.ErrInputMismatch)
+	}
+}
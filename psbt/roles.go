@@ -0,0 +1,441 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package psbt
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/scriptclass"
+	"github.com/zeusyf/btcutil/signing"
+	"github.com/zeusyf/btcutil/taproot"
+)
+
+var (
+	// ErrNotEmpty describes an error where a packet handed out by its
+	// creator already carries data for its inputs or outputs.
+	ErrNotEmpty = errors.New("packet carries input or output data")
+
+	// ErrRedeemScriptMismatch describes an error where the redeem script
+	// of an input or output does not hash to the script hash paid by its
+	// output, or is set for an output which does not pay to a script
+	// hash.
+	ErrRedeemScriptMismatch = errors.New("redeem script does not match " +
+		"output")
+
+	// ErrUnsatisfied describes an error where the signatures of an input
+	// do not satisfy the script of the output it spends.
+	ErrUnsatisfied = errors.New("input signatures do not satisfy script")
+
+	// ErrNotPushOnly describes an error where the final signature script
+	// of an input holds opcodes other than data pushes.
+	ErrNotPushOnly = errors.New("final signature script is not push-only")
+)
+
+// Role is a role of BIP0174, a party handling a packet at some stage of its
+// life.
+type Role byte
+
+// These constants are the roles whose packets can be checked.  Combiners are
+// left out, since Combine checks the packets it combines itself.
+const (
+	RoleCreator Role = iota
+	RoleUpdater
+	RoleSigner
+	RoleFinalizer
+	RoleExtractor
+)
+
+// roleNames maps each role to its name.
+var roleNames = map[Role]string{
+	RoleCreator:   "creator",
+	RoleUpdater:   "updater",
+	RoleSigner:    "signer",
+	RoleFinalizer: "finalizer",
+	RoleExtractor: "extractor",
+}
+
+// String implements the fmt.Stringer interface.
+func (r Role) String() string {
+	if name, ok := roleNames[r]; ok {
+		return name
+	}
+	return "unknown role"
+}
+
+// RoleError describes an error where a packet is not in a legal state for a
+// role to handle it.
+type RoleError struct {
+	// Role is the role the packet was checked for.
+	Role Role
+
+	// Input is the index of the offending input, or -1 when no input is
+	// at fault.
+	Input int
+
+	// Output is the index of the offending output, or -1 when no output
+	// is at fault.
+	Output int
+
+	// Err is the reason the packet is not legal.
+	Err error
+}
+
+// Error returns the error along with the role and the offending input or
+// output.
+func (e *RoleError) Error() string {
+	switch {
+	case e.Input >= 0:
+		return fmt.Sprintf("%v: input %d: %v", e.Role, e.Input, e.Err)
+	case e.Output >= 0:
+		return fmt.Sprintf("%v: output %d: %v", e.Role, e.Output, e.Err)
+	}
+	return fmt.Sprintf("%v: %v", e.Role, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *RoleError) Unwrap() error {
+	return e.Err
+}
+
+// packetError returns a *RoleError for the packet as a whole.
+func packetError(role Role, err error) *RoleError {
+	return &RoleError{Role: role, Input: -1, Output: -1, Err: err}
+}
+
+// inputError returns a *RoleError for the i-th input.
+func inputError(role Role, i int, err error) *RoleError {
+	return &RoleError{Role: role, Input: i, Output: -1, Err: err}
+}
+
+// outputError returns a *RoleError for the i-th output.
+func outputError(role Role, i int, err error) *RoleError {
+	return &RoleError{Role: role, Input: -1, Output: i, Err: err}
+}
+
+// CheckRole verifies the packet is in a legal state for role to handle it,
+// calling the check of the role.  An error of type *RoleError is returned
+// otherwise.
+func (p *Packet) CheckRole(role Role) error {
+	switch role {
+	case RoleCreator:
+		return p.SanityCheckCreator()
+	case RoleUpdater:
+		return p.SanityCheckUpdater()
+	case RoleSigner:
+		return p.SanityCheckSigner()
+	case RoleFinalizer:
+		return p.SanityCheckFinalizer()
+	case RoleExtractor:
+		return p.SanityCheckExtractor()
+	}
+	return fmt.Errorf("psbt: unknown role %d", role)
+}
+
+// sanityCheck returns the error of SanityCheck as a *RoleError of role.
+func (p *Packet) sanityCheck(role Role) error {
+	if err := p.SanityCheck(); err != nil {
+		return packetError(role, err)
+	}
+	return nil
+}
+
+// SanityCheckCreator verifies the packet is as a creator hands it out: it
+// passes SanityCheck, and the maps of its inputs and outputs are empty but
+// for proprietary fields, such as the token types SetTokenType annotates.
+// Errors are of type *RoleError, wrapping ErrNotEmpty for inputs and outputs
+// with data.
+func (p *Packet) SanityCheckCreator() error {
+	if err := p.sanityCheck(RoleCreator); err != nil {
+		return err
+	}
+	for i := range p.Inputs {
+		in := &p.Inputs[i]
+		if in.NonWitnessUtxo != nil || in.PartialSigs != nil ||
+			in.SighashType != 0 || in.RedeemScript != nil ||
+			in.Bip32Derivations != nil ||
+			in.FinalScriptSig != nil || in.TaprootKeySpendSig != nil ||
+			in.TaprootScriptSpendSigs != nil ||
+			in.TaprootLeafScripts != nil ||
+			in.TaprootInternalKey != nil ||
+			in.TaprootMerkleRoot != nil ||
+			!onlyProprietary(in.Unknowns) {
+
+			return inputError(RoleCreator, i, ErrNotEmpty)
+		}
+	}
+	for i := range p.Outputs {
+		out := &p.Outputs[i]
+		if out.RedeemScript != nil || out.TaprootInternalKey != nil ||
+			out.TaprootTapTree != nil || out.Bip32Derivations != nil ||
+			!onlyProprietary(out.Unknowns) {
+
+			return outputError(RoleCreator, i, ErrNotEmpty)
+		}
+	}
+	return nil
+}
+
+// SanityCheckUpdater verifies the packet can be handed to an updater: it
+// passes SanityCheck, the signature hash type of each input is valid, and
+// the redeem scripts of its inputs and outputs hash to the script hashes
+// their outputs pay, when the outputs are known.  Errors are of type
+// *RoleError, wrapping signing.ErrInvalidSigHashType or
+// ErrRedeemScriptMismatch.
+func (p *Packet) SanityCheckUpdater() error {
+	return p.checkUpdater(RoleUpdater)
+}
+
+// checkUpdater runs the checks of SanityCheckUpdater, reporting errors for
+// role.
+func (p *Packet) checkUpdater(role Role) error {
+	if err := p.sanityCheck(role); err != nil {
+		return err
+	}
+	for i := range p.Inputs {
+		in := &p.Inputs[i]
+		if !validSighashType(in.SighashType) {
+			return inputError(role, i, signing.ErrInvalidSigHashType)
+		}
+		prevOut := p.PrevOutput(i)
+		if in.RedeemScript == nil || prevOut == nil {
+			continue
+		}
+		if !redeemScriptMatches(prevOut.PkScript, in.RedeemScript) {
+			return inputError(role, i, ErrRedeemScriptMismatch)
+		}
+	}
+	for i, out := range p.Outputs {
+		if out.RedeemScript == nil {
+			continue
+		}
+		pkScript := p.UnsignedTx.TxOut[i].PkScript
+		if !redeemScriptMatches(pkScript, out.RedeemScript) {
+			return outputError(role, i, ErrRedeemScriptMismatch)
+		}
+	}
+	return nil
+}
+
+// SanityCheckSigner verifies the packet can be handed to a signer: it passes
+// the checks of SanityCheckUpdater, and the previous transaction of every
+// input left to sign is known, since signers can not tell what an input
+// spends, nor compute the signature hash of a Schnorr signature, without
+// them.  Errors are of type *RoleError, wrapping ErrMissingPrevOut for inputs
+// lacking their previous transaction.
+func (p *Packet) SanityCheckSigner() error {
+	if err := p.checkUpdater(RoleSigner); err != nil {
+		return err
+	}
+	return p.checkPrevOuts(RoleSigner)
+}
+
+// checkPrevOuts verifies the previous transaction of every input which is not
+// finalized is known, reporting errors for role.
+func (p *Packet) checkPrevOuts(role Role) error {
+	for i := range p.Inputs {
+		if p.Inputs[i].FinalScriptSig == nil && p.PrevOutput(i) == nil {
+			return inputError(role, i, ErrMissingPrevOut)
+		}
+	}
+	return nil
+}
+
+// SanityCheckFinalizer verifies the packet can be handed to a finalizer: it
+// passes SanityCheck, and the signatures of every input which is not
+// finalized satisfy the script of the output it spends.  The scripts are not
+// executed, nor the signatures verified; an input satisfies:
+//
+//   - a pay-to-pubkey or pay-to-pubkey-hash output, of any kind, when it has
+//     a partial signature by the key
+//   - a bare multi-signature output when it has partial signatures by as
+//     many of the keys as the script requires
+//   - a pay-to-script-hash output, of any kind, when its redeem script
+//     hashes to the script hash and it satisfies the redeem script
+//   - a taproot output when it has a key path signature, or the signatures
+//     of every key of a leaf script whose control block proves the output
+//     commits to it
+//
+// Errors are of type *RoleError, wrapping ErrMissingPrevOut, ErrUnsatisfied,
+// ErrRedeemScriptMismatch, or ErrUnsupportedScript for outputs of other
+// kinds, whose satisfaction can not be told without executing them.  The
+// errors of inputs which are not satisfied also wrap ErrNotFinalizable.
+func (p *Packet) SanityCheckFinalizer() error {
+	if err := p.sanityCheck(RoleFinalizer); err != nil {
+		return err
+	}
+	if err := p.checkPrevOuts(RoleFinalizer); err != nil {
+		return err
+	}
+	for i := range p.Inputs {
+		in := &p.Inputs[i]
+		if in.FinalScriptSig != nil {
+			continue
+		}
+		if err := in.checkSatisfied(p.PrevOutput(i).PkScript); err != nil {
+			return inputError(RoleFinalizer, i,
+				fmt.Errorf("%w: %w", ErrNotFinalizable, err))
+		}
+	}
+	return nil
+}
+
+// SanityCheckExtractor verifies the transaction of the packet can be
+// extracted: it passes SanityCheck, and every input has a final signature
+// script holding only data pushes.  Errors are of type *RoleError, wrapping
+// ErrIncomplete or ErrNotPushOnly.
+func (p *Packet) SanityCheckExtractor() error {
+	if err := p.sanityCheck(RoleExtractor); err != nil {
+		return err
+	}
+	for i := range p.Inputs {
+		script := p.Inputs[i].FinalScriptSig
+		if script == nil {
+			return inputError(RoleExtractor, i, ErrIncomplete)
+		}
+		if !isPushOnly(script) {
+			return inputError(RoleExtractor, i, ErrNotPushOnly)
+		}
+	}
+	return nil
+}
+
+// validSighashType returns whether t is zero, for inputs signers may sign with
+// any type, or a signature hash type.
+func validSighashType(t uint32) bool {
+	switch signing.SigHashType(t) &^ signing.SigHashAnyOneCanPay {
+	case signing.SigHashDefault:
+		return t == 0
+	case signing.SigHashAll, signing.SigHashNone, signing.SigHashSingle:
+		return t <= 0xff
+	}
+	return false
+}
+
+// scriptHash returns the script hash paid by pkScript, or nil when it does
+// not pay to a script hash.
+func scriptHash(pkScript []byte) []byte {
+	switch scriptclass.Classify(pkScript) {
+	case scriptclass.ScriptHashTy:
+		return pkScript[2:22]
+	case scriptclass.OmegaScriptHashTy:
+		out, _ := scriptclass.DecodeOmega(pkScript)
+		return out.Hash[:]
+	}
+	return nil
+}
+
+// redeemScriptMatches returns whether pkScript pays to the script hash of
+// redeemScript.
+func redeemScriptMatches(pkScript, redeemScript []byte) bool {
+	hash := scriptHash(pkScript)
+	return hash != nil && bytes.Equal(btcutil.Hash160(redeemScript), hash)
+}
+
+// checkSatisfied returns nil when the signatures of the input satisfy
+// pkScript, the script of the output it spends.  See SanityCheckFinalizer.
+func (in *Input) checkSatisfied(pkScript []byte) error {
+	script := pkScript
+	if scriptHash(pkScript) != nil {
+		if in.RedeemScript == nil {
+			return ErrUnsatisfied
+		}
+		if !redeemScriptMatches(pkScript, in.RedeemScript) {
+			return ErrRedeemScriptMismatch
+		}
+		script = in.RedeemScript
+	}
+
+	switch scriptclass.Classify(script) {
+	case scriptclass.PubKeyTy:
+		if !hasPartialSig(in.PartialSigs, script[1:len(script)-1]) {
+			return ErrUnsatisfied
+		}
+	case scriptclass.PubKeyHashTy:
+		return in.checkSignedByHash(script[3:23])
+	case scriptclass.WitnessV0PubKeyHashTy:
+		return in.checkSignedByHash(script[2:22])
+	case scriptclass.OmegaPubKeyHashTy:
+		out, _ := scriptclass.DecodeOmega(script)
+		return in.checkSignedByHash(out.Hash[:])
+	case scriptclass.MultiSigTy:
+		return in.checkMultiSig(script)
+	case scriptclass.WitnessV1TaprootTy:
+		return in.checkTaproot(script)
+	default:
+		return ErrUnsupportedScript
+	}
+	return nil
+}
+
+// checkSignedByHash returns nil when the input has a partial signature by a
+// key whose hash is pkHash.
+func (in *Input) checkSignedByHash(pkHash []byte) error {
+	for _, sig := range in.PartialSigs {
+		if bytes.Equal(btcutil.Hash160(sig.PubKey), pkHash) {
+			return nil
+		}
+	}
+	return ErrUnsatisfied
+}
+
+// checkMultiSig returns nil when the input has partial signatures by as many
+// keys of script, a bare multi-signature script, as it requires.
+func (in *Input) checkMultiSig(script []byte) error {
+	// OP_M <pubkey>... OP_N OP_CHECKMULTISIG
+	ops, err := scriptclass.Parse(script)
+	if err != nil {
+		return ErrUnsupportedScript
+	}
+	required := int(ops[0].Op-scriptclass.OP_1) + 1
+	for _, op := range ops[1 : len(ops)-2] {
+		if hasPartialSig(in.PartialSigs, op.Data) {
+			required--
+		}
+	}
+	if required > 0 {
+		return ErrUnsatisfied
+	}
+	return nil
+}
+
+// checkTaproot returns nil when the input has a key path signature of script,
+// a taproot output, or the signatures of every key of one of its leaf
+// scripts, whose control block proves the output commits to it.
+func (in *Input) checkTaproot(script []byte) error {
+	outputKey := script[2:]
+	if in.TaprootKeySpendSig != nil || hasPartialSig(in.PartialSigs, outputKey) {
+		return nil
+	}
+	for _, leaf := range in.TaprootLeafScripts {
+		if in.leafScriptSigs(leaf) == nil {
+			continue
+		}
+		cb, err := taproot.ParseControlBlock(leaf.ControlBlock)
+		if err != nil {
+			continue
+		}
+		if taproot.VerifyTaprootLeafCommitment(cb, outputKey, leaf.Script) == nil {
+			return nil
+		}
+	}
+	return ErrUnsatisfied
+}
+
+// isPushOnly returns whether script parses into data pushes only.
+func isPushOnly(script []byte) bool {
+	ops, err := scriptclass.Parse(script)
+	if err != nil {
+		return false
+	}
+	for _, op := range ops {
+		if op.Op > scriptclass.OP_16 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,230 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package psbt_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/psbt"
+	"github.com/zeusyf/btcutil/signing"
+)
+
+// checkRoleError ensures err is a *psbt.RoleError of role for input in,
+// wrapping want.
+func checkRoleError(t *testing.T, name string, err error, role psbt.Role,
+	in int, want error) {
+
+	t.Helper()
+	var roleErr *psbt.RoleError
+	if !errors.As(err, &roleErr) || !errors.Is(err, want) ||
+		roleErr.Role != role || roleErr.Input != in {
+
+		t.Errorf("%s: got error %v, want %v of %v for input %d", name, err,
+			want, role, in)
+	}
+}
+
+// TestSanityCheckCreator ensures creators hand out packets without input or
+// output data.
+func TestSanityCheckCreator(t *testing.T) {
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(&wire.TxIn{Sequence: wire.MaxTxInSequenceNum})
+	tx.AddTxOut(output(900, []byte{0x53}))
+	p, err := psbt.New(tx)
+	if err != nil {
+		t.Fatalf("New: unexpected error: %v", err)
+	}
+	if err := p.CheckRole(psbt.RoleCreator); err != nil {
+		t.Errorf("CheckRole: unexpected error: %v", err)
+	}
+
+	// Creators annotate token types and other proprietary fields, but no
+	// other unknown fields.
+	p.Inputs[0].SetTokenType(3)
+	p.Outputs[0].SetTokenType(3)
+	p.Outputs[0].SetProprietary(&psbt.ProprietaryKey{
+		Identifier: []byte("acme"),
+	}, []byte{0x01})
+	if err := p.SanityCheckCreator(); err != nil {
+		t.Errorf("token types: unexpected error: %v", err)
+	}
+	p.Inputs[0].Unknowns = append(p.Inputs[0].Unknowns,
+		&psbt.Unknown{Key: []byte{0x70}, Value: []byte{0x01}})
+	checkRoleError(t, "unknown field", p.SanityCheckCreator(),
+		psbt.RoleCreator, 0, psbt.ErrNotEmpty)
+	p.Inputs[0].Unknowns = nil
+
+	p.Inputs[0].SighashType = uint32(signing.SigHashAll)
+	checkRoleError(t, "input data", p.SanityCheckCreator(), psbt.RoleCreator, 0,
+		psbt.ErrNotEmpty)
+
+	p.Inputs[0].SighashType = 0
+	p.Outputs[0].RedeemScript = []byte{0x51}
+	err = p.SanityCheckCreator()
+	var roleErr *psbt.RoleError
+	if !errors.As(err, &roleErr) || roleErr.Input != -1 ||
+		roleErr.Output != 0 || roleErr.Err != psbt.ErrNotEmpty {
+
+		t.Errorf("output data: got error %v", err)
+	}
+	want := "creator: output 0: " + psbt.ErrNotEmpty.Error()
+	if err.Error() != want {
+		t.Errorf("Error: got %q, want %q", err, want)
+	}
+}
+
+// TestSanityCheckSigner ensures signers refuse inputs whose previous
+// transaction is unknown, invalid signature hash types, and redeem scripts
+// not matching their output.
+func TestSanityCheckSigner(t *testing.T) {
+	key, _ := btcec.PrivKeyFromBytes(btcec.S256(), []byte{0x2a})
+	pkHash := btcutil.Hash160(key.PubKey().SerializeCompressed())
+	pkhScript := append(append([]byte{0x76, 0xa9, 0x14}, pkHash...), 0x88, 0xac)
+	redeemScript := []byte{0x51}
+	shScript := append(append([]byte{0xa9, 0x14},
+		btcutil.Hash160(redeemScript)...), 0x87)
+
+	p := scriptPacket(t, pkhScript, shScript)
+	p.Inputs[1].RedeemScript = redeemScript
+	if err := p.CheckRole(psbt.RoleSigner); err != nil {
+		t.Errorf("CheckRole: unexpected error: %v", err)
+	}
+
+	p.Inputs[1].NonWitnessUtxo = nil
+	checkRoleError(t, "missing previous output", p.SanityCheckSigner(),
+		psbt.RoleSigner, 1, psbt.ErrMissingPrevOut)
+
+	// Updaters may add the previous transaction later.
+	if err := p.SanityCheckUpdater(); err != nil {
+		t.Errorf("SanityCheckUpdater: unexpected error: %v", err)
+	}
+
+	p = scriptPacket(t, pkhScript, shScript)
+	p.Inputs[0].SighashType = 0x04
+	checkRoleError(t, "sighash type", p.SanityCheckSigner(), psbt.RoleSigner, 0,
+		signing.ErrInvalidSigHashType)
+	p.Inputs[0].SighashType = uint32(signing.SigHashAll) | 0x100
+	checkRoleError(t, "wide sighash type", p.SanityCheckUpdater(),
+		psbt.RoleUpdater, 0, signing.ErrInvalidSigHashType)
+
+	p = scriptPacket(t, pkhScript, shScript)
+	p.Inputs[1].RedeemScript = []byte{0x52}
+	checkRoleError(t, "redeem script", p.SanityCheckUpdater(), psbt.RoleUpdater,
+		1, psbt.ErrRedeemScriptMismatch)
+	p.Inputs[1].RedeemScript = nil
+	p.Inputs[0].RedeemScript = redeemScript
+	checkRoleError(t, "redeem script of key hash", p.SanityCheckSigner(),
+		psbt.RoleSigner, 0, psbt.ErrRedeemScriptMismatch)
+}
+
+// TestSanityCheckFinalizer ensures finalizers refuse inputs whose signatures
+// do not satisfy the output they spend.
+func TestSanityCheckFinalizer(t *testing.T) {
+	key, _ := btcec.PrivKeyFromBytes(btcec.S256(), []byte{0x2a})
+	pubKey := key.PubKey().SerializeCompressed()
+	pkHash := btcutil.Hash160(pubKey)
+	omegaScript := append(append([]byte{0x00}, pkHash...), 0x41)
+	witnessScript := append([]byte{0x00, 0x14}, pkHash...)
+	multiSig := append(append([]byte{0x51, 0x21}, pubKey...), 0x51, 0xae)
+	shScript := append(append([]byte{0xa9, 0x14},
+		btcutil.Hash160(multiSig)...), 0x87)
+
+	p := scriptPacket(t, omegaScript, witnessScript, shScript)
+	p.Inputs[2].RedeemScript = multiSig
+	checkRoleError(t, "unsigned", p.SanityCheckFinalizer(), psbt.RoleFinalizer,
+		0, psbt.ErrUnsatisfied)
+	checkRoleError(t, "unsigned", p.SanityCheckFinalizer(), psbt.RoleFinalizer,
+		0, psbt.ErrNotFinalizable)
+
+	signer := psbt.NewSoftwareSigner()
+	signer.AddKey(key)
+	for i := 0; i < 2; i++ {
+		if err := signer.SignInput(p, i); err != nil {
+			t.Fatalf("SignInput(%d): unexpected error: %v", i, err)
+		}
+	}
+	checkRoleError(t, "unsigned multisig", p.SanityCheckFinalizer(),
+		psbt.RoleFinalizer, 2, psbt.ErrUnsatisfied)
+	p.Inputs[2].PartialSigs = []*psbt.PartialSig{{
+		PubKey:    pubKey,
+		Signature: []byte{0x30, byte(signing.SigHashAll)},
+	}}
+	if err := p.CheckRole(psbt.RoleFinalizer); err != nil {
+		t.Errorf("CheckRole: unexpected error: %v", err)
+	}
+
+	p.Inputs[2].RedeemScript = nil
+	checkRoleError(t, "missing redeem script", p.SanityCheckFinalizer(),
+		psbt.RoleFinalizer, 2, psbt.ErrUnsatisfied)
+
+	p = scriptPacket(t, []byte{0x53})
+	checkRoleError(t, "nonstandard", p.SanityCheckFinalizer(),
+		psbt.RoleFinalizer, 0, psbt.ErrUnsupportedScript)
+	p.Inputs[0].FinalScriptSig = []byte{}
+	if err := p.SanityCheckFinalizer(); err != nil {
+		t.Errorf("finalized: unexpected error: %v", err)
+	}
+}
+
+// TestSanityCheckTaproot ensures taproot inputs satisfy their output once
+// signed for the key path or a leaf script, and can be extracted once
+// finalized.
+func TestSanityCheckTaproot(t *testing.T) {
+	f := newTaprootFixture(t)
+	if err := f.p.UpdateTaprootInput(0, f.internal.PubKey(), f.tree); err != nil {
+		t.Fatalf("UpdateTaprootInput: unexpected error: %v", err)
+	}
+	checkRoleError(t, "unsigned", f.p.SanityCheckFinalizer(),
+		psbt.RoleFinalizer, 0, psbt.ErrUnsatisfied)
+
+	signer := psbt.NewSoftwareSigner()
+	signer.AddKey(f.a)
+	if err := signer.SignInput(f.p, 0); err != nil {
+		t.Fatalf("SignInput: unexpected error: %v", err)
+	}
+	if err := f.p.SanityCheckFinalizer(); err != nil {
+		t.Errorf("SanityCheckFinalizer: unexpected error: %v", err)
+	}
+
+	// A leaf whose control block does not commit to the output is not
+	// enough.
+	in := &f.p.Inputs[0]
+	for _, leaf := range in.TaprootLeafScripts {
+		leaf.ControlBlock = append([]byte(nil), leaf.ControlBlock...)
+		leaf.ControlBlock[len(leaf.ControlBlock)-1] ^= 0x01
+	}
+	checkRoleError(t, "bad control block", f.p.SanityCheckFinalizer(),
+		psbt.RoleFinalizer, 0, psbt.ErrUnsatisfied)
+
+	f = newTaprootFixture(t)
+	checkRoleError(t, "not finalized", f.p.CheckRole(psbt.RoleExtractor),
+		psbt.RoleExtractor, 0, psbt.ErrIncomplete)
+	if err := f.p.UpdateTaprootInput(0, f.internal.PubKey(), f.tree); err != nil {
+		t.Fatalf("UpdateTaprootInput: unexpected error: %v", err)
+	}
+	signer = psbt.NewSoftwareSigner()
+	signer.AddKey(f.internal)
+	if err := signer.SignInput(f.p, 0); err != nil {
+		t.Fatalf("SignInput: unexpected error: %v", err)
+	}
+	if err := f.p.SanityCheckFinalizer(); err != nil {
+		t.Errorf("SanityCheckFinalizer: unexpected error: %v", err)
+	}
+	if err := f.p.FinalizeTaprootInput(0); err != nil {
+		t.Fatalf("FinalizeTaprootInput: unexpected error: %v", err)
+	}
+	if err := f.p.SanityCheckExtractor(); err != nil {
+		t.Errorf("SanityCheckExtractor: unexpected error: %v", err)
+	}
+
+	// OP_CHECKSIG
+	f.p.Inputs[0].FinalScriptSig = []byte{0xac}
+	checkRoleError(t, "not push-only", f.p.SanityCheckExtractor(),
+		psbt.RoleExtractor, 0, psbt.ErrNotPushOnly)
+}
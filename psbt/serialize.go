@@ -0,0 +1,500 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package psbt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcd/wire/common"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/signing"
+	"github.com/zeusyf/btcutil/taproot"
+)
+
+// varIntProtoVer is the protocol version to use for serializing the lengths
+// of keys and values.
+const varIntProtoVer uint32 = 0
+
+// writeBytes writes b prefixed with its length.
+func writeBytes(w io.Writer, b []byte) error {
+	if err := common.WriteVarInt(w, varIntProtoVer, uint64(len(b))); err != nil {
+		return err
+	}
+	_, err := w.Write(b)
+	return err
+}
+
+// writePair writes a key and value pair whose key is keyType followed by
+// keyData.
+func writePair(w io.Writer, keyType byte, keyData, value []byte) error {
+	if err := writeBytes(w, append([]byte{keyType}, keyData...)); err != nil {
+		return err
+	}
+	return writeBytes(w, value)
+}
+
+// writeUnknowns writes unknowns followed by the separator ending a map.
+func writeUnknowns(w io.Writer, unknowns []*Unknown) error {
+	for _, u := range unknowns {
+		if err := writeBytes(w, u.Key); err != nil {
+			return err
+		}
+		if err := writeBytes(w, u.Value); err != nil {
+			return err
+		}
+	}
+	_, err := w.Write([]byte{0x00})
+	return err
+}
+
+// serializeTx returns the serialization of tx.
+func serializeTx(tx *wire.MsgTx) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := tx.Serialize(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Serialize writes the packet to w in the BIP0174 format.
+func (p *Packet) Serialize(w io.Writer) error {
+	if err := p.SanityCheck(); err != nil {
+		return err
+	}
+	if _, err := w.Write(magic); err != nil {
+		return err
+	}
+
+	tx, err := serializeTx(p.UnsignedTx)
+	if err != nil {
+		return err
+	}
+	if err := writePair(w, globalUnsignedTx, nil, tx); err != nil {
+		return err
+	}
+	if err := writeUnknowns(w, p.Unknowns); err != nil {
+		return err
+	}
+
+	for i := range p.Inputs {
+		if err := p.Inputs[i].serialize(w); err != nil {
+			return err
+		}
+	}
+	for i := range p.Outputs {
+		if err := p.Outputs[i].serialize(w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// serialize writes the map of the input.
+func (in *Input) serialize(w io.Writer) error {
+	if in.NonWitnessUtxo != nil {
+		tx, err := serializeTx(in.NonWitnessUtxo)
+		if err != nil {
+			return err
+		}
+		if err := writePair(w, inputNonWitnessUtxo, nil, tx); err != nil {
+			return err
+		}
+	}
+	for _, sig := range in.PartialSigs {
+		err := writePair(w, inputPartialSig, sig.PubKey, sig.Signature)
+		if err != nil {
+			return err
+		}
+	}
+	if in.SighashType != 0 {
+		var value [4]byte
+		binary.LittleEndian.PutUint32(value[:], in.SighashType)
+		if err := writePair(w, inputSighashType, nil, value[:]); err != nil {
+			return err
+		}
+	}
+	if in.RedeemScript != nil {
+		err := writePair(w, inputRedeemScript, nil, in.RedeemScript)
+		if err != nil {
+			return err
+		}
+	}
+	err := writeBip32Derivations(w, inputBip32Derivation,
+		in.Bip32Derivations)
+	if err != nil {
+		return err
+	}
+	if in.FinalScriptSig != nil {
+		err := writePair(w, inputFinalScriptSig, nil, in.FinalScriptSig)
+		if err != nil {
+			return err
+		}
+	}
+	if in.TaprootKeySpendSig != nil {
+		err := writePair(w, inputTaprootKeySig, nil, in.TaprootKeySpendSig)
+		if err != nil {
+			return err
+		}
+	}
+	for _, sig := range in.TaprootScriptSpendSigs {
+		keyData := append(append([]byte(nil), sig.XOnlyPubKey...),
+			sig.LeafHash...)
+		err := writePair(w, inputTaprootScriptSig, keyData, sig.Signature)
+		if err != nil {
+			return err
+		}
+	}
+	for _, leaf := range in.TaprootLeafScripts {
+		value := append(append([]byte(nil), leaf.Script...),
+			leaf.LeafVersion)
+		err := writePair(w, inputTaprootLeafScript, leaf.ControlBlock,
+			value)
+		if err != nil {
+			return err
+		}
+	}
+	if in.TaprootInternalKey != nil {
+		err := writePair(w, inputTaprootInternalKey, nil,
+			in.TaprootInternalKey)
+		if err != nil {
+			return err
+		}
+	}
+	if in.TaprootMerkleRoot != nil {
+		err := writePair(w, inputTaprootMerkleRoot, nil,
+			in.TaprootMerkleRoot)
+		if err != nil {
+			return err
+		}
+	}
+	return writeUnknowns(w, in.Unknowns)
+}
+
+// serialize writes the map of the output.
+func (out *Output) serialize(w io.Writer) error {
+	if out.RedeemScript != nil {
+		err := writePair(w, outputRedeemScript, nil, out.RedeemScript)
+		if err != nil {
+			return err
+		}
+	}
+	err := writeBip32Derivations(w, outputBip32Derivation,
+		out.Bip32Derivations)
+	if err != nil {
+		return err
+	}
+	if out.TaprootInternalKey != nil {
+		err := writePair(w, outputTaprootInternalKey, nil,
+			out.TaprootInternalKey)
+		if err != nil {
+			return err
+		}
+	}
+	if out.TaprootTapTree != nil {
+		var buf bytes.Buffer
+		for _, leaf := range out.TaprootTapTree {
+			buf.Write([]byte{leaf.Depth, leaf.LeafVersion})
+			err := common.WriteVarInt(&buf, varIntProtoVer,
+				uint64(len(leaf.Script)))
+			if err != nil {
+				return err
+			}
+			buf.Write(leaf.Script)
+		}
+		err := writePair(w, outputTaprootTapTree, nil, buf.Bytes())
+		if err != nil {
+			return err
+		}
+	}
+	return writeUnknowns(w, out.Unknowns)
+}
+
+// readBytes reads a length prefixed byte slice of at most MaxValueSize bytes.
+func readBytes(r io.Reader) ([]byte, error) {
+	n, err := common.ReadVarInt(r, varIntProtoVer)
+	if err != nil {
+		return nil, ErrInvalidFormat
+	}
+	if n > MaxValueSize {
+		return nil, ErrInvalidFormat
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return nil, ErrInvalidFormat
+	}
+	return b, nil
+}
+
+// readMap reads the pairs of a map up to its separator, calling set for each
+// of them.  Duplicate keys are rejected, unless dups is set, in which case
+// all but the first pair with a key are skipped.
+func readMap(r io.Reader, dups bool, set func(key, value []byte) error) error {
+	seen := make(map[string]struct{})
+	for {
+		key, err := readBytes(r)
+		if err != nil {
+			return err
+		}
+		if len(key) == 0 {
+			return nil
+		}
+		_, dup := seen[string(key)]
+		if dup && !dups {
+			return ErrInvalidFormat
+		}
+		seen[string(key)] = struct{}{}
+
+		value, err := readBytes(r)
+		if err != nil {
+			return err
+		}
+		if dup {
+			continue
+		}
+		if err := set(key, value); err != nil {
+			return err
+		}
+	}
+}
+
+// parseTx parses a serialized transaction, which must span all of b.
+func parseTx(b []byte) (*wire.MsgTx, error) {
+	r := bytes.NewReader(b)
+	tx := new(wire.MsgTx)
+	if err := tx.Deserialize(r); err != nil || r.Len() != 0 {
+		return nil, ErrInvalidFormat
+	}
+	return tx, nil
+}
+
+// Parse reads a packet in the BIP0174 format from r.  Duplicate keys are
+// rejected, and any data following the packet is left unread.
+func Parse(r io.Reader) (*Packet, error) {
+	return parse(r, false)
+}
+
+// ParseWithOptions parses the packet serialized in b, tolerating the
+// deviations from the format allowed by the strictness of opts.  Strict
+// parsing rejects data following the packet with ErrTrailingData, which
+// lenient parsing ignores.  Recovering parsing also accepts maps holding a
+// key more than once, such as packets combined by faulty software, and keeps
+// the first value of each key.
+func ParseWithOptions(b []byte, opts btcutil.DecodeOptions) (*Packet, error) {
+	r := bytes.NewReader(b)
+	p, err := parse(r, opts.Strictness >= btcutil.Recovery)
+	if err != nil {
+		return nil, err
+	}
+	if r.Len() != 0 && opts.Strictness < btcutil.Lenient {
+		return nil, ErrTrailingData
+	}
+	return p, nil
+}
+
+// parse reads a packet from r, skipping duplicate keys when dups is set.
+func parse(r io.Reader, dups bool) (*Packet, error) {
+	var m [5]byte
+	if _, err := io.ReadFull(r, m[:]); err != nil || !bytes.Equal(m[:], magic) {
+		return nil, ErrInvalidMagic
+	}
+
+	p := new(Packet)
+	err := readMap(r, dups, func(key, value []byte) error {
+		if len(key) == 1 && key[0] == globalUnsignedTx {
+			tx, err := parseTx(value)
+			if err != nil {
+				return err
+			}
+			p.UnsignedTx = tx
+			return nil
+		}
+		p.Unknowns = append(p.Unknowns, &Unknown{key, value})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if p.UnsignedTx == nil {
+		return nil, ErrInvalidFormat
+	}
+
+	p.Inputs = make([]Input, len(p.UnsignedTx.TxIn))
+	for i := range p.Inputs {
+		if err := readMap(r, dups, p.Inputs[i].set); err != nil {
+			return nil, err
+		}
+	}
+	p.Outputs = make([]Output, len(p.UnsignedTx.TxOut))
+	for i := range p.Outputs {
+		if err := readMap(r, dups, p.Outputs[i].set); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := p.SanityCheck(); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// set sets the field of the input identified by key.
+func (in *Input) set(key, value []byte) error {
+	switch {
+	case len(key) == 1 && key[0] == inputNonWitnessUtxo:
+		tx, err := parseTx(value)
+		if err != nil {
+			return err
+		}
+		in.NonWitnessUtxo = tx
+
+	case key[0] == inputPartialSig && len(key) > 1:
+		in.PartialSigs = append(in.PartialSigs, &PartialSig{
+			PubKey:    key[1:],
+			Signature: value,
+		})
+
+	case len(key) == 1 && key[0] == inputSighashType:
+		if len(value) != 4 {
+			return ErrInvalidFormat
+		}
+		in.SighashType = binary.LittleEndian.Uint32(value)
+
+	case len(key) == 1 && key[0] == inputRedeemScript:
+		in.RedeemScript = value
+
+	case key[0] == inputBip32Derivation:
+		d, err := parseBip32Derivation(key[1:], value)
+		if err != nil {
+			return err
+		}
+		in.Bip32Derivations = append(in.Bip32Derivations, d)
+
+	case len(key) == 1 && key[0] == inputFinalScriptSig:
+		in.FinalScriptSig = value
+
+	case len(key) == 1 && key[0] == inputTaprootKeySig:
+		if !validSchnorrSig(value) {
+			return ErrInvalidFormat
+		}
+		in.TaprootKeySpendSig = value
+
+	case key[0] == inputTaprootScriptSig:
+		if len(key) != 1+taproot.XOnlyPubKeySize+chainhash.HashSize ||
+			!validSchnorrSig(value) {
+			return ErrInvalidFormat
+		}
+		in.TaprootScriptSpendSigs = append(in.TaprootScriptSpendSigs,
+			&TaprootScriptSpendSig{
+				XOnlyPubKey: key[1 : 1+taproot.XOnlyPubKeySize],
+				LeafHash:    key[1+taproot.XOnlyPubKeySize:],
+				Signature:   value,
+			})
+
+	case key[0] == inputTaprootLeafScript:
+		if !validControlBlockSize(len(key)-1) || len(value) == 0 {
+			return ErrInvalidFormat
+		}
+		in.TaprootLeafScripts = append(in.TaprootLeafScripts,
+			&TaprootTapLeafScript{
+				ControlBlock: key[1:],
+				Script:       value[:len(value)-1],
+				LeafVersion:  value[len(value)-1],
+			})
+
+	case len(key) == 1 && key[0] == inputTaprootInternalKey:
+		if len(value) != taproot.XOnlyPubKeySize {
+			return ErrInvalidFormat
+		}
+		in.TaprootInternalKey = value
+
+	case len(key) == 1 && key[0] == inputTaprootMerkleRoot:
+		if len(value) != chainhash.HashSize {
+			return ErrInvalidFormat
+		}
+		in.TaprootMerkleRoot = value
+
+	default:
+		in.Unknowns = append(in.Unknowns, &Unknown{key, value})
+	}
+	return nil
+}
+
+// set sets the field of the output identified by key.
+func (out *Output) set(key, value []byte) error {
+	switch {
+	case len(key) == 1 && key[0] == outputRedeemScript:
+		out.RedeemScript = value
+
+	case key[0] == outputBip32Derivation:
+		d, err := parseBip32Derivation(key[1:], value)
+		if err != nil {
+			return err
+		}
+		out.Bip32Derivations = append(out.Bip32Derivations, d)
+
+	case len(key) == 1 && key[0] == outputTaprootInternalKey:
+		if len(value) != taproot.XOnlyPubKeySize {
+			return ErrInvalidFormat
+		}
+		out.TaprootInternalKey = value
+
+	case len(key) == 1 && key[0] == outputTaprootTapTree:
+		tree, err := parseTapTree(value)
+		if err != nil {
+			return err
+		}
+		out.TaprootTapTree = tree
+
+	default:
+		out.Unknowns = append(out.Unknowns, &Unknown{key, value})
+	}
+	return nil
+}
+
+// validSchnorrSig returns whether sig has the size of a Schnorr signature,
+// with or without its signature hash type.
+func validSchnorrSig(sig []byte) bool {
+	return len(sig) == signing.SchnorrSigSize ||
+		len(sig) == signing.SchnorrSigSize+1
+}
+
+// validControlBlockSize returns whether a control block may have n bytes.
+func validControlBlockSize(n int) bool {
+	proofLen := n - taproot.ControlBlockBaseSize
+	return proofLen >= 0 && proofLen%taproot.ControlBlockNodeSize == 0 &&
+		proofLen/taproot.ControlBlockNodeSize <= taproot.ControlBlockMaxNodeCount
+}
+
+// parseTapTree parses the leaves of a serialized script tree.
+func parseTapTree(b []byte) ([]TaprootTapLeaf, error) {
+	r := bytes.NewReader(b)
+	var tree []TaprootTapLeaf
+	for r.Len() != 0 {
+		var head [2]byte
+		if _, err := io.ReadFull(r, head[:]); err != nil {
+			return nil, ErrInvalidFormat
+		}
+		if head[0] > taproot.ControlBlockMaxNodeCount {
+			return nil, ErrInvalidFormat
+		}
+		script, err := readBytes(r)
+		if err != nil {
+			return nil, err
+		}
+		tree = append(tree, TaprootTapLeaf{
+			Depth:       head[0],
+			LeafVersion: head[1],
+			Script:      script,
+		})
+	}
+	if len(tree) == 0 {
+		return nil, ErrInvalidFormat
+	}
+	return tree, nil
+}
@@ -0,0 +1,215 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package psbt_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/psbt"
+)
+
+// TestSerializeRoundTrip ensures packets parse back to the same packet, both
+// from their binary and base64 encodings.
+func TestSerializeRoundTrip(t *testing.T) {
+	p := testPacket(t)
+	p.Inputs[0].PartialSigs = []*psbt.PartialSig{{PubKey: []byte{0x02, 0x01}, Signature: []byte{0x30}}}
+	p.Inputs[0].SighashType = 1
+	p.Inputs[0].RedeemScript = []byte{0x51}
+	p.Inputs[1].FinalScriptSig = []byte{}
+	p.Inputs[1].Unknowns = []*psbt.Unknown{{Key: []byte{0x42}, Value: []byte{0x01}}}
+	p.Outputs[0].RedeemScript = []byte{0x52}
+	p.Unknowns = []*psbt.Unknown{{Key: []byte{0xfc, 0x01}, Value: nil}}
+
+	var buf bytes.Buffer
+	if err := p.Serialize(&buf); err != nil {
+		t.Fatalf("Serialize: unexpected error: %v", err)
+	}
+	if !bytes.HasPrefix(buf.Bytes(), []byte("psbt\xff")) {
+		t.Errorf("Serialize: got %x", buf.Bytes())
+	}
+	parsed, err := psbt.Parse(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatalf("Parse: unexpected error: %v", err)
+	}
+	in := parsed.Inputs
+	if parsed.UnsignedTx.TxHash() != p.UnsignedTx.TxHash() ||
+		in[0].NonWitnessUtxo.TxHash() != p.Inputs[0].NonWitnessUtxo.TxHash() ||
+		len(in[0].PartialSigs) != 1 || in[0].SighashType != 1 ||
+		in[1].FinalScriptSig == nil || len(in[1].Unknowns) != 1 ||
+		parsed.Outputs[0].RedeemScript[0] != 0x52 || len(parsed.Unknowns) != 1 {
+		t.Errorf("Parse: got %+v", parsed)
+	}
+
+	b64, err := p.B64Encode()
+	if err != nil {
+		t.Fatalf("B64Encode: unexpected error: %v", err)
+	}
+	parsed, err = psbt.ParseBase64(b64)
+	if err != nil {
+		t.Fatalf("ParseBase64: unexpected error: %v", err)
+	}
+	var again bytes.Buffer
+	if err := parsed.Serialize(&again); err != nil {
+		t.Fatalf("Serialize: unexpected error: %v", err)
+	}
+	if !bytes.Equal(again.Bytes(), buf.Bytes()) {
+		t.Errorf("Serialize: got %x after round trip, want %x",
+			again.Bytes(), buf.Bytes())
+	}
+	if _, err := psbt.ParseBase64("!"); err == nil {
+		t.Errorf("ParseBase64: no error for invalid base64")
+	}
+}
+
+// TestParseErrors ensures malformed packets are rejected.
+func TestParseErrors(t *testing.T) {
+	p := testPacket(t)
+	var buf bytes.Buffer
+	if err := p.Serialize(&buf); err != nil {
+		t.Fatalf("Serialize: unexpected error: %v", err)
+	}
+	valid := buf.Bytes()
+
+	withGlobal := func(global ...byte) []byte {
+		return append(append([]byte("psbt\xff"), global...), 0x00)
+	}
+
+	tests := []struct {
+		name string
+		b    []byte
+		err  error
+	}{
+		{"bad magic", append([]byte("psbu\xff"), valid[5:]...), psbt.ErrInvalidMagic},
+		{"short magic", []byte("psb"), psbt.ErrInvalidMagic},
+		{"truncated", valid[:len(valid)-1], psbt.ErrInvalidFormat},
+		{"no transaction", withGlobal(0x01, 0x70, 0x00), psbt.ErrInvalidFormat},
+		{"duplicate key", withGlobal(0x01, 0x70, 0x00, 0x01, 0x70, 0x00), psbt.ErrInvalidFormat},
+		{"oversized value", withGlobal(0x01, 0x70, 0xfe, 0xff, 0xff, 0xff, 0xff), psbt.ErrInvalidFormat},
+		{"bad transaction", withGlobal(0x01, 0x00, 0x01, 0x00), psbt.ErrInvalidFormat},
+	}
+	for _, test := range tests {
+		if _, err := psbt.Parse(bytes.NewReader(test.b)); err != test.err {
+			t.Errorf("Parse(%s): got error %v, want %v", test.name, err,
+				test.err)
+		}
+	}
+
+	// Unknown pairs are written unchecked, so they can stand in for
+	// malformed fields of the second input.
+	var prev bytes.Buffer
+	if err := p.UnsignedTx.Serialize(&prev); err != nil {
+		t.Fatalf("Serialize: unexpected error: %v", err)
+	}
+	fields := []struct {
+		name string
+		pair *psbt.Unknown
+		err  error
+	}{
+		{"short sighash", &psbt.Unknown{Key: []byte{0x03}, Value: []byte{0x01}}, psbt.ErrInvalidFormat},
+		{"wrong utxo", &psbt.Unknown{Key: []byte{0x00}, Value: prev.Bytes()}, psbt.ErrInputMismatch},
+	}
+	for _, test := range fields {
+		p.Inputs[1].NonWitnessUtxo = nil
+		p.Inputs[1].Unknowns = []*psbt.Unknown{test.pair}
+		buf.Reset()
+		if err := p.Serialize(&buf); err != nil {
+			t.Fatalf("Serialize: unexpected error: %v", err)
+		}
+		if _, err := psbt.Parse(bytes.NewReader(buf.Bytes())); err != test.err {
+			t.Errorf("Parse(%s): got error %v, want %v", test.name, err,
+				test.err)
+		}
+	}
+}
+
+// TestParseWithOptions ensures deviations from the format are accepted only
+// at the strictness allowing them.
+func TestParseWithOptions(t *testing.T) {
+	strict := btcutil.DecodeOptions{Strictness: btcutil.Strict}
+	lenient := btcutil.DecodeOptions{Strictness: btcutil.Lenient}
+	recovery := btcutil.DecodeOptions{Strictness: btcutil.Recovery}
+
+	// Grow the packet until its base64 encoding needs padding.
+	p := testPacket(t)
+	p.Unknowns = []*psbt.Unknown{{Key: []byte{0xfc}, Value: []byte{}}}
+	var buf bytes.Buffer
+	for {
+		buf.Reset()
+		if err := p.Serialize(&buf); err != nil {
+			t.Fatalf("Serialize: unexpected error: %v", err)
+		}
+		if buf.Len()%3 != 0 {
+			break
+		}
+		p.Unknowns[0].Value = append(p.Unknowns[0].Value, 0x01)
+	}
+	valid := buf.Bytes()
+
+	if _, err := psbt.ParseWithOptions(valid, strict); err != nil {
+		t.Errorf("ParseWithOptions: unexpected error: %v", err)
+	}
+	trailing := append(append([]byte(nil), valid...), 0x00)
+	if _, err := psbt.ParseWithOptions(trailing, strict); err != psbt.ErrTrailingData {
+		t.Errorf("ParseWithOptions(strict): got error %v, want %v", err,
+			psbt.ErrTrailingData)
+	}
+	if _, err := psbt.ParseWithOptions(trailing, lenient); err != nil {
+		t.Errorf("ParseWithOptions(lenient): unexpected error: %v", err)
+	}
+
+	// Unknown pairs are written unchecked, so they can hold duplicate keys.
+	dup := testPacket(t)
+	dup.Inputs[1].Unknowns = []*psbt.Unknown{
+		{Key: []byte{0x42}, Value: []byte{0x01}},
+		{Key: []byte{0x42}, Value: []byte{0x02}},
+	}
+	buf.Reset()
+	if err := dup.Serialize(&buf); err != nil {
+		t.Fatalf("Serialize: unexpected error: %v", err)
+	}
+	if _, err := psbt.ParseWithOptions(buf.Bytes(), lenient); err != psbt.ErrInvalidFormat {
+		t.Errorf("ParseWithOptions(lenient): got error %v, want %v", err,
+			psbt.ErrInvalidFormat)
+	}
+	parsed, err := psbt.ParseWithOptions(buf.Bytes(), recovery)
+	if err != nil {
+		t.Fatalf("ParseWithOptions(recovery): unexpected error: %v", err)
+	}
+	if u := parsed.Inputs[1].Unknowns; len(u) != 1 || !bytes.Equal(u[0].Value, []byte{0x01}) {
+		t.Errorf("ParseWithOptions(recovery): got unknowns %v, want first pair", u)
+	}
+
+	b64, err := p.B64Encode()
+	if err != nil {
+		t.Fatalf("B64Encode: unexpected error: %v", err)
+	}
+	tests := []struct {
+		name    string
+		s       string
+		strict  bool
+		lenient bool
+	}{
+		{"canonical", b64, true, true},
+		{"line breaks", b64[:8] + "\r\n" + b64[8:], false, true},
+		{"spaces", " " + b64[:8] + " " + b64[8:] + "\t", false, true},
+		{"unpadded", strings.TrimRight(b64, "="), false, true},
+		{"invalid", "!" + b64, false, false},
+	}
+	for _, test := range tests {
+		_, err := psbt.ParseBase64WithOptions(test.s, strict)
+		if (err == nil) != test.strict {
+			t.Errorf("ParseBase64WithOptions(%s, strict): got error %v",
+				test.name, err)
+		}
+		_, err = psbt.ParseBase64WithOptions(test.s, lenient)
+		if (err == nil) != test.lenient {
+			t.Errorf("ParseBase64WithOptions(%s, lenient): got error %v",
+				test.name, err)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package psbt
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil/signing"
+	"github.com/zeusyf/btcutil/taproot"
+)
+
+var (
+	// ErrMissingPrevOut describes an error where an input is signed with a
+	// Schnorr signature while the previous transaction of some input of
+	// the packet is unknown.  Schnorr signatures commit to all spent
+	// outputs.
+	ErrMissingPrevOut = errors.New("packet lacks previous transactions")
+
+	// ErrSighashMismatch describes an error where an input is signed with
+	// a signature hash type other than the one the packet requires.
+	ErrSighashMismatch = errors.New("signature hash type not allowed by " +
+		"packet")
+)
+
+// PrevOutputs returns the outputs spent by the inputs of the transaction, in
+// the order of the inputs.
+func (p *Packet) PrevOutputs() ([]*wire.TxOut, error) {
+	prevOuts := make([]*wire.TxOut, len(p.Inputs))
+	for i := range p.Inputs {
+		prevOuts[i] = p.PrevOutput(i)
+		if prevOuts[i] == nil {
+			return nil, ErrMissingPrevOut
+		}
+	}
+	return prevOuts, nil
+}
+
+// PrevOutFetcher returns a fetcher of the outputs spent by the inputs whose
+// previous transaction is known.  Compose it with a signing.MultiPrevOutFetcher
+// to supply the outputs the packet lacks from elsewhere.
+func (p *Packet) PrevOutFetcher() signing.MapPrevOutFetcher {
+	fetcher := make(signing.MapPrevOutFetcher, len(p.Inputs))
+	for i, in := range p.UnsignedTx.TxIn {
+		if prevOut := p.PrevOutput(i); prevOut != nil {
+			fetcher.AddPrevOut(in.PreviousOutPoint, prevOut)
+		}
+	}
+	return fetcher
+}
+
+// SignSchnorr adds the Schnorr signature of the i-th input by signer to its
+// partial signatures, keyed by the x-only serialization of the key of the
+// signer.  The signature hash commits to the outputs spent by all inputs, so
+// the previous transactions of all inputs must be known.
+func (p *Packet) SignSchnorr(i int, signer signing.SchnorrSigner,
+	hashType signing.SigHashType) error {
+	if i < 0 || i >= len(p.Inputs) {
+		return signing.ErrInputIndex
+	}
+	in := &p.Inputs[i]
+	if in.SighashType != 0 && in.SighashType != uint32(hashType) {
+		return ErrSighashMismatch
+	}
+	prevOuts, err := p.PrevOutputs()
+	if err != nil {
+		return err
+	}
+	sig, err := signing.SignSchnorrInput(signer, p.UnsignedTx, i, prevOuts,
+		hashType)
+	if err != nil {
+		return err
+	}
+
+	in.addPartialSig(taproot.SerializeXOnly(signer.PubKey()), sig)
+	return nil
+}
+
+// addPartialSig adds sig by pubKey to the partial signatures of the input,
+// replacing any previous signature by the same key.
+func (in *Input) addPartialSig(pubKey, sig []byte) {
+	for _, partial := range in.PartialSigs {
+		if bytes.Equal(partial.PubKey, pubKey) {
+			partial.Signature = sig
+			return
+		}
+	}
+	in.PartialSigs = append(in.PartialSigs, &PartialSig{
+		PubKey:    pubKey,
+		Signature: sig,
+	})
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package psbt_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil/psbt"
+	"github.com/zeusyf/btcutil/signing"
+	"github.com/zeusyf/btcutil/taproot"
+)
+
+// TestSignSchnorr ensures Schnorr signatures of inputs are added to their
+// partial signatures and commit to the spent outputs of the packet.
+func TestSignSchnorr(t *testing.T) {
+	key, _ := btcec.PrivKeyFromBytes(btcec.S256(), []byte{0x2a})
+	signer := signing.NewKeySigner(key)
+
+	p := testPacket(t)
+	if err := p.SignSchnorr(1, signer, signing.SigHashAll); err != nil {
+		t.Fatalf("SignSchnorr: unexpected error: %v", err)
+	}
+	sigs := p.Inputs[1].PartialSigs
+	if len(sigs) != 1 {
+		t.Fatalf("got %d partial signatures, want 1", len(sigs))
+	}
+	if !bytes.Equal(sigs[0].PubKey, taproot.SerializeXOnly(key.PubKey())) {
+		t.Errorf("partial signature has key %x", sigs[0].PubKey)
+	}
+	prevOuts, err := p.PrevOutputs()
+	if err != nil {
+		t.Fatalf("PrevOutputs: unexpected error: %v", err)
+	}
+	if !signing.VerifySchnorrInput(key.PubKey(), sigs[0].Signature,
+		p.UnsignedTx, 1, prevOuts) {
+		t.Errorf("partial signature does not verify")
+	}
+
+	// Signing again replaces the signature.
+	if err := p.SignSchnorr(1, signer, signing.SigHashDefault); err != nil {
+		t.Fatalf("SignSchnorr: unexpected error: %v", err)
+	}
+	if n := len(p.Inputs[1].PartialSigs); n != 1 {
+		t.Errorf("got %d partial signatures after signing again", n)
+	}
+	if n := len(p.Inputs[1].PartialSigs[0].Signature); n != signing.SchnorrSigSize {
+		t.Errorf("got %d byte signature for the default hash type", n)
+	}
+
+	p.Inputs[0].SighashType = uint32(signing.SigHashSingle)
+	if err := p.SignSchnorr(0, signer, signing.SigHashAll); err != psbt.ErrSighashMismatch {
+		t.Errorf("hash type: got %v, want %v", err, psbt.ErrSighashMismatch)
+	}
+
+	p.Inputs[0].NonWitnessUtxo = nil
+	if err := p.SignSchnorr(1, signer, signing.SigHashAll); err != psbt.ErrMissingPrevOut {
+		t.Errorf("missing previous transaction: got %v, want %v", err,
+			psbt.ErrMissingPrevOut)
+	}
+}
+
+// TestPrevOutFetcher ensures packets supply the outputs spent by the inputs
+// whose previous transaction they carry, and compose with other fetchers.
+func TestPrevOutFetcher(t *testing.T) {
+	p := testPacket(t)
+	prev := p.Inputs[1].NonWitnessUtxo
+	p.Inputs[0].NonWitnessUtxo = nil
+
+	fetcher := p.PrevOutFetcher()
+	ops := []wire.OutPoint{
+		p.UnsignedTx.TxIn[0].PreviousOutPoint,
+		p.UnsignedTx.TxIn[1].PreviousOutPoint,
+	}
+	if got := fetcher.FetchPrevOutput(ops[0]); got != nil {
+		t.Errorf("unknown output: got %v", got)
+	}
+	if got := fetcher.FetchPrevOutput(ops[1]); got != prev.TxOut[1] {
+		t.Errorf("known output: got %v, want %v", got, prev.TxOut[1])
+	}
+
+	multi := signing.NewMultiPrevOutFetcher(fetcher,
+		signing.MapPrevOutFetcher{ops[0]: prev.TxOut[0]})
+	cache := signing.NewSigHashCache(p.UnsignedTx, multi)
+	if _, err := cache.SchnorrSigHash(0, signing.SigHashAll); err != nil {
+		t.Errorf("SchnorrSigHash: unexpected error: %v", err)
+	}
+}
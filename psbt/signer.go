@@ -0,0 +1,241 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package psbt
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/hdkeychain"
+	"github.com/zeusyf/btcutil/scriptclass"
+	"github.com/zeusyf/btcutil/signing"
+	"github.com/zeusyf/btcutil/taproot"
+)
+
+var (
+	// ErrNoSigningKey describes an error where a signer has no key able to
+	// sign an input.
+	ErrNoSigningKey = errors.New("signer has no key for input")
+
+	// ErrUnsupportedScript describes an error where an input spends an
+	// output whose signature hash can't be computed from the packet, such
+	// as a witness script hash output, whose witness script packets do not
+	// carry.
+	ErrUnsupportedScript = errors.New("unsupported script")
+)
+
+// Signer signs the inputs of packets.
+type Signer interface {
+	// SignInput adds the signatures of the i-th input of p by the keys of
+	// the signer to its partial signatures.
+	SignInput(p *Packet, i int) error
+}
+
+// softwareKey is a private key of a SoftwareSigner along with its public key
+// serialized as scripts commit to it.
+type softwareKey struct {
+	key    *btcec.PrivateKey
+	pubKey []byte
+}
+
+// SoftwareSigner is a Signer with private keys held in memory.  It signs the
+// inputs spending outputs which commit to any of its keys, with the signature
+// hash their script calls for:
+//
+//   - BIP0341 Schnorr signatures for taproot outputs whose internal key the
+//     input lists: by the key path when the internal key is one of its
+//     keys, and by each leaf script of the input pushing one of its keys
+//   - BIP0341 Schnorr signatures for other taproot outputs whose output key
+//     is the BIP0086 output key of one of its keys, signed by the key path
+//     and added to the partial signatures
+//   - BIP0143 signatures for pay-to-witness-pubkey-hash outputs, nested in a
+//     pay-to-script-hash output when the input has a redeem script
+//   - legacy signatures for all other outputs, over the redeem script of the
+//     input when it has one, and over the public key script of the output
+//     otherwise
+//
+// ECDSA signatures use the signature hash type of the input, or SigHashAll
+// when it has none.
+type SoftwareSigner struct {
+	keys []softwareKey
+	opts []signing.Option
+}
+
+// Ensure SoftwareSigner implements the Signer interface.
+var _ Signer = (*SoftwareSigner)(nil)
+
+// NewSoftwareSigner returns a signer without keys, whose signatures are made
+// by signing.KeySigners with the passed options.
+func NewSoftwareSigner(opts ...signing.Option) *SoftwareSigner {
+	return &SoftwareSigner{opts: opts}
+}
+
+// AddKey adds a private key to the signer, whose public key is serialized
+// compressed.
+func (s *SoftwareSigner) AddKey(key *btcec.PrivateKey) {
+	s.keys = append(s.keys, softwareKey{
+		key:    key,
+		pubKey: key.PubKey().SerializeCompressed(),
+	})
+}
+
+// AddWIF adds the private key of wif to the signer, whose public key is
+// serialized as wif specifies.
+func (s *SoftwareSigner) AddWIF(wif *btcutil.WIF) {
+	s.keys = append(s.keys, softwareKey{
+		key:    wif.PrivKey,
+		pubKey: wif.SerializePubKey(),
+	})
+}
+
+// AddExtendedKey adds the private key of an extended key to the signer.
+// hdkeychain.ErrNotPrivExtKey is returned when the key is public.
+func (s *SoftwareSigner) AddExtendedKey(key *hdkeychain.ExtendedKey) error {
+	privKey, err := key.ECPrivKey()
+	if err != nil {
+		return err
+	}
+	s.AddKey(privKey)
+	return nil
+}
+
+// SignInput adds the signatures of the i-th input of p by every key of the
+// signer its spent output commits to.  ErrNoSigningKey is returned when the
+// output commits to none.  Part of the Signer interface.
+func (s *SoftwareSigner) SignInput(p *Packet, i int) error {
+	if i < 0 || i >= len(p.Inputs) {
+		return signing.ErrInputIndex
+	}
+	prevOut := p.PrevOutput(i)
+	if prevOut == nil {
+		return ErrMissingPrevOut
+	}
+	in := &p.Inputs[i]
+	script := prevOut.PkScript
+	if in.RedeemScript != nil {
+		script = in.RedeemScript
+	}
+	class := scriptclass.Classify(script)
+	if class == scriptclass.WitnessV0ScriptHashTy {
+		return ErrUnsupportedScript
+	}
+	hashType := signing.SigHashType(in.SighashType)
+	ecdsaHashType := hashType
+	if ecdsaHashType == signing.SigHashDefault {
+		ecdsaHashType = signing.SigHashAll
+	}
+
+	signed := false
+	for _, k := range s.keys {
+		if class == scriptclass.WitnessV1TaprootTy && in.TaprootInternalKey != nil {
+			ok, err := s.signTaprootInput(p, i, k, hashType)
+			if err != nil {
+				return err
+			}
+			signed = signed || ok
+			continue
+		}
+		if class == scriptclass.WitnessV1TaprootTy {
+			outputKey, err := taproot.ComputeTaprootKeyNoScript(k.key.PubKey())
+			if err != nil {
+				return err
+			}
+			if !bytes.Equal(script[2:], taproot.SerializeXOnly(outputKey)) {
+				continue
+			}
+			tweaked, err := taproot.TweakTaprootPrivKey(k.key, nil)
+			if err != nil {
+				return err
+			}
+			signer := signing.NewKeySigner(tweaked, s.opts...)
+			if err := p.SignSchnorr(i, signer, hashType); err != nil {
+				return err
+			}
+			signed = true
+			continue
+		}
+
+		pkHash := btcutil.Hash160(k.pubKey)
+		if !bytes.Contains(script, k.pubKey) && !bytes.Contains(script, pkHash) {
+			continue
+		}
+		var sigHash []byte
+		var err error
+		if class == scriptclass.WitnessV0PubKeyHashTy {
+			sigHash, err = signing.WitnessV0SigHash(p.UnsignedTx, i,
+				pubKeyHashScript(pkHash), prevOut, ecdsaHashType)
+		} else {
+			sigHash, err = signing.LegacySigHash(p.UnsignedTx, i, script,
+				ecdsaHashType)
+		}
+		if err != nil {
+			return err
+		}
+		signer := signing.NewKeySigner(k.key, s.opts...)
+		sig, err := signing.SignECDSA(signer, sigHash, ecdsaHashType)
+		if err != nil {
+			return err
+		}
+		in.addPartialSig(k.pubKey, sig)
+		signed = true
+	}
+	if !signed {
+		return ErrNoSigningKey
+	}
+	return nil
+}
+
+// signTaprootInput signs the i-th input of p, a taproot input listing its
+// internal key, with k: by the key path when k is the internal key, and by
+// each leaf script of the input pushing the x-only key of k.  It returns
+// whether it signed.
+func (s *SoftwareSigner) signTaprootInput(p *Packet, i int, k softwareKey,
+	hashType signing.SigHashType) (bool, error) {
+	in := &p.Inputs[i]
+	xOnly := taproot.SerializeXOnly(k.key.PubKey())
+	signed := false
+	if bytes.Equal(xOnly, in.TaprootInternalKey) {
+		tweaked, err := taproot.TweakTaprootPrivKey(k.key,
+			in.TaprootMerkleRoot)
+		if err != nil {
+			return false, err
+		}
+		signer := signing.NewKeySigner(tweaked, s.opts...)
+		if err := p.SignTaprootKeySpend(i, signer, hashType); err != nil {
+			return false, err
+		}
+		signed = true
+	}
+
+	signer := signing.NewKeySigner(k.key, s.opts...)
+	for _, leaf := range in.TaprootLeafScripts {
+		if !bytes.Contains(leaf.Script, xOnly) {
+			continue
+		}
+		tapLeaf := taproot.TapLeaf{
+			LeafVersion: leaf.LeafVersion,
+			Script:      leaf.Script,
+		}
+		err := p.SignTaprootScriptSpend(i, signer, tapLeaf, hashType)
+		if err != nil {
+			return false, err
+		}
+		signed = true
+	}
+	return signed, nil
+}
+
+// pubKeyHashScript returns the script code of a pay-to-witness-pubkey-hash
+// output, the pay-to-pubkey-hash script of its key hash.
+func pubKeyHashScript(pkHash []byte) []byte {
+	// OP_DUP OP_HASH160 <20 byte hash> OP_EQUALVERIFY OP_CHECKSIG
+	script := make([]byte, 0, 25)
+	script = append(script, scriptclass.OP_DUP, scriptclass.OP_HASH160,
+		scriptclass.OP_DATA_20)
+	script = append(script, pkHash...)
+	return append(script, scriptclass.OP_EQUALVERIFY, scriptclass.OP_CHECKSIG)
+}
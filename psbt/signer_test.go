@@ -0,0 +1,195 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package psbt_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/hdkeychain"
+	"github.com/zeusyf/btcutil/psbt"
+	"github.com/zeusyf/btcutil/signing"
+	"github.com/zeusyf/btcutil/taproot"
+)
+
+// scriptPacket returns a packet spending an output paying to each of the
+// passed scripts.
+func scriptPacket(t *testing.T, pkScripts ...[]byte) *psbt.Packet {
+	prev := wire.NewMsgTx(wire.TxVersion)
+	prev.AddTxIn(&wire.TxIn{SignatureIndex: 0xffffffff})
+	for _, pkScript := range pkScripts {
+		prev.AddTxOut(output(1000, pkScript))
+	}
+
+	tx := wire.NewMsgTx(wire.TxVersion)
+	for i := range pkScripts {
+		tx.AddTxIn(&wire.TxIn{
+			PreviousOutPoint: wire.OutPoint{Hash: prev.TxHash(), Index: uint32(i)},
+			Sequence:         wire.MaxTxInSequenceNum,
+		})
+	}
+	tx.AddTxOut(output(900, []byte{0x53}))
+
+	p, err := psbt.New(tx)
+	if err != nil {
+		t.Fatalf("New: unexpected error: %v", err)
+	}
+	for i := range p.Inputs {
+		p.Inputs[i].NonWitnessUtxo = prev
+	}
+	return p
+}
+
+// verifyECDSA returns whether sig, followed by its hash type, is a signature
+// of sigHash by pubKey.
+func verifyECDSA(sig []byte, sigHash []byte, pubKey *btcec.PublicKey) bool {
+	parsed, err := btcec.ParseDERSignature(sig[:len(sig)-1], btcec.S256())
+	return err == nil && parsed.Verify(sigHash, pubKey)
+}
+
+// TestSoftwareSigner ensures inputs are signed with the signature hash their
+// spent output calls for, by the keys it commits to.
+func TestSoftwareSigner(t *testing.T) {
+	key, _ := btcec.PrivKeyFromBytes(btcec.S256(), []byte{0x2a})
+	pubKey := key.PubKey().SerializeCompressed()
+	pkHash := btcutil.Hash160(pubKey)
+	outputKey, err := taproot.ComputeTaprootKeyNoScript(key.PubKey())
+	if err != nil {
+		t.Fatalf("ComputeTaprootKeyNoScript: unexpected error: %v", err)
+	}
+
+	omegaScript := append(append([]byte{0x00}, pkHash...), 0x41)
+	witnessScript := append([]byte{0x00, 0x14}, pkHash...)
+	p := scriptPacket(t, omegaScript, witnessScript,
+		taproot.PayToTaprootScript(outputKey))
+	p.Inputs[0].SighashType = uint32(signing.SigHashSingle)
+
+	signer := psbt.NewSoftwareSigner(signing.WithLowR())
+	signer.AddKey(key)
+	for i := range p.Inputs {
+		if err := signer.SignInput(p, i); err != nil {
+			t.Fatalf("SignInput(%d): unexpected error: %v", i, err)
+		}
+		sigs := p.Inputs[i].PartialSigs
+		if len(sigs) != 1 {
+			t.Fatalf("SignInput(%d): got %d partial signatures", i, len(sigs))
+		}
+	}
+
+	// Legacy signature over the public key script, with the hash type of
+	// the input.
+	sig := p.Inputs[0].PartialSigs[0]
+	sigHash, _ := signing.LegacySigHash(p.UnsignedTx, 0, omegaScript,
+		signing.SigHashSingle)
+	if !bytes.Equal(sig.PubKey, pubKey) || !verifyECDSA(sig.Signature,
+		sigHash, key.PubKey()) ||
+		sig.Signature[len(sig.Signature)-1] != byte(signing.SigHashSingle) {
+		t.Errorf("input 0: got invalid legacy signature")
+	}
+
+	// Witness version 0 signature over the pay-to-pubkey-hash script code.
+	scriptCode := append(append([]byte{0x76, 0xa9, 0x14}, pkHash...), 0x88, 0xac)
+	sig = p.Inputs[1].PartialSigs[0]
+	sigHash, _ = signing.WitnessV0SigHash(p.UnsignedTx, 1, scriptCode,
+		p.PrevOutput(1), signing.SigHashAll)
+	if !verifyECDSA(sig.Signature, sigHash, key.PubKey()) {
+		t.Errorf("input 1: got invalid witness signature")
+	}
+
+	// Schnorr signature by the output key.
+	sig = p.Inputs[2].PartialSigs[0]
+	prevOuts, _ := p.PrevOutputs()
+	if !bytes.Equal(sig.PubKey, taproot.SerializeXOnly(outputKey)) ||
+		!signing.VerifySchnorrInput(outputKey, sig.Signature, p.UnsignedTx,
+			2, prevOuts) {
+		t.Errorf("input 2: got invalid taproot signature")
+	}
+
+	// A nested witness program is read from the redeem script.
+	p = scriptPacket(t, []byte{0x05, 0x01, 0x42})
+	p.Inputs[0].RedeemScript = witnessScript
+	if err := signer.SignInput(p, 0); err != nil {
+		t.Fatalf("SignInput: unexpected error: %v", err)
+	}
+	sigHash, _ = signing.WitnessV0SigHash(p.UnsignedTx, 0, scriptCode,
+		p.PrevOutput(0), signing.SigHashAll)
+	if !verifyECDSA(p.Inputs[0].PartialSigs[0].Signature, sigHash,
+		key.PubKey()) {
+		t.Errorf("nested witness: got invalid signature")
+	}
+
+	errs := []struct {
+		name   string
+		script []byte
+		err    error
+	}{
+		{"other key", append([]byte{0x00, 0x14}, make([]byte, 20)...),
+			psbt.ErrNoSigningKey},
+		{"witness script hash", append([]byte{0x00, 0x20}, make([]byte, 32)...),
+			psbt.ErrUnsupportedScript},
+	}
+	for _, test := range errs {
+		p := scriptPacket(t, test.script)
+		if err := signer.SignInput(p, 0); err != test.err {
+			t.Errorf("%s: got error %v, want %v", test.name, err, test.err)
+		}
+	}
+	if err := signer.SignInput(p, 1); err != signing.ErrInputIndex {
+		t.Errorf("index: got error %v, want %v", err, signing.ErrInputIndex)
+	}
+}
+
+// TestSoftwareSignerKeys ensures keys imported as WIF and extended keys sign
+// for the public keys they serialize to.
+func TestSoftwareSignerKeys(t *testing.T) {
+	key, _ := btcec.PrivKeyFromBytes(btcec.S256(), []byte{0x2b})
+	wif, err := btcutil.NewWIF(key, &chaincfg.MainNetParams, false)
+	if err != nil {
+		t.Fatalf("NewWIF: unexpected error: %v", err)
+	}
+	uncompressed := wif.SerializePubKey()
+	p := scriptPacket(t, append(append([]byte{0x00},
+		btcutil.Hash160(uncompressed)...), 0x41))
+
+	signer := psbt.NewSoftwareSigner()
+	signer.AddKey(key)
+	if err := signer.SignInput(p, 0); err != psbt.ErrNoSigningKey {
+		t.Errorf("compressed key: got error %v, want %v", err,
+			psbt.ErrNoSigningKey)
+	}
+	signer.AddWIF(wif)
+	if err := signer.SignInput(p, 0); err != nil {
+		t.Fatalf("SignInput: unexpected error: %v", err)
+	}
+	if sigs := p.Inputs[0].PartialSigs; len(sigs) != 1 ||
+		!bytes.Equal(sigs[0].PubKey, uncompressed) {
+		t.Errorf("SignInput: got partial signatures %v", sigs)
+	}
+
+	master, err := hdkeychain.NewMaster(bytes.Repeat([]byte{1}, 32),
+		&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("NewMaster: unexpected error: %v", err)
+	}
+	pub, _ := master.ECPubKey()
+	p = scriptPacket(t, append(append([]byte{0x00},
+		btcutil.Hash160(pub.SerializeCompressed())...), 0x41))
+	signer = psbt.NewSoftwareSigner()
+	if err := signer.AddExtendedKey(master); err != nil {
+		t.Fatalf("AddExtendedKey: unexpected error: %v", err)
+	}
+	if err := signer.SignInput(p, 0); err != nil {
+		t.Errorf("SignInput: unexpected error: %v", err)
+	}
+	neutered, _ := master.Neuter()
+	if err := signer.AddExtendedKey(neutered); err != hdkeychain.ErrNotPrivExtKey {
+		t.Errorf("AddExtendedKey: got error %v, want %v", err,
+			hdkeychain.ErrNotPrivExtKey)
+	}
+}
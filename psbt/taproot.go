@@ -0,0 +1,372 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package psbt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil/scriptclass"
+	"github.com/zeusyf/btcutil/signing"
+	"github.com/zeusyf/btcutil/taproot"
+)
+
+var (
+	// ErrOutputIndex describes an error where an output the transaction of
+	// a packet does not have is updated.
+	ErrOutputIndex = errors.New("output index out of range")
+
+	// ErrTaprootKeyMismatch describes an error where the internal key and
+	// script tree passed for a taproot input or output do not yield the
+	// output key of its script.
+	ErrTaprootKeyMismatch = errors.New("taproot output key does not " +
+		"match script")
+
+	// ErrUnknownTapLeaf describes an error where a script of a taproot
+	// input is signed while the input does not list it.
+	ErrUnknownTapLeaf = errors.New("input has no such taproot leaf script")
+)
+
+// combineTaproot adds the taproot fields of o to the input, keeping the
+// values it already has.
+func (in *Input) combineTaproot(o *Input) {
+	if in.TaprootKeySpendSig == nil {
+		in.TaprootKeySpendSig = o.TaprootKeySpendSig
+	}
+	for _, sig := range o.TaprootScriptSpendSigs {
+		if in.taprootScriptSig(sig.XOnlyPubKey, sig.LeafHash) == nil {
+			in.TaprootScriptSpendSigs = append(in.TaprootScriptSpendSigs,
+				sig)
+		}
+	}
+	for _, leaf := range o.TaprootLeafScripts {
+		found := false
+		for _, l := range in.TaprootLeafScripts {
+			if bytes.Equal(l.ControlBlock, leaf.ControlBlock) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			in.TaprootLeafScripts = append(in.TaprootLeafScripts, leaf)
+		}
+	}
+	if in.TaprootInternalKey == nil {
+		in.TaprootInternalKey = o.TaprootInternalKey
+	}
+	if in.TaprootMerkleRoot == nil {
+		in.TaprootMerkleRoot = o.TaprootMerkleRoot
+	}
+}
+
+// taprootScriptSig returns the signature by xOnlyPubKey of the script whose
+// leaf hash is leafHash, or nil when the input has none.
+func (in *Input) taprootScriptSig(xOnlyPubKey, leafHash []byte) *TaprootScriptSpendSig {
+	for _, sig := range in.TaprootScriptSpendSigs {
+		if bytes.Equal(sig.XOnlyPubKey, xOnlyPubKey) &&
+			bytes.Equal(sig.LeafHash, leafHash) {
+			return sig
+		}
+	}
+	return nil
+}
+
+// checkTaprootOutput returns ErrTaprootKeyMismatch unless script pays the
+// output key committing to the internal key and tree.
+func checkTaprootOutput(script []byte, internalKey *btcec.PublicKey,
+	tree *taproot.TapscriptTree) error {
+	var root []byte
+	if tree != nil {
+		root = tree.RootHash[:]
+	}
+	outputKey, err := taproot.ComputeTaprootOutputKey(internalKey, root)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(script, taproot.PayToTaprootScript(outputKey)) {
+		return ErrTaprootKeyMismatch
+	}
+	return nil
+}
+
+// UpdateTaprootInput adds to the i-th input the data signers and finalizers
+// need to spend a taproot output committing to the internal key and, unless
+// it is nil, to the script tree: the internal key, the root hash of the tree,
+// and each script of the tree along with its control block.  When the
+// previous transaction of the input is known, ErrTaprootKeyMismatch is
+// returned unless the spent output pays the resulting output key.
+func (p *Packet) UpdateTaprootInput(i int, internalKey *btcec.PublicKey,
+	tree *taproot.TapscriptTree) error {
+	if i < 0 || i >= len(p.Inputs) {
+		return signing.ErrInputIndex
+	}
+	if prevOut := p.PrevOutput(i); prevOut != nil {
+		err := checkTaprootOutput(prevOut.PkScript, internalKey, tree)
+		if err != nil {
+			return err
+		}
+	}
+
+	in := &p.Inputs[i]
+	in.TaprootInternalKey = taproot.SerializeXOnly(internalKey)
+	in.TaprootMerkleRoot = nil
+	in.TaprootLeafScripts = nil
+	if tree == nil {
+		return nil
+	}
+	in.TaprootMerkleRoot = append([]byte(nil), tree.RootHash[:]...)
+	for j := range tree.LeafProofs {
+		proof := &tree.LeafProofs[j]
+		cb, err := proof.ToControlBlock(internalKey)
+		if err != nil {
+			return err
+		}
+		in.TaprootLeafScripts = append(in.TaprootLeafScripts,
+			&TaprootTapLeafScript{
+				ControlBlock: cb.ToBytes(),
+				Script:       proof.Script,
+				LeafVersion:  proof.LeafVersion,
+			})
+	}
+	return nil
+}
+
+// UpdateTaprootOutput adds to the i-th output its internal key and, unless it
+// is nil, its script tree, so the recipient of a change output can spend it.
+// ErrTaprootKeyMismatch is returned unless the output pays the resulting
+// output key.
+func (p *Packet) UpdateTaprootOutput(i int, internalKey *btcec.PublicKey,
+	tree *taproot.TapscriptTree) error {
+	if i < 0 || i >= len(p.Outputs) {
+		return ErrOutputIndex
+	}
+	err := checkTaprootOutput(p.UnsignedTx.TxOut[i].PkScript, internalKey,
+		tree)
+	if err != nil {
+		return err
+	}
+
+	out := &p.Outputs[i]
+	out.TaprootInternalKey = taproot.SerializeXOnly(internalKey)
+	out.TaprootTapTree = nil
+	if tree == nil {
+		return nil
+	}
+	for _, proof := range tree.LeafProofs {
+		out.TaprootTapTree = append(out.TaprootTapTree, TaprootTapLeaf{
+			Depth: uint8(len(proof.InclusionProof) /
+				taproot.ControlBlockNodeSize),
+			LeafVersion: proof.LeafVersion,
+			Script:      proof.Script,
+		})
+	}
+	return nil
+}
+
+// taprootSigning returns the outputs spent by the inputs of the packet, after
+// checking the i-th input may be signed with hashType and spends a taproot
+// output.
+func (p *Packet) taprootSigning(i int, hashType signing.SigHashType) ([]*wire.TxOut, error) {
+	if i < 0 || i >= len(p.Inputs) {
+		return nil, signing.ErrInputIndex
+	}
+	in := &p.Inputs[i]
+	if in.SighashType != 0 && in.SighashType != uint32(hashType) {
+		return nil, ErrSighashMismatch
+	}
+	prevOuts, err := p.PrevOutputs()
+	if err != nil {
+		return nil, err
+	}
+	if scriptclass.Classify(prevOuts[i].PkScript) != scriptclass.WitnessV1TaprootTy {
+		return nil, ErrUnsupportedScript
+	}
+	return prevOuts, nil
+}
+
+// SignTaprootKeySpend sets the key path signature of the i-th input, which
+// spends a taproot output, to the signature by signer.  The key of the signer
+// must be the output key, the internal key tweaked by
+// taproot.TweakTaprootPrivKey with the merkle root of the input.
+// ErrNoSigningKey is returned when it is not.
+func (p *Packet) SignTaprootKeySpend(i int, signer signing.SchnorrSigner,
+	hashType signing.SigHashType) error {
+	prevOuts, err := p.taprootSigning(i, hashType)
+	if err != nil {
+		return err
+	}
+	outputKey := prevOuts[i].PkScript[2:]
+	if !bytes.Equal(taproot.SerializeXOnly(signer.PubKey()), outputKey) {
+		return ErrNoSigningKey
+	}
+	sig, err := signing.SignSchnorrInput(signer, p.UnsignedTx, i, prevOuts,
+		hashType)
+	if err != nil {
+		return err
+	}
+	p.Inputs[i].TaprootKeySpendSig = sig
+	return nil
+}
+
+// SignTaprootScriptSpend adds the signature by signer of the i-th input,
+// spending the script of leaf, to its script path signatures, replacing any
+// previous signature by the same key of the same script.  The input must
+// list the script among its leaf scripts, or ErrUnknownTapLeaf is returned.
+func (p *Packet) SignTaprootScriptSpend(i int, signer signing.SchnorrSigner,
+	leaf taproot.TapLeaf, hashType signing.SigHashType) error {
+	prevOuts, err := p.taprootSigning(i, hashType)
+	if err != nil {
+		return err
+	}
+	in := &p.Inputs[i]
+	found := false
+	for _, l := range in.TaprootLeafScripts {
+		if l.LeafVersion == leaf.LeafVersion &&
+			bytes.Equal(l.Script, leaf.Script) {
+			found = true
+			break
+		}
+	}
+	if !found {
+		return ErrUnknownTapLeaf
+	}
+
+	leafHash := leaf.TapHash()
+	sig, err := signing.SignTapscriptInput(signer, p.UnsignedTx, i, prevOuts,
+		hashType, leafHash[:])
+	if err != nil {
+		return err
+	}
+	xOnly := taproot.SerializeXOnly(signer.PubKey())
+	if partial := in.taprootScriptSig(xOnly, leafHash[:]); partial != nil {
+		partial.Signature = sig
+		return nil
+	}
+	in.TaprootScriptSpendSigs = append(in.TaprootScriptSpendSigs,
+		&TaprootScriptSpendSig{
+			XOnlyPubKey: xOnly,
+			LeafHash:    leafHash[:],
+			Signature:   sig,
+		})
+	return nil
+}
+
+// FinalizeTaprootInput sets the final signature script of the i-th input,
+// which spends a taproot output, from its signatures.  Omega transactions
+// have no witness, so the signature script pushes the elements of the
+// witness of BIP0341 in their order: the key path signature, or the
+// signatures, script and control block of a script path.
+//
+// The key path is used when the input has its signature, or a partial
+// signature by the output key as SignSchnorr adds.  Otherwise the first of
+// its leaf scripts signed by every key the script pushes is used, with the
+// signatures in the reverse order of the keys, as scripts checking each key
+// in turn expect.  An error wrapping ErrNotFinalizable and ErrIncomplete is
+// returned when no path is signed.  Once finalized, the input only keeps its
+// previous transaction, final signature script and unknowns.
+func (p *Packet) FinalizeTaprootInput(i int) error {
+	if i < 0 || i >= len(p.Inputs) {
+		return signing.ErrInputIndex
+	}
+	in := &p.Inputs[i]
+	prevOut := p.PrevOutput(i)
+	if prevOut == nil {
+		return ErrMissingPrevOut
+	}
+	if scriptclass.Classify(prevOut.PkScript) != scriptclass.WitnessV1TaprootTy {
+		return ErrUnsupportedScript
+	}
+
+	var witness [][]byte
+	keySig := in.TaprootKeySpendSig
+	if keySig == nil {
+		for _, sig := range in.PartialSigs {
+			if bytes.Equal(sig.PubKey, prevOut.PkScript[2:]) {
+				keySig = sig.Signature
+				break
+			}
+		}
+	}
+	if keySig != nil {
+		witness = [][]byte{keySig}
+	} else {
+		for _, leaf := range in.TaprootLeafScripts {
+			if sigs := in.leafScriptSigs(leaf); sigs != nil {
+				witness = append(sigs, leaf.Script, leaf.ControlBlock)
+				break
+			}
+		}
+	}
+	if witness == nil {
+		return fmt.Errorf("%w: %w", ErrNotFinalizable, ErrIncomplete)
+	}
+
+	var script []byte
+	for _, elem := range witness {
+		script = appendPush(script, elem)
+	}
+	*in = Input{
+		NonWitnessUtxo: in.NonWitnessUtxo,
+		FinalScriptSig: script,
+		Unknowns:       in.Unknowns,
+	}
+	return nil
+}
+
+// leafScriptSigs returns the signatures of the leaf script by each of the
+// keys it pushes, in the reverse order of the keys, or nil unless the script
+// pushes keys and all of them signed it.
+func (in *Input) leafScriptSigs(leaf *TaprootTapLeafScript) [][]byte {
+	ops, err := scriptclass.Parse(leaf.Script)
+	if err != nil {
+		return nil
+	}
+	leafHash := taproot.TapLeaf{
+		LeafVersion: leaf.LeafVersion,
+		Script:      leaf.Script,
+	}.TapHash()
+
+	var sigs [][]byte
+	for _, op := range ops {
+		if op.Op != scriptclass.OP_DATA_32 {
+			continue
+		}
+		sig := in.taprootScriptSig(op.Data, leafHash[:])
+		if sig == nil {
+			return nil
+		}
+		sigs = append([][]byte{sig.Signature}, sigs...)
+	}
+	return sigs
+}
+
+// appendPush appends the minimal push of data to script.
+func appendPush(script, data []byte) []byte {
+	switch {
+	case len(data) == 0:
+		return append(script, scriptclass.OP_0)
+	case len(data) == 1 && data[0] >= 1 && data[0] <= 16:
+		return append(script, scriptclass.OP_1-1+data[0])
+	case len(data) == 1 && data[0] == 0x81:
+		return append(script, scriptclass.OP_1NEGATE)
+	case len(data) <= scriptclass.OP_DATA_75:
+		script = append(script, byte(len(data)))
+	case len(data) <= 0xff:
+		script = append(script, scriptclass.OP_PUSHDATA1, byte(len(data)))
+	case len(data) <= 0xffff:
+		script = append(script, scriptclass.OP_PUSHDATA2, 0, 0)
+		binary.LittleEndian.PutUint16(script[len(script)-2:],
+			uint16(len(data)))
+	default:
+		script = append(script, scriptclass.OP_PUSHDATA4, 0, 0, 0, 0)
+		binary.LittleEndian.PutUint32(script[len(script)-4:],
+			uint32(len(data)))
+	}
+	return append(script, data...)
+}
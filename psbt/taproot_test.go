@@ -0,0 +1,319 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package psbt_test
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/psbt"
+	"github.com/zeusyf/btcutil/scriptclass"
+	"github.com/zeusyf/btcutil/signing"
+	"github.com/zeusyf/btcutil/taproot"
+)
+
+// taprootFixture is a packet spending a taproot output whose internal key is
+// internal, with a tree of a script signed by a and of a script signed by a
+// and b.
+type taprootFixture struct {
+	p           *psbt.Packet
+	internal    *btcec.PrivateKey
+	a, b        *btcec.PrivateKey
+	tree        *taproot.TapscriptTree
+	outputKey   *btcec.PublicKey
+	single, and taproot.TapLeaf
+}
+
+func newTaprootFixture(t *testing.T) *taprootFixture {
+	f := new(taprootFixture)
+	f.internal, _ = btcec.PrivKeyFromBytes(btcec.S256(), []byte{0x01})
+	f.a, _ = btcec.PrivKeyFromBytes(btcec.S256(), []byte{0x02})
+	f.b, _ = btcec.PrivKeyFromBytes(btcec.S256(), []byte{0x03})
+
+	// <a> OP_CHECKSIG and <a> OP_CHECKSIGVERIFY <b> OP_CHECKSIG.
+	xa := taproot.SerializeXOnly(f.a.PubKey())
+	xb := taproot.SerializeXOnly(f.b.PubKey())
+	f.single = taproot.NewBaseTapLeaf(append(append([]byte{0x20}, xa...), 0xac))
+	and := append(append([]byte{0x20}, xa...), 0xad, 0x20)
+	f.and = taproot.NewBaseTapLeaf(append(append(and, xb...), 0xac))
+	f.tree = taproot.AssembleTaprootScriptTree(f.single, f.and)
+
+	var err error
+	f.outputKey, err = taproot.ComputeTaprootOutputKey(f.internal.PubKey(),
+		f.tree.RootHash[:])
+	if err != nil {
+		t.Fatalf("ComputeTaprootOutputKey: unexpected error: %v", err)
+	}
+	script := taproot.PayToTaprootScript(f.outputKey)
+
+	prev := wire.NewMsgTx(wire.TxVersion)
+	prev.AddTxIn(&wire.TxIn{SignatureIndex: 0xffffffff})
+	prev.AddTxOut(output(1000, script))
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: wire.OutPoint{Hash: prev.TxHash()},
+		Sequence:         wire.MaxTxInSequenceNum,
+	})
+	tx.AddTxOut(output(900, script))
+	f.p, err = psbt.New(tx)
+	if err != nil {
+		t.Fatalf("New: unexpected error: %v", err)
+	}
+	f.p.Inputs[0].NonWitnessUtxo = prev
+	return f
+}
+
+// TestUpdateTaproot ensures updaters add the internal key and scripts of
+// taproot inputs and outputs, which survive serialization.
+func TestUpdateTaproot(t *testing.T) {
+	f := newTaprootFixture(t)
+	if err := f.p.UpdateTaprootInput(0, f.internal.PubKey(), nil); err != psbt.ErrTaprootKeyMismatch {
+		t.Errorf("UpdateTaprootInput: got error %v, want %v", err,
+			psbt.ErrTaprootKeyMismatch)
+	}
+	if err := f.p.UpdateTaprootInput(0, f.internal.PubKey(), f.tree); err != nil {
+		t.Fatalf("UpdateTaprootInput: unexpected error: %v", err)
+	}
+	if err := f.p.UpdateTaprootOutput(0, f.internal.PubKey(), f.tree); err != nil {
+		t.Fatalf("UpdateTaprootOutput: unexpected error: %v", err)
+	}
+	if err := f.p.UpdateTaprootOutput(1, f.internal.PubKey(), f.tree); err != psbt.ErrOutputIndex {
+		t.Errorf("UpdateTaprootOutput: got error %v, want %v", err,
+			psbt.ErrOutputIndex)
+	}
+
+	in := f.p.Inputs[0]
+	if !bytes.Equal(in.TaprootInternalKey, taproot.SerializeXOnly(f.internal.PubKey())) ||
+		!bytes.Equal(in.TaprootMerkleRoot, f.tree.RootHash[:]) ||
+		len(in.TaprootLeafScripts) != 2 {
+
+		t.Fatalf("UpdateTaprootInput: got input %+v", in)
+	}
+	for i, leaf := range in.TaprootLeafScripts {
+		cb, err := taproot.ParseControlBlock(leaf.ControlBlock)
+		if err != nil {
+			t.Fatalf("ParseControlBlock: unexpected error: %v", err)
+		}
+		err = taproot.VerifyTaprootLeafCommitment(cb,
+			taproot.SerializeXOnly(f.outputKey), leaf.Script)
+		if err != nil || leaf.LeafVersion != taproot.BaseLeafVersion {
+			t.Errorf("leaf script %d: got error %v", i, err)
+		}
+	}
+	wantTree := []psbt.TaprootTapLeaf{
+		{Depth: 1, LeafVersion: taproot.BaseLeafVersion, Script: f.single.Script},
+		{Depth: 1, LeafVersion: taproot.BaseLeafVersion, Script: f.and.Script},
+	}
+	if got := f.p.Outputs[0].TaprootTapTree; !reflect.DeepEqual(got, wantTree) {
+		t.Errorf("UpdateTaprootOutput: got tree %+v, want %+v", got, wantTree)
+	}
+
+	var buf bytes.Buffer
+	if err := f.p.Serialize(&buf); err != nil {
+		t.Fatalf("Serialize: unexpected error: %v", err)
+	}
+	parsed, err := psbt.Parse(&buf)
+	if err != nil {
+		t.Fatalf("Parse: unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(parsed.Inputs[0].TaprootLeafScripts, in.TaprootLeafScripts) ||
+		!bytes.Equal(parsed.Inputs[0].TaprootMerkleRoot, in.TaprootMerkleRoot) ||
+		!reflect.DeepEqual(parsed.Outputs[0], f.p.Outputs[0]) {
+
+		t.Errorf("Parse: got %+v, want %+v", parsed.Inputs[0], in)
+	}
+}
+
+// TestTaprootScriptSpend ensures script path signatures are made for each
+// script pushing a key of the signer, and finalized into the pushes of a
+// script path witness.
+func TestTaprootScriptSpend(t *testing.T) {
+	f := newTaprootFixture(t)
+	if err := f.p.UpdateTaprootInput(0, f.internal.PubKey(), f.tree); err != nil {
+		t.Fatalf("UpdateTaprootInput: unexpected error: %v", err)
+	}
+	err := f.p.FinalizeTaprootInput(0)
+	if !errors.Is(err, psbt.ErrNotFinalizable) ||
+		!errors.Is(err, psbt.ErrIncomplete) {
+
+		t.Errorf("FinalizeTaprootInput: got error %v, want %v", err,
+			psbt.ErrNotFinalizable)
+	}
+
+	// The signatures of each key are made on a copy of the packet, and
+	// combined.
+	other := *f.p
+	other.Inputs = []psbt.Input{f.p.Inputs[0]}
+	for _, s := range []struct {
+		p   *psbt.Packet
+		key *btcec.PrivateKey
+	}{{f.p, f.b}, {&other, f.a}} {
+		signer := psbt.NewSoftwareSigner()
+		signer.AddKey(s.key)
+		if err := signer.SignInput(s.p, 0); err != nil {
+			t.Fatalf("SignInput: unexpected error: %v", err)
+		}
+	}
+	if err := f.p.Combine(&other); err != nil {
+		t.Fatalf("Combine: unexpected error: %v", err)
+	}
+	in := &f.p.Inputs[0]
+	if in.TaprootKeySpendSig != nil || len(in.TaprootScriptSpendSigs) != 3 {
+		t.Fatalf("SignInput: got %d script signatures and key signature %x",
+			len(in.TaprootScriptSpendSigs), in.TaprootKeySpendSig)
+	}
+	prevOuts, _ := f.p.PrevOutputs()
+	for _, sig := range in.TaprootScriptSpendSigs {
+		pub, _ := taproot.ParseXOnlyPubKey(sig.XOnlyPubKey)
+		sigHash, err := signing.TapscriptSigHash(f.p.UnsignedTx, 0,
+			prevOuts, signing.SigHashDefault, sig.LeafHash)
+		if err != nil {
+			t.Fatalf("TapscriptSigHash: unexpected error: %v", err)
+		}
+		if !signing.VerifySchnorr(pub, sigHash, sig.Signature) {
+			t.Errorf("script signature by %x does not verify",
+				sig.XOnlyPubKey)
+		}
+	}
+
+	// The first leaf script whose keys all signed is used.
+	leaf := in.TaprootLeafScripts[0]
+	singleHash := f.single.TapHash()
+	var sigA []byte
+	for _, sig := range in.TaprootScriptSpendSigs {
+		if bytes.Equal(sig.LeafHash, singleHash[:]) {
+			sigA = sig.Signature
+		}
+	}
+	if err := f.p.FinalizeTaprootInput(0); err != nil {
+		t.Fatalf("FinalizeTaprootInput: unexpected error: %v", err)
+	}
+	ops, err := scriptclass.Parse(in.FinalScriptSig)
+	if err != nil || len(ops) != 3 || !bytes.Equal(ops[0].Data, sigA) ||
+		!bytes.Equal(ops[1].Data, leaf.Script) ||
+		!bytes.Equal(ops[2].Data, leaf.ControlBlock) {
+
+		t.Errorf("FinalizeTaprootInput: got script %x", in.FinalScriptSig)
+	}
+	if in.TaprootLeafScripts != nil || in.TaprootScriptSpendSigs != nil ||
+		in.NonWitnessUtxo == nil {
+
+		t.Errorf("FinalizeTaprootInput: got input %+v", in)
+	}
+	if _, err := f.p.Extract(); err != nil {
+		t.Errorf("Extract: unexpected error: %v", err)
+	}
+}
+
+// TestTaprootKeySpend ensures key path signatures are made by the internal
+// key tweaked with the merkle root, and finalized into a single push.
+func TestTaprootKeySpend(t *testing.T) {
+	f := newTaprootFixture(t)
+	if err := f.p.UpdateTaprootInput(0, f.internal.PubKey(), f.tree); err != nil {
+		t.Fatalf("UpdateTaprootInput: unexpected error: %v", err)
+	}
+	untweaked := signing.NewKeySigner(f.internal)
+	if err := f.p.SignTaprootKeySpend(0, untweaked, signing.SigHashAll); err != psbt.ErrNoSigningKey {
+		t.Errorf("SignTaprootKeySpend: got error %v, want %v", err,
+			psbt.ErrNoSigningKey)
+	}
+	err := f.p.SignTaprootScriptSpend(0, untweaked,
+		taproot.NewBaseTapLeaf([]byte{0x51}), signing.SigHashAll)
+	if err != psbt.ErrUnknownTapLeaf {
+		t.Errorf("SignTaprootScriptSpend: got error %v, want %v", err,
+			psbt.ErrUnknownTapLeaf)
+	}
+
+	signer := psbt.NewSoftwareSigner()
+	signer.AddWIF(&btcutil.WIF{PrivKey: f.internal, CompressPubKey: true})
+	if err := signer.SignInput(f.p, 0); err != nil {
+		t.Fatalf("SignInput: unexpected error: %v", err)
+	}
+	in := &f.p.Inputs[0]
+	prevOuts, _ := f.p.PrevOutputs()
+	if !signing.VerifySchnorrInput(f.outputKey, in.TaprootKeySpendSig,
+		f.p.UnsignedTx, 0, prevOuts) || len(in.TaprootScriptSpendSigs) != 0 {
+
+		t.Fatalf("SignInput: got key signature %x", in.TaprootKeySpendSig)
+	}
+
+	var buf bytes.Buffer
+	if err := f.p.Serialize(&buf); err != nil {
+		t.Fatalf("Serialize: unexpected error: %v", err)
+	}
+	parsed, err := psbt.Parse(&buf)
+	if err != nil {
+		t.Fatalf("Parse: unexpected error: %v", err)
+	}
+	if !bytes.Equal(parsed.Inputs[0].TaprootKeySpendSig, in.TaprootKeySpendSig) {
+		t.Errorf("Parse: got key signature %x",
+			parsed.Inputs[0].TaprootKeySpendSig)
+	}
+
+	sig := in.TaprootKeySpendSig
+	if err := f.p.FinalizeTaprootInput(0); err != nil {
+		t.Fatalf("FinalizeTaprootInput: unexpected error: %v", err)
+	}
+	want := append([]byte{byte(len(sig))}, sig...)
+	if !bytes.Equal(in.FinalScriptSig, want) {
+		t.Errorf("FinalizeTaprootInput: got script %x, want %x",
+			in.FinalScriptSig, want)
+	}
+
+	p := testPacket(t)
+	if err := p.FinalizeTaprootInput(0); err != psbt.ErrUnsupportedScript {
+		t.Errorf("FinalizeTaprootInput: got error %v, want %v", err,
+			psbt.ErrUnsupportedScript)
+	}
+}
+
+// taprootKey returns a key of type keyType followed by n zero bytes.
+func taprootKey(keyType byte, n int) []byte {
+	return append([]byte{keyType}, make([]byte, n)...)
+}
+
+// TestParseTaprootInvalid ensures taproot fields of the wrong size are
+// rejected.
+func TestParseTaprootInvalid(t *testing.T) {
+	tests := []struct {
+		name  string
+		input bool
+		key   []byte
+		value []byte
+	}{
+		{"key signature", true, taprootKey(0x13, 0), make([]byte, 63)},
+		{"script signature key", true, taprootKey(0x14, 63), make([]byte, 64)},
+		{"script signature", true, taprootKey(0x14, 64), make([]byte, 66)},
+		{"control block", true, taprootKey(0x15, 34), []byte{0xc0}},
+		{"leaf script", true, taprootKey(0x15, 33), nil},
+		{"internal key", true, taprootKey(0x17, 0), make([]byte, 33)},
+		{"merkle root", true, taprootKey(0x18, 0), make([]byte, 31)},
+		{"output internal key", false, taprootKey(0x05, 0), make([]byte, 31)},
+		{"tap tree", false, taprootKey(0x06, 0), []byte{0x00, 0xc0, 0x02, 0x51}},
+		{"empty tap tree", false, taprootKey(0x06, 0), nil},
+	}
+	for _, test := range tests {
+		p := testPacket(t)
+		u := []*psbt.Unknown{{Key: test.key, Value: test.value}}
+		if test.input {
+			p.Inputs[0].Unknowns = u
+		} else {
+			p.Outputs[0].Unknowns = u
+		}
+		var buf bytes.Buffer
+		if err := p.Serialize(&buf); err != nil {
+			t.Fatalf("%s: Serialize: unexpected error: %v", test.name, err)
+		}
+		if _, err := psbt.Parse(&buf); err != psbt.ErrInvalidFormat {
+			t.Errorf("%s: Parse: got error %v, want %v", test.name, err,
+				psbt.ErrInvalidFormat)
+		}
+	}
+}
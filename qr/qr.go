@@ -0,0 +1,139 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package qr prepares addresses and payment URIs for QR codes.
+//
+// QR codes encode text in one of several modes.  The alphanumeric mode packs
+// two characters in 11 bits but only covers digits, uppercase letters and a
+// few symbols, while the byte mode takes 8 bits a character.  Bech32 strings
+// are case insensitive, so uppercasing them lets them use the alphanumeric
+// mode and shrinks the code by about a third.  Base58 addresses are case
+// sensitive and must stay in the byte mode.
+//
+// The functions of this package return the string to feed a QR library along
+// with the mode it fits in.
+package qr
+
+import (
+	"strings"
+
+	"github.com/zeusyf/btcutil/bech32"
+)
+
+// Mode is a QR code encoding mode.
+type Mode int
+
+const (
+	// ModeNumeric encodes digits only, three in 10 bits.
+	ModeNumeric Mode = iota
+
+	// ModeAlphanumeric encodes digits, uppercase letters and the symbols
+	// space, $, %, *, +, -, ., / and :, two in 11 bits.
+	ModeAlphanumeric
+
+	// ModeByte encodes any byte in 8 bits.
+	ModeByte
+)
+
+// Map of modes back to their constant names for pretty printing.
+var modeStrings = map[Mode]string{
+	ModeNumeric:      "ModeNumeric",
+	ModeAlphanumeric: "ModeAlphanumeric",
+	ModeByte:         "ModeByte",
+}
+
+// String returns the Mode in human-readable form.
+func (m Mode) String() string {
+	if s, ok := modeStrings[m]; ok {
+		return s
+	}
+	return "Unknown Mode"
+}
+
+// alphanumeric is the character set of the alphanumeric mode.
+const alphanumeric = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ $%*+-./:"
+
+// ModeOf returns the most compact mode s can be encoded in.
+func ModeOf(s string) Mode {
+	mode := ModeNumeric
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c >= '0' && c <= '9':
+		case strings.IndexByte(alphanumeric, c) >= 0:
+			mode = ModeAlphanumeric
+		default:
+			return ModeByte
+		}
+	}
+	return mode
+}
+
+// DataBits returns the number of bits encoding s in mode m, excluding the
+// mode indicator and character count.  It returns -1 when s does not fit in
+// m.
+func DataBits(s string, m Mode) int {
+	if ModeOf(s) > m {
+		return -1
+	}
+	n := len(s)
+	switch m {
+	case ModeNumeric:
+		bits := n / 3 * 10
+		switch n % 3 {
+		case 1:
+			bits += 4
+		case 2:
+			bits += 7
+		}
+		return bits
+	case ModeAlphanumeric:
+		return n/2*11 + n%2*6
+	case ModeByte:
+		return n * 8
+	}
+	return -1
+}
+
+// isBech32 returns whether s is a valid bech32 string, which is case
+// insensitive.
+func isBech32(s string) bool {
+	_, _, err := bech32.DecodeNoLimit(s)
+	return err == nil
+}
+
+// AddressPayload returns the payload of a QR code for the encoded address
+// addr, and its mode.  Bech32 addresses, and any other bech32 string such as
+// an invoice, are uppercased to fit in the alphanumeric mode.  Other
+// addresses are returned unchanged.
+func AddressPayload(addr string) (string, Mode) {
+	if isBech32(addr) {
+		addr = strings.ToUpper(addr)
+	}
+	return addr, ModeOf(addr)
+}
+
+// URIPayload returns the payload of a QR code for the BIP0021 payment URI
+// uri, and its mode.  The scheme is case insensitive, so it is uppercased
+// along with a bech32 address.  Parameters are case sensitive and left
+// unchanged, so a URI having any is returned in the byte mode, with an
+// uppercase prefix which QR libraries splitting payloads in segments can
+// still encode in the alphanumeric mode.  URIs with a base58 address are
+// returned unchanged.
+func URIPayload(uri string) (string, Mode) {
+	colon := strings.IndexByte(uri, ':')
+	if colon < 0 {
+		return uri, ModeOf(uri)
+	}
+	end := len(uri)
+	if q := strings.IndexByte(uri, '?'); q > colon {
+		end = q
+	}
+	addr := uri[colon+1 : end]
+	if !isBech32(addr) {
+		return uri, ModeOf(uri)
+	}
+	payload := strings.ToUpper(uri[:end]) + uri[end:]
+	return payload, ModeOf(payload)
+}
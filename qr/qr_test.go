@@ -0,0 +1,92 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package qr_test
+
+import (
+	"testing"
+
+	"github.com/zeusyf/btcutil/qr"
+)
+
+const (
+	bech32Addr = "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4"
+	base58Addr = "1BvBMSEYstWetqTFn5Au4m4GFg7xJaNVN2"
+)
+
+// TestAddressPayload ensures bech32 addresses are uppercased to fit in the
+// alphanumeric mode and other addresses are left unchanged.
+func TestAddressPayload(t *testing.T) {
+	tests := []struct {
+		addr    string
+		payload string
+		mode    qr.Mode
+	}{
+		{bech32Addr, "BC1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KV8F3T4", qr.ModeAlphanumeric},
+		{"BC1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KV8F3T4", "BC1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KV8F3T4", qr.ModeAlphanumeric},
+		{base58Addr, base58Addr, qr.ModeByte},
+		// A bad checksum is not a bech32 address.
+		{"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t5", "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t5", qr.ModeByte},
+	}
+	for _, test := range tests {
+		payload, mode := qr.AddressPayload(test.addr)
+		if payload != test.payload || mode != test.mode {
+			t.Errorf("AddressPayload(%s): got %s, %v, want %s, %v",
+				test.addr, payload, mode, test.payload, test.mode)
+		}
+	}
+
+	upper, _ := qr.AddressPayload(bech32Addr)
+	if qr.DataBits(upper, qr.ModeAlphanumeric) >= qr.DataBits(bech32Addr, qr.ModeByte) {
+		t.Errorf("DataBits: uppercased address is not smaller")
+	}
+}
+
+// TestURIPayload ensures the scheme and bech32 address of payment URIs are
+// uppercased and their parameters left unchanged.
+func TestURIPayload(t *testing.T) {
+	tests := []struct {
+		uri     string
+		payload string
+		mode    qr.Mode
+	}{
+		{"omega:" + bech32Addr, "OMEGA:BC1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KV8F3T4", qr.ModeAlphanumeric},
+		{"omega:" + bech32Addr + "?amount=1.5&label=Luke", "OMEGA:BC1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KV8F3T4?amount=1.5&label=Luke", qr.ModeByte},
+		{"omega:" + base58Addr + "?amount=1", "omega:" + base58Addr + "?amount=1", qr.ModeByte},
+		{"omega:?amount=1", "omega:?amount=1", qr.ModeByte},
+		{"1234", "1234", qr.ModeNumeric},
+	}
+	for _, test := range tests {
+		payload, mode := qr.URIPayload(test.uri)
+		if payload != test.payload || mode != test.mode {
+			t.Errorf("URIPayload(%s): got %s, %v, want %s, %v",
+				test.uri, payload, mode, test.payload, test.mode)
+		}
+	}
+}
+
+// TestDataBits ensures the sizes of encoded data match the QR specification.
+func TestDataBits(t *testing.T) {
+	tests := []struct {
+		s    string
+		mode qr.Mode
+		bits int
+	}{
+		{"01234567", qr.ModeNumeric, 27},
+		{"AC-42", qr.ModeAlphanumeric, 28},
+		{"AC-42", qr.ModeByte, 40},
+		{"AC-42", qr.ModeNumeric, -1},
+		{"ac-42", qr.ModeAlphanumeric, -1},
+		{"", qr.ModeByte, 0},
+	}
+	for _, test := range tests {
+		if bits := qr.DataBits(test.s, test.mode); bits != test.bits {
+			t.Errorf("DataBits(%q, %v): got %d, want %d", test.s,
+				test.mode, bits, test.bits)
+		}
+	}
+	if s := qr.Mode(7).String(); s != "Unknown Mode" {
+		t.Errorf("String: got %s", s)
+	}
+}
@@ -0,0 +1,417 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package recurring tracks recurring payments, such as subscriptions paid in
+// OMC or any other numeric token.
+//
+// A Subscription defines payments of a fixed amount to an address at regular
+// calendar intervals.  A Scheduler generates the payments due under each of
+// its subscriptions, matches them against the transactions it observes, and
+// flags the payments which were not made in time.  The Scheduler implements
+// the BlockConnected and BlockDisconnected methods of regtest.Listener, so it
+// can follow the blocks of a chain directly, using the block timestamps as
+// the times payments were made.  Without a watch-only tracker in btcutil to
+// subscribe to, the Scheduler scans the transactions of those blocks itself.
+package recurring
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcd/txscript"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/omega/token"
+)
+
+var (
+	// ErrInvalidSubscription describes an error where a subscription has
+	// no ID or address, a non-positive amount, a hash token type, or an
+	// empty interval.
+	ErrInvalidSubscription = errors.New("invalid subscription")
+
+	// ErrDuplicateSubscription describes an error where a subscription is
+	// added with the ID of one the scheduler already has.
+	ErrDuplicateSubscription = errors.New("duplicate subscription ID")
+
+	// ErrUnknownSubscription describes an error where a subscription the
+	// scheduler does not have is requested.
+	ErrUnknownSubscription = errors.New("unknown subscription")
+)
+
+// Interval is the calendar interval between the payments of a subscription.
+// Intervals are added to dates as by time.Time.AddDate, so a monthly payment
+// starting on the 15th stays on the 15th.
+type Interval struct {
+	Years  int
+	Months int
+	Days   int
+}
+
+// Common intervals.
+var (
+	Weekly  = Interval{Days: 7}
+	Monthly = Interval{Months: 1}
+	Yearly  = Interval{Years: 1}
+)
+
+// Subscription defines recurring payments of Amount of a numeric token to
+// Address, the first due at Start and the others every Interval after it.
+type Subscription struct {
+	ID        string
+	Address   btcutil.Address
+	TokenType uint64
+	Amount    btcutil.Amount
+	Start     time.Time
+	Interval  Interval
+
+	// Count is the number of payments, or zero for a subscription which
+	// runs until it is removed.
+	Count int
+
+	// Tolerance is how far from its due date a payment may be made.
+	// Payments made earlier are not counted, and a payment not made by
+	// its due date plus Tolerance is missed.
+	Tolerance time.Duration
+}
+
+// dueDate returns the due date of the payment with the passed sequence
+// number, counting from zero.  Dates are computed from the start, so short
+// months do not shift later payments.
+func (s *Subscription) dueDate(seq int) time.Time {
+	return s.Start.AddDate(seq*s.Interval.Years, seq*s.Interval.Months,
+		seq*s.Interval.Days)
+}
+
+// Status is the status of a payment.
+type Status uint8
+
+const (
+	// StatusUpcoming is the status of a payment whose due date, less the
+	// tolerance, has not been reached.
+	StatusUpcoming Status = iota
+
+	// StatusDue is the status of an unpaid payment which can still be
+	// made in time.
+	StatusDue
+
+	// StatusPaid is the status of a payment made in time.
+	StatusPaid
+
+	// StatusPaidLate is the status of a payment made after it was missed.
+	StatusPaidLate
+
+	// StatusMissed is the status of an unpaid payment which was not made
+	// in time.
+	StatusMissed
+)
+
+// statusStrings is a map of payment statuses back to their constant names
+// for pretty printing.
+var statusStrings = map[Status]string{
+	StatusUpcoming: "StatusUpcoming",
+	StatusDue:      "StatusDue",
+	StatusPaid:     "StatusPaid",
+	StatusPaidLate: "StatusPaidLate",
+	StatusMissed:   "StatusMissed",
+}
+
+// String returns the Status as a human-readable name.
+func (s Status) String() string {
+	if str, ok := statusStrings[s]; ok {
+		return str
+	}
+	return "Unknown Status"
+}
+
+// Payment is a payment due under a subscription.
+type Payment struct {
+	Subscription string
+	Seq          int
+	Address      btcutil.Address
+	TokenType    uint64
+	Amount       btcutil.Amount
+	DueDate      time.Time
+	Status       Status
+
+	// TxHash and PaidAt are the transaction which made the payment and
+	// the time it was observed, when the payment was made.
+	TxHash chainhash.Hash
+	PaidAt time.Time
+}
+
+// fulfillment records the transaction making a payment.
+type fulfillment struct {
+	txHash chainhash.Hash
+	at     time.Time
+}
+
+// schedule is a subscription along with the payments made under it.
+type schedule struct {
+	sub      Subscription
+	pkScript []byte
+	paid     map[int]fulfillment
+}
+
+// payment returns the payment with the passed sequence number and its status
+// at now.
+func (s *schedule) payment(seq int, now time.Time) Payment {
+	p := Payment{
+		Subscription: s.sub.ID,
+		Seq:          seq,
+		Address:      s.sub.Address,
+		TokenType:    s.sub.TokenType,
+		Amount:       s.sub.Amount,
+		DueDate:      s.sub.dueDate(seq),
+	}
+	deadline := p.DueDate.Add(s.sub.Tolerance)
+	if f, ok := s.paid[seq]; ok {
+		p.TxHash, p.PaidAt = f.txHash, f.at
+		p.Status = StatusPaid
+		if f.at.After(deadline) {
+			p.Status = StatusPaidLate
+		}
+		return p
+	}
+	switch {
+	case now.After(deadline):
+		p.Status = StatusMissed
+	case now.Before(p.DueDate.Add(-s.sub.Tolerance)):
+		p.Status = StatusUpcoming
+	default:
+		p.Status = StatusDue
+	}
+	return p
+}
+
+// nextUnpaid returns the sequence number of the earliest unpaid payment.
+func (s *schedule) nextUnpaid() int {
+	seq := 0
+	for {
+		if _, ok := s.paid[seq]; !ok {
+			return seq
+		}
+		seq++
+	}
+}
+
+// ended returns whether the subscription has no payment with the passed
+// sequence number.
+func (s *schedule) ended(seq int) bool {
+	return s.sub.Count > 0 && seq >= s.sub.Count
+}
+
+// Scheduler generates the payments due under its subscriptions and tracks
+// which of them were made.  It is safe for concurrent use.
+type Scheduler struct {
+	mtx       sync.Mutex
+	schedules map[string]*schedule
+	txs       map[chainhash.Hash][]paidRef
+}
+
+// paidRef identifies a payment made by a transaction.
+type paidRef struct {
+	id  string
+	seq int
+}
+
+// NewScheduler returns a scheduler without subscriptions.
+func NewScheduler() *Scheduler {
+	return &Scheduler{
+		schedules: make(map[string]*schedule),
+		txs:       make(map[chainhash.Hash][]paidRef),
+	}
+}
+
+// Add adds a subscription to the scheduler.
+func (s *Scheduler) Add(sub *Subscription) error {
+	if sub.ID == "" || sub.Address == nil || sub.Amount <= 0 ||
+		sub.TokenType&1 != 0 || sub.Count < 0 || sub.Tolerance < 0 ||
+		sub.Interval == (Interval{}) {
+		return ErrInvalidSubscription
+	}
+	pkScript, err := txscript.PayToAddrScript(sub.Address)
+	if err != nil {
+		return err
+	}
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if _, ok := s.schedules[sub.ID]; ok {
+		return ErrDuplicateSubscription
+	}
+	s.schedules[sub.ID] = &schedule{
+		sub:      *sub,
+		pkScript: pkScript,
+		paid:     make(map[int]fulfillment),
+	}
+	return nil
+}
+
+// Remove removes the subscription with the passed ID, along with the record
+// of its payments.
+func (s *Scheduler) Remove(id string) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if _, ok := s.schedules[id]; !ok {
+		return ErrUnknownSubscription
+	}
+	delete(s.schedules, id)
+	for txHash, refs := range s.txs {
+		kept := refs[:0]
+		for _, ref := range refs {
+			if ref.id != id {
+				kept = append(kept, ref)
+			}
+		}
+		if len(kept) == 0 {
+			delete(s.txs, txHash)
+		} else {
+			s.txs[txHash] = kept
+		}
+	}
+	return nil
+}
+
+// Payments returns the payments of the subscription with the passed ID
+// whose due dates, less the tolerance, are not after now, with their status
+// at now.
+func (s *Scheduler) Payments(id string, now time.Time) ([]Payment, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	sched, ok := s.schedules[id]
+	if !ok {
+		return nil, ErrUnknownSubscription
+	}
+	var payments []Payment
+	for seq := 0; !sched.ended(seq); seq++ {
+		p := sched.payment(seq, now)
+		if p.Status == StatusUpcoming {
+			break
+		}
+		payments = append(payments, p)
+	}
+	return payments, nil
+}
+
+// filter returns the payments of all subscriptions with the passed status at
+// now, ordered by due date.
+func (s *Scheduler) filter(status Status, now time.Time) []Payment {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	var payments []Payment
+	for _, sched := range s.schedules {
+		for seq := 0; !sched.ended(seq); seq++ {
+			p := sched.payment(seq, now)
+			if p.Status == StatusUpcoming {
+				break
+			}
+			if p.Status == status {
+				payments = append(payments, p)
+			}
+		}
+	}
+	sort.Slice(payments, func(i, j int) bool {
+		if !payments[i].DueDate.Equal(payments[j].DueDate) {
+			return payments[i].DueDate.Before(payments[j].DueDate)
+		}
+		return payments[i].Subscription < payments[j].Subscription
+	})
+	return payments
+}
+
+// Due returns the unpaid payments of all subscriptions which can still be
+// made in time at now, ordered by due date.
+func (s *Scheduler) Due(now time.Time) []Payment {
+	return s.filter(StatusDue, now)
+}
+
+// Missed returns the unpaid payments of all subscriptions which were not made
+// in time at now, ordered by due date.
+func (s *Scheduler) Missed(now time.Time) []Payment {
+	return s.filter(StatusMissed, now)
+}
+
+// ObserveTx records the payments made by tx, observed at the passed time.
+// Each output paying at least the amount of a subscription to its address
+// makes the earliest unpaid payment of the subscription, provided it is not
+// made earlier than its tolerance allows.  Transactions already observed are
+// ignored.
+func (s *Scheduler) ObserveTx(tx *wire.MsgTx, at time.Time) {
+	txHash := tx.TxHash()
+
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if _, ok := s.txs[txHash]; ok {
+		return
+	}
+	// Subscriptions are tried in order of their IDs, so the payment an
+	// output makes does not depend on map order when several share an
+	// address.
+	ids := make([]string, 0, len(s.schedules))
+	for id := range s.schedules {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	var refs []paidRef
+	for _, txOut := range tx.TxOut {
+		value, ok := txOut.Token.Value.(*token.NumeralVal)
+		if !ok {
+			continue
+		}
+		for _, id := range ids {
+			sched := s.schedules[id]
+			if sched.sub.TokenType != txOut.Token.TokenType ||
+				btcutil.Amount(value.Val) < sched.sub.Amount ||
+				string(sched.pkScript) != string(txOut.PkScript) {
+				continue
+			}
+			seq := sched.nextUnpaid()
+			if sched.ended(seq) || at.Before(sched.sub.dueDate(seq).
+				Add(-sched.sub.Tolerance)) {
+				continue
+			}
+			sched.paid[seq] = fulfillment{txHash: txHash, at: at}
+			refs = append(refs, paidRef{id: id, seq: seq})
+
+			// An output pays a single subscription.
+			break
+		}
+	}
+	if len(refs) != 0 {
+		s.txs[txHash] = refs
+	}
+}
+
+// RemoveTx forgets the payments made by the transaction with the passed hash,
+// such as when it is removed from the chain by a reorg.
+func (s *Scheduler) RemoveTx(txHash *chainhash.Hash) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	for _, ref := range s.txs[*txHash] {
+		if sched, ok := s.schedules[ref.id]; ok {
+			delete(sched.paid, ref.seq)
+		}
+	}
+	delete(s.txs, *txHash)
+}
+
+// BlockConnected observes the transactions of block at its timestamp.
+func (s *Scheduler) BlockConnected(block *btcutil.Block) {
+	at := block.MsgBlock().Header.Timestamp
+	for _, tx := range block.Transactions() {
+		s.ObserveTx(tx.MsgTx(), at)
+	}
+}
+
+// BlockDisconnected forgets the payments made by the transactions of block.
+func (s *Scheduler) BlockDisconnected(block *btcutil.Block) {
+	for _, tx := range block.Transactions() {
+		s.RemoveTx(tx.Hash())
+	}
+}
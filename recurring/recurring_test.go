@@ -0,0 +1,270 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package recurring_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcd/txscript"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/recurring"
+	"github.com/zeusyf/omega/token"
+)
+
+// testAddress returns a pay-to-pubkey-hash address of a hash filled with b.
+func testAddress(t *testing.T, b byte) btcutil.Address {
+	hash := make([]byte, 20)
+	for i := range hash {
+		hash[i] = b
+	}
+	addr, err := btcutil.NewAddressPubKeyHash(hash, &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("NewAddressPubKeyHash: unexpected error: %v", err)
+	}
+	return addr
+}
+
+// payTx returns a transaction paying value of the passed token type to addr.
+func payTx(t *testing.T, addr btcutil.Address, tokenType uint64, value int64) *wire.MsgTx {
+	pkScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		t.Fatalf("PayToAddrScript: unexpected error: %v", err)
+	}
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: wire.OutPoint{Index: uint32(value)},
+		SignatureIndex:   0xffffffff,
+	})
+	tx.AddTxOut(&wire.TxOut{
+		Token: token.Token{
+			TokenType: tokenType,
+			Value:     &token.NumeralVal{Val: value},
+		},
+		PkScript: pkScript,
+	})
+	return tx
+}
+
+// date returns midnight UTC of the passed day of 2021.
+func date(month time.Month, day int) time.Time {
+	return time.Date(2021, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// statuses returns the statuses of payments.
+func statuses(payments []recurring.Payment) []recurring.Status {
+	s := make([]recurring.Status, len(payments))
+	for i, p := range payments {
+		s[i] = p.Status
+	}
+	return s
+}
+
+// equalStatuses returns whether the statuses of payments are want.
+func equalStatuses(payments []recurring.Payment, want ...recurring.Status) bool {
+	got := statuses(payments)
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// TestAdd ensures invalid and duplicate subscriptions are rejected.
+func TestAdd(t *testing.T) {
+	valid := recurring.Subscription{
+		ID:       "rent",
+		Address:  testAddress(t, 1),
+		Amount:   1000,
+		Start:    date(time.January, 31),
+		Interval: recurring.Monthly,
+	}
+	tests := []struct {
+		name   string
+		modify func(s *recurring.Subscription)
+	}{
+		{"no ID", func(s *recurring.Subscription) { s.ID = "" }},
+		{"no address", func(s *recurring.Subscription) { s.Address = nil }},
+		{"zero amount", func(s *recurring.Subscription) { s.Amount = 0 }},
+		{"hash token", func(s *recurring.Subscription) { s.TokenType = 1 }},
+		{"negative count", func(s *recurring.Subscription) { s.Count = -1 }},
+		{"negative tolerance", func(s *recurring.Subscription) { s.Tolerance = -1 }},
+		{"no interval", func(s *recurring.Subscription) { s.Interval = recurring.Interval{} }},
+	}
+	s := recurring.NewScheduler()
+	for _, test := range tests {
+		sub := valid
+		test.modify(&sub)
+		if err := s.Add(&sub); err != recurring.ErrInvalidSubscription {
+			t.Errorf("Add(%s): got error %v, want %v", test.name, err,
+				recurring.ErrInvalidSubscription)
+		}
+	}
+
+	if err := s.Add(&valid); err != nil {
+		t.Fatalf("Add: unexpected error: %v", err)
+	}
+	if err := s.Add(&valid); err != recurring.ErrDuplicateSubscription {
+		t.Errorf("Add: got error %v, want %v", err,
+			recurring.ErrDuplicateSubscription)
+	}
+	if err := s.Remove("rent"); err != nil {
+		t.Errorf("Remove: unexpected error: %v", err)
+	}
+	if err := s.Remove("rent"); err != recurring.ErrUnknownSubscription {
+		t.Errorf("Remove: got error %v, want %v", err,
+			recurring.ErrUnknownSubscription)
+	}
+	if _, err := s.Payments("rent", date(time.March, 1)); err != recurring.ErrUnknownSubscription {
+		t.Errorf("Payments: got error %v, want %v", err,
+			recurring.ErrUnknownSubscription)
+	}
+}
+
+// TestSchedule ensures payments fall due at calendar intervals, are matched
+// against the transactions observed, and are flagged once missed.
+func TestSchedule(t *testing.T) {
+	addr := testAddress(t, 1)
+	s := recurring.NewScheduler()
+	err := s.Add(&recurring.Subscription{
+		ID:        "rent",
+		Address:   addr,
+		Amount:    1000,
+		Start:     date(time.January, 31),
+		Interval:  recurring.Monthly,
+		Count:     3,
+		Tolerance: 48 * time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("Add: unexpected error: %v", err)
+	}
+
+	// The first payment is due from two days before its due date.
+	payments, _ := s.Payments("rent", date(time.January, 28))
+	if len(payments) != 0 {
+		t.Errorf("Payments: got %d payments before the first is due",
+			len(payments))
+	}
+	due := s.Due(date(time.January, 29))
+	if len(due) != 1 || due[0].Seq != 0 || !due[0].DueDate.Equal(date(time.January, 31)) ||
+		due[0].Amount != 1000 || due[0].Address != addr {
+		t.Fatalf("Due: got %+v", due)
+	}
+
+	// Payments which are too early, too small, of another token or to
+	// another address do not count.
+	s.ObserveTx(payTx(t, addr, 0, 1000), date(time.January, 1))
+	s.ObserveTx(payTx(t, addr, 0, 999), date(time.January, 30))
+	s.ObserveTx(payTx(t, addr, 4, 1000), date(time.January, 30))
+	s.ObserveTx(payTx(t, testAddress(t, 2), 0, 1000), date(time.January, 30))
+	if due := s.Due(date(time.January, 30)); len(due) != 1 {
+		t.Fatalf("Due: got %d payments after unrelated transactions",
+			len(due))
+	}
+	paid := payTx(t, addr, 0, 1500)
+	s.ObserveTx(paid, date(time.January, 30))
+	s.ObserveTx(paid, date(time.January, 30))
+
+	// The second payment falls due on March 3rd, Jan 31 plus a month,
+	// and is missed after March 5th.
+	payments, _ = s.Payments("rent", date(time.March, 10))
+	if !equalStatuses(payments, recurring.StatusPaid, recurring.StatusMissed) ||
+		payments[0].TxHash != paid.TxHash() ||
+		!payments[1].DueDate.Equal(date(time.March, 3)) {
+		t.Fatalf("Payments: got %+v", payments)
+	}
+	missed := s.Missed(date(time.March, 10))
+	if len(missed) != 1 || missed[0].Seq != 1 {
+		t.Errorf("Missed: got %+v", missed)
+	}
+
+	// A late payment makes the missed payment, and the third falls due
+	// on March 31st, not the 3rd of April.
+	late := payTx(t, addr, 0, 1000)
+	s.ObserveTx(late, date(time.March, 11))
+	payments, _ = s.Payments("rent", date(time.March, 30))
+	if !equalStatuses(payments, recurring.StatusPaid, recurring.StatusPaidLate,
+		recurring.StatusDue) || !payments[2].DueDate.Equal(date(time.March, 31)) {
+		t.Fatalf("Payments: got %+v", payments)
+	}
+	if missed := s.Missed(date(time.March, 30)); len(missed) != 0 {
+		t.Errorf("Missed: got %+v after late payment", missed)
+	}
+
+	// Once the late payment is forgotten, the next transaction makes the
+	// second payment instead, and the subscription ends after its third.
+	lateHash := late.TxHash()
+	s.RemoveTx(&lateHash)
+	s.ObserveTx(payTx(t, addr, 0, 1001), date(time.March, 31))
+	s.ObserveTx(payTx(t, addr, 0, 1002), date(time.March, 31))
+	s.ObserveTx(payTx(t, addr, 0, 1003), date(time.December, 31))
+	payments, _ = s.Payments("rent", date(time.December, 31))
+	if !equalStatuses(payments, recurring.StatusPaid, recurring.StatusPaidLate,
+		recurring.StatusPaid) {
+		t.Errorf("Payments: got %v", statuses(payments))
+	}
+}
+
+// TestBlocks ensures the scheduler follows connected and disconnected blocks.
+func TestBlocks(t *testing.T) {
+	addr := testAddress(t, 3)
+	s := recurring.NewScheduler()
+	err := s.Add(&recurring.Subscription{
+		ID:        "gym",
+		Address:   addr,
+		TokenType: 4,
+		Amount:    5,
+		Start:     date(time.June, 1),
+		Interval:  recurring.Weekly,
+		Tolerance: 24 * time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("Add: unexpected error: %v", err)
+	}
+
+	block := btcutil.NewBlock(&wire.MsgBlock{
+		Header:       wire.BlockHeader{Timestamp: date(time.June, 8)},
+		Transactions: []*wire.MsgTx{payTx(t, addr, 4, 5), payTx(t, addr, 4, 6)},
+	})
+	s.BlockConnected(block)
+	payments, _ := s.Payments("gym", date(time.June, 17))
+	if !equalStatuses(payments, recurring.StatusPaidLate, recurring.StatusPaid,
+		recurring.StatusMissed) {
+		t.Errorf("Payments: got %v after block", statuses(payments))
+	}
+
+	s.BlockDisconnected(block)
+	payments, _ = s.Payments("gym", date(time.June, 17))
+	if !equalStatuses(payments, recurring.StatusMissed, recurring.StatusMissed,
+		recurring.StatusMissed) {
+		t.Errorf("Payments: got %v after reorg", statuses(payments))
+	}
+}
+
+// TestStatusStringer tests the stringized output for the Status type.
+func TestStatusStringer(t *testing.T) {
+	tests := []struct {
+		in   recurring.Status
+		want string
+	}{
+		{recurring.StatusUpcoming, "StatusUpcoming"},
+		{recurring.StatusDue, "StatusDue"},
+		{recurring.StatusPaid, "StatusPaid"},
+		{recurring.StatusPaidLate, "StatusPaidLate"},
+		{recurring.StatusMissed, "StatusMissed"},
+		{0xff, "Unknown Status"},
+	}
+	for i, test := range tests {
+		if got := test.in.String(); got != test.want {
+			t.Errorf("String #%d: got %s, want %s", i, got, test.want)
+		}
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package btcutil
+
+import "log/slog"
+
+// RedactKeep is the number of characters Redact keeps at each end of a
+// string.
+const RedactKeep = 4
+
+// redactEllipsis replaces the characters removed by Redact.
+const redactEllipsis = "..."
+
+// Redact returns s with all but its first and last RedactKeep characters
+// replaced by an ellipsis, which is enough to tell apart the addresses and
+// keys of a log without writing them in full.  Strings too short to keep both
+// ends and hide anything are replaced by the ellipsis altogether.
+func Redact(s string) string {
+	r := []rune(s)
+	if len(r) <= 2*RedactKeep {
+		return redactEllipsis
+	}
+	return string(r[:RedactKeep]) + redactEllipsis +
+		string(r[len(r)-RedactKeep:])
+}
+
+// RedactAddress returns the encoding of addr as by EncodeAddress, redacted.
+// Every address type of this package logs itself this way with package slog,
+// while String keeps returning the full encoding.
+func RedactAddress(addr Address) string {
+	return Redact(addr.EncodeAddress())
+}
+
+// LogValue implements the slog.LogValuer interface, logging the address
+// redacted as by RedactAddress.
+func (a *AddressContract) LogValue() slog.Value {
+	return slog.StringValue(RedactAddress(a))
+}
+
+// LogValue implements the slog.LogValuer interface, logging the address
+// redacted as by RedactAddress.
+func (a *AddressPubKeyHash) LogValue() slog.Value {
+	return slog.StringValue(RedactAddress(a))
+}
+
+// LogValue implements the slog.LogValuer interface, logging the address
+// redacted as by RedactAddress.
+func (a *AddressScriptHash) LogValue() slog.Value {
+	return slog.StringValue(RedactAddress(a))
+}
+
+// LogValue implements the slog.LogValuer interface, logging the address
+// redacted as by RedactAddress.
+func (a *AddressPubKey) LogValue() slog.Value {
+	return slog.StringValue(RedactAddress(a))
+}
+
+// LogValue implements the slog.LogValuer interface, logging the address
+// redacted as by RedactAddress.
+func (a *AddressMultiSig) LogValue() slog.Value {
+	return slog.StringValue(RedactAddress(a))
+}
+
+// Redacted returns the Wallet Import Format string of the key redacted as by
+// Redact, for logs and error messages which must not hold the key itself.
+// Zeroed WIF structures are logged as "zeroed WIF".
+func (w *WIF) Redacted() string {
+	if w.PrivKey == nil {
+		return "zeroed WIF"
+	}
+	return Redact(w.String())
+}
+
+// LogValue implements the slog.LogValuer interface, logging the key redacted
+// as by Redacted.
+func (w *WIF) LogValue() slog.Value {
+	return slog.StringValue(w.Redacted())
+}
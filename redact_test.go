@@ -0,0 +1,74 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package btcutil_test
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil"
+)
+
+// TestRedact ensures only the ends of long strings are kept.
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"", "..."},
+		{"abcdefgh", "..."},
+		{"abcdefghi", "abcd...fghi"},
+		{"ωωωωxωωωω", "ωωωω...ωωωω"},
+	}
+	for _, test := range tests {
+		if got := btcutil.Redact(test.in); got != test.want {
+			t.Errorf("Redact(%q): got %q, want %q", test.in, got,
+				test.want)
+		}
+	}
+}
+
+// TestRedactLog ensures addresses and WIFs are logged redacted with package
+// slog, also when logged through the Address interface.
+func TestRedactLog(t *testing.T) {
+	var addr btcutil.Address
+	addr, err := btcutil.NewAddressPubKeyHash(make([]byte, 20),
+		&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("NewAddressPubKeyHash: unexpected error: %v", err)
+	}
+	wif, err := btcutil.DecodeWIF(
+		"5HueCGU8rMjxEXxiPuD5BDku4MkFqeZyd4dZ1jvhTVqvbTLvyTJ")
+	if err != nil {
+		t.Fatalf("DecodeWIF: unexpected error: %v", err)
+	}
+	if got, want := wif.Redacted(), "5Hue...vyTJ"; got != want {
+		t.Errorf("Redacted: got %q, want %q", got, want)
+	}
+
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	logger.Info("payment", "addr", addr, "key", wif)
+	out := buf.String()
+	for _, secret := range []string{addr.EncodeAddress(), wif.String()} {
+		if strings.Contains(out, secret) {
+			t.Errorf("log %q holds %q in full", out, secret)
+		}
+	}
+	for _, redacted := range []string{btcutil.RedactAddress(addr),
+		wif.Redacted()} {
+
+		if !strings.Contains(out, redacted) {
+			t.Errorf("log %q lacks %q", out, redacted)
+		}
+	}
+
+	wif.Zero()
+	if got := wif.Redacted(); got != "zeroed WIF" {
+		t.Errorf("Redacted: got %q for a zeroed WIF", got)
+	}
+}
@@ -0,0 +1,151 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package refund derives refund addresses bound to the payments they refund.
+//
+// The refund key of a payment is the public key of the payer tweaked by a
+// commitment to the outpoint the payment created:
+//
+//	commitment = SHA256(SHA256(tag) || SHA256(tag) || serP(payerKey) || outpoint)
+//	refundKey  = payerKey + commitment*G
+//
+// where tag is "OmegaRefund" and the outpoint is the hash of the paying
+// transaction followed by the little-endian output index.  Only the payer,
+// holding the private key of payerKey, can spend from the refund key, and
+// anybody knowing the payer key and the outpoint can recompute it.  A
+// merchant refunding a payment to the pay-to-pubkey-hash address of the
+// refund key can therefore prove to a third party that the refund went to a
+// destination the payer controls, and which was fixed by the payment itself
+// rather than chosen by the merchant.
+package refund
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"math/big"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcd/txscript"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/omega/token"
+)
+
+// Tag is the tag of the tagged hash committing to a payment.
+const Tag = "OmegaRefund"
+
+var (
+	// ErrInvalidTweak describes an error where the commitment to a
+	// payment is not a valid private key, or tweaks the payer key to the
+	// point at infinity.  Either happens with negligible probability.
+	ErrInvalidTweak = errors.New("refund commitment is not a valid tweak")
+
+	// ErrNoRefundOutput describes an error where a transaction offered as
+	// a refund has no output paying the refund address.
+	ErrNoRefundOutput = errors.New("transaction does not pay the refund " +
+		"address")
+)
+
+// Commitment returns the commitment of the payer key to the outpoint of a
+// payment.
+func Commitment(payerKey *btcec.PublicKey, op *wire.OutPoint) [sha256.Size]byte {
+	tag := sha256.Sum256([]byte(Tag))
+	h := sha256.New()
+	h.Write(tag[:])
+	h.Write(tag[:])
+	h.Write(payerKey.SerializeCompressed())
+	h.Write(op.Hash[:])
+	var index [4]byte
+	binary.LittleEndian.PutUint32(index[:], op.Index)
+	h.Write(index[:])
+
+	var commitment [sha256.Size]byte
+	copy(commitment[:], h.Sum(nil))
+	return commitment
+}
+
+// tweak returns the commitment to the payment as a scalar.
+func tweak(payerKey *btcec.PublicKey, op *wire.OutPoint) ([]byte, *big.Int, error) {
+	commitment := Commitment(payerKey, op)
+	t := new(big.Int).SetBytes(commitment[:])
+	if t.Sign() == 0 || t.Cmp(btcec.S256().N) >= 0 {
+		return nil, nil, ErrInvalidTweak
+	}
+	return commitment[:], t, nil
+}
+
+// PubKey returns the refund key of the payment with the passed outpoint by
+// the payer with the passed key.
+func PubKey(payerKey *btcec.PublicKey, op *wire.OutPoint) (*btcec.PublicKey, error) {
+	commitment, _, err := tweak(payerKey, op)
+	if err != nil {
+		return nil, err
+	}
+	curve := btcec.S256()
+	cx, cy := curve.ScalarBaseMult(commitment)
+	x, y := curve.Add(cx, cy, payerKey.X, payerKey.Y)
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return nil, ErrInvalidTweak
+	}
+	return &btcec.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+// PrivKey returns the private key of the refund key of the payment with the
+// passed outpoint, for the payer holding payerKey to spend the refund.
+func PrivKey(payerKey *btcec.PrivateKey, op *wire.OutPoint) (*btcec.PrivateKey, error) {
+	_, t, err := tweak(payerKey.PubKey(), op)
+	if err != nil {
+		return nil, err
+	}
+	curve := btcec.S256()
+	d := t.Add(t, payerKey.D)
+	d.Mod(d, curve.N)
+	if d.Sign() == 0 {
+		return nil, ErrInvalidTweak
+	}
+
+	var b [32]byte
+	d.FillBytes(b[:])
+	privKey, _ := btcec.PrivKeyFromBytes(curve, b[:])
+	return privKey, nil
+}
+
+// Address returns the pay-to-pubkey-hash refund address of the payment with
+// the passed outpoint by the payer with the passed key.
+func Address(payerKey *btcec.PublicKey, op *wire.OutPoint, net *chaincfg.Params) (*btcutil.AddressPubKeyHash, error) {
+	refundKey, err := PubKey(payerKey, op)
+	if err != nil {
+		return nil, err
+	}
+	return btcutil.NewAddressPubKeyHash(
+		btcutil.Hash160(refundKey.SerializeCompressed()), net)
+}
+
+// CheckRefund verifies that tx refunds the payment with the passed outpoint
+// by the payer with the passed key, returning the index and amount of the
+// first output paying the base token to the refund address.
+func CheckRefund(tx *wire.MsgTx, payerKey *btcec.PublicKey, op *wire.OutPoint,
+	net *chaincfg.Params) (int, btcutil.Amount, error) {
+
+	addr, err := Address(payerKey, op, net)
+	if err != nil {
+		return 0, 0, err
+	}
+	pkScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		return 0, 0, err
+	}
+	for i, txOut := range tx.TxOut {
+		value, ok := txOut.Token.Value.(*token.NumeralVal)
+		if !ok || txOut.Token.TokenType != 0 ||
+			!bytes.Equal(txOut.PkScript, pkScript) {
+			continue
+		}
+		return i, btcutil.Amount(value.Val), nil
+	}
+	return 0, 0, ErrNoRefundOutput
+}
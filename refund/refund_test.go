@@ -0,0 +1,117 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package refund_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcd/txscript"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/refund"
+	"github.com/zeusyf/omega/token"
+)
+
+// TestRefundKey ensures the payer derives the private key of the refund key
+// the merchant derives from the payer key, and the key depends on the
+// payment.
+func TestRefundKey(t *testing.T) {
+	payerPriv, payerPub := btcec.PrivKeyFromBytes(btcec.S256(), []byte{0x2a})
+	op := wire.OutPoint{Hash: chainhash.Hash{0x01}, Index: 1}
+
+	refundPub, err := refund.PubKey(payerPub, &op)
+	if err != nil {
+		t.Fatalf("PubKey: unexpected error: %v", err)
+	}
+	refundPriv, err := refund.PrivKey(payerPriv, &op)
+	if err != nil {
+		t.Fatalf("PrivKey: unexpected error: %v", err)
+	}
+	if !bytes.Equal(refundPriv.PubKey().SerializeCompressed(),
+		refundPub.SerializeCompressed()) {
+		t.Errorf("PrivKey: key does not match the refund key")
+	}
+	if bytes.Equal(refundPub.SerializeCompressed(), payerPub.SerializeCompressed()) {
+		t.Errorf("PubKey: refund key equals the payer key")
+	}
+
+	others := []wire.OutPoint{
+		{Hash: chainhash.Hash{0x01}, Index: 0},
+		{Hash: chainhash.Hash{0x02}, Index: 1},
+	}
+	for _, other := range others {
+		otherPub, err := refund.PubKey(payerPub, &other)
+		if err != nil {
+			t.Fatalf("PubKey: unexpected error: %v", err)
+		}
+		if bytes.Equal(otherPub.SerializeCompressed(), refundPub.SerializeCompressed()) {
+			t.Errorf("PubKey: outpoint %v gives the refund key of %v",
+				other, op)
+		}
+	}
+
+	// The commitment is a tagged hash of the payer key and outpoint.
+	c1 := refund.Commitment(payerPub, &op)
+	c2 := refund.Commitment(payerPub, &op)
+	if c1 != c2 || c1 == refund.Commitment(payerPub, &others[0]) {
+		t.Errorf("Commitment: not deterministic or independent of outpoint")
+	}
+}
+
+// TestCheckRefund ensures refunds are recognized by their output paying the
+// refund address.
+func TestCheckRefund(t *testing.T) {
+	_, payerPub := btcec.PrivKeyFromBytes(btcec.S256(), []byte{0x2a})
+	op := wire.OutPoint{Hash: chainhash.Hash{0x01}, Index: 1}
+	net := &chaincfg.MainNetParams
+
+	addr, err := refund.Address(payerPub, &op, net)
+	if err != nil {
+		t.Fatalf("Address: unexpected error: %v", err)
+	}
+	refundPub, _ := refund.PubKey(payerPub, &op)
+	if !bytes.Equal(addr.ScriptAddress(), btcutil.Hash160(refundPub.SerializeCompressed())) {
+		t.Errorf("Address: got %x", addr.ScriptAddress())
+	}
+	pkScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		t.Fatalf("PayToAddrScript: unexpected error: %v", err)
+	}
+
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(&wire.TxIn{PreviousOutPoint: wire.OutPoint{Index: 7}})
+	tx.AddTxOut(&wire.TxOut{
+		Token:    token.Token{Value: &token.NumeralVal{Val: 100}},
+		PkScript: []byte{0x51},
+	})
+	tx.AddTxOut(&wire.TxOut{
+		Token:    token.Token{TokenType: 4, Value: &token.NumeralVal{Val: 200}},
+		PkScript: pkScript,
+	})
+	if _, _, err := refund.CheckRefund(tx, payerPub, &op, net); err != refund.ErrNoRefundOutput {
+		t.Errorf("CheckRefund: got error %v, want %v", err,
+			refund.ErrNoRefundOutput)
+	}
+
+	tx.AddTxOut(&wire.TxOut{
+		Token:    token.Token{Value: &token.NumeralVal{Val: 300}},
+		PkScript: pkScript,
+	})
+	index, amount, err := refund.CheckRefund(tx, payerPub, &op, net)
+	if err != nil || index != 2 || amount != 300 {
+		t.Errorf("CheckRefund: got output %d of %d and error %v", index,
+			amount, err)
+	}
+
+	other := wire.OutPoint{Hash: chainhash.Hash{0x01}, Index: 2}
+	if _, _, err := refund.CheckRefund(tx, payerPub, &other, net); err != refund.ErrNoRefundOutput {
+		t.Errorf("CheckRefund: got error %v for another payment, want %v",
+			err, refund.ErrNoRefundOutput)
+	}
+}
@@ -0,0 +1,274 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package regtest provides a scenario builder which programmatically creates
+// chains of blocks for the regression test network.  It is intended for
+// integration testing of software which consumes blocks, such as indexers and
+// watch-only wallets, without running a node.
+//
+// A Scenario mines blocks with valid proof of work for the configured network
+// parameters, funds addresses through coinbase outputs, and creates reorgs of
+// arbitrary depth.  Every connected and disconnected block is delivered, in
+// order, to the registered Listeners.
+//
+// Consumers receive the blocks by implementing Listener themselves, as the
+// refwallet example and the recurring package do.  There are no chain view
+// or watch-only tracker packages in btcutil to adapt the stream to.
+package regtest
+
+import (
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcd/txscript"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/internal/chaingen"
+	"github.com/zeusyf/omega/token"
+)
+
+// DefaultBlockInterval is the time between the timestamps of consecutive
+// blocks produced by a Scenario.
+const DefaultBlockInterval = chaingen.DefaultBlockInterval
+
+var (
+	// ErrReorgTooDeep describes an error where a reorg is requested that
+	// would disconnect the genesis block.
+	ErrReorgTooDeep = errors.New("reorg depth exceeds chain length")
+
+	// ErrNoSolution describes an error where no nonce satisfying the proof
+	// of work target could be found.
+	ErrNoSolution = chaingen.ErrNoSolution
+)
+
+// Listener is notified of every change to the best chain of a Scenario.
+// Blocks are disconnected from the tip backwards before the blocks of the new
+// branch are connected.
+type Listener interface {
+	// BlockConnected is invoked when a block extends the best chain.
+	BlockConnected(block *btcutil.Block)
+
+	// BlockDisconnected is invoked when the tip block is removed from the
+	// best chain during a reorg.
+	BlockDisconnected(block *btcutil.Block)
+}
+
+// payment is a pending coinbase output.
+type payment struct {
+	pkScript []byte
+	amount   btcutil.Amount
+}
+
+// Scenario builds a chain of blocks for the configured network.  It is not
+// safe for concurrent access.
+type Scenario struct {
+	params    *chaincfg.Params
+	target    *big.Int
+	interval  time.Duration
+	subsidy   btcutil.Amount
+	chain     []*btcutil.Block
+	pending   []payment
+	listeners []Listener
+}
+
+// New returns a scenario whose chain consists only of the genesis block of the
+// passed network parameters.  The regression test network parameters are used
+// when params is nil.
+func New(params *chaincfg.Params) *Scenario {
+	if params == nil {
+		params = &chaincfg.RegressionNetParams
+	}
+	return &Scenario{
+		params:   params,
+		target:   params.PowLimit,
+		interval: DefaultBlockInterval,
+		subsidy:  50 * btcutil.HaoPerBitcoin,
+		chain:    []*btcutil.Block{chaingen.Genesis(params)},
+	}
+}
+
+// SetBlockInterval sets the time between the timestamps of consecutive
+// blocks.
+func (s *Scenario) SetBlockInterval(d time.Duration) *Scenario {
+	s.interval = d
+	return s
+}
+
+// SetSubsidy sets the amount paid by the coinbase of each mined block to the
+// (otherwise unspendable) default coinbase output.
+func (s *Scenario) SetSubsidy(amount btcutil.Amount) *Scenario {
+	s.subsidy = amount
+	return s
+}
+
+// AddListener registers a listener which is notified of all subsequent chain
+// changes.
+func (s *Scenario) AddListener(l Listener) {
+	s.listeners = append(s.listeners, l)
+}
+
+// Params returns the network parameters of the scenario.
+func (s *Scenario) Params() *chaincfg.Params {
+	return s.params
+}
+
+// Height returns the height of the current tip.
+func (s *Scenario) Height() int32 {
+	return int32(len(s.chain) - 1)
+}
+
+// Tip returns the current tip of the best chain.
+func (s *Scenario) Tip() *btcutil.Block {
+	return s.chain[len(s.chain)-1]
+}
+
+// Chain returns the blocks of the best chain from genesis to tip.
+func (s *Scenario) Chain() []*btcutil.Block {
+	chain := make([]*btcutil.Block, len(s.chain))
+	copy(chain, s.chain)
+	return chain
+}
+
+// Fund queues a coinbase output paying amount to addr.  The output is
+// included in the next mined block.
+func (s *Scenario) Fund(addr btcutil.Address, amount btcutil.Amount) error {
+	pkScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		return err
+	}
+	s.pending = append(s.pending, payment{pkScript, amount})
+	return nil
+}
+
+// Mine mines n blocks on top of the current tip and returns them.  The first
+// block includes all outputs queued with Fund.
+func (s *Scenario) Mine(n int) ([]*btcutil.Block, error) {
+	blocks := make([]*btcutil.Block, 0, n)
+	for i := 0; i < n; i++ {
+		block, err := s.solve(s.Tip(), s.takePending(), nil)
+		if err != nil {
+			return blocks, err
+		}
+		s.connect(block)
+		blocks = append(blocks, block)
+	}
+	return blocks, nil
+}
+
+// MineWithTxs mines a single block containing the passed transactions after
+// its coinbase.
+func (s *Scenario) MineWithTxs(txns ...*wire.MsgTx) (*btcutil.Block, error) {
+	block, err := s.solve(s.Tip(), s.takePending(), txns)
+	if err != nil {
+		return nil, err
+	}
+	s.connect(block)
+	return block, nil
+}
+
+// Reorg disconnects the top depth blocks of the best chain and replaces them
+// with a competing branch of newLen blocks.  newLen must exceed depth for the
+// new branch to be the best chain.  Outputs queued with Fund are included in
+// the first block of the new branch.  The disconnected blocks are returned.
+func (s *Scenario) Reorg(depth, newLen int) ([]*btcutil.Block, error) {
+	if depth >= len(s.chain) {
+		return nil, ErrReorgTooDeep
+	}
+	if newLen <= depth {
+		return nil, errors.New("new branch must be longer than the " +
+			"disconnected one")
+	}
+
+	// Build the complete competing branch before touching the best chain
+	// so a failure leaves the scenario unmodified.  The timestamps of the
+	// new branch are offset by one second so its blocks can never collide
+	// with the ones being replaced.
+	fork := s.chain[len(s.chain)-1-depth]
+	branch := make([]*btcutil.Block, 0, newLen)
+	prev := fork
+	pending := s.takePending()
+	for i := 0; i < newLen; i++ {
+		block, err := s.solveAt(prev, pending, nil, time.Second)
+		if err != nil {
+			return nil, err
+		}
+		pending = nil
+		branch = append(branch, block)
+		prev = block
+	}
+
+	disconnected := make([]*btcutil.Block, 0, depth)
+	for i := 0; i < depth; i++ {
+		tip := s.chain[len(s.chain)-1]
+		s.chain = s.chain[:len(s.chain)-1]
+		for _, l := range s.listeners {
+			l.BlockDisconnected(tip)
+		}
+		disconnected = append(disconnected, tip)
+	}
+	for _, block := range branch {
+		s.connect(block)
+	}
+
+	return disconnected, nil
+}
+
+// takePending returns and clears the queued coinbase outputs.
+func (s *Scenario) takePending() []payment {
+	pending := s.pending
+	s.pending = nil
+	return pending
+}
+
+// connect appends the block to the best chain and notifies listeners.
+func (s *Scenario) connect(block *btcutil.Block) {
+	s.chain = append(s.chain, block)
+	for _, l := range s.listeners {
+		l.BlockConnected(block)
+	}
+}
+
+// coinbaseTx returns a coinbase transaction for a block at the given height
+// paying the subsidy plus the passed outputs.
+func (s *Scenario) coinbaseTx(height int32, pays []payment) *wire.MsgTx {
+	tx := chaingen.CoinbaseTx(height)
+	tx.AddTxOut(&wire.TxOut{
+		Token: token.Token{
+			TokenType: 0,
+			Value:     &token.NumeralVal{Val: int64(s.subsidy)},
+		},
+		PkScript: []byte{},
+	})
+	for _, p := range pays {
+		tx.AddTxOut(&wire.TxOut{
+			Token: token.Token{
+				TokenType: 0,
+				Value:     &token.NumeralVal{Val: int64(p.amount)},
+			},
+			PkScript: p.pkScript,
+		})
+	}
+	return tx
+}
+
+// solve creates a block on top of prev and finds a nonce which satisfies the
+// proof of work target.
+func (s *Scenario) solve(prev *btcutil.Block, pays []payment, txns []*wire.MsgTx) (*btcutil.Block, error) {
+	return s.solveAt(prev, pays, txns, 0)
+}
+
+// solveAt is like solve, but offsets the block timestamp by skew.
+func (s *Scenario) solveAt(prev *btcutil.Block, pays []payment,
+	txns []*wire.MsgTx, skew time.Duration) (*btcutil.Block, error) {
+
+	msgBlock := &wire.MsgBlock{}
+	msgBlock.AddTransaction(s.coinbaseTx(prev.Height()+1, pays))
+	for _, tx := range txns {
+		msgBlock.AddTransaction(tx)
+	}
+	timestamp := prev.MsgBlock().Header.Timestamp.Add(s.interval + skew)
+	return chaingen.Solve(prev, msgBlock, timestamp, s.target)
+}
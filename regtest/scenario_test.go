@@ -0,0 +1,89 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package regtest_test
+
+import (
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/regtest"
+)
+
+// recorder is a regtest.Listener which records the notifications it receives.
+type recorder struct {
+	events []string
+	tip    *btcutil.Block
+}
+
+func (r *recorder) BlockConnected(block *btcutil.Block) {
+	r.events = append(r.events, "+"+block.Hash().String())
+	r.tip = block
+}
+
+func (r *recorder) BlockDisconnected(block *btcutil.Block) {
+	r.events = append(r.events, "-"+block.Hash().String())
+}
+
+// TestScenario exercises mining, funding, and reorgs.
+func TestScenario(t *testing.T) {
+	s := regtest.New(nil)
+	var rec recorder
+	s.AddListener(&rec)
+
+	addr, err := btcutil.NewAddressPubKeyHash(make([]byte, 20),
+		&chaincfg.RegressionNetParams)
+	if err != nil {
+		t.Fatalf("NewAddressPubKeyHash: %v", err)
+	}
+	if err := s.Fund(addr, 12345); err != nil {
+		t.Fatalf("Fund: %v", err)
+	}
+
+	blocks, err := s.Mine(5)
+	if err != nil {
+		t.Fatalf("Mine: %v", err)
+	}
+	if s.Height() != 5 || len(rec.events) != 5 {
+		t.Fatalf("Mine: got height %d with %d events, want 5 and 5",
+			s.Height(), len(rec.events))
+	}
+	if n := len(blocks[0].MsgBlock().Transactions[0].TxOut); n != 2 {
+		t.Fatalf("Mine: funded coinbase has %d outputs, want 2", n)
+	}
+	if n := len(blocks[1].MsgBlock().Transactions[0].TxOut); n != 1 {
+		t.Fatalf("Mine: unfunded coinbase has %d outputs, want 1", n)
+	}
+	for i, block := range blocks {
+		if block.Height() != int32(i+1) {
+			t.Fatalf("Mine: block %d has height %d", i, block.Height())
+		}
+	}
+
+	orphaned, err := s.Reorg(2, 3)
+	if err != nil {
+		t.Fatalf("Reorg: %v", err)
+	}
+	if len(orphaned) != 2 || *orphaned[0].Hash() != *blocks[4].Hash() {
+		t.Fatalf("Reorg: unexpected disconnected blocks")
+	}
+	if s.Height() != 6 || rec.tip != s.Tip() {
+		t.Fatalf("Reorg: got height %d, want 6", s.Height())
+	}
+	if len(rec.events) != 10 {
+		t.Fatalf("Reorg: got %d events, want 10", len(rec.events))
+	}
+	chain := s.Chain()
+	for i := 1; i < len(chain); i++ {
+		prev := chain[i].MsgBlock().Header.PrevBlock
+		if prev != *chain[i-1].Hash() {
+			t.Fatalf("Chain: block %d does not connect", i)
+		}
+	}
+
+	if _, err := s.Reorg(7, 8); err != regtest.ErrReorgTooDeep {
+		t.Fatalf("Reorg: got %v, want %v", err, regtest.ErrReorgTooDeep)
+	}
+}
@@ -0,0 +1,250 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package rescan plans the rescan of a range of blocks for the outputs of a
+// watch-only wallet, as a light client matching block filters does.
+//
+// A wallet watches ranged descriptors, templates deriving a public key script
+// for each index of a range, such as the receiving and change branches of an
+// account, along with outpoints it already holds.  A Plan expands the
+// descriptors into the concrete scripts to match against the filters of the
+// blocks, adds the scripts of the outpoints, whose spends show up in the
+// filters as the scripts of spent outputs, and splits the height range into
+// chunks of filters to fetch and match at once.
+//
+// btcutil has no output descriptor package, so this package does not parse
+// descriptor strings.  Anything deriving scripts by index satisfies
+// Descriptor; KeyDescriptor covers the descriptors of a single extended
+// public key.
+package rescan
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil/discovery"
+	"github.com/zeusyf/btcutil/gcs"
+	"github.com/zeusyf/btcutil/hdkeychain"
+	"github.com/zeusyf/btcutil/scriptclass"
+)
+
+// DefaultChunkSize is the number of blocks of a chunk when no chunk size is
+// configured.  It is the largest number of filters a peer returns for a
+// single request of BIP0157.
+const DefaultChunkSize = 1000
+
+var (
+	// ErrInvalidRange describes an error where a plan is requested for a
+	// height range which is empty or starts below zero.
+	ErrInvalidRange = errors.New("invalid height range")
+
+	// ErrInvalidIndexRange describes an error where a descriptor is
+	// watched over an empty range of indexes.
+	ErrInvalidIndexRange = errors.New("invalid descriptor index range")
+
+	// ErrFilterCount describes an error where a chunk is matched against
+	// a number of filters other than its number of blocks.
+	ErrFilterCount = errors.New("filter count does not match chunk")
+)
+
+// Descriptor is a ranged output descriptor, deriving a public key script for
+// each index.
+type Descriptor interface {
+	// PkScripts returns the public key scripts of the count indexes
+	// starting at start.  Indexes which derive no script, such as
+	// invalid children of extended keys, may be skipped.
+	PkScripts(start uint32, count int) ([][]byte, error)
+}
+
+// KeyDescriptor is a Descriptor deriving the scripts of the non-hardened
+// children of an extended key, such as a branch of an account.
+type KeyDescriptor struct {
+	// Key is the extended key, which may be public, whose children are
+	// derived.
+	Key *hdkeychain.ExtendedKey
+
+	// Net is the network of the addresses of the children.
+	Net *chaincfg.Params
+
+	// Address returns the address of the public key of a child.  Nil
+	// selects discovery.PubKeyHashAddress.
+	Address discovery.AddressFunc
+}
+
+// PkScripts returns the scripts paying to the addresses of the count children
+// of the key starting at start.  Invalid children are skipped.  Part of the
+// Descriptor interface.
+func (d *KeyDescriptor) PkScripts(start uint32, count int) ([][]byte, error) {
+	keys, err := d.Key.DeriveRange(start, count)
+	if err != nil {
+		return nil, err
+	}
+	address := d.Address
+	if address == nil {
+		address = discovery.PubKeyHashAddress
+	}
+	scripts := make([][]byte, 0, len(keys))
+	for _, key := range keys {
+		addr, err := address(key.PubKey, d.Net)
+		if err != nil {
+			return nil, err
+		}
+		script, err := scriptclass.PayToAddrScript(addr)
+		if err != nil {
+			return nil, err
+		}
+		scripts = append(scripts, script)
+	}
+	return scripts, nil
+}
+
+// Ranged is a descriptor along with the range of indexes to watch.
+type Ranged struct {
+	// Descriptor is the descriptor.
+	Descriptor Descriptor
+
+	// Start is the first index watched.
+	Start uint32
+
+	// Count is the number of indexes watched.
+	Count int
+}
+
+// WatchedOutPoint is an outpoint held by the wallet along with the script of
+// its output, which the filter of the block spending it holds.
+type WatchedOutPoint struct {
+	OutPoint wire.OutPoint
+	PkScript []byte
+}
+
+// Chunk is a range of blocks whose filters are fetched and matched at once.
+type Chunk struct {
+	// StartHeight is the height of the first block of the chunk.
+	StartHeight int32
+
+	// EndHeight is the height of the last block of the chunk.
+	EndHeight int32
+}
+
+// Len returns the number of blocks of the chunk.
+func (c Chunk) Len() int {
+	return int(c.EndHeight-c.StartHeight) + 1
+}
+
+// Plan is the plan of a rescan: the scripts and outpoints watched, and the
+// chunks of the height range the filters are matched in.
+type Plan struct {
+	// Scripts are the scripts watched, sorted and without duplicates.
+	// They are the data matched against the filters.
+	Scripts [][]byte
+
+	// OutPoints are the outpoints watched, whose spends a light client
+	// looks for in the blocks whose filters match.
+	OutPoints []wire.OutPoint
+
+	// Chunks are the chunks of the height range, in increasing order of
+	// height.
+	Chunks []Chunk
+}
+
+// Option configures a plan.
+type Option func(*planner)
+
+// WithChunkSize sets the number of blocks of the chunks.  Values less than
+// one select DefaultChunkSize, which is the default.
+func WithChunkSize(n int) Option {
+	return func(p *planner) {
+		p.chunkSize = n
+	}
+}
+
+// planner holds the configuration of a plan.
+type planner struct {
+	chunkSize int
+}
+
+// NewPlan returns the plan of a rescan of the blocks from startHeight to
+// endHeight, inclusive, for the scripts of the ranged descriptors and the
+// outpoints.  The last chunk holds the remaining blocks when the range is not
+// a multiple of the chunk size.
+func NewPlan(descriptors []Ranged, outPoints []WatchedOutPoint,
+	startHeight, endHeight int32, opts ...Option) (*Plan, error) {
+
+	cfg := planner{chunkSize: DefaultChunkSize}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if cfg.chunkSize < 1 {
+		cfg.chunkSize = DefaultChunkSize
+	}
+	if startHeight < 0 || endHeight < startHeight {
+		return nil, ErrInvalidRange
+	}
+
+	var scripts [][]byte
+	for _, r := range descriptors {
+		if r.Count < 1 {
+			return nil, ErrInvalidIndexRange
+		}
+		derived, err := r.Descriptor.PkScripts(r.Start, r.Count)
+		if err != nil {
+			return nil, err
+		}
+		scripts = append(scripts, derived...)
+	}
+	plan := &Plan{OutPoints: make([]wire.OutPoint, 0, len(outPoints))}
+	for _, op := range outPoints {
+		plan.OutPoints = append(plan.OutPoints, op.OutPoint)
+		scripts = append(scripts, op.PkScript)
+	}
+
+	sort.Slice(scripts, func(i, j int) bool {
+		return bytes.Compare(scripts[i], scripts[j]) < 0
+	})
+	for i, script := range scripts {
+		if len(script) == 0 || i > 0 && bytes.Equal(script, scripts[i-1]) {
+			continue
+		}
+		plan.Scripts = append(plan.Scripts, script)
+	}
+
+	size := int64(cfg.chunkSize)
+	for start := int64(startHeight); start <= int64(endHeight); start += size {
+		end := start + size - 1
+		if end > int64(endHeight) {
+			end = int64(endHeight)
+		}
+		plan.Chunks = append(plan.Chunks, Chunk{
+			StartHeight: int32(start),
+			EndHeight:   int32(end),
+		})
+	}
+	return plan, nil
+}
+
+// MatchChunk returns the heights, in increasing order, of the blocks of chunk
+// whose filters likely (within collision probability) hold any of the
+// scripts of the plan.  filters holds the filter of each block of the chunk,
+// in order of height; ErrFilterCount is returned when their number is not
+// the length of the chunk.  The error of ctx is returned once it is done.
+func (p *Plan) MatchChunk(ctx context.Context, m *gcs.Matcher, chunk Chunk,
+	filters []gcs.KeyedFilter) ([]int32, error) {
+
+	if len(filters) != chunk.Len() {
+		return nil, ErrFilterCount
+	}
+	matched, err := m.MatchFilters(ctx, filters, p.Scripts)
+	if err != nil {
+		return nil, err
+	}
+	heights := make([]int32, 0, len(matched))
+	for _, i := range matched {
+		heights = append(heights, chunk.StartHeight+int32(i))
+	}
+	return heights, nil
+}
@@ -0,0 +1,171 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rescan_test
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil/gcs"
+	"github.com/zeusyf/btcutil/hdkeychain"
+	"github.com/zeusyf/btcutil/rescan"
+)
+
+// testDescriptor returns a descriptor of the external branch of a public
+// account key.
+func testDescriptor(t *testing.T) *rescan.KeyDescriptor {
+	master, err := hdkeychain.NewMaster(bytes.Repeat([]byte{7}, 32),
+		&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("NewMaster: unexpected error: %v", err)
+	}
+	account, err := master.Neuter()
+	if err != nil {
+		t.Fatalf("Neuter: unexpected error: %v", err)
+	}
+	branch, err := account.Child(0)
+	if err != nil {
+		t.Fatalf("Child: unexpected error: %v", err)
+	}
+	return &rescan.KeyDescriptor{Key: branch, Net: &chaincfg.MainNetParams}
+}
+
+// TestNewPlan ensures plans hold the sorted scripts of the watched indexes
+// and outpoints without duplicates, and cover the height range with chunks.
+func TestNewPlan(t *testing.T) {
+	d := testDescriptor(t)
+	want, err := d.PkScripts(0, 15)
+	if err != nil {
+		t.Fatalf("PkScripts: unexpected error: %v", err)
+	}
+	if len(want) != 15 {
+		t.Fatalf("PkScripts: got %d scripts, want 15", len(want))
+	}
+
+	op := wire.OutPoint{Index: 3}
+	plan, err := rescan.NewPlan([]rescan.Ranged{
+		{Descriptor: d, Start: 0, Count: 10},
+		{Descriptor: d, Start: 5, Count: 10},
+	}, []rescan.WatchedOutPoint{{OutPoint: op, PkScript: want[2]}},
+		10, 2509)
+	if err != nil {
+		t.Fatalf("NewPlan: unexpected error: %v", err)
+	}
+	if len(plan.Scripts) != 15 {
+		t.Fatalf("NewPlan: got %d scripts, want 15", len(plan.Scripts))
+	}
+	for i, script := range plan.Scripts {
+		if i > 0 && bytes.Compare(plan.Scripts[i-1], script) >= 0 {
+			t.Fatalf("NewPlan: scripts %d and %d not sorted", i-1, i)
+		}
+	}
+	for _, script := range want {
+		found := false
+		for _, s := range plan.Scripts {
+			found = found || bytes.Equal(s, script)
+		}
+		if !found {
+			t.Errorf("NewPlan: script %x missing", script)
+		}
+	}
+	if !reflect.DeepEqual(plan.OutPoints, []wire.OutPoint{op}) {
+		t.Errorf("NewPlan: got outpoints %v", plan.OutPoints)
+	}
+
+	wantChunks := []rescan.Chunk{
+		{StartHeight: 10, EndHeight: 1009},
+		{StartHeight: 1010, EndHeight: 2009},
+		{StartHeight: 2010, EndHeight: 2509},
+	}
+	if !reflect.DeepEqual(plan.Chunks, wantChunks) {
+		t.Errorf("NewPlan: got chunks %v, want %v", plan.Chunks, wantChunks)
+	}
+
+	plan, err = rescan.NewPlan(nil, nil, 0, 4, rescan.WithChunkSize(2))
+	if err != nil {
+		t.Fatalf("NewPlan: unexpected error: %v", err)
+	}
+	wantChunks = []rescan.Chunk{
+		{StartHeight: 0, EndHeight: 1},
+		{StartHeight: 2, EndHeight: 3},
+		{StartHeight: 4, EndHeight: 4},
+	}
+	if !reflect.DeepEqual(plan.Chunks, wantChunks) {
+		t.Errorf("WithChunkSize: got chunks %v, want %v", plan.Chunks,
+			wantChunks)
+	}
+
+	errs := []struct {
+		name       string
+		ranged     []rescan.Ranged
+		start, end int32
+		err        error
+	}{
+		{"negative start", nil, -1, 10, rescan.ErrInvalidRange},
+		{"empty range", nil, 10, 9, rescan.ErrInvalidRange},
+		{"no indexes", []rescan.Ranged{{Descriptor: d}}, 0, 10,
+			rescan.ErrInvalidIndexRange},
+	}
+	for _, test := range errs {
+		_, err := rescan.NewPlan(test.ranged, nil, test.start, test.end)
+		if err != test.err {
+			t.Errorf("%s: got error %v, want %v", test.name, err, test.err)
+		}
+	}
+}
+
+// TestMatchChunk ensures the heights of the blocks whose filters hold a
+// watched script are matched.
+func TestMatchChunk(t *testing.T) {
+	d := testDescriptor(t)
+	scripts, err := d.PkScripts(0, 3)
+	if err != nil {
+		t.Fatalf("PkScripts: unexpected error: %v", err)
+	}
+	plan, err := rescan.NewPlan([]rescan.Ranged{{Descriptor: d, Count: 2}},
+		nil, 100, 103)
+	if err != nil {
+		t.Fatalf("NewPlan: unexpected error: %v", err)
+	}
+
+	// Blocks 101 and 103 pay the watched scripts, block 102 a script
+	// past the watched range.
+	blocks := [][][]byte{
+		{{0x51}},
+		{{0x52}, scripts[1]},
+		{scripts[2]},
+		{scripts[0]},
+	}
+	filters := make([]gcs.KeyedFilter, len(blocks))
+	for i, data := range blocks {
+		key := [gcs.KeySize]byte{byte(i)}
+		f, err := gcs.BuildGCSFilter(19, 784931, key, data)
+		if err != nil {
+			t.Fatalf("BuildGCSFilter: unexpected error: %v", err)
+		}
+		filters[i] = gcs.KeyedFilter{Filter: f, Key: key}
+	}
+
+	var m gcs.Matcher
+	heights, err := plan.MatchChunk(context.Background(), &m,
+		plan.Chunks[0], filters)
+	if err != nil {
+		t.Fatalf("MatchChunk: unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(heights, []int32{101, 103}) {
+		t.Errorf("MatchChunk: got heights %v, want [101 103]", heights)
+	}
+
+	_, err = plan.MatchChunk(context.Background(), &m, plan.Chunks[0],
+		filters[1:])
+	if err != rescan.ErrFilterCount {
+		t.Errorf("MatchChunk: got error %v, want %v", err,
+			rescan.ErrFilterCount)
+	}
+}
@@ -0,0 +1,122 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rights
+
+import (
+	"errors"
+
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/omega/token"
+)
+
+// hashTokenRights are the token type bits of tokens with a hash value and
+// rights, such as land.
+const hashTokenRights = 3
+
+// ErrUnsupportedToken describes an error where the token to delegate does
+// not have both a hash value and rights.  The value of numeric tokens would
+// have to be divided along with their rights, which a delegation does not
+// do.
+var ErrUnsupportedToken = errors.New("only hash valued tokens with rights " +
+	"can be delegated")
+
+// Delegation is the plan of splitting the rights of a token between a
+// delegated output and a retained output, both of the value of the token.
+type Delegation struct {
+	// Token is the token whose rights are split.
+	Token token.Token
+
+	// Delegated and Retained are the rights of the two outputs, which
+	// partition the rights of Token.
+	Delegated []chainhash.Hash
+	Retained  []chainhash.Hash
+}
+
+// NewDelegation plans delegating the passed rights of tok, and retaining the
+// others.  The rights of tok are resolved against sets as by Resolve, and
+// ErrEmptyPart is returned unless some but not all of them are delegated.
+func NewDelegation(tok token.Token, sets map[chainhash.Hash]*token.RightSetDef,
+	delegated []chainhash.Hash) (*Delegation, error) {
+
+	if tok.TokenType&hashTokenRights != hashTokenRights {
+		return nil, ErrUnsupportedToken
+	}
+	all, err := Resolve(tok.Rights, sets)
+	if err != nil {
+		return nil, err
+	}
+
+	isDelegated := make(map[chainhash.Hash]bool, len(delegated))
+	for _, right := range delegated {
+		isDelegated[right] = true
+	}
+	var retained []chainhash.Hash
+	for _, right := range all {
+		if !isDelegated[right] {
+			isDelegated[right] = true
+			retained = append(retained, right)
+		}
+	}
+	if err := CheckPartition(all, delegated, retained); err != nil {
+		return nil, err
+	}
+
+	return &Delegation{
+		Token:     tok,
+		Delegated: delegated,
+		Retained:  retained,
+	}, nil
+}
+
+// Check verifies that the delegated and retained rights partition the
+// rights of the token, resolved against sets.  It is meant for delegations
+// which were not planned by NewDelegation, such as ones decoded from a
+// proposal.
+func (d *Delegation) Check(sets map[chainhash.Hash]*token.RightSetDef) error {
+	if d.Token.TokenType&hashTokenRights != hashTokenRights {
+		return ErrUnsupportedToken
+	}
+	all, err := Resolve(d.Token.Rights, sets)
+	if err != nil {
+		return err
+	}
+	return CheckPartition(all, d.Delegated, d.Retained)
+}
+
+// Definitions returns the right set definitions the outputs of the
+// delegation refer to, which are to be added to the transaction.
+func (d *Delegation) Definitions() []token.Definition {
+	var defs []token.Definition
+	for _, part := range [][]chainhash.Hash{d.Delegated, d.Retained} {
+		if _, set := Reference(part); set != nil {
+			defs = append(defs, set)
+		}
+	}
+	return defs
+}
+
+// Outputs returns the delegated output paying to delegatee, followed by the
+// retained output paying to owner.
+func (d *Delegation) Outputs(delegatee, owner []byte) []*wire.TxOut {
+	delegated, _ := Reference(d.Delegated)
+	retained, _ := Reference(d.Retained)
+	return []*wire.TxOut{
+		d.output(delegated, delegatee),
+		d.output(retained, owner),
+	}
+}
+
+// output returns an output of the token with rights paying to pkScript.
+func (d *Delegation) output(rights *chainhash.Hash, pkScript []byte) *wire.TxOut {
+	return &wire.TxOut{
+		Token: token.Token{
+			TokenType: d.Token.TokenType,
+			Value:     d.Token.Value,
+			Rights:    rights,
+		},
+		PkScript: pkScript,
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rights_test
+
+import (
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcutil/rights"
+	"github.com/zeusyf/omega/token"
+)
+
+// TestDelegation ensures delegations split the rights of a land between
+// their outputs, and reject anything but a proper partition.
+func TestDelegation(t *testing.T) {
+	all := &token.RightSetDef{Rights: []chainhash.Hash{use, build, sell}}
+	sets := map[chainhash.Hash]*token.RightSetDef{all.Hash(): all}
+	hash := all.Hash()
+	land := token.Token{
+		TokenType: 3,
+		Value:     &token.HashVal{Hash: chainhash.Hash{0x42}},
+		Rights:    &hash,
+	}
+
+	d, err := rights.NewDelegation(land, sets, []chainhash.Hash{use})
+	if err != nil {
+		t.Fatalf("NewDelegation: unexpected error: %v", err)
+	}
+	if len(d.Retained) != 2 || d.Retained[0] != build || d.Retained[1] != sell {
+		t.Errorf("NewDelegation: got retained rights %v", d.Retained)
+	}
+	if err := d.Check(sets); err != nil {
+		t.Errorf("Check: unexpected error: %v", err)
+	}
+
+	defs := d.Definitions()
+	if len(defs) != 1 {
+		t.Fatalf("Definitions: got %d, want 1", len(defs))
+	}
+	tenant, owner := []byte{0x51}, []byte{0x52}
+	outputs := d.Outputs(tenant, owner)
+	if len(outputs) != 2 {
+		t.Fatalf("Outputs: got %d, want 2", len(outputs))
+	}
+	if *outputs[0].Token.Rights != use || outputs[0].PkScript[0] != tenant[0] {
+		t.Errorf("Outputs: got delegated output %+v", outputs[0])
+	}
+	if *outputs[1].Token.Rights != defs[0].Hash() || outputs[1].PkScript[0] != owner[0] {
+		t.Errorf("Outputs: got retained output %+v", outputs[1])
+	}
+	for i, out := range outputs {
+		if out.Token.TokenType != land.TokenType || out.Token.Value != land.Value {
+			t.Errorf("Outputs: output %d has token %+v", i, out.Token)
+		}
+	}
+
+	coin := token.Token{TokenType: 2, Value: &token.NumeralVal{Val: 1}, Rights: &hash}
+	tests := []struct {
+		name      string
+		tok       token.Token
+		delegated []chainhash.Hash
+		err       error
+	}{
+		{"numeric token", coin, []chainhash.Hash{use}, rights.ErrUnsupportedToken},
+		{"nothing", land, nil, rights.ErrEmptyPart},
+		{"everything", land, []chainhash.Hash{sell, use, build}, rights.ErrEmptyPart},
+		{"duplicate", land, []chainhash.Hash{use, use}, rights.ErrNotPartition},
+		{"unknown right", land, []chainhash.Hash{{0x04}}, rights.ErrNotPartition},
+		{"single right", token.Token{TokenType: 3, Rights: &use}, []chainhash.Hash{use},
+			rights.ErrEmptyPart},
+	}
+	for _, test := range tests {
+		_, err := rights.NewDelegation(test.tok, sets, test.delegated)
+		if err != test.err {
+			t.Errorf("NewDelegation(%s): got error %v, want %v", test.name,
+				err, test.err)
+		}
+	}
+
+	d.Retained = d.Retained[1:]
+	if err := d.Check(sets); err != rights.ErrNotPartition {
+		t.Errorf("Check: got error %v, want %v", err, rights.ErrNotPartition)
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package rights builds right-of-use delegations of Omega tokens.
+//
+// The rights of a token refer either to a single right definition, or to a
+// right set definition listing several rights.  A token holding a set of
+// rights may be spent into several outputs of the same value whose rights
+// partition the set, so that each holder may exercise only the rights of its
+// output.  This is the basis of rental and lease style applications: the
+// owner of a land delegates the right to use it to a tenant and retains the
+// others, and the two outputs are later spent together to restore the land
+// with its full rights.
+//
+// Delegation plans such a split, checking that the delegated and retained
+// rights are a proper partition of the rights of the token, and creates the
+// right set definitions and outputs to add to the transaction.
+package rights
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/omega/token"
+)
+
+var (
+	// ErrNoRights describes an error where a token carries no rights to
+	// delegate.
+	ErrNoRights = errors.New("token carries no rights")
+
+	// ErrEmptyPart describes an error where a part of a partition holds
+	// no rights.
+	ErrEmptyPart = errors.New("partition has an empty part")
+
+	// ErrNotPartition describes an error where parts share rights, hold
+	// rights not in the set, or leave rights of the set out.
+	ErrNotPartition = errors.New("parts do not partition the rights")
+)
+
+// Resolve returns the rights referred to by the rights hash of a token: the
+// rights of the right set defined by sets[*rights] when there is one, or the
+// single right otherwise.
+func Resolve(rights *chainhash.Hash, sets map[chainhash.Hash]*token.RightSetDef) ([]chainhash.Hash, error) {
+	if rights == nil {
+		return nil, ErrNoRights
+	}
+	if set, ok := sets[*rights]; ok {
+		if len(set.Rights) == 0 {
+			return nil, ErrNoRights
+		}
+		return set.Rights, nil
+	}
+	return []chainhash.Hash{*rights}, nil
+}
+
+// CheckPartition verifies that parts are a partition of set: none of them is
+// empty, and each right of set is in exactly one of them.  Duplicates within
+// set are ignored.
+func CheckPartition(set []chainhash.Hash, parts ...[]chainhash.Hash) error {
+	remaining := make(map[chainhash.Hash]struct{}, len(set))
+	for _, right := range set {
+		remaining[right] = struct{}{}
+	}
+	for _, part := range parts {
+		if len(part) == 0 {
+			return ErrEmptyPart
+		}
+		for _, right := range part {
+			if _, ok := remaining[right]; !ok {
+				return ErrNotPartition
+			}
+			delete(remaining, right)
+		}
+	}
+	if len(remaining) != 0 {
+		return ErrNotPartition
+	}
+	return nil
+}
+
+// Reference returns the rights hash for outputs carrying rights, along with
+// the right set definition it refers to when rights has more than one
+// member.  The members of a right set are sorted, so that the same rights
+// always make the same set.
+func Reference(rights []chainhash.Hash) (*chainhash.Hash, *token.RightSetDef) {
+	if len(rights) == 1 {
+		hash := rights[0]
+		return &hash, nil
+	}
+
+	sorted := make([]chainhash.Hash, len(rights))
+	copy(sorted, rights)
+	sort.Slice(sorted, func(i, j int) bool {
+		return bytes.Compare(sorted[i][:], sorted[j][:]) < 0
+	})
+	set := &token.RightSetDef{Rights: sorted}
+	hash := set.Hash()
+	return &hash, set
+}
@@ -0,0 +1,90 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package rights_test
+
+import (
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcutil/rights"
+	"github.com/zeusyf/omega/token"
+)
+
+// Rights used by the tests, such as to use, to build and to sell a land.
+var (
+	use   = chainhash.Hash{0x01}
+	build = chainhash.Hash{0x02}
+	sell  = chainhash.Hash{0x03}
+)
+
+// TestResolve ensures rights hashes resolve to the members of known right
+// sets, or to the single right otherwise.
+func TestResolve(t *testing.T) {
+	set := &token.RightSetDef{Rights: []chainhash.Hash{use, build}}
+	sets := map[chainhash.Hash]*token.RightSetDef{
+		set.Hash():           set,
+		chainhash.Hash{0xff}: {},
+	}
+
+	hash := set.Hash()
+	got, err := rights.Resolve(&hash, sets)
+	if err != nil || len(got) != 2 || got[0] != use || got[1] != build {
+		t.Errorf("Resolve(set): got %v, %v", got, err)
+	}
+	got, err = rights.Resolve(&sell, sets)
+	if err != nil || len(got) != 1 || got[0] != sell {
+		t.Errorf("Resolve(right): got %v, %v", got, err)
+	}
+
+	empty := chainhash.Hash{0xff}
+	for _, hash := range []*chainhash.Hash{nil, &empty} {
+		if _, err := rights.Resolve(hash, sets); err != rights.ErrNoRights {
+			t.Errorf("Resolve(%v): got error %v, want %v", hash, err,
+				rights.ErrNoRights)
+		}
+	}
+}
+
+// TestCheckPartition ensures only parts holding each right of the set exactly
+// once are accepted.
+func TestCheckPartition(t *testing.T) {
+	set := []chainhash.Hash{use, build, sell}
+	tests := []struct {
+		name  string
+		parts [][]chainhash.Hash
+		err   error
+	}{
+		{"two parts", [][]chainhash.Hash{{use}, {sell, build}}, nil},
+		{"single part", [][]chainhash.Hash{{build, sell, use}}, nil},
+		{"empty part", [][]chainhash.Hash{{use, build, sell}, {}}, rights.ErrEmptyPart},
+		{"overlap", [][]chainhash.Hash{{use, build}, {build, sell}}, rights.ErrNotPartition},
+		{"missing", [][]chainhash.Hash{{use}, {build}}, rights.ErrNotPartition},
+		{"unknown", [][]chainhash.Hash{{use, build}, {sell, {0x04}}}, rights.ErrNotPartition},
+	}
+	for _, test := range tests {
+		if err := rights.CheckPartition(set, test.parts...); err != test.err {
+			t.Errorf("CheckPartition(%s): got error %v, want %v", test.name,
+				err, test.err)
+		}
+	}
+}
+
+// TestReference ensures single rights are referred to directly and several
+// through a right set independent of their order.
+func TestReference(t *testing.T) {
+	hash, set := rights.Reference([]chainhash.Hash{sell})
+	if *hash != sell || set != nil {
+		t.Errorf("Reference(single): got %v, %v", hash, set)
+	}
+
+	hash, set = rights.Reference([]chainhash.Hash{sell, use})
+	if set == nil || *hash != set.Hash() || set.Rights[0] != use {
+		t.Fatalf("Reference(several): got %v, %v", hash, set)
+	}
+	other, _ := rights.Reference([]chainhash.Hash{use, sell})
+	if *other != *hash {
+		t.Errorf("Reference: got %v for reordered rights, want %v", other, hash)
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package btcutil
+
+import (
+	"fmt"
+	"math/big"
+)
+
+// RoundingMode selects how a value falling between two integers is rounded.
+type RoundingMode uint8
+
+// These constants define the supported rounding modes.
+const (
+	// RoundHalfAwayFromZero rounds to the nearest integer, and halves away
+	// from zero.  It is how NewAmount and MulF64 round.
+	RoundHalfAwayFromZero RoundingMode = iota
+
+	// RoundHalfEven rounds to the nearest integer, and halves to the even
+	// one, as in banker's rounding.  It does not bias sums of many rounded
+	// values.
+	RoundHalfEven
+
+	// RoundFloor rounds towards negative infinity.
+	RoundFloor
+
+	// RoundCeil rounds towards positive infinity.
+	RoundCeil
+)
+
+// Map of rounding modes back to their constant names for pretty printing.
+var roundingModeStrings = map[RoundingMode]string{
+	RoundHalfAwayFromZero: "RoundHalfAwayFromZero",
+	RoundHalfEven:         "RoundHalfEven",
+	RoundFloor:            "RoundFloor",
+	RoundCeil:             "RoundCeil",
+}
+
+// String returns the RoundingMode as a human-readable name.
+func (m RoundingMode) String() string {
+	if s := roundingModeStrings[m]; s != "" {
+		return s
+	}
+	return fmt.Sprintf("Unknown RoundingMode (%d)", uint8(m))
+}
+
+// roundRat rounds r to an integer according to mode.  Unknown modes round as
+// RoundHalfAwayFromZero.
+func roundRat(r *big.Rat, mode RoundingMode) *big.Int {
+	// The denominator of a big.Rat is always positive, so the remainder
+	// has the sign of r and the quotient is truncated towards zero.
+	var rem big.Int
+	q, _ := new(big.Int).QuoRem(r.Num(), r.Denom(), &rem)
+	if rem.Sign() == 0 {
+		return q
+	}
+
+	var away bool
+	switch mode {
+	case RoundFloor:
+		away = r.Sign() < 0
+	case RoundCeil:
+		away = r.Sign() > 0
+	default:
+		// Compare twice the magnitude of the remainder with the
+		// denominator to tell whether the fraction exceeds one half.
+		twice := new(big.Int).Abs(&rem)
+		twice.Lsh(twice, 1)
+		switch twice.Cmp(r.Denom()) {
+		case 1:
+			away = true
+		case 0:
+			away = mode != RoundHalfEven || q.Bit(0) == 1
+		}
+	}
+	if away {
+		q.Add(q, big.NewInt(int64(r.Sign())))
+	}
+	return q
+}
@@ -0,0 +1,68 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package screening
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/scriptclass"
+)
+
+// LineError describes an error where a line of an address list can't be
+// decoded.
+type LineError struct {
+	// Line is the number of the line, counting from one.
+	Line int
+
+	// Err is the error decoding the address of the line.
+	Err error
+}
+
+// Error returns the error along with the number of the offending line.
+func (e *LineError) Error() string {
+	return fmt.Sprintf("line %d: %v", e.Line, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *LineError) Unwrap() error {
+	return e.Err
+}
+
+// LoadAddresses returns a list of the addresses read from r, one per line.
+// Surrounding whitespace is ignored, as are empty lines and lines starting
+// with '#'.  A LineError is returned for an address which can't be decoded,
+// which is not for net, or which has no public key script.
+func LoadAddresses(r io.Reader, net *chaincfg.Params,
+	opts ...Option) (*List, error) {
+	var pkScripts [][]byte
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		s := strings.TrimSpace(scanner.Text())
+		if s == "" || strings.HasPrefix(s, "#") {
+			continue
+		}
+		addr, err := btcutil.DecodeAddress(s, net)
+		if err != nil {
+			return nil, &LineError{Line: line, Err: err}
+		}
+		if !addr.IsForNet(net) {
+			return nil, &LineError{Line: line, Err: btcutil.ErrWrongChain}
+		}
+		pkScript, err := scriptclass.PayToAddrScript(addr)
+		if err != nil {
+			return nil, &LineError{Line: line, Err: err}
+		}
+		pkScripts = append(pkScripts, pkScript)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return NewList(pkScripts, opts...)
+}
@@ -0,0 +1,38 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package screening_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil/screening"
+)
+
+// TestLoadAddresses ensures address lists are read skipping blank lines and
+// comments, and report the line of an invalid address.
+func TestLoadAddresses(t *testing.T) {
+	a, b := testAddr(t, 1), testAddr(t, 2)
+	input := "# sanctioned addresses\n\n  " + a.EncodeAddress() +
+		"  \n" + b.EncodeAddress() + "\n"
+	l, err := screening.LoadAddresses(strings.NewReader(input),
+		&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("LoadAddresses: unexpected error: %v", err)
+	}
+	if l.Len() != 2 || !l.Match(a) || !l.Match(b) || l.Match(testAddr(t, 3)) {
+		t.Errorf("LoadAddresses: got %d addresses", l.Len())
+	}
+
+	input = a.EncodeAddress() + "\n\nnot an address\n"
+	_, err = screening.LoadAddresses(strings.NewReader(input),
+		&chaincfg.MainNetParams)
+	var lineErr *screening.LineError
+	if !errors.As(err, &lineErr) || lineErr.Line != 3 {
+		t.Errorf("LoadAddresses: got error %v, want error at line 3", err)
+	}
+}
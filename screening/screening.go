@@ -0,0 +1,221 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package screening matches addresses and public key scripts against large
+// allow and deny lists, such as the sanction lists payment processors screen
+// withdrawals against.
+//
+// Lists are keyed by the public key script paying to each address, so an
+// address and any output paying to it match alike.  Every list is indexed by
+// an exact set of its scripts fronted by a bloom filter, which answers most
+// lookups of unlisted scripts, the common case when screening payments,
+// without touching the set.
+package screening
+
+import (
+	"errors"
+	"fmt"
+	"hash/maphash"
+	"math"
+
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/scriptclass"
+)
+
+// DefaultFalsePositiveRate is the false positive rate of the bloom filter of
+// a list when none is set.  False positives only cost an exact lookup, never
+// a wrong match.
+const DefaultFalsePositiveRate = 0.001
+
+var (
+	// ErrDenied describes an error where a script is on the deny list of a
+	// policy.
+	ErrDenied = errors.New("script is denied")
+
+	// ErrNotAllowed describes an error where a policy has an allow list
+	// and a script is not on it.
+	ErrNotAllowed = errors.New("script is not allowed")
+
+	// ErrInvalidFalsePositiveRate describes an error where a false positive
+	// rate is not strictly between 0 and 1.
+	ErrInvalidFalsePositiveRate = errors.New("false positive rate out of range")
+)
+
+// AddressError describes an error where one of the addresses of a list has no
+// public key script.
+type AddressError struct {
+	// Index is the position of the address in the list.
+	Index int
+
+	// Err is the error returned by scriptclass.PayToAddrScript.
+	Err error
+}
+
+// Error returns the error along with the index of the offending address.
+func (e *AddressError) Error() string {
+	return fmt.Sprintf("address %d: %v", e.Index, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *AddressError) Unwrap() error {
+	return e.Err
+}
+
+// Option is a functional option of NewList.
+type Option func(*List)
+
+// WithFalsePositiveRate sets the false positive rate of the bloom filter of
+// the list, trading memory for exact lookups.
+func WithFalsePositiveRate(rate float64) Option {
+	return func(l *List) {
+		l.fpRate = rate
+	}
+}
+
+// List is an immutable set of public key scripts.  It is safe for concurrent
+// use.
+type List struct {
+	fpRate    float64
+	seed      maphash.Seed
+	bits      []uint64
+	hashFuncs uint64
+	scripts   map[string]struct{}
+}
+
+// NewList returns a list of the passed public key scripts.  Duplicate scripts
+// are listed once.
+func NewList(pkScripts [][]byte, opts ...Option) (*List, error) {
+	l := &List{
+		fpRate:  DefaultFalsePositiveRate,
+		seed:    maphash.MakeSeed(),
+		scripts: make(map[string]struct{}, len(pkScripts)),
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	if !(l.fpRate > 0 && l.fpRate < 1) {
+		return nil, ErrInvalidFalsePositiveRate
+	}
+	for _, pkScript := range pkScripts {
+		l.scripts[string(pkScript)] = struct{}{}
+	}
+
+	// m = -(n*ln(p) / ln(2)^2) bits, rounded up to whole words, and
+	// k = (m/n) * ln(2) hash functions, rounded to the nearest.
+	n := math.Max(float64(len(l.scripts)), 1)
+	bitLen := math.Ceil(-n * math.Log(l.fpRate) / (math.Ln2 * math.Ln2))
+	l.bits = make([]uint64, int(math.Ceil(bitLen/64)))
+	l.hashFuncs = uint64(math.Max(math.Round(
+		float64(len(l.bits)*64)/n*math.Ln2), 1))
+	for pkScript := range l.scripts {
+		l.add([]byte(pkScript))
+	}
+	return l, nil
+}
+
+// NewAddressList returns a list of the public key scripts paying to addrs.
+// An AddressError is returned when an address has no script.
+func NewAddressList(addrs []btcutil.Address, opts ...Option) (*List, error) {
+	pkScripts := make([][]byte, len(addrs))
+	for i, addr := range addrs {
+		pkScript, err := scriptclass.PayToAddrScript(addr)
+		if err != nil {
+			return nil, &AddressError{Index: i, Err: err}
+		}
+		pkScripts[i] = pkScript
+	}
+	return NewList(pkScripts, opts...)
+}
+
+// positions returns the two hashes from which the bit positions of pkScript
+// are derived, by double hashing.
+func (l *List) positions(pkScript []byte) (h1, h2 uint64) {
+	h := maphash.Bytes(l.seed, pkScript)
+	return h, h>>32 | 1
+}
+
+// add sets the bits of pkScript in the bloom filter.
+func (l *List) add(pkScript []byte) {
+	m := uint64(len(l.bits) * 64)
+	h1, h2 := l.positions(pkScript)
+	for i := uint64(0); i < l.hashFuncs; i++ {
+		bit := (h1 + i*h2) % m
+		l.bits[bit/64] |= 1 << (bit % 64)
+	}
+}
+
+// mayContain returns whether all the bits of pkScript are set in the bloom
+// filter.
+func (l *List) mayContain(pkScript []byte) bool {
+	m := uint64(len(l.bits) * 64)
+	h1, h2 := l.positions(pkScript)
+	for i := uint64(0); i < l.hashFuncs; i++ {
+		bit := (h1 + i*h2) % m
+		if l.bits[bit/64]&(1<<(bit%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Len returns the number of scripts of the list.
+func (l *List) Len() int {
+	return len(l.scripts)
+}
+
+// MatchScript returns whether pkScript is on the list.  Scripts are compared
+// byte for byte.
+func (l *List) MatchScript(pkScript []byte) bool {
+	if !l.mayContain(pkScript) {
+		return false
+	}
+	_, ok := l.scripts[string(pkScript)]
+	return ok
+}
+
+// Match returns whether the public key script paying to addr is on the list.
+// Addresses without a script never match.
+func (l *List) Match(addr btcutil.Address) bool {
+	pkScript, err := scriptclass.PayToAddrScript(addr)
+	if err != nil {
+		return false
+	}
+	return l.MatchScript(pkScript)
+}
+
+// Policy screens scripts against an optional allow list and an optional deny
+// list.  Denial takes precedence, so a script on both lists is denied.
+type Policy struct {
+	// Allow, when not nil, lists the only scripts allowed.
+	Allow *List
+
+	// Deny, when not nil, lists scripts which are never allowed.
+	Deny *List
+}
+
+// CheckScript returns ErrDenied when pkScript is on the deny list, and
+// ErrNotAllowed when the policy has an allow list pkScript is not on.
+func (p *Policy) CheckScript(pkScript []byte) error {
+	if p.Deny != nil && p.Deny.MatchScript(pkScript) {
+		return ErrDenied
+	}
+	if p.Allow != nil && !p.Allow.MatchScript(pkScript) {
+		return ErrNotAllowed
+	}
+	return nil
+}
+
+// Check screens the public key script paying to addr as CheckScript does.
+// An address without a script is only allowed when the policy has no allow
+// list, since it can't be on any list.
+func (p *Policy) Check(addr btcutil.Address) error {
+	pkScript, err := scriptclass.PayToAddrScript(addr)
+	if err != nil {
+		if p.Allow != nil {
+			return ErrNotAllowed
+		}
+		return nil
+	}
+	return p.CheckScript(pkScript)
+}
@@ -0,0 +1,115 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package screening_test
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/screening"
+	"github.com/zeusyf/btcutil/scriptclass"
+)
+
+// testAddr returns a pubkey hash address whose hash is derived from i.
+func testAddr(t *testing.T, i int) btcutil.Address {
+	t.Helper()
+	hash := btcutil.Hash160([]byte(fmt.Sprint(i)))
+	addr, err := btcutil.NewAddressPubKeyHash(hash, &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("NewAddressPubKeyHash: unexpected error: %v", err)
+	}
+	return addr
+}
+
+// TestList ensures listed addresses and scripts match and others do not.
+func TestList(t *testing.T) {
+	const listed = 10000
+	addrs := make([]btcutil.Address, listed)
+	for i := range addrs {
+		addrs[i] = testAddr(t, i)
+	}
+	addrs = append(addrs, addrs[0])
+	l, err := screening.NewAddressList(addrs)
+	if err != nil {
+		t.Fatalf("NewAddressList: unexpected error: %v", err)
+	}
+	if l.Len() != listed {
+		t.Errorf("Len: got %d, want %d", l.Len(), listed)
+	}
+	for i, addr := range addrs {
+		if !l.Match(addr) {
+			t.Fatalf("Match: listed address %d did not match", i)
+		}
+		pkScript, _ := scriptclass.PayToAddrScript(addr)
+		if !l.MatchScript(pkScript) {
+			t.Fatalf("MatchScript: listed script %d did not match", i)
+		}
+	}
+	for i := listed; i < 2*listed; i++ {
+		if l.Match(testAddr(t, i)) {
+			t.Fatalf("Match: unlisted address %d matched", i)
+		}
+	}
+	if l.MatchScript(nil) {
+		t.Errorf("MatchScript: empty script matched")
+	}
+
+	empty, err := screening.NewList(nil)
+	if err != nil {
+		t.Fatalf("NewList: unexpected error: %v", err)
+	}
+	if empty.Len() != 0 || empty.Match(addrs[0]) {
+		t.Errorf("NewList: empty list matched")
+	}
+
+	for _, rate := range []float64{0, 1, -0.5} {
+		_, err := screening.NewList(nil, screening.WithFalsePositiveRate(rate))
+		if err != screening.ErrInvalidFalsePositiveRate {
+			t.Errorf("NewList(%v): got error %v, want %v", rate, err,
+				screening.ErrInvalidFalsePositiveRate)
+		}
+	}
+}
+
+// TestPolicy ensures policies deny scripts on their deny list and those not
+// on their allow list.
+func TestPolicy(t *testing.T) {
+	a, b, c := testAddr(t, 1), testAddr(t, 2), testAddr(t, 3)
+	allow, err := screening.NewAddressList([]btcutil.Address{a, b})
+	if err != nil {
+		t.Fatalf("NewAddressList: unexpected error: %v", err)
+	}
+	deny, err := screening.NewAddressList([]btcutil.Address{b})
+	if err != nil {
+		t.Fatalf("NewAddressList: unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		policy screening.Policy
+		addr   btcutil.Address
+		want   error
+	}{
+		{screening.Policy{}, c, nil},
+		{screening.Policy{Deny: deny}, a, nil},
+		{screening.Policy{Deny: deny}, b, screening.ErrDenied},
+		{screening.Policy{Allow: allow}, a, nil},
+		{screening.Policy{Allow: allow}, c, screening.ErrNotAllowed},
+		{screening.Policy{Allow: allow, Deny: deny}, b, screening.ErrDenied},
+	}
+	for i, test := range tests {
+		if err := test.policy.Check(test.addr); !errors.Is(err, test.want) {
+			t.Errorf("Check #%d: got error %v, want %v", i, err, test.want)
+		}
+		pkScript, _ := scriptclass.PayToAddrScript(test.addr)
+		err := test.policy.CheckScript(pkScript)
+		if !errors.Is(err, test.want) {
+			t.Errorf("CheckScript #%d: got error %v, want %v", i, err,
+				test.want)
+		}
+	}
+}
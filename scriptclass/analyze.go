@@ -0,0 +1,236 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package scriptclass
+
+import (
+	"fmt"
+
+	"github.com/zeusyf/btcutil"
+)
+
+// Severity indicates how serious a Finding is.
+type Severity byte
+
+const (
+	// SeverityWarning marks a script which is spendable under the current
+	// rules but is probably not what the payer intended.
+	SeverityWarning Severity = iota
+
+	// SeverityBurn marks a script which can never be spent.  Any value
+	// paid to it is irreversibly lost.
+	SeverityBurn
+)
+
+// String implements the fmt.Stringer interface.
+func (s Severity) String() string {
+	switch s {
+	case SeverityWarning:
+		return "warning"
+	case SeverityBurn:
+		return "burn"
+	}
+	return "invalid"
+}
+
+// FindingCode identifies the kind of problem a Finding reports.
+type FindingCode byte
+
+// These constants are the problems reported by Analyze.
+const (
+	// NullDataWithValue is a provably unspendable OP_RETURN output
+	// carrying a non-zero amount.
+	NullDataWithValue FindingCode = iota
+
+	// Unspendable is a script which fails whenever it is executed, such
+	// as one starting with OP_RETURN or containing a disabled opcode.
+	Unspendable
+
+	// MalformedScript is a script which cannot be parsed because a push
+	// runs past its end.
+	MalformedScript
+
+	// OversizedScript is a script longer than MaxScriptSize.
+	OversizedScript
+
+	// OversizedPush is a push of more than MaxScriptElementSize bytes.
+	OversizedPush
+
+	// WrongWitnessProgramSize is a version 0 witness program that is
+	// neither 20 nor 32 bytes long, or a version 1 program that is not 32
+	// bytes long.
+	WrongWitnessProgramSize
+
+	// FutureWitnessVersion is a witness program with a version not yet
+	// given meaning by consensus.  Such outputs are anyone-can-spend
+	// today and may become unspendable after a future soft fork.
+	FutureWitnessVersion
+
+	// InvalidPubKey is a pay-to-pubkey or multi-signature script with a
+	// key which is not a valid serialized public key.
+	InvalidPubKey
+
+	// EmptyScript is an empty script, which anyone can spend.
+	EmptyScript
+
+	// KnownBurnAddress is a script paying a hash registered as a burn
+	// address with btcutil.RegisterBurnAddress.
+	KnownBurnAddress
+)
+
+// findingCodeStrings is a map of finding codes back to their constant names
+// for pretty printing.
+var findingCodeStrings = map[FindingCode]string{
+	NullDataWithValue:       "NullDataWithValue",
+	Unspendable:             "Unspendable",
+	MalformedScript:         "MalformedScript",
+	OversizedScript:         "OversizedScript",
+	OversizedPush:           "OversizedPush",
+	WrongWitnessProgramSize: "WrongWitnessProgramSize",
+	FutureWitnessVersion:    "FutureWitnessVersion",
+	InvalidPubKey:           "InvalidPubKey",
+	EmptyScript:             "EmptyScript",
+	KnownBurnAddress:        "KnownBurnAddress",
+}
+
+// String returns the FindingCode as a human-readable name.
+func (c FindingCode) String() string {
+	if s := findingCodeStrings[c]; s != "" {
+		return s
+	}
+	return fmt.Sprintf("Unknown FindingCode (%d)", int(c))
+}
+
+// Finding describes a single problem discovered in a public key script.
+type Finding struct {
+	Severity Severity
+	Code     FindingCode
+	Message  string
+}
+
+// Error satisfies the error interface and prints the finding.
+func (f Finding) Error() string {
+	return f.Severity.String() + ": " + f.Message
+}
+
+// addressHash returns the 20 byte hash committed to by a pay-to-pubkey-hash,
+// pay-to-script-hash, or pay-to-witness-pubkey-hash script, or nil for any
+// other script.
+func addressHash(script []byte) []byte {
+	switch Classify(script) {
+	case PubKeyHashTy:
+		return script[3:23]
+	case ScriptHashTy:
+		return script[2:22]
+	case WitnessV0PubKeyHashTy:
+		return script[2:]
+	}
+	return nil
+}
+
+// Analyze inspects a public key script a wallet is about to pay amount to and
+// reports patterns which lose or endanger the funds.  It only looks at the
+// script itself, so it recognizes outputs which can never be spent but cannot
+// know whether anybody holds the keys to an otherwise valid script.  A nil
+// result means no problems were found.
+func Analyze(pkScript []byte, amount btcutil.Amount) []Finding {
+	var findings []Finding
+	add := func(sev Severity, code FindingCode, format string, args ...interface{}) {
+		findings = append(findings, Finding{
+			Severity: sev,
+			Code:     code,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	if len(pkScript) == 0 {
+		add(SeverityWarning, EmptyScript, "empty script can be "+
+			"spent by anyone")
+		return findings
+	}
+	if len(pkScript) > MaxScriptSize {
+		add(SeverityBurn, OversizedScript, "script of %d bytes exceeds "+
+			"the maximum of %d", len(pkScript), MaxScriptSize)
+	}
+
+	if hash := addressHash(pkScript); btcutil.IsBurnHash(hash) {
+		add(SeverityBurn, KnownBurnAddress, "script pays a known burn "+
+			"address")
+		return findings
+	}
+
+	if version, program, ok := ExtractWitnessProgram(pkScript); ok {
+		switch {
+		case version == 0 && len(program) != 20 && len(program) != 32:
+			add(SeverityBurn, WrongWitnessProgramSize, "version 0 "+
+				"witness program of %d bytes must be 20 or 32 "+
+				"bytes", len(program))
+		case version == 1 && len(program) != 32:
+			add(SeverityWarning, WrongWitnessProgramSize, "version 1 "+
+				"witness program of %d bytes is not a taproot "+
+				"output key", len(program))
+		case version > 1:
+			add(SeverityWarning, FutureWitnessVersion, "witness "+
+				"version %d is not defined and may become "+
+				"unspendable", version)
+		}
+		return findings
+	}
+
+	ops, err := Parse(pkScript)
+	if err != nil {
+		add(SeverityBurn, MalformedScript, "script is malformed: %v", err)
+		return findings
+	}
+
+	if isNullData(ops) {
+		if amount != 0 {
+			add(SeverityBurn, NullDataWithValue, "OP_RETURN output "+
+				"burns %v", amount)
+		}
+		return findings
+	}
+
+	// Only the first opcode which makes execution fail is reported.
+scan:
+	for _, op := range ops {
+		switch {
+		case len(op.Data) > MaxScriptElementSize:
+			add(SeverityBurn, OversizedPush, "push of %d bytes "+
+				"exceeds the maximum of %d", len(op.Data),
+				MaxScriptElementSize)
+			break scan
+		case op.Op == OP_RETURN:
+			add(SeverityBurn, Unspendable, "script contains OP_RETURN")
+			break scan
+		case op.Op == OP_VERIF || op.Op == OP_VERNOTIF:
+			add(SeverityBurn, Unspendable, "script contains reserved "+
+				"opcode 0x%02x", op.Op)
+			break scan
+		case isDisabled(op.Op):
+			add(SeverityBurn, Unspendable, "script contains disabled "+
+				"opcode 0x%02x", op.Op)
+			break scan
+		}
+	}
+
+	// <pubkey> OP_CHECKSIG with something other than a public key.
+	if len(ops) == 2 && ops[1].Op == OP_CHECKSIG && ops[0].Data != nil &&
+		!isPubKey(ops[0].Data) && (len(ops[0].Data) == 33 ||
+		len(ops[0].Data) == 65) {
+
+		add(SeverityBurn, InvalidPubKey, "pay-to-pubkey script has an "+
+			"invalid public key")
+	}
+
+	// OP_M <pubkey>... OP_N OP_CHECKMULTISIG with bad keys or counts.
+	if len(ops) >= 3 && ops[len(ops)-1].Op == OP_CHECKMULTISIG {
+		if _, _, ok := multiSigParams(ops); !ok {
+			add(SeverityBurn, InvalidPubKey, "multi-signature script "+
+				"has invalid keys or signature counts")
+		}
+	}
+
+	return findings
+}
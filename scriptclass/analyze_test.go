@@ -0,0 +1,152 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package scriptclass_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/scriptclass"
+)
+
+// TestAnalyze ensures burn and unspendable patterns are reported and standard
+// scripts pass cleanly.
+func TestAnalyze(t *testing.T) {
+	tests := []struct {
+		name     string
+		script   string
+		amount   btcutil.Amount
+		clean    bool
+		severity scriptclass.Severity
+		code     scriptclass.FindingCode
+	}{
+		{name: "p2pkh", script: "76a914" + testHash160 + "88ac", amount: 1e8, clean: true},
+		{name: "p2wpkh", script: "0014" + testHash160, amount: 1e8, clean: true},
+		{
+			name:     "p2pkh to zero hash",
+			script:   "76a914" + testHash20 + "88ac",
+			amount:   1,
+			severity: scriptclass.SeverityBurn,
+			code:     scriptclass.KnownBurnAddress,
+		},
+		{
+			name:     "p2wpkh to zero hash",
+			script:   "0014" + testHash20,
+			amount:   1,
+			severity: scriptclass.SeverityBurn,
+			code:     scriptclass.KnownBurnAddress,
+		},
+		{name: "p2tr", script: "5120" + testHash32, amount: 1e8, clean: true},
+		{name: "p2pk", script: "21" + testPubKey + "ac", amount: 1e8, clean: true},
+		{name: "null data without value", script: "6a0401020304", clean: true},
+		{
+			name:     "null data with value",
+			script:   "6a0401020304",
+			amount:   1,
+			severity: scriptclass.SeverityBurn,
+			code:     scriptclass.NullDataWithValue,
+		},
+		{
+			name:     "OP_RETURN after push",
+			script:   "516a",
+			amount:   1,
+			severity: scriptclass.SeverityBurn,
+			code:     scriptclass.Unspendable,
+		},
+		{
+			name:     "disabled opcode",
+			script:   "51517e",
+			amount:   1,
+			severity: scriptclass.SeverityBurn,
+			code:     scriptclass.Unspendable,
+		},
+		{
+			name:     "OP_VERIF",
+			script:   "65",
+			amount:   1,
+			severity: scriptclass.SeverityBurn,
+			code:     scriptclass.Unspendable,
+		},
+		{
+			name:     "truncated push",
+			script:   "4c05",
+			amount:   1,
+			severity: scriptclass.SeverityBurn,
+			code:     scriptclass.MalformedScript,
+		},
+		{
+			name:     "oversized push",
+			script:   "4d0902" + strings.Repeat("00", 521),
+			amount:   1,
+			severity: scriptclass.SeverityBurn,
+			code:     scriptclass.OversizedPush,
+		},
+		{
+			name:     "version 0 program of 21 bytes",
+			script:   "0015" + testHash20 + "00",
+			amount:   1,
+			severity: scriptclass.SeverityBurn,
+			code:     scriptclass.WrongWitnessProgramSize,
+		},
+		{
+			name:     "version 1 program of 20 bytes",
+			script:   "5114" + testHash20,
+			amount:   1,
+			severity: scriptclass.SeverityWarning,
+			code:     scriptclass.WrongWitnessProgramSize,
+		},
+		{
+			name:     "future witness version",
+			script:   "6020" + testHash32,
+			amount:   1,
+			severity: scriptclass.SeverityWarning,
+			code:     scriptclass.FutureWitnessVersion,
+		},
+		{
+			name:     "p2pk invalid key",
+			script:   "2105" + testPubKey[2:] + "ac",
+			amount:   1,
+			severity: scriptclass.SeverityBurn,
+			code:     scriptclass.InvalidPubKey,
+		},
+		{
+			name:     "multisig m > n",
+			script:   "5221" + testPubKey + "51ae",
+			amount:   1,
+			severity: scriptclass.SeverityBurn,
+			code:     scriptclass.InvalidPubKey,
+		},
+		{
+			name:     "empty script",
+			script:   "",
+			amount:   1,
+			severity: scriptclass.SeverityWarning,
+			code:     scriptclass.EmptyScript,
+		},
+	}
+
+	for _, test := range tests {
+		findings := scriptclass.Analyze(hexToBytes(test.script), test.amount)
+		if test.clean {
+			if len(findings) != 0 {
+				t.Errorf("Analyze(%s): unexpected findings %v",
+					test.name, findings)
+			}
+			continue
+		}
+		if len(findings) != 1 {
+			t.Errorf("Analyze(%s): got %d findings, want 1: %v",
+				test.name, len(findings), findings)
+			continue
+		}
+		f := findings[0]
+		if f.Severity != test.severity || f.Code != test.code {
+			t.Errorf("Analyze(%s): got %v code %d, want %v code %d",
+				test.name, f.Severity, f.Code, test.severity,
+				test.code)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package scriptclass
+
+import (
+	"errors"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil"
+)
+
+// MaxDataCarrierSize is the largest payload of a null data script relayed by
+// nodes under the default policy.
+const MaxDataCarrierSize = 80
+
+var (
+	// ErrUnsupportedAddress describes an error where a script is requested
+	// for an address of a type no script template pays to.
+	ErrUnsupportedAddress = errors.New("unsupported address type")
+
+	// ErrTooMuchNullData describes an error where the payload of a null
+	// data script is larger than MaxDataCarrierSize.
+	ErrTooMuchNullData = errors.New("null data payload too large")
+)
+
+// PayToAddrScript returns the canonical public key script paying to addr,
+// which Classify and ExtractPkScriptAddrs map back to the address:
+//
+//   - a pay-to-pubkey address is paid with <pubkey> OP_CHECKSIG
+//   - pubkey hash, script hash and multi-signature addresses are paid with
+//     an Omega script of their network identifier, hash and pay opcode
+//   - a contract address is paid with an Omega script of its network
+//     identifier and hash, without call data
+//
+// ErrUnsupportedAddress is returned for any other address type.
+func PayToAddrScript(addr btcutil.Address) ([]byte, error) {
+	info, ok := addr.(btcutil.AddressInfo)
+	if !ok {
+		return nil, ErrUnsupportedAddress
+	}
+	var payOp byte
+	switch info.ScriptType() {
+	case btcutil.ScriptTypePubKey:
+		pubKey := addr.ScriptAddress()
+		script := make([]byte, 0, len(pubKey)+2)
+		script = append(script, byte(len(pubKey)))
+		script = append(script, pubKey...)
+		return append(script, OP_CHECKSIG), nil
+
+	case btcutil.ScriptTypePubKeyHash:
+		payOp = OP_PAY2PKH
+	case btcutil.ScriptTypeScriptHash:
+		payOp = OP_PAY2SCRIPTH
+	case btcutil.ScriptTypeMultiSig:
+		payOp = OP_PAY2MULTI
+	case btcutil.ScriptTypeContract:
+		return addr.ScriptNetAddress(), nil
+	default:
+		return nil, ErrUnsupportedAddress
+	}
+	return append(addr.ScriptNetAddress(), payOp), nil
+}
+
+// ContractCallScript returns the script of an output calling the contract at
+// addr with the passed call data, such as an ABI encoded method call.
+func ContractCallScript(addr *btcutil.AddressContract, call []byte) []byte {
+	script := make([]byte, 0, 1+omegaHashSize+len(call))
+	script = append(script, addr.ScriptNetAddress()...)
+	return append(script, call...)
+}
+
+// ContractCreationScript returns the script of an output creating a contract
+// with the passed code on the network net.
+func ContractCreationScript(code []byte, net *chaincfg.Params) []byte {
+	script := make([]byte, 1+omegaHashSize, 1+omegaHashSize+len(code))
+	script[0] = net.ContractAddrID
+	return append(script, code...)
+}
+
+// NullDataScript returns a provably unspendable script carrying data: an
+// OP_RETURN followed by a minimal push of data.  ErrTooMuchNullData is
+// returned when data is larger than MaxDataCarrierSize.
+func NullDataScript(data []byte) ([]byte, error) {
+	if len(data) > MaxDataCarrierSize {
+		return nil, ErrTooMuchNullData
+	}
+	script := make([]byte, 0, len(data)+3)
+	script = append(script, OP_RETURN)
+	return appendPush(script, data), nil
+}
+
+// appendPush appends the minimal push of data, which must be at most 255
+// bytes, to script as required by the standardness rules: small integers are
+// pushed with their opcode, and other data with the shortest push opcode for
+// its length.
+func appendPush(script, data []byte) []byte {
+	switch {
+	case len(data) == 0:
+		return append(script, OP_0)
+	case len(data) == 1 && data[0] >= 1 && data[0] <= 16:
+		return append(script, OP_1-1+data[0])
+	case len(data) == 1 && data[0] == 0x81:
+		return append(script, OP_1NEGATE)
+	case len(data) <= OP_DATA_75:
+		script = append(script, byte(len(data)))
+	default:
+		script = append(script, OP_PUSHDATA1, byte(len(data)))
+	}
+	return append(script, data...)
+}
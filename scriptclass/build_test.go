@@ -0,0 +1,134 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package scriptclass_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/scriptclass"
+)
+
+// TestPayToAddrScript ensures the script paying to each type of address is
+// classified and extracted back to the address.
+func TestPayToAddrScript(t *testing.T) {
+	net := &chaincfg.MainNetParams
+	hash := hexToBytes(testHash160)
+	pubKey, err := btcutil.NewAddressPubKey(hexToBytes(testPubKey), net)
+	if err != nil {
+		t.Fatalf("NewAddressPubKey: %v", err)
+	}
+	pkHash, err := btcutil.NewAddressPubKeyHash(hash, net)
+	if err != nil {
+		t.Fatalf("NewAddressPubKeyHash: %v", err)
+	}
+	scriptHash, err := btcutil.NewAddressScriptHashFromHash(hash, net)
+	if err != nil {
+		t.Fatalf("NewAddressScriptHashFromHash: %v", err)
+	}
+	multiSig, err := btcutil.NewAddressMultiSig(hash, net)
+	if err != nil {
+		t.Fatalf("NewAddressMultiSig: %v", err)
+	}
+	contract, err := btcutil.NewAddressContract(hash, net)
+	if err != nil {
+		t.Fatalf("NewAddressContract: %v", err)
+	}
+
+	tests := []struct {
+		addr   btcutil.Address
+		script string
+		class  scriptclass.Class
+	}{
+		{pubKey, "21" + testPubKey + "ac", scriptclass.PubKeyTy},
+		{pkHash, hex.EncodeToString([]byte{net.PubKeyHashAddrID}) +
+			testHash160 + "41", scriptclass.OmegaPubKeyHashTy},
+		{scriptHash, hex.EncodeToString([]byte{net.ScriptHashAddrID}) +
+			testHash160 + "42", scriptclass.OmegaScriptHashTy},
+		{multiSig, hex.EncodeToString([]byte{net.MultiSigAddrID}) +
+			testHash160 + "43", scriptclass.OmegaMultiSigTy},
+		{contract, hex.EncodeToString([]byte{net.ContractAddrID}) +
+			testHash160, scriptclass.ContractTy},
+	}
+	for _, test := range tests {
+		script, err := scriptclass.PayToAddrScript(test.addr)
+		if err != nil {
+			t.Errorf("PayToAddrScript(%v): %v", test.addr, err)
+			continue
+		}
+		if !bytes.Equal(script, hexToBytes(test.script)) {
+			t.Errorf("PayToAddrScript(%v): got %x, want %s", test.addr,
+				script, test.script)
+		}
+		class, addrs, _, err := scriptclass.ExtractPkScriptAddrs(script, net)
+		if err != nil || class != test.class || len(addrs) != 1 ||
+			btcutil.AddressScriptType(addrs[0]) != btcutil.AddressScriptType(test.addr) ||
+			!bytes.Equal(addrs[0].ScriptAddress(), test.addr.ScriptAddress()) {
+			t.Errorf("ExtractPkScriptAddrs(%v): got %v, %v, %v", test.addr,
+				class, addrs, err)
+		}
+	}
+
+	call := []byte{0xde, 0xad, 0xbe, 0xef}
+	script := scriptclass.ContractCallScript(contract, call)
+	out, ok := scriptclass.DecodeOmega(script)
+	if !ok || out.Class() != scriptclass.ContractTy ||
+		!bytes.Equal(out.Hash[:], hash) || !bytes.Equal(out.Data, call) {
+		t.Errorf("ContractCallScript: got %x", script)
+	}
+	script = scriptclass.ContractCreationScript(call, net)
+	out, ok = scriptclass.DecodeOmega(script)
+	if !ok || out.Class() != scriptclass.ContractCreationTy ||
+		!bytes.Equal(out.Data, call) {
+		t.Errorf("ContractCreationScript: got %x", script)
+	}
+
+	// Addresses of other packages do not describe their script.
+	foreign := struct{ btcutil.Address }{pkHash}
+	if _, err := scriptclass.PayToAddrScript(foreign); err != scriptclass.ErrUnsupportedAddress {
+		t.Errorf("PayToAddrScript: got error %v, want %v", err,
+			scriptclass.ErrUnsupportedAddress)
+	}
+}
+
+// TestNullDataScript ensures payloads are pushed minimally and oversized
+// payloads are rejected.
+func TestNullDataScript(t *testing.T) {
+	tests := []struct {
+		data   []byte
+		script string
+	}{
+		{nil, "6a00"},
+		{[]byte{0x05}, "6a55"},
+		{[]byte{0x81}, "6a4f"},
+		{[]byte{0x00}, "6a0100"},
+		{[]byte{1, 2, 3, 4}, "6a0401020304"},
+		{bytes.Repeat([]byte{0xab}, 76), "6a4c4c" +
+			hex.EncodeToString(bytes.Repeat([]byte{0xab}, 76))},
+	}
+	for _, test := range tests {
+		script, err := scriptclass.NullDataScript(test.data)
+		if err != nil || hex.EncodeToString(script) != test.script {
+			t.Errorf("NullDataScript(%x): got %x, %v, want %s", test.data,
+				script, err, test.script)
+		}
+		if class := scriptclass.Classify(script); class != scriptclass.NullDataTy {
+			t.Errorf("NullDataScript(%x): classified as %v", test.data,
+				class)
+		}
+	}
+
+	data := make([]byte, scriptclass.MaxDataCarrierSize+1)
+	if _, err := scriptclass.NullDataScript(data); err != scriptclass.ErrTooMuchNullData {
+		t.Errorf("NullDataScript: got error %v, want %v", err,
+			scriptclass.ErrTooMuchNullData)
+	}
+	if _, err := scriptclass.NullDataScript(data[1:]); err != nil {
+		t.Errorf("NullDataScript: %v", err)
+	}
+}
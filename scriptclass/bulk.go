@@ -0,0 +1,157 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package scriptclass
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil"
+)
+
+// bulkChunkSize is the number of scripts a worker of ExtractPkScriptAddrsBulk
+// claims at a time.  Chunks amortize the synchronization of workers over many
+// scripts while keeping them balanced when scripts are of uneven cost.
+const bulkChunkSize = 256
+
+// AddrResult is the outcome of extracting the addresses of a public key
+// script, as returned by ExtractPkScriptAddrs.
+type AddrResult struct {
+	Class   Class
+	Addrs   []btcutil.Address
+	ReqSigs int
+	Err     error
+}
+
+// BulkOption configures ExtractPkScriptAddrsBulk.
+type BulkOption func(*bulkConfig)
+
+// bulkConfig holds the configuration of ExtractPkScriptAddrsBulk.
+type bulkConfig struct {
+	workers int
+}
+
+// WithWorkers sets the number of goroutines extracting addresses.  The
+// number of CPUs usable by the process is used by default.
+func WithWorkers(n int) BulkOption {
+	return func(c *bulkConfig) {
+		c.workers = n
+	}
+}
+
+// ExtractPkScriptAddrsBulk extracts the addresses of many public key scripts
+// on the network net, as ExtractPkScriptAddrs does for each of them, using a
+// pool of workers.  The result at each index is the one of the script at the
+// same index, and a script failing does not affect the others.
+//
+// The common pay-to-pubkey-hash and pay-to-script-hash scripts and Omega
+// pay-to-pubkey-hash scripts of net are matched directly, without
+// classifying or decoding them, which makes converting the outputs of whole
+// chains much faster.
+func ExtractPkScriptAddrsBulk(pkScripts [][]byte, net *chaincfg.Params,
+	opts ...BulkOption) []AddrResult {
+	cfg := bulkConfig{workers: runtime.GOMAXPROCS(0)}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	results := make([]AddrResult, len(pkScripts))
+
+	extract := func(start, end int) {
+		for i := start; i < end; i++ {
+			r := &results[i]
+			if extractFast(pkScripts[i], net, r) {
+				continue
+			}
+			r.Class, r.Addrs, r.ReqSigs, r.Err =
+				ExtractPkScriptAddrs(pkScripts[i], net)
+		}
+	}
+
+	chunks := (len(pkScripts) + bulkChunkSize - 1) / bulkChunkSize
+	workers := cfg.workers
+	if workers > chunks {
+		workers = chunks
+	}
+	if workers <= 1 {
+		extract(0, len(pkScripts))
+		return results
+	}
+
+	var (
+		next int64
+		wg   sync.WaitGroup
+	)
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for {
+				chunk := int(atomic.AddInt64(&next, 1) - 1)
+				if chunk >= chunks {
+					return
+				}
+				start := chunk * bulkChunkSize
+				end := start + bulkChunkSize
+				if end > len(pkScripts) {
+					end = len(pkScripts)
+				}
+				extract(start, end)
+			}
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// extractFast sets the result of the most common scripts paying to a single
+// address, exactly as ExtractPkScriptAddrs would.  It returns false for any
+// other script.
+func extractFast(script []byte, net *chaincfg.Params, r *AddrResult) bool {
+	var (
+		addr btcutil.Address
+		err  error
+	)
+	switch {
+	// OP_DUP OP_HASH160 <20 bytes> OP_EQUALVERIFY OP_CHECKSIG
+	case len(script) == 25 && script[0] == OP_DUP &&
+		script[1] == OP_HASH160 && script[2] == OP_DATA_20 &&
+		script[23] == OP_EQUALVERIFY && script[24] == OP_CHECKSIG:
+		r.Class = PubKeyHashTy
+		addr, err = btcutil.NewAddressPubKeyHash(script[3:23], net)
+
+	// OP_HASH160 <20 bytes> OP_EQUAL
+	case len(script) == 23 && script[0] == OP_HASH160 &&
+		script[1] == OP_DATA_20 && script[22] == OP_EQUAL:
+		r.Class = ScriptHashTy
+		addr, err = btcutil.NewAddressScriptHashFromHash(script[2:22], net)
+
+	// <net pubkey hash ID> <20 bytes> OP_PAY2PKH, which Classify matches
+	// as a witness program when the ID and the first byte of the hash
+	// happen to form one.
+	case len(script) == omegaPayScriptLen &&
+		script[0] == net.PubKeyHashAddrID &&
+		script[omegaPayScriptLen-1] == OP_PAY2PKH &&
+		!chaincfg.IsContractAddrID(script[0]) &&
+		chaincfg.IsPubKeyHashAddrID(script[0]):
+		if _, _, ok := ExtractWitnessProgram(script); ok {
+			return false
+		}
+		r.Class = OmegaPubKeyHashTy
+		addr, err = btcutil.NewAddressPubKeyHash(script[1:1+omegaHashSize],
+			net)
+
+	default:
+		return false
+	}
+
+	if err != nil {
+		r.Err = err
+		return true
+	}
+	r.Addrs, r.ReqSigs = []btcutil.Address{addr}, 1
+	return true
+}
@@ -0,0 +1,70 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package scriptclass_test
+
+import (
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil/scriptclass"
+)
+
+// TestExtractPkScriptAddrsBulk ensures the bulk extraction, including its
+// fast paths, gives the results of ExtractPkScriptAddrs for every script.
+func TestExtractPkScriptAddrsBulk(t *testing.T) {
+	net := &chaincfg.MainNetParams
+	testNet := &chaincfg.TestNet3Params
+	kinds := [][]byte{
+		hexToBytes("76a914" + testHash160 + "88ac"),
+		hexToBytes("a914" + testHash160 + "87"),
+		omegaScript(net.PubKeyHashAddrID, testHash160, scriptclass.OP_PAY2PKH),
+		omegaScript(net.PubKeyHashAddrID, "14"+testHash160[2:],
+			scriptclass.OP_PAY2PKH),
+		omegaScript(net.ScriptHashAddrID, testHash160,
+			scriptclass.OP_PAY2SCRIPTH),
+		omegaScript(testNet.PubKeyHashAddrID, testHash160,
+			scriptclass.OP_PAY2PKH),
+		hexToBytes("21" + testPubKey + "ac"),
+		hexToBytes("0014" + testHash160),
+		hexToBytes("6a0401020304"),
+		hexToBytes("51"),
+		nil,
+	}
+
+	// Enough scripts for several chunks of work.
+	scripts := make([][]byte, 0, 1000*len(kinds))
+	for i := 0; i < 1000; i++ {
+		scripts = append(scripts, kinds...)
+	}
+
+	for _, workers := range []int{1, 4} {
+		results := scriptclass.ExtractPkScriptAddrsBulk(scripts, net,
+			scriptclass.WithWorkers(workers))
+		if len(results) != len(scripts) {
+			t.Fatalf("%d workers: got %d results, want %d", workers,
+				len(results), len(scripts))
+		}
+		for i, r := range results {
+			class, addrs, reqSigs, err := scriptclass.ExtractPkScriptAddrs(
+				scripts[i], net)
+			if r.Class != class || r.ReqSigs != reqSigs || r.Err != err ||
+				len(r.Addrs) != len(addrs) {
+				t.Fatalf("%d workers: script %d: got %v, want %v %v "+
+					"%d %v", workers, i, r, class, addrs, reqSigs, err)
+			}
+			for j := range addrs {
+				if r.Addrs[j].EncodeAddress() != addrs[j].EncodeAddress() {
+					t.Fatalf("%d workers: script %d: got address %v, "+
+						"want %v", workers, i, r.Addrs[j], addrs[j])
+				}
+			}
+		}
+	}
+
+	if results := scriptclass.ExtractPkScriptAddrsBulk(nil, net); len(results) != 0 {
+		t.Errorf("ExtractPkScriptAddrsBulk: got %d results for no scripts",
+			len(results))
+	}
+}
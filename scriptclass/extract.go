@@ -0,0 +1,120 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package scriptclass
+
+import (
+	"errors"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil"
+)
+
+// ErrWrongNetwork describes an error where an Omega script pays to an address
+// whose network identifier is not that of the network the addresses are
+// extracted for.
+var ErrWrongNetwork = errors.New("script pays an address of another network")
+
+// ExtractPkScriptAddrs returns the class of the passed public key script along
+// with the addresses it pays on the network net and the number of signatures
+// required to spend it, like the function of the same name of the script
+// engine.
+//
+// No addresses are returned for scripts which do not pay to an address, such
+// as null data scripts, contract creations and non-standard scripts, nor for
+// witness programs since this module has no witness address types.  Public
+// keys of pay-to-pubkey and bare multi-signature scripts which do not parse
+// are skipped.  The number of required signatures is zero when the script
+// can't be spent by signatures, or when the script does not tell, as for
+// Omega multi-signature hashes and contracts.
+//
+// ErrWrongNetwork is returned for an Omega script paying to an address of
+// another network than net.
+func ExtractPkScriptAddrs(pkScript []byte,
+	net *chaincfg.Params) (Class, []btcutil.Address, int, error) {
+	class := Classify(pkScript)
+	switch class {
+	case PubKeyTy:
+		var addrs []btcutil.Address
+		addr, err := btcutil.NewAddressPubKey(pkScript[1:len(pkScript)-1], net)
+		if err == nil {
+			addrs = append(addrs, addr)
+		}
+		return class, addrs, 1, nil
+
+	case PubKeyHashTy:
+		addr, err := btcutil.NewAddressPubKeyHash(pkScript[3:23], net)
+		if err != nil {
+			return class, nil, 0, err
+		}
+		return class, []btcutil.Address{addr}, 1, nil
+
+	case ScriptHashTy:
+		addr, err := btcutil.NewAddressScriptHashFromHash(pkScript[2:22], net)
+		if err != nil {
+			return class, nil, 0, err
+		}
+		return class, []btcutil.Address{addr}, 1, nil
+
+	case WitnessV0PubKeyHashTy, WitnessV0ScriptHashTy, WitnessV1TaprootTy:
+		return class, nil, 1, nil
+
+	case MultiSigTy:
+		ops, _ := Parse(pkScript)
+		reqSigs, pubKeys, _ := multiSigParams(ops)
+		var addrs []btcutil.Address
+		for _, pubKey := range pubKeys {
+			addr, err := btcutil.NewAddressPubKey(pubKey, net)
+			if err == nil {
+				addrs = append(addrs, addr)
+			}
+		}
+		return class, addrs, reqSigs, nil
+
+	case OmegaPubKeyHashTy, OmegaScriptHashTy, OmegaMultiSigTy, ContractTy:
+		out, _ := DecodeOmega(pkScript)
+		addr, reqSigs, err := out.address(net)
+		if err != nil {
+			return class, nil, 0, err
+		}
+		return class, []btcutil.Address{addr}, reqSigs, nil
+	}
+	return class, nil, 0, nil
+}
+
+// address returns the address paid by the script on the network net and the
+// number of signatures required to spend it.  The script must pay to an
+// address.
+func (s *OmegaScript) address(net *chaincfg.Params) (btcutil.Address, int, error) {
+	switch s.Class() {
+	case OmegaPubKeyHashTy:
+		if s.NetID != net.PubKeyHashAddrID {
+			return nil, 0, ErrWrongNetwork
+		}
+		addr, err := btcutil.NewAddressPubKeyHash(s.Hash[:], net)
+		return addr, 1, err
+
+	case OmegaScriptHashTy:
+		if s.NetID != net.ScriptHashAddrID {
+			return nil, 0, ErrWrongNetwork
+		}
+		addr, err := btcutil.NewAddressScriptHashFromHash(s.Hash[:], net)
+		return addr, 1, err
+
+	case OmegaMultiSigTy:
+		if s.NetID != net.MultiSigAddrID {
+			return nil, 0, ErrWrongNetwork
+		}
+		addr, err := btcutil.NewAddressMultiSig(s.Hash[:], net)
+		return addr, 0, err
+
+	case ContractTy:
+		if s.NetID != net.ContractAddrID {
+			return nil, 0, ErrWrongNetwork
+		}
+		addr, err := btcutil.NewAddressContract(s.Hash[:], net)
+		return addr, 0, err
+	}
+	return nil, 0, ErrWrongNetwork
+}
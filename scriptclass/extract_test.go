@@ -0,0 +1,161 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package scriptclass_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil/scriptclass"
+)
+
+// TestExtractPkScriptAddrs ensures the addresses and required signatures of
+// each class of script are extracted.
+func TestExtractPkScriptAddrs(t *testing.T) {
+	net := &chaincfg.MainNetParams
+	code := []byte{0x01, 0x02, 0x03}
+
+	tests := []struct {
+		name    string
+		script  []byte
+		class   scriptclass.Class
+		addrs   [][]byte
+		reqSigs int
+	}{
+		{
+			name:    "p2pk",
+			script:  hexToBytes("21" + testPubKey + "ac"),
+			class:   scriptclass.PubKeyTy,
+			addrs:   [][]byte{hexToBytes(testPubKey)},
+			reqSigs: 1,
+		},
+		{
+			name:    "p2pkh",
+			script:  hexToBytes("76a914" + testHash160 + "88ac"),
+			class:   scriptclass.PubKeyHashTy,
+			addrs:   [][]byte{hexToBytes(testHash160)},
+			reqSigs: 1,
+		},
+		{
+			name:    "p2sh",
+			script:  hexToBytes("a914" + testHash160 + "87"),
+			class:   scriptclass.ScriptHashTy,
+			addrs:   [][]byte{hexToBytes(testHash160)},
+			reqSigs: 1,
+		},
+		{
+			name:    "p2wpkh",
+			script:  hexToBytes("0014" + testHash160),
+			class:   scriptclass.WitnessV0PubKeyHashTy,
+			reqSigs: 1,
+		},
+		{
+			name:    "p2tr",
+			script:  hexToBytes("5120" + testHash32),
+			class:   scriptclass.WitnessV1TaprootTy,
+			reqSigs: 1,
+		},
+		{
+			name:   "witness v2",
+			script: hexToBytes("5210" + testHash32[:32]),
+			class:  scriptclass.WitnessUnknownTy,
+		},
+		{
+			name:    "1-of-2 multisig",
+			script:  hexToBytes("5121" + testPubKey + "21" + testPubKey + "52ae"),
+			class:   scriptclass.MultiSigTy,
+			addrs:   [][]byte{hexToBytes(testPubKey), hexToBytes(testPubKey)},
+			reqSigs: 1,
+		},
+		{
+			name:   "null data",
+			script: hexToBytes("6a0401020304"),
+			class:  scriptclass.NullDataTy,
+		},
+		{
+			name:   "nonstandard",
+			script: hexToBytes("51"),
+			class:  scriptclass.NonStandardTy,
+		},
+		{
+			name: "omega p2pkh",
+			script: omegaScript(net.PubKeyHashAddrID, testHash160,
+				scriptclass.OP_PAY2PKH),
+			class:   scriptclass.OmegaPubKeyHashTy,
+			addrs:   [][]byte{hexToBytes(testHash160)},
+			reqSigs: 1,
+		},
+		{
+			name: "omega p2sh",
+			script: omegaScript(net.ScriptHashAddrID, testHash160,
+				scriptclass.OP_PAY2SCRIPTH),
+			class:   scriptclass.OmegaScriptHashTy,
+			addrs:   [][]byte{hexToBytes(testHash160)},
+			reqSigs: 1,
+		},
+		{
+			name: "omega multisig",
+			script: omegaScript(net.MultiSigAddrID, testHash160,
+				scriptclass.OP_PAY2MULTI),
+			class: scriptclass.OmegaMultiSigTy,
+			addrs: [][]byte{hexToBytes(testHash160)},
+		},
+		{
+			name: "pay2none",
+			script: omegaScript(net.PubKeyHashAddrID, testHash160,
+				scriptclass.OP_PAY2NONE),
+			class: scriptclass.PayToNoneTy,
+		},
+		{
+			name:   "contract call",
+			script: omegaScript(net.ContractAddrID, testHash160, code...),
+			class:  scriptclass.ContractTy,
+			addrs:  [][]byte{hexToBytes(testHash160)},
+		},
+		{
+			name:   "contract creation",
+			script: omegaScript(net.ContractAddrID, testHash20, code...),
+			class:  scriptclass.ContractCreationTy,
+		},
+	}
+
+	for _, test := range tests {
+		class, addrs, reqSigs, err := scriptclass.ExtractPkScriptAddrs(
+			test.script, net)
+		if err != nil {
+			t.Errorf("%s: unexpected error %v", test.name, err)
+			continue
+		}
+		if class != test.class || reqSigs != test.reqSigs {
+			t.Errorf("%s: got class %v with %d signatures, want %v "+
+				"with %d", test.name, class, reqSigs, test.class,
+				test.reqSigs)
+		}
+		if len(addrs) != len(test.addrs) {
+			t.Errorf("%s: got %d addresses, want %d", test.name,
+				len(addrs), len(test.addrs))
+			continue
+		}
+		for i, addr := range addrs {
+			if !addr.IsForNet(net) ||
+				!bytes.Equal(addr.ScriptAddress(), test.addrs[i]) {
+				t.Errorf("%s: address %d: got %v", test.name, i, addr)
+			}
+		}
+	}
+
+	// Omega scripts paying addresses of another network are rejected.
+	testNet := &chaincfg.TestNet3Params
+	script := omegaScript(testNet.PubKeyHashAddrID, testHash160,
+		scriptclass.OP_PAY2PKH)
+	if testNet.PubKeyHashAddrID != net.PubKeyHashAddrID {
+		_, _, _, err := scriptclass.ExtractPkScriptAddrs(script, net)
+		if err != scriptclass.ErrWrongNetwork {
+			t.Errorf("ExtractPkScriptAddrs: got error %v, want %v", err,
+				scriptclass.ErrWrongNetwork)
+		}
+	}
+}
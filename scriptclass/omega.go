@@ -0,0 +1,100 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package scriptclass
+
+import (
+	"github.com/zeusyf/btcd/chaincfg"
+)
+
+const (
+	// omegaHashSize is the size of the address hash of an Omega script.
+	omegaHashSize = 20
+
+	// omegaPayScriptLen is the length of an Omega script paying to an
+	// address: the network identifier, the address hash and the pay
+	// opcode.
+	omegaPayScriptLen = 1 + omegaHashSize + 1
+)
+
+// OmegaScript is the decoded form of an Omega public key script, which names
+// the address paid to by its network identifier and hash rather than with
+// opcodes.
+type OmegaScript struct {
+	// NetID is the network identifier of the address, which selects its
+	// kind.
+	NetID byte
+
+	// Hash is the hash of the address.  It is all zero for contract
+	// creations.
+	Hash [omegaHashSize]byte
+
+	// PayOp is the pay opcode selecting how the output is spent.  It is
+	// zero for contracts, whose outputs are governed by the contract.
+	PayOp byte
+
+	// Data is the remainder of a contract script, such as the code of a
+	// contract being created.  It refers to the decoded script.
+	Data []byte
+}
+
+// DecodeOmega decodes an Omega public key script.  It returns false for any
+// other script, including scripts whose network identifier is not registered
+// with chaincfg.
+func DecodeOmega(script []byte) (*OmegaScript, bool) {
+	if len(script) < 1+omegaHashSize {
+		return nil, false
+	}
+	out := &OmegaScript{NetID: script[0]}
+	copy(out.Hash[:], script[1:1+omegaHashSize])
+
+	if chaincfg.IsContractAddrID(out.NetID) {
+		out.Data = script[1+omegaHashSize:]
+		return out, true
+	}
+
+	if len(script) != omegaPayScriptLen {
+		return nil, false
+	}
+	out.PayOp = script[omegaPayScriptLen-1]
+	switch out.PayOp {
+	case OP_PAY2PKH, OP_PAY2SCRIPTH, OP_PAY2MULTI, OP_PAY2NONE:
+	default:
+		return nil, false
+	}
+	if !chaincfg.IsPubKeyHashAddrID(out.NetID) &&
+		!chaincfg.IsScriptHashAddrID(out.NetID) &&
+		!chaincfg.IsMultiSigAddrID(out.NetID) {
+		return nil, false
+	}
+	return out, true
+}
+
+// IsContractCreation returns whether the script creates a new contract, which
+// is a payment to a contract whose hash is all zero.
+func (s *OmegaScript) IsContractCreation() bool {
+	return chaincfg.IsContractAddrID(s.NetID) &&
+		s.Hash == [omegaHashSize]byte{}
+}
+
+// Class returns the class of the script.
+func (s *OmegaScript) Class() Class {
+	if chaincfg.IsContractAddrID(s.NetID) {
+		if s.IsContractCreation() {
+			return ContractCreationTy
+		}
+		return ContractTy
+	}
+	switch s.PayOp {
+	case OP_PAY2PKH:
+		return OmegaPubKeyHashTy
+	case OP_PAY2SCRIPTH:
+		return OmegaScriptHashTy
+	case OP_PAY2MULTI:
+		return OmegaMultiSigTy
+	case OP_PAY2NONE:
+		return PayToNoneTy
+	}
+	return NonStandardTy
+}
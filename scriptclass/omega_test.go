@@ -0,0 +1,89 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package scriptclass_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil/scriptclass"
+)
+
+// omegaScript returns an Omega script paying to the address with the passed
+// network identifier and hash, followed by tail.
+func omegaScript(netID byte, hash string, tail ...byte) []byte {
+	script := append([]byte{netID}, hexToBytes(hash)...)
+	return append(script, tail...)
+}
+
+// TestClassifyOmega ensures Omega scripts are recognized and decoded.
+func TestClassifyOmega(t *testing.T) {
+	net := &chaincfg.MainNetParams
+	code := []byte{0x01, 0x02, 0x03}
+
+	tests := []struct {
+		name   string
+		script []byte
+		want   scriptclass.Class
+		payOp  byte
+	}{
+		{"pay2pkh", omegaScript(net.PubKeyHashAddrID, testHash160,
+			scriptclass.OP_PAY2PKH), scriptclass.OmegaPubKeyHashTy,
+			scriptclass.OP_PAY2PKH},
+		{"pay2scripth", omegaScript(net.ScriptHashAddrID, testHash160,
+			scriptclass.OP_PAY2SCRIPTH), scriptclass.OmegaScriptHashTy,
+			scriptclass.OP_PAY2SCRIPTH},
+		{"pay2multi", omegaScript(net.MultiSigAddrID, testHash160,
+			scriptclass.OP_PAY2MULTI), scriptclass.OmegaMultiSigTy,
+			scriptclass.OP_PAY2MULTI},
+		{"pay2none", omegaScript(net.PubKeyHashAddrID, testHash160,
+			scriptclass.OP_PAY2NONE), scriptclass.PayToNoneTy,
+			scriptclass.OP_PAY2NONE},
+		{"contract", omegaScript(net.ContractAddrID, testHash160),
+			scriptclass.ContractTy, 0},
+		{"contract call", omegaScript(net.ContractAddrID, testHash160,
+			code...), scriptclass.ContractTy, 0},
+		{"contract creation", omegaScript(net.ContractAddrID, testHash20,
+			code...), scriptclass.ContractCreationTy, 0},
+	}
+
+	for _, test := range tests {
+		if got := scriptclass.Classify(test.script); got != test.want {
+			t.Errorf("Classify(%s): got %v, want %v", test.name, got,
+				test.want)
+		}
+		out, ok := scriptclass.DecodeOmega(test.script)
+		if !ok {
+			t.Errorf("DecodeOmega(%s): not decoded", test.name)
+			continue
+		}
+		if out.NetID != test.script[0] ||
+			!bytes.Equal(out.Hash[:], test.script[1:21]) ||
+			out.PayOp != test.payOp {
+			t.Errorf("DecodeOmega(%s): got %+v", test.name, out)
+		}
+		if out.IsContractCreation() != (test.want == scriptclass.ContractCreationTy) {
+			t.Errorf("IsContractCreation(%s): got %v", test.name,
+				out.IsContractCreation())
+		}
+	}
+
+	// Scripts which only resemble Omega scripts are not decoded.
+	invalid := []struct {
+		name   string
+		script []byte
+	}{
+		{"short", omegaScript(net.PubKeyHashAddrID, testHash160)},
+		{"unknown pay op", omegaScript(net.PubKeyHashAddrID, testHash160, 0x4f)},
+		{"trailing data", omegaScript(net.PubKeyHashAddrID, testHash160,
+			scriptclass.OP_PAY2PKH, 0x00)},
+	}
+	for _, test := range invalid {
+		if _, ok := scriptclass.DecodeOmega(test.script); ok {
+			t.Errorf("DecodeOmega(%s): unexpected success", test.name)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package scriptclass
+
+// These constants are the values of the opcodes needed to recognize the
+// standard script templates.  They match the values used by the script
+// engine.
+const (
+	OP_0             = 0x00
+	OP_DATA_1        = 0x01
+	OP_DATA_20       = 0x14
+	OP_DATA_32       = 0x20
+	OP_DATA_33       = 0x21
+	OP_DATA_65       = 0x41
+	OP_DATA_75       = 0x4b
+	OP_PUSHDATA1     = 0x4c
+	OP_PUSHDATA2     = 0x4d
+	OP_PUSHDATA4     = 0x4e
+	OP_1NEGATE       = 0x4f
+	OP_1             = 0x51
+	OP_16            = 0x60
+	OP_VERIF         = 0x65
+	OP_VERNOTIF      = 0x66
+	OP_RETURN        = 0x6a
+	OP_CAT           = 0x7e
+	OP_SUBSTR        = 0x7f
+	OP_LEFT          = 0x80
+	OP_RIGHT         = 0x81
+	OP_INVERT        = 0x83
+	OP_AND           = 0x84
+	OP_OR            = 0x85
+	OP_XOR           = 0x86
+	OP_EQUAL         = 0x87
+	OP_EQUALVERIFY   = 0x88
+	OP_2MUL          = 0x8d
+	OP_2DIV          = 0x8e
+	OP_MUL           = 0x95
+	OP_DIV           = 0x96
+	OP_MOD           = 0x97
+	OP_LSHIFT        = 0x98
+	OP_RSHIFT        = 0x99
+	OP_HASH160       = 0xa9
+	OP_CHECKSIG      = 0xac
+	OP_CHECKMULTISIG = 0xae
+	OP_DUP           = 0x76
+)
+
+// These constants are the values of the flow control, hashing and lock time
+// opcodes of the contract scripts with several spending branches, such as
+// hash time locked contracts.
+const (
+	OP_IF                  = 0x63
+	OP_ELSE                = 0x67
+	OP_ENDIF               = 0x68
+	OP_DROP                = 0x75
+	OP_SIZE                = 0x82
+	OP_SHA256              = 0xa8
+	OP_CHECKLOCKTIMEVERIFY = 0xb1
+	OP_CHECKSEQUENCEVERIFY = 0xb2
+)
+
+// These constants are the pay opcodes ending Omega public key scripts, which
+// select how the output is spent.  They match the values used by the Omega
+// virtual machine.
+const (
+	OP_PAY2PKH     = 0x41
+	OP_PAY2SCRIPTH = 0x42
+	OP_PAY2MULTI   = 0x43
+	OP_PAY2NONE    = 0x44
+)
+
+// isSmallInt returns whether the opcode pushes a small integer, OP_0 through
+// OP_16, onto the stack.
+func isSmallInt(op byte) bool {
+	return op == OP_0 || (op >= OP_1 && op <= OP_16)
+}
+
+// asSmallInt returns the integer pushed by a small integer opcode.
+func asSmallInt(op byte) int {
+	if op == OP_0 {
+		return 0
+	}
+	return int(op - (OP_1 - 1))
+}
+
+// isDisabled returns whether the opcode is disabled, which makes any script
+// executing it fail.
+func isDisabled(op byte) bool {
+	switch op {
+	case OP_CAT, OP_SUBSTR, OP_LEFT, OP_RIGHT, OP_INVERT, OP_AND, OP_OR,
+		OP_XOR, OP_2MUL, OP_2DIV, OP_MUL, OP_DIV, OP_MOD, OP_LSHIFT,
+		OP_RSHIFT:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,257 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package scriptclass recognizes and builds the standard public key script
+// templates without requiring the full script engine.  It is intended for
+// software such as block explorers and wallets which only need to know what
+// kind of output they are looking at, or to pay to an address.
+package scriptclass
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+const (
+	// MaxScriptSize is the maximum allowed length of a script which can be
+	// executed.  Outputs with longer scripts can never be spent.
+	MaxScriptSize = 10000
+
+	// MaxScriptElementSize is the maximum number of bytes which can be
+	// pushed onto the stack by a single opcode.
+	MaxScriptElementSize = 520
+
+	// MaxPubKeysPerMultiSig is the maximum number of public keys allowed
+	// in a multi-signature script.
+	MaxPubKeysPerMultiSig = 20
+)
+
+// ErrMalformedPush describes an error where a script contains a data push
+// which extends beyond the end of the script.
+var ErrMalformedPush = errors.New("malformed data push")
+
+// Class identifies the template a public key script matches.
+type Class byte
+
+// These constants are the classes of scripts recognized by Classify.
+const (
+	NonStandardTy         Class = iota // None of the recognized forms.
+	PubKeyTy                           // Pay pubkey.
+	PubKeyHashTy                       // Pay pubkey hash.
+	ScriptHashTy                       // Pay to script hash.
+	WitnessV0PubKeyHashTy              // Pay to witness pubkey hash.
+	WitnessV0ScriptHashTy              // Pay to witness script hash.
+	WitnessV1TaprootTy                 // Pay to taproot output key.
+	WitnessUnknownTy                   // Witness program of unknown form.
+	MultiSigTy                         // Bare multi-signature.
+	NullDataTy                         // Provably prunable data carrier.
+	OmegaPubKeyHashTy                  // Omega pay to pubkey hash.
+	OmegaScriptHashTy                  // Omega pay to script hash.
+	OmegaMultiSigTy                    // Omega pay to multi-signature hash.
+	PayToNoneTy                        // Omega output nobody can spend.
+	ContractTy                         // Omega payment to a contract.
+	ContractCreationTy                 // Omega contract creation.
+)
+
+// classNames maps each class to its name as reported by the node RPCs.
+var classNames = map[Class]string{
+	NonStandardTy:         "nonstandard",
+	PubKeyTy:              "pubkey",
+	PubKeyHashTy:          "pubkeyhash",
+	ScriptHashTy:          "scripthash",
+	WitnessV0PubKeyHashTy: "witness_v0_keyhash",
+	WitnessV0ScriptHashTy: "witness_v0_scripthash",
+	WitnessV1TaprootTy:    "witness_v1_taproot",
+	WitnessUnknownTy:      "witness_unknown",
+	MultiSigTy:            "multisig",
+	NullDataTy:            "nulldata",
+	OmegaPubKeyHashTy:     "omega_pubkeyhash",
+	OmegaScriptHashTy:     "omega_scripthash",
+	OmegaMultiSigTy:       "omega_multisig",
+	PayToNoneTy:           "pay2none",
+	ContractTy:            "contract",
+	ContractCreationTy:    "contract_creation",
+}
+
+// String implements the fmt.Stringer interface.
+func (c Class) String() string {
+	if name, ok := classNames[c]; ok {
+		return name
+	}
+	return "invalid"
+}
+
+// Opcode is a single parsed opcode of a script along with any data it pushes.
+type Opcode struct {
+	Op   byte
+	Data []byte
+}
+
+// Parse splits a script into its opcodes.  The returned data slices refer to
+// the passed script.  ErrMalformedPush is returned, along with the opcodes
+// parsed so far, when a push extends beyond the end of the script.
+func Parse(script []byte) ([]Opcode, error) {
+	ops := make([]Opcode, 0, len(script)/2)
+	for i := 0; i < len(script); {
+		op := script[i]
+		i++
+
+		var n int
+		switch {
+		case op >= OP_DATA_1 && op <= OP_DATA_75:
+			n = int(op)
+		case op == OP_PUSHDATA1:
+			if len(script)-i < 1 {
+				return ops, ErrMalformedPush
+			}
+			n = int(script[i])
+			i++
+		case op == OP_PUSHDATA2:
+			if len(script)-i < 2 {
+				return ops, ErrMalformedPush
+			}
+			n = int(binary.LittleEndian.Uint16(script[i:]))
+			i += 2
+		case op == OP_PUSHDATA4:
+			if len(script)-i < 4 {
+				return ops, ErrMalformedPush
+			}
+			n = int(binary.LittleEndian.Uint32(script[i:]))
+			i += 4
+		default:
+			ops = append(ops, Opcode{Op: op})
+			continue
+		}
+
+		if n < 0 || len(script)-i < n {
+			return ops, ErrMalformedPush
+		}
+		ops = append(ops, Opcode{Op: op, Data: script[i : i+n]})
+		i += n
+	}
+	return ops, nil
+}
+
+// ExtractWitnessProgram returns the version and program of a witness program
+// script.  A witness program is a small integer version opcode followed by a
+// single direct push of 2 to 40 bytes.
+func ExtractWitnessProgram(script []byte) (int, []byte, bool) {
+	if len(script) < 4 || len(script) > 42 {
+		return 0, nil, false
+	}
+	if !isSmallInt(script[0]) {
+		return 0, nil, false
+	}
+	if int(script[1])+2 != len(script) {
+		return 0, nil, false
+	}
+	return asSmallInt(script[0]), script[2:], true
+}
+
+// isPubKey returns whether the data is a plausible serialized public key by
+// its length and leading format byte.
+func isPubKey(data []byte) bool {
+	switch len(data) {
+	case 33:
+		return data[0] == 0x02 || data[0] == 0x03
+	case 65:
+		return data[0] == 0x04 || data[0] == 0x06 || data[0] == 0x07
+	}
+	return false
+}
+
+// multiSigParams returns the number of required signatures and the public
+// keys of a bare multi-signature script.
+func multiSigParams(ops []Opcode) (int, [][]byte, bool) {
+	// OP_M <pubkey>... OP_N OP_CHECKMULTISIG
+	if len(ops) < 4 || ops[len(ops)-1].Op != OP_CHECKMULTISIG {
+		return 0, nil, false
+	}
+	mOp, nOp := ops[0].Op, ops[len(ops)-2].Op
+	if !isSmallInt(mOp) || !isSmallInt(nOp) {
+		return 0, nil, false
+	}
+	m, n := asSmallInt(mOp), asSmallInt(nOp)
+	keys := ops[1 : len(ops)-2]
+	if m == 0 || n != len(keys) || m > n {
+		return 0, nil, false
+	}
+	pubKeys := make([][]byte, 0, len(keys))
+	for _, key := range keys {
+		if !isPubKey(key.Data) {
+			return 0, nil, false
+		}
+		pubKeys = append(pubKeys, key.Data)
+	}
+	return m, pubKeys, true
+}
+
+// isNullData returns whether the script is an OP_RETURN followed only by data
+// pushes.
+func isNullData(ops []Opcode) bool {
+	if len(ops) == 0 || ops[0].Op != OP_RETURN {
+		return false
+	}
+	for _, op := range ops[1:] {
+		if op.Op > OP_16 {
+			return false
+		}
+	}
+	return true
+}
+
+// Classify returns the class of the passed public key script.
+func Classify(script []byte) Class {
+	if version, program, ok := ExtractWitnessProgram(script); ok {
+		switch {
+		case version == 0 && len(program) == 20:
+			return WitnessV0PubKeyHashTy
+		case version == 0 && len(program) == 32:
+			return WitnessV0ScriptHashTy
+		case version == 1 && len(program) == 32:
+			return WitnessV1TaprootTy
+		case version != 0:
+			return WitnessUnknownTy
+		}
+		return NonStandardTy
+	}
+
+	switch {
+	// OP_DUP OP_HASH160 <20 bytes> OP_EQUALVERIFY OP_CHECKSIG
+	case len(script) == 25 && script[0] == OP_DUP &&
+		script[1] == OP_HASH160 && script[2] == OP_DATA_20 &&
+		script[23] == OP_EQUALVERIFY && script[24] == OP_CHECKSIG:
+		return PubKeyHashTy
+
+	// OP_HASH160 <20 bytes> OP_EQUAL
+	case len(script) == 23 && script[0] == OP_HASH160 &&
+		script[1] == OP_DATA_20 && script[22] == OP_EQUAL:
+		return ScriptHashTy
+
+	// <33 or 65 byte pubkey> OP_CHECKSIG
+	case (len(script) == 35 || len(script) == 67) &&
+		int(script[0]) == len(script)-2 &&
+		script[len(script)-1] == OP_CHECKSIG &&
+		isPubKey(script[1:len(script)-1]):
+		return PubKeyTy
+	}
+
+	// Omega scripts lead with a network identifier rather than an opcode,
+	// so they are matched before parsing.
+	if out, ok := DecodeOmega(script); ok {
+		return out.Class()
+	}
+
+	ops, err := Parse(script)
+	if err != nil {
+		return NonStandardTy
+	}
+	if _, _, ok := multiSigParams(ops); ok {
+		return MultiSigTy
+	}
+	if isNullData(ops) {
+		return NullDataTy
+	}
+	return NonStandardTy
+}
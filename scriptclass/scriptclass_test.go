@@ -0,0 +1,99 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package scriptclass_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/zeusyf/btcutil/scriptclass"
+)
+
+// hexToBytes converts the passed hex string into bytes and will panic if
+// there is an error.  This is only provided for the hard-coded constants so
+// errors in the source code can be detected.
+func hexToBytes(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic("invalid hex in source file: " + s)
+	}
+	return b
+}
+
+// Script fragments shared by the tests.
+const (
+	testPubKey  = "0279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798"
+	testHash20  = "0000000000000000000000000000000000000000"
+	testHash160 = "751e76e8199196d454941c45d1b3a323f1433bd6"
+	testHash32  = testHash20 + "000000000000000000000000"
+)
+
+// TestClassify ensures the standard script templates are recognized.
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+		want   scriptclass.Class
+	}{
+		{"empty", "", scriptclass.NonStandardTy},
+		{"p2pkh", "76a914" + testHash20 + "88ac", scriptclass.PubKeyHashTy},
+		{"p2sh", "a914" + testHash20 + "87", scriptclass.ScriptHashTy},
+		{"p2pk", "21" + testPubKey + "ac", scriptclass.PubKeyTy},
+		{"p2pk bad key", "2105" + testPubKey[2:] + "ac", scriptclass.NonStandardTy},
+		{"p2wpkh", "0014" + testHash20, scriptclass.WitnessV0PubKeyHashTy},
+		{"p2wsh", "0020" + testHash32, scriptclass.WitnessV0ScriptHashTy},
+		{"p2wsh short", "001f" + testHash32[2:], scriptclass.NonStandardTy},
+		{"p2tr", "5120" + testHash32, scriptclass.WitnessV1TaprootTy},
+		{"witness v2", "5210" + testHash32[:32], scriptclass.WitnessUnknownTy},
+		{"1-of-1 multisig", "5121" + testPubKey + "51ae", scriptclass.MultiSigTy},
+		{"2-of-1 multisig", "5221" + testPubKey + "51ae", scriptclass.NonStandardTy},
+		{"null data", "6a0401020304", scriptclass.NullDataTy},
+		{"bare OP_RETURN", "6a", scriptclass.NullDataTy},
+		{"OP_RETURN OP_CHECKSIG", "6aac", scriptclass.NonStandardTy},
+		{"truncated push", "6a4c", scriptclass.NonStandardTy},
+	}
+
+	for _, test := range tests {
+		got := scriptclass.Classify(hexToBytes(test.script))
+		if got != test.want {
+			t.Errorf("Classify(%s): got %v, want %v", test.name, got,
+				test.want)
+		}
+	}
+}
+
+// TestParse ensures scripts are split into opcodes and truncated pushes are
+// detected.
+func TestParse(t *testing.T) {
+	ops, err := scriptclass.Parse(hexToBytes("004c02aabb4d0100cc51ac"))
+	if err != nil {
+		t.Fatalf("Parse: unexpected error: %v", err)
+	}
+	want := []scriptclass.Opcode{
+		{Op: scriptclass.OP_0},
+		{Op: scriptclass.OP_PUSHDATA1, Data: []byte{0xaa, 0xbb}},
+		{Op: scriptclass.OP_PUSHDATA2, Data: []byte{0xcc}},
+		{Op: scriptclass.OP_1},
+		{Op: scriptclass.OP_CHECKSIG},
+	}
+	if len(ops) != len(want) {
+		t.Fatalf("Parse: got %d opcodes, want %d", len(ops), len(want))
+	}
+	for i := range want {
+		if ops[i].Op != want[i].Op || !bytes.Equal(ops[i].Data, want[i].Data) {
+			t.Errorf("Parse: opcode %d: got %+v, want %+v", i, ops[i],
+				want[i])
+		}
+	}
+
+	for _, s := range []string{"01", "4c", "4c01", "4d01", "4d0100", "4e01000000"} {
+		_, err := scriptclass.Parse(hexToBytes(s))
+		if err != scriptclass.ErrMalformedPush {
+			t.Errorf("Parse(%s): got %v, want %v", s, err,
+				scriptclass.ErrMalformedPush)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package scriptindex
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/hashing"
+	"github.com/zeusyf/btcutil/scriptclass"
+)
+
+// ErrInvalidScriptHash describes an error where a script hash is not 64
+// hexadecimal characters.
+var ErrInvalidScriptHash = errors.New("invalid script hash")
+
+// HashAddress returns the hash of the public key script paying addr.
+func HashAddress(addr btcutil.Address) (ScriptHash, error) {
+	pkScript, err := scriptclass.PayToAddrScript(addr)
+	if err != nil {
+		return ScriptHash{}, err
+	}
+	return HashScript(pkScript), nil
+}
+
+// ElectrumString returns the hash as Electrum protocol servers key scripts,
+// the hex string of its reversed bytes.  It is the script hash clients pass
+// to blockchain.scripthash.subscribe and the other scripthash methods.
+func (h ScriptHash) ElectrumString() string {
+	var buf [2 * len(h)]byte
+	hashing.EncodeReversedHex(buf[:], h[:])
+	return string(buf[:])
+}
+
+// ParseElectrumScriptHash parses a script hash in the form of ElectrumString.
+func ParseElectrumScriptHash(s string) (ScriptHash, error) {
+	var h ScriptHash
+	if len(s) != 2*len(h) {
+		return h, ErrInvalidScriptHash
+	}
+	if _, err := hashing.DecodeReversedHex(h[:], []byte(s)); err != nil {
+		return h, ErrInvalidScriptHash
+	}
+	return h, nil
+}
+
+// HistoryItem is a transaction of the history of a script, as the Electrum
+// protocol lists it.
+type HistoryItem struct {
+	TxHash chainhash.Hash
+
+	// Height is the height of the block of the transaction, zero for a
+	// mempool transaction whose inputs are all confirmed, and -1 for one
+	// spending unconfirmed outputs.
+	Height int32
+}
+
+// ElectrumStatus returns the status of a script with the passed history, as
+// sent to clients subscribed to it: the hex string of the SHA256 of the
+// concatenation of "txhash:height:" for every transaction in order, the
+// confirmed ones by height and then the mempool ones.  The empty string is
+// returned for a script without history, whose status Electrum sends as
+// null.
+func ElectrumStatus(history []HistoryItem) string {
+	if len(history) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	for _, item := range history {
+		fmt.Fprintf(&b, "%s:%d:", item.TxHash, item.Height)
+	}
+	status := sha256.Sum256([]byte(b.String()))
+	return hex.EncodeToString(status[:])
+}
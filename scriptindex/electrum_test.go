@@ -0,0 +1,68 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package scriptindex_test
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/scriptclass"
+	"github.com/zeusyf/btcutil/scriptindex"
+)
+
+// TestElectrumScriptHash ensures script hashes convert to and from the
+// reversed hex of the Electrum protocol.
+func TestElectrumScriptHash(t *testing.T) {
+	// The example script of the Electrum protocol documentation.
+	pkScript, _ := hex.DecodeString(
+		"76a91462e907b15cbf27d5425399ebf6f0fb50ebb88f1888ac")
+	const want = "8b01df4e368ea28f8dc0423bcf7a4923e3a12d307c875e47a0cfbf90b5c39161"
+
+	h := scriptindex.HashScript(pkScript)
+	if got := h.ElectrumString(); got != want {
+		t.Errorf("ElectrumString: got %s, want %s", got, want)
+	}
+	parsed, err := scriptindex.ParseElectrumScriptHash(want)
+	if err != nil || parsed != h {
+		t.Errorf("ParseElectrumScriptHash: got %v, %v", parsed, err)
+	}
+	for _, s := range []string{want[2:], want[:62] + "zz"} {
+		_, err := scriptindex.ParseElectrumScriptHash(s)
+		if err != scriptindex.ErrInvalidScriptHash {
+			t.Errorf("ParseElectrumScriptHash(%s): got error %v, want %v",
+				s, err, scriptindex.ErrInvalidScriptHash)
+		}
+	}
+
+	addr, err := btcutil.NewAddressPubKeyHash(make([]byte, 20),
+		&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("NewAddressPubKeyHash: unexpected error: %v", err)
+	}
+	addrScript, _ := scriptclass.PayToAddrScript(addr)
+	got, err := scriptindex.HashAddress(addr)
+	if err != nil || got != scriptindex.HashScript(addrScript) {
+		t.Errorf("HashAddress: got %v, %v", got, err)
+	}
+}
+
+// TestElectrumStatus ensures the status is the hash of the history, and
+// empty without history.
+func TestElectrumStatus(t *testing.T) {
+	history := []scriptindex.HistoryItem{
+		{TxHash: chainhash.Hash{0x01}, Height: 100},
+		{TxHash: chainhash.Hash{0x02}, Height: 0},
+	}
+	const want = "4672c55abd57a4a28de156837d8a70844a409f2bd04079f1ec8879e1e02daccb"
+	if got := scriptindex.ElectrumStatus(history); got != want {
+		t.Errorf("ElectrumStatus: got %s, want %s", got, want)
+	}
+	if got := scriptindex.ElectrumStatus(nil); got != "" {
+		t.Errorf("ElectrumStatus: got %q for no history", got)
+	}
+}
@@ -0,0 +1,232 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package scriptindex builds an index of the outputs of a chain by the hash
+// of their public key script, for lightweight balance lookups.
+//
+// Blocks are added in order of height.  The index keeps the unspent outputs
+// of the chain so spends can be matched to the script they spend from, and
+// an entry for every script holding the address it pays, the balance of its
+// unspent outputs and its activity.  Indexes are written to snapshots which
+// can be restored to continue indexing, or opened read-only and memory mapped
+// so lookups don't need the index in memory.
+//
+// The hash keying the index is the script hash of the Electrum protocol,
+// whose servers write it as the hex string of its reversed bytes, so the
+// index can back Electrum compatible servers.
+package scriptindex
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sync"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/scriptclass"
+	"github.com/zeusyf/omega/token"
+)
+
+// ErrBlockOrder describes an error where a block is added to an index at a
+// height not above that of the last block added.
+var ErrBlockOrder = errors.New("block is not above the index height")
+
+// ScriptHash is the SHA256 hash of a public key script, which keys the index.
+type ScriptHash [sha256.Size]byte
+
+// HashScript returns the hash of pkScript.
+func HashScript(pkScript []byte) ScriptHash {
+	return sha256.Sum256(pkScript)
+}
+
+// String returns the hash as a hex string.
+func (h ScriptHash) String() string {
+	return hex.EncodeToString(h[:])
+}
+
+// Entry is what the index knows of a script.
+type Entry struct {
+	// Address is the encoded address the script pays, or empty when it
+	// pays no address.
+	Address string
+
+	// Balance holds the amounts of the unspent outputs of the script.
+	Balance btcutil.Balance
+
+	// Unspent is the number of unspent outputs of the script, including
+	// those of hash tokens, which have no amount.
+	Unspent uint32
+
+	// TxCount is the number of transactions paying to or spending from
+	// the script.
+	TxCount uint32
+
+	// FirstHeight and LastHeight are the heights of the first and last
+	// blocks holding such a transaction.
+	FirstHeight int32
+	LastHeight  int32
+}
+
+// clone returns a copy of the entry which shares nothing with it.
+func (e *Entry) clone() Entry {
+	c := *e
+	c.Balance = e.Balance.Clone()
+	return c
+}
+
+// utxo is an unspent output of the index.  Outputs of hash tokens have a
+// zero amount.
+type utxo struct {
+	script    ScriptHash
+	tokenType uint64
+	amount    btcutil.Amount
+}
+
+// Index is an in-memory index of scripts.  It is safe for concurrent use.
+type Index struct {
+	net *chaincfg.Params
+
+	mtx     sync.RWMutex
+	height  int32
+	entries map[ScriptHash]*Entry
+	utxos   map[wire.OutPoint]utxo
+}
+
+// New returns an empty index of the chain of net.
+func New(net *chaincfg.Params) *Index {
+	return &Index{
+		net:     net,
+		height:  -1,
+		entries: make(map[ScriptHash]*Entry),
+		utxos:   make(map[wire.OutPoint]utxo),
+	}
+}
+
+// Height returns the height of the last block added, or -1 when none was.
+func (ix *Index) Height() int32 {
+	ix.mtx.RLock()
+	defer ix.mtx.RUnlock()
+	return ix.height
+}
+
+// Len returns the number of scripts in the index.
+func (ix *Index) Len() int {
+	ix.mtx.RLock()
+	defer ix.mtx.RUnlock()
+	return len(ix.entries)
+}
+
+// entry returns the entry of the script pkScript hashing to h, creating it
+// when the script was not seen before.
+func (ix *Index) entry(h ScriptHash, pkScript []byte) *Entry {
+	if e, ok := ix.entries[h]; ok {
+		return e
+	}
+	e := &Entry{Balance: make(btcutil.Balance)}
+	_, addrs, _, _ := scriptclass.ExtractPkScriptAddrs(pkScript, ix.net)
+	if len(addrs) > 0 {
+		e.Address = addrs[0].EncodeAddress()
+	}
+	ix.entries[h] = e
+	return e
+}
+
+// AddBlock adds the outputs of the transactions of block to the index and
+// removes those they spend.  ErrBlockOrder is returned when the height of the
+// block is not above that of the index.  Spends of outputs the index does not
+// hold, such as those of blocks not added, are ignored.
+func (ix *Index) AddBlock(block *btcutil.Block) error {
+	ix.mtx.Lock()
+	defer ix.mtx.Unlock()
+
+	height := block.Height()
+	if height <= ix.height {
+		return ErrBlockOrder
+	}
+	for _, tx := range block.Transactions() {
+		touched := make(map[ScriptHash]*Entry)
+		if !tx.IsCoinBase() {
+			for _, txIn := range tx.MsgTx().TxIn {
+				u, ok := ix.utxos[txIn.PreviousOutPoint]
+				if !ok {
+					continue
+				}
+				delete(ix.utxos, txIn.PreviousOutPoint)
+				e := ix.entries[u.script]
+				e.Balance.AddAmount(u.tokenType, -u.amount)
+				e.Unspent--
+				touched[u.script] = e
+			}
+		}
+		for i, txOut := range tx.MsgTx().TxOut {
+			if txOut.IsSeparator() {
+				continue
+			}
+			h := HashScript(txOut.PkScript)
+			e := ix.entry(h, txOut.PkScript)
+			u := utxo{script: h, tokenType: txOut.Token.TokenType}
+			if v, ok := txOut.Token.Value.(*token.NumeralVal); ok &&
+				u.tokenType&1 == 0 {
+				u.amount = btcutil.Amount(v.Val)
+				e.Balance.AddAmount(u.tokenType, u.amount)
+			}
+			op := wire.OutPoint{Hash: *tx.Hash(), Index: uint32(i)}
+			ix.utxos[op] = u
+			e.Unspent++
+			touched[h] = e
+		}
+		for _, e := range touched {
+			if e.TxCount == 0 {
+				e.FirstHeight = height
+			}
+			e.TxCount++
+			e.LastHeight = height
+		}
+	}
+	ix.height = height
+	return nil
+}
+
+// Lookup returns the entry of the script hashing to h, and whether the index
+// holds it.
+func (ix *Index) Lookup(h ScriptHash) (Entry, bool) {
+	ix.mtx.RLock()
+	defer ix.mtx.RUnlock()
+	e, ok := ix.entries[h]
+	if !ok {
+		return Entry{}, false
+	}
+	return e.clone(), true
+}
+
+// LookupScript returns the entry of pkScript, and whether the index holds it.
+func (ix *Index) LookupScript(pkScript []byte) (Entry, bool) {
+	return ix.Lookup(HashScript(pkScript))
+}
+
+// Compact removes the entries of scripts without unspent outputs, whose
+// balance is zero, and reallocates the index to release the memory they and
+// the outputs spent since held.  The activity of the scripts removed is lost,
+// and starts over should they be paid again.  It returns the number of
+// entries removed.
+func (ix *Index) Compact() int {
+	ix.mtx.Lock()
+	defer ix.mtx.Unlock()
+
+	entries := make(map[ScriptHash]*Entry, len(ix.entries))
+	for h, e := range ix.entries {
+		if e.Unspent != 0 {
+			entries[h] = e
+		}
+	}
+	utxos := make(map[wire.OutPoint]utxo, len(ix.utxos))
+	for op, u := range ix.utxos {
+		utxos[op] = u
+	}
+	removed := len(ix.entries) - len(entries)
+	ix.entries, ix.utxos = entries, utxos
+	return removed
+}
@@ -0,0 +1,163 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package scriptindex_test
+
+import (
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/scriptclass"
+	"github.com/zeusyf/btcutil/scriptindex"
+	"github.com/zeusyf/omega/token"
+)
+
+var (
+	scriptA = []byte{0x51}
+	scriptB = []byte{0x52}
+	scriptC = []byte{0x53}
+)
+
+// output returns an output paying amount of the base token to pkScript.
+func output(amount int64, pkScript []byte) *wire.TxOut {
+	return &wire.TxOut{
+		Token:    token.Token{Value: &token.NumeralVal{Val: amount}},
+		PkScript: pkScript,
+	}
+}
+
+// spend returns a transaction spending the output index of prev.
+func spend(prev *wire.MsgTx, index uint32, outs ...*wire.TxOut) *wire.MsgTx {
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: wire.OutPoint{Hash: prev.TxHash(), Index: index},
+	})
+	for _, out := range outs {
+		tx.AddTxOut(out)
+	}
+	return tx
+}
+
+// coinbase returns a coinbase transaction paying outs.
+func coinbase(outs ...*wire.TxOut) *wire.MsgTx {
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: wire.OutPoint{Hash: chainhash.Hash{},
+			Index: wire.MaxPrevOutIndex},
+	})
+	for _, out := range outs {
+		tx.AddTxOut(out)
+	}
+	return tx
+}
+
+// block returns the block at height holding txs.
+func block(height int32, txs ...*wire.MsgTx) *btcutil.Block {
+	b := btcutil.NewBlock(&wire.MsgBlock{Transactions: txs})
+	b.SetHeight(height)
+	return b
+}
+
+// testChain returns the blocks of a short chain, whose first block pays 50
+// to script A and 10 to script B, and whose second block spends the output of
+// A to pay 30 to B and 20 to C and, in another transaction, that payment to C
+// to pay a hash token and 20 to C.
+func testChain() []*btcutil.Block {
+	cb := coinbase(output(50, scriptA), output(10, scriptB))
+	tx1 := spend(cb, 0, output(30, scriptB), output(20, scriptC))
+	hashOut := &wire.TxOut{
+		Token:    token.Token{TokenType: 1, Value: &token.HashVal{}},
+		PkScript: scriptC,
+	}
+	tx2 := spend(tx1, 1, hashOut, output(20, scriptC))
+	return []*btcutil.Block{block(1, cb), block(2, coinbase(), tx1, tx2)}
+}
+
+// testIndex returns an index of testChain.
+func testIndex(t *testing.T) *scriptindex.Index {
+	t.Helper()
+	ix := scriptindex.New(&chaincfg.MainNetParams)
+	for _, b := range testChain() {
+		if err := ix.AddBlock(b); err != nil {
+			t.Fatalf("AddBlock: unexpected error: %v", err)
+		}
+	}
+	return ix
+}
+
+// checkEntry ensures lookup returns the wanted entry of pkScript.
+func checkEntry(t *testing.T, lookup func([]byte) (scriptindex.Entry, bool),
+	pkScript []byte, balance btcutil.Amount, unspent, txCount uint32,
+	first, last int32) {
+	t.Helper()
+	e, ok := lookup(pkScript)
+	if !ok {
+		t.Fatalf("Lookup(%x): no entry", pkScript)
+	}
+	if e.Balance.Amount(0) != balance || e.Unspent != unspent ||
+		e.TxCount != txCount || e.FirstHeight != first ||
+		e.LastHeight != last {
+		t.Errorf("Lookup(%x): got %+v, want balance %v, %d unspent, "+
+			"%d txs, heights %d-%d", pkScript, e, balance, unspent,
+			txCount, first, last)
+	}
+}
+
+// TestIndex ensures the index tracks the balance and activity of scripts.
+func TestIndex(t *testing.T) {
+	ix := testIndex(t)
+	if ix.Height() != 2 || ix.Len() != 3 {
+		t.Errorf("AddBlock: got height %d and %d entries, want 2 and 3",
+			ix.Height(), ix.Len())
+	}
+	checkEntry(t, ix.LookupScript, scriptA, 0, 0, 2, 1, 2)
+	checkEntry(t, ix.LookupScript, scriptB, 40, 2, 2, 1, 2)
+	checkEntry(t, ix.LookupScript, scriptC, 20, 2, 2, 2, 2)
+
+	e, _ := ix.LookupScript(scriptB)
+	e.Balance.AddAmount(0, 1)
+	checkEntry(t, ix.LookupScript, scriptB, 40, 2, 2, 1, 2)
+
+	if _, ok := ix.LookupScript([]byte{0x54}); ok {
+		t.Errorf("LookupScript: unexpected entry of an unknown script")
+	}
+	if err := ix.AddBlock(block(2)); err != scriptindex.ErrBlockOrder {
+		t.Errorf("AddBlock: got error %v, want %v", err,
+			scriptindex.ErrBlockOrder)
+	}
+
+	if removed := ix.Compact(); removed != 1 || ix.Len() != 2 {
+		t.Errorf("Compact: removed %d, %d left, want 1 and 2", removed,
+			ix.Len())
+	}
+	if _, ok := ix.LookupScript(scriptA); ok {
+		t.Errorf("Compact: spent script A was kept")
+	}
+	checkEntry(t, ix.LookupScript, scriptB, 40, 2, 2, 1, 2)
+}
+
+// TestIndexAddress ensures entries hold the address their script pays.
+func TestIndexAddress(t *testing.T) {
+	net := &chaincfg.MainNetParams
+	addr, err := btcutil.NewAddressPubKeyHash(make([]byte, 20), net)
+	if err != nil {
+		t.Fatalf("NewAddressPubKeyHash: unexpected error: %v", err)
+	}
+	pkScript, err := scriptclass.PayToAddrScript(addr)
+	if err != nil {
+		t.Fatalf("PayToAddrScript: unexpected error: %v", err)
+	}
+	ix := scriptindex.New(net)
+	if err := ix.AddBlock(block(0, coinbase(output(1, pkScript)))); err != nil {
+		t.Fatalf("AddBlock: unexpected error: %v", err)
+	}
+	e, ok := ix.Lookup(scriptindex.HashScript(pkScript))
+	if !ok || e.Address != addr.EncodeAddress() {
+		t.Errorf("Lookup: got address %q, want %q", e.Address,
+			addr.EncodeAddress())
+	}
+}
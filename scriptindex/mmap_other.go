@@ -0,0 +1,28 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+//go:build !darwin && !freebsd && !linux && !netbsd && !openbsd
+
+package scriptindex
+
+import (
+	"io"
+	"os"
+)
+
+// mapFile reads the size bytes of f, since files can't be memory mapped on
+// this platform.
+func mapFile(f *os.File, size int) ([]byte, error) {
+	b := make([]byte, size)
+	if _, err := io.ReadFull(f, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// unmapFile does nothing, leaving memory read by mapFile to the garbage
+// collector.
+func unmapFile(b []byte) error {
+	return nil
+}
@@ -0,0 +1,23 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+//go:build darwin || freebsd || linux || netbsd || openbsd
+
+package scriptindex
+
+import (
+	"os"
+	"syscall"
+)
+
+// mapFile maps the size bytes of f read-only into memory.
+func mapFile(f *os.File, size int) ([]byte, error) {
+	return syscall.Mmap(int(f.Fd()), 0, size, syscall.PROT_READ,
+		syscall.MAP_SHARED)
+}
+
+// unmapFile unmaps memory mapped by mapFile.
+func unmapFile(b []byte) error {
+	return syscall.Munmap(b)
+}
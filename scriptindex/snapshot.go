@@ -0,0 +1,345 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package scriptindex
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"sort"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcd/wire/common"
+	"github.com/zeusyf/btcutil"
+)
+
+// SnapshotVersion is the version of the snapshot format written by
+// WriteSnapshot.
+const SnapshotVersion = 1
+
+// The layout of a snapshot.  The header holds the magic bytes, the version,
+// the height, the number of entries, the length of the entry data and the
+// number of unspent outputs.  It is followed by the index of entries sorted
+// by script hash, each with the offset and length of its data, then the data
+// of the entries and last the unspent outputs.  Integers are little endian.
+const (
+	headerLen      = 4 + 4 + 4 + 8 + 8 + 8
+	indexRecordLen = sha256.Size + 8 + 4
+	utxoRecordLen  = chainhash.HashSize + 4 + sha256.Size + 8 + 8
+)
+
+// snapshotMagic starts every snapshot.
+var snapshotMagic = [4]byte{'S', 'I', 'D', 'X'}
+
+// ErrInvalidSnapshot describes an error where a snapshot is truncated, has an
+// unknown version or holds malformed entries.
+var ErrInvalidSnapshot = errors.New("invalid index snapshot")
+
+// encodeEntry returns the serialization of e: the address, the number of
+// unspent outputs and transactions, the first and last heights and the
+// balance.
+func encodeEntry(e *Entry) ([]byte, error) {
+	var buf bytes.Buffer
+	common.WriteVarInt(&buf, 0, uint64(len(e.Address)))
+	buf.WriteString(e.Address)
+	common.WriteVarInt(&buf, 0, uint64(e.Unspent))
+	common.WriteVarInt(&buf, 0, uint64(e.TxCount))
+	var heights [8]byte
+	binary.LittleEndian.PutUint32(heights[:4], uint32(e.FirstHeight))
+	binary.LittleEndian.PutUint32(heights[4:], uint32(e.LastHeight))
+	buf.Write(heights[:])
+	balance, err := e.Balance.MarshalBinary()
+	if err != nil {
+		return nil, err
+	}
+	buf.Write(balance)
+	return buf.Bytes(), nil
+}
+
+// decodeEntry parses an entry serialized by encodeEntry.
+func decodeEntry(b []byte) (*Entry, error) {
+	r := bytes.NewReader(b)
+	n, err := common.ReadVarInt(r, 0)
+	if err != nil || n > uint64(r.Len()) {
+		return nil, ErrInvalidSnapshot
+	}
+	addr := make([]byte, n)
+	r.Read(addr)
+	unspent, err := common.ReadVarInt(r, 0)
+	if err != nil || unspent > 1<<32-1 {
+		return nil, ErrInvalidSnapshot
+	}
+	txCount, err := common.ReadVarInt(r, 0)
+	if err != nil || txCount > 1<<32-1 {
+		return nil, ErrInvalidSnapshot
+	}
+	var heights [8]byte
+	if _, err := io.ReadFull(r, heights[:]); err != nil {
+		return nil, ErrInvalidSnapshot
+	}
+	e := &Entry{
+		Address:     string(addr),
+		Unspent:     uint32(unspent),
+		TxCount:     uint32(txCount),
+		FirstHeight: int32(binary.LittleEndian.Uint32(heights[:4])),
+		LastHeight:  int32(binary.LittleEndian.Uint32(heights[4:])),
+	}
+	if err := e.Balance.UnmarshalBinary(b[len(b)-r.Len():]); err != nil {
+		return nil, ErrInvalidSnapshot
+	}
+	return e, nil
+}
+
+// WriteSnapshot writes the index to w, to be restored by ReadSnapshot or
+// opened by OpenSnapshot.  Indexes holding the same data always write the
+// same snapshot.
+func (ix *Index) WriteSnapshot(w io.Writer) error {
+	ix.mtx.RLock()
+	defer ix.mtx.RUnlock()
+
+	hashes := make([]ScriptHash, 0, len(ix.entries))
+	for h := range ix.entries {
+		hashes = append(hashes, h)
+	}
+	sort.Slice(hashes, func(i, j int) bool {
+		return bytes.Compare(hashes[i][:], hashes[j][:]) < 0
+	})
+	ops := make([]wire.OutPoint, 0, len(ix.utxos))
+	for op := range ix.utxos {
+		ops = append(ops, op)
+	}
+	sort.Slice(ops, func(i, j int) bool {
+		if c := bytes.Compare(ops[i].Hash[:], ops[j].Hash[:]); c != 0 {
+			return c < 0
+		}
+		return ops[i].Index < ops[j].Index
+	})
+
+	index := make([]byte, 0, len(hashes)*indexRecordLen)
+	var data []byte
+	for _, h := range hashes {
+		b, err := encodeEntry(ix.entries[h])
+		if err != nil {
+			return err
+		}
+		index = append(index, h[:]...)
+		index = binary.LittleEndian.AppendUint64(index, uint64(len(data)))
+		index = binary.LittleEndian.AppendUint32(index, uint32(len(b)))
+		data = append(data, b...)
+	}
+
+	header := make([]byte, 0, headerLen)
+	header = append(header, snapshotMagic[:]...)
+	header = binary.LittleEndian.AppendUint32(header, SnapshotVersion)
+	header = binary.LittleEndian.AppendUint32(header, uint32(ix.height))
+	header = binary.LittleEndian.AppendUint64(header, uint64(len(hashes)))
+	header = binary.LittleEndian.AppendUint64(header, uint64(len(data)))
+	header = binary.LittleEndian.AppendUint64(header, uint64(len(ops)))
+	for _, b := range [][]byte{header, index, data} {
+		if _, err := w.Write(b); err != nil {
+			return err
+		}
+	}
+
+	record := make([]byte, 0, utxoRecordLen)
+	for _, op := range ops {
+		u := ix.utxos[op]
+		record = append(record[:0], op.Hash[:]...)
+		record = binary.LittleEndian.AppendUint32(record, op.Index)
+		record = append(record, u.script[:]...)
+		record = binary.LittleEndian.AppendUint64(record, u.tokenType)
+		record = binary.LittleEndian.AppendUint64(record, uint64(u.amount))
+		if _, err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// snapshot is a parsed snapshot whose sections alias its serialization.
+type snapshot struct {
+	height int32
+	index  []byte
+	data   []byte
+	utxos  []byte
+}
+
+// parseSnapshot splits the serialization b of a snapshot into its sections,
+// checking their lengths.
+func parseSnapshot(b []byte) (*snapshot, error) {
+	if len(b) < headerLen || !bytes.Equal(b[:4], snapshotMagic[:]) ||
+		binary.LittleEndian.Uint32(b[4:]) != SnapshotVersion {
+		return nil, ErrInvalidSnapshot
+	}
+	numEntries := binary.LittleEndian.Uint64(b[12:])
+	dataLen := binary.LittleEndian.Uint64(b[20:])
+	numUTXOs := binary.LittleEndian.Uint64(b[28:])
+	rest := uint64(len(b) - headerLen)
+	if numEntries > rest/indexRecordLen || numUTXOs > rest/utxoRecordLen ||
+		dataLen > rest ||
+		numEntries*indexRecordLen+dataLen+numUTXOs*utxoRecordLen != rest {
+		return nil, ErrInvalidSnapshot
+	}
+	indexEnd := headerLen + numEntries*indexRecordLen
+	dataEnd := indexEnd + dataLen
+	return &snapshot{
+		height: int32(binary.LittleEndian.Uint32(b[8:])),
+		index:  b[headerLen:indexEnd],
+		data:   b[indexEnd:dataEnd],
+		utxos:  b[dataEnd:],
+	}, nil
+}
+
+// len returns the number of entries of the snapshot.
+func (s *snapshot) len() int {
+	return len(s.index) / indexRecordLen
+}
+
+// entry returns the hash and entry of the ith entry of the snapshot.
+func (s *snapshot) entry(i int) (ScriptHash, *Entry, error) {
+	var h ScriptHash
+	record := s.index[i*indexRecordLen:]
+	copy(h[:], record)
+	offset := binary.LittleEndian.Uint64(record[sha256.Size:])
+	length := uint64(binary.LittleEndian.Uint32(record[sha256.Size+8:]))
+	if offset > uint64(len(s.data)) || length > uint64(len(s.data))-offset {
+		return h, nil, ErrInvalidSnapshot
+	}
+	e, err := decodeEntry(s.data[offset : offset+length])
+	return h, e, err
+}
+
+// lookup returns the entry of the script hashing to h, and whether the
+// snapshot holds it.
+func (s *snapshot) lookup(h ScriptHash) (*Entry, bool, error) {
+	n := s.len()
+	i := sort.Search(n, func(i int) bool {
+		return bytes.Compare(s.index[i*indexRecordLen:][:sha256.Size],
+			h[:]) >= 0
+	})
+	if i == n || !bytes.Equal(s.index[i*indexRecordLen:][:sha256.Size], h[:]) {
+		return nil, false, nil
+	}
+	_, e, err := s.entry(i)
+	if err != nil {
+		return nil, false, err
+	}
+	return e, true, nil
+}
+
+// ReadSnapshot restores an index of the chain of net from a snapshot written
+// by WriteSnapshot.  ErrInvalidSnapshot is returned when the snapshot is
+// malformed, including when its entries are not sorted or its unspent
+// outputs pay to scripts without an entry.
+func ReadSnapshot(r io.Reader, net *chaincfg.Params) (*Index, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	s, err := parseSnapshot(b)
+	if err != nil {
+		return nil, err
+	}
+
+	ix := New(net)
+	ix.height = s.height
+	var prev ScriptHash
+	for i := 0; i < s.len(); i++ {
+		h, e, err := s.entry(i)
+		if err != nil {
+			return nil, err
+		}
+		if i > 0 && bytes.Compare(prev[:], h[:]) >= 0 {
+			return nil, ErrInvalidSnapshot
+		}
+		ix.entries[h] = e
+		prev = h
+	}
+	for rec := s.utxos; len(rec) > 0; rec = rec[utxoRecordLen:] {
+		var op wire.OutPoint
+		var u utxo
+		copy(op.Hash[:], rec)
+		op.Index = binary.LittleEndian.Uint32(rec[chainhash.HashSize:])
+		out := rec[chainhash.HashSize+4:]
+		copy(u.script[:], out)
+		u.tokenType = binary.LittleEndian.Uint64(out[sha256.Size:])
+		u.amount = btcutil.Amount(binary.LittleEndian.Uint64(out[sha256.Size+8:]))
+		if _, ok := ix.entries[u.script]; !ok {
+			return nil, ErrInvalidSnapshot
+		}
+		ix.utxos[op] = u
+	}
+	return ix, nil
+}
+
+// Snapshot is a read-only index opened from a snapshot file.  The file is
+// memory mapped where the platform allows, so entries are only decoded when
+// looked up and the index needs no memory of its own.  It is safe for
+// concurrent use until closed.
+type Snapshot struct {
+	b []byte
+	s *snapshot
+}
+
+// OpenSnapshot opens the snapshot file at path.
+func OpenSnapshot(path string) (*Snapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if fi.Size() < headerLen || int64(int(fi.Size())) != fi.Size() {
+		return nil, ErrInvalidSnapshot
+	}
+	b, err := mapFile(f, int(fi.Size()))
+	if err != nil {
+		return nil, err
+	}
+	s, err := parseSnapshot(b)
+	if err != nil {
+		unmapFile(b)
+		return nil, err
+	}
+	return &Snapshot{b: b, s: s}, nil
+}
+
+// Height returns the height of the last block added to the index.
+func (s *Snapshot) Height() int32 {
+	return s.s.height
+}
+
+// Len returns the number of scripts in the index.
+func (s *Snapshot) Len() int {
+	return s.s.len()
+}
+
+// Lookup returns the entry of the script hashing to h, and whether the index
+// holds it.  ErrInvalidSnapshot is returned when the entry is malformed.
+func (s *Snapshot) Lookup(h ScriptHash) (Entry, bool, error) {
+	e, ok, err := s.s.lookup(h)
+	if !ok {
+		return Entry{}, false, err
+	}
+	return *e, true, nil
+}
+
+// LookupScript returns the entry of pkScript as Lookup does.
+func (s *Snapshot) LookupScript(pkScript []byte) (Entry, bool, error) {
+	return s.Lookup(HashScript(pkScript))
+}
+
+// Close releases the snapshot.  It must not be used afterwards.
+func (s *Snapshot) Close() error {
+	return unmapFile(s.b)
+}
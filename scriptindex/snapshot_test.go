@@ -0,0 +1,114 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package scriptindex_test
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil/scriptindex"
+)
+
+// TestSnapshot ensures restored snapshots hold the same entries and continue
+// indexing where the index left off.
+func TestSnapshot(t *testing.T) {
+	net := &chaincfg.MainNetParams
+	chain := testChain()
+	ix := scriptindex.New(net)
+	if err := ix.AddBlock(chain[0]); err != nil {
+		t.Fatalf("AddBlock: unexpected error: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := ix.WriteSnapshot(&buf); err != nil {
+		t.Fatalf("WriteSnapshot: unexpected error: %v", err)
+	}
+
+	restored, err := scriptindex.ReadSnapshot(bytes.NewReader(buf.Bytes()), net)
+	if err != nil {
+		t.Fatalf("ReadSnapshot: unexpected error: %v", err)
+	}
+	var again bytes.Buffer
+	if err := restored.WriteSnapshot(&again); err != nil {
+		t.Fatalf("WriteSnapshot: unexpected error: %v", err)
+	}
+	if !bytes.Equal(buf.Bytes(), again.Bytes()) {
+		t.Errorf("ReadSnapshot: restored index writes another snapshot")
+	}
+
+	// The spends of the second block are matched to the outputs restored.
+	if err := restored.AddBlock(chain[1]); err != nil {
+		t.Fatalf("AddBlock: unexpected error: %v", err)
+	}
+	checkEntry(t, restored.LookupScript, scriptA, 0, 0, 2, 1, 2)
+	checkEntry(t, restored.LookupScript, scriptB, 40, 2, 2, 1, 2)
+	checkEntry(t, restored.LookupScript, scriptC, 20, 2, 2, 2, 2)
+
+	snapshot := buf.Bytes()
+	invalid := [][]byte{
+		nil,
+		snapshot[:len(snapshot)-1],
+		append([]byte("XIDX"), snapshot[4:]...),
+		append(append([]byte(nil), snapshot...), 0),
+	}
+	for i, b := range invalid {
+		_, err := scriptindex.ReadSnapshot(bytes.NewReader(b), net)
+		if err != scriptindex.ErrInvalidSnapshot {
+			t.Errorf("ReadSnapshot #%d: got error %v, want %v", i, err,
+				scriptindex.ErrInvalidSnapshot)
+		}
+	}
+}
+
+// TestOpenSnapshot ensures snapshot files are looked up without restoring
+// them.
+func TestOpenSnapshot(t *testing.T) {
+	ix := testIndex(t)
+	path := filepath.Join(t.TempDir(), "index.snapshot")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("Create: unexpected error: %v", err)
+	}
+	if err := ix.WriteSnapshot(f); err != nil {
+		t.Fatalf("WriteSnapshot: unexpected error: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("Close: unexpected error: %v", err)
+	}
+
+	s, err := scriptindex.OpenSnapshot(path)
+	if err != nil {
+		t.Fatalf("OpenSnapshot: unexpected error: %v", err)
+	}
+	defer s.Close()
+	if s.Height() != ix.Height() || s.Len() != ix.Len() {
+		t.Errorf("OpenSnapshot: got height %d and %d entries, want %d "+
+			"and %d", s.Height(), s.Len(), ix.Height(), ix.Len())
+	}
+	lookup := func(pkScript []byte) (scriptindex.Entry, bool) {
+		e, ok, err := s.LookupScript(pkScript)
+		if err != nil {
+			t.Fatalf("LookupScript: unexpected error: %v", err)
+		}
+		return e, ok
+	}
+	checkEntry(t, lookup, scriptA, 0, 0, 2, 1, 2)
+	checkEntry(t, lookup, scriptB, 40, 2, 2, 1, 2)
+	checkEntry(t, lookup, scriptC, 20, 2, 2, 2, 2)
+	if _, ok := lookup([]byte{0x54}); ok {
+		t.Errorf("LookupScript: unexpected entry of an unknown script")
+	}
+
+	empty := filepath.Join(t.TempDir(), "empty.snapshot")
+	if err := os.WriteFile(empty, nil, 0600); err != nil {
+		t.Fatalf("WriteFile: unexpected error: %v", err)
+	}
+	if _, err := scriptindex.OpenSnapshot(empty); err != scriptindex.ErrInvalidSnapshot {
+		t.Errorf("OpenSnapshot: got error %v, want %v", err,
+			scriptindex.ErrInvalidSnapshot)
+	}
+}
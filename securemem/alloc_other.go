@@ -0,0 +1,16 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+//go:build !darwin && !freebsd && !linux && !netbsd && !openbsd
+
+package securemem
+
+// alloc returns size bytes of the heap, since memory can't be locked on this
+// platform.
+func alloc(size int) ([]byte, bool) {
+	return make([]byte, size), false
+}
+
+// free does nothing, leaving memory of the heap to the garbage collector.
+func free(b []byte, locked bool) {}
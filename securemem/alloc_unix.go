@@ -0,0 +1,39 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+//go:build darwin || freebsd || linux || netbsd || openbsd
+
+package securemem
+
+import (
+	"syscall"
+)
+
+// alloc returns size bytes of anonymous memory mapped outside of the Go heap
+// and locked with mlock, or of the heap when they can't be locked.
+func alloc(size int) ([]byte, bool) {
+	if size <= 0 {
+		return make([]byte, size), false
+	}
+	b, err := syscall.Mmap(-1, 0, size, syscall.PROT_READ|syscall.PROT_WRITE,
+		syscall.MAP_ANON|syscall.MAP_PRIVATE)
+	if err != nil {
+		return make([]byte, size), false
+	}
+	if err := syscall.Mlock(b); err != nil {
+		syscall.Munmap(b)
+		return make([]byte, size), false
+	}
+	return b, true
+}
+
+// free unlocks and unmaps memory returned by alloc.  Memory of the heap is
+// left to the garbage collector.
+func free(b []byte, locked bool) {
+	if !locked {
+		return
+	}
+	syscall.Munlock(b)
+	syscall.Munmap(b)
+}
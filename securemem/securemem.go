@@ -0,0 +1,115 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package securemem provides buffers for secrets such as seeds and private
+// keys which are locked into physical memory, so the operating system never
+// writes them to swap, and zeroed when destroyed.
+//
+// Memory of the Go heap may be moved, copied and reused by the runtime, and
+// may be swapped out to disk, so a secret zeroed on the heap can survive
+// elsewhere.  A Buffer is allocated outside of the heap and locked with mlock
+// on platforms supporting it.  Locking is best effort: when the platform does
+// not support it, or the process exceeds its limit of locked memory, the
+// buffer is allocated on the heap, which Locked reports.
+//
+// A Buffer only protects the bytes it holds.  Secrets must be written to it
+// directly, and copies made while using them, such as the keys of a
+// hdkeychain.ExtendedKey created from a seed, must be cleared by their own
+// Zero methods.  The Lock methods of btcutil.WIF and hdkeychain.ExtendedKey
+// move their private keys into buffers, and LockInt the scalars of private
+// keys held by big integers.
+package securemem
+
+import (
+	"math/big"
+	"sync"
+	"unsafe"
+)
+
+// Buffer is a fixed size buffer for secrets.  A Buffer must be destroyed once
+// its secret is no longer needed, to zero it and release its memory.
+type Buffer struct {
+	mtx    sync.Mutex
+	b      []byte
+	locked bool
+}
+
+// New returns a zeroed buffer of size bytes.
+func New(size int) *Buffer {
+	b, locked := alloc(size)
+	return &Buffer{b: b, locked: locked}
+}
+
+// NewFromBytes returns a buffer holding a copy of secret, and zeroes secret.
+func NewFromBytes(secret []byte) *Buffer {
+	buf := New(len(secret))
+	copy(buf.b, secret)
+	Zero(secret)
+	return buf
+}
+
+// LockInt moves the absolute value of n into a new buffer, zeroing the words
+// it was held in, and makes n refer to the memory of the buffer, so secrets
+// such as the scalars of private keys are used in place.  n must not be
+// changed while it refers to the buffer, since operations growing it move its
+// value back to the heap, and must be cleared with n.SetBits(nil) before the
+// buffer is destroyed.
+func LockInt(n *big.Int) *Buffer {
+	words := n.Bits()
+	buf := New(len(words) * int(unsafe.Sizeof(big.Word(0))))
+	if len(words) == 0 {
+		return buf
+	}
+	locked := unsafe.Slice((*big.Word)(unsafe.Pointer(&buf.b[0])), len(words))
+	copy(locked, words)
+	for i := range words {
+		words[i] = 0
+	}
+	n.SetBits(locked)
+	return buf
+}
+
+// Bytes returns the secret held by the buffer.  The returned slice refers to
+// the memory of the buffer and must not be used after Destroy.  It is nil
+// once the buffer is destroyed.
+func (b *Buffer) Bytes() []byte {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return b.b
+}
+
+// Len returns the size of the buffer, which is zero once it is destroyed.
+func (b *Buffer) Len() int {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return len(b.b)
+}
+
+// Locked returns whether the buffer is locked into physical memory.
+func (b *Buffer) Locked() bool {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return b.locked
+}
+
+// Destroy zeroes the buffer and releases its memory.  Calling Destroy more
+// than once is safe.
+func (b *Buffer) Destroy() {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	if b.b == nil {
+		return
+	}
+	Zero(b.b)
+	free(b.b, b.locked)
+	b.b = nil
+	b.locked = false
+}
+
+// Zero sets all bytes of b to zero.
+func Zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package securemem_test
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/zeusyf/btcutil/securemem"
+)
+
+// TestBuffer ensures buffers hold their secret until destroyed.
+func TestBuffer(t *testing.T) {
+	secret := []byte("correct horse battery staple")
+	want := append([]byte(nil), secret...)
+
+	buf := securemem.NewFromBytes(secret)
+	if !bytes.Equal(buf.Bytes(), want) || buf.Len() != len(want) {
+		t.Fatalf("NewFromBytes: got %q, want %q", buf.Bytes(), want)
+	}
+	if !bytes.Equal(secret, make([]byte, len(secret))) {
+		t.Errorf("NewFromBytes: secret not zeroed")
+	}
+	t.Logf("buffer locked: %v", buf.Locked())
+
+	buf.Destroy()
+	if buf.Bytes() != nil || buf.Len() != 0 || buf.Locked() {
+		t.Errorf("Destroy: buffer still usable")
+	}
+	buf.Destroy()
+
+	empty := securemem.New(0)
+	if empty.Len() != 0 {
+		t.Errorf("New: got %d bytes, want 0", empty.Len())
+	}
+	empty.Destroy()
+
+	b := securemem.New(64)
+	if !bytes.Equal(b.Bytes(), make([]byte, 64)) {
+		t.Errorf("New: buffer is not zeroed")
+	}
+	b.Destroy()
+}
+
+// TestLockInt ensures integers moved into a buffer keep their value and use
+// its memory, and the words they were held in are zeroed.
+func TestLockInt(t *testing.T) {
+	n, _ := new(big.Int).SetString("fedcba9876543210fedcba9876543210", 16)
+	want := new(big.Int).Set(n)
+	words := n.Bits()
+
+	buf := securemem.LockInt(n)
+	if n.Cmp(want) != 0 {
+		t.Errorf("LockInt: got %x, want %x", n, want)
+	}
+	for _, word := range words {
+		if word != 0 {
+			t.Fatalf("LockInt: words of the heap not zeroed")
+		}
+	}
+	securemem.Zero(buf.Bytes())
+	for _, word := range n.Bits() {
+		if word != 0 {
+			t.Fatalf("LockInt: integer does not use the buffer")
+		}
+	}
+	n.SetBits(nil)
+	buf.Destroy()
+
+	if empty := securemem.LockInt(new(big.Int)); empty.Len() != 0 {
+		t.Errorf("LockInt: got %d bytes for zero, want 0", empty.Len())
+	}
+}
@@ -0,0 +1,134 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package btcutil
+
+import (
+	"bytes"
+	"sync"
+)
+
+// maxPooledBufferSize is the capacity above which serialization buffers are
+// not returned to the pool, so a single huge block does not pin its buffer
+// in memory for the lifetime of the process.
+const maxPooledBufferSize = 16 * 1024 * 1024
+
+// bufferPool holds the buffers handed out by PooledBytes.
+var bufferPool = sync.Pool{
+	New: func() interface{} { return new(bytes.Buffer) },
+}
+
+// PooledBuffer holds serialized bytes in a buffer borrowed from a pool shared
+// by all blocks and transactions.  Release must be called once the bytes are
+// no longer used, after which they must not be accessed, since the buffer is
+// reused for other serializations.
+type PooledBuffer struct {
+	buf *bytes.Buffer
+}
+
+// Bytes returns the serialized bytes.  They are only valid until Release is
+// called.
+func (p *PooledBuffer) Bytes() []byte {
+	if p.buf == nil {
+		return nil
+	}
+	return p.buf.Bytes()
+}
+
+// Release returns the buffer to the pool.  Calling Release more than once is
+// safe.
+func (p *PooledBuffer) Release() {
+	if p.buf == nil {
+		return
+	}
+	if p.buf.Cap() <= maxPooledBufferSize {
+		bufferPool.Put(p.buf)
+	}
+	p.buf = nil
+}
+
+// pooledBuffer returns an empty buffer from the pool with room for at least
+// size bytes.
+func pooledBuffer(size int) *bytes.Buffer {
+	buf := bufferPool.Get().(*bytes.Buffer)
+	buf.Reset()
+	buf.Grow(size)
+	return buf
+}
+
+// appendWriter is an io.Writer appending to a byte slice.
+type appendWriter struct {
+	b []byte
+}
+
+// Write appends p to the slice.  It never fails.
+func (w *appendWriter) Write(p []byte) (int, error) {
+	w.b = append(w.b, p...)
+	return len(p), nil
+}
+
+// PooledBytes returns the serialized bytes of the block in a buffer borrowed
+// from a pool, which the caller must release.  Unlike Bytes, the result is not
+// cached with the block, so serializing many blocks only once, as indexers
+// do, does not allocate a new buffer for each of them.  The bytes cached by a
+// previous call to Bytes are copied when present.
+func (b *Block) PooledBytes() (*PooledBuffer, error) {
+	if serializedBlock := b.cachedBytes(); len(serializedBlock) != 0 {
+		buf := pooledBuffer(len(serializedBlock))
+		buf.Write(serializedBlock)
+		return &PooledBuffer{buf: buf}, nil
+	}
+
+	p := &PooledBuffer{buf: pooledBuffer(b.msgBlock.SerializeSize())}
+	if err := b.msgBlock.Serialize(p.buf); err != nil {
+		p.Release()
+		return nil, err
+	}
+	return p, nil
+}
+
+// AppendBytes appends the serialized bytes of the block to dst and returns
+// the extended slice.  No allocation is made when dst has enough spare
+// capacity, so callers can reuse a single buffer for any number of blocks.
+// Like PooledBytes, the result is not cached with the block.
+func (b *Block) AppendBytes(dst []byte) ([]byte, error) {
+	if serializedBlock := b.cachedBytes(); len(serializedBlock) != 0 {
+		return append(dst, serializedBlock...), nil
+	}
+
+	w := appendWriter{b: dst}
+	if cap(dst)-len(dst) < b.msgBlock.SerializeSize() {
+		w.b = make([]byte, len(dst), len(dst)+b.msgBlock.SerializeSize())
+		copy(w.b, dst)
+	}
+	if err := b.msgBlock.Serialize(&w); err != nil {
+		return dst, err
+	}
+	return w.b, nil
+}
+
+// PooledBytes returns the serialized bytes of the transaction in a buffer
+// borrowed from a pool, which the caller must release.  See Block.PooledBytes.
+func (t *Tx) PooledBytes() (*PooledBuffer, error) {
+	p := &PooledBuffer{buf: pooledBuffer(t.msgTx.SerializeSize())}
+	if err := t.msgTx.Serialize(p.buf); err != nil {
+		p.Release()
+		return nil, err
+	}
+	return p, nil
+}
+
+// AppendBytes appends the serialized bytes of the transaction to dst and
+// returns the extended slice.  See Block.AppendBytes.
+func (t *Tx) AppendBytes(dst []byte) ([]byte, error) {
+	w := appendWriter{b: dst}
+	if cap(dst)-len(dst) < t.msgTx.SerializeSize() {
+		w.b = make([]byte, len(dst), len(dst)+t.msgTx.SerializeSize())
+		copy(w.b, dst)
+	}
+	if err := t.msgTx.Serialize(&w); err != nil {
+		return dst, err
+	}
+	return w.b, nil
+}
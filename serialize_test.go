@@ -0,0 +1,71 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package btcutil_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/zeusyf/btcutil"
+)
+
+// TestPooledSerialization ensures pooled and appended serializations of
+// blocks and transactions match their regular serialization.
+func TestPooledSerialization(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Block100000.Serialize(&buf); err != nil {
+		t.Fatalf("Serialize: %v", err)
+	}
+	want := buf.Bytes()
+
+	// Serialize a fresh block, then one with cached bytes.
+	for i, b := range []*btcutil.Block{btcutil.NewBlock(&Block100000),
+		btcutil.NewBlockFromBlockAndBytes(&Block100000, want)} {
+
+		p, err := b.PooledBytes()
+		if err != nil {
+			t.Fatalf("%d: PooledBytes: %v", i, err)
+		}
+		if !bytes.Equal(p.Bytes(), want) {
+			t.Errorf("%d: PooledBytes: mismatched bytes", i)
+		}
+		p.Release()
+		p.Release()
+		if p.Bytes() != nil {
+			t.Errorf("%d: Bytes: got bytes after Release", i)
+		}
+
+		prefix := []byte{0xaa, 0xbb}
+		got, err := b.AppendBytes(prefix)
+		if err != nil {
+			t.Fatalf("%d: AppendBytes: %v", i, err)
+		}
+		if !bytes.Equal(got[:2], prefix) || !bytes.Equal(got[2:], want) {
+			t.Errorf("%d: AppendBytes: mismatched bytes", i)
+		}
+
+		// A buffer with enough spare capacity is appended to in place.
+		dst := make([]byte, 0, len(want))
+		got, err = b.AppendBytes(dst)
+		if err != nil || &got[0] != &dst[:1][0] || !bytes.Equal(got, want) {
+			t.Errorf("%d: AppendBytes: did not reuse buffer", i)
+		}
+	}
+
+	tx := btcutil.NewTx(Block100000.Transactions[1])
+	buf.Reset()
+	if err := tx.MsgTx().Serialize(&buf); err != nil {
+		t.Fatalf("Serialize: %v", err)
+	}
+	p, err := tx.PooledBytes()
+	if err != nil || !bytes.Equal(p.Bytes(), buf.Bytes()) {
+		t.Errorf("PooledBytes: mismatched bytes, %v", err)
+	}
+	p.Release()
+	got, err := tx.AppendBytes(nil)
+	if err != nil || !bytes.Equal(got, buf.Bytes()) {
+		t.Errorf("AppendBytes: mismatched bytes, %v", err)
+	}
+}
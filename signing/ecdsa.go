@@ -0,0 +1,74 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package signing
+
+import (
+	"github.com/zeusyf/btcd/txscript"
+	"github.com/zeusyf/btcd/wire"
+)
+
+// validECDSA returns whether t is a signature hash type of ECDSA signatures,
+// which have no default type.
+func (t SigHashType) validECDSA() bool {
+	return t != SigHashDefault && t.valid()
+}
+
+// LegacySigHash returns the hash of the input at idx of tx that ECDSA
+// signatures of the input sign, the original signature hash of Bitcoin over
+// Omega transactions as the script engine of the node computes it with
+// txscript.CalcSignatureHash.  subScript is the public key script of the
+// spent output, or the redeem script of a pay-to-script-hash output.
+//
+// Unlike Bitcoin, ErrNoSingleOutput is returned for a SigHashSingle input
+// without an output at its index rather than signing a constant hash.
+func LegacySigHash(tx *wire.MsgTx, idx int, subScript []byte,
+	hashType SigHashType) ([]byte, error) {
+	if !hashType.validECDSA() {
+		return nil, ErrInvalidSigHashType
+	}
+	if idx < 0 || idx >= len(tx.TxIn) {
+		return nil, ErrInputIndex
+	}
+	if hashType&sigHashOutputMask == SigHashSingle && idx >= len(tx.TxOut) {
+		return nil, ErrNoSingleOutput
+	}
+	return txscript.CalcSignatureHash(subScript, txscript.SigHashType(hashType),
+		tx, idx)
+}
+
+// WitnessV0SigHash returns the hash of the input at idx of tx that ECDSA
+// signatures of the input sign when it spends a version 0 witness program,
+// the hash of BIP0143 as the script engine of the node computes it with
+// txscript.CalcWitnessSigHash.  scriptCode is the script executed by the input
+// and prevOut the output it spends, whose amount the hash commits to.
+// ErrNoAmount is returned when prevOut holds a token without an amount.
+//
+// Signing several inputs of tx this way hashes all its inputs and outputs for
+// each of them; a SigHashCache hashes them once.
+func WitnessV0SigHash(tx *wire.MsgTx, idx int, scriptCode []byte,
+	prevOut *wire.TxOut, hashType SigHashType) ([]byte, error) {
+	if !hashType.validECDSA() {
+		return nil, ErrInvalidSigHashType
+	}
+	if idx < 0 || idx >= len(tx.TxIn) {
+		return nil, ErrInputIndex
+	}
+	fetcher := MapPrevOutFetcher{tx.TxIn[idx].PreviousOutPoint: prevOut}
+	return NewSigHashCache(tx, fetcher).WitnessV0SigHash(idx, scriptCode,
+		hashType)
+}
+
+// SignECDSA returns the DER encoded signature of sigHash by signer followed
+// by hashType, as pushed by signature scripts.
+func SignECDSA(signer Signer, sigHash []byte, hashType SigHashType) ([]byte, error) {
+	if !hashType.validECDSA() {
+		return nil, ErrInvalidSigHashType
+	}
+	sig, err := signer.Sign(sigHash)
+	if err != nil {
+		return nil, err
+	}
+	return append(sig.Serialize(), byte(hashType)), nil
+}
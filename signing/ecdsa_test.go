@@ -0,0 +1,239 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package signing_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcd/txscript"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/signing"
+)
+
+// TestECDSASigHash tests which changes to a transaction change its legacy and
+// witness version 0 signature hashes for each hash type.
+func TestECDSASigHash(t *testing.T) {
+	subScript := []byte{0x53}
+	tests := []struct {
+		name     string
+		modify   func(tx *wire.MsgTx, prevOuts []*wire.TxOut)
+		hashType signing.SigHashType
+		legacy   bool
+		witness  bool
+	}{
+		{"unchanged", nil, signing.SigHashAll, false, false},
+		{"other input", func(tx *wire.MsgTx, _ []*wire.TxOut) {
+			tx.TxIn[1].PreviousOutPoint.Index = 7
+		}, signing.SigHashAll, true, true},
+		{"other input anyonecanpay", func(tx *wire.MsgTx, _ []*wire.TxOut) {
+			tx.TxIn[1].PreviousOutPoint.Index = 7
+		}, signing.SigHashAll | signing.SigHashAnyOneCanPay, false, false},
+		{"other sequence none", func(tx *wire.MsgTx, _ []*wire.TxOut) {
+			tx.TxIn[1].Sequence = 5
+		}, signing.SigHashNone, false, false},
+		{"own sequence none", func(tx *wire.MsgTx, _ []*wire.TxOut) {
+			tx.TxIn[0].Sequence = 5
+		}, signing.SigHashNone, true, true},
+		{"spent amount", func(_ *wire.MsgTx, prevOuts []*wire.TxOut) {
+			prevOuts[0] = omcOutput(5000, prevOuts[0].PkScript)
+		}, signing.SigHashAll, false, true},
+		{"output", func(tx *wire.MsgTx, _ []*wire.TxOut) {
+			tx.TxOut[1].PkScript = []byte{0x55}
+		}, signing.SigHashAll, true, true},
+		{"output none", func(tx *wire.MsgTx, _ []*wire.TxOut) {
+			tx.TxOut[1].PkScript = []byte{0x55}
+		}, signing.SigHashNone, false, false},
+		{"other output single", func(tx *wire.MsgTx, _ []*wire.TxOut) {
+			tx.TxOut[1].PkScript = []byte{0x55}
+		}, signing.SigHashSingle, false, false},
+		{"own output single", func(tx *wire.MsgTx, _ []*wire.TxOut) {
+			tx.TxOut[0].PkScript = []byte{0x55}
+		}, signing.SigHashSingle, true, true},
+		{"lock time", func(tx *wire.MsgTx, _ []*wire.TxOut) {
+			tx.LockTime = 100
+		}, signing.SigHashNone, true, true},
+	}
+	for _, test := range tests {
+		tx, prevOuts := sigHashTx()
+		legacy, err := signing.LegacySigHash(tx, 0, subScript, test.hashType)
+		if err != nil {
+			t.Fatalf("%s: LegacySigHash: %v", test.name, err)
+		}
+		witness, err := signing.WitnessV0SigHash(tx, 0, subScript,
+			prevOuts[0], test.hashType)
+		if err != nil {
+			t.Fatalf("%s: WitnessV0SigHash: %v", test.name, err)
+		}
+		if bytes.Equal(legacy, witness) {
+			t.Errorf("%s: legacy and witness hashes are equal", test.name)
+		}
+		if test.modify != nil {
+			test.modify(tx, prevOuts)
+		}
+
+		got, _ := signing.LegacySigHash(tx, 0, subScript, test.hashType)
+		if changed := !bytes.Equal(got, legacy); changed != test.legacy {
+			t.Errorf("%s: legacy hash changed %v, want %v", test.name,
+				changed, test.legacy)
+		}
+		got, _ = signing.WitnessV0SigHash(tx, 0, subScript, prevOuts[0],
+			test.hashType)
+		if changed := !bytes.Equal(got, witness); changed != test.witness {
+			t.Errorf("%s: witness hash changed %v, want %v", test.name,
+				changed, test.witness)
+		}
+	}
+
+	// The hashes commit to the script and the hash type.
+	tx, prevOuts := sigHashTx()
+	a, _ := signing.LegacySigHash(tx, 0, subScript, signing.SigHashAll)
+	b, _ := signing.LegacySigHash(tx, 0, []byte{0x54}, signing.SigHashAll)
+	c, _ := signing.WitnessV0SigHash(tx, 0, subScript, prevOuts[0],
+		signing.SigHashAll|signing.SigHashAnyOneCanPay)
+	d, _ := signing.WitnessV0SigHash(tx, 0, subScript, prevOuts[0],
+		signing.SigHashAll)
+	if bytes.Equal(a, b) || bytes.Equal(c, d) {
+		t.Errorf("signature hashes do not commit to the script or hash type")
+	}
+}
+
+// TestECDSASigHashErrors tests invalid signature hash requests are rejected.
+func TestECDSASigHashErrors(t *testing.T) {
+	tx, prevOuts := sigHashTx()
+	tx.TxIn = append(tx.TxIn, &wire.TxIn{})
+
+	tests := []struct {
+		name     string
+		idx      int
+		hashType signing.SigHashType
+		err      error
+	}{
+		{"default", 0, signing.SigHashDefault, signing.ErrInvalidSigHashType},
+		{"hash type", 0, 0x04, signing.ErrInvalidSigHashType},
+		{"index", 3, signing.SigHashAll, signing.ErrInputIndex},
+		{"single", 2, signing.SigHashSingle, signing.ErrNoSingleOutput},
+	}
+	for _, test := range tests {
+		_, err := signing.LegacySigHash(tx, test.idx, nil, test.hashType)
+		if err != test.err {
+			t.Errorf("%s: LegacySigHash: got %v, want %v", test.name, err,
+				test.err)
+		}
+		if test.err == signing.ErrNoSingleOutput {
+			continue
+		}
+		_, err = signing.WitnessV0SigHash(tx, test.idx, nil, prevOuts[0],
+			test.hashType)
+		if err != test.err {
+			t.Errorf("%s: WitnessV0SigHash: got %v, want %v", test.name,
+				err, test.err)
+		}
+	}
+}
+
+// TestSignECDSA ensures ECDSA signatures are followed by their hash type and
+// verify against the signature hash.
+func TestSignECDSA(t *testing.T) {
+	key, _ := btcec.PrivKeyFromBytes(btcec.S256(), []byte{0x2a})
+	signer := signing.NewKeySigner(key)
+	tx, _ := sigHashTx()
+	sigHash, err := signing.LegacySigHash(tx, 1, []byte{0x54},
+		signing.SigHashSingle)
+	if err != nil {
+		t.Fatalf("LegacySigHash: unexpected error: %v", err)
+	}
+
+	sig, err := signing.SignECDSA(signer, sigHash, signing.SigHashSingle)
+	if err != nil {
+		t.Fatalf("SignECDSA: unexpected error: %v", err)
+	}
+	if sig[len(sig)-1] != byte(signing.SigHashSingle) {
+		t.Errorf("SignECDSA: got hash type %x", sig[len(sig)-1])
+	}
+	parsed, err := btcec.ParseDERSignature(sig[:len(sig)-1], btcec.S256())
+	if err != nil || !parsed.Verify(sigHash, key.PubKey()) {
+		t.Errorf("SignECDSA: signature does not verify: %v", err)
+	}
+
+	if _, err := signing.SignECDSA(signer, sigHash, signing.SigHashDefault); err != signing.ErrInvalidSigHashType {
+		t.Errorf("SignECDSA: got error %v, want %v", err,
+			signing.ErrInvalidSigHashType)
+	}
+}
+
+// TestECDSASigHashTxScript ensures the signature hashes are those of the
+// script engine of the node, and a transaction signed with them passes it.
+func TestECDSASigHashTxScript(t *testing.T) {
+	tx, prevOuts := sigHashTx()
+	for _, hashType := range []signing.SigHashType{signing.SigHashAll,
+		signing.SigHashNone, signing.SigHashSingle | signing.SigHashAnyOneCanPay} {
+
+		got, err := signing.LegacySigHash(tx, 1, []byte{0x54}, hashType)
+		if err != nil {
+			t.Fatalf("LegacySigHash: unexpected error: %v", err)
+		}
+		want, _ := txscript.CalcSignatureHash([]byte{0x54},
+			txscript.SigHashType(hashType), tx, 1)
+		if !bytes.Equal(got, want) {
+			t.Errorf("LegacySigHash(%x): got %x, want %x", hashType, got,
+				want)
+		}
+
+		got, err = signing.WitnessV0SigHash(tx, 1, []byte{0x54},
+			prevOuts[1], hashType)
+		if err != nil {
+			t.Fatalf("WitnessV0SigHash: unexpected error: %v", err)
+		}
+		want, _ = txscript.CalcWitnessSigHash([]byte{0x54},
+			txscript.NewTxSigHashes(tx), txscript.SigHashType(hashType),
+			tx, 1, 1000)
+		if !bytes.Equal(got, want) {
+			t.Errorf("WitnessV0SigHash(%x): got %x, want %x", hashType,
+				got, want)
+		}
+	}
+
+	// A pay-to-pubkey-hash input signed with the legacy hash executes.
+	key, _ := btcec.PrivKeyFromBytes(btcec.S256(), []byte{0x2a})
+	pubKey := key.PubKey().SerializeCompressed()
+	addr, err := btcutil.NewAddressPubKeyHash(btcutil.Hash160(pubKey),
+		&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("NewAddressPubKeyHash: unexpected error: %v", err)
+	}
+	pkScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		t.Fatalf("PayToAddrScript: unexpected error: %v", err)
+	}
+	sigHash, err := signing.LegacySigHash(tx, 0, pkScript, signing.SigHashAll)
+	if err != nil {
+		t.Fatalf("LegacySigHash: unexpected error: %v", err)
+	}
+	sig, err := signing.SignECDSA(signing.NewKeySigner(key), sigHash,
+		signing.SigHashAll)
+	if err != nil {
+		t.Fatalf("SignECDSA: unexpected error: %v", err)
+	}
+	sigScript, err := txscript.NewScriptBuilder().AddData(sig).
+		AddData(pubKey).Script()
+	if err != nil {
+		t.Fatalf("Script: unexpected error: %v", err)
+	}
+	tx.TxIn[0].SignatureIndex = 0
+	tx.SignatureScripts = [][]byte{sigScript}
+
+	vm, err := txscript.NewEngine(pkScript, tx, 0,
+		txscript.StandardVerifyFlags, nil, nil, 1000)
+	if err != nil {
+		t.Fatalf("NewEngine: unexpected error: %v", err)
+	}
+	if err := vm.Execute(); err != nil {
+		t.Errorf("Execute: unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,91 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package signing
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"math/big"
+)
+
+// int2octets returns the big-endian representation of v padded to rlen bytes
+// as defined in section 2.3.3 of RFC6979.
+func int2octets(v *big.Int, rlen int) []byte {
+	out := v.Bytes()
+	if len(out) < rlen {
+		padded := make([]byte, rlen)
+		copy(padded[rlen-len(out):], out)
+		return padded
+	}
+	if len(out) > rlen {
+		return out[len(out)-rlen:]
+	}
+	return out
+}
+
+// bits2octets converts the hash to an integer modulo q and returns its octet
+// representation as defined in section 2.3.4 of RFC6979.
+func bits2octets(hash []byte, q *big.Int, rlen int) []byte {
+	z := new(big.Int).SetBytes(hash)
+	if excess := len(hash)*8 - q.BitLen(); excess > 0 {
+		z.Rsh(z, uint(excess))
+	}
+	if z.Cmp(q) >= 0 {
+		z.Sub(z, q)
+	}
+	return int2octets(z, rlen)
+}
+
+// nonceRFC6979 generates a deterministic nonce for signing hash with the
+// private scalar d on a curve of order q as described in section 3.2 of
+// RFC6979.  The optional extra data is appended to the seed material as
+// permitted by section 3.6, which allows a signer to derive alternative
+// nonces for the same key and message.  A nil extra produces the standard
+// RFC6979 nonce.
+func nonceRFC6979(d *big.Int, hash []byte, q *big.Int, extra []byte) *big.Int {
+	qlen := q.BitLen()
+	rlen := (qlen + 7) / 8
+
+	x := int2octets(d, rlen)
+	h1 := bits2octets(hash, q, rlen)
+
+	v := bytes.Repeat([]byte{0x01}, sha256.Size)
+	k := make([]byte, sha256.Size)
+
+	mac := func(key []byte, data ...[]byte) []byte {
+		m := hmac.New(sha256.New, key)
+		for _, d := range data {
+			m.Write(d)
+		}
+		return m.Sum(nil)
+	}
+
+	// Steps d through g of section 3.2.
+	k = mac(k, v, []byte{0x00}, x, h1, extra)
+	v = mac(k, v)
+	k = mac(k, v, []byte{0x01}, x, h1, extra)
+	v = mac(k, v)
+
+	// Step h of section 3.2.
+	for {
+		var t []byte
+		for len(t)*8 < qlen {
+			v = mac(k, v)
+			t = append(t, v...)
+		}
+
+		secret := new(big.Int).SetBytes(t[:rlen])
+		if excess := rlen*8 - qlen; excess > 0 {
+			secret.Rsh(secret, uint(excess))
+		}
+		if secret.Sign() > 0 && secret.Cmp(q) < 0 {
+			return secret
+		}
+
+		k = mac(k, v, []byte{0x00})
+		v = mac(k, v)
+	}
+}
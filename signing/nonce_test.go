@@ -0,0 +1,50 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package signing
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"math/big"
+	"testing"
+)
+
+// TestNonceRFC6979 ensures nonce generation matches known secp256k1 RFC6979
+// test vectors.
+func TestNonceRFC6979(t *testing.T) {
+	n, _ := new(big.Int).SetString("FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEBAAEDC"+
+		"E6AF48A03BBFD25E8CD0364141", 16)
+	tests := []struct {
+		key   string
+		msg   string
+		nonce string
+	}{
+		{
+			key:   "1",
+			msg:   "Satoshi Nakamoto",
+			nonce: "8F8A276C19F4149656B280621E358CCE24F5F52542772691EE69063B74F15D15",
+		},
+		{
+			key:   "FFFFFFFFFFFFFFFFFFFFFFFFFFFFFFFEBAAEDCE6AF48A03BBFD25E8CD0364140",
+			msg:   "Satoshi Nakamoto",
+			nonce: "33A19B60E25FB6F4435AF53A3D42D493644827367E6453928554F43E49AA6F90",
+		},
+	}
+
+	for i, test := range tests {
+		d, _ := new(big.Int).SetString(test.key, 16)
+		hash := sha256.Sum256([]byte(test.msg))
+		got := fmt.Sprintf("%064X", nonceRFC6979(d, hash[:], n, nil))
+		if got != test.nonce {
+			t.Errorf("#%d: got nonce %s, want %s", i, got, test.nonce)
+		}
+
+		// Extra data must produce a different nonce.
+		extra := nonceRFC6979(d, hash[:], n, []byte{1})
+		if fmt.Sprintf("%064X", extra) == test.nonce {
+			t.Errorf("#%d: extra data did not change the nonce", i)
+		}
+	}
+}
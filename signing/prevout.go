@@ -0,0 +1,115 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package signing
+
+import (
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/omega/token"
+)
+
+// PrevOutFetcher looks up the outputs spent by the inputs of transactions.
+// Callers keeping outputs elsewhere, such as in a database, plug it into the
+// signature hash code by implementing it, or by wrapping a lookup function in
+// a PrevOutFetcherFunc.
+type PrevOutFetcher interface {
+	// FetchPrevOutput returns the output op refers to, or nil when it is
+	// unknown.
+	FetchPrevOutput(op wire.OutPoint) *wire.TxOut
+}
+
+// PrevOutFetcherFunc is a function used as a PrevOutFetcher.
+type PrevOutFetcherFunc func(op wire.OutPoint) *wire.TxOut
+
+// Ensure PrevOutFetcherFunc implements the PrevOutFetcher interface.
+var _ PrevOutFetcher = PrevOutFetcherFunc(nil)
+
+// FetchPrevOutput returns f(op).  Part of the PrevOutFetcher interface.
+func (f PrevOutFetcherFunc) FetchPrevOutput(op wire.OutPoint) *wire.TxOut {
+	return f(op)
+}
+
+// MapPrevOutFetcher is a PrevOutFetcher looking outputs up in a map.
+type MapPrevOutFetcher map[wire.OutPoint]*wire.TxOut
+
+// Ensure MapPrevOutFetcher implements the PrevOutFetcher interface.
+var _ PrevOutFetcher = MapPrevOutFetcher(nil)
+
+// NewPrevOutFetcher returns a fetcher of prevOuts, the outputs spent by the
+// inputs of tx in their order.
+func NewPrevOutFetcher(tx *wire.MsgTx, prevOuts []*wire.TxOut) MapPrevOutFetcher {
+	m := make(MapPrevOutFetcher, len(prevOuts))
+	for i, in := range tx.TxIn {
+		if i < len(prevOuts) {
+			m[in.PreviousOutPoint] = prevOuts[i]
+		}
+	}
+	return m
+}
+
+// AddPrevOut adds the output op refers to, replacing any output it had for
+// op.
+func (m MapPrevOutFetcher) AddPrevOut(op wire.OutPoint, txOut *wire.TxOut) {
+	m[op] = txOut
+}
+
+// FetchPrevOutput returns the output op refers to, or nil when it is not in
+// the map.  Part of the PrevOutFetcher interface.
+func (m MapPrevOutFetcher) FetchPrevOutput(op wire.OutPoint) *wire.TxOut {
+	return m[op]
+}
+
+// CannedPrevOutputFetcher is a PrevOutFetcher returning the same output for
+// every outpoint.  It suits legacy and witness version 0 signature hashes,
+// which only commit to the output spent by the signed input, when nothing
+// else about the spent outputs is known.  Schnorr signature hashes commit to
+// the outputs spent by all inputs, so a canned output makes them wrong for
+// any transaction with more than one input.
+type CannedPrevOutputFetcher struct {
+	txOut wire.TxOut
+}
+
+// Ensure CannedPrevOutputFetcher implements the PrevOutFetcher interface.
+var _ PrevOutFetcher = (*CannedPrevOutputFetcher)(nil)
+
+// NewCannedPrevOutputFetcher returns a fetcher returning an output of tok
+// paying to pkScript for every outpoint.
+func NewCannedPrevOutputFetcher(pkScript []byte,
+	tok token.Token) *CannedPrevOutputFetcher {
+	return &CannedPrevOutputFetcher{
+		txOut: wire.TxOut{Token: tok, PkScript: pkScript},
+	}
+}
+
+// FetchPrevOutput returns the canned output, whatever op is.  Part of the
+// PrevOutFetcher interface.
+func (f *CannedPrevOutputFetcher) FetchPrevOutput(wire.OutPoint) *wire.TxOut {
+	return &f.txOut
+}
+
+// MultiPrevOutFetcher is a PrevOutFetcher asking each of its fetchers in turn,
+// returning the first output found.  It composes fetchers, such as a map of
+// the outputs of unconfirmed transactions in front of a database of the
+// unspent outputs of the chain.
+type MultiPrevOutFetcher []PrevOutFetcher
+
+// Ensure MultiPrevOutFetcher implements the PrevOutFetcher interface.
+var _ PrevOutFetcher = MultiPrevOutFetcher(nil)
+
+// NewMultiPrevOutFetcher returns a fetcher asking fetchers in their order.
+func NewMultiPrevOutFetcher(fetchers ...PrevOutFetcher) MultiPrevOutFetcher {
+	return MultiPrevOutFetcher(fetchers)
+}
+
+// FetchPrevOutput returns the output op refers to from the first fetcher
+// which knows it, or nil when none does.  Part of the PrevOutFetcher
+// interface.
+func (m MultiPrevOutFetcher) FetchPrevOutput(op wire.OutPoint) *wire.TxOut {
+	for _, f := range m {
+		if txOut := f.FetchPrevOutput(op); txOut != nil {
+			return txOut
+		}
+	}
+	return nil
+}
@@ -0,0 +1,63 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package signing_test
+
+import (
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil/signing"
+	"github.com/zeusyf/omega/token"
+)
+
+// TestPrevOutFetchers tests the outputs returned by each fetcher.
+func TestPrevOutFetchers(t *testing.T) {
+	tx, prevOuts := sigHashTx()
+	known := tx.TxIn[0].PreviousOutPoint
+	unknown := wire.OutPoint{Hash: chainhash.Hash{2}}
+
+	m := signing.NewPrevOutFetcher(tx, prevOuts[:1])
+	if got := m.FetchPrevOutput(known); got != prevOuts[0] {
+		t.Errorf("MapPrevOutFetcher: got %v, want %v", got, prevOuts[0])
+	}
+	if got := m.FetchPrevOutput(tx.TxIn[1].PreviousOutPoint); got != nil {
+		t.Errorf("MapPrevOutFetcher: got %v for an unknown output", got)
+	}
+	m.AddPrevOut(unknown, prevOuts[1])
+	if got := m.FetchPrevOutput(unknown); got != prevOuts[1] {
+		t.Errorf("AddPrevOut: got %v, want %v", got, prevOuts[1])
+	}
+
+	canned := signing.NewCannedPrevOutputFetcher([]byte{0x51},
+		token.Token{Value: &token.NumeralVal{Val: 5}})
+	for _, op := range []wire.OutPoint{known, unknown} {
+		got := canned.FetchPrevOutput(op)
+		if got == nil || got.PkScript[0] != 0x51 ||
+			got.Token.Value.(*token.NumeralVal).Val != 5 {
+			t.Errorf("CannedPrevOutputFetcher: got %v", got)
+		}
+	}
+
+	calls := 0
+	db := signing.PrevOutFetcherFunc(func(op wire.OutPoint) *wire.TxOut {
+		calls++
+		if op == unknown {
+			return prevOuts[0]
+		}
+		return nil
+	})
+	multi := signing.NewMultiPrevOutFetcher(
+		signing.NewPrevOutFetcher(tx, prevOuts[:1]), db)
+	if got := multi.FetchPrevOutput(known); got != prevOuts[0] || calls != 0 {
+		t.Errorf("MultiPrevOutFetcher: got %v after %d calls", got, calls)
+	}
+	if got := multi.FetchPrevOutput(unknown); got != prevOuts[0] || calls != 1 {
+		t.Errorf("MultiPrevOutFetcher: got %v after %d calls", got, calls)
+	}
+	if got := multi.FetchPrevOutput(wire.OutPoint{}); got != nil {
+		t.Errorf("MultiPrevOutFetcher: got %v for an unknown output", got)
+	}
+}
@@ -0,0 +1,161 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package signing
+
+import (
+	"math/big"
+	"time"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcutil/audit"
+	"github.com/zeusyf/btcutil/entropy"
+	"github.com/zeusyf/btcutil/taproot"
+)
+
+// SchnorrSigSize is the size of a BIP0340 signature, without the signature
+// hash type byte which follows it unless the type is SigHashDefault.
+const SchnorrSigSize = 64
+
+// Tags of the tagged hashes of BIP0340.
+const (
+	TagBIP340Aux       = "BIP0340/aux"
+	TagBIP340Nonce     = "BIP0340/nonce"
+	TagBIP340Challenge = "BIP0340/challenge"
+)
+
+// SchnorrSigner produces BIP0340 signatures over signature hashes with a
+// single key.
+type SchnorrSigner interface {
+	// PubKey returns the public key corresponding to the signing key.
+	// Signatures verify with its x-only serialization.
+	PubKey() *btcec.PublicKey
+
+	// SignSchnorr returns a BIP0340 signature over the passed 32 byte
+	// hash.
+	SignSchnorr(hash []byte) ([]byte, error)
+}
+
+// Ensure KeySigner implements the SchnorrSigner interface.
+var _ SchnorrSigner = (*KeySigner)(nil)
+
+// SignSchnorr returns a BIP0340 signature over the passed 32 byte hash.  The
+// auxiliary random data of the signature is zero, making signatures
+// deterministic, unless WithExtraEntropy is set, in which case it is read from
+// the entropy source.  Part of the SchnorrSigner interface.
+func (s *KeySigner) SignSchnorr(hash []byte) ([]byte, error) {
+	if len(hash) != 32 {
+		return nil, ErrInvalidHash
+	}
+	if s.auditSink != nil {
+		err := s.auditSink.Record(&audit.Event{
+			Time:      time.Now(),
+			Operation: audit.OpSign,
+			Origin:    s.auditOrigin,
+			Purpose:   s.auditPurpose,
+			Digest:    append([]byte(nil), hash...),
+			PubKey:    s.key.PubKey().SerializeCompressed(),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var aux [32]byte
+	if s.extraEntropy {
+		if err := entropy.Read(s.entropySrc, aux[:]); err != nil {
+			return nil, err
+		}
+	}
+	return signSchnorr(s.key, hash, aux[:]), nil
+}
+
+// signSchnorr creates a BIP0340 signature over hash with the auxiliary random
+// data aux.  Nonces are derived from the key, the hash and aux, and are zero
+// with negligible probability only, in which case the hash of the nonce is
+// retried.
+func signSchnorr(key *btcec.PrivateKey, hash, aux []byte) []byte {
+	curve := btcec.S256()
+	n := curve.Params().N
+
+	// The key is used with an even y coordinate, so the private key is
+	// negated when its public key has an odd one.
+	pub := key.PubKey()
+	d := new(big.Int).Set(key.D)
+	if pub.Y.Bit(0) == 1 {
+		d.Sub(n, d)
+	}
+	var dBytes [32]byte
+	d.FillBytes(dBytes[:])
+	pubBytes := taproot.SerializeXOnly(pub)
+
+	auxHash := taproot.TaggedHash(TagBIP340Aux, aux)
+	var t [32]byte
+	for i := range t {
+		t[i] = dBytes[i] ^ auxHash[i]
+	}
+	nonce := taproot.TaggedHash(TagBIP340Nonce, t[:], pubBytes, hash)
+	k := new(big.Int).SetBytes(nonce[:])
+	k.Mod(k, n)
+	for k.Sign() == 0 {
+		nonce = taproot.TaggedHash(TagBIP340Nonce, nonce[:])
+		k.SetBytes(nonce[:]).Mod(k, n)
+	}
+
+	rx, ry := curve.ScalarBaseMult(k.Bytes())
+	if ry.Bit(0) == 1 {
+		k.Sub(n, k)
+	}
+	var rBytes [32]byte
+	rx.FillBytes(rBytes[:])
+
+	// s = k + e*d
+	e := challenge(rBytes[:], pubBytes, hash)
+	sv := new(big.Int).Mul(e, d)
+	sv.Add(sv, k).Mod(sv, n)
+
+	sig := make([]byte, SchnorrSigSize)
+	copy(sig, rBytes[:])
+	sv.FillBytes(sig[32:])
+	return sig
+}
+
+// challenge returns the BIP0340 challenge of a signature with the passed
+// nonce point and key, both x-only, over hash.
+func challenge(r, pubKey, hash []byte) *big.Int {
+	h := taproot.TaggedHash(TagBIP340Challenge, r, pubKey, hash)
+	e := new(big.Int).SetBytes(h[:])
+	return e.Mod(e, btcec.S256().Params().N)
+}
+
+// VerifySchnorr returns whether sig is a valid BIP0340 signature over the
+// passed 32 byte hash by the public key, used with an even y coordinate as
+// implied by its x-only serialization.
+func VerifySchnorr(pubKey *btcec.PublicKey, hash, sig []byte) bool {
+	if len(hash) != 32 || len(sig) != SchnorrSigSize {
+		return false
+	}
+	curve := btcec.S256()
+	params := curve.Params()
+	pubBytes := taproot.SerializeXOnly(pubKey)
+	p, err := taproot.ParseXOnlyPubKey(pubBytes)
+	if err != nil {
+		return false
+	}
+	r := new(big.Int).SetBytes(sig[:32])
+	s := new(big.Int).SetBytes(sig[32:])
+	if r.Cmp(params.P) >= 0 || s.Cmp(params.N) >= 0 {
+		return false
+	}
+	e := challenge(sig[:32], pubBytes, hash)
+
+	// R = s*G - e*P = s*G + (n-e)*P
+	sx, sy := curve.ScalarBaseMult(s.Bytes())
+	ex, ey := curve.ScalarMult(p.X, p.Y, new(big.Int).Sub(params.N, e).Bytes())
+	rx, ry := curve.Add(sx, sy, ex, ey)
+	if rx.Sign() == 0 && ry.Sign() == 0 {
+		return false
+	}
+	return ry.Bit(0) == 0 && rx.Cmp(r) == 0
+}
@@ -0,0 +1,85 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package signing_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcutil/signing"
+)
+
+// hexToBytes converts the passed hex string into bytes and will panic if there
+// is an error.  This is only provided for the hard-coded constants so errors in
+// the source code can be detected.  It will only (and must only) be called with
+// hard-coded values.
+func hexToBytes(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic("invalid hex in source file: " + s)
+	}
+	return b
+}
+
+// TestSignSchnorr tests signatures against the vectors of BIP0340.
+func TestSignSchnorr(t *testing.T) {
+	tests := []struct {
+		key string
+		aux string
+		msg string
+		sig string
+	}{
+		{
+			key: "0000000000000000000000000000000000000000000000000000000000000003",
+			aux: "0000000000000000000000000000000000000000000000000000000000000000",
+			msg: "0000000000000000000000000000000000000000000000000000000000000000",
+			sig: "E907831F80848D1069A5371B402410364BDF1C5F8307B0084C55F1CE2DCA8215" +
+				"25F66A4A85EA8B71E482A74F382D2CE5EBEEE8FDB2172F477DF4900D310536C0",
+		},
+		{
+			key: "B7E151628AED2A6ABF7158809CF4F3C762E7160F38B4DA56A784D9045190CFEF",
+			aux: "0000000000000000000000000000000000000000000000000000000000000001",
+			msg: "243F6A8885A308D313198A2E03707344A4093822299F31D0082EFA98EC4E6C89",
+			sig: "6896BD60EEAE296DB48A229FF71DFE071BDE413E6D43F917DC8DCF8C78DE3341" +
+				"8906D11AC976ABCCB20B091292BFF4EA897EFCB639EA871CFA95F6DE339E4B0A",
+		},
+	}
+
+	for i, test := range tests {
+		key, _ := btcec.PrivKeyFromBytes(btcec.S256(), hexToBytes(test.key))
+		aux := hexToBytes(test.aux)
+		var opts []signing.Option
+		if !bytes.Equal(aux, make([]byte, 32)) {
+			opts = append(opts,
+				signing.WithExtraEntropy(bytes.NewReader(aux)))
+		}
+		signer := signing.NewKeySigner(key, opts...)
+
+		msg := hexToBytes(test.msg)
+		sig, err := signer.SignSchnorr(msg)
+		if err != nil {
+			t.Fatalf("test %d: SignSchnorr: %v", i, err)
+		}
+		if want := hexToBytes(test.sig); !bytes.Equal(sig, want) {
+			t.Errorf("test %d: got %x, want %x", i, sig, want)
+		}
+		if !signing.VerifySchnorr(signer.PubKey(), msg, sig) {
+			t.Errorf("test %d: signature does not verify", i)
+		}
+
+		msg[0] ^= 1
+		if signing.VerifySchnorr(signer.PubKey(), msg, sig) {
+			t.Errorf("test %d: signature verifies another message", i)
+		}
+	}
+
+	key, _ := btcec.PrivKeyFromBytes(btcec.S256(), []byte{1})
+	signer := signing.NewKeySigner(key)
+	if _, err := signer.SignSchnorr([]byte{1}); err != signing.ErrInvalidHash {
+		t.Errorf("short hash: got %v, want %v", err, signing.ErrInvalidHash)
+	}
+}
@@ -0,0 +1,224 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package signing
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"hash"
+	"io"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcd/wire/common"
+	"github.com/zeusyf/omega/token"
+)
+
+// TagTapSighash is the tag of the tagged hash of Schnorr signature hashes.
+const TagTapSighash = "TapSighash"
+
+// SigHashType selects the parts of a transaction a Schnorr signature commits
+// to, as in BIP0341.
+type SigHashType uint8
+
+// Signature hash types of Schnorr signatures.
+const (
+	// SigHashDefault commits to the whole transaction like SigHashAll,
+	// and is not appended to signatures, saving a byte.
+	SigHashDefault SigHashType = 0x00
+
+	SigHashAll          SigHashType = 0x01
+	SigHashNone         SigHashType = 0x02
+	SigHashSingle       SigHashType = 0x03
+	SigHashAnyOneCanPay SigHashType = 0x80
+
+	// sigHashOutputMask selects the output type of a signature hash type.
+	sigHashOutputMask SigHashType = 0x03
+)
+
+var (
+	// ErrInvalidSigHashType describes an error where a signature hash type
+	// is not one of the types defined by BIP0341.
+	ErrInvalidSigHashType = errors.New("invalid signature hash type")
+
+	// ErrInputIndex describes an error where a signature hash is computed
+	// for an input the transaction does not have.
+	ErrInputIndex = errors.New("input index out of range")
+
+	// ErrPrevOutsMismatch describes an error where the number of spent
+	// outputs passed for a signature hash does not match the number of
+	// inputs of the transaction, or where a spent output a signature hash
+	// commits to is unknown.
+	ErrPrevOutsMismatch = errors.New("spent outputs do not match inputs")
+
+	// ErrNoSingleOutput describes an error where a SigHashSingle signature
+	// hash is computed for an input without an output at its index.
+	ErrNoSingleOutput = errors.New("no output for SigHashSingle input")
+
+	// ErrNoAmount describes an error where a BIP0143 signature hash is
+	// computed for an input spending a token which holds a hash rather
+	// than an amount.
+	ErrNoAmount = errors.New("spent output holds no amount")
+
+	// ErrInvalidLeafHash describes an error where the leaf hash of a
+	// tapscript signature hash is not 32 bytes.
+	ErrInvalidLeafHash = errors.New("invalid tapscript leaf hash")
+)
+
+// valid returns whether t is a signature hash type defined by BIP0341.
+func (t SigHashType) valid() bool {
+	switch t &^ SigHashAnyOneCanPay {
+	case SigHashAll, SigHashNone, SigHashSingle:
+		return true
+	case SigHashDefault:
+		return t == SigHashDefault
+	}
+	return false
+}
+
+// writeToken writes the serialization of tok: its type, its value, and its
+// rights when it has any.
+func writeToken(w io.Writer, tok *token.Token) {
+	var b [8]byte
+	binary.LittleEndian.PutUint64(b[:], tok.TokenType)
+	w.Write(b[:])
+	switch v := tok.Value.(type) {
+	case *token.HashVal:
+		w.Write(v.Hash[:])
+	case *token.NumeralVal:
+		binary.LittleEndian.PutUint64(b[:], uint64(v.Val))
+		w.Write(b[:])
+	}
+	if tok.Rights != nil {
+		w.Write(tok.Rights[:])
+	}
+}
+
+// writeScript writes script prefixed with its length.
+func writeScript(w io.Writer, script []byte) {
+	common.WriteVarInt(w, 0, uint64(len(script)))
+	w.Write(script)
+}
+
+// writeUint32 writes v in little endian.
+func writeUint32(w io.Writer, v uint32) {
+	var b [4]byte
+	binary.LittleEndian.PutUint32(b[:], v)
+	w.Write(b[:])
+}
+
+// writeOutPoint writes the hash and index of op.
+func writeOutPoint(w io.Writer, op *wire.OutPoint) {
+	w.Write(op.Hash[:])
+	writeUint32(w, op.Index)
+}
+
+// sum returns the sha256 of the data written by write.
+func sum(write func(h hash.Hash)) []byte {
+	h := sha256.New()
+	write(h)
+	return h.Sum(nil)
+}
+
+// SchnorrSigHash returns the hash of the input at idx of tx that Schnorr
+// signatures of the input sign, computed as the key path signature hash of
+// BIP0341.  Unlike the signature hashes of ECDSA signatures, it commits to
+// the tokens and amounts of all outputs spent by the transaction, passed in
+// prevOuts in the order of the inputs, so a signer learning them from an
+// untrusted source can't be tricked into paying an unexpected fee.  Since
+// txscript has no BIP0341 signature hash, it is computed here, with the
+// tokens of Omega serialized in place of the amounts of Bitcoin, and checked
+// by VerifySchnorrInput rather than by the script engine of the node.
+//
+// Signing several inputs of tx this way hashes all its inputs and outputs for
+// each of them; a SigHashCache hashes them once.
+func SchnorrSigHash(tx *wire.MsgTx, idx int, prevOuts []*wire.TxOut,
+	hashType SigHashType) ([]byte, error) {
+	if !hashType.valid() {
+		return nil, ErrInvalidSigHashType
+	}
+	if idx < 0 || idx >= len(tx.TxIn) {
+		return nil, ErrInputIndex
+	}
+	if len(prevOuts) != len(tx.TxIn) {
+		return nil, ErrPrevOutsMismatch
+	}
+	return NewSigHashCache(tx, NewPrevOutFetcher(tx, prevOuts)).SchnorrSigHash(
+		idx, hashType)
+}
+
+// SignSchnorrInput returns the Schnorr signature of the input at idx of tx by
+// signer, followed by the signature hash type unless it is SigHashDefault.
+// prevOuts are the outputs spent by the inputs of tx, in their order.
+func SignSchnorrInput(signer SchnorrSigner, tx *wire.MsgTx, idx int,
+	prevOuts []*wire.TxOut, hashType SigHashType) ([]byte, error) {
+	sigHash, err := SchnorrSigHash(tx, idx, prevOuts, hashType)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := signer.SignSchnorr(sigHash)
+	if err != nil {
+		return nil, err
+	}
+	if hashType != SigHashDefault {
+		sig = append(sig, byte(hashType))
+	}
+	return sig, nil
+}
+
+// TapscriptSigHash returns the hash of the input at idx of tx that Schnorr
+// signatures of the script of a taproot output whose leaf hash is leafHash
+// sign, computed as the script path signature hash of BIP0342.  prevOuts are
+// the outputs spent by the inputs of tx, in their order.
+func TapscriptSigHash(tx *wire.MsgTx, idx int, prevOuts []*wire.TxOut,
+	hashType SigHashType, leafHash []byte) ([]byte, error) {
+	if len(prevOuts) != len(tx.TxIn) {
+		return nil, ErrPrevOutsMismatch
+	}
+	return NewSigHashCache(tx, NewPrevOutFetcher(tx, prevOuts)).TapscriptSigHash(
+		idx, hashType, leafHash)
+}
+
+// SignTapscriptInput returns the Schnorr signature by signer of the input at
+// idx of tx spending the script whose leaf hash is leafHash, followed by the
+// signature hash type unless it is SigHashDefault.
+func SignTapscriptInput(signer SchnorrSigner, tx *wire.MsgTx, idx int,
+	prevOuts []*wire.TxOut, hashType SigHashType, leafHash []byte) ([]byte, error) {
+	sigHash, err := TapscriptSigHash(tx, idx, prevOuts, hashType, leafHash)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := signer.SignSchnorr(sigHash)
+	if err != nil {
+		return nil, err
+	}
+	if hashType != SigHashDefault {
+		sig = append(sig, byte(hashType))
+	}
+	return sig, nil
+}
+
+// VerifySchnorrInput returns whether sig, as returned by SignSchnorrInput, is a
+// valid signature of the input at idx of tx by pubKey.
+func VerifySchnorrInput(pubKey *btcec.PublicKey, sig []byte, tx *wire.MsgTx,
+	idx int, prevOuts []*wire.TxOut) bool {
+	hashType := SigHashDefault
+	switch len(sig) {
+	case SchnorrSigSize:
+	case SchnorrSigSize + 1:
+		hashType = SigHashType(sig[SchnorrSigSize])
+		if hashType == SigHashDefault {
+			return false
+		}
+	default:
+		return false
+	}
+	sigHash, err := SchnorrSigHash(tx, idx, prevOuts, hashType)
+	if err != nil {
+		return false
+	}
+	return VerifySchnorr(pubKey, sigHash, sig[:SchnorrSigSize])
+}
@@ -0,0 +1,202 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package signing_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil/signing"
+	"github.com/zeusyf/omega/token"
+)
+
+// omcOutput returns an output paying value of OMC to pkScript.
+func omcOutput(value int64, pkScript []byte) *wire.TxOut {
+	return &wire.TxOut{
+		Token:    token.Token{Value: &token.NumeralVal{Val: value}},
+		PkScript: pkScript,
+	}
+}
+
+// sigHashTx returns a transaction with two inputs and outputs, and the
+// outputs it spends.
+func sigHashTx() (*wire.MsgTx, []*wire.TxOut) {
+	tx := wire.NewMsgTx(wire.TxVersion)
+	for i := uint32(0); i < 2; i++ {
+		tx.AddTxIn(&wire.TxIn{
+			PreviousOutPoint: wire.OutPoint{Hash: chainhash.Hash{1}, Index: i},
+			Sequence:         wire.MaxTxInSequenceNum,
+		})
+	}
+	tx.AddTxOut(omcOutput(1500, []byte{0x51}))
+	tx.AddTxOut(&wire.TxOut{
+		Token: token.Token{
+			TokenType: 4,
+			Value:     &token.NumeralVal{Val: 7},
+		},
+		PkScript: []byte{0x52},
+	})
+	prevOuts := []*wire.TxOut{
+		omcOutput(1000, []byte{0x53}),
+		omcOutput(1000, []byte{0x54}),
+	}
+	return tx, prevOuts
+}
+
+// TestSchnorrSigHash tests which changes to a transaction and its spent
+// outputs invalidate signatures of each hash type.
+func TestSchnorrSigHash(t *testing.T) {
+	key, _ := btcec.PrivKeyFromBytes(btcec.S256(), []byte{0x2a})
+	signer := signing.NewKeySigner(key)
+
+	tests := []struct {
+		name     string
+		modify   func(tx *wire.MsgTx, prevOuts []*wire.TxOut)
+		hashType signing.SigHashType
+		valid    bool
+	}{
+		{"unchanged", nil, signing.SigHashDefault, true},
+		{"spent amount", func(_ *wire.MsgTx, prevOuts []*wire.TxOut) {
+			prevOuts[1] = omcOutput(5000, prevOuts[1].PkScript)
+		}, signing.SigHashAll, false},
+		{"spent amount anyonecanpay", func(_ *wire.MsgTx, prevOuts []*wire.TxOut) {
+			prevOuts[1] = omcOutput(5000, prevOuts[1].PkScript)
+		}, signing.SigHashAll | signing.SigHashAnyOneCanPay, true},
+		{"own amount anyonecanpay", func(_ *wire.MsgTx, prevOuts []*wire.TxOut) {
+			prevOuts[0] = omcOutput(5000, prevOuts[0].PkScript)
+		}, signing.SigHashAll | signing.SigHashAnyOneCanPay, false},
+		{"spent token", func(_ *wire.MsgTx, prevOuts []*wire.TxOut) {
+			prevOuts[1].Token.TokenType = 4
+		}, signing.SigHashDefault, false},
+		{"output", func(tx *wire.MsgTx, _ []*wire.TxOut) {
+			tx.TxOut[1].PkScript = []byte{0x55}
+		}, signing.SigHashAll, false},
+		{"output none", func(tx *wire.MsgTx, _ []*wire.TxOut) {
+			tx.TxOut[1].PkScript = []byte{0x55}
+		}, signing.SigHashNone, true},
+		{"other output single", func(tx *wire.MsgTx, _ []*wire.TxOut) {
+			tx.TxOut[1].PkScript = []byte{0x55}
+		}, signing.SigHashSingle, true},
+		{"own output single", func(tx *wire.MsgTx, _ []*wire.TxOut) {
+			tx.TxOut[0].PkScript = []byte{0x55}
+		}, signing.SigHashSingle, false},
+		{"lock time", func(tx *wire.MsgTx, _ []*wire.TxOut) {
+			tx.LockTime = 100
+		}, signing.SigHashNone, false},
+	}
+	for _, test := range tests {
+		tx, prevOuts := sigHashTx()
+		sig, err := signing.SignSchnorrInput(signer, tx, 0, prevOuts,
+			test.hashType)
+		if err != nil {
+			t.Fatalf("%s: SignSchnorrInput: %v", test.name, err)
+		}
+		wantLen := signing.SchnorrSigSize + 1
+		if test.hashType == signing.SigHashDefault {
+			wantLen = signing.SchnorrSigSize
+		}
+		if len(sig) != wantLen {
+			t.Errorf("%s: got %d byte signature, want %d", test.name,
+				len(sig), wantLen)
+		}
+		if test.modify != nil {
+			test.modify(tx, prevOuts)
+		}
+		got := signing.VerifySchnorrInput(signer.PubKey(), sig, tx, 0,
+			prevOuts)
+		if got != test.valid {
+			t.Errorf("%s: got valid %v, want %v", test.name, got,
+				test.valid)
+		}
+	}
+}
+
+// TestSchnorrSigHashErrors tests invalid signature hash requests are
+// rejected.
+func TestSchnorrSigHashErrors(t *testing.T) {
+	tx, prevOuts := sigHashTx()
+	tx.TxIn = append(tx.TxIn, &wire.TxIn{})
+	prevOuts = append(prevOuts, omcOutput(1, nil))
+
+	tests := []struct {
+		name     string
+		idx      int
+		prevOuts []*wire.TxOut
+		hashType signing.SigHashType
+		err      error
+	}{
+		{"hash type", 0, prevOuts, 0x04, signing.ErrInvalidSigHashType},
+		{"default anyonecanpay", 0, prevOuts,
+			signing.SigHashAnyOneCanPay, signing.ErrInvalidSigHashType},
+		{"index", 3, prevOuts, signing.SigHashAll, signing.ErrInputIndex},
+		{"prevouts", 0, prevOuts[:2], signing.SigHashAll,
+			signing.ErrPrevOutsMismatch},
+		{"single", 2, prevOuts, signing.SigHashSingle,
+			signing.ErrNoSingleOutput},
+	}
+	for _, test := range tests {
+		_, err := signing.SchnorrSigHash(tx, test.idx, test.prevOuts,
+			test.hashType)
+		if err != test.err {
+			t.Errorf("%s: got %v, want %v", test.name, err, test.err)
+		}
+	}
+}
+
+// TestTapscriptSigHash ensures script path signature hashes commit to the
+// leaf hash and differ from the key path signature hash.
+func TestTapscriptSigHash(t *testing.T) {
+	tx, prevOuts := sigHashTx()
+	leafA := bytes.Repeat([]byte{0x01}, chainhash.HashSize)
+	leafB := bytes.Repeat([]byte{0x02}, chainhash.HashSize)
+
+	keyPath, err := signing.SchnorrSigHash(tx, 0, prevOuts, signing.SigHashAll)
+	if err != nil {
+		t.Fatalf("SchnorrSigHash: unexpected error: %v", err)
+	}
+	hashA, err := signing.TapscriptSigHash(tx, 0, prevOuts,
+		signing.SigHashAll, leafA)
+	if err != nil {
+		t.Fatalf("TapscriptSigHash: unexpected error: %v", err)
+	}
+	hashB, err := signing.TapscriptSigHash(tx, 0, prevOuts,
+		signing.SigHashAll, leafB)
+	if err != nil {
+		t.Fatalf("TapscriptSigHash: unexpected error: %v", err)
+	}
+	if bytes.Equal(hashA, keyPath) || bytes.Equal(hashA, hashB) {
+		t.Errorf("TapscriptSigHash: got %x for both %x and %x", hashA,
+			keyPath, hashB)
+	}
+
+	key, _ := btcec.PrivKeyFromBytes(btcec.S256(), []byte{0x2a})
+	signer := signing.NewKeySigner(key)
+	sig, err := signing.SignTapscriptInput(signer, tx, 0, prevOuts,
+		signing.SigHashSingle, leafA)
+	if err != nil {
+		t.Fatalf("SignTapscriptInput: unexpected error: %v", err)
+	}
+	sigHash, _ := signing.TapscriptSigHash(tx, 0, prevOuts,
+		signing.SigHashSingle, leafA)
+	if len(sig) != signing.SchnorrSigSize+1 ||
+		!signing.VerifySchnorr(key.PubKey(), sigHash, sig[:signing.SchnorrSigSize]) {
+
+		t.Errorf("SignTapscriptInput: signature does not verify")
+	}
+
+	if _, err := signing.TapscriptSigHash(tx, 0, prevOuts, signing.SigHashAll,
+		leafA[1:]); err != signing.ErrInvalidLeafHash {
+		t.Errorf("TapscriptSigHash: got error %v, want %v", err,
+			signing.ErrInvalidLeafHash)
+	}
+	if _, err := signing.TapscriptSigHash(tx, 0, prevOuts[:1],
+		signing.SigHashAll, leafA); err != signing.ErrPrevOutsMismatch {
+		t.Errorf("TapscriptSigHash: got error %v, want %v", err,
+			signing.ErrPrevOutsMismatch)
+	}
+}
@@ -0,0 +1,220 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package signing
+
+import (
+	"crypto/sha256"
+	"hash"
+	"sync"
+
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcd/txscript"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil/taproot"
+	"github.com/zeusyf/omega/token"
+)
+
+// SigHashCache computes the signature hashes of the inputs of a transaction,
+// sharing the hashes of its inputs, sequence numbers and outputs which the
+// BIP0143 and BIP0341 signature hashes of all inputs commit to.  Computing
+// them once rather than for every input keeps signing and verifying a
+// transaction linear in its size instead of quadratic.
+//
+// The shared hashes are computed on first use, so a cache only signing ECDSA
+// inputs never fetches the outputs spent by the other inputs.  A cache is
+// safe for concurrent use, but the transaction must not be modified while it
+// is in use.
+type SigHashCache struct {
+	tx      *wire.MsgTx
+	fetcher PrevOutFetcher
+
+	v0Once    sync.Once
+	sigHashes *txscript.TxSigHashes
+
+	v1Once           sync.Once
+	v1Err            error
+	prevOuts         []*wire.TxOut
+	shaPrevOuts      []byte
+	shaAmounts       []byte
+	shaScriptPubKeys []byte
+	shaSequences     []byte
+	shaOutputs       []byte
+}
+
+// NewSigHashCache returns a cache of the signature hashes of tx, whose spent
+// outputs are looked up with fetcher.
+func NewSigHashCache(tx *wire.MsgTx, fetcher PrevOutFetcher) *SigHashCache {
+	return &SigHashCache{tx: tx, fetcher: fetcher}
+}
+
+// v0Hashes computes the hashes of the inputs, sequence numbers and outputs
+// shared by the BIP0143 signature hashes of the inputs, as the script engine
+// of the node does.
+func (c *SigHashCache) v0Hashes() {
+	c.sigHashes = txscript.NewTxSigHashes(c.tx)
+}
+
+// v1Hashes computes the SHA256 hashes shared by the BIP0341 signature hashes
+// of the inputs, which commit to the outputs spent by all inputs.
+func (c *SigHashCache) v1Hashes() {
+	c.prevOuts = make([]*wire.TxOut, len(c.tx.TxIn))
+	for i, in := range c.tx.TxIn {
+		c.prevOuts[i] = c.fetcher.FetchPrevOutput(in.PreviousOutPoint)
+		if c.prevOuts[i] == nil {
+			c.v1Err = ErrPrevOutsMismatch
+			return
+		}
+	}
+
+	c.shaPrevOuts = sum(func(h hash.Hash) {
+		for _, in := range c.tx.TxIn {
+			writeOutPoint(h, &in.PreviousOutPoint)
+		}
+	})
+	c.shaAmounts = sum(func(h hash.Hash) {
+		for _, out := range c.prevOuts {
+			writeToken(h, &out.Token)
+		}
+	})
+	c.shaScriptPubKeys = sum(func(h hash.Hash) {
+		for _, out := range c.prevOuts {
+			writeScript(h, out.PkScript)
+		}
+	})
+	c.shaSequences = sum(func(h hash.Hash) {
+		for _, in := range c.tx.TxIn {
+			writeUint32(h, in.Sequence)
+		}
+	})
+	c.shaOutputs = sum(func(h hash.Hash) {
+		for _, out := range c.tx.TxOut {
+			writeToken(h, &out.Token)
+			writeScript(h, out.PkScript)
+		}
+	})
+}
+
+// LegacySigHash returns the legacy signature hash of the input at idx.  See
+// LegacySigHash, which shares nothing between inputs.
+func (c *SigHashCache) LegacySigHash(idx int, subScript []byte,
+	hashType SigHashType) ([]byte, error) {
+	return LegacySigHash(c.tx, idx, subScript, hashType)
+}
+
+// WitnessV0SigHash returns the BIP0143 signature hash of the input at idx
+// executing scriptCode, computed by txscript.CalcWitnessSigHash.
+// ErrPrevOutsMismatch is returned when the fetcher does not know the output
+// spent by the input, and ErrNoAmount when that output holds no amount.
+func (c *SigHashCache) WitnessV0SigHash(idx int, scriptCode []byte,
+	hashType SigHashType) ([]byte, error) {
+	if !hashType.validECDSA() {
+		return nil, ErrInvalidSigHashType
+	}
+	if idx < 0 || idx >= len(c.tx.TxIn) {
+		return nil, ErrInputIndex
+	}
+	prevOut := c.fetcher.FetchPrevOutput(c.tx.TxIn[idx].PreviousOutPoint)
+	if prevOut == nil {
+		return nil, ErrPrevOutsMismatch
+	}
+	amount, ok := prevOut.Token.Value.(*token.NumeralVal)
+	if !ok {
+		return nil, ErrNoAmount
+	}
+	c.v0Once.Do(c.v0Hashes)
+	return txscript.CalcWitnessSigHash(scriptCode, c.sigHashes,
+		txscript.SigHashType(hashType), c.tx, idx, amount.Val)
+}
+
+// SchnorrSigHash returns the BIP0341 signature hash of the input at idx.
+// ErrPrevOutsMismatch is returned when the fetcher does not know the output
+// spent by any input.
+func (c *SigHashCache) SchnorrSigHash(idx int, hashType SigHashType) ([]byte, error) {
+	return c.schnorrSigHash(idx, hashType, nil)
+}
+
+// TapscriptSigHash returns the BIP0342 signature hash of the input at idx
+// spending the script of a taproot output whose leaf hash is leafHash.  It
+// extends the key path signature hash with the leaf hash, so a signature of
+// one script can't be used with another.
+func (c *SigHashCache) TapscriptSigHash(idx int, hashType SigHashType,
+	leafHash []byte) ([]byte, error) {
+	if len(leafHash) != chainhash.HashSize {
+		return nil, ErrInvalidLeafHash
+	}
+	return c.schnorrSigHash(idx, hashType, leafHash)
+}
+
+// schnorrSigHash returns the signature hash of the input at idx spending a
+// taproot output through its key path, or through the script whose leaf hash
+// is leafHash when it is not nil.
+func (c *SigHashCache) schnorrSigHash(idx int, hashType SigHashType,
+	leafHash []byte) ([]byte, error) {
+	if !hashType.valid() {
+		return nil, ErrInvalidSigHashType
+	}
+	if idx < 0 || idx >= len(c.tx.TxIn) {
+		return nil, ErrInputIndex
+	}
+	c.v1Once.Do(c.v1Hashes)
+	if c.v1Err != nil {
+		return nil, c.v1Err
+	}
+	outputType := hashType & sigHashOutputMask
+	anyoneCanPay := hashType&SigHashAnyOneCanPay != 0
+	if outputType == SigHashSingle && idx >= len(c.tx.TxOut) {
+		return nil, ErrNoSingleOutput
+	}
+
+	h := sha256.New()
+	h.Write([]byte{0x00, byte(hashType)})
+	writeUint32(h, uint32(c.tx.Version))
+	writeUint32(h, c.tx.LockTime)
+
+	if !anyoneCanPay {
+		h.Write(c.shaPrevOuts)
+		h.Write(c.shaAmounts)
+		h.Write(c.shaScriptPubKeys)
+		h.Write(c.shaSequences)
+	}
+	if outputType != SigHashNone && outputType != SigHashSingle {
+		h.Write(c.shaOutputs)
+	}
+
+	// The spend type, which is the extension flag doubled as there is
+	// no annex: zero for key path spends and one for script path spends.
+	if leafHash == nil {
+		h.Write([]byte{0x00})
+	} else {
+		h.Write([]byte{0x02})
+	}
+
+	if anyoneCanPay {
+		in := c.tx.TxIn[idx]
+		writeOutPoint(h, &in.PreviousOutPoint)
+		writeToken(h, &c.prevOuts[idx].Token)
+		writeScript(h, c.prevOuts[idx].PkScript)
+		writeUint32(h, in.Sequence)
+	} else {
+		writeUint32(h, uint32(idx))
+	}
+	if outputType == SigHashSingle {
+		h.Write(sum(func(h hash.Hash) {
+			writeToken(h, &c.tx.TxOut[idx].Token)
+			writeScript(h, c.tx.TxOut[idx].PkScript)
+		}))
+	}
+
+	if leafHash != nil {
+		// The leaf hash, the key version and the position of the last
+		// executed OP_CODESEPARATOR, none.
+		h.Write(leafHash)
+		h.Write([]byte{0x00})
+		writeUint32(h, 0xffffffff)
+	}
+
+	sigHash := taproot.TaggedHash(TagTapSighash, h.Sum(nil))
+	return sigHash[:], nil
+}
@@ -0,0 +1,100 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package signing_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil/signing"
+)
+
+// TestSigHashCache ensures the signature hashes computed by a cache equal the
+// ones computed from scratch for every input and hash type.
+func TestSigHashCache(t *testing.T) {
+	tx, prevOuts := sigHashTx()
+	cache := signing.NewSigHashCache(tx, signing.NewPrevOutFetcher(tx,
+		prevOuts))
+
+	hashTypes := []signing.SigHashType{
+		signing.SigHashAll,
+		signing.SigHashNone,
+		signing.SigHashSingle,
+		signing.SigHashAll | signing.SigHashAnyOneCanPay,
+		signing.SigHashNone | signing.SigHashAnyOneCanPay,
+		signing.SigHashSingle | signing.SigHashAnyOneCanPay,
+	}
+	subScript := []byte{0x53}
+	for idx := range tx.TxIn {
+		for _, hashType := range hashTypes {
+			want, _ := signing.LegacySigHash(tx, idx, subScript, hashType)
+			got, err := cache.LegacySigHash(idx, subScript, hashType)
+			if err != nil || !bytes.Equal(got, want) {
+				t.Errorf("LegacySigHash(%d, %v): got %x, %v, want %x",
+					idx, hashType, got, err, want)
+			}
+
+			want, _ = signing.WitnessV0SigHash(tx, idx, subScript,
+				prevOuts[idx], hashType)
+			got, err = cache.WitnessV0SigHash(idx, subScript, hashType)
+			if err != nil || !bytes.Equal(got, want) {
+				t.Errorf("WitnessV0SigHash(%d, %v): got %x, %v, want %x",
+					idx, hashType, got, err, want)
+			}
+
+			want, _ = signing.SchnorrSigHash(tx, idx, prevOuts, hashType)
+			got, err = cache.SchnorrSigHash(idx, hashType)
+			if err != nil || !bytes.Equal(got, want) {
+				t.Errorf("SchnorrSigHash(%d, %v): got %x, %v, want %x",
+					idx, hashType, got, err, want)
+			}
+		}
+	}
+	if _, err := cache.SchnorrSigHash(0, signing.SigHashDefault); err != nil {
+		t.Errorf("SchnorrSigHash: unexpected error: %v", err)
+	}
+}
+
+// TestSigHashCacheErrors tests invalid signature hash requests and unknown
+// spent outputs are rejected.
+func TestSigHashCacheErrors(t *testing.T) {
+	tx, prevOuts := sigHashTx()
+	tx.TxIn = append(tx.TxIn, &wire.TxIn{})
+	prevOuts = append(prevOuts, omcOutput(1000, nil))
+	cache := signing.NewSigHashCache(tx, signing.NewPrevOutFetcher(tx,
+		prevOuts))
+
+	if _, err := cache.WitnessV0SigHash(0, nil, signing.SigHashDefault); err != signing.ErrInvalidSigHashType {
+		t.Errorf("WitnessV0SigHash: got error %v, want %v", err,
+			signing.ErrInvalidSigHashType)
+	}
+	if _, err := cache.SchnorrSigHash(3, signing.SigHashAll); err != signing.ErrInputIndex {
+		t.Errorf("SchnorrSigHash: got error %v, want %v", err,
+			signing.ErrInputIndex)
+	}
+	if _, err := cache.SchnorrSigHash(2, signing.SigHashSingle); err != signing.ErrNoSingleOutput {
+		t.Errorf("SchnorrSigHash: got error %v, want %v", err,
+			signing.ErrNoSingleOutput)
+	}
+
+	// The cache only fetches the spent outputs a signature hash commits
+	// to: a witness version 0 hash only needs the one of its input.
+	fetcher := signing.MapPrevOutFetcher{
+		tx.TxIn[0].PreviousOutPoint: prevOuts[0],
+	}
+	cache = signing.NewSigHashCache(tx, fetcher)
+	if _, err := cache.WitnessV0SigHash(0, nil, signing.SigHashAll); err != nil {
+		t.Errorf("WitnessV0SigHash: unexpected error: %v", err)
+	}
+	if _, err := cache.WitnessV0SigHash(1, nil, signing.SigHashAll); err != signing.ErrPrevOutsMismatch {
+		t.Errorf("WitnessV0SigHash: got error %v, want %v", err,
+			signing.ErrPrevOutsMismatch)
+	}
+	if _, err := cache.SchnorrSigHash(0, signing.SigHashAll); err != signing.ErrPrevOutsMismatch {
+		t.Errorf("SchnorrSigHash: got error %v, want %v", err,
+			signing.ErrPrevOutsMismatch)
+	}
+}
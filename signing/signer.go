@@ -0,0 +1,196 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package signing provides adapters which produce ECDSA and BIP0340 Schnorr
+// signatures over transaction signature hashes on behalf of wallets and
+// transaction builders.
+package signing
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"math/big"
+	"time"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcutil/audit"
+	"github.com/zeusyf/btcutil/entropy"
+)
+
+const (
+	// LowRSigLen is the maximum length of a DER encoded signature with a
+	// low R value, including the trailing signature hash type byte.  This
+	// is the size produced by a signer with low R grinding enabled and
+	// the size transaction size estimators should assume for it.
+	LowRSigLen = 71
+
+	// HighRSigLen is the maximum length of a DER encoded signature,
+	// including the trailing signature hash type byte, when no grinding
+	// is done.
+	HighRSigLen = 72
+)
+
+// ErrInvalidHash describes an error where the message to sign is not a 32
+// byte hash.
+var ErrInvalidHash = errors.New("hash to sign must be 32 bytes")
+
+// Signer produces signatures over signature hashes with a single key.
+type Signer interface {
+	// PubKey returns the public key corresponding to the signing key.
+	PubKey() *btcec.PublicKey
+
+	// Sign returns a signature over the passed 32 byte hash.
+	Sign(hash []byte) (*btcec.Signature, error)
+}
+
+// Option configures a KeySigner.
+type Option func(*KeySigner)
+
+// WithLowR enables grinding for signatures with a low R value.  The nonce is
+// derived deterministically as in RFC6979, with an incrementing counter added
+// as extra data until R is below 2^255.  This makes the DER encoding of every
+// signature at most LowRSigLen bytes, including the hash type byte, so
+// transaction sizes estimated before signing match the signed transaction.
+// On average two signing attempts are needed.
+func WithLowR() Option {
+	return func(s *KeySigner) {
+		s.lowR = true
+	}
+}
+
+// WithExtraEntropy mixes 32 bytes read from src into the nonce of every
+// signature as RFC6979 extra data, as libsecp256k1 does.  The nonce then no
+// longer depends on the key and message alone, which hardens signing against
+// fault injection and side channel attacks, at the cost of signatures no
+// longer being deterministic.  A nil src selects the default entropy source.
+// When combined with WithLowR, the entropy is read once per signature and the
+// grinding counter is mixed into it.
+func WithExtraEntropy(src io.Reader) Option {
+	return func(s *KeySigner) {
+		s.extraEntropy = true
+		s.entropySrc = src
+	}
+}
+
+// WithAudit makes the signer record every signature to sink, identifying its
+// key by origin and declaring purpose as the reason for signing.  The event is
+// recorded before the signature is created, and Sign fails with the error of
+// the sink if recording fails.
+func WithAudit(sink audit.Sink, origin audit.KeyOrigin, purpose string) Option {
+	return func(s *KeySigner) {
+		s.auditSink = sink
+		s.auditOrigin = origin
+		s.auditPurpose = purpose
+	}
+}
+
+// KeySigner is a Signer backed by an in-memory private key.
+type KeySigner struct {
+	key          *btcec.PrivateKey
+	lowR         bool
+	extraEntropy bool
+	entropySrc   io.Reader
+	auditSink    audit.Sink
+	auditOrigin  audit.KeyOrigin
+	auditPurpose string
+}
+
+// Ensure KeySigner implements the Signer interface.
+var _ Signer = (*KeySigner)(nil)
+
+// NewKeySigner returns a signer for the passed private key.
+func NewKeySigner(key *btcec.PrivateKey, opts ...Option) *KeySigner {
+	s := &KeySigner{key: key}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// PubKey returns the public key corresponding to the signing key.  Part of
+// the Signer interface.
+func (s *KeySigner) PubKey() *btcec.PublicKey {
+	return s.key.PubKey()
+}
+
+// Sign returns a signature over the passed 32 byte hash.  The signature is
+// canonical, meaning S is always in the lower half of the curve order.  Part
+// of the Signer interface.
+func (s *KeySigner) Sign(hash []byte) (*btcec.Signature, error) {
+	if len(hash) != 32 {
+		return nil, ErrInvalidHash
+	}
+	if s.auditSink != nil {
+		err := s.auditSink.Record(&audit.Event{
+			Time:      time.Now(),
+			Operation: audit.OpSign,
+			Origin:    s.auditOrigin,
+			Purpose:   s.auditPurpose,
+			Digest:    append([]byte(nil), hash...),
+			PubKey:    s.key.PubKey().SerializeCompressed(),
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !s.lowR && !s.extraEntropy {
+		return s.key.Sign(hash)
+	}
+
+	var random, extra [32]byte
+	if s.extraEntropy {
+		if err := entropy.Read(s.entropySrc, random[:]); err != nil {
+			return nil, err
+		}
+	}
+	for counter := uint32(0); ; counter++ {
+		// Without extra entropy, the first attempt uses the standard
+		// RFC6979 nonce so the output is identical to an ungrinded
+		// signature whenever that already has a low R value.
+		var extraData []byte
+		if s.extraEntropy || counter > 0 {
+			extra = random
+			mixed := binary.LittleEndian.Uint32(random[:]) ^ counter
+			binary.LittleEndian.PutUint32(extra[:], mixed)
+			extraData = extra[:]
+		}
+
+		sig, ok := signWithExtra(s.key, hash, extraData)
+		if ok && (!s.lowR || sig.R.BitLen() <= 255) {
+			return sig, nil
+		}
+	}
+}
+
+// signWithExtra creates a canonical ECDSA signature over hash using a nonce
+// derived from the key, the hash, and the extra data.  It returns false in the
+// astronomically unlikely case the nonce produces an invalid signature.
+func signWithExtra(key *btcec.PrivateKey, hash, extra []byte) (*btcec.Signature, bool) {
+	curve := btcec.S256()
+	n := curve.Params().N
+	halfOrder := new(big.Int).Rsh(n, 1)
+
+	k := nonceRFC6979(key.D, hash, n, extra)
+	rx, _ := curve.ScalarBaseMult(k.Bytes())
+	r := new(big.Int).Mod(rx, n)
+	if r.Sign() == 0 {
+		return nil, false
+	}
+
+	// s = k^-1 * (e + r*d) mod n
+	e := new(big.Int).SetBytes(hash)
+	sv := new(big.Int).Mul(r, key.D)
+	sv.Add(sv, e)
+	sv.Mul(sv, new(big.Int).ModInverse(k, n))
+	sv.Mod(sv, n)
+	if sv.Sign() == 0 {
+		return nil, false
+	}
+	if sv.Cmp(halfOrder) > 0 {
+		sv.Sub(n, sv)
+	}
+
+	return &btcec.Signature{R: r, S: sv}, true
+}
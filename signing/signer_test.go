@@ -0,0 +1,159 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package signing_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcutil/audit"
+	"github.com/zeusyf/btcutil/entropy"
+	"github.com/zeusyf/btcutil/signing"
+)
+
+// TestLowRGrinding ensures grinded signatures are valid, deterministic, and
+// never exceed the low R size bound.
+func TestLowRGrinding(t *testing.T) {
+	key, _ := btcec.PrivKeyFromBytes(btcec.S256(), bytes.Repeat([]byte{0x2a}, 32))
+	plain := signing.NewKeySigner(key)
+	grinder := signing.NewKeySigner(key, signing.WithLowR())
+
+	var highR int
+	for i := 0; i < 256; i++ {
+		hash := chainhash.DoubleHashB([]byte{byte(i)})
+
+		sig, err := grinder.Sign(hash)
+		if err != nil {
+			t.Fatalf("Sign: unexpected error: %v", err)
+		}
+		if !sig.Verify(hash, grinder.PubKey()) {
+			t.Fatalf("Sign #%d: signature does not verify", i)
+		}
+		if sig.R.BitLen() > 255 {
+			t.Fatalf("Sign #%d: high R value", i)
+		}
+		if n := len(sig.Serialize()) + 1; n > signing.LowRSigLen {
+			t.Fatalf("Sign #%d: signature of %d bytes exceeds %d", i,
+				n, signing.LowRSigLen)
+		}
+
+		again, _ := grinder.Sign(hash)
+		if !bytes.Equal(again.Serialize(), sig.Serialize()) {
+			t.Fatalf("Sign #%d: signature is not deterministic", i)
+		}
+
+		// Signatures which already have a low R must match the
+		// standard RFC6979 signature.
+		std, err := plain.Sign(hash)
+		if err != nil {
+			t.Fatalf("Sign: unexpected error: %v", err)
+		}
+		if std.R.BitLen() > 255 {
+			highR++
+			continue
+		}
+		if !bytes.Equal(std.Serialize(), sig.Serialize()) {
+			t.Fatalf("Sign #%d: grinded signature differs from "+
+				"standard low R signature", i)
+		}
+	}
+
+	// About half of the standard signatures are expected to have a high R.
+	if highR == 0 {
+		t.Fatalf("no high R signatures were produced")
+	}
+
+	if _, err := grinder.Sign([]byte{1, 2, 3}); err != signing.ErrInvalidHash {
+		t.Fatalf("Sign: got %v, want %v", err, signing.ErrInvalidHash)
+	}
+}
+
+// TestExtraEntropy ensures signatures with extra entropy are valid, depend on
+// the entropy read, and fail when the source does.
+func TestExtraEntropy(t *testing.T) {
+	key, _ := btcec.PrivKeyFromBytes(btcec.S256(), bytes.Repeat([]byte{0x2a}, 32))
+	hash := chainhash.DoubleHashB([]byte("extra entropy"))
+
+	random := bytes.Repeat([]byte{1}, 32)
+	random = append(random, bytes.Repeat([]byte{2}, 32)...)
+	for _, opts := range [][]signing.Option{
+		{signing.WithExtraEntropy(bytes.NewReader(random))},
+		{signing.WithExtraEntropy(bytes.NewReader(random)), signing.WithLowR()},
+	} {
+		s := signing.NewKeySigner(key, opts...)
+		first, err := s.Sign(hash)
+		if err != nil {
+			t.Fatalf("Sign: unexpected error: %v", err)
+		}
+		second, err := s.Sign(hash)
+		if err != nil {
+			t.Fatalf("Sign: unexpected error: %v", err)
+		}
+		for _, sig := range []*btcec.Signature{first, second} {
+			if !sig.Verify(hash, s.PubKey()) {
+				t.Fatal("Sign: signature does not verify")
+			}
+		}
+		if bytes.Equal(first.Serialize(), second.Serialize()) {
+			t.Fatal("Sign: signatures with different entropy are equal")
+		}
+
+		// The source is exhausted.
+		if _, err := s.Sign(hash); err != io.EOF {
+			t.Fatalf("Sign: got error %v, want %v", err, io.EOF)
+		}
+	}
+
+	// A nil source reads from the default source.
+	entropy.SetDefault(bytes.NewReader(random))
+	defer entropy.SetDefault(nil)
+	got, err := signing.NewKeySigner(key, signing.WithExtraEntropy(nil)).Sign(hash)
+	if err != nil {
+		t.Fatalf("Sign: unexpected error: %v", err)
+	}
+	want, _ := signing.NewKeySigner(key,
+		signing.WithExtraEntropy(bytes.NewReader(random))).Sign(hash)
+	if !bytes.Equal(got.Serialize(), want.Serialize()) {
+		t.Fatal("Sign: default source was not used")
+	}
+}
+
+// TestAudit ensures every signature is recorded with its digest and that a
+// failing sink prevents signing.
+func TestAudit(t *testing.T) {
+	key, _ := btcec.PrivKeyFromBytes(btcec.S256(), bytes.Repeat([]byte{0x2a}, 32))
+	hash := chainhash.DoubleHashB([]byte("audited"))
+	origin := audit.KeyOrigin{Fingerprint: 0x3442193e, Path: []uint32{0, 7}}
+
+	var events []*audit.Event
+	sink := audit.SinkFunc(func(e *audit.Event) error {
+		events = append(events, e)
+		return nil
+	})
+	s := signing.NewKeySigner(key, signing.WithAudit(sink, origin, "payout"))
+	if _, err := s.Sign(hash); err != nil {
+		t.Fatalf("Sign: unexpected error: %v", err)
+	}
+	if len(events) != 1 {
+		t.Fatalf("Sign: got %d events, want 1", len(events))
+	}
+	e := events[0]
+	if e.Operation != audit.OpSign || e.Purpose != "payout" ||
+		e.Origin.String() != "3442193e/0/7" || !bytes.Equal(e.Digest, hash) ||
+		!bytes.Equal(e.PubKey, s.PubKey().SerializeCompressed()) {
+		t.Fatalf("Sign: unexpected event %+v", e)
+	}
+
+	sinkErr := errors.New("audit log unavailable")
+	failing := audit.SinkFunc(func(*audit.Event) error { return sinkErr })
+	s = signing.NewKeySigner(key, signing.WithAudit(failing, origin, "payout"))
+	if sig, err := s.Sign(hash); sig != nil || err != sinkErr {
+		t.Fatalf("Sign: got %v, %v, want %v", sig, err, sinkErr)
+	}
+}
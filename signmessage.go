@@ -0,0 +1,137 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package btcutil
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcd/wire/common"
+)
+
+// MessageMagic is prepended to messages before they are hashed for signing,
+// so a message signature can never be mistaken for a transaction signature.
+const MessageMagic = "\x18OMC Signed Message:\n"
+
+// compactSigLen is the length of a compact recoverable signature: a header
+// byte carrying the recovery code and key format, followed by R and S.
+const compactSigLen = 65
+
+var (
+	// ErrInvalidMessageSignature describes an error where a message
+	// signature is not the base64 encoding of a compact recoverable
+	// signature, or no public key can be recovered from it.
+	ErrInvalidMessageSignature = errors.New("malformed message signature")
+
+	// ErrMessageSignatureMismatch describes an error where a well formed
+	// message signature was not made by the key of the address it is
+	// verified against, or was made for a different message.
+	ErrMessageSignatureMismatch = errors.New("message signature does not " +
+		"match address")
+
+	// ErrMessageAddressType describes an error where a message signature
+	// is verified against an address which is not controlled by a single
+	// public key, such as a pay-to-script-hash address.  Ownership of those
+	// addresses can not be proven with a compact signature.
+	ErrMessageAddressType = errors.New("address type does not support " +
+		"message signatures")
+)
+
+// MessageHash returns the hash signed by SignMessage for the passed message:
+// the double SHA256 of MessageMagic followed by the message as a variable
+// length string.
+func MessageHash(message string) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(MessageMagic)
+	common.WriteVarInt(&buf, 0, uint64(len(message)))
+	buf.WriteString(message)
+	return chainhash.DoubleHashB(buf.Bytes())
+}
+
+// SignMessage signs the passed message with key and returns the base64
+// encoded compact signature, as produced by the signmessage RPC.  compressed
+// selects whether the signature commits to the compressed or uncompressed
+// serialization of the public key, which must match the serialization the
+// pay-to-pubkey-hash address of the key was derived from.
+func SignMessage(key *btcec.PrivateKey, message string, compressed bool) (string, error) {
+	sig, err := btcec.SignCompact(btcec.S256(), key, MessageHash(message),
+		compressed)
+	if err != nil {
+		return "", err
+	}
+	return base64.StdEncoding.EncodeToString(sig), nil
+}
+
+// recoverMessageKey returns the public key which made the base64 encoded
+// compact signature of the message, and whether the signature commits to its
+// compressed serialization.
+func recoverMessageKey(signature, message string) (*btcec.PublicKey, bool, error) {
+	sig, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil || len(sig) != compactSigLen {
+		return nil, false, ErrInvalidMessageSignature
+	}
+	pubKey, compressed, err := btcec.RecoverCompact(btcec.S256(), sig,
+		MessageHash(message))
+	if err != nil {
+		return nil, false, ErrInvalidMessageSignature
+	}
+	return pubKey, compressed, nil
+}
+
+// RecoverMessageAddress returns the pay-to-pubkey-hash address for the passed
+// network of the key which made the base64 encoded compact signature of the
+// message.  Any well formed signature recovers to some address, so the result
+// proves nothing until it is compared with the expected address; use
+// VerifyMessage for that.
+func RecoverMessageAddress(signature, message string, net *chaincfg.Params) (*AddressPubKeyHash, error) {
+	pubKey, compressed, err := recoverMessageKey(signature, message)
+	if err != nil {
+		return nil, err
+	}
+	serialized := pubKey.SerializeUncompressed()
+	if compressed {
+		serialized = pubKey.SerializeCompressed()
+	}
+	return NewAddressPubKeyHash(Hash160(serialized), net)
+}
+
+// VerifyMessage verifies that the base64 encoded compact signature of the
+// message was made by the key controlling addr.  Pay-to-pubkey-hash and
+// pay-to-pubkey addresses are supported; other addresses return
+// ErrMessageAddressType.  A signature by another key, or for another message,
+// returns ErrMessageSignatureMismatch.
+func VerifyMessage(addr Address, signature, message string) error {
+	switch addr.(type) {
+	case *AddressPubKeyHash, *AddressPubKey:
+	default:
+		return ErrMessageAddressType
+	}
+
+	pubKey, compressed, err := recoverMessageKey(signature, message)
+	if err != nil {
+		return err
+	}
+
+	var match bool
+	switch a := addr.(type) {
+	case *AddressPubKeyHash:
+		serialized := pubKey.SerializeUncompressed()
+		if compressed {
+			serialized = pubKey.SerializeCompressed()
+		}
+		match = bytes.Equal(Hash160(serialized), a.ScriptAddress())
+
+	case *AddressPubKey:
+		match = pubKey.IsEqual(a.PubKey())
+	}
+	if !match {
+		return ErrMessageSignatureMismatch
+	}
+	return nil
+}
@@ -0,0 +1,98 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package btcutil_test
+
+import (
+	"encoding/base64"
+	"testing"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcd/chaincfg"
+	. "github.com/zeusyf/btcutil"
+)
+
+// TestSignMessage ensures signed messages verify against the addresses of the
+// signing key and fail against other addresses and messages.
+func TestSignMessage(t *testing.T) {
+	net := &chaincfg.MainNetParams
+	key, pubKey := btcec.PrivKeyFromBytes(btcec.S256(), []byte{
+		0x0c, 0x28, 0xfc, 0xa3, 0x86, 0xc7, 0xa2, 0x27,
+		0x60, 0x0b, 0x2f, 0xe5, 0x0b, 0x7c, 0xae, 0x11,
+		0xec, 0x86, 0xd3, 0xbf, 0x1f, 0xbe, 0x47, 0x1b,
+		0xe8, 0x98, 0x27, 0xe1, 0x9d, 0x72, 0xaa, 0x1d})
+
+	compressedAddr, err := NewAddressPubKeyHash(
+		Hash160(pubKey.SerializeCompressed()), net)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uncompressedAddr, err := NewAddressPubKeyHash(
+		Hash160(pubKey.SerializeUncompressed()), net)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pubKeyAddr, err := NewAddressPubKey(pubKey.SerializeCompressed(), net)
+	if err != nil {
+		t.Fatal(err)
+	}
+	scriptAddr, err := NewAddressScriptHashFromHash(
+		Hash160(pubKey.SerializeCompressed()), net)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const message = "I own this address"
+	for _, compressed := range []bool{true, false} {
+		sig, err := SignMessage(key, message, compressed)
+		if err != nil {
+			t.Fatalf("SignMessage: unexpected error: %v", err)
+		}
+
+		addr, other := compressedAddr, uncompressedAddr
+		if !compressed {
+			addr, other = uncompressedAddr, compressedAddr
+		}
+		tests := []struct {
+			name    string
+			addr    Address
+			sig     string
+			message string
+			err     error
+		}{
+			{"own address", addr, sig, message, nil},
+			{"pubkey address", pubKeyAddr, sig, message, nil},
+			{"other key format", other, sig, message, ErrMessageSignatureMismatch},
+			{"other message", addr, sig, message + ".", ErrMessageSignatureMismatch},
+			{"script hash", scriptAddr, sig, message, ErrMessageAddressType},
+			{"not base64", addr, "!" + sig[1:], message, ErrInvalidMessageSignature},
+			{"short", addr, sig[:40], message, ErrInvalidMessageSignature},
+		}
+		for _, test := range tests {
+			err := VerifyMessage(test.addr, test.sig, test.message)
+			if err != test.err {
+				t.Errorf("compressed %v, %s: got error %v, want %v",
+					compressed, test.name, err, test.err)
+			}
+		}
+
+		recovered, err := RecoverMessageAddress(sig, message, net)
+		if err != nil {
+			t.Fatalf("RecoverMessageAddress: unexpected error: %v", err)
+		}
+		if recovered.String() != addr.String() {
+			t.Errorf("RecoverMessageAddress: got %v, want %v",
+				recovered, addr)
+		}
+
+		// A corrupted recovery header must not recover the signer.
+		raw, _ := base64.StdEncoding.DecodeString(sig)
+		raw[0] ^= 1
+		corrupted := base64.StdEncoding.EncodeToString(raw)
+		if err := VerifyMessage(addr, corrupted, message); err == nil {
+			t.Errorf("compressed %v: corrupted header verified",
+				compressed)
+		}
+	}
+}
@@ -0,0 +1,205 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package silentpay
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/big"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil/taproot"
+)
+
+// InputKey is the public part of an input of a scanned transaction.
+type InputKey struct {
+	// OutPoint is the output the input spends.
+	OutPoint wire.OutPoint
+
+	// PubKey is the public key the input reveals.
+	PubKey *btcec.PublicKey
+
+	// Taproot is set for inputs spending pay-to-taproot outputs, whose
+	// x-only keys stand for their even y point.
+	Taproot bool
+}
+
+// Match is an output of a scanned transaction paying the receiver.
+type Match struct {
+	// Index is the index of the output among the scanned output keys.
+	Index int
+
+	// OutputKey is the key of the output.
+	OutputKey *btcec.PublicKey
+
+	// Tweak is the 32 byte tweak that added to the private spend key
+	// gives the private key of the output.
+	Tweak []byte
+
+	// Label is the label of the address paid, when Labeled is set.
+	Label   uint32
+	Labeled bool
+}
+
+// label is a label registered with a receiver.
+type label struct {
+	m     uint32
+	tweak *big.Int
+}
+
+// Receiver scans transactions for outputs paying its addresses.
+type Receiver struct {
+	scanKey  *btcec.PrivateKey
+	spendKey *btcec.PublicKey
+	labels   map[string]label
+}
+
+// NewReceiver returns a receiver of the passed private scan key and public
+// spend key.  The private spend key is only needed to spend matches.
+func NewReceiver(scanKey *btcec.PrivateKey, spendKey *btcec.PublicKey) *Receiver {
+	return &Receiver{
+		scanKey:  scanKey,
+		spendKey: spendKey,
+		labels:   make(map[string]label),
+	}
+}
+
+// Address returns the unlabeled address of the receiver.
+func (r *Receiver) Address(net *chaincfg.Params) *Address {
+	return &Address{ScanKey: r.scanKey.PubKey(), SpendKey: r.spendKey, Net: net}
+}
+
+// LabeledAddress returns the address of the receiver with label m, and
+// registers the label so scanning finds outputs paying it.  Labels let a
+// receiver tell apart payments to addresses given out for different
+// purposes while scanning with the same key.
+func (r *Receiver) LabeledAddress(m uint32, net *chaincfg.Params) (*Address, error) {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], m)
+	t, err := scalar(TagLabel, serializeScalar(r.scanKey.D), b[:])
+	if err != nil {
+		return nil, err
+	}
+	point := mult(nil, t)
+	spendKey := add(r.spendKey, point)
+	if spendKey == nil {
+		return nil, ErrInvalidTweak
+	}
+	r.labels[string(point.SerializeCompressed())] = label{m: m, tweak: t}
+	return &Address{ScanKey: r.scanKey.PubKey(), SpendKey: spendKey, Net: net}, nil
+}
+
+// Scan returns the outputs of a transaction paying the receiver, given the
+// keys of its inputs eligible for silent payments and the x-only keys of
+// its pay-to-taproot outputs.  Output keys of another size are skipped.
+func (r *Receiver) Scan(inputs []InputKey, outputKeys [][]byte) ([]Match, error) {
+	if len(inputs) == 0 {
+		return nil, ErrNoInputs
+	}
+	var sum *btcec.PublicKey
+	outPoints := make([]wire.OutPoint, len(inputs))
+	for i, in := range inputs {
+		outPoints[i] = in.OutPoint
+		k := in.PubKey
+		if in.Taproot && k.Y.Bit(0) == 1 {
+			k = negate(k)
+		}
+		sum = add(sum, k)
+	}
+	if sum == nil {
+		return nil, ErrNoInputs
+	}
+	hash, err := inputHash(outPoints, sum)
+	if err != nil {
+		return nil, err
+	}
+	curve := btcec.S256()
+	d := new(big.Int).Mul(hash, r.scanKey.D)
+	secret := mult(sum, d.Mod(d, curve.N))
+
+	outputs := make([]*btcec.PublicKey, len(outputKeys))
+	for i, b := range outputKeys {
+		if len(b) != 32 {
+			continue
+		}
+		outputs[i], _ = taproot.ParseXOnlyPubKey(b)
+	}
+	found := make([]bool, len(outputs))
+
+	var matches []Match
+	for k := uint32(0); ; k++ {
+		t, err := sharedSecretTweak(secret, k)
+		if err != nil {
+			return nil, err
+		}
+		p := add(r.spendKey, mult(nil, t))
+		if p == nil {
+			return nil, ErrInvalidTweak
+		}
+		m, ok := r.match(p, t, outputs, found)
+		if !ok {
+			return matches, nil
+		}
+		found[m.Index] = true
+		matches = append(matches, m)
+	}
+}
+
+// match returns the first output not yet found that is the key p, possibly
+// tweaked with a registered label.
+func (r *Receiver) match(p *btcec.PublicKey, t *big.Int,
+	outputs []*btcec.PublicKey, found []bool) (Match, bool) {
+
+	xOnly := taproot.SerializeXOnly(p)
+	for i, out := range outputs {
+		if out == nil || found[i] {
+			continue
+		}
+		if bytes.Equal(taproot.SerializeXOnly(out), xOnly) {
+			return Match{Index: i, OutputKey: out,
+				Tweak: serializeScalar(t)}, true
+		}
+		if len(r.labels) == 0 {
+			continue
+		}
+
+		// An x-only output key is either the even point or its
+		// negation, so check both for a label.
+		neg := negate(p)
+		for _, o := range []*btcec.PublicKey{out, negate(out)} {
+			diff := add(o, neg)
+			if diff == nil {
+				continue
+			}
+			l, ok := r.labels[string(diff.SerializeCompressed())]
+			if !ok {
+				continue
+			}
+			tweak := new(big.Int).Add(t, l.tweak)
+			tweak.Mod(tweak, btcec.S256().N)
+			return Match{Index: i, OutputKey: o,
+				Tweak: serializeScalar(tweak), Label: l.m,
+				Labeled: true}, true
+		}
+	}
+	return Match{}, false
+}
+
+// PrivKey returns the private key of a matched output from the private spend
+// key of the receiver.  It signs the output's key path spend directly, with
+// no taproot tweak.
+func PrivKey(spendKey *btcec.PrivateKey, m *Match) (*btcec.PrivateKey, error) {
+	curve := btcec.S256()
+	d := new(big.Int).SetBytes(m.Tweak)
+	d.Add(d, spendKey.D)
+	d.Mod(d, curve.N)
+	if d.Sign() == 0 {
+		return nil, ErrInvalidTweak
+	}
+	priv, _ := btcec.PrivKeyFromBytes(curve, serializeScalar(d))
+	return priv, nil
+}
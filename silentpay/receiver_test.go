@@ -0,0 +1,126 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package silentpay_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil/silentpay"
+	"github.com/zeusyf/btcutil/taproot"
+)
+
+// oddPrivKey returns the first private key from k on whose public key has
+// an odd y coordinate.
+func oddPrivKey(k byte) *btcec.PrivateKey {
+	for ; ; k++ {
+		priv := privKey(k)
+		if priv.PubKey().Y.Bit(0) == 1 {
+			return priv
+		}
+	}
+}
+
+// TestScan ensures a receiver finds the outputs paying its addresses, with
+// and without labels, and derives their private keys.
+func TestScan(t *testing.T) {
+	scanKey, spendKey := privKey(10), privKey(11)
+	r := silentpay.NewReceiver(scanKey, spendKey.PubKey())
+	net := &chaincfg.MainNetParams
+	addr := r.Address(net)
+	labeled, err := r.LabeledAddress(7, net)
+	if err != nil {
+		t.Fatalf("LabeledAddress: %v", err)
+	}
+	if labeled.SpendKey.IsEqual(addr.SpendKey) {
+		t.Fatalf("labeled address has the unlabeled spend key")
+	}
+	other := &silentpay.Address{
+		ScanKey:  privKey(12).PubKey(),
+		SpendKey: privKey(13).PubKey(),
+		Net:      net,
+	}
+
+	tapKey := oddPrivKey(20)
+	inputs := []silentpay.Input{
+		{OutPoint: wire.OutPoint{Hash: [32]byte{1}, Index: 3},
+			PrivKey: privKey(14)},
+		{OutPoint: wire.OutPoint{Hash: [32]byte{2}}, PrivKey: tapKey,
+			Taproot: true},
+	}
+	recipients := []*silentpay.Address{addr, other, labeled, addr}
+	keys, err := silentpay.OutputKeys(inputs, recipients)
+	if err != nil {
+		t.Fatalf("OutputKeys: %v", err)
+	}
+
+	// The receiver only sees the x-only key of the taproot input.
+	tapPub, _ := taproot.ParseXOnlyPubKey(
+		taproot.SerializeXOnly(tapKey.PubKey()))
+	inputKeys := []silentpay.InputKey{
+		{OutPoint: inputs[0].OutPoint, PubKey: privKey(14).PubKey()},
+		{OutPoint: inputs[1].OutPoint, PubKey: tapPub, Taproot: true},
+	}
+	outputKeys := make([][]byte, len(keys)+1)
+	outputKeys[0] = []byte{1, 2, 3}
+	for i, k := range keys {
+		outputKeys[i+1] = taproot.SerializeXOnly(k)
+	}
+
+	matches, err := r.Scan(inputKeys, outputKeys)
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	wantIndexes := []int{1, 3, 4}
+	if len(matches) != len(wantIndexes) {
+		t.Fatalf("got %d matches, want %d", len(matches),
+			len(wantIndexes))
+	}
+	for i, m := range matches {
+		if m.Index != wantIndexes[i] {
+			t.Fatalf("match %d: got index %d, want %d", i, m.Index,
+				wantIndexes[i])
+		}
+		wantLabeled := m.Index == 3
+		if m.Labeled != wantLabeled || (wantLabeled && m.Label != 7) {
+			t.Fatalf("match %d: got label %d (%v)", i, m.Label,
+				m.Labeled)
+		}
+		priv, err := silentpay.PrivKey(spendKey, &m)
+		if err != nil {
+			t.Fatalf("match %d: PrivKey: %v", i, err)
+		}
+		if !bytes.Equal(taproot.SerializeXOnly(priv.PubKey()),
+			outputKeys[m.Index]) {
+
+			t.Fatalf("match %d: private key does not match output", i)
+		}
+	}
+
+	// Another receiver finds only its own output.
+	o := silentpay.NewReceiver(privKey(12), privKey(13).PubKey())
+	matches, err = o.Scan(inputKeys, outputKeys)
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	if len(matches) != 1 || matches[0].Index != 2 {
+		t.Fatalf("other receiver got matches %v", matches)
+	}
+
+	// Without the inputs' keys the outputs are not found.
+	matches, err = r.Scan(inputKeys[:1], outputKeys)
+	if err != nil {
+		t.Fatalf("Scan: %v", err)
+	}
+	if len(matches) != 0 {
+		t.Fatalf("got %d matches with missing inputs", len(matches))
+	}
+	if _, err := r.Scan(nil, outputKeys); err != silentpay.ErrNoInputs {
+		t.Fatalf("no inputs: got %v, want %v", err, silentpay.ErrNoInputs)
+	}
+}
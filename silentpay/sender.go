@@ -0,0 +1,106 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package silentpay
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/big"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcd/wire"
+)
+
+// Input is an input of a transaction paying silent payment addresses, with
+// the private key whose public key the input reveals.
+type Input struct {
+	// OutPoint is the output the input spends.
+	OutPoint wire.OutPoint
+
+	// PrivKey is the private key of the input.
+	PrivKey *btcec.PrivateKey
+
+	// Taproot is set for inputs spending pay-to-taproot outputs, whose
+	// keys are revealed x-only and so stand for their even y point.
+	Taproot bool
+}
+
+// serializeOutPoint returns the serialization of an outpoint hashed into
+// the input hash.
+func serializeOutPoint(op *wire.OutPoint) []byte {
+	b := make([]byte, 36)
+	copy(b, op.Hash[:])
+	binary.LittleEndian.PutUint32(b[32:], op.Index)
+	return b
+}
+
+// inputHash returns the input hash of a transaction from its outpoints and
+// the sum of its input keys.
+func inputHash(outPoints []wire.OutPoint, sum *btcec.PublicKey) (*big.Int, error) {
+	var smallest []byte
+	for i := range outPoints {
+		b := serializeOutPoint(&outPoints[i])
+		if smallest == nil || bytes.Compare(b, smallest) < 0 {
+			smallest = b
+		}
+	}
+	return scalar(TagInputs, smallest, sum.SerializeCompressed())
+}
+
+// OutputKeys returns the output keys paying each of the recipients from a
+// transaction with the passed inputs, in the order of the recipients.  Each
+// key is paid with taproot.PayToTaprootScript, with no taproot tweak.
+//
+// The outputs of recipients sharing a scan key are numbered in the order the
+// recipients are passed, so recipients may be passed more than once to pay
+// an address with several outputs.
+func OutputKeys(inputs []Input, recipients []*Address) ([]*btcec.PublicKey, error) {
+	if len(inputs) == 0 {
+		return nil, ErrNoInputs
+	}
+	curve := btcec.S256()
+	sum := new(big.Int)
+	outPoints := make([]wire.OutPoint, len(inputs))
+	for i, in := range inputs {
+		outPoints[i] = in.OutPoint
+		k := in.PrivKey.D
+		if in.Taproot && in.PrivKey.PubKey().Y.Bit(0) == 1 {
+			k = new(big.Int).Sub(curve.N, k)
+		}
+		sum.Add(sum, k)
+	}
+	sum.Mod(sum, curve.N)
+	if sum.Sign() == 0 {
+		return nil, ErrNoInputs
+	}
+	hash, err := inputHash(outPoints, mult(nil, sum))
+	if err != nil {
+		return nil, err
+	}
+	sum.Mul(sum, hash)
+	sum.Mod(sum, curve.N)
+
+	keys := make([]*btcec.PublicKey, len(recipients))
+	secrets := make(map[string]*btcec.PublicKey)
+	counts := make(map[string]uint32)
+	for i, r := range recipients {
+		scan := string(r.ScanKey.SerializeCompressed())
+		secret, ok := secrets[scan]
+		if !ok {
+			secret = mult(r.ScanKey, sum)
+			secrets[scan] = secret
+		}
+		t, err := sharedSecretTweak(secret, counts[scan])
+		if err != nil {
+			return nil, err
+		}
+		counts[scan]++
+		keys[i] = add(r.SpendKey, mult(nil, t))
+		if keys[i] == nil {
+			return nil, ErrInvalidTweak
+		}
+	}
+	return keys, nil
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package silentpay_test
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil/silentpay"
+)
+
+// TestOutputKeys ensures repeated payments to an address get distinct
+// outputs, and that the outputs do not depend on the order of the inputs.
+func TestOutputKeys(t *testing.T) {
+	addr := &silentpay.Address{
+		ScanKey:  privKey(3).PubKey(),
+		SpendKey: privKey(4).PubKey(),
+		Net:      &chaincfg.MainNetParams,
+	}
+	a := silentpay.Input{
+		OutPoint: wire.OutPoint{Index: 1},
+		PrivKey:  privKey(5),
+	}
+	b := silentpay.Input{
+		OutPoint: wire.OutPoint{Index: 0},
+		PrivKey:  privKey(6),
+	}
+
+	keys, err := silentpay.OutputKeys([]silentpay.Input{a, b},
+		[]*silentpay.Address{addr, addr})
+	if err != nil {
+		t.Fatalf("OutputKeys: %v", err)
+	}
+	if keys[0].IsEqual(keys[1]) {
+		t.Fatalf("outputs to the same address are equal")
+	}
+
+	swapped, err := silentpay.OutputKeys([]silentpay.Input{b, a},
+		[]*silentpay.Address{addr, addr})
+	if err != nil {
+		t.Fatalf("OutputKeys: %v", err)
+	}
+	for i := range keys {
+		if !keys[i].IsEqual(swapped[i]) {
+			t.Fatalf("output %d depends on input order", i)
+		}
+	}
+}
+
+// TestOutputKeysNoInputs ensures outputs are not derived without inputs or
+// from inputs whose keys cancel out.
+func TestOutputKeysNoInputs(t *testing.T) {
+	addr := &silentpay.Address{
+		ScanKey:  privKey(3).PubKey(),
+		SpendKey: privKey(4).PubKey(),
+	}
+	_, err := silentpay.OutputKeys(nil, []*silentpay.Address{addr})
+	if err != silentpay.ErrNoInputs {
+		t.Fatalf("no inputs: got %v, want %v", err, silentpay.ErrNoInputs)
+	}
+
+	n := new(big.Int).Sub(btcec.S256().N, big.NewInt(5))
+	neg, _ := btcec.PrivKeyFromBytes(btcec.S256(), n.Bytes())
+	inputs := []silentpay.Input{
+		{PrivKey: privKey(5)},
+		{OutPoint: wire.OutPoint{Index: 1}, PrivKey: neg},
+	}
+	_, err = silentpay.OutputKeys(inputs, []*silentpay.Address{addr})
+	if err != silentpay.ErrNoInputs {
+		t.Fatalf("cancelling inputs: got %v, want %v", err,
+			silentpay.ErrNoInputs)
+	}
+}
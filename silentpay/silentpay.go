@@ -0,0 +1,220 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package silentpay implements reusable payment addresses for OMC in the
+// manner of BIP0352 silent payments, so a receiver can publish a single
+// address, such as for donations, without any two payments to it sharing an
+// output or being linkable on chain.
+//
+// An address holds a scan key and a spend key.  A sender derives each output
+// paying the address from the keys of the inputs of its transaction and the
+// scan key, and pays the resulting key with a pay-to-taproot output.  The
+// receiver scans transactions with the private scan key, recognizing its
+// outputs from the public keys of their inputs, and spends them with the
+// private spend key tweaked by the tweak found while scanning.
+//
+// Addresses are bech32 strings holding a version, zero, followed by the two
+// compressed keys.  The Omega networks have bech32 rather than bech32m, which
+// BIP0352 uses, and their own prefixes:
+//
+//	spomc   main network
+//	tspomc  test network
+//	rspomc  regression test network
+//	sspomc  simulation test network
+//
+// The derivations follow BIP0352, hashing input outpoints in their Omega
+// serialization: the transaction hash followed by the little endian index.
+package silentpay
+
+import (
+	"encoding/binary"
+	"errors"
+	"math/big"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil/bech32"
+	"github.com/zeusyf/btcutil/taproot"
+)
+
+// Tags of the tagged hashes of BIP0352.
+const (
+	TagInputs       = "BIP0352/Inputs"
+	TagSharedSecret = "BIP0352/SharedSecret"
+	TagLabel        = "BIP0352/Label"
+)
+
+const (
+	// addressVersion is the version of addresses.
+	addressVersion = 0
+
+	// addressKeysLen is the size of the keys of an address.
+	addressKeysLen = 2 * btcec.PubKeyBytesLenCompressed
+)
+
+var (
+	// ErrUnknownNet describes an error where an address is encoded or
+	// decoded for a network without an address prefix.
+	ErrUnknownNet = errors.New("unknown network for silent payments")
+
+	// ErrInvalidPrefix describes an error where the human-readable part
+	// of an address is not the prefix of the network.
+	ErrInvalidPrefix = errors.New("address prefix does not match network")
+
+	// ErrInvalidAddress describes an error where an address has an
+	// unknown version, the wrong length, or an invalid key.
+	ErrInvalidAddress = errors.New("invalid silent payment address")
+
+	// ErrNoInputs describes an error where outputs are derived or scanned
+	// for a transaction without eligible inputs, or whose input keys sum
+	// to zero.
+	ErrNoInputs = errors.New("no eligible inputs")
+
+	// ErrInvalidTweak describes an error where a tweak is not less than
+	// the order of the curve.  It happens with negligible probability.
+	ErrInvalidTweak = errors.New("invalid silent payment tweak")
+)
+
+// prefixes are the address prefixes of the networks.
+var prefixes = map[*chaincfg.Params]string{
+	&chaincfg.MainNetParams:       "spomc",
+	&chaincfg.TestNet3Params:      "tspomc",
+	&chaincfg.RegressionNetParams: "rspomc",
+	&chaincfg.SimNetParams:        "sspomc",
+}
+
+// Prefix returns the address prefix of the passed network.
+func Prefix(net *chaincfg.Params) (string, error) {
+	prefix, ok := prefixes[net]
+	if !ok {
+		return "", ErrUnknownNet
+	}
+	return prefix, nil
+}
+
+// Address is a reusable payment address.
+type Address struct {
+	// ScanKey is the key senders derive outputs with, whose private key
+	// lets the receiver find them.
+	ScanKey *btcec.PublicKey
+
+	// SpendKey is the key outputs are derived from, tweaked with the
+	// label of labeled addresses.
+	SpendKey *btcec.PublicKey
+
+	// Net is the network of the address.
+	Net *chaincfg.Params
+}
+
+// Encode returns the bech32 encoding of the address.
+func (a *Address) Encode() (string, error) {
+	prefix, err := Prefix(a.Net)
+	if err != nil {
+		return "", err
+	}
+	keys := append(a.ScanKey.SerializeCompressed(),
+		a.SpendKey.SerializeCompressed()...)
+	groups, err := bech32.ConvertBits(keys, 8, 5, true)
+	if err != nil {
+		return "", err
+	}
+	return bech32.Encode(prefix, append([]byte{addressVersion}, groups...))
+}
+
+// String returns the encoding of the address, or an empty string when its
+// network has no prefix.
+func (a *Address) String() string {
+	s, _ := a.Encode()
+	return s
+}
+
+// DecodeAddress decodes an address of the passed network.
+func DecodeAddress(s string, net *chaincfg.Params) (*Address, error) {
+	prefix, err := Prefix(net)
+	if err != nil {
+		return nil, err
+	}
+	hrp, data, err := bech32.DecodeNoLimit(s)
+	if err != nil {
+		return nil, err
+	}
+	if hrp != prefix {
+		return nil, ErrInvalidPrefix
+	}
+	if len(data) == 0 || data[0] != addressVersion {
+		return nil, ErrInvalidAddress
+	}
+	keys, err := bech32.ConvertBits(data[1:], 5, 8, false)
+	if err != nil || len(keys) != addressKeysLen {
+		return nil, ErrInvalidAddress
+	}
+	scanKey, err := btcec.ParsePubKey(keys[:addressKeysLen/2], btcec.S256())
+	if err != nil {
+		return nil, ErrInvalidAddress
+	}
+	spendKey, err := btcec.ParsePubKey(keys[addressKeysLen/2:], btcec.S256())
+	if err != nil {
+		return nil, ErrInvalidAddress
+	}
+	return &Address{ScanKey: scanKey, SpendKey: spendKey, Net: net}, nil
+}
+
+// scalar returns the tagged hash of msgs as a scalar, rejecting hashes not
+// less than the order of the curve.
+func scalar(tag string, msgs ...[]byte) (*big.Int, error) {
+	h := taproot.TaggedHash(tag, msgs...)
+	s := new(big.Int).SetBytes(h[:])
+	if s.Cmp(btcec.S256().N) >= 0 {
+		return nil, ErrInvalidTweak
+	}
+	return s, nil
+}
+
+// serializeScalar returns the 32 byte big endian serialization of s.
+func serializeScalar(s *big.Int) []byte {
+	var b [32]byte
+	s.FillBytes(b[:])
+	return b[:]
+}
+
+// pubKey returns the public key of the point, or nil for the point at
+// infinity.
+func pubKey(x, y *big.Int) *btcec.PublicKey {
+	if x.Sign() == 0 && y.Sign() == 0 {
+		return nil
+	}
+	return &btcec.PublicKey{Curve: btcec.S256(), X: x, Y: y}
+}
+
+// add returns the sum of the keys, or nil for the point at infinity.
+func add(a, b *btcec.PublicKey) *btcec.PublicKey {
+	if a == nil {
+		return b
+	}
+	if b == nil {
+		return a
+	}
+	return pubKey(btcec.S256().Add(a.X, a.Y, b.X, b.Y))
+}
+
+// negate returns the negation of the key.
+func negate(k *btcec.PublicKey) *btcec.PublicKey {
+	return pubKey(new(big.Int).Set(k.X), new(big.Int).Sub(btcec.S256().P, k.Y))
+}
+
+// mult returns k times the key, or times the generator when the key is nil.
+func mult(k *btcec.PublicKey, s *big.Int) *btcec.PublicKey {
+	if k == nil {
+		return pubKey(btcec.S256().ScalarBaseMult(serializeScalar(s)))
+	}
+	return pubKey(btcec.S256().ScalarMult(k.X, k.Y, serializeScalar(s)))
+}
+
+// sharedSecretTweak returns the tweak of the k-th output derived from the
+// shared secret of a sender and a receiver.
+func sharedSecretTweak(secret *btcec.PublicKey, k uint32) (*big.Int, error) {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], k)
+	return scalar(TagSharedSecret, secret.SerializeCompressed(), b[:])
+}
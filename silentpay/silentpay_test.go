@@ -0,0 +1,103 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package silentpay_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil/bech32"
+	"github.com/zeusyf/btcutil/silentpay"
+)
+
+// privKey returns the private key of the passed scalar.
+func privKey(k byte) *btcec.PrivateKey {
+	priv, _ := btcec.PrivKeyFromBytes(btcec.S256(), []byte{k})
+	return priv
+}
+
+// TestAddress ensures addresses round trip through their encoding and are
+// rejected on the wrong network.
+func TestAddress(t *testing.T) {
+	addr := &silentpay.Address{
+		ScanKey:  privKey(1).PubKey(),
+		SpendKey: privKey(2).PubKey(),
+		Net:      &chaincfg.MainNetParams,
+	}
+	s, err := addr.Encode()
+	if err != nil {
+		t.Fatalf("Encode: %v", err)
+	}
+	if !strings.HasPrefix(s, "spomc1q") {
+		t.Fatalf("address %s has wrong prefix", s)
+	}
+	if addr.String() != s {
+		t.Fatalf("String returned %s, want %s", addr.String(), s)
+	}
+
+	got, err := silentpay.DecodeAddress(s, &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("DecodeAddress: %v", err)
+	}
+	if !got.ScanKey.IsEqual(addr.ScanKey) ||
+		!got.SpendKey.IsEqual(addr.SpendKey) {
+		t.Fatalf("decoded keys differ")
+	}
+
+	_, err = silentpay.DecodeAddress(s, &chaincfg.TestNet3Params)
+	if err != silentpay.ErrInvalidPrefix {
+		t.Fatalf("DecodeAddress on test network: got %v, want %v",
+			err, silentpay.ErrInvalidPrefix)
+	}
+	_, err = silentpay.DecodeAddress(s, &chaincfg.Params{})
+	if err != silentpay.ErrUnknownNet {
+		t.Fatalf("DecodeAddress on unknown network: got %v, want %v",
+			err, silentpay.ErrUnknownNet)
+	}
+	addr.Net = &chaincfg.Params{}
+	if _, err := addr.Encode(); err != silentpay.ErrUnknownNet {
+		t.Fatalf("Encode on unknown network: got %v, want %v",
+			err, silentpay.ErrUnknownNet)
+	}
+}
+
+// TestDecodeAddressInvalid ensures addresses with an unknown version, the
+// wrong length or invalid keys are rejected.
+func TestDecodeAddressInvalid(t *testing.T) {
+	keys := append(privKey(1).PubKey().SerializeCompressed(),
+		privKey(2).PubKey().SerializeCompressed()...)
+	// There is no point with an x coordinate of zero.
+	badKey := append([]byte{}, keys[:34]...)
+	badKey = append(badKey, make([]byte, 32)...)
+
+	tests := []struct {
+		name    string
+		version byte
+		keys    []byte
+	}{
+		{"version", 1, keys},
+		{"short", 0, keys[:65]},
+		{"long", 0, append(keys, 0)},
+		{"key", 0, badKey},
+	}
+	for _, test := range tests {
+		groups, err := bech32.ConvertBits(test.keys, 8, 5, true)
+		if err != nil {
+			t.Fatalf("%s: ConvertBits: %v", test.name, err)
+		}
+		s, err := bech32.Encode("spomc",
+			append([]byte{test.version}, groups...))
+		if err != nil {
+			t.Fatalf("%s: Encode: %v", test.name, err)
+		}
+		_, err = silentpay.DecodeAddress(s, &chaincfg.MainNetParams)
+		if err != silentpay.ErrInvalidAddress {
+			t.Errorf("%s: got %v, want %v", test.name, err,
+				silentpay.ErrInvalidAddress)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package slip39
+
+// expTable and logTable are the exponentials and logarithms of GF(256) with
+// the Rijndael reduction polynomial x^8 + x^4 + x^3 + x + 1 and generator
+// x + 1, used to multiply and divide field elements.
+var (
+	expTable [255]byte
+	logTable [256]byte
+)
+
+func init() {
+	p := 1
+	for i := 0; i < 255; i++ {
+		expTable[i] = byte(p)
+		logTable[p] = byte(i)
+
+		// Multiply by the generator x + 1.
+		p ^= p << 1
+		if p&0x100 != 0 {
+			p ^= 0x11b
+		}
+	}
+}
+
+// point is a share of a secret: the values of the polynomials, one per byte
+// of the secret, at x.
+type point struct {
+	x     byte
+	value []byte
+}
+
+// interpolate returns the values at x of the polynomials of the lowest degree
+// passing through the passed points, using Lagrange interpolation.  The points
+// must have distinct x coordinates and values of the same length.
+func interpolate(points []point, x byte) []byte {
+	for _, p := range points {
+		if p.x == x {
+			return append([]byte(nil), p.value...)
+		}
+	}
+
+	// The basis polynomial of point i evaluated at x is the product of
+	// (x - x_j) / (x_i - x_j) over all j != i, where subtraction is XOR.
+	// The logarithm of the numerator is computed once for all points,
+	// including the term of point i itself, which is divided out again.
+	var logProd int
+	for _, p := range points {
+		logProd += int(logTable[p.x^x])
+	}
+
+	result := make([]byte, len(points[0].value))
+	for i, p := range points {
+		logBasis := logProd - int(logTable[p.x^x])
+		for j, q := range points {
+			if j != i {
+				logBasis -= int(logTable[p.x^q.x])
+			}
+		}
+		logBasis = (logBasis%255 + 255) % 255
+
+		for k, v := range p.value {
+			if v != 0 {
+				result[k] ^= expTable[(int(logTable[v])+logBasis)%255]
+			}
+		}
+	}
+	return result
+}
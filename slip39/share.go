@@ -0,0 +1,265 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package slip39
+
+import (
+	"strings"
+)
+
+const (
+	// radixBits is the number of bits encoded by a word.
+	radixBits = 10
+
+	// radix is the number of words in the word list.
+	radix = 1 << radixBits
+
+	// idBits is the length of the random identifier of a set of shares.
+	idBits = 15
+
+	// metadataWords is the number of words of a share which do not encode
+	// the share value: the identifier and parameters, and the checksum.
+	metadataWords = 4 + checksumWords
+
+	// checksumWords is the number of words of the checksum.
+	checksumWords = 3
+
+	// MinMnemonicWords is the number of words of the shortest share, which
+	// holds a 128 bit value.
+	MinMnemonicWords = metadataWords + (8*minSecretLen+radixBits-1)/radixBits
+)
+
+// customization returns the customization string of the checksum, which
+// separates shares of extendable and non-extendable backups.
+func customization(extendable bool) string {
+	if extendable {
+		return "shamir_extendable"
+	}
+	return "shamir"
+}
+
+// checksumGen are the generators of the RS1024 checksum.
+var checksumGen = [10]uint32{
+	0xe0e040, 0x1c1c080, 0x3838100, 0x7070200, 0xe0e0009,
+	0x1c0c2412, 0x38086c24, 0x3090fc48, 0x21b1f890, 0x3f3f120,
+}
+
+// polymod computes the RS1024 checksum of the customization string followed
+// by the passed words.
+func polymod(custom string, words []uint16) uint32 {
+	chk := uint32(1)
+	step := func(v uint32) {
+		b := chk >> 20
+		chk = (chk&0xfffff)<<radixBits ^ v
+		for i, gen := range checksumGen {
+			if (b>>uint(i))&1 != 0 {
+				chk ^= gen
+			}
+		}
+	}
+	for i := 0; i < len(custom); i++ {
+		step(uint32(custom[i]))
+	}
+	for _, w := range words {
+		step(uint32(w))
+	}
+	return chk
+}
+
+// Share is a single share of a master secret split with GenerateShares.
+type Share struct {
+	// Identifier is the random identifier common to all shares of a
+	// master secret.
+	Identifier uint16
+
+	// Extendable is set when more shares of the same master secret may be
+	// created later.  It excludes the identifier from the encryption of
+	// the master secret.
+	Extendable bool
+
+	// IterationExponent selects 10000 * 2^IterationExponent PBKDF2
+	// iterations for the encryption of the master secret.
+	IterationExponent uint8
+
+	// GroupIndex is the index of the group of the share.
+	GroupIndex uint8
+
+	// GroupThreshold is the number of groups required to recover the
+	// master secret.
+	GroupThreshold uint8
+
+	// GroupCount is the total number of groups.
+	GroupCount uint8
+
+	// MemberIndex is the index of the share within its group.
+	MemberIndex uint8
+
+	// MemberThreshold is the number of shares of the group required to
+	// recover the group secret.
+	MemberThreshold uint8
+
+	// Value is the share of the group secret.
+	Value []byte
+}
+
+// wordWriter packs bit fields into words.
+type wordWriter struct {
+	words []uint16
+	acc   uint64
+	n     uint
+}
+
+// write appends the low bits of v, most significant bit first.
+func (w *wordWriter) write(v uint32, bits uint) {
+	w.acc = w.acc<<bits | uint64(v)&(1<<bits-1)
+	w.n += bits
+	for w.n >= radixBits {
+		w.n -= radixBits
+		w.words = append(w.words, uint16(w.acc>>w.n)&(radix-1))
+	}
+	w.acc &= 1<<w.n - 1
+}
+
+// wordReader unpacks bit fields from words.
+type wordReader struct {
+	words []uint16
+	acc   uint64
+	n     uint
+}
+
+// read returns the next bits of the words, most significant bit first.
+func (r *wordReader) read(bits uint) uint32 {
+	for r.n < bits {
+		r.acc = r.acc<<radixBits | uint64(r.words[0])
+		r.words = r.words[1:]
+		r.n += radixBits
+	}
+	r.n -= bits
+	v := uint32(r.acc >> r.n & (1<<bits - 1))
+	r.acc &= 1<<r.n - 1
+	return v
+}
+
+// Indexes returns the word list indexes of the words of the share, including
+// the checksum.
+func (s *Share) Indexes() []uint16 {
+	var ext uint32
+	if s.Extendable {
+		ext = 1
+	}
+
+	w := &wordWriter{}
+	w.write(uint32(s.Identifier), idBits)
+	w.write(ext, 1)
+	w.write(uint32(s.IterationExponent), 4)
+	w.write(uint32(s.GroupIndex), 4)
+	w.write(uint32(s.GroupThreshold)-1, 4)
+	w.write(uint32(s.GroupCount)-1, 4)
+	w.write(uint32(s.MemberIndex), 4)
+	w.write(uint32(s.MemberThreshold)-1, 4)
+
+	// The value is padded with leading zero bits to a whole number of
+	// words.
+	valueWords := (8*len(s.Value) + radixBits - 1) / radixBits
+	w.write(0, uint(radixBits*valueWords-8*len(s.Value)))
+	for _, b := range s.Value {
+		w.write(uint32(b), 8)
+	}
+
+	custom := customization(s.Extendable)
+	chk := polymod(custom, append(w.words, 0, 0, 0)) ^ 1
+	for i := checksumWords - 1; i >= 0; i-- {
+		w.write(chk>>(radixBits*uint(i)), radixBits)
+	}
+	return w.words
+}
+
+// Mnemonic returns the share encoded as words separated by spaces.
+func (s *Share) Mnemonic() string {
+	indexes := s.Indexes()
+	words := make([]string, len(indexes))
+	for i, index := range indexes {
+		words[i] = wordList[index]
+	}
+	return strings.Join(words, " ")
+}
+
+// wordIndexes maps the first four letters of every word to its index.
+var wordIndexes = func() map[string]uint16 {
+	m := make(map[string]uint16, radix)
+	for i, word := range wordList {
+		m[prefix(word)] = uint16(i)
+	}
+	return m
+}()
+
+// prefix returns the up to four letters identifying a word.
+func prefix(word string) string {
+	if len(word) > 4 {
+		return word[:4]
+	}
+	return word
+}
+
+// ParseShare decodes a share from its mnemonic.  Words are matched ignoring
+// case, and may be abbreviated to their first four letters, which identify
+// them uniquely.
+func ParseShare(mnemonic string) (*Share, error) {
+	fields := strings.Fields(strings.ToLower(mnemonic))
+	indexes := make([]uint16, len(fields))
+	for i, field := range fields {
+		index, ok := wordIndexes[prefix(field)]
+		if !ok || !strings.HasPrefix(wordList[index], field) {
+			return nil, ErrInvalidWord
+		}
+		indexes[i] = index
+	}
+	return ShareFromIndexes(indexes)
+}
+
+// ShareFromIndexes decodes a share from the word list indexes of its words.
+func ShareFromIndexes(indexes []uint16) (*Share, error) {
+	if len(indexes) < MinMnemonicWords {
+		return nil, ErrInvalidShareLen
+	}
+	for _, index := range indexes {
+		if index >= radix {
+			return nil, ErrInvalidWord
+		}
+	}
+
+	// The padding of the value must be shorter than a byte, and the value
+	// a whole number of 16 bit units.
+	valueWords := len(indexes) - metadataWords
+	padding := uint(radixBits*valueWords) % 16
+	if padding > 8 {
+		return nil, ErrInvalidShareLen
+	}
+
+	r := &wordReader{words: indexes}
+	s := &Share{}
+	s.Identifier = uint16(r.read(idBits))
+	s.Extendable = r.read(1) == 1
+	if polymod(customization(s.Extendable), indexes) != 1 {
+		return nil, ErrInvalidChecksum
+	}
+	s.IterationExponent = uint8(r.read(4))
+	s.GroupIndex = uint8(r.read(4))
+	s.GroupThreshold = uint8(r.read(4)) + 1
+	s.GroupCount = uint8(r.read(4)) + 1
+	s.MemberIndex = uint8(r.read(4))
+	s.MemberThreshold = uint8(r.read(4)) + 1
+	if s.GroupThreshold > s.GroupCount {
+		return nil, ErrInvalidThreshold
+	}
+
+	if r.read(padding) != 0 {
+		return nil, ErrInvalidPadding
+	}
+	s.Value = make([]byte, (uint(radixBits*valueWords)-padding)/8)
+	for i := range s.Value {
+		s.Value[i] = byte(r.read(8))
+	}
+	return s, nil
+}
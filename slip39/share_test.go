@@ -0,0 +1,71 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package slip39_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zeusyf/btcutil/slip39"
+)
+
+// TestParseShare ensures mnemonics are decoded leniently where the encoding
+// allows it and rejected when corrupted.
+func TestParseShare(t *testing.T) {
+	const mnemonic = "duckling enlarge academic academic agency result length solution fridge kidney coal piece deal husband erode duke ajar critical decision keyboard"
+
+	s, err := slip39.ParseShare(mnemonic)
+	if err != nil {
+		t.Fatalf("ParseShare: %v", err)
+	}
+	if s.Extendable || s.IterationExponent != 0 || s.GroupThreshold != 1 ||
+		s.GroupCount != 1 || s.MemberThreshold != 1 || len(s.Value) != 16 {
+
+		t.Errorf("ParseShare: unexpected share %+v", s)
+	}
+	if indexes := s.Indexes(); len(indexes) != slip39.MinMnemonicWords {
+		t.Errorf("Indexes: got %d words, want %d", len(indexes),
+			slip39.MinMnemonicWords)
+	}
+
+	// Words may be abbreviated to four letters and in any case.
+	var abbreviated []string
+	for _, word := range strings.Fields(mnemonic) {
+		if len(word) > 4 {
+			word = word[:4]
+		}
+		abbreviated = append(abbreviated, strings.ToUpper(word))
+	}
+	s2, err := slip39.ParseShare(strings.Join(abbreviated, "  "))
+	if err != nil {
+		t.Fatalf("ParseShare of abbreviated mnemonic: %v", err)
+	}
+	if s2.Mnemonic() != mnemonic {
+		t.Errorf("abbreviated mnemonic decoded to %q", s2.Mnemonic())
+	}
+
+	tests := []struct {
+		name     string
+		mnemonic string
+		err      error
+	}{{
+		name:     "changed word",
+		mnemonic: strings.Replace(mnemonic, "fridge", "friar", 1),
+		err:      slip39.ErrInvalidChecksum,
+	}, {
+		name:     "unknown word",
+		mnemonic: strings.Replace(mnemonic, "fridge", "fridges", 1),
+		err:      slip39.ErrInvalidWord,
+	}, {
+		name:     "short",
+		mnemonic: mnemonic[:strings.LastIndexByte(mnemonic, ' ')],
+		err:      slip39.ErrInvalidShareLen,
+	}}
+	for _, test := range tests {
+		if _, err := slip39.ParseShare(test.mnemonic); err != test.err {
+			t.Errorf("%s: got error %v, want %v", test.name, err, test.err)
+		}
+	}
+}
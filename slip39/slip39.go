@@ -0,0 +1,441 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package slip39 splits master secrets into mnemonic shares and recovers them
+// as specified by SLIP-0039, Shamir's Secret-Sharing for Mnemonic Codes.
+//
+// A master secret, such as the seed of an hdkeychain master key, is split
+// into groups, of which a threshold number is needed to recover it.  Each
+// group is in turn split into member shares, of which a per group threshold
+// number is needed to recover the group's share.  A backup could for example
+// require two of three groups: the owner's single share, two of three shares
+// held by family members, or three of five shares held by custodians.  No set
+// of shares below the thresholds reveals anything about the master secret.
+//
+// The master secret is encrypted with a passphrase before it is split, so
+// the shares alone do not reveal it either.  Every passphrase decrypts the
+// shares to a valid master secret, which allows plausible deniability.
+package slip39
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"errors"
+	"io"
+
+	"github.com/zeusyf/btcutil/entropy"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	// minSecretLen is the minimum length of a master secret in bytes.
+	minSecretLen = 16
+
+	// maxShareCount is the maximum number of groups, and of members of a
+	// group.
+	maxShareCount = 16
+
+	// secretIndex and digestIndex are the x coordinates at which the
+	// polynomials of a split secret evaluate to the secret and to its
+	// digest.
+	secretIndex = 255
+	digestIndex = 254
+
+	// digestLen is the length of the digest verifying a recovered secret.
+	digestLen = 4
+
+	// baseIterations is the number of PBKDF2 iterations of the encryption
+	// for an iteration exponent of zero, spread over all rounds.
+	baseIterations = 10000
+
+	// rounds is the number of rounds of the Feistel network encrypting the
+	// master secret.
+	rounds = 4
+
+	// DefaultIterationExponent is the iteration exponent used by
+	// GenerateShares unless WithIterationExponent is passed.
+	DefaultIterationExponent = 1
+)
+
+var (
+	// ErrInvalidSecretLen describes an error where a master secret is
+	// shorter than 128 bits or not a whole number of 16 bit units.
+	ErrInvalidSecretLen = errors.New("master secret must be at least 16 " +
+		"bytes and of even length")
+
+	// ErrInvalidThreshold describes an error where a threshold is zero,
+	// exceeds its share count, or the share count exceeds 16.
+	ErrInvalidThreshold = errors.New("invalid threshold or share count")
+
+	// ErrSingleMemberThreshold describes an error where a group with a
+	// member threshold of one has multiple members, which would only be
+	// copies of the same share.
+	ErrSingleMemberThreshold = errors.New("groups with a member threshold " +
+		"of 1 must have a single member")
+
+	// ErrInvalidIterationExponent describes an error where an iteration
+	// exponent does not fit in 4 bits.
+	ErrInvalidIterationExponent = errors.New("iteration exponent must be " +
+		"at most 15")
+
+	// ErrInvalidWord describes an error where a mnemonic contains a word
+	// which is not in the word list.
+	ErrInvalidWord = errors.New("invalid mnemonic word")
+
+	// ErrInvalidShareLen describes an error where a mnemonic has too few
+	// words or a number of words which can not encode a share.
+	ErrInvalidShareLen = errors.New("invalid mnemonic length")
+
+	// ErrInvalidChecksum describes an error where the checksum of a
+	// mnemonic does not match.
+	ErrInvalidChecksum = errors.New("invalid mnemonic checksum")
+
+	// ErrInvalidPadding describes an error where the padding bits of a
+	// share value are not zero.
+	ErrInvalidPadding = errors.New("invalid mnemonic padding")
+
+	// ErrShareMismatch describes an error where shares being combined
+	// belong to different master secrets or disagree on their parameters.
+	ErrShareMismatch = errors.New("shares do not belong together")
+
+	// ErrInsufficientShares describes an error where too few groups, or
+	// too few members of a group, are passed to recover a secret.
+	ErrInsufficientShares = errors.New("insufficient shares")
+
+	// ErrDigestMismatch describes an error where a recovered secret does
+	// not match its digest, because a share was altered or shares of
+	// different secrets were mixed.
+	ErrDigestMismatch = errors.New("recovered secret does not match its " +
+		"digest")
+)
+
+// Group describes the member shares of a group.
+type Group struct {
+	// MemberThreshold is the number of member shares required to recover
+	// the group's share of the master secret.
+	MemberThreshold int
+
+	// MemberCount is the number of member shares of the group.
+	MemberCount int
+}
+
+// GenerateOption configures GenerateShares.
+type GenerateOption func(*generateConfig)
+
+// generateConfig holds the settings of GenerateShares.
+type generateConfig struct {
+	iterationExponent uint8
+	extendable        bool
+	entropySrc        io.Reader
+}
+
+// WithIterationExponent sets the iteration exponent of the passphrase
+// encryption.  Every increment doubles the work of both recovering and
+// attacking the passphrase.
+func WithIterationExponent(e uint8) GenerateOption {
+	return func(c *generateConfig) {
+		c.iterationExponent = e
+	}
+}
+
+// WithExtendable sets whether the shares are extendable, so that more shares
+// of the same master secret with the same identifier can be created later.
+// Shares are extendable by default.  Non-extendable shares are only needed for
+// compatibility with software which predates extendable backups.
+func WithExtendable(extendable bool) GenerateOption {
+	return func(c *generateConfig) {
+		c.extendable = extendable
+	}
+}
+
+// WithEntropy sets the source of the random identifier and the random
+// polynomial coefficients.  By default the default source of the entropy
+// package is used.
+func WithEntropy(src io.Reader) GenerateOption {
+	return func(c *generateConfig) {
+		c.entropySrc = src
+	}
+}
+
+// GenerateShares splits the master secret into shares of the passed groups,
+// groupThreshold of which are required to recover it, after encrypting it
+// with the passphrase.  The returned shares are indexed by group and member.
+func GenerateShares(groupThreshold int, groups []Group, masterSecret,
+	passphrase []byte, opts ...GenerateOption) ([][]*Share, error) {
+
+	cfg := generateConfig{
+		iterationExponent: DefaultIterationExponent,
+		extendable:        true,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	if len(masterSecret) < minSecretLen || len(masterSecret)%2 != 0 {
+		return nil, ErrInvalidSecretLen
+	}
+	if cfg.iterationExponent > 15 {
+		return nil, ErrInvalidIterationExponent
+	}
+	if groupThreshold < 1 || groupThreshold > len(groups) ||
+		len(groups) > maxShareCount {
+
+		return nil, ErrInvalidThreshold
+	}
+	for _, g := range groups {
+		if g.MemberThreshold < 1 || g.MemberThreshold > g.MemberCount ||
+			g.MemberCount > maxShareCount {
+
+			return nil, ErrInvalidThreshold
+		}
+		if g.MemberThreshold == 1 && g.MemberCount > 1 {
+			return nil, ErrSingleMemberThreshold
+		}
+	}
+
+	var id [2]byte
+	if err := entropy.Read(cfg.entropySrc, id[:]); err != nil {
+		return nil, err
+	}
+	identifier := (uint16(id[0])<<8 | uint16(id[1])) & (1<<idBits - 1)
+
+	ems := encrypt(masterSecret, passphrase, cfg.iterationExponent,
+		identifier, cfg.extendable)
+	groupSecrets, err := splitSecret(cfg.entropySrc, groupThreshold,
+		len(groups), ems)
+	if err != nil {
+		return nil, err
+	}
+
+	shares := make([][]*Share, len(groups))
+	for i, g := range groups {
+		members, err := splitSecret(cfg.entropySrc, g.MemberThreshold,
+			g.MemberCount, groupSecrets[i].value)
+		if err != nil {
+			return nil, err
+		}
+		shares[i] = make([]*Share, len(members))
+		for j, m := range members {
+			shares[i][j] = &Share{
+				Identifier:        identifier,
+				Extendable:        cfg.extendable,
+				IterationExponent: cfg.iterationExponent,
+				GroupIndex:        uint8(i),
+				GroupThreshold:    uint8(groupThreshold),
+				GroupCount:        uint8(len(groups)),
+				MemberIndex:       m.x,
+				MemberThreshold:   uint8(g.MemberThreshold),
+				Value:             m.value,
+			}
+		}
+	}
+	return shares, nil
+}
+
+// CombineShares recovers the master secret from the passed shares and
+// decrypts it with the passphrase.  The shares must include at least the
+// member threshold of shares of at least the group threshold of groups;
+// extra shares and shares of incomplete groups are ignored.
+//
+// A wrong passphrase is not detected: it yields a different master secret.
+func CombineShares(shares []*Share, passphrase []byte) ([]byte, error) {
+	if len(shares) == 0 {
+		return nil, ErrInsufficientShares
+	}
+
+	// All shares must agree on the common parameters, and shares of the
+	// same group on the member threshold.
+	first := shares[0]
+	if first.GroupThreshold < 1 || first.GroupThreshold > first.GroupCount {
+		return nil, ErrInvalidThreshold
+	}
+	groups := make(map[uint8][]point)
+	memberThresholds := make(map[uint8]uint8)
+	for _, s := range shares {
+		if s.Identifier != first.Identifier ||
+			s.Extendable != first.Extendable ||
+			s.IterationExponent != first.IterationExponent ||
+			s.GroupThreshold != first.GroupThreshold ||
+			s.GroupCount != first.GroupCount ||
+			len(s.Value) != len(first.Value) ||
+			s.GroupIndex >= s.GroupCount || s.MemberThreshold < 1 {
+
+			return nil, ErrShareMismatch
+		}
+		if t, ok := memberThresholds[s.GroupIndex]; ok && t != s.MemberThreshold {
+			return nil, ErrShareMismatch
+		}
+		memberThresholds[s.GroupIndex] = s.MemberThreshold
+
+		// A share entered twice is only used once.
+		duplicate := false
+		for _, p := range groups[s.GroupIndex] {
+			if p.x != s.MemberIndex {
+				continue
+			}
+			if !bytes.Equal(p.value, s.Value) {
+				return nil, ErrShareMismatch
+			}
+			duplicate = true
+		}
+		if !duplicate {
+			groups[s.GroupIndex] = append(groups[s.GroupIndex],
+				point{x: s.MemberIndex, value: s.Value})
+		}
+	}
+
+	// Recover the shares of the complete groups, in group order so the
+	// result does not depend on the order of the passed shares.
+	var groupShares []point
+	for i := uint8(0); i < first.GroupCount; i++ {
+		members := groups[i]
+		threshold := int(memberThresholds[i])
+		if len(members) == 0 || len(members) < threshold {
+			continue
+		}
+		value, err := recoverSecret(members[:threshold])
+		if err != nil {
+			return nil, err
+		}
+		groupShares = append(groupShares, point{x: i, value: value})
+		if len(groupShares) == int(first.GroupThreshold) {
+			break
+		}
+	}
+	if len(groupShares) < int(first.GroupThreshold) {
+		return nil, ErrInsufficientShares
+	}
+
+	ems, err := recoverSecret(groupShares)
+	if err != nil {
+		return nil, err
+	}
+	return decrypt(ems, passphrase, first.IterationExponent,
+		first.Identifier, first.Extendable), nil
+}
+
+// splitSecret splits the secret into count shares, threshold of which
+// recover it.  The shares are the values at 0 through count-1 of random
+// polynomials which evaluate to the secret at secretIndex and to a digest of
+// it at digestIndex.
+func splitSecret(src io.Reader, threshold, count int, secret []byte) ([]point, error) {
+	if threshold < 1 || threshold > count || count > maxShareCount {
+		return nil, ErrInvalidThreshold
+	}
+
+	shares := make([]point, 0, count)
+	if threshold == 1 {
+		for i := 0; i < count; i++ {
+			value := append([]byte(nil), secret...)
+			shares = append(shares, point{x: byte(i), value: value})
+		}
+		return shares, nil
+	}
+
+	// The first threshold-2 shares are random, and together with the
+	// digest and the secret determine the polynomials.
+	for i := 0; i < threshold-2; i++ {
+		value := make([]byte, len(secret))
+		if err := entropy.Read(src, value); err != nil {
+			return nil, err
+		}
+		shares = append(shares, point{x: byte(i), value: value})
+	}
+
+	digest := make([]byte, len(secret))
+	if err := entropy.Read(src, digest[digestLen:]); err != nil {
+		return nil, err
+	}
+	copy(digest, secretDigest(digest[digestLen:], secret))
+
+	base := append(shares[:len(shares):len(shares)],
+		point{x: digestIndex, value: digest},
+		point{x: secretIndex, value: secret})
+	for i := threshold - 2; i < count; i++ {
+		shares = append(shares, point{x: byte(i), value: interpolate(base, byte(i))})
+	}
+	return shares, nil
+}
+
+// recoverSecret recovers a secret split with splitSecret from threshold of
+// its shares and verifies its digest.
+func recoverSecret(shares []point) ([]byte, error) {
+	if len(shares) == 1 {
+		return append([]byte(nil), shares[0].value...), nil
+	}
+
+	secret := interpolate(shares, secretIndex)
+	digest := interpolate(shares, digestIndex)
+	if !hmac.Equal(digest[:digestLen], secretDigest(digest[digestLen:], secret)) {
+		return nil, ErrDigestMismatch
+	}
+	return secret, nil
+}
+
+// secretDigest returns the digest of the secret keyed with random bytes.
+func secretDigest(random, secret []byte) []byte {
+	mac := hmac.New(sha256.New, random)
+	mac.Write(secret)
+	return mac.Sum(nil)[:digestLen]
+}
+
+// salt returns the salt of the encryption rounds.  Only non-extendable shares
+// bind the encryption to their identifier.
+func salt(identifier uint16, extendable bool) []byte {
+	if extendable {
+		return nil
+	}
+	return append([]byte(customization(false)), byte(identifier>>8),
+		byte(identifier))
+}
+
+// roundFunction is the round function of the Feistel network encrypting the
+// master secret.
+func roundFunction(i byte, passphrase []byte, e uint8, salt, r []byte) []byte {
+	iterations := (baseIterations << e) / rounds
+	password := append([]byte{i}, passphrase...)
+	return pbkdf2.Key(password, append(salt[:len(salt):len(salt)], r...),
+		iterations, len(r), sha256.New)
+}
+
+// encrypt encrypts the master secret with the passphrase using a four round
+// Feistel network.
+func encrypt(masterSecret, passphrase []byte, e uint8, identifier uint16,
+	extendable bool) []byte {
+
+	half := len(masterSecret) / 2
+	l := append([]byte(nil), masterSecret[:half]...)
+	r := append([]byte(nil), masterSecret[half:]...)
+	s := salt(identifier, extendable)
+	for i := 0; i < rounds; i++ {
+		f := roundFunction(byte(i), passphrase, e, s, r)
+		xor(l, f)
+		l, r = r, l
+	}
+	return append(r, l...)
+}
+
+// decrypt reverses encrypt.
+func decrypt(ems, passphrase []byte, e uint8, identifier uint16,
+	extendable bool) []byte {
+
+	half := len(ems) / 2
+	l := append([]byte(nil), ems[:half]...)
+	r := append([]byte(nil), ems[half:]...)
+	s := salt(identifier, extendable)
+	for i := rounds - 1; i >= 0; i-- {
+		f := roundFunction(byte(i), passphrase, e, s, r)
+		xor(l, f)
+		l, r = r, l
+	}
+	return append(r, l...)
+}
+
+// xor sets dst to dst XOR src.
+func xor(dst, src []byte) {
+	for i := range dst {
+		dst[i] ^= src[i]
+	}
+}
@@ -0,0 +1,201 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package slip39_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/zeusyf/btcutil/slip39"
+)
+
+// parseShares parses the passed mnemonics.
+func parseShares(t *testing.T, mnemonics ...string) []*slip39.Share {
+	t.Helper()
+	shares := make([]*slip39.Share, len(mnemonics))
+	for i, m := range mnemonics {
+		s, err := slip39.ParseShare(m)
+		if err != nil {
+			t.Fatalf("ParseShare(%q): %v", m, err)
+		}
+		shares[i] = s
+	}
+	return shares
+}
+
+// TestVectors ensures shares of the SLIP-0039 test vectors combine to their
+// master secrets.
+func TestVectors(t *testing.T) {
+	tests := []struct {
+		name      string
+		mnemonics []string
+		want      string
+	}{{
+		name: "1-of-1 share",
+		mnemonics: []string{
+			"duckling enlarge academic academic agency result length solution fridge kidney coal piece deal husband erode duke ajar critical decision keyboard",
+		},
+		want: "bb54aac4b89dc868ba37d9cc21b2cece",
+	}, {
+		name: "2-of-3 shares",
+		mnemonics: []string{
+			"shadow pistol academic always adequate wildlife fancy gross oasis cylinder mustang wrist rescue view short owner flip making coding armed",
+			"shadow pistol academic acid actress prayer class unknown daughter sweater depict flip twice unkind craft early superior advocate guest smoking",
+		},
+		want: "b43ceb7e57a0ea8766221624d01b0864",
+	}}
+
+	for _, test := range tests {
+		shares := parseShares(t, test.mnemonics...)
+		secret, err := slip39.CombineShares(shares, []byte("TREZOR"))
+		if err != nil {
+			t.Errorf("%s: CombineShares: %v", test.name, err)
+			continue
+		}
+		if hex.EncodeToString(secret) != test.want {
+			t.Errorf("%s: got secret %x, want %s", test.name, secret,
+				test.want)
+		}
+
+		// Shares must encode back to the same mnemonics.
+		for i, s := range shares {
+			if got := s.Mnemonic(); got != test.mnemonics[i] {
+				t.Errorf("%s: Mnemonic: got %q, want %q", test.name,
+					got, test.mnemonics[i])
+			}
+		}
+	}
+
+	// Too few shares of the 2-of-3 vector must be rejected.
+	shares := parseShares(t, tests[1].mnemonics[0])
+	if _, err := slip39.CombineShares(shares, nil); err != slip39.ErrInsufficientShares {
+		t.Errorf("CombineShares: got error %v, want %v", err,
+			slip39.ErrInsufficientShares)
+	}
+}
+
+// TestGenerateShares ensures generated shares of a two level scheme recover
+// the master secret from any qualifying subset and not from others.
+func TestGenerateShares(t *testing.T) {
+	secret := bytes.Repeat([]byte{0x5a}, 32)
+	passphrase := []byte("passphrase")
+	groups := []slip39.Group{
+		{MemberThreshold: 1, MemberCount: 1},
+		{MemberThreshold: 2, MemberCount: 3},
+		{MemberThreshold: 3, MemberCount: 5},
+	}
+	shares, err := slip39.GenerateShares(2, groups, secret, passphrase,
+		slip39.WithIterationExponent(0))
+	if err != nil {
+		t.Fatalf("GenerateShares: %v", err)
+	}
+	for i, g := range groups {
+		if len(shares[i]) != g.MemberCount {
+			t.Fatalf("group %d: got %d shares, want %d", i,
+				len(shares[i]), g.MemberCount)
+		}
+	}
+
+	// Round trip every share through its mnemonic.
+	for _, group := range shares {
+		for j, s := range group {
+			parsed, err := slip39.ParseShare(s.Mnemonic())
+			if err != nil {
+				t.Fatalf("ParseShare: %v", err)
+			}
+			group[j] = parsed
+		}
+	}
+
+	combine := func(shares ...*slip39.Share) ([]byte, error) {
+		return slip39.CombineShares(shares, passphrase)
+	}
+	valid := [][]*slip39.Share{
+		{shares[0][0], shares[1][0], shares[1][2]},
+		{shares[2][4], shares[1][1], shares[2][0], shares[1][0], shares[2][2]},
+		{shares[0][0], shares[2][1], shares[2][2], shares[2][3]},
+		{shares[0][0], shares[0][0], shares[1][1], shares[1][2]},
+		{shares[0][0], shares[1][0], shares[1][1], shares[1][2], shares[2][0]},
+	}
+	for i, set := range valid {
+		got, err := combine(set...)
+		if err != nil {
+			t.Errorf("valid set %d: CombineShares: %v", i, err)
+			continue
+		}
+		if !bytes.Equal(got, secret) {
+			t.Errorf("valid set %d: got secret %x, want %x", i, got, secret)
+		}
+	}
+
+	insufficient := [][]*slip39.Share{
+		{shares[0][0]},
+		{shares[0][0], shares[1][0]},
+		{shares[1][0], shares[1][1], shares[2][0], shares[2][1]},
+	}
+	for i, set := range insufficient {
+		if _, err := combine(set...); err != slip39.ErrInsufficientShares {
+			t.Errorf("insufficient set %d: got error %v, want %v", i,
+				err, slip39.ErrInsufficientShares)
+		}
+	}
+
+	// A wrong passphrase yields another secret, and an altered share a
+	// digest mismatch.
+	got, err := slip39.CombineShares(valid[0], []byte("wrong"))
+	if err != nil || bytes.Equal(got, secret) {
+		t.Errorf("wrong passphrase: got %x (%v)", got, err)
+	}
+	altered := *shares[1][0]
+	altered.Value = append([]byte(nil), altered.Value...)
+	altered.Value[0] ^= 1
+	_, err = combine(shares[0][0], &altered, shares[1][2])
+	if err != slip39.ErrDigestMismatch {
+		t.Errorf("altered share: got error %v, want %v", err,
+			slip39.ErrDigestMismatch)
+	}
+
+	// Shares of another secret must not combine.
+	other, err := slip39.GenerateShares(2, groups, secret, passphrase,
+		slip39.WithIterationExponent(0))
+	if err != nil {
+		t.Fatalf("GenerateShares: %v", err)
+	}
+	if other[0][0].Identifier != shares[0][0].Identifier {
+		_, err = combine(shares[0][0], other[1][0], other[1][1])
+		if err != slip39.ErrShareMismatch {
+			t.Errorf("mixed shares: got error %v, want %v", err,
+				slip39.ErrShareMismatch)
+		}
+	}
+}
+
+// TestGenerateSharesErrors ensures invalid parameters are rejected.
+func TestGenerateSharesErrors(t *testing.T) {
+	secret := make([]byte, 16)
+	tests := []struct {
+		name      string
+		threshold int
+		groups    []slip39.Group
+		secret    []byte
+		err       error
+	}{
+		{"short secret", 1, []slip39.Group{{1, 1}}, make([]byte, 14), slip39.ErrInvalidSecretLen},
+		{"odd secret", 1, []slip39.Group{{1, 1}}, make([]byte, 17), slip39.ErrInvalidSecretLen},
+		{"zero threshold", 0, []slip39.Group{{1, 1}}, secret, slip39.ErrInvalidThreshold},
+		{"threshold above count", 2, []slip39.Group{{1, 1}}, secret, slip39.ErrInvalidThreshold},
+		{"member threshold above count", 1, []slip39.Group{{3, 2}}, secret, slip39.ErrInvalidThreshold},
+		{"too many members", 1, []slip39.Group{{2, 17}}, secret, slip39.ErrInvalidThreshold},
+		{"copies of one share", 1, []slip39.Group{{1, 2}}, secret, slip39.ErrSingleMemberThreshold},
+	}
+	for _, test := range tests {
+		_, err := slip39.GenerateShares(test.threshold, test.groups,
+			test.secret, nil)
+		if err != test.err {
+			t.Errorf("%s: got error %v, want %v", test.name, err, test.err)
+		}
+	}
+}
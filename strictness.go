@@ -0,0 +1,295 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package btcutil
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcutil/base58"
+	"github.com/zeusyf/btcutil/bech32"
+	"golang.org/x/crypto/ripemd160"
+)
+
+// ErrAmbiguousRepair describes an error where a corrupted string could be
+// repaired into more than one valid encoding, so none of them is returned.
+var ErrAmbiguousRepair = errors.New("corrupted encoding has more than one " +
+	"possible repair")
+
+// Strictness controls which deviations from the canonical encoding the
+// decoders accepting DecodeOptions tolerate.
+type Strictness uint8
+
+const (
+	// Strict accepts only the canonical encoding.  It is the default, and
+	// the only level suitable for production payment paths.
+	Strict Strictness = iota
+
+	// Lenient additionally ignores white space and invisible formatting
+	// characters anywhere in the input, as well as quotes, brackets and
+	// sentence punctuation around it, which commonly sneak in when
+	// copying from documents and chat messages.
+	Lenient
+
+	// Recovery additionally accepts input whose checksum is missing, and
+	// repairs a single substituted, missing or extra character when that
+	// yields exactly one valid encoding.  It is meant for tools salvaging
+	// damaged backups, whose users must confirm the result against another
+	// record, since a repair may produce a valid but unintended encoding.
+	Recovery
+)
+
+// String returns the Strictness in human-readable form.
+func (s Strictness) String() string {
+	switch s {
+	case Strict:
+		return "Strict"
+	case Lenient:
+		return "Lenient"
+	case Recovery:
+		return "Recovery"
+	}
+	return fmt.Sprintf("Unknown Strictness (%d)", uint8(s))
+}
+
+// DecodeOptions controls the decoding of addresses, WIF strings, base58check
+// and bech32 strings by DecodeAddressWithOptions, DecodeWIFWithOptions,
+// DecodeBase58CheckWithOptions and DecodeBech32WithOptions, and of packets by
+// the psbt package.  The zero value decodes strictly, like DecodeAddress and
+// DecodeWIF.
+type DecodeOptions struct {
+	// Strictness is the level of tolerance for deviations from the
+	// canonical encoding.
+	Strictness Strictness
+}
+
+// base58Alphabet is the alphabet of the base58 encoding, used to enumerate
+// repair candidates.
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// bech32Alphabet is the alphabet of the data part of bech32 strings, used to
+// enumerate repair candidates.
+const bech32Alphabet = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+// surroundingJunk are the characters Lenient decoding strips from both ends
+// of the input.
+const surroundingJunk = "\"'`<>()[]{}.,;:!?‘’“”"
+
+// cleanEncoding removes white space and invisible formatting characters, such
+// as zero width spaces, from s, and strips surrounding quotes, brackets and
+// punctuation.
+func cleanEncoding(s string) string {
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || unicode.Is(unicode.Cf, r) {
+			return -1
+		}
+		return r
+	}, s)
+	return strings.Trim(s, surroundingJunk)
+}
+
+// repairBase58 returns the only string within one substituted, deleted or
+// inserted base58 character of s for which valid returns true.  It returns
+// false when there is none, and ErrAmbiguousRepair when there are several.
+func repairBase58(s string, valid func(string) bool) (string, bool, error) {
+	return repairString(s, base58Alphabet, valid)
+}
+
+// repairString returns the only string within one substituted, deleted or
+// inserted character of the alphabet of s for which valid returns true, like
+// repairBase58.
+func repairString(s, alphabet string, valid func(string) bool) (string, bool, error) {
+	found := repairCandidates(s, alphabet, valid)
+	if len(found) > 1 {
+		return "", false, ErrAmbiguousRepair
+	}
+	if len(found) == 0 {
+		return "", false, nil
+	}
+	return found[0], true, nil
+}
+
+// repairCandidates returns, in sorted order, all strings within one
+// substituted, deleted or inserted character of the alphabet of s for which
+// valid returns true.
+func repairCandidates(s, alphabet string, valid func(string) bool) []string {
+	// Different edits can produce the same candidate, such as inserting
+	// a character before or after an equal one, so repairs are counted by
+	// their result.
+	found := make(map[string]struct{})
+	try := func(candidate string) {
+		if _, ok := found[candidate]; !ok && valid(candidate) {
+			found[candidate] = struct{}{}
+		}
+	}
+	for i := 0; i <= len(s); i++ {
+		if i < len(s) {
+			try(s[:i] + s[i+1:])
+		}
+		for j := 0; j < len(alphabet); j++ {
+			c := alphabet[j : j+1]
+			try(s[:i] + c + s[i:])
+			if i < len(s) && s[i] != c[0] {
+				try(s[:i] + c + s[i+1:])
+			}
+		}
+	}
+	candidates := make([]string, 0, len(found))
+	for candidate := range found {
+		candidates = append(candidates, candidate)
+	}
+	sort.Strings(candidates)
+	return candidates
+}
+
+// addChecksum returns the base58 encoding of the decoded payload of s with a
+// checksum appended, for input whose checksum was lost.
+func addChecksum(s string) string {
+	payload := base58.Decode(s)
+	if len(payload) == 0 {
+		return ""
+	}
+	cksum := chainhash.DoubleHashB(payload)[:4]
+	return base58.Encode(append(payload, cksum...))
+}
+
+// DecodeAddressWithOptions decodes an address like DecodeAddress, tolerating
+// the deviations from the canonical encoding allowed by the strictness of the
+// options.  Callers of lenient or recovering decoding detect that the input
+// was altered by comparing it with the EncodeAddress result.
+func DecodeAddressWithOptions(addr string, defaultNet *chaincfg.Params,
+	opts DecodeOptions) (Address, error) {
+
+	if opts.Strictness >= Lenient {
+		addr = cleanEncoding(addr)
+	}
+	a, err := DecodeAddress(addr, defaultNet)
+	if err == nil || opts.Strictness < Recovery {
+		return a, err
+	}
+
+	// Base58 addresses hold a version byte and a 20 byte hash.
+	if decoded := base58.Decode(addr); len(decoded) == 1+ripemd160.Size {
+		if a, err := DecodeAddress(addChecksum(addr), defaultNet); err == nil {
+			return a, nil
+		}
+	}
+	repaired, ok, repairErr := repairBase58(addr, func(candidate string) bool {
+		_, _, err := base58.CheckDecode(candidate)
+		if err != nil {
+			return false
+		}
+		_, err = DecodeAddress(candidate, defaultNet)
+		return err == nil
+	})
+	if repairErr != nil {
+		return nil, repairErr
+	}
+	if !ok {
+		return nil, err
+	}
+	return DecodeAddress(repaired, defaultNet)
+}
+
+// DecodeWIFWithOptions decodes a WIF string like DecodeWIF, tolerating the
+// deviations from the canonical encoding allowed by the strictness of the
+// options.
+func DecodeWIFWithOptions(wif string, opts DecodeOptions) (*WIF, error) {
+	if opts.Strictness >= Lenient {
+		wif = cleanEncoding(wif)
+	}
+	w, err := DecodeWIF(wif)
+	if err == nil || opts.Strictness < Recovery {
+		return w, err
+	}
+
+	// A WIF holds a version byte, the private key and, for compressed
+	// public keys, a marker byte.
+	switch len(base58.Decode(wif)) {
+	case 1 + btcec.PrivKeyBytesLen, 1 + btcec.PrivKeyBytesLen + 1:
+		if w, err := DecodeWIF(addChecksum(wif)); err == nil {
+			return w, nil
+		}
+	}
+	repaired, ok, repairErr := repairBase58(wif, func(candidate string) bool {
+		_, err := DecodeWIF(candidate)
+		return err == nil
+	})
+	if repairErr != nil {
+		return nil, repairErr
+	}
+	if !ok {
+		return nil, err
+	}
+	return DecodeWIF(repaired)
+}
+
+// DecodeBase58CheckWithOptions decodes a base58check string like
+// base58.CheckDecode, tolerating the deviations from the canonical encoding
+// allowed by the strictness of the options.  Since the checksum does not
+// reveal the length of the payload, recovering decoding does not accept
+// input whose checksum is missing.
+func DecodeBase58CheckWithOptions(s string, opts DecodeOptions) ([]byte, byte, error) {
+	if opts.Strictness >= Lenient {
+		s = cleanEncoding(s)
+	}
+	payload, version, err := base58.CheckDecode(s)
+	if err == nil || opts.Strictness < Recovery {
+		return payload, version, err
+	}
+
+	repaired, ok, repairErr := repairBase58(s, func(candidate string) bool {
+		_, _, err := base58.CheckDecode(candidate)
+		return err == nil
+	})
+	if repairErr != nil {
+		return nil, 0, repairErr
+	}
+	if !ok {
+		return nil, 0, err
+	}
+	return base58.CheckDecode(repaired)
+}
+
+// DecodeBech32WithOptions decodes a bech32 string like bech32.Decode,
+// tolerating the deviations from the canonical encoding allowed by the
+// strictness of the options.  Strict decoding rejects strings mixing upper
+// and lower case, which lenient decoding accepts.  Recovering decoding
+// repairs a single character of the data part, which the bech32 checksum
+// guarantees to be unambiguous.
+func DecodeBech32WithOptions(s string, opts DecodeOptions) (string, []byte, error) {
+	if opts.Strictness >= Lenient {
+		s = strings.ToLower(cleanEncoding(s))
+	}
+	hrp, data, err := bech32.Decode(s)
+	if err == nil || opts.Strictness < Recovery {
+		return hrp, data, err
+	}
+
+	// Only the data part is repaired, since the human-readable part
+	// holds characters outside of the alphabet.
+	one := strings.LastIndexByte(s, '1')
+	if one < 1 {
+		return "", nil, err
+	}
+	repaired, ok, repairErr := repairString(s[one+1:], bech32Alphabet,
+		func(candidate string) bool {
+			_, _, err := bech32.Decode(s[:one+1] + candidate)
+			return err == nil
+		})
+	if repairErr != nil {
+		return "", nil, repairErr
+	}
+	if !ok {
+		return "", nil, err
+	}
+	return bech32.Decode(s[:one+1] + repaired)
+}
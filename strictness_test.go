@@ -0,0 +1,203 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package btcutil_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	. "github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/base58"
+)
+
+// stripChecksum returns the base58 encoding of the payload of s without its
+// checksum.
+func stripChecksum(s string) string {
+	decoded := base58.Decode(s)
+	return base58.Encode(decoded[:len(decoded)-4])
+}
+
+func TestDecodeAddressWithOptions(t *testing.T) {
+	const addr = "1MirQ9bwyQcGVJPwKUgapu5ouK2E2Ey4gX"
+
+	tests := []struct {
+		name       string
+		in         string
+		strictness Strictness
+		valid      bool
+	}{
+		{"canonical strict", addr, Strict, true},
+		{"padded strict", " " + addr + "\n", Strict, false},
+		{"padded lenient", " " + addr + "\n", Lenient, true},
+		{"quoted lenient", "\"" + addr + "\u200b\".", Lenient, true},
+		{"split lenient", addr[:17] + " " + addr[17:], Lenient, true},
+		{"substituted lenient", addr[:10] + "x" + addr[11:], Lenient, false},
+		{"substituted recovery", addr[:10] + "x" + addr[11:], Recovery, true},
+		{"deleted recovery", addr[:20] + addr[21:], Recovery, true},
+		{"inserted recovery", addr[:5] + "z" + addr[5:], Recovery, true},
+		{"quoted recovery", "'" + addr[:10] + "x" + addr[11:] + "'", Recovery, true},
+		{"no checksum lenient", stripChecksum(addr), Lenient, false},
+		{"no checksum recovery", stripChecksum(addr), Recovery, true},
+		{"garbage recovery", "not an address", Recovery, false},
+	}
+
+	for _, test := range tests {
+		opts := DecodeOptions{Strictness: test.strictness}
+		a, err := DecodeAddressWithOptions(test.in,
+			&chaincfg.MainNetParams, opts)
+		if !test.valid {
+			if err == nil {
+				t.Errorf("%s: decoded invalid input %q as %v", test.name,
+					test.in, a.EncodeAddress())
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+		if got := a.EncodeAddress(); got != addr {
+			t.Errorf("%s: got %v, want %v", test.name, got, addr)
+		}
+	}
+}
+
+func TestDecodeWIFWithOptions(t *testing.T) {
+	const wif = "5HueCGU8rMjxEXxiPuD5BDku4MkFqeZyd4dZ1jvhTVqvbTLvyTJ"
+
+	tests := []struct {
+		name       string
+		in         string
+		strictness Strictness
+		valid      bool
+	}{
+		{"canonical strict", wif, Strict, true},
+		{"padded strict", "\t" + wif + " ", Strict, false},
+		{"padded lenient", "\t" + wif + " ", Lenient, true},
+		{"substituted lenient", wif[:30] + "1" + wif[31:], Lenient, false},
+		{"substituted recovery", wif[:30] + "1" + wif[31:], Recovery, true},
+		{"deleted recovery", wif[:1] + wif[2:], Recovery, true},
+		{"no checksum strict", stripChecksum(wif), Strict, false},
+		{"no checksum recovery", stripChecksum(wif), Recovery, true},
+	}
+
+	for _, test := range tests {
+		opts := DecodeOptions{Strictness: test.strictness}
+		w, err := DecodeWIFWithOptions(test.in, opts)
+		if !test.valid {
+			if err == nil {
+				t.Errorf("%s: decoded invalid input %q as %v", test.name,
+					test.in, w)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+		if got := w.String(); got != wif {
+			t.Errorf("%s: got %v, want %v", test.name, got, wif)
+		}
+	}
+}
+
+func TestDecodeBase58CheckWithOptions(t *testing.T) {
+	payload := []byte("strict and lenient")
+	s := base58.CheckEncode(payload, 0x42)
+
+	tests := []struct {
+		name       string
+		in         string
+		strictness Strictness
+		valid      bool
+	}{
+		{"canonical strict", s, Strict, true},
+		{"padded strict", " " + s, Strict, false},
+		{"quoted lenient", "\"" + s + "\"", Lenient, true},
+		{"substituted lenient", s[:8] + "x" + s[9:], Lenient, false},
+		{"substituted recovery", s[:8] + "x" + s[9:], Recovery, true},
+		{"inserted recovery", s[:4] + "z" + s[4:], Recovery, true},
+		{"no checksum recovery", stripChecksum(s), Recovery, false},
+	}
+
+	for _, test := range tests {
+		opts := DecodeOptions{Strictness: test.strictness}
+		got, version, err := DecodeBase58CheckWithOptions(test.in, opts)
+		if !test.valid {
+			if err == nil {
+				t.Errorf("%s: decoded invalid input %q", test.name,
+					test.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+		if string(got) != string(payload) || version != 0x42 {
+			t.Errorf("%s: got %q version %#x, want %q version 0x42",
+				test.name, got, version, payload)
+		}
+	}
+}
+
+func TestDecodeBech32WithOptions(t *testing.T) {
+	const s = "abcdef1qpzry9x8gf2tvdw0s3jn54khce6mua7lmqqqxw"
+	mixed := "ABCDEF" + s[6:]
+
+	tests := []struct {
+		name       string
+		in         string
+		strictness Strictness
+		valid      bool
+	}{
+		{"canonical strict", s, Strict, true},
+		{"upper case strict", strings.ToUpper(s), Strict, true},
+		{"mixed case strict", mixed, Strict, false},
+		{"mixed case lenient", mixed, Lenient, true},
+		{"padded lenient", "<" + s + ">\n", Lenient, true},
+		{"substituted lenient", s[:10] + "q" + s[11:], Lenient, false},
+		{"substituted recovery", s[:10] + "q" + s[11:], Recovery, true},
+		{"substituted hrp recovery", "x" + s[1:], Recovery, false},
+	}
+
+	for _, test := range tests {
+		opts := DecodeOptions{Strictness: test.strictness}
+		hrp, data, err := DecodeBech32WithOptions(test.in, opts)
+		if !test.valid {
+			if err == nil {
+				t.Errorf("%s: decoded invalid input %q", test.name,
+					test.in)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", test.name, err)
+			continue
+		}
+		if hrp != "abcdef" || len(data) != 32 {
+			t.Errorf("%s: got hrp %q with %d values, want \"abcdef\" "+
+				"with 32", test.name, hrp, len(data))
+		}
+	}
+}
+
+func TestStrictnessStringer(t *testing.T) {
+	tests := []struct {
+		in   Strictness
+		want string
+	}{
+		{Strict, "Strict"},
+		{Lenient, "Lenient"},
+		{Recovery, "Recovery"},
+		{0xff, "Unknown Strictness (255)"},
+	}
+	for _, test := range tests {
+		if got := test.in.String(); got != test.want {
+			t.Errorf("String: got %q, want %q", got, test.want)
+		}
+	}
+}
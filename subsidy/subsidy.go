@@ -0,0 +1,216 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package subsidy calculates the block subsidy of the chain, the supply it
+// has issued by a height, and when its subsidy is next halved, so explorers
+// and wallets need not hardcode the emission schedule.
+//
+// The schedule is the one of the chain's consensus rules: each block pays a
+// base subsidy, halved every SubsidyReductionInterval blocks of the network
+// parameters, until the issued supply reaches btcutil.MaxHao.  The base
+// subsidy is derived from the reduction interval and that cap rather than
+// hardcoded, so the schedule of every network issues exactly MaxOMC.
+// Transaction fees collected by coinbases are not part of the issued supply.
+package subsidy
+
+import (
+	"math"
+	"time"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil"
+)
+
+// Schedule is an emission schedule.
+type Schedule struct {
+	// Base is the subsidy of the blocks before the first halving.
+	Base btcutil.Amount
+
+	// HalvingInterval is the number of blocks between halvings, or zero
+	// for a subsidy which is never halved.
+	HalvingInterval int32
+
+	// TargetTimePerBlock is the expected time between blocks, used to
+	// estimate when the next halving happens.
+	TargetTimePerBlock time.Duration
+
+	// MaxSupply caps the issued supply, or is zero for an uncapped
+	// schedule.  The block reaching it pays the remainder, and the
+	// blocks after it pay nothing.
+	MaxSupply btcutil.Amount
+}
+
+// NewSchedule returns the emission schedule of the passed network, which
+// issues btcutil.MaxHao in total.  Networks without a reduction interval pay
+// the base subsidy of the main network until the supply is issued.
+func NewSchedule(net *chaincfg.Params) *Schedule {
+	return &Schedule{
+		Base:               BaseSubsidy(net.SubsidyReductionInterval),
+		HalvingInterval:    net.SubsidyReductionInterval,
+		TargetTimePerBlock: net.TargetTimePerBlock,
+		MaxSupply:          btcutil.MaxHao,
+	}
+}
+
+// BaseSubsidy returns the subsidy of the blocks before the first halving of
+// a network halving it every halvingInterval blocks.  It is the smallest
+// subsidy whose halvings issue at least btcutil.MaxHao, since halving in whole
+// Hao rounds every era down.  The base subsidy of the main network is
+// returned when halvingInterval is not positive.
+func BaseSubsidy(halvingInterval int32) btcutil.Amount {
+	if halvingInterval <= 0 {
+		halvingInterval = chaincfg.MainNetParams.SubsidyReductionInterval
+	}
+	interval := btcutil.Amount(halvingInterval)
+	s := Schedule{
+		Base:            (btcutil.MaxHao + 2*interval - 1) / (2 * interval),
+		HalvingInterval: halvingInterval,
+	}
+	for s.TotalSupply() < btcutil.MaxHao {
+		s.Base++
+	}
+	return s.Base
+}
+
+// Era returns the number of halvings before the block at height.
+func (s *Schedule) Era(height int32) int {
+	if height < 0 || s.HalvingInterval <= 0 {
+		return 0
+	}
+	return int(height / s.HalvingInterval)
+}
+
+// subsidy returns the subsidy of the blocks of era.
+func (s *Schedule) subsidy(era int) btcutil.Amount {
+	if era >= 63 {
+		return 0
+	}
+	return s.Base >> uint(era)
+}
+
+// BlockSubsidy returns the subsidy of the block at height.  It is zero for
+// negative heights.
+func (s *Schedule) BlockSubsidy(height int32) btcutil.Amount {
+	if height < 0 {
+		return 0
+	}
+	if s.MaxSupply > 0 {
+		return s.Supply(height) - s.Supply(height-1)
+	}
+	return s.subsidy(s.Era(height))
+}
+
+// Supply returns the supply issued by the blocks up to and including the one
+// at height, the genesis block included.  It is zero for negative heights,
+// and saturates at the largest amount rather than overflowing for schedules
+// which never halve.
+func (s *Schedule) Supply(height int32) btcutil.Amount {
+	return s.capped(s.supply(height))
+}
+
+// capped returns supply limited to the maximum supply of the schedule.
+func (s *Schedule) capped(supply btcutil.Amount) btcutil.Amount {
+	if s.MaxSupply > 0 && supply > s.MaxSupply {
+		return s.MaxSupply
+	}
+	return supply
+}
+
+// supply returns the supply issued by the blocks up to and including the one
+// at height, ignoring the maximum supply.
+func (s *Schedule) supply(height int32) btcutil.Amount {
+	if height < 0 {
+		return 0
+	}
+	if s.HalvingInterval <= 0 {
+		return mulSaturating(s.Base, int64(height)+1)
+	}
+
+	var supply btcutil.Amount
+	for era := 0; ; era++ {
+		subsidy := s.subsidy(era)
+		start := int64(era) * int64(s.HalvingInterval)
+		if subsidy == 0 || start > int64(height) {
+			return supply
+		}
+		end := start + int64(s.HalvingInterval) - 1
+		if end > int64(height) {
+			end = int64(height)
+		}
+		supply += mulSaturating(subsidy, end-start+1)
+	}
+}
+
+// TotalSupply returns the supply issued once the subsidy has reached zero,
+// or the largest amount for uncapped schedules which never halve.
+func (s *Schedule) TotalSupply() btcutil.Amount {
+	if s.HalvingInterval <= 0 {
+		return s.capped(math.MaxInt64)
+	}
+	var supply btcutil.Amount
+	for era := 0; s.subsidy(era) > 0; era++ {
+		supply += mulSaturating(s.subsidy(era), int64(s.HalvingInterval))
+	}
+	return s.capped(supply)
+}
+
+// mulSaturating returns a times n, or the largest amount when the product
+// overflows.
+func mulSaturating(a btcutil.Amount, n int64) btcutil.Amount {
+	if n != 0 && int64(a) > math.MaxInt64/n {
+		return math.MaxInt64
+	}
+	return a * btcutil.Amount(n)
+}
+
+// Halving describes a halving of the subsidy.
+type Halving struct {
+	// Height is the height of the first block paying the halved subsidy.
+	Height int32
+
+	// Era is the number of halvings up to and including this one.
+	Era int
+
+	// Subsidy is the subsidy from Height on.
+	Subsidy btcutil.Amount
+
+	// Blocks is the number of blocks from the queried height to Height.
+	Blocks int32
+
+	// Expected is the expected time until the halving, Blocks times the
+	// target time per block.
+	Expected time.Duration
+}
+
+// NextHalving returns the first halving after the block at height.  False is
+// returned when the subsidy is never halved again, either because the
+// schedule never halves or because it reaches zero or the maximum supply
+// first.
+func (s *Schedule) NextHalving(height int32) (Halving, bool) {
+	if s.HalvingInterval <= 0 {
+		return Halving{}, false
+	}
+	if height < 0 {
+		height = -1
+	}
+	era := s.Era(height) + 1
+	if s.subsidy(era-1) == 0 {
+		return Halving{}, false
+	}
+	next := int64(era) * int64(s.HalvingInterval)
+	if next > math.MaxInt32 {
+		return Halving{}, false
+	}
+	if s.MaxSupply > 0 && s.Supply(int32(next-1)) >= s.MaxSupply {
+		return Halving{}, false
+	}
+	blocks := int32(next - int64(height))
+	return Halving{
+		Height:   int32(next),
+		Era:      era,
+		Subsidy:  s.BlockSubsidy(int32(next)),
+		Blocks:   blocks,
+		Expected: time.Duration(blocks) * s.TargetTimePerBlock,
+	}, true
+}
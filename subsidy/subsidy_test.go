@@ -0,0 +1,197 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package subsidy_test
+
+import (
+	"math"
+	"testing"
+	"time"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/subsidy"
+)
+
+// testBase is the base subsidy of the test schedules.
+const testBase = 50 * btcutil.HaoPerOMC
+
+// testSchedule halves 50 OMC every 100 blocks, with a block every minute.
+var testSchedule = &subsidy.Schedule{
+	Base:               testBase,
+	HalvingInterval:    100,
+	TargetTimePerBlock: time.Minute,
+}
+
+// TestNewSchedule ensures schedules follow their network parameters and
+// issue exactly the supply of OMC.
+func TestNewSchedule(t *testing.T) {
+	for _, net := range []*chaincfg.Params{&chaincfg.MainNetParams,
+		&chaincfg.TestNet3Params, &chaincfg.RegressionNetParams,
+		&chaincfg.SimNetParams} {
+
+		s := subsidy.NewSchedule(net)
+		if s.Base != subsidy.BaseSubsidy(net.SubsidyReductionInterval) ||
+			s.HalvingInterval != net.SubsidyReductionInterval ||
+			s.TargetTimePerBlock != net.TargetTimePerBlock ||
+			s.MaxSupply != btcutil.MaxHao {
+			t.Errorf("%s: got schedule %+v", net.Name, s)
+		}
+		if got := s.TotalSupply(); got != btcutil.MaxHao {
+			t.Errorf("%s: TotalSupply: got %d, want %d", net.Name, got,
+				int64(btcutil.MaxHao))
+		}
+		if got := s.Supply(math.MaxInt32); got != btcutil.MaxHao {
+			t.Errorf("%s: Supply(MaxInt32): got %d, want %d", net.Name,
+				got, int64(btcutil.MaxHao))
+		}
+
+		// A smaller base subsidy would fall short of the supply.
+		uncapped := *s
+		uncapped.MaxSupply = 0
+		uncapped.Base--
+		if net.SubsidyReductionInterval > 0 &&
+			uncapped.TotalSupply() >= btcutil.MaxHao {
+			t.Errorf("%s: base subsidy %d is not the smallest", net.Name,
+				s.Base)
+		}
+	}
+}
+
+// TestMaxSupply ensures capped schedules stop paying once the maximum supply
+// is issued, the block reaching it paying the remainder.
+func TestMaxSupply(t *testing.T) {
+	s := *testSchedule
+	s.MaxSupply = 5990 * btcutil.HaoPerOMC
+
+	tests := []struct {
+		height int32
+		want   btcutil.Amount
+	}{
+		{0, 50 * btcutil.HaoPerOMC},
+		{138, 25 * btcutil.HaoPerOMC},
+		{139, 15 * btcutil.HaoPerOMC},
+		{140, 0},
+		{250, 0},
+	}
+	for _, test := range tests {
+		if got := s.BlockSubsidy(test.height); got != test.want {
+			t.Errorf("BlockSubsidy(%d): got %d, want %d", test.height,
+				got, test.want)
+		}
+	}
+	if got := s.Supply(139); got != s.MaxSupply {
+		t.Errorf("Supply(139): got %d, want %d", got, s.MaxSupply)
+	}
+	if got := s.TotalSupply(); got != s.MaxSupply {
+		t.Errorf("TotalSupply: got %d, want %d", got, s.MaxSupply)
+	}
+	if _, ok := s.NextHalving(120); ok {
+		t.Errorf("NextHalving: got a halving after the supply ended")
+	}
+
+	forever := &subsidy.Schedule{Base: testBase, MaxSupply: s.MaxSupply}
+	if got := forever.TotalSupply(); got != s.MaxSupply {
+		t.Errorf("TotalSupply without halvings: got %d, want %d", got,
+			s.MaxSupply)
+	}
+}
+
+// TestBlockSubsidy ensures the subsidy halves at the end of each interval
+// until it reaches zero.
+func TestBlockSubsidy(t *testing.T) {
+	tests := []struct {
+		height int32
+		want   btcutil.Amount
+	}{
+		{-1, 0},
+		{0, 50 * btcutil.HaoPerOMC},
+		{99, 50 * btcutil.HaoPerOMC},
+		{100, 25 * btcutil.HaoPerOMC},
+		{250, 12.5 * btcutil.HaoPerOMC},
+		{3200, 1},
+		{3299, 1},
+		{3300, 0},
+		{math.MaxInt32, 0},
+	}
+	for _, test := range tests {
+		if got := testSchedule.BlockSubsidy(test.height); got != test.want {
+			t.Errorf("BlockSubsidy(%d): got %d, want %d", test.height,
+				got, test.want)
+		}
+	}
+
+	forever := &subsidy.Schedule{Base: testBase}
+	if got := forever.BlockSubsidy(1e9); got != testBase {
+		t.Errorf("BlockSubsidy without halvings: got %d", got)
+	}
+}
+
+// TestSupply ensures the supply adds up the subsidies of every block up to
+// a height.
+func TestSupply(t *testing.T) {
+	var want btcutil.Amount
+	for h := int32(0); h < 3400; h++ {
+		want += testSchedule.BlockSubsidy(h)
+		if got := testSchedule.Supply(h); got != want {
+			t.Fatalf("Supply(%d): got %d, want %d", h, got, want)
+		}
+	}
+	if got := testSchedule.TotalSupply(); got != want {
+		t.Errorf("TotalSupply: got %d, want %d", got, want)
+	}
+	if got := testSchedule.Supply(math.MaxInt32); got != want {
+		t.Errorf("Supply(MaxInt32): got %d, want %d", got, want)
+	}
+	if got := testSchedule.Supply(-1); got != 0 {
+		t.Errorf("Supply(-1): got %d, want 0", got)
+	}
+
+	forever := &subsidy.Schedule{Base: testBase}
+	if got := forever.Supply(9); got != 10*testBase {
+		t.Errorf("Supply without halvings: got %d", got)
+	}
+	if got := forever.Supply(math.MaxInt32); got != math.MaxInt64 {
+		t.Errorf("Supply without halvings: got %d, want saturation", got)
+	}
+	if got := forever.TotalSupply(); got != math.MaxInt64 {
+		t.Errorf("TotalSupply without halvings: got %d", got)
+	}
+}
+
+// TestNextHalving ensures the next halving is the first block of the next
+// interval, and that there is none once the subsidy has reached zero.
+func TestNextHalving(t *testing.T) {
+	tests := []struct {
+		height int32
+		want   subsidy.Halving
+	}{
+		{-5, subsidy.Halving{Height: 100, Era: 1,
+			Subsidy: 25 * btcutil.HaoPerOMC, Blocks: 101,
+			Expected: 101 * time.Minute}},
+		{0, subsidy.Halving{Height: 100, Era: 1,
+			Subsidy: 25 * btcutil.HaoPerOMC, Blocks: 100,
+			Expected: 100 * time.Minute}},
+		{100, subsidy.Halving{Height: 200, Era: 2,
+			Subsidy: 12.5 * btcutil.HaoPerOMC, Blocks: 100,
+			Expected: 100 * time.Minute}},
+		{3250, subsidy.Halving{Height: 3300, Era: 33, Subsidy: 0,
+			Blocks: 50, Expected: 50 * time.Minute}},
+	}
+	for _, test := range tests {
+		got, ok := testSchedule.NextHalving(test.height)
+		if !ok || got != test.want {
+			t.Errorf("NextHalving(%d): got %+v (%v), want %+v",
+				test.height, got, ok, test.want)
+		}
+	}
+
+	if _, ok := testSchedule.NextHalving(3300); ok {
+		t.Errorf("NextHalving: got a halving after the subsidy ended")
+	}
+	forever := &subsidy.Schedule{Base: testBase}
+	if _, ok := forever.NextHalving(0); ok {
+		t.Errorf("NextHalving: got a halving of a schedule without any")
+	}
+}
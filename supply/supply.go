@@ -0,0 +1,217 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package supply calculates the circulating supply of the chain from a
+// snapshot of its unspent transaction outputs.
+//
+// The circulating supply is the total issued supply less every amount which
+// can not, or by policy will not, move: outputs to burn addresses and other
+// unspendable scripts, outputs locked by scripts known to the calculator, the
+// unspendable genesis coinbase, and issued coins which are absent from the
+// snapshot altogether because they were destroyed or never claimed.  Each of
+// these is reported as a separate Component so the result can be explained.
+package supply
+
+import (
+	"errors"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/scriptclass"
+)
+
+// ErrSnapshotExceedsIssued describes an error where the unspent outputs of a
+// snapshot add up to more than the total issued supply, meaning either the
+// snapshot or the issued supply is wrong.
+var ErrSnapshotExceedsIssued = errors.New("unspent outputs exceed issued supply")
+
+// UTXO is a single unspent transaction output of a snapshot.
+type UTXO struct {
+	OutPoint wire.OutPoint
+	Amount   btcutil.Amount
+	PkScript []byte
+}
+
+// ComponentKind identifies why a Component is excluded from the circulating
+// supply.
+type ComponentKind byte
+
+// These constants are the kinds of components reported by Calculate.
+const (
+	// Burned is the value of unspent outputs whose scripts can never be
+	// spent, as reported by scriptclass.Analyze.  This includes outputs
+	// to registered burn addresses.
+	Burned ComponentKind = iota
+
+	// Locked is the value of unspent outputs whose scripts were
+	// registered with AddLockedScript.
+	Locked
+
+	// Genesis is the value of snapshot outputs of the genesis coinbase,
+	// which can not be spent, and of outputs registered with
+	// AddUnspendableOutPoint.  Nodes usually leave the genesis coinbase
+	// out of their UTXO set, in which case its value is part of Missing.
+	Genesis
+
+	// Missing is the issued value not present in the snapshot.  It is
+	// made up of coinbases which claimed less than allowed and outputs
+	// which were pruned from the UTXO set because they were provably
+	// unspendable.
+	Missing
+)
+
+// String returns the kind as a human-readable name.
+func (k ComponentKind) String() string {
+	switch k {
+	case Burned:
+		return "burned"
+	case Locked:
+		return "locked"
+	case Genesis:
+		return "genesis"
+	case Missing:
+		return "missing"
+	}
+	return "unknown"
+}
+
+// Component is an amount excluded from the circulating supply for a single
+// reason.
+type Component struct {
+	Kind   ComponentKind
+	Reason string
+	Amount btcutil.Amount
+
+	// Count is the number of outputs making up the component.  It is
+	// zero for the Missing component.
+	Count int
+}
+
+// Report is the result of a circulating supply calculation.
+type Report struct {
+	// Issued is the total issued supply the calculation started from.
+	Issued btcutil.Amount
+
+	// Unspent is the total value of the snapshot.
+	Unspent btcutil.Amount
+
+	// Circulating is the issued supply less all components.
+	Circulating btcutil.Amount
+
+	// Components are the amounts excluded from the circulating supply,
+	// ordered by kind and then by the order their reasons were first
+	// encountered.  Components with no amount are omitted.
+	Components []Component
+}
+
+// Calculator computes the circulating supply from UTXO snapshots.  It is not
+// safe for concurrent modification, but Calculate may be called concurrently
+// once all scripts and outpoints are registered.
+type Calculator struct {
+	locked  map[string]string
+	genesis map[wire.OutPoint]string
+}
+
+// NewCalculator returns a calculator which excludes the outputs of the
+// genesis coinbase of the passed network.  No genesis outputs are excluded
+// when params is nil.
+func NewCalculator(params *chaincfg.Params) *Calculator {
+	c := &Calculator{
+		locked:  make(map[string]string),
+		genesis: make(map[wire.OutPoint]string),
+	}
+	if params != nil && params.GenesisBlock != nil &&
+		len(params.GenesisBlock.Transactions) > 0 {
+
+		coinbase := params.GenesisBlock.Transactions[0]
+		hash := coinbase.TxHash()
+		for i := range coinbase.TxOut {
+			op := wire.OutPoint{Hash: hash, Index: uint32(i)}
+			c.genesis[op] = "genesis coinbase"
+		}
+	}
+	return c
+}
+
+// AddLockedScript registers a public key script whose outputs are excluded
+// from the circulating supply, such as a treasury or time-locked escrow
+// script.  The reason is reported with the Locked component of its outputs.
+func (c *Calculator) AddLockedScript(pkScript []byte, reason string) {
+	c.locked[string(pkScript)] = reason
+}
+
+// AddUnspendableOutPoint registers an outpoint whose output is excluded from
+// the circulating supply for the passed reason and reported with the Genesis
+// components.  It is intended for outputs the consensus rules can never
+// spend despite their script, like additional genesis outputs.
+func (c *Calculator) AddUnspendableOutPoint(op wire.OutPoint, reason string) {
+	c.genesis[op] = reason
+}
+
+// componentKey identifies the component an excluded output is added to.
+type componentKey struct {
+	kind   ComponentKind
+	reason string
+}
+
+// Calculate returns the circulating supply given the total issued supply and
+// a snapshot of all unspent outputs.
+func (c *Calculator) Calculate(issued btcutil.Amount, utxos []UTXO) (*Report, error) {
+	report := &Report{Issued: issued}
+
+	var order []componentKey
+	amounts := make(map[componentKey]*Component)
+	var excluded btcutil.Amount
+	exclude := func(kind ComponentKind, reason string, amount btcutil.Amount, count int) {
+		key := componentKey{kind, reason}
+		comp, ok := amounts[key]
+		if !ok {
+			comp = &Component{Kind: kind, Reason: reason}
+			amounts[key] = comp
+			order = append(order, key)
+		}
+		comp.Amount += amount
+		comp.Count += count
+		excluded += amount
+	}
+
+	for _, utxo := range utxos {
+		report.Unspent += utxo.Amount
+		if reason, ok := c.genesis[utxo.OutPoint]; ok {
+			exclude(Genesis, reason, utxo.Amount, 1)
+			continue
+		}
+		if reason, ok := c.locked[string(utxo.PkScript)]; ok {
+			exclude(Locked, reason, utxo.Amount, 1)
+			continue
+		}
+		for _, f := range scriptclass.Analyze(utxo.PkScript, utxo.Amount) {
+			if f.Severity == scriptclass.SeverityBurn {
+				exclude(Burned, f.Code.String(), utxo.Amount, 1)
+				break
+			}
+		}
+	}
+
+	if report.Unspent > issued {
+		return nil, ErrSnapshotExceedsIssued
+	}
+	if missing := issued - report.Unspent; missing > 0 {
+		exclude(Missing, "issued but not in snapshot", missing, 0)
+	}
+
+	// Stable order by kind, keeping the order of first appearance within
+	// each kind.
+	for kind := Burned; kind <= Missing; kind++ {
+		for _, key := range order {
+			if key.kind == kind && amounts[key].Amount != 0 {
+				report.Components = append(report.Components,
+					*amounts[key])
+			}
+		}
+	}
+	report.Circulating = issued - excluded
+	return report, nil
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package supply_test
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/scriptclass"
+	"github.com/zeusyf/btcutil/supply"
+)
+
+// p2pkh returns a pay-to-pubkey-hash script paying a hash made of b.
+func p2pkh(b byte) []byte {
+	script := []byte{0x76, 0xa9, 0x14}
+	script = append(script, bytes.Repeat([]byte{b}, 20)...)
+	return append(script, 0x88, 0xac)
+}
+
+// TestCalculate ensures each kind of excluded output is accounted for and
+// explained.
+func TestCalculate(t *testing.T) {
+	treasury := p2pkh(0x11)
+	genesisOut := wire.OutPoint{Hash: chainhash.Hash{0x01}, Index: 0}
+
+	calc := supply.NewCalculator(nil)
+	calc.AddLockedScript(treasury, "treasury")
+	calc.AddUnspendableOutPoint(genesisOut, "genesis coinbase")
+
+	op := func(i uint32) wire.OutPoint {
+		return wire.OutPoint{Hash: chainhash.Hash{0x02}, Index: i}
+	}
+	utxos := []supply.UTXO{
+		{OutPoint: genesisOut, Amount: 50, PkScript: p2pkh(0x22)},
+		{OutPoint: op(0), Amount: 1000, PkScript: p2pkh(0x33)},
+		{OutPoint: op(1), Amount: 300, PkScript: treasury},
+		{OutPoint: op(2), Amount: 7, PkScript: p2pkh(0x00)},
+		{OutPoint: op(3), Amount: 5, PkScript: []byte{0x6a, 0x01, 0x00}},
+		{OutPoint: op(4), Amount: 3, PkScript: p2pkh(0x00)},
+		{OutPoint: op(5), Amount: 100, PkScript: treasury},
+	}
+
+	report, err := calc.Calculate(2000, utxos)
+	if err != nil {
+		t.Fatalf("Calculate: unexpected error: %v", err)
+	}
+
+	want := &supply.Report{
+		Issued:      2000,
+		Unspent:     1465,
+		Circulating: 1000,
+		Components: []supply.Component{{
+			Kind:   supply.Burned,
+			Reason: scriptclass.KnownBurnAddress.String(),
+			Amount: 10,
+			Count:  2,
+		}, {
+			Kind:   supply.Burned,
+			Reason: scriptclass.NullDataWithValue.String(),
+			Amount: 5,
+			Count:  1,
+		}, {
+			Kind:   supply.Locked,
+			Reason: "treasury",
+			Amount: 400,
+			Count:  2,
+		}, {
+			Kind:   supply.Genesis,
+			Reason: "genesis coinbase",
+			Amount: 50,
+			Count:  1,
+		}, {
+			Kind:   supply.Missing,
+			Reason: "issued but not in snapshot",
+			Amount: 535,
+		}},
+	}
+	if !reflect.DeepEqual(report, want) {
+		t.Fatalf("Calculate: got %+v, want %+v", report, want)
+	}
+
+	var total btcutil.Amount
+	for _, c := range report.Components {
+		total += c.Amount
+	}
+	if report.Circulating+total != report.Issued {
+		t.Fatalf("Calculate: components do not add up to issued supply")
+	}
+
+	_, err = calc.Calculate(1000, utxos)
+	if err != supply.ErrSnapshotExceedsIssued {
+		t.Fatalf("Calculate: got %v, want %v", err,
+			supply.ErrSnapshotExceedsIssued)
+	}
+}
@@ -0,0 +1,273 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package sweep builds transactions moving all the coins of a set of keys to
+// a single address, as done when recovering funds from cold storage or from
+// a paper wallet.
+//
+// Sweep spends every passed output paying to the pay-to-pubkey-hash address
+// of one of the keys, with either an Omega or a Bitcoin style script, and
+// pays their total, less the fee at the requested fee rate, to the
+// destination.  The fee is computed from the size of the signed transaction
+// assuming signatures of the largest size, so the fee rate paid is never
+// below the requested one.  Inputs signal replaceability by default,
+// so a sweep stuck at a low fee rate can be replaced by one paying more.
+package sweep
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcd/txscript"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/scriptclass"
+	"github.com/zeusyf/btcutil/signing"
+	"github.com/zeusyf/btcutil/txsizes"
+	"github.com/zeusyf/omega/token"
+)
+
+const (
+	// SequenceRBF is the sequence number of inputs signaling, as in
+	// BIP0125, that their transaction may be replaced.
+	SequenceRBF = wire.MaxTxInSequenceNum - 2
+
+	// DefaultDustRelayFeeRate is the fee rate the sweep output is checked
+	// against for dust when no other is set.
+	DefaultDustRelayFeeRate btcutil.FeeRate = 3000
+)
+
+var (
+	// ErrNoUTXOs describes an error where a sweep is requested without
+	// any output to spend.
+	ErrNoUTXOs = errors.New("no outputs to sweep")
+
+	// ErrNoKey describes an error where an output to sweep does not pay to
+	// the pay-to-pubkey-hash address of any of the passed keys.
+	ErrNoKey = errors.New("output does not pay to any sweep key")
+
+	// ErrInsufficientFunds describes an error where the swept outputs
+	// don't cover the fee, or leave a dust output once it is paid.
+	ErrInsufficientFunds = errors.New("swept amount does not cover the fee")
+)
+
+// UTXOError describes an error where one of the outputs passed to Sweep can
+// not be swept.
+type UTXOError struct {
+	// Index is the position of the output among the passed outputs.
+	Index int
+
+	// Err is the reason the output can not be swept.
+	Err error
+}
+
+// Error returns the error along with the index of the offending output.
+func (e *UTXOError) Error() string {
+	return fmt.Sprintf("output %d: %v", e.Index, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *UTXOError) Unwrap() error {
+	return e.Err
+}
+
+// UTXO is an unspent output of the base token to sweep.
+type UTXO struct {
+	OutPoint wire.OutPoint
+	Amount   btcutil.Amount
+	PkScript []byte
+}
+
+// Option configures Sweep.
+type Option func(*config)
+
+// config holds the configuration of Sweep.
+type config struct {
+	sequence    uint32
+	lockTime    uint32
+	dustFeeRate btcutil.FeeRate
+	signingOpts []signing.Option
+}
+
+// WithSequence sets the sequence number of the inputs.  SequenceRBF is used
+// by default, and wire.MaxTxInSequenceNum makes the sweep final and not
+// replaceable.
+func WithSequence(sequence uint32) Option {
+	return func(c *config) {
+		c.sequence = sequence
+	}
+}
+
+// WithLockTime sets the lock time of the sweep.  The sequence number must
+// then be below wire.MaxTxInSequenceNum for the lock time to be enforced.
+func WithLockTime(lockTime uint32) Option {
+	return func(c *config) {
+		c.lockTime = lockTime
+	}
+}
+
+// WithDustRelayFeeRate sets the fee rate the sweep output is checked against
+// for dust.  DefaultDustRelayFeeRate is used by default.
+func WithDustRelayFeeRate(feeRate btcutil.FeeRate) Option {
+	return func(c *config) {
+		c.dustFeeRate = feeRate
+	}
+}
+
+// WithSigningOptions sets the options of the signing.KeySigners signing the
+// inputs, such as signing.WithLowR.
+func WithSigningOptions(opts ...signing.Option) Option {
+	return func(c *config) {
+		c.signingOpts = opts
+	}
+}
+
+// Result is a signed sweep transaction.
+type Result struct {
+	// Tx is the signed transaction.
+	Tx *wire.MsgTx
+
+	// Amount is the amount paid to the destination.
+	Amount btcutil.Amount
+
+	// Fee is the fee paid by the transaction.
+	Fee btcutil.Amount
+
+	// Size is the size the fee was computed for, which the signed
+	// transaction does not exceed.
+	Size int
+}
+
+// pubKeyHash returns the public key hash paid to by pkScript, an Omega or
+// Bitcoin style pay-to-pubkey-hash script, or nil for any other script.
+func pubKeyHash(pkScript []byte) []byte {
+	// OP_DUP OP_HASH160 <20 byte hash> OP_EQUALVERIFY OP_CHECKSIG
+	if len(pkScript) == 25 && pkScript[0] == scriptclass.OP_DUP &&
+		pkScript[1] == scriptclass.OP_HASH160 &&
+		pkScript[2] == scriptclass.OP_DATA_20 &&
+		pkScript[23] == scriptclass.OP_EQUALVERIFY &&
+		pkScript[24] == scriptclass.OP_CHECKSIG {
+		return pkScript[3:23]
+	}
+	omega, ok := scriptclass.DecodeOmega(pkScript)
+	if !ok || omega.PayOp != scriptclass.OP_PAY2PKH ||
+		!chaincfg.IsPubKeyHashAddrID(omega.NetID) {
+		return nil
+	}
+	return omega.Hash[:]
+}
+
+// sigScript returns the signature script pushing sig followed by pubKey.
+func sigScript(sig, pubKey []byte) []byte {
+	script := make([]byte, 0, 2+len(sig)+len(pubKey))
+	script = append(script, byte(len(sig)))
+	script = append(script, sig...)
+	script = append(script, byte(len(pubKey)))
+	return append(script, pubKey...)
+}
+
+// Sweep returns a signed transaction spending utxos with keys and paying
+// their total less the fee at feeRate to dest.  A *UTXOError wrapping ErrNoKey
+// is returned for an output which no key can spend, and one wrapping the
+// error of Amount.CheckRange for an output whose amount is not a valid OMC
+// amount.  btcutil.ErrAmountOverflow is returned when the total exceeds
+// btcutil.MaxHao, and ErrInsufficientFunds when the outputs don't cover the
+// fee or leave a dust output.
+func Sweep(utxos []UTXO, keys []*btcutil.WIF, dest btcutil.Address,
+	feeRate btcutil.FeeRate, opts ...Option) (*Result, error) {
+	cfg := config{
+		sequence:    SequenceRBF,
+		dustFeeRate: DefaultDustRelayFeeRate,
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+	if len(utxos) == 0 {
+		return nil, ErrNoUTXOs
+	}
+
+	// Match each output with the key it pays to.
+	hashes := make(map[string]*btcutil.WIF, len(keys))
+	for _, key := range keys {
+		hashes[string(btcutil.Hash160(key.SerializePubKey()))] = key
+	}
+	signers := make([]*btcutil.WIF, len(utxos))
+	amounts := make([]btcutil.Amount, len(utxos))
+	for i, utxo := range utxos {
+		key, ok := hashes[string(pubKeyHash(utxo.PkScript))]
+		if !ok {
+			return nil, &UTXOError{Index: i, Err: ErrNoKey}
+		}
+		if err := utxo.Amount.CheckRange(btcutil.MaxHao); err != nil {
+			return nil, &UTXOError{Index: i, Err: err}
+		}
+		signers[i] = key
+		amounts[i] = utxo.Amount
+	}
+	total, err := btcutil.SumChecked(amounts)
+	if err != nil || total.CheckRange(btcutil.MaxHao) != nil {
+		return nil, btcutil.ErrAmountOverflow
+	}
+
+	destScript, err := txscript.PayToAddrScript(dest)
+	if err != nil {
+		return nil, err
+	}
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.LockTime = cfg.lockTime
+	for _, utxo := range utxos {
+		tx.AddTxIn(&wire.TxIn{
+			PreviousOutPoint: utxo.OutPoint,
+			Sequence:         cfg.sequence,
+		})
+	}
+	out := &wire.TxOut{
+		Token: token.Token{
+			TokenType: 0,
+			Value:     &token.NumeralVal{Val: int64(total)},
+		},
+		PkScript: destScript,
+	}
+	tx.AddTxOut(out)
+
+	// Size the signed transaction with worst case signatures.
+	var est txsizes.Estimator
+	for _, signer := range signers {
+		if signer.CompressPubKey {
+			est.AddInputs(txsizes.P2PKH, 1)
+		} else {
+			est.AddInputs(txsizes.P2PKHUncompressed, 1)
+		}
+	}
+	est.AddTxOut(out)
+	size := est.VSize()
+	fee := feeRate.FeeForVSize(int64(size))
+	amount := total - fee
+	out.Token.Value = &token.NumeralVal{Val: int64(amount)}
+	if amount <= 0 || txsizes.IsDust(out, cfg.dustFeeRate) {
+		return nil, ErrInsufficientFunds
+	}
+
+	sigScripts := make([][]byte, len(utxos))
+	for i, utxo := range utxos {
+		sigHash, err := signing.LegacySigHash(tx, i, utxo.PkScript,
+			signing.SigHashAll)
+		if err != nil {
+			return nil, err
+		}
+		signer := signing.NewKeySigner(signers[i].PrivKey, cfg.signingOpts...)
+		sig, err := signing.SignECDSA(signer, sigHash, signing.SigHashAll)
+		if err != nil {
+			return nil, err
+		}
+		sigScripts[i] = sigScript(sig, signers[i].SerializePubKey())
+	}
+	for i, txIn := range tx.TxIn {
+		txIn.SignatureIndex = uint32(i)
+	}
+	tx.SignatureScripts = sigScripts
+
+	return &Result{Tx: tx, Amount: amount, Fee: fee, Size: size}, nil
+}
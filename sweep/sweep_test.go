@@ -0,0 +1,215 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package sweep_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcd/txscript"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/scriptclass"
+	"github.com/zeusyf/btcutil/signing"
+	"github.com/zeusyf/btcutil/sweep"
+	"github.com/zeusyf/omega/token"
+)
+
+// testKey returns a WIF of a key derived from seed and the Omega
+// pay-to-pubkey-hash script of its public key.
+func testKey(t *testing.T, seed byte, compress bool) (*btcutil.WIF, []byte) {
+	t.Helper()
+	privKey, _ := btcec.PrivKeyFromBytes(btcec.S256(),
+		bytes.Repeat([]byte{seed}, 32))
+	wif, err := btcutil.NewWIF(privKey, &chaincfg.MainNetParams, compress)
+	if err != nil {
+		t.Fatalf("NewWIF: unexpected error: %v", err)
+	}
+	pkScript := []byte{chaincfg.MainNetParams.PubKeyHashAddrID}
+	pkScript = append(pkScript, btcutil.Hash160(wif.SerializePubKey())...)
+	return wif, append(pkScript, scriptclass.OP_PAY2PKH)
+}
+
+// bitcoinScript returns the Bitcoin style pay-to-pubkey-hash script paying to
+// the same key hash as the Omega script pkScript.
+func bitcoinScript(pkScript []byte) []byte {
+	script := []byte{scriptclass.OP_DUP, scriptclass.OP_HASH160,
+		scriptclass.OP_DATA_20}
+	script = append(script, pkScript[1:21]...)
+	return append(script, scriptclass.OP_EQUALVERIFY, scriptclass.OP_CHECKSIG)
+}
+
+// testDest returns the destination address of the tests.
+func testDest(t *testing.T) btcutil.Address {
+	t.Helper()
+	dest, err := btcutil.NewAddressPubKeyHash(bytes.Repeat([]byte{9}, 20),
+		&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("NewAddressPubKeyHash: unexpected error: %v", err)
+	}
+	return dest
+}
+
+// TestSweep ensures sweeps spend every output, pay at least the fee rate,
+// and carry valid signatures.
+func TestSweep(t *testing.T) {
+	wifA, scriptA := testKey(t, 1, true)
+	wifB, scriptB := testKey(t, 2, false)
+	utxos := []sweep.UTXO{
+		{OutPoint: wire.OutPoint{Hash: chainhash.Hash{1}, Index: 0}, Amount: 50000, PkScript: scriptA},
+		{OutPoint: wire.OutPoint{Hash: chainhash.Hash{2}, Index: 3}, Amount: 70000, PkScript: scriptB},
+		{OutPoint: wire.OutPoint{Hash: chainhash.Hash{3}, Index: 1}, Amount: 30000, PkScript: bitcoinScript(scriptA)},
+	}
+	feeRate := btcutil.NewFeeRateFromHaoPerVByte(10)
+	res, err := sweep.Sweep(utxos, []*btcutil.WIF{wifA, wifB}, testDest(t),
+		feeRate)
+	if err != nil {
+		t.Fatalf("Sweep: unexpected error: %v", err)
+	}
+
+	tx := res.Tx
+	if len(tx.TxIn) != 3 || len(tx.TxOut) != 1 || len(tx.SignatureScripts) != 3 {
+		t.Fatalf("Sweep: got %d inputs, %d outputs and %d signature "+
+			"scripts", len(tx.TxIn), len(tx.TxOut), len(tx.SignatureScripts))
+	}
+	if res.Amount+res.Fee != 150000 ||
+		res.Fee != feeRate.FeeForVSize(int64(res.Size)) {
+		t.Errorf("Sweep: got amount %v and fee %v for size %d", res.Amount,
+			res.Fee, res.Size)
+	}
+	if v, ok := tx.TxOut[0].Token.Value.(*token.NumeralVal); !ok ||
+		btcutil.Amount(v.Val) != res.Amount {
+		t.Errorf("Sweep: got output %+v, want %v", tx.TxOut[0].Token,
+			res.Amount)
+	}
+	var buf bytes.Buffer
+	if err := tx.Serialize(&buf); err != nil {
+		t.Fatalf("Serialize: unexpected error: %v", err)
+	}
+	if buf.Len() > res.Size {
+		t.Errorf("Sweep: signed size %d exceeds estimate %d", buf.Len(),
+			res.Size)
+	}
+
+	for i, txIn := range tx.TxIn {
+		if txIn.PreviousOutPoint != utxos[i].OutPoint ||
+			txIn.Sequence != sweep.SequenceRBF {
+			t.Errorf("Sweep: got input %d %+v", i, txIn)
+		}
+		script := tx.SignatureScripts[txIn.SignatureIndex]
+		sig := script[1 : 1+script[0]]
+		pubKey := script[2+script[0]:]
+		pkHash := utxos[i].PkScript[1:21]
+		if len(utxos[i].PkScript) == 25 {
+			pkHash = utxos[i].PkScript[3:23]
+		}
+		if !bytes.Equal(btcutil.Hash160(pubKey), pkHash) {
+			t.Errorf("Sweep: input %d pushes the wrong key", i)
+		}
+		sigHash, err := signing.LegacySigHash(tx, i, utxos[i].PkScript,
+			signing.SigHashAll)
+		if err != nil {
+			t.Fatalf("LegacySigHash: unexpected error: %v", err)
+		}
+		parsed, err := btcec.ParseDERSignature(sig[:len(sig)-1], btcec.S256())
+		if err != nil {
+			t.Fatalf("ParseDERSignature: unexpected error: %v", err)
+		}
+		key, err := btcec.ParsePubKey(pubKey, btcec.S256())
+		if err != nil {
+			t.Fatalf("ParsePubKey: unexpected error: %v", err)
+		}
+		if !parsed.Verify(sigHash, key) ||
+			sig[len(sig)-1] != byte(signing.SigHashAll) {
+			t.Errorf("Sweep: invalid signature of input %d", i)
+		}
+
+		// The input passes the script engine of the node.
+		vm, err := txscript.NewEngine(utxos[i].PkScript, tx, i,
+			txscript.StandardVerifyFlags, nil, nil, int64(utxos[i].Amount))
+		if err != nil {
+			t.Fatalf("NewEngine: unexpected error: %v", err)
+		}
+		if err := vm.Execute(); err != nil {
+			t.Errorf("Execute: input %d: unexpected error: %v", i, err)
+		}
+	}
+
+	res, err = sweep.Sweep(utxos[:1], []*btcutil.WIF{wifA}, testDest(t),
+		feeRate, sweep.WithSequence(wire.MaxTxInSequenceNum),
+		sweep.WithLockTime(100))
+	if err != nil {
+		t.Fatalf("Sweep: unexpected error: %v", err)
+	}
+	if res.Tx.LockTime != 100 || res.Tx.TxIn[0].Sequence != wire.MaxTxInSequenceNum {
+		t.Errorf("Sweep: options not applied to %+v", res.Tx)
+	}
+}
+
+// TestSweepErrors ensures sweeps of outputs the keys can't spend, or not
+// worth the fee, are rejected.
+func TestSweepErrors(t *testing.T) {
+	wifA, scriptA := testKey(t, 1, true)
+	_, scriptB := testKey(t, 2, true)
+	dest := testDest(t)
+	feeRate := btcutil.NewFeeRateFromHaoPerVByte(10)
+	keys := []*btcutil.WIF{wifA}
+
+	if _, err := sweep.Sweep(nil, keys, dest, feeRate); err != sweep.ErrNoUTXOs {
+		t.Errorf("Sweep: got error %v, want %v", err, sweep.ErrNoUTXOs)
+	}
+
+	utxos := []sweep.UTXO{
+		{OutPoint: wire.OutPoint{Index: 0}, Amount: 50000, PkScript: scriptA},
+		{OutPoint: wire.OutPoint{Index: 1}, Amount: 50000, PkScript: scriptB},
+	}
+	_, err := sweep.Sweep(utxos, keys, dest, feeRate)
+	var utxoErr *sweep.UTXOError
+	if !errors.As(err, &utxoErr) || utxoErr.Index != 1 ||
+		!errors.Is(err, sweep.ErrNoKey) {
+		t.Errorf("Sweep: got error %v, want %v at 1", err, sweep.ErrNoKey)
+	}
+
+	rangeTests := []struct {
+		amount btcutil.Amount
+		err    error
+	}{
+		{-1, btcutil.ErrNegativeAmount},
+		{btcutil.MaxHao + 1, btcutil.ErrAmountTooLarge},
+	}
+	for _, test := range rangeTests {
+		utxos := []sweep.UTXO{
+			{OutPoint: wire.OutPoint{Index: 0}, Amount: 50000, PkScript: scriptA},
+			{OutPoint: wire.OutPoint{Index: 1}, Amount: test.amount, PkScript: scriptA},
+		}
+		_, err := sweep.Sweep(utxos, keys, dest, feeRate)
+		if !errors.As(err, &utxoErr) || utxoErr.Index != 1 ||
+			!errors.Is(err, test.err) {
+			t.Errorf("Sweep(%v): got error %v, want %v at 1", test.amount,
+				err, test.err)
+		}
+	}
+	utxos = []sweep.UTXO{
+		{OutPoint: wire.OutPoint{Index: 0}, Amount: btcutil.MaxHao, PkScript: scriptA},
+		{OutPoint: wire.OutPoint{Index: 1}, Amount: 1, PkScript: scriptA},
+	}
+	if _, err := sweep.Sweep(utxos, keys, dest, feeRate); err != btcutil.ErrAmountOverflow {
+		t.Errorf("Sweep: got error %v, want %v", err,
+			btcutil.ErrAmountOverflow)
+	}
+
+	for _, amount := range []btcutil.Amount{1000, 2500} {
+		utxos := []sweep.UTXO{{Amount: amount, PkScript: scriptA}}
+		_, err := sweep.Sweep(utxos, keys, dest, feeRate)
+		if err != sweep.ErrInsufficientFunds {
+			t.Errorf("Sweep(%v): got error %v, want %v", amount, err,
+				sweep.ErrInsufficientFunds)
+		}
+	}
+}
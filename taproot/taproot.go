@@ -0,0 +1,162 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package taproot implements the key tweaking and script trees of BIP0341, so
+// wallets can create pay-to-taproot outputs and spend them through either
+// their key path or one of their scripts.
+//
+// An output key commits to an internal key and, optionally, to the root of a
+// tree of scripts.  The key path is spent with a signature of the private key
+// tweaked by TweakTaprootPrivKey, and a script path by revealing a script
+// along with a control block proving the output key commits to it.
+//
+// Keys are passed as btcec public keys and serialized in the 32 byte x-only
+// form of BIP0340, which implies the point with an even y coordinate.
+package taproot
+
+import (
+	"crypto/sha256"
+	"errors"
+	"math/big"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+)
+
+const (
+	// XOnlyPubKeySize is the size of a serialized x-only public key.
+	XOnlyPubKeySize = 32
+
+	// witnessV1 is the OP_1 opcode pushing the witness version of
+	// pay-to-taproot scripts.
+	witnessV1 = 0x51
+
+	// opData32 is the opcode pushing the 32 bytes of an output key.
+	opData32 = 0x20
+)
+
+// Tags of the tagged hashes of BIP0341.
+const (
+	TagTapLeaf   = "TapLeaf"
+	TagTapBranch = "TapBranch"
+	TagTapTweak  = "TapTweak"
+)
+
+var (
+	// ErrInvalidXOnlyPubKey describes an error where a serialized x-only
+	// public key is not 32 bytes or not the x coordinate of a point.
+	ErrInvalidXOnlyPubKey = errors.New("invalid x-only public key")
+
+	// ErrInvalidTweak describes an error where a tweak is not less than
+	// the order of the curve, or tweaking a key yields the point at
+	// infinity or a zero private key.  Either happens with negligible
+	// probability.
+	ErrInvalidTweak = errors.New("invalid taproot tweak")
+)
+
+// TaggedHash returns the tagged hash of BIP0340 of the concatenated msgs:
+// SHA256(SHA256(tag) || SHA256(tag) || msgs).
+func TaggedHash(tag string, msgs ...[]byte) chainhash.Hash {
+	tagHash := sha256.Sum256([]byte(tag))
+	h := sha256.New()
+	h.Write(tagHash[:])
+	h.Write(tagHash[:])
+	for _, msg := range msgs {
+		h.Write(msg)
+	}
+	var hash chainhash.Hash
+	copy(hash[:], h.Sum(nil))
+	return hash
+}
+
+// SerializeXOnly returns the x-only serialization of the public key.
+func SerializeXOnly(pub *btcec.PublicKey) []byte {
+	return pub.SerializeCompressed()[1:]
+}
+
+// ParseXOnlyPubKey returns the public key with an even y coordinate whose x
+// coordinate is serialized in b.
+func ParseXOnlyPubKey(b []byte) (*btcec.PublicKey, error) {
+	if len(b) != XOnlyPubKeySize {
+		return nil, ErrInvalidXOnlyPubKey
+	}
+	pub, err := btcec.ParsePubKey(append([]byte{0x02}, b...), btcec.S256())
+	if err != nil {
+		return nil, ErrInvalidXOnlyPubKey
+	}
+	return pub, nil
+}
+
+// tweak returns the tweak of the internal key for the passed script root.
+func tweak(internalKey *btcec.PublicKey, scriptRoot []byte) (*big.Int, error) {
+	h := TaggedHash(TagTapTweak, SerializeXOnly(internalKey), scriptRoot)
+	t := new(big.Int).SetBytes(h[:])
+	if t.Cmp(btcec.S256().N) >= 0 {
+		return nil, ErrInvalidTweak
+	}
+	return t, nil
+}
+
+// ComputeTaprootOutputKey returns the output key committing to the internal
+// key and the root hash of a script tree, which is empty for outputs spent
+// only through their key path.  The internal key is used with an even y
+// coordinate, as implied by its x-only serialization.
+func ComputeTaprootOutputKey(internalKey *btcec.PublicKey, scriptRoot []byte) (*btcec.PublicKey, error) {
+	curve := btcec.S256()
+	p, err := ParseXOnlyPubKey(SerializeXOnly(internalKey))
+	if err != nil {
+		return nil, err
+	}
+	t, err := tweak(p, scriptRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	// Q = P + t*G
+	tx, ty := curve.ScalarBaseMult(t.Bytes())
+	qx, qy := curve.Add(p.X, p.Y, tx, ty)
+	if qx.Sign() == 0 && qy.Sign() == 0 {
+		return nil, ErrInvalidTweak
+	}
+	return &btcec.PublicKey{Curve: curve, X: qx, Y: qy}, nil
+}
+
+// ComputeTaprootKeyNoScript returns the output key of an output without a
+// script tree, as BIP0086 wallets derive.
+func ComputeTaprootKeyNoScript(internalKey *btcec.PublicKey) (*btcec.PublicKey, error) {
+	return ComputeTaprootOutputKey(internalKey, nil)
+}
+
+// TweakTaprootPrivKey returns the private key of the output key committing to
+// the public key of privKey and the passed script root, which signs for the
+// key path of the output.
+func TweakTaprootPrivKey(privKey *btcec.PrivateKey, scriptRoot []byte) (*btcec.PrivateKey, error) {
+	n := btcec.S256().N
+	pub := privKey.PubKey()
+
+	// The internal key is used with an even y coordinate, so the private
+	// key is negated when its public key has an odd one.
+	d := new(big.Int).Set(privKey.D)
+	if pub.Y.Bit(0) == 1 {
+		d.Sub(n, d)
+	}
+	t, err := tweak(pub, scriptRoot)
+	if err != nil {
+		return nil, err
+	}
+	d.Add(d, t).Mod(d, n)
+	if d.Sign() == 0 {
+		return nil, ErrInvalidTweak
+	}
+
+	var b [32]byte
+	d.FillBytes(b[:])
+	tweaked, _ := btcec.PrivKeyFromBytes(btcec.S256(), b[:])
+	return tweaked, nil
+}
+
+// PayToTaprootScript returns the pay-to-taproot script paying the output key.
+func PayToTaprootScript(outputKey *btcec.PublicKey) []byte {
+	return append([]byte{witnessV1, opData32}, SerializeXOnly(outputKey)...)
+}
@@ -0,0 +1,119 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package taproot_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcutil/taproot"
+)
+
+// hexToBytes converts the passed hex string into bytes and panics on error.
+func hexToBytes(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// testKey returns a private key whose scalar is filled with b.
+func testKey(b byte) *btcec.PrivateKey {
+	key, _ := btcec.PrivKeyFromBytes(btcec.S256(), bytes.Repeat([]byte{b}, 32))
+	return key
+}
+
+// TestComputeTaprootKeyNoScript ensures the output key of the first receiving
+// address of the BIP0086 test vectors is derived from its internal key.
+func TestComputeTaprootKeyNoScript(t *testing.T) {
+	internalKey, err := taproot.ParseXOnlyPubKey(hexToBytes(
+		"cc8a4bc64d897bddc5fbc2f670f7a8ba0b386779106cf1223c6fc5d7cd6fc115"))
+	if err != nil {
+		t.Fatalf("ParseXOnlyPubKey: unexpected error: %v", err)
+	}
+	outputKey, err := taproot.ComputeTaprootKeyNoScript(internalKey)
+	if err != nil {
+		t.Fatalf("ComputeTaprootKeyNoScript: unexpected error: %v", err)
+	}
+	want := hexToBytes(
+		"a60869f0dbcf1dc659c9cecbaf8050135ea9e8cdc487053f1dc6880949dc684c")
+	if got := taproot.SerializeXOnly(outputKey); !bytes.Equal(got, want) {
+		t.Errorf("ComputeTaprootKeyNoScript: got %x, want %x", got, want)
+	}
+
+	script := taproot.PayToTaprootScript(outputKey)
+	if !bytes.Equal(script, append([]byte{0x51, 0x20}, want...)) {
+		t.Errorf("PayToTaprootScript: got %x", script)
+	}
+}
+
+// TestTweakTaprootPrivKey ensures tweaked private keys are the private keys of
+// the output keys, whichever the parity of the internal key.
+func TestTweakTaprootPrivKey(t *testing.T) {
+	root := taproot.NewBaseTapLeaf([]byte{0x51}).TapHash()
+	parities := make(map[uint]bool)
+	for b := byte(1); b <= 8; b++ {
+		key := testKey(b)
+		parities[key.PubKey().Y.Bit(0)] = true
+		for _, scriptRoot := range [][]byte{nil, root[:]} {
+			outputKey, err := taproot.ComputeTaprootOutputKey(
+				key.PubKey(), scriptRoot)
+			if err != nil {
+				t.Fatalf("ComputeTaprootOutputKey: unexpected "+
+					"error: %v", err)
+			}
+			tweaked, err := taproot.TweakTaprootPrivKey(key, scriptRoot)
+			if err != nil {
+				t.Fatalf("TweakTaprootPrivKey: unexpected error: %v",
+					err)
+			}
+			got := tweaked.PubKey()
+			if got.X.Cmp(outputKey.X) != 0 || got.Y.Cmp(outputKey.Y) != 0 {
+				t.Errorf("key %d: tweaked private key does not "+
+					"match output key", b)
+			}
+		}
+	}
+	if len(parities) != 2 {
+		t.Errorf("test keys only have parity %v", parities)
+	}
+}
+
+// TestParseXOnlyPubKey ensures x-only keys round trip, and keys of the wrong
+// size or off the curve are rejected.
+func TestParseXOnlyPubKey(t *testing.T) {
+	pub := testKey(1).PubKey()
+	parsed, err := taproot.ParseXOnlyPubKey(taproot.SerializeXOnly(pub))
+	if err != nil {
+		t.Fatalf("ParseXOnlyPubKey: unexpected error: %v", err)
+	}
+	if parsed.X.Cmp(pub.X) != 0 || parsed.Y.Bit(0) != 0 {
+		t.Error("ParseXOnlyPubKey: got the wrong point")
+	}
+
+	// x = 5 is not on the curve: 5^3 + 7 is not a square.
+	offCurve := make([]byte, 32)
+	offCurve[31] = 5
+	for _, b := range [][]byte{nil, make([]byte, 33), offCurve} {
+		if _, err := taproot.ParseXOnlyPubKey(b); err != taproot.ErrInvalidXOnlyPubKey {
+			t.Errorf("ParseXOnlyPubKey(%x): got error %v, want %v", b,
+				err, taproot.ErrInvalidXOnlyPubKey)
+		}
+	}
+}
+
+// TestTaggedHash ensures tagged hashes commit to the tag and the
+// concatenation of the messages.
+func TestTaggedHash(t *testing.T) {
+	a := taproot.TaggedHash("TapLeaf", []byte("ab"), []byte("c"))
+	b := taproot.TaggedHash("TapLeaf", []byte("abc"))
+	c := taproot.TaggedHash("TapBranch", []byte("abc"))
+	if a != b || a == c {
+		t.Errorf("TaggedHash: got %x, %x and %x", a, b, c)
+	}
+}
@@ -0,0 +1,242 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package taproot
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcd/wire/common"
+)
+
+const (
+	// BaseLeafVersion is the leaf version of tapscript.
+	BaseLeafVersion = 0xc0
+
+	// ControlBlockBaseSize is the size of a control block without an
+	// inclusion proof: the leaf version and output key parity byte
+	// followed by the internal key.
+	ControlBlockBaseSize = 1 + XOnlyPubKeySize
+
+	// ControlBlockNodeSize is the size of each node of the inclusion
+	// proof of a control block.
+	ControlBlockNodeSize = chainhash.HashSize
+
+	// ControlBlockMaxNodeCount is the largest number of nodes of an
+	// inclusion proof, the largest depth of a script tree.
+	ControlBlockMaxNodeCount = 128
+
+	// leafVersionMask selects the leaf version from the first byte of a
+	// control block, whose low bit is the parity of the output key.
+	leafVersionMask = 0xfe
+)
+
+var (
+	// ErrInvalidControlBlock describes an error where a serialized
+	// control block has the wrong size, or an invalid internal key.
+	ErrInvalidControlBlock = errors.New("invalid taproot control block")
+
+	// ErrLeafCommitmentMismatch describes an error where a control block
+	// and script do not prove the commitment of an output key to the
+	// script.
+	ErrLeafCommitmentMismatch = errors.New("taproot output key does not " +
+		"commit to script")
+)
+
+// TapLeaf is a leaf of a script tree: a script and its leaf version.
+type TapLeaf struct {
+	LeafVersion byte
+	Script      []byte
+}
+
+// NewBaseTapLeaf returns a tapscript leaf of the passed script.
+func NewBaseTapLeaf(script []byte) TapLeaf {
+	return TapLeaf{LeafVersion: BaseLeafVersion, Script: script}
+}
+
+// TapHash returns the hash of the leaf: the TapLeaf tagged hash of its leaf
+// version and its script prefixed with its length.
+func (l TapLeaf) TapHash() chainhash.Hash {
+	var buf bytes.Buffer
+	buf.WriteByte(l.LeafVersion)
+	common.WriteVarInt(&buf, 0, uint64(len(l.Script)))
+	buf.Write(l.Script)
+	return TaggedHash(TagTapLeaf, buf.Bytes())
+}
+
+// TapBranchHash returns the hash of a branch of the passed children: the
+// TapBranch tagged hash of the children in lexicographic order, so the hash
+// does not depend on which child is on which side.
+func TapBranchHash(a, b []byte) chainhash.Hash {
+	if bytes.Compare(a, b) > 0 {
+		a, b = b, a
+	}
+	return TaggedHash(TagTapBranch, a, b)
+}
+
+// ControlBlock proves the commitment of an output key to a script: it holds
+// the internal key, the parity of the output key, the leaf version of the
+// script, and the hashes of the nodes on the path from the leaf of the script
+// to the root of the tree.
+type ControlBlock struct {
+	InternalKey     *btcec.PublicKey
+	OutputKeyYIsOdd bool
+	LeafVersion     byte
+	InclusionProof  []byte
+}
+
+// ToBytes returns the serialized control block.
+func (c *ControlBlock) ToBytes() []byte {
+	b := make([]byte, 0, ControlBlockBaseSize+len(c.InclusionProof))
+	first := c.LeafVersion & leafVersionMask
+	if c.OutputKeyYIsOdd {
+		first |= 1
+	}
+	b = append(b, first)
+	b = append(b, SerializeXOnly(c.InternalKey)...)
+	return append(b, c.InclusionProof...)
+}
+
+// ParseControlBlock parses a serialized control block.
+func ParseControlBlock(b []byte) (*ControlBlock, error) {
+	proofLen := len(b) - ControlBlockBaseSize
+	if proofLen < 0 || proofLen%ControlBlockNodeSize != 0 ||
+		proofLen/ControlBlockNodeSize > ControlBlockMaxNodeCount {
+		return nil, ErrInvalidControlBlock
+	}
+	internalKey, err := ParseXOnlyPubKey(b[1:ControlBlockBaseSize])
+	if err != nil {
+		return nil, ErrInvalidControlBlock
+	}
+	return &ControlBlock{
+		InternalKey:     internalKey,
+		OutputKeyYIsOdd: b[0]&1 == 1,
+		LeafVersion:     b[0] & leafVersionMask,
+		InclusionProof:  append([]byte(nil), b[ControlBlockBaseSize:]...),
+	}, nil
+}
+
+// RootHash returns the root hash of the script tree proven by the control
+// block to include the passed script.
+func (c *ControlBlock) RootHash(script []byte) chainhash.Hash {
+	hash := TapLeaf{LeafVersion: c.LeafVersion, Script: script}.TapHash()
+	for i := 0; i < len(c.InclusionProof); i += ControlBlockNodeSize {
+		hash = TapBranchHash(hash[:],
+			c.InclusionProof[i:i+ControlBlockNodeSize])
+	}
+	return hash
+}
+
+// VerifyTaprootLeafCommitment verifies the control block proves that the
+// output key, serialized in x-only form, commits to the script.
+func VerifyTaprootLeafCommitment(c *ControlBlock, outputKey []byte, script []byte) error {
+	root := c.RootHash(script)
+	expected, err := ComputeTaprootOutputKey(c.InternalKey, root[:])
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(SerializeXOnly(expected), outputKey) ||
+		(expected.Y.Bit(0) == 1) != c.OutputKeyYIsOdd {
+		return ErrLeafCommitmentMismatch
+	}
+	return nil
+}
+
+// TapscriptProof is a leaf of a script tree along with the proof of its
+// inclusion in the tree.
+type TapscriptProof struct {
+	TapLeaf
+
+	// RootHash is the root hash of the tree.
+	RootHash chainhash.Hash
+
+	// InclusionProof is the concatenated hashes of the nodes on the path
+	// from the leaf to the root.
+	InclusionProof []byte
+}
+
+// ToControlBlock returns the control block spending the leaf of an output
+// committing to the internal key and the tree.
+func (p *TapscriptProof) ToControlBlock(internalKey *btcec.PublicKey) (*ControlBlock, error) {
+	outputKey, err := ComputeTaprootOutputKey(internalKey, p.RootHash[:])
+	if err != nil {
+		return nil, err
+	}
+	internal, err := ParseXOnlyPubKey(SerializeXOnly(internalKey))
+	if err != nil {
+		return nil, err
+	}
+	return &ControlBlock{
+		InternalKey:     internal,
+		OutputKeyYIsOdd: outputKey.Y.Bit(0) == 1,
+		LeafVersion:     p.LeafVersion,
+		InclusionProof:  p.InclusionProof,
+	}, nil
+}
+
+// TapscriptTree is a script tree along with the inclusion proof of each of
+// its leaves.
+type TapscriptTree struct {
+	RootHash   chainhash.Hash
+	LeafProofs []TapscriptProof
+}
+
+// treeNode is a node of a script tree being assembled, along with the indexes
+// of the leaves under it.
+type treeNode struct {
+	hash   chainhash.Hash
+	leaves []int
+}
+
+// AssembleTaprootScriptTree returns a script tree of the passed leaves.  The
+// tree is built bottom up by pairing adjacent nodes, carrying an odd node of a
+// level up to the next, which balances the tree so every leaf is at a depth of
+// at most one more than the logarithm of the number of leaves.  Wallets
+// wanting likely scripts closer to the root build their trees with
+// TapBranchHash directly.  It returns nil when there are no leaves.
+func AssembleTaprootScriptTree(leaves ...TapLeaf) *TapscriptTree {
+	if len(leaves) == 0 {
+		return nil
+	}
+	tree := &TapscriptTree{LeafProofs: make([]TapscriptProof, len(leaves))}
+	level := make([]treeNode, len(leaves))
+	for i, leaf := range leaves {
+		tree.LeafProofs[i].TapLeaf = leaf
+		level[i] = treeNode{hash: leaf.TapHash(), leaves: []int{i}}
+	}
+
+	for len(level) > 1 {
+		next := make([]treeNode, 0, (len(level)+1)/2)
+		for i := 0; i+1 < len(level); i += 2 {
+			left, right := level[i], level[i+1]
+			for _, idx := range left.leaves {
+				p := &tree.LeafProofs[idx]
+				p.InclusionProof = append(p.InclusionProof,
+					right.hash[:]...)
+			}
+			for _, idx := range right.leaves {
+				p := &tree.LeafProofs[idx]
+				p.InclusionProof = append(p.InclusionProof,
+					left.hash[:]...)
+			}
+			next = append(next, treeNode{
+				hash:   TapBranchHash(left.hash[:], right.hash[:]),
+				leaves: append(left.leaves, right.leaves...),
+			})
+		}
+		if len(level)%2 == 1 {
+			next = append(next, level[len(level)-1])
+		}
+		level = next
+	}
+
+	tree.RootHash = level[0].hash
+	for i := range tree.LeafProofs {
+		tree.LeafProofs[i].RootHash = tree.RootHash
+	}
+	return tree
+}
@@ -0,0 +1,128 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package taproot_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/zeusyf/btcutil/taproot"
+)
+
+// testLeaves returns n tapscript leaves of distinct scripts.
+func testLeaves(n int) []taproot.TapLeaf {
+	leaves := make([]taproot.TapLeaf, n)
+	for i := range leaves {
+		leaves[i] = taproot.NewBaseTapLeaf([]byte{0x01, byte(i), 0x75, 0x51})
+	}
+	return leaves
+}
+
+// TestTapBranchHash ensures branch hashes do not depend on the order of the
+// children.
+func TestTapBranchHash(t *testing.T) {
+	leaves := testLeaves(2)
+	a, b := leaves[0].TapHash(), leaves[1].TapHash()
+	if taproot.TapBranchHash(a[:], b[:]) != taproot.TapBranchHash(b[:], a[:]) {
+		t.Error("TapBranchHash: hash depends on the order of the children")
+	}
+	other := taproot.TapLeaf{LeafVersion: 0xc2, Script: leaves[0].Script}
+	if other.TapHash() == a {
+		t.Error("TapHash: hash does not commit to the leaf version")
+	}
+}
+
+// TestScriptTree ensures every leaf of assembled trees can be spent with its
+// control block, and no other script can.
+func TestScriptTree(t *testing.T) {
+	internalKey := testKey(1).PubKey()
+	if taproot.AssembleTaprootScriptTree() != nil {
+		t.Error("AssembleTaprootScriptTree: got a tree without leaves")
+	}
+
+	for n := 1; n <= 7; n++ {
+		leaves := testLeaves(n)
+		tree := taproot.AssembleTaprootScriptTree(leaves...)
+		if n == 1 && tree.RootHash != leaves[0].TapHash() {
+			t.Errorf("single leaf tree: got root %v", tree.RootHash)
+		}
+		outputKey, err := taproot.ComputeTaprootOutputKey(internalKey,
+			tree.RootHash[:])
+		if err != nil {
+			t.Fatalf("ComputeTaprootOutputKey: unexpected error: %v", err)
+		}
+		xOnly := taproot.SerializeXOnly(outputKey)
+
+		for i, proof := range tree.LeafProofs {
+			if !bytes.Equal(proof.Script, leaves[i].Script) {
+				t.Fatalf("%d leaves: proof %d is of the wrong leaf", n, i)
+			}
+			cb, err := proof.ToControlBlock(internalKey)
+			if err != nil {
+				t.Fatalf("ToControlBlock: unexpected error: %v", err)
+			}
+			parsed, err := taproot.ParseControlBlock(cb.ToBytes())
+			if err != nil {
+				t.Fatalf("ParseControlBlock: unexpected error: %v", err)
+			}
+			if !bytes.Equal(parsed.ToBytes(), cb.ToBytes()) ||
+				parsed.LeafVersion != taproot.BaseLeafVersion {
+				t.Errorf("%d leaves: control block %d does not "+
+					"round trip", n, i)
+			}
+
+			err = taproot.VerifyTaprootLeafCommitment(parsed, xOnly,
+				proof.Script)
+			if err != nil {
+				t.Errorf("%d leaves: leaf %d: unexpected error: %v",
+					n, i, err)
+			}
+			err = taproot.VerifyTaprootLeafCommitment(parsed, xOnly,
+				[]byte{0x51})
+			if err != taproot.ErrLeafCommitmentMismatch {
+				t.Errorf("%d leaves: leaf %d: got error %v for "+
+					"another script, want %v", n, i, err,
+					taproot.ErrLeafCommitmentMismatch)
+			}
+
+			parsed.OutputKeyYIsOdd = !parsed.OutputKeyYIsOdd
+			err = taproot.VerifyTaprootLeafCommitment(parsed, xOnly,
+				proof.Script)
+			if err != taproot.ErrLeafCommitmentMismatch {
+				t.Errorf("%d leaves: leaf %d: got error %v for the "+
+					"wrong parity, want %v", n, i, err,
+					taproot.ErrLeafCommitmentMismatch)
+			}
+		}
+	}
+}
+
+// TestParseControlBlock ensures malformed control blocks are rejected.
+func TestParseControlBlock(t *testing.T) {
+	valid := append([]byte{taproot.BaseLeafVersion},
+		taproot.SerializeXOnly(testKey(1).PubKey())...)
+	offCurve := make([]byte, 33)
+	offCurve[32] = 5
+
+	tests := []struct {
+		name string
+		b    []byte
+	}{
+		{"short", valid[:32]},
+		{"partial node", append(valid, make([]byte, 31)...)},
+		{"too deep", append(valid, make([]byte, 129*32)...)},
+		{"internal key off curve", offCurve},
+	}
+	for _, test := range tests {
+		if _, err := taproot.ParseControlBlock(test.b); err != taproot.ErrInvalidControlBlock {
+			t.Errorf("%s: got error %v, want %v", test.name, err,
+				taproot.ErrInvalidControlBlock)
+		}
+	}
+	if _, err := taproot.ParseControlBlock(append(valid, make([]byte, 128*32)...)); err != nil {
+		t.Errorf("ParseControlBlock: unexpected error at the largest "+
+			"depth: %v", err)
+	}
+}
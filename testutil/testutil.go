@@ -0,0 +1,451 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package testutil certifies the parameters of custom OMC networks against
+// this module.
+//
+// Given the NetParams of a network, Generate derives addresses of each type,
+// WIF encoded private keys and extended keys from a fixed seed, encoding them
+// with the version bytes of the network.  Verify decodes every encoding back,
+// checking it re-encodes to the same string, carries the version bytes of the
+// network and holds the key or hash it was encoded from.  CheckGolden does
+// both in a test and compares the result with a golden file, so a network
+// whose parameters pass can publish the file as the reference other
+// implementations are checked against.
+package testutil
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/base58"
+	"github.com/zeusyf/btcutil/hdkeychain"
+	"github.com/zeusyf/btcutil/scriptclass"
+	"github.com/zeusyf/btcutil/testvectors"
+)
+
+// Version is the version of the layout of golden files, increased whenever a
+// field is changed or removed.
+const Version = 1
+
+// numKeys is the number of keys derived for the address and WIF vectors.
+const numKeys = 3
+
+var (
+	// ErrMismatch describes an error where an encoding does not decode
+	// back to what it was encoded from.
+	ErrMismatch = errors.New("round trip mismatch")
+
+	// ErrWrongNet describes an error where an encoding decodes with the
+	// version bytes of another network.
+	ErrWrongNet = errors.New("encoding is for another network")
+
+	// ErrGoldenMismatch describes an error where the vectors of a network
+	// differ from those of its golden file.
+	ErrGoldenMismatch = errors.New("vectors differ from golden file")
+)
+
+// VectorError describes a vector failing to round trip.
+type VectorError struct {
+	// Kind is the kind of the vector, such as pubkeyhash, wif or xpub.
+	Kind string
+
+	// Encoded is the encoding failing to round trip.
+	Encoded string
+
+	// Err is the reason.
+	Err error
+}
+
+// Error returns the vector along with the reason it failed.
+func (e *VectorError) Error() string {
+	return fmt.Sprintf("%s %s: %v", e.Kind, e.Encoded, e.Err)
+}
+
+// Unwrap returns the reason the vector failed.
+func (e *VectorError) Unwrap() error {
+	return e.Err
+}
+
+// Params are the parameters of a network as recorded in golden files, with
+// version bytes hex encoded.
+type Params struct {
+	Name             string `json:"name"`
+	PubKeyHashAddrID string `json:"pubKeyHashAddrID"`
+	ScriptHashAddrID string `json:"scriptHashAddrID"`
+	PrivateKeyID     string `json:"privateKeyID"`
+	Bech32HRP        string `json:"bech32HRP"`
+	HDPrivateKeyID   string `json:"hdPrivateKeyID"`
+	HDPublicKeyID    string `json:"hdPublicKeyID"`
+}
+
+// Golden is the content of the golden file of a network.  The vectors share
+// the layout of the testvectors package.
+type Golden struct {
+	Version      int                             `json:"version"`
+	Seed         string                          `json:"seed"`
+	Params       Params                          `json:"params"`
+	Addresses    []testvectors.AddressVector     `json:"addresses"`
+	WIFs         []testvectors.WIFVector         `json:"wifs"`
+	ExtendedKeys []testvectors.ExtendedKeyVector `json:"extendedKeys"`
+}
+
+// JSON returns the golden file encoding of g.
+func (g *Golden) JSON() ([]byte, error) {
+	b, err := json.MarshalIndent(g, "", "\t")
+	if err != nil {
+		return nil, err
+	}
+	return append(b, '\n'), nil
+}
+
+// Generate returns the vectors of net, derived from testvectors.Seed.  The
+// keys of the address and WIF vectors are the children of m/0'.  Addresses
+// are pay-to-pubkey-hash and pay-to-script-hash, the types whose version
+// bytes NetParams defines.
+func Generate(net btcutil.NetParams) (*Golden, error) {
+	hdPriv, hdPub := net.HDPrivateKeyID(), net.HDPublicKeyID()
+	g := &Golden{
+		Version: Version,
+		Seed:    hex.EncodeToString(testvectors.Seed),
+		Params: Params{
+			Name:             net.Name(),
+			PubKeyHashAddrID: hex.EncodeToString([]byte{net.PubKeyHashAddrID()}),
+			ScriptHashAddrID: hex.EncodeToString([]byte{net.ScriptHashAddrID()}),
+			PrivateKeyID:     hex.EncodeToString([]byte{net.PrivateKeyID()}),
+			Bech32HRP:        net.Bech32HRP(),
+			HDPrivateKeyID:   hex.EncodeToString(hdPriv[:]),
+			HDPublicKeyID:    hex.EncodeToString(hdPub[:]),
+		},
+	}
+
+	// The master key is derived with any versions, since the versions of
+	// net are set when the keys are encoded.
+	master, err := hdkeychain.NewMaster(testvectors.Seed, &chaincfg.MainNetParams)
+	if err != nil {
+		return nil, err
+	}
+	account, err := master.Child(hdkeychain.HardenedKeyStart)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := []string{"m", "m/0'"}
+	keys := []*hdkeychain.ExtendedKey{master, account}
+	for i := uint32(0); i < numKeys; i++ {
+		child, err := account.Child(i)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, fmt.Sprintf("m/0'/%d", i))
+		keys = append(keys, child)
+
+		privKey, err := child.ECPrivKey()
+		if err != nil {
+			return nil, err
+		}
+		for _, compressed := range []bool{true, false} {
+			wif, err := btcutil.NewWIFForNet(privKey, net, compressed)
+			if err != nil {
+				return nil, err
+			}
+			g.WIFs = append(g.WIFs, testvectors.WIFVector{
+				PrivKey:    hex.EncodeToString(wif.PrivKey.Serialize()),
+				Compressed: compressed,
+				WIF:        wif.String(),
+				PubKey:     hex.EncodeToString(wif.SerializePubKey()),
+			})
+		}
+
+		addrs, err := keyAddresses(child, net)
+		if err != nil {
+			return nil, err
+		}
+		g.Addresses = append(g.Addresses, addrs...)
+	}
+
+	for i, key := range keys {
+		xprv, xpub, err := encodeKey(key, net)
+		if err != nil {
+			return nil, err
+		}
+		g.ExtendedKeys = append(g.ExtendedKeys, testvectors.ExtendedKeyVector{
+			Path: paths[i],
+			XPrv: xprv,
+			XPub: xpub,
+		})
+	}
+	return g, nil
+}
+
+// keyAddresses returns the pay-to-pubkey-hash address of the public key of
+// key, and the pay-to-script-hash address of the script paying to it.
+func keyAddresses(key *hdkeychain.ExtendedKey,
+	net btcutil.NetParams) ([]testvectors.AddressVector, error) {
+
+	pubKey, err := key.ECPubKey()
+	if err != nil {
+		return nil, err
+	}
+	pkHash, err := btcutil.NewAddressPubKeyHashForNet(
+		btcutil.Hash160(pubKey.SerializeCompressed()), net)
+	if err != nil {
+		return nil, err
+	}
+	redeemScript, err := scriptclass.PayToAddrScript(pkHash)
+	if err != nil {
+		return nil, err
+	}
+	scriptHash, err := btcutil.NewAddressScriptHashForNet(
+		btcutil.Hash160(redeemScript), net)
+	if err != nil {
+		return nil, err
+	}
+
+	var vectors []testvectors.AddressVector
+	for _, a := range []struct {
+		typ  string
+		addr btcutil.Address
+	}{
+		{"pubkeyhash", pkHash},
+		{"scripthash", scriptHash},
+	} {
+		pkScript, err := scriptclass.PayToAddrScript(a.addr)
+		if err != nil {
+			return nil, err
+		}
+		vectors = append(vectors, testvectors.AddressVector{
+			Type:          a.typ,
+			Address:       a.addr.String(),
+			ScriptAddress: hex.EncodeToString(a.addr.ScriptAddress()),
+			PkScript:      hex.EncodeToString(pkScript),
+		})
+	}
+	return vectors, nil
+}
+
+// encodeKey returns the extended private and public keys of key, encoded with
+// the versions of net.  The public key is built from the fields of key rather
+// than by Neuter, which only knows the versions of registered networks.
+func encodeKey(key *hdkeychain.ExtendedKey, net btcutil.NetParams) (string, string, error) {
+	privKey, err := key.ECPrivKey()
+	if err != nil {
+		return "", "", err
+	}
+	pubKey, err := key.ECPubKey()
+	if err != nil {
+		return "", "", err
+	}
+	var parentFP [4]byte
+	binary.BigEndian.PutUint32(parentFP[:], key.ParentFingerprint())
+
+	hdPriv, hdPub := net.HDPrivateKeyID(), net.HDPublicKeyID()
+	xprv := hdkeychain.NewExtendedKey(hdPriv[:], privKey.Serialize(),
+		key.ChainCode(), parentFP[:], key.Depth(), key.ChildIndex(), true)
+	xpub := hdkeychain.NewExtendedKey(hdPub[:], pubKey.SerializeCompressed(),
+		key.ChainCode(), parentFP[:], key.Depth(), key.ChildIndex(), false)
+	return xprv.String(), xpub.String(), nil
+}
+
+// Verify decodes every vector of g, checking it re-encodes to the same
+// string, carries the version bytes of net and holds the hash or key it was
+// encoded from.  Custom networks must be registered with RegisterNetParams
+// for their addresses to decode.  The errors of all failing vectors are
+// returned joined, each as a *VectorError.
+func Verify(net btcutil.NetParams, g *Golden) error {
+	var errs []error
+	fail := func(kind, encoded string, err error) {
+		errs = append(errs, &VectorError{kind, encoded, err})
+	}
+
+	for _, v := range g.Addresses {
+		if err := verifyAddress(net, v); err != nil {
+			fail(v.Type, v.Address, err)
+		}
+	}
+	for _, v := range g.WIFs {
+		if err := verifyWIF(net, v); err != nil {
+			fail("wif", v.WIF, err)
+		}
+	}
+	for _, v := range g.ExtendedKeys {
+		if err := verifyExtendedKey(net, v); err != nil {
+			fail("extended key "+v.Path, v.XPrv, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// verifyAddress checks the round trip of an address vector.
+func verifyAddress(net btcutil.NetParams, v testvectors.AddressVector) error {
+	addr, err := btcutil.DecodeAddress(v.Address, nil)
+	if err != nil {
+		return err
+	}
+
+	var version byte
+	switch a := addr.(type) {
+	case *btcutil.AddressPubKeyHash:
+		if v.Type == "pubkeyhash" {
+			version = a.Version()
+		}
+	case *btcutil.AddressScriptHash:
+		if v.Type == "scripthash" {
+			version = a.Version()
+		}
+	}
+	want := net.PubKeyHashAddrID()
+	if v.Type == "scripthash" {
+		want = net.ScriptHashAddrID()
+	}
+	if version != want {
+		return fmt.Errorf("%w: decoded as %T version %#02x, want %s "+
+			"version %#02x", ErrWrongNet, addr, version, v.Type, want)
+	}
+
+	pkScript, err := scriptclass.PayToAddrScript(addr)
+	if err != nil {
+		return err
+	}
+	switch {
+	case addr.EncodeAddress() != v.Address:
+		return fmt.Errorf("%w: re-encoded as %s", ErrMismatch,
+			addr.EncodeAddress())
+	case hex.EncodeToString(addr.ScriptAddress()) != v.ScriptAddress:
+		return fmt.Errorf("%w: got script address %x, want %s",
+			ErrMismatch, addr.ScriptAddress(), v.ScriptAddress)
+	case hex.EncodeToString(pkScript) != v.PkScript:
+		return fmt.Errorf("%w: got pkScript %x, want %s", ErrMismatch,
+			pkScript, v.PkScript)
+	}
+	return nil
+}
+
+// verifyWIF checks the round trip of a WIF vector.
+func verifyWIF(net btcutil.NetParams, v testvectors.WIFVector) error {
+	wif, err := btcutil.DecodeWIF(v.WIF)
+	if err != nil {
+		return err
+	}
+	if !wif.IsForNetParams(net) {
+		return ErrWrongNet
+	}
+	switch {
+	case wif.String() != v.WIF:
+		return fmt.Errorf("%w: re-encoded as %s", ErrMismatch, wif)
+	case wif.CompressPubKey != v.Compressed:
+		return fmt.Errorf("%w: got compressed %v, want %v", ErrMismatch,
+			wif.CompressPubKey, v.Compressed)
+	case hex.EncodeToString(wif.PrivKey.Serialize()) != v.PrivKey:
+		return fmt.Errorf("%w: private key differs", ErrMismatch)
+	case hex.EncodeToString(wif.SerializePubKey()) != v.PubKey:
+		return fmt.Errorf("%w: got public key %x, want %s", ErrMismatch,
+			wif.SerializePubKey(), v.PubKey)
+	}
+	return nil
+}
+
+// verifyExtendedKey checks the round trip of an extended key vector, and that
+// its public key is that of its private key.
+func verifyExtendedKey(net btcutil.NetParams, v testvectors.ExtendedKeyVector) error {
+	hdPriv, hdPub := net.HDPrivateKeyID(), net.HDPublicKeyID()
+	for _, k := range []struct {
+		encoded string
+		version []byte
+		private bool
+	}{
+		{v.XPrv, hdPriv[:], true},
+		{v.XPub, hdPub[:], false},
+	} {
+		key, err := hdkeychain.NewKeyFromString(k.encoded)
+		if err != nil {
+			return err
+		}
+		if !bytes.Equal(base58.Decode(k.encoded)[:4], k.version) {
+			return fmt.Errorf("%w: %s", ErrWrongNet, k.encoded)
+		}
+		if key.IsPrivate() != k.private || key.String() != k.encoded {
+			return fmt.Errorf("%w: re-encoded as %s", ErrMismatch, key)
+		}
+	}
+
+	key, err := hdkeychain.NewKeyFromString(v.XPrv)
+	if err != nil {
+		return err
+	}
+	_, xpub, err := encodeKey(key, net)
+	if err != nil {
+		return err
+	}
+	if xpub != v.XPub {
+		return fmt.Errorf("%w: got xpub %s, want %s", ErrMismatch, xpub,
+			v.XPub)
+	}
+	return nil
+}
+
+// ReadGolden reads the golden file at path.
+func ReadGolden(path string) (*Golden, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var g Golden
+	if err := json.Unmarshal(b, &g); err != nil {
+		return nil, err
+	}
+	return &g, nil
+}
+
+// WriteGolden writes g to the golden file at path.
+func WriteGolden(path string, g *Golden) error {
+	b, err := g.JSON()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, b, 0644)
+}
+
+// CheckGolden generates and verifies the vectors of net, failing t on any
+// vector failing to round trip, and compares them with the golden file at
+// path.  When update is set, typically by an -update flag of the test, the
+// golden file is written instead of compared.  The golden file of a network
+// only changes with its parameters or with Version.
+func CheckGolden(t testing.TB, net btcutil.NetParams, path string, update bool) {
+	t.Helper()
+
+	g, err := Generate(net)
+	if err != nil {
+		t.Fatalf("%s: Generate: unexpected error: %v", net.Name(), err)
+	}
+	if err := Verify(net, g); err != nil {
+		t.Errorf("%s: Verify: %v", net.Name(), err)
+	}
+
+	if update {
+		if err := WriteGolden(path, g); err != nil {
+			t.Fatalf("%s: WriteGolden: unexpected error: %v", net.Name(), err)
+		}
+		return
+	}
+	got, err := g.JSON()
+	if err != nil {
+		t.Fatalf("%s: JSON: unexpected error: %v", net.Name(), err)
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%s: unexpected error: %v", net.Name(), err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s: %v %s", net.Name(), ErrGoldenMismatch, path)
+	}
+}
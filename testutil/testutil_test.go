@@ -0,0 +1,116 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package testutil_test
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/testutil"
+)
+
+// customNet returns the parameters of a custom network, registered so its
+// addresses decode.
+func customNet(t *testing.T) btcutil.NetParams {
+	t.Helper()
+	net := &btcutil.BasicNetParams{
+		Net:        "testutilnet",
+		PubKeyHash: 0xf6,
+		ScriptHash: 0xf7,
+		PrivateKey: 0xf8,
+		HRP:        "tu",
+		HDPrivate:  [4]byte{0x04, 0x11, 0x22, 0x33},
+		HDPublic:   [4]byte{0x04, 0x11, 0x22, 0x34},
+	}
+	err := btcutil.RegisterNetParams(net)
+	if err != nil && err != btcutil.ErrDuplicateNet {
+		t.Fatalf("RegisterNetParams: unexpected error: %v", err)
+	}
+	registered, _ := btcutil.LookupNetParams(net.Net)
+	return registered
+}
+
+// TestGenerate ensures the vectors of the main network and of a custom
+// network are deterministic and round trip.
+func TestGenerate(t *testing.T) {
+	for _, net := range []btcutil.NetParams{btcutil.MainNet, customNet(t)} {
+		g, err := testutil.Generate(net)
+		if err != nil {
+			t.Fatalf("%s: Generate: unexpected error: %v", net.Name(), err)
+		}
+		again, err := testutil.Generate(net)
+		if err != nil {
+			t.Fatalf("%s: Generate: unexpected error: %v", net.Name(), err)
+		}
+		if !reflect.DeepEqual(g, again) {
+			t.Errorf("%s: Generate: vectors differ between runs", net.Name())
+		}
+		if len(g.Addresses) == 0 || len(g.WIFs) == 0 ||
+			len(g.ExtendedKeys) == 0 {
+
+			t.Errorf("%s: missing vectors", net.Name())
+		}
+		if g.Params.Name != net.Name() {
+			t.Errorf("%s: got name %s", net.Name(), g.Params.Name)
+		}
+		if err := testutil.Verify(net, g); err != nil {
+			t.Errorf("%s: Verify: unexpected error: %v", net.Name(), err)
+		}
+	}
+}
+
+// TestVerifyWrongNet ensures vectors are rejected for the parameters of
+// another network.
+func TestVerifyWrongNet(t *testing.T) {
+	g, err := testutil.Generate(customNet(t))
+	if err != nil {
+		t.Fatalf("Generate: unexpected error: %v", err)
+	}
+	err = testutil.Verify(btcutil.MainNet, g)
+	if !errors.Is(err, testutil.ErrWrongNet) {
+		t.Fatalf("Verify: got error %v, want %v", err, testutil.ErrWrongNet)
+	}
+	var vErr *testutil.VectorError
+	if !errors.As(err, &vErr) || vErr.Kind != "pubkeyhash" ||
+		vErr.Encoded != g.Addresses[0].Address {
+
+		t.Errorf("Verify: got error %v, want first address", err)
+	}
+
+	// A vector altered after generation no longer round trips.
+	g.WIFs[0].PubKey = g.WIFs[1].PubKey
+	err = testutil.Verify(customNet(t), g)
+	if !errors.Is(err, testutil.ErrMismatch) {
+		t.Errorf("Verify: got error %v, want %v", err, testutil.ErrMismatch)
+	}
+}
+
+// TestGolden ensures golden files read back as written and are matched by
+// CheckGolden.
+func TestGolden(t *testing.T) {
+	net := customNet(t)
+	path := filepath.Join(t.TempDir(), "testutilnet.json")
+	testutil.CheckGolden(t, net, path, true)
+	testutil.CheckGolden(t, net, path, false)
+
+	g, err := testutil.ReadGolden(path)
+	if err != nil {
+		t.Fatalf("ReadGolden: unexpected error: %v", err)
+	}
+	want, err := testutil.Generate(net)
+	if err != nil {
+		t.Fatalf("Generate: unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(g, want) {
+		t.Errorf("ReadGolden: got %+v, want %+v", g, want)
+	}
+	if g.Version != testutil.Version || g.Params.PubKeyHashAddrID != "f6" {
+		t.Errorf("ReadGolden: got version %d, params %+v", g.Version,
+			g.Params)
+	}
+}
@@ -0,0 +1,31 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+//go:build ignore
+
+// This program writes the test vectors generated by the testvectors package
+// to vectors.json.  It is run by go generate.
+package main
+
+import (
+	"encoding/json"
+	"log"
+	"os"
+
+	"github.com/zeusyf/btcutil/testvectors"
+)
+
+func main() {
+	v, err := testvectors.Generate()
+	if err != nil {
+		log.Fatalf("failed to generate test vectors: %v", err)
+	}
+	b, err := json.MarshalIndent(v, "", "\t")
+	if err != nil {
+		log.Fatalf("failed to encode test vectors: %v", err)
+	}
+	if err := os.WriteFile("vectors.json", append(b, '\n'), 0644); err != nil {
+		log.Fatalf("failed to write test vectors: %v", err)
+	}
+}
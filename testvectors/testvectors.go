@@ -0,0 +1,329 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package testvectors generates deterministic test vectors from this module,
+// so implementations of the Omega formats in other languages can be checked
+// against it as the reference implementation.
+//
+// The vectors cover, for every supported network, the addresses of each type,
+// WIF encoded private keys, extended keys along a derivation path and an
+// unsigned PSBT packet, as well as the formatting of amounts.  They are derived
+// from fixed seeds only, so every run produces identical output.  Running
+// go generate in this directory writes them to vectors.json.
+package testvectors
+
+//go:generate go run gen.go
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/hdkeychain"
+	"github.com/zeusyf/btcutil/psbt"
+	"github.com/zeusyf/btcutil/scriptclass"
+	"github.com/zeusyf/omega/token"
+)
+
+// Version is the version of the layout of the vectors, increased whenever a
+// field is changed or removed.
+const Version = 1
+
+// Seed is the seed of the master extended key all vectors derive from, which
+// is the seed of the first BIP0032 test vector.
+var Seed = []byte{
+	0x00, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07,
+	0x08, 0x09, 0x0a, 0x0b, 0x0c, 0x0d, 0x0e, 0x0f,
+}
+
+// Networks are the networks vectors are generated for.
+var Networks = []*chaincfg.Params{
+	&chaincfg.MainNetParams,
+	&chaincfg.TestNet3Params,
+	&chaincfg.RegressionNetParams,
+	&chaincfg.SimNetParams,
+}
+
+// numKeys is the number of keys derived for the address and WIF vectors of
+// each network.
+const numKeys = 3
+
+// testAmounts are the amounts of the amount vectors, in Hao.
+var testAmounts = []int64{
+	0, 1, 99, 100000000, 123456789, -50000000, 2100000000000000,
+}
+
+// Vectors is the set of all test vectors.
+type Vectors struct {
+	Version  int            `json:"version"`
+	Seed     string         `json:"seed"`
+	Networks []*Network     `json:"networks"`
+	Amounts  []AmountVector `json:"amounts"`
+}
+
+// Network holds the vectors of a network.
+type Network struct {
+	Name         string              `json:"name"`
+	Addresses    []AddressVector     `json:"addresses"`
+	WIFs         []WIFVector         `json:"wifs"`
+	ExtendedKeys []ExtendedKeyVector `json:"extendedKeys"`
+	PSBTs        []PSBTVector        `json:"psbts"`
+}
+
+// AddressVector is an address along with the script paying to it.  The
+// address of a public key is its hex encoding, as accepted by DecodeAddress.
+type AddressVector struct {
+	Type          string `json:"type"`
+	Address       string `json:"address"`
+	ScriptAddress string `json:"scriptAddress"`
+	PkScript      string `json:"pkScript"`
+}
+
+// WIFVector is a private key along with its WIF encoding and public key.
+type WIFVector struct {
+	PrivKey    string `json:"privKey"`
+	Compressed bool   `json:"compressed"`
+	WIF        string `json:"wif"`
+	PubKey     string `json:"pubKey"`
+}
+
+// ExtendedKeyVector is the pair of extended keys at a derivation path from
+// the master key.
+type ExtendedKeyVector struct {
+	Path string `json:"path"`
+	XPrv string `json:"xprv"`
+	XPub string `json:"xpub"`
+}
+
+// PSBTVector is an unsigned packet along with the transaction it signs.
+type PSBTVector struct {
+	Description string `json:"description"`
+	UnsignedTx  string `json:"unsignedTx"`
+	Base64      string `json:"base64"`
+}
+
+// AmountVector is an amount along with its encodings.
+type AmountVector struct {
+	Hao    int64  `json:"hao"`
+	Text   string `json:"text"`
+	String string `json:"string"`
+}
+
+// Generate returns the test vectors of all Networks.
+func Generate() (*Vectors, error) {
+	v := &Vectors{
+		Version: Version,
+		Seed:    hex.EncodeToString(Seed),
+	}
+	for _, net := range Networks {
+		n, err := generateNetwork(net)
+		if err != nil {
+			return nil, err
+		}
+		v.Networks = append(v.Networks, n)
+	}
+	for _, hao := range testAmounts {
+		amt := btcutil.Amount(hao)
+		text, err := amt.MarshalText()
+		if err != nil {
+			return nil, err
+		}
+		v.Amounts = append(v.Amounts, AmountVector{
+			Hao:    hao,
+			Text:   string(text),
+			String: amt.String(),
+		})
+	}
+	return v, nil
+}
+
+// generateNetwork returns the vectors of net.  The keys of the address and
+// WIF vectors are the children of m/0'.
+func generateNetwork(net *chaincfg.Params) (*Network, error) {
+	n := &Network{Name: net.Name}
+
+	master, err := hdkeychain.NewMaster(Seed, net)
+	if err != nil {
+		return nil, err
+	}
+	account, err := master.Child(hdkeychain.HardenedKeyStart)
+	if err != nil {
+		return nil, err
+	}
+
+	paths := []keyPath{{"m", master}, {"m/0'", account}}
+	var pkScripts [][]byte
+	for i := uint32(0); i < numKeys; i++ {
+		child, err := account.Child(i)
+		if err != nil {
+			return nil, err
+		}
+		paths = append(paths, keyPath{fmt.Sprintf("m/0'/%d", i), child})
+
+		privKey, err := child.ECPrivKey()
+		if err != nil {
+			return nil, err
+		}
+		for _, compressed := range []bool{true, false} {
+			wif, err := btcutil.NewWIF(privKey, net, compressed)
+			if err != nil {
+				return nil, err
+			}
+			n.WIFs = append(n.WIFs, WIFVector{
+				PrivKey:    hex.EncodeToString(wif.PrivKey.Serialize()),
+				Compressed: compressed,
+				WIF:        wif.String(),
+				PubKey:     hex.EncodeToString(wif.SerializePubKey()),
+			})
+		}
+
+		addrs, err := keyAddresses(child, net)
+		if err != nil {
+			return nil, err
+		}
+		for _, a := range addrs {
+			pkScript, err := scriptclass.PayToAddrScript(a.addr)
+			if err != nil {
+				return nil, err
+			}
+			if a.typ == "pubkeyhash" {
+				pkScripts = append(pkScripts, pkScript)
+			}
+			n.Addresses = append(n.Addresses, AddressVector{
+				Type:          a.typ,
+				Address:       a.addr.String(),
+				ScriptAddress: hex.EncodeToString(a.addr.ScriptAddress()),
+				PkScript:      hex.EncodeToString(pkScript),
+			})
+		}
+	}
+
+	for _, p := range paths {
+		pub, err := p.key.Neuter()
+		if err != nil {
+			return nil, err
+		}
+		n.ExtendedKeys = append(n.ExtendedKeys, ExtendedKeyVector{
+			Path: p.path,
+			XPrv: p.key.String(),
+			XPub: pub.String(),
+		})
+	}
+
+	packet, err := generatePSBT(pkScripts)
+	if err != nil {
+		return nil, err
+	}
+	n.PSBTs = append(n.PSBTs, *packet)
+	return n, nil
+}
+
+// keyPath is an extended key along with its derivation path.
+type keyPath struct {
+	path string
+	key  *hdkeychain.ExtendedKey
+}
+
+// typedAddress is an address along with the name of its type in the vectors.
+type typedAddress struct {
+	typ  string
+	addr btcutil.Address
+}
+
+// keyAddresses returns an address of each type for the public key of key:
+// the key itself, its hash, the hash of the script paying to the key, and
+// multi-signature and contract addresses of the same hash.
+func keyAddresses(key *hdkeychain.ExtendedKey,
+	net *chaincfg.Params) ([]typedAddress, error) {
+	pubKey, err := key.ECPubKey()
+	if err != nil {
+		return nil, err
+	}
+	serialized := pubKey.SerializeCompressed()
+	hash := btcutil.Hash160(serialized)
+
+	pk, err := btcutil.NewAddressPubKey(serialized, net)
+	if err != nil {
+		return nil, err
+	}
+	pkHash, err := btcutil.NewAddressPubKeyHash(hash, net)
+	if err != nil {
+		return nil, err
+	}
+	redeemScript, err := scriptclass.PayToAddrScript(pk)
+	if err != nil {
+		return nil, err
+	}
+	scriptHash, err := btcutil.NewAddressScriptHash(redeemScript, net)
+	if err != nil {
+		return nil, err
+	}
+	multiSig, err := btcutil.NewAddressMultiSig(hash, net)
+	if err != nil {
+		return nil, err
+	}
+	contract, err := btcutil.NewAddressContract(hash, net)
+	if err != nil {
+		return nil, err
+	}
+	return []typedAddress{
+		{"pubkey", pk},
+		{"pubkeyhash", pkHash},
+		{"scripthash", scriptHash},
+		{"multisig", multiSig},
+		{"contract", contract},
+	}, nil
+}
+
+// generatePSBT returns a packet spending an output paying the first of
+// pkScripts to the others.  The previous transaction is a coinbase-like
+// transaction, so the packet depends on nothing but pkScripts.
+func generatePSBT(pkScripts [][]byte) (*PSBTVector, error) {
+	const value = 100000000
+	const fee = 10000
+
+	prev := wire.NewMsgTx(wire.TxVersion)
+	prev.AddTxIn(&wire.TxIn{SignatureIndex: 0xffffffff})
+	prev.AddTxOut(output(value, pkScripts[0]))
+
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: wire.OutPoint{Hash: prev.TxHash(), Index: 0},
+		Sequence:         wire.MaxTxInSequenceNum,
+	})
+	share := (value - fee) / int64(len(pkScripts)-1)
+	for _, pkScript := range pkScripts[1:] {
+		tx.AddTxOut(output(share, pkScript))
+	}
+
+	p, err := psbt.New(tx)
+	if err != nil {
+		return nil, err
+	}
+	p.Inputs[0].NonWitnessUtxo = prev
+	encoded, err := p.B64Encode()
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := tx.Serialize(&buf); err != nil {
+		return nil, err
+	}
+	return &PSBTVector{
+		Description: "unsigned spend of a pubkey hash output to two outputs",
+		UnsignedTx:  hex.EncodeToString(buf.Bytes()),
+		Base64:      encoded,
+	}, nil
+}
+
+// output returns an output paying value Hao of OMC to pkScript.
+func output(value int64, pkScript []byte) *wire.TxOut {
+	return &wire.TxOut{
+		Token:    token.Token{Value: &token.NumeralVal{Val: value}},
+		PkScript: pkScript,
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package testvectors_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/hdkeychain"
+	"github.com/zeusyf/btcutil/psbt"
+	"github.com/zeusyf/btcutil/testvectors"
+)
+
+// TestGenerate ensures the vectors are deterministic and decode back with the
+// package they were generated from.
+func TestGenerate(t *testing.T) {
+	v, err := testvectors.Generate()
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	again, err := testvectors.Generate()
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	if !reflect.DeepEqual(v, again) {
+		t.Fatalf("Generate: vectors differ between runs")
+	}
+	if len(v.Networks) != len(testvectors.Networks) {
+		t.Fatalf("Generate: got %d networks, want %d", len(v.Networks),
+			len(testvectors.Networks))
+	}
+
+	for i, n := range v.Networks {
+		net := testvectors.Networks[i]
+		if len(n.Addresses) == 0 || len(n.WIFs) == 0 ||
+			len(n.ExtendedKeys) == 0 || len(n.PSBTs) == 0 {
+			t.Errorf("%s: missing vectors", n.Name)
+		}
+		for _, a := range n.Addresses {
+			addr, err := btcutil.DecodeAddress(a.Address, net)
+			if err != nil {
+				t.Errorf("%s: DecodeAddress(%s): %v", n.Name, a.Address, err)
+				continue
+			}
+			if hex.EncodeToString(addr.ScriptAddress()) != a.ScriptAddress ||
+				!addr.IsForNet(net) {
+				t.Errorf("%s: DecodeAddress(%s): got %x", n.Name, a.Address,
+					addr.ScriptAddress())
+			}
+		}
+		for _, w := range n.WIFs {
+			wif, err := btcutil.DecodeWIF(w.WIF)
+			if err != nil || !wif.IsForNet(net) ||
+				wif.CompressPubKey != w.Compressed ||
+				hex.EncodeToString(wif.PrivKey.Serialize()) != w.PrivKey {
+				t.Errorf("%s: DecodeWIF(%s): got %v, %v", n.Name, w.WIF,
+					wif, err)
+			}
+		}
+		for _, k := range n.ExtendedKeys {
+			key, err := hdkeychain.NewKeyFromString(k.XPrv)
+			if err != nil || !key.IsPrivate() || !key.IsForNet(net) {
+				t.Errorf("%s: NewKeyFromString(%s): %v", n.Name, k.XPrv, err)
+				continue
+			}
+			pub, err := key.Neuter()
+			if err != nil || pub.String() != k.XPub {
+				t.Errorf("%s %s: got xpub %v, want %s", n.Name, k.Path,
+					pub, k.XPub)
+			}
+		}
+		for _, p := range n.PSBTs {
+			packet, err := psbt.ParseBase64(p.Base64)
+			if err != nil {
+				t.Errorf("%s: ParseBase64: %v", n.Name, err)
+				continue
+			}
+			var buf bytes.Buffer
+			if err := packet.UnsignedTx.Serialize(&buf); err != nil ||
+				hex.EncodeToString(buf.Bytes()) != p.UnsignedTx {
+				t.Errorf("%s: unsigned transaction mismatch", n.Name)
+			}
+		}
+	}
+
+	for _, a := range v.Amounts {
+		amt, err := btcutil.ParseAmount(a.Text)
+		if err != nil || int64(amt) != a.Hao {
+			t.Errorf("ParseAmount(%s): got %v, %v, want %d", a.Text, amt,
+				err, a.Hao)
+		}
+	}
+}
+
+// TestVectorsFile ensures a committed vectors.json is up to date with the
+// generator, so go generate must be rerun whenever the output changes.
+func TestVectorsFile(t *testing.T) {
+	b, err := os.ReadFile("vectors.json")
+	if os.IsNotExist(err) {
+		t.Skip("vectors.json has not been generated")
+	}
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	v, err := testvectors.Generate()
+	if err != nil {
+		t.Fatalf("Generate: %v", err)
+	}
+	want, err := json.MarshalIndent(v, "", "\t")
+	if err != nil {
+		t.Fatalf("MarshalIndent: %v", err)
+	}
+	if !bytes.Equal(b, append(want, '\n')) {
+		t.Errorf("vectors.json is stale; run go generate")
+	}
+}
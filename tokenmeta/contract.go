@@ -0,0 +1,109 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package tokenmeta
+
+import (
+	"encoding/binary"
+	"errors"
+
+	"github.com/zeusyf/btcutil"
+)
+
+// TokenInfoMethod is the signature of the method of registry contracts
+// returning the symbol and decimals of a token type, or an empty symbol for
+// token types not registered.
+const TokenInfoMethod = "tokenInfo(uint64)"
+
+// wordSize is the size of the words of the contract call encoding.
+const wordSize = 32
+
+// ErrMalformedResponse describes an error where the output of a registry
+// contract call is not a valid encoding of a symbol and decimals.
+var ErrMalformedResponse = errors.New("malformed registry contract response")
+
+// tokenInfoSelector is the method selector of TokenInfoMethod: the first four
+// bytes of its Keccak-256 hash.
+var tokenInfoSelector = [4]byte{0x62, 0x2d, 0x1e, 0xeb}
+
+// ContractCaller is the interface to a node executing contract calls without
+// creating a transaction, such as the RPC client of an Omega node.  btcutil
+// has no RPC client of its own, so callers wrap the client they use in a
+// ContractCaller.
+//
+// CallContract executes input against the contract at addr and returns its
+// output.
+type ContractCaller interface {
+	CallContract(addr *btcutil.AddressContract, input []byte) ([]byte, error)
+}
+
+// ContractResolver is a Resolver calling the TokenInfoMethod of a registry
+// contract through Caller.  Calls and their outputs use the encoding of
+// Ethereum contract calls.
+type ContractResolver struct {
+	Caller   ContractCaller
+	Contract *btcutil.AddressContract
+}
+
+// ResolveToken returns the metadata of tokenType registered with the
+// contract, or ErrUnknownToken when the contract returns an empty symbol.
+func (r *ContractResolver) ResolveToken(tokenType uint64) (Info, error) {
+	input := make([]byte, len(tokenInfoSelector)+wordSize)
+	copy(input, tokenInfoSelector[:])
+	binary.BigEndian.PutUint64(input[len(input)-8:], tokenType)
+
+	output, err := r.Caller.CallContract(r.Contract, input)
+	if err != nil {
+		return Info{}, err
+	}
+	info, err := decodeInfo(output)
+	if err != nil {
+		return Info{}, err
+	}
+	if info.Symbol == "" {
+		return Info{}, ErrUnknownToken
+	}
+	return info, nil
+}
+
+// word returns the i-th word of output as an integer, and false when there
+// is no such word or its value does not fit in 32 bits.
+func word(output []byte, i uint64) (uint64, bool) {
+	if i >= uint64(len(output))/wordSize {
+		return 0, false
+	}
+	w := output[i*wordSize : (i+1)*wordSize]
+	for _, b := range w[:wordSize-4] {
+		if b != 0 {
+			return 0, false
+		}
+	}
+	return uint64(binary.BigEndian.Uint32(w[wordSize-4:])), true
+}
+
+// decodeInfo decodes the output of TokenInfoMethod: the offset of the
+// symbol, the decimals, and at the offset, the length of the symbol followed
+// by its bytes padded to a whole word.
+func decodeInfo(output []byte) (Info, error) {
+	offset, ok := word(output, 0)
+	if !ok || offset%wordSize != 0 || offset < 2*wordSize {
+		return Info{}, ErrMalformedResponse
+	}
+	decimals, ok := word(output, 1)
+	if !ok || decimals > 0xff {
+		return Info{}, ErrMalformedResponse
+	}
+	length, ok := word(output, offset/wordSize)
+	if !ok {
+		return Info{}, ErrMalformedResponse
+	}
+	start := offset + wordSize
+	if length > uint64(len(output))-start {
+		return Info{}, ErrMalformedResponse
+	}
+	return Info{
+		Symbol:   string(output[start : start+length]),
+		Decimals: uint8(decimals),
+	}, nil
+}
@@ -0,0 +1,96 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package tokenmeta_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/tokenmeta"
+)
+
+// fakeCaller returns a fixed output for calls to a contract, recording the
+// last input.
+type fakeCaller struct {
+	output []byte
+	err    error
+	addr   *btcutil.AddressContract
+	input  []byte
+}
+
+func (c *fakeCaller) CallContract(addr *btcutil.AddressContract, input []byte) ([]byte, error) {
+	c.addr, c.input = addr, input
+	return c.output, c.err
+}
+
+// encodeInfo returns the contract call encoding of a symbol and decimals.
+func encodeInfo(symbol string, decimals uint8) []byte {
+	words := func(vals ...uint64) []byte {
+		b := make([]byte, 32*len(vals))
+		for i, v := range vals {
+			binary.BigEndian.PutUint64(b[32*i+24:], v)
+		}
+		return b
+	}
+	out := words(64, uint64(decimals), uint64(len(symbol)))
+	padded := make([]byte, (len(symbol)+31)/32*32)
+	copy(padded, symbol)
+	return append(out, padded...)
+}
+
+// TestContractResolver ensures token types are resolved by calling the
+// registry contract and decoding its output.
+func TestContractResolver(t *testing.T) {
+	addr, err := btcutil.NewAddressContract(make([]byte, 20),
+		&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("NewAddressContract: %v", err)
+	}
+	caller := &fakeCaller{output: encodeInfo("GOLD", 2)}
+	r := &tokenmeta.ContractResolver{Caller: caller, Contract: addr}
+
+	info, err := r.ResolveToken(0x0102)
+	if err != nil || info != (tokenmeta.Info{Symbol: "GOLD", Decimals: 2}) {
+		t.Fatalf("ResolveToken: got %+v, %v", info, err)
+	}
+	wantInput, _ := hex.DecodeString("622d1eeb" +
+		"0000000000000000000000000000000000000000000000000000000000000102")
+	if caller.addr != addr || !bytes.Equal(caller.input, wantInput) {
+		t.Errorf("ResolveToken: called with %x", caller.input)
+	}
+
+	callErr := errors.New("connection refused")
+	truncated := encodeInfo("GOLD", 2)
+	badOffset := encodeInfo("GOLD", 2)
+	badOffset[31] = 32
+	highWord := encodeInfo("GOLD", 2)
+	highWord[32] = 1
+	tests := []struct {
+		name   string
+		output []byte
+		err    error
+		want   error
+	}{
+		{"call error", nil, callErr, callErr},
+		{"not registered", encodeInfo("", 0), nil, tokenmeta.ErrUnknownToken},
+		{"empty", nil, nil, tokenmeta.ErrMalformedResponse},
+		{"truncated", truncated[:len(truncated)-29], nil, tokenmeta.ErrMalformedResponse},
+		{"no symbol", truncated[:64], nil, tokenmeta.ErrMalformedResponse},
+		{"bad offset", badOffset, nil, tokenmeta.ErrMalformedResponse},
+		{"high word", highWord, nil, tokenmeta.ErrMalformedResponse},
+	}
+	for _, test := range tests {
+		caller.output, caller.err = test.output, test.err
+		if _, err := r.ResolveToken(1); err != test.want {
+			t.Errorf("ResolveToken(%s): got error %v, want %v", test.name,
+				err, test.want)
+		}
+	}
+}
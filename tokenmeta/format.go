@@ -0,0 +1,130 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package tokenmeta
+
+import (
+	"errors"
+	"math"
+	"strings"
+
+	"github.com/zeusyf/btcutil"
+)
+
+var (
+	// ErrSymbolMismatch describes an error where an amount is parsed for
+	// a token while labeled with the symbol of another.
+	ErrSymbolMismatch = errors.New("amount is labeled with another symbol")
+
+	// ErrUnknownSymbol describes an error where an amount is labeled with
+	// a symbol no known token has.
+	ErrUnknownSymbol = errors.New("unknown token symbol")
+
+	// ErrAmbiguousSymbol describes an error where an amount is labeled
+	// with a symbol several known tokens share.
+	ErrAmbiguousSymbol = errors.New("token symbol is ambiguous")
+)
+
+// FormatAmount formats an amount of the base unit of the token in its display
+// unit, followed by a space and its symbol, such as "150.5 GOLD".  Trailing
+// fractional zeros are dropped.
+func (info Info) FormatAmount(a btcutil.Amount) string {
+	return a.FormatWithOptions(btcutil.FormatOptions{
+		Unit:              btcutil.AmountUnit(int(info.Decimals) - 8),
+		TrimTrailingZeros: true,
+		UnitPlacement:     btcutil.UnitNone,
+	}) + " " + info.Symbol
+}
+
+// ParseAmount parses an amount in the display unit of the token, optionally
+// followed by a space and the symbol of the token, and returns it in the base
+// unit.  The conversion is exact, and amounts more precise than the base unit
+// are rejected.
+func (info Info) ParseAmount(s string) (btcutil.Amount, error) {
+	num := s
+	if i := strings.IndexByte(s, ' '); i >= 0 {
+		if s[i+1:] != info.Symbol {
+			return 0, ErrSymbolMismatch
+		}
+		num = s[:i]
+	}
+	return btcutil.ParseAmountForChain(num, &btcutil.BasicChainCodec{
+		Decimals: int(info.Decimals),
+	})
+}
+
+// NewAmount converts a floating point number of the display unit of the token
+// to an amount of its base unit, rounded to the nearest.  It fails like
+// btcutil.NewAmount for NaN and infinities.
+func (info Info) NewAmount(f float64) (btcutil.Amount, error) {
+	return btcutil.NewAmount(f*math.Pow10(int(info.Decimals)), 1)
+}
+
+// FormatAmount formats an amount of tokenType as Info.FormatAmount does,
+// resolving the metadata of the token type when it is not known.
+func (r *Registry) FormatAmount(tokenType uint64, a btcutil.Amount) (string, error) {
+	info, err := r.Resolve(tokenType)
+	if err != nil {
+		return "", err
+	}
+	return info.FormatAmount(a), nil
+}
+
+// ParseAmount parses an amount of tokenType as Info.ParseAmount does,
+// resolving the metadata of the token type when it is not known.
+func (r *Registry) ParseAmount(tokenType uint64, s string) (btcutil.Amount, error) {
+	info, err := r.Resolve(tokenType)
+	if err != nil {
+		return 0, err
+	}
+	return info.ParseAmount(s)
+}
+
+// NewAmount converts a floating point number of the display unit of tokenType
+// to an amount of its base unit as Info.NewAmount does, resolving the metadata
+// of the token type when it is not known.
+func (r *Registry) NewAmount(f float64, tokenType uint64) (btcutil.Amount, error) {
+	info, err := r.Resolve(tokenType)
+	if err != nil {
+		return 0, err
+	}
+	return info.NewAmount(f)
+}
+
+// ParseTokenAmount parses an amount labeled with the symbol of a token, such
+// as "150.5 GOLD", and returns its token type along with the amount in the
+// base unit of the token.  Only known token types are matched, since symbols
+// can't be resolved, and a symbol shared by several tokens fails with
+// ErrAmbiguousSymbol.
+func (r *Registry) ParseTokenAmount(s string) (uint64, btcutil.Amount, error) {
+	i := strings.IndexByte(s, ' ')
+	if i < 0 {
+		return 0, 0, ErrUnknownSymbol
+	}
+	symbol := s[i+1:]
+
+	var (
+		tokenType uint64
+		info      Info
+		found     bool
+	)
+	for t, known := range r.snapshot() {
+		if known.Symbol != symbol {
+			continue
+		}
+		if found {
+			return 0, 0, ErrAmbiguousSymbol
+		}
+		tokenType, info, found = t, known, true
+	}
+	if !found {
+		return 0, 0, ErrUnknownSymbol
+	}
+
+	a, err := info.ParseAmount(s)
+	if err != nil {
+		return 0, 0, err
+	}
+	return tokenType, a, nil
+}
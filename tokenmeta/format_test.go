@@ -0,0 +1,119 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package tokenmeta_test
+
+import (
+	"testing"
+
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/tokenmeta"
+)
+
+// TestFormatAmount ensures amounts are formatted in the display unit of their
+// token followed by its symbol.
+func TestFormatAmount(t *testing.T) {
+	tests := []struct {
+		info tokenmeta.Info
+		a    btcutil.Amount
+		want string
+	}{
+		{tokenmeta.OMC, 150e8, "150 OMC"},
+		{tokenmeta.OMC, 1, "0.00000001 OMC"},
+		{tokenmeta.Info{Symbol: "XYZ", Decimals: 2}, 15050, "150.5 XYZ"},
+		{tokenmeta.Info{Symbol: "XYZ", Decimals: 2}, -1, "-0.01 XYZ"},
+		{tokenmeta.Info{Symbol: "NFT"}, 3, "3 NFT"},
+		{tokenmeta.Info{Symbol: "WEI", Decimals: 18}, 15e17, "1.5 WEI"},
+	}
+	for _, test := range tests {
+		if got := test.info.FormatAmount(test.a); got != test.want {
+			t.Errorf("FormatAmount(%d) with %s: got %q, want %q",
+				test.a, test.info.Symbol, got, test.want)
+		}
+	}
+}
+
+// TestParseAmount ensures amounts are parsed exactly from the display unit of
+// their token, with or without its symbol.
+func TestParseAmount(t *testing.T) {
+	xyz := tokenmeta.Info{Symbol: "XYZ", Decimals: 2}
+	tests := []struct {
+		s    string
+		want btcutil.Amount
+		err  error
+	}{
+		{"150.5", 15050, nil},
+		{"150.5 XYZ", 15050, nil},
+		{"-0.01 XYZ", -1, nil},
+		{"150.5 OMC", 0, tokenmeta.ErrSymbolMismatch},
+		{"150.5 ", 0, tokenmeta.ErrSymbolMismatch},
+	}
+	for _, test := range tests {
+		got, err := xyz.ParseAmount(test.s)
+		if err != test.err || got != test.want {
+			t.Errorf("ParseAmount(%q): got %d, %v, want %d, %v", test.s,
+				got, err, test.want, test.err)
+		}
+	}
+	if _, err := xyz.ParseAmount("0.001 XYZ"); err == nil {
+		t.Errorf("ParseAmount: accepted an amount below the base unit")
+	}
+}
+
+// TestRegistryAmounts ensures the registry formats and parses amounts with the
+// metadata of their token type, and finds token types by symbol.
+func TestRegistryAmounts(t *testing.T) {
+	resolver := &mapResolver{infos: map[uint64]tokenmeta.Info{
+		4: {Symbol: "XYZ", Decimals: 2, Name: "Xyz Token"},
+	}}
+	r := tokenmeta.NewRegistry(resolver)
+
+	s, err := r.FormatAmount(4, 15050)
+	if err != nil || s != "150.5 XYZ" {
+		t.Errorf("FormatAmount: got %q, %v", s, err)
+	}
+	if _, err := r.FormatAmount(6, 1); err != tokenmeta.ErrUnknownToken {
+		t.Errorf("FormatAmount: got error %v, want %v", err,
+			tokenmeta.ErrUnknownToken)
+	}
+	a, err := r.ParseAmount(0, "1.5 OMC")
+	if err != nil || a != 15e7 {
+		t.Errorf("ParseAmount: got %d, %v", a, err)
+	}
+	a, err = r.NewAmount(150.5, 4)
+	if err != nil || a != 15050 {
+		t.Errorf("NewAmount: got %d, %v", a, err)
+	}
+
+	// Resolved token types are found by their symbol.
+	tokenType, a, err := r.ParseTokenAmount("150.5 XYZ")
+	if err != nil || tokenType != 4 || a != 15050 {
+		t.Errorf("ParseTokenAmount: got %d, %d, %v", tokenType, a, err)
+	}
+	if _, _, err := r.ParseTokenAmount("1 GOLD"); err != tokenmeta.ErrUnknownSymbol {
+		t.Errorf("ParseTokenAmount: got error %v, want %v", err,
+			tokenmeta.ErrUnknownSymbol)
+	}
+	if _, _, err := r.ParseTokenAmount("1"); err != tokenmeta.ErrUnknownSymbol {
+		t.Errorf("ParseTokenAmount: got error %v, want %v", err,
+			tokenmeta.ErrUnknownSymbol)
+	}
+
+	// Registering a token at runtime makes its symbol known, and a symbol
+	// shared by two tokens is ambiguous.
+	if err := r.Register(8, tokenmeta.Info{Symbol: "GOLD"}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	tokenType, a, err = r.ParseTokenAmount("7 GOLD")
+	if err != nil || tokenType != 8 || a != 7 {
+		t.Errorf("ParseTokenAmount: got %d, %d, %v", tokenType, a, err)
+	}
+	if err := r.Register(10, tokenmeta.Info{Symbol: "XYZ"}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if _, _, err := r.ParseTokenAmount("1 XYZ"); err != tokenmeta.ErrAmbiguousSymbol {
+		t.Errorf("ParseTokenAmount: got error %v, want %v", err,
+			tokenmeta.ErrAmbiguousSymbol)
+	}
+}
@@ -0,0 +1,210 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package tokenmeta resolves the display metadata of Omega token types.
+//
+// Amounts of a token are counted in its base unit, and displaying them needs
+// the symbol of the token and the number of decimals of its display unit.
+// These are well known for OMC, token type 0, but not for the token types
+// issued since an explorer or wallet was deployed.  A Registry holds the
+// metadata of known token types, and asks a Resolver for the others, caching
+// what it returns.  ContractResolver is a Resolver querying a registry
+// contract through a node, so new tokens are displayed as soon as they are
+// registered on chain.
+//
+// Info and Registry format and parse amounts with the symbol and decimals of
+// their token, so amounts of any token are displayed as "150.5 GOLD" rather
+// than as an integer of its base unit.
+package tokenmeta
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"unicode"
+)
+
+const (
+	// MaxSymbolLen is the maximum length of a token symbol in bytes.
+	MaxSymbolLen = 16
+
+	// MaxDecimals is the maximum number of decimals of a token.
+	MaxDecimals = 18
+
+	// MaxNameLen is the maximum length of the display name of a token in
+	// bytes.
+	MaxNameLen = 64
+)
+
+var (
+	// ErrUnknownToken describes an error where the metadata of a token
+	// type is neither registered nor resolvable.
+	ErrUnknownToken = errors.New("unknown token type")
+
+	// ErrInvalidMetadata describes an error where token metadata has an
+	// empty, overlong or unprintable symbol, an overlong or unprintable
+	// name, or too many decimals.
+	ErrInvalidMetadata = errors.New("invalid token metadata")
+)
+
+// Info is the display metadata of a token type.
+type Info struct {
+	// Symbol is the ticker symbol of the token, such as "OMC".
+	Symbol string
+
+	// Decimals is the number of decimal places of the display unit, so
+	// that an amount of the base unit is displayed divided by
+	// 10^Decimals.
+	Decimals uint8
+
+	// Name is the display name of the token, such as "Omega".  It is
+	// optional.
+	Name string
+}
+
+// OMC is the metadata of token type 0, whose base unit is the Hao.
+var OMC = Info{Symbol: "OMC", Decimals: 8, Name: "Omega"}
+
+// Check verifies the symbol is between 1 and MaxSymbolLen bytes of
+// printable characters without spaces, the name is at most MaxNameLen bytes
+// of printable characters, and there are at most MaxDecimals decimals.
+// Metadata is checked before being registered since it is displayed to users.
+func (info Info) Check() error {
+	if info.Symbol == "" || len(info.Symbol) > MaxSymbolLen ||
+		len(info.Name) > MaxNameLen || info.Decimals > MaxDecimals {
+		return ErrInvalidMetadata
+	}
+	for _, r := range info.Symbol {
+		if !unicode.IsPrint(r) || unicode.IsSpace(r) {
+			return ErrInvalidMetadata
+		}
+	}
+	for _, r := range info.Name {
+		if !unicode.IsPrint(r) {
+			return ErrInvalidMetadata
+		}
+	}
+	return nil
+}
+
+// Resolver is the interface to a source of metadata for token types the
+// Registry does not know.
+//
+// ResolveToken returns the metadata of tokenType, or ErrUnknownToken when
+// the source has none.
+type Resolver interface {
+	ResolveToken(tokenType uint64) (Info, error)
+}
+
+// Registry holds the metadata of token types, resolving unknown ones through
+// its Resolver and caching the results.  Failed resolutions are not cached,
+// so a token registered on chain later is picked up on the next lookup.  It
+// is safe for concurrent use.
+//
+// Like the registries of the btcutil package, a Registry is copy-on-write:
+// the map stored in infos is never modified once stored, so lookups load it
+// without locking and always see a consistent snapshot.  Writers serialize
+// on mtx, copy the current map, add their entry, and store the copy.
+type Registry struct {
+	resolver Resolver
+
+	mtx   sync.Mutex
+	infos atomic.Value // map[uint64]Info
+}
+
+// NewRegistry returns a registry knowing only OMC, which resolves other token
+// types through resolver.  The resolver may be nil, in which case only
+// registered token types are known.
+func NewRegistry(resolver Resolver) *Registry {
+	r := &Registry{resolver: resolver}
+	r.infos.Store(map[uint64]Info{0: OMC})
+	return r
+}
+
+// snapshot returns the current, immutable contents of the registry.
+func (r *Registry) snapshot() map[uint64]Info {
+	infos, _ := r.infos.Load().(map[uint64]Info)
+	return infos
+}
+
+// store sets the metadata of tokenType in a copy of the registry and stores
+// the copy.  It must be called with mtx held.
+func (r *Registry) store(tokenType uint64, info Info) {
+	old := r.snapshot()
+	infos := make(map[uint64]Info, len(old)+1)
+	for k, v := range old {
+		infos[k] = v
+	}
+	infos[tokenType] = info
+	r.infos.Store(infos)
+}
+
+// Register sets the metadata of tokenType, replacing any known before.
+func (r *Registry) Register(tokenType uint64, info Info) error {
+	if err := info.Check(); err != nil {
+		return err
+	}
+	r.mtx.Lock()
+	r.store(tokenType, info)
+	r.mtx.Unlock()
+	return nil
+}
+
+// Lookup returns the metadata of tokenType when it is known, without
+// resolving it.
+func (r *Registry) Lookup(tokenType uint64) (Info, bool) {
+	info, ok := r.snapshot()[tokenType]
+	return info, ok
+}
+
+// Entry is a token type known to a Registry and its metadata.
+type Entry struct {
+	TokenType uint64
+	Info      Info
+}
+
+// Entries returns every known token type, sorted by type.  The result is a
+// snapshot of the registry taken at the time of the call; token types
+// registered or resolved concurrently or later are not included.
+func (r *Registry) Entries() []Entry {
+	infos := r.snapshot()
+	entries := make([]Entry, 0, len(infos))
+	for tokenType, info := range infos {
+		entries = append(entries, Entry{tokenType, info})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].TokenType < entries[j].TokenType
+	})
+	return entries
+}
+
+// Resolve returns the metadata of tokenType, resolving and caching it when
+// it is not known.  Resolved metadata failing Info.Check is rejected with
+// ErrInvalidMetadata.
+func (r *Registry) Resolve(tokenType uint64) (Info, error) {
+	if info, ok := r.Lookup(tokenType); ok {
+		return info, nil
+	}
+	if r.resolver == nil {
+		return Info{}, ErrUnknownToken
+	}
+
+	info, err := r.resolver.ResolveToken(tokenType)
+	if err != nil {
+		return Info{}, err
+	}
+	if err := info.Check(); err != nil {
+		return Info{}, err
+	}
+
+	// Keep metadata registered while resolving, which takes precedence.
+	r.mtx.Lock()
+	defer r.mtx.Unlock()
+	if known, ok := r.snapshot()[tokenType]; ok {
+		return known, nil
+	}
+	r.store(tokenType, info)
+	return info, nil
+}
@@ -0,0 +1,176 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package tokenmeta_test
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/zeusyf/btcutil/tokenmeta"
+)
+
+// mapResolver resolves token types from a map, counting its calls.
+type mapResolver struct {
+	infos map[uint64]tokenmeta.Info
+	calls int
+}
+
+func (r *mapResolver) ResolveToken(tokenType uint64) (tokenmeta.Info, error) {
+	r.calls++
+	info, ok := r.infos[tokenType]
+	if !ok {
+		return tokenmeta.Info{}, tokenmeta.ErrUnknownToken
+	}
+	return info, nil
+}
+
+// TestInfoCheck ensures only displayable metadata is accepted.
+func TestInfoCheck(t *testing.T) {
+	tests := []struct {
+		info  tokenmeta.Info
+		valid bool
+	}{
+		{tokenmeta.OMC, true},
+		{tokenmeta.Info{Symbol: "GOLD", Decimals: 18}, true},
+		{tokenmeta.Info{Symbol: "Ωmega"}, true},
+		{tokenmeta.Info{Symbol: ""}, false},
+		{tokenmeta.Info{Symbol: strings.Repeat("X", 17)}, false},
+		{tokenmeta.Info{Symbol: "GOLD", Decimals: 19}, false},
+		{tokenmeta.Info{Symbol: "GO LD"}, false},
+		{tokenmeta.Info{Symbol: "GOLD\x00"}, false},
+		{tokenmeta.Info{Symbol: "GOLD", Name: "Digital Gold"}, true},
+		{tokenmeta.Info{Symbol: "GOLD", Name: strings.Repeat("X", 65)}, false},
+		{tokenmeta.Info{Symbol: "GOLD", Name: "Gold\n"}, false},
+	}
+	for _, test := range tests {
+		err := test.info.Check()
+		if (err == nil) != test.valid {
+			t.Errorf("Check(%+v): got error %v, want valid %v", test.info,
+				err, test.valid)
+		}
+	}
+}
+
+// TestRegistry ensures unknown token types are resolved once and cached,
+// while failures are retried.
+func TestRegistry(t *testing.T) {
+	gold := tokenmeta.Info{Symbol: "GOLD", Decimals: 2}
+	resolver := &mapResolver{infos: map[uint64]tokenmeta.Info{
+		4: gold,
+		5: {Symbol: ""},
+	}}
+	r := tokenmeta.NewRegistry(resolver)
+
+	if info, ok := r.Lookup(0); !ok || info != tokenmeta.OMC {
+		t.Errorf("Lookup(0): got %+v, %v", info, ok)
+	}
+	if _, ok := r.Lookup(4); ok {
+		t.Errorf("Lookup(4): found before resolving")
+	}
+	for i := 0; i < 2; i++ {
+		info, err := r.Resolve(4)
+		if err != nil || info != gold {
+			t.Errorf("Resolve(4): got %+v, %v", info, err)
+		}
+	}
+	if resolver.calls != 1 {
+		t.Errorf("Resolve: resolver called %d times, want 1", resolver.calls)
+	}
+
+	for _, test := range []struct {
+		tokenType uint64
+		err       error
+	}{
+		{6, tokenmeta.ErrUnknownToken},
+		{5, tokenmeta.ErrInvalidMetadata},
+	} {
+		for i := 0; i < 2; i++ {
+			if _, err := r.Resolve(test.tokenType); err != test.err {
+				t.Errorf("Resolve(%d): got error %v, want %v",
+					test.tokenType, err, test.err)
+			}
+		}
+	}
+	if resolver.calls != 5 {
+		t.Errorf("Resolve: resolver called %d times, want 5", resolver.calls)
+	}
+
+	// Registered metadata takes precedence over the resolver.
+	silver := tokenmeta.Info{Symbol: "SILVER", Decimals: 4}
+	if err := r.Register(6, silver); err != nil {
+		t.Fatalf("Register: unexpected error: %v", err)
+	}
+	if info, err := r.Resolve(6); err != nil || info != silver {
+		t.Errorf("Resolve(6): got %+v, %v", info, err)
+	}
+	if err := r.Register(7, tokenmeta.Info{}); err != tokenmeta.ErrInvalidMetadata {
+		t.Errorf("Register: got error %v, want %v", err,
+			tokenmeta.ErrInvalidMetadata)
+	}
+
+	r = tokenmeta.NewRegistry(nil)
+	if _, err := r.Resolve(4); !errors.Is(err, tokenmeta.ErrUnknownToken) {
+		t.Errorf("Resolve without resolver: got error %v, want %v", err,
+			tokenmeta.ErrUnknownToken)
+	}
+}
+
+// TestRegistryConcurrent registers token types while other goroutines look
+// up and iterate the registry, to be run with the race detector, and ensures
+// snapshots are sorted and complete once registration finishes.
+func TestRegistryConcurrent(t *testing.T) {
+	const writers, perWriter = 4, 50
+	r := tokenmeta.NewRegistry(nil)
+	info := tokenmeta.Info{Symbol: "TKN", Decimals: 2}
+
+	var wg sync.WaitGroup
+	done := make(chan struct{})
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				entries := r.Entries()
+				for j := 1; j < len(entries); j++ {
+					if entries[j-1].TokenType >= entries[j].TokenType {
+						t.Errorf("Entries: snapshot not sorted")
+						return
+					}
+				}
+				r.Lookup(1)
+			}
+		}()
+	}
+
+	var writersWg sync.WaitGroup
+	for w := 0; w < writers; w++ {
+		writersWg.Add(1)
+		go func(w int) {
+			defer writersWg.Done()
+			for i := 0; i < perWriter; i++ {
+				tokenType := uint64(1 + w*perWriter + i)
+				if err := r.Register(tokenType, info); err != nil {
+					t.Errorf("Register: unexpected error: %v", err)
+				}
+			}
+		}(w)
+	}
+	writersWg.Wait()
+	close(done)
+	wg.Wait()
+
+	entries := r.Entries()
+	if len(entries) != 1+writers*perWriter || entries[0].Info != tokenmeta.OMC {
+		t.Fatalf("Entries: got %d entries, want %d", len(entries),
+			1+writers*perWriter)
+	}
+}
@@ -8,6 +8,7 @@ package btcutil
 import (
 	"bytes"
 	"io"
+	"sync/atomic"
 
 	"github.com/zeusyf/btcd/chaincfg"
 	"github.com/zeusyf/btcd/chaincfg/chainhash"
@@ -24,23 +25,98 @@ const TxIndexUnknown = -1
 // manipulation of raw transactions.  It also memoizes the hash for the
 // transaction on its first access so subsequent accesses don't have to repeat
 // the relatively expensive hashing operations.
+//
+// The memoized hash is safe for concurrent readers: any number of goroutines
+// may call Hash on a shared transaction, and all of them get the same
+// pointer, so indexers can hand wrapped transactions to their workers.  The
+// transaction must however not be modified, through the methods adding to it
+// or through its MsgTx, while it is shared, and a hash already memoized is
+// not updated by such modifications.
+//
+// A Tx holds atomic values, so it must not be copied after first use; share
+// a *Tx instead, or use Copy.
 type Tx struct {
-	msgTx         *wire.MsgTx     // Underlying MsgTx
-	txHash        *chainhash.Hash // Cached transaction hash
-	txHashSignature *chainhash.Hash // Cached transaction witness hash
-	txIndex       int             // Position within a block or TxIndexUnknown
-	HasOuts		  bool			  // temp data indicating whether there is TxOuts added by contracts
-	HasIns		  bool			  // temp data indicating whether there is TxIns added by contracts
-	HasDefs		  bool			  // temp data indicating whether there is TxDefs added by contracts
-	Executed	  bool			  // whether contracts in the tx have been executed
+	msgTx           *wire.MsgTx                    // Underlying MsgTx
+	txHash          atomic.Pointer[chainhash.Hash] // Cached transaction hash
+	txHashSignature atomic.Pointer[chainhash.Hash] // Cached transaction witness hash
+	txIndex         int                            // Position within a block or TxIndexUnknown
+	HasOuts         bool                           // temp data indicating whether there is TxOuts added by contracts
+	HasIns          bool                           // temp data indicating whether there is TxIns added by contracts
+	HasDefs         bool                           // temp data indicating whether there is TxDefs added by contracts
+	Executed        bool                           // whether contracts in the tx have been executed
+	readOnly        bool                           // MsgTx returns copies and mutation panics
 }
 
-// MsgTx returns the underlying wire.MsgTx for the transaction.
+// MsgTx returns the underlying wire.MsgTx for the transaction.  A read-only
+// transaction returns a deep copy of it instead, which the caller is free to
+// modify.
 func (t *Tx) MsgTx() *wire.MsgTx {
+	if t.readOnly {
+		return t.msgTx.Copy()
+	}
+
 	// Return the cached transaction.
 	return t.msgTx
 }
 
+// Copy returns a deep copy of the transaction, including its underlying
+// wire.MsgTx, which can be modified without affecting the original.  The copy
+// is never read-only, and keeps the index and cached hash of the original.
+func (t *Tx) Copy() *Tx {
+	c := t.clone(t.msgTx.Copy(), false)
+	if h := t.txHash.Load(); h != nil {
+		hash := *h
+		c.txHash.Store(&hash)
+	}
+	if h := t.txHashSignature.Load(); h != nil {
+		hash := *h
+		c.txHashSignature.Store(&hash)
+	}
+	return c
+}
+
+// clone returns a transaction wrapping msgTx with the index and flags of t,
+// but none of its cached hashes.  The fields are copied one by one since the
+// caches must not be copied.
+func (t *Tx) clone(msgTx *wire.MsgTx, readOnly bool) *Tx {
+	return &Tx{
+		msgTx:    msgTx,
+		txIndex:  t.txIndex,
+		HasOuts:  t.HasOuts,
+		HasIns:   t.HasIns,
+		HasDefs:  t.HasDefs,
+		Executed: t.Executed,
+		readOnly: readOnly,
+	}
+}
+
+// ReadOnly returns a read-only view of the transaction, sharing its
+// underlying wire.MsgTx.  MsgTx returns a deep copy on every call, so callers
+// of the view can't corrupt the shared transaction, and the methods adding
+// inputs, outputs and definitions, as well as SetIndex, panic.  Caches
+// handing out shared transactions should hand out views.
+func (t *Tx) ReadOnly() *Tx {
+	if t.readOnly {
+		return t
+	}
+	c := t.clone(t.msgTx, true)
+	c.txHash.Store(t.txHash.Load())
+	c.txHashSignature.Store(t.txHashSignature.Load())
+	return c
+}
+
+// IsReadOnly returns whether the transaction is a read-only view.
+func (t *Tx) IsReadOnly() bool {
+	return t.readOnly
+}
+
+// mustBeMutable panics when the transaction is read-only.
+func (t *Tx) mustBeMutable() {
+	if t.readOnly {
+		panic("btcutil: modification of a read-only transaction")
+	}
+}
+
 func (t *Tx) ContainContract() bool {
 	for _, p := range t.msgTx.TxOut {
 		if p.IsSeparator() {
@@ -54,7 +130,7 @@ func (t *Tx) ContainContract() bool {
 }
 
 func (t *Tx) IsCoinBase() bool {
-	return t.MsgTx().IsCoinBase()
+	return t.msgTx.IsCoinBase()
 }
 
 func (s *Tx) VerifyContractOut(t *Tx) bool {
@@ -83,6 +159,7 @@ func (s *Tx) VerifyContractOut(t *Tx) bool {
 }
 
 func (s *Tx) AddTxOut(t wire.TxOut) int {
+	s.mustBeMutable()
 	if !s.HasOuts {
 		// this servers as a separater. only TokenType is serialized
 		to := wire.TxOut{}
@@ -94,6 +171,7 @@ func (s *Tx) AddTxOut(t wire.TxOut) int {
 }
 
 func (s *Tx) AddTxIn(t wire.OutPoint, sig []byte) {
+	s.mustBeMutable()
 	if !s.HasIns {
 		s.msgTx.AddTxIn(&wire.TxIn{})
 		s.HasIns = true
@@ -126,6 +204,7 @@ func (s *Tx) AddTxIn(t wire.OutPoint, sig []byte) {
 }
 
 func (s *Tx) AddDef(t token.Definition) chainhash.Hash {
+	s.mustBeMutable()
 	if !s.HasDefs {
 		to := token.SeparatorDef{}
 		s.msgTx.AddDef(&to)
@@ -140,17 +219,21 @@ func (s *Tx) Match(t *Tx) bool {
 
 // Hash returns the hash of the transaction.  This is equivalent to
 // calling TxHash on the underlying wire.MsgTx, however it caches the
-// result so subsequent calls are more efficient.
+// result so subsequent calls are more efficient.  It is safe for concurrent
+// use; goroutines racing to hash the transaction first all return the hash
+// cached by the winner.
 func (t *Tx) Hash() *chainhash.Hash {
 	// Return the cached hash if it has already been generated.
-	if t.txHash != nil {
-		return t.txHash
+	if hash := t.txHash.Load(); hash != nil {
+		return hash
 	}
 
 	// Cache the hash and return it.
 	hash := t.msgTx.TxHash()	// hash w/o signature
-	t.txHash = &hash
-	return &hash
+	if t.txHash.CompareAndSwap(nil, &hash) {
+		return &hash
+	}
+	return t.txHash.Load()
 }
 
 /*
@@ -192,6 +275,7 @@ func (t *Tx) Index() int {
 
 // SetIndex sets the index of the transaction in within a block.
 func (t *Tx) SetIndex(index int) {
+	t.mustBeMutable()
 	t.txIndex = index
 }
 
@@ -8,10 +8,12 @@ import (
 	"bytes"
 	"io"
 	"reflect"
+	"sync"
 	"testing"
 
 	"github.com/davecgh/go-spew/spew"
 	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcd/wire"
 	"github.com/zeusyf/btcutil"
 )
 
@@ -95,3 +97,91 @@ func TestTxErrors(t *testing.T) {
 			"got %v, want %v", err, io.EOF)
 	}
 }
+
+// assertPanics ensures f panics.
+func assertPanics(t *testing.T, name string, f func()) {
+	t.Helper()
+	defer func() {
+		if recover() == nil {
+			t.Errorf("%s: did not panic", name)
+		}
+	}()
+	f()
+}
+
+// TestTxCopy ensures copies and read-only views of a transaction can't be
+// used to modify it.
+func TestTxCopy(t *testing.T) {
+	msgTx := Block100000.Transactions[1].Copy()
+	want := msgTx.Copy()
+	tx := btcutil.NewTx(msgTx)
+	tx.SetIndex(1)
+	hash := *tx.Hash()
+
+	c := tx.Copy()
+	if c.IsReadOnly() || c.Index() != 1 || *c.Hash() != hash ||
+		!reflect.DeepEqual(c.MsgTx(), want) {
+		t.Errorf("Copy: mismatched copy %v", spew.Sdump(c.MsgTx()))
+	}
+	c.MsgTx().TxOut[0].PkScript[0] ^= 0xff
+	c.MsgTx().LockTime++
+	c.SetIndex(2)
+	if !reflect.DeepEqual(tx.MsgTx(), want) || tx.Index() != 1 {
+		t.Errorf("Copy: transaction modified through its copy")
+	}
+
+	view := tx.ReadOnly()
+	if !view.IsReadOnly() || view.ReadOnly() != view || tx.IsReadOnly() ||
+		view.Index() != 1 || *view.Hash() != hash {
+		t.Errorf("ReadOnly: mismatched view")
+	}
+	if got := view.MsgTx(); got == msgTx || !reflect.DeepEqual(got, want) {
+		t.Errorf("MsgTx: got %v, want a copy of %v", spew.Sdump(got),
+			spew.Sdump(want))
+	}
+	view.MsgTx().TxOut[0].PkScript[0] ^= 0xff
+	view.MsgTx().TxIn[0].PreviousOutPoint.Index++
+	if !reflect.DeepEqual(msgTx, want) {
+		t.Errorf("ReadOnly: transaction modified through its view")
+	}
+	if view.Copy().IsReadOnly() {
+		t.Errorf("Copy: copy of a view is read-only")
+	}
+
+	assertPanics(t, "SetIndex", func() { view.SetIndex(0) })
+	assertPanics(t, "AddTxOut", func() { view.AddTxOut(wire.TxOut{}) })
+	assertPanics(t, "AddTxIn", func() { view.AddTxIn(wire.OutPoint{}, nil) })
+	if !reflect.DeepEqual(msgTx, want) {
+		t.Errorf("ReadOnly: transaction modified through its view")
+	}
+}
+
+// TestTxConcurrentHash ensures goroutines hashing a shared transaction all
+// get the same cached hash.  It is meant to be run with the race detector.
+func TestTxConcurrentHash(t *testing.T) {
+	testTx := Block100000.Transactions[1]
+	tx := btcutil.NewTx(testTx)
+
+	const goroutines = 8
+	hashes := make([]*chainhash.Hash, goroutines)
+	var wg sync.WaitGroup
+	for i := range hashes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			hashes[i] = tx.Hash()
+		}(i)
+	}
+	wg.Wait()
+
+	want := testTx.TxHash()
+	for i, hash := range hashes {
+		if hash != hashes[0] || *hash != want {
+			t.Errorf("Hash #%d: got %v (%p), want %v (%p)", i, hash,
+				hash, want, hashes[0])
+		}
+	}
+	if view := tx.ReadOnly(); view.Hash() != hashes[0] {
+		t.Errorf("ReadOnly: view does not share the cached hash")
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package txbuilder
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcd/txscript"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/hashing"
+	"github.com/zeusyf/btcutil/txsizes"
+	"github.com/zeusyf/omega/token"
+)
+
+// DefaultDustRelayFeeRate is the fee rate outputs built by NewTxOut are
+// checked against for dust.
+const DefaultDustRelayFeeRate btcutil.FeeRate = 3000
+
+var (
+	// ErrInvalidOutPoint describes an error where an outpoint string is
+	// not a transaction hash and an output index separated by a colon.
+	ErrInvalidOutPoint = errors.New("outpoint must be of the form txid:vout")
+
+	// ErrDustOutput describes an error where an output would carry less
+	// than the dust limit of its script.
+	ErrDustOutput = errors.New("output amount is dust")
+)
+
+// NewOutPointFromString parses an outpoint written as the hash of its
+// transaction, in the byte-reversed hex of block explorers, followed by a
+// colon and the decimal index of the output, such as
+// "4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b:0".  It
+// is the form the String method of wire.OutPoint returns.
+func NewOutPointFromString(s string) (*wire.OutPoint, error) {
+	txid, vout, ok := strings.Cut(s, ":")
+	if !ok {
+		return nil, ErrInvalidOutPoint
+	}
+	hash, err := hashing.NewHashFromStr(txid)
+	if err != nil {
+		return nil, ErrInvalidOutPoint
+	}
+	if vout == "" || vout[0] == '+' {
+		return nil, ErrInvalidOutPoint
+	}
+	index, err := strconv.ParseUint(vout, 10, 32)
+	if err != nil {
+		return nil, ErrInvalidOutPoint
+	}
+	return wire.NewOutPoint((*chainhash.Hash)(&hash), uint32(index)), nil
+}
+
+// NewTxInFromString returns an input spending the outpoint written as s, as
+// parsed by NewOutPointFromString, with the maximum sequence number.
+func NewTxInFromString(s string) (*wire.TxIn, error) {
+	op, err := NewOutPointFromString(s)
+	if err != nil {
+		return nil, err
+	}
+	return &wire.TxIn{
+		PreviousOutPoint: *op,
+		Sequence:         wire.MaxTxInSequenceNum,
+	}, nil
+}
+
+// NewTxOut returns an output paying amount of the base token to addr, which
+// must be an address of net.  btcutil.ErrWrongChain is returned for addresses
+// of other networks, the errors of Amount.CheckRange for amounts which are
+// not valid, and ErrDustOutput for amounts below the dust limit of the script
+// at DefaultDustRelayFeeRate.
+func NewTxOut(addr btcutil.Address, amount btcutil.Amount, net *chaincfg.Params) (*wire.TxOut, error) {
+	return NewTxOutWithDustRelayFeeRate(addr, amount, net,
+		DefaultDustRelayFeeRate)
+}
+
+// NewTxOutWithDustRelayFeeRate is like NewTxOut, but checks the output for
+// dust at relayFeeRate.
+func NewTxOutWithDustRelayFeeRate(addr btcutil.Address, amount btcutil.Amount,
+	net *chaincfg.Params, relayFeeRate btcutil.FeeRate) (*wire.TxOut, error) {
+
+	if !addr.IsForNet(net) {
+		return nil, btcutil.ErrWrongChain
+	}
+	if err := amount.CheckRange(btcutil.MaxHao); err != nil {
+		return nil, err
+	}
+	pkScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		return nil, err
+	}
+	txOut := &wire.TxOut{
+		Token: token.Token{
+			TokenType: 0,
+			Value:     &token.NumeralVal{Val: int64(amount)},
+		},
+		PkScript: pkScript,
+	}
+	if txsizes.IsDust(txOut, relayFeeRate) {
+		return nil, ErrDustOutput
+	}
+	return txOut, nil
+}
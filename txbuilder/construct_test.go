@@ -0,0 +1,116 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package txbuilder_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcd/txscript"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/txbuilder"
+	"github.com/zeusyf/btcutil/txsizes"
+	"github.com/zeusyf/omega/token"
+)
+
+// TestNewOutPointFromString ensures outpoints parse from the hash of their
+// transaction and their index, and malformed strings are rejected.
+func TestNewOutPointFromString(t *testing.T) {
+	const txid = "4a5e1e4baab89f3a32518a88c31bc87f618f76673e2cc77ab2127b7afdeda33b"
+	op, err := txbuilder.NewOutPointFromString(txid + ":4294967295")
+	if err != nil {
+		t.Fatalf("NewOutPointFromString: unexpected error: %v", err)
+	}
+	if op.Hash.String() != txid || op.Index != 4294967295 {
+		t.Errorf("NewOutPointFromString: got %v:%d", op.Hash, op.Index)
+	}
+
+	in, err := txbuilder.NewTxInFromString(txid + ":1")
+	if err != nil {
+		t.Fatalf("NewTxInFromString: unexpected error: %v", err)
+	}
+	if in.PreviousOutPoint.Hash != op.Hash || in.PreviousOutPoint.Index != 1 ||
+		in.Sequence != wire.MaxTxInSequenceNum {
+
+		t.Errorf("NewTxInFromString: got input %+v", in)
+	}
+
+	invalid := []string{
+		"",
+		txid,
+		txid + ":",
+		txid + ":-1",
+		txid + ":+1",
+		txid + ":4294967296",
+		txid + ":0x1",
+		txid[2:] + ":0",
+		"zz" + txid[2:] + ":0",
+		txid + ":1:2",
+	}
+	for _, s := range invalid {
+		if _, err := txbuilder.NewOutPointFromString(s); err != txbuilder.ErrInvalidOutPoint {
+			t.Errorf("NewOutPointFromString(%q): got error %v, want %v", s,
+				err, txbuilder.ErrInvalidOutPoint)
+		}
+		if _, err := txbuilder.NewTxInFromString(s); err != txbuilder.ErrInvalidOutPoint {
+			t.Errorf("NewTxInFromString(%q): got error %v, want %v", s,
+				err, txbuilder.ErrInvalidOutPoint)
+		}
+	}
+}
+
+// TestNewTxOut ensures outputs pay the script of their address, and outputs
+// to other networks, of invalid amounts, or of dust are rejected.
+func TestNewTxOut(t *testing.T) {
+	hash := bytes.Repeat([]byte{0x42}, 20)
+	addr, err := btcutil.NewAddressPubKeyHash(hash, &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("NewAddressPubKeyHash: %v", err)
+	}
+	pkScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		t.Fatalf("PayToAddrScript: %v", err)
+	}
+	limit := txsizes.DustLimitForScript(pkScript,
+		txbuilder.DefaultDustRelayFeeRate)
+
+	txOut, err := txbuilder.NewTxOut(addr, limit, &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("NewTxOut: unexpected error: %v", err)
+	}
+	value, ok := txOut.Token.Value.(*token.NumeralVal)
+	if !ok || value.Val != int64(limit) || txOut.Token.TokenType != 0 ||
+		!bytes.Equal(txOut.PkScript, pkScript) {
+
+		t.Errorf("NewTxOut: got output %+v", txOut)
+	}
+
+	tests := []struct {
+		name   string
+		amount btcutil.Amount
+		net    *chaincfg.Params
+		err    error
+	}{
+		{"wrong network", limit, &chaincfg.TestNet3Params, btcutil.ErrWrongChain},
+		{"negative", -1, &chaincfg.MainNetParams, btcutil.ErrNegativeAmount},
+		{"too large", btcutil.MaxHao + 1, &chaincfg.MainNetParams,
+			btcutil.ErrAmountTooLarge},
+		{"dust", limit - 1, &chaincfg.MainNetParams, txbuilder.ErrDustOutput},
+	}
+	for _, test := range tests {
+		_, err := txbuilder.NewTxOut(addr, test.amount, test.net)
+		if err != test.err {
+			t.Errorf("%s: got error %v, want %v", test.name, err, test.err)
+		}
+	}
+
+	// Without a relay fee, no amount is dust.
+	if _, err := txbuilder.NewTxOutWithDustRelayFeeRate(addr, 1,
+		&chaincfg.MainNetParams, 0); err != nil {
+		t.Errorf("NewTxOutWithDustRelayFeeRate: unexpected error: %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package txbuilder
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+
+	"github.com/zeusyf/btcd/txscript"
+	"github.com/zeusyf/btcutil"
+)
+
+// minScriptChunk is the smallest number of addresses compiled by a single
+// goroutine, below which spreading the work costs more than it saves.
+const minScriptChunk = 256
+
+// scriptBufHint is the expected size of a compiled output script, used to
+// size the shared buffer of a chunk.  Longer scripts only grow it.
+const scriptBufHint = 32
+
+// AddressScriptError describes an error where the output script of one of the
+// addresses passed to PayToAddrScriptBatch could not be compiled.
+type AddressScriptError struct {
+	// Index is the position of the address in the batch.
+	Index int
+
+	// Err is the error returned for the address by PayToAddrScript.
+	Err error
+}
+
+// Error returns the error along with the index of the offending address.
+func (e *AddressScriptError) Error() string {
+	return fmt.Sprintf("address %d: %v", e.Index, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *AddressScriptError) Unwrap() error {
+	return e.Err
+}
+
+// PayToAddrScriptBatch returns the output scripts paying to each of the passed
+// addresses, as txscript.PayToAddrScript would, in the same order.  It is
+// meant for services creating many outputs at once, such as the payouts of a
+// batched withdrawal:
+//
+//   - large batches are split over runtime.NumCPU goroutines
+//   - the scripts of a goroutine share a single backing array, so the result
+//     holds a handful of allocations rather than one per script
+//
+// The returned scripts have their capacity limited to their length, so
+// appending to one never overwrites its neighbour.  When any address can not
+// be compiled, an *AddressScriptError for the first such address is returned.
+func PayToAddrScriptBatch(addrs []btcutil.Address) ([][]byte, error) {
+	scripts := make([][]byte, len(addrs))
+	if len(addrs) == 0 {
+		return scripts, nil
+	}
+
+	workers := runtime.NumCPU()
+	chunk := (len(addrs) + workers - 1) / workers
+	if chunk < minScriptChunk {
+		chunk = minScriptChunk
+	}
+	numChunks := (len(addrs) + chunk - 1) / chunk
+	errs := make([]error, numChunks)
+
+	var wg sync.WaitGroup
+	for c := 0; c < numChunks; c++ {
+		lo := c * chunk
+		hi := lo + chunk
+		if hi > len(addrs) {
+			hi = len(addrs)
+		}
+		wg.Add(1)
+		go func(c, lo, hi int) {
+			defer wg.Done()
+			errs[c] = compileScripts(addrs[lo:hi], scripts[lo:hi], lo)
+		}(c, lo, hi)
+	}
+	wg.Wait()
+
+	// Chunks are in address order, so the first error of the first
+	// failing chunk is the first error overall.
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	return scripts, nil
+}
+
+// compileScripts compiles the output scripts of addrs into scripts, which must
+// have the same length, sharing a single buffer.  offset is the index of the
+// first address within the batch, used to report errors.
+func compileScripts(addrs []btcutil.Address, scripts [][]byte, offset int) error {
+	buf := make([]byte, 0, len(addrs)*scriptBufHint)
+	ends := make([]int, len(addrs))
+	for i, addr := range addrs {
+		script, err := txscript.PayToAddrScript(addr)
+		if err != nil {
+			return &AddressScriptError{Index: offset + i, Err: err}
+		}
+		buf = append(buf, script...)
+		ends[i] = len(buf)
+	}
+
+	// The buffer may have been reallocated while growing, so the scripts
+	// are only sliced from it once it is complete.
+	start := 0
+	for i, end := range ends {
+		scripts[i] = buf[start:end:end]
+		start = end
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package txbuilder_test
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcd/txscript"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/txbuilder"
+)
+
+// testAddresses returns n distinct addresses alternating between
+// pay-to-pubkey-hash and pay-to-script-hash.
+func testAddresses(t *testing.T, n int) []btcutil.Address {
+	addrs := make([]btcutil.Address, n)
+	for i := range addrs {
+		var seed [4]byte
+		binary.BigEndian.PutUint32(seed[:], uint32(i))
+		hash := btcutil.Hash160(seed[:])
+
+		var err error
+		if i%2 == 0 {
+			addrs[i], err = btcutil.NewAddressPubKeyHash(hash,
+				&chaincfg.MainNetParams)
+		} else {
+			addrs[i], err = btcutil.NewAddressScriptHashFromHash(hash,
+				&chaincfg.MainNetParams)
+		}
+		if err != nil {
+			t.Fatalf("address %d: %v", i, err)
+		}
+	}
+	return addrs
+}
+
+// TestPayToAddrScriptBatch ensures batches compile to the same scripts as
+// PayToAddrScript, in order, for batch sizes handled by one and by several
+// goroutines.
+func TestPayToAddrScriptBatch(t *testing.T) {
+	for _, n := range []int{0, 1, 7, 1000, 5000} {
+		addrs := testAddresses(t, n)
+		scripts, err := txbuilder.PayToAddrScriptBatch(addrs)
+		if err != nil {
+			t.Fatalf("%d addresses: unexpected error: %v", n, err)
+		}
+		if len(scripts) != n {
+			t.Fatalf("%d addresses: got %d scripts", n, len(scripts))
+		}
+		for i, addr := range addrs {
+			want, err := txscript.PayToAddrScript(addr)
+			if err != nil {
+				t.Fatalf("PayToAddrScript: %v", err)
+			}
+			if !bytes.Equal(scripts[i], want) {
+				t.Fatalf("%d addresses: script %d: got %x, want %x",
+					n, i, scripts[i], want)
+			}
+			if cap(scripts[i]) != len(scripts[i]) {
+				t.Fatalf("%d addresses: script %d has spare "+
+					"capacity %d", n, i, cap(scripts[i])-len(scripts[i]))
+			}
+		}
+	}
+}
+
+// TestPayToAddrScriptBatchError ensures the first address which can not be
+// compiled is reported by its index.
+func TestPayToAddrScriptBatchError(t *testing.T) {
+	addrs := testAddresses(t, 3000)
+	addrs[2900] = nil
+	addrs[1700] = nil
+
+	_, err := txbuilder.PayToAddrScriptBatch(addrs)
+	var addrErr *txbuilder.AddressScriptError
+	if !errors.As(err, &addrErr) {
+		t.Fatalf("got error %v, want an AddressScriptError", err)
+	}
+	if addrErr.Index != 1700 {
+		t.Errorf("got index %d, want 1700", addrErr.Index)
+	}
+}
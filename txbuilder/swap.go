@@ -0,0 +1,249 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package txbuilder
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcd/wire/common"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/psbt"
+	"github.com/zeusyf/omega/token"
+)
+
+// DefaultSigScriptSize is the size of the signature script assumed for each
+// input of a swap when estimating its fee: a pay-to-pubkey-hash signature
+// script with a worst case signature.
+const DefaultSigScriptSize = 1 + 72 + 1 + 33
+
+var (
+	// ErrPrevTxMismatch describes an error where the previous transaction
+	// of a swap input does not have the output the input spends.
+	ErrPrevTxMismatch = errors.New("previous transaction does not match " +
+		"outpoint")
+
+	// ErrInsufficientFunds describes an error where the outputs a party
+	// to a swap pays exceed its inputs of some token.
+	ErrInsufficientFunds = errors.New("party pays more than its inputs")
+
+	// ErrInsufficientFee describes an error where the fee payer of a swap
+	// does not have the OMC left to pay the fee.
+	ErrInsufficientFee = errors.New("fee payer can not cover the fee")
+
+	// ErrInvalidFeePayer describes an error where the fee payer of a swap
+	// is not one of its parties.
+	ErrInvalidFeePayer = errors.New("fee payer is not a party")
+)
+
+// SwapInput is a coin a party spends in a swap, along with the transaction
+// creating it.
+type SwapInput struct {
+	OutPoint wire.OutPoint
+	PrevTx   *wire.MsgTx
+}
+
+// SwapParty is a party to a swap: the coins it spends, the outputs it pays
+// for, and the script receiving what is left of its coins.  Paying 10 OMC
+// for a token X, for example, the buyer spends OMC coins and pays an output
+// of 10 OMC to the seller, while the seller spends the coin of token X and
+// pays an output of it to the buyer.
+type SwapParty struct {
+	Inputs       []SwapInput
+	Outputs      []*wire.TxOut
+	ChangeScript []byte
+}
+
+// Swap describes an atomic transaction between parties exchanging tokens.
+// Every token a party spends and does not pay in its outputs is returned to
+// it as change, so each party gives up no more than its outputs, and the
+// fee, in OMC, is paid from the change of the fee payer.
+type Swap struct {
+	Parties []*SwapParty
+
+	// FeeRate is the fee rate of the swap transaction.
+	FeeRate btcutil.FeeRate
+
+	// FeePayer is the index of the party paying the fee.
+	FeePayer int
+
+	// MinChange is the smallest OMC change paid to the fee payer.  Less
+	// is added to the fee instead.
+	MinChange btcutil.Amount
+
+	// SigScriptSize is the size of the signature script of each input
+	// assumed when estimating the fee.  DefaultSigScriptSize is used
+	// when it is zero.
+	SigScriptSize int
+}
+
+// balanceKey identifies the holdings of a token: numeric tokens by their
+// type and rights, hash tokens by their hash as well.
+type balanceKey struct {
+	tokenType uint64
+	hash      chainhash.Hash
+	rights    chainhash.Hash
+	hasRights bool
+}
+
+// keyOf returns the balance key of tok and its numeric value, which is one
+// for hash tokens.
+func keyOf(tok *token.Token) (balanceKey, int64) {
+	key := balanceKey{tokenType: tok.TokenType}
+	if tok.Rights != nil {
+		key.rights, key.hasRights = *tok.Rights, true
+	}
+	switch v := tok.Value.(type) {
+	case *token.HashVal:
+		key.hash = v.Hash
+		return key, 1
+	case *token.NumeralVal:
+		return key, v.Val
+	}
+	return key, 0
+}
+
+// token returns a token of the balance key worth value.
+func (k balanceKey) token(value int64) token.Token {
+	tok := token.Token{TokenType: k.tokenType}
+	if k.hasRights {
+		rights := k.rights
+		tok.Rights = &rights
+	}
+	if k.tokenType&1 != 0 {
+		tok.Value = &token.HashVal{Hash: k.hash}
+	} else {
+		tok.Value = &token.NumeralVal{Val: value}
+	}
+	return tok
+}
+
+// omcKey is the balance key of OMC.
+var omcKey = balanceKey{}
+
+// balance returns the tokens left to party after paying its outputs, as
+// balance keys in the order they were first spent, and the amount of each.
+func (p *SwapParty) balance() ([]balanceKey, map[balanceKey]int64, error) {
+	var keys []balanceKey
+	amounts := make(map[balanceKey]int64)
+	for _, in := range p.Inputs {
+		op := in.OutPoint
+		if in.PrevTx == nil || in.PrevTx.TxHash() != op.Hash ||
+			op.Index >= uint32(len(in.PrevTx.TxOut)) {
+			return nil, nil, ErrPrevTxMismatch
+		}
+		key, value := keyOf(&in.PrevTx.TxOut[op.Index].Token)
+		if _, ok := amounts[key]; !ok {
+			keys = append(keys, key)
+		}
+		amounts[key] += value
+	}
+	for _, out := range p.Outputs {
+		key, value := keyOf(&out.Token)
+		amounts[key] -= value
+		if amounts[key] < 0 {
+			return nil, nil, ErrInsufficientFunds
+		}
+	}
+	return keys, amounts, nil
+}
+
+// Build returns the swap as a packet for the parties to sign, whose inputs
+// carry the transactions creating the coins they spend.  The transaction has
+// the inputs and outputs of each party in turn, followed by the change of
+// each party, the OMC change of the fee payer last.  The options configure
+// the transaction as for New.
+func (s *Swap) Build(opts ...Option) (*psbt.Packet, error) {
+	if s.FeePayer < 0 || s.FeePayer >= len(s.Parties) {
+		return nil, ErrInvalidFeePayer
+	}
+
+	b := New(opts...)
+	var prevTxs []*wire.MsgTx
+	for _, p := range s.Parties {
+		for _, in := range p.Inputs {
+			b.AddInput(in.OutPoint)
+			prevTxs = append(prevTxs, in.PrevTx)
+		}
+		for _, out := range p.Outputs {
+			b.AddTxOut(out)
+		}
+	}
+
+	var payerOMC int64
+	for i, p := range s.Parties {
+		keys, amounts, err := p.balance()
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range keys {
+			if i == s.FeePayer && key == omcKey {
+				payerOMC = amounts[key]
+				continue
+			}
+			if amounts[key] == 0 {
+				continue
+			}
+			b.AddTxOut(&wire.TxOut{
+				Token:    key.token(amounts[key]),
+				PkScript: p.ChangeScript,
+			})
+		}
+	}
+	payer := s.Parties[s.FeePayer]
+	b.AddTxOut(&wire.TxOut{
+		Token:    omcKey.token(payerOMC),
+		PkScript: payer.ChangeScript,
+	})
+
+	tx, err := b.Build()
+	if err != nil {
+		return nil, err
+	}
+
+	// The change of the fee payer is what is left after the fee for the
+	// transaction with it.
+	fee, err := s.fee(tx)
+	if err != nil {
+		return nil, err
+	}
+	change := btcutil.Amount(payerOMC) - fee
+	if change < 0 {
+		return nil, ErrInsufficientFee
+	}
+	if change < s.MinChange || change == 0 {
+		tx.TxOut = tx.TxOut[:len(tx.TxOut)-1]
+	} else {
+		tx.TxOut[len(tx.TxOut)-1].Token = omcKey.token(int64(change))
+	}
+
+	packet, err := psbt.New(tx)
+	if err != nil {
+		return nil, err
+	}
+	for i, prevTx := range prevTxs {
+		packet.Inputs[i].NonWitnessUtxo = prevTx
+	}
+	return packet, nil
+}
+
+// fee returns the fee of tx once signed.
+func (s *Swap) fee(tx *wire.MsgTx) (btcutil.Amount, error) {
+	var buf bytes.Buffer
+	if err := tx.Serialize(&buf); err != nil {
+		return 0, err
+	}
+	sigScriptSize := s.SigScriptSize
+	if sigScriptSize == 0 {
+		sigScriptSize = DefaultSigScriptSize
+	}
+	n := len(tx.TxIn)
+	size := buf.Len() + n*(common.VarIntSerializeSize(uint64(sigScriptSize))+
+		sigScriptSize) + common.VarIntSerializeSize(uint64(n)) -
+		common.VarIntSerializeSize(0)
+	return s.FeeRate.FeeForVSize(int64(size)), nil
+}
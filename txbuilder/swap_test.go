@@ -0,0 +1,163 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package txbuilder_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/txbuilder"
+	"github.com/zeusyf/omega/token"
+)
+
+var (
+	buyerScript  = []byte{0x51}
+	sellerScript = []byte{0x52}
+	tokenX       = token.Token{TokenType: 1, Value: &token.HashVal{Hash: chainhash.Hash{0x58}}}
+)
+
+// omc returns an output paying value of OMC to pkScript.
+func omc(value int64, pkScript []byte) *wire.TxOut {
+	return &wire.TxOut{
+		Token:    token.Token{Value: &token.NumeralVal{Val: value}},
+		PkScript: pkScript,
+	}
+}
+
+// swapInput returns an input spending a new coin with the passed output.
+func swapInput(out *wire.TxOut) txbuilder.SwapInput {
+	prev := wire.NewMsgTx(wire.TxVersion)
+	prev.AddTxIn(&wire.TxIn{SignatureIndex: 0xffffffff})
+	prev.AddTxOut(out)
+	return txbuilder.SwapInput{
+		OutPoint: wire.OutPoint{Hash: prev.TxHash()},
+		PrevTx:   prev,
+	}
+}
+
+// testSwap returns a swap of a buyer paying 10000 Hao of OMC from a coin of
+// 50000 for token X and 3 of a numeric token from a coin of 7.
+func testSwap() *txbuilder.Swap {
+	numeric := token.Token{TokenType: 4, Value: &token.NumeralVal{Val: 7}}
+	buyer := &txbuilder.SwapParty{
+		Inputs:       []txbuilder.SwapInput{swapInput(omc(50000, buyerScript))},
+		Outputs:      []*wire.TxOut{omc(10000, sellerScript)},
+		ChangeScript: buyerScript,
+	}
+	seller := &txbuilder.SwapParty{
+		Inputs: []txbuilder.SwapInput{
+			swapInput(&wire.TxOut{Token: tokenX, PkScript: sellerScript}),
+			swapInput(&wire.TxOut{Token: numeric, PkScript: sellerScript}),
+		},
+		Outputs: []*wire.TxOut{
+			{Token: tokenX, PkScript: buyerScript},
+			{Token: token.Token{TokenType: 4, Value: &token.NumeralVal{Val: 3}}, PkScript: buyerScript},
+		},
+		ChangeScript: sellerScript,
+	}
+	return &txbuilder.Swap{
+		Parties: []*txbuilder.SwapParty{buyer, seller},
+		FeeRate: btcutil.NewFeeRateFromHaoPerVByte(10),
+	}
+}
+
+// TestSwap ensures the swap transaction returns every party its change and
+// pays the fee at the fee rate from the OMC of the fee payer.
+func TestSwap(t *testing.T) {
+	swap := testSwap()
+	packet, err := swap.Build(txbuilder.WithLockTime(0))
+	if err != nil {
+		t.Fatalf("Build: unexpected error: %v", err)
+	}
+	tx := packet.UnsignedTx
+	if len(tx.TxIn) != 3 || len(tx.TxOut) != 5 {
+		t.Fatalf("Build: got %d inputs and %d outputs", len(tx.TxIn),
+			len(tx.TxOut))
+	}
+	for i := range tx.TxIn {
+		if packet.PrevOutput(i) == nil {
+			t.Errorf("Build: input %d lacks its previous transaction", i)
+		}
+	}
+
+	// The seller is left 4 of the numeric token, and the buyer the OMC
+	// after the fee.
+	change := tx.TxOut[3]
+	if change.Token.TokenType != 4 || change.Token.Value.(*token.NumeralVal).Val != 4 ||
+		!bytes.Equal(change.PkScript, sellerScript) {
+		t.Errorf("Build: got seller change %+v", change)
+	}
+	change = tx.TxOut[4]
+	if change.Token.TokenType != 0 || !bytes.Equal(change.PkScript, buyerScript) {
+		t.Errorf("Build: got buyer change %+v", change)
+	}
+
+	// Once signed with scripts of the assumed size, the transaction pays
+	// the fee rate.
+	for i := range packet.Inputs {
+		packet.Inputs[i].FinalScriptSig = make([]byte, txbuilder.DefaultSigScriptSize)
+	}
+	signed, err := packet.Extract()
+	if err != nil {
+		t.Fatalf("Extract: unexpected error: %v", err)
+	}
+	var buf bytes.Buffer
+	if err := signed.Serialize(&buf); err != nil {
+		t.Fatalf("Serialize: unexpected error: %v", err)
+	}
+	fee := btcutil.Amount(40000 - change.Token.Value.(*token.NumeralVal).Val)
+	if want := swap.FeeRate.FeeForVSize(int64(buf.Len())); fee != want {
+		t.Errorf("Build: got fee %d, want %d", fee, want)
+	}
+
+	// Change below the minimum goes to the fee.
+	swap.MinChange = 40000
+	packet, err = swap.Build()
+	if err != nil {
+		t.Fatalf("Build: unexpected error: %v", err)
+	}
+	if n := len(packet.UnsignedTx.TxOut); n != 4 {
+		t.Errorf("Build: got %d outputs with dust change, want 4", n)
+	}
+}
+
+// TestSwapErrors ensures swaps a party can not fund are rejected.
+func TestSwapErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(s *txbuilder.Swap)
+		err    error
+	}{
+		{"no fee payer", func(s *txbuilder.Swap) { s.FeePayer = 2 }, txbuilder.ErrInvalidFeePayer},
+		{"wrong previous tx", func(s *txbuilder.Swap) {
+			s.Parties[1].Inputs[0].PrevTx = s.Parties[1].Inputs[1].PrevTx
+		}, txbuilder.ErrPrevTxMismatch},
+		{"missing previous tx", func(s *txbuilder.Swap) {
+			s.Parties[0].Inputs[0].PrevTx = nil
+		}, txbuilder.ErrPrevTxMismatch},
+		{"token not held", func(s *txbuilder.Swap) {
+			s.Parties[0].Outputs = append(s.Parties[0].Outputs,
+				&wire.TxOut{Token: tokenX, PkScript: sellerScript})
+		}, txbuilder.ErrInsufficientFunds},
+		{"omc not held", func(s *txbuilder.Swap) {
+			s.Parties[0].Outputs[0] = omc(50001, sellerScript)
+		}, txbuilder.ErrInsufficientFunds},
+		{"fee not held", func(s *txbuilder.Swap) {
+			s.Parties[0].Outputs[0] = omc(50000, sellerScript)
+		}, txbuilder.ErrInsufficientFee},
+		{"seller pays fee", func(s *txbuilder.Swap) { s.FeePayer = 1 }, txbuilder.ErrInsufficientFee},
+	}
+	for _, test := range tests {
+		swap := testSwap()
+		test.modify(swap)
+		if _, err := swap.Build(); err != test.err {
+			t.Errorf("Build(%s): got error %v, want %v", test.name, err,
+				test.err)
+		}
+	}
+}
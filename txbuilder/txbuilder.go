@@ -0,0 +1,283 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package txbuilder assembles unsigned transactions from a set of inputs and
+// outputs while applying wallet policies such as anti-fee-sniping lock times.
+package txbuilder
+
+import (
+	"errors"
+	"io"
+	"math/rand"
+	"time"
+
+	"github.com/zeusyf/btcd/txscript"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/entropy"
+	"github.com/zeusyf/omega/token"
+)
+
+const (
+	// MaxTipAge is the maximum age of the chain tip for which an
+	// anti-fee-sniping lock time is applied.  When the tip is older the
+	// wallet is likely still syncing and setting a lock time would only
+	// fingerprint the transaction.
+	MaxTipAge = 8 * time.Hour
+
+	// antiSnipingMaxOffset is the exclusive upper bound of the random
+	// offset occasionally subtracted from the tip height.
+	antiSnipingMaxOffset = 100
+
+	// antiSnipingOffsetOdds is the inverse of the probability that a
+	// random offset is applied to the lock time.
+	antiSnipingOffsetOdds = 10
+)
+
+var (
+	// ErrNoInputs describes an error where a transaction is built without
+	// any inputs.
+	ErrNoInputs = errors.New("transaction has no inputs")
+
+	// ErrNoOutputs describes an error where a transaction is built without
+	// any outputs.
+	ErrNoOutputs = errors.New("transaction has no outputs")
+
+	// ErrTokensUnsupported describes an error where a transaction for a
+	// chain without tokens has an output carrying a token other than the
+	// coin of the chain.
+	ErrTokensUnsupported = errors.New("chain does not support tokens")
+)
+
+// TipSource provides the current tip of the best chain.
+type TipSource interface {
+	// BestBlock returns the height and timestamp of the current tip.
+	BestBlock() (int32, time.Time, error)
+}
+
+// Option configures a Builder.
+type Option func(*Builder)
+
+// WithVersion sets the version of the built transaction.
+func WithVersion(version int32) Option {
+	return func(b *Builder) {
+		b.version = version
+	}
+}
+
+// WithLockTime sets a fixed lock time for the built transaction.  It takes
+// precedence over WithAntiFeeSniping.
+func WithLockTime(lockTime uint32) Option {
+	return func(b *Builder) {
+		b.lockTime = lockTime
+		b.fixedLockTime = true
+	}
+}
+
+// WithAntiFeeSniping makes the builder set the lock time of the transaction
+// to the height of the current tip as reported by tip, discouraging miners
+// from reorging the chain to claim fees.  Like modern wallets, one time in ten
+// a random offset of up to 99 blocks is subtracted so that transactions that
+// were delayed for privacy or by high latency don't stand out.
+//
+// No lock time is set when the tip is older than MaxTipAge.
+func WithAntiFeeSniping(tip TipSource) Option {
+	return func(b *Builder) {
+		b.tip = tip
+	}
+}
+
+// WithRand sets the random source used for policy decisions such as the
+// anti-fee-sniping offset.  It is primarily useful for deterministic tests.
+// It takes precedence over WithEntropy.
+func WithRand(r *rand.Rand) Option {
+	return func(b *Builder) {
+		b.rand = r
+	}
+}
+
+// WithEntropy sets the entropy source used for policy decisions such as the
+// anti-fee-sniping offset.  By default the default source of the entropy
+// package is used.
+func WithEntropy(src io.Reader) Option {
+	return func(b *Builder) {
+		b.entropySrc = src
+	}
+}
+
+// WithChainCodec makes the builder check the transaction against the chain
+// described by codec: addresses passed to AddAddressOutput must be of its
+// network, and outputs may only carry tokens when the chain has them.  By
+// default no chain is assumed and no such checks are made.
+func WithChainCodec(codec btcutil.ChainCodec) Option {
+	return func(b *Builder) {
+		b.codec = codec
+	}
+}
+
+// Builder assembles an unsigned transaction.  The zero value is not usable; a
+// Builder must be created with New.
+type Builder struct {
+	version       int32
+	lockTime      uint32
+	fixedLockTime bool
+	tip           TipSource
+	rand          *rand.Rand
+	entropySrc    io.Reader
+	now           func() time.Time
+	codec         btcutil.ChainCodec
+	inputs        []*wire.TxIn
+	outputs       []*wire.TxOut
+	err           error
+}
+
+// New returns a builder configured with the passed options.
+func New(opts ...Option) *Builder {
+	b := &Builder{
+		version: wire.TxVersion,
+		now:     time.Now,
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// AddInput adds an input spending the passed outpoint with the maximum
+// sequence number.  The sequence is adjusted when a lock time needs to be
+// enforced.
+func (b *Builder) AddInput(op wire.OutPoint) *Builder {
+	return b.AddInputWithSequence(op, wire.MaxTxInSequenceNum)
+}
+
+// AddInputWithSequence adds an input spending the passed outpoint with an
+// explicit sequence number.
+func (b *Builder) AddInputWithSequence(op wire.OutPoint, sequence uint32) *Builder {
+	b.inputs = append(b.inputs, &wire.TxIn{
+		PreviousOutPoint: op,
+		Sequence:         sequence,
+	})
+	return b
+}
+
+// AddOutput adds an output paying amount of the base token to pkScript.
+func (b *Builder) AddOutput(pkScript []byte, amount btcutil.Amount) *Builder {
+	b.outputs = append(b.outputs, &wire.TxOut{
+		Token: token.Token{
+			TokenType: 0,
+			Value:     &token.NumeralVal{Val: int64(amount)},
+		},
+		PkScript: pkScript,
+	})
+	return b
+}
+
+// AddAddressOutput adds an output paying amount of the base token to addr.
+// Any error compiling the script of the address, or an address of another
+// network than that of the chain codec, is returned by Build.
+func (b *Builder) AddAddressOutput(addr btcutil.Address, amount btcutil.Amount) *Builder {
+	if b.err != nil {
+		return b
+	}
+	if b.codec != nil && !addr.IsForNet(b.codec.Params()) {
+		b.err = btcutil.ErrWrongChain
+		return b
+	}
+	pkScript, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		b.err = err
+		return b
+	}
+	return b.AddOutput(pkScript, amount)
+}
+
+// AddTxOut adds a copy of the passed output, which may carry any token.
+func (b *Builder) AddTxOut(out *wire.TxOut) *Builder {
+	txOut := *out
+	b.outputs = append(b.outputs, &txOut)
+	return b
+}
+
+// antiSnipingLockTime returns the lock time to use as protection against fee
+// sniping, or zero when none should be set.
+func (b *Builder) antiSnipingLockTime() (uint32, error) {
+	height, timestamp, err := b.tip.BestBlock()
+	if err != nil {
+		return 0, err
+	}
+	if height <= 0 || b.now().Sub(timestamp) > MaxTipAge {
+		return 0, nil
+	}
+
+	odds, err := b.intn(antiSnipingOffsetOdds)
+	if err != nil {
+		return 0, err
+	}
+	if odds == 0 {
+		offset, err := b.intn(antiSnipingMaxOffset)
+		if err != nil {
+			return 0, err
+		}
+		height -= int32(offset)
+		if height < 0 {
+			height = 0
+		}
+	}
+	return uint32(height), nil
+}
+
+// intn returns a random integer in [0, n) for policy decisions.
+func (b *Builder) intn(n int) (int, error) {
+	if b.rand != nil {
+		return b.rand.Intn(n), nil
+	}
+	return entropy.Intn(b.entropySrc, n)
+}
+
+// Build returns the assembled transaction.  When a lock time is set, any
+// input with a final sequence number is changed to the maximum non-final
+// sequence number so the lock time is enforced.
+func (b *Builder) Build() (*wire.MsgTx, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	if len(b.inputs) == 0 {
+		return nil, ErrNoInputs
+	}
+	if len(b.outputs) == 0 {
+		return nil, ErrNoOutputs
+	}
+	if b.codec != nil && !b.codec.HasTokens() {
+		for _, out := range b.outputs {
+			if out.Token.TokenType != 0 || out.Token.Rights != nil {
+				return nil, ErrTokensUnsupported
+			}
+		}
+	}
+
+	lockTime := b.lockTime
+	if !b.fixedLockTime && b.tip != nil {
+		var err error
+		lockTime, err = b.antiSnipingLockTime()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	tx := wire.NewMsgTx(b.version)
+	tx.LockTime = lockTime
+	for _, in := range b.inputs {
+		txIn := *in
+		if lockTime != 0 && txIn.Sequence == wire.MaxTxInSequenceNum {
+			txIn.Sequence = wire.MaxTxInSequenceNum - 1
+		}
+		tx.AddTxIn(&txIn)
+	}
+	for _, out := range b.outputs {
+		txOut := *out
+		tx.AddTxOut(&txOut)
+	}
+
+	return tx, nil
+}
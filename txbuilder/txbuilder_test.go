@@ -0,0 +1,206 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package txbuilder_test
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcd/txscript"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/txbuilder"
+	"github.com/zeusyf/omega/token"
+)
+
+// staticTip is a txbuilder.TipSource returning a fixed tip.
+type staticTip struct {
+	height    int32
+	timestamp time.Time
+	err       error
+}
+
+func (s staticTip) BestBlock() (int32, time.Time, error) {
+	return s.height, s.timestamp, s.err
+}
+
+// TestAntiFeeSniping ensures the lock time is set near the tip height and
+// that inputs are made non-final so it is enforced.
+func TestAntiFeeSniping(t *testing.T) {
+	tip := staticTip{height: 500000, timestamp: time.Now()}
+	r := rand.New(rand.NewSource(1))
+
+	var offsets int
+	for i := 0; i < 1000; i++ {
+		tx, err := txbuilder.New(txbuilder.WithAntiFeeSniping(tip),
+			txbuilder.WithRand(r)).
+			AddInput(wire.OutPoint{Index: 1}).
+			AddOutput([]byte{0x51}, 1000).
+			Build()
+		if err != nil {
+			t.Fatalf("Build: unexpected error: %v", err)
+		}
+		if tx.LockTime > 500000 || tx.LockTime <= 500000-100 {
+			t.Fatalf("Build: lock time %d not near tip", tx.LockTime)
+		}
+		if tx.LockTime != 500000 {
+			offsets++
+		}
+		if tx.TxIn[0].Sequence != wire.MaxTxInSequenceNum-1 {
+			t.Fatalf("Build: input sequence %x is final",
+				tx.TxIn[0].Sequence)
+		}
+	}
+
+	// Roughly one in ten transactions should have an offset applied.
+	if offsets < 50 || offsets > 150 {
+		t.Fatalf("Build: %d of 1000 lock times were offset", offsets)
+	}
+}
+
+// TestAntiFeeSnipingStaleTip ensures no lock time is set when the tip is
+// stale and that errors from the tip source are returned.
+func TestAntiFeeSnipingStaleTip(t *testing.T) {
+	stale := staticTip{height: 500000, timestamp: time.Now().Add(-9 * time.Hour)}
+	tx, err := txbuilder.New(txbuilder.WithAntiFeeSniping(stale)).
+		AddInput(wire.OutPoint{}).
+		AddOutput([]byte{0x51}, 1000).
+		Build()
+	if err != nil {
+		t.Fatalf("Build: unexpected error: %v", err)
+	}
+	if tx.LockTime != 0 || tx.TxIn[0].Sequence != wire.MaxTxInSequenceNum {
+		t.Fatalf("Build: got lock time %d and sequence %x for stale tip",
+			tx.LockTime, tx.TxIn[0].Sequence)
+	}
+
+	tipErr := errors.New("backend down")
+	_, err = txbuilder.New(txbuilder.WithAntiFeeSniping(staticTip{err: tipErr})).
+		AddInput(wire.OutPoint{}).
+		AddOutput([]byte{0x51}, 1000).
+		Build()
+	if err != tipErr {
+		t.Fatalf("Build: got error %v, want %v", err, tipErr)
+	}
+
+	// An explicit lock time overrides anti-fee-sniping.
+	tx, err = txbuilder.New(txbuilder.WithAntiFeeSniping(stale),
+		txbuilder.WithLockTime(42)).
+		AddInput(wire.OutPoint{}).
+		AddOutput([]byte{0x51}, 1000).
+		Build()
+	if err != nil || tx.LockTime != 42 {
+		t.Fatalf("Build: got lock time %d (%v), want 42", tx.LockTime, err)
+	}
+}
+
+// TestAntiFeeSnipingEntropy ensures the offset is drawn from the configured
+// entropy source and that its errors are returned.
+func TestAntiFeeSnipingEntropy(t *testing.T) {
+	tip := staticTip{height: 500000, timestamp: time.Now()}
+
+	// A zero roll of the odds applies an offset, here 5.
+	src := bytes.NewReader([]byte{
+		0, 0, 0, 0, 0, 0, 0, 0,
+		5, 0, 0, 0, 0, 0, 0, 0,
+	})
+	tx, err := txbuilder.New(txbuilder.WithAntiFeeSniping(tip),
+		txbuilder.WithEntropy(src)).
+		AddInput(wire.OutPoint{}).
+		AddOutput([]byte{0x51}, 1000).
+		Build()
+	if err != nil || tx.LockTime != 499995 {
+		t.Fatalf("Build: got lock time %d (%v), want 499995", tx.LockTime,
+			err)
+	}
+
+	// The source is exhausted.
+	_, err = txbuilder.New(txbuilder.WithAntiFeeSniping(tip),
+		txbuilder.WithEntropy(src)).
+		AddInput(wire.OutPoint{}).
+		AddOutput([]byte{0x51}, 1000).
+		Build()
+	if err != io.EOF {
+		t.Fatalf("Build: got error %v, want %v", err, io.EOF)
+	}
+}
+
+// TestBuildErrors ensures incomplete transactions are rejected.
+func TestBuildErrors(t *testing.T) {
+	_, err := txbuilder.New().AddOutput([]byte{0x51}, 1).Build()
+	if err != txbuilder.ErrNoInputs {
+		t.Fatalf("Build: got %v, want %v", err, txbuilder.ErrNoInputs)
+	}
+	_, err = txbuilder.New().AddInput(wire.OutPoint{}).Build()
+	if err != txbuilder.ErrNoOutputs {
+		t.Fatalf("Build: got %v, want %v", err, txbuilder.ErrNoOutputs)
+	}
+}
+
+// TestChainCodec ensures a builder for a chain rejects addresses of other
+// networks and tokens the chain does not have.
+func TestChainCodec(t *testing.T) {
+	hash := bytes.Repeat([]byte{0x11}, 20)
+	addr, err := btcutil.NewAddressPubKeyHash(hash, &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("NewAddressPubKeyHash: %v", err)
+	}
+	omega := btcutil.NewOmegaChainCodec(&chaincfg.MainNetParams)
+	tokenless := &btcutil.BasicChainCodec{Net: &chaincfg.MainNetParams}
+
+	tx, err := txbuilder.New(txbuilder.WithChainCodec(omega)).
+		AddInput(wire.OutPoint{Index: 1}).
+		AddAddressOutput(addr, 1000).
+		Build()
+	if err != nil {
+		t.Fatalf("Build: unexpected error: %v", err)
+	}
+	want, err := txscript.PayToAddrScript(addr)
+	if err != nil {
+		t.Fatalf("PayToAddrScript: %v", err)
+	}
+	if !bytes.Equal(tx.TxOut[0].PkScript, want) {
+		t.Errorf("got script %x, want %x", tx.TxOut[0].PkScript, want)
+	}
+
+	other := btcutil.NewOmegaChainCodec(&chaincfg.TestNet3Params)
+	_, err = txbuilder.New(txbuilder.WithChainCodec(other)).
+		AddInput(wire.OutPoint{Index: 1}).
+		AddAddressOutput(addr, 1000).
+		Build()
+	if err != btcutil.ErrWrongChain {
+		t.Errorf("other network: got error %v, want %v", err,
+			btcutil.ErrWrongChain)
+	}
+
+	tokenOut := &wire.TxOut{
+		Token: token.Token{
+			TokenType: 4,
+			Value:     &token.NumeralVal{Val: 1},
+		},
+		PkScript: want,
+	}
+	for _, test := range []struct {
+		codec btcutil.ChainCodec
+		err   error
+	}{
+		{omega, nil},
+		{tokenless, txbuilder.ErrTokensUnsupported},
+	} {
+		_, err := txbuilder.New(txbuilder.WithChainCodec(test.codec)).
+			AddInput(wire.OutPoint{Index: 1}).
+			AddTxOut(tokenOut).
+			Build()
+		if err != test.err {
+			t.Errorf("token output: got error %v, want %v", err,
+				test.err)
+		}
+	}
+}
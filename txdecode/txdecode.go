@@ -0,0 +1,244 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package txdecode decodes transactions into a structured, human-readable
+// summary, as the decoderawtransaction command of a node does, for tools
+// without a node at hand.
+//
+// A summary lists the outpoint each input spends and its relative lock time,
+// the token, amount, script type and addresses of each output, and what the
+// lock time of the transaction means.  When the outputs spent by the inputs
+// are supplied, inputs list them as well and the summary holds the fee.  A
+// summary encodes to JSON with the field names of the node's command where
+// there are any.
+package txdecode
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/locktime"
+	"github.com/zeusyf/btcutil/scriptclass"
+	"github.com/zeusyf/btcutil/signing"
+	"github.com/zeusyf/omega/token"
+)
+
+// ErrUnknownTokenValue describes an error where an output holds a token
+// value which is neither numeric nor a hash.
+var ErrUnknownTokenValue = errors.New("unknown token value")
+
+// Lock time kinds.
+const (
+	LockTimeNone   = "none"
+	LockTimeHeight = "height"
+	LockTimeTime   = "time"
+)
+
+// Tx is the summary of a transaction.
+type Tx struct {
+	TxID     string    `json:"txid"`
+	Version  int32     `json:"version"`
+	Size     int       `json:"size"`
+	LockTime LockTime  `json:"locktime"`
+	Coinbase bool      `json:"coinbase,omitempty"`
+	Inputs   []*Input  `json:"vin"`
+	Outputs  []*Output `json:"vout"`
+
+	// Fee is the OMC spent by the inputs less the OMC paid by the
+	// outputs.  It is nil for coinbase transactions, and unless the output
+	// spent by every input was supplied.
+	Fee *btcutil.Amount `json:"fee,omitempty"`
+}
+
+// LockTime is the lock time of a transaction.
+type LockTime struct {
+	// Value is the lock time as serialized.
+	Value uint32 `json:"value"`
+
+	// Kind is LockTimeNone for the zero lock time, LockTimeHeight for a
+	// block height and LockTimeTime for a timestamp.
+	Kind string `json:"kind"`
+
+	// Description is the height or the RFC 3339 time of the lock time,
+	// as returned by locktime.LockTime.String, or empty for the zero lock
+	// time.
+	Description string `json:"description,omitempty"`
+
+	// Enforced is whether the lock time applies, which it does only when
+	// an input has a sequence number below the maximum.
+	Enforced bool `json:"enforced"`
+}
+
+// Input is the summary of an input.
+type Input struct {
+	TxID           string `json:"txid"`
+	Vout           uint32 `json:"vout"`
+	SignatureIndex uint32 `json:"signature_index"`
+	Sequence       uint32 `json:"sequence"`
+
+	// RelativeLock is the relative lock time of the sequence number, as
+	// returned by locktime.RelativeLock.String, or empty when it has
+	// none.  Only transactions of version 2 and above have them.
+	RelativeLock string `json:"relative_lock,omitempty"`
+
+	// PrevOut is the output spent, or nil when it was not supplied.
+	PrevOut *Output `json:"prevout,omitempty"`
+}
+
+// Output is the summary of an output.
+type Output struct {
+	// N is the index of the output, or of the output spent by an input.
+	N uint32 `json:"n"`
+
+	TokenType uint64 `json:"token_type"`
+
+	// Value is the value of numeric tokens, in hao for OMC, and nil for
+	// tokens holding a hash.
+	Value *int64 `json:"value,omitempty"`
+
+	// Amount is the value of OMC outputs as returned by Amount.String.
+	Amount string `json:"amount,omitempty"`
+
+	// Hash is the hash of tokens holding one.
+	Hash string `json:"hash,omitempty"`
+
+	Rights   string   `json:"rights,omitempty"`
+	PkScript string   `json:"pkscript"`
+	Type     string   `json:"type"`
+	Address  []string `json:"addresses,omitempty"`
+}
+
+// newOutput returns the summary of the n-th output, txOut, of a transaction
+// on the network net.
+func newOutput(n uint32, txOut *wire.TxOut, net *chaincfg.Params) (*Output, error) {
+	out := &Output{
+		N:         n,
+		TokenType: txOut.Token.TokenType,
+		PkScript:  hex.EncodeToString(txOut.PkScript),
+	}
+	switch v := txOut.Token.Value.(type) {
+	case *token.NumeralVal:
+		val := v.Val
+		out.Value = &val
+		if txOut.Token.TokenType == 0 {
+			out.Amount = btcutil.Amount(val).String()
+		}
+	case *token.HashVal:
+		out.Hash = v.Hash.String()
+	default:
+		return nil, ErrUnknownTokenValue
+	}
+	if txOut.Token.Rights != nil {
+		out.Rights = txOut.Token.Rights.String()
+	}
+
+	// Scripts paying to another network have no addresses on net, but
+	// still have a type.
+	class, addrs, _, _ := scriptclass.ExtractPkScriptAddrs(txOut.PkScript, net)
+	out.Type = class.String()
+	for _, addr := range addrs {
+		out.Address = append(out.Address, addr.EncodeAddress())
+	}
+	return out, nil
+}
+
+// omcValue returns the amount of OMC of txOut, or zero when it holds another
+// token.
+func omcValue(txOut *wire.TxOut) btcutil.Amount {
+	value, ok := txOut.Token.Value.(*token.NumeralVal)
+	if !ok || txOut.Token.TokenType != 0 {
+		return 0
+	}
+	return btcutil.Amount(value.Val)
+}
+
+// Decode returns the summary of tx, whose addresses are those of the network
+// net.  The outputs spent by its inputs are looked up with fetcher, which may
+// be nil when none are known.
+func Decode(tx *btcutil.Tx, net *chaincfg.Params,
+	fetcher signing.PrevOutFetcher) (*Tx, error) {
+
+	msgTx := tx.MsgTx()
+	d := &Tx{
+		TxID:     tx.Hash().String(),
+		Version:  msgTx.Version,
+		Size:     msgTx.SerializeSize(),
+		Coinbase: tx.IsCoinBase(),
+		Inputs:   make([]*Input, 0, len(msgTx.TxIn)),
+		Outputs:  make([]*Output, 0, len(msgTx.TxOut)),
+	}
+
+	lockTime := locktime.LockTime(msgTx.LockTime)
+	d.LockTime.Value = msgTx.LockTime
+	switch {
+	case lockTime == 0:
+		d.LockTime.Kind = LockTimeNone
+	case lockTime.IsHeight():
+		d.LockTime.Kind = LockTimeHeight
+	default:
+		d.LockTime.Kind = LockTimeTime
+	}
+	if lockTime != 0 {
+		d.LockTime.Description = lockTime.String()
+	}
+
+	var in, out btcutil.Amount
+	complete := !d.Coinbase
+	for _, txIn := range msgTx.TxIn {
+		op := txIn.PreviousOutPoint
+		input := &Input{
+			TxID:           op.Hash.String(),
+			Vout:           op.Index,
+			SignatureIndex: txIn.SignatureIndex,
+			Sequence:       txIn.Sequence,
+		}
+		if txIn.Sequence != wire.MaxTxInSequenceNum {
+			d.LockTime.Enforced = lockTime != 0
+		}
+		if msgTx.Version >= 2 {
+			if lock, ok := locktime.DecodeSequence(txIn.Sequence); ok {
+				input.RelativeLock = lock.String()
+			}
+		}
+
+		var prevOut *wire.TxOut
+		if fetcher != nil && !d.Coinbase {
+			prevOut = fetcher.FetchPrevOutput(op)
+		}
+		if prevOut == nil {
+			complete = false
+		} else {
+			var err error
+			input.PrevOut, err = newOutput(op.Index, prevOut, net)
+			if err != nil {
+				return nil, err
+			}
+			in += omcValue(prevOut)
+		}
+		d.Inputs = append(d.Inputs, input)
+	}
+
+	for i, txOut := range msgTx.TxOut {
+		output, err := newOutput(uint32(i), txOut, net)
+		if err != nil {
+			return nil, err
+		}
+		out += omcValue(txOut)
+		d.Outputs = append(d.Outputs, output)
+	}
+	if complete {
+		fee := in - out
+		d.Fee = &fee
+	}
+	return d, nil
+}
+
+// JSON returns the summary encoded as indented JSON.
+func (d *Tx) JSON() ([]byte, error) {
+	return json.MarshalIndent(d, "", "  ")
+}
@@ -0,0 +1,287 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package txdecode_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/locktime"
+	"github.com/zeusyf/btcutil/scriptclass"
+	"github.com/zeusyf/btcutil/signing"
+	"github.com/zeusyf/btcutil/txdecode"
+	"github.com/zeusyf/omega/token"
+)
+
+// testOutput returns an output of amount OMC paying to a pay-to-pubkey-hash
+// address of a hash filled with b, and the address.
+func testOutput(t *testing.T, b byte, amount int64) (*wire.TxOut, btcutil.Address) {
+	t.Helper()
+	addr, err := btcutil.NewAddressPubKeyHash(bytes.Repeat([]byte{b}, 20),
+		&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("NewAddressPubKeyHash: unexpected error: %v", err)
+	}
+	pkScript, err := scriptclass.PayToAddrScript(addr)
+	if err != nil {
+		t.Fatalf("PayToAddrScript: unexpected error: %v", err)
+	}
+	return &wire.TxOut{
+		Token:    token.Token{Value: &token.NumeralVal{Val: amount}},
+		PkScript: pkScript,
+	}, addr
+}
+
+// TestDecode ensures transactions decode into summaries of their inputs,
+// outputs and lock time, with the spent outputs and the fee when known.
+func TestDecode(t *testing.T) {
+	ops := []wire.OutPoint{
+		{Hash: chainhash.Hash{1}, Index: 0},
+		{Hash: chainhash.Hash{2}, Index: 7},
+	}
+	relative := locktime.RelativeBlocks(10)
+
+	msgTx := wire.NewMsgTx(2)
+	msgTx.LockTime = 500
+	msgTx.AddTxIn(&wire.TxIn{PreviousOutPoint: ops[0],
+		Sequence: relative.Sequence()})
+	msgTx.AddTxIn(&wire.TxIn{PreviousOutPoint: ops[1],
+		Sequence: wire.MaxTxInSequenceNum, SignatureIndex: 1})
+	payment, addr := testOutput(t, 0x11, 1e8)
+	msgTx.AddTxOut(payment)
+	msgTx.AddTxOut(&wire.TxOut{
+		Token: token.Token{
+			TokenType: 3,
+			Value:     &token.HashVal{Hash: chainhash.Hash{9}},
+			Rights:    &chainhash.Hash{5},
+		},
+		PkScript: payment.PkScript,
+	})
+	tx := btcutil.NewTx(msgTx)
+
+	d, err := txdecode.Decode(tx, &chaincfg.MainNetParams, nil)
+	if err != nil {
+		t.Fatalf("Decode: unexpected error: %v", err)
+	}
+	if d.TxID != tx.Hash().String() || d.Version != 2 ||
+		d.Size != msgTx.SerializeSize() || d.Coinbase {
+
+		t.Errorf("Decode: got summary %+v", d)
+	}
+	wantLockTime := txdecode.LockTime{
+		Value:       500,
+		Kind:        txdecode.LockTimeHeight,
+		Description: locktime.LockTime(500).String(),
+		Enforced:    true,
+	}
+	if d.LockTime != wantLockTime {
+		t.Errorf("Decode: got lock time %+v, want %+v", d.LockTime,
+			wantLockTime)
+	}
+	if d.Fee != nil {
+		t.Errorf("Decode: got fee %v without spent outputs", *d.Fee)
+	}
+
+	if len(d.Inputs) != 2 {
+		t.Fatalf("Decode: got %d inputs, want 2", len(d.Inputs))
+	}
+	for i, in := range d.Inputs {
+		if in.TxID != ops[i].Hash.String() || in.Vout != ops[i].Index ||
+			in.SignatureIndex != uint32(i) || in.PrevOut != nil {
+
+			t.Errorf("Decode: got input %d %+v", i, in)
+		}
+	}
+	if d.Inputs[0].RelativeLock != relative.String() ||
+		d.Inputs[1].RelativeLock != "" {
+
+		t.Errorf("Decode: got relative locks %q and %q",
+			d.Inputs[0].RelativeLock, d.Inputs[1].RelativeLock)
+	}
+
+	if len(d.Outputs) != 2 {
+		t.Fatalf("Decode: got %d outputs, want 2", len(d.Outputs))
+	}
+	out := d.Outputs[0]
+	wantType := scriptclass.Classify(payment.PkScript).String()
+	if out.N != 0 || out.TokenType != 0 || out.Value == nil ||
+		*out.Value != 1e8 || out.Amount != btcutil.Amount(1e8).String() ||
+		out.Hash != "" || out.Type != wantType ||
+		out.PkScript != hex.EncodeToString(payment.PkScript) ||
+		len(out.Address) != 1 || out.Address[0] != addr.EncodeAddress() {
+
+		t.Errorf("Decode: got output 0 %+v", out)
+	}
+	out = d.Outputs[1]
+	if out.N != 1 || out.TokenType != 3 || out.Value != nil ||
+		out.Amount != "" || out.Hash != (chainhash.Hash{9}).String() ||
+		out.Rights != (chainhash.Hash{5}).String() {
+
+		t.Errorf("Decode: got output 1 %+v", out)
+	}
+
+	// With the spent outputs known, inputs list them and the fee is the
+	// OMC not paid out.
+	prev0, _ := testOutput(t, 0x22, 6e7)
+	prev1, _ := testOutput(t, 0x33, 5e7)
+	fetcher := signing.MapPrevOutFetcher{ops[0]: prev0, ops[1]: prev1}
+	d, err = txdecode.Decode(tx, &chaincfg.MainNetParams, fetcher)
+	if err != nil {
+		t.Fatalf("Decode: unexpected error: %v", err)
+	}
+	if d.Fee == nil || *d.Fee != 1e7 {
+		t.Errorf("Decode: got fee %v, want %v", d.Fee, btcutil.Amount(1e7))
+	}
+	if p := d.Inputs[1].PrevOut; p == nil || p.N != 7 || *p.Value != 5e7 {
+		t.Errorf("Decode: got spent output %+v", p)
+	}
+
+	// A single unknown spent output leaves the fee unknown.
+	delete(fetcher, ops[1])
+	d, err = txdecode.Decode(tx, &chaincfg.MainNetParams, fetcher)
+	if err != nil {
+		t.Fatalf("Decode: unexpected error: %v", err)
+	}
+	if d.Fee != nil || d.Inputs[0].PrevOut == nil {
+		t.Errorf("Decode: got fee %v with an unknown spent output", d.Fee)
+	}
+}
+
+// TestDecodeLockTime ensures lock times decode by kind, and are enforced only
+// when an input has a sequence number below the maximum.
+func TestDecodeLockTime(t *testing.T) {
+	tests := []struct {
+		lockTime uint32
+		sequence uint32
+		kind     string
+		enforced bool
+	}{
+		{0, 0, txdecode.LockTimeNone, false},
+		{499999999, 0, txdecode.LockTimeHeight, true},
+		{500000000, 0, txdecode.LockTimeTime, true},
+		{500000000, wire.MaxTxInSequenceNum, txdecode.LockTimeTime, false},
+	}
+	for _, test := range tests {
+		msgTx := wire.NewMsgTx(1)
+		msgTx.LockTime = test.lockTime
+		msgTx.AddTxIn(&wire.TxIn{
+			PreviousOutPoint: wire.OutPoint{Hash: chainhash.Hash{1}},
+			Sequence:         test.sequence,
+		})
+		d, err := txdecode.Decode(btcutil.NewTx(msgTx),
+			&chaincfg.MainNetParams, nil)
+		if err != nil {
+			t.Fatalf("Decode: unexpected error: %v", err)
+		}
+		if d.LockTime.Kind != test.kind || d.LockTime.Enforced != test.enforced {
+			t.Errorf("lock time %d: got %+v", test.lockTime, d.LockTime)
+		}
+
+		// Relative lock times only apply from version 2.
+		if d.Inputs[0].RelativeLock != "" {
+			t.Errorf("lock time %d: got relative lock %q in version 1",
+				test.lockTime, d.Inputs[0].RelativeLock)
+		}
+	}
+}
+
+// TestDecodeJSON ensures summaries encode to JSON with the field names of
+// decoderawtransaction, and coinbase transactions have no fee.
+func TestDecodeJSON(t *testing.T) {
+	msgTx := wire.NewMsgTx(1)
+	msgTx.AddTxIn(&wire.TxIn{
+		PreviousOutPoint: wire.OutPoint{Index: wire.MaxPrevOutIndex},
+		Sequence:         wire.MaxTxInSequenceNum,
+	})
+	out, _ := testOutput(t, 0x11, 5e8)
+	msgTx.AddTxOut(out)
+	fetcher := signing.NewCannedPrevOutputFetcher(out.PkScript, out.Token)
+
+	d, err := txdecode.Decode(btcutil.NewTx(msgTx), &chaincfg.MainNetParams,
+		fetcher)
+	if err != nil {
+		t.Fatalf("Decode: unexpected error: %v", err)
+	}
+	if !d.Coinbase || d.Fee != nil || d.Inputs[0].PrevOut != nil {
+		t.Errorf("Decode: got coinbase summary %+v", d)
+	}
+
+	b, err := d.JSON()
+	if err != nil {
+		t.Fatalf("JSON: unexpected error: %v", err)
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(b, &fields); err != nil {
+		t.Fatalf("Unmarshal: unexpected error: %v", err)
+	}
+	for _, name := range []string{"txid", "version", "size", "locktime",
+		"coinbase", "vin", "vout"} {
+
+		if _, ok := fields[name]; !ok {
+			t.Errorf("JSON: field %q missing from %s", name, b)
+		}
+	}
+	if _, ok := fields["fee"]; ok {
+		t.Errorf("JSON: got fee of coinbase transaction in %s", b)
+	}
+
+	var decoded txdecode.Tx
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatalf("Unmarshal: unexpected error: %v", err)
+	}
+	if decoded.TxID != d.TxID || len(decoded.Outputs) != 1 ||
+		*decoded.Outputs[0].Value != 5e8 {
+
+		t.Errorf("Unmarshal: got %+v", decoded)
+	}
+}
+
+// TestTxJSONEncoding pins the JSON of a summary, so amounts such as the fee
+// keep encoding as integers in hao like the values of outputs.
+func TestTxJSONEncoding(t *testing.T) {
+	value := int64(5e8)
+	fee := btcutil.Amount(1e7)
+	d := &txdecode.Tx{
+		TxID:     "00",
+		Version:  1,
+		Size:     10,
+		LockTime: txdecode.LockTime{Kind: txdecode.LockTimeNone},
+		Inputs:   []*txdecode.Input{{TxID: "01", Sequence: 1}},
+		Outputs: []*txdecode.Output{{
+			Value:    &value,
+			Amount:   btcutil.Amount(value).String(),
+			PkScript: "51",
+			Type:     "nonstandard",
+		}},
+		Fee: &fee,
+	}
+	b, err := json.Marshal(d)
+	if err != nil {
+		t.Fatalf("Marshal: unexpected error: %v", err)
+	}
+	want := `{"txid":"00","version":1,"size":10,` +
+		`"locktime":{"value":0,"kind":"none","enforced":false},` +
+		`"vin":[{"txid":"01","vout":0,"signature_index":0,"sequence":1}],` +
+		`"vout":[{"n":0,"token_type":0,"value":500000000,` +
+		`"amount":"` + btcutil.Amount(value).String() + `",` +
+		`"pkscript":"51","type":"nonstandard"}],"fee":10000000}`
+	if string(b) != want {
+		t.Errorf("Marshal: got %s, want %s", b, want)
+	}
+
+	var decoded txdecode.Tx
+	if err := json.Unmarshal(b, &decoded); err != nil {
+		t.Fatalf("Unmarshal: unexpected error: %v", err)
+	}
+	if decoded.Fee == nil || *decoded.Fee != fee {
+		t.Errorf("Unmarshal: got fee %v, want %v", decoded.Fee, fee)
+	}
+}
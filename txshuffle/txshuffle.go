@@ -0,0 +1,108 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package txshuffle randomizes the order of transaction outputs, as an
+// alternative to the BIP 69 sorting of package txsort.
+//
+// Sorting outputs hides which one is the change only as long as every wallet
+// sorts them; a uniformly random order looks the same whichever wallet made
+// it.  The shuffle reads its randomness from a caller-provided source, the
+// default source of package entropy when none is passed, or a SeededSource
+// for shuffles which must be reproducible, such as in tests.
+package txshuffle
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"io"
+
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil/entropy"
+)
+
+// InPlaceShuffle shuffles the outputs of the passed transaction into a
+// uniformly random order read from src, or from the default source of
+// package entropy when src is nil.  It returns the permutation applied: the
+// i-th output of the shuffled transaction is the perm[i]-th output of the
+// original one.  The outputs are left unchanged when an error is returned.
+//
+// WARNING: As with txsort.InPlaceSort, this function must NOT be called with
+// published transactions, whose hash it changes.
+func InPlaceShuffle(tx *wire.MsgTx, src io.Reader) ([]int, error) {
+	perm, err := Perm(len(tx.TxOut), src)
+	if err != nil {
+		return nil, err
+	}
+	outs := make([]*wire.TxOut, len(tx.TxOut))
+	for i, j := range perm {
+		outs[i] = tx.TxOut[j]
+	}
+	copy(tx.TxOut, outs)
+	return perm, nil
+}
+
+// Shuffle returns a copy of the passed transaction with its outputs shuffled
+// as by InPlaceShuffle, along with the permutation applied.  The passed
+// transaction is not modified.
+func Shuffle(tx *wire.MsgTx, src io.Reader) (*wire.MsgTx, []int, error) {
+	txCopy := tx.Copy()
+	perm, err := InPlaceShuffle(txCopy, src)
+	if err != nil {
+		return nil, nil, err
+	}
+	return txCopy, perm, nil
+}
+
+// Perm returns a uniformly random permutation of [0, n) read from src, or
+// from the default source of package entropy when src is nil.
+func Perm(n int, src io.Reader) ([]int, error) {
+	perm := make([]int, n)
+	for i := range perm {
+		perm[i] = i
+	}
+	// Fisher-Yates, swapping each position with one not yet visited.
+	for i := n - 1; i > 0; i-- {
+		j, err := entropy.Intn(src, i+1)
+		if err != nil {
+			return nil, err
+		}
+		perm[i], perm[j] = perm[j], perm[i]
+	}
+	return perm, nil
+}
+
+// SeededSource is a deterministic source of random bytes, the SHA256 hashes
+// of its seed followed by a 64-bit big endian counter starting at zero.  The
+// same seed always gives the same shuffles, which makes it suitable for
+// tests, but it offers no privacy when the seed is known or guessable.
+type SeededSource struct {
+	seed    [sha256.Size]byte
+	counter uint64
+	buf     []byte
+}
+
+// NewSeededSource returns a deterministic source of random bytes derived from
+// seed.
+func NewSeededSource(seed []byte) *SeededSource {
+	return &SeededSource{seed: sha256.Sum256(seed)}
+}
+
+// Read fills p with the next bytes of the source.  It never fails.
+func (s *SeededSource) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if len(s.buf) == 0 {
+			var block [sha256.Size + 8]byte
+			copy(block[:], s.seed[:])
+			binary.BigEndian.PutUint64(block[sha256.Size:], s.counter)
+			s.counter++
+			h := sha256.Sum256(block[:])
+			s.buf = h[:]
+		}
+		c := copy(p[n:], s.buf)
+		s.buf = s.buf[c:]
+		n += c
+	}
+	return n, nil
+}
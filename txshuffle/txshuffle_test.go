@@ -0,0 +1,148 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package txshuffle_test
+
+import (
+	"crypto/sha256"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil/txshuffle"
+	"github.com/zeusyf/omega/token"
+)
+
+// testTx returns a transaction with n outputs, the i-th paying i.
+func testTx(n int) *wire.MsgTx {
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(&wire.TxIn{})
+	for i := 0; i < n; i++ {
+		tx.AddTxOut(&wire.TxOut{
+			Token:    token.Token{Value: &token.NumeralVal{Val: int64(i)}},
+			PkScript: []byte{0x51},
+		})
+	}
+	return tx
+}
+
+// values returns the values paid by the outputs of tx.
+func values(tx *wire.MsgTx) []int {
+	vals := make([]int, len(tx.TxOut))
+	for i, out := range tx.TxOut {
+		vals[i] = int(out.Token.Value.(*token.NumeralVal).Val)
+	}
+	return vals
+}
+
+// TestSeededSource ensures the seeded source is the stream of hashes of its
+// seed and counter, however it is read.
+func TestSeededSource(t *testing.T) {
+	seed := sha256.Sum256([]byte("seed"))
+	var want []byte
+	for i := byte(0); i < 3; i++ {
+		block := append(seed[:], 0, 0, 0, 0, 0, 0, 0, i)
+		h := sha256.Sum256(block)
+		want = append(want, h[:]...)
+	}
+
+	src := txshuffle.NewSeededSource([]byte("seed"))
+	var got []byte
+	for _, n := range []int{1, 40, 7, 48} {
+		b := make([]byte, n)
+		if _, err := src.Read(b); err != nil {
+			t.Fatalf("Read: unexpected error: %v", err)
+		}
+		got = append(got, b...)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Read: got %x, want %x", got, want)
+	}
+}
+
+// TestShuffle ensures shuffles with the same seed are the same, that the
+// permutation returned is the one applied, and that Shuffle leaves the
+// passed transaction untouched.
+func TestShuffle(t *testing.T) {
+	tx := testTx(10)
+	shuffled, perm, err := txshuffle.Shuffle(tx,
+		txshuffle.NewSeededSource([]byte("seed")))
+	if err != nil {
+		t.Fatalf("Shuffle: unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(values(tx), []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}) {
+		t.Fatalf("Shuffle: modified the passed transaction")
+	}
+	if !reflect.DeepEqual(values(shuffled), perm) {
+		t.Fatalf("Shuffle: got outputs %v, permutation %v",
+			values(shuffled), perm)
+	}
+
+	again := testTx(10)
+	perm2, err := txshuffle.InPlaceShuffle(again,
+		txshuffle.NewSeededSource([]byte("seed")))
+	if err != nil {
+		t.Fatalf("InPlaceShuffle: unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(perm, perm2) ||
+		!reflect.DeepEqual(values(again), values(shuffled)) {
+		t.Errorf("InPlaceShuffle: got %v, want %v", perm2, perm)
+	}
+
+	other, err := txshuffle.Perm(10, txshuffle.NewSeededSource([]byte("other")))
+	if err != nil {
+		t.Fatalf("Perm: unexpected error: %v", err)
+	}
+	if reflect.DeepEqual(other, perm) {
+		t.Errorf("Perm: different seeds gave the same permutation %v", perm)
+	}
+
+	// The default source is used without one.
+	if _, err := txshuffle.InPlaceShuffle(testTx(3), nil); err != nil {
+		t.Errorf("InPlaceShuffle: unexpected error: %v", err)
+	}
+}
+
+// TestPermUniform ensures every permutation of three elements is drawn.
+func TestPermUniform(t *testing.T) {
+	src := txshuffle.NewSeededSource([]byte("uniform"))
+	counts := make(map[[3]int]int)
+	for i := 0; i < 6000; i++ {
+		perm, err := txshuffle.Perm(3, src)
+		if err != nil {
+			t.Fatalf("Perm: unexpected error: %v", err)
+		}
+		counts[[3]int{perm[0], perm[1], perm[2]}]++
+	}
+	if len(counts) != 6 {
+		t.Fatalf("Perm: drew %d permutations, want 6", len(counts))
+	}
+	for perm, n := range counts {
+		if n < 800 || n > 1200 {
+			t.Errorf("Perm: drew %v %d times in 6000", perm, n)
+		}
+	}
+}
+
+// failingReader fails every read.
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) {
+	return 0, errors.New("no entropy")
+}
+
+// TestShuffleError ensures a failing source leaves the outputs unchanged.
+func TestShuffleError(t *testing.T) {
+	tx := testTx(4)
+	if _, err := txshuffle.InPlaceShuffle(tx, failingReader{}); err == nil {
+		t.Fatalf("InPlaceShuffle: expected an error")
+	}
+	if !reflect.DeepEqual(values(tx), []int{0, 1, 2, 3}) {
+		t.Errorf("InPlaceShuffle: got outputs %v after an error", values(tx))
+	}
+	if _, _, err := txshuffle.Shuffle(tx, failingReader{}); err == nil {
+		t.Errorf("Shuffle: expected an error")
+	}
+}
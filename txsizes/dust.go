@@ -0,0 +1,66 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package txsizes
+
+import (
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcd/wire/common"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/scriptclass"
+	"github.com/zeusyf/btcutil/signing"
+	"github.com/zeusyf/omega/token"
+)
+
+const (
+	// DustFactor is how many times the fee of spending it an output must
+	// be worth for it not to be dust.  An output worth less than that
+	// costs more than a third of its value to spend, making it
+	// uneconomical, and relay policy rejects transactions creating it.
+	DustFactor = 3
+
+	// redeemP2PKHInputSize is the worst case size of an input spending a
+	// pay-to-pubkey-hash output, which is assumed for the dust limit of
+	// every script without a witness program.
+	redeemP2PKHInputSize = outPointSize + 1 + 1 + signing.HighRSigLen +
+		1 + compressedPubKeySize + sequenceSize
+
+	// redeemWitnessInputSize is the virtual size assumed for an input
+	// spending a witness program.  It is the size of a pay-to-pubkey-hash
+	// input with the signature script moved to the discounted witness.
+	redeemWitnessInputSize = outPointSize + 1 + sequenceSize +
+		(1+signing.HighRSigLen+1+compressedPubKeySize)/WitnessScaleFactor
+)
+
+// DustLimitForScript returns the smallest amount an output paying pkScript
+// may carry without being dust when transactions are relayed at
+// relayFeeRate.  The limit is DustFactor times the fee for both creating and
+// later spending the output, with inputs spending witness programs getting
+// the witness discount.  Provably unspendable data carrier outputs have no
+// dust limit.
+func DustLimitForScript(pkScript []byte, relayFeeRate btcutil.FeeRate) btcutil.Amount {
+	if scriptclass.Classify(pkScript) == scriptclass.NullDataTy {
+		return 0
+	}
+
+	size := valueSize + common.VarIntSerializeSize(uint64(len(pkScript))) +
+		len(pkScript)
+	if _, _, ok := scriptclass.ExtractWitnessProgram(pkScript); ok {
+		size += redeemWitnessInputSize
+	} else {
+		size += redeemP2PKHInputSize
+	}
+	return DustFactor * relayFeeRate.FeeForVSize(int64(size))
+}
+
+// IsDust returns whether the output carries less than the dust limit of its
+// script at relayFeeRate.  Only outputs of numeric tokens can be dust.
+func IsDust(output *wire.TxOut, relayFeeRate btcutil.FeeRate) bool {
+	value, ok := output.Token.Value.(*token.NumeralVal)
+	if !ok {
+		return false
+	}
+	limit := DustLimitForScript(output.PkScript, relayFeeRate)
+	return btcutil.Amount(value.Val) < limit
+}
@@ -0,0 +1,72 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package txsizes_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/txsizes"
+	"github.com/zeusyf/omega/token"
+)
+
+// TestDustLimitForScript ensures the dust limits of the standard scripts
+// match the limits enforced by relay policy at the default relay fee rate.
+func TestDustLimitForScript(t *testing.T) {
+	hash20 := bytes.Repeat([]byte{0x01}, 20)
+	hash32 := bytes.Repeat([]byte{0x01}, 32)
+	p2pkh := append(append([]byte{0x76, 0xa9, 0x14}, hash20...), 0x88, 0xac)
+
+	tests := []struct {
+		name   string
+		script []byte
+		rate   btcutil.FeeRate
+		want   btcutil.Amount
+	}{
+		{"p2pkh", p2pkh, 1000, 546},
+		{"p2pkh at higher rate", p2pkh, 3000, 1638},
+		{"p2pkh at zero rate", p2pkh, 0, 0},
+		{"p2sh", append(append([]byte{0xa9, 0x14}, hash20...), 0x87), 1000, 540},
+		{"p2wpkh", append([]byte{0x00, 0x14}, hash20...), 1000, 294},
+		{"p2wsh", append([]byte{0x00, 0x20}, hash32...), 1000, 330},
+		{"p2tr", append([]byte{0x51, 0x20}, hash32...), 1000, 330},
+		{"null data", []byte{0x6a, 0x01, 0x00}, 1000, 0},
+	}
+
+	for _, test := range tests {
+		got := txsizes.DustLimitForScript(test.script, test.rate)
+		if got != test.want {
+			t.Errorf("DustLimitForScript(%s): got %d, want %d",
+				test.name, got, test.want)
+		}
+	}
+}
+
+// TestIsDust ensures outputs are dust exactly when their value is below the
+// dust limit of their script.
+func TestIsDust(t *testing.T) {
+	script := append([]byte{0x00, 0x14}, bytes.Repeat([]byte{0x01}, 20)...)
+	output := func(value int64) *wire.TxOut {
+		return &wire.TxOut{
+			Token: token.Token{
+				TokenType: 0,
+				Value:     &token.NumeralVal{Val: value},
+			},
+			PkScript: script,
+		}
+	}
+
+	if !txsizes.IsDust(output(293), 1000) {
+		t.Errorf("IsDust: 293 Hao output is not dust")
+	}
+	if txsizes.IsDust(output(294), 1000) {
+		t.Errorf("IsDust: 294 Hao output is dust")
+	}
+	if !txsizes.IsDust(output(0), 1) {
+		t.Errorf("IsDust: zero output is not dust")
+	}
+}
@@ -0,0 +1,294 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package txsizes estimates the serialized size, weight, and virtual size of
+// Omega transactions before they are signed, so fees can be calculated while
+// the inputs and outputs are still being selected.
+//
+// The estimates follow the wire format of Omega transactions rather than the
+// one of Bitcoin.  Inputs carry the index of their signature script instead of
+// the script itself, outputs carry a token instead of an amount, and the
+// signature scripts of all inputs follow the lock time.  There is no witness:
+// the signature data of every input type, including those spending witness
+// programs, is carried by its signature script, and every byte of the
+// transaction weighs the same.
+//
+// Estimates assume the worst case signature size, so a signed transaction is
+// never larger than estimated.  Signers which grind for low R values, such as
+// those created with signing.WithLowR, may set Estimator.LowR to tighten the
+// estimate by one byte per signature.
+package txsizes
+
+import (
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcd/wire/common"
+	"github.com/zeusyf/btcutil/signing"
+	"github.com/zeusyf/omega/token"
+)
+
+// WitnessScaleFactor is the weight of a byte of non-witness data relative to
+// a byte of witness data.  Omega transactions have no witness data, so their
+// weight is their size times this factor.
+const WitnessScaleFactor = 4
+
+// Sizes of the standard public key scripts.
+const (
+	// P2PKHPkScriptSize is the size of an Omega pay-to-pubkey-hash script:
+	// <pubkey hash ID> <20 bytes> OP_PAY2PKH
+	P2PKHPkScriptSize = 1 + 20 + 1
+
+	// P2SHPkScriptSize is the size of an Omega pay-to-script-hash script:
+	// <script hash ID> <20 bytes> OP_PAY2SCRIPTH
+	P2SHPkScriptSize = 1 + 20 + 1
+
+	// P2WPKHPkScriptSize is the size of a pay-to-witness-pubkey-hash
+	// script: OP_0 OP_DATA_20 <20 bytes>
+	P2WPKHPkScriptSize = 1 + 1 + 20
+
+	// P2WSHPkScriptSize is the size of a pay-to-witness-script-hash
+	// script: OP_0 OP_DATA_32 <32 bytes>
+	P2WSHPkScriptSize = 1 + 1 + 32
+
+	// P2TRPkScriptSize is the size of a pay-to-taproot script:
+	// OP_1 OP_DATA_32 <32 bytes>
+	P2TRPkScriptSize = 1 + 1 + 32
+)
+
+const (
+	// compressedPubKeySize is the size of a serialized compressed public
+	// key.
+	compressedPubKeySize = 33
+
+	// uncompressedPubKeySize is the size of a serialized uncompressed
+	// public key.
+	uncompressedPubKeySize = 65
+
+	// schnorrSigSize is the size of a taproot key path signature using
+	// the default signature hash type, which is not serialized.
+	schnorrSigSize = signing.SchnorrSigSize
+
+	// txOverhead is the size of the version and lock time fields, and of
+	// the count of token definitions, which transactions built by wallets
+	// have none of.
+	txOverhead = 4 + 1 + 4
+
+	// outPointSize is the size of the outpoint of an input.
+	outPointSize = 32 + 4
+
+	// sequenceSize is the size of the sequence number of an input.
+	sequenceSize = 4
+
+	// txInSize is the size of an input: its outpoint, its sequence number
+	// and the index of its signature script.
+	txInSize = outPointSize + sequenceSize + 4
+
+	// valueSize is the size of the value of a numeric token.
+	valueSize = 8
+
+	// omcTokenSize is the size of the token of an output paying OMC, a
+	// numeric token of type zero without rights.
+	omcTokenSize = 1 + valueSize
+)
+
+// ScriptType identifies the kind of script an input spends or an output pays.
+type ScriptType byte
+
+// These constants are the script types understood by the Estimator.
+const (
+	// P2PKH is a pay-to-pubkey-hash script spent with a compressed key.
+	P2PKH ScriptType = iota
+
+	// P2WPKH is a pay-to-witness-pubkey-hash script.  Its signature and
+	// public key are pushed by the signature script of the input.
+	P2WPKH
+
+	// P2SHMultiSig is a pay-to-script-hash script redeemed by a bare
+	// m-of-n multi-signature script with compressed keys.
+	P2SHMultiSig
+
+	// P2WSHMultiSig is a pay-to-witness-script-hash script redeemed by a
+	// bare m-of-n multi-signature script with compressed keys, pushed by
+	// the signature script of the input like P2SHMultiSig.
+	P2WSHMultiSig
+
+	// P2TR is a pay-to-taproot script spent through the key path.  Its
+	// signature is pushed by the signature script of the input.
+	P2TR
+
+	// P2PKHUncompressed is a pay-to-pubkey-hash script spent with an
+	// uncompressed key, as paid by old wallets.
+	P2PKHUncompressed
+)
+
+// Estimator accumulates the inputs and outputs of a transaction and reports
+// its estimated size.  The zero value is an empty transaction ready for use.
+type Estimator struct {
+	// LowR makes the estimate assume signatures with a low R value, which
+	// are one byte shorter in the worst case.
+	LowR bool
+
+	numInputs      int
+	sigScriptsSize int
+	numOutputs     int
+	outputsSize    int
+}
+
+// sigPushSize returns the size of a push of a worst case ECDSA signature,
+// including its signature hash type byte.
+func (e *Estimator) sigPushSize() int {
+	if e.LowR {
+		return 1 + signing.LowRSigLen
+	}
+	return 1 + signing.HighRSigLen
+}
+
+// MultiSigScriptSize returns the size of a bare m-of-n multi-signature script
+// with compressed public keys:
+// OP_M <OP_DATA_33 <pubkey>>... OP_N OP_CHECKMULTISIG
+func MultiSigScriptSize(n int) int {
+	return 1 + n*(1+compressedPubKeySize) + 1 + 1
+}
+
+// pushSize returns the size of a canonical push of n bytes of data.
+func pushSize(n int) int {
+	switch {
+	case n < 0x4c:
+		return 1 + n
+	case n <= 0xff:
+		return 2 + n
+	case n <= 0xffff:
+		return 3 + n
+	}
+	return 5 + n
+}
+
+// AddInputs adds count inputs spending scripts of the passed type.  The
+// multi-signature types must be added with AddMultiSigInputs instead and are
+// ignored.
+func (e *Estimator) AddInputs(t ScriptType, count int) {
+	var sigScriptSize int
+	switch t {
+	case P2PKH, P2WPKH:
+		// <sig> <pubkey>
+		sigScriptSize = e.sigPushSize() + 1 + compressedPubKeySize
+
+	case P2PKHUncompressed:
+		// <sig> <pubkey>
+		sigScriptSize = e.sigPushSize() + 1 + uncompressedPubKeySize
+
+	case P2TR:
+		// <schnorr sig>
+		sigScriptSize = 1 + schnorrSigSize
+
+	default:
+		return
+	}
+	e.addInputs(sigScriptSize, count)
+}
+
+// AddMultiSigInputs adds count inputs spending m-of-n multi-signature scripts
+// of the passed type, which must be P2SHMultiSig or P2WSHMultiSig.  Other
+// types are ignored.
+func (e *Estimator) AddMultiSigInputs(t ScriptType, m, n, count int) {
+	switch t {
+	case P2SHMultiSig, P2WSHMultiSig:
+		// OP_0 <sig>... <redeem script>
+		sigScriptSize := 1 + m*e.sigPushSize() +
+			pushSize(MultiSigScriptSize(n))
+		e.addInputs(sigScriptSize, count)
+	}
+}
+
+// addInputs adds count inputs with signature scripts of sigScriptSize bytes.
+func (e *Estimator) addInputs(sigScriptSize, count int) {
+	e.numInputs += count
+	e.sigScriptsSize += count * (common.VarIntSerializeSize(
+		uint64(sigScriptSize)) + sigScriptSize)
+}
+
+// AddOutputs adds count outputs paying OMC to scripts of the passed type.
+// Outputs of the multi-signature types pay the script hash of the redeem
+// script.
+func (e *Estimator) AddOutputs(t ScriptType, count int) {
+	var size int
+	switch t {
+	case P2PKH, P2PKHUncompressed:
+		size = P2PKHPkScriptSize
+	case P2WPKH:
+		size = P2WPKHPkScriptSize
+	case P2SHMultiSig:
+		size = P2SHPkScriptSize
+	case P2WSHMultiSig:
+		size = P2WSHPkScriptSize
+	case P2TR:
+		size = P2TRPkScriptSize
+	default:
+		return
+	}
+	e.addOutputs(omcTokenSize, size, count)
+}
+
+// AddOutputScript adds an output paying OMC to the passed script, which may
+// be of any type.
+func (e *Estimator) AddOutputScript(pkScript []byte) {
+	e.addOutputs(omcTokenSize, len(pkScript), 1)
+}
+
+// AddTxOut adds the passed output, which may carry any token.
+func (e *Estimator) AddTxOut(out *wire.TxOut) {
+	e.addOutputs(tokenSize(&out.Token), len(out.PkScript), 1)
+}
+
+// tokenSize returns the serialized size of tok: its type, followed by a
+// value for numeric tokens or a hash for the others, and by the hash of its
+// rights when its type has them.
+func tokenSize(tok *token.Token) int {
+	size := common.VarIntSerializeSize(tok.TokenType)
+	if tok.TokenType&1 == 0 {
+		size += valueSize
+	} else {
+		size += 32
+	}
+	if tok.TokenType&2 != 0 {
+		size += 32
+	}
+	return size
+}
+
+// addOutputs adds count outputs with tokens of tokenSize bytes and public key
+// scripts of scriptSize bytes.
+func (e *Estimator) addOutputs(tokenSize, scriptSize, count int) {
+	e.numOutputs += count
+	e.outputsSize += count * (tokenSize +
+		common.VarIntSerializeSize(uint64(scriptSize)) + scriptSize)
+}
+
+// BaseSize returns the estimated size of the transaction serialized without
+// its signature scripts.
+func (e *Estimator) BaseSize() int {
+	return txOverhead + common.VarIntSerializeSize(uint64(e.numInputs)) +
+		e.numInputs*txInSize +
+		common.VarIntSerializeSize(uint64(e.numOutputs)) + e.outputsSize
+}
+
+// TotalSize returns the estimated size of the signed transaction: its base
+// size followed by the signature script of every input.
+func (e *Estimator) TotalSize() int {
+	return e.BaseSize() + common.VarIntSerializeSize(uint64(e.numInputs)) +
+		e.sigScriptsSize
+}
+
+// Weight returns the estimated weight of the transaction.  Since Omega
+// transactions have no witness data, it is the total size times
+// WitnessScaleFactor.
+func (e *Estimator) Weight() int {
+	return e.TotalSize() * WitnessScaleFactor
+}
+
+// VSize returns the estimated virtual size of the transaction, which is the
+// size fees are paid for.  It is the total size, as no byte of an Omega
+// transaction is discounted.
+func (e *Estimator) VSize() int {
+	return e.TotalSize()
+}
@@ -0,0 +1,239 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package txsizes_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcd/txscript"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/scriptclass"
+	"github.com/zeusyf/btcutil/signing"
+	"github.com/zeusyf/btcutil/txsizes"
+	"github.com/zeusyf/omega/token"
+)
+
+// TestEstimator ensures the estimates for common transaction shapes match
+// their well known sizes.
+func TestEstimator(t *testing.T) {
+	tests := []struct {
+		name   string
+		build  func(*txsizes.Estimator)
+		base   int
+		total  int
+		weight int
+		vsize  int
+	}{
+		{
+			name: "empty",
+			build: func(e *txsizes.Estimator) {
+			},
+			base:   11,
+			total:  12,
+			weight: 48,
+			vsize:  12,
+		},
+		{
+			name: "1 p2pkh in, 2 p2pkh out",
+			build: func(e *txsizes.Estimator) {
+				e.AddInputs(txsizes.P2PKH, 1)
+				e.AddOutputs(txsizes.P2PKH, 2)
+			},
+			base:   119,
+			total:  228,
+			weight: 912,
+			vsize:  228,
+		},
+		{
+			name: "1 uncompressed p2pkh in, 1 p2pkh out",
+			build: func(e *txsizes.Estimator) {
+				e.AddInputs(txsizes.P2PKHUncompressed, 1)
+				e.AddOutputs(txsizes.P2PKHUncompressed, 1)
+			},
+			base:   87,
+			total:  228,
+			weight: 912,
+			vsize:  228,
+		},
+		{
+			name: "1 p2wpkh in, 1 p2wpkh out",
+			build: func(e *txsizes.Estimator) {
+				e.AddInputs(txsizes.P2WPKH, 1)
+				e.AddOutputs(txsizes.P2WPKH, 1)
+			},
+			base:   87,
+			total:  196,
+			weight: 784,
+			vsize:  196,
+		},
+		{
+			name: "1 p2tr in, 1 p2tr out",
+			build: func(e *txsizes.Estimator) {
+				e.AddInputs(txsizes.P2TR, 1)
+				e.AddOutputs(txsizes.P2TR, 1)
+			},
+			base:   99,
+			total:  166,
+			weight: 664,
+			vsize:  166,
+		},
+		{
+			name: "2-of-3 p2sh in, p2sh out",
+			build: func(e *txsizes.Estimator) {
+				e.AddMultiSigInputs(txsizes.P2SHMultiSig, 2, 3, 1)
+				e.AddOutputs(txsizes.P2SHMultiSig, 1)
+			},
+			base:   87,
+			total:  342,
+			weight: 1368,
+			vsize:  342,
+		},
+		{
+			name: "mixed p2pkh and p2wpkh in, custom script out",
+			build: func(e *txsizes.Estimator) {
+				e.AddInputs(txsizes.P2PKH, 1)
+				e.AddInputs(txsizes.P2WPKH, 1)
+				e.AddOutputScript([]byte{0x6a, 0x01, 0x00})
+			},
+			base:   112,
+			total:  329,
+			weight: 1316,
+			vsize:  329,
+		},
+		{
+			name: "1 p2pkh in, token with rights out",
+			build: func(e *txsizes.Estimator) {
+				e.AddInputs(txsizes.P2PKH, 1)
+				e.AddTxOut(&wire.TxOut{
+					Token:    token.Token{TokenType: 3},
+					PkScript: make([]byte, txsizes.P2PKHPkScriptSize),
+				})
+			},
+			base:   143,
+			total:  252,
+			weight: 1008,
+			vsize:  252,
+		},
+	}
+
+	for _, test := range tests {
+		var e txsizes.Estimator
+		test.build(&e)
+		if got := e.BaseSize(); got != test.base {
+			t.Errorf("%s: BaseSize: got %d, want %d", test.name, got,
+				test.base)
+		}
+		if got := e.TotalSize(); got != test.total {
+			t.Errorf("%s: TotalSize: got %d, want %d", test.name, got,
+				test.total)
+		}
+		if got := e.Weight(); got != test.weight {
+			t.Errorf("%s: Weight: got %d, want %d", test.name, got,
+				test.weight)
+		}
+		if got := e.VSize(); got != test.vsize {
+			t.Errorf("%s: VSize: got %d, want %d", test.name, got,
+				test.vsize)
+		}
+	}
+}
+
+// TestEstimatorLowR ensures assuming low R signatures saves one byte per
+// signature.
+func TestEstimatorLowR(t *testing.T) {
+	var high, low txsizes.Estimator
+	low.LowR = true
+	for _, e := range []*txsizes.Estimator{&high, &low} {
+		e.AddInputs(txsizes.P2PKH, 2)
+		e.AddMultiSigInputs(txsizes.P2SHMultiSig, 2, 2, 1)
+		e.AddOutputs(txsizes.P2PKH, 1)
+	}
+	if diff := high.TotalSize() - low.TotalSize(); diff != 4 {
+		t.Fatalf("low R saved %d bytes, want 4", diff)
+	}
+}
+
+// TestEstimatorSerializeSize ensures the estimates match the serialized size
+// of signed Omega transactions.
+func TestEstimatorSerializeSize(t *testing.T) {
+	var e txsizes.Estimator
+	e.LowR = true
+	e.AddInputs(txsizes.P2PKH, 2)
+	e.AddOutputs(txsizes.P2PKH, 1)
+	tokenOut := &wire.TxOut{
+		Token: token.Token{
+			TokenType: 3,
+			Value:     &token.HashVal{Hash: chainhash.Hash{7}},
+			Rights:    &chainhash.Hash{8},
+		},
+		PkScript: bytes.Repeat([]byte{0x01}, 40),
+	}
+	e.AddTxOut(tokenOut)
+
+	tx := wire.NewMsgTx(wire.TxVersion)
+	var pkScripts [][]byte
+	var keys []*btcec.PrivateKey
+	for i := byte(1); i <= 2; i++ {
+		key, _ := btcec.PrivKeyFromBytes(btcec.S256(),
+			bytes.Repeat([]byte{i}, 32))
+		pkScript := []byte{chaincfg.MainNetParams.PubKeyHashAddrID}
+		pkScript = append(pkScript, btcutil.Hash160(
+			key.PubKey().SerializeCompressed())...)
+		pkScript = append(pkScript, scriptclass.OP_PAY2PKH)
+		tx.AddTxIn(&wire.TxIn{
+			PreviousOutPoint: wire.OutPoint{Hash: chainhash.Hash{i}},
+			Sequence:         wire.MaxTxInSequenceNum,
+			SignatureIndex:   uint32(i - 1),
+		})
+		pkScripts = append(pkScripts, pkScript)
+		keys = append(keys, key)
+	}
+	tx.AddTxOut(&wire.TxOut{
+		Token: token.Token{
+			TokenType: 0,
+			Value:     &token.NumeralVal{Val: 5000},
+		},
+		PkScript: pkScripts[0],
+	})
+	tx.AddTxOut(tokenOut)
+
+	// Signature scripts of the worst case size match the estimate exactly.
+	tx.SignatureScripts = [][]byte{make([]byte, 1+signing.LowRSigLen+1+33),
+		make([]byte, 1+signing.LowRSigLen+1+33)}
+	if got, want := tx.SerializeSize(), e.TotalSize(); got != want {
+		t.Errorf("worst case: serialized %d bytes, estimated %d", got, want)
+	}
+
+	// Signed, the transaction is at most a byte per signature smaller.
+	sigScripts := make([][]byte, len(tx.TxIn))
+	for i := range tx.TxIn {
+		sigHash, err := signing.LegacySigHash(tx, i, pkScripts[i],
+			signing.SigHashAll)
+		if err != nil {
+			t.Fatalf("LegacySigHash: unexpected error: %v", err)
+		}
+		sig, err := signing.SignECDSA(signing.NewKeySigner(keys[i],
+			signing.WithLowR()), sigHash, signing.SigHashAll)
+		if err != nil {
+			t.Fatalf("SignECDSA: unexpected error: %v", err)
+		}
+		sigScripts[i], err = txscript.NewScriptBuilder().AddData(sig).
+			AddData(keys[i].PubKey().SerializeCompressed()).Script()
+		if err != nil {
+			t.Fatalf("Script: unexpected error: %v", err)
+		}
+	}
+	tx.SignatureScripts = sigScripts
+	size := tx.SerializeSize()
+	if size > e.TotalSize() || e.TotalSize()-size > len(tx.TxIn) {
+		t.Errorf("signed: serialized %d bytes, estimated %d", size,
+			e.TotalSize())
+	}
+}
@@ -0,0 +1,178 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ur
+
+import (
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"strings"
+)
+
+var (
+	// ErrInvalidByteword describes an error where a bytewords string holds
+	// a word which is not in the word list, or is not made of whole words.
+	ErrInvalidByteword = errors.New("invalid byteword")
+
+	// ErrBytewordsChecksum describes an error where the checksum of a
+	// bytewords string does not match its data.
+	ErrBytewordsChecksum = errors.New("bytewords checksum mismatch")
+)
+
+// Style is a way of writing bytewords.
+type Style int
+
+const (
+	// StyleStandard separates whole words with spaces.
+	StyleStandard Style = iota
+
+	// StyleURI separates whole words with dashes.
+	StyleURI
+
+	// StyleMinimal writes the first and last letters of each word
+	// without separators.  Uniform resources use it.
+	StyleMinimal
+)
+
+// Map of styles back to their constant names for pretty printing.
+var styleStrings = map[Style]string{
+	StyleStandard: "StyleStandard",
+	StyleURI:      "StyleURI",
+	StyleMinimal:  "StyleMinimal",
+}
+
+// String returns the Style in human-readable form.
+func (s Style) String() string {
+	if str, ok := styleStrings[s]; ok {
+		return str
+	}
+	return "Unknown Style"
+}
+
+// bytewords is the list of the 256 bytewords, four letters each, in the
+// order of the byte they stand for.
+const bytewords = "" +
+	"ableacidalsoapexaquaarchatomaunt" +
+	"awayaxisbackbaldbarnbeltbetabias" +
+	"bluebodybragbrewbulbbuzzcalmcash" +
+	"catschefcityclawcodecolacookcost" +
+	"cruxcurlcuspcyandarkdatadaysdeli" +
+	"dicedietdoordowndrawdropdrumdull" +
+	"dutyeacheasyechoedgeepicevenexam" +
+	"exiteyesfactfairfernfigsfilmfish" +
+	"fizzflapflewfluxfoxyfreefrogfuel" +
+	"fundgalagamegeargemsgiftgirlglow" +
+	"goodgraygrimgurugushgyrohalfhang" +
+	"hardhawkheathelphighhillholyhope" +
+	"hornhutsicedideaidleinchinkyinto" +
+	"irisironitemjadejazzjoinjoltjowl" +
+	"judojugsjumpjunkjurykeepkenokept" +
+	"keyskickkilnkingkitekiwiknoblamb" +
+	"lavalazyleaflegsliarlimplionlist" +
+	"logoloudloveluaulucklungmainmany" +
+	"mathmazememomenumeowmildmintmiss" +
+	"monknailnavyneednewsnextnoonnote" +
+	"numbobeyoboeomitonyxopenovalowls" +
+	"paidpartpeckplaypluspoempoolpose" +
+	"puffpumapurrquadquizracerampreal" +
+	"redorichroadrockroofrubyruinruns" +
+	"rustsafesagascarsetssilkskewslot" +
+	"soapsolosongstubsurfswantacotask" +
+	"taxitenttiedtimetinytoiltombtoys" +
+	"triptunatwinuglyundouniturgeuser" +
+	"vastveryvetovialvibeviewvisavoid" +
+	"vowswallwandwarmwaspwavewaxywebs" +
+	"whatwhenwhizwolfworkyankyawnyell" +
+	"yogayurtzapszerozestzinczonezoom"
+
+// minimalIndex maps the first and last letters of each word to its byte.
+var minimalIndex = func() map[string]byte {
+	m := make(map[string]byte, 256)
+	for i := 0; i < 256; i++ {
+		m[minimalWord(byte(i))] = byte(i)
+	}
+	return m
+}()
+
+// word returns the byteword of b.
+func word(b byte) string {
+	return bytewords[int(b)*4 : int(b)*4+4]
+}
+
+// minimalWord returns the first and last letters of the byteword of b.
+func minimalWord(b byte) string {
+	w := word(b)
+	return w[:1] + w[3:]
+}
+
+// EncodeBytewords encodes data with its CRC32 checksum appended as bytewords
+// in the passed style.
+func EncodeBytewords(data []byte, style Style) string {
+	b := make([]byte, len(data), len(data)+4)
+	copy(b, data)
+	b = binary.BigEndian.AppendUint32(b, crc32.ChecksumIEEE(data))
+
+	var sb strings.Builder
+	for i, c := range b {
+		switch {
+		case style == StyleMinimal:
+			sb.WriteString(minimalWord(c))
+			continue
+		case i == 0:
+		case style == StyleURI:
+			sb.WriteByte('-')
+		default:
+			sb.WriteByte(' ')
+		}
+		sb.WriteString(word(c))
+	}
+	return sb.String()
+}
+
+// DecodeBytewords decodes bytewords in the passed style and verifies their
+// checksum.  Words may be in any case.
+func DecodeBytewords(s string, style Style) ([]byte, error) {
+	s = strings.ToLower(s)
+	var words []string
+	switch style {
+	case StyleMinimal:
+		if len(s)%2 != 0 {
+			return nil, ErrInvalidByteword
+		}
+		for i := 0; i < len(s); i += 2 {
+			words = append(words, s[i:i+2])
+		}
+	case StyleURI:
+		words = strings.Split(s, "-")
+	default:
+		words = strings.Split(s, " ")
+	}
+
+	b := make([]byte, 0, len(words))
+	for _, w := range words {
+		var c byte
+		var ok bool
+		switch {
+		case style == StyleMinimal:
+			c, ok = minimalIndex[w]
+		case len(w) == 4:
+			c, ok = minimalIndex[w[:1]+w[3:]]
+			ok = ok && word(c) == w
+		}
+		if !ok {
+			return nil, ErrInvalidByteword
+		}
+		b = append(b, c)
+	}
+	if len(b) < 4 {
+		return nil, ErrInvalidByteword
+	}
+
+	data, checksum := b[:len(b)-4], binary.BigEndian.Uint32(b[len(b)-4:])
+	if crc32.ChecksumIEEE(data) != checksum {
+		return nil, ErrBytewordsChecksum
+	}
+	return data, nil
+}
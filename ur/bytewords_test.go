@@ -0,0 +1,60 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ur_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/zeusyf/btcutil/ur"
+)
+
+// TestBytewords ensures data encodes to the reference bytewords in every
+// style and decodes back, and corrupted strings are rejected.
+func TestBytewords(t *testing.T) {
+	data := []byte{0, 1, 2, 128, 255}
+	tests := []struct {
+		style ur.Style
+		want  string
+	}{
+		{ur.StyleStandard, "able acid also lava zoom jade need echo taxi"},
+		{ur.StyleURI, "able-acid-also-lava-zoom-jade-need-echo-taxi"},
+		{ur.StyleMinimal, "aeadaolazmjendeoti"},
+	}
+	for _, test := range tests {
+		got := ur.EncodeBytewords(data, test.style)
+		if got != test.want {
+			t.Errorf("EncodeBytewords(%v): got %q, want %q", test.style,
+				got, test.want)
+			continue
+		}
+		decoded, err := ur.DecodeBytewords(got, test.style)
+		if err != nil || !bytes.Equal(decoded, data) {
+			t.Errorf("DecodeBytewords(%v): got %x, %v, want %x",
+				test.style, decoded, err, data)
+		}
+	}
+
+	errTests := []struct {
+		s     string
+		style ur.Style
+		err   error
+	}{
+		{"able acid also lava zoom jade need echo tied", ur.StyleStandard,
+			ur.ErrBytewordsChecksum},
+		{"able acid also lava zoom jade need echo tax", ur.StyleStandard,
+			ur.ErrInvalidByteword},
+		{"able-acid-also-lava-zoom-jade-need-echo-taxx", ur.StyleURI,
+			ur.ErrInvalidByteword},
+		{"aeadaolazmjendeotia", ur.StyleMinimal, ur.ErrInvalidByteword},
+		{"aeadaolazmjendeotd", ur.StyleMinimal, ur.ErrBytewordsChecksum},
+	}
+	for _, test := range errTests {
+		if _, err := ur.DecodeBytewords(test.s, test.style); err != test.err {
+			t.Errorf("DecodeBytewords(%q): got error %v, want %v", test.s,
+				err, test.err)
+		}
+	}
+}
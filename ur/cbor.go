@@ -0,0 +1,195 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ur
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+// ErrInvalidCBOR describes an error where the CBOR encoding of a resource is
+// malformed or does not hold the items its type requires.
+var ErrInvalidCBOR = errors.New("invalid CBOR encoding")
+
+// CBOR major types.
+const (
+	cborUint  = 0
+	cborBytes = 2
+	cborText  = 3
+	cborArray = 4
+	cborMap   = 5
+	cborTag   = 6
+	cborOther = 7
+)
+
+// CBOR simple values.
+const (
+	cborFalse = 20
+	cborTrue  = 21
+)
+
+// maxCBORDepth is the deepest nesting of items skipped by cborReader.skip.
+const maxCBORDepth = 16
+
+// appendHead appends the head of an item of the passed major type and
+// argument to b, in the shortest form.
+func appendHead(b []byte, major byte, n uint64) []byte {
+	major <<= 5
+	switch {
+	case n < 24:
+		return append(b, major|byte(n))
+	case n <= 0xff:
+		return append(b, major|24, byte(n))
+	case n <= 0xffff:
+		return binary.BigEndian.AppendUint16(append(b, major|25), uint16(n))
+	case n <= 0xffffffff:
+		return binary.BigEndian.AppendUint32(append(b, major|26), uint32(n))
+	}
+	return binary.BigEndian.AppendUint64(append(b, major|27), n)
+}
+
+// appendBytes appends a byte string item to b.
+func appendBytes(b, data []byte) []byte {
+	return append(appendHead(b, cborBytes, uint64(len(data))), data...)
+}
+
+// appendBool appends a boolean item to b.
+func appendBool(b []byte, v bool) []byte {
+	if v {
+		return appendHead(b, cborOther, cborTrue)
+	}
+	return appendHead(b, cborOther, cborFalse)
+}
+
+// cborReader reads the items of a CBOR encoding in turn.  Indefinite length
+// items are not supported.
+type cborReader struct {
+	b []byte
+}
+
+// done returns whether every item was read.
+func (r *cborReader) done() bool {
+	return len(r.b) == 0
+}
+
+// head reads the head of an item, returning its major type and argument.
+func (r *cborReader) head() (byte, uint64, error) {
+	if len(r.b) == 0 {
+		return 0, 0, ErrInvalidCBOR
+	}
+	major, info := r.b[0]>>5, r.b[0]&0x1f
+	r.b = r.b[1:]
+	if info < 24 {
+		return major, uint64(info), nil
+	}
+	if info > 27 {
+		return 0, 0, ErrInvalidCBOR
+	}
+	size := 1 << (info - 24)
+	if len(r.b) < size {
+		return 0, 0, ErrInvalidCBOR
+	}
+	var n uint64
+	for _, c := range r.b[:size] {
+		n = n<<8 | uint64(c)
+	}
+	r.b = r.b[size:]
+	return major, n, nil
+}
+
+// expect reads the head of an item of the passed major type and returns its
+// argument.
+func (r *cborReader) expect(major byte) (uint64, error) {
+	m, n, err := r.head()
+	if err != nil {
+		return 0, err
+	}
+	if m != major {
+		return 0, ErrInvalidCBOR
+	}
+	return n, nil
+}
+
+// uint reads an unsigned integer item no greater than max.
+func (r *cborReader) uint(max uint64) (uint64, error) {
+	n, err := r.expect(cborUint)
+	if err != nil {
+		return 0, err
+	}
+	if n > max {
+		return 0, ErrInvalidCBOR
+	}
+	return n, nil
+}
+
+// bytes reads a byte string item.  The returned slice shares the memory of
+// the encoding.
+func (r *cborReader) bytes() ([]byte, error) {
+	n, err := r.expect(cborBytes)
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(r.b)) < n {
+		return nil, ErrInvalidCBOR
+	}
+	data := r.b[:n]
+	r.b = r.b[n:]
+	return data, nil
+}
+
+// bool reads a boolean item.
+func (r *cborReader) bool() (bool, error) {
+	n, err := r.expect(cborOther)
+	if err != nil {
+		return false, err
+	}
+	switch n {
+	case cborTrue:
+		return true, nil
+	case cborFalse:
+		return false, nil
+	}
+	return false, ErrInvalidCBOR
+}
+
+// skip reads an item of any type, such as the value of a map key which is
+// not understood.
+func (r *cborReader) skip() error {
+	return r.skipDepth(0)
+}
+
+// skipDepth reads an item nested depth items deep.
+func (r *cborReader) skipDepth(depth int) error {
+	if depth > maxCBORDepth {
+		return ErrInvalidCBOR
+	}
+	major, n, err := r.head()
+	if err != nil {
+		return err
+	}
+	switch major {
+	case cborBytes, cborText:
+		if uint64(len(r.b)) < n {
+			return ErrInvalidCBOR
+		}
+		r.b = r.b[n:]
+	case cborArray, cborMap:
+		// Every item takes at least a byte.
+		if uint64(len(r.b)) < n {
+			return ErrInvalidCBOR
+		}
+		if major == cborMap {
+			n *= 2
+		}
+		for i := uint64(0); i < n; i++ {
+			if err := r.skipDepth(depth + 1); err != nil {
+				return err
+			}
+		}
+	case cborTag:
+		return r.skipDepth(depth + 1)
+	}
+	return nil
+}
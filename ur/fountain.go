@@ -0,0 +1,331 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ur
+
+import (
+	"errors"
+	"hash/crc32"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrPartMismatch describes an error where a part of a multi-part
+	// resource belongs to another message than the parts decoded before
+	// it.
+	ErrPartMismatch = errors.New("part belongs to another message")
+
+	// ErrMessageChecksum describes an error where the message joined from
+	// the parts of a multi-part resource does not match their checksum.
+	ErrMessageChecksum = errors.New("message checksum mismatch")
+)
+
+// minFragmentLen is the length of the shortest fragments a message is split
+// into.
+const minFragmentLen = 10
+
+// maxMessageLen is the length of the longest message accepted by decoders,
+// which bounds the memory a forged part can make them allocate.
+const maxMessageLen = math.MaxInt32
+
+// part is a part of a message split by the fountain code.  Its data is a
+// fragment of the message, or several of them mixed together.
+type part struct {
+	seqNum     uint32
+	seqLen     uint32
+	messageLen uint64
+	checksum   uint32
+	data       []byte
+}
+
+// cbor returns the CBOR encoding of the part.
+func (p *part) cbor() []byte {
+	b := make([]byte, 0, len(p.data)+32)
+	b = appendHead(b, cborArray, 5)
+	b = appendHead(b, cborUint, uint64(p.seqNum))
+	b = appendHead(b, cborUint, uint64(p.seqLen))
+	b = appendHead(b, cborUint, p.messageLen)
+	b = appendHead(b, cborUint, uint64(p.checksum))
+	return appendBytes(b, p.data)
+}
+
+// parsePart parses the CBOR encoding of a part and checks its fields are
+// consistent.
+func parsePart(b []byte) (*part, error) {
+	r := cborReader{b}
+	if n, err := r.expect(cborArray); err != nil || n != 5 {
+		return nil, ErrInvalidCBOR
+	}
+	var fields [4]uint64
+	for i, max := range []uint64{math.MaxUint32, math.MaxUint32,
+		maxMessageLen, math.MaxUint32} {
+
+		n, err := r.uint(max)
+		if err != nil {
+			return nil, err
+		}
+		fields[i] = n
+	}
+	data, err := r.bytes()
+	if err != nil || !r.done() {
+		return nil, ErrInvalidCBOR
+	}
+
+	p := &part{
+		seqNum:     uint32(fields[0]),
+		seqLen:     uint32(fields[1]),
+		messageLen: fields[2],
+		checksum:   uint32(fields[3]),
+		data:       data,
+	}
+
+	// The fragments must cover the message without a spare one.
+	fragLen := uint64(len(data))
+	if p.seqNum == 0 || p.seqLen == 0 || fragLen == 0 ||
+		fragLen*uint64(p.seqLen) < p.messageLen ||
+		fragLen*uint64(p.seqLen-1) >= p.messageLen {
+
+		return nil, ErrInvalidCBOR
+	}
+	return p, nil
+}
+
+// fragmentLen returns the length of the fragments a message is split into:
+// the shortest splitting it in the fewest fragments no longer than maxLen.
+func fragmentLen(messageLen, maxLen int) int {
+	maxCount := messageLen / minFragmentLen
+	length := messageLen
+	for count := 1; count <= maxCount; count++ {
+		length = (messageLen + count - 1) / count
+		if length <= maxLen {
+			break
+		}
+	}
+	return length
+}
+
+// xorInto sets dst to the exclusive or of dst and src.
+func xorInto(dst, src []byte) {
+	for i := range dst {
+		dst[i] ^= src[i]
+	}
+}
+
+// fountainEncoder splits a message into fragments and produces an unbounded
+// sequence of parts from them.  Any set of parts slightly larger than the
+// number of fragments is likely to hold the whole message.
+type fountainEncoder struct {
+	fragments  [][]byte
+	messageLen int
+	checksum   uint32
+	seqNum     uint32
+}
+
+// newFountainEncoder returns an encoder of message into fragments no longer
+// than maxFragmentLen, whose next part has sequence number firstSeqNum+1.
+func newFountainEncoder(message []byte, maxFragmentLen int,
+	firstSeqNum uint32) *fountainEncoder {
+
+	fragLen := fragmentLen(len(message), maxFragmentLen)
+	count := (len(message) + fragLen - 1) / fragLen
+	padded := make([]byte, count*fragLen)
+	copy(padded, message)
+
+	e := &fountainEncoder{
+		messageLen: len(message),
+		checksum:   crc32.ChecksumIEEE(message),
+		seqNum:     firstSeqNum,
+	}
+	for i := 0; i < count; i++ {
+		e.fragments = append(e.fragments, padded[i*fragLen:(i+1)*fragLen])
+	}
+	return e
+}
+
+// seqLen returns the number of fragments.
+func (e *fountainEncoder) seqLen() uint32 {
+	return uint32(len(e.fragments))
+}
+
+// nextPart returns the next part of the sequence.
+func (e *fountainEncoder) nextPart() *part {
+	e.seqNum++
+	indexes := chooseFragments(e.seqNum, e.seqLen(), e.checksum)
+	data := make([]byte, len(e.fragments[0]))
+	for _, i := range indexes {
+		xorInto(data, e.fragments[i])
+	}
+	return &part{
+		seqNum:     e.seqNum,
+		seqLen:     e.seqLen(),
+		messageLen: uint64(e.messageLen),
+		checksum:   e.checksum,
+		data:       data,
+	}
+}
+
+// mixedPart is the exclusive or of the fragments with the listed indexes,
+// which are sorted.
+type mixedPart struct {
+	indexes []int
+	data    []byte
+}
+
+// key returns a string identifying the set of indexes of the part.
+func (p *mixedPart) key() string {
+	var sb strings.Builder
+	for _, i := range p.indexes {
+		sb.WriteString(strconv.Itoa(i))
+		sb.WriteByte(',')
+	}
+	return sb.String()
+}
+
+// reduceBy returns the part with the fragments of other removed when they are
+// a strict subset of those of p, and p otherwise.
+func (p *mixedPart) reduceBy(other *mixedPart) *mixedPart {
+	if len(other.indexes) >= len(p.indexes) {
+		return p
+	}
+	in := make(map[int]bool, len(other.indexes))
+	for _, i := range other.indexes {
+		in[i] = true
+	}
+	remaining := make([]int, 0, len(p.indexes)-len(other.indexes))
+	for _, i := range p.indexes {
+		if !in[i] {
+			remaining = append(remaining, i)
+		}
+	}
+	if len(remaining) != len(p.indexes)-len(other.indexes) {
+		return p
+	}
+	data := append([]byte(nil), p.data...)
+	xorInto(data, other.data)
+	return &mixedPart{indexes: remaining, data: data}
+}
+
+// fountainDecoder joins the fragments of a message from the parts produced
+// by a fountainEncoder, in any order.  Parts mixing several fragments are
+// reduced by the parts whose fragments they include until single fragments
+// are left.
+type fountainDecoder struct {
+	first   *part
+	simple  map[int][]byte
+	mixed   map[string]*mixedPart
+	queue   []*mixedPart
+	message []byte
+	err     error
+}
+
+// complete returns whether the message was joined, or could not be.
+func (d *fountainDecoder) complete() bool {
+	return d.message != nil || d.err != nil
+}
+
+// progress returns the fraction of the fragments recovered.
+func (d *fountainDecoder) progress() float64 {
+	if d.first == nil {
+		return 0
+	}
+	return float64(len(d.simple)) / float64(d.first.seqLen)
+}
+
+// receive adds a part to the decoder.  Parts received once the message is
+// complete are ignored.
+func (d *fountainDecoder) receive(p *part) error {
+	if d.complete() {
+		return nil
+	}
+	if d.first == nil {
+		d.first = p
+		d.simple = make(map[int][]byte)
+		d.mixed = make(map[string]*mixedPart)
+	} else if p.seqLen != d.first.seqLen ||
+		p.messageLen != d.first.messageLen ||
+		p.checksum != d.first.checksum ||
+		len(p.data) != len(d.first.data) {
+
+		return ErrPartMismatch
+	}
+
+	indexes := chooseFragments(p.seqNum, p.seqLen, p.checksum)
+	sort.Ints(indexes)
+	d.queue = append(d.queue, &mixedPart{indexes: indexes, data: p.data})
+	for len(d.queue) > 0 && !d.complete() {
+		next := d.queue[0]
+		d.queue = d.queue[1:]
+		if len(next.indexes) == 1 {
+			d.processSimple(next)
+		} else {
+			d.processMixed(next)
+		}
+	}
+	return d.err
+}
+
+// processSimple records a single fragment, and joins the message once every
+// fragment is recorded.
+func (d *fountainDecoder) processSimple(p *mixedPart) {
+	i := p.indexes[0]
+	if _, ok := d.simple[i]; ok {
+		return
+	}
+	d.simple[i] = p.data
+
+	if len(d.simple) < int(d.first.seqLen) {
+		d.reduceMixedBy(p)
+		return
+	}
+	message := make([]byte, 0, len(p.data)*len(d.simple))
+	for i := 0; i < len(d.simple); i++ {
+		message = append(message, d.simple[i]...)
+	}
+	message = message[:d.first.messageLen]
+	if crc32.ChecksumIEEE(message) != d.first.checksum {
+		d.err = ErrMessageChecksum
+		return
+	}
+	d.message = message
+}
+
+// processMixed reduces a part mixing several fragments by the parts recorded
+// so far, and records it.
+func (d *fountainDecoder) processMixed(p *mixedPart) {
+	if _, ok := d.mixed[p.key()]; ok {
+		return
+	}
+	for i, data := range d.simple {
+		p = p.reduceBy(&mixedPart{indexes: []int{i}, data: data})
+	}
+	for _, m := range d.mixed {
+		p = p.reduceBy(m)
+	}
+	if len(p.indexes) == 1 {
+		d.queue = append(d.queue, p)
+		return
+	}
+	d.reduceMixedBy(p)
+	d.mixed[p.key()] = p
+}
+
+// reduceMixedBy reduces the recorded mixed parts by p, queueing those left
+// with a single fragment.
+func (d *fountainDecoder) reduceMixedBy(p *mixedPart) {
+	for key, m := range d.mixed {
+		reduced := m.reduceBy(p)
+		if reduced == m {
+			continue
+		}
+		delete(d.mixed, key)
+		if len(reduced.indexes) == 1 {
+			d.queue = append(d.queue, reduced)
+		} else {
+			d.mixed[reduced.key()] = reduced
+		}
+	}
+}
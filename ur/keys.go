@@ -0,0 +1,374 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ur
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"math"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/hdkeychain"
+	"github.com/zeusyf/btcutil/psbt"
+)
+
+// ErrPublicKey describes an error where a resource holding a public key is
+// converted to a private key.
+var ErrPublicKey = errors.New("resource holds a public key")
+
+// The types of the resources produced by this package.  Resources of the
+// types of the second version of the specifications, such as "psbt", are
+// also accepted.
+const (
+	// TypePSBT is the type of partially signed transactions.
+	TypePSBT = "crypto-psbt"
+
+	// TypeECKey is the type of private keys.
+	TypeECKey = "crypto-eckey"
+
+	// TypeHDKey is the type of extended keys.
+	TypeHDKey = "crypto-hdkey"
+)
+
+// The map keys of private keys.
+const (
+	ecKeyCurve   = 1
+	ecKeyPrivate = 2
+	ecKeyData    = 3
+)
+
+// The map keys of extended keys and of their origins.
+const (
+	hdKeyMaster    = 1
+	hdKeyPrivate   = 2
+	hdKeyData      = 3
+	hdKeyChainCode = 4
+	hdKeyOrigin    = 6
+	hdKeyParentFP  = 8
+
+	keypathComponents = 1
+	keypathSourceFP   = 2
+	keypathDepth      = 3
+)
+
+// tagKeypath is the CBOR tag of the origin of extended keys.
+const tagKeypath = 304
+
+// checkType returns ErrUnexpectedType unless the resource has the passed
+// type, or its name in the second version of the specifications.
+func (u UR) checkType(t string) error {
+	if u.Type != t && "crypto-"+u.Type != t {
+		return ErrUnexpectedType
+	}
+	return nil
+}
+
+// NewPSBT returns the resource holding the packet.
+func NewPSBT(p *psbt.Packet) (UR, error) {
+	var buf bytes.Buffer
+	if err := p.Serialize(&buf); err != nil {
+		return UR{}, err
+	}
+	return UR{Type: TypePSBT, CBOR: appendBytes(nil, buf.Bytes())}, nil
+}
+
+// PSBT returns the packet held by a resource of type TypePSBT.
+func (u UR) PSBT() (*psbt.Packet, error) {
+	if err := u.checkType(TypePSBT); err != nil {
+		return nil, err
+	}
+	r := cborReader{u.CBOR}
+	b, err := r.bytes()
+	if err != nil || !r.done() {
+		return nil, ErrInvalidCBOR
+	}
+	return psbt.ParseWithOptions(b, btcutil.DecodeOptions{})
+}
+
+// NewECKey returns the resource holding the private key of the WIF.
+func NewECKey(w *btcutil.WIF) UR {
+	b := appendHead(nil, cborMap, 2)
+	b = appendHead(b, cborUint, ecKeyPrivate)
+	b = appendBool(b, true)
+	b = appendHead(b, cborUint, ecKeyData)
+	b = appendBytes(b, w.PrivKey.Serialize())
+	return UR{Type: TypeECKey, CBOR: b}
+}
+
+// privKey returns the private key of the 32 bytes of b.
+func privKey(b []byte) (*btcec.PrivateKey, error) {
+	if len(b) != btcec.PrivKeyBytesLen {
+		return nil, ErrInvalidCBOR
+	}
+	k, _ := btcec.PrivKeyFromBytes(btcec.S256(), b)
+	if k.D.Sign() == 0 || k.D.Cmp(btcec.S256().N) >= 0 {
+		return nil, ErrInvalidCBOR
+	}
+	return k, nil
+}
+
+// WIF returns the WIF of the private key held by a resource of type
+// TypeECKey, for the passed network.  Resources do not record whether the
+// addresses of the key hash its compressed public key, which is assumed.
+func (u UR) WIF(net *chaincfg.Params) (*btcutil.WIF, error) {
+	if err := u.checkType(TypeECKey); err != nil {
+		return nil, err
+	}
+	r := cborReader{u.CBOR}
+	n, err := r.expect(cborMap)
+	if err != nil {
+		return nil, err
+	}
+	var private bool
+	var data []byte
+	for i := uint64(0); i < n; i++ {
+		key, err := r.uint(math.MaxUint64)
+		if err != nil {
+			return nil, err
+		}
+		switch key {
+		case ecKeyCurve:
+			// Only secp256k1 keys are supported.
+			_, err = r.uint(0)
+		case ecKeyPrivate:
+			private, err = r.bool()
+		case ecKeyData:
+			data, err = r.bytes()
+		default:
+			err = r.skip()
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !r.done() {
+		return nil, ErrInvalidCBOR
+	}
+	if !private {
+		return nil, ErrPublicKey
+	}
+	k, err := privKey(data)
+	if err != nil {
+		return nil, err
+	}
+	return btcutil.NewWIF(k, net, true)
+}
+
+// NewHDKey returns the resource holding the extended key.  Since extended
+// keys only record the last step of their derivation, the origin of derived
+// keys holds that step and their depth.
+func NewHDKey(k *hdkeychain.ExtendedKey) (UR, error) {
+	keyData, chainCode := k.ChainCode(), k.ChainCode()
+	if k.IsPrivate() {
+		priv, err := k.ECPrivKey()
+		if err != nil {
+			return UR{}, err
+		}
+		keyData = append([]byte{0x00}, priv.Serialize()...)
+	} else {
+		pub, err := k.ECPubKey()
+		if err != nil {
+			return UR{}, err
+		}
+		keyData = pub.SerializeCompressed()
+	}
+
+	if k.IsPrivate() && k.Depth() == 0 && k.ParentFingerprint() == 0 {
+		b := appendHead(nil, cborMap, 3)
+		b = appendHead(b, cborUint, hdKeyMaster)
+		b = appendBool(b, true)
+		b = appendHead(b, cborUint, hdKeyData)
+		b = appendBytes(b, keyData)
+		b = appendHead(b, cborUint, hdKeyChainCode)
+		b = appendBytes(b, chainCode)
+		return UR{Type: TypeHDKey, CBOR: b}, nil
+	}
+
+	entries := 2
+	for _, present := range []bool{k.IsPrivate(), k.Depth() > 0,
+		k.ParentFingerprint() != 0} {
+
+		if present {
+			entries++
+		}
+	}
+	b := appendHead(nil, cborMap, uint64(entries))
+	if k.IsPrivate() {
+		b = appendHead(b, cborUint, hdKeyPrivate)
+		b = appendBool(b, true)
+	}
+	b = appendHead(b, cborUint, hdKeyData)
+	b = appendBytes(b, keyData)
+	b = appendHead(b, cborUint, hdKeyChainCode)
+	b = appendBytes(b, chainCode)
+	if k.Depth() > 0 {
+		index := k.ChildIndex()
+		b = appendHead(b, cborUint, hdKeyOrigin)
+		b = appendHead(b, cborTag, tagKeypath)
+		b = appendHead(b, cborMap, 2)
+		b = appendHead(b, cborUint, keypathComponents)
+		b = appendHead(b, cborArray, 2)
+		b = appendHead(b, cborUint, uint64(index&^hdkeychain.HardenedKeyStart))
+		b = appendBool(b, index >= hdkeychain.HardenedKeyStart)
+		b = appendHead(b, cborUint, keypathDepth)
+		b = appendHead(b, cborUint, uint64(k.Depth()))
+	}
+	if fp := k.ParentFingerprint(); fp != 0 {
+		b = appendHead(b, cborUint, hdKeyParentFP)
+		b = appendHead(b, cborUint, uint64(fp))
+	}
+	return UR{Type: TypeHDKey, CBOR: b}, nil
+}
+
+// keypath is the part of the origin of an extended key it records.
+type keypath struct {
+	depth    int
+	index    uint32
+	sourceFP uint32
+}
+
+// readKeypath reads the origin of an extended key.  Wildcard and range
+// components, which only describe the children of a key, are rejected.
+func readKeypath(r *cborReader) (*keypath, error) {
+	if tag, err := r.expect(cborTag); err != nil || tag != tagKeypath {
+		return nil, ErrInvalidCBOR
+	}
+	n, err := r.expect(cborMap)
+	if err != nil {
+		return nil, err
+	}
+	kp := &keypath{depth: -1}
+	components := 0
+	for i := uint64(0); i < n; i++ {
+		key, err := r.uint(math.MaxUint64)
+		if err != nil {
+			return nil, err
+		}
+		switch key {
+		case keypathComponents:
+			count, err := r.expect(cborArray)
+			if err != nil || count%2 != 0 || count > 2*math.MaxUint8 {
+				return nil, ErrInvalidCBOR
+			}
+			components = int(count / 2)
+			for j := 0; j < components; j++ {
+				index, err := r.uint(hdkeychain.HardenedKeyStart - 1)
+				if err != nil {
+					return nil, err
+				}
+				hardened, err := r.bool()
+				if err != nil {
+					return nil, err
+				}
+				kp.index = uint32(index)
+				if hardened {
+					kp.index += hdkeychain.HardenedKeyStart
+				}
+			}
+		case keypathSourceFP:
+			var fp uint64
+			fp, err = r.uint(math.MaxUint32)
+			kp.sourceFP = uint32(fp)
+		case keypathDepth:
+			var depth uint64
+			depth, err = r.uint(math.MaxUint8)
+			kp.depth = int(depth)
+		default:
+			err = r.skip()
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if kp.depth < 0 {
+		kp.depth = components
+	}
+	if kp.depth == 0 || components == 0 {
+		return nil, ErrInvalidCBOR
+	}
+	return kp, nil
+}
+
+// ExtendedKey returns the extended key held by a resource of type TypeHDKey,
+// with the version bytes of the passed network.
+func (u UR) ExtendedKey(net *chaincfg.Params) (*hdkeychain.ExtendedKey, error) {
+	if err := u.checkType(TypeHDKey); err != nil {
+		return nil, err
+	}
+	r := cborReader{u.CBOR}
+	n, err := r.expect(cborMap)
+	if err != nil {
+		return nil, err
+	}
+	var master, private bool
+	var keyData, chainCode []byte
+	var origin *keypath
+	var parentFP uint64
+	for i := uint64(0); i < n; i++ {
+		key, err := r.uint(math.MaxUint64)
+		if err != nil {
+			return nil, err
+		}
+		switch key {
+		case hdKeyMaster:
+			master, err = r.bool()
+		case hdKeyPrivate:
+			private, err = r.bool()
+		case hdKeyData:
+			keyData, err = r.bytes()
+		case hdKeyChainCode:
+			chainCode, err = r.bytes()
+		case hdKeyOrigin:
+			origin, err = readKeypath(&r)
+		case hdKeyParentFP:
+			parentFP, err = r.uint(math.MaxUint32)
+		default:
+			err = r.skip()
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if !r.done() || len(keyData) != btcec.PubKeyBytesLenCompressed ||
+		len(chainCode) != 32 {
+
+		return nil, ErrInvalidCBOR
+	}
+
+	private = private || master
+	key := keyData
+	if private {
+		if keyData[0] != 0x00 {
+			return nil, ErrInvalidCBOR
+		}
+		if _, err := privKey(keyData[1:]); err != nil {
+			return nil, err
+		}
+		key = keyData[1:]
+	} else if _, err := btcec.ParsePubKey(keyData, btcec.S256()); err != nil {
+		return nil, ErrInvalidCBOR
+	}
+
+	var depth uint8
+	var childIndex uint32
+	if origin != nil && !master {
+		depth, childIndex = uint8(origin.depth), origin.index
+		if parentFP == 0 && depth == 1 {
+			parentFP = uint64(origin.sourceFP)
+		}
+	}
+	version := net.HDPublicKeyID[:]
+	if private {
+		version = net.HDPrivateKeyID[:]
+	}
+	var fp [4]byte
+	binary.BigEndian.PutUint32(fp[:], uint32(parentFP))
+	return hdkeychain.NewExtendedKey(append([]byte(nil), version...),
+		append([]byte(nil), key...), append([]byte(nil), chainCode...),
+		fp[:], depth, childIndex, private), nil
+}
@@ -0,0 +1,199 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ur_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/hdkeychain"
+	"github.com/zeusyf/btcutil/psbt"
+	"github.com/zeusyf/btcutil/ur"
+	"github.com/zeusyf/omega/token"
+)
+
+// roundTrip returns the resource decoded from the parts of u, split into
+// fragments of at most 20 bytes.
+func roundTrip(t *testing.T, u ur.UR) ur.UR {
+	e, err := ur.NewEncoder(u, 20)
+	if err != nil {
+		t.Fatalf("NewEncoder: unexpected error: %v", err)
+	}
+	var d ur.Decoder
+	for !d.IsComplete() {
+		if err := d.ReceivePart(e.NextPart()); err != nil {
+			t.Fatalf("ReceivePart: unexpected error: %v", err)
+		}
+	}
+	got, err := d.Result()
+	if err != nil {
+		t.Fatalf("Result: unexpected error: %v", err)
+	}
+	return got
+}
+
+// TestPSBT ensures packets are carried by resources of type TypePSBT.
+func TestPSBT(t *testing.T) {
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(&wire.TxIn{Sequence: wire.MaxTxInSequenceNum})
+	tx.AddTxOut(&wire.TxOut{
+		Token:    token.Token{Value: &token.NumeralVal{Val: 1000}},
+		PkScript: []byte{0x51},
+	})
+	p, err := psbt.New(tx)
+	if err != nil {
+		t.Fatalf("New: unexpected error: %v", err)
+	}
+	u, err := ur.NewPSBT(p)
+	if err != nil {
+		t.Fatalf("NewPSBT: unexpected error: %v", err)
+	}
+	if u.Type != ur.TypePSBT {
+		t.Errorf("NewPSBT: got type %s, want %s", u.Type, ur.TypePSBT)
+	}
+
+	got, err := roundTrip(t, u).PSBT()
+	if err != nil {
+		t.Fatalf("PSBT: unexpected error: %v", err)
+	}
+	var want, buf bytes.Buffer
+	p.Serialize(&want)
+	got.Serialize(&buf)
+	if !bytes.Equal(buf.Bytes(), want.Bytes()) {
+		t.Errorf("PSBT: got %x, want %x", buf.Bytes(), want.Bytes())
+	}
+
+	// Resources of the second version of the specifications are accepted.
+	u.Type = "psbt"
+	if _, err := u.PSBT(); err != nil {
+		t.Errorf("PSBT: unexpected error: %v", err)
+	}
+	if _, err := ur.NewECKey(testWIF(t)).PSBT(); err != ur.ErrUnexpectedType {
+		t.Errorf("PSBT: got error %v, want %v", err, ur.ErrUnexpectedType)
+	}
+}
+
+// testWIF returns a WIF of the test network.
+func testWIF(t *testing.T) *btcutil.WIF {
+	b, _ := hex.DecodeString("dda35a1488fb97b6eb3fe6e9ef2a25814e396fb5dc295fe994b96789b21a0398")
+	priv, _ := btcec.PrivKeyFromBytes(btcec.S256(), b)
+	w, err := btcutil.NewWIF(priv, &chaincfg.TestNet3Params, true)
+	if err != nil {
+		t.Fatalf("NewWIF: unexpected error: %v", err)
+	}
+	return w
+}
+
+// TestECKey ensures private keys are carried by resources of type
+// TypeECKey.
+func TestECKey(t *testing.T) {
+	w := testWIF(t)
+	u := ur.NewECKey(w)
+	want := "a202f50358" + "20dda35a1488fb97b6eb3fe6e9ef2a25814e396fb5dc295fe994b96789b21a0398"
+	if got := hex.EncodeToString(u.CBOR); got != want {
+		t.Errorf("NewECKey: got %s, want %s", got, want)
+	}
+
+	got, err := roundTrip(t, u).WIF(&chaincfg.TestNet3Params)
+	if err != nil {
+		t.Fatalf("WIF: unexpected error: %v", err)
+	}
+	if !bytes.Equal(got.PrivKey.Serialize(), w.PrivKey.Serialize()) ||
+		!got.CompressPubKey {
+
+		t.Errorf("WIF: got %x, want %x", got.PrivKey.Serialize(),
+			w.PrivKey.Serialize())
+	}
+
+	tests := []struct {
+		cbor string
+		err  error
+	}{
+		// Public key.
+		{"a10358210279be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798",
+			ur.ErrPublicKey},
+		// Key out of range.
+		{"a202f5035820" + strings.Repeat("00", 32), ur.ErrInvalidCBOR},
+		// Other curve.
+		{"a3010102f50358" + want[10:], ur.ErrInvalidCBOR},
+		// Trailing data.
+		{want + "00", ur.ErrInvalidCBOR},
+	}
+	for _, test := range tests {
+		b, _ := hex.DecodeString(test.cbor)
+		u := ur.UR{Type: ur.TypeECKey, CBOR: b}
+		if _, err := u.WIF(&chaincfg.TestNet3Params); err != test.err {
+			t.Errorf("WIF(%s): got error %v, want %v", test.cbor, err,
+				test.err)
+		}
+	}
+}
+
+// TestHDKey ensures extended keys are carried by resources of type
+// TypeHDKey, and keep their depth, child index and parent fingerprint.
+func TestHDKey(t *testing.T) {
+	seed, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f")
+	master, err := hdkeychain.NewMaster(seed, &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("NewMaster: unexpected error: %v", err)
+	}
+	child, err := master.Child(hdkeychain.HardenedKeyStart)
+	if err != nil {
+		t.Fatalf("Child: unexpected error: %v", err)
+	}
+	grandchild, err := child.Child(1)
+	if err != nil {
+		t.Fatalf("Child: unexpected error: %v", err)
+	}
+	pub, err := grandchild.Neuter()
+	if err != nil {
+		t.Fatalf("Neuter: unexpected error: %v", err)
+	}
+	masterPub, err := master.Neuter()
+	if err != nil {
+		t.Fatalf("Neuter: unexpected error: %v", err)
+	}
+
+	for _, k := range []*hdkeychain.ExtendedKey{master, child, grandchild,
+		pub, masterPub} {
+
+		u, err := ur.NewHDKey(k)
+		if err != nil {
+			t.Fatalf("NewHDKey: unexpected error: %v", err)
+		}
+		got, err := roundTrip(t, u).ExtendedKey(&chaincfg.MainNetParams)
+		if err != nil {
+			t.Errorf("ExtendedKey(%v): unexpected error: %v", k, err)
+			continue
+		}
+		if got.String() != k.String() {
+			t.Errorf("ExtendedKey: got %v, want %v", got, k)
+		}
+	}
+
+	u, _ := ur.NewHDKey(master)
+	want := "a301f503582100e8f32e723decf4051aefac8e2c93c9c5b214313817cdb01a1494b917c8436b35" +
+		"045820873dff81c02f525623fd1fe5167eac3a55a049de3d314bb42ee227ffed37d508"
+	if got := hex.EncodeToString(u.CBOR); got != want {
+		t.Errorf("NewHDKey: got %s, want %s", got, want)
+	}
+	if _, err := u.WIF(&chaincfg.MainNetParams); err != ur.ErrUnexpectedType {
+		t.Errorf("WIF: got error %v, want %v", err, ur.ErrUnexpectedType)
+	}
+
+	// Keys without a chain code can't be converted to extended keys.
+	b, _ := hex.DecodeString(want[:len(want)-70])
+	b[0] = 0xa2
+	u = ur.UR{Type: ur.TypeHDKey, CBOR: b}
+	if _, err := u.ExtendedKey(&chaincfg.MainNetParams); err != ur.ErrInvalidCBOR {
+		t.Errorf("ExtendedKey: got error %v, want %v", err, ur.ErrInvalidCBOR)
+	}
+}
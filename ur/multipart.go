@@ -0,0 +1,166 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ur
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Encoder produces the parts of a resource, to be shown in turn as the frames
+// of an animated QR code.  The first parts each hold a fragment of the
+// resource, and the following ones random mixes of fragments, so the
+// sequence never ends and a scanner can join the resource from any parts it
+// happens to read.
+type Encoder struct {
+	ur       UR
+	fountain *fountainEncoder
+}
+
+// NewEncoder returns an Encoder splitting the resource into fragments of at
+// most maxFragmentLen bytes, or of ten bytes when maxFragmentLen is lower.
+// Resources no longer than maxFragmentLen are encoded in a single part.
+func NewEncoder(ur UR, maxFragmentLen int) (*Encoder, error) {
+	if !isValidType(ur.Type) {
+		return nil, ErrInvalidType
+	}
+	if len(ur.CBOR) == 0 {
+		return nil, ErrInvalidCBOR
+	}
+	return &Encoder{
+		ur:       ur,
+		fountain: newFountainEncoder(ur.CBOR, maxFragmentLen, 0),
+	}, nil
+}
+
+// IsSinglePart returns whether the resource fits in a single part, in which
+// case every part is its single-part encoding.
+func (e *Encoder) IsSinglePart() bool {
+	return e.fountain.seqLen() == 1
+}
+
+// SeqLen returns the number of fragments of the resource, which is the
+// number of parts a scanner missing none needs.
+func (e *Encoder) SeqLen() int {
+	return int(e.fountain.seqLen())
+}
+
+// NextPart returns the next part of the sequence.
+func (e *Encoder) NextPart() string {
+	if e.IsSinglePart() {
+		s, _ := Encode(e.ur)
+		return s
+	}
+	p := e.fountain.nextPart()
+	return fmt.Sprintf("ur:%s/%d-%d/%s", e.ur.Type, p.seqNum, p.seqLen,
+		EncodeBytewords(p.cbor(), StyleMinimal))
+}
+
+// Decoder joins a resource from its parts, received in any order.  It also
+// accepts the single-part encoding of a resource.
+type Decoder struct {
+	typ      string
+	fountain fountainDecoder
+	result   *UR
+}
+
+// parseSequence parses the sequence component of a part.
+func parseSequence(s string) (seqNum, seqLen uint32, err error) {
+	num, length, ok := strings.Cut(s, "-")
+	if !ok {
+		return 0, 0, ErrInvalidSequence
+	}
+	n, err := strconv.ParseUint(num, 10, 32)
+	if err != nil {
+		return 0, 0, ErrInvalidSequence
+	}
+	l, err := strconv.ParseUint(length, 10, 32)
+	if err != nil {
+		return 0, 0, ErrInvalidSequence
+	}
+	return uint32(n), uint32(l), nil
+}
+
+// ReceivePart adds a part, in any case, to the decoder.  Parts of another
+// resource are rejected with ErrUnexpectedType when they have another type
+// and ErrPartMismatch otherwise, and leave the decoder unchanged.  Parts
+// received once the resource is complete are ignored.
+func (d *Decoder) ReceivePart(s string) error {
+	if d.IsComplete() {
+		return nil
+	}
+	t, components, err := splitUR(s)
+	if err != nil {
+		return err
+	}
+	if d.typ != "" && t != d.typ {
+		return ErrUnexpectedType
+	}
+
+	switch len(components) {
+	case 1:
+		ur, err := Decode(s)
+		if err != nil {
+			return err
+		}
+		d.typ, d.result = t, &ur
+		return nil
+	case 2:
+	default:
+		return ErrInvalidPath
+	}
+
+	seqNum, seqLen, err := parseSequence(components[0])
+	if err != nil {
+		return err
+	}
+	b, err := DecodeBytewords(components[1], StyleMinimal)
+	if err != nil {
+		return err
+	}
+	p, err := parsePart(b)
+	if err != nil {
+		return err
+	}
+	if p.seqNum != seqNum || p.seqLen != seqLen {
+		return ErrInvalidSequence
+	}
+	if err := d.fountain.receive(p); err != nil {
+		return err
+	}
+	d.typ = t
+	if d.fountain.message != nil {
+		d.result = &UR{Type: t, CBOR: d.fountain.message}
+	}
+	return nil
+}
+
+// IsComplete returns whether the resource was joined, or failed to join, in
+// which case Result reports the error.
+func (d *Decoder) IsComplete() bool {
+	return d.result != nil || d.fountain.err != nil
+}
+
+// Progress returns the fraction of the fragments of the resource recovered
+// so far, from 0 to 1.
+func (d *Decoder) Progress() float64 {
+	if d.result != nil {
+		return 1
+	}
+	return d.fountain.progress()
+}
+
+// Result returns the resource once it is complete.  ErrMessageChecksum is
+// returned when the joined parts do not match their checksum.
+func (d *Decoder) Result() (UR, error) {
+	switch {
+	case d.result != nil:
+		return *d.result, nil
+	case d.fountain.err != nil:
+		return UR{}, d.fountain.err
+	}
+	return UR{}, ErrIncomplete
+}
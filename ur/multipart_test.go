@@ -0,0 +1,119 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ur
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// TestMultiPart ensures resources split into parts are joined back from
+// their parts, whichever of them are lost.
+func TestMultiPart(t *testing.T) {
+	u := UR{Type: "bytes", CBOR: appendBytes(nil, makeMessage(256))}
+	e, err := NewEncoder(u, 30)
+	if err != nil {
+		t.Fatalf("NewEncoder: unexpected error: %v", err)
+	}
+	if e.IsSinglePart() || e.SeqLen() != 9 {
+		t.Fatalf("NewEncoder: got %d parts, want 9", e.SeqLen())
+	}
+	parts := make([]string, 40)
+	for i := range parts {
+		parts[i] = e.NextPart()
+	}
+	want := "ur:bytes/1-9/lpadascfadaxcywenbpljkhdcahkadaemejtswhhylkepmykhhts"
+	if !strings.HasPrefix(parts[0], want) {
+		t.Errorf("NextPart: got %s, want prefix %s", parts[0], want)
+	}
+
+	tests := []struct {
+		name string
+		keep func(i int) bool
+	}{
+		{"all", func(i int) bool { return true }},
+		{"uppercase", func(i int) bool { return true }},
+		{"every other", func(i int) bool { return i%2 == 1 }},
+		{"first lost", func(i int) bool { return i >= 3 }},
+		{"mixed only", func(i int) bool { return i >= 9 }},
+	}
+	for _, test := range tests {
+		var d Decoder
+		for i, part := range parts {
+			if !test.keep(i) {
+				continue
+			}
+			if test.name == "uppercase" {
+				part = strings.ToUpper(part)
+			}
+			if err := d.ReceivePart(part); err != nil {
+				t.Fatalf("%s: ReceivePart: unexpected error: %v",
+					test.name, err)
+			}
+			if d.IsComplete() {
+				break
+			}
+			if p := d.Progress(); p < 0 || p >= 1 {
+				t.Errorf("%s: Progress: got %v", test.name, p)
+			}
+		}
+		got, err := d.Result()
+		if err != nil {
+			t.Errorf("%s: Result: unexpected error: %v", test.name, err)
+			continue
+		}
+		if got.Type != u.Type || !bytes.Equal(got.CBOR, u.CBOR) {
+			t.Errorf("%s: Result: got %x, want %x", test.name, got.CBOR,
+				u.CBOR)
+		}
+		if d.Progress() != 1 {
+			t.Errorf("%s: Progress: got %v, want 1", test.name,
+				d.Progress())
+		}
+	}
+
+	// Parts of another resource are rejected.
+	var d Decoder
+	if _, err := d.Result(); err != ErrIncomplete {
+		t.Errorf("Result: got error %v, want %v", err, ErrIncomplete)
+	}
+	if err := d.ReceivePart(parts[0]); err != nil {
+		t.Fatalf("ReceivePart: unexpected error: %v", err)
+	}
+	other, _ := NewEncoder(UR{Type: "bytes",
+		CBOR: appendBytes(nil, makeMessage(300))}, 30)
+	if err := d.ReceivePart(other.NextPart()); err != ErrPartMismatch {
+		t.Errorf("ReceivePart: got error %v, want %v", err, ErrPartMismatch)
+	}
+	psbtPart := strings.Replace(parts[1], "bytes", "crypto-psbt", 1)
+	if err := d.ReceivePart(psbtPart); err != ErrUnexpectedType {
+		t.Errorf("ReceivePart: got error %v, want %v", err,
+			ErrUnexpectedType)
+	}
+	badSeq := strings.Replace(parts[1], "/2-9/", "/3-9/", 1)
+	if err := d.ReceivePart(badSeq); err != ErrInvalidSequence {
+		t.Errorf("ReceivePart: got error %v, want %v", err,
+			ErrInvalidSequence)
+	}
+	if d.IsComplete() {
+		t.Errorf("IsComplete: decoder completed from rejected parts")
+	}
+
+	// Small resources are encoded in a single part.
+	single, err := NewEncoder(UR{Type: "bytes",
+		CBOR: appendBytes(nil, makeMessage(50))}, 100)
+	if err != nil {
+		t.Fatalf("NewEncoder: unexpected error: %v", err)
+	}
+	if !single.IsSinglePart() || single.NextPart() != singlePart {
+		t.Errorf("NextPart: got %s, want %s", single.NextPart(), singlePart)
+	}
+	var sd Decoder
+	if err := sd.ReceivePart(singlePart); err != nil || !sd.IsComplete() {
+		t.Errorf("ReceivePart: got error %v, complete %v", err,
+			sd.IsComplete())
+	}
+}
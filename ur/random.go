@@ -0,0 +1,159 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ur
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"math"
+	"math/bits"
+)
+
+// xoshiro is the xoshiro256** generator the fountain code uses to choose the
+// fragments mixed into each part.  Encoders and decoders must draw exactly
+// the same numbers, so the seeding and the conversions to floating point
+// follow the reference implementation bit for bit.
+type xoshiro [4]uint64
+
+// newXoshiro returns a generator seeded with the SHA256 hash of seed.
+func newXoshiro(seed []byte) *xoshiro {
+	h := sha256.Sum256(seed)
+	var x xoshiro
+	for i := range x {
+		x[i] = binary.BigEndian.Uint64(h[i*8:])
+	}
+	return &x
+}
+
+// next returns the next 64 bits of the generator.
+func (x *xoshiro) next() uint64 {
+	result := bits.RotateLeft64(x[1]*5, 7) * 9
+	t := x[1] << 17
+
+	x[2] ^= x[0]
+	x[3] ^= x[1]
+	x[1] ^= x[2]
+	x[0] ^= x[3]
+
+	x[2] ^= t
+	x[3] = bits.RotateLeft64(x[3], 45)
+
+	return result
+}
+
+// nextDouble returns a number in [0, 1).
+func (x *xoshiro) nextDouble() float64 {
+	return float64(x.next()) / (float64(math.MaxUint64) + 1)
+}
+
+// nextInt returns a number in [low, high].
+func (x *xoshiro) nextInt(low, high int) int {
+	return int(x.nextDouble()*float64(high-low+1)) + low
+}
+
+// shuffled returns the numbers 0 to n-1 in random order.
+func shuffled(n int, rng *xoshiro) []int {
+	remaining := make([]int, n)
+	for i := range remaining {
+		remaining[i] = i
+	}
+	result := make([]int, 0, n)
+	for len(remaining) > 0 {
+		i := rng.nextInt(0, len(remaining)-1)
+		result = append(result, remaining[i])
+		remaining = append(remaining[:i], remaining[i+1:]...)
+	}
+	return result
+}
+
+// sampler draws indexes with the passed weights using Vose's alias method.
+type sampler struct {
+	probs   []float64
+	aliases []int
+}
+
+// newSampler returns a sampler drawing index i with a probability
+// proportional to weights[i].
+func newSampler(weights []float64) *sampler {
+	n := len(weights)
+	var sum float64
+	for _, w := range weights {
+		sum += w
+	}
+	p := make([]float64, n)
+	for i, w := range weights {
+		p[i] = w * float64(n) / sum
+	}
+
+	// Unlike Schwarz's description, the indexes are listed in reverse
+	// order, as the reference implementation does.
+	var small, large []int
+	for i := n - 1; i >= 0; i-- {
+		if p[i] < 1 {
+			small = append(small, i)
+		} else {
+			large = append(large, i)
+		}
+	}
+
+	s := &sampler{probs: make([]float64, n), aliases: make([]int, n)}
+	for len(small) > 0 && len(large) > 0 {
+		a := small[len(small)-1]
+		small = small[:len(small)-1]
+		g := large[len(large)-1]
+		large = large[:len(large)-1]
+
+		s.probs[a] = p[a]
+		s.aliases[a] = g
+		p[g] += p[a] - 1
+		if p[g] < 1 {
+			small = append(small, g)
+		} else {
+			large = append(large, g)
+		}
+	}
+
+	// Indexes left in small can only be due to rounding errors.
+	for _, i := range large {
+		s.probs[i] = 1
+	}
+	for _, i := range small {
+		s.probs[i] = 1
+	}
+	return s
+}
+
+// next draws an index.
+func (s *sampler) next(rng *xoshiro) int {
+	r1 := rng.nextDouble()
+	r2 := rng.nextDouble()
+	i := int(float64(len(s.probs)) * r1)
+	if r2 < s.probs[i] {
+		return i
+	}
+	return s.aliases[i]
+}
+
+// chooseFragments returns the indexes of the fragments mixed into the part
+// with the passed sequence number.  The first seqLen parts each hold one
+// fragment in order, and later parts a random mix of them whose size favors
+// small mixes.
+func chooseFragments(seqNum, seqLen, checksum uint32) []int {
+	if seqNum <= seqLen {
+		return []int{int(seqNum) - 1}
+	}
+
+	var seed [8]byte
+	binary.BigEndian.PutUint32(seed[:4], seqNum)
+	binary.BigEndian.PutUint32(seed[4:], checksum)
+	rng := newXoshiro(seed[:])
+
+	weights := make([]float64, seqLen)
+	for i := range weights {
+		weights[i] = 1 / float64(i+1)
+	}
+	degree := newSampler(weights).next(rng) + 1
+	return shuffled(int(seqLen), rng)[:degree]
+}
@@ -0,0 +1,75 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ur
+
+import (
+	"encoding/hex"
+	"reflect"
+	"testing"
+)
+
+// makeMessage returns the n pseudo-random bytes of the message the reference
+// test vectors are built from.
+func makeMessage(n int) []byte {
+	rng := newXoshiro([]byte("Wolf"))
+	b := make([]byte, n)
+	for i := range b {
+		b[i] = byte(rng.nextInt(0, 255))
+	}
+	return b
+}
+
+// TestRandom ensures the random generator, shuffle and sampler match the
+// reference implementation.
+func TestRandom(t *testing.T) {
+	want := "916ec65cf77cadf55cd7f9cda1a1030026ddd42e905b77adc36e4f2d3ccba44f" +
+		"7f04f2de44f42d84c374a0e149136f25b018"
+	if got := hex.EncodeToString(makeMessage(50)); got != want {
+		t.Errorf("makeMessage: got %s, want %s", got, want)
+	}
+
+	rng := newXoshiro([]byte("Wolf"))
+	var rolls []uint64
+	for i := 0; i < 4; i++ {
+		rolls = append(rolls, rng.next()%100)
+	}
+	if want := []uint64{42, 81, 85, 8}; !reflect.DeepEqual(rolls, want) {
+		t.Errorf("next: got %v, want %v", rolls, want)
+	}
+
+	rng = newXoshiro([]byte("Wolf"))
+	shuffle := shuffled(10, rng)
+	if want := []int{5, 3, 8, 2, 9, 4, 6, 7, 0, 1}; !reflect.DeepEqual(shuffle, want) {
+		t.Errorf("shuffled: got %v, want %v", shuffle, want)
+	}
+
+	rng = newXoshiro([]byte("Wolf"))
+	s := newSampler([]float64{1, 2, 4, 8})
+	var samples []int
+	for i := 0; i < 10; i++ {
+		samples = append(samples, s.next(rng))
+	}
+	if want := []int{3, 3, 3, 3, 3, 3, 3, 0, 2, 3}; !reflect.DeepEqual(samples, want) {
+		t.Errorf("sampler: got %v, want %v", samples, want)
+	}
+}
+
+// TestChooseFragments ensures the first parts of a sequence each hold one
+// fragment and the following ones mix at least one.
+func TestChooseFragments(t *testing.T) {
+	for seqNum := uint32(1); seqNum <= 30; seqNum++ {
+		got := chooseFragments(seqNum, 10, 0x12345678)
+		if seqNum <= 10 {
+			if want := []int{int(seqNum) - 1}; !reflect.DeepEqual(got, want) {
+				t.Errorf("chooseFragments(%d): got %v, want %v", seqNum,
+					got, want)
+			}
+			continue
+		}
+		if len(got) == 0 || len(got) > 10 {
+			t.Errorf("chooseFragments(%d): got %v", seqNum, got)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package ur encodes keys and partially signed transactions as uniform
+// resources (URs), for air-gapped signers exchanging them through QR codes.
+//
+// A UR is a typed CBOR encoding written as "ur:<type>/<bytewords>", where the
+// bytewords encode the CBOR with a checksum.  The encoding only uses letters,
+// digits, ':', '/' and '-', so uppercasing it lets it fit the alphanumeric
+// mode of QR codes.  Resources too large for a single QR code are split by
+// an Encoder into a sequence of parts produced by a fountain code, shown as
+// an animated QR code.  A Decoder joins any sufficient subset of the parts,
+// received in any order, so a scanner missing frames does not wait for the
+// animation to loop.
+//
+// The encoding is that of the Blockchain Commons specifications BCR-2020-005
+// (UR), BCR-2020-012 (bytewords) and BCR-2020-006 (multi-part URs), so OMC
+// wallets interoperate with signers implementing them.  The types of keys
+// and packets follow BCR-2020-007 and BCR-2020-008.
+package ur
+
+import (
+	"errors"
+	"strings"
+)
+
+var (
+	// ErrInvalidScheme describes an error where a string does not start
+	// with the "ur:" scheme.
+	ErrInvalidScheme = errors.New("invalid UR scheme")
+
+	// ErrInvalidType describes an error where the type of a resource is
+	// empty or holds characters other than lowercase letters, digits and
+	// dashes.
+	ErrInvalidType = errors.New("invalid UR type")
+
+	// ErrUnexpectedType describes an error where a resource is converted
+	// to a value of another type than its own.
+	ErrUnexpectedType = errors.New("unexpected UR type")
+
+	// ErrInvalidPath describes an error where a string is not made of a
+	// type and a body, with a sequence component between them for the
+	// parts of multi-part resources.
+	ErrInvalidPath = errors.New("invalid UR path")
+
+	// ErrInvalidSequence describes an error where the sequence component
+	// of a part is malformed or does not match the part.
+	ErrInvalidSequence = errors.New("invalid UR sequence component")
+
+	// ErrIncomplete describes an error where the result of a Decoder is
+	// requested before enough parts were received.
+	ErrIncomplete = errors.New("resource is incomplete")
+)
+
+// UR is a uniform resource: a CBOR encoding with a type telling how to
+// interpret it.
+type UR struct {
+	// Type is the registered type of the resource, such as
+	// "crypto-psbt".
+	Type string
+
+	// CBOR is the CBOR encoding of the resource.
+	CBOR []byte
+}
+
+// isValidType returns whether t is a valid type.
+func isValidType(t string) bool {
+	if t == "" {
+		return false
+	}
+	for i := 0; i < len(t); i++ {
+		c := t[i]
+		if (c < 'a' || c > 'z') && (c < '0' || c > '9') && c != '-' {
+			return false
+		}
+	}
+	return true
+}
+
+// Encode returns the single-part encoding of the resource.  Use an Encoder
+// for resources too large for a single QR code.
+func Encode(ur UR) (string, error) {
+	if !isValidType(ur.Type) {
+		return "", ErrInvalidType
+	}
+	return "ur:" + ur.Type + "/" + EncodeBytewords(ur.CBOR, StyleMinimal),
+		nil
+}
+
+// splitUR splits s, in any case, into its type and the remaining components
+// of its path.
+func splitUR(s string) (string, []string, error) {
+	s = strings.ToLower(s)
+	if !strings.HasPrefix(s, "ur:") {
+		return "", nil, ErrInvalidScheme
+	}
+	components := strings.Split(s[len("ur:"):], "/")
+	if len(components) < 2 {
+		return "", nil, ErrInvalidPath
+	}
+	if !isValidType(components[0]) {
+		return "", nil, ErrInvalidType
+	}
+	return components[0], components[1:], nil
+}
+
+// Decode decodes a single-part resource, in any case.  Use a Decoder for
+// the parts of multi-part resources.
+func Decode(s string) (UR, error) {
+	t, components, err := splitUR(s)
+	if err != nil {
+		return UR{}, err
+	}
+	if len(components) != 1 {
+		return UR{}, ErrInvalidPath
+	}
+	cbor, err := DecodeBytewords(components[0], StyleMinimal)
+	if err != nil {
+		return UR{}, err
+	}
+	return UR{Type: t, CBOR: cbor}, nil
+}
@@ -0,0 +1,60 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package ur
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// singlePart is the reference single-part encoding of the CBOR byte string of
+// the 50 byte test message.
+const singlePart = "ur:bytes/hdeymejtswhhylkepmykhhtsytsnoyoyaxaedsuttydmmh" +
+	"hpktpmsrjtgwdpfnsboxgwlbaawzuefywkdplrsrjynbvygabwjldapfcsdwkbrkch"
+
+// TestSinglePart ensures resources encode to the reference single-part
+// encoding and decode back in any case.
+func TestSinglePart(t *testing.T) {
+	u := UR{Type: "bytes", CBOR: appendBytes(nil, makeMessage(50))}
+	got, err := Encode(u)
+	if err != nil {
+		t.Fatalf("Encode: unexpected error: %v", err)
+	}
+	if got != singlePart {
+		t.Errorf("Encode: got %s, want %s", got, singlePart)
+	}
+
+	for _, s := range []string{singlePart, strings.ToUpper(singlePart)} {
+		decoded, err := Decode(s)
+		if err != nil {
+			t.Errorf("Decode: unexpected error: %v", err)
+			continue
+		}
+		if decoded.Type != u.Type || !bytes.Equal(decoded.CBOR, u.CBOR) {
+			t.Errorf("Decode: got %v, want %v", decoded, u)
+		}
+	}
+
+	tests := []struct {
+		s   string
+		err error
+	}{
+		{"uri:bytes/aeadaolazmjendeoti", ErrInvalidScheme},
+		{"ur:bytes", ErrInvalidPath},
+		{"ur:bytes/1-2/aeadaolazmjendeoti", ErrInvalidPath},
+		{"ur:by_tes/aeadaolazmjendeoti", ErrInvalidType},
+		{"ur:bytes/aeadaolazmjendeotd", ErrBytewordsChecksum},
+	}
+	for _, test := range tests {
+		if _, err := Decode(test.s); err != test.err {
+			t.Errorf("Decode(%q): got error %v, want %v", test.s, err,
+				test.err)
+		}
+	}
+	if _, err := Encode(UR{Type: "Bytes"}); err != ErrInvalidType {
+		t.Errorf("Encode: got error %v, want %v", err, ErrInvalidType)
+	}
+}
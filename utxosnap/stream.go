@@ -0,0 +1,180 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package utxosnap
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"hash"
+	"io"
+	"sort"
+
+	"github.com/zeusyf/btcd/wire"
+)
+
+// SortEntries sorts entries in ascending order of outpoint, the order they
+// are written in.
+func SortEntries(entries []*Entry) {
+	sort.Slice(entries, func(i, j int) bool {
+		return compareOutPoints(&entries[i].OutPoint,
+			&entries[j].OutPoint) < 0
+	})
+}
+
+// Writer writes a snapshot to an underlying writer.
+type Writer struct {
+	w       io.Writer
+	hash    hash.Hash
+	count   uint64
+	written uint64
+	prev    wire.OutPoint
+	sum     [sha256.Size]byte
+}
+
+// NewWriter writes the header of a snapshot to w and returns a writer of its
+// entries.  ErrInvalidHeader is returned for a negative height.
+func NewWriter(w io.Writer, header *Header) (*Writer, error) {
+	if header.Height < 0 {
+		return nil, ErrInvalidHeader
+	}
+	sw := &Writer{
+		w:     w,
+		hash:  sha256.New(),
+		count: header.Count,
+	}
+	if err := sw.write(header.serialize()); err != nil {
+		return nil, err
+	}
+	return sw, nil
+}
+
+// write writes b to the underlying writer and adds it to the hash.
+func (w *Writer) write(b []byte) error {
+	w.hash.Write(b)
+	_, err := w.w.Write(b)
+	return err
+}
+
+// Write writes the next entry of the snapshot.  ErrUnsorted is returned when
+// its outpoint does not follow that of the entry written before, and
+// ErrCountMismatch when the snapshot already holds the entries declared by
+// its header.
+func (w *Writer) Write(e *Entry) error {
+	if w.written == w.count {
+		return ErrCountMismatch
+	}
+	if w.written > 0 && compareOutPoints(&w.prev, &e.OutPoint) >= 0 {
+		return ErrUnsorted
+	}
+	var buf bytes.Buffer
+	if err := e.serialize(&buf); err != nil {
+		return err
+	}
+	if err := w.write(buf.Bytes()); err != nil {
+		return err
+	}
+	w.prev = e.OutPoint
+	w.written++
+	return nil
+}
+
+// Close writes the hash trailing the snapshot.  ErrCountMismatch is returned
+// when fewer entries were written than declared by the header.  The
+// underlying writer is not closed.
+func (w *Writer) Close() error {
+	if w.written != w.count {
+		return ErrCountMismatch
+	}
+	copy(w.sum[:], w.hash.Sum(nil))
+	_, err := w.w.Write(w.sum[:])
+	return err
+}
+
+// Sum returns the hash of the snapshot, which is valid once the writer is
+// closed.
+func (w *Writer) Sum() [sha256.Size]byte {
+	return w.sum
+}
+
+// Reader reads a snapshot from an underlying reader.  It buffers its input,
+// so it may read past the end of the snapshot.
+type Reader struct {
+	r      *bufio.Reader
+	hash   hash.Hash
+	header Header
+	read   uint64
+	prev   wire.OutPoint
+	sum    [sha256.Size]byte
+	done   bool
+}
+
+// NewReader reads the header of a snapshot from r and returns a reader of its
+// entries.  ErrInvalidHeader is returned when r does not start with a
+// snapshot header, and ErrUnknownVersion for snapshots of another version.
+func NewReader(r io.Reader) (*Reader, error) {
+	sr := &Reader{
+		r:    bufio.NewReader(r),
+		hash: sha256.New(),
+	}
+	var b [headerLen]byte
+	if _, err := io.ReadFull(sr.r, b[:]); err != nil {
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			return nil, ErrInvalidHeader
+		}
+		return nil, err
+	}
+	header, err := deserializeHeader(b[:])
+	if err != nil {
+		return nil, err
+	}
+	sr.hash.Write(b[:])
+	sr.header = *header
+	return sr, nil
+}
+
+// Header returns the header of the snapshot.
+func (r *Reader) Header() Header {
+	return r.header
+}
+
+// Next returns the next entry of the snapshot, or io.EOF once all the entries
+// declared by the header were read and the trailing hash matched them.
+// ErrUnsorted is returned for entries out of order and ErrChecksum when the
+// hash does not match.
+func (r *Reader) Next() (*Entry, error) {
+	if r.done {
+		return nil, io.EOF
+	}
+	if r.read == r.header.Count {
+		var sum [sha256.Size]byte
+		if _, err := io.ReadFull(r.r, sum[:]); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+		copy(r.sum[:], r.hash.Sum(nil))
+		if sum != r.sum {
+			return nil, ErrChecksum
+		}
+		r.done = true
+		return nil, io.EOF
+	}
+
+	e, err := deserializeEntry(io.TeeReader(r.r, r.hash))
+	if err != nil {
+		return nil, err
+	}
+	if r.read > 0 && compareOutPoints(&r.prev, &e.OutPoint) >= 0 {
+		return nil, ErrUnsorted
+	}
+	r.prev = e.OutPoint
+	r.read++
+	return e, nil
+}
+
+// Sum returns the hash of the snapshot, which is valid once Next returned
+// io.EOF.
+func (r *Reader) Sum() [sha256.Size]byte {
+	return r.sum
+}
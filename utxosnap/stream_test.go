@@ -0,0 +1,215 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package utxosnap_test
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil/utxosnap"
+)
+
+// headerLen is the length of a snapshot header.
+const headerLen = 52
+
+// testEntries returns sorted entries covering every kind of token.
+func testEntries() []*utxosnap.Entry {
+	rights := chainhash.Hash{0x52}
+	entries := []*utxosnap.Entry{
+		{
+			OutPoint:   wire.OutPoint{Hash: chainhash.Hash{0x02}, Index: 0},
+			Amount:     5000000000,
+			PkScript:   []byte{0x51},
+			Height:     1,
+			IsCoinBase: true,
+		},
+		{
+			OutPoint:  wire.OutPoint{Hash: chainhash.Hash{0x01}, Index: 7},
+			TokenType: 3,
+			Hash:      chainhash.Hash{0x99},
+			Rights:    &rights,
+			PkScript:  []byte{0x52, 0x53},
+			Height:    500000,
+		},
+		{
+			OutPoint:  wire.OutPoint{Hash: chainhash.Hash{0x01}, Index: 300},
+			TokenType: 4,
+			Amount:    1,
+			PkScript:  []byte{},
+			Height:    0,
+		},
+	}
+	utxosnap.SortEntries(entries)
+	return entries
+}
+
+// writeSnapshot returns the snapshot of entries and its hash.
+func writeSnapshot(t *testing.T, entries []*utxosnap.Entry) ([]byte, [sha256.Size]byte) {
+	t.Helper()
+	var buf bytes.Buffer
+	header := &utxosnap.Header{
+		BlockHash: chainhash.Hash{0xbb},
+		Height:    500001,
+		Count:     uint64(len(entries)),
+	}
+	w, err := utxosnap.NewWriter(&buf, header)
+	if err != nil {
+		t.Fatalf("NewWriter: unexpected error: %v", err)
+	}
+	for _, e := range entries {
+		if err := w.Write(e); err != nil {
+			t.Fatalf("Write: unexpected error: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close: unexpected error: %v", err)
+	}
+	return buf.Bytes(), w.Sum()
+}
+
+// readSnapshot returns the entries of the snapshot b.
+func readSnapshot(b []byte) (*utxosnap.Reader, []*utxosnap.Entry, error) {
+	r, err := utxosnap.NewReader(bytes.NewReader(b))
+	if err != nil {
+		return nil, nil, err
+	}
+	var entries []*utxosnap.Entry
+	for {
+		e, err := r.Next()
+		if err == io.EOF {
+			return r, entries, nil
+		}
+		if err != nil {
+			return r, entries, err
+		}
+		entries = append(entries, e)
+	}
+}
+
+// TestSnapshot ensures snapshots round-trip with their header and hash.
+func TestSnapshot(t *testing.T) {
+	entries := testEntries()
+	b, sum := writeSnapshot(t, entries)
+	if got := sha256.Sum256(b[:len(b)-sha256.Size]); got != sum ||
+		!bytes.Equal(b[len(b)-sha256.Size:], sum[:]) {
+		t.Errorf("Sum: got %x, want %x", sum, got)
+	}
+
+	r, got, err := readSnapshot(b)
+	if err != nil {
+		t.Fatalf("Next: unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, entries) {
+		t.Errorf("Next: got %+v, want %+v", got, entries)
+	}
+	header := r.Header()
+	if header.BlockHash != (chainhash.Hash{0xbb}) || header.Height != 500001 ||
+		header.Count != 3 || r.Sum() != sum {
+		t.Errorf("Reader: got header %+v and sum %x", header, r.Sum())
+	}
+	if _, err := r.Next(); err != io.EOF {
+		t.Errorf("Next: got error %v after the end, want %v", err, io.EOF)
+	}
+
+	// The empty set has a snapshot too.
+	empty, _ := writeSnapshot(t, nil)
+	if _, got, err := readSnapshot(empty); err != nil || len(got) != 0 {
+		t.Errorf("Next: got %d entries (%v), want none", len(got), err)
+	}
+}
+
+// TestWriterErrors ensures writers reject entries out of order and counts not
+// matching their header.
+func TestWriterErrors(t *testing.T) {
+	entries := testEntries()
+	w, err := utxosnap.NewWriter(io.Discard, &utxosnap.Header{Count: 2})
+	if err != nil {
+		t.Fatalf("NewWriter: unexpected error: %v", err)
+	}
+	if err := w.Write(entries[1]); err != nil {
+		t.Fatalf("Write: unexpected error: %v", err)
+	}
+	for _, e := range entries[:2] {
+		if err := w.Write(e); err != utxosnap.ErrUnsorted {
+			t.Errorf("Write: got error %v, want %v", err,
+				utxosnap.ErrUnsorted)
+		}
+	}
+	if err := w.Close(); err != utxosnap.ErrCountMismatch {
+		t.Errorf("Close: got error %v, want %v", err,
+			utxosnap.ErrCountMismatch)
+	}
+	if err := w.Write(entries[2]); err != nil {
+		t.Fatalf("Write: unexpected error: %v", err)
+	}
+	if err := w.Write(&utxosnap.Entry{OutPoint: wire.OutPoint{
+		Hash: chainhash.Hash{0xff}}}); err != utxosnap.ErrCountMismatch {
+		t.Errorf("Write: got error %v, want %v", err,
+			utxosnap.ErrCountMismatch)
+	}
+
+	invalid := &utxosnap.Entry{Height: -1}
+	w, _ = utxosnap.NewWriter(io.Discard, &utxosnap.Header{Count: 1})
+	if err := w.Write(invalid); err != utxosnap.ErrInvalidEntry {
+		t.Errorf("Write: got error %v, want %v", err,
+			utxosnap.ErrInvalidEntry)
+	}
+	_, err = utxosnap.NewWriter(io.Discard, &utxosnap.Header{Height: -1})
+	if err != utxosnap.ErrInvalidHeader {
+		t.Errorf("NewWriter: got error %v, want %v", err,
+			utxosnap.ErrInvalidHeader)
+	}
+}
+
+// TestReaderErrors ensures readers reject corrupt snapshots.
+func TestReaderErrors(t *testing.T) {
+	b, _ := writeSnapshot(t, testEntries())
+	corrupt := func(i int) []byte {
+		c := append([]byte(nil), b...)
+		c[i] ^= 0x01
+		return c
+	}
+
+	// A snapshot with its last two entries swapped, written by hand since
+	// writers refuse to.
+	entries := testEntries()
+	first, _ := writeSnapshot(t, entries[:1])
+	third, _ := writeSnapshot(t, entries[2:])
+	second, _ := writeSnapshot(t, entries[1:2])
+	body := func(snap []byte) []byte {
+		return snap[headerLen : len(snap)-sha256.Size]
+	}
+	unsorted := append([]byte(nil), b[:headerLen]...)
+	unsorted = append(unsorted, body(first)...)
+	unsorted = append(unsorted, body(third)...)
+	unsorted = append(unsorted, body(second)...)
+
+	tests := []struct {
+		name string
+		b    []byte
+		want error
+	}{
+		{"empty", nil, utxosnap.ErrInvalidHeader},
+		{"magic", corrupt(0), utxosnap.ErrInvalidHeader},
+		{"version", corrupt(4), utxosnap.ErrUnknownVersion},
+		{"entry", corrupt(len(b) - sha256.Size - 1), utxosnap.ErrChecksum},
+		{"hash", corrupt(len(b) - 1), utxosnap.ErrChecksum},
+		{"truncated", b[:len(b)-1], io.ErrUnexpectedEOF},
+		{"truncated entry", b[:60], io.ErrUnexpectedEOF},
+		{"unsorted", unsorted, utxosnap.ErrUnsorted},
+	}
+	for _, test := range tests {
+		_, _, err := readSnapshot(test.b)
+		if err != test.want {
+			t.Errorf("%s: got error %v, want %v", test.name, err,
+				test.want)
+		}
+	}
+}
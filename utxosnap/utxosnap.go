@@ -0,0 +1,308 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package utxosnap defines a compact, versioned serialization of the set of
+// unspent transaction outputs of a chain at some block, so nodes and tools
+// can exchange it to sync without replaying the chain.
+//
+// A snapshot is a header naming the block and the number of entries, the
+// entries in ascending order of outpoint, and a trailing SHA256 hash of all
+// the bytes before it.  Entries being sorted, the same set always serializes
+// to the same snapshot, and its hash commits to the set.  Snapshots are
+// written and read as streams so sets larger than memory can be processed.
+package utxosnap
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcd/wire/common"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/omega/token"
+)
+
+// Version is the version of the snapshot format written by this package.
+const Version = 1
+
+// MaxScriptSize is the length of the longest public key script of an entry.
+const MaxScriptSize = 10000
+
+// magic starts every snapshot.
+var magic = [4]byte{'U', 'T', 'X', 'O'}
+
+var (
+	// ErrInvalidEntry describes an error where an entry has a negative
+	// height or amount, rights not matching its token type, or a script
+	// longer than MaxScriptSize.
+	ErrInvalidEntry = errors.New("invalid snapshot entry")
+
+	// ErrInvalidHeader describes an error where a snapshot does not start
+	// with a valid header.
+	ErrInvalidHeader = errors.New("invalid snapshot header")
+
+	// ErrUnknownVersion describes an error where a snapshot is of a
+	// version this package does not read.
+	ErrUnknownVersion = errors.New("unknown snapshot version")
+
+	// ErrUnsorted describes an error where the entries of a snapshot are
+	// not in strictly ascending order of outpoint.
+	ErrUnsorted = errors.New("snapshot entries are not sorted")
+
+	// ErrCountMismatch describes an error where a snapshot holds another
+	// number of entries than its header declares.
+	ErrCountMismatch = errors.New("snapshot entry count mismatch")
+
+	// ErrChecksum describes an error where the hash trailing a snapshot
+	// does not match its contents.
+	ErrChecksum = errors.New("snapshot checksum mismatch")
+)
+
+// Header describes the set of a snapshot.
+type Header struct {
+	// BlockHash and Height identify the last block whose transactions
+	// the set reflects.
+	BlockHash chainhash.Hash
+	Height    int32
+
+	// Count is the number of entries of the snapshot.
+	Count uint64
+}
+
+// headerLen is the length of a serialized header: the magic bytes, the
+// version, the block hash, the height and the count.
+const headerLen = 4 + 4 + chainhash.HashSize + 4 + 8
+
+// serialize returns the serialization of the header.
+func (h *Header) serialize() []byte {
+	b := make([]byte, 0, headerLen)
+	b = append(b, magic[:]...)
+	b = binary.LittleEndian.AppendUint32(b, Version)
+	b = append(b, h.BlockHash[:]...)
+	b = binary.LittleEndian.AppendUint32(b, uint32(h.Height))
+	return binary.LittleEndian.AppendUint64(b, h.Count)
+}
+
+// deserializeHeader parses a header serialized by serialize.
+func deserializeHeader(b []byte) (*Header, error) {
+	if !bytes.Equal(b[:4], magic[:]) {
+		return nil, ErrInvalidHeader
+	}
+	if binary.LittleEndian.Uint32(b[4:]) != Version {
+		return nil, ErrUnknownVersion
+	}
+	h := &Header{
+		Height: int32(binary.LittleEndian.Uint32(b[8+chainhash.HashSize:])),
+		Count:  binary.LittleEndian.Uint64(b[12+chainhash.HashSize:]),
+	}
+	copy(h.BlockHash[:], b[8:])
+	if h.Height < 0 {
+		return nil, ErrInvalidHeader
+	}
+	return h, nil
+}
+
+// Entry is an unspent output of the set.
+type Entry struct {
+	// OutPoint is the outpoint of the output.
+	OutPoint wire.OutPoint
+
+	// TokenType is the type of the token of the output.
+	TokenType uint64
+
+	// Amount is the value of numeric tokens, whose type has the lowest
+	// bit clear.
+	Amount btcutil.Amount
+
+	// Hash is the value of hash tokens, whose type has the lowest bit
+	// set.
+	Hash chainhash.Hash
+
+	// Rights are the rights of tokens whose type has the second lowest
+	// bit set, and nil for others.
+	Rights *chainhash.Hash
+
+	// PkScript is the public key script of the output.
+	PkScript []byte
+
+	// Height is the height of the block holding the transaction of the
+	// output.
+	Height int32
+
+	// IsCoinBase is whether that transaction is a coinbase.
+	IsCoinBase bool
+}
+
+// NewEntry returns the entry of txOut, the output at op of a transaction of
+// the block at height.  ErrInvalidEntry is returned when the value of the
+// token of txOut does not match its type.
+func NewEntry(op wire.OutPoint, txOut *wire.TxOut, height int32,
+	isCoinBase bool) (*Entry, error) {
+	e := &Entry{
+		OutPoint:   op,
+		TokenType:  txOut.Token.TokenType,
+		Rights:     txOut.Token.Rights,
+		PkScript:   txOut.PkScript,
+		Height:     height,
+		IsCoinBase: isCoinBase,
+	}
+	switch v := txOut.Token.Value.(type) {
+	case *token.NumeralVal:
+		if e.TokenType&1 != 0 {
+			return nil, ErrInvalidEntry
+		}
+		e.Amount = btcutil.Amount(v.Val)
+	case *token.HashVal:
+		if e.TokenType&1 == 0 {
+			return nil, ErrInvalidEntry
+		}
+		e.Hash = v.Hash
+	default:
+		return nil, ErrInvalidEntry
+	}
+	if err := e.validate(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// TxOut returns the output of the entry.
+func (e *Entry) TxOut() *wire.TxOut {
+	tok := token.Token{TokenType: e.TokenType, Rights: e.Rights}
+	if e.TokenType&1 != 0 {
+		tok.Value = &token.HashVal{Hash: e.Hash}
+	} else {
+		tok.Value = &token.NumeralVal{Val: int64(e.Amount)}
+	}
+	return &wire.TxOut{Token: tok, PkScript: e.PkScript}
+}
+
+// validate returns ErrInvalidEntry when the entry can't be serialized.
+func (e *Entry) validate() error {
+	if e.Height < 0 || (e.TokenType&1 == 0 && e.Amount < 0) ||
+		(e.TokenType&2 != 0) != (e.Rights != nil) ||
+		len(e.PkScript) > MaxScriptSize {
+		return ErrInvalidEntry
+	}
+	return nil
+}
+
+// serialize appends the entry to buf: the outpoint, the height and coinbase
+// flag as a single variable length integer, the token type, the amount or
+// hash, the rights if any, and the script prefixed by its length.
+func (e *Entry) serialize(buf *bytes.Buffer) error {
+	if err := e.validate(); err != nil {
+		return err
+	}
+	buf.Write(e.OutPoint.Hash[:])
+	common.WriteVarInt(buf, 0, uint64(e.OutPoint.Index))
+	code := uint64(e.Height) << 1
+	if e.IsCoinBase {
+		code |= 1
+	}
+	common.WriteVarInt(buf, 0, code)
+	common.WriteVarInt(buf, 0, e.TokenType)
+	if e.TokenType&1 != 0 {
+		buf.Write(e.Hash[:])
+	} else {
+		common.WriteVarInt(buf, 0, uint64(e.Amount))
+	}
+	if e.Rights != nil {
+		buf.Write(e.Rights[:])
+	}
+	common.WriteVarInt(buf, 0, uint64(len(e.PkScript)))
+	buf.Write(e.PkScript)
+	return nil
+}
+
+// readVarInt reads a variable length integer no larger than max, returning
+// ErrInvalidEntry for larger ones and io.ErrUnexpectedEOF for truncated
+// ones.
+func readVarInt(r io.Reader, max uint64) (uint64, error) {
+	v, err := common.ReadVarInt(r, 0)
+	if err == io.EOF {
+		return 0, io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return 0, err
+	}
+	if v > max {
+		return 0, ErrInvalidEntry
+	}
+	return v, nil
+}
+
+// deserializeEntry reads an entry written by serialize.
+func deserializeEntry(r io.Reader) (*Entry, error) {
+	e := new(Entry)
+	if _, err := io.ReadFull(r, e.OutPoint.Hash[:]); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	index, err := readVarInt(r, 1<<32-1)
+	if err != nil {
+		return nil, err
+	}
+	e.OutPoint.Index = uint32(index)
+	code, err := readVarInt(r, 1<<32-1)
+	if err != nil {
+		return nil, err
+	}
+	e.Height, e.IsCoinBase = int32(code>>1), code&1 != 0
+	if e.TokenType, err = readVarInt(r, 1<<64-1); err != nil {
+		return nil, err
+	}
+	if e.TokenType&1 != 0 {
+		if _, err := io.ReadFull(r, e.Hash[:]); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+	} else {
+		amount, err := readVarInt(r, 1<<63-1)
+		if err != nil {
+			return nil, err
+		}
+		e.Amount = btcutil.Amount(amount)
+	}
+	if e.TokenType&2 != 0 {
+		e.Rights = new(chainhash.Hash)
+		if _, err := io.ReadFull(r, e.Rights[:]); err != nil {
+			return nil, unexpectedEOF(err)
+		}
+	}
+	n, err := readVarInt(r, MaxScriptSize)
+	if err != nil {
+		return nil, err
+	}
+	e.PkScript = make([]byte, n)
+	if _, err := io.ReadFull(r, e.PkScript); err != nil {
+		return nil, unexpectedEOF(err)
+	}
+	return e, nil
+}
+
+// unexpectedEOF returns io.ErrUnexpectedEOF for io.EOF, since a snapshot
+// never ends within an entry, and err otherwise.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// compareOutPoints returns -1, 0 or +1 depending on whether a sorts before,
+// with or after b: by transaction hash, then output index.
+func compareOutPoints(a, b *wire.OutPoint) int {
+	if c := bytes.Compare(a.Hash[:], b.Hash[:]); c != 0 {
+		return c
+	}
+	switch {
+	case a.Index < b.Index:
+		return -1
+	case a.Index > b.Index:
+		return 1
+	}
+	return 0
+}
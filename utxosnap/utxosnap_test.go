@@ -0,0 +1,70 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package utxosnap_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/zeusyf/btcd/chaincfg/chainhash"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil/utxosnap"
+	"github.com/zeusyf/omega/token"
+)
+
+// TestNewEntry ensures entries convert to and from outputs, and outputs whose
+// token can't be represented are rejected.
+func TestNewEntry(t *testing.T) {
+	rights := chainhash.Hash{0x52}
+	outs := []*wire.TxOut{
+		{
+			Token:    token.Token{Value: &token.NumeralVal{Val: 5000}},
+			PkScript: []byte{0x51},
+		},
+		{
+			Token: token.Token{
+				TokenType: 3,
+				Value:     &token.HashVal{Hash: chainhash.Hash{0x01}},
+				Rights:    &rights,
+			},
+			PkScript: []byte{0x52},
+		},
+	}
+	op := wire.OutPoint{Hash: chainhash.Hash{0xaa}, Index: 1}
+	for i, out := range outs {
+		e, err := utxosnap.NewEntry(op, out, 100, i == 0)
+		if err != nil {
+			t.Fatalf("NewEntry #%d: unexpected error: %v", i, err)
+		}
+		if e.OutPoint != op || e.Height != 100 || e.IsCoinBase != (i == 0) {
+			t.Errorf("NewEntry #%d: got %+v", i, e)
+		}
+		if got := e.TxOut(); !reflect.DeepEqual(got, out) {
+			t.Errorf("TxOut #%d: got %+v, want %+v", i, got, out)
+		}
+	}
+
+	invalid := []struct {
+		out    *wire.TxOut
+		height int32
+	}{
+		{&wire.TxOut{Token: token.Token{TokenType: 1,
+			Value: &token.NumeralVal{Val: 1}}}, 0},
+		{&wire.TxOut{Token: token.Token{Value: &token.HashVal{}}}, 0},
+		{&wire.TxOut{Token: token.Token{Value: &token.NumeralVal{Val: -1}}}, 0},
+		{&wire.TxOut{Token: token.Token{TokenType: 2,
+			Value: &token.NumeralVal{Val: 1}}}, 0},
+		{&wire.TxOut{Token: token.Token{Value: &token.NumeralVal{Val: 1}},
+			PkScript: make([]byte, utxosnap.MaxScriptSize+1)}, 0},
+		{&wire.TxOut{Token: token.Token{Value: &token.NumeralVal{Val: 1}}}, -1},
+	}
+	for i, test := range invalid {
+		_, err := utxosnap.NewEntry(op, test.out, test.height, false)
+		if err != utxosnap.ErrInvalidEntry {
+			t.Errorf("NewEntry #%d: got error %v, want %v", i, err,
+				utxosnap.ErrInvalidEntry)
+		}
+	}
+}
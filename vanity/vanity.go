@@ -0,0 +1,206 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package vanity searches for vanity addresses: addresses matching a chosen
+// prefix or regular expression.
+//
+// Rather than drawing random keys, a search walks the non-hardened children
+// of a base HD key in order of index, so a found key is recovered from the
+// base key and its index alone, and an interrupted search is resumed from the
+// index reported by its last progress callback.  Children are derived in
+// rounds by an hdkeychain.BulkDeriver spread over several goroutines, and a
+// search returns the lowest matching index, so its result does not depend on
+// the number of goroutines or on scheduling.
+package vanity
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/base58"
+	"github.com/zeusyf/btcutil/hdkeychain"
+)
+
+// DefaultRoundSize is the number of children derived and matched between two
+// progress callbacks when no round size is configured.
+const DefaultRoundSize = 4096
+
+var (
+	// ErrInvalidPrefix describes an error where a prefix has characters
+	// outside the base58 alphabet, which no address can start with.
+	ErrInvalidPrefix = errors.New("prefix is not base58")
+
+	// ErrExhausted describes an error where every non-hardened child of
+	// the base key was tried without a match.
+	ErrExhausted = errors.New("vanity search exhausted the base key")
+)
+
+// Matcher matches encoded addresses.
+type Matcher interface {
+	// Match returns whether the encoded address addr is a match.
+	Match(addr string) bool
+}
+
+// MatcherFunc is an adapter allowing an ordinary function to be used as a
+// Matcher.
+type MatcherFunc func(addr string) bool
+
+// Match returns f(addr).  Part of the Matcher interface.
+func (f MatcherFunc) Match(addr string) bool {
+	return f(addr)
+}
+
+// PrefixMatcher returns a matcher of the addresses starting with prefix.
+// ErrInvalidPrefix is returned when prefix has characters outside the base58
+// alphabet.  Note that the first character of an address is set by its
+// network, so most prefixes should start with it.
+func PrefixMatcher(prefix string) (Matcher, error) {
+	if prefix != "" && len(base58.Decode(prefix)) == 0 {
+		return nil, ErrInvalidPrefix
+	}
+	return MatcherFunc(func(addr string) bool {
+		return strings.HasPrefix(addr, prefix)
+	}), nil
+}
+
+// RegexpMatcher returns a matcher of the addresses matched by re.
+func RegexpMatcher(re *regexp.Regexp) Matcher {
+	return MatcherFunc(re.MatchString)
+}
+
+// Progress reports the progress of a search.
+type Progress struct {
+	// Next is the index the search continues from, and from which a new
+	// search resumes it with WithStart.
+	Next uint32
+
+	// Tried is the number of children tried by the search.
+	Tried uint64
+
+	// Elapsed is the time since the search started.
+	Elapsed time.Duration
+}
+
+// Result is a found vanity address.
+type Result struct {
+	// Index is the index of the child of the base key with the address.
+	Index uint32
+
+	// Key is the child of the base key with the address.  It is private
+	// when the base key is.
+	Key *hdkeychain.ExtendedKey
+
+	// Address is the matching address.
+	Address *btcutil.AddressPubKeyHash
+}
+
+// Option configures a search.
+type Option func(*searcher)
+
+// WithWorkers sets the number of goroutines deriving children.  Values less
+// than one select runtime.NumCPU, which is the default.
+func WithWorkers(n int) Option {
+	return func(s *searcher) {
+		s.workers = n
+	}
+}
+
+// WithStart sets the index of the first child tried, such as the Next index
+// of the last progress of an interrupted search.  By default the search
+// starts at index zero.
+func WithStart(index uint32) Option {
+	return func(s *searcher) {
+		s.start = index
+	}
+}
+
+// WithRoundSize sets the number of children derived and matched between two
+// progress callbacks.  DefaultRoundSize is used by default.
+func WithRoundSize(n int) Option {
+	return func(s *searcher) {
+		s.roundSize = n
+	}
+}
+
+// WithProgress sets a function called with the progress of the search after
+// every round.  It is called from the goroutine running Search.
+func WithProgress(f func(Progress)) Option {
+	return func(s *searcher) {
+		s.progress = f
+	}
+}
+
+// searcher holds the configuration of a search.
+type searcher struct {
+	workers   int
+	start     uint32
+	roundSize int
+	progress  func(Progress)
+}
+
+// Search returns the child of base with the lowest index, from the start
+// index on, whose pay-to-pubkey-hash address on the network net is matched by
+// m.  The error of ctx is returned when it is done before a match is found,
+// even in the middle of a round, and ErrExhausted when no non-hardened child
+// of base matches.
+func Search(ctx context.Context, base *hdkeychain.ExtendedKey, net *chaincfg.Params,
+	m Matcher, opts ...Option) (*Result, error) {
+	s := searcher{roundSize: DefaultRoundSize}
+	for _, opt := range opts {
+		opt(&s)
+	}
+	if s.roundSize < 1 {
+		s.roundSize = 1
+	}
+	d, err := hdkeychain.NewBulkDeriver(base,
+		hdkeychain.WithWorkers(s.workers))
+	if err != nil {
+		return nil, err
+	}
+
+	began := time.Now()
+	next := uint64(s.start)
+	var tried uint64
+	for next < hdkeychain.HardenedKeyStart {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		count := uint64(s.roundSize)
+		if next+count > hdkeychain.HardenedKeyStart {
+			count = hdkeychain.HardenedKeyStart - next
+		}
+		addrs, err := d.AddressesContext(ctx, net, uint32(next),
+			int(count))
+		if err != nil {
+			return nil, err
+		}
+		for i, addr := range addrs {
+			if addr == nil || !m.Match(addr.EncodeAddress()) {
+				continue
+			}
+			index := uint32(next) + uint32(i)
+			key, err := base.Child(index)
+			if err != nil {
+				return nil, err
+			}
+			return &Result{Index: index, Key: key, Address: addr}, nil
+		}
+
+		next += count
+		tried += count
+		if s.progress != nil {
+			s.progress(Progress{
+				Next:    uint32(next),
+				Tried:   tried,
+				Elapsed: time.Since(began),
+			})
+		}
+	}
+	return nil, ErrExhausted
+}
@@ -0,0 +1,142 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package vanity_test
+
+import (
+	"bytes"
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcutil/hdkeychain"
+	"github.com/zeusyf/btcutil/vanity"
+)
+
+// testBase returns a master key of a seed of repeated bytes.
+func testBase(t *testing.T) *hdkeychain.ExtendedKey {
+	t.Helper()
+	base, err := hdkeychain.NewMaster(bytes.Repeat([]byte{0x5a}, 32),
+		&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("NewMaster: %v", err)
+	}
+	return base
+}
+
+// childAddress returns the encoded address of the child of base at index.
+func childAddress(t *testing.T, base *hdkeychain.ExtendedKey, index uint32) string {
+	t.Helper()
+	child, err := base.Child(index)
+	if err != nil {
+		t.Fatalf("Child: %v", err)
+	}
+	addr, err := child.Address(&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("Address: %v", err)
+	}
+	return addr.EncodeAddress()
+}
+
+// TestSearch ensures searches find the lowest matching child whatever the
+// number of workers, report their progress and resume from it.
+func TestSearch(t *testing.T) {
+	base := testBase(t)
+	net := &chaincfg.MainNetParams
+	target := childAddress(t, base, 25)
+	m := vanity.MatcherFunc(func(addr string) bool {
+		return addr == target
+	})
+
+	for _, workers := range []int{1, 4} {
+		var progress []vanity.Progress
+		res, err := vanity.Search(context.Background(), base, net, m,
+			vanity.WithWorkers(workers), vanity.WithRoundSize(10),
+			vanity.WithProgress(func(p vanity.Progress) {
+				progress = append(progress, p)
+			}))
+		if err != nil {
+			t.Fatalf("%d workers: Search: %v", workers, err)
+		}
+		if res.Index != 25 || res.Address.EncodeAddress() != target ||
+			!res.Key.IsPrivate() {
+			t.Fatalf("%d workers: Search: got index %d, address %v",
+				workers, res.Index, res.Address)
+		}
+		if len(progress) != 2 || progress[1].Next != 20 ||
+			progress[1].Tried != 20 {
+			t.Fatalf("%d workers: got progress %+v", workers, progress)
+		}
+	}
+
+	// Resuming past the match finds nothing before the search is
+	// canceled.
+	ctx, cancel := context.WithCancel(context.Background())
+	var last vanity.Progress
+	_, err := vanity.Search(ctx, base, net, m, vanity.WithStart(26),
+		vanity.WithRoundSize(5), vanity.WithProgress(func(p vanity.Progress) {
+			last = p
+			cancel()
+		}))
+	if err != context.Canceled || last.Next != 31 || last.Tried != 5 {
+		t.Fatalf("Search: got error %v, progress %+v", err, last)
+	}
+
+	// A search is canceled in the middle of a round.
+	ctx, cancel = context.WithTimeout(context.Background(),
+		10*time.Millisecond)
+	defer cancel()
+	never := vanity.MatcherFunc(func(string) bool { return false })
+	_, err = vanity.Search(ctx, base, net, never, vanity.WithRoundSize(1<<20))
+	if err != context.DeadlineExceeded {
+		t.Fatalf("Search: got error %v, want %v", err,
+			context.DeadlineExceeded)
+	}
+
+	// The search ends at the last non-hardened child.
+	_, err = vanity.Search(context.Background(), base, net, never,
+		vanity.WithStart(hdkeychain.HardenedKeyStart-3))
+	if err != vanity.ErrExhausted {
+		t.Fatalf("Search: got error %v, want %v", err, vanity.ErrExhausted)
+	}
+}
+
+// TestMatchers ensures prefixes are validated and prefix and regular
+// expression matchers find the lowest matching child.
+func TestMatchers(t *testing.T) {
+	if _, err := vanity.PrefixMatcher("1O"); err != vanity.ErrInvalidPrefix {
+		t.Errorf("PrefixMatcher: got error %v, want %v", err,
+			vanity.ErrInvalidPrefix)
+	}
+
+	base := testBase(t)
+	net := &chaincfg.MainNetParams
+	prefix := childAddress(t, base, 3)[:2]
+	pm, err := vanity.PrefixMatcher(prefix)
+	if err != nil {
+		t.Fatalf("PrefixMatcher: %v", err)
+	}
+	matchers := []vanity.Matcher{
+		pm,
+		vanity.RegexpMatcher(regexp.MustCompile("^" + prefix)),
+	}
+	for i, m := range matchers {
+		res, err := vanity.Search(context.Background(), base, net, m,
+			vanity.WithRoundSize(16))
+		if err != nil {
+			t.Fatalf("#%d Search: %v", i, err)
+		}
+		if res.Index > 3 || !m.Match(res.Address.EncodeAddress()) {
+			t.Fatalf("#%d Search: got index %d, address %v", i,
+				res.Index, res.Address)
+		}
+		for index := uint32(0); index < res.Index; index++ {
+			if m.Match(childAddress(t, base, index)) {
+				t.Fatalf("#%d Search: skipped index %d", i, index)
+			}
+		}
+	}
+}
@@ -0,0 +1,112 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package vault
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/contracts"
+	"github.com/zeusyf/btcutil/psbt"
+	"github.com/zeusyf/btcutil/txbuilder"
+)
+
+var (
+	// ErrInvalidFee describes an error where the fee of a transaction
+	// spending a vault is negative or leaves nothing of its funds.
+	ErrInvalidFee = errors.New("fee must be less than the vault funds")
+
+	// ErrNotVaultSpend describes an error where a packet finalized by a
+	// vault does not spend its funds with its only input.
+	ErrNotVaultSpend = errors.New("packet does not spend the vault")
+)
+
+// Recovery returns the transaction sweeping the vault funds less fee to the
+// recovery address, for the recovery key to sign.  It spends the funds at
+// once, so a watcher broadcasting it beats a spend by the hot key.  The
+// options configure the transaction as for txbuilder.New.
+func (v *Vault) Recovery(fee btcutil.Amount, opts ...txbuilder.Option) (*psbt.Packet, error) {
+	return v.spend(&v.contract.Recover, v.recoveryScript, fee, opts)
+}
+
+// Spend returns the transaction paying the vault funds less fee to pkScript,
+// for the hot key to sign.  The input has the relative lock time of the
+// delay, so the transaction is not valid before the delay has passed since
+// the funds confirmed.  The options configure the transaction as for
+// txbuilder.New.
+func (v *Vault) Spend(pkScript []byte, fee btcutil.Amount, opts ...txbuilder.Option) (*psbt.Packet, error) {
+	return v.spend(&v.contract.Spend, pkScript, fee, opts)
+}
+
+// spend returns the packet of a transaction spending the vault funds less fee
+// to pkScript along branch.  The input carries the funding transaction and
+// the redeem script, which signers need to sign it.
+func (v *Vault) spend(branch *contracts.Branch, pkScript []byte,
+	fee btcutil.Amount, opts []txbuilder.Option) (*psbt.Packet, error) {
+	if v.fundTx == nil {
+		return nil, ErrNotFunded
+	}
+	if fee < 0 || fee >= v.amount {
+		return nil, ErrInvalidFee
+	}
+
+	tx, err := txbuilder.New(opts...).
+		AddInputWithSequence(v.outPoint, branch.Sequence).
+		AddOutput(pkScript, v.amount-fee).
+		Build()
+	if err != nil {
+		return nil, err
+	}
+	packet, err := psbt.New(tx)
+	if err != nil {
+		return nil, err
+	}
+	packet.Inputs[0].NonWitnessUtxo = v.fundTx
+	packet.Inputs[0].RedeemScript = v.contract.RedeemScript
+	return packet, nil
+}
+
+// Finalize completes the signature script of a recovery or spending
+// transaction of the vault from the partial signature of the key of its
+// branch, which the sequence number of its input tells.  An error wrapping
+// psbt.ErrNotFinalizable and psbt.ErrIncomplete is returned when the key has
+// not signed yet.
+func (v *Vault) Finalize(p *psbt.Packet) error {
+	if len(p.UnsignedTx.TxIn) != 1 ||
+		p.UnsignedTx.TxIn[0].PreviousOutPoint != v.outPoint {
+		return ErrNotVaultSpend
+	}
+	branch := &v.contract.Recover
+	if p.UnsignedTx.TxIn[0].Sequence == v.contract.Spend.Sequence {
+		branch = &v.contract.Spend
+	}
+
+	in := &p.Inputs[0]
+	pubKey := branch.PubKeys[0].SerializeCompressed()
+	var sig []byte
+	for _, partial := range in.PartialSigs {
+		if bytes.Equal(partial.PubKey, pubKey) {
+			sig = partial.Signature
+			break
+		}
+	}
+	if sig == nil {
+		return fmt.Errorf("%w: %w", psbt.ErrNotFinalizable,
+			psbt.ErrIncomplete)
+	}
+	script, err := branch.SignatureScript(v.contract.RedeemScript,
+		[][]byte{sig}, nil)
+	if err != nil {
+		return err
+	}
+	*in = psbt.Input{
+		NonWitnessUtxo: in.NonWitnessUtxo,
+		FinalScriptSig: script,
+		Unknowns:       in.Unknowns,
+	}
+	return nil
+}
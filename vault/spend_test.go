@@ -0,0 +1,121 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package vault_test
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/locktime"
+	"github.com/zeusyf/btcutil/psbt"
+	"github.com/zeusyf/btcutil/vault"
+	"github.com/zeusyf/omega/token"
+)
+
+// TestRecovery ensures the recovery transaction sweeps the vault funds at
+// once to the recovery address, and is finalized with the recovery key's
+// signature.
+func TestRecovery(t *testing.T) {
+	v := testVault(t)
+	if _, err := v.Recovery(100); err != vault.ErrNotFunded {
+		t.Fatalf("Recovery: got error %v, want %v", err, vault.ErrNotFunded)
+	}
+	fundTx := fundingTx(0, 5000, v.PkScript())
+	if err := v.Fund(fundTx); err != nil {
+		t.Fatalf("Fund: unexpected error: %v", err)
+	}
+	for _, fee := range []int64{-1, 5000} {
+		if _, err := v.Recovery(btcutil.Amount(fee)); err != vault.ErrInvalidFee {
+			t.Errorf("Recovery: fee %d: got error %v, want %v", fee,
+				err, vault.ErrInvalidFee)
+		}
+	}
+
+	packet, err := v.Recovery(100)
+	if err != nil {
+		t.Fatalf("Recovery: unexpected error: %v", err)
+	}
+	tx := packet.UnsignedTx
+	op, _ := v.Funds()
+	if len(tx.TxIn) != 1 || tx.TxIn[0].PreviousOutPoint != op ||
+		tx.TxIn[0].Sequence != locktime.SequenceFinal {
+		t.Fatalf("Recovery: got inputs %v", tx.TxIn)
+	}
+	if len(tx.TxOut) != 1 ||
+		!bytes.Equal(tx.TxOut[0].PkScript, v.RecoveryScript()) ||
+		tx.TxOut[0].Token.Value.(*token.NumeralVal).Val != 4900 {
+		t.Fatalf("Recovery: got outputs %v", tx.TxOut)
+	}
+	if packet.Inputs[0].NonWitnessUtxo != fundTx ||
+		!bytes.Equal(packet.Inputs[0].RedeemScript,
+			v.Contract().RedeemScript) {
+		t.Fatalf("Recovery: input lacks the funding transaction or " +
+			"redeem script")
+	}
+
+	// A signature by the hot key does not finalize the recovery.
+	sig := []byte{0x30, 0x01, 0x01}
+	packet.Inputs[0].PartialSigs = []*psbt.PartialSig{
+		{PubKey: pubKey(1).SerializeCompressed(), Signature: sig},
+	}
+	if err := v.Finalize(packet); !errors.Is(err, psbt.ErrIncomplete) {
+		t.Fatalf("Finalize: got error %v, want %v", err, psbt.ErrIncomplete)
+	}
+	packet.Inputs[0].PartialSigs = append(packet.Inputs[0].PartialSigs,
+		&psbt.PartialSig{PubKey: pubKey(2).SerializeCompressed(),
+			Signature: sig})
+	if err := v.Finalize(packet); err != nil {
+		t.Fatalf("Finalize: unexpected error: %v", err)
+	}
+	want, _ := v.Contract().Recover.SignatureScript(
+		v.Contract().RedeemScript, [][]byte{sig}, nil)
+	in := packet.Inputs[0]
+	if !bytes.Equal(in.FinalScriptSig, want) || in.PartialSigs != nil ||
+		in.RedeemScript != nil || in.NonWitnessUtxo != fundTx {
+		t.Errorf("Finalize: got input %+v", in)
+	}
+}
+
+// TestSpend ensures the hot key's spending transaction has the relative lock
+// time of the delay, and is finalized with the hot key's signature.
+func TestSpend(t *testing.T) {
+	v := testVault(t)
+	if err := v.Fund(fundingTx(0, 5000, v.PkScript())); err != nil {
+		t.Fatalf("Fund: unexpected error: %v", err)
+	}
+	pkScript := []byte{0x51}
+	packet, err := v.Spend(pkScript, 100)
+	if err != nil {
+		t.Fatalf("Spend: unexpected error: %v", err)
+	}
+	tx := packet.UnsignedTx
+	if tx.TxIn[0].Sequence != locktime.RelativeBlocks(144).Sequence() ||
+		!bytes.Equal(tx.TxOut[0].PkScript, pkScript) {
+		t.Fatalf("Spend: got transaction %v", tx)
+	}
+
+	sig := []byte{0x30, 0x01, 0x01}
+	packet.Inputs[0].PartialSigs = []*psbt.PartialSig{
+		{PubKey: pubKey(1).SerializeCompressed(), Signature: sig},
+	}
+	if err := v.Finalize(packet); err != nil {
+		t.Fatalf("Finalize: unexpected error: %v", err)
+	}
+	want, _ := v.Contract().Spend.SignatureScript(
+		v.Contract().RedeemScript, [][]byte{sig}, nil)
+	if !bytes.Equal(packet.Inputs[0].FinalScriptSig, want) {
+		t.Errorf("Finalize: got script %x, want %x",
+			packet.Inputs[0].FinalScriptSig, want)
+	}
+
+	// A packet spending another output is rejected.
+	tx.TxIn[0].PreviousOutPoint.Index++
+	if err := v.Finalize(packet); err != vault.ErrNotVaultSpend {
+		t.Errorf("Finalize: got error %v, want %v", err,
+			vault.ErrNotVaultSpend)
+	}
+}
@@ -0,0 +1,126 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+// Package vault implements timelocked vaults for self-custody, keeping funds
+// safe from the theft of the key spending them day to day.
+//
+// Funds are unvaulted by paying the pay-to-script-hash address of a
+// contracts.Vault, from which the hot key spends them once the delay of the
+// vault has passed since they confirmed, and the recovery key at any time.
+// The recovery key signs, ahead of time and kept offline, the transaction
+// sweeping the funds to a recovery address.  A watcher of the watch addresses
+// of the vault, holding the signed recovery transaction, broadcasts it as soon
+// as funds are unvaulted without the owner's consent, well before the thief
+// holding the hot key can spend them.
+//
+// A Vault tracks the funds held by one such output, builds the recovery and
+// spending transactions as partially signed transactions, and finalizes them
+// once signed.
+package vault
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcd/txscript"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/contracts"
+	"github.com/zeusyf/btcutil/locktime"
+	"github.com/zeusyf/omega/token"
+)
+
+var (
+	// ErrNotFunding describes an error where a transaction observed as
+	// funding a vault has no output paying the base token to its address.
+	ErrNotFunding = errors.New("transaction does not fund the vault")
+
+	// ErrNotFunded describes an error where a transaction spending a vault
+	// is built before its funds were observed.
+	ErrNotFunded = errors.New("vault is not funded")
+)
+
+// Vault tracks the funds of one vault.  The zero value is not usable; a
+// Vault must be created with New.
+type Vault struct {
+	contract       *contracts.Vault
+	pkScript       []byte
+	recoveryAddr   btcutil.Address
+	recoveryScript []byte
+
+	fundTx   *wire.MsgTx
+	outPoint wire.OutPoint
+	amount   btcutil.Amount
+}
+
+// New returns the vault paying hot after delay, or recovery at once, whose
+// funds are recovered to recoveryAddr.  contracts.ErrInvalidDelay is returned
+// when delay is zero.
+func New(hot, recovery *btcec.PublicKey, delay locktime.RelativeLock,
+	recoveryAddr btcutil.Address, net *chaincfg.Params) (*Vault, error) {
+	contract, err := contracts.NewVault(hot, recovery, delay, net)
+	if err != nil {
+		return nil, err
+	}
+	pkScript, err := txscript.PayToAddrScript(contract.Address)
+	if err != nil {
+		return nil, err
+	}
+	recoveryScript, err := txscript.PayToAddrScript(recoveryAddr)
+	if err != nil {
+		return nil, err
+	}
+	return &Vault{
+		contract:       contract,
+		pkScript:       pkScript,
+		recoveryAddr:   recoveryAddr,
+		recoveryScript: recoveryScript,
+	}, nil
+}
+
+// Contract returns the contract of the vault.
+func (v *Vault) Contract() *contracts.Vault {
+	return v.contract
+}
+
+// PkScript returns the public key script paying to the vault address.
+func (v *Vault) PkScript() []byte {
+	return v.pkScript
+}
+
+// RecoveryScript returns the public key script the funds are recovered to.
+func (v *Vault) RecoveryScript() []byte {
+	return v.recoveryScript
+}
+
+// WatchAddresses returns the addresses a watcher of the vault watches: the
+// vault address, payments to which unvault funds, and the recovery address,
+// which tells the recovery transaction confirmed.
+func (v *Vault) WatchAddresses() []btcutil.Address {
+	return []btcutil.Address{v.contract.Address, v.recoveryAddr}
+}
+
+// Funds returns the outpoint and amount held by a funded vault.
+func (v *Vault) Funds() (wire.OutPoint, btcutil.Amount) {
+	return v.outPoint, v.amount
+}
+
+// Fund records tx as funding the vault.  The first output of tx paying the
+// base token to the vault address holds the funds.
+func (v *Vault) Fund(tx *wire.MsgTx) error {
+	for i, txOut := range tx.TxOut {
+		value, ok := txOut.Token.Value.(*token.NumeralVal)
+		if txOut.Token.TokenType != 0 || !ok || value.Val <= 0 ||
+			!bytes.Equal(txOut.PkScript, v.pkScript) {
+			continue
+		}
+		v.fundTx = tx
+		v.outPoint = wire.OutPoint{Hash: tx.TxHash(), Index: uint32(i)}
+		v.amount = btcutil.Amount(value.Val)
+		return nil
+	}
+	return ErrNotFunding
+}
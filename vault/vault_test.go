@@ -0,0 +1,118 @@
+// Copyright (c) 2018-2021 The Omegasuite developers
+// Use of this source code is governed by an ISC
+// license that can be found in the LICENSE file.
+
+package vault_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/zeusyf/btcd/btcec"
+	"github.com/zeusyf/btcd/chaincfg"
+	"github.com/zeusyf/btcd/txscript"
+	"github.com/zeusyf/btcd/wire"
+	"github.com/zeusyf/btcutil"
+	"github.com/zeusyf/btcutil/contracts"
+	"github.com/zeusyf/btcutil/locktime"
+	"github.com/zeusyf/btcutil/vault"
+	"github.com/zeusyf/omega/token"
+)
+
+// pubKey returns the public key of the private key b.
+func pubKey(b byte) *btcec.PublicKey {
+	_, pub := btcec.PrivKeyFromBytes(btcec.S256(), []byte{b})
+	return pub
+}
+
+// testVault returns a vault of the hot key 1 and the recovery key 2, with a
+// delay of 144 blocks, recovering to the pay-to-pubkey-hash address of the
+// key 3.
+func testVault(t *testing.T) *vault.Vault {
+	recoveryAddr, err := btcutil.NewAddressPubKeyHash(
+		btcutil.Hash160(pubKey(3).SerializeCompressed()),
+		&chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("NewAddressPubKeyHash: unexpected error: %v", err)
+	}
+	v, err := vault.New(pubKey(1), pubKey(2), locktime.RelativeBlocks(144),
+		recoveryAddr, &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("New: unexpected error: %v", err)
+	}
+	return v
+}
+
+// fundingTx returns a transaction paying value of the passed token type to
+// pkScript as its second output.
+func fundingTx(tokenType uint64, value int64, pkScript []byte) *wire.MsgTx {
+	tx := wire.NewMsgTx(wire.TxVersion)
+	tx.AddTxIn(&wire.TxIn{SignatureIndex: 0xffffffff})
+	tx.AddTxOut(&wire.TxOut{
+		Token:    token.Token{Value: &token.NumeralVal{Val: 1000}},
+		PkScript: []byte{0x51},
+	})
+	tx.AddTxOut(&wire.TxOut{
+		Token:    token.Token{TokenType: tokenType, Value: &token.NumeralVal{Val: value}},
+		PkScript: pkScript,
+	})
+	return tx
+}
+
+// TestNew ensures a vault pays the address of its contract and watches it
+// along with the recovery address.
+func TestNew(t *testing.T) {
+	v := testVault(t)
+	contract, err := contracts.NewVault(pubKey(1), pubKey(2),
+		locktime.RelativeBlocks(144), &chaincfg.MainNetParams)
+	if err != nil {
+		t.Fatalf("NewVault: unexpected error: %v", err)
+	}
+	if !bytes.Equal(v.Contract().RedeemScript, contract.RedeemScript) {
+		t.Errorf("Contract: got script %x, want %x",
+			v.Contract().RedeemScript, contract.RedeemScript)
+	}
+	pkScript, _ := txscript.PayToAddrScript(contract.Address)
+	if !bytes.Equal(v.PkScript(), pkScript) {
+		t.Errorf("PkScript: got %x, want %x", v.PkScript(), pkScript)
+	}
+
+	addrs := v.WatchAddresses()
+	if len(addrs) != 2 ||
+		addrs[0].EncodeAddress() != contract.Address.EncodeAddress() {
+		t.Fatalf("WatchAddresses: got %v", addrs)
+	}
+	recoveryScript, _ := txscript.PayToAddrScript(addrs[1])
+	if !bytes.Equal(v.RecoveryScript(), recoveryScript) {
+		t.Errorf("RecoveryScript: got %x, want %x", v.RecoveryScript(),
+			recoveryScript)
+	}
+
+	_, err = vault.New(pubKey(1), pubKey(2), locktime.RelativeBlocks(0),
+		addrs[1], &chaincfg.MainNetParams)
+	if err != contracts.ErrInvalidDelay {
+		t.Errorf("New: got error %v, want %v", err,
+			contracts.ErrInvalidDelay)
+	}
+}
+
+// TestFund ensures only outputs paying the base token to the vault address
+// fund it.
+func TestFund(t *testing.T) {
+	v := testVault(t)
+	if err := v.Fund(fundingTx(0, 5000, []byte{0x51})); err != vault.ErrNotFunding {
+		t.Fatalf("Fund: got error %v, want %v", err, vault.ErrNotFunding)
+	}
+	if err := v.Fund(fundingTx(1, 5000, v.PkScript())); err != vault.ErrNotFunding {
+		t.Fatalf("Fund: got error %v, want %v", err, vault.ErrNotFunding)
+	}
+
+	tx := fundingTx(0, 5000, v.PkScript())
+	if err := v.Fund(tx); err != nil {
+		t.Fatalf("Fund: unexpected error: %v", err)
+	}
+	op, amount := v.Funds()
+	if op.Hash != tx.TxHash() || op.Index != 1 || amount != 5000 {
+		t.Errorf("Funds: got %v, %v", op, amount)
+	}
+}
@@ -5,13 +5,14 @@
 package btcutil
 
 import (
-	"bytes"
+	"crypto/subtle"
 	"errors"
 
 	"github.com/zeusyf/btcd/btcec"
 	"github.com/zeusyf/btcd/chaincfg"
 	"github.com/zeusyf/btcd/chaincfg/chainhash"
 	"github.com/zeusyf/btcutil/base58"
+	"github.com/zeusyf/btcutil/securemem"
 )
 
 // ErrMalformedPrivateKey describes an error where a WIF-encoded private
@@ -43,6 +44,10 @@ type WIF struct {
 	// netID is the bitcoin network identifier byte used when
 	// WIF encoding the private key.
 	netID byte
+
+	// secret is the buffer holding the private key once Lock moved it
+	// there, or nil.
+	secret *securemem.Buffer
 }
 
 // NewWIF creates a new WIF structure to export an address and its private key
@@ -51,9 +56,10 @@ type WIF struct {
 // by serializing the public key compressed rather than uncompressed.
 func NewWIF(privKey *btcec.PrivateKey, net *chaincfg.Params, compress bool) (*WIF, error) {
 	if net == nil {
-		return nil, errors.New("no network")
+		return nil, ErrNoNet
 	}
-	return &WIF{privKey, compress, net.PrivateKeyID}, nil
+	return &WIF{PrivKey: privKey, CompressPubKey: compress,
+		netID: net.PrivateKeyID}, nil
 }
 
 // IsForNet returns whether or not the decoded WIF structure is associated
@@ -84,6 +90,7 @@ func (w *WIF) IsForNet(net *chaincfg.Params) bool {
 // if the expected WIF checksum does not match the calculated checksum.
 func DecodeWIF(wif string) (*WIF, error) {
 	decoded := base58.Decode(wif)
+	defer zero(decoded)
 	decodedLen := len(decoded)
 	var compress bool
 
@@ -111,20 +118,26 @@ func DecodeWIF(wif string) (*WIF, error) {
 		tosum = decoded[:1+btcec.PrivKeyBytesLen]
 	}
 	cksum := chainhash.DoubleHashB(tosum)[:4]
-	if !bytes.Equal(cksum, decoded[decodedLen-4:]) {
+	if subtle.ConstantTimeCompare(cksum, decoded[decodedLen-4:]) != 1 {
 		return nil, ErrChecksumMismatch
 	}
 
 	netID := decoded[0]
 	privKeyBytes := decoded[1 : 1+btcec.PrivKeyBytesLen]
 	privKey, _ := btcec.PrivKeyFromBytes(btcec.S256(), privKeyBytes)
-	return &WIF{privKey, compress, netID}, nil
+	return &WIF{PrivKey: privKey, CompressPubKey: compress, netID: netID}, nil
 }
 
 // String creates the Wallet Import Format string encoding of a WIF structure.
 // See DecodeWIF for a detailed breakdown of the format and requirements of
-// a valid WIF string.
+// a valid WIF string.  The bytes of the private key serialized on the way are
+// zeroed before returning.  An empty string is returned once the WIF structure
+// is zeroed.
 func (w *WIF) String() string {
+	if w.PrivKey == nil {
+		return ""
+	}
+
 	// Precalculate size.  Maximum number of bytes before base58 encoding
 	// is one byte for the network, 32 bytes of private key, possibly one
 	// extra byte if the pubkey is to be compressed, and finally four
@@ -134,17 +147,80 @@ func (w *WIF) String() string {
 		encodeLen++
 	}
 
-	a := make([]byte, 0, encodeLen)
-	a = append(a, w.netID)
-	// Pad and append bytes manually, instead of using Serialize, to
-	// avoid another call to make.
-	a = paddedAppend(btcec.PrivKeyBytesLen, a, w.PrivKey.D.Bytes())
+	a := make([]byte, 1+btcec.PrivKeyBytesLen, encodeLen)
+	a[0] = w.netID
+	// Fill the padded key in place, instead of using Serialize, to avoid
+	// another call to make and a copy of the key which is not zeroed.
+	w.PrivKey.D.FillBytes(a[1:])
 	if w.CompressPubKey {
 		a = append(a, compressMagic)
 	}
 	cksum := chainhash.DoubleHashB(a)[:4]
 	a = append(a, cksum...)
-	return base58.Encode(a)
+	s := base58.Encode(a)
+	zero(a)
+	return s
+}
+
+// Equal returns whether the WIF structures encode the same private key for the
+// same network and public key serialization.  The private keys are compared
+// in constant time.
+func (w *WIF) Equal(other *WIF) bool {
+	return w.netID == other.netID &&
+		w.CompressPubKey == other.CompressPubKey &&
+		EqualPrivKeys(w.PrivKey, other.PrivKey)
+}
+
+// EqualPrivKeys returns whether the private keys are equal, comparing them in
+// constant time so the comparison does not leak how much of the keys match.
+// Two nil keys are equal.
+func EqualPrivKeys(a, b *btcec.PrivateKey) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	var ka, kb [btcec.PrivKeyBytesLen]byte
+	a.D.FillBytes(ka[:])
+	b.D.FillBytes(kb[:])
+	equal := subtle.ConstantTimeCompare(ka[:], kb[:]) == 1
+	zero(ka[:])
+	zero(kb[:])
+	return equal
+}
+
+// Lock moves the private key of the WIF structure into a securemem.Buffer,
+// locked into physical memory where the platform supports it, so it is never
+// written to swap, and returns whether it is locked.  The private key keeps
+// working in place, and must not be changed or in use elsewhere once Zero
+// destroys the buffer.  Locking a WIF structure again does nothing.
+func (w *WIF) Lock() bool {
+	if w.PrivKey == nil || w.PrivKey.D == nil {
+		return false
+	}
+	if w.secret == nil {
+		w.secret = securemem.LockInt(w.PrivKey.D)
+	}
+	return w.secret.Locked()
+}
+
+// Zero clears the private key of the WIF structure from memory, along with its
+// other fields, and destroys the buffer Lock moved it into.  The private key
+// must not be in use elsewhere, since it is cleared in place.  Zeroed WIF
+// structures encode as an empty string.
+func (w *WIF) Zero() {
+	if w.PrivKey != nil && w.PrivKey.D != nil {
+		words := w.PrivKey.D.Bits()
+		for i := range words {
+			words[i] = 0
+		}
+		w.PrivKey.D.SetBits(nil)
+	}
+	if w.secret != nil {
+		w.secret.Destroy()
+		w.secret = nil
+	}
+	w.PrivKey = nil
+	w.CompressPubKey = false
+	w.netID = 0
 }
 
 // SerializePubKey serializes the associated public key of the imported or
@@ -167,3 +243,11 @@ func paddedAppend(size uint, dst, src []byte) []byte {
 	}
 	return append(dst, src...)
 }
+
+// zero sets all bytes in the passed slice to zero.  This is used to
+// explicitly clear private key material from memory.
+func zero(b []byte) {
+	for i := range b {
+		b[i] = 0
+	}
+}
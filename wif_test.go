@@ -5,6 +5,7 @@
 package btcutil_test
 
 import (
+	"bytes"
 	"testing"
 
 	"github.com/zeusyf/btcd/btcec"
@@ -69,3 +70,105 @@ func TestEncodeDecodeWIF(t *testing.T) {
 		}
 	}
 }
+
+// TestWIFZero ensures zeroing a WIF clears its private key in place.
+func TestWIFZero(t *testing.T) {
+	w, err := DecodeWIF("5HueCGU8rMjxEXxiPuD5BDku4MkFqeZyd4dZ1jvhTVqvbTLvyTJ")
+	if err != nil {
+		t.Fatalf("DecodeWIF: unexpected error: %v", err)
+	}
+	d := w.PrivKey.D
+	words := d.Bits()
+	w.Zero()
+	if d.Sign() != 0 {
+		t.Errorf("Zero: private key is %v, want 0", d)
+	}
+	for _, word := range words {
+		if word != 0 {
+			t.Fatalf("Zero: private key words not cleared")
+		}
+	}
+	if w.PrivKey != nil {
+		t.Errorf("Zero: private key not cleared")
+	}
+	if s := w.String(); s != "" {
+		t.Errorf("String: got %q for a zeroed WIF", s)
+	}
+}
+
+// TestWIFEqual ensures WIFs are equal only when they encode the same key for
+// the same network and public key serialization.
+func TestWIFEqual(t *testing.T) {
+	priv1, _ := btcec.PrivKeyFromBytes(btcec.S256(), []byte{0x01})
+	priv2, _ := btcec.PrivKeyFromBytes(btcec.S256(), []byte{0x02})
+	same, _ := btcec.PrivKeyFromBytes(btcec.S256(), []byte{0x01})
+
+	w, _ := NewWIF(priv1, &chaincfg.MainNetParams, true)
+	tests := []struct {
+		name  string
+		other func() (*WIF, error)
+		equal bool
+	}{
+		{"same key", func() (*WIF, error) {
+			return NewWIF(same, &chaincfg.MainNetParams, true)
+		}, true},
+		{"other key", func() (*WIF, error) {
+			return NewWIF(priv2, &chaincfg.MainNetParams, true)
+		}, false},
+		{"uncompressed", func() (*WIF, error) {
+			return NewWIF(same, &chaincfg.MainNetParams, false)
+		}, false},
+		{"testnet", func() (*WIF, error) {
+			return NewWIF(same, &chaincfg.TestNet3Params, true)
+		}, false},
+	}
+	for _, test := range tests {
+		other, err := test.other()
+		if err != nil {
+			t.Fatalf("%s: NewWIF: unexpected error: %v", test.name, err)
+		}
+		if got := w.Equal(other); got != test.equal {
+			t.Errorf("%s: got equal %v, want %v", test.name, got, test.equal)
+		}
+	}
+
+	if EqualPrivKeys(priv1, nil) || !EqualPrivKeys(nil, nil) {
+		t.Errorf("EqualPrivKeys: wrong result for nil keys")
+	}
+}
+
+// TestWIFLock ensures a locked WIF keeps encoding and signing with its private
+// key, and zeroing it clears the key.
+func TestWIFLock(t *testing.T) {
+	const encoded = "KwdMAjGmerYanjeui5SHS7JkmpZvVipYvB2LJGU1ZxJwYvP98617"
+	w, err := DecodeWIF(encoded)
+	if err != nil {
+		t.Fatalf("DecodeWIF: unexpected error: %v", err)
+	}
+	pubKey := w.SerializePubKey()
+	words := w.PrivKey.D.Bits()
+	locked := w.Lock()
+	t.Logf("key locked: %v", locked)
+	if w.Lock() != locked {
+		t.Errorf("Lock: got another result locking again")
+	}
+	for _, word := range words {
+		if word != 0 {
+			t.Fatalf("Lock: private key words of the heap not cleared")
+		}
+	}
+	if s := w.String(); s != encoded {
+		t.Errorf("String: got %s, want %s", s, encoded)
+	}
+	if !bytes.Equal(w.SerializePubKey(), pubKey) {
+		t.Errorf("SerializePubKey: got another key once locked")
+	}
+	if _, err := w.PrivKey.Sign(make([]byte, 32)); err != nil {
+		t.Errorf("Sign: unexpected error: %v", err)
+	}
+
+	w.Zero()
+	if w.PrivKey != nil || w.String() != "" || w.Lock() {
+		t.Errorf("Zero: locked WIF not cleared")
+	}
+}